@@ -89,6 +89,11 @@ func provideCleanup(
 	codexVersionSync *service.OpenAICodexVersionSyncService,
 	proxyExpiry *service.ProxyExpiryService,
 	subscriptionExpiry *service.SubscriptionExpiryService,
+	credentialReencryption *service.CredentialReencryptionService,
+	apiKeyHashBackfill *service.APIKeyHashBackfillService,
+	apiKeyLeakDetection *service.APIKeyLeakDetectionService,
+	apiKeyRotation *service.APIKeyRotationService,
+	userData *service.UserDataService,
 	usageCleanup *service.UsageCleanupService,
 	idempotencyCleanup *service.IdempotencyCleanupService,
 	batchImageCleanup *service.BatchImageCleanupService,
@@ -111,6 +116,7 @@ func provideCleanup(
 	upstreamBillingProbe *service.UpstreamBillingProbeService,
 	ollamaCloudUsage *service.OllamaCloudUsageService,
 	auditLog *service.AuditLogService,
+	auditLogExport *service.AuditLogExportService,
 	promptAudit *securityaudit.PromptService,
 ) func() {
 	return func() {
@@ -172,6 +178,12 @@ func provideCleanup(
 				}
 				return nil
 			}},
+			{"AuditLogExportService", func() error {
+				if auditLogExport != nil {
+					auditLogExport.Stop()
+				}
+				return nil
+			}},
 			{"AuditLogService", func() error {
 				if auditLog != nil {
 					auditLog.Stop()
@@ -246,6 +258,26 @@ func provideCleanup(
 				subscriptionExpiry.Stop()
 				return nil
 			}},
+			{"CredentialReencryptionService", func() error {
+				credentialReencryption.Stop()
+				return nil
+			}},
+			{"APIKeyHashBackfillService", func() error {
+				apiKeyHashBackfill.Stop()
+				return nil
+			}},
+			{"APIKeyLeakDetectionService", func() error {
+				apiKeyLeakDetection.Stop()
+				return nil
+			}},
+			{"APIKeyRotationService", func() error {
+				apiKeyRotation.Stop()
+				return nil
+			}},
+			{"UserDataService", func() error {
+				userData.Stop()
+				return nil
+			}},
 			{"SubscriptionService", func() error {
 				if subscriptionService != nil {
 					subscriptionService.Stop()
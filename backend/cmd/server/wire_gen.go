@@ -50,8 +50,17 @@ func initializeApplication(buildInfo handler.BuildInfo) (*Application, error) {
 	refreshTokenCache := repository.NewRefreshTokenCache(redisClient)
 	settingRepository := repository.NewSettingRepository(client)
 	groupRepository := repository.NewGroupRepository(client, db)
-	proxyRepository := repository.NewProxyRepository(client, db)
-	settingService := service.ProvideSettingService(settingRepository, groupRepository, proxyRepository, configConfig)
+	masterKeyProvider, err := repository.ProvideMasterKeyProvider(configConfig)
+	if err != nil {
+		return nil, err
+	}
+	secretEncryptor, err := repository.NewAESEncryptor(configConfig)
+	if err != nil {
+		return nil, err
+	}
+	credentialCipher := service.NewCredentialCipher(masterKeyProvider, secretEncryptor)
+	proxyRepository := repository.ProvideProxyRepository(client, db, credentialCipher)
+	settingService := service.ProvideSettingService(settingRepository, groupRepository, proxyRepository, credentialCipher, configConfig)
 	emailCache := repository.NewEmailCache(redisClient)
 	emailService := service.NewEmailService(settingRepository, emailCache)
 	turnstileVerifier := repository.NewTurnstileVerifier()
@@ -64,7 +73,7 @@ func initializeApplication(buildInfo handler.BuildInfo) (*Application, error) {
 	promoCodeRepository := repository.NewPromoCodeRepository(client)
 	billingCache := repository.NewBillingCache(redisClient)
 	userSubscriptionRepository := repository.NewUserSubscriptionRepository(client)
-	apiKeyRepository := repository.NewAPIKeyRepository(client, db)
+	apiKeyRepository := repository.ProvideAPIKeyRepository(client, db, configConfig)
 	userRPMCache := repository.NewUserRPMCache(redisClient)
 	userGroupRateRepository := repository.NewUserGroupRateRepository(db)
 	userPlatformQuotaRepository := repository.NewUserPlatformQuotaRepository(client)
@@ -73,7 +82,7 @@ func initializeApplication(buildInfo handler.BuildInfo) (*Application, error) {
 	apiKeyCache := repository.NewAPIKeyCache(redisClient)
 	concurrencyCache := repository.ProvideConcurrencyCache(redisClient, configConfig)
 	schedulerCache := repository.ProvideSchedulerCache(redisClient, configConfig)
-	accountRepository := repository.NewAccountRepository(client, db, schedulerCache)
+	accountRepository := repository.ProvideAccountRepository(client, db, schedulerCache, credentialCipher)
 	concurrencyService := service.ProvideConcurrencyService(concurrencyCache, accountRepository, configConfig)
 	apiKeyService := service.ProvideAPIKeyService(apiKeyRepository, userRepository, groupRepository, userSubscriptionRepository, userGroupRateRepository, apiKeyCache, configConfig, billingCacheService, concurrencyService)
 	apiKeyAuthCacheInvalidator := service.ProvideAPIKeyAuthCacheInvalidator(apiKeyService)
@@ -87,10 +96,6 @@ func initializeApplication(buildInfo handler.BuildInfo) (*Application, error) {
 	userService := service.NewUserService(userRepository, settingRepository, apiKeyAuthCacheInvalidator, billingCache)
 	redeemCache := repository.NewRedeemCache(redisClient)
 	redeemService := service.NewRedeemService(redeemCodeRepository, userRepository, subscriptionService, redeemCache, billingCacheService, client, apiKeyAuthCacheInvalidator, referralService)
-	secretEncryptor, err := repository.NewAESEncryptor(configConfig)
-	if err != nil {
-		return nil, err
-	}
 	totpCache := repository.NewTotpCache(redisClient)
 	totpService := service.NewTotpService(userRepository, secretEncryptor, totpCache, settingService, emailService, emailQueueService)
 	userAttributeDefinitionRepository := repository.NewUserAttributeDefinitionRepository(client)
@@ -181,7 +186,7 @@ func initializeApplication(buildInfo handler.BuildInfo) (*Application, error) {
 	dashboardAggregationService := service.ProvideDashboardAggregationService(dashboardAggregationRepository, timingWheelService, leaderLockCache, db, configConfig)
 	dashboardHandler := admin.NewDashboardHandler(dashboardService, dashboardAggregationService)
 	adminGroupRepository := repository.NewAdminGroupRepository(client, db)
-	adminAccountRepository := repository.NewAdminAccountRepository(client, db, schedulerCache)
+	adminAccountRepository := repository.ProvideAdminAccountRepository(client, db, schedulerCache, credentialCipher)
 	proxyExitInfoProber := repository.NewProxyExitInfoProber(configConfig)
 	proxyLatencyCache := repository.NewProxyLatencyCache(redisClient)
 	adminService := service.NewAdminService(userRepository, adminGroupRepository, adminAccountRepository, proxyRepository, apiKeyRepository, redeemCodeRepository, userGroupRateRepository, userRPMCache, billingCacheService, proxyExitInfoProber, proxyLatencyCache, apiKeyAuthCacheInvalidator, client, settingService, subscriptionService, userSubscriptionRepository, privacyClientFactory, openAIGatewayService, compositeModelRouteRepository, compositeRouteResolver, channelService)
@@ -207,10 +212,10 @@ func initializeApplication(buildInfo handler.BuildInfo) (*Application, error) {
 	dataManagementHandler := admin.NewDataManagementHandler(dataManagementService)
 	backupObjectStoreFactory := repository.NewS3BackupStoreFactory()
 	dbDumper := repository.NewPgDumper(configConfig)
-	backupService := service.ProvideBackupService(settingRepository, configConfig, secretEncryptor, backupObjectStoreFactory, dbDumper, leaderLockCache, db)
+	backupService := service.ProvideBackupService(settingRepository, configConfig, credentialCipher, backupObjectStoreFactory, dbDumper, leaderLockCache, db)
 	imageStorageFactory := repository.ProvideImageStorageFactory()
-	imageStorageSettingService := service.ProvideImageStorageSettingService(settingRepository, secretEncryptor, backupService, imageStorageFactory, configConfig)
-	invoiceStorageSettingService := service.NewInvoiceStorageSettingService(settingRepository, secretEncryptor, backupService)
+	imageStorageSettingService := service.ProvideImageStorageSettingService(settingRepository, credentialCipher, backupService, imageStorageFactory, configConfig)
+	invoiceStorageSettingService := service.ProvideInvoiceStorageSettingService(settingRepository, credentialCipher, backupService)
 	backupHandler := admin.NewBackupHandler(backupService, userService, imageStorageSettingService, invoiceStorageSettingService)
 	oAuthHandler := admin.NewOAuthHandler(oAuthService)
 	openAIOAuthHandler := admin.NewOpenAIOAuthHandler(openAIOAuthService, adminService, openAIQuotaService, rateLimitService)
@@ -260,18 +265,37 @@ func initializeApplication(buildInfo handler.BuildInfo) (*Application, error) {
 	promptAdminHandler := securityaudit.NewPromptAdminHandler(promptService)
 	complianceHandler := admin.NewComplianceHandler(settingService)
 	auditLogRepository := repository.NewAuditLogRepository(db)
-	auditLogService := service.ProvideAuditLogService(auditLogRepository, settingService)
-	auditLogHandler := admin.NewAuditLogHandler(auditLogService, totpService)
+	auditLogService := service.ProvideAuditLogService(auditLogRepository, settingService, backupService, configConfig)
+	auditLogExportCursorRepository := repository.NewAuditLogExportCursorRepository(db)
+	auditLogExportService := service.ProvideAuditLogExportService(auditLogRepository, auditLogExportCursorRepository, auditLogService, configConfig, serviceBuildInfo, leaderLockCache, db)
+	auditLogHandler := admin.NewAuditLogHandler(auditLogService, totpService, auditLogExportService)
+	adminRBACRepository := repository.NewAdminRBACRepository(db)
+	adminRBACService := service.NewAdminRBACService(adminRBACRepository)
+	adminRBACHandler := admin.NewAdminRBACHandler(adminRBACService, adminService)
+	accountCostRepository := repository.NewAccountCostRepository(db)
+	accountCostService := service.NewAccountCostService(accountCostRepository, accountRepository)
+	accountCostHandler := admin.NewAccountCostHandler(accountCostService)
+	proxyPoolRepository := repository.NewProxyPoolRepository(db)
+	proxyPoolService := service.NewProxyPoolService(proxyPoolRepository, proxyRepository, accountRepository, proxyLatencyCache, proxyExitInfoProber)
+	proxyPoolHandler := admin.NewProxyPoolHandler(proxyPoolService)
+	proxySubscriptionRepository := repository.NewProxySubscriptionRepository(db)
+	proxySubscriptionService := service.NewProxySubscriptionService(proxySubscriptionRepository, proxyRepository, configConfig)
+	proxySubscriptionHandler := admin.NewProxySubscriptionHandler(proxySubscriptionService)
+	apiKeyLeakRepository := repository.NewAPIKeyLeakRepository(db)
+	apiKeyLeakDetectionService := service.ProvideAPIKeyLeakDetectionService(apiKeyLeakRepository, apiKeyRepository, userRepository, apiKeyAuthCacheInvalidator, notificationEmailService, auditLogService, configConfig, leaderLockCache, db)
+	adminAPIKeyLeakHandler := admin.NewAPIKeyLeakHandler(apiKeyLeakDetectionService)
 	upstreamBillingProbeService := service.ProvideUpstreamBillingProbeService(accountRepository, accountTestService, settingService, leaderLockCache, db)
 	ollamaCloudUsageService := service.ProvideOllamaCloudUsageService(accountRepository, httpUpstream, settingService, secretEncryptor, configConfig, leaderLockCache, db)
-	adminHandlers := handler.ProvideAdminHandlers(dashboardHandler, adminUserHandler, groupHandler, accountHandler, adminAnnouncementHandler, dataManagementHandler, backupHandler, oAuthHandler, openAIOAuthHandler, geminiOAuthHandler, antigravityOAuthHandler, grokOAuthHandler, proxyHandler, adminRedeemHandler, promoHandler, settingHandler, opsHandler, systemHandler, adminSubscriptionHandler, adminUsageHandler, userAttributeHandler, errorPassthroughHandler, referralHandler, tlsFingerprintProfileHandler, adminAPIKeyHandler, scheduledTestHandler, channelHandler, contentModerationHandler, promptAdminHandler, complianceHandler, auditLogHandler, upstreamBillingProbeService, ollamaCloudUsageService)
+	adminHandlers := handler.ProvideAdminHandlers(dashboardHandler, adminUserHandler, groupHandler, accountHandler, adminAnnouncementHandler, dataManagementHandler, backupHandler, oAuthHandler, openAIOAuthHandler, geminiOAuthHandler, antigravityOAuthHandler, grokOAuthHandler, proxyHandler, adminRedeemHandler, promoHandler, settingHandler, opsHandler, systemHandler, adminSubscriptionHandler, adminUsageHandler, userAttributeHandler, errorPassthroughHandler, referralHandler, tlsFingerprintProfileHandler, adminAPIKeyHandler, scheduledTestHandler, channelHandler, contentModerationHandler, promptAdminHandler, complianceHandler, auditLogHandler, adminRBACHandler, accountCostHandler, proxyPoolHandler, proxySubscriptionHandler, adminAPIKeyLeakHandler, upstreamBillingProbeService, ollamaCloudUsageService)
 	usageRecordWorkerPool := service.NewUsageRecordWorkerPool(configConfig)
 	userMsgQueueCache := repository.NewUserMsgQueueCache(redisClient)
 	userMessageQueueService := service.ProvideUserMessageQueueService(userMsgQueueCache, rpmCache, configConfig)
 	legacyEngine := securityaudit.NewLegacyModerationAdapter(contentModerationService)
 	coordinator := securityaudit.NewCoordinator(legacyEngine, promptService)
-	gatewayHandler := handler.ProvideGatewayHandler(gatewayService, openAIGatewayService, geminiMessagesCompatService, antigravityGatewayService, userService, concurrencyService, billingCacheService, usageService, apiKeyService, usageRecordWorkerPool, errorPassthroughService, contentModerationService, userMessageQueueService, configConfig, settingService, coordinator)
-	openAIGatewayHandler := handler.ProvideOpenAIGatewayHandler(openAIGatewayService, concurrencyService, billingCacheService, apiKeyService, usageRecordWorkerPool, errorPassthroughService, contentModerationService, opsService, grokQuotaService, configConfig, coordinator)
+	upstreamFileRepository := repository.NewUpstreamFileRepository(db)
+	upstreamFileService := service.NewUpstreamFileService(upstreamFileRepository, accountRepository, gatewayService, openAIGatewayService, httpUpstream)
+	gatewayHandler := handler.ProvideGatewayHandler(gatewayService, openAIGatewayService, geminiMessagesCompatService, antigravityGatewayService, userService, concurrencyService, billingCacheService, usageService, apiKeyService, usageRecordWorkerPool, errorPassthroughService, contentModerationService, userMessageQueueService, configConfig, settingService, coordinator, upstreamFileService)
+	openAIGatewayHandler := handler.ProvideOpenAIGatewayHandler(openAIGatewayService, concurrencyService, billingCacheService, apiKeyService, usageRecordWorkerPool, errorPassthroughService, contentModerationService, opsService, grokQuotaService, configConfig, coordinator, upstreamFileService)
 	handlerSettingHandler := handler.ProvideSettingHandler(settingService, buildInfo, notificationEmailService)
 	totpHandler := handler.NewTotpHandler(totpService)
 	handlerReferralHandler := handler.NewReferralHandler(referralService)
@@ -290,6 +314,16 @@ func initializeApplication(buildInfo handler.BuildInfo) (*Application, error) {
 	imageTaskStore := repository.NewImageTaskStore(redisClient)
 	imageTaskService := service.ProvideImageTaskService(imageTaskStore, imageStorageSettingService)
 	asyncImageHandler := handler.NewAsyncImageHandler(imageTaskService, openAIGatewayHandler)
+	backgroundResponseStore := repository.NewBackgroundResponseStore(redisClient)
+	backgroundResponseService := service.ProvideBackgroundResponseService(backgroundResponseStore, imageStorageSettingService)
+	backgroundResponseHandler := handler.NewBackgroundResponseHandler(backgroundResponseService, openAIGatewayHandler)
+	streamResumeStore := repository.NewStreamResumeStore(redisClient)
+	streamResumeService := service.NewStreamResumeService(streamResumeStore)
+	streamResumeHandler := handler.NewStreamResumeHandler(streamResumeService)
+	inferenceIdempotencyService := service.ProvideInferenceIdempotencyService(idempotencyRepository, configConfig)
+	inferenceIdempotencyHandler := handler.NewInferenceIdempotencyHandler(inferenceIdempotencyService)
+	contextCompactionService := service.NewContextCompactionService(pricingService, channelService)
+	contextCompactionHandler := handler.NewContextCompactionHandler(contextCompactionService)
 	batchImageRepository := repository.NewBatchImageRepository(db)
 	batchImageQueue := repository.NewBatchImageQueue(redisClient, configConfig)
 	batchImageModelPricingResolver := service.ProvideBatchImageModelPricingResolver(modelPricingResolver)
@@ -302,12 +336,25 @@ func initializeApplication(buildInfo handler.BuildInfo) (*Application, error) {
 	payAttachmentService := service.NewPayAttachmentService(invoiceStorageSettingService, payAttachmentStoreFactory)
 	payInvoiceNotifyService := service.NewPayInvoiceNotifyService(notificationEmailService, userService)
 	payBridgeHandler := handler.NewPayBridgeHandler(payAttachmentService, payInvoiceNotifyService)
+	scimRepository := repository.NewSCIMRepository(db)
+	scimService := service.NewSCIMService(scimRepository, userRepository, groupRepository, adminService, subscriptionService, authService, apiKeyAuthCacheInvalidator)
+	scimHandler := handler.NewSCIMHandler(scimService)
+	apiKeyLeakHandler := handler.NewAPIKeyLeakHandler(apiKeyLeakDetectionService)
+	apiKeyRotationRepository := repository.NewAPIKeyRotationRepository(client, db, configConfig)
+	apiKeyRotationService := service.ProvideAPIKeyRotationService(apiKeyRotationRepository, apiKeyService, userRepository, credentialCipher, notificationEmailService, auditLogService, configConfig, leaderLockCache, db)
+	apiKeyRotationHandler := handler.NewAPIKeyRotationHandler(apiKeyRotationService)
+	scopedTokenUsageCache := repository.NewScopedTokenUsageCache(redisClient)
+	scopedTokenService := service.ProvideScopedTokenService(apiKeyRepository, apiKeyService, scopedTokenUsageCache, configConfig)
+	scopedTokenHandler := handler.NewScopedTokenHandler(scopedTokenService)
+	userDataRepository := repository.NewUserDataRepository(db)
+	userDataService := service.ProvideUserDataService(userDataRepository, userRepository, adminService, authService, auditLogService, configConfig, leaderLockCache, db)
+	userDataHandler := handler.NewUserDataHandler(userDataService)
 	idempotencyCoordinator := service.ProvideIdempotencyCoordinator(idempotencyRepository, configConfig)
 	idempotencyCleanupService := service.ProvideIdempotencyCleanupService(idempotencyRepository, configConfig)
-	handlers := handler.ProvideHandlers(authHandler, userHandler, apiKeyHandler, usageHandler, redeemHandler, subscriptionHandler, announcementHandler, adminHandlers, gatewayHandler, openAIGatewayHandler, handlerSettingHandler, totpHandler, handlerReferralHandler, modelCatalogHandler, publicPricingHandler, groupStatusHandler, passkeyHandler, availableChannelHandler, asyncImageHandler, batchImageHandler, payBridgeHandler, idempotencyCoordinator, idempotencyCleanupService)
+	handlers := handler.ProvideHandlers(authHandler, userHandler, apiKeyHandler, usageHandler, redeemHandler, subscriptionHandler, announcementHandler, adminHandlers, gatewayHandler, openAIGatewayHandler, handlerSettingHandler, totpHandler, handlerReferralHandler, modelCatalogHandler, publicPricingHandler, groupStatusHandler, passkeyHandler, availableChannelHandler, asyncImageHandler, backgroundResponseHandler, streamResumeHandler, inferenceIdempotencyHandler, contextCompactionHandler, batchImageHandler, payBridgeHandler, scimHandler, apiKeyLeakHandler, apiKeyRotationHandler, scopedTokenHandler, userDataHandler, idempotencyCoordinator, idempotencyCleanupService)
 	jwtAuthMiddleware := middleware.NewJWTAuthMiddleware(authService, userService, settingService, auditLogService)
 	optionalJWTAuthMiddleware := middleware.NewOptionalJWTAuthMiddleware(authService, userService, settingService, auditLogService)
-	adminAuthMiddleware := middleware.NewAdminAuthMiddleware(authService, userService, settingService, auditLogService)
+	adminAuthMiddleware := middleware.NewAdminAuthMiddleware(authService, userService, settingService, auditLogService, adminRBACService)
 	apiKeyAuthMiddleware := middleware.NewAPIKeyAuthMiddleware(apiKeyService, subscriptionService, configConfig)
 	auditLogMiddleware := middleware.NewAuditLogMiddleware(auditLogService)
 	stepUpAuthMiddleware := middleware.NewStepUpAuthMiddleware(totpService, userService, settingService)
@@ -321,13 +368,17 @@ func initializeApplication(buildInfo handler.BuildInfo) (*Application, error) {
 	opsIngressRejectAggregator := service.ProvideOpsIngressRejectAggregator(opsRepository, opsService)
 	accountExpiryService := service.ProvideAccountExpiryService(accountRepository)
 	openAICodexVersionSyncService := service.ProvideOpenAICodexVersionSyncService(settingRepository, settingService, gitHubReleaseClient)
-	proxyExpiryService := service.ProvideProxyExpiryService(proxyRepository)
+	proxyExpiryService := service.ProvideProxyExpiryService(proxyRepository, proxyPoolService, proxySubscriptionService)
 	subscriptionExpiryService := service.ProvideSubscriptionExpiryService(userSubscriptionRepository, settingRepository, notificationEmailService, leaderLockCache, db)
+	credentialReencryptionRepository := repository.NewCredentialReencryptionRepository(db)
+	credentialReencryptionService := service.ProvideCredentialReencryptionService(credentialReencryptionRepository, settingRepository, credentialCipher, configConfig, leaderLockCache, db)
+	apiKeyHashBackfillRepository := repository.ProvideAPIKeyHashBackfillRepository(client, db, configConfig)
+	apiKeyHashBackfillService := service.ProvideAPIKeyHashBackfillService(apiKeyHashBackfillRepository, apiKeyService, configConfig, leaderLockCache, db)
 	batchImageWorkerRuntime := service.ProvideBatchImageWorkerRuntime(batchImageRepository, accountRepository, batchImageQueue, usageBillingRepository, usageLogRepository, batchImageModelPricingResolver, apiKeyAuthCacheInvalidator, configConfig)
 	scheduledTestRunnerService := service.ProvideScheduledTestRunnerService(scheduledTestPlanRepository, scheduledTestService, accountTestService, rateLimitService, configConfig)
 	groupStatusRunnerService := service.ProvideGroupStatusRunnerService(groupStatusRepository, groupStatusProbeService, configConfig)
 	userPlatformQuotaUsageFlusher := service.ProvideUserPlatformQuotaUsageFlusher(configConfig, billingCache, serviceUserPlatformQuotaRepository, timingWheelService)
-	v := provideCleanup(client, redisClient, opsMetricsCollector, opsAggregationService, opsAlertEvaluatorService, opsCleanupService, opsScheduledReportService, opsSystemLogSink, opsService, opsIngressRejectAggregator, apiKeyService, authCacheInvalidationWorker, schedulerSnapshotService, tokenRefreshService, accountExpiryService, openAICodexVersionSyncService, proxyExpiryService, subscriptionExpiryService, usageCleanupService, idempotencyCleanupService, batchImageCleanupService, batchImageWorkerRuntime, pricingService, emailQueueService, billingCacheService, usageRecordWorkerPool, subscriptionService, oAuthService, openAIOAuthService, geminiOAuthService, antigravityOAuthService, grokOAuthService, openAIGatewayService, scheduledTestRunnerService, groupStatusRunnerService, backupService, userPlatformQuotaUsageFlusher, upstreamBillingProbeService, ollamaCloudUsageService, auditLogService, promptService)
+	v := provideCleanup(client, redisClient, opsMetricsCollector, opsAggregationService, opsAlertEvaluatorService, opsCleanupService, opsScheduledReportService, opsSystemLogSink, opsService, opsIngressRejectAggregator, apiKeyService, authCacheInvalidationWorker, schedulerSnapshotService, tokenRefreshService, accountExpiryService, openAICodexVersionSyncService, proxyExpiryService, subscriptionExpiryService, credentialReencryptionService, apiKeyHashBackfillService, apiKeyLeakDetectionService, apiKeyRotationService, userDataService, usageCleanupService, idempotencyCleanupService, batchImageCleanupService, batchImageWorkerRuntime, pricingService, emailQueueService, billingCacheService, usageRecordWorkerPool, subscriptionService, oAuthService, openAIOAuthService, geminiOAuthService, antigravityOAuthService, grokOAuthService, openAIGatewayService, scheduledTestRunnerService, groupStatusRunnerService, backupService, userPlatformQuotaUsageFlusher, upstreamBillingProbeService, ollamaCloudUsageService, auditLogService, auditLogExportService, promptService)
 	application := &Application{
 		Server:      httpServer,
 		PromptAudit: promptService,
@@ -374,6 +425,11 @@ func provideCleanup(
 	codexVersionSync *service.OpenAICodexVersionSyncService,
 	proxyExpiry *service.ProxyExpiryService,
 	subscriptionExpiry *service.SubscriptionExpiryService,
+	credentialReencryption *service.CredentialReencryptionService,
+	apiKeyHashBackfill *service.APIKeyHashBackfillService,
+	apiKeyLeakDetection *service.APIKeyLeakDetectionService,
+	apiKeyRotation *service.APIKeyRotationService,
+	userData *service.UserDataService,
 	usageCleanup *service.UsageCleanupService,
 	idempotencyCleanup *service.IdempotencyCleanupService,
 	batchImageCleanup *service.BatchImageCleanupService,
@@ -396,6 +452,7 @@ func provideCleanup(
 	upstreamBillingProbe *service.UpstreamBillingProbeService,
 	ollamaCloudUsage *service.OllamaCloudUsageService,
 	auditLog *service.AuditLogService,
+	auditLogExport *service.AuditLogExportService,
 	promptAudit *securityaudit.PromptService,
 ) func() {
 	return func() {
@@ -456,6 +513,12 @@ func provideCleanup(
 				}
 				return nil
 			}},
+			{"AuditLogExportService", func() error {
+				if auditLogExport != nil {
+					auditLogExport.Stop()
+				}
+				return nil
+			}},
 			{"AuditLogService", func() error {
 				if auditLog != nil {
 					auditLog.Stop()
@@ -530,6 +593,26 @@ func provideCleanup(
 				subscriptionExpiry.Stop()
 				return nil
 			}},
+			{"CredentialReencryptionService", func() error {
+				credentialReencryption.Stop()
+				return nil
+			}},
+			{"APIKeyHashBackfillService", func() error {
+				apiKeyHashBackfill.Stop()
+				return nil
+			}},
+			{"APIKeyLeakDetectionService", func() error {
+				apiKeyLeakDetection.Stop()
+				return nil
+			}},
+			{"APIKeyRotationService", func() error {
+				apiKeyRotation.Stop()
+				return nil
+			}},
+			{"UserDataService", func() error {
+				userData.Stop()
+				return nil
+			}},
 			{"SubscriptionService", func() error {
 				if subscriptionService != nil {
 					subscriptionService.Stop()
@@ -80,11 +80,21 @@ func Key(v string) predicate.APIKey {
 	return predicate.APIKey(sql.FieldEQ(FieldKey, v))
 }
 
+// KeyPrefix applies equality check predicate on the "key_prefix" field. It's identical to KeyPrefixEQ.
+func KeyPrefix(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldEQ(FieldKeyPrefix, v))
+}
+
 // Name applies equality check predicate on the "name" field. It's identical to NameEQ.
 func Name(v string) predicate.APIKey {
 	return predicate.APIKey(sql.FieldEQ(FieldName, v))
 }
 
+// TokenNonce applies equality check predicate on the "token_nonce" field. It's identical to TokenNonceEQ.
+func TokenNonce(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldEQ(FieldTokenNonce, v))
+}
+
 // GroupID applies equality check predicate on the "group_id" field. It's identical to GroupIDEQ.
 func GroupID(v int64) predicate.APIKey {
 	return predicate.APIKey(sql.FieldEQ(FieldGroupID, v))
@@ -375,6 +385,71 @@ func KeyContainsFold(v string) predicate.APIKey {
 	return predicate.APIKey(sql.FieldContainsFold(FieldKey, v))
 }
 
+// KeyPrefixEQ applies the EQ predicate on the "key_prefix" field.
+func KeyPrefixEQ(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldEQ(FieldKeyPrefix, v))
+}
+
+// KeyPrefixNEQ applies the NEQ predicate on the "key_prefix" field.
+func KeyPrefixNEQ(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldNEQ(FieldKeyPrefix, v))
+}
+
+// KeyPrefixIn applies the In predicate on the "key_prefix" field.
+func KeyPrefixIn(vs ...string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldIn(FieldKeyPrefix, vs...))
+}
+
+// KeyPrefixNotIn applies the NotIn predicate on the "key_prefix" field.
+func KeyPrefixNotIn(vs ...string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldNotIn(FieldKeyPrefix, vs...))
+}
+
+// KeyPrefixGT applies the GT predicate on the "key_prefix" field.
+func KeyPrefixGT(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldGT(FieldKeyPrefix, v))
+}
+
+// KeyPrefixGTE applies the GTE predicate on the "key_prefix" field.
+func KeyPrefixGTE(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldGTE(FieldKeyPrefix, v))
+}
+
+// KeyPrefixLT applies the LT predicate on the "key_prefix" field.
+func KeyPrefixLT(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldLT(FieldKeyPrefix, v))
+}
+
+// KeyPrefixLTE applies the LTE predicate on the "key_prefix" field.
+func KeyPrefixLTE(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldLTE(FieldKeyPrefix, v))
+}
+
+// KeyPrefixContains applies the Contains predicate on the "key_prefix" field.
+func KeyPrefixContains(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldContains(FieldKeyPrefix, v))
+}
+
+// KeyPrefixHasPrefix applies the HasPrefix predicate on the "key_prefix" field.
+func KeyPrefixHasPrefix(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldHasPrefix(FieldKeyPrefix, v))
+}
+
+// KeyPrefixHasSuffix applies the HasSuffix predicate on the "key_prefix" field.
+func KeyPrefixHasSuffix(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldHasSuffix(FieldKeyPrefix, v))
+}
+
+// KeyPrefixEqualFold applies the EqualFold predicate on the "key_prefix" field.
+func KeyPrefixEqualFold(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldEqualFold(FieldKeyPrefix, v))
+}
+
+// KeyPrefixContainsFold applies the ContainsFold predicate on the "key_prefix" field.
+func KeyPrefixContainsFold(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldContainsFold(FieldKeyPrefix, v))
+}
+
 // NameEQ applies the EQ predicate on the "name" field.
 func NameEQ(v string) predicate.APIKey {
 	return predicate.APIKey(sql.FieldEQ(FieldName, v))
@@ -440,6 +515,71 @@ func NameContainsFold(v string) predicate.APIKey {
 	return predicate.APIKey(sql.FieldContainsFold(FieldName, v))
 }
 
+// TokenNonceEQ applies the EQ predicate on the "token_nonce" field.
+func TokenNonceEQ(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldEQ(FieldTokenNonce, v))
+}
+
+// TokenNonceNEQ applies the NEQ predicate on the "token_nonce" field.
+func TokenNonceNEQ(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldNEQ(FieldTokenNonce, v))
+}
+
+// TokenNonceIn applies the In predicate on the "token_nonce" field.
+func TokenNonceIn(vs ...string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldIn(FieldTokenNonce, vs...))
+}
+
+// TokenNonceNotIn applies the NotIn predicate on the "token_nonce" field.
+func TokenNonceNotIn(vs ...string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldNotIn(FieldTokenNonce, vs...))
+}
+
+// TokenNonceGT applies the GT predicate on the "token_nonce" field.
+func TokenNonceGT(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldGT(FieldTokenNonce, v))
+}
+
+// TokenNonceGTE applies the GTE predicate on the "token_nonce" field.
+func TokenNonceGTE(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldGTE(FieldTokenNonce, v))
+}
+
+// TokenNonceLT applies the LT predicate on the "token_nonce" field.
+func TokenNonceLT(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldLT(FieldTokenNonce, v))
+}
+
+// TokenNonceLTE applies the LTE predicate on the "token_nonce" field.
+func TokenNonceLTE(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldLTE(FieldTokenNonce, v))
+}
+
+// TokenNonceContains applies the Contains predicate on the "token_nonce" field.
+func TokenNonceContains(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldContains(FieldTokenNonce, v))
+}
+
+// TokenNonceHasPrefix applies the HasPrefix predicate on the "token_nonce" field.
+func TokenNonceHasPrefix(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldHasPrefix(FieldTokenNonce, v))
+}
+
+// TokenNonceHasSuffix applies the HasSuffix predicate on the "token_nonce" field.
+func TokenNonceHasSuffix(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldHasSuffix(FieldTokenNonce, v))
+}
+
+// TokenNonceEqualFold applies the EqualFold predicate on the "token_nonce" field.
+func TokenNonceEqualFold(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldEqualFold(FieldTokenNonce, v))
+}
+
+// TokenNonceContainsFold applies the ContainsFold predicate on the "token_nonce" field.
+func TokenNonceContainsFold(v string) predicate.APIKey {
+	return predicate.APIKey(sql.FieldContainsFold(FieldTokenNonce, v))
+}
+
 // GroupIDEQ applies the EQ predicate on the "group_id" field.
 func GroupIDEQ(v int64) predicate.APIKey {
 	return predicate.APIKey(sql.FieldEQ(FieldGroupID, v))
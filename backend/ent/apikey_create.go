@@ -79,12 +79,40 @@ func (_c *APIKeyCreate) SetKey(v string) *APIKeyCreate {
 	return _c
 }
 
+// SetKeyPrefix sets the "key_prefix" field.
+func (_c *APIKeyCreate) SetKeyPrefix(v string) *APIKeyCreate {
+	_c.mutation.SetKeyPrefix(v)
+	return _c
+}
+
+// SetNillableKeyPrefix sets the "key_prefix" field if the given value is not nil.
+func (_c *APIKeyCreate) SetNillableKeyPrefix(v *string) *APIKeyCreate {
+	if v != nil {
+		_c.SetKeyPrefix(*v)
+	}
+	return _c
+}
+
 // SetName sets the "name" field.
 func (_c *APIKeyCreate) SetName(v string) *APIKeyCreate {
 	_c.mutation.SetName(v)
 	return _c
 }
 
+// SetTokenNonce sets the "token_nonce" field.
+func (_c *APIKeyCreate) SetTokenNonce(v string) *APIKeyCreate {
+	_c.mutation.SetTokenNonce(v)
+	return _c
+}
+
+// SetNillableTokenNonce sets the "token_nonce" field if the given value is not nil.
+func (_c *APIKeyCreate) SetNillableTokenNonce(v *string) *APIKeyCreate {
+	if v != nil {
+		_c.SetTokenNonce(*v)
+	}
+	return _c
+}
+
 // SetGroupID sets the "group_id" field.
 func (_c *APIKeyCreate) SetGroupID(v int64) *APIKeyCreate {
 	_c.mutation.SetGroupID(v)
@@ -383,6 +411,14 @@ func (_c *APIKeyCreate) defaults() error {
 		v := apikey.DefaultUpdatedAt()
 		_c.mutation.SetUpdatedAt(v)
 	}
+	if _, ok := _c.mutation.KeyPrefix(); !ok {
+		v := apikey.DefaultKeyPrefix
+		_c.mutation.SetKeyPrefix(v)
+	}
+	if _, ok := _c.mutation.TokenNonce(); !ok {
+		v := apikey.DefaultTokenNonce
+		_c.mutation.SetTokenNonce(v)
+	}
 	if _, ok := _c.mutation.Status(); !ok {
 		v := apikey.DefaultStatus
 		_c.mutation.SetStatus(v)
@@ -441,6 +477,14 @@ func (_c *APIKeyCreate) check() error {
 			return &ValidationError{Name: "key", err: fmt.Errorf(`ent: validator failed for field "APIKey.key": %w`, err)}
 		}
 	}
+	if _, ok := _c.mutation.KeyPrefix(); !ok {
+		return &ValidationError{Name: "key_prefix", err: errors.New(`ent: missing required field "APIKey.key_prefix"`)}
+	}
+	if v, ok := _c.mutation.KeyPrefix(); ok {
+		if err := apikey.KeyPrefixValidator(v); err != nil {
+			return &ValidationError{Name: "key_prefix", err: fmt.Errorf(`ent: validator failed for field "APIKey.key_prefix": %w`, err)}
+		}
+	}
 	if _, ok := _c.mutation.Name(); !ok {
 		return &ValidationError{Name: "name", err: errors.New(`ent: missing required field "APIKey.name"`)}
 	}
@@ -449,6 +493,14 @@ func (_c *APIKeyCreate) check() error {
 			return &ValidationError{Name: "name", err: fmt.Errorf(`ent: validator failed for field "APIKey.name": %w`, err)}
 		}
 	}
+	if _, ok := _c.mutation.TokenNonce(); !ok {
+		return &ValidationError{Name: "token_nonce", err: errors.New(`ent: missing required field "APIKey.token_nonce"`)}
+	}
+	if v, ok := _c.mutation.TokenNonce(); ok {
+		if err := apikey.TokenNonceValidator(v); err != nil {
+			return &ValidationError{Name: "token_nonce", err: fmt.Errorf(`ent: validator failed for field "APIKey.token_nonce": %w`, err)}
+		}
+	}
 	if _, ok := _c.mutation.Status(); !ok {
 		return &ValidationError{Name: "status", err: errors.New(`ent: missing required field "APIKey.status"`)}
 	}
@@ -527,10 +579,18 @@ func (_c *APIKeyCreate) createSpec() (*APIKey, *sqlgraph.CreateSpec) {
 		_spec.SetField(apikey.FieldKey, field.TypeString, value)
 		_node.Key = value
 	}
+	if value, ok := _c.mutation.KeyPrefix(); ok {
+		_spec.SetField(apikey.FieldKeyPrefix, field.TypeString, value)
+		_node.KeyPrefix = value
+	}
 	if value, ok := _c.mutation.Name(); ok {
 		_spec.SetField(apikey.FieldName, field.TypeString, value)
 		_node.Name = value
 	}
+	if value, ok := _c.mutation.TokenNonce(); ok {
+		_spec.SetField(apikey.FieldTokenNonce, field.TypeString, value)
+		_node.TokenNonce = value
+	}
 	if value, ok := _c.mutation.Status(); ok {
 		_spec.SetField(apikey.FieldStatus, field.TypeString, value)
 		_node.Status = value
@@ -751,6 +811,18 @@ func (u *APIKeyUpsert) UpdateKey() *APIKeyUpsert {
 	return u
 }
 
+// SetKeyPrefix sets the "key_prefix" field.
+func (u *APIKeyUpsert) SetKeyPrefix(v string) *APIKeyUpsert {
+	u.Set(apikey.FieldKeyPrefix, v)
+	return u
+}
+
+// UpdateKeyPrefix sets the "key_prefix" field to the value that was provided on create.
+func (u *APIKeyUpsert) UpdateKeyPrefix() *APIKeyUpsert {
+	u.SetExcluded(apikey.FieldKeyPrefix)
+	return u
+}
+
 // SetName sets the "name" field.
 func (u *APIKeyUpsert) SetName(v string) *APIKeyUpsert {
 	u.Set(apikey.FieldName, v)
@@ -763,6 +835,18 @@ func (u *APIKeyUpsert) UpdateName() *APIKeyUpsert {
 	return u
 }
 
+// SetTokenNonce sets the "token_nonce" field.
+func (u *APIKeyUpsert) SetTokenNonce(v string) *APIKeyUpsert {
+	u.Set(apikey.FieldTokenNonce, v)
+	return u
+}
+
+// UpdateTokenNonce sets the "token_nonce" field to the value that was provided on create.
+func (u *APIKeyUpsert) UpdateTokenNonce() *APIKeyUpsert {
+	u.SetExcluded(apikey.FieldTokenNonce)
+	return u
+}
+
 // SetGroupID sets the "group_id" field.
 func (u *APIKeyUpsert) SetGroupID(v int64) *APIKeyUpsert {
 	u.Set(apikey.FieldGroupID, v)
@@ -1171,6 +1255,20 @@ func (u *APIKeyUpsertOne) UpdateKey() *APIKeyUpsertOne {
 	})
 }
 
+// SetKeyPrefix sets the "key_prefix" field.
+func (u *APIKeyUpsertOne) SetKeyPrefix(v string) *APIKeyUpsertOne {
+	return u.Update(func(s *APIKeyUpsert) {
+		s.SetKeyPrefix(v)
+	})
+}
+
+// UpdateKeyPrefix sets the "key_prefix" field to the value that was provided on create.
+func (u *APIKeyUpsertOne) UpdateKeyPrefix() *APIKeyUpsertOne {
+	return u.Update(func(s *APIKeyUpsert) {
+		s.UpdateKeyPrefix()
+	})
+}
+
 // SetName sets the "name" field.
 func (u *APIKeyUpsertOne) SetName(v string) *APIKeyUpsertOne {
 	return u.Update(func(s *APIKeyUpsert) {
@@ -1185,6 +1283,20 @@ func (u *APIKeyUpsertOne) UpdateName() *APIKeyUpsertOne {
 	})
 }
 
+// SetTokenNonce sets the "token_nonce" field.
+func (u *APIKeyUpsertOne) SetTokenNonce(v string) *APIKeyUpsertOne {
+	return u.Update(func(s *APIKeyUpsert) {
+		s.SetTokenNonce(v)
+	})
+}
+
+// UpdateTokenNonce sets the "token_nonce" field to the value that was provided on create.
+func (u *APIKeyUpsertOne) UpdateTokenNonce() *APIKeyUpsertOne {
+	return u.Update(func(s *APIKeyUpsert) {
+		s.UpdateTokenNonce()
+	})
+}
+
 // SetGroupID sets the "group_id" field.
 func (u *APIKeyUpsertOne) SetGroupID(v int64) *APIKeyUpsertOne {
 	return u.Update(func(s *APIKeyUpsert) {
@@ -1809,6 +1921,20 @@ func (u *APIKeyUpsertBulk) UpdateKey() *APIKeyUpsertBulk {
 	})
 }
 
+// SetKeyPrefix sets the "key_prefix" field.
+func (u *APIKeyUpsertBulk) SetKeyPrefix(v string) *APIKeyUpsertBulk {
+	return u.Update(func(s *APIKeyUpsert) {
+		s.SetKeyPrefix(v)
+	})
+}
+
+// UpdateKeyPrefix sets the "key_prefix" field to the value that was provided on create.
+func (u *APIKeyUpsertBulk) UpdateKeyPrefix() *APIKeyUpsertBulk {
+	return u.Update(func(s *APIKeyUpsert) {
+		s.UpdateKeyPrefix()
+	})
+}
+
 // SetName sets the "name" field.
 func (u *APIKeyUpsertBulk) SetName(v string) *APIKeyUpsertBulk {
 	return u.Update(func(s *APIKeyUpsert) {
@@ -1823,6 +1949,20 @@ func (u *APIKeyUpsertBulk) UpdateName() *APIKeyUpsertBulk {
 	})
 }
 
+// SetTokenNonce sets the "token_nonce" field.
+func (u *APIKeyUpsertBulk) SetTokenNonce(v string) *APIKeyUpsertBulk {
+	return u.Update(func(s *APIKeyUpsert) {
+		s.SetTokenNonce(v)
+	})
+}
+
+// UpdateTokenNonce sets the "token_nonce" field to the value that was provided on create.
+func (u *APIKeyUpsertBulk) UpdateTokenNonce() *APIKeyUpsertBulk {
+	return u.Update(func(s *APIKeyUpsert) {
+		s.UpdateTokenNonce()
+	})
+}
+
 // SetGroupID sets the "group_id" field.
 func (u *APIKeyUpsertBulk) SetGroupID(v int64) *APIKeyUpsertBulk {
 	return u.Update(func(s *APIKeyUpsert) {
@@ -97,6 +97,10 @@ type Group struct {
 	AudioTtsPricePerMillionChars *float64 `json:"audio_tts_price_per_million_chars,omitempty"`
 	// STT 每小时价格（USD）
 	AudioSttPricePerHour *float64 `json:"audio_stt_price_per_hour,omitempty"`
+	// 内容审计每次调用价格（USD/次）；nil 表示免费
+	ModerationPricePerCall *float64 `json:"moderation_price_per_call,omitempty"`
+	// 内容审计每百万输入 token 价格（USD）；nil 表示免费
+	ModerationPricePerMillionTokens *float64 `json:"moderation_price_per_million_tokens,omitempty"`
 	// 是否按上下文长度应用模型阶梯价格；默认开启以保持官方/渠道长上下文价
 	LongContextPricingEnabled bool `json:"long_context_pricing_enabled,omitempty"`
 	// 分组逐模型定价；优先级高于渠道和内置定价
@@ -143,6 +147,16 @@ type Group struct {
 	ProfitMinMargin float64 `json:"profit_min_margin,omitempty"`
 	// 安全缓冲，小数；与 margin 相加后从下游倍率中扣除，默认 0
 	ProfitSafetyBuffer float64 `json:"profit_safety_buffer,omitempty"`
+	// 是否按可缓存提示词前缀一致性哈希选择账号，提高上游 prompt cache 命中率
+	PromptPrefixRoutingEnabled bool `json:"prompt_prefix_routing_enabled,omitempty"`
+	// 参与前缀哈希的前 N 条消息，0 表示仅 tools + system
+	PromptPrefixMessages int `json:"prompt_prefix_messages,omitempty"`
+	// 是否为流式请求缓冲事件，允许客户端断线后凭 Last-Event-ID 续传
+	StreamResumeEnabled bool `json:"stream_resume_enabled,omitempty"`
+	// 超出模型上下文窗口时的处理方式：空/off 不处理，truncate 丢弃最早轮次，summarize 摘要最早轮次
+	ContextCompactionMode string `json:"context_compaction_mode,omitempty"`
+	// summarize 模式使用的摘要模型（同分组账号池）
+	ContextCompactionModel string `json:"context_compaction_model,omitempty"`
 	// Edges holds the relations/edges for other nodes in the graph.
 	// The values are being populated by the GroupQuery when eager-loading is set.
 	Edges        GroupEdges `json:"edges"`
@@ -251,13 +265,13 @@ func (*Group) scanValues(columns []string) ([]any, error) {
 		switch columns[i] {
 		case group.FieldVideoModelPrices, group.FieldModelPricing, group.FieldModelRouting, group.FieldSupportedModelScopes, group.FieldMessagesDispatchModelConfig, group.FieldModelsListConfig, group.FieldReasoningEffortMappings:
 			values[i] = new([]byte)
-		case group.FieldPeakRateEnabled, group.FieldIsExclusive, group.FieldAllowImageGeneration, group.FieldAllowBatchImageGeneration, group.FieldImageRateIndependent, group.FieldVideoRateIndependent, group.FieldLongContextPricingEnabled, group.FieldClaudeCodeOnly, group.FieldModelRoutingEnabled, group.FieldMcpXMLInject, group.FieldAllowMessagesDispatch, group.FieldAllowLive, group.FieldRequireOauthOnly, group.FieldRequirePrivacySet, group.FieldProfitControlEnabled:
+		case group.FieldPeakRateEnabled, group.FieldIsExclusive, group.FieldAllowImageGeneration, group.FieldAllowBatchImageGeneration, group.FieldImageRateIndependent, group.FieldVideoRateIndependent, group.FieldLongContextPricingEnabled, group.FieldClaudeCodeOnly, group.FieldModelRoutingEnabled, group.FieldMcpXMLInject, group.FieldAllowMessagesDispatch, group.FieldAllowLive, group.FieldRequireOauthOnly, group.FieldRequirePrivacySet, group.FieldProfitControlEnabled, group.FieldPromptPrefixRoutingEnabled, group.FieldStreamResumeEnabled:
 			values[i] = new(sql.NullBool)
-		case group.FieldRateMultiplier, group.FieldPeakRateMultiplier, group.FieldDailyLimitUsd, group.FieldWeeklyLimitUsd, group.FieldMonthlyLimitUsd, group.FieldImageRateMultiplier, group.FieldImagePrice1k, group.FieldImagePrice2k, group.FieldImagePrice4k, group.FieldBatchImageDiscountMultiplier, group.FieldBatchImageHoldMultiplier, group.FieldVideoRateMultiplier, group.FieldVideoPrice480p, group.FieldVideoPrice720p, group.FieldVideoPrice1080p, group.FieldWebSearchPricePerCall, group.FieldSearchPricePer1k, group.FieldAudioRealtimePricePerMin, group.FieldAudioTtsPricePerMillionChars, group.FieldAudioSttPricePerHour, group.FieldProfitMinMargin, group.FieldProfitSafetyBuffer:
+		case group.FieldRateMultiplier, group.FieldPeakRateMultiplier, group.FieldDailyLimitUsd, group.FieldWeeklyLimitUsd, group.FieldMonthlyLimitUsd, group.FieldImageRateMultiplier, group.FieldImagePrice1k, group.FieldImagePrice2k, group.FieldImagePrice4k, group.FieldBatchImageDiscountMultiplier, group.FieldBatchImageHoldMultiplier, group.FieldVideoRateMultiplier, group.FieldVideoPrice480p, group.FieldVideoPrice720p, group.FieldVideoPrice1080p, group.FieldWebSearchPricePerCall, group.FieldSearchPricePer1k, group.FieldAudioRealtimePricePerMin, group.FieldAudioTtsPricePerMillionChars, group.FieldAudioSttPricePerHour, group.FieldModerationPricePerCall, group.FieldModerationPricePerMillionTokens, group.FieldProfitMinMargin, group.FieldProfitSafetyBuffer:
 			values[i] = new(sql.NullFloat64)
-		case group.FieldID, group.FieldDefaultValidityDays, group.FieldFallbackGroupID, group.FieldFallbackGroupIDOnInvalidRequest, group.FieldSortOrder, group.FieldRpmLimit:
+		case group.FieldID, group.FieldDefaultValidityDays, group.FieldFallbackGroupID, group.FieldFallbackGroupIDOnInvalidRequest, group.FieldSortOrder, group.FieldRpmLimit, group.FieldPromptPrefixMessages:
 			values[i] = new(sql.NullInt64)
-		case group.FieldName, group.FieldDescription, group.FieldPeakStart, group.FieldPeakEnd, group.FieldStatus, group.FieldDuplicateOperationID, group.FieldPlatform, group.FieldSubscriptionType, group.FieldDefaultMappedModel, group.FieldMaxReasoningEffort:
+		case group.FieldName, group.FieldDescription, group.FieldPeakStart, group.FieldPeakEnd, group.FieldStatus, group.FieldDuplicateOperationID, group.FieldPlatform, group.FieldSubscriptionType, group.FieldDefaultMappedModel, group.FieldMaxReasoningEffort, group.FieldContextCompactionMode, group.FieldContextCompactionModel:
 			values[i] = new(sql.NullString)
 		case group.FieldCreatedAt, group.FieldUpdatedAt, group.FieldDeletedAt:
 			values[i] = new(sql.NullTime)
@@ -535,6 +549,20 @@ func (_m *Group) assignValues(columns []string, values []any) error {
 				_m.AudioSttPricePerHour = new(float64)
 				*_m.AudioSttPricePerHour = value.Float64
 			}
+		case group.FieldModerationPricePerCall:
+			if value, ok := values[i].(*sql.NullFloat64); !ok {
+				return fmt.Errorf("unexpected type %T for field moderation_price_per_call", values[i])
+			} else if value.Valid {
+				_m.ModerationPricePerCall = new(float64)
+				*_m.ModerationPricePerCall = value.Float64
+			}
+		case group.FieldModerationPricePerMillionTokens:
+			if value, ok := values[i].(*sql.NullFloat64); !ok {
+				return fmt.Errorf("unexpected type %T for field moderation_price_per_million_tokens", values[i])
+			} else if value.Valid {
+				_m.ModerationPricePerMillionTokens = new(float64)
+				*_m.ModerationPricePerMillionTokens = value.Float64
+			}
 		case group.FieldLongContextPricingEnabled:
 			if value, ok := values[i].(*sql.NullBool); !ok {
 				return fmt.Errorf("unexpected type %T for field long_context_pricing_enabled", values[i])
@@ -687,6 +715,36 @@ func (_m *Group) assignValues(columns []string, values []any) error {
 			} else if value.Valid {
 				_m.ProfitSafetyBuffer = value.Float64
 			}
+		case group.FieldPromptPrefixRoutingEnabled:
+			if value, ok := values[i].(*sql.NullBool); !ok {
+				return fmt.Errorf("unexpected type %T for field prompt_prefix_routing_enabled", values[i])
+			} else if value.Valid {
+				_m.PromptPrefixRoutingEnabled = value.Bool
+			}
+		case group.FieldPromptPrefixMessages:
+			if value, ok := values[i].(*sql.NullInt64); !ok {
+				return fmt.Errorf("unexpected type %T for field prompt_prefix_messages", values[i])
+			} else if value.Valid {
+				_m.PromptPrefixMessages = int(value.Int64)
+			}
+		case group.FieldStreamResumeEnabled:
+			if value, ok := values[i].(*sql.NullBool); !ok {
+				return fmt.Errorf("unexpected type %T for field stream_resume_enabled", values[i])
+			} else if value.Valid {
+				_m.StreamResumeEnabled = value.Bool
+			}
+		case group.FieldContextCompactionMode:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field context_compaction_mode", values[i])
+			} else if value.Valid {
+				_m.ContextCompactionMode = value.String
+			}
+		case group.FieldContextCompactionModel:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field context_compaction_model", values[i])
+			} else if value.Valid {
+				_m.ContextCompactionModel = value.String
+			}
 		default:
 			_m.selectValues.Set(columns[i], values[i])
 		}
@@ -914,6 +972,16 @@ func (_m *Group) String() string {
 		builder.WriteString(fmt.Sprintf("%v", *v))
 	}
 	builder.WriteString(", ")
+	if v := _m.ModerationPricePerCall; v != nil {
+		builder.WriteString("moderation_price_per_call=")
+		builder.WriteString(fmt.Sprintf("%v", *v))
+	}
+	builder.WriteString(", ")
+	if v := _m.ModerationPricePerMillionTokens; v != nil {
+		builder.WriteString("moderation_price_per_million_tokens=")
+		builder.WriteString(fmt.Sprintf("%v", *v))
+	}
+	builder.WriteString(", ")
 	builder.WriteString("long_context_pricing_enabled=")
 	builder.WriteString(fmt.Sprintf("%v", _m.LongContextPricingEnabled))
 	builder.WriteString(", ")
@@ -986,6 +1054,21 @@ func (_m *Group) String() string {
 	builder.WriteString(", ")
 	builder.WriteString("profit_safety_buffer=")
 	builder.WriteString(fmt.Sprintf("%v", _m.ProfitSafetyBuffer))
+	builder.WriteString(", ")
+	builder.WriteString("prompt_prefix_routing_enabled=")
+	builder.WriteString(fmt.Sprintf("%v", _m.PromptPrefixRoutingEnabled))
+	builder.WriteString(", ")
+	builder.WriteString("prompt_prefix_messages=")
+	builder.WriteString(fmt.Sprintf("%v", _m.PromptPrefixMessages))
+	builder.WriteString(", ")
+	builder.WriteString("stream_resume_enabled=")
+	builder.WriteString(fmt.Sprintf("%v", _m.StreamResumeEnabled))
+	builder.WriteString(", ")
+	builder.WriteString("context_compaction_mode=")
+	builder.WriteString(_m.ContextCompactionMode)
+	builder.WriteString(", ")
+	builder.WriteString("context_compaction_model=")
+	builder.WriteString(_m.ContextCompactionModel)
 	builder.WriteByte(')')
 	return builder.String()
 }
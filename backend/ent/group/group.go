@@ -94,6 +94,10 @@ const (
 	FieldAudioTtsPricePerMillionChars = "audio_tts_price_per_million_chars"
 	// FieldAudioSttPricePerHour holds the string denoting the audio_stt_price_per_hour field in the database.
 	FieldAudioSttPricePerHour = "audio_stt_price_per_hour"
+	// FieldModerationPricePerCall holds the string denoting the moderation_price_per_call field in the database.
+	FieldModerationPricePerCall = "moderation_price_per_call"
+	// FieldModerationPricePerMillionTokens holds the string denoting the moderation_price_per_million_tokens field in the database.
+	FieldModerationPricePerMillionTokens = "moderation_price_per_million_tokens"
 	// FieldLongContextPricingEnabled holds the string denoting the long_context_pricing_enabled field in the database.
 	FieldLongContextPricingEnabled = "long_context_pricing_enabled"
 	// FieldModelPricing holds the string denoting the model_pricing field in the database.
@@ -140,6 +144,16 @@ const (
 	FieldProfitMinMargin = "profit_min_margin"
 	// FieldProfitSafetyBuffer holds the string denoting the profit_safety_buffer field in the database.
 	FieldProfitSafetyBuffer = "profit_safety_buffer"
+	// FieldPromptPrefixRoutingEnabled holds the string denoting the prompt_prefix_routing_enabled field in the database.
+	FieldPromptPrefixRoutingEnabled = "prompt_prefix_routing_enabled"
+	// FieldPromptPrefixMessages holds the string denoting the prompt_prefix_messages field in the database.
+	FieldPromptPrefixMessages = "prompt_prefix_messages"
+	// FieldStreamResumeEnabled holds the string denoting the stream_resume_enabled field in the database.
+	FieldStreamResumeEnabled = "stream_resume_enabled"
+	// FieldContextCompactionMode holds the string denoting the context_compaction_mode field in the database.
+	FieldContextCompactionMode = "context_compaction_mode"
+	// FieldContextCompactionModel holds the string denoting the context_compaction_model field in the database.
+	FieldContextCompactionModel = "context_compaction_model"
 	// EdgeAPIKeys holds the string denoting the api_keys edge name in mutations.
 	EdgeAPIKeys = "api_keys"
 	// EdgeRedeemCodes holds the string denoting the redeem_codes edge name in mutations.
@@ -254,6 +268,8 @@ var Columns = []string{
 	FieldAudioRealtimePricePerMin,
 	FieldAudioTtsPricePerMillionChars,
 	FieldAudioSttPricePerHour,
+	FieldModerationPricePerCall,
+	FieldModerationPricePerMillionTokens,
 	FieldLongContextPricingEnabled,
 	FieldModelPricing,
 	FieldClaudeCodeOnly,
@@ -277,6 +293,11 @@ var Columns = []string{
 	FieldProfitControlEnabled,
 	FieldProfitMinMargin,
 	FieldProfitSafetyBuffer,
+	FieldPromptPrefixRoutingEnabled,
+	FieldPromptPrefixMessages,
+	FieldStreamResumeEnabled,
+	FieldContextCompactionMode,
+	FieldContextCompactionModel,
 }
 
 var (
@@ -370,6 +391,10 @@ var (
 	AudioTtsPricePerMillionCharsValidator func(float64) error
 	// AudioSttPricePerHourValidator is a validator for the "audio_stt_price_per_hour" field. It is called by the builders before save.
 	AudioSttPricePerHourValidator func(float64) error
+	// ModerationPricePerCallValidator is a validator for the "moderation_price_per_call" field. It is called by the builders before save.
+	ModerationPricePerCallValidator func(float64) error
+	// ModerationPricePerMillionTokensValidator is a validator for the "moderation_price_per_million_tokens" field. It is called by the builders before save.
+	ModerationPricePerMillionTokensValidator func(float64) error
 	// DefaultLongContextPricingEnabled holds the default value on creation for the "long_context_pricing_enabled" field.
 	DefaultLongContextPricingEnabled bool
 	// DefaultClaudeCodeOnly holds the default value on creation for the "claude_code_only" field.
@@ -412,6 +437,20 @@ var (
 	DefaultProfitMinMargin float64
 	// DefaultProfitSafetyBuffer holds the default value on creation for the "profit_safety_buffer" field.
 	DefaultProfitSafetyBuffer float64
+	// DefaultPromptPrefixRoutingEnabled holds the default value on creation for the "prompt_prefix_routing_enabled" field.
+	DefaultPromptPrefixRoutingEnabled bool
+	// DefaultPromptPrefixMessages holds the default value on creation for the "prompt_prefix_messages" field.
+	DefaultPromptPrefixMessages int
+	// DefaultStreamResumeEnabled holds the default value on creation for the "stream_resume_enabled" field.
+	DefaultStreamResumeEnabled bool
+	// DefaultContextCompactionMode holds the default value on creation for the "context_compaction_mode" field.
+	DefaultContextCompactionMode string
+	// ContextCompactionModeValidator is a validator for the "context_compaction_mode" field. It is called by the builders before save.
+	ContextCompactionModeValidator func(string) error
+	// DefaultContextCompactionModel holds the default value on creation for the "context_compaction_model" field.
+	DefaultContextCompactionModel string
+	// ContextCompactionModelValidator is a validator for the "context_compaction_model" field. It is called by the builders before save.
+	ContextCompactionModelValidator func(string) error
 )
 
 // OrderOption defines the ordering options for the Group queries.
@@ -612,6 +651,16 @@ func ByAudioSttPricePerHour(opts ...sql.OrderTermOption) OrderOption {
 	return sql.OrderByField(FieldAudioSttPricePerHour, opts...).ToFunc()
 }
 
+// ByModerationPricePerCall orders the results by the moderation_price_per_call field.
+func ByModerationPricePerCall(opts ...sql.OrderTermOption) OrderOption {
+	return sql.OrderByField(FieldModerationPricePerCall, opts...).ToFunc()
+}
+
+// ByModerationPricePerMillionTokens orders the results by the moderation_price_per_million_tokens field.
+func ByModerationPricePerMillionTokens(opts ...sql.OrderTermOption) OrderOption {
+	return sql.OrderByField(FieldModerationPricePerMillionTokens, opts...).ToFunc()
+}
+
 // ByLongContextPricingEnabled orders the results by the long_context_pricing_enabled field.
 func ByLongContextPricingEnabled(opts ...sql.OrderTermOption) OrderOption {
 	return sql.OrderByField(FieldLongContextPricingEnabled, opts...).ToFunc()
@@ -697,6 +746,31 @@ func ByProfitSafetyBuffer(opts ...sql.OrderTermOption) OrderOption {
 	return sql.OrderByField(FieldProfitSafetyBuffer, opts...).ToFunc()
 }
 
+// ByPromptPrefixRoutingEnabled orders the results by the prompt_prefix_routing_enabled field.
+func ByPromptPrefixRoutingEnabled(opts ...sql.OrderTermOption) OrderOption {
+	return sql.OrderByField(FieldPromptPrefixRoutingEnabled, opts...).ToFunc()
+}
+
+// ByPromptPrefixMessages orders the results by the prompt_prefix_messages field.
+func ByPromptPrefixMessages(opts ...sql.OrderTermOption) OrderOption {
+	return sql.OrderByField(FieldPromptPrefixMessages, opts...).ToFunc()
+}
+
+// ByStreamResumeEnabled orders the results by the stream_resume_enabled field.
+func ByStreamResumeEnabled(opts ...sql.OrderTermOption) OrderOption {
+	return sql.OrderByField(FieldStreamResumeEnabled, opts...).ToFunc()
+}
+
+// ByContextCompactionMode orders the results by the context_compaction_mode field.
+func ByContextCompactionMode(opts ...sql.OrderTermOption) OrderOption {
+	return sql.OrderByField(FieldContextCompactionMode, opts...).ToFunc()
+}
+
+// ByContextCompactionModel orders the results by the context_compaction_model field.
+func ByContextCompactionModel(opts ...sql.OrderTermOption) OrderOption {
+	return sql.OrderByField(FieldContextCompactionModel, opts...).ToFunc()
+}
+
 // ByAPIKeysCount orders the results by api_keys count.
 func ByAPIKeysCount(opts ...sql.OrderTermOption) OrderOption {
 	return func(s *sql.Selector) {
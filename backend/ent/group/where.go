@@ -245,6 +245,16 @@ func AudioSttPricePerHour(v float64) predicate.Group {
 	return predicate.Group(sql.FieldEQ(FieldAudioSttPricePerHour, v))
 }
 
+// ModerationPricePerCall applies equality check predicate on the "moderation_price_per_call" field. It's identical to ModerationPricePerCallEQ.
+func ModerationPricePerCall(v float64) predicate.Group {
+	return predicate.Group(sql.FieldEQ(FieldModerationPricePerCall, v))
+}
+
+// ModerationPricePerMillionTokens applies equality check predicate on the "moderation_price_per_million_tokens" field. It's identical to ModerationPricePerMillionTokensEQ.
+func ModerationPricePerMillionTokens(v float64) predicate.Group {
+	return predicate.Group(sql.FieldEQ(FieldModerationPricePerMillionTokens, v))
+}
+
 // LongContextPricingEnabled applies equality check predicate on the "long_context_pricing_enabled" field. It's identical to LongContextPricingEnabledEQ.
 func LongContextPricingEnabled(v bool) predicate.Group {
 	return predicate.Group(sql.FieldEQ(FieldLongContextPricingEnabled, v))
@@ -330,6 +340,31 @@ func ProfitSafetyBuffer(v float64) predicate.Group {
 	return predicate.Group(sql.FieldEQ(FieldProfitSafetyBuffer, v))
 }
 
+// PromptPrefixRoutingEnabled applies equality check predicate on the "prompt_prefix_routing_enabled" field. It's identical to PromptPrefixRoutingEnabledEQ.
+func PromptPrefixRoutingEnabled(v bool) predicate.Group {
+	return predicate.Group(sql.FieldEQ(FieldPromptPrefixRoutingEnabled, v))
+}
+
+// PromptPrefixMessages applies equality check predicate on the "prompt_prefix_messages" field. It's identical to PromptPrefixMessagesEQ.
+func PromptPrefixMessages(v int) predicate.Group {
+	return predicate.Group(sql.FieldEQ(FieldPromptPrefixMessages, v))
+}
+
+// StreamResumeEnabled applies equality check predicate on the "stream_resume_enabled" field. It's identical to StreamResumeEnabledEQ.
+func StreamResumeEnabled(v bool) predicate.Group {
+	return predicate.Group(sql.FieldEQ(FieldStreamResumeEnabled, v))
+}
+
+// ContextCompactionMode applies equality check predicate on the "context_compaction_mode" field. It's identical to ContextCompactionModeEQ.
+func ContextCompactionMode(v string) predicate.Group {
+	return predicate.Group(sql.FieldEQ(FieldContextCompactionMode, v))
+}
+
+// ContextCompactionModel applies equality check predicate on the "context_compaction_model" field. It's identical to ContextCompactionModelEQ.
+func ContextCompactionModel(v string) predicate.Group {
+	return predicate.Group(sql.FieldEQ(FieldContextCompactionModel, v))
+}
+
 // CreatedAtEQ applies the EQ predicate on the "created_at" field.
 func CreatedAtEQ(v time.Time) predicate.Group {
 	return predicate.Group(sql.FieldEQ(FieldCreatedAt, v))
@@ -2050,6 +2085,106 @@ func AudioSttPricePerHourNotNil() predicate.Group {
 	return predicate.Group(sql.FieldNotNull(FieldAudioSttPricePerHour))
 }
 
+// ModerationPricePerCallEQ applies the EQ predicate on the "moderation_price_per_call" field.
+func ModerationPricePerCallEQ(v float64) predicate.Group {
+	return predicate.Group(sql.FieldEQ(FieldModerationPricePerCall, v))
+}
+
+// ModerationPricePerCallNEQ applies the NEQ predicate on the "moderation_price_per_call" field.
+func ModerationPricePerCallNEQ(v float64) predicate.Group {
+	return predicate.Group(sql.FieldNEQ(FieldModerationPricePerCall, v))
+}
+
+// ModerationPricePerCallIn applies the In predicate on the "moderation_price_per_call" field.
+func ModerationPricePerCallIn(vs ...float64) predicate.Group {
+	return predicate.Group(sql.FieldIn(FieldModerationPricePerCall, vs...))
+}
+
+// ModerationPricePerCallNotIn applies the NotIn predicate on the "moderation_price_per_call" field.
+func ModerationPricePerCallNotIn(vs ...float64) predicate.Group {
+	return predicate.Group(sql.FieldNotIn(FieldModerationPricePerCall, vs...))
+}
+
+// ModerationPricePerCallGT applies the GT predicate on the "moderation_price_per_call" field.
+func ModerationPricePerCallGT(v float64) predicate.Group {
+	return predicate.Group(sql.FieldGT(FieldModerationPricePerCall, v))
+}
+
+// ModerationPricePerCallGTE applies the GTE predicate on the "moderation_price_per_call" field.
+func ModerationPricePerCallGTE(v float64) predicate.Group {
+	return predicate.Group(sql.FieldGTE(FieldModerationPricePerCall, v))
+}
+
+// ModerationPricePerCallLT applies the LT predicate on the "moderation_price_per_call" field.
+func ModerationPricePerCallLT(v float64) predicate.Group {
+	return predicate.Group(sql.FieldLT(FieldModerationPricePerCall, v))
+}
+
+// ModerationPricePerCallLTE applies the LTE predicate on the "moderation_price_per_call" field.
+func ModerationPricePerCallLTE(v float64) predicate.Group {
+	return predicate.Group(sql.FieldLTE(FieldModerationPricePerCall, v))
+}
+
+// ModerationPricePerCallIsNil applies the IsNil predicate on the "moderation_price_per_call" field.
+func ModerationPricePerCallIsNil() predicate.Group {
+	return predicate.Group(sql.FieldIsNull(FieldModerationPricePerCall))
+}
+
+// ModerationPricePerCallNotNil applies the NotNil predicate on the "moderation_price_per_call" field.
+func ModerationPricePerCallNotNil() predicate.Group {
+	return predicate.Group(sql.FieldNotNull(FieldModerationPricePerCall))
+}
+
+// ModerationPricePerMillionTokensEQ applies the EQ predicate on the "moderation_price_per_million_tokens" field.
+func ModerationPricePerMillionTokensEQ(v float64) predicate.Group {
+	return predicate.Group(sql.FieldEQ(FieldModerationPricePerMillionTokens, v))
+}
+
+// ModerationPricePerMillionTokensNEQ applies the NEQ predicate on the "moderation_price_per_million_tokens" field.
+func ModerationPricePerMillionTokensNEQ(v float64) predicate.Group {
+	return predicate.Group(sql.FieldNEQ(FieldModerationPricePerMillionTokens, v))
+}
+
+// ModerationPricePerMillionTokensIn applies the In predicate on the "moderation_price_per_million_tokens" field.
+func ModerationPricePerMillionTokensIn(vs ...float64) predicate.Group {
+	return predicate.Group(sql.FieldIn(FieldModerationPricePerMillionTokens, vs...))
+}
+
+// ModerationPricePerMillionTokensNotIn applies the NotIn predicate on the "moderation_price_per_million_tokens" field.
+func ModerationPricePerMillionTokensNotIn(vs ...float64) predicate.Group {
+	return predicate.Group(sql.FieldNotIn(FieldModerationPricePerMillionTokens, vs...))
+}
+
+// ModerationPricePerMillionTokensGT applies the GT predicate on the "moderation_price_per_million_tokens" field.
+func ModerationPricePerMillionTokensGT(v float64) predicate.Group {
+	return predicate.Group(sql.FieldGT(FieldModerationPricePerMillionTokens, v))
+}
+
+// ModerationPricePerMillionTokensGTE applies the GTE predicate on the "moderation_price_per_million_tokens" field.
+func ModerationPricePerMillionTokensGTE(v float64) predicate.Group {
+	return predicate.Group(sql.FieldGTE(FieldModerationPricePerMillionTokens, v))
+}
+
+// ModerationPricePerMillionTokensLT applies the LT predicate on the "moderation_price_per_million_tokens" field.
+func ModerationPricePerMillionTokensLT(v float64) predicate.Group {
+	return predicate.Group(sql.FieldLT(FieldModerationPricePerMillionTokens, v))
+}
+
+// ModerationPricePerMillionTokensLTE applies the LTE predicate on the "moderation_price_per_million_tokens" field.
+func ModerationPricePerMillionTokensLTE(v float64) predicate.Group {
+	return predicate.Group(sql.FieldLTE(FieldModerationPricePerMillionTokens, v))
+}
+
+// ModerationPricePerMillionTokensIsNil applies the IsNil predicate on the "moderation_price_per_million_tokens" field.
+func ModerationPricePerMillionTokensIsNil() predicate.Group {
+	return predicate.Group(sql.FieldIsNull(FieldModerationPricePerMillionTokens))
+}
+
+// ModerationPricePerMillionTokensNotNil applies the NotNil predicate on the "moderation_price_per_million_tokens" field.
+func ModerationPricePerMillionTokensNotNil() predicate.Group {
+	return predicate.Group(sql.FieldNotNull(FieldModerationPricePerMillionTokens))
+}
+
 // LongContextPricingEnabledEQ applies the EQ predicate on the "long_context_pricing_enabled" field.
 func LongContextPricingEnabledEQ(v bool) predicate.Group {
 	return predicate.Group(sql.FieldEQ(FieldLongContextPricingEnabled, v))
@@ -2550,6 +2685,196 @@ func ProfitSafetyBufferLTE(v float64) predicate.Group {
 	return predicate.Group(sql.FieldLTE(FieldProfitSafetyBuffer, v))
 }
 
+// PromptPrefixRoutingEnabledEQ applies the EQ predicate on the "prompt_prefix_routing_enabled" field.
+func PromptPrefixRoutingEnabledEQ(v bool) predicate.Group {
+	return predicate.Group(sql.FieldEQ(FieldPromptPrefixRoutingEnabled, v))
+}
+
+// PromptPrefixRoutingEnabledNEQ applies the NEQ predicate on the "prompt_prefix_routing_enabled" field.
+func PromptPrefixRoutingEnabledNEQ(v bool) predicate.Group {
+	return predicate.Group(sql.FieldNEQ(FieldPromptPrefixRoutingEnabled, v))
+}
+
+// PromptPrefixMessagesEQ applies the EQ predicate on the "prompt_prefix_messages" field.
+func PromptPrefixMessagesEQ(v int) predicate.Group {
+	return predicate.Group(sql.FieldEQ(FieldPromptPrefixMessages, v))
+}
+
+// PromptPrefixMessagesNEQ applies the NEQ predicate on the "prompt_prefix_messages" field.
+func PromptPrefixMessagesNEQ(v int) predicate.Group {
+	return predicate.Group(sql.FieldNEQ(FieldPromptPrefixMessages, v))
+}
+
+// PromptPrefixMessagesIn applies the In predicate on the "prompt_prefix_messages" field.
+func PromptPrefixMessagesIn(vs ...int) predicate.Group {
+	return predicate.Group(sql.FieldIn(FieldPromptPrefixMessages, vs...))
+}
+
+// PromptPrefixMessagesNotIn applies the NotIn predicate on the "prompt_prefix_messages" field.
+func PromptPrefixMessagesNotIn(vs ...int) predicate.Group {
+	return predicate.Group(sql.FieldNotIn(FieldPromptPrefixMessages, vs...))
+}
+
+// PromptPrefixMessagesGT applies the GT predicate on the "prompt_prefix_messages" field.
+func PromptPrefixMessagesGT(v int) predicate.Group {
+	return predicate.Group(sql.FieldGT(FieldPromptPrefixMessages, v))
+}
+
+// PromptPrefixMessagesGTE applies the GTE predicate on the "prompt_prefix_messages" field.
+func PromptPrefixMessagesGTE(v int) predicate.Group {
+	return predicate.Group(sql.FieldGTE(FieldPromptPrefixMessages, v))
+}
+
+// PromptPrefixMessagesLT applies the LT predicate on the "prompt_prefix_messages" field.
+func PromptPrefixMessagesLT(v int) predicate.Group {
+	return predicate.Group(sql.FieldLT(FieldPromptPrefixMessages, v))
+}
+
+// PromptPrefixMessagesLTE applies the LTE predicate on the "prompt_prefix_messages" field.
+func PromptPrefixMessagesLTE(v int) predicate.Group {
+	return predicate.Group(sql.FieldLTE(FieldPromptPrefixMessages, v))
+}
+
+// StreamResumeEnabledEQ applies the EQ predicate on the "stream_resume_enabled" field.
+func StreamResumeEnabledEQ(v bool) predicate.Group {
+	return predicate.Group(sql.FieldEQ(FieldStreamResumeEnabled, v))
+}
+
+// StreamResumeEnabledNEQ applies the NEQ predicate on the "stream_resume_enabled" field.
+func StreamResumeEnabledNEQ(v bool) predicate.Group {
+	return predicate.Group(sql.FieldNEQ(FieldStreamResumeEnabled, v))
+}
+
+// ContextCompactionModeEQ applies the EQ predicate on the "context_compaction_mode" field.
+func ContextCompactionModeEQ(v string) predicate.Group {
+	return predicate.Group(sql.FieldEQ(FieldContextCompactionMode, v))
+}
+
+// ContextCompactionModeNEQ applies the NEQ predicate on the "context_compaction_mode" field.
+func ContextCompactionModeNEQ(v string) predicate.Group {
+	return predicate.Group(sql.FieldNEQ(FieldContextCompactionMode, v))
+}
+
+// ContextCompactionModeIn applies the In predicate on the "context_compaction_mode" field.
+func ContextCompactionModeIn(vs ...string) predicate.Group {
+	return predicate.Group(sql.FieldIn(FieldContextCompactionMode, vs...))
+}
+
+// ContextCompactionModeNotIn applies the NotIn predicate on the "context_compaction_mode" field.
+func ContextCompactionModeNotIn(vs ...string) predicate.Group {
+	return predicate.Group(sql.FieldNotIn(FieldContextCompactionMode, vs...))
+}
+
+// ContextCompactionModeGT applies the GT predicate on the "context_compaction_mode" field.
+func ContextCompactionModeGT(v string) predicate.Group {
+	return predicate.Group(sql.FieldGT(FieldContextCompactionMode, v))
+}
+
+// ContextCompactionModeGTE applies the GTE predicate on the "context_compaction_mode" field.
+func ContextCompactionModeGTE(v string) predicate.Group {
+	return predicate.Group(sql.FieldGTE(FieldContextCompactionMode, v))
+}
+
+// ContextCompactionModeLT applies the LT predicate on the "context_compaction_mode" field.
+func ContextCompactionModeLT(v string) predicate.Group {
+	return predicate.Group(sql.FieldLT(FieldContextCompactionMode, v))
+}
+
+// ContextCompactionModeLTE applies the LTE predicate on the "context_compaction_mode" field.
+func ContextCompactionModeLTE(v string) predicate.Group {
+	return predicate.Group(sql.FieldLTE(FieldContextCompactionMode, v))
+}
+
+// ContextCompactionModeContains applies the Contains predicate on the "context_compaction_mode" field.
+func ContextCompactionModeContains(v string) predicate.Group {
+	return predicate.Group(sql.FieldContains(FieldContextCompactionMode, v))
+}
+
+// ContextCompactionModeHasPrefix applies the HasPrefix predicate on the "context_compaction_mode" field.
+func ContextCompactionModeHasPrefix(v string) predicate.Group {
+	return predicate.Group(sql.FieldHasPrefix(FieldContextCompactionMode, v))
+}
+
+// ContextCompactionModeHasSuffix applies the HasSuffix predicate on the "context_compaction_mode" field.
+func ContextCompactionModeHasSuffix(v string) predicate.Group {
+	return predicate.Group(sql.FieldHasSuffix(FieldContextCompactionMode, v))
+}
+
+// ContextCompactionModeEqualFold applies the EqualFold predicate on the "context_compaction_mode" field.
+func ContextCompactionModeEqualFold(v string) predicate.Group {
+	return predicate.Group(sql.FieldEqualFold(FieldContextCompactionMode, v))
+}
+
+// ContextCompactionModeContainsFold applies the ContainsFold predicate on the "context_compaction_mode" field.
+func ContextCompactionModeContainsFold(v string) predicate.Group {
+	return predicate.Group(sql.FieldContainsFold(FieldContextCompactionMode, v))
+}
+
+// ContextCompactionModelEQ applies the EQ predicate on the "context_compaction_model" field.
+func ContextCompactionModelEQ(v string) predicate.Group {
+	return predicate.Group(sql.FieldEQ(FieldContextCompactionModel, v))
+}
+
+// ContextCompactionModelNEQ applies the NEQ predicate on the "context_compaction_model" field.
+func ContextCompactionModelNEQ(v string) predicate.Group {
+	return predicate.Group(sql.FieldNEQ(FieldContextCompactionModel, v))
+}
+
+// ContextCompactionModelIn applies the In predicate on the "context_compaction_model" field.
+func ContextCompactionModelIn(vs ...string) predicate.Group {
+	return predicate.Group(sql.FieldIn(FieldContextCompactionModel, vs...))
+}
+
+// ContextCompactionModelNotIn applies the NotIn predicate on the "context_compaction_model" field.
+func ContextCompactionModelNotIn(vs ...string) predicate.Group {
+	return predicate.Group(sql.FieldNotIn(FieldContextCompactionModel, vs...))
+}
+
+// ContextCompactionModelGT applies the GT predicate on the "context_compaction_model" field.
+func ContextCompactionModelGT(v string) predicate.Group {
+	return predicate.Group(sql.FieldGT(FieldContextCompactionModel, v))
+}
+
+// ContextCompactionModelGTE applies the GTE predicate on the "context_compaction_model" field.
+func ContextCompactionModelGTE(v string) predicate.Group {
+	return predicate.Group(sql.FieldGTE(FieldContextCompactionModel, v))
+}
+
+// ContextCompactionModelLT applies the LT predicate on the "context_compaction_model" field.
+func ContextCompactionModelLT(v string) predicate.Group {
+	return predicate.Group(sql.FieldLT(FieldContextCompactionModel, v))
+}
+
+// ContextCompactionModelLTE applies the LTE predicate on the "context_compaction_model" field.
+func ContextCompactionModelLTE(v string) predicate.Group {
+	return predicate.Group(sql.FieldLTE(FieldContextCompactionModel, v))
+}
+
+// ContextCompactionModelContains applies the Contains predicate on the "context_compaction_model" field.
+func ContextCompactionModelContains(v string) predicate.Group {
+	return predicate.Group(sql.FieldContains(FieldContextCompactionModel, v))
+}
+
+// ContextCompactionModelHasPrefix applies the HasPrefix predicate on the "context_compaction_model" field.
+func ContextCompactionModelHasPrefix(v string) predicate.Group {
+	return predicate.Group(sql.FieldHasPrefix(FieldContextCompactionModel, v))
+}
+
+// ContextCompactionModelHasSuffix applies the HasSuffix predicate on the "context_compaction_model" field.
+func ContextCompactionModelHasSuffix(v string) predicate.Group {
+	return predicate.Group(sql.FieldHasSuffix(FieldContextCompactionModel, v))
+}
+
+// ContextCompactionModelEqualFold applies the EqualFold predicate on the "context_compaction_model" field.
+func ContextCompactionModelEqualFold(v string) predicate.Group {
+	return predicate.Group(sql.FieldEqualFold(FieldContextCompactionModel, v))
+}
+
+// ContextCompactionModelContainsFold applies the ContainsFold predicate on the "context_compaction_model" field.
+func ContextCompactionModelContainsFold(v string) predicate.Group {
+	return predicate.Group(sql.FieldContainsFold(FieldContextCompactionModel, v))
+}
+
 // HasAPIKeys applies the HasEdge predicate on the "api_keys" edge.
 func HasAPIKeys() predicate.Group {
 	return predicate.Group(func(s *sql.Selector) {
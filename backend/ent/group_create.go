@@ -560,6 +560,34 @@ func (_c *GroupCreate) SetNillableAudioSttPricePerHour(v *float64) *GroupCreate
 	return _c
 }
 
+// SetModerationPricePerCall sets the "moderation_price_per_call" field.
+func (_c *GroupCreate) SetModerationPricePerCall(v float64) *GroupCreate {
+	_c.mutation.SetModerationPricePerCall(v)
+	return _c
+}
+
+// SetNillableModerationPricePerCall sets the "moderation_price_per_call" field if the given value is not nil.
+func (_c *GroupCreate) SetNillableModerationPricePerCall(v *float64) *GroupCreate {
+	if v != nil {
+		_c.SetModerationPricePerCall(*v)
+	}
+	return _c
+}
+
+// SetModerationPricePerMillionTokens sets the "moderation_price_per_million_tokens" field.
+func (_c *GroupCreate) SetModerationPricePerMillionTokens(v float64) *GroupCreate {
+	_c.mutation.SetModerationPricePerMillionTokens(v)
+	return _c
+}
+
+// SetNillableModerationPricePerMillionTokens sets the "moderation_price_per_million_tokens" field if the given value is not nil.
+func (_c *GroupCreate) SetNillableModerationPricePerMillionTokens(v *float64) *GroupCreate {
+	if v != nil {
+		_c.SetModerationPricePerMillionTokens(*v)
+	}
+	return _c
+}
+
 // SetLongContextPricingEnabled sets the "long_context_pricing_enabled" field.
 func (_c *GroupCreate) SetLongContextPricingEnabled(v bool) *GroupCreate {
 	_c.mutation.SetLongContextPricingEnabled(v)
@@ -850,6 +878,76 @@ func (_c *GroupCreate) SetNillableProfitSafetyBuffer(v *float64) *GroupCreate {
 	return _c
 }
 
+// SetPromptPrefixRoutingEnabled sets the "prompt_prefix_routing_enabled" field.
+func (_c *GroupCreate) SetPromptPrefixRoutingEnabled(v bool) *GroupCreate {
+	_c.mutation.SetPromptPrefixRoutingEnabled(v)
+	return _c
+}
+
+// SetNillablePromptPrefixRoutingEnabled sets the "prompt_prefix_routing_enabled" field if the given value is not nil.
+func (_c *GroupCreate) SetNillablePromptPrefixRoutingEnabled(v *bool) *GroupCreate {
+	if v != nil {
+		_c.SetPromptPrefixRoutingEnabled(*v)
+	}
+	return _c
+}
+
+// SetPromptPrefixMessages sets the "prompt_prefix_messages" field.
+func (_c *GroupCreate) SetPromptPrefixMessages(v int) *GroupCreate {
+	_c.mutation.SetPromptPrefixMessages(v)
+	return _c
+}
+
+// SetNillablePromptPrefixMessages sets the "prompt_prefix_messages" field if the given value is not nil.
+func (_c *GroupCreate) SetNillablePromptPrefixMessages(v *int) *GroupCreate {
+	if v != nil {
+		_c.SetPromptPrefixMessages(*v)
+	}
+	return _c
+}
+
+// SetStreamResumeEnabled sets the "stream_resume_enabled" field.
+func (_c *GroupCreate) SetStreamResumeEnabled(v bool) *GroupCreate {
+	_c.mutation.SetStreamResumeEnabled(v)
+	return _c
+}
+
+// SetNillableStreamResumeEnabled sets the "stream_resume_enabled" field if the given value is not nil.
+func (_c *GroupCreate) SetNillableStreamResumeEnabled(v *bool) *GroupCreate {
+	if v != nil {
+		_c.SetStreamResumeEnabled(*v)
+	}
+	return _c
+}
+
+// SetContextCompactionMode sets the "context_compaction_mode" field.
+func (_c *GroupCreate) SetContextCompactionMode(v string) *GroupCreate {
+	_c.mutation.SetContextCompactionMode(v)
+	return _c
+}
+
+// SetNillableContextCompactionMode sets the "context_compaction_mode" field if the given value is not nil.
+func (_c *GroupCreate) SetNillableContextCompactionMode(v *string) *GroupCreate {
+	if v != nil {
+		_c.SetContextCompactionMode(*v)
+	}
+	return _c
+}
+
+// SetContextCompactionModel sets the "context_compaction_model" field.
+func (_c *GroupCreate) SetContextCompactionModel(v string) *GroupCreate {
+	_c.mutation.SetContextCompactionModel(v)
+	return _c
+}
+
+// SetNillableContextCompactionModel sets the "context_compaction_model" field if the given value is not nil.
+func (_c *GroupCreate) SetNillableContextCompactionModel(v *string) *GroupCreate {
+	if v != nil {
+		_c.SetContextCompactionModel(*v)
+	}
+	return _c
+}
+
 // AddAPIKeyIDs adds the "api_keys" edge to the APIKey entity by IDs.
 func (_c *GroupCreate) AddAPIKeyIDs(ids ...int64) *GroupCreate {
 	_c.mutation.AddAPIKeyIDs(ids...)
@@ -1139,6 +1237,26 @@ func (_c *GroupCreate) defaults() error {
 		v := group.DefaultProfitSafetyBuffer
 		_c.mutation.SetProfitSafetyBuffer(v)
 	}
+	if _, ok := _c.mutation.PromptPrefixRoutingEnabled(); !ok {
+		v := group.DefaultPromptPrefixRoutingEnabled
+		_c.mutation.SetPromptPrefixRoutingEnabled(v)
+	}
+	if _, ok := _c.mutation.PromptPrefixMessages(); !ok {
+		v := group.DefaultPromptPrefixMessages
+		_c.mutation.SetPromptPrefixMessages(v)
+	}
+	if _, ok := _c.mutation.StreamResumeEnabled(); !ok {
+		v := group.DefaultStreamResumeEnabled
+		_c.mutation.SetStreamResumeEnabled(v)
+	}
+	if _, ok := _c.mutation.ContextCompactionMode(); !ok {
+		v := group.DefaultContextCompactionMode
+		_c.mutation.SetContextCompactionMode(v)
+	}
+	if _, ok := _c.mutation.ContextCompactionModel(); !ok {
+		v := group.DefaultContextCompactionModel
+		_c.mutation.SetContextCompactionModel(v)
+	}
 	return nil
 }
 
@@ -1262,6 +1380,16 @@ func (_c *GroupCreate) check() error {
 			return &ValidationError{Name: "audio_stt_price_per_hour", err: fmt.Errorf(`ent: validator failed for field "Group.audio_stt_price_per_hour": %w`, err)}
 		}
 	}
+	if v, ok := _c.mutation.ModerationPricePerCall(); ok {
+		if err := group.ModerationPricePerCallValidator(v); err != nil {
+			return &ValidationError{Name: "moderation_price_per_call", err: fmt.Errorf(`ent: validator failed for field "Group.moderation_price_per_call": %w`, err)}
+		}
+	}
+	if v, ok := _c.mutation.ModerationPricePerMillionTokens(); ok {
+		if err := group.ModerationPricePerMillionTokensValidator(v); err != nil {
+			return &ValidationError{Name: "moderation_price_per_million_tokens", err: fmt.Errorf(`ent: validator failed for field "Group.moderation_price_per_million_tokens": %w`, err)}
+		}
+	}
 	if _, ok := _c.mutation.LongContextPricingEnabled(); !ok {
 		return &ValidationError{Name: "long_context_pricing_enabled", err: errors.New(`ent: missing required field "Group.long_context_pricing_enabled"`)}
 	}
@@ -1329,6 +1457,31 @@ func (_c *GroupCreate) check() error {
 	if _, ok := _c.mutation.ProfitSafetyBuffer(); !ok {
 		return &ValidationError{Name: "profit_safety_buffer", err: errors.New(`ent: missing required field "Group.profit_safety_buffer"`)}
 	}
+	if _, ok := _c.mutation.PromptPrefixRoutingEnabled(); !ok {
+		return &ValidationError{Name: "prompt_prefix_routing_enabled", err: errors.New(`ent: missing required field "Group.prompt_prefix_routing_enabled"`)}
+	}
+	if _, ok := _c.mutation.PromptPrefixMessages(); !ok {
+		return &ValidationError{Name: "prompt_prefix_messages", err: errors.New(`ent: missing required field "Group.prompt_prefix_messages"`)}
+	}
+	if _, ok := _c.mutation.StreamResumeEnabled(); !ok {
+		return &ValidationError{Name: "stream_resume_enabled", err: errors.New(`ent: missing required field "Group.stream_resume_enabled"`)}
+	}
+	if _, ok := _c.mutation.ContextCompactionMode(); !ok {
+		return &ValidationError{Name: "context_compaction_mode", err: errors.New(`ent: missing required field "Group.context_compaction_mode"`)}
+	}
+	if v, ok := _c.mutation.ContextCompactionMode(); ok {
+		if err := group.ContextCompactionModeValidator(v); err != nil {
+			return &ValidationError{Name: "context_compaction_mode", err: fmt.Errorf(`ent: validator failed for field "Group.context_compaction_mode": %w`, err)}
+		}
+	}
+	if _, ok := _c.mutation.ContextCompactionModel(); !ok {
+		return &ValidationError{Name: "context_compaction_model", err: errors.New(`ent: missing required field "Group.context_compaction_model"`)}
+	}
+	if v, ok := _c.mutation.ContextCompactionModel(); ok {
+		if err := group.ContextCompactionModelValidator(v); err != nil {
+			return &ValidationError{Name: "context_compaction_model", err: fmt.Errorf(`ent: validator failed for field "Group.context_compaction_model": %w`, err)}
+		}
+	}
 	return nil
 }
 
@@ -1512,6 +1665,14 @@ func (_c *GroupCreate) createSpec() (*Group, *sqlgraph.CreateSpec) {
 		_spec.SetField(group.FieldAudioSttPricePerHour, field.TypeFloat64, value)
 		_node.AudioSttPricePerHour = &value
 	}
+	if value, ok := _c.mutation.ModerationPricePerCall(); ok {
+		_spec.SetField(group.FieldModerationPricePerCall, field.TypeFloat64, value)
+		_node.ModerationPricePerCall = &value
+	}
+	if value, ok := _c.mutation.ModerationPricePerMillionTokens(); ok {
+		_spec.SetField(group.FieldModerationPricePerMillionTokens, field.TypeFloat64, value)
+		_node.ModerationPricePerMillionTokens = &value
+	}
 	if value, ok := _c.mutation.LongContextPricingEnabled(); ok {
 		_spec.SetField(group.FieldLongContextPricingEnabled, field.TypeBool, value)
 		_node.LongContextPricingEnabled = value
@@ -1604,6 +1765,26 @@ func (_c *GroupCreate) createSpec() (*Group, *sqlgraph.CreateSpec) {
 		_spec.SetField(group.FieldProfitSafetyBuffer, field.TypeFloat64, value)
 		_node.ProfitSafetyBuffer = value
 	}
+	if value, ok := _c.mutation.PromptPrefixRoutingEnabled(); ok {
+		_spec.SetField(group.FieldPromptPrefixRoutingEnabled, field.TypeBool, value)
+		_node.PromptPrefixRoutingEnabled = value
+	}
+	if value, ok := _c.mutation.PromptPrefixMessages(); ok {
+		_spec.SetField(group.FieldPromptPrefixMessages, field.TypeInt, value)
+		_node.PromptPrefixMessages = value
+	}
+	if value, ok := _c.mutation.StreamResumeEnabled(); ok {
+		_spec.SetField(group.FieldStreamResumeEnabled, field.TypeBool, value)
+		_node.StreamResumeEnabled = value
+	}
+	if value, ok := _c.mutation.ContextCompactionMode(); ok {
+		_spec.SetField(group.FieldContextCompactionMode, field.TypeString, value)
+		_node.ContextCompactionMode = value
+	}
+	if value, ok := _c.mutation.ContextCompactionModel(); ok {
+		_spec.SetField(group.FieldContextCompactionModel, field.TypeString, value)
+		_node.ContextCompactionModel = value
+	}
 	if nodes := _c.mutation.APIKeysIDs(); len(nodes) > 0 {
 		edge := &sqlgraph.EdgeSpec{
 			Rel:     sqlgraph.O2M,
@@ -2432,6 +2613,54 @@ func (u *GroupUpsert) ClearAudioSttPricePerHour() *GroupUpsert {
 	return u
 }
 
+// SetModerationPricePerCall sets the "moderation_price_per_call" field.
+func (u *GroupUpsert) SetModerationPricePerCall(v float64) *GroupUpsert {
+	u.Set(group.FieldModerationPricePerCall, v)
+	return u
+}
+
+// UpdateModerationPricePerCall sets the "moderation_price_per_call" field to the value that was provided on create.
+func (u *GroupUpsert) UpdateModerationPricePerCall() *GroupUpsert {
+	u.SetExcluded(group.FieldModerationPricePerCall)
+	return u
+}
+
+// AddModerationPricePerCall adds v to the "moderation_price_per_call" field.
+func (u *GroupUpsert) AddModerationPricePerCall(v float64) *GroupUpsert {
+	u.Add(group.FieldModerationPricePerCall, v)
+	return u
+}
+
+// ClearModerationPricePerCall clears the value of the "moderation_price_per_call" field.
+func (u *GroupUpsert) ClearModerationPricePerCall() *GroupUpsert {
+	u.SetNull(group.FieldModerationPricePerCall)
+	return u
+}
+
+// SetModerationPricePerMillionTokens sets the "moderation_price_per_million_tokens" field.
+func (u *GroupUpsert) SetModerationPricePerMillionTokens(v float64) *GroupUpsert {
+	u.Set(group.FieldModerationPricePerMillionTokens, v)
+	return u
+}
+
+// UpdateModerationPricePerMillionTokens sets the "moderation_price_per_million_tokens" field to the value that was provided on create.
+func (u *GroupUpsert) UpdateModerationPricePerMillionTokens() *GroupUpsert {
+	u.SetExcluded(group.FieldModerationPricePerMillionTokens)
+	return u
+}
+
+// AddModerationPricePerMillionTokens adds v to the "moderation_price_per_million_tokens" field.
+func (u *GroupUpsert) AddModerationPricePerMillionTokens(v float64) *GroupUpsert {
+	u.Add(group.FieldModerationPricePerMillionTokens, v)
+	return u
+}
+
+// ClearModerationPricePerMillionTokens clears the value of the "moderation_price_per_million_tokens" field.
+func (u *GroupUpsert) ClearModerationPricePerMillionTokens() *GroupUpsert {
+	u.SetNull(group.FieldModerationPricePerMillionTokens)
+	return u
+}
+
 // SetLongContextPricingEnabled sets the "long_context_pricing_enabled" field.
 func (u *GroupUpsert) SetLongContextPricingEnabled(v bool) *GroupUpsert {
 	u.Set(group.FieldLongContextPricingEnabled, v)
@@ -2768,6 +2997,72 @@ func (u *GroupUpsert) AddProfitSafetyBuffer(v float64) *GroupUpsert {
 	return u
 }
 
+// SetPromptPrefixRoutingEnabled sets the "prompt_prefix_routing_enabled" field.
+func (u *GroupUpsert) SetPromptPrefixRoutingEnabled(v bool) *GroupUpsert {
+	u.Set(group.FieldPromptPrefixRoutingEnabled, v)
+	return u
+}
+
+// UpdatePromptPrefixRoutingEnabled sets the "prompt_prefix_routing_enabled" field to the value that was provided on create.
+func (u *GroupUpsert) UpdatePromptPrefixRoutingEnabled() *GroupUpsert {
+	u.SetExcluded(group.FieldPromptPrefixRoutingEnabled)
+	return u
+}
+
+// SetPromptPrefixMessages sets the "prompt_prefix_messages" field.
+func (u *GroupUpsert) SetPromptPrefixMessages(v int) *GroupUpsert {
+	u.Set(group.FieldPromptPrefixMessages, v)
+	return u
+}
+
+// UpdatePromptPrefixMessages sets the "prompt_prefix_messages" field to the value that was provided on create.
+func (u *GroupUpsert) UpdatePromptPrefixMessages() *GroupUpsert {
+	u.SetExcluded(group.FieldPromptPrefixMessages)
+	return u
+}
+
+// AddPromptPrefixMessages adds v to the "prompt_prefix_messages" field.
+func (u *GroupUpsert) AddPromptPrefixMessages(v int) *GroupUpsert {
+	u.Add(group.FieldPromptPrefixMessages, v)
+	return u
+}
+
+// SetStreamResumeEnabled sets the "stream_resume_enabled" field.
+func (u *GroupUpsert) SetStreamResumeEnabled(v bool) *GroupUpsert {
+	u.Set(group.FieldStreamResumeEnabled, v)
+	return u
+}
+
+// UpdateStreamResumeEnabled sets the "stream_resume_enabled" field to the value that was provided on create.
+func (u *GroupUpsert) UpdateStreamResumeEnabled() *GroupUpsert {
+	u.SetExcluded(group.FieldStreamResumeEnabled)
+	return u
+}
+
+// SetContextCompactionMode sets the "context_compaction_mode" field.
+func (u *GroupUpsert) SetContextCompactionMode(v string) *GroupUpsert {
+	u.Set(group.FieldContextCompactionMode, v)
+	return u
+}
+
+// UpdateContextCompactionMode sets the "context_compaction_mode" field to the value that was provided on create.
+func (u *GroupUpsert) UpdateContextCompactionMode() *GroupUpsert {
+	u.SetExcluded(group.FieldContextCompactionMode)
+	return u
+}
+
+// SetContextCompactionModel sets the "context_compaction_model" field.
+func (u *GroupUpsert) SetContextCompactionModel(v string) *GroupUpsert {
+	u.Set(group.FieldContextCompactionModel, v)
+	return u
+}
+
+// UpdateContextCompactionModel sets the "context_compaction_model" field to the value that was provided on create.
+func (u *GroupUpsert) UpdateContextCompactionModel() *GroupUpsert {
+	u.SetExcluded(group.FieldContextCompactionModel)
+	return u
+}
+
 // UpdateNewValues updates the mutable fields using the new values that were set on create.
 // Using this option is equivalent to using:
 //
@@ -3600,6 +3895,62 @@ func (u *GroupUpsertOne) ClearAudioSttPricePerHour() *GroupUpsertOne {
 	})
 }
 
+// SetModerationPricePerCall sets the "moderation_price_per_call" field.
+func (u *GroupUpsertOne) SetModerationPricePerCall(v float64) *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.SetModerationPricePerCall(v)
+	})
+}
+
+// AddModerationPricePerCall adds v to the "moderation_price_per_call" field.
+func (u *GroupUpsertOne) AddModerationPricePerCall(v float64) *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.AddModerationPricePerCall(v)
+	})
+}
+
+// UpdateModerationPricePerCall sets the "moderation_price_per_call" field to the value that was provided on create.
+func (u *GroupUpsertOne) UpdateModerationPricePerCall() *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.UpdateModerationPricePerCall()
+	})
+}
+
+// ClearModerationPricePerCall clears the value of the "moderation_price_per_call" field.
+func (u *GroupUpsertOne) ClearModerationPricePerCall() *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.ClearModerationPricePerCall()
+	})
+}
+
+// SetModerationPricePerMillionTokens sets the "moderation_price_per_million_tokens" field.
+func (u *GroupUpsertOne) SetModerationPricePerMillionTokens(v float64) *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.SetModerationPricePerMillionTokens(v)
+	})
+}
+
+// AddModerationPricePerMillionTokens adds v to the "moderation_price_per_million_tokens" field.
+func (u *GroupUpsertOne) AddModerationPricePerMillionTokens(v float64) *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.AddModerationPricePerMillionTokens(v)
+	})
+}
+
+// UpdateModerationPricePerMillionTokens sets the "moderation_price_per_million_tokens" field to the value that was provided on create.
+func (u *GroupUpsertOne) UpdateModerationPricePerMillionTokens() *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.UpdateModerationPricePerMillionTokens()
+	})
+}
+
+// ClearModerationPricePerMillionTokens clears the value of the "moderation_price_per_million_tokens" field.
+func (u *GroupUpsertOne) ClearModerationPricePerMillionTokens() *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.ClearModerationPricePerMillionTokens()
+	})
+}
+
 // SetLongContextPricingEnabled sets the "long_context_pricing_enabled" field.
 func (u *GroupUpsertOne) SetLongContextPricingEnabled(v bool) *GroupUpsertOne {
 	return u.Update(func(s *GroupUpsert) {
@@ -3992,6 +4343,83 @@ func (u *GroupUpsertOne) UpdateProfitSafetyBuffer() *GroupUpsertOne {
 	})
 }
 
+// SetPromptPrefixRoutingEnabled sets the "prompt_prefix_routing_enabled" field.
+func (u *GroupUpsertOne) SetPromptPrefixRoutingEnabled(v bool) *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.SetPromptPrefixRoutingEnabled(v)
+	})
+}
+
+// UpdatePromptPrefixRoutingEnabled sets the "prompt_prefix_routing_enabled" field to the value that was provided on create.
+func (u *GroupUpsertOne) UpdatePromptPrefixRoutingEnabled() *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.UpdatePromptPrefixRoutingEnabled()
+	})
+}
+
+// SetPromptPrefixMessages sets the "prompt_prefix_messages" field.
+func (u *GroupUpsertOne) SetPromptPrefixMessages(v int) *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.SetPromptPrefixMessages(v)
+	})
+}
+
+// AddPromptPrefixMessages adds v to the "prompt_prefix_messages" field.
+func (u *GroupUpsertOne) AddPromptPrefixMessages(v int) *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.AddPromptPrefixMessages(v)
+	})
+}
+
+// UpdatePromptPrefixMessages sets the "prompt_prefix_messages" field to the value that was provided on create.
+func (u *GroupUpsertOne) UpdatePromptPrefixMessages() *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.UpdatePromptPrefixMessages()
+	})
+}
+
+// SetStreamResumeEnabled sets the "stream_resume_enabled" field.
+func (u *GroupUpsertOne) SetStreamResumeEnabled(v bool) *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.SetStreamResumeEnabled(v)
+	})
+}
+
+// UpdateStreamResumeEnabled sets the "stream_resume_enabled" field to the value that was provided on create.
+func (u *GroupUpsertOne) UpdateStreamResumeEnabled() *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.UpdateStreamResumeEnabled()
+	})
+}
+
+// SetContextCompactionMode sets the "context_compaction_mode" field.
+func (u *GroupUpsertOne) SetContextCompactionMode(v string) *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.SetContextCompactionMode(v)
+	})
+}
+
+// UpdateContextCompactionMode sets the "context_compaction_mode" field to the value that was provided on create.
+func (u *GroupUpsertOne) UpdateContextCompactionMode() *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.UpdateContextCompactionMode()
+	})
+}
+
+// SetContextCompactionModel sets the "context_compaction_model" field.
+func (u *GroupUpsertOne) SetContextCompactionModel(v string) *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.SetContextCompactionModel(v)
+	})
+}
+
+// UpdateContextCompactionModel sets the "context_compaction_model" field to the value that was provided on create.
+func (u *GroupUpsertOne) UpdateContextCompactionModel() *GroupUpsertOne {
+	return u.Update(func(s *GroupUpsert) {
+		s.UpdateContextCompactionModel()
+	})
+}
+
 // Exec executes the query.
 func (u *GroupUpsertOne) Exec(ctx context.Context) error {
 	if len(u.create.conflict) == 0 {
@@ -4990,6 +5418,62 @@ func (u *GroupUpsertBulk) ClearAudioSttPricePerHour() *GroupUpsertBulk {
 	})
 }
 
+// SetModerationPricePerCall sets the "moderation_price_per_call" field.
+func (u *GroupUpsertBulk) SetModerationPricePerCall(v float64) *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.SetModerationPricePerCall(v)
+	})
+}
+
+// AddModerationPricePerCall adds v to the "moderation_price_per_call" field.
+func (u *GroupUpsertBulk) AddModerationPricePerCall(v float64) *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.AddModerationPricePerCall(v)
+	})
+}
+
+// UpdateModerationPricePerCall sets the "moderation_price_per_call" field to the value that was provided on create.
+func (u *GroupUpsertBulk) UpdateModerationPricePerCall() *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.UpdateModerationPricePerCall()
+	})
+}
+
+// ClearModerationPricePerCall clears the value of the "moderation_price_per_call" field.
+func (u *GroupUpsertBulk) ClearModerationPricePerCall() *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.ClearModerationPricePerCall()
+	})
+}
+
+// SetModerationPricePerMillionTokens sets the "moderation_price_per_million_tokens" field.
+func (u *GroupUpsertBulk) SetModerationPricePerMillionTokens(v float64) *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.SetModerationPricePerMillionTokens(v)
+	})
+}
+
+// AddModerationPricePerMillionTokens adds v to the "moderation_price_per_million_tokens" field.
+func (u *GroupUpsertBulk) AddModerationPricePerMillionTokens(v float64) *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.AddModerationPricePerMillionTokens(v)
+	})
+}
+
+// UpdateModerationPricePerMillionTokens sets the "moderation_price_per_million_tokens" field to the value that was provided on create.
+func (u *GroupUpsertBulk) UpdateModerationPricePerMillionTokens() *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.UpdateModerationPricePerMillionTokens()
+	})
+}
+
+// ClearModerationPricePerMillionTokens clears the value of the "moderation_price_per_million_tokens" field.
+func (u *GroupUpsertBulk) ClearModerationPricePerMillionTokens() *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.ClearModerationPricePerMillionTokens()
+	})
+}
+
 // SetLongContextPricingEnabled sets the "long_context_pricing_enabled" field.
 func (u *GroupUpsertBulk) SetLongContextPricingEnabled(v bool) *GroupUpsertBulk {
 	return u.Update(func(s *GroupUpsert) {
@@ -5382,6 +5866,83 @@ func (u *GroupUpsertBulk) UpdateProfitSafetyBuffer() *GroupUpsertBulk {
 	})
 }
 
+// SetPromptPrefixRoutingEnabled sets the "prompt_prefix_routing_enabled" field.
+func (u *GroupUpsertBulk) SetPromptPrefixRoutingEnabled(v bool) *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.SetPromptPrefixRoutingEnabled(v)
+	})
+}
+
+// UpdatePromptPrefixRoutingEnabled sets the "prompt_prefix_routing_enabled" field to the value that was provided on create.
+func (u *GroupUpsertBulk) UpdatePromptPrefixRoutingEnabled() *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.UpdatePromptPrefixRoutingEnabled()
+	})
+}
+
+// SetPromptPrefixMessages sets the "prompt_prefix_messages" field.
+func (u *GroupUpsertBulk) SetPromptPrefixMessages(v int) *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.SetPromptPrefixMessages(v)
+	})
+}
+
+// AddPromptPrefixMessages adds v to the "prompt_prefix_messages" field.
+func (u *GroupUpsertBulk) AddPromptPrefixMessages(v int) *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.AddPromptPrefixMessages(v)
+	})
+}
+
+// UpdatePromptPrefixMessages sets the "prompt_prefix_messages" field to the value that was provided on create.
+func (u *GroupUpsertBulk) UpdatePromptPrefixMessages() *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.UpdatePromptPrefixMessages()
+	})
+}
+
+// SetStreamResumeEnabled sets the "stream_resume_enabled" field.
+func (u *GroupUpsertBulk) SetStreamResumeEnabled(v bool) *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.SetStreamResumeEnabled(v)
+	})
+}
+
+// UpdateStreamResumeEnabled sets the "stream_resume_enabled" field to the value that was provided on create.
+func (u *GroupUpsertBulk) UpdateStreamResumeEnabled() *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.UpdateStreamResumeEnabled()
+	})
+}
+
+// SetContextCompactionMode sets the "context_compaction_mode" field.
+func (u *GroupUpsertBulk) SetContextCompactionMode(v string) *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.SetContextCompactionMode(v)
+	})
+}
+
+// UpdateContextCompactionMode sets the "context_compaction_mode" field to the value that was provided on create.
+func (u *GroupUpsertBulk) UpdateContextCompactionMode() *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.UpdateContextCompactionMode()
+	})
+}
+
+// SetContextCompactionModel sets the "context_compaction_model" field.
+func (u *GroupUpsertBulk) SetContextCompactionModel(v string) *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.SetContextCompactionModel(v)
+	})
+}
+
+// UpdateContextCompactionModel sets the "context_compaction_model" field to the value that was provided on create.
+func (u *GroupUpsertBulk) UpdateContextCompactionModel() *GroupUpsertBulk {
+	return u.Update(func(s *GroupUpsert) {
+		s.UpdateContextCompactionModel()
+	})
+}
+
 // Exec executes the query.
 func (u *GroupUpsertBulk) Exec(ctx context.Context) error {
 	if u.create.err != nil {
@@ -788,6 +788,60 @@ func (_u *GroupUpdate) ClearAudioSttPricePerHour() *GroupUpdate {
 	return _u
 }
 
+// SetModerationPricePerCall sets the "moderation_price_per_call" field.
+func (_u *GroupUpdate) SetModerationPricePerCall(v float64) *GroupUpdate {
+	_u.mutation.ResetModerationPricePerCall()
+	_u.mutation.SetModerationPricePerCall(v)
+	return _u
+}
+
+// SetNillableModerationPricePerCall sets the "moderation_price_per_call" field if the given value is not nil.
+func (_u *GroupUpdate) SetNillableModerationPricePerCall(v *float64) *GroupUpdate {
+	if v != nil {
+		_u.SetModerationPricePerCall(*v)
+	}
+	return _u
+}
+
+// AddModerationPricePerCall adds value to the "moderation_price_per_call" field.
+func (_u *GroupUpdate) AddModerationPricePerCall(v float64) *GroupUpdate {
+	_u.mutation.AddModerationPricePerCall(v)
+	return _u
+}
+
+// ClearModerationPricePerCall clears the value of the "moderation_price_per_call" field.
+func (_u *GroupUpdate) ClearModerationPricePerCall() *GroupUpdate {
+	_u.mutation.ClearModerationPricePerCall()
+	return _u
+}
+
+// SetModerationPricePerMillionTokens sets the "moderation_price_per_million_tokens" field.
+func (_u *GroupUpdate) SetModerationPricePerMillionTokens(v float64) *GroupUpdate {
+	_u.mutation.ResetModerationPricePerMillionTokens()
+	_u.mutation.SetModerationPricePerMillionTokens(v)
+	return _u
+}
+
+// SetNillableModerationPricePerMillionTokens sets the "moderation_price_per_million_tokens" field if the given value is not nil.
+func (_u *GroupUpdate) SetNillableModerationPricePerMillionTokens(v *float64) *GroupUpdate {
+	if v != nil {
+		_u.SetModerationPricePerMillionTokens(*v)
+	}
+	return _u
+}
+
+// AddModerationPricePerMillionTokens adds value to the "moderation_price_per_million_tokens" field.
+func (_u *GroupUpdate) AddModerationPricePerMillionTokens(v float64) *GroupUpdate {
+	_u.mutation.AddModerationPricePerMillionTokens(v)
+	return _u
+}
+
+// ClearModerationPricePerMillionTokens clears the value of the "moderation_price_per_million_tokens" field.
+func (_u *GroupUpdate) ClearModerationPricePerMillionTokens() *GroupUpdate {
+	_u.mutation.ClearModerationPricePerMillionTokens()
+	return _u
+}
+
 // SetLongContextPricingEnabled sets the "long_context_pricing_enabled" field.
 func (_u *GroupUpdate) SetLongContextPricingEnabled(v bool) *GroupUpdate {
 	_u.mutation.SetLongContextPricingEnabled(v)
@@ -1162,6 +1216,83 @@ func (_u *GroupUpdate) AddProfitSafetyBuffer(v float64) *GroupUpdate {
 	return _u
 }
 
+// SetPromptPrefixRoutingEnabled sets the "prompt_prefix_routing_enabled" field.
+func (_u *GroupUpdate) SetPromptPrefixRoutingEnabled(v bool) *GroupUpdate {
+	_u.mutation.SetPromptPrefixRoutingEnabled(v)
+	return _u
+}
+
+// SetNillablePromptPrefixRoutingEnabled sets the "prompt_prefix_routing_enabled" field if the given value is not nil.
+func (_u *GroupUpdate) SetNillablePromptPrefixRoutingEnabled(v *bool) *GroupUpdate {
+	if v != nil {
+		_u.SetPromptPrefixRoutingEnabled(*v)
+	}
+	return _u
+}
+
+// SetPromptPrefixMessages sets the "prompt_prefix_messages" field.
+func (_u *GroupUpdate) SetPromptPrefixMessages(v int) *GroupUpdate {
+	_u.mutation.ResetPromptPrefixMessages()
+	_u.mutation.SetPromptPrefixMessages(v)
+	return _u
+}
+
+// SetNillablePromptPrefixMessages sets the "prompt_prefix_messages" field if the given value is not nil.
+func (_u *GroupUpdate) SetNillablePromptPrefixMessages(v *int) *GroupUpdate {
+	if v != nil {
+		_u.SetPromptPrefixMessages(*v)
+	}
+	return _u
+}
+
+// AddPromptPrefixMessages adds value to the "prompt_prefix_messages" field.
+func (_u *GroupUpdate) AddPromptPrefixMessages(v int) *GroupUpdate {
+	_u.mutation.AddPromptPrefixMessages(v)
+	return _u
+}
+
+// SetStreamResumeEnabled sets the "stream_resume_enabled" field.
+func (_u *GroupUpdate) SetStreamResumeEnabled(v bool) *GroupUpdate {
+	_u.mutation.SetStreamResumeEnabled(v)
+	return _u
+}
+
+// SetNillableStreamResumeEnabled sets the "stream_resume_enabled" field if the given value is not nil.
+func (_u *GroupUpdate) SetNillableStreamResumeEnabled(v *bool) *GroupUpdate {
+	if v != nil {
+		_u.SetStreamResumeEnabled(*v)
+	}
+	return _u
+}
+
+// SetContextCompactionMode sets the "context_compaction_mode" field.
+func (_u *GroupUpdate) SetContextCompactionMode(v string) *GroupUpdate {
+	_u.mutation.SetContextCompactionMode(v)
+	return _u
+}
+
+// SetNillableContextCompactionMode sets the "context_compaction_mode" field if the given value is not nil.
+func (_u *GroupUpdate) SetNillableContextCompactionMode(v *string) *GroupUpdate {
+	if v != nil {
+		_u.SetContextCompactionMode(*v)
+	}
+	return _u
+}
+
+// SetContextCompactionModel sets the "context_compaction_model" field.
+func (_u *GroupUpdate) SetContextCompactionModel(v string) *GroupUpdate {
+	_u.mutation.SetContextCompactionModel(v)
+	return _u
+}
+
+// SetNillableContextCompactionModel sets the "context_compaction_model" field if the given value is not nil.
+func (_u *GroupUpdate) SetNillableContextCompactionModel(v *string) *GroupUpdate {
+	if v != nil {
+		_u.SetContextCompactionModel(*v)
+	}
+	return _u
+}
+
 // AddAPIKeyIDs adds the "api_keys" edge to the APIKey entity by IDs.
 func (_u *GroupUpdate) AddAPIKeyIDs(ids ...int64) *GroupUpdate {
 	_u.mutation.AddAPIKeyIDs(ids...)
@@ -1477,6 +1608,16 @@ func (_u *GroupUpdate) check() error {
 			return &ValidationError{Name: "audio_stt_price_per_hour", err: fmt.Errorf(`ent: validator failed for field "Group.audio_stt_price_per_hour": %w`, err)}
 		}
 	}
+	if v, ok := _u.mutation.ModerationPricePerCall(); ok {
+		if err := group.ModerationPricePerCallValidator(v); err != nil {
+			return &ValidationError{Name: "moderation_price_per_call", err: fmt.Errorf(`ent: validator failed for field "Group.moderation_price_per_call": %w`, err)}
+		}
+	}
+	if v, ok := _u.mutation.ModerationPricePerMillionTokens(); ok {
+		if err := group.ModerationPricePerMillionTokensValidator(v); err != nil {
+			return &ValidationError{Name: "moderation_price_per_million_tokens", err: fmt.Errorf(`ent: validator failed for field "Group.moderation_price_per_million_tokens": %w`, err)}
+		}
+	}
 	if v, ok := _u.mutation.DefaultMappedModel(); ok {
 		if err := group.DefaultMappedModelValidator(v); err != nil {
 			return &ValidationError{Name: "default_mapped_model", err: fmt.Errorf(`ent: validator failed for field "Group.default_mapped_model": %w`, err)}
@@ -1487,6 +1628,16 @@ func (_u *GroupUpdate) check() error {
 			return &ValidationError{Name: "max_reasoning_effort", err: fmt.Errorf(`ent: validator failed for field "Group.max_reasoning_effort": %w`, err)}
 		}
 	}
+	if v, ok := _u.mutation.ContextCompactionMode(); ok {
+		if err := group.ContextCompactionModeValidator(v); err != nil {
+			return &ValidationError{Name: "context_compaction_mode", err: fmt.Errorf(`ent: validator failed for field "Group.context_compaction_mode": %w`, err)}
+		}
+	}
+	if v, ok := _u.mutation.ContextCompactionModel(); ok {
+		if err := group.ContextCompactionModelValidator(v); err != nil {
+			return &ValidationError{Name: "context_compaction_model", err: fmt.Errorf(`ent: validator failed for field "Group.context_compaction_model": %w`, err)}
+		}
+	}
 	return nil
 }
 
@@ -1730,6 +1881,24 @@ func (_u *GroupUpdate) sqlSave(ctx context.Context) (_node int, err error) {
 	if _u.mutation.AudioSttPricePerHourCleared() {
 		_spec.ClearField(group.FieldAudioSttPricePerHour, field.TypeFloat64)
 	}
+	if value, ok := _u.mutation.ModerationPricePerCall(); ok {
+		_spec.SetField(group.FieldModerationPricePerCall, field.TypeFloat64, value)
+	}
+	if value, ok := _u.mutation.AddedModerationPricePerCall(); ok {
+		_spec.AddField(group.FieldModerationPricePerCall, field.TypeFloat64, value)
+	}
+	if _u.mutation.ModerationPricePerCallCleared() {
+		_spec.ClearField(group.FieldModerationPricePerCall, field.TypeFloat64)
+	}
+	if value, ok := _u.mutation.ModerationPricePerMillionTokens(); ok {
+		_spec.SetField(group.FieldModerationPricePerMillionTokens, field.TypeFloat64, value)
+	}
+	if value, ok := _u.mutation.AddedModerationPricePerMillionTokens(); ok {
+		_spec.AddField(group.FieldModerationPricePerMillionTokens, field.TypeFloat64, value)
+	}
+	if _u.mutation.ModerationPricePerMillionTokensCleared() {
+		_spec.ClearField(group.FieldModerationPricePerMillionTokens, field.TypeFloat64)
+	}
 	if value, ok := _u.mutation.LongContextPricingEnabled(); ok {
 		_spec.SetField(group.FieldLongContextPricingEnabled, field.TypeBool, value)
 	}
@@ -1844,6 +2013,24 @@ func (_u *GroupUpdate) sqlSave(ctx context.Context) (_node int, err error) {
 	if value, ok := _u.mutation.AddedProfitSafetyBuffer(); ok {
 		_spec.AddField(group.FieldProfitSafetyBuffer, field.TypeFloat64, value)
 	}
+	if value, ok := _u.mutation.PromptPrefixRoutingEnabled(); ok {
+		_spec.SetField(group.FieldPromptPrefixRoutingEnabled, field.TypeBool, value)
+	}
+	if value, ok := _u.mutation.PromptPrefixMessages(); ok {
+		_spec.SetField(group.FieldPromptPrefixMessages, field.TypeInt, value)
+	}
+	if value, ok := _u.mutation.AddedPromptPrefixMessages(); ok {
+		_spec.AddField(group.FieldPromptPrefixMessages, field.TypeInt, value)
+	}
+	if value, ok := _u.mutation.StreamResumeEnabled(); ok {
+		_spec.SetField(group.FieldStreamResumeEnabled, field.TypeBool, value)
+	}
+	if value, ok := _u.mutation.ContextCompactionMode(); ok {
+		_spec.SetField(group.FieldContextCompactionMode, field.TypeString, value)
+	}
+	if value, ok := _u.mutation.ContextCompactionModel(); ok {
+		_spec.SetField(group.FieldContextCompactionModel, field.TypeString, value)
+	}
 	if _u.mutation.APIKeysCleared() {
 		edge := &sqlgraph.EdgeSpec{
 			Rel:     sqlgraph.O2M,
@@ -2909,6 +3096,60 @@ func (_u *GroupUpdateOne) ClearAudioSttPricePerHour() *GroupUpdateOne {
 	return _u
 }
 
+// SetModerationPricePerCall sets the "moderation_price_per_call" field.
+func (_u *GroupUpdateOne) SetModerationPricePerCall(v float64) *GroupUpdateOne {
+	_u.mutation.ResetModerationPricePerCall()
+	_u.mutation.SetModerationPricePerCall(v)
+	return _u
+}
+
+// SetNillableModerationPricePerCall sets the "moderation_price_per_call" field if the given value is not nil.
+func (_u *GroupUpdateOne) SetNillableModerationPricePerCall(v *float64) *GroupUpdateOne {
+	if v != nil {
+		_u.SetModerationPricePerCall(*v)
+	}
+	return _u
+}
+
+// AddModerationPricePerCall adds value to the "moderation_price_per_call" field.
+func (_u *GroupUpdateOne) AddModerationPricePerCall(v float64) *GroupUpdateOne {
+	_u.mutation.AddModerationPricePerCall(v)
+	return _u
+}
+
+// ClearModerationPricePerCall clears the value of the "moderation_price_per_call" field.
+func (_u *GroupUpdateOne) ClearModerationPricePerCall() *GroupUpdateOne {
+	_u.mutation.ClearModerationPricePerCall()
+	return _u
+}
+
+// SetModerationPricePerMillionTokens sets the "moderation_price_per_million_tokens" field.
+func (_u *GroupUpdateOne) SetModerationPricePerMillionTokens(v float64) *GroupUpdateOne {
+	_u.mutation.ResetModerationPricePerMillionTokens()
+	_u.mutation.SetModerationPricePerMillionTokens(v)
+	return _u
+}
+
+// SetNillableModerationPricePerMillionTokens sets the "moderation_price_per_million_tokens" field if the given value is not nil.
+func (_u *GroupUpdateOne) SetNillableModerationPricePerMillionTokens(v *float64) *GroupUpdateOne {
+	if v != nil {
+		_u.SetModerationPricePerMillionTokens(*v)
+	}
+	return _u
+}
+
+// AddModerationPricePerMillionTokens adds value to the "moderation_price_per_million_tokens" field.
+func (_u *GroupUpdateOne) AddModerationPricePerMillionTokens(v float64) *GroupUpdateOne {
+	_u.mutation.AddModerationPricePerMillionTokens(v)
+	return _u
+}
+
+// ClearModerationPricePerMillionTokens clears the value of the "moderation_price_per_million_tokens" field.
+func (_u *GroupUpdateOne) ClearModerationPricePerMillionTokens() *GroupUpdateOne {
+	_u.mutation.ClearModerationPricePerMillionTokens()
+	return _u
+}
+
 // SetLongContextPricingEnabled sets the "long_context_pricing_enabled" field.
 func (_u *GroupUpdateOne) SetLongContextPricingEnabled(v bool) *GroupUpdateOne {
 	_u.mutation.SetLongContextPricingEnabled(v)
@@ -3283,6 +3524,83 @@ func (_u *GroupUpdateOne) AddProfitSafetyBuffer(v float64) *GroupUpdateOne {
 	return _u
 }
 
+// SetPromptPrefixRoutingEnabled sets the "prompt_prefix_routing_enabled" field.
+func (_u *GroupUpdateOne) SetPromptPrefixRoutingEnabled(v bool) *GroupUpdateOne {
+	_u.mutation.SetPromptPrefixRoutingEnabled(v)
+	return _u
+}
+
+// SetNillablePromptPrefixRoutingEnabled sets the "prompt_prefix_routing_enabled" field if the given value is not nil.
+func (_u *GroupUpdateOne) SetNillablePromptPrefixRoutingEnabled(v *bool) *GroupUpdateOne {
+	if v != nil {
+		_u.SetPromptPrefixRoutingEnabled(*v)
+	}
+	return _u
+}
+
+// SetPromptPrefixMessages sets the "prompt_prefix_messages" field.
+func (_u *GroupUpdateOne) SetPromptPrefixMessages(v int) *GroupUpdateOne {
+	_u.mutation.ResetPromptPrefixMessages()
+	_u.mutation.SetPromptPrefixMessages(v)
+	return _u
+}
+
+// SetNillablePromptPrefixMessages sets the "prompt_prefix_messages" field if the given value is not nil.
+func (_u *GroupUpdateOne) SetNillablePromptPrefixMessages(v *int) *GroupUpdateOne {
+	if v != nil {
+		_u.SetPromptPrefixMessages(*v)
+	}
+	return _u
+}
+
+// AddPromptPrefixMessages adds value to the "prompt_prefix_messages" field.
+func (_u *GroupUpdateOne) AddPromptPrefixMessages(v int) *GroupUpdateOne {
+	_u.mutation.AddPromptPrefixMessages(v)
+	return _u
+}
+
+// SetStreamResumeEnabled sets the "stream_resume_enabled" field.
+func (_u *GroupUpdateOne) SetStreamResumeEnabled(v bool) *GroupUpdateOne {
+	_u.mutation.SetStreamResumeEnabled(v)
+	return _u
+}
+
+// SetNillableStreamResumeEnabled sets the "stream_resume_enabled" field if the given value is not nil.
+func (_u *GroupUpdateOne) SetNillableStreamResumeEnabled(v *bool) *GroupUpdateOne {
+	if v != nil {
+		_u.SetStreamResumeEnabled(*v)
+	}
+	return _u
+}
+
+// SetContextCompactionMode sets the "context_compaction_mode" field.
+func (_u *GroupUpdateOne) SetContextCompactionMode(v string) *GroupUpdateOne {
+	_u.mutation.SetContextCompactionMode(v)
+	return _u
+}
+
+// SetNillableContextCompactionMode sets the "context_compaction_mode" field if the given value is not nil.
+func (_u *GroupUpdateOne) SetNillableContextCompactionMode(v *string) *GroupUpdateOne {
+	if v != nil {
+		_u.SetContextCompactionMode(*v)
+	}
+	return _u
+}
+
+// SetContextCompactionModel sets the "context_compaction_model" field.
+func (_u *GroupUpdateOne) SetContextCompactionModel(v string) *GroupUpdateOne {
+	_u.mutation.SetContextCompactionModel(v)
+	return _u
+}
+
+// SetNillableContextCompactionModel sets the "context_compaction_model" field if the given value is not nil.
+func (_u *GroupUpdateOne) SetNillableContextCompactionModel(v *string) *GroupUpdateOne {
+	if v != nil {
+		_u.SetContextCompactionModel(*v)
+	}
+	return _u
+}
+
 // AddAPIKeyIDs adds the "api_keys" edge to the APIKey entity by IDs.
 func (_u *GroupUpdateOne) AddAPIKeyIDs(ids ...int64) *GroupUpdateOne {
 	_u.mutation.AddAPIKeyIDs(ids...)
@@ -3611,6 +3929,16 @@ func (_u *GroupUpdateOne) check() error {
 			return &ValidationError{Name: "audio_stt_price_per_hour", err: fmt.Errorf(`ent: validator failed for field "Group.audio_stt_price_per_hour": %w`, err)}
 		}
 	}
+	if v, ok := _u.mutation.ModerationPricePerCall(); ok {
+		if err := group.ModerationPricePerCallValidator(v); err != nil {
+			return &ValidationError{Name: "moderation_price_per_call", err: fmt.Errorf(`ent: validator failed for field "Group.moderation_price_per_call": %w`, err)}
+		}
+	}
+	if v, ok := _u.mutation.ModerationPricePerMillionTokens(); ok {
+		if err := group.ModerationPricePerMillionTokensValidator(v); err != nil {
+			return &ValidationError{Name: "moderation_price_per_million_tokens", err: fmt.Errorf(`ent: validator failed for field "Group.moderation_price_per_million_tokens": %w`, err)}
+		}
+	}
 	if v, ok := _u.mutation.DefaultMappedModel(); ok {
 		if err := group.DefaultMappedModelValidator(v); err != nil {
 			return &ValidationError{Name: "default_mapped_model", err: fmt.Errorf(`ent: validator failed for field "Group.default_mapped_model": %w`, err)}
@@ -3621,6 +3949,16 @@ func (_u *GroupUpdateOne) check() error {
 			return &ValidationError{Name: "max_reasoning_effort", err: fmt.Errorf(`ent: validator failed for field "Group.max_reasoning_effort": %w`, err)}
 		}
 	}
+	if v, ok := _u.mutation.ContextCompactionMode(); ok {
+		if err := group.ContextCompactionModeValidator(v); err != nil {
+			return &ValidationError{Name: "context_compaction_mode", err: fmt.Errorf(`ent: validator failed for field "Group.context_compaction_mode": %w`, err)}
+		}
+	}
+	if v, ok := _u.mutation.ContextCompactionModel(); ok {
+		if err := group.ContextCompactionModelValidator(v); err != nil {
+			return &ValidationError{Name: "context_compaction_model", err: fmt.Errorf(`ent: validator failed for field "Group.context_compaction_model": %w`, err)}
+		}
+	}
 	return nil
 }
 
@@ -3881,6 +4219,24 @@ func (_u *GroupUpdateOne) sqlSave(ctx context.Context) (_node *Group, err error)
 	if _u.mutation.AudioSttPricePerHourCleared() {
 		_spec.ClearField(group.FieldAudioSttPricePerHour, field.TypeFloat64)
 	}
+	if value, ok := _u.mutation.ModerationPricePerCall(); ok {
+		_spec.SetField(group.FieldModerationPricePerCall, field.TypeFloat64, value)
+	}
+	if value, ok := _u.mutation.AddedModerationPricePerCall(); ok {
+		_spec.AddField(group.FieldModerationPricePerCall, field.TypeFloat64, value)
+	}
+	if _u.mutation.ModerationPricePerCallCleared() {
+		_spec.ClearField(group.FieldModerationPricePerCall, field.TypeFloat64)
+	}
+	if value, ok := _u.mutation.ModerationPricePerMillionTokens(); ok {
+		_spec.SetField(group.FieldModerationPricePerMillionTokens, field.TypeFloat64, value)
+	}
+	if value, ok := _u.mutation.AddedModerationPricePerMillionTokens(); ok {
+		_spec.AddField(group.FieldModerationPricePerMillionTokens, field.TypeFloat64, value)
+	}
+	if _u.mutation.ModerationPricePerMillionTokensCleared() {
+		_spec.ClearField(group.FieldModerationPricePerMillionTokens, field.TypeFloat64)
+	}
 	if value, ok := _u.mutation.LongContextPricingEnabled(); ok {
 		_spec.SetField(group.FieldLongContextPricingEnabled, field.TypeBool, value)
 	}
@@ -3995,6 +4351,24 @@ func (_u *GroupUpdateOne) sqlSave(ctx context.Context) (_node *Group, err error)
 	if value, ok := _u.mutation.AddedProfitSafetyBuffer(); ok {
 		_spec.AddField(group.FieldProfitSafetyBuffer, field.TypeFloat64, value)
 	}
+	if value, ok := _u.mutation.PromptPrefixRoutingEnabled(); ok {
+		_spec.SetField(group.FieldPromptPrefixRoutingEnabled, field.TypeBool, value)
+	}
+	if value, ok := _u.mutation.PromptPrefixMessages(); ok {
+		_spec.SetField(group.FieldPromptPrefixMessages, field.TypeInt, value)
+	}
+	if value, ok := _u.mutation.AddedPromptPrefixMessages(); ok {
+		_spec.AddField(group.FieldPromptPrefixMessages, field.TypeInt, value)
+	}
+	if value, ok := _u.mutation.StreamResumeEnabled(); ok {
+		_spec.SetField(group.FieldStreamResumeEnabled, field.TypeBool, value)
+	}
+	if value, ok := _u.mutation.ContextCompactionMode(); ok {
+		_spec.SetField(group.FieldContextCompactionMode, field.TypeString, value)
+	}
+	if value, ok := _u.mutation.ContextCompactionModel(); ok {
+		_spec.SetField(group.FieldContextCompactionModel, field.TypeString, value)
+	}
 	if _u.mutation.APIKeysCleared() {
 		edge := &sqlgraph.EdgeSpec{
 			Rel:     sqlgraph.O2M,
@@ -50,6 +50,7 @@ import (
 	"github.com/Wei-Shaw/sub2api/ent/userreferral"
 	"github.com/Wei-Shaw/sub2api/ent/usersubscription"
 	"github.com/Wei-Shaw/sub2api/internal/domain"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tlsfingerprint"
 )
 
 const (
@@ -109,7 +110,9 @@ type APIKeyMutation struct {
 	updated_at         *time.Time
 	deleted_at         *time.Time
 	key                *string
+	key_prefix         *string
 	name               *string
+	token_nonce        *string
 	status             *string
 	last_used_at       *time.Time
 	ip_whitelist       *[]string
@@ -440,6 +443,42 @@ func (m *APIKeyMutation) ResetKey() {
 	m.key = nil
 }
 
+// SetKeyPrefix sets the "key_prefix" field.
+func (m *APIKeyMutation) SetKeyPrefix(s string) {
+	m.key_prefix = &s
+}
+
+// KeyPrefix returns the value of the "key_prefix" field in the mutation.
+func (m *APIKeyMutation) KeyPrefix() (r string, exists bool) {
+	v := m.key_prefix
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldKeyPrefix returns the old "key_prefix" field's value of the APIKey entity.
+// If the APIKey object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *APIKeyMutation) OldKeyPrefix(ctx context.Context) (v string, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldKeyPrefix is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldKeyPrefix requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldKeyPrefix: %w", err)
+	}
+	return oldValue.KeyPrefix, nil
+}
+
+// ResetKeyPrefix resets all changes to the "key_prefix" field.
+func (m *APIKeyMutation) ResetKeyPrefix() {
+	m.key_prefix = nil
+}
+
 // SetName sets the "name" field.
 func (m *APIKeyMutation) SetName(s string) {
 	m.name = &s
@@ -476,6 +515,42 @@ func (m *APIKeyMutation) ResetName() {
 	m.name = nil
 }
 
+// SetTokenNonce sets the "token_nonce" field.
+func (m *APIKeyMutation) SetTokenNonce(s string) {
+	m.token_nonce = &s
+}
+
+// TokenNonce returns the value of the "token_nonce" field in the mutation.
+func (m *APIKeyMutation) TokenNonce() (r string, exists bool) {
+	v := m.token_nonce
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldTokenNonce returns the old "token_nonce" field's value of the APIKey entity.
+// If the APIKey object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *APIKeyMutation) OldTokenNonce(ctx context.Context) (v string, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldTokenNonce is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldTokenNonce requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldTokenNonce: %w", err)
+	}
+	return oldValue.TokenNonce, nil
+}
+
+// ResetTokenNonce resets all changes to the "token_nonce" field.
+func (m *APIKeyMutation) ResetTokenNonce() {
+	m.token_nonce = nil
+}
+
 // SetGroupID sets the "group_id" field.
 func (m *APIKeyMutation) SetGroupID(i int64) {
 	m.group = &i
@@ -1526,7 +1601,7 @@ func (m *APIKeyMutation) Type() string {
 // order to get all numeric fields that were incremented/decremented, call
 // AddedFields().
 func (m *APIKeyMutation) Fields() []string {
-	fields := make([]string, 0, 23)
+	fields := make([]string, 0, 25)
 	if m.created_at != nil {
 		fields = append(fields, apikey.FieldCreatedAt)
 	}
@@ -1542,9 +1617,15 @@ func (m *APIKeyMutation) Fields() []string {
 	if m.key != nil {
 		fields = append(fields, apikey.FieldKey)
 	}
+	if m.key_prefix != nil {
+		fields = append(fields, apikey.FieldKeyPrefix)
+	}
 	if m.name != nil {
 		fields = append(fields, apikey.FieldName)
 	}
+	if m.token_nonce != nil {
+		fields = append(fields, apikey.FieldTokenNonce)
+	}
 	if m.group != nil {
 		fields = append(fields, apikey.FieldGroupID)
 	}
@@ -1614,8 +1695,12 @@ func (m *APIKeyMutation) Field(name string) (ent.Value, bool) {
 		return m.UserID()
 	case apikey.FieldKey:
 		return m.Key()
+	case apikey.FieldKeyPrefix:
+		return m.KeyPrefix()
 	case apikey.FieldName:
 		return m.Name()
+	case apikey.FieldTokenNonce:
+		return m.TokenNonce()
 	case apikey.FieldGroupID:
 		return m.GroupID()
 	case apikey.FieldStatus:
@@ -1669,8 +1754,12 @@ func (m *APIKeyMutation) OldField(ctx context.Context, name string) (ent.Value,
 		return m.OldUserID(ctx)
 	case apikey.FieldKey:
 		return m.OldKey(ctx)
+	case apikey.FieldKeyPrefix:
+		return m.OldKeyPrefix(ctx)
 	case apikey.FieldName:
 		return m.OldName(ctx)
+	case apikey.FieldTokenNonce:
+		return m.OldTokenNonce(ctx)
 	case apikey.FieldGroupID:
 		return m.OldGroupID(ctx)
 	case apikey.FieldStatus:
@@ -1749,6 +1838,13 @@ func (m *APIKeyMutation) SetField(name string, value ent.Value) error {
 		}
 		m.SetKey(v)
 		return nil
+	case apikey.FieldKeyPrefix:
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetKeyPrefix(v)
+		return nil
 	case apikey.FieldName:
 		v, ok := value.(string)
 		if !ok {
@@ -1756,6 +1852,13 @@ func (m *APIKeyMutation) SetField(name string, value ent.Value) error {
 		}
 		m.SetName(v)
 		return nil
+	case apikey.FieldTokenNonce:
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetTokenNonce(v)
+		return nil
 	case apikey.FieldGroupID:
 		v, ok := value.(int64)
 		if !ok {
@@ -2095,9 +2198,15 @@ func (m *APIKeyMutation) ResetField(name string) error {
 	case apikey.FieldKey:
 		m.ResetKey()
 		return nil
+	case apikey.FieldKeyPrefix:
+		m.ResetKeyPrefix()
+		return nil
 	case apikey.FieldName:
 		m.ResetName()
 		return nil
+	case apikey.FieldTokenNonce:
+		m.ResetTokenNonce()
+		return nil
 	case apikey.FieldGroupID:
 		m.ResetGroupID()
 		return nil
@@ -17029,6 +17138,10 @@ type GroupMutation struct {
 	addaudio_tts_price_per_million_chars    *float64
 	audio_stt_price_per_hour                *float64
 	addaudio_stt_price_per_hour             *float64
+	moderation_price_per_call               *float64
+	addmoderation_price_per_call            *float64
+	moderation_price_per_million_tokens     *float64
+	addmoderation_price_per_million_tokens  *float64
 	long_context_pricing_enabled            *bool
 	model_pricing                           *json.RawMessage
 	appendmodel_pricing                     json.RawMessage
@@ -17061,6 +17174,12 @@ type GroupMutation struct {
 	addprofit_min_margin                    *float64
 	profit_safety_buffer                    *float64
 	addprofit_safety_buffer                 *float64
+	prompt_prefix_routing_enabled           *bool
+	prompt_prefix_messages                  *int
+	addprompt_prefix_messages               *int
+	stream_resume_enabled                   *bool
+	context_compaction_mode                 *string
+	context_compaction_model                *string
 	clearedFields                           map[string]struct{}
 	api_keys                                map[int64]struct{}
 	removedapi_keys                         map[int64]struct{}
@@ -19255,6 +19374,146 @@ func (m *GroupMutation) ResetAudioSttPricePerHour() {
 	delete(m.clearedFields, group.FieldAudioSttPricePerHour)
 }
 
+// SetModerationPricePerCall sets the "moderation_price_per_call" field.
+func (m *GroupMutation) SetModerationPricePerCall(f float64) {
+	m.moderation_price_per_call = &f
+	m.addmoderation_price_per_call = nil
+}
+
+// ModerationPricePerCall returns the value of the "moderation_price_per_call" field in the mutation.
+func (m *GroupMutation) ModerationPricePerCall() (r float64, exists bool) {
+	v := m.moderation_price_per_call
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldModerationPricePerCall returns the old "moderation_price_per_call" field's value of the Group entity.
+// If the Group object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *GroupMutation) OldModerationPricePerCall(ctx context.Context) (v *float64, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldModerationPricePerCall is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldModerationPricePerCall requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldModerationPricePerCall: %w", err)
+	}
+	return oldValue.ModerationPricePerCall, nil
+}
+
+// AddModerationPricePerCall adds f to the "moderation_price_per_call" field.
+func (m *GroupMutation) AddModerationPricePerCall(f float64) {
+	if m.addmoderation_price_per_call != nil {
+		*m.addmoderation_price_per_call += f
+	} else {
+		m.addmoderation_price_per_call = &f
+	}
+}
+
+// AddedModerationPricePerCall returns the value that was added to the "moderation_price_per_call" field in this mutation.
+func (m *GroupMutation) AddedModerationPricePerCall() (r float64, exists bool) {
+	v := m.addmoderation_price_per_call
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// ClearModerationPricePerCall clears the value of the "moderation_price_per_call" field.
+func (m *GroupMutation) ClearModerationPricePerCall() {
+	m.moderation_price_per_call = nil
+	m.addmoderation_price_per_call = nil
+	m.clearedFields[group.FieldModerationPricePerCall] = struct{}{}
+}
+
+// ModerationPricePerCallCleared returns if the "moderation_price_per_call" field was cleared in this mutation.
+func (m *GroupMutation) ModerationPricePerCallCleared() bool {
+	_, ok := m.clearedFields[group.FieldModerationPricePerCall]
+	return ok
+}
+
+// ResetModerationPricePerCall resets all changes to the "moderation_price_per_call" field.
+func (m *GroupMutation) ResetModerationPricePerCall() {
+	m.moderation_price_per_call = nil
+	m.addmoderation_price_per_call = nil
+	delete(m.clearedFields, group.FieldModerationPricePerCall)
+}
+
+// SetModerationPricePerMillionTokens sets the "moderation_price_per_million_tokens" field.
+func (m *GroupMutation) SetModerationPricePerMillionTokens(f float64) {
+	m.moderation_price_per_million_tokens = &f
+	m.addmoderation_price_per_million_tokens = nil
+}
+
+// ModerationPricePerMillionTokens returns the value of the "moderation_price_per_million_tokens" field in the mutation.
+func (m *GroupMutation) ModerationPricePerMillionTokens() (r float64, exists bool) {
+	v := m.moderation_price_per_million_tokens
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldModerationPricePerMillionTokens returns the old "moderation_price_per_million_tokens" field's value of the Group entity.
+// If the Group object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *GroupMutation) OldModerationPricePerMillionTokens(ctx context.Context) (v *float64, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldModerationPricePerMillionTokens is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldModerationPricePerMillionTokens requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldModerationPricePerMillionTokens: %w", err)
+	}
+	return oldValue.ModerationPricePerMillionTokens, nil
+}
+
+// AddModerationPricePerMillionTokens adds f to the "moderation_price_per_million_tokens" field.
+func (m *GroupMutation) AddModerationPricePerMillionTokens(f float64) {
+	if m.addmoderation_price_per_million_tokens != nil {
+		*m.addmoderation_price_per_million_tokens += f
+	} else {
+		m.addmoderation_price_per_million_tokens = &f
+	}
+}
+
+// AddedModerationPricePerMillionTokens returns the value that was added to the "moderation_price_per_million_tokens" field in this mutation.
+func (m *GroupMutation) AddedModerationPricePerMillionTokens() (r float64, exists bool) {
+	v := m.addmoderation_price_per_million_tokens
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// ClearModerationPricePerMillionTokens clears the value of the "moderation_price_per_million_tokens" field.
+func (m *GroupMutation) ClearModerationPricePerMillionTokens() {
+	m.moderation_price_per_million_tokens = nil
+	m.addmoderation_price_per_million_tokens = nil
+	m.clearedFields[group.FieldModerationPricePerMillionTokens] = struct{}{}
+}
+
+// ModerationPricePerMillionTokensCleared returns if the "moderation_price_per_million_tokens" field was cleared in this mutation.
+func (m *GroupMutation) ModerationPricePerMillionTokensCleared() bool {
+	_, ok := m.clearedFields[group.FieldModerationPricePerMillionTokens]
+	return ok
+}
+
+// ResetModerationPricePerMillionTokens resets all changes to the "moderation_price_per_million_tokens" field.
+func (m *GroupMutation) ResetModerationPricePerMillionTokens() {
+	m.moderation_price_per_million_tokens = nil
+	m.addmoderation_price_per_million_tokens = nil
+	delete(m.clearedFields, group.FieldModerationPricePerMillionTokens)
+}
+
 // SetLongContextPricingEnabled sets the "long_context_pricing_enabled" field.
 func (m *GroupMutation) SetLongContextPricingEnabled(b bool) {
 	m.long_context_pricing_enabled = &b
@@ -19292,8 +19551,8 @@ func (m *GroupMutation) ResetLongContextPricingEnabled() {
 }
 
 // SetModelPricing sets the "model_pricing" field.
-func (m *GroupMutation) SetModelPricing(jm json.RawMessage) {
-	m.model_pricing = &jm
+func (m *GroupMutation) SetModelPricing(j json.RawMessage) {
+	m.model_pricing = &j
 	m.appendmodel_pricing = nil
 }
 
@@ -19323,9 +19582,9 @@ func (m *GroupMutation) OldModelPricing(ctx context.Context) (v json.RawMessage,
 	return oldValue.ModelPricing, nil
 }
 
-// AppendModelPricing adds jm to the "model_pricing" field.
-func (m *GroupMutation) AppendModelPricing(jm json.RawMessage) {
-	m.appendmodel_pricing = append(m.appendmodel_pricing, jm...)
+// AppendModelPricing adds j to the "model_pricing" field.
+func (m *GroupMutation) AppendModelPricing(j json.RawMessage) {
+	m.appendmodel_pricing = append(m.appendmodel_pricing, j...)
 }
 
 // AppendedModelPricing returns the list of values that were appended to the "model_pricing" field in this mutation.
@@ -20303,6 +20562,206 @@ func (m *GroupMutation) ResetProfitSafetyBuffer() {
 	m.addprofit_safety_buffer = nil
 }
 
+// SetPromptPrefixRoutingEnabled sets the "prompt_prefix_routing_enabled" field.
+func (m *GroupMutation) SetPromptPrefixRoutingEnabled(b bool) {
+	m.prompt_prefix_routing_enabled = &b
+}
+
+// PromptPrefixRoutingEnabled returns the value of the "prompt_prefix_routing_enabled" field in the mutation.
+func (m *GroupMutation) PromptPrefixRoutingEnabled() (r bool, exists bool) {
+	v := m.prompt_prefix_routing_enabled
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldPromptPrefixRoutingEnabled returns the old "prompt_prefix_routing_enabled" field's value of the Group entity.
+// If the Group object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *GroupMutation) OldPromptPrefixRoutingEnabled(ctx context.Context) (v bool, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldPromptPrefixRoutingEnabled is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldPromptPrefixRoutingEnabled requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldPromptPrefixRoutingEnabled: %w", err)
+	}
+	return oldValue.PromptPrefixRoutingEnabled, nil
+}
+
+// ResetPromptPrefixRoutingEnabled resets all changes to the "prompt_prefix_routing_enabled" field.
+func (m *GroupMutation) ResetPromptPrefixRoutingEnabled() {
+	m.prompt_prefix_routing_enabled = nil
+}
+
+// SetPromptPrefixMessages sets the "prompt_prefix_messages" field.
+func (m *GroupMutation) SetPromptPrefixMessages(i int) {
+	m.prompt_prefix_messages = &i
+	m.addprompt_prefix_messages = nil
+}
+
+// PromptPrefixMessages returns the value of the "prompt_prefix_messages" field in the mutation.
+func (m *GroupMutation) PromptPrefixMessages() (r int, exists bool) {
+	v := m.prompt_prefix_messages
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldPromptPrefixMessages returns the old "prompt_prefix_messages" field's value of the Group entity.
+// If the Group object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *GroupMutation) OldPromptPrefixMessages(ctx context.Context) (v int, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldPromptPrefixMessages is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldPromptPrefixMessages requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldPromptPrefixMessages: %w", err)
+	}
+	return oldValue.PromptPrefixMessages, nil
+}
+
+// AddPromptPrefixMessages adds i to the "prompt_prefix_messages" field.
+func (m *GroupMutation) AddPromptPrefixMessages(i int) {
+	if m.addprompt_prefix_messages != nil {
+		*m.addprompt_prefix_messages += i
+	} else {
+		m.addprompt_prefix_messages = &i
+	}
+}
+
+// AddedPromptPrefixMessages returns the value that was added to the "prompt_prefix_messages" field in this mutation.
+func (m *GroupMutation) AddedPromptPrefixMessages() (r int, exists bool) {
+	v := m.addprompt_prefix_messages
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// ResetPromptPrefixMessages resets all changes to the "prompt_prefix_messages" field.
+func (m *GroupMutation) ResetPromptPrefixMessages() {
+	m.prompt_prefix_messages = nil
+	m.addprompt_prefix_messages = nil
+}
+
+// SetStreamResumeEnabled sets the "stream_resume_enabled" field.
+func (m *GroupMutation) SetStreamResumeEnabled(b bool) {
+	m.stream_resume_enabled = &b
+}
+
+// StreamResumeEnabled returns the value of the "stream_resume_enabled" field in the mutation.
+func (m *GroupMutation) StreamResumeEnabled() (r bool, exists bool) {
+	v := m.stream_resume_enabled
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldStreamResumeEnabled returns the old "stream_resume_enabled" field's value of the Group entity.
+// If the Group object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *GroupMutation) OldStreamResumeEnabled(ctx context.Context) (v bool, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldStreamResumeEnabled is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldStreamResumeEnabled requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldStreamResumeEnabled: %w", err)
+	}
+	return oldValue.StreamResumeEnabled, nil
+}
+
+// ResetStreamResumeEnabled resets all changes to the "stream_resume_enabled" field.
+func (m *GroupMutation) ResetStreamResumeEnabled() {
+	m.stream_resume_enabled = nil
+}
+
+// SetContextCompactionMode sets the "context_compaction_mode" field.
+func (m *GroupMutation) SetContextCompactionMode(s string) {
+	m.context_compaction_mode = &s
+}
+
+// ContextCompactionMode returns the value of the "context_compaction_mode" field in the mutation.
+func (m *GroupMutation) ContextCompactionMode() (r string, exists bool) {
+	v := m.context_compaction_mode
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldContextCompactionMode returns the old "context_compaction_mode" field's value of the Group entity.
+// If the Group object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *GroupMutation) OldContextCompactionMode(ctx context.Context) (v string, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldContextCompactionMode is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldContextCompactionMode requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldContextCompactionMode: %w", err)
+	}
+	return oldValue.ContextCompactionMode, nil
+}
+
+// ResetContextCompactionMode resets all changes to the "context_compaction_mode" field.
+func (m *GroupMutation) ResetContextCompactionMode() {
+	m.context_compaction_mode = nil
+}
+
+// SetContextCompactionModel sets the "context_compaction_model" field.
+func (m *GroupMutation) SetContextCompactionModel(s string) {
+	m.context_compaction_model = &s
+}
+
+// ContextCompactionModel returns the value of the "context_compaction_model" field in the mutation.
+func (m *GroupMutation) ContextCompactionModel() (r string, exists bool) {
+	v := m.context_compaction_model
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldContextCompactionModel returns the old "context_compaction_model" field's value of the Group entity.
+// If the Group object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *GroupMutation) OldContextCompactionModel(ctx context.Context) (v string, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldContextCompactionModel is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldContextCompactionModel requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldContextCompactionModel: %w", err)
+	}
+	return oldValue.ContextCompactionModel, nil
+}
+
+// ResetContextCompactionModel resets all changes to the "context_compaction_model" field.
+func (m *GroupMutation) ResetContextCompactionModel() {
+	m.context_compaction_model = nil
+}
+
 // AddAPIKeyIDs adds the "api_keys" edge to the APIKey entity by ids.
 func (m *GroupMutation) AddAPIKeyIDs(ids ...int64) {
 	if m.api_keys == nil {
@@ -20661,7 +21120,7 @@ func (m *GroupMutation) Type() string {
 // order to get all numeric fields that were incremented/decremented, call
 // AddedFields().
 func (m *GroupMutation) Fields() []string {
-	fields := make([]string, 0, 62)
+	fields := make([]string, 0, 69)
 	if m.created_at != nil {
 		fields = append(fields, group.FieldCreatedAt)
 	}
@@ -20779,6 +21238,12 @@ func (m *GroupMutation) Fields() []string {
 	if m.audio_stt_price_per_hour != nil {
 		fields = append(fields, group.FieldAudioSttPricePerHour)
 	}
+	if m.moderation_price_per_call != nil {
+		fields = append(fields, group.FieldModerationPricePerCall)
+	}
+	if m.moderation_price_per_million_tokens != nil {
+		fields = append(fields, group.FieldModerationPricePerMillionTokens)
+	}
 	if m.long_context_pricing_enabled != nil {
 		fields = append(fields, group.FieldLongContextPricingEnabled)
 	}
@@ -20848,6 +21313,21 @@ func (m *GroupMutation) Fields() []string {
 	if m.profit_safety_buffer != nil {
 		fields = append(fields, group.FieldProfitSafetyBuffer)
 	}
+	if m.prompt_prefix_routing_enabled != nil {
+		fields = append(fields, group.FieldPromptPrefixRoutingEnabled)
+	}
+	if m.prompt_prefix_messages != nil {
+		fields = append(fields, group.FieldPromptPrefixMessages)
+	}
+	if m.stream_resume_enabled != nil {
+		fields = append(fields, group.FieldStreamResumeEnabled)
+	}
+	if m.context_compaction_mode != nil {
+		fields = append(fields, group.FieldContextCompactionMode)
+	}
+	if m.context_compaction_model != nil {
+		fields = append(fields, group.FieldContextCompactionModel)
+	}
 	return fields
 }
 
@@ -20934,6 +21414,10 @@ func (m *GroupMutation) Field(name string) (ent.Value, bool) {
 		return m.AudioTtsPricePerMillionChars()
 	case group.FieldAudioSttPricePerHour:
 		return m.AudioSttPricePerHour()
+	case group.FieldModerationPricePerCall:
+		return m.ModerationPricePerCall()
+	case group.FieldModerationPricePerMillionTokens:
+		return m.ModerationPricePerMillionTokens()
 	case group.FieldLongContextPricingEnabled:
 		return m.LongContextPricingEnabled()
 	case group.FieldModelPricing:
@@ -20980,6 +21464,16 @@ func (m *GroupMutation) Field(name string) (ent.Value, bool) {
 		return m.ProfitMinMargin()
 	case group.FieldProfitSafetyBuffer:
 		return m.ProfitSafetyBuffer()
+	case group.FieldPromptPrefixRoutingEnabled:
+		return m.PromptPrefixRoutingEnabled()
+	case group.FieldPromptPrefixMessages:
+		return m.PromptPrefixMessages()
+	case group.FieldStreamResumeEnabled:
+		return m.StreamResumeEnabled()
+	case group.FieldContextCompactionMode:
+		return m.ContextCompactionMode()
+	case group.FieldContextCompactionModel:
+		return m.ContextCompactionModel()
 	}
 	return nil, false
 }
@@ -21067,6 +21561,10 @@ func (m *GroupMutation) OldField(ctx context.Context, name string) (ent.Value, e
 		return m.OldAudioTtsPricePerMillionChars(ctx)
 	case group.FieldAudioSttPricePerHour:
 		return m.OldAudioSttPricePerHour(ctx)
+	case group.FieldModerationPricePerCall:
+		return m.OldModerationPricePerCall(ctx)
+	case group.FieldModerationPricePerMillionTokens:
+		return m.OldModerationPricePerMillionTokens(ctx)
 	case group.FieldLongContextPricingEnabled:
 		return m.OldLongContextPricingEnabled(ctx)
 	case group.FieldModelPricing:
@@ -21113,6 +21611,16 @@ func (m *GroupMutation) OldField(ctx context.Context, name string) (ent.Value, e
 		return m.OldProfitMinMargin(ctx)
 	case group.FieldProfitSafetyBuffer:
 		return m.OldProfitSafetyBuffer(ctx)
+	case group.FieldPromptPrefixRoutingEnabled:
+		return m.OldPromptPrefixRoutingEnabled(ctx)
+	case group.FieldPromptPrefixMessages:
+		return m.OldPromptPrefixMessages(ctx)
+	case group.FieldStreamResumeEnabled:
+		return m.OldStreamResumeEnabled(ctx)
+	case group.FieldContextCompactionMode:
+		return m.OldContextCompactionMode(ctx)
+	case group.FieldContextCompactionModel:
+		return m.OldContextCompactionModel(ctx)
 	}
 	return nil, fmt.Errorf("unknown Group field %s", name)
 }
@@ -21395,6 +21903,20 @@ func (m *GroupMutation) SetField(name string, value ent.Value) error {
 		}
 		m.SetAudioSttPricePerHour(v)
 		return nil
+	case group.FieldModerationPricePerCall:
+		v, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetModerationPricePerCall(v)
+		return nil
+	case group.FieldModerationPricePerMillionTokens:
+		v, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetModerationPricePerMillionTokens(v)
+		return nil
 	case group.FieldLongContextPricingEnabled:
 		v, ok := value.(bool)
 		if !ok {
@@ -21556,6 +22078,41 @@ func (m *GroupMutation) SetField(name string, value ent.Value) error {
 		}
 		m.SetProfitSafetyBuffer(v)
 		return nil
+	case group.FieldPromptPrefixRoutingEnabled:
+		v, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetPromptPrefixRoutingEnabled(v)
+		return nil
+	case group.FieldPromptPrefixMessages:
+		v, ok := value.(int)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetPromptPrefixMessages(v)
+		return nil
+	case group.FieldStreamResumeEnabled:
+		v, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetStreamResumeEnabled(v)
+		return nil
+	case group.FieldContextCompactionMode:
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetContextCompactionMode(v)
+		return nil
+	case group.FieldContextCompactionModel:
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetContextCompactionModel(v)
+		return nil
 	}
 	return fmt.Errorf("unknown Group field %s", name)
 }
@@ -21627,6 +22184,12 @@ func (m *GroupMutation) AddedFields() []string {
 	if m.addaudio_stt_price_per_hour != nil {
 		fields = append(fields, group.FieldAudioSttPricePerHour)
 	}
+	if m.addmoderation_price_per_call != nil {
+		fields = append(fields, group.FieldModerationPricePerCall)
+	}
+	if m.addmoderation_price_per_million_tokens != nil {
+		fields = append(fields, group.FieldModerationPricePerMillionTokens)
+	}
 	if m.addfallback_group_id != nil {
 		fields = append(fields, group.FieldFallbackGroupID)
 	}
@@ -21645,6 +22208,9 @@ func (m *GroupMutation) AddedFields() []string {
 	if m.addprofit_safety_buffer != nil {
 		fields = append(fields, group.FieldProfitSafetyBuffer)
 	}
+	if m.addprompt_prefix_messages != nil {
+		fields = append(fields, group.FieldPromptPrefixMessages)
+	}
 	return fields
 }
 
@@ -21695,6 +22261,10 @@ func (m *GroupMutation) AddedField(name string) (ent.Value, bool) {
 		return m.AddedAudioTtsPricePerMillionChars()
 	case group.FieldAudioSttPricePerHour:
 		return m.AddedAudioSttPricePerHour()
+	case group.FieldModerationPricePerCall:
+		return m.AddedModerationPricePerCall()
+	case group.FieldModerationPricePerMillionTokens:
+		return m.AddedModerationPricePerMillionTokens()
 	case group.FieldFallbackGroupID:
 		return m.AddedFallbackGroupID()
 	case group.FieldFallbackGroupIDOnInvalidRequest:
@@ -21707,6 +22277,8 @@ func (m *GroupMutation) AddedField(name string) (ent.Value, bool) {
 		return m.AddedProfitMinMargin()
 	case group.FieldProfitSafetyBuffer:
 		return m.AddedProfitSafetyBuffer()
+	case group.FieldPromptPrefixMessages:
+		return m.AddedPromptPrefixMessages()
 	}
 	return nil, false
 }
@@ -21863,6 +22435,20 @@ func (m *GroupMutation) AddField(name string, value ent.Value) error {
 		}
 		m.AddAudioSttPricePerHour(v)
 		return nil
+	case group.FieldModerationPricePerCall:
+		v, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.AddModerationPricePerCall(v)
+		return nil
+	case group.FieldModerationPricePerMillionTokens:
+		v, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.AddModerationPricePerMillionTokens(v)
+		return nil
 	case group.FieldFallbackGroupID:
 		v, ok := value.(int64)
 		if !ok {
@@ -21905,6 +22491,13 @@ func (m *GroupMutation) AddField(name string, value ent.Value) error {
 		}
 		m.AddProfitSafetyBuffer(v)
 		return nil
+	case group.FieldPromptPrefixMessages:
+		v, ok := value.(int)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.AddPromptPrefixMessages(v)
+		return nil
 	}
 	return fmt.Errorf("unknown Group numeric field %s", name)
 }
@@ -21967,6 +22560,12 @@ func (m *GroupMutation) ClearedFields() []string {
 	if m.FieldCleared(group.FieldAudioSttPricePerHour) {
 		fields = append(fields, group.FieldAudioSttPricePerHour)
 	}
+	if m.FieldCleared(group.FieldModerationPricePerCall) {
+		fields = append(fields, group.FieldModerationPricePerCall)
+	}
+	if m.FieldCleared(group.FieldModerationPricePerMillionTokens) {
+		fields = append(fields, group.FieldModerationPricePerMillionTokens)
+	}
 	if m.FieldCleared(group.FieldModelPricing) {
 		fields = append(fields, group.FieldModelPricing)
 	}
@@ -22047,6 +22646,12 @@ func (m *GroupMutation) ClearField(name string) error {
 	case group.FieldAudioSttPricePerHour:
 		m.ClearAudioSttPricePerHour()
 		return nil
+	case group.FieldModerationPricePerCall:
+		m.ClearModerationPricePerCall()
+		return nil
+	case group.FieldModerationPricePerMillionTokens:
+		m.ClearModerationPricePerMillionTokens()
+		return nil
 	case group.FieldModelPricing:
 		m.ClearModelPricing()
 		return nil
@@ -22184,6 +22789,12 @@ func (m *GroupMutation) ResetField(name string) error {
 	case group.FieldAudioSttPricePerHour:
 		m.ResetAudioSttPricePerHour()
 		return nil
+	case group.FieldModerationPricePerCall:
+		m.ResetModerationPricePerCall()
+		return nil
+	case group.FieldModerationPricePerMillionTokens:
+		m.ResetModerationPricePerMillionTokens()
+		return nil
 	case group.FieldLongContextPricingEnabled:
 		m.ResetLongContextPricingEnabled()
 		return nil
@@ -22253,6 +22864,21 @@ func (m *GroupMutation) ResetField(name string) error {
 	case group.FieldProfitSafetyBuffer:
 		m.ResetProfitSafetyBuffer()
 		return nil
+	case group.FieldPromptPrefixRoutingEnabled:
+		m.ResetPromptPrefixRoutingEnabled()
+		return nil
+	case group.FieldPromptPrefixMessages:
+		m.ResetPromptPrefixMessages()
+		return nil
+	case group.FieldStreamResumeEnabled:
+		m.ResetStreamResumeEnabled()
+		return nil
+	case group.FieldContextCompactionMode:
+		m.ResetContextCompactionMode()
+		return nil
+	case group.FieldContextCompactionModel:
+		m.ResetContextCompactionModel()
+		return nil
 	}
 	return fmt.Errorf("unknown Group field %s", name)
 }
@@ -35353,6 +35979,7 @@ type TLSFingerprintProfileMutation struct {
 	appendpsk_modes            []uint16
 	extensions                 *[]uint16
 	appendextensions           []uint16
+	http2                      **tlsfingerprint.HTTP2Profile
 	clearedFields              map[string]struct{}
 	done                       bool
 	oldValue                   func(context.Context) (*TLSFingerprintProfile, error)
@@ -36235,6 +36862,55 @@ func (m *TLSFingerprintProfileMutation) ResetExtensions() {
 	delete(m.clearedFields, tlsfingerprintprofile.FieldExtensions)
 }
 
+// SetHttp2 sets the "http2" field.
+func (m *TLSFingerprintProfileMutation) SetHttp2(t *tlsfingerprint.HTTP2Profile) {
+	m.http2 = &t
+}
+
+// Http2 returns the value of the "http2" field in the mutation.
+func (m *TLSFingerprintProfileMutation) Http2() (r *tlsfingerprint.HTTP2Profile, exists bool) {
+	v := m.http2
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldHttp2 returns the old "http2" field's value of the TLSFingerprintProfile entity.
+// If the TLSFingerprintProfile object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *TLSFingerprintProfileMutation) OldHttp2(ctx context.Context) (v *tlsfingerprint.HTTP2Profile, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldHttp2 is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldHttp2 requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldHttp2: %w", err)
+	}
+	return oldValue.Http2, nil
+}
+
+// ClearHttp2 clears the value of the "http2" field.
+func (m *TLSFingerprintProfileMutation) ClearHttp2() {
+	m.http2 = nil
+	m.clearedFields[tlsfingerprintprofile.FieldHttp2] = struct{}{}
+}
+
+// Http2Cleared returns if the "http2" field was cleared in this mutation.
+func (m *TLSFingerprintProfileMutation) Http2Cleared() bool {
+	_, ok := m.clearedFields[tlsfingerprintprofile.FieldHttp2]
+	return ok
+}
+
+// ResetHttp2 resets all changes to the "http2" field.
+func (m *TLSFingerprintProfileMutation) ResetHttp2() {
+	m.http2 = nil
+	delete(m.clearedFields, tlsfingerprintprofile.FieldHttp2)
+}
+
 // Where appends a list predicates to the TLSFingerprintProfileMutation builder.
 func (m *TLSFingerprintProfileMutation) Where(ps ...predicate.TLSFingerprintProfile) {
 	m.predicates = append(m.predicates, ps...)
@@ -36269,7 +36945,7 @@ func (m *TLSFingerprintProfileMutation) Type() string {
 // order to get all numeric fields that were incremented/decremented, call
 // AddedFields().
 func (m *TLSFingerprintProfileMutation) Fields() []string {
-	fields := make([]string, 0, 14)
+	fields := make([]string, 0, 15)
 	if m.created_at != nil {
 		fields = append(fields, tlsfingerprintprofile.FieldCreatedAt)
 	}
@@ -36312,6 +36988,9 @@ func (m *TLSFingerprintProfileMutation) Fields() []string {
 	if m.extensions != nil {
 		fields = append(fields, tlsfingerprintprofile.FieldExtensions)
 	}
+	if m.http2 != nil {
+		fields = append(fields, tlsfingerprintprofile.FieldHttp2)
+	}
 	return fields
 }
 
@@ -36348,6 +37027,8 @@ func (m *TLSFingerprintProfileMutation) Field(name string) (ent.Value, bool) {
 		return m.PskModes()
 	case tlsfingerprintprofile.FieldExtensions:
 		return m.Extensions()
+	case tlsfingerprintprofile.FieldHttp2:
+		return m.Http2()
 	}
 	return nil, false
 }
@@ -36385,6 +37066,8 @@ func (m *TLSFingerprintProfileMutation) OldField(ctx context.Context, name strin
 		return m.OldPskModes(ctx)
 	case tlsfingerprintprofile.FieldExtensions:
 		return m.OldExtensions(ctx)
+	case tlsfingerprintprofile.FieldHttp2:
+		return m.OldHttp2(ctx)
 	}
 	return nil, fmt.Errorf("unknown TLSFingerprintProfile field %s", name)
 }
@@ -36492,6 +37175,13 @@ func (m *TLSFingerprintProfileMutation) SetField(name string, value ent.Value) e
 		}
 		m.SetExtensions(v)
 		return nil
+	case tlsfingerprintprofile.FieldHttp2:
+		v, ok := value.(*tlsfingerprint.HTTP2Profile)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetHttp2(v)
+		return nil
 	}
 	return fmt.Errorf("unknown TLSFingerprintProfile field %s", name)
 }
@@ -36552,6 +37242,9 @@ func (m *TLSFingerprintProfileMutation) ClearedFields() []string {
 	if m.FieldCleared(tlsfingerprintprofile.FieldExtensions) {
 		fields = append(fields, tlsfingerprintprofile.FieldExtensions)
 	}
+	if m.FieldCleared(tlsfingerprintprofile.FieldHttp2) {
+		fields = append(fields, tlsfingerprintprofile.FieldHttp2)
+	}
 	return fields
 }
 
@@ -36596,6 +37289,9 @@ func (m *TLSFingerprintProfileMutation) ClearField(name string) error {
 	case tlsfingerprintprofile.FieldExtensions:
 		m.ClearExtensions()
 		return nil
+	case tlsfingerprintprofile.FieldHttp2:
+		m.ClearHttp2()
+		return nil
 	}
 	return fmt.Errorf("unknown TLSFingerprintProfile nullable field %s", name)
 }
@@ -36646,6 +37342,9 @@ func (m *TLSFingerprintProfileMutation) ResetField(name string) error {
 	case tlsfingerprintprofile.FieldExtensions:
 		m.ResetExtensions()
 		return nil
+	case tlsfingerprintprofile.FieldHttp2:
+		m.ResetHttp2()
+		return nil
 	}
 	return fmt.Errorf("unknown TLSFingerprintProfile field %s", name)
 }
@@ -36932,8 +37631,8 @@ func (m *UsageCleanupTaskMutation) ResetStatus() {
 }
 
 // SetFilters sets the "filters" field.
-func (m *UsageCleanupTaskMutation) SetFilters(jm json.RawMessage) {
-	m.filters = &jm
+func (m *UsageCleanupTaskMutation) SetFilters(j json.RawMessage) {
+	m.filters = &j
 	m.appendfilters = nil
 }
 
@@ -36963,9 +37662,9 @@ func (m *UsageCleanupTaskMutation) OldFilters(ctx context.Context) (v json.RawMe
 	return oldValue.Filters, nil
 }
 
-// AppendFilters adds jm to the "filters" field.
-func (m *UsageCleanupTaskMutation) AppendFilters(jm json.RawMessage) {
-	m.appendfilters = append(m.appendfilters, jm...)
+// AppendFilters adds j to the "filters" field.
+func (m *UsageCleanupTaskMutation) AppendFilters(j json.RawMessage) {
+	m.appendfilters = append(m.appendfilters, j...)
 }
 
 // AppendedFilters returns the list of values that were appended to the "filters" field in this mutation.
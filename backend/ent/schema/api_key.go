@@ -37,10 +37,19 @@ func (APIKey) Fields() []ent.Field {
 		field.String("key").
 			MaxLen(128).
 			NotEmpty().
-			Unique(),
+			Unique().
+			Comment("HMAC-SHA256 of the key (hmac-sha256:<hex>); legacy rows may still hold plaintext until backfilled"),
+		field.String("key_prefix").
+			MaxLen(16).
+			Default("").
+			Comment("Leading characters of the key for display; the full key is only revealed at creation"),
 		field.String("name").
 			MaxLen(100).
 			NotEmpty(),
+		field.String("token_nonce").
+			MaxLen(64).
+			Default("").
+			Comment("Signing nonce for short-lived scoped tokens derived from this key; rotating it revokes every outstanding token"),
 		field.Int64("group_id").
 			Optional().
 			Nillable(),
@@ -187,6 +187,20 @@ func (Group) Fields() []ent.Field {
 			Min(0).
 			SchemaType(map[string]string{dialect.Postgres: "decimal(20,8)"}).
 			Comment("STT 每小时价格（USD）"),
+
+		// /v1/moderations 对外审计定价：按次 + 按输入 token，两者可叠加。
+		field.Float("moderation_price_per_call").
+			Optional().
+			Nillable().
+			Min(0).
+			SchemaType(map[string]string{dialect.Postgres: "decimal(20,8)"}).
+			Comment("内容审计每次调用价格（USD/次）；nil 表示免费"),
+		field.Float("moderation_price_per_million_tokens").
+			Optional().
+			Nillable().
+			Min(0).
+			SchemaType(map[string]string{dialect.Postgres: "decimal(20,8)"}).
+			Comment("内容审计每百万输入 token 价格（USD）；nil 表示免费"),
 		field.Bool("long_context_pricing_enabled").
 			Default(true).
 			Comment("是否按上下文长度应用模型阶梯价格；默认开启以保持官方/渠道长上下文价"),
@@ -289,6 +303,29 @@ func (Group) Fields() []ent.Field {
 			SchemaType(map[string]string{dialect.Postgres: "decimal(10,4)"}).
 			Default(0).
 			Comment("安全缓冲，小数；与 margin 相加后从下游倍率中扣除，默认 0"),
+
+		// 提示词前缀一致性哈希路由（migration 224）
+		field.Bool("prompt_prefix_routing_enabled").
+			Default(false).
+			Comment("是否按可缓存提示词前缀一致性哈希选择账号，提高上游 prompt cache 命中率"),
+		field.Int("prompt_prefix_messages").
+			Default(0).
+			Comment("参与前缀哈希的前 N 条消息，0 表示仅 tools + system"),
+
+		// 流式断线续传（migration 231）
+		field.Bool("stream_resume_enabled").
+			Default(false).
+			Comment("是否为流式请求缓冲事件，允许客户端断线后凭 Last-Event-ID 续传"),
+
+		// 上下文窗口溢出压缩（migration 232）
+		field.String("context_compaction_mode").
+			MaxLen(20).
+			Default("").
+			Comment("超出模型上下文窗口时的处理方式：空/off 不处理，truncate 丢弃最早轮次，summarize 摘要最早轮次"),
+		field.String("context_compaction_model").
+			MaxLen(100).
+			Default("").
+			Comment("summarize 模式使用的摘要模型（同分组账号池）"),
 	}
 }
 
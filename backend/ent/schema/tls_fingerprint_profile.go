@@ -3,6 +3,7 @@ package schema
 
 import (
 	"github.com/Wei-Shaw/sub2api/ent/schema/mixins"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tlsfingerprint"
 
 	"entgo.io/ent"
 	"entgo.io/ent/dialect"
@@ -96,5 +97,10 @@ func (TLSFingerprintProfile) Fields() []ent.Field {
 		field.JSON("extensions", []uint16{}).
 			Optional().
 			SchemaType(map[string]string{dialect.Postgres: "jsonb"}),
+
+		// http2: HTTP/2 指纹（SETTINGS 顺序、WINDOW_UPDATE、PRIORITY、伪头顺序），仅在 ALPN 协商到 h2 时生效
+		field.JSON("http2", &tlsfingerprint.HTTP2Profile{}).
+			Optional().
+			SchemaType(map[string]string{dialect.Postgres: "jsonb"}),
 	}
 }
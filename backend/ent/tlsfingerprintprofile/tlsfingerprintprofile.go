@@ -41,6 +41,8 @@ const (
 	FieldPskModes = "psk_modes"
 	// FieldExtensions holds the string denoting the extensions field in the database.
 	FieldExtensions = "extensions"
+	// FieldHttp2 holds the string denoting the http2 field in the database.
+	FieldHttp2 = "http2"
 	// Table holds the table name of the tlsfingerprintprofile in the database.
 	Table = "tls_fingerprint_profiles"
 )
@@ -62,6 +64,7 @@ var Columns = []string{
 	FieldKeyShareGroups,
 	FieldPskModes,
 	FieldExtensions,
+	FieldHttp2,
 }
 
 // ValidColumn reports if the column name is valid (part of the table columns).
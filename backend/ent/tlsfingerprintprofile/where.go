@@ -399,6 +399,16 @@ func ExtensionsNotNil() predicate.TLSFingerprintProfile {
 	return predicate.TLSFingerprintProfile(sql.FieldNotNull(FieldExtensions))
 }
 
+// Http2IsNil applies the IsNil predicate on the "http2" field.
+func Http2IsNil() predicate.TLSFingerprintProfile {
+	return predicate.TLSFingerprintProfile(sql.FieldIsNull(FieldHttp2))
+}
+
+// Http2NotNil applies the NotNil predicate on the "http2" field.
+func Http2NotNil() predicate.TLSFingerprintProfile {
+	return predicate.TLSFingerprintProfile(sql.FieldNotNull(FieldHttp2))
+}
+
 // And groups predicates with the AND operator between them.
 func And(predicates ...predicate.TLSFingerprintProfile) predicate.TLSFingerprintProfile {
 	return predicate.TLSFingerprintProfile(sql.AndPredicates(predicates...))
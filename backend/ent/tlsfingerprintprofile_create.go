@@ -12,6 +12,7 @@ import (
 	"entgo.io/ent/dialect/sql/sqlgraph"
 	"entgo.io/ent/schema/field"
 	"github.com/Wei-Shaw/sub2api/ent/tlsfingerprintprofile"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tlsfingerprint"
 )
 
 // TLSFingerprintProfileCreate is the builder for creating a TLSFingerprintProfile entity.
@@ -138,6 +139,12 @@ func (_c *TLSFingerprintProfileCreate) SetExtensions(v []uint16) *TLSFingerprint
 	return _c
 }
 
+// SetHttp2 sets the "http2" field.
+func (_c *TLSFingerprintProfileCreate) SetHttp2(v *tlsfingerprint.HTTP2Profile) *TLSFingerprintProfileCreate {
+	_c.mutation.SetHttp2(v)
+	return _c
+}
+
 // Mutation returns the TLSFingerprintProfileMutation object of the builder.
 func (_c *TLSFingerprintProfileCreate) Mutation() *TLSFingerprintProfileMutation {
 	return _c.mutation
@@ -206,6 +213,11 @@ func (_c *TLSFingerprintProfileCreate) check() error {
 	if _, ok := _c.mutation.EnableGrease(); !ok {
 		return &ValidationError{Name: "enable_grease", err: errors.New(`ent: missing required field "TLSFingerprintProfile.enable_grease"`)}
 	}
+	if v, ok := _c.mutation.Http2(); ok {
+		if err := v.Validate(); err != nil {
+			return &ValidationError{Name: "http2", err: fmt.Errorf(`ent: validator failed for field "TLSFingerprintProfile.http2": %w`, err)}
+		}
+	}
 	return nil
 }
 
@@ -289,6 +301,10 @@ func (_c *TLSFingerprintProfileCreate) createSpec() (*TLSFingerprintProfile, *sq
 		_spec.SetField(tlsfingerprintprofile.FieldExtensions, field.TypeJSON, value)
 		_node.Extensions = value
 	}
+	if value, ok := _c.mutation.Http2(); ok {
+		_spec.SetField(tlsfingerprintprofile.FieldHttp2, field.TypeJSON, value)
+		_node.Http2 = value
+	}
 	return _node, _spec
 }
 
@@ -557,6 +573,24 @@ func (u *TLSFingerprintProfileUpsert) ClearExtensions() *TLSFingerprintProfileUp
 	return u
 }
 
+// SetHttp2 sets the "http2" field.
+func (u *TLSFingerprintProfileUpsert) SetHttp2(v *tlsfingerprint.HTTP2Profile) *TLSFingerprintProfileUpsert {
+	u.Set(tlsfingerprintprofile.FieldHttp2, v)
+	return u
+}
+
+// UpdateHttp2 sets the "http2" field to the value that was provided on create.
+func (u *TLSFingerprintProfileUpsert) UpdateHttp2() *TLSFingerprintProfileUpsert {
+	u.SetExcluded(tlsfingerprintprofile.FieldHttp2)
+	return u
+}
+
+// ClearHttp2 clears the value of the "http2" field.
+func (u *TLSFingerprintProfileUpsert) ClearHttp2() *TLSFingerprintProfileUpsert {
+	u.SetNull(tlsfingerprintprofile.FieldHttp2)
+	return u
+}
+
 // UpdateNewValues updates the mutable fields using the new values that were set on create.
 // Using this option is equivalent to using:
 //
@@ -854,6 +888,27 @@ func (u *TLSFingerprintProfileUpsertOne) ClearExtensions() *TLSFingerprintProfil
 	})
 }
 
+// SetHttp2 sets the "http2" field.
+func (u *TLSFingerprintProfileUpsertOne) SetHttp2(v *tlsfingerprint.HTTP2Profile) *TLSFingerprintProfileUpsertOne {
+	return u.Update(func(s *TLSFingerprintProfileUpsert) {
+		s.SetHttp2(v)
+	})
+}
+
+// UpdateHttp2 sets the "http2" field to the value that was provided on create.
+func (u *TLSFingerprintProfileUpsertOne) UpdateHttp2() *TLSFingerprintProfileUpsertOne {
+	return u.Update(func(s *TLSFingerprintProfileUpsert) {
+		s.UpdateHttp2()
+	})
+}
+
+// ClearHttp2 clears the value of the "http2" field.
+func (u *TLSFingerprintProfileUpsertOne) ClearHttp2() *TLSFingerprintProfileUpsertOne {
+	return u.Update(func(s *TLSFingerprintProfileUpsert) {
+		s.ClearHttp2()
+	})
+}
+
 // Exec executes the query.
 func (u *TLSFingerprintProfileUpsertOne) Exec(ctx context.Context) error {
 	if len(u.create.conflict) == 0 {
@@ -1317,6 +1372,27 @@ func (u *TLSFingerprintProfileUpsertBulk) ClearExtensions() *TLSFingerprintProfi
 	})
 }
 
+// SetHttp2 sets the "http2" field.
+func (u *TLSFingerprintProfileUpsertBulk) SetHttp2(v *tlsfingerprint.HTTP2Profile) *TLSFingerprintProfileUpsertBulk {
+	return u.Update(func(s *TLSFingerprintProfileUpsert) {
+		s.SetHttp2(v)
+	})
+}
+
+// UpdateHttp2 sets the "http2" field to the value that was provided on create.
+func (u *TLSFingerprintProfileUpsertBulk) UpdateHttp2() *TLSFingerprintProfileUpsertBulk {
+	return u.Update(func(s *TLSFingerprintProfileUpsert) {
+		s.UpdateHttp2()
+	})
+}
+
+// ClearHttp2 clears the value of the "http2" field.
+func (u *TLSFingerprintProfileUpsertBulk) ClearHttp2() *TLSFingerprintProfileUpsertBulk {
+	return u.Update(func(s *TLSFingerprintProfileUpsert) {
+		s.ClearHttp2()
+	})
+}
+
 // Exec executes the query.
 func (u *TLSFingerprintProfileUpsertBulk) Exec(ctx context.Context) error {
 	if u.create.err != nil {
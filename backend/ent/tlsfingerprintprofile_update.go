@@ -14,6 +14,7 @@ import (
 	"entgo.io/ent/schema/field"
 	"github.com/Wei-Shaw/sub2api/ent/predicate"
 	"github.com/Wei-Shaw/sub2api/ent/tlsfingerprintprofile"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tlsfingerprint"
 )
 
 // TLSFingerprintProfileUpdate is the builder for updating TLSFingerprintProfile entities.
@@ -245,6 +246,18 @@ func (_u *TLSFingerprintProfileUpdate) ClearExtensions() *TLSFingerprintProfileU
 	return _u
 }
 
+// SetHttp2 sets the "http2" field.
+func (_u *TLSFingerprintProfileUpdate) SetHttp2(v *tlsfingerprint.HTTP2Profile) *TLSFingerprintProfileUpdate {
+	_u.mutation.SetHttp2(v)
+	return _u
+}
+
+// ClearHttp2 clears the value of the "http2" field.
+func (_u *TLSFingerprintProfileUpdate) ClearHttp2() *TLSFingerprintProfileUpdate {
+	_u.mutation.ClearHttp2()
+	return _u
+}
+
 // Mutation returns the TLSFingerprintProfileMutation object of the builder.
 func (_u *TLSFingerprintProfileUpdate) Mutation() *TLSFingerprintProfileMutation {
 	return _u.mutation
@@ -293,6 +306,11 @@ func (_u *TLSFingerprintProfileUpdate) check() error {
 			return &ValidationError{Name: "name", err: fmt.Errorf(`ent: validator failed for field "TLSFingerprintProfile.name": %w`, err)}
 		}
 	}
+	if v, ok := _u.mutation.Http2(); ok {
+		if err := v.Validate(); err != nil {
+			return &ValidationError{Name: "http2", err: fmt.Errorf(`ent: validator failed for field "TLSFingerprintProfile.http2": %w`, err)}
+		}
+	}
 	return nil
 }
 
@@ -422,6 +440,12 @@ func (_u *TLSFingerprintProfileUpdate) sqlSave(ctx context.Context) (_node int,
 	if _u.mutation.ExtensionsCleared() {
 		_spec.ClearField(tlsfingerprintprofile.FieldExtensions, field.TypeJSON)
 	}
+	if value, ok := _u.mutation.Http2(); ok {
+		_spec.SetField(tlsfingerprintprofile.FieldHttp2, field.TypeJSON, value)
+	}
+	if _u.mutation.Http2Cleared() {
+		_spec.ClearField(tlsfingerprintprofile.FieldHttp2, field.TypeJSON)
+	}
 	if _node, err = sqlgraph.UpdateNodes(ctx, _u.driver, _spec); err != nil {
 		if _, ok := err.(*sqlgraph.NotFoundError); ok {
 			err = &NotFoundError{tlsfingerprintprofile.Label}
@@ -658,6 +682,18 @@ func (_u *TLSFingerprintProfileUpdateOne) ClearExtensions() *TLSFingerprintProfi
 	return _u
 }
 
+// SetHttp2 sets the "http2" field.
+func (_u *TLSFingerprintProfileUpdateOne) SetHttp2(v *tlsfingerprint.HTTP2Profile) *TLSFingerprintProfileUpdateOne {
+	_u.mutation.SetHttp2(v)
+	return _u
+}
+
+// ClearHttp2 clears the value of the "http2" field.
+func (_u *TLSFingerprintProfileUpdateOne) ClearHttp2() *TLSFingerprintProfileUpdateOne {
+	_u.mutation.ClearHttp2()
+	return _u
+}
+
 // Mutation returns the TLSFingerprintProfileMutation object of the builder.
 func (_u *TLSFingerprintProfileUpdateOne) Mutation() *TLSFingerprintProfileMutation {
 	return _u.mutation
@@ -719,6 +755,11 @@ func (_u *TLSFingerprintProfileUpdateOne) check() error {
 			return &ValidationError{Name: "name", err: fmt.Errorf(`ent: validator failed for field "TLSFingerprintProfile.name": %w`, err)}
 		}
 	}
+	if v, ok := _u.mutation.Http2(); ok {
+		if err := v.Validate(); err != nil {
+			return &ValidationError{Name: "http2", err: fmt.Errorf(`ent: validator failed for field "TLSFingerprintProfile.http2": %w`, err)}
+		}
+	}
 	return nil
 }
 
@@ -865,6 +906,12 @@ func (_u *TLSFingerprintProfileUpdateOne) sqlSave(ctx context.Context) (_node *T
 	if _u.mutation.ExtensionsCleared() {
 		_spec.ClearField(tlsfingerprintprofile.FieldExtensions, field.TypeJSON)
 	}
+	if value, ok := _u.mutation.Http2(); ok {
+		_spec.SetField(tlsfingerprintprofile.FieldHttp2, field.TypeJSON, value)
+	}
+	if _u.mutation.Http2Cleared() {
+		_spec.ClearField(tlsfingerprintprofile.FieldHttp2, field.TypeJSON)
+	}
 	_node = &TLSFingerprintProfile{config: _u.config}
 	_spec.Assign = _node.assignValues
 	_spec.ScanValues = _node.scanValues
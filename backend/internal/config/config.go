@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log/slog"
 	"math"
+	"net"
 	"net/textproto"
 	"net/url"
 	"os"
@@ -78,6 +79,7 @@ type Config struct {
 	WeChat                  WeChatConnectConfig           `mapstructure:"wechat_connect"`
 	OIDC                    OIDCConnectConfig             `mapstructure:"oidc_connect"`
 	DingTalk                DingTalkConnectConfig         `mapstructure:"dingtalk_connect"`
+	SAML                    SAMLConfig                    `mapstructure:"saml"`
 	GitHub                  GitHubOAuthConfig             `mapstructure:"github_oauth"`
 	GoogleOAuth             EmailOAuthProviderConfig      `mapstructure:"google_oauth"`
 	Default                 DefaultConfig                 `mapstructure:"default"`
@@ -90,6 +92,7 @@ type Config struct {
 	Dashboard               DashboardCacheConfig          `mapstructure:"dashboard_cache"`
 	DashboardAgg            DashboardAggregationConfig    `mapstructure:"dashboard_aggregation"`
 	UsageCleanup            UsageCleanupConfig            `mapstructure:"usage_cleanup"`
+	UserData                UserDataConfig                `mapstructure:"user_data"`
 	Concurrency             ConcurrencyConfig             `mapstructure:"concurrency"`
 	TokenRefresh            TokenRefreshConfig            `mapstructure:"token_refresh"`
 	RunMode                 string                        `mapstructure:"run_mode" yaml:"run_mode"`
@@ -351,6 +354,41 @@ type OIDCConnectConfig struct {
 	UserInfoUsernamePath string `mapstructure:"userinfo_username_path"`
 }
 
+// SAMLConfig SAML 2.0 SSO（SP 侧）配置，仅支持配置文件 / 环境变量。
+type SAMLConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	ProviderName string `mapstructure:"provider_name"` // 显示名: "Okta" / "ADFS" 等
+
+	// SP 自身信息
+	EntityID            string `mapstructure:"entity_id"`             // 为空时使用 metadata 地址
+	ACSURL              string `mapstructure:"acs_url"`               // 后端 ACS 地址（需在 IdP 登记）：.../api/v1/auth/oauth/saml/acs
+	MetadataURL         string `mapstructure:"metadata_url"`          // SP metadata 地址：.../api/v1/auth/oauth/saml/metadata
+	CertificateFile     string `mapstructure:"certificate_file"`      // SP 签名证书（PEM）
+	PrivateKeyFile      string `mapstructure:"private_key_file"`      // SP 签名私钥（PEM，RSA）
+	FrontendRedirectURL string `mapstructure:"frontend_redirect_url"` // 前端接收结果的路由（默认：/auth/saml/callback）
+	AllowIdPInitiated   bool   `mapstructure:"allow_idp_initiated"`   // 是否接受 IdP 发起的登录（不校验 InResponseTo）
+
+	// IdP metadata：二选一
+	IdPMetadataFile string `mapstructure:"idp_metadata_file"`
+	IdPMetadataXML  string `mapstructure:"idp_metadata_xml"`
+
+	// 断言属性映射；为空时尝试一组常见属性名（subject 默认取 NameID）。
+	SubjectAttribute     string `mapstructure:"subject_attribute"`
+	EmailAttribute       string `mapstructure:"email_attribute"`
+	UsernameAttribute    string `mapstructure:"username_attribute"`
+	DisplayNameAttribute string `mapstructure:"display_name_attribute"`
+
+	// 角色映射：配置 role_attribute 后以 IdP 为准，每次登录同步；
+	// 属性值命中 admin_role_values 时为管理员，否则降为普通用户。
+	RoleAttribute   string   `mapstructure:"role_attribute"`
+	AdminRoleValues []string `mapstructure:"admin_role_values"`
+
+	// 分组映射：配置 groups_attribute 与 group_mapping 后以 IdP 为准，
+	// 每次登录把用户允许分组替换为命中的分组 ID（键不区分大小写）。
+	GroupsAttribute string             `mapstructure:"groups_attribute"`
+	GroupMapping    map[string][]int64 `mapstructure:"group_mapping"`
+}
+
 type DingTalkConnectConfig struct {
 	Enabled             bool   `mapstructure:"enabled"`
 	ClientID            string `mapstructure:"client_id"`
@@ -726,6 +764,22 @@ type SecurityConfig struct {
 	CSP             CSPConfig            `mapstructure:"csp"`
 	ProxyFallback   ProxyFallbackConfig  `mapstructure:"proxy_fallback"`
 	ProxyProbe      ProxyProbeConfig     `mapstructure:"proxy_probe"`
+	// CredentialEncryption 上游凭证（账号 credentials、代理密码、设置中的第三方密钥）信封加密
+	CredentialEncryption CredentialEncryptionConfig `mapstructure:"credential_encryption"`
+	// APIKeyHashPepper 用户 API Key 哈希存储（HMAC-SHA256）使用的服务端 pepper；
+	// 留空时启动阶段自动生成并持久化到数据库，多实例共享同一值
+	APIKeyHashPepper string `mapstructure:"api_key_hash_pepper"`
+	// APIKeyHashBackfillIntervalMinutes 历史明文 API Key 哈希回填任务间隔（分钟），
+	// 0 表示本实例不回填（仍会检查回填进度，以便明文 Key 清空后恢复认证缓存）
+	APIKeyHashBackfillIntervalMinutes int `mapstructure:"api_key_hash_backfill_interval_minutes"`
+	// APIKeyLeakDetection 用户 API Key 泄露检测（来源 IP / 网络 / 国家 / UA 多样性、费用突增、托管机房来源）
+	APIKeyLeakDetection APIKeyLeakDetectionConfig `mapstructure:"api_key_leak_detection"`
+	// APIKeyRotation 用户 API Key 轮换（宽限期、定期轮换调度）
+	APIKeyRotation APIKeyRotationConfig `mapstructure:"api_key_rotation"`
+	// ScopedToken 由 API Key 派生的短期受限令牌（供浏览器与边缘端调用，避免下发长期 Key）
+	ScopedToken ScopedTokenConfig `mapstructure:"scoped_token"`
+	// AuditLog 审计日志哈希链、归档与 SIEM 外送
+	AuditLog AuditLogSecurityConfig `mapstructure:"audit_log"`
 	// TrustForwardedIPForAPIKeyACL enables legacy raw forwarded-header takeover.
 	// When disabled, server.trusted_proxies is authoritative for all client-IP consumers.
 	TrustForwardedIPForAPIKeyACL  bool                                       `mapstructure:"trust_forwarded_ip_for_api_key_acl"`
@@ -844,10 +898,153 @@ type ProxyFallbackConfig struct {
 	AllowDirectOnError bool `mapstructure:"allow_direct_on_error"`
 }
 
+// APIKeyLeakDetectionConfig 用户 API Key 泄露检测配置。
+// 检测任务按窗口聚合 usage_logs 中每个 Key 的来源信号，超出阈值时按用户策略处置。
+// 各项阈值为 0 表示不检测该信号。
+type APIKeyLeakDetectionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// IntervalSeconds 检测任务间隔（秒）
+	IntervalSeconds int `mapstructure:"interval_seconds"`
+	// WindowMinutes 信号统计窗口（分钟）
+	WindowMinutes int `mapstructure:"window_minutes"`
+	// BaselineDays 费用与常用 IP 基线的回看天数（不含当前窗口）
+	BaselineDays int `mapstructure:"baseline_days"`
+	// DefaultAction 用户未单独设置时的处置策略：notify / require_allowlist / disable
+	DefaultAction string `mapstructure:"default_action"`
+	// CooldownMinutes 同一 Key 两次告警的最小间隔（分钟）
+	CooldownMinutes int `mapstructure:"cooldown_minutes"`
+
+	MaxDistinctIPs        int `mapstructure:"max_distinct_ips"`
+	MaxDistinctNetworks   int `mapstructure:"max_distinct_networks"`
+	MaxDistinctCountries  int `mapstructure:"max_distinct_countries"`
+	MaxDistinctUserAgents int `mapstructure:"max_distinct_user_agents"`
+	// SpendSpikeMultiplier 窗口费用超过基线同等时长平均费用的倍数时视为突增
+	SpendSpikeMultiplier float64 `mapstructure:"spend_spike_multiplier"`
+	// SpendSpikeMinUSD 窗口费用低于该值时不判定突增，避免低用量 Key 误报
+	SpendSpikeMinUSD float64 `mapstructure:"spend_spike_min_usd"`
+	// FlagNewHostingIPs 窗口内出现基线期未见过的托管机房 IP 时告警
+	FlagNewHostingIPs bool `mapstructure:"flag_new_hosting_ips"`
+
+	// NetworkRules 网段归属规则，按声明顺序取第一条命中；
+	// 未命中的 IP 以 IPv4 /24、IPv6 /48 前缀近似网络归属，国家记为未知
+	NetworkRules []APIKeyLeakNetworkRule `mapstructure:"network_rules"`
+}
+
+// APIKeyLeakNetworkRule 描述一个网段的 ASN / 国家 / 是否为托管机房（云厂商、IDC、VPS）。
+type APIKeyLeakNetworkRule struct {
+	CIDR    string `mapstructure:"cidr"`
+	ASN     string `mapstructure:"asn"`
+	Country string `mapstructure:"country"`
+	Hosting bool   `mapstructure:"hosting"`
+}
+
+// APIKeyRotationConfig 用户 API Key 轮换配置。
+// 轮换时生成继承原 Key 配置与用量的新 Key，旧 Key 在宽限期内继续可用，随后自动过期。
+type APIKeyRotationConfig struct {
+	// DefaultGraceHours 请求未指定宽限期时旧 Key 的保留时长（小时）
+	DefaultGraceHours int `mapstructure:"default_grace_hours"`
+	// MaxGraceHours 宽限期上限（小时）
+	MaxGraceHours int `mapstructure:"max_grace_hours"`
+	// MinScheduleIntervalDays / MaxScheduleIntervalDays 定期轮换周期的取值范围（天）
+	MinScheduleIntervalDays int `mapstructure:"min_schedule_interval_days"`
+	MaxScheduleIntervalDays int `mapstructure:"max_schedule_interval_days"`
+	// CheckIntervalSeconds 定期轮换调度任务的检查间隔（秒），0 表示关闭定期轮换
+	CheckIntervalSeconds int `mapstructure:"check_interval_seconds"`
+}
+
+// ScopedTokenConfig 派生令牌配置。
+// 令牌由持有 API Key 的调用方签发，自带收窄后的模型、花费、请求数、有效期与来源限制，
+// 认证时只校验签名与 Key 的认证快照，不回源数据库。
+type ScopedTokenConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// SigningSecret 令牌签名密钥；留空时启动阶段自动生成并持久化到数据库，多实例共享同一值。
+	// 更换后全部已签发令牌立即失效
+	SigningSecret string `mapstructure:"signing_secret"`
+	// DefaultTTLSeconds 签发请求未指定有效期时的默认有效期（秒）
+	DefaultTTLSeconds int `mapstructure:"default_ttl_seconds"`
+	// MaxTTLSeconds 有效期上限（秒）
+	MaxTTLSeconds int `mapstructure:"max_ttl_seconds"`
+	// SpendReservation 设有花费上限的令牌每个计费请求受理时预占的花费（美元），
+	// 请求结束或扣费时按实际花费结清；0 表示只检查已结清的花费
+	SpendReservation float64 `mapstructure:"spend_reservation"`
+}
+
+// AuditLogSecurityConfig 审计日志防篡改与外送配置。
+// 审计记录写入时按 id 顺序串成 SHA-256 哈希链；清空只能以归档到对象存储（复用备份 S3 配置）的方式进行。
+type AuditLogSecurityConfig struct {
+	// ArchivePageSize 归档与链校验时单次读取的行数
+	ArchivePageSize int                  `mapstructure:"archive_page_size"`
+	Export          AuditLogExportConfig `mapstructure:"export"`
+}
+
+// AuditLogExportConfig SIEM 外送配置。
+// 每个 sink 独立持久化游标（已确认送达的最大审计 id），重启后从游标继续。
+type AuditLogExportConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// IntervalSeconds 外送任务轮询间隔（秒）
+	IntervalSeconds int `mapstructure:"interval_seconds"`
+	// BatchSize 单批外送的最大记录数；一批送达后才推进游标
+	BatchSize int                  `mapstructure:"batch_size"`
+	Sinks     []AuditLogSinkConfig `mapstructure:"sinks"`
+}
+
+// AuditLogSinkConfig 单个外送目标。
+type AuditLogSinkConfig struct {
+	// Name 游标持久化使用的唯一名称；改名等同于新 sink，会从头重新外送
+	Name    string `mapstructure:"name"`
+	Enabled bool   `mapstructure:"enabled"`
+	// Type syslog（RFC 5424，TCP/TLS，RFC 6587 octet-counting 分帧）或 https（JSON 数组 POST）
+	Type string `mapstructure:"type"`
+	// Format 单条事件格式：json 或 cef
+	Format string `mapstructure:"format"`
+
+	// Network syslog 传输方式：tcp 或 tls
+	Network string `mapstructure:"network"`
+	// Address syslog 服务器地址（host:port）
+	Address string `mapstructure:"address"`
+	// Facility syslog facility（0-23），默认 13（log audit）
+	Facility int `mapstructure:"facility"`
+	// AppName RFC 5424 APP-NAME 字段
+	AppName string `mapstructure:"app_name"`
+
+	// URL https sink 地址，必须为 https://
+	URL string `mapstructure:"url"`
+	// BearerToken 非空时以 Authorization: Bearer 发送
+	BearerToken string            `mapstructure:"bearer_token"`
+	Headers     map[string]string `mapstructure:"headers"`
+
+	// CAFile 自定义 CA 证书（PEM）；CertFile/KeyFile 用于双向 TLS。不支持跳过证书校验
+	CAFile   string `mapstructure:"ca_file"`
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+	// TimeoutSeconds 单批发送超时（秒）
+	TimeoutSeconds int `mapstructure:"timeout_seconds"`
+}
+
 type ProxyProbeConfig struct {
 	InsecureSkipVerify bool `mapstructure:"insecure_skip_verify"` // 已禁用：禁止跳过 TLS 证书验证
 }
 
+// CredentialEncryptionConfig 上游凭证静态加密配置。
+// 每条记录使用独立数据密钥加密，数据密钥由主密钥包裹；主密钥来自配置或密钥文件。
+type CredentialEncryptionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Provider 主密钥提供方，目前支持 local（配置/密钥文件）
+	Provider string `mapstructure:"provider"`
+	// ActiveKeyID 新数据密钥使用的主密钥 ID
+	ActiveKeyID string `mapstructure:"active_key_id"`
+	// MasterKey 当前主密钥（32 字节 hex 编码）
+	MasterKey string `mapstructure:"master_key"`
+	// PreviousMasterKeys 轮换前的主密钥（ID -> hex），重加密完成前用于解包旧数据密钥
+	PreviousMasterKeys map[string]string `mapstructure:"previous_master_keys"`
+	// KeyFile 主密钥文件（JSON: {"active_key_id": "...", "keys": {"id": "hex"}}），配置后覆盖上面的内联密钥
+	KeyFile string `mapstructure:"key_file"`
+	// ReencryptIntervalMinutes 重加密任务间隔（分钟），把旧主密钥包裹的数据密钥迁移到当前主密钥
+	ReencryptIntervalMinutes int `mapstructure:"reencrypt_interval_minutes"`
+	// ReencryptBatchSize 重加密任务每批处理的记录数
+	ReencryptBatchSize int `mapstructure:"reencrypt_batch_size"`
+}
+
 type BillingConfig struct {
 	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
 	// MinimumBalanceReserve is the conservative preflight floor for balance billing.
@@ -1689,6 +1886,41 @@ type UsageCleanupConfig struct {
 	TaskTimeoutSeconds int `mapstructure:"task_timeout_seconds"`
 }
 
+// UserDataConfig 用户自助数据导出与账号注销配置。
+type UserDataConfig struct {
+	// Enabled: 是否开放自助导出/注销并启用后台执行器
+	Enabled bool `mapstructure:"enabled"`
+	// WorkerIntervalSeconds: 后台任务轮询间隔（秒）
+	WorkerIntervalSeconds int                    `mapstructure:"worker_interval_seconds"`
+	Export                UserDataExportConfig   `mapstructure:"export"`
+	Deletion              UserDataDeletionConfig `mapstructure:"deletion"`
+}
+
+// UserDataExportConfig 数据导出任务配置。
+type UserDataExportConfig struct {
+	// DownloadTTLHours: 导出包可下载时长（小时），过期后删除归档内容
+	DownloadTTLHours int `mapstructure:"download_ttl_hours"`
+	// MaxArchiveBytes: 单个导出包大小上限（字节），超出时任务失败
+	MaxArchiveBytes int64 `mapstructure:"max_archive_bytes"`
+	// MaxUsageRows: 导出的使用记录条数上限（按时间倒序截取），0 表示不限制
+	MaxUsageRows int `mapstructure:"max_usage_rows"`
+	// MinIntervalMinutes: 同一用户两次发起导出的最小间隔（分钟）
+	MinIntervalMinutes int `mapstructure:"min_interval_minutes"`
+}
+
+// UserDataDeletionConfig 账号注销配置。
+// 注销执行时匿名化用户资料与使用记录（保留行与金额，保证计费汇总不变），删除登录凭证与身份绑定。
+type UserDataDeletionConfig struct {
+	// CoolingOffDays: 注销冷静期（天），期间用户可撤销
+	CoolingOffDays int `mapstructure:"cooling_off_days"`
+	// PurgeRequestContent: 是否删除该用户的提示词审计与内容审核记录（含请求内容）
+	PurgeRequestContent bool `mapstructure:"purge_request_content"`
+	// PurgeErrorLogDetails: 是否清除运维错误日志中该用户的请求体、IP 与 User-Agent
+	PurgeErrorLogDetails bool `mapstructure:"purge_error_log_details"`
+	// BatchSize: 匿名化使用记录时的单批更新数量
+	BatchSize int `mapstructure:"batch_size"`
+}
+
 func NormalizeRunMode(value string) string {
 	normalized := strings.ToLower(strings.TrimSpace(value))
 	switch normalized {
@@ -1997,6 +2229,44 @@ func setDefaults() {
 	viper.SetDefault("security.csp.enabled", true)
 	viper.SetDefault("security.csp.policy", DefaultCSPPolicy)
 	viper.SetDefault("security.proxy_probe.insecure_skip_verify", false)
+	viper.SetDefault("security.credential_encryption.enabled", false)
+	viper.SetDefault("security.credential_encryption.provider", "local")
+	viper.SetDefault("security.credential_encryption.active_key_id", "")
+	viper.SetDefault("security.credential_encryption.master_key", "")
+	viper.SetDefault("security.credential_encryption.key_file", "")
+	viper.SetDefault("security.credential_encryption.reencrypt_interval_minutes", 60)
+	viper.SetDefault("security.credential_encryption.reencrypt_batch_size", 200)
+	viper.SetDefault("security.api_key_hash_pepper", "")
+	viper.SetDefault("security.api_key_hash_backfill_interval_minutes", 10)
+	viper.SetDefault("security.api_key_leak_detection.enabled", true)
+	viper.SetDefault("security.api_key_leak_detection.interval_seconds", 60)
+	viper.SetDefault("security.api_key_leak_detection.window_minutes", 60)
+	viper.SetDefault("security.api_key_leak_detection.baseline_days", 7)
+	viper.SetDefault("security.api_key_leak_detection.default_action", "notify")
+	viper.SetDefault("security.api_key_leak_detection.cooldown_minutes", 360)
+	viper.SetDefault("security.api_key_leak_detection.max_distinct_ips", 20)
+	viper.SetDefault("security.api_key_leak_detection.max_distinct_networks", 10)
+	viper.SetDefault("security.api_key_leak_detection.max_distinct_countries", 3)
+	viper.SetDefault("security.api_key_leak_detection.max_distinct_user_agents", 10)
+	viper.SetDefault("security.api_key_leak_detection.spend_spike_multiplier", 5.0)
+	viper.SetDefault("security.api_key_leak_detection.spend_spike_min_usd", 10.0)
+	viper.SetDefault("security.api_key_leak_detection.flag_new_hosting_ips", true)
+	viper.SetDefault("security.api_key_leak_detection.network_rules", []any{})
+	viper.SetDefault("security.api_key_rotation.default_grace_hours", 24)
+	viper.SetDefault("security.api_key_rotation.max_grace_hours", 720)
+	viper.SetDefault("security.api_key_rotation.min_schedule_interval_days", 1)
+	viper.SetDefault("security.api_key_rotation.max_schedule_interval_days", 365)
+	viper.SetDefault("security.api_key_rotation.check_interval_seconds", 300)
+	viper.SetDefault("security.scoped_token.enabled", true)
+	viper.SetDefault("security.scoped_token.signing_secret", "")
+	viper.SetDefault("security.scoped_token.default_ttl_seconds", 900)
+	viper.SetDefault("security.scoped_token.max_ttl_seconds", 86400)
+	viper.SetDefault("security.scoped_token.spend_reservation", 0.05)
+	viper.SetDefault("security.audit_log.archive_page_size", 5000)
+	viper.SetDefault("security.audit_log.export.enabled", false)
+	viper.SetDefault("security.audit_log.export.interval_seconds", 10)
+	viper.SetDefault("security.audit_log.export.batch_size", 500)
+	viper.SetDefault("security.audit_log.export.sinks", []any{})
 	viper.SetDefault("security.trust_forwarded_ip_for_api_key_acl", true)
 
 	// Security - disable direct fallback on proxy error
@@ -2078,6 +2348,26 @@ func setDefaults() {
 	viper.SetDefault("oidc_connect.userinfo_id_path", "")
 	viper.SetDefault("oidc_connect.userinfo_username_path", "")
 
+	// SAML 2.0 SSO
+	viper.SetDefault("saml.enabled", false)
+	viper.SetDefault("saml.provider_name", "SAML")
+	viper.SetDefault("saml.entity_id", "")
+	viper.SetDefault("saml.acs_url", "")
+	viper.SetDefault("saml.metadata_url", "")
+	viper.SetDefault("saml.certificate_file", "")
+	viper.SetDefault("saml.private_key_file", "")
+	viper.SetDefault("saml.frontend_redirect_url", "/auth/saml/callback")
+	viper.SetDefault("saml.allow_idp_initiated", false)
+	viper.SetDefault("saml.idp_metadata_file", "")
+	viper.SetDefault("saml.idp_metadata_xml", "")
+	viper.SetDefault("saml.subject_attribute", "")
+	viper.SetDefault("saml.email_attribute", "")
+	viper.SetDefault("saml.username_attribute", "")
+	viper.SetDefault("saml.display_name_attribute", "")
+	viper.SetDefault("saml.role_attribute", "")
+	viper.SetDefault("saml.admin_role_values", []string{})
+	viper.SetDefault("saml.groups_attribute", "")
+
 	// DingTalk Connect OAuth 登录
 	viper.SetDefault("dingtalk_connect.enabled", false)
 	viper.SetDefault("dingtalk_connect.authorize_url", "https://login.dingtalk.com/oauth2/auth")
@@ -2277,6 +2567,18 @@ func setDefaults() {
 	viper.SetDefault("usage_cleanup.worker_interval_seconds", 10)
 	viper.SetDefault("usage_cleanup.task_timeout_seconds", 1800)
 
+	// User data export / account deletion
+	viper.SetDefault("user_data.enabled", true)
+	viper.SetDefault("user_data.worker_interval_seconds", 30)
+	viper.SetDefault("user_data.export.download_ttl_hours", 72)
+	viper.SetDefault("user_data.export.max_archive_bytes", int64(256*1024*1024))
+	viper.SetDefault("user_data.export.max_usage_rows", 1000000)
+	viper.SetDefault("user_data.export.min_interval_minutes", 60)
+	viper.SetDefault("user_data.deletion.cooling_off_days", 14)
+	viper.SetDefault("user_data.deletion.purge_request_content", true)
+	viper.SetDefault("user_data.deletion.purge_error_log_details", true)
+	viper.SetDefault("user_data.deletion.batch_size", 5000)
+
 	// Idempotency
 	viper.SetDefault("idempotency.observe_only", true)
 	viper.SetDefault("idempotency.default_ttl_seconds", 86400)
@@ -2575,6 +2877,69 @@ func (c *Config) Validate() error {
 	}
 	c.Security.ForwardedClientIPHeaders = forwardedClientIPHeaders
 	c.SetForwardedClientIPSettings(c.Security.TrustForwardedIPForAPIKeyACL, forwardedClientIPHeaders)
+	if enc := c.Security.CredentialEncryption; enc.Enabled {
+		if provider := strings.ToLower(strings.TrimSpace(enc.Provider)); provider != "" && provider != "local" {
+			return fmt.Errorf("security.credential_encryption.provider must be local")
+		}
+		if strings.TrimSpace(enc.KeyFile) == "" {
+			if strings.TrimSpace(enc.ActiveKeyID) == "" || strings.TrimSpace(enc.MasterKey) == "" {
+				return fmt.Errorf("security.credential_encryption requires active_key_id and master_key, or key_file")
+			}
+			if strings.Contains(enc.ActiveKeyID, ":") {
+				return fmt.Errorf("security.credential_encryption.active_key_id must not contain ':'")
+			}
+		}
+		if enc.ReencryptIntervalMinutes < 0 {
+			return fmt.Errorf("security.credential_encryption.reencrypt_interval_minutes must be non-negative")
+		}
+		if enc.ReencryptBatchSize < 0 {
+			return fmt.Errorf("security.credential_encryption.reencrypt_batch_size must be non-negative")
+		}
+	}
+	if c.Security.APIKeyHashBackfillIntervalMinutes < 0 {
+		return fmt.Errorf("security.api_key_hash_backfill_interval_minutes must be non-negative")
+	}
+	if leak := c.Security.APIKeyLeakDetection; leak.Enabled {
+		switch strings.ToLower(strings.TrimSpace(leak.DefaultAction)) {
+		case "", "notify", "require_allowlist", "disable":
+		default:
+			return fmt.Errorf("security.api_key_leak_detection.default_action must be one of notify/require_allowlist/disable")
+		}
+		if leak.IntervalSeconds < 0 || leak.WindowMinutes < 0 || leak.BaselineDays < 0 || leak.CooldownMinutes < 0 {
+			return fmt.Errorf("security.api_key_leak_detection interval/window/baseline/cooldown must be non-negative")
+		}
+		if leak.MaxDistinctIPs < 0 || leak.MaxDistinctNetworks < 0 || leak.MaxDistinctCountries < 0 || leak.MaxDistinctUserAgents < 0 {
+			return fmt.Errorf("security.api_key_leak_detection thresholds must be non-negative")
+		}
+		if leak.SpendSpikeMultiplier < 0 || leak.SpendSpikeMinUSD < 0 {
+			return fmt.Errorf("security.api_key_leak_detection spend spike settings must be non-negative")
+		}
+		for i, rule := range leak.NetworkRules {
+			if _, _, err := net.ParseCIDR(strings.TrimSpace(rule.CIDR)); err != nil {
+				return fmt.Errorf("security.api_key_leak_detection.network_rules[%d].cidr invalid: %w", i, err)
+			}
+		}
+	}
+	if rot := c.Security.APIKeyRotation; rot.DefaultGraceHours < 0 || rot.MaxGraceHours < 0 || rot.CheckIntervalSeconds < 0 ||
+		rot.MinScheduleIntervalDays < 0 || rot.MaxScheduleIntervalDays < 0 {
+		return fmt.Errorf("security.api_key_rotation values must be non-negative")
+	} else if (rot.MaxGraceHours > 0 && rot.DefaultGraceHours > rot.MaxGraceHours) ||
+		(rot.MaxScheduleIntervalDays > 0 && rot.MinScheduleIntervalDays > rot.MaxScheduleIntervalDays) {
+		return fmt.Errorf("security.api_key_rotation default_grace_hours/min_schedule_interval_days must not exceed their max")
+	}
+	if st := c.Security.ScopedToken; st.DefaultTTLSeconds < 0 || st.MaxTTLSeconds < 0 {
+		return fmt.Errorf("security.scoped_token ttl values must be non-negative")
+	} else if st.SpendReservation < 0 || math.IsNaN(st.SpendReservation) || math.IsInf(st.SpendReservation, 0) {
+		return fmt.Errorf("security.scoped_token.spend_reservation must be a non-negative number")
+	} else if st.MaxTTLSeconds > 0 && st.DefaultTTLSeconds > st.MaxTTLSeconds {
+		return fmt.Errorf("security.scoped_token.default_ttl_seconds must not exceed max_ttl_seconds")
+	}
+	if err := validateAuditLogSecurityConfig(c.Security.AuditLog); err != nil {
+		return err
+	}
+	if strings.ContainsAny(c.Default.APIKeyPrefix, ":$") {
+		return fmt.Errorf("default.api_key_prefix must not contain ':' or '$'")
+	}
 	if c.Server.ReadHeaderTimeout < 1 || c.Server.ReadHeaderTimeout > 60 {
 		return fmt.Errorf("server.read_header_timeout must be between 1 and 60 seconds")
 	}
@@ -2944,6 +3309,37 @@ func (c *Config) Validate() error {
 		warnIfInsecureURL("oidc_connect.redirect_url", c.OIDC.RedirectURL)
 		warnIfInsecureURL("oidc_connect.frontend_redirect_url", c.OIDC.FrontendRedirectURL)
 	}
+	if c.SAML.Enabled {
+		if err := ValidateAbsoluteHTTPURL(c.SAML.ACSURL); err != nil {
+			return fmt.Errorf("saml.acs_url invalid: %w", err)
+		}
+		if err := ValidateAbsoluteHTTPURL(c.SAML.MetadataURL); err != nil {
+			return fmt.Errorf("saml.metadata_url invalid: %w", err)
+		}
+		if strings.TrimSpace(c.SAML.CertificateFile) == "" || strings.TrimSpace(c.SAML.PrivateKeyFile) == "" {
+			return fmt.Errorf("saml.certificate_file and saml.private_key_file are required when saml.enabled=true")
+		}
+		if strings.TrimSpace(c.SAML.IdPMetadataFile) == "" && strings.TrimSpace(c.SAML.IdPMetadataXML) == "" {
+			return fmt.Errorf("saml.idp_metadata_file or saml.idp_metadata_xml is required when saml.enabled=true")
+		}
+		if err := ValidateFrontendRedirectURL(c.SAML.FrontendRedirectURL); err != nil {
+			return fmt.Errorf("saml.frontend_redirect_url invalid: %w", err)
+		}
+		if strings.TrimSpace(c.SAML.RoleAttribute) != "" && len(c.SAML.AdminRoleValues) == 0 {
+			return fmt.Errorf("saml.admin_role_values is required when saml.role_attribute is set")
+		}
+		for value, groupIDs := range c.SAML.GroupMapping {
+			for _, groupID := range groupIDs {
+				if groupID <= 0 {
+					return fmt.Errorf("saml.group_mapping[%s] contains invalid group id %d", value, groupID)
+				}
+			}
+		}
+
+		warnIfInsecureURL("saml.acs_url", c.SAML.ACSURL)
+		warnIfInsecureURL("saml.metadata_url", c.SAML.MetadataURL)
+		warnIfInsecureURL("saml.frontend_redirect_url", c.SAML.FrontendRedirectURL)
+	}
 	if c.Billing.CircuitBreaker.Enabled {
 		if c.Billing.CircuitBreaker.FailureThreshold <= 0 {
 			return fmt.Errorf("billing.circuit_breaker.failure_threshold must be positive")
@@ -3159,6 +3555,26 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("usage_cleanup.task_timeout_seconds must be non-negative")
 		}
 	}
+	if c.UserData.Enabled {
+		if c.UserData.WorkerIntervalSeconds <= 0 {
+			return fmt.Errorf("user_data.worker_interval_seconds must be positive")
+		}
+		if c.UserData.Export.DownloadTTLHours <= 0 {
+			return fmt.Errorf("user_data.export.download_ttl_hours must be positive")
+		}
+		if c.UserData.Export.MaxArchiveBytes <= 0 {
+			return fmt.Errorf("user_data.export.max_archive_bytes must be positive")
+		}
+		if c.UserData.Deletion.BatchSize <= 0 {
+			return fmt.Errorf("user_data.deletion.batch_size must be positive")
+		}
+	}
+	if c.UserData.Export.MaxUsageRows < 0 || c.UserData.Export.MinIntervalMinutes < 0 {
+		return fmt.Errorf("user_data.export max_usage_rows/min_interval_minutes must be non-negative")
+	}
+	if c.UserData.Deletion.CoolingOffDays < 0 {
+		return fmt.Errorf("user_data.deletion.cooling_off_days must be non-negative")
+	}
 	if c.Idempotency.DefaultTTLSeconds <= 0 {
 		return fmt.Errorf("idempotency.default_ttl_seconds must be positive")
 	}
@@ -3613,6 +4029,60 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+func validateAuditLogSecurityConfig(cfg AuditLogSecurityConfig) error {
+	if cfg.ArchivePageSize < 0 {
+		return fmt.Errorf("security.audit_log.archive_page_size must be non-negative")
+	}
+	export := cfg.Export
+	if export.IntervalSeconds < 0 || export.BatchSize < 0 {
+		return fmt.Errorf("security.audit_log.export interval_seconds/batch_size must be non-negative")
+	}
+	names := make(map[string]struct{}, len(export.Sinks))
+	for i, sink := range export.Sinks {
+		name := strings.TrimSpace(sink.Name)
+		if name == "" {
+			return fmt.Errorf("security.audit_log.export.sinks[%d].name is required", i)
+		}
+		if _, dup := names[name]; dup {
+			return fmt.Errorf("security.audit_log.export.sinks[%d].name %q is duplicated", i, name)
+		}
+		names[name] = struct{}{}
+		switch strings.ToLower(strings.TrimSpace(sink.Format)) {
+		case "", "json", "cef":
+		default:
+			return fmt.Errorf("security.audit_log.export.sinks[%d].format must be json or cef", i)
+		}
+		if sink.TimeoutSeconds < 0 {
+			return fmt.Errorf("security.audit_log.export.sinks[%d].timeout_seconds must be non-negative", i)
+		}
+		if (strings.TrimSpace(sink.CertFile) == "") != (strings.TrimSpace(sink.KeyFile) == "") {
+			return fmt.Errorf("security.audit_log.export.sinks[%d] cert_file and key_file must be set together", i)
+		}
+		switch strings.ToLower(strings.TrimSpace(sink.Type)) {
+		case "syslog":
+			switch strings.ToLower(strings.TrimSpace(sink.Network)) {
+			case "", "tcp", "tls":
+			default:
+				return fmt.Errorf("security.audit_log.export.sinks[%d].network must be tcp or tls", i)
+			}
+			if _, _, err := net.SplitHostPort(strings.TrimSpace(sink.Address)); err != nil {
+				return fmt.Errorf("security.audit_log.export.sinks[%d].address invalid: %w", i, err)
+			}
+			if sink.Facility < 0 || sink.Facility > 23 {
+				return fmt.Errorf("security.audit_log.export.sinks[%d].facility must be between 0 and 23", i)
+			}
+		case "https":
+			u, err := url.Parse(strings.TrimSpace(sink.URL))
+			if err != nil || !strings.EqualFold(u.Scheme, "https") || u.Host == "" {
+				return fmt.Errorf("security.audit_log.export.sinks[%d].url must be an absolute https:// URL", i)
+			}
+		default:
+			return fmt.Errorf("security.audit_log.export.sinks[%d].type must be syslog or https", i)
+		}
+	}
+	return nil
+}
+
 func normalizeStringSlice(values []string) []string {
 	if len(values) == 0 {
 		return values
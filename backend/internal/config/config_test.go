@@ -1008,6 +1008,54 @@ func TestValidateOIDCAllowsExplicitCompatibilityOverridesForPKCEAndIDTokenValida
 	}
 }
 
+func TestValidateSAMLRequiresKeysMetadataAndAdminRoleValues(t *testing.T) {
+	resetViperWithJWTSecret(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.SAML.ProviderName != "SAML" || cfg.SAML.FrontendRedirectURL != "/auth/saml/callback" {
+		t.Fatalf("unexpected SAML defaults: %+v", cfg.SAML)
+	}
+
+	cfg.SAML.Enabled = true
+	cfg.SAML.EntityID = "https://example.com/api/v1/auth/oauth/saml/metadata"
+	cfg.SAML.ACSURL = "https://example.com/api/v1/auth/oauth/saml/acs"
+	cfg.SAML.MetadataURL = "https://example.com/api/v1/auth/oauth/saml/metadata"
+
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "saml.certificate_file") {
+		t.Fatalf("Validate() expected saml.certificate_file error, got: %v", err)
+	}
+
+	cfg.SAML.CertificateFile = "/etc/sub2api/saml/sp.crt"
+	cfg.SAML.PrivateKeyFile = "/etc/sub2api/saml/sp.key"
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "saml.idp_metadata") {
+		t.Fatalf("Validate() expected saml.idp_metadata error, got: %v", err)
+	}
+
+	cfg.SAML.IdPMetadataFile = "/etc/sub2api/saml/idp.xml"
+	cfg.SAML.RoleAttribute = "memberOf"
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "saml.admin_role_values") {
+		t.Fatalf("Validate() expected saml.admin_role_values error, got: %v", err)
+	}
+
+	cfg.SAML.AdminRoleValues = []string{"admins"}
+	cfg.SAML.GroupMapping = map[string][]int64{"staff": {0}}
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "saml.group_mapping") {
+		t.Fatalf("Validate() expected saml.group_mapping error, got: %v", err)
+	}
+
+	cfg.SAML.GroupMapping = map[string][]int64{"staff": {1, 2}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() expected SAML config to pass, got: %v", err)
+	}
+}
+
 func TestLoadDefaultDashboardCacheConfig(t *testing.T) {
 	resetViperWithJWTSecret(t)
 
@@ -2125,6 +2173,30 @@ func TestValidateConfigErrors(t *testing.T) {
 			mutate:  func(c *Config) { c.Ops.Cleanup.MinuteMetricsRetentionDays = -1 },
 			wantErr: "ops.cleanup.minute_metrics_retention_days",
 		},
+		{
+			name: "audit log https sink requires https url",
+			mutate: func(c *Config) {
+				c.Security.AuditLog.Export.Sinks = []AuditLogSinkConfig{{Name: "siem", Type: "https", URL: "http://collector.example.com"}}
+			},
+			wantErr: "security.audit_log.export.sinks[0].url",
+		},
+		{
+			name: "audit log syslog sink address",
+			mutate: func(c *Config) {
+				c.Security.AuditLog.Export.Sinks = []AuditLogSinkConfig{{Name: "siem", Type: "syslog", Network: "tls", Address: "siem.example.com"}}
+			},
+			wantErr: "security.audit_log.export.sinks[0].address",
+		},
+		{
+			name: "audit log duplicate sink name",
+			mutate: func(c *Config) {
+				c.Security.AuditLog.Export.Sinks = []AuditLogSinkConfig{
+					{Name: "siem", Type: "syslog", Address: "siem.example.com:6514"},
+					{Name: "siem", Type: "https", URL: "https://collector.example.com"},
+				}
+			},
+			wantErr: "security.audit_log.export.sinks[1].name",
+		},
 	}
 
 	for _, tt := range cases {
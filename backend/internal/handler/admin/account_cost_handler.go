@@ -0,0 +1,118 @@
+package admin
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountCostHandler 账号采购成本台账与 ROI 报表接口。
+type AccountCostHandler struct {
+	accountCostService *service.AccountCostService
+}
+
+// NewAccountCostHandler 创建账号成本处理器。
+func NewAccountCostHandler(accountCostService *service.AccountCostService) *AccountCostHandler {
+	return &AccountCostHandler{accountCostService: accountCostService}
+}
+
+type upsertAccountCostBasisRequest struct {
+	BillingType string     `json:"billing_type"`
+	Cost        float64    `json:"cost"`
+	PeriodDays  int        `json:"period_days"`
+	StartedAt   *time.Time `json:"started_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	Notes       string     `json:"notes"`
+}
+
+// Get 查询账号成本记录。
+// GET /api/v1/admin/accounts/:id/cost-basis
+func (h *AccountCostHandler) Get(c *gin.Context) {
+	accountID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid account ID")
+		return
+	}
+	basis, err := h.accountCostService.Get(c.Request.Context(), accountID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, basis)
+}
+
+// Upsert 创建或覆盖账号成本记录。
+// PUT /api/v1/admin/accounts/:id/cost-basis
+func (h *AccountCostHandler) Upsert(c *gin.Context) {
+	accountID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid account ID")
+		return
+	}
+	var req upsertAccountCostBasisRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	basis := &service.AccountCostBasis{
+		AccountID:   accountID,
+		BillingType: req.BillingType,
+		Cost:        req.Cost,
+		PeriodDays:  req.PeriodDays,
+		ExpiresAt:   req.ExpiresAt,
+		Notes:       req.Notes,
+	}
+	if req.StartedAt != nil {
+		basis.StartedAt = *req.StartedAt
+	}
+	saved, err := h.accountCostService.Upsert(c.Request.Context(), basis)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, saved)
+}
+
+// Delete 删除账号成本记录。
+// DELETE /api/v1/admin/accounts/:id/cost-basis
+func (h *AccountCostHandler) Delete(c *gin.Context) {
+	accountID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid account ID")
+		return
+	}
+	if err := h.accountCostService.Delete(c.Request.Context(), accountID); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, gin.H{"message": "Account cost basis deleted successfully"})
+}
+
+// GetROIReport 账号/代理维度的收入与摊销成本对比。
+// GET /api/v1/admin/dashboard/account-roi
+// Query params: start_date, end_date (YYYY-MM-DD), timezone, renewal_days
+func (h *AccountCostHandler) GetROIReport(c *gin.Context) {
+	startTime, endTime := parseTimeRange(c)
+
+	renewalDays := 0
+	if v := c.Query("renewal_days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 365 {
+			response.BadRequest(c, "Invalid renewal_days, expect 1-365")
+			return
+		}
+		renewalDays = n
+	}
+
+	report, err := h.accountCostService.GetROIReport(c.Request.Context(), startTime, endTime, renewalDays)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, report)
+}
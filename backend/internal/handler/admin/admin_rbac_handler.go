@@ -0,0 +1,208 @@
+package admin
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	"github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminRBACHandler 管理员角色、角色分配与具名管理员 API Token 接口。
+// 路由组要求 admins:manage 权限。
+type AdminRBACHandler struct {
+	rbacService  *service.AdminRBACService
+	adminService service.AdminService
+}
+
+// NewAdminRBACHandler 创建管理员 RBAC 处理器。
+func NewAdminRBACHandler(rbacService *service.AdminRBACService, adminService service.AdminService) *AdminRBACHandler {
+	return &AdminRBACHandler{rbacService: rbacService, adminService: adminService}
+}
+
+// UpsertAdminRoleRequest 创建 / 更新自定义角色请求。
+type UpsertAdminRoleRequest struct {
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// AssignAdminRoleRequest 为管理员分配角色请求；role 为空或 super_admin 表示全部权限。
+type AssignAdminRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// CreateAdminAPITokenRequest 创建具名 Token 请求。
+type CreateAdminAPITokenRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// ListPermissions 返回全部可分配的权限。
+// GET /api/v1/admin/rbac/permissions
+func (h *AdminRBACHandler) ListPermissions(c *gin.Context) {
+	response.Success(c, gin.H{"permissions": service.AdminPermissions})
+}
+
+// GetMe 返回当前管理员主体的角色与有效权限，供前端按权限隐藏入口。
+// GET /api/v1/admin/rbac/me
+func (h *AdminRBACHandler) GetMe(c *gin.Context) {
+	role, perms := middleware.GetAdminPermissions(c)
+	response.Success(c, gin.H{"role": role, "permissions": perms.List()})
+}
+
+// ListRoles 列出内置与自定义角色。
+// GET /api/v1/admin/rbac/roles
+func (h *AdminRBACHandler) ListRoles(c *gin.Context) {
+	roles, err := h.rbacService.ListRoles(c.Request.Context())
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, roles)
+}
+
+// UpsertRole 创建或更新自定义角色。
+// PUT /api/v1/admin/rbac/roles/:name
+func (h *AdminRBACHandler) UpsertRole(c *gin.Context) {
+	var req UpsertAdminRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	_, perms := middleware.GetAdminPermissions(c)
+	role, err := h.rbacService.UpsertRole(c.Request.Context(), perms, &service.AdminRole{
+		Name:        c.Param("name"),
+		Description: req.Description,
+		Permissions: req.Permissions,
+	})
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, role)
+}
+
+// DeleteRole 删除自定义角色。
+// DELETE /api/v1/admin/rbac/roles/:name
+func (h *AdminRBACHandler) DeleteRole(c *gin.Context) {
+	if err := h.rbacService.DeleteRole(c.Request.Context(), c.Param("name")); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, gin.H{"message": "Role deleted successfully"})
+}
+
+// ListAssignments 返回 user_id → 角色名（未出现的管理员为 super_admin）。
+// GET /api/v1/admin/rbac/assignments
+func (h *AdminRBACHandler) ListAssignments(c *gin.Context) {
+	assignments, err := h.rbacService.ListRoleAssignments(c.Request.Context())
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, assignments)
+}
+
+// AssignUserRole 为管理员分配角色。
+// PUT /api/v1/admin/rbac/users/:id/role
+func (h *AdminRBACHandler) AssignUserRole(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || userID <= 0 {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+	var req AssignAdminRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	user, err := h.adminService.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	if !user.IsAdmin() {
+		response.BadRequest(c, "Roles can only be assigned to admin users")
+		return
+	}
+	if subject, ok := middleware.GetAuthSubjectFromContext(c); ok && subject.UserID == userID {
+		// 防止误把自己降权后失去 admins:manage 而无法恢复
+		response.BadRequest(c, "Cannot change your own admin role")
+		return
+	}
+	_, perms := middleware.GetAdminPermissions(c)
+	if err := h.rbacService.AssignUserRole(c.Request.Context(), perms, userID, req.Role); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	role := strings.TrimSpace(req.Role)
+	if role == "" {
+		role = service.AdminRoleSuperAdmin
+	}
+	middleware.SetAuditExtra(c, map[string]any{"admin_role": role})
+	response.Success(c, gin.H{"user_id": userID, "role": role})
+}
+
+// ListTokens 列出具名 Token（不含明文）。
+// GET /api/v1/admin/rbac/tokens
+func (h *AdminRBACHandler) ListTokens(c *gin.Context) {
+	tokens, err := h.rbacService.ListTokens(c.Request.Context())
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, tokens)
+}
+
+// CreateToken 创建具名 Token；明文只在本次响应中返回。
+// POST /api/v1/admin/rbac/tokens
+func (h *AdminRBACHandler) CreateToken(c *gin.Context) {
+	var req CreateAdminAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	// 机器凭证不能再签发凭证，Token 只能由真人管理员会话创建
+	if service.IsMachineAdminAuthMethod(c.GetString("auth_method")) {
+		response.Forbidden(c, "Admin API tokens must be created from an admin session")
+		return
+	}
+	subject, ok := middleware.GetAuthSubjectFromContext(c)
+	if !ok || subject.UserID <= 0 {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	_, perms := middleware.GetAdminPermissions(c)
+	plain, token, err := h.rbacService.CreateToken(c.Request.Context(), subject.UserID, perms, service.CreateAdminAPITokenInput{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	middleware.SetAuditExtra(c, map[string]any{"admin_token_id": token.ID, "admin_token_name": token.Name})
+	response.Success(c, gin.H{"token": plain, "info": token})
+}
+
+// RevokeToken 吊销具名 Token。
+// DELETE /api/v1/admin/rbac/tokens/:id
+func (h *AdminRBACHandler) RevokeToken(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.BadRequest(c, "Invalid token ID")
+		return
+	}
+	if err := h.rbacService.RevokeToken(c.Request.Context(), id); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	middleware.SetAuditExtra(c, map[string]any{"admin_token_id": id})
+	response.Success(c, gin.H{"message": "Token revoked successfully"})
+}
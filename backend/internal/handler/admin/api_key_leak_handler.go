@@ -0,0 +1,91 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyLeakHandler 管理端 API Key 泄露检测事件与用户处置策略接口。
+type APIKeyLeakHandler struct {
+	leakService *service.APIKeyLeakDetectionService
+}
+
+// NewAPIKeyLeakHandler 创建 API Key 泄露检测处理器。
+func NewAPIKeyLeakHandler(leakService *service.APIKeyLeakDetectionService) *APIKeyLeakHandler {
+	return &APIKeyLeakHandler{leakService: leakService}
+}
+
+type updateAPIKeyLeakPolicyRequest struct {
+	Action string `json:"action" binding:"required"`
+}
+
+// ListEvents 分页查询泄露检测事件，可按 user_id / api_key_id 过滤。
+// GET /api/v1/admin/api-keys/leak-events
+func (h *APIKeyLeakHandler) ListEvents(c *gin.Context) {
+	var filter service.APIKeyLeakEventFilter
+	if raw := c.Query("user_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			response.BadRequest(c, "Invalid user_id")
+			return
+		}
+		filter.UserID = id
+	}
+	if raw := c.Query("api_key_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			response.BadRequest(c, "Invalid api_key_id")
+			return
+		}
+		filter.APIKeyID = id
+	}
+	page, pageSize := response.ParsePagination(c)
+	events, result, err := h.leakService.ListEvents(c.Request.Context(), filter, pagination.PaginationParams{Page: page, PageSize: pageSize})
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Paginated(c, events, result.Total, page, pageSize)
+}
+
+// GetUserPolicy 查询用户的泄露处置策略。
+// GET /api/v1/admin/users/:id/api-key-leak-policy
+func (h *APIKeyLeakHandler) GetUserPolicy(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+	policy, err := h.leakService.GetPolicy(c.Request.Context(), userID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, policy)
+}
+
+// UpdateUserPolicy 代用户设置泄露处置策略。
+// PUT /api/v1/admin/users/:id/api-key-leak-policy
+func (h *APIKeyLeakHandler) UpdateUserPolicy(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+	var req updateAPIKeyLeakPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	policy, err := h.leakService.SetPolicy(c.Request.Context(), userID, req.Action)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, policy)
+}
@@ -14,17 +14,19 @@ import (
 )
 
 // AuditLogHandler 操作审计日志管理接口。
-// 审计日志仅管理员可见；不提供单条删除，仅支持带 TOTP 验证的全量清空。
+// 审计日志仅管理员可见；不提供单条删除，清空仅支持带 TOTP 验证的归档（上传对象存储后删除）。
 type AuditLogHandler struct {
-	auditService *service.AuditLogService
-	totpService  *service.TotpService
+	auditService  *service.AuditLogService
+	totpService   *service.TotpService
+	exportService *service.AuditLogExportService
 }
 
 // NewAuditLogHandler 创建审计日志处理器。
-func NewAuditLogHandler(auditService *service.AuditLogService, totpService *service.TotpService) *AuditLogHandler {
+func NewAuditLogHandler(auditService *service.AuditLogService, totpService *service.TotpService, exportService *service.AuditLogExportService) *AuditLogHandler {
 	return &AuditLogHandler{
-		auditService: auditService,
-		totpService:  totpService,
+		auditService:  auditService,
+		totpService:   totpService,
+		exportService: exportService,
 	}
 }
 
@@ -100,20 +102,54 @@ func (h *AuditLogHandler) Get(c *gin.Context) {
 	response.Success(c, item)
 }
 
+// Verify 校验审计日志哈希链，返回首个断点。
+// GET /api/v1/admin/audit-logs/verify
+func (h *AuditLogHandler) Verify(c *gin.Context) {
+	result, err := h.auditService.VerifyChain(c.Request.Context())
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, result)
+}
+
+// ListArchives 列出已归档到对象存储的审计日志区间。
+// GET /api/v1/admin/audit-logs/archives
+func (h *AuditLogHandler) ListArchives(c *gin.Context) {
+	archives, err := h.auditService.ListArchives(c.Request.Context())
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, archives)
+}
+
+// ExportStatus 查询 SIEM 外送各 sink 的游标与最近错误。
+// GET /api/v1/admin/audit-logs/export-status
+func (h *AuditLogHandler) ExportStatus(c *gin.Context) {
+	status, err := h.exportService.Status(c.Request.Context())
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, status)
+}
+
 type auditLogClearRequest struct {
 	TotpCode string `json:"totp_code" binding:"required"`
 }
 
-// Clear 全量清空审计日志。
+// Clear 清空审计日志：先归档到对象存储，归档成功后才删除。
 // POST /api/v1/admin/audit-logs/clear
 //
 // 安全要求（与需求对齐）：
 //  1. 每次清空都必须现场验证 TOTP 码（不复用 step-up sudo 窗口）
 //  2. 未启用 TOTP 的管理员不允许清空
 //  3. admin API key（机器凭证）不允许清空
-//  4. 清空完成后同步写入一条留痕记录（操作者、IP、UA、删除行数）
+//  4. 未配置对象存储时拒绝；SIEM 尚未送达的记录保留不删
+//  5. 归档完成后同步写入一条留痕记录（操作者、IP、UA、删除行数、归档对象），作为新链的首条
 func (h *AuditLogHandler) Clear(c *gin.Context) {
-	if c.GetString("auth_method") == service.AuditAuthMethodAdminAPIKey {
+	if service.IsMachineAdminAuthMethod(c.GetString("auth_method")) {
 		response.ErrorWithDetails(c, http.StatusForbidden,
 			"Admin API key cannot clear audit logs; a two-factor verified admin session is required",
 			"STEP_UP_ADMIN_API_KEY_FORBIDDEN", nil)
@@ -157,7 +193,7 @@ func (h *AuditLogHandler) Clear(c *gin.Context) {
 		trace.RequestID = requestID
 	}
 
-	deleted, err := h.auditService.ClearAll(c.Request.Context(), trace)
+	archive, deleted, err := h.auditService.ArchiveAll(c.Request.Context(), trace)
 	if err != nil {
 		response.ErrorFrom(c, err)
 		return
@@ -165,5 +201,5 @@ func (h *AuditLogHandler) Clear(c *gin.Context) {
 
 	// 留痕记录已同步落库，跳过异步审计中间件的重复记录。
 	middleware.SkipAudit(c)
-	response.Success(c, gin.H{"deleted": deleted})
+	response.Success(c, gin.H{"deleted": deleted, "archive": archive})
 }
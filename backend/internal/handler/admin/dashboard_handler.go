@@ -446,6 +446,32 @@ func (h *DashboardHandler) GetGroupStats(c *gin.Context) {
 	})
 }
 
+// GetGroupCacheHitRate handles getting per-group prompt-cache hit rates
+// GET /api/v1/admin/dashboard/groups/cache-hit-rate
+// Query params: start_date, end_date (YYYY-MM-DD), group_id
+func (h *DashboardHandler) GetGroupCacheHitRate(c *gin.Context) {
+	startTime, endTime := parseTimeRange(c)
+
+	var groupID int64
+	if groupIDStr := c.Query("group_id"); groupIDStr != "" {
+		if id, err := strconv.ParseInt(groupIDStr, 10, 64); err == nil {
+			groupID = id
+		}
+	}
+
+	stats, err := h.dashboardService.GetGroupCacheHitRates(c.Request.Context(), startTime, endTime, groupID)
+	if err != nil {
+		response.Error(c, 500, "Failed to get group cache hit rates")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"groups":     stats,
+		"start_date": startTime.Format("2006-01-02"),
+		"end_date":   endTime.Add(-24 * time.Hour).Format("2006-01-02"),
+	})
+}
+
 // GetAPIKeyUsageTrend handles getting API key usage trend data
 // GET /api/v1/admin/dashboard/api-keys-trend
 // Query params: start_date, end_date (YYYY-MM-DD), granularity (day/hour), limit (default 5)
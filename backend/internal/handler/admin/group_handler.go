@@ -125,6 +125,11 @@ type CreateGroupRequest struct {
 	ProfitControlEnabled            bool                          `json:"profit_control_enabled"`
 	ProfitMinMargin                 *float64                      `json:"profit_min_margin"`
 	ProfitSafetyBuffer              *float64                      `json:"profit_safety_buffer"`
+	PromptPrefixRoutingEnabled      bool                          `json:"prompt_prefix_routing_enabled"`
+	PromptPrefixMessages            int                           `json:"prompt_prefix_messages"`
+	StreamResumeEnabled             bool                          `json:"stream_resume_enabled"`
+	ContextCompactionMode           string                        `json:"context_compaction_mode"`
+	ContextCompactionModel          string                        `json:"context_compaction_model"`
 	ImagePrice1K                    *float64                      `json:"image_price_1k"`
 	ImagePrice2K                    *float64                      `json:"image_price_2k"`
 	ImagePrice4K                    *float64                      `json:"image_price_4k"`
@@ -137,6 +142,8 @@ type CreateGroupRequest struct {
 	AudioRealtimePricePerMin        *float64                      `json:"audio_realtime_price_per_min"`
 	AudioTtsPricePerMillionChars    *float64                      `json:"audio_tts_price_per_million_chars"`
 	AudioSttPricePerHour            *float64                      `json:"audio_stt_price_per_hour"`
+	ModerationPricePerCall          *float64                      `json:"moderation_price_per_call"`
+	ModerationPricePerMillionTokens *float64                      `json:"moderation_price_per_million_tokens"`
 	ClaudeCodeOnly                  bool                          `json:"claude_code_only"`
 	FallbackGroupID                 *int64                        `json:"fallback_group_id"`
 	FallbackGroupIDOnInvalidRequest *int64                        `json:"fallback_group_id_on_invalid_request"`
@@ -194,6 +201,11 @@ type UpdateGroupRequest struct {
 	ProfitControlEnabled            *bool                         `json:"profit_control_enabled"`
 	ProfitMinMargin                 *float64                      `json:"profit_min_margin"`
 	ProfitSafetyBuffer              *float64                      `json:"profit_safety_buffer"`
+	PromptPrefixRoutingEnabled      *bool                         `json:"prompt_prefix_routing_enabled"`
+	PromptPrefixMessages            *int                          `json:"prompt_prefix_messages"`
+	StreamResumeEnabled             *bool                         `json:"stream_resume_enabled"`
+	ContextCompactionMode           *string                       `json:"context_compaction_mode"`
+	ContextCompactionModel          *string                       `json:"context_compaction_model"`
 	ImagePrice1K                    *float64                      `json:"image_price_1k"`
 	ImagePrice2K                    *float64                      `json:"image_price_2k"`
 	ImagePrice4K                    *float64                      `json:"image_price_4k"`
@@ -206,6 +218,8 @@ type UpdateGroupRequest struct {
 	AudioRealtimePricePerMin        *float64                      `json:"audio_realtime_price_per_min"`
 	AudioTtsPricePerMillionChars    *float64                      `json:"audio_tts_price_per_million_chars"`
 	AudioSttPricePerHour            *float64                      `json:"audio_stt_price_per_hour"`
+	ModerationPricePerCall          *float64                      `json:"moderation_price_per_call"`
+	ModerationPricePerMillionTokens *float64                      `json:"moderation_price_per_million_tokens"`
 	ClaudeCodeOnly                  *bool                         `json:"claude_code_only"`
 	FallbackGroupID                 *int64                        `json:"fallback_group_id"`
 	FallbackGroupIDOnInvalidRequest *int64                        `json:"fallback_group_id_on_invalid_request"`
@@ -531,6 +545,11 @@ func (h *GroupHandler) Create(c *gin.Context) {
 		ProfitControlEnabled:            req.ProfitControlEnabled,
 		ProfitMinMargin:                 req.ProfitMinMargin,
 		ProfitSafetyBuffer:              req.ProfitSafetyBuffer,
+		PromptPrefixRoutingEnabled:      req.PromptPrefixRoutingEnabled,
+		PromptPrefixMessages:            req.PromptPrefixMessages,
+		StreamResumeEnabled:             req.StreamResumeEnabled,
+		ContextCompactionMode:           req.ContextCompactionMode,
+		ContextCompactionModel:          req.ContextCompactionModel,
 		ImagePrice1K:                    req.ImagePrice1K,
 		ImagePrice2K:                    req.ImagePrice2K,
 		ImagePrice4K:                    req.ImagePrice4K,
@@ -543,6 +562,8 @@ func (h *GroupHandler) Create(c *gin.Context) {
 		AudioRealtimePricePerMin:        req.AudioRealtimePricePerMin,
 		AudioTTSPricePerMillionChars:    req.AudioTtsPricePerMillionChars,
 		AudioSTTPricePerHour:            req.AudioSttPricePerHour,
+		ModerationPricePerCall:          req.ModerationPricePerCall,
+		ModerationPricePerMillionTokens: req.ModerationPricePerMillionTokens,
 		ClaudeCodeOnly:                  req.ClaudeCodeOnly,
 		FallbackGroupID:                 req.FallbackGroupID,
 		FallbackGroupIDOnInvalidRequest: req.FallbackGroupIDOnInvalidRequest,
@@ -660,6 +681,11 @@ func (h *GroupHandler) Update(c *gin.Context) {
 		ProfitControlEnabled:            req.ProfitControlEnabled,
 		ProfitMinMargin:                 req.ProfitMinMargin,
 		ProfitSafetyBuffer:              req.ProfitSafetyBuffer,
+		PromptPrefixRoutingEnabled:      req.PromptPrefixRoutingEnabled,
+		PromptPrefixMessages:            req.PromptPrefixMessages,
+		StreamResumeEnabled:             req.StreamResumeEnabled,
+		ContextCompactionMode:           req.ContextCompactionMode,
+		ContextCompactionModel:          req.ContextCompactionModel,
 		ImagePrice1K:                    req.ImagePrice1K,
 		ImagePrice2K:                    req.ImagePrice2K,
 		ImagePrice4K:                    req.ImagePrice4K,
@@ -672,6 +698,8 @@ func (h *GroupHandler) Update(c *gin.Context) {
 		AudioRealtimePricePerMin:        req.AudioRealtimePricePerMin,
 		AudioTTSPricePerMillionChars:    req.AudioTtsPricePerMillionChars,
 		AudioSTTPricePerHour:            req.AudioSttPricePerHour,
+		ModerationPricePerCall:          req.ModerationPricePerCall,
+		ModerationPricePerMillionTokens: req.ModerationPricePerMillionTokens,
 		ClaudeCodeOnly:                  req.ClaudeCodeOnly,
 		FallbackGroupID:                 req.FallbackGroupID,
 		FallbackGroupIDOnInvalidRequest: req.FallbackGroupIDOnInvalidRequest,
@@ -0,0 +1,237 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProxyPoolHandler handles admin proxy pool management and account auto-assignment
+type ProxyPoolHandler struct {
+	proxyPoolService *service.ProxyPoolService
+}
+
+// NewProxyPoolHandler creates a new admin proxy pool handler
+func NewProxyPoolHandler(proxyPoolService *service.ProxyPoolService) *ProxyPoolHandler {
+	return &ProxyPoolHandler{proxyPoolService: proxyPoolService}
+}
+
+// ProxyPoolRequest represents create/update proxy pool request
+type ProxyPoolRequest struct {
+	Name                string   `json:"name" binding:"required"`
+	Region              string   `json:"region"`
+	Tags                []string `json:"tags"`
+	MaxAccountsPerProxy int      `json:"max_accounts_per_proxy" binding:"omitempty,min=0"`
+}
+
+// SetProxyPoolMembersRequest replaces the proxies of a pool
+type SetProxyPoolMembersRequest struct {
+	ProxyIDs []int64 `json:"proxy_ids"`
+}
+
+// SetAccountProxyPoolRequest switches an account into (pool_id set) or out of (null) auto-assign mode
+type SetAccountProxyPoolRequest struct {
+	PoolID *int64 `json:"pool_id"`
+}
+
+// List handles listing proxy pools
+// GET /api/v1/admin/proxy-pools
+func (h *ProxyPoolHandler) List(c *gin.Context) {
+	pools, err := h.proxyPoolService.ListPools(c.Request.Context())
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, pools)
+}
+
+// GetByID handles getting a proxy pool by ID
+// GET /api/v1/admin/proxy-pools/:id
+func (h *ProxyPoolHandler) GetByID(c *gin.Context) {
+	poolID, ok := parseProxyPoolID(c)
+	if !ok {
+		return
+	}
+	pool, err := h.proxyPoolService.GetPool(c.Request.Context(), poolID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, pool)
+}
+
+// Create handles creating a proxy pool
+// POST /api/v1/admin/proxy-pools
+func (h *ProxyPoolHandler) Create(c *gin.Context) {
+	var req ProxyPoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	pool, err := h.proxyPoolService.CreatePool(c.Request.Context(), &service.ProxyPool{
+		Name:                req.Name,
+		Region:              req.Region,
+		Tags:                req.Tags,
+		MaxAccountsPerProxy: req.MaxAccountsPerProxy,
+	})
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, pool)
+}
+
+// Update handles updating a proxy pool
+// PUT /api/v1/admin/proxy-pools/:id
+func (h *ProxyPoolHandler) Update(c *gin.Context) {
+	poolID, ok := parseProxyPoolID(c)
+	if !ok {
+		return
+	}
+	var req ProxyPoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	pool, err := h.proxyPoolService.UpdatePool(c.Request.Context(), &service.ProxyPool{
+		ID:                  poolID,
+		Name:                req.Name,
+		Region:              req.Region,
+		Tags:                req.Tags,
+		MaxAccountsPerProxy: req.MaxAccountsPerProxy,
+	})
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, pool)
+}
+
+// Delete handles deleting a proxy pool
+// DELETE /api/v1/admin/proxy-pools/:id
+func (h *ProxyPoolHandler) Delete(c *gin.Context) {
+	poolID, ok := parseProxyPoolID(c)
+	if !ok {
+		return
+	}
+	if err := h.proxyPoolService.DeletePool(c.Request.Context(), poolID); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, gin.H{"message": "Proxy pool deleted successfully"})
+}
+
+// SetMembers handles replacing the proxies of a pool
+// PUT /api/v1/admin/proxy-pools/:id/members
+func (h *ProxyPoolHandler) SetMembers(c *gin.Context) {
+	poolID, ok := parseProxyPoolID(c)
+	if !ok {
+		return
+	}
+	var req SetProxyPoolMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	pool, err := h.proxyPoolService.SetMembers(c.Request.Context(), poolID, req.ProxyIDs)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, pool)
+}
+
+// Rebalance handles reassigning unhealthy/over-capacity accounts and evening out load within a pool
+// POST /api/v1/admin/proxy-pools/:id/rebalance
+func (h *ProxyPoolHandler) Rebalance(c *gin.Context) {
+	poolID, ok := parseProxyPoolID(c)
+	if !ok {
+		return
+	}
+	moved, err := h.proxyPoolService.RebalancePool(c.Request.Context(), poolID, true)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, gin.H{"reassigned": moved})
+}
+
+// ListReassignments handles listing proxy reassignment audit records
+// GET /api/v1/admin/proxy-pools/reassignments
+// Query params: account_id, pool_id, page, page_size
+func (h *ProxyPoolHandler) ListReassignments(c *gin.Context) {
+	page, pageSize := response.ParsePagination(c)
+	var filter service.ProxyReassignmentFilter
+	if v := c.Query("account_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			response.BadRequest(c, "Invalid account_id")
+			return
+		}
+		filter.AccountID = id
+	}
+	if v := c.Query("pool_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			response.BadRequest(c, "Invalid pool_id")
+			return
+		}
+		filter.PoolID = id
+	}
+
+	items, result, err := h.proxyPoolService.ListReassignments(c.Request.Context(), pagination.PaginationParams{Page: page, PageSize: pageSize}, filter)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Paginated(c, items, result.Total, page, pageSize)
+}
+
+// GetAccountPool handles getting the proxy pool an account is auto-assigned from
+// GET /api/v1/admin/accounts/:id/proxy-pool
+func (h *ProxyPoolHandler) GetAccountPool(c *gin.Context) {
+	accountID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid account ID")
+		return
+	}
+	poolID, err := h.proxyPoolService.GetAccountPoolID(c.Request.Context(), accountID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, gin.H{"pool_id": poolID})
+}
+
+// SetAccountPool handles switching an account's proxy assignment mode
+// PUT /api/v1/admin/accounts/:id/proxy-pool
+func (h *ProxyPoolHandler) SetAccountPool(c *gin.Context) {
+	accountID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid account ID")
+		return
+	}
+	var req SetAccountProxyPoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	if err := h.proxyPoolService.SetAccountPool(c.Request.Context(), accountID, req.PoolID); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, gin.H{"pool_id": req.PoolID})
+}
+
+func parseProxyPoolID(c *gin.Context) (int64, bool) {
+	poolID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid proxy pool ID")
+		return 0, false
+	}
+	return poolID, true
+}
@@ -0,0 +1,168 @@
+package admin
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProxySubscriptionHandler handles proxy list import and periodic proxy subscriptions
+type ProxySubscriptionHandler struct {
+	proxySubscriptionService *service.ProxySubscriptionService
+}
+
+// NewProxySubscriptionHandler creates a new admin proxy subscription handler
+func NewProxySubscriptionHandler(proxySubscriptionService *service.ProxySubscriptionService) *ProxySubscriptionHandler {
+	return &ProxySubscriptionHandler{proxySubscriptionService: proxySubscriptionService}
+}
+
+// ImportProxySubscriptionRequest represents a one-off import of a vendor proxy list
+type ImportProxySubscriptionRequest struct {
+	Content   string     `json:"content" binding:"required"`
+	Format    string     `json:"format" binding:"omitempty,oneof=auto clash singbox v2ray text"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// ProxySubscriptionRequest represents create/update proxy subscription request
+type ProxySubscriptionRequest struct {
+	Name            string `json:"name" binding:"required"`
+	URL             string `json:"url" binding:"required"`
+	Format          string `json:"format" binding:"omitempty,oneof=auto clash singbox v2ray text"`
+	IntervalMinutes int    `json:"interval_minutes" binding:"omitempty,min=5"`
+	Enabled         *bool  `json:"enabled"`
+}
+
+// Import handles importing proxies from Clash YAML, sing-box/V2Ray JSON or URI list content
+// POST /api/v1/admin/proxies/import-subscription
+func (h *ProxySubscriptionHandler) Import(c *gin.Context) {
+	var req ImportProxySubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	result, err := h.proxySubscriptionService.Import(c.Request.Context(), []byte(req.Content), req.Format, req.ExpiresAt)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, result)
+}
+
+// List handles listing proxy subscriptions
+// GET /api/v1/admin/proxy-subscriptions
+func (h *ProxySubscriptionHandler) List(c *gin.Context) {
+	subs, err := h.proxySubscriptionService.List(c.Request.Context())
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, subs)
+}
+
+// GetByID handles getting a proxy subscription by ID
+// GET /api/v1/admin/proxy-subscriptions/:id
+func (h *ProxySubscriptionHandler) GetByID(c *gin.Context) {
+	id, ok := parseProxySubscriptionID(c)
+	if !ok {
+		return
+	}
+	sub, err := h.proxySubscriptionService.Get(c.Request.Context(), id)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, sub)
+}
+
+// Create handles creating a proxy subscription
+// POST /api/v1/admin/proxy-subscriptions
+func (h *ProxySubscriptionHandler) Create(c *gin.Context) {
+	var req ProxySubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	sub, err := h.proxySubscriptionService.Create(c.Request.Context(), req.toSubscription(0))
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, sub)
+}
+
+// Update handles updating a proxy subscription
+// PUT /api/v1/admin/proxy-subscriptions/:id
+func (h *ProxySubscriptionHandler) Update(c *gin.Context) {
+	id, ok := parseProxySubscriptionID(c)
+	if !ok {
+		return
+	}
+	var req ProxySubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	sub, err := h.proxySubscriptionService.Update(c.Request.Context(), req.toSubscription(id))
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, sub)
+}
+
+// Delete handles deleting a proxy subscription (imported proxies are kept)
+// DELETE /api/v1/admin/proxy-subscriptions/:id
+func (h *ProxySubscriptionHandler) Delete(c *gin.Context) {
+	id, ok := parseProxySubscriptionID(c)
+	if !ok {
+		return
+	}
+	if err := h.proxySubscriptionService.Delete(c.Request.Context(), id); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, gin.H{"message": "Proxy subscription deleted successfully"})
+}
+
+// Sync handles fetching and applying a proxy subscription immediately
+// POST /api/v1/admin/proxy-subscriptions/:id/sync
+func (h *ProxySubscriptionHandler) Sync(c *gin.Context) {
+	id, ok := parseProxySubscriptionID(c)
+	if !ok {
+		return
+	}
+	result, err := h.proxySubscriptionService.Sync(c.Request.Context(), id)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, result)
+}
+
+func (r *ProxySubscriptionRequest) toSubscription(id int64) *service.ProxySubscription {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return &service.ProxySubscription{
+		ID:              id,
+		Name:            r.Name,
+		URL:             r.URL,
+		Format:          r.Format,
+		IntervalMinutes: r.IntervalMinutes,
+		Enabled:         enabled,
+	}
+}
+
+func parseProxySubscriptionID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid proxy subscription ID")
+		return 0, false
+	}
+	return id, true
+}
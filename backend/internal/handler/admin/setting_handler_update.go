@@ -380,7 +380,7 @@ type UpdateSettingsRequest struct {
 // 必须是真人管理员会话（admin API key 无法完成 TOTP step-up，拒绝）且本人已启用 TOTP。
 // 校验失败时写入错误响应并返回 false。
 func (h *SettingHandler) ensureActorTotpForStepUp(c *gin.Context) bool {
-	if c.GetString("auth_method") == service.AuditAuthMethodAdminAPIKey {
+	if service.IsMachineAdminAuthMethod(c.GetString("auth_method")) {
 		response.ErrorWithDetails(c, http.StatusForbidden,
 			"Admin API key cannot enable step-up verification; use an admin session with TOTP enabled",
 			"STEP_UP_ADMIN_API_KEY_FORBIDDEN", nil)
@@ -1,10 +1,12 @@
 package admin
 
 import (
+	"encoding/json"
 	"strconv"
 
 	"github.com/Wei-Shaw/sub2api/internal/model"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tlsfingerprint"
 	"github.com/Wei-Shaw/sub2api/internal/service"
 	"github.com/gin-gonic/gin"
 )
@@ -21,18 +23,19 @@ func NewTLSFingerprintProfileHandler(service *service.TLSFingerprintProfileServi
 
 // CreateTLSFingerprintProfileRequest 创建模板请求
 type CreateTLSFingerprintProfileRequest struct {
-	Name                string   `json:"name" binding:"required"`
-	Description         *string  `json:"description"`
-	EnableGREASE        *bool    `json:"enable_grease"`
-	CipherSuites        []uint16 `json:"cipher_suites"`
-	Curves              []uint16 `json:"curves"`
-	PointFormats        []uint16 `json:"point_formats"`
-	SignatureAlgorithms []uint16 `json:"signature_algorithms"`
-	ALPNProtocols       []string `json:"alpn_protocols"`
-	SupportedVersions   []uint16 `json:"supported_versions"`
-	KeyShareGroups      []uint16 `json:"key_share_groups"`
-	PSKModes            []uint16 `json:"psk_modes"`
-	Extensions          []uint16 `json:"extensions"`
+	Name                string                       `json:"name" binding:"required"`
+	Description         *string                      `json:"description"`
+	EnableGREASE        *bool                        `json:"enable_grease"`
+	CipherSuites        []uint16                     `json:"cipher_suites"`
+	Curves              []uint16                     `json:"curves"`
+	PointFormats        []uint16                     `json:"point_formats"`
+	SignatureAlgorithms []uint16                     `json:"signature_algorithms"`
+	ALPNProtocols       []string                     `json:"alpn_protocols"`
+	SupportedVersions   []uint16                     `json:"supported_versions"`
+	KeyShareGroups      []uint16                     `json:"key_share_groups"`
+	PSKModes            []uint16                     `json:"psk_modes"`
+	Extensions          []uint16                     `json:"extensions"`
+	HTTP2               *tlsfingerprint.HTTP2Profile `json:"http2"`
 }
 
 // UpdateTLSFingerprintProfileRequest 更新模板请求（部分更新）
@@ -49,6 +52,8 @@ type UpdateTLSFingerprintProfileRequest struct {
 	KeyShareGroups      []uint16 `json:"key_share_groups"`
 	PSKModes            []uint16 `json:"psk_modes"`
 	Extensions          []uint16 `json:"extensions"`
+	// HTTP2 省略时保持不变，显式传 null 时清除 HTTP/2 指纹段
+	HTTP2 json.RawMessage `json:"http2"`
 }
 
 // List 获取所有模板
@@ -105,6 +110,7 @@ func (h *TLSFingerprintProfileHandler) Create(c *gin.Context) {
 		KeyShareGroups:      req.KeyShareGroups,
 		PSKModes:            req.PSKModes,
 		Extensions:          req.Extensions,
+		HTTP2:               req.HTTP2,
 	}
 
 	if req.EnableGREASE != nil {
@@ -164,6 +170,7 @@ func (h *TLSFingerprintProfileHandler) Update(c *gin.Context) {
 		KeyShareGroups:      existing.KeyShareGroups,
 		PSKModes:            existing.PSKModes,
 		Extensions:          existing.Extensions,
+		HTTP2:               existing.HTTP2,
 	}
 
 	if req.Name != nil {
@@ -202,6 +209,14 @@ func (h *TLSFingerprintProfileHandler) Update(c *gin.Context) {
 	if req.Extensions != nil {
 		profile.Extensions = req.Extensions
 	}
+	if req.HTTP2 != nil {
+		var h2 *tlsfingerprint.HTTP2Profile
+		if err := json.Unmarshal(req.HTTP2, &h2); err != nil {
+			response.BadRequest(c, "Invalid request: "+err.Error())
+			return
+		}
+		profile.HTTP2 = h2
+	}
 
 	updated, err := h.service.Update(c.Request.Context(), profile)
 	if err != nil {
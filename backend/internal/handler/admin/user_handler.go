@@ -276,6 +276,15 @@ func (h *UserHandler) Create(c *gin.Context) {
 		return
 	}
 
+	if req.Role == service.RoleAdmin && lacksAdminPermission(c, service.AdminPermissionAdminsManage) {
+		response.Forbidden(c, "Missing admin permission: "+service.AdminPermissionAdminsManage)
+		return
+	}
+	if req.Balance != nil && *req.Balance != 0 && lacksAdminPermission(c, service.AdminPermissionBillingWrite) {
+		response.Forbidden(c, "Missing admin permission: "+service.AdminPermissionBillingWrite)
+		return
+	}
+
 	// 创建管理员账号属权限敏感操作：需最近完成 step-up 2FA 验证。
 	if req.Role == service.RoleAdmin {
 		if !middleware.EnforceStepUp(c, h.totpService, h.userService, h.settingService) {
@@ -325,6 +334,10 @@ func (h *UserHandler) Update(c *gin.Context) {
 		return
 	}
 
+	if !h.checkUpdateUserPermissions(c, userID, &req) {
+		return
+	}
+
 	// 把普通用户提升为管理员属权限敏感操作：需最近完成 step-up 2FA 验证。
 	// 目标已是管理员时（前端编辑表单总是携带 role）不触发，避免日常编辑被打断。
 	if req.Role == service.RoleAdmin {
@@ -363,6 +376,39 @@ func (h *UserHandler) Update(c *gin.Context) {
 	response.Success(c, dto.UserFromServiceAdmin(user))
 }
 
+// checkUpdateUserPermissions 字段级权限：余额归 billing:write，分组倍率归 groups:write，
+// 修改角色或编辑管理员账号需要 admins:manage（避免 support 角色借此提权）。
+func (h *UserHandler) checkUpdateUserPermissions(c *gin.Context, userID int64, req *UpdateUserRequest) bool {
+	if req.Balance != nil && lacksAdminPermission(c, service.AdminPermissionBillingWrite) {
+		response.Forbidden(c, "Missing admin permission: "+service.AdminPermissionBillingWrite)
+		return false
+	}
+	if req.GroupRates != nil && lacksAdminPermission(c, service.AdminPermissionGroupsWrite) {
+		response.Forbidden(c, "Missing admin permission: "+service.AdminPermissionGroupsWrite)
+		return false
+	}
+	if !lacksAdminPermission(c, service.AdminPermissionAdminsManage) {
+		return true
+	}
+	target, err := h.adminService.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return false
+	}
+	if target.IsAdmin() || (req.Role != "" && req.Role != target.Role) {
+		response.Forbidden(c, "Missing admin permission: "+service.AdminPermissionAdminsManage)
+		return false
+	}
+	return true
+}
+
+// lacksAdminPermission 判断当前主体是否明确缺少某权限。
+// 路由组守卫已保证上下文中有权限集合；未注入时（如 handler 单测）不做字段级限制。
+func lacksAdminPermission(c *gin.Context, perm string) bool {
+	_, perms := middleware.GetAdminPermissions(c)
+	return perms != nil && !perms.Has(perm)
+}
+
 // Delete handles deleting a user
 // DELETE /api/v1/admin/users/:id
 func (h *UserHandler) Delete(c *gin.Context) {
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyLeakHandler 用户侧 API Key 泄露处置策略与检测事件。
+type APIKeyLeakHandler struct {
+	leakService *service.APIKeyLeakDetectionService
+}
+
+// NewAPIKeyLeakHandler creates a new APIKeyLeakHandler
+func NewAPIKeyLeakHandler(leakService *service.APIKeyLeakDetectionService) *APIKeyLeakHandler {
+	return &APIKeyLeakHandler{leakService: leakService}
+}
+
+// UpdateAPIKeyLeakPolicyRequest 设置泄露处置策略。
+type UpdateAPIKeyLeakPolicyRequest struct {
+	Action string `json:"action" binding:"required"`
+}
+
+// GetPolicy 获取当前用户的泄露处置策略
+// GET /api/v1/user/api-key-leak-policy
+func (h *APIKeyLeakHandler) GetPolicy(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	policy, err := h.leakService.GetPolicy(c.Request.Context(), subject.UserID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, policy)
+}
+
+// UpdatePolicy 设置当前用户的泄露处置策略（notify / require_allowlist / disable）
+// PUT /api/v1/user/api-key-leak-policy
+func (h *APIKeyLeakHandler) UpdatePolicy(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	var req UpdateAPIKeyLeakPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	policy, err := h.leakService.SetPolicy(c.Request.Context(), subject.UserID, req.Action)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, policy)
+}
+
+// ListEvents 获取当前用户 API Key 的泄露检测记录
+// GET /api/v1/user/api-key-leak-events
+func (h *APIKeyLeakHandler) ListEvents(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	page, pageSize := response.ParsePagination(c)
+	events, result, err := h.leakService.ListEvents(c.Request.Context(),
+		service.APIKeyLeakEventFilter{UserID: subject.UserID},
+		pagination.PaginationParams{Page: page, PageSize: pageSize})
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Paginated(c, events, result.Total, page, pageSize)
+}
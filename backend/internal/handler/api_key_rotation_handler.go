@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/handler/dto"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyRotationHandler 用户侧 API Key 轮换、定期轮换设置与谱系用量。
+type APIKeyRotationHandler struct {
+	rotationService *service.APIKeyRotationService
+}
+
+// NewAPIKeyRotationHandler creates a new APIKeyRotationHandler
+func NewAPIKeyRotationHandler(rotationService *service.APIKeyRotationService) *APIKeyRotationHandler {
+	return &APIKeyRotationHandler{rotationService: rotationService}
+}
+
+// RotateAPIKeyRequest 轮换请求；grace_hours 为空时使用系统默认宽限期，0 表示旧 Key 立即失效。
+type RotateAPIKeyRequest struct {
+	GraceHours *int `json:"grace_hours"`
+}
+
+// UpdateAPIKeyRotationScheduleRequest 设置定期轮换；interval_days 为 0 表示关闭。
+type UpdateAPIKeyRotationScheduleRequest struct {
+	IntervalDays int  `json:"interval_days"`
+	GraceHours   *int `json:"grace_hours"`
+}
+
+// APIKeyRotationResponse 轮换结果：新 Key（含明文，仅此一次）与旧 Key 的失效时间。
+type APIKeyRotationResponse struct {
+	APIKey         *dto.APIKey `json:"api_key"`
+	PreviousKeyID  int64       `json:"previous_key_id"`
+	LineageID      int64       `json:"lineage_id"`
+	GraceExpiresAt time.Time   `json:"grace_expires_at"`
+}
+
+func parseAPIKeyIDParam(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.BadRequest(c, "Invalid key ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// Rotate 轮换 API Key：新 Key 继承分组、额度、限流、IP 规则与用量计数，旧 Key 在宽限期后自动过期
+// POST /api/v1/user/api-keys/:id/rotate
+func (h *APIKeyRotationHandler) Rotate(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	keyID, ok := parseAPIKeyIDParam(c)
+	if !ok {
+		return
+	}
+	var req RotateAPIKeyRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, "Invalid request: "+err.Error())
+			return
+		}
+	}
+
+	payload := struct {
+		KeyID      int64 `json:"key_id"`
+		GraceHours *int  `json:"grace_hours"`
+	}{KeyID: keyID, GraceHours: req.GraceHours}
+	executeUserIdempotentJSON(c, "user.api_keys.rotate", payload, service.DefaultWriteIdempotencyTTL(), func(ctx context.Context) (any, error) {
+		result, err := h.rotationService.Rotate(ctx, subject.UserID, keyID, service.RotateAPIKeyRequest{GraceHours: req.GraceHours})
+		if err != nil {
+			return nil, err
+		}
+		return APIKeyRotationResponse{
+			APIKey:         dto.APIKeyFromService(result.Successor),
+			PreviousKeyID:  result.Predecessor.ID,
+			LineageID:      result.LineageID,
+			GraceExpiresAt: result.GraceExpiresAt,
+		}, nil
+	})
+}
+
+// GetSchedule 获取 Key 的轮换状态与定期轮换设置
+// GET /api/v1/user/api-keys/:id/rotation
+func (h *APIKeyRotationHandler) GetSchedule(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	keyID, ok := parseAPIKeyIDParam(c)
+	if !ok {
+		return
+	}
+	state, err := h.rotationService.GetState(c.Request.Context(), subject.UserID, keyID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, state)
+}
+
+// UpdateSchedule 设置定期轮换周期（天）与宽限期（小时）
+// PUT /api/v1/user/api-keys/:id/rotation
+func (h *APIKeyRotationHandler) UpdateSchedule(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	keyID, ok := parseAPIKeyIDParam(c)
+	if !ok {
+		return
+	}
+	var req UpdateAPIKeyRotationScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	state, err := h.rotationService.SetSchedule(c.Request.Context(), subject.UserID, keyID, service.UpdateAPIKeyRotationScheduleRequest{
+		IntervalDays: req.IntervalDays,
+		GraceHours:   req.GraceHours,
+	})
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, state)
+}
+
+// RevealPendingKey 查看定期轮换生成的新 Key 明文（仅可查看一次）
+// POST /api/v1/user/api-keys/:id/rotation/reveal
+func (h *APIKeyRotationHandler) RevealPendingKey(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	keyID, ok := parseAPIKeyIDParam(c)
+	if !ok {
+		return
+	}
+	key, err := h.rotationService.RevealPendingSecret(c.Request.Context(), subject.UserID, keyID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, gin.H{"key": key})
+}
+
+// GetLineage 获取 Key 所属谱系（跨轮换的同一逻辑 Key）的成员与合计用量
+// GET /api/v1/user/api-keys/:id/lineage?days=30
+func (h *APIKeyRotationHandler) GetLineage(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	keyID, ok := parseAPIKeyIDParam(c)
+	if !ok {
+		return
+	}
+	days, ok := parseAPIKeyDailyUsageDays(c.DefaultQuery("days", ""))
+	if !ok {
+		response.BadRequest(c, "Invalid days, allowed range is 1-90")
+		return
+	}
+	lineage, err := h.rotationService.GetLineage(c.Request.Context(), subject.UserID, keyID, days)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, lineage)
+}
@@ -543,6 +543,7 @@ func (h *AuthHandler) findOAuthIdentityUser(ctx context.Context, identity servic
 func (h *AuthHandler) BindLinuxDoOAuthLogin(c *gin.Context) { h.bindPendingOAuthLogin(c, "linuxdo") }
 func (h *AuthHandler) BindOIDCOAuthLogin(c *gin.Context)    { h.bindPendingOAuthLogin(c, "oidc") }
 func (h *AuthHandler) BindWeChatOAuthLogin(c *gin.Context)  { h.bindPendingOAuthLogin(c, "wechat") }
+func (h *AuthHandler) BindSAMLOAuthLogin(c *gin.Context)    { h.bindPendingOAuthLogin(c, "saml") }
 func (h *AuthHandler) BindPendingOAuthLogin(c *gin.Context) { h.bindPendingOAuthLogin(c, "") }
 
 func (h *AuthHandler) CreateLinuxDoOAuthAccount(c *gin.Context) {
@@ -551,6 +552,8 @@ func (h *AuthHandler) CreateLinuxDoOAuthAccount(c *gin.Context) {
 
 func (h *AuthHandler) CreateOIDCOAuthAccount(c *gin.Context) { h.createPendingOAuthAccount(c, "oidc") }
 
+func (h *AuthHandler) CreateSAMLOAuthAccount(c *gin.Context) { h.createPendingOAuthAccount(c, "saml") }
+
 func (h *AuthHandler) CreateWeChatOAuthAccount(c *gin.Context) {
 	h.createPendingOAuthAccount(c, "wechat")
 }
@@ -1263,6 +1266,13 @@ func applyPendingOAuthBindingTx(
 		}
 	}
 
+	// SAML 的角色/分组以 IdP 断言为准，每次完成登录或绑定时同步
+	if strings.EqualFold(strings.TrimSpace(session.ProviderType), "saml") && authService != nil {
+		if err := authService.ApplySAMLUserMapping(ctx, targetUserID, samlUserMappingFromClaims(session.UpstreamIdentityClaims)); err != nil {
+			return err
+		}
+	}
+
 	if shouldAdoptAvatar && userService != nil {
 		if _, err := userService.SetAvatar(ctx, targetUserID, adoptedAvatarURL); err != nil {
 			return err
@@ -2035,6 +2045,15 @@ func (h *AuthHandler) ExchangePendingOAuthCompletion(c *gin.Context) {
 		response.ErrorFrom(c, infraerrors.InternalServer("PENDING_AUTH_ADOPTION_APPLY_FAILED", "failed to apply oauth profile adoption").WithCause(err))
 		return
 	}
+	if canIssueTokenPair && strings.EqualFold(strings.TrimSpace(session.ProviderType), "saml") {
+		// 角色可能刚被 SAML 映射改写，重新读取以签发正确的令牌
+		loginUser, err = h.userService.GetByID(c.Request.Context(), *session.TargetUserID)
+		if err != nil {
+			clearCookies()
+			response.ErrorFrom(c, err)
+			return
+		}
+	}
 
 	if _, err := svc.ConsumeBrowserSession(c.Request.Context(), sessionToken, browserSessionKey); err != nil {
 		clearCookies()
@@ -0,0 +1,668 @@
+package handler
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/crewjam/saml"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	samlOAuthCookiePath          = "/api/v1/auth/oauth/saml"
+	samlOAuthRequestIDCookieName = "saml_oauth_request_id"
+	samlOAuthRedirectCookie      = "saml_oauth_redirect"
+	samlOAuthIntentCookieName    = "saml_oauth_intent"
+	samlOAuthBindUserCookieName  = "saml_oauth_bind_user"
+	samlOAuthBrowserCookieName   = "saml_oauth_browser_session"
+	samlOAuthCookieMaxAgeSec     = 10 * 60 // 10 minutes
+	samlOAuthDefaultRedirectTo   = "/dashboard"
+	samlOAuthDefaultFrontendCB   = "/auth/saml/callback"
+
+	samlSignatureMethodRSASHA256 = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+
+	// 写入 pending session 上游 claims 的映射结果，完成绑定时由 applyPendingOAuthBindingTx 落库。
+	samlClaimRole          = "saml_role"
+	samlClaimAllowedGroups = "saml_allowed_group_ids"
+)
+
+var (
+	samlDefaultEmailAttributes = []string{
+		"email",
+		"mail",
+		"emailAddress",
+		"urn:oid:0.9.2342.19200300.100.1.3",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+	}
+	samlDefaultUsernameAttributes = []string{
+		"username",
+		"preferred_username",
+		"uid",
+		"urn:oid:0.9.2342.19200300.100.1.1",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/name",
+	}
+	samlDefaultDisplayNameAttributes = []string{
+		"displayName",
+		"cn",
+		"urn:oid:2.16.840.1.113730.3.1.241",
+		"urn:oid:2.5.4.3",
+		"http://schemas.microsoft.com/identity/claims/displayname",
+	}
+)
+
+// samlAssertionProfile 是从已验签断言中提取出的本地身份信息。
+type samlAssertionProfile struct {
+	Issuer      string
+	Subject     string
+	Email       string
+	Username    string
+	DisplayName string
+	Mapping     service.SAMLUserMapping
+}
+
+// SAMLMetadata 输出 SP metadata，供 IdP 导入。
+// GET /api/v1/auth/oauth/saml/metadata
+func (h *AuthHandler) SAMLMetadata(c *gin.Context) {
+	cfg, err := h.getSAMLConfig()
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	sp, err := buildSAMLServiceProvider(cfg)
+	if err != nil {
+		response.ErrorFrom(c, infraerrors.InternalServer("SAML_CONFIG_INVALID", "saml service provider is misconfigured").WithCause(err))
+		return
+	}
+	body, err := xml.MarshalIndent(sp.Metadata(), "", "  ")
+	if err != nil {
+		response.ErrorFrom(c, infraerrors.InternalServer("SAML_METADATA_FAILED", "failed to render saml metadata").WithCause(err))
+		return
+	}
+	c.Data(http.StatusOK, "application/samlmetadata+xml", append([]byte(xml.Header), body...))
+}
+
+// SAMLOAuthStart 生成签名的 AuthnRequest（HTTP-Redirect 绑定）并跳转到 IdP。
+// GET /api/v1/auth/oauth/saml/start?redirect=/dashboard
+func (h *AuthHandler) SAMLOAuthStart(c *gin.Context) {
+	if !h.requireActionCaptchaForOAuthLoginStart(c) {
+		return
+	}
+	cfg, err := h.getSAMLConfig()
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	sp, err := buildSAMLServiceProvider(cfg)
+	if err != nil {
+		response.ErrorFrom(c, infraerrors.InternalServer("SAML_CONFIG_INVALID", "saml service provider is misconfigured").WithCause(err))
+		return
+	}
+	ssoURL := sp.GetSSOBindingLocation(saml.HTTPRedirectBinding)
+	if ssoURL == "" {
+		response.ErrorFrom(c, infraerrors.InternalServer("SAML_CONFIG_INVALID", "idp metadata has no HTTP-Redirect sso endpoint"))
+		return
+	}
+	authnRequest, err := sp.MakeAuthenticationRequest(ssoURL, saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		response.ErrorFrom(c, infraerrors.InternalServer("SAML_REQUEST_FAILED", "failed to build saml authn request").WithCause(err))
+		return
+	}
+	authURL, err := authnRequest.Redirect("", sp)
+	if err != nil {
+		response.ErrorFrom(c, infraerrors.InternalServer("SAML_REQUEST_FAILED", "failed to sign saml authn request").WithCause(err))
+		return
+	}
+
+	redirectTo := sanitizeFrontendRedirectPath(c.Query("redirect"))
+	if redirectTo == "" {
+		redirectTo = samlOAuthDefaultRedirectTo
+	}
+	browserSessionKey, err := generateOAuthPendingBrowserSession()
+	if err != nil {
+		response.ErrorFrom(c, infraerrors.InternalServer("OAUTH_BROWSER_SESSION_GEN_FAILED", "failed to generate oauth browser session").WithCause(err))
+		return
+	}
+
+	secureCookie := isRequestHTTPS(c)
+	samlSetCookie(c, samlOAuthRequestIDCookieName, encodeCookieValue(authnRequest.ID), samlOAuthCookieMaxAgeSec, secureCookie)
+	samlSetCookie(c, samlOAuthRedirectCookie, encodeCookieValue(redirectTo), samlOAuthCookieMaxAgeSec, secureCookie)
+	// ACS 是 IdP 发起的跨站 POST，Lax 的浏览器会话 cookie 不会带上，这里额外存一份
+	samlSetCookie(c, samlOAuthBrowserCookieName, encodeCookieValue(browserSessionKey), samlOAuthCookieMaxAgeSec, secureCookie)
+	intent := normalizeOAuthIntent(c.Query("intent"))
+	samlSetCookie(c, samlOAuthIntentCookieName, encodeCookieValue(intent), samlOAuthCookieMaxAgeSec, secureCookie)
+	captureOAuthPromoCode(c, secureCookie)
+	setOAuthPendingBrowserCookie(c, browserSessionKey, secureCookie)
+	clearOAuthPendingSessionCookie(c, secureCookie)
+	if intent == oauthIntentBindCurrentUser {
+		bindCookieValue, err := h.buildOAuthBindUserCookieFromContext(c)
+		if err != nil {
+			response.ErrorFrom(c, err)
+			return
+		}
+		samlSetCookie(c, samlOAuthBindUserCookieName, encodeCookieValue(bindCookieValue), samlOAuthCookieMaxAgeSec, secureCookie)
+	} else {
+		samlClearCookie(c, samlOAuthBindUserCookieName, secureCookie)
+	}
+
+	respondOAuthStart(c, authURL.String())
+}
+
+// SAMLAssertionConsumer 处理 IdP POST 回来的 SAMLResponse：校验签名、受众、有效期与
+// InResponseTo，然后按 OIDC 相同的 pending-identity 流程创建/登录/绑定用户。
+// POST /api/v1/auth/oauth/saml/acs
+func (h *AuthHandler) SAMLAssertionConsumer(c *gin.Context) {
+	cfg, cfgErr := h.getSAMLConfig()
+	if cfgErr != nil {
+		response.ErrorFrom(c, cfgErr)
+		return
+	}
+
+	frontendCallback := strings.TrimSpace(cfg.FrontendRedirectURL)
+	if frontendCallback == "" {
+		frontendCallback = samlOAuthDefaultFrontendCB
+	}
+
+	secureCookie := isRequestHTTPS(c)
+	defer func() {
+		samlClearCookie(c, samlOAuthRequestIDCookieName, secureCookie)
+		samlClearCookie(c, samlOAuthRedirectCookie, secureCookie)
+		samlClearCookie(c, samlOAuthBrowserCookieName, secureCookie)
+		samlClearCookie(c, samlOAuthIntentCookieName, secureCookie)
+		samlClearCookie(c, samlOAuthBindUserCookieName, secureCookie)
+		clearOAuthPromoCodeCookie(c, secureCookie)
+	}()
+
+	rawResponse := strings.TrimSpace(c.PostForm("SAMLResponse"))
+	if rawResponse == "" {
+		redirectOAuthError(c, frontendCallback, "missing_params", "missing SAMLResponse", "")
+		return
+	}
+	requestID, _ := readCookieDecoded(c, samlOAuthRequestIDCookieName)
+	if requestID == "" && !cfg.AllowIdPInitiated {
+		redirectOAuthError(c, frontendCallback, "invalid_state", "missing saml request state", "")
+		return
+	}
+
+	sp, err := buildSAMLServiceProvider(cfg)
+	if err != nil {
+		log.Printf("[SAML] service provider config invalid: %v", err)
+		redirectOAuthError(c, frontendCallback, "config_error", "saml service provider is misconfigured", "")
+		return
+	}
+	decoded, err := base64.StdEncoding.DecodeString(rawResponse)
+	if err != nil {
+		redirectOAuthError(c, frontendCallback, "invalid_assertion", "malformed SAMLResponse", "")
+		return
+	}
+	assertion, err := sp.ParseXMLResponse(decoded, []string{requestID})
+	if err != nil {
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) && invalid.PrivateErr != nil {
+			log.Printf("[SAML] assertion rejected: %v", invalid.PrivateErr)
+		} else {
+			log.Printf("[SAML] assertion rejected: %v", err)
+		}
+		redirectOAuthError(c, frontendCallback, "invalid_assertion", "failed to validate saml assertion", "")
+		return
+	}
+
+	profile, err := parseSAMLAssertionProfile(cfg, assertion)
+	if err != nil {
+		redirectOAuthError(c, frontendCallback, "missing_subject", err.Error(), "")
+		return
+	}
+
+	redirectTo, _ := readCookieDecoded(c, samlOAuthRedirectCookie)
+	redirectTo = sanitizeFrontendRedirectPath(redirectTo)
+	if redirectTo == "" {
+		redirectTo = samlOAuthDefaultRedirectTo
+	}
+	intent, _ := readCookieDecoded(c, samlOAuthIntentCookieName)
+	intent = normalizeOAuthIntent(intent)
+	browserSessionKey, _ := readCookieDecoded(c, samlOAuthBrowserCookieName)
+	if browserSessionKey == "" {
+		browserSessionKey, _ = readOAuthPendingBrowserCookie(c)
+	}
+	if browserSessionKey == "" {
+		if requestID != "" {
+			redirectOAuthError(c, frontendCallback, "missing_browser_session", "missing oauth browser session", "")
+			return
+		}
+		// IdP 发起的登录没有经过 start，这里补建浏览器会话
+		browserSessionKey, err = generateOAuthPendingBrowserSession()
+		if err != nil {
+			redirectOAuthError(c, frontendCallback, "session_error", "failed to generate oauth browser session", "")
+			return
+		}
+		setOAuthPendingBrowserCookie(c, browserSessionKey, secureCookie)
+	}
+
+	email := samlSyntheticEmail(profile.Issuer, profile.Subject)
+	username := firstNonEmpty(profile.Username, profile.DisplayName, samlFallbackUsername(profile.Subject))
+	identityRef := service.PendingAuthIdentityKey{
+		ProviderType:    "saml",
+		ProviderKey:     profile.Issuer,
+		ProviderSubject: profile.Subject,
+	}
+	upstreamClaims := map[string]any{
+		"email":                  email,
+		"username":               username,
+		"subject":                profile.Subject,
+		"issuer":                 profile.Issuer,
+		"email_verified":         profile.Email != "",
+		"provider_fallback":      strings.TrimSpace(cfg.ProviderName),
+		"suggested_display_name": firstNonEmpty(profile.DisplayName, username),
+		"suggested_avatar_url":   "",
+	}
+	if profile.Email != "" && !strings.EqualFold(profile.Email, email) {
+		upstreamClaims["compat_email"] = profile.Email
+	}
+	if profile.Mapping.Role != "" {
+		upstreamClaims[samlClaimRole] = profile.Mapping.Role
+	}
+	if profile.Mapping.SyncGroups {
+		upstreamClaims[samlClaimAllowedGroups] = profile.Mapping.AllowedGroupIDs
+	}
+
+	if intent == oauthIntentBindCurrentUser {
+		targetUserID, err := h.readOAuthBindUserIDFromCookie(c, samlOAuthBindUserCookieName)
+		if err != nil {
+			redirectOAuthError(c, frontendCallback, "invalid_state", "invalid oauth bind target", "")
+			return
+		}
+		if err := h.createOAuthPendingSession(c, oauthPendingSessionPayload{
+			Intent:                 oauthIntentBindCurrentUser,
+			Identity:               identityRef,
+			TargetUserID:           &targetUserID,
+			ResolvedEmail:          email,
+			RedirectTo:             redirectTo,
+			BrowserSessionKey:      browserSessionKey,
+			UpstreamIdentityClaims: upstreamClaims,
+			CompletionResponse: map[string]any{
+				"redirect": redirectTo,
+			},
+		}); err != nil {
+			redirectOAuthError(c, frontendCallback, "session_error", "failed to continue oauth bind", "")
+			return
+		}
+		redirectToFrontendCallback(c, frontendCallback)
+		return
+	}
+
+	existingIdentityUser, err := h.findOAuthIdentityUser(c.Request.Context(), identityRef)
+	if err != nil {
+		redirectOAuthError(c, frontendCallback, "session_error", infraerrors.Reason(err), infraerrors.Message(err))
+		return
+	}
+	if existingIdentityUser != nil {
+		if err := h.createOAuthPendingSession(c, oauthPendingSessionPayload{
+			Intent:                 oauthIntentLogin,
+			Identity:               identityRef,
+			TargetUserID:           &existingIdentityUser.ID,
+			ResolvedEmail:          existingIdentityUser.Email,
+			RedirectTo:             redirectTo,
+			BrowserSessionKey:      browserSessionKey,
+			UpstreamIdentityClaims: upstreamClaims,
+			CompletionResponse: map[string]any{
+				"redirect": redirectTo,
+			},
+		}); err != nil {
+			redirectOAuthError(c, frontendCallback, "session_error", "failed to continue oauth login", "")
+			return
+		}
+		redirectToFrontendCallback(c, frontendCallback)
+		return
+	}
+
+	// 与 OIDC 一致：同邮箱的本地账号只作为可绑定候选，需用户登录确认后才会绑定
+	compatEmailUser, err := h.findOIDCCompatEmailUser(c.Request.Context(), profile.Email)
+	if err != nil {
+		redirectOAuthError(c, frontendCallback, "session_error", infraerrors.Reason(err), infraerrors.Message(err))
+		return
+	}
+	if err := h.createOIDCOAuthChoicePendingSession(
+		c,
+		identityRef,
+		email,
+		email,
+		redirectTo,
+		browserSessionKey,
+		upstreamClaims,
+		profile.Email,
+		compatEmailUser,
+		h.isForceEmailOnThirdPartySignup(c.Request.Context()),
+	); err != nil {
+		redirectOAuthError(c, frontendCallback, "session_error", "failed to continue oauth login", "")
+		return
+	}
+	redirectToFrontendCallback(c, frontendCallback)
+}
+
+func (h *AuthHandler) getSAMLConfig() (config.SAMLConfig, error) {
+	if h == nil || h.cfg == nil {
+		return config.SAMLConfig{}, infraerrors.ServiceUnavailable("CONFIG_NOT_READY", "config not loaded")
+	}
+	if !h.cfg.SAML.Enabled {
+		return config.SAMLConfig{}, infraerrors.NotFound("OAUTH_DISABLED", "saml login is disabled")
+	}
+	return h.cfg.SAML, nil
+}
+
+// buildSAMLServiceProvider 按配置组装 SP；登录频率低，每次请求重新读取证书与 IdP metadata，
+// 轮换文件后无需重启。
+func buildSAMLServiceProvider(cfg config.SAMLConfig) (*saml.ServiceProvider, error) {
+	certificate, err := loadSAMLCertificate(cfg.CertificateFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := loadSAMLPrivateKey(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	idpMetadata, err := loadSAMLIdPMetadata(cfg)
+	if err != nil {
+		return nil, err
+	}
+	acsURL, err := url.Parse(strings.TrimSpace(cfg.ACSURL))
+	if err != nil || acsURL.Host == "" {
+		return nil, fmt.Errorf("invalid saml acs_url")
+	}
+	metadataURL, err := url.Parse(strings.TrimSpace(cfg.MetadataURL))
+	if err != nil || metadataURL.Host == "" {
+		return nil, fmt.Errorf("invalid saml metadata_url")
+	}
+	return &saml.ServiceProvider{
+		EntityID:          strings.TrimSpace(cfg.EntityID),
+		Key:               key,
+		Certificate:       certificate,
+		MetadataURL:       *metadataURL,
+		AcsURL:            *acsURL,
+		IDPMetadata:       idpMetadata,
+		AuthnNameIDFormat: saml.UnspecifiedNameIDFormat,
+		AllowIDPInitiated: cfg.AllowIdPInitiated,
+		SignatureMethod:   samlSignatureMethodRSASHA256,
+	}, nil
+}
+
+func loadSAMLCertificate(path string) (*x509.Certificate, error) {
+	raw, err := os.ReadFile(strings.TrimSpace(path))
+	if err != nil {
+		return nil, fmt.Errorf("read saml certificate: %w", err)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("saml certificate is not a PEM certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func loadSAMLPrivateKey(path string) (*rsa.PrivateKey, error) {
+	raw, err := os.ReadFile(strings.TrimSpace(path))
+	if err != nil {
+		return nil, fmt.Errorf("read saml private key: %w", err)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("saml private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse saml private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("saml private key must be RSA")
+	}
+	return key, nil
+}
+
+func loadSAMLIdPMetadata(cfg config.SAMLConfig) (*saml.EntityDescriptor, error) {
+	raw := []byte(strings.TrimSpace(cfg.IdPMetadataXML))
+	if len(raw) == 0 {
+		data, err := os.ReadFile(strings.TrimSpace(cfg.IdPMetadataFile))
+		if err != nil {
+			return nil, fmt.Errorf("read saml idp metadata: %w", err)
+		}
+		raw = data
+	}
+	return parseSAMLIdPMetadata(raw)
+}
+
+// parseSAMLIdPMetadata 解析 IdP metadata；聚合 metadata（EntitiesDescriptor）取第一个 IdP。
+func parseSAMLIdPMetadata(raw []byte) (*saml.EntityDescriptor, error) {
+	var entity saml.EntityDescriptor
+	if err := xml.Unmarshal(raw, &entity); err == nil && len(entity.IDPSSODescriptors) > 0 {
+		return &entity, nil
+	}
+	var entities saml.EntitiesDescriptor
+	if err := xml.Unmarshal(raw, &entities); err != nil {
+		return nil, fmt.Errorf("parse saml idp metadata: %w", err)
+	}
+	for i := range entities.EntityDescriptors {
+		if len(entities.EntityDescriptors[i].IDPSSODescriptors) > 0 {
+			return &entities.EntityDescriptors[i], nil
+		}
+	}
+	return nil, fmt.Errorf("saml idp metadata has no IDPSSODescriptor")
+}
+
+// parseSAMLAssertionProfile 从已验签的断言提取身份与角色/分组映射。
+func parseSAMLAssertionProfile(cfg config.SAMLConfig, assertion *saml.Assertion) (*samlAssertionProfile, error) {
+	if assertion == nil {
+		return nil, fmt.Errorf("missing saml assertion")
+	}
+	profile := &samlAssertionProfile{Issuer: strings.TrimSpace(assertion.Issuer.Value)}
+	if profile.Issuer == "" {
+		return nil, fmt.Errorf("missing saml issuer")
+	}
+
+	var nameID *saml.NameID
+	if assertion.Subject != nil {
+		nameID = assertion.Subject.NameID
+	}
+	if attr := strings.TrimSpace(cfg.SubjectAttribute); attr != "" {
+		profile.Subject = samlFirstAttributeValue(assertion, attr)
+	} else if nameID != nil {
+		// transient NameID 每次登录都会变化，无法作为稳定身份
+		if nameID.Format == string(saml.TransientNameIDFormat) {
+			return nil, fmt.Errorf("transient NameID cannot identify a user; configure saml.subject_attribute")
+		}
+		profile.Subject = strings.TrimSpace(nameID.Value)
+	}
+	if profile.Subject == "" {
+		return nil, fmt.Errorf("missing saml subject")
+	}
+
+	profile.Email = strings.ToLower(samlFirstAttributeValue(assertion, samlAttributeNames(cfg.EmailAttribute, samlDefaultEmailAttributes)...))
+	if profile.Email == "" && nameID != nil && nameID.Format == string(saml.EmailAddressNameIDFormat) {
+		profile.Email = strings.ToLower(strings.TrimSpace(nameID.Value))
+	}
+	profile.Username = samlFirstAttributeValue(assertion, samlAttributeNames(cfg.UsernameAttribute, samlDefaultUsernameAttributes)...)
+	profile.DisplayName = samlFirstAttributeValue(assertion, samlAttributeNames(cfg.DisplayNameAttribute, samlDefaultDisplayNameAttributes)...)
+
+	if attr := strings.TrimSpace(cfg.RoleAttribute); attr != "" {
+		profile.Mapping.Role = samlMappedRole(samlAttributeValues(assertion, attr), cfg.AdminRoleValues)
+	}
+	if attr := strings.TrimSpace(cfg.GroupsAttribute); attr != "" && len(cfg.GroupMapping) > 0 {
+		profile.Mapping.SyncGroups = true
+		profile.Mapping.AllowedGroupIDs = samlMappedGroupIDs(samlAttributeValues(assertion, attr), cfg.GroupMapping)
+	}
+	return profile, nil
+}
+
+func samlMappedRole(values []string, adminValues []string) string {
+	for _, value := range values {
+		if containsString(adminValues, value) {
+			return service.RoleAdmin
+		}
+	}
+	return service.RoleUser
+}
+
+// samlMappedGroupIDs 把 IdP 分组值映射为本地分组 ID；viper 会把 map 键转成小写，因此按不区分大小写匹配。
+func samlMappedGroupIDs(values []string, mapping map[string][]int64) []int64 {
+	seen := make(map[int64]struct{})
+	for _, value := range values {
+		for key, groupIDs := range mapping {
+			if !strings.EqualFold(strings.TrimSpace(key), value) {
+				continue
+			}
+			for _, id := range groupIDs {
+				if id > 0 {
+					seen[id] = struct{}{}
+				}
+			}
+		}
+	}
+	out := make([]int64, 0, len(seen))
+	for id := range seen {
+		out = append(out, id)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// samlUserMappingFromClaims 还原 ACS 阶段写入 pending session 的映射结果（经 JSON 往返后为 []any）。
+func samlUserMappingFromClaims(claims map[string]any) service.SAMLUserMapping {
+	mapping := service.SAMLUserMapping{Role: pendingSessionStringValue(claims, samlClaimRole)}
+	raw, ok := claims[samlClaimAllowedGroups]
+	if !ok {
+		return mapping
+	}
+	mapping.SyncGroups = true
+	appendID := func(id int64) {
+		if id > 0 {
+			mapping.AllowedGroupIDs = append(mapping.AllowedGroupIDs, id)
+		}
+	}
+	switch values := raw.(type) {
+	case []int64:
+		for _, id := range values {
+			appendID(id)
+		}
+	case []any:
+		for _, value := range values {
+			switch v := value.(type) {
+			case float64:
+				appendID(int64(v))
+			case int64:
+				appendID(v)
+			case json.Number:
+				id, _ := v.Int64()
+				appendID(id)
+			case string:
+				id, _ := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+				appendID(id)
+			}
+		}
+	}
+	return mapping
+}
+
+func samlAttributeNames(configured string, defaults []string) []string {
+	if configured = strings.TrimSpace(configured); configured != "" {
+		return []string{configured}
+	}
+	return defaults
+}
+
+// samlAttributeValues 返回第一个命中的属性的全部非空值；Name 精确匹配，FriendlyName 不区分大小写。
+func samlAttributeValues(assertion *saml.Assertion, names ...string) []string {
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		for _, statement := range assertion.AttributeStatements {
+			for _, attr := range statement.Attributes {
+				if attr.Name != name && !strings.EqualFold(attr.FriendlyName, name) {
+					continue
+				}
+				values := make([]string, 0, len(attr.Values))
+				for _, v := range attr.Values {
+					if value := strings.TrimSpace(v.Value); value != "" {
+						values = append(values, value)
+					}
+				}
+				if len(values) > 0 {
+					return values
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func samlFirstAttributeValue(assertion *saml.Assertion, names ...string) string {
+	values := samlAttributeValues(assertion, names...)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func samlSyntheticEmail(issuer, subject string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(issuer) + "\x1f" + strings.TrimSpace(subject)))
+	return "saml-" + hex.EncodeToString(sum[:16]) + service.SAMLSyntheticEmailDomain
+}
+
+func samlFallbackUsername(subject string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(subject)))
+	return "saml_" + hex.EncodeToString(sum[:])[:12]
+}
+
+// samlSetCookie 写入 SAML 流程 cookie。ACS 是跨站 POST，HTTPS 下必须 SameSite=None 才会被带回；
+// 纯 HTTP（本地调试）浏览器不接受非 Secure 的 None，退回 Lax。
+func samlSetCookie(c *gin.Context, name, value string, maxAgeSec int, secure bool) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     samlOAuthCookiePath,
+		MaxAge:   maxAgeSec,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: samlCookieSameSite(secure),
+	})
+}
+
+func samlClearCookie(c *gin.Context, name string, secure bool) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     samlOAuthCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: samlCookieSameSite(secure),
+	})
+}
+
+func samlCookieSameSite(secure bool) http.SameSite {
+	if secure {
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteLaxMode
+}
@@ -0,0 +1,475 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/ent/pendingauthsession"
+	"github.com/Wei-Shaw/sub2api/ent/userallowedgroup"
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/crewjam/saml"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	samlTestIdPEntityID = "https://idp.example.test/metadata"
+	samlTestSPEntityID  = "https://sp.example.test/api/v1/auth/oauth/saml/metadata"
+	samlTestACSURL      = "https://sp.example.test/api/v1/auth/oauth/saml/acs"
+)
+
+// samlTestIdP 是本地测试 IdP：用 crewjam 的 IdentityProvider 处理 SP 发出的 AuthnRequest 并签发断言。
+type samlTestIdP struct {
+	idp *saml.IdentityProvider
+	sp  *saml.EntityDescriptor
+}
+
+func (p *samlTestIdP) GetServiceProvider(_ *http.Request, serviceProviderID string) (*saml.EntityDescriptor, error) {
+	if p.sp == nil || p.sp.EntityID != serviceProviderID {
+		return nil, os.ErrNotExist
+	}
+	return p.sp, nil
+}
+
+func newSAMLTestKeyPair(t *testing.T, dir, name, commonName string) (*rsa.PrivateKey, *x509.Certificate, string, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	certPath := filepath.Join(dir, name+".crt")
+	keyPath := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0o600))
+	return key, cert, certPath, keyPath
+}
+
+func newSAMLTestFixture(t *testing.T) (config.SAMLConfig, *samlTestIdP) {
+	t.Helper()
+
+	dir := t.TempDir()
+	_, _, spCertPath, spKeyPath := newSAMLTestKeyPair(t, dir, "sp", "sp.example.test")
+	idpKey, idpCert, _, _ := newSAMLTestKeyPair(t, dir, "idp", "idp.example.test")
+
+	metadataURL, err := url.Parse(samlTestIdPEntityID)
+	require.NoError(t, err)
+	ssoURL, err := url.Parse("https://idp.example.test/sso")
+	require.NoError(t, err)
+	fixture := &samlTestIdP{}
+	fixture.idp = &saml.IdentityProvider{
+		Key:                     idpKey,
+		Certificate:             idpCert,
+		MetadataURL:             *metadataURL,
+		SSOURL:                  *ssoURL,
+		ServiceProviderProvider: fixture,
+	}
+	idpMetadata, err := xmlMarshalSAMLMetadata(fixture.idp.Metadata())
+	require.NoError(t, err)
+
+	cfg := config.SAMLConfig{
+		Enabled:             true,
+		ProviderName:        "Corp SSO",
+		EntityID:            samlTestSPEntityID,
+		ACSURL:              samlTestACSURL,
+		MetadataURL:         samlTestSPEntityID,
+		CertificateFile:     spCertPath,
+		PrivateKeyFile:      spKeyPath,
+		FrontendRedirectURL: "/auth/saml/callback",
+		IdPMetadataXML:      idpMetadata,
+		EmailAttribute:      "eduPersonPrincipalName",
+		RoleAttribute:       "role",
+		AdminRoleValues:     []string{"sub2api-admins"},
+		GroupsAttribute:     "eduPersonAffiliation",
+	}
+	sp, err := buildSAMLServiceProvider(cfg)
+	require.NoError(t, err)
+	// 去掉 SP 的加密证书，让 IdP 签发明文断言，便于构造篡改场景
+	fixture.sp = sp.Metadata()
+	for i := range fixture.sp.SPSSODescriptors {
+		keys := fixture.sp.SPSSODescriptors[i].KeyDescriptors[:0]
+		for _, key := range fixture.sp.SPSSODescriptors[i].KeyDescriptors {
+			if key.Use != "encryption" {
+				keys = append(keys, key)
+			}
+		}
+		fixture.sp.SPSSODescriptors[i].KeyDescriptors = keys
+	}
+	return cfg, fixture
+}
+
+func xmlMarshalSAMLMetadata(descriptor *saml.EntityDescriptor) (string, error) {
+	raw, err := xml.Marshal(descriptor)
+	return string(raw), err
+}
+
+// issue 按 SP 的 AuthnRequest 签发 SAMLResponse；mutate 可在签名前改写断言以构造异常场景。
+func (p *samlTestIdP) issue(t *testing.T, authURL string, session *saml.Session, mutate func(*saml.Assertion)) string {
+	t.Helper()
+
+	idpReq, err := saml.NewIdpAuthnRequest(p.idp, httptest.NewRequest(http.MethodGet, authURL, nil))
+	require.NoError(t, err)
+	require.NoError(t, idpReq.Validate())
+	require.NoError(t, saml.DefaultAssertionMaker{}.MakeAssertion(idpReq, session))
+	if mutate != nil {
+		mutate(idpReq.Assertion)
+	}
+	require.NoError(t, idpReq.MakeAssertionEl())
+	form, err := idpReq.PostBinding()
+	require.NoError(t, err)
+	require.Equal(t, samlTestACSURL, form.URL)
+	return form.SAMLResponse
+}
+
+func samlTestSession(groups ...string) *saml.Session {
+	return &saml.Session{
+		ID:           "idp-session",
+		CreateTime:   time.Now(),
+		ExpireTime:   time.Now().Add(time.Hour),
+		Index:        "idx-1",
+		NameID:       "alice-123",
+		NameIDFormat: string(saml.PersistentNameIDFormat),
+		UserName:     "alice",
+		UserEmail:    "Alice@Example.com",
+		Groups:       groups,
+		CustomAttributes: []saml.Attribute{{
+			Name:   "role",
+			Values: []saml.AttributeValue{{Type: "xs:string", Value: "sub2api-admins"}},
+		}},
+	}
+}
+
+func startSAMLTestLogin(t *testing.T, handler *AuthHandler) (string, []*http.Cookie) {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(recorder)
+	ginCtx.Request = httptest.NewRequest(http.MethodGet, "/api/v1/auth/oauth/saml/start?redirect=/keys", nil)
+
+	handler.SAMLOAuthStart(ginCtx)
+
+	require.Equal(t, http.StatusFound, recorder.Code)
+	location := recorder.Header().Get("Location")
+	require.True(t, strings.HasPrefix(location, "https://idp.example.test/sso?"), location)
+	parsed, err := url.Parse(location)
+	require.NoError(t, err)
+	require.NotEmpty(t, parsed.Query().Get("Signature"))
+	require.Equal(t, samlSignatureMethodRSASHA256, parsed.Query().Get("SigAlg"))
+	return location, recorder.Result().Cookies()
+}
+
+func postSAMLTestAssertion(t *testing.T, handler *AuthHandler, samlResponse string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+
+	form := url.Values{"SAMLResponse": {samlResponse}}
+	recorder := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(recorder)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/oauth/saml/acs", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, cookie := range cookies {
+		if cookie.MaxAge >= 0 {
+			req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+		}
+	}
+	ginCtx.Request = req
+
+	handler.SAMLAssertionConsumer(ginCtx)
+	// POST 的重定向不写响应体，gin 的引擎会在链路结束时补写状态码，测试上下文需手动触发
+	ginCtx.Writer.WriteHeaderNow()
+	return recorder
+}
+
+func newSAMLTestHandler(t *testing.T) (*AuthHandler, *samlTestIdP) {
+	t.Helper()
+
+	handler, _ := newOAuthPendingFlowTestHandler(t, false)
+	samlCfg, idp := newSAMLTestFixture(t)
+	handler.cfg = &config.Config{SAML: samlCfg}
+	return handler, idp
+}
+
+func TestSAMLMetadataPublishesSigningCertAndACS(t *testing.T) {
+	handler, _ := newSAMLTestHandler(t)
+
+	recorder := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(recorder)
+	ginCtx.Request = httptest.NewRequest(http.MethodGet, "/api/v1/auth/oauth/saml/metadata", nil)
+
+	handler.SAMLMetadata(ginCtx)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "application/samlmetadata+xml", recorder.Header().Get("Content-Type"))
+	body := recorder.Body.String()
+	require.Contains(t, body, samlTestSPEntityID)
+	require.Contains(t, body, samlTestACSURL)
+	require.Contains(t, body, "X509Certificate")
+}
+
+func TestSAMLOAuthStartReturnsNotFoundWhenDisabled(t *testing.T) {
+	handler, _ := newOAuthPendingFlowTestHandler(t, false)
+	handler.cfg = &config.Config{}
+
+	recorder := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(recorder)
+	ginCtx.Request = httptest.NewRequest(http.MethodGet, "/api/v1/auth/oauth/saml/start", nil)
+
+	handler.SAMLOAuthStart(ginCtx)
+
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestSAMLAssertionConsumerNewIdentityCreatesChoicePendingSession(t *testing.T) {
+	handler, idp := newSAMLTestHandler(t)
+	ctx := context.Background()
+
+	authURL, cookies := startSAMLTestLogin(t, handler)
+	samlResponse := idp.issue(t, authURL, samlTestSession(), nil)
+	recorder := postSAMLTestAssertion(t, handler, samlResponse, cookies)
+
+	require.Equal(t, http.StatusFound, recorder.Code)
+	require.Equal(t, "/auth/saml/callback", recorder.Header().Get("Location"))
+	sessionCookie := findCookie(recorder.Result().Cookies(), oauthPendingSessionCookieName)
+	require.NotNil(t, sessionCookie)
+
+	client := handler.entClient()
+	session, err := client.PendingAuthSession.Query().
+		Where(pendingauthsession.ProviderTypeEQ("saml")).
+		Only(ctx)
+	require.NoError(t, err)
+	require.Equal(t, samlTestIdPEntityID, session.ProviderKey)
+	require.Equal(t, "alice-123", session.ProviderSubject)
+	require.Equal(t, "/keys", session.RedirectTo)
+	require.True(t, strings.HasSuffix(session.ResolvedEmail, service.SAMLSyntheticEmailDomain))
+	require.Equal(t, "alice@example.com", session.UpstreamIdentityClaims["compat_email"])
+	require.Equal(t, service.RoleAdmin, session.UpstreamIdentityClaims[samlClaimRole])
+}
+
+func TestSAMLAssertionConsumerExistingIdentityLoginAppliesRoleAndGroups(t *testing.T) {
+	handler, idp := newSAMLTestHandler(t)
+	ctx := context.Background()
+	client := handler.entClient()
+
+	keep, err := client.Group.Create().SetName("saml-keep").Save(ctx)
+	require.NoError(t, err)
+	stale, err := client.Group.Create().SetName("saml-stale").Save(ctx)
+	require.NoError(t, err)
+	handler.cfg.SAML.GroupMapping = map[string][]int64{"staff": {keep.ID}}
+
+	userEntity, err := client.User.Create().
+		SetEmail("saml-existing@example.com").
+		SetUsername("alice").
+		SetPasswordHash("hash").
+		SetRole(service.RoleUser).
+		SetStatus(service.StatusActive).
+		Save(ctx)
+	require.NoError(t, err)
+	_, err = client.AuthIdentity.Create().
+		SetUserID(userEntity.ID).
+		SetProviderType("saml").
+		SetProviderKey(samlTestIdPEntityID).
+		SetProviderSubject("alice-123").
+		SetMetadata(map[string]any{}).
+		Save(ctx)
+	require.NoError(t, err)
+	_, err = client.UserAllowedGroup.Create().SetUserID(userEntity.ID).SetGroupID(stale.ID).Save(ctx)
+	require.NoError(t, err)
+
+	authURL, cookies := startSAMLTestLogin(t, handler)
+	samlResponse := idp.issue(t, authURL, samlTestSession("Staff"), nil)
+	acsRecorder := postSAMLTestAssertion(t, handler, samlResponse, cookies)
+	require.Equal(t, http.StatusFound, acsRecorder.Code)
+	require.Equal(t, "/auth/saml/callback", acsRecorder.Header().Get("Location"))
+
+	sessionCookie := findCookie(acsRecorder.Result().Cookies(), oauthPendingSessionCookieName)
+	require.NotNil(t, sessionCookie)
+	browserCookie := findCookie(cookies, oauthPendingBrowserCookieName)
+	require.NotNil(t, browserCookie)
+
+	recorder := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(recorder)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/oauth/pending/exchange", bytes.NewBufferString(`{"adopt_display_name":false,"adopt_avatar":false}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: oauthPendingSessionCookieName, Value: sessionCookie.Value})
+	req.AddCookie(&http.Cookie{Name: oauthPendingBrowserCookieName, Value: browserCookie.Value})
+	ginCtx.Request = req
+
+	handler.ExchangePendingOAuthCompletion(ginCtx)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	data := decodeJSONResponseData(t, recorder)
+	require.NotEmpty(t, data["access_token"])
+
+	storedUser, err := client.User.Get(ctx, userEntity.ID)
+	require.NoError(t, err)
+	require.Equal(t, service.RoleAdmin, storedUser.Role)
+	groupIDs, err := client.UserAllowedGroup.Query().
+		Where(userallowedgroup.UserIDEQ(userEntity.ID)).
+		Select(userallowedgroup.FieldGroupID).
+		Ints(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int{int(keep.ID)}, groupIDs)
+}
+
+func TestSAMLAssertionConsumerRejectsInvalidAssertions(t *testing.T) {
+	cases := []struct {
+		name   string
+		mutate func(*saml.Assertion)
+		tamper func(*testing.T, string) string
+	}{
+		{
+			name: "tampered signature",
+			tamper: func(t *testing.T, encoded string) string {
+				raw, err := base64.StdEncoding.DecodeString(encoded)
+				require.NoError(t, err)
+				require.Contains(t, string(raw), "alice-123")
+				return base64.StdEncoding.EncodeToString(bytes.ReplaceAll(raw, []byte("alice-123"), []byte("mallory-1")))
+			},
+		},
+		{
+			name: "wrong audience",
+			mutate: func(assertion *saml.Assertion) {
+				assertion.Conditions.AudienceRestrictions = []saml.AudienceRestriction{{
+					Audience: saml.Audience{Value: "https://other-sp.example.test/metadata"},
+				}}
+			},
+		},
+		{
+			name: "expired",
+			mutate: func(assertion *saml.Assertion) {
+				past := time.Now().Add(-2 * time.Hour)
+				assertion.Conditions.NotBefore = past.Add(-time.Hour)
+				assertion.Conditions.NotOnOrAfter = past
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, idp := newSAMLTestHandler(t)
+
+			authURL, cookies := startSAMLTestLogin(t, handler)
+			samlResponse := idp.issue(t, authURL, samlTestSession(), tc.mutate)
+			if tc.tamper != nil {
+				samlResponse = tc.tamper(t, samlResponse)
+			}
+			recorder := postSAMLTestAssertion(t, handler, samlResponse, cookies)
+
+			require.Equal(t, http.StatusFound, recorder.Code)
+			require.Contains(t, recorder.Header().Get("Location"), "invalid_assertion")
+			require.Nil(t, findCookie(recorder.Result().Cookies(), oauthPendingSessionCookieName))
+			count, err := handler.entClient().PendingAuthSession.Query().
+				Where(pendingauthsession.ProviderTypeEQ("saml")).
+				Count(context.Background())
+			require.NoError(t, err)
+			require.Zero(t, count)
+		})
+	}
+}
+
+func TestSAMLAssertionConsumerRejectsMissingRequestState(t *testing.T) {
+	handler, idp := newSAMLTestHandler(t)
+
+	authURL, cookies := startSAMLTestLogin(t, handler)
+	samlResponse := idp.issue(t, authURL, samlTestSession(), nil)
+	filtered := make([]*http.Cookie, 0, len(cookies))
+	for _, cookie := range cookies {
+		if cookie.Name != samlOAuthRequestIDCookieName {
+			filtered = append(filtered, cookie)
+		}
+	}
+	recorder := postSAMLTestAssertion(t, handler, samlResponse, filtered)
+
+	require.Equal(t, http.StatusFound, recorder.Code)
+	require.Contains(t, recorder.Header().Get("Location"), "invalid_state")
+}
+
+func TestParseSAMLAssertionProfileMapsAttributes(t *testing.T) {
+	cfg := config.SAMLConfig{
+		RoleAttribute:   "memberOf",
+		AdminRoleValues: []string{"Admins"},
+		GroupsAttribute: "memberOf",
+		GroupMapping:    map[string][]int64{"engineering": {3, 1}, "admins": {1}},
+	}
+	assertion := &saml.Assertion{
+		Issuer: saml.Issuer{Value: "https://idp.example.test"},
+		Subject: &saml.Subject{NameID: &saml.NameID{
+			Format: string(saml.EmailAddressNameIDFormat),
+			Value:  "Bob@Example.com",
+		}},
+		AttributeStatements: []saml.AttributeStatement{{
+			Attributes: []saml.Attribute{
+				{Name: "urn:oid:0.9.2342.19200300.100.1.1", FriendlyName: "uid", Values: []saml.AttributeValue{{Value: "bob"}}},
+				{Name: "displayName", Values: []saml.AttributeValue{{Value: "Bob Builder"}}},
+				{Name: "memberOf", Values: []saml.AttributeValue{{Value: "admins"}, {Value: "Engineering"}, {Value: "unknown"}}},
+			},
+		}},
+	}
+
+	profile, err := parseSAMLAssertionProfile(cfg, assertion)
+	require.NoError(t, err)
+	require.Equal(t, "Bob@Example.com", profile.Subject)
+	require.Equal(t, "bob@example.com", profile.Email)
+	require.Equal(t, "bob", profile.Username)
+	require.Equal(t, "Bob Builder", profile.DisplayName)
+	require.Equal(t, service.RoleAdmin, profile.Mapping.Role)
+	require.True(t, profile.Mapping.SyncGroups)
+	require.Equal(t, []int64{1, 3}, profile.Mapping.AllowedGroupIDs)
+
+	roundTrip := samlUserMappingFromClaims(map[string]any{
+		samlClaimRole:          service.RoleAdmin,
+		samlClaimAllowedGroups: []any{float64(1), float64(3)},
+	})
+	require.Equal(t, profile.Mapping, roundTrip)
+}
+
+func TestParseSAMLAssertionProfileRejectsTransientNameID(t *testing.T) {
+	assertion := &saml.Assertion{
+		Issuer: saml.Issuer{Value: "https://idp.example.test"},
+		Subject: &saml.Subject{NameID: &saml.NameID{
+			Format: string(saml.TransientNameIDFormat),
+			Value:  "_random",
+		}},
+	}
+
+	_, err := parseSAMLAssertionProfile(config.SAMLConfig{}, assertion)
+	require.Error(t, err)
+
+	profile, err := parseSAMLAssertionProfile(config.SAMLConfig{SubjectAttribute: "employeeNumber"}, &saml.Assertion{
+		Issuer:  assertion.Issuer,
+		Subject: assertion.Subject,
+		AttributeStatements: []saml.AttributeStatement{{
+			Attributes: []saml.Attribute{{Name: "employeeNumber", Values: []saml.AttributeValue{{Value: "E-42"}}}},
+		}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "E-42", profile.Subject)
+	require.Empty(t, profile.Mapping.Role)
+	require.False(t, profile.Mapping.SyncGroups)
+}
@@ -0,0 +1,506 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"go.uber.org/zap"
+)
+
+const (
+	backgroundResponsePollInterval      = 500 * time.Millisecond
+	backgroundResponseKeepaliveInterval = 15 * time.Second
+	// backgroundResponseMaxErrorBody 限制非 SSE 上游响应（通常是错误 JSON）的缓冲大小。
+	backgroundResponseMaxErrorBody = 1 << 20
+)
+
+// BackgroundResponseHandler runs `background: true` Responses requests inside
+// the gateway. The request is accepted immediately, executed on a worker
+// through the regular Responses handler (scheduling, failover, billing and
+// auditing included), and its events are persisted so that GET, cancel and
+// stream resumption can be served from any instance.
+type BackgroundResponseHandler struct {
+	responses    *service.BackgroundResponseService
+	openAI       *OpenAIGatewayHandler
+	execute      func(c *gin.Context)
+	pollInterval time.Duration
+}
+
+func NewBackgroundResponseHandler(responses *service.BackgroundResponseService, openAI *OpenAIGatewayHandler) *BackgroundResponseHandler {
+	h := &BackgroundResponseHandler{responses: responses, openAI: openAI, pollInterval: backgroundResponsePollInterval}
+	h.execute = h.executeWithGateway
+	return h
+}
+
+func (h *BackgroundResponseHandler) enabled() bool {
+	return h != nil && h.responses != nil && h.responses.Enabled() && h.execute != nil
+}
+
+// pollable mirrors AsyncImageHandler.pollable: accepted responses stay readable
+// after the feature is switched off.
+func (h *BackgroundResponseHandler) pollable() bool {
+	return h != nil && h.responses != nil && h.responses.Pollable()
+}
+
+// Submit takes over a POST /v1/responses request when it asks for background
+// mode. It returns false, with the request body restored, when the request
+// should continue through the synchronous Responses handler.
+func (h *BackgroundResponseHandler) Submit(c *gin.Context) bool {
+	if !h.enabled() || !isBareOpenAIResponsesPath(c) {
+		return false
+	}
+	var cfg *config.Config
+	if h.openAI != nil {
+		cfg = h.openAI.cfg
+	}
+	body, err := readLenientJSONRequestBodyWithPrealloc(c.Request, cfg)
+	if err != nil {
+		if maxErr, ok := extractMaxBytesError(err); ok {
+			backgroundResponseJSONError(c, http.StatusRequestEntityTooLarge, "invalid_request_error", buildBodyTooLargeMessage(maxErr.Limit))
+			return true
+		}
+		backgroundResponseJSONError(c, http.StatusBadRequest, "invalid_request_error", "Failed to read request body")
+		return true
+	}
+	restoreBackgroundResponseBody(c.Request, body)
+	if !gjson.GetBytes(body, "background").Bool() {
+		return false
+	}
+	model := gjson.GetBytes(body, "model").String()
+	if strings.TrimSpace(model) == "" {
+		// 交给同步 handler 输出统一的参数校验错误。
+		return false
+	}
+	apiKey, ok := middleware2.GetAPIKeyFromContext(c)
+	if !ok || apiKey == nil || apiKey.UserID <= 0 || apiKey.ID <= 0 {
+		backgroundResponseJSONError(c, http.StatusUnauthorized, "authentication_error", "Invalid API key")
+		return true
+	}
+
+	// 上游一律以流式执行：事件逐条落库，供续流与终态组装使用。
+	execBody, err := sjson.DeleteBytes(body, "background")
+	if err == nil {
+		execBody, err = sjson.SetBytes(execBody, "stream", true)
+	}
+	if err != nil {
+		backgroundResponseJSONError(c, http.StatusBadRequest, "invalid_request_error", "Failed to parse request body")
+		return true
+	}
+
+	owner := service.BackgroundResponseOwner{UserID: apiKey.UserID, APIKeyID: apiKey.ID}
+	record, err := h.responses.Create(c.Request.Context(), owner, model)
+	if err != nil {
+		backgroundResponseError(c, err)
+		return true
+	}
+	taskCtx, writer, cancel := newBackgroundResponseContext(c, execBody, h.responses.ExecutionTimeout())
+	go h.run(record.ID, taskCtx, writer, cancel)
+
+	if gjson.GetBytes(body, "stream").Bool() {
+		h.streamEvents(c, owner, record.ID, -1)
+		return true
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/json", record.Response)
+	return true
+}
+
+// Get serves GET /v1/responses/:response_id, optionally as a resumable event
+// stream (`?stream=true&starting_after=N`).
+func (h *BackgroundResponseHandler) Get(c *gin.Context) {
+	if !h.pollable() {
+		backgroundResponseError(c, service.ErrBackgroundResponseNotFound)
+		return
+	}
+	owner, ok := backgroundResponseOwner(c)
+	if !ok {
+		return
+	}
+	id := c.Param("response_id")
+	record, err := h.responses.Get(c.Request.Context(), owner, id)
+	if err != nil {
+		backgroundResponseError(c, err)
+		return
+	}
+	if stream, _ := strconv.ParseBool(c.Query("stream")); stream {
+		startingAfter := int64(-1)
+		if raw := strings.TrimSpace(c.Query("starting_after")); raw != "" {
+			parsed, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || parsed < 0 {
+				backgroundResponseJSONError(c, http.StatusBadRequest, "invalid_request_error", "starting_after must be a non-negative integer")
+				return
+			}
+			startingAfter = parsed
+		}
+		h.streamEvents(c, owner, record.ID, startingAfter)
+		return
+	}
+	h.writeResponseObject(c, record)
+}
+
+// Cancel serves POST /v1/responses/{id}/cancel for gateway-managed responses.
+// It returns false for any other subpath so the request keeps its existing
+// upstream forwarding behavior.
+func (h *BackgroundResponseHandler) Cancel(c *gin.Context) bool {
+	id, ok := backgroundResponseCancelTarget(c.Param("subpath"))
+	if !ok || !h.pollable() {
+		return false
+	}
+	owner, ok := backgroundResponseOwner(c)
+	if !ok {
+		return true
+	}
+	record, err := h.responses.Cancel(c.Request.Context(), owner, id)
+	if err != nil {
+		backgroundResponseError(c, err)
+		return true
+	}
+	h.writeResponseObject(c, record)
+	return true
+}
+
+func (h *BackgroundResponseHandler) writeResponseObject(c *gin.Context, record *service.BackgroundResponseRecord) {
+	response, err := h.responses.ResponseObject(c.Request.Context(), record)
+	if err != nil {
+		backgroundResponseError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/json", response)
+}
+
+// streamEvents replays persisted events after startingAfter and then follows
+// the live event list until the response reaches a terminal status.
+func (h *BackgroundResponseHandler) streamEvents(c *gin.Context, owner service.BackgroundResponseOwner, id string, startingAfter int64) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	deadline := time.Now().Add(h.responses.ExecutionTimeout())
+	lastWrite := time.Now()
+	next := startingAfter
+	for {
+		record, err := h.responses.Get(ctx, owner, id)
+		if err != nil {
+			writeBackgroundResponseStreamError(c, err)
+			return
+		}
+		events, err := h.responses.Events(ctx, record, next)
+		if err != nil {
+			writeBackgroundResponseStreamError(c, err)
+			return
+		}
+		for _, event := range events {
+			eventType := gjson.GetBytes(event, "type").String()
+			if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", eventType, event); err != nil {
+				return
+			}
+			if seq := gjson.GetBytes(event, "sequence_number"); seq.Exists() {
+				next = seq.Int()
+			} else {
+				next++
+			}
+		}
+		if len(events) > 0 {
+			c.Writer.Flush()
+			lastWrite = time.Now()
+			continue
+		}
+		if record.Terminal() || time.Now().After(deadline) {
+			return
+		}
+		if time.Since(lastWrite) >= backgroundResponseKeepaliveInterval {
+			if _, err := io.WriteString(c.Writer, ": keepalive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+			lastWrite = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(h.pollInterval):
+		}
+	}
+}
+
+func (h *BackgroundResponseHandler) executeWithGateway(c *gin.Context) {
+	if h.openAI == nil {
+		backgroundResponseJSONError(c, http.StatusServiceUnavailable, "api_error", "responses gateway is unavailable")
+		return
+	}
+	h.openAI.Responses(c)
+}
+
+func (h *BackgroundResponseHandler) run(id string, taskCtx *gin.Context, writer *backgroundResponseWriter, cancel context.CancelFunc) {
+	defer cancel()
+	ctx := context.Background()
+	run, err := h.responses.Start(ctx, id)
+	if err != nil {
+		logger.L().Error("background_response.start_failed", zap.String("response_id", id), zap.Error(err))
+		return
+	}
+	writer.run = run
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logger.L().Error("background_response.execution_panicked", zap.String("response_id", id), zap.Any("panic", recovered))
+			h.finishRun(id, run.Fail(ctx, backgroundResponseErrorPayload("server_error", "background response panicked")))
+		}
+	}()
+
+	done := make(chan struct{})
+	go h.watch(id, writer, cancel, done)
+	func() {
+		defer close(done)
+		h.execute(taskCtx)
+	}()
+	writer.flush(ctx)
+
+	status, body, sse := writer.result()
+	switch {
+	case run.Final() != nil:
+		h.finishRun(id, run.Complete(ctx, run.Final()))
+	case h.responses.CancelRequested(ctx, id):
+		h.finishRun(id, run.Cancelled(ctx))
+	case run.Failure() != nil:
+		h.finishRun(id, run.Fail(ctx, run.Failure()))
+	case !sse && status >= http.StatusBadRequest:
+		h.finishRun(id, run.Fail(ctx, extractImageTaskError(body)))
+	case !sse && status < http.StatusMultipleChoices && gjson.GetBytes(body, "object").String() == "response":
+		h.finishRun(id, run.Complete(ctx, json.RawMessage(body)))
+	case taskCtx.Request.Context().Err() != nil:
+		h.finishRun(id, run.Fail(ctx, backgroundResponseErrorPayload("timeout", "background response timed out")))
+	default:
+		h.finishRun(id, run.Fail(ctx, backgroundResponseErrorPayload("server_error", "upstream stream ended before the response completed")))
+	}
+}
+
+// watch periodically persists buffered events and aborts the execution once a
+// cancel request is observed from any instance.
+func (h *BackgroundResponseHandler) watch(id string, writer *backgroundResponseWriter, cancel context.CancelFunc, done <-chan struct{}) {
+	ticker := time.NewTicker(h.pollInterval)
+	defer ticker.Stop()
+	ctx := context.Background()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			writer.flush(ctx)
+			if h.responses.CancelRequested(ctx, id) {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+func (h *BackgroundResponseHandler) finishRun(id string, err error) {
+	if err != nil {
+		logger.L().Error("background_response.finish_store_failed", zap.String("response_id", id), zap.Error(err))
+	}
+}
+
+// backgroundResponseWriter stands in for the client connection of a background
+// execution. SSE output is split into events and buffered until the next flush;
+// any other output (typically an error JSON) is kept for the final outcome.
+type backgroundResponseWriter struct {
+	mu      sync.Mutex
+	header  http.Header
+	status  int
+	sse     bool
+	partial bytes.Buffer
+	body    bytes.Buffer
+	pending []json.RawMessage
+
+	flushMu sync.Mutex
+	run     *service.BackgroundResponseRun
+}
+
+func newBackgroundResponseWriter() *backgroundResponseWriter {
+	return &backgroundResponseWriter{header: make(http.Header)}
+}
+
+func (w *backgroundResponseWriter) Header() http.Header { return w.header }
+
+func (w *backgroundResponseWriter) WriteHeader(statusCode int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writeHeaderLocked(statusCode)
+}
+
+func (w *backgroundResponseWriter) writeHeaderLocked(statusCode int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = statusCode
+	w.sse = strings.HasPrefix(strings.ToLower(w.header.Get("Content-Type")), "text/event-stream")
+}
+
+func (w *backgroundResponseWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writeHeaderLocked(http.StatusOK)
+	if !w.sse {
+		if remaining := backgroundResponseMaxErrorBody - w.body.Len(); remaining > 0 {
+			if len(p) > remaining {
+				w.body.Write(p[:remaining])
+			} else {
+				w.body.Write(p)
+			}
+		}
+		return len(p), nil
+	}
+	w.partial.Write(bytes.ReplaceAll(p, []byte("\r\n"), []byte("\n")))
+	for {
+		buffered := w.partial.Bytes()
+		idx := bytes.Index(buffered, []byte("\n\n"))
+		if idx < 0 {
+			break
+		}
+		block := append([]byte(nil), buffered[:idx]...)
+		w.partial.Next(idx + 2)
+		if data := sseBlockData(block); len(data) > 0 && json.Valid(data) {
+			w.pending = append(w.pending, json.RawMessage(data))
+		}
+	}
+	return len(p), nil
+}
+
+// Flush satisfies http.Flusher; events are persisted on the watcher's cadence.
+func (w *backgroundResponseWriter) Flush() {}
+
+func (w *backgroundResponseWriter) flush(ctx context.Context) {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = nil
+	w.mu.Unlock()
+	if len(pending) == 0 || w.run == nil {
+		return
+	}
+	if err := w.run.Append(ctx, pending); err != nil {
+		logger.L().Warn("background_response.append_events_failed", zap.Error(err))
+	}
+}
+
+func (w *backgroundResponseWriter) result() (int, []byte, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return status, bytes.TrimSpace(w.body.Bytes()), w.sse
+}
+
+// sseBlockData joins the data lines of one SSE event block, skipping comments
+// and the `[DONE]` sentinel.
+func sseBlockData(block []byte) []byte {
+	var data [][]byte
+	for _, line := range bytes.Split(block, []byte("\n")) {
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		data = append(data, bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" ")))
+	}
+	joined := bytes.TrimSpace(bytes.Join(data, []byte("\n")))
+	if bytes.Equal(joined, []byte("[DONE]")) {
+		return nil
+	}
+	return joined
+}
+
+func newBackgroundResponseContext(c *gin.Context, body []byte, timeoutDuration time.Duration) (*gin.Context, *backgroundResponseWriter, context.CancelFunc) {
+	base := context.WithoutCancel(c.Request.Context())
+	executionCtx, cancel := context.WithTimeout(base, timeoutDuration)
+	request := c.Request.Clone(executionCtx)
+	restoreBackgroundResponseBody(request, body)
+
+	taskCtx := c.Copy()
+	writer := newBackgroundResponseWriter()
+	writerCtx, _ := gin.CreateTestContext(writer)
+	taskCtx.Writer = writerCtx.Writer
+	taskCtx.Request = request
+	return taskCtx, writer, cancel
+}
+
+func restoreBackgroundResponseBody(request *http.Request, body []byte) {
+	request.Body = io.NopCloser(bytes.NewReader(body))
+	request.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	request.ContentLength = int64(len(body))
+}
+
+// backgroundResponseCancelTarget extracts the response ID from a
+// `/{id}/cancel` subpath when the ID was issued by the gateway.
+func backgroundResponseCancelTarget(subpath string) (string, bool) {
+	id, action, ok := strings.Cut(strings.Trim(subpath, "/"), "/")
+	if !ok || action != "cancel" || !service.IsBackgroundResponseID(id) {
+		return "", false
+	}
+	return id, true
+}
+
+func backgroundResponseOwner(c *gin.Context) (service.BackgroundResponseOwner, bool) {
+	apiKey, ok := middleware2.GetAPIKeyFromContext(c)
+	if !ok || apiKey == nil || apiKey.UserID <= 0 || apiKey.ID <= 0 {
+		backgroundResponseJSONError(c, http.StatusUnauthorized, "authentication_error", "Invalid API key")
+		return service.BackgroundResponseOwner{}, false
+	}
+	return service.BackgroundResponseOwner{UserID: apiKey.UserID, APIKeyID: apiKey.ID}, true
+}
+
+func writeBackgroundResponseStreamError(c *gin.Context, err error) {
+	payload, _ := json.Marshal(gin.H{
+		"type":    "error",
+		"code":    infraerrors.Reason(err),
+		"message": infraerrors.Message(err),
+	})
+	_, _ = fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", payload)
+	c.Writer.Flush()
+}
+
+func backgroundResponseErrorPayload(code, message string) json.RawMessage {
+	data, _ := json.Marshal(gin.H{"code": code, "message": message})
+	return data
+}
+
+func backgroundResponseError(c *gin.Context, err error) {
+	status := infraerrors.Code(err)
+	if status <= 0 {
+		status = http.StatusInternalServerError
+	}
+	errType := "api_error"
+	if status == http.StatusNotFound {
+		errType = "invalid_request_error"
+	}
+	backgroundResponseJSONError(c, status, errType, infraerrors.Message(err))
+}
+
+func backgroundResponseJSONError(c *gin.Context, status int, errType, message string) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, gin.H{"error": gin.H{"type": errType, "message": message}})
+}
@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+type backgroundResponseMemoryStore struct {
+	mu        sync.Mutex
+	records   map[string]service.BackgroundResponseRecord
+	events    map[string][]json.RawMessage
+	cancelled map[string]bool
+}
+
+func newBackgroundResponseMemoryStore() *backgroundResponseMemoryStore {
+	return &backgroundResponseMemoryStore{
+		records:   make(map[string]service.BackgroundResponseRecord),
+		events:    make(map[string][]json.RawMessage),
+		cancelled: make(map[string]bool),
+	}
+}
+
+func (s *backgroundResponseMemoryStore) Save(_ context.Context, record *service.BackgroundResponseRecord, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[record.ID] = *record
+	return nil
+}
+
+func (s *backgroundResponseMemoryStore) Get(_ context.Context, id string) (*service.BackgroundResponseRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[id]
+	if !ok {
+		return nil, service.ErrBackgroundResponseNotFound
+	}
+	return &record, nil
+}
+
+func (s *backgroundResponseMemoryStore) AppendEvents(_ context.Context, id string, events []json.RawMessage, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events[id] = append(s.events[id], events...)
+	return nil
+}
+
+func (s *backgroundResponseMemoryStore) ListEvents(_ context.Context, id string, from int64) ([]json.RawMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := s.events[id]
+	if from >= int64(len(events)) {
+		return nil, nil
+	}
+	return append([]json.RawMessage(nil), events[from:]...), nil
+}
+
+func (s *backgroundResponseMemoryStore) DeleteEvents(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.events, id)
+	return nil
+}
+
+func (s *backgroundResponseMemoryStore) RequestCancel(_ context.Context, id string, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancelled[id] = true
+	return nil
+}
+
+func (s *backgroundResponseMemoryStore) CancelRequested(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cancelled[id], nil
+}
+
+type backgroundResponseObjectStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *backgroundResponseObjectStorage) Save(_ context.Context, key, _ string, data []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = append([]byte(nil), data...)
+	return "https://objects.example.test/" + key, nil
+}
+
+func (s *backgroundResponseObjectStorage) Load(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("object %s not found", key)
+	}
+	return data, nil
+}
+
+func newBackgroundResponseTestRouter(t *testing.T, execute func(c *gin.Context)) (*gin.Engine, *backgroundResponseMemoryStore, *backgroundResponseObjectStorage) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	store := newBackgroundResponseMemoryStore()
+	objects := &backgroundResponseObjectStorage{objects: make(map[string][]byte)}
+	uploader := service.NewImageResultUploader(objects, "gw/", 0, nil)
+	responses := service.NewBackgroundResponseService(store, func() (*service.ImageResultUploader, bool) {
+		return uploader, true
+	}, time.Hour, time.Minute)
+	h := &BackgroundResponseHandler{responses: responses, execute: execute, pollInterval: 10 * time.Millisecond}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(string(middleware2.ContextKeyAPIKey), &service.APIKey{ID: 9, UserID: 7})
+		c.Next()
+	})
+	router.POST("/v1/responses", func(c *gin.Context) {
+		if h.Submit(c) {
+			return
+		}
+		body, _ := io.ReadAll(c.Request.Body)
+		c.JSON(http.StatusTeapot, gin.H{"passthrough": string(body)})
+	})
+	router.POST("/v1/responses/*subpath", func(c *gin.Context) {
+		if h.Cancel(c) {
+			return
+		}
+		c.Status(http.StatusTeapot)
+	})
+	router.GET("/v1/responses/:response_id", h.Get)
+	return router, store, objects
+}
+
+func writeBackgroundResponseTestEvent(c *gin.Context, payload string) {
+	_, _ = fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", gjson.Get(payload, "type").String(), payload)
+	c.Writer.Flush()
+}
+
+func TestBackgroundResponseSubmitPollAndResume(t *testing.T) {
+	release := make(chan struct{})
+	var upstreamBody string
+	router, store, objects := newBackgroundResponseTestRouter(t, func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		upstreamBody = string(body)
+		<-release
+		c.Header("Content-Type", "text/event-stream")
+		c.Status(http.StatusOK)
+		writeBackgroundResponseTestEvent(c, `{"type":"response.created","response":{"id":"resp_upstream","status":"in_progress","output":[]}}`)
+		writeBackgroundResponseTestEvent(c, `{"type":"response.output_text.delta","delta":"hello"}`)
+		writeBackgroundResponseTestEvent(c, `{"type":"response.completed","response":{"id":"resp_upstream","status":"completed","output":[{"type":"message","content":[{"type":"output_text","text":"hello"}]}]}}`)
+		_, _ = io.WriteString(c.Writer, "data: [DONE]\n\n")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(`{"model":"gpt-5","input":"hi","background":true}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	id := gjson.Get(w.Body.String(), "id").String()
+	require.True(t, service.IsBackgroundResponseID(id))
+	require.Equal(t, service.BackgroundResponseStatusQueued, gjson.Get(w.Body.String(), "status").String())
+	require.True(t, gjson.Get(w.Body.String(), "background").Bool())
+
+	close(release)
+	require.Eventually(t, func() bool {
+		record, err := store.Get(context.Background(), id)
+		return err == nil && record.Status == service.BackgroundResponseStatusCompleted
+	}, time.Second, 10*time.Millisecond)
+	require.False(t, gjson.Get(upstreamBody, "background").Exists())
+	require.True(t, gjson.Get(upstreamBody, "stream").Bool())
+
+	getReq := httptest.NewRequest(http.MethodGet, "/v1/responses/"+id, nil)
+	getWriter := httptest.NewRecorder()
+	router.ServeHTTP(getWriter, getReq)
+	require.Equal(t, http.StatusOK, getWriter.Code)
+	require.Equal(t, id, gjson.Get(getWriter.Body.String(), "id").String())
+	require.Equal(t, "completed", gjson.Get(getWriter.Body.String(), "status").String())
+	require.Equal(t, "hello", gjson.Get(getWriter.Body.String(), "output.0.content.0.text").String())
+
+	// 终态后完整事件流已转存对象存储，Redis 中的事件列表被清理。
+	require.Contains(t, objects.objects, "gw/responses/"+id+"/events.jsonl")
+	events, err := store.ListEvents(context.Background(), id, 0)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	streamReq := httptest.NewRequest(http.MethodGet, "/v1/responses/"+id+"?stream=true&starting_after=0", nil)
+	streamWriter := httptest.NewRecorder()
+	router.ServeHTTP(streamWriter, streamReq)
+	require.Equal(t, http.StatusOK, streamWriter.Code)
+	require.Equal(t, "text/event-stream", streamWriter.Header().Get("Content-Type"))
+	stream := streamWriter.Body.String()
+	require.NotContains(t, stream, "response.created")
+	require.Contains(t, stream, `"sequence_number":1`)
+	require.Contains(t, stream, "event: response.completed")
+	require.Contains(t, stream, `"id":"`+id+`"`)
+
+	otherOwner := gin.New()
+	otherOwner.Use(func(c *gin.Context) {
+		c.Set(string(middleware2.ContextKeyAPIKey), &service.APIKey{ID: 10, UserID: 8})
+		c.Next()
+	})
+	h := &BackgroundResponseHandler{responses: service.NewBackgroundResponseService(store, nil, time.Hour, time.Minute)}
+	otherOwner.GET("/v1/responses/:response_id", h.Get)
+	foreignWriter := httptest.NewRecorder()
+	otherOwner.ServeHTTP(foreignWriter, httptest.NewRequest(http.MethodGet, "/v1/responses/"+id, nil))
+	require.Equal(t, http.StatusNotFound, foreignWriter.Code)
+}
+
+func TestBackgroundResponseCancelStopsExecution(t *testing.T) {
+	stopped := make(chan struct{})
+	router, store, _ := newBackgroundResponseTestRouter(t, func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Status(http.StatusOK)
+		writeBackgroundResponseTestEvent(c, `{"type":"response.created","response":{"id":"resp_upstream","status":"in_progress"}}`)
+		<-c.Request.Context().Done()
+		close(stopped)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(`{"model":"gpt-5","input":"hi","background":true}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	id := gjson.Get(w.Body.String(), "id").String()
+
+	cancelWriter := httptest.NewRecorder()
+	router.ServeHTTP(cancelWriter, httptest.NewRequest(http.MethodPost, "/v1/responses/"+id+"/cancel", nil))
+	require.Equal(t, http.StatusOK, cancelWriter.Code)
+	require.Equal(t, service.BackgroundResponseStatusCancelled, gjson.Get(cancelWriter.Body.String(), "status").String())
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("background execution was not cancelled")
+	}
+	require.Eventually(t, func() bool {
+		record, err := store.Get(context.Background(), id)
+		return err == nil && record.Status == service.BackgroundResponseStatusCancelled
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestBackgroundResponseSubmitPassesThroughForegroundAndUpstreamIDs(t *testing.T) {
+	router, _, _ := newBackgroundResponseTestRouter(t, func(c *gin.Context) {
+		t.Fatal("foreground requests must not be executed in the background")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(`{"model":"gpt-5","input":"hi"}`)))
+	require.Equal(t, http.StatusTeapot, w.Code)
+	require.Contains(t, w.Body.String(), `\"input\":\"hi\"`)
+
+	cancelWriter := httptest.NewRecorder()
+	router.ServeHTTP(cancelWriter, httptest.NewRequest(http.MethodPost, "/v1/responses/resp_upstream123/cancel", nil))
+	require.Equal(t, http.StatusTeapot, cancelWriter.Code)
+}
+
+func TestBackgroundResponseWriterSplitsSSEEvents(t *testing.T) {
+	w := newBackgroundResponseWriter()
+	w.Header().Set("Content-Type", "text/event-stream")
+	_, _ = w.Write([]byte("event: a\r\ndata: {\"type\":\"a\"}\r\n\r\n: keepalive\n\nevent: b\ndata: {\"type\":"))
+	_, _ = w.Write([]byte("\"b\"}\n\ndata: [DONE]\n\n"))
+	require.Len(t, w.pending, 2)
+	require.JSONEq(t, `{"type":"b"}`, string(w.pending[1]))
+
+	status, body, sse := w.result()
+	require.Equal(t, http.StatusOK, status)
+	require.Empty(t, body)
+	require.True(t, sse)
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// contextCompactionMaxSummaryBody caps the captured summary response.
+const contextCompactionMaxSummaryBody = 1 << 20
+
+// ContextCompactionHandler applies the group's context-window overflow policy
+// to /v1/messages and /v1/chat/completions before the request is forwarded.
+// Summaries are produced by running the wrapped handler once more with the
+// summary request, so they go through the normal scheduling and are billed as
+// a separate usage log on the same API key.
+type ContextCompactionHandler struct {
+	compaction *service.ContextCompactionService
+}
+
+func NewContextCompactionHandler(compaction *service.ContextCompactionService) *ContextCompactionHandler {
+	return &ContextCompactionHandler{compaction: compaction}
+}
+
+// Wrap leaves requests untouched unless the group enables compaction and the
+// estimated prompt exceeds the mapped model's context window.
+func (h *ContextCompactionHandler) Wrap(format service.ContextCompactionFormat, next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h == nil || h.compaction == nil || c.Request.Body == nil {
+			next(c)
+			return
+		}
+		apiKey, ok := middleware2.GetAPIKeyFromContext(c)
+		if !ok || apiKey == nil || apiKey.Group == nil || apiKey.Group.ContextCompactionMode == "" {
+			next(c)
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			if maxErr, ok := extractMaxBytesError(err); ok {
+				inferenceIdempotencyJSONError(c, http.StatusRequestEntityTooLarge, "invalid_request_error", buildBodyTooLargeMessage(maxErr.Limit), nil)
+				return
+			}
+			inferenceIdempotencyJSONError(c, http.StatusBadRequest, "invalid_request_error", "Failed to read request body", nil)
+			return
+		}
+
+		plan := h.compaction.Plan(c.Request.Context(), apiKey.Group, format, body)
+		if plan == nil {
+			restoreBackgroundResponseBody(c.Request, body)
+			next(c)
+			return
+		}
+		summarized := false
+		if plan.Mode == service.ContextCompactionModeSummarize {
+			// 摘要失败时退化为截断，不让主请求因此失败。
+			if err := h.summarize(c, plan, next); err != nil {
+				logger.L().Warn("context_compaction.summarize_failed",
+					zap.Int64("group_id", apiKey.Group.ID),
+					zap.String("summary_model", plan.SummaryModel),
+					zap.Error(err),
+				)
+			} else {
+				summarized = true
+			}
+		}
+		logger.L().Info("context_compaction.applied",
+			zap.Int64("group_id", apiKey.Group.ID),
+			zap.String("format", string(plan.Format)),
+			zap.Bool("summarized", summarized),
+			zap.Int("dropped_messages", plan.DroppedMessages),
+			zap.Int("estimated_before", plan.EstimatedBefore),
+			zap.Int("estimated_after", plan.EstimatedAfter),
+			zap.Int("window", plan.Window),
+		)
+		c.Header(service.ContextCompactionHeader, plan.HeaderValue(summarized))
+		restoreBackgroundResponseBody(c.Request, plan.Body)
+		next(c)
+	}
+}
+
+func (h *ContextCompactionHandler) summarize(c *gin.Context, plan *service.ContextCompactionPlan, next gin.HandlerFunc) error {
+	summaryBody, err := plan.SummaryRequest()
+	if err != nil {
+		return err
+	}
+	request := c.Request.Clone(c.Request.Context())
+	restoreBackgroundResponseBody(request, summaryBody)
+	request.Header.Del("Accept-Encoding")
+
+	taskCtx := c.Copy()
+	writer := &contextCompactionCaptureWriter{header: make(http.Header)}
+	writerCtx, _ := gin.CreateTestContext(writer)
+	taskCtx.Writer = writerCtx.Writer
+	taskCtx.Request = request
+	next(taskCtx)
+
+	status, responseBody := writer.result()
+	if status != http.StatusOK {
+		return fmt.Errorf("summary request failed with status %d", status)
+	}
+	return plan.ApplySummary(service.ExtractContextCompactionSummary(plan.Format, responseBody))
+}
+
+// contextCompactionCaptureWriter buffers the summary response instead of
+// sending it to the client.
+type contextCompactionCaptureWriter struct {
+	mu     sync.Mutex
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *contextCompactionCaptureWriter) Header() http.Header { return w.header }
+
+func (w *contextCompactionCaptureWriter) WriteHeader(statusCode int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 {
+		w.status = statusCode
+	}
+}
+
+func (w *contextCompactionCaptureWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if remaining := contextCompactionMaxSummaryBody - w.body.Len(); remaining > 0 {
+		if len(p) > remaining {
+			w.body.Write(p[:remaining])
+		} else {
+			w.body.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (w *contextCompactionCaptureWriter) Flush() {}
+
+func (w *contextCompactionCaptureWriter) result() (int, []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return status, w.body.Bytes()
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func newContextCompactionTestRouter(t *testing.T, mode string, upstream gin.HandlerFunc) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.Pricing.DataDir = t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.Pricing.DataDir, "model_pricing.json"),
+		[]byte(`{"claude-small":{"max_input_tokens":1200,"input_cost_per_token":0.000001,"litellm_provider":"anthropic","mode":"chat"}}`), 0o644))
+	pricing := service.NewPricingService(cfg, nil)
+	require.NoError(t, pricing.Initialize())
+	t.Cleanup(pricing.Stop)
+	h := NewContextCompactionHandler(service.NewContextCompactionService(pricing, nil))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(string(middleware2.ContextKeyAPIKey), &service.APIKey{
+			ID:     9,
+			UserID: 7,
+			Group: &service.Group{
+				ID:                     1,
+				ContextCompactionMode:  mode,
+				ContextCompactionModel: "claude-haiku",
+			},
+		})
+		c.Next()
+	})
+	router.POST("/v1/messages", h.Wrap(service.ContextCompactionFormatAnthropic, upstream))
+	return router
+}
+
+func contextCompactionOverflowBody() string {
+	filler := strings.Repeat("word ", 400)
+	return `{"model":"claude-small","max_tokens":100,"stream":true,"messages":[` +
+		`{"role":"user","content":"` + filler + `"},` +
+		`{"role":"assistant","content":"` + filler + `"},` +
+		`{"role":"user","content":"latest"}]}`
+}
+
+func TestContextCompactionSummarizesThroughWrappedHandler(t *testing.T) {
+	var mu sync.Mutex
+	var models []string
+	var forwarded string
+	router := newContextCompactionTestRouter(t, service.ContextCompactionModeSummarize, func(c *gin.Context) {
+		raw, _ := io.ReadAll(c.Request.Body)
+		model := gjson.GetBytes(raw, "model").String()
+		mu.Lock()
+		models = append(models, model)
+		mu.Unlock()
+		if model == "claude-haiku" {
+			c.JSON(http.StatusOK, gin.H{"content": []gin.H{{"type": "text", "text": "they said hello"}}})
+			return
+		}
+		forwarded = string(raw)
+		c.JSON(http.StatusOK, gin.H{"id": "msg_1"})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(contextCompactionOverflowBody())))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"id":"msg_1"}`, w.Body.String())
+	require.Equal(t, []string{"claude-haiku", "claude-small"}, models)
+	require.Len(t, gjson.Get(forwarded, "messages").Array(), 1)
+	require.Contains(t, gjson.Get(forwarded, "system").String(), "they said hello")
+	require.True(t, strings.HasPrefix(w.Header().Get(service.ContextCompactionHeader), "summarized; dropped_messages=2;"))
+}
+
+func TestContextCompactionFallsBackToTruncationWhenSummaryFails(t *testing.T) {
+	var forwarded string
+	router := newContextCompactionTestRouter(t, service.ContextCompactionModeSummarize, func(c *gin.Context) {
+		raw, _ := io.ReadAll(c.Request.Body)
+		if gjson.GetBytes(raw, "model").String() == "claude-haiku" {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "no account"})
+			return
+		}
+		forwarded = string(raw)
+		c.JSON(http.StatusOK, gin.H{"id": "msg_1"})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(contextCompactionOverflowBody())))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, gjson.Get(forwarded, "messages").Array(), 1)
+	require.False(t, gjson.Get(forwarded, "system").Exists())
+	require.True(t, strings.HasPrefix(w.Header().Get(service.ContextCompactionHeader), "truncated; dropped_messages=2;"))
+}
+
+func TestContextCompactionDisabledGroupPassesBodyThrough(t *testing.T) {
+	var forwarded string
+	router := newContextCompactionTestRouter(t, "", func(c *gin.Context) {
+		raw, _ := io.ReadAll(c.Request.Body)
+		forwarded = string(raw)
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(contextCompactionOverflowBody())))
+	require.Equal(t, contextCompactionOverflowBody(), forwarded)
+	require.Empty(t, w.Header().Get(service.ContextCompactionHeader))
+}
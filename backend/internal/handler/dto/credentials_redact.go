@@ -14,6 +14,10 @@ func RedactCredentials(in map[string]any) (out map[string]any, status map[string
 	}
 	out = make(map[string]any, len(in))
 	for k, v := range in {
+		// 静态加密写入的 API Key 指纹属于派生值，同样不返回前端。
+		if k == service.CredentialAPIKeyFingerprintKey {
+			continue
+		}
 		if service.IsSensitiveCredentialKey(k) {
 			if isCredentialValuePresent(v) {
 				if status == nil {
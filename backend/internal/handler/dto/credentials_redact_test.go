@@ -99,3 +99,13 @@ func TestRedactCredentials_AllKnownSensitiveKeys(t *testing.T) {
 		require.True(t, status["has_"+k], "key %s 应在 status 中标记为已配置", k)
 	}
 }
+
+func TestRedactCredentials_StripsAPIKeyFingerprint(t *testing.T) {
+	out, status := RedactCredentials(map[string]any{
+		"api_key_fingerprint": "abc123",
+		"base_url":            "https://api.example.com",
+	})
+	require.NotContains(t, out, "api_key_fingerprint")
+	require.Equal(t, "https://api.example.com", out["base_url"])
+	require.Nil(t, status)
+}
@@ -77,7 +77,8 @@ func APIKeyFromService(k *service.APIKey) *APIKey {
 	out := &APIKey{
 		ID:                 k.ID,
 		UserID:             k.UserID,
-		Key:                k.Key,
+		Key:                service.RevealableAPIKey(k.Key),
+		KeyPrefix:          k.KeyPrefix,
 		Name:               k.Name,
 		GroupID:            k.GroupID,
 		Status:             k.Status,
@@ -145,6 +146,11 @@ func GroupFromServiceAdmin(g *service.Group) *AdminGroup {
 		ProfitMinMargin:             g.ProfitMinMargin,
 		ProfitSafetyBuffer:          g.ProfitSafetyBuffer,
 		ModelPricing:                g.ModelPricing,
+		PromptPrefixRoutingEnabled:  g.PromptPrefixRoutingEnabled,
+		PromptPrefixMessages:        g.PromptPrefixMessages,
+		StreamResumeEnabled:         g.StreamResumeEnabled,
+		ContextCompactionMode:       g.ContextCompactionMode,
+		ContextCompactionModel:      g.ContextCompactionModel,
 		ModelRouting:                g.ModelRouting,
 		ModelRoutingEnabled:         g.ModelRoutingEnabled,
 		MCPXMLInject:                g.MCPXMLInject,
@@ -205,6 +211,8 @@ func groupFromServiceBase(g *service.Group) Group {
 		AudioRealtimePricePerMin:        g.AudioRealtimePricePerMin,
 		AudioTtsPricePerMillionChars:    g.AudioTTSPricePerMillionChars,
 		AudioSttPricePerHour:            g.AudioSTTPricePerHour,
+		ModerationPricePerCall:          g.ModerationPricePerCall,
+		ModerationPricePerMillionTokens: g.ModerationPricePerMillionTokens,
 		ClaudeCodeOnly:                  g.ClaudeCodeOnly,
 		FallbackGroupID:                 g.FallbackGroupID,
 		FallbackGroupIDOnInvalidRequest: g.FallbackGroupIDOnInvalidRequest,
@@ -375,6 +375,8 @@ type PublicSettings struct {
 	OIDCOAuthProviderName               string                   `json:"oidc_oauth_provider_name"`
 	GitHubOAuthEnabled                  bool                     `json:"github_oauth_enabled"`
 	GoogleOAuthEnabled                  bool                     `json:"google_oauth_enabled"`
+	SAMLEnabled                         bool                     `json:"saml_enabled"`
+	SAMLProviderName                    string                   `json:"saml_provider_name"`
 	SoraClientEnabled                   bool                     `json:"sora_client_enabled"`
 	BackendModeEnabled                  bool                     `json:"backend_mode_enabled"`
 	Version                             string                   `json:"version"`
@@ -53,7 +53,8 @@ type AdminUser struct {
 type APIKey struct {
 	ID          int64      `json:"id"`
 	UserID      int64      `json:"user_id"`
-	Key         string     `json:"key"`
+	Key         string     `json:"key"` // 仅创建时返回完整 Key，之后为空
+	KeyPrefix   string     `json:"key_prefix"`
 	Name        string     `json:"name"`
 	GroupID     *int64     `json:"group_id"`
 	Status      string     `json:"status"`
@@ -125,11 +126,13 @@ type Group struct {
 	// VideoModelPrices 可选按模型族×分辨率覆盖视频每秒单价 (USD/s)。
 	VideoModelPrices map[string]map[string]float64 `json:"video_model_prices,omitempty"`
 	// Codex alpha/search 网页搜索单次价格（USD/次）；null 表示使用默认价 0.01
-	WebSearchPricePerCall        *float64 `json:"web_search_price_per_call"`
-	SearchPricePer1k             *float64 `json:"search_price_per_1k"`
-	AudioRealtimePricePerMin     *float64 `json:"audio_realtime_price_per_min"`
-	AudioTtsPricePerMillionChars *float64 `json:"audio_tts_price_per_million_chars"`
-	AudioSttPricePerHour         *float64 `json:"audio_stt_price_per_hour"`
+	WebSearchPricePerCall           *float64 `json:"web_search_price_per_call"`
+	SearchPricePer1k                *float64 `json:"search_price_per_1k"`
+	AudioRealtimePricePerMin        *float64 `json:"audio_realtime_price_per_min"`
+	AudioTtsPricePerMillionChars    *float64 `json:"audio_tts_price_per_million_chars"`
+	AudioSttPricePerHour            *float64 `json:"audio_stt_price_per_hour"`
+	ModerationPricePerCall          *float64 `json:"moderation_price_per_call"`
+	ModerationPricePerMillionTokens *float64 `json:"moderation_price_per_million_tokens"`
 
 	// Claude Code 客户端限制
 	ClaudeCodeOnly  bool   `json:"claude_code_only"`
@@ -170,6 +173,17 @@ type AdminGroup struct {
 	ProfitSafetyBuffer   float64                       `json:"profit_safety_buffer"`
 	ModelPricing         []service.ChannelModelPricing `json:"model_pricing"`
 
+	// 提示词前缀一致性哈希路由（调度内部配置）
+	PromptPrefixRoutingEnabled bool `json:"prompt_prefix_routing_enabled"`
+	PromptPrefixMessages       int  `json:"prompt_prefix_messages"`
+
+	// 流式断线续传
+	StreamResumeEnabled bool `json:"stream_resume_enabled"`
+
+	// 上下文窗口溢出压缩
+	ContextCompactionMode  string `json:"context_compaction_mode"`
+	ContextCompactionModel string `json:"context_compaction_model"`
+
 	// 模型路由配置（仅 anthropic 平台使用）
 	ModelRouting        map[string][]int64 `json:"model_routing"`
 	ModelRoutingEnabled bool               `json:"model_routing_enabled"`
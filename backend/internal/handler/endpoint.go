@@ -18,7 +18,11 @@ const (
 	EndpointMessages          = "/v1/messages"
 	EndpointChatCompletions   = "/v1/chat/completions"
 	EndpointEmbeddings        = "/v1/embeddings"
+	EndpointModerations       = "/v1/moderations"
 	EndpointAlphaSearch       = "/v1/alpha/search"
+	EndpointAudioSpeech       = "/v1/audio/speech"
+	EndpointAudioTranscribe   = "/v1/audio/transcriptions"
+	EndpointAudioTranslate    = "/v1/audio/translations"
 	EndpointResponses         = "/v1/responses"
 	EndpointResponsesCompact  = "/v1/responses/compact"
 	EndpointImagesGenerations = "/v1/images/generations"
@@ -82,6 +86,14 @@ func NormalizeInboundEndpoint(path string) string {
 	switch {
 	case strings.Contains(path, EndpointEmbeddings):
 		return EndpointEmbeddings
+	case strings.Contains(path, EndpointModerations) || isBareOrSubpathOf(strings.TrimRight(path, "/"), "/moderations"):
+		return EndpointModerations
+	case strings.Contains(path, EndpointAudioSpeech) || isBareOrSubpathOf(strings.TrimRight(path, "/"), "/audio/speech"):
+		return EndpointAudioSpeech
+	case strings.Contains(path, EndpointAudioTranscribe) || isBareOrSubpathOf(strings.TrimRight(path, "/"), "/audio/transcriptions"):
+		return EndpointAudioTranscribe
+	case strings.Contains(path, EndpointAudioTranslate) || isBareOrSubpathOf(strings.TrimRight(path, "/"), "/audio/translations"):
+		return EndpointAudioTranslate
 	case strings.Contains(path, EndpointAlphaSearch) || isBareOrSubpathOf(strings.TrimRight(path, "/"), "/alpha/search") || isBareOrSubpathOf(strings.TrimRight(path, "/"), "/backend-api/codex/alpha/search"):
 		return EndpointAlphaSearch
 	case strings.Contains(path, EndpointChatCompletions):
@@ -185,6 +197,18 @@ func DeriveUpstreamEndpoint(inbound, rawRequestPath, platform string) string {
 
 	switch platform {
 	case service.PlatformOpenAI, service.PlatformGrok:
+		if platform == service.PlatformGrok {
+			// Grok groups serve OpenAI Audio through xAI Voice /tts and /stt.
+			switch inbound {
+			case EndpointAudioSpeech:
+				return "/v1/tts"
+			case EndpointAudioTranscribe:
+				return "/v1/stt"
+			}
+		}
+		if inbound == EndpointAudioSpeech || inbound == EndpointAudioTranscribe || inbound == EndpointAudioTranslate {
+			return inbound
+		}
 		if inbound == EndpointEmbeddings || inbound == EndpointAlphaSearch || inbound == EndpointImagesGenerations || inbound == EndpointImagesEdits || inbound == EndpointVideosGenerations || inbound == EndpointVideosEdits || inbound == EndpointVideosExtensions || inbound == EndpointVideos {
 			return inbound
 		}
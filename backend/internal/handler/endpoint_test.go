@@ -25,7 +25,12 @@ func TestNormalizeInboundEndpoint(t *testing.T) {
 		{"/v1/messages", EndpointMessages},
 		{"/v1/chat/completions", EndpointChatCompletions},
 		{"/v1/embeddings", EndpointEmbeddings},
+		{"/v1/moderations", EndpointModerations},
+		{"/moderations", EndpointModerations},
 		{"/v1/alpha/search", EndpointAlphaSearch},
+		{"/v1/audio/speech", EndpointAudioSpeech},
+		{"/audio/transcriptions", EndpointAudioTranscribe},
+		{"/v1/audio/translations", EndpointAudioTranslate},
 		{"/v1/responses", EndpointResponses},
 		{"/v1/responses/compact", EndpointResponsesCompact},
 		{"/v1/responses/compact/detail", EndpointResponsesCompact},
@@ -124,6 +129,9 @@ func TestDeriveUpstreamEndpoint(t *testing.T) {
 		{"openai from messages", EndpointMessages, "/v1/messages", service.PlatformOpenAI, EndpointResponses},
 		{"openai from completions", EndpointChatCompletions, "/v1/chat/completions", service.PlatformOpenAI, EndpointResponses},
 		{"openai embeddings", EndpointEmbeddings, "/v1/embeddings", service.PlatformOpenAI, EndpointEmbeddings},
+		{"openai audio transcriptions", EndpointAudioTranscribe, "/v1/audio/transcriptions", service.PlatformOpenAI, EndpointAudioTranscribe},
+		{"grok audio speech maps to tts", EndpointAudioSpeech, "/v1/audio/speech", service.PlatformGrok, "/v1/tts"},
+		{"grok audio transcriptions maps to stt", EndpointAudioTranscribe, "/audio/transcriptions", service.PlatformGrok, "/v1/stt"},
 		{"openai alpha search", EndpointAlphaSearch, "/backend-api/codex/alpha/search", service.PlatformOpenAI, EndpointAlphaSearch},
 		{"openai image generations", EndpointImagesGenerations, "/v1/images/generations", service.PlatformOpenAI, EndpointImagesGenerations},
 		{"openai image edits", EndpointImagesEdits, "/openai/v1/images/edits", service.PlatformOpenAI, EndpointImagesEdits},
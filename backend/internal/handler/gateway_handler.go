@@ -51,6 +51,7 @@ type GatewayHandler struct {
 	errorPassthroughService   *service.ErrorPassthroughService
 	contentModerationService  *service.ContentModerationService
 	securityAuditCoordinator  *securityaudit.Coordinator
+	upstreamFileService       *service.UpstreamFileService
 	concurrencyHelper         *ConcurrencyHelper
 	userMsgQueueHelper        *UserMsgQueueHelper
 	maxAccountSwitches        int
@@ -256,6 +257,8 @@ func (h *GatewayHandler) Messages(c *gin.Context) {
 		APIKeyID:  apiKey.ID,
 	}
 	sessionHash := h.gatewayService.GenerateSessionHash(parsedReq)
+	// 提示词前缀路由：仅分组启用时计算可缓存前缀哈希，供尚无粘性绑定的新会话选账号
+	c.Request = c.Request.WithContext(service.WithPromptPrefixRouting(c.Request.Context(), apiKey.Group, parsedReq))
 
 	// [DEBUG-STICKY] 打印会话 hash 生成结果
 	reqLog.Info("sticky.session_hash_generated",
@@ -276,11 +279,22 @@ func (h *GatewayHandler) Messages(c *gin.Context) {
 	if platform == service.PlatformGemini && sessionHash != "" {
 		sessionKey = "gemini:" + sessionHash
 	}
+	// Files API：引用已上传文件的请求只能由持有该文件的账号处理。
+	fileAccountID, ok := resolveUpstreamFileAffinity(c, h.upstreamFileService, apiKey, subject.UserID, service.PlatformAnthropic, body, h.errorResponse)
+	if !ok {
+		return
+	}
+	if fileAccountID > 0 {
+		sessionKey = service.UpstreamFileSessionHash(fileAccountID)
+	}
 
 	// 查询粘性会话绑定的账号 ID
 	var sessionBoundAccountID int64
 	if sessionKey != "" {
 		sessionBoundAccountID, _ = h.gatewayService.GetCachedSessionAccountID(c.Request.Context(), apiKey.GroupID, sessionKey)
+		if fileAccountID > 0 {
+			sessionBoundAccountID = fileAccountID
+		}
 		// [DEBUG-STICKY] 打印粘性会话查询结果
 		reqLog.Info("sticky.cache_lookup",
 			zap.String("session_key", sessionKey),
@@ -663,6 +677,9 @@ func (h *GatewayHandler) Messages(c *gin.Context) {
 					return
 				}
 			}
+			if rejectIfFileAccountMismatch(c, fileAccountID, selection, h.errorResponse) {
+				return
+			}
 			account := selection.Account
 			setOpsSelectedAccount(c, account.ID, account.Platform)
 
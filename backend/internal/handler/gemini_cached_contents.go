@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	pkghttputil "github.com/Wei-Shaw/sub2api/internal/pkg/httputil"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/ip"
+	"github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Gemini context caching passthrough:
+//
+//	POST   /v1beta/cachedContents
+//	GET    /v1beta/cachedContents
+//	GET    /v1beta/cachedContents/{id}
+//	PATCH  /v1beta/cachedContents/{id}
+//	DELETE /v1beta/cachedContents/{id}
+//
+// A cache only exists on the account that created it, so every operation on an
+// existing cache is pinned to that account (see service/gemini_cached_contents.go).
+
+// cachedContentsCaller validates the key and group platform shared by all
+// cachedContents routes. It writes the error response and returns false on failure.
+func (h *GatewayHandler) cachedContentsCaller(c *gin.Context) (*service.APIKey, middleware.AuthSubject, service.GeminiCachedContentCaller, bool) {
+	apiKey, ok := middleware.GetAPIKeyFromContext(c)
+	if !ok || apiKey == nil {
+		googleError(c, http.StatusUnauthorized, "Invalid API key")
+		return nil, middleware.AuthSubject{}, service.GeminiCachedContentCaller{}, false
+	}
+	authSubject, ok := middleware.GetAuthSubjectFromContext(c)
+	if !ok {
+		googleError(c, http.StatusInternalServerError, "User context not found")
+		return nil, middleware.AuthSubject{}, service.GeminiCachedContentCaller{}, false
+	}
+	if effectiveAPIKeyPlatform(c, apiKey) != service.PlatformGemini {
+		googleError(c, http.StatusBadRequest, "API key group platform is not gemini")
+		return nil, middleware.AuthSubject{}, service.GeminiCachedContentCaller{}, false
+	}
+	caller := service.GeminiCachedContentCaller{
+		GroupID:  apiKey.GroupID,
+		UserID:   authSubject.UserID,
+		APIKeyID: apiKey.ID,
+	}
+	return apiKey, authSubject, caller, true
+}
+
+func writeCachedContentsError(c *gin.Context, err error) {
+	googleError(c, infraerrors.Code(err), infraerrors.Message(err))
+}
+
+// GeminiCachedContentsCreate creates a cache on a Gemini account and bills its
+// storage for the requested TTL.
+func (h *GatewayHandler) GeminiCachedContentsCreate(c *gin.Context) {
+	apiKey, authSubject, caller, ok := h.cachedContentsCaller(c)
+	if !ok {
+		return
+	}
+	reqLog := requestLogger(
+		c,
+		"handler.gemini_v1beta.cached_contents",
+		zap.Int64("user_id", authSubject.UserID),
+		zap.Int64("api_key_id", apiKey.ID),
+		zap.Any("group_id", apiKey.GroupID),
+	)
+
+	body, err := pkghttputil.ReadRequestBodyWithPrealloc(c.Request)
+	if err != nil {
+		if maxErr, ok := extractMaxBytesError(err); ok {
+			googleError(c, http.StatusRequestEntityTooLarge, buildBodyTooLargeMessage(maxErr.Limit))
+			return
+		}
+		googleError(c, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	if len(body) == 0 {
+		googleError(c, http.StatusBadRequest, "Request body is empty")
+		return
+	}
+	modelName := service.GeminiCachedContentModel(body)
+	reqLog = reqLog.With(zap.String("model", modelName))
+	setOpsRequestContext(c, modelName, false)
+	pricingCtx, pricingAt := service.WithGatewayTokenRequestPricing(c.Request.Context())
+	c.Request = c.Request.WithContext(pricingCtx)
+
+	// 缓存内容会在后续请求中作为上下文参与生成，创建时按 generateContent 同口径审计。
+	if decision := h.checkSecurityAudit(c, reqLog, apiKey, authSubject, service.ContentModerationProtocolGemini, modelName, body); decision != nil && !decision.AllowNextStage {
+		googleSecurityAuditError(c, decision)
+		return
+	}
+
+	subscription, _ := middleware.GetSubscriptionFromContext(c)
+	if err := h.billingCacheService.CheckBillingEligibility(c.Request.Context(), apiKey.User, apiKey, apiKey.Group, subscription, service.QuotaPlatform(c.Request.Context(), apiKey)); err != nil {
+		reqLog.Info("gemini.cached_contents.billing_eligibility_check_failed", zap.Error(err))
+		status, _, message, retryAfter := billingErrorDetails(err)
+		if retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+		googleError(c, status, message)
+		return
+	}
+
+	result, err := h.geminiCompatService.CreateCachedContent(c.Request.Context(), caller, body)
+	if err != nil {
+		reqLog.Warn("gemini.cached_contents.create_failed", zap.Error(err))
+		if infraerrors.Reason(err) == service.ErrGeminiCachedContentUnavailable.Reason {
+			markOpsRoutingCapacityLimitedIfNoAvailable(c, err)
+		}
+		writeCachedContentsError(c, err)
+		return
+	}
+	setOpsSelectedAccount(c, result.Account.ID, result.Account.Platform)
+	h.writeCachedContentsResult(c, reqLog, apiKey, subscription, body, pricingAt, result)
+}
+
+// GeminiCachedContentsList lists the caller's own caches.
+func (h *GatewayHandler) GeminiCachedContentsList(c *gin.Context) {
+	_, _, caller, ok := h.cachedContentsCaller(c)
+	if !ok {
+		return
+	}
+	body, err := h.geminiCompatService.ListCachedContents(c.Request.Context(), caller)
+	if err != nil {
+		writeCachedContentsError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/json", body)
+}
+
+// GeminiCachedContentsGet reads a cache from the account that holds it.
+func (h *GatewayHandler) GeminiCachedContentsGet(c *gin.Context) {
+	_, _, caller, ok := h.cachedContentsCaller(c)
+	if !ok {
+		return
+	}
+	result, err := h.geminiCompatService.GetCachedContent(c.Request.Context(), caller, c.Param("id"))
+	if err != nil {
+		writeCachedContentsError(c, err)
+		return
+	}
+	writeUpstreamResponse(c, result.Response)
+}
+
+// GeminiCachedContentsUpdate patches a cache (typically its TTL); any extension
+// of the expiry is billed as additional storage.
+func (h *GatewayHandler) GeminiCachedContentsUpdate(c *gin.Context) {
+	apiKey, authSubject, caller, ok := h.cachedContentsCaller(c)
+	if !ok {
+		return
+	}
+	reqLog := requestLogger(
+		c,
+		"handler.gemini_v1beta.cached_contents",
+		zap.Int64("user_id", authSubject.UserID),
+		zap.Int64("api_key_id", apiKey.ID),
+		zap.Any("group_id", apiKey.GroupID),
+	)
+	body, err := pkghttputil.ReadRequestBodyWithPrealloc(c.Request)
+	if err != nil {
+		googleError(c, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	if len(body) == 0 {
+		googleError(c, http.StatusBadRequest, "Request body is empty")
+		return
+	}
+	pricingCtx, pricingAt := service.WithGatewayTokenRequestPricing(c.Request.Context())
+	c.Request = c.Request.WithContext(pricingCtx)
+
+	subscription, _ := middleware.GetSubscriptionFromContext(c)
+	if err := h.billingCacheService.CheckBillingEligibility(c.Request.Context(), apiKey.User, apiKey, apiKey.Group, subscription, service.QuotaPlatform(c.Request.Context(), apiKey)); err != nil {
+		status, _, message, retryAfter := billingErrorDetails(err)
+		if retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+		googleError(c, status, message)
+		return
+	}
+
+	result, err := h.geminiCompatService.UpdateCachedContent(c.Request.Context(), caller, c.Param("id"), c.Request.URL.RawQuery, body)
+	if err != nil {
+		reqLog.Warn("gemini.cached_contents.update_failed", zap.Error(err))
+		writeCachedContentsError(c, err)
+		return
+	}
+	setOpsSelectedAccount(c, result.Account.ID, result.Account.Platform)
+	h.writeCachedContentsResult(c, reqLog, apiKey, subscription, body, pricingAt, result)
+}
+
+// GeminiCachedContentsDelete deletes a cache and its account binding.
+func (h *GatewayHandler) GeminiCachedContentsDelete(c *gin.Context) {
+	_, _, caller, ok := h.cachedContentsCaller(c)
+	if !ok {
+		return
+	}
+	result, err := h.geminiCompatService.DeleteCachedContent(c.Request.Context(), caller, c.Param("id"))
+	if err != nil {
+		writeCachedContentsError(c, err)
+		return
+	}
+	writeUpstreamResponse(c, result.Response)
+}
+
+// writeCachedContentsResult writes the upstream response and records storage usage when billed.
+func (h *GatewayHandler) writeCachedContentsResult(
+	c *gin.Context,
+	reqLog *zap.Logger,
+	apiKey *service.APIKey,
+	subscription *service.UserSubscription,
+	body []byte,
+	pricingAt time.Time,
+	result *service.GeminiCachedContentResult,
+) {
+	writeUpstreamResponse(c, result.Response)
+	if result.Usage == nil {
+		return
+	}
+
+	account := result.Account
+	usage := result.Usage
+	input := &service.RecordUsageInput{
+		Result:             usage,
+		QuotaPlatform:      service.QuotaPlatform(c.Request.Context(), apiKey),
+		APIKey:             apiKey,
+		User:               apiKey.User,
+		Account:            account,
+		Subscription:       subscription,
+		PricingAt:          pricingAt,
+		InboundEndpoint:    GetInboundEndpoint(c),
+		UpstreamEndpoint:   GetUpstreamEndpoint(c, account.Platform),
+		UserAgent:          c.GetHeader("User-Agent"),
+		IPAddress:          ip.GetClientIP(c),
+		RequestPayloadHash: service.HashUsageRequestPayload(body),
+		APIKeyService:      h.apiKeyService,
+		SessionID:          service.ExtractClientSessionID(c),
+	}
+	h.submitUsageRecordTask(c.Request.Context(), func(ctx context.Context) {
+		if err := h.gatewayService.RecordUsage(ctx, input); err != nil {
+			reqLog.Error("gemini.cached_contents.record_usage_failed",
+				zap.Int64("account_id", account.ID),
+				zap.Error(err),
+			)
+		}
+	})
+}
@@ -0,0 +1,380 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/apicompat"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/googleapi"
+	pkghttputil "github.com/Wei-Shaw/sub2api/internal/pkg/httputil"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/sjson"
+)
+
+// GeminiV1BetaMessagesCompat 为 Anthropic / OpenAI 分组提供 Gemini 原生接口：
+// POST /v1beta/models/{model}:generateContent
+// POST /v1beta/models/{model}:streamGenerateContent[?alt=sse]
+// POST /v1beta/models/{model}:countTokens
+//
+// 请求体转换为 Anthropic Messages 后交给 messages / countTokens 处理器（OpenAI
+// 分组沿用现有的 Anthropic → Responses 桥接），响应再由 geminiCompatWriter
+// 转回 Gemini 格式。调度、计费、故障转移全部复用原处理器，不另起一套。
+func GeminiV1BetaMessagesCompat(messages, countTokens gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		modelName, action, err := parseGeminiModelAction(strings.TrimPrefix(c.Param("modelAction"), "/"))
+		if err != nil {
+			googleError(c, http.StatusNotFound, err.Error())
+			return
+		}
+		if !service.IsSafeGeminiModelPathSegment(modelName) {
+			googleError(c, http.StatusBadRequest, "Invalid model in URL")
+			return
+		}
+
+		var next gin.HandlerFunc
+		mode := geminiCompatModeGenerate
+		switch action {
+		case "generateContent":
+			next = messages
+		case "streamGenerateContent":
+			next = messages
+			mode = geminiCompatModeStream
+		case "countTokens":
+			next = countTokens
+			mode = geminiCompatModeCountTokens
+		default:
+			googleError(c, http.StatusNotFound, "Action "+action+" is not supported for this group platform")
+			return
+		}
+
+		body, err := pkghttputil.ReadRequestBodyWithPrealloc(c.Request)
+		if err != nil {
+			if maxErr, ok := extractMaxBytesError(err); ok {
+				googleError(c, http.StatusRequestEntityTooLarge, buildBodyTooLargeMessage(maxErr.Limit))
+				return
+			}
+			googleError(c, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		if len(body) == 0 {
+			googleError(c, http.StatusBadRequest, "Request body is empty")
+			return
+		}
+
+		geminiReq, err := parseGeminiCompatRequest(body, mode)
+		if err != nil {
+			googleError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+			return
+		}
+		anthropicReq, err := apicompat.GeminiToAnthropicRequest(geminiReq, modelName, mode == geminiCompatModeStream)
+		if err != nil {
+			googleError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+			return
+		}
+		converted, err := json.Marshal(anthropicReq)
+		if err == nil && mode == geminiCompatModeCountTokens {
+			// count_tokens 不接受 max_tokens
+			converted, err = sjson.DeleteBytes(converted, "max_tokens")
+		}
+		if err != nil {
+			googleError(c, http.StatusInternalServerError, "Failed to build upstream request")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(converted))
+		c.Request.ContentLength = int64(len(converted))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.Header.Del("Content-Encoding")
+
+		original := c.Writer
+		w := newGeminiCompatWriter(original, mode, c.Query("alt") == "sse", modelName, apicompat.GeminiIncludeThoughts(geminiReq))
+		c.Writer = w
+		next(c)
+		c.Writer = original
+		w.finish(c)
+	}
+}
+
+// geminiCountTokensRequest 同时兼容 countTokens 的两种写法：
+// 直接给 contents，或包一层 generateContentRequest。
+type geminiCountTokensRequest struct {
+	Contents               []apicompat.GeminiContent `json:"contents,omitempty"`
+	GenerateContentRequest *apicompat.GeminiRequest  `json:"generateContentRequest,omitempty"`
+}
+
+func parseGeminiCompatRequest(body []byte, mode geminiCompatMode) (*apicompat.GeminiRequest, error) {
+	if mode != geminiCompatModeCountTokens {
+		var req apicompat.GeminiRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		return &req, nil
+	}
+	var req geminiCountTokensRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	if req.GenerateContentRequest != nil {
+		return req.GenerateContentRequest, nil
+	}
+	return &apicompat.GeminiRequest{Contents: req.Contents}, nil
+}
+
+type geminiCompatMode int
+
+const (
+	geminiCompatModeGenerate geminiCompatMode = iota
+	geminiCompatModeStream
+	geminiCompatModeCountTokens
+)
+
+// geminiCompatWriter 拦截内层 Anthropic 处理器的输出并改写为 Gemini 格式。
+//
+// Size()/Written() 反映内层处理器写出的字节数而非实际下发的字节数：故障转移
+// 依据 "是否已写响应" 决定能否换号，流式场景下 Gemini 块可能晚于 Anthropic
+// 事件下发，不能让内层误以为仍可重试。
+type geminiCompatWriter struct {
+	gin.ResponseWriter
+
+	mode            geminiCompatMode
+	altSSE          bool
+	includeThoughts bool
+	state           *apicompat.AnthropicEventToGeminiState
+
+	status int
+	size   int
+	buf    bytes.Buffer
+
+	// 流式：按行解析内层 SSE
+	sse        bool
+	line       []byte
+	started    bool
+	chunks     int
+	streamErrd bool
+}
+
+func newGeminiCompatWriter(rw gin.ResponseWriter, mode geminiCompatMode, altSSE bool, model string, includeThoughts bool) *geminiCompatWriter {
+	return &geminiCompatWriter{
+		ResponseWriter:  rw,
+		mode:            mode,
+		altSSE:          altSSE,
+		includeThoughts: includeThoughts,
+		state:           apicompat.NewAnthropicEventToGeminiState(model, includeThoughts),
+		status:          http.StatusOK,
+		size:            -1,
+	}
+}
+
+func (w *geminiCompatWriter) WriteHeader(code int) {
+	if code > 0 && w.size == -1 {
+		w.status = code
+	}
+}
+
+func (w *geminiCompatWriter) WriteHeaderNow() {
+	if w.size == -1 {
+		w.size = 0
+	}
+}
+
+func (w *geminiCompatWriter) Status() int   { return w.status }
+func (w *geminiCompatWriter) Size() int     { return w.size }
+func (w *geminiCompatWriter) Written() bool { return w.size != -1 }
+
+func (w *geminiCompatWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *geminiCompatWriter) Write(p []byte) (int, error) {
+	if w.size == -1 {
+		w.size = 0
+		w.sse = w.mode == geminiCompatModeStream && w.status < http.StatusBadRequest &&
+			strings.Contains(w.Header().Get("Content-Type"), "text/event-stream")
+	}
+	w.size += len(p)
+	if !w.sse {
+		w.buf.Write(p)
+		return len(p), nil
+	}
+	w.line = append(w.line, p...)
+	for {
+		i := bytes.IndexByte(w.line, '\n')
+		if i < 0 {
+			break
+		}
+		w.handleSSELine(bytes.TrimRight(w.line[:i], "\r"))
+		w.line = w.line[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *geminiCompatWriter) Flush() {
+	if w.started {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *geminiCompatWriter) handleSSELine(line []byte) {
+	data, ok := bytes.CutPrefix(line, []byte("data:"))
+	if !ok || w.streamErrd {
+		return
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("[DONE]")) {
+		return
+	}
+	var evt apicompat.AnthropicStreamEvent
+	if err := json.Unmarshal(data, &evt); err != nil {
+		return
+	}
+	if evt.Type == "error" {
+		// 状态码已固化为 200，按 Gemini 的流内错误格式下发
+		w.streamErrd = true
+		status, message := geminiCompatErrorFromAnthropic(http.StatusBadGateway, data)
+		w.writeStreamPayload(googleErrorBody(status, message))
+		return
+	}
+	for _, chunk := range apicompat.AnthropicEventToGeminiChunks(&evt, w.state) {
+		w.writeStreamChunk(chunk)
+	}
+}
+
+func (w *geminiCompatWriter) writeStreamChunk(chunk apicompat.GeminiResponse) {
+	payload, err := json.Marshal(chunk)
+	if err != nil {
+		return
+	}
+	w.writeStreamPayload(payload)
+}
+
+// writeStreamPayload 按 alt=sse 输出 data 行，否则输出流式 JSON 数组元素。
+func (w *geminiCompatWriter) writeStreamPayload(payload []byte) {
+	if !w.started {
+		w.started = true
+		h := w.ResponseWriter.Header()
+		h.Del("Content-Length")
+		if w.altSSE {
+			h.Set("Content-Type", "text/event-stream")
+		} else {
+			h.Set("Content-Type", "application/json")
+		}
+		w.ResponseWriter.WriteHeader(http.StatusOK)
+		if !w.altSSE {
+			_, _ = w.ResponseWriter.WriteString("[")
+		}
+	}
+	if w.altSSE {
+		_, _ = w.ResponseWriter.WriteString("data: ")
+		_, _ = w.ResponseWriter.Write(payload)
+		_, _ = w.ResponseWriter.WriteString("\r\n\r\n")
+	} else {
+		if w.chunks > 0 {
+			_, _ = w.ResponseWriter.WriteString(",\r\n")
+		}
+		_, _ = w.ResponseWriter.Write(payload)
+	}
+	w.chunks++
+	w.ResponseWriter.Flush()
+}
+
+// finish 在内层处理器返回后调用，此时 c.Writer 已恢复为原始 writer。
+func (w *geminiCompatWriter) finish(c *gin.Context) {
+	if w.size == -1 {
+		return
+	}
+	if w.sse {
+		if len(w.line) > 0 {
+			w.handleSSELine(bytes.TrimRight(w.line, "\r"))
+			w.line = nil
+		}
+		if !w.streamErrd {
+			for _, chunk := range apicompat.FinalizeAnthropicGeminiStream(w.state) {
+				w.writeStreamChunk(chunk)
+			}
+		}
+		if !w.altSSE {
+			_, _ = w.ResponseWriter.WriteString("]")
+		}
+		w.ResponseWriter.Flush()
+		return
+	}
+
+	c.Writer.Header().Del("Content-Length")
+	if w.status >= http.StatusBadRequest {
+		status, message := geminiCompatErrorFromAnthropic(w.status, w.buf.Bytes())
+		googleError(c, status, message)
+		return
+	}
+
+	if w.mode == geminiCompatModeCountTokens {
+		var counted struct {
+			InputTokens int `json:"input_tokens"`
+		}
+		if err := json.Unmarshal(w.buf.Bytes(), &counted); err != nil {
+			googleError(c, http.StatusBadGateway, "Failed to parse upstream response")
+			return
+		}
+		c.JSON(http.StatusOK, apicompat.GeminiCountTokensResponse{TotalTokens: counted.InputTokens})
+		return
+	}
+
+	var resp apicompat.AnthropicResponse
+	if err := json.Unmarshal(w.buf.Bytes(), &resp); err != nil {
+		googleError(c, http.StatusBadGateway, "Failed to parse upstream response")
+		return
+	}
+	out := apicompat.AnthropicToGeminiResponse(&resp, w.state.Model, w.includeThoughts)
+	if w.mode != geminiCompatModeStream {
+		c.JSON(http.StatusOK, out)
+		return
+	}
+	// 流式请求但内层返回了非流式 JSON：作为单个块下发
+	payload, err := json.Marshal(out)
+	if err != nil {
+		googleError(c, http.StatusBadGateway, "Failed to encode response")
+		return
+	}
+	w.writeStreamPayload(payload)
+	if !w.altSSE {
+		_, _ = w.ResponseWriter.WriteString("]")
+	}
+	w.ResponseWriter.Flush()
+}
+
+// geminiCompatErrorFromAnthropic 从 Anthropic（或 OpenAI）错误体中提取消息。
+func geminiCompatErrorFromAnthropic(status int, body []byte) (int, string) {
+	var parsed struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		Message string `json:"message"`
+	}
+	message := ""
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		message = parsed.Error.Message
+		if message == "" {
+			message = parsed.Message
+		}
+	}
+	if message == "" {
+		message = strings.TrimSpace(string(body))
+	}
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return status, message
+}
+
+func googleErrorBody(status int, message string) []byte {
+	payload, _ := json.Marshal(gin.H{
+		"error": gin.H{
+			"code":    status,
+			"message": message,
+			"status":  googleapi.HTTPStatusToGoogleStatus(status),
+		},
+	})
+	return payload
+}
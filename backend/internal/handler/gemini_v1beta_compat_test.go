@@ -0,0 +1,134 @@
+//go:build unit
+
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func runGeminiCompat(t *testing.T, path, body string, messages, countTokens gin.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/v1beta/models/*modelAction", GeminiV1BetaMessagesCompat(messages, countTokens))
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGeminiV1BetaMessagesCompat_GenerateContent(t *testing.T) {
+	var upstreamBody map[string]any
+	messages := func(c *gin.Context) {
+		raw, err := io.ReadAll(c.Request.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(raw, &upstreamBody))
+		require.False(t, c.Writer.Written())
+		c.JSON(http.StatusOK, gin.H{
+			"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-sonnet-4-5",
+			"content":     []gin.H{{"type": "text", "text": "hello"}},
+			"stop_reason": "end_turn",
+			"usage":       gin.H{"input_tokens": 3, "output_tokens": 2},
+		})
+		require.True(t, c.Writer.Written())
+	}
+
+	rec := runGeminiCompat(t, "/v1beta/models/claude-sonnet-4-5:generateContent",
+		`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`, messages, nil)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "claude-sonnet-4-5", upstreamBody["model"])
+	require.NotContains(t, upstreamBody, "stream")
+	require.JSONEq(t, `{
+		"candidates":[{"content":{"role":"model","parts":[{"text":"hello"}]},"finishReason":"STOP","index":0}],
+		"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":2,"totalTokenCount":5},
+		"modelVersion":"claude-sonnet-4-5","responseId":"msg_1"
+	}`, rec.Body.String())
+}
+
+func TestGeminiV1BetaMessagesCompat_StreamAltSSE(t *testing.T) {
+	messages := func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Status(http.StatusOK)
+		events := []string{
+			`{"type":"message_start","message":{"id":"msg_2","usage":{"input_tokens":4}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"He"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"llo"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`,
+			`{"type":"message_stop"}`,
+		}
+		for _, evt := range events {
+			// 故意把一行拆成两次写入，覆盖跨 Write 的行缓冲
+			_, _ = c.Writer.WriteString("event: x\ndata: " + evt[:5])
+			_, _ = c.Writer.WriteString(evt[5:] + "\n\n")
+			c.Writer.Flush()
+		}
+	}
+
+	rec := runGeminiCompat(t, "/v1beta/models/claude-sonnet-4-5:streamGenerateContent?alt=sse",
+		`{"contents":[{"parts":[{"text":"hi"}]}]}`, messages, nil)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	var chunks []map[string]any
+	for _, line := range strings.Split(rec.Body.String(), "\r\n\r\n") {
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var chunk map[string]any
+			require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+			chunks = append(chunks, chunk)
+		}
+	}
+	require.Len(t, chunks, 3)
+	last := chunks[2]
+	require.Equal(t, "STOP", last["candidates"].([]any)[0].(map[string]any)["finishReason"])
+	require.Equal(t, float64(6), last["usageMetadata"].(map[string]any)["totalTokenCount"])
+}
+
+func TestGeminiV1BetaMessagesCompat_StreamJSONArray(t *testing.T) {
+	messages := func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		_, _ = c.Writer.WriteString("data: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_3\"}}\n\n" +
+			"data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"ok\"}}\n\n")
+	}
+
+	rec := runGeminiCompat(t, "/v1beta/models/gpt-5.2:streamGenerateContent",
+		`{"contents":[{"parts":[{"text":"hi"}]}]}`, messages, nil)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var chunks []map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &chunks))
+	require.Len(t, chunks, 2)
+}
+
+func TestGeminiV1BetaMessagesCompat_ErrorAndCountTokens(t *testing.T) {
+	failing := func(c *gin.Context) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"type": "error", "error": gin.H{"type": "rate_limit_error", "message": "slow down"}})
+	}
+	rec := runGeminiCompat(t, "/v1beta/models/m:generateContent", `{"contents":[{"parts":[{"text":"hi"}]}]}`, failing, nil)
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.JSONEq(t, `{"error":{"code":429,"message":"slow down","status":"RESOURCE_EXHAUSTED"}}`, rec.Body.String())
+
+	var countBody map[string]any
+	counter := func(c *gin.Context) {
+		raw, _ := io.ReadAll(c.Request.Body)
+		require.NoError(t, json.Unmarshal(raw, &countBody))
+		c.JSON(http.StatusOK, gin.H{"input_tokens": 17})
+	}
+	rec = runGeminiCompat(t, "/v1beta/models/m:countTokens",
+		`{"generateContentRequest":{"contents":[{"parts":[{"text":"hi"}]}]}}`, nil, counter)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"totalTokens":17}`, rec.Body.String())
+	require.NotContains(t, countBody, "max_tokens")
+
+	rec = runGeminiCompat(t, "/v1beta/models/m:embedContent", `{}`, nil, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
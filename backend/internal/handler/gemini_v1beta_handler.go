@@ -14,6 +14,7 @@ import (
 
 	"github.com/Wei-Shaw/sub2api/internal/domain"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/antigravity"
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/gemini"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/googleapi"
 	pkghttputil "github.com/Wei-Shaw/sub2api/internal/pkg/httputil"
@@ -253,9 +254,23 @@ func (h *GatewayHandler) GeminiV1BetaModels(c *gin.Context) {
 		return
 	}
 
+	// 引用 cachedContent 的请求只能由创建该缓存的账号处理：强制粘性到该账号。
+	cachedContentAccountID, body, err := h.geminiCompatService.ResolveCachedContentAccount(c.Request.Context(), service.GeminiCachedContentCaller{
+		GroupID:  apiKey.GroupID,
+		UserID:   authSubject.UserID,
+		APIKeyID: apiKey.ID,
+	}, body)
+	if err != nil {
+		googleError(c, infraerrors.Code(err), infraerrors.Message(err))
+		return
+	}
+
 	// 3) select account (sticky session based on request body)
 	// 优先使用 Gemini CLI 的会话标识（privileged-user-id + tmp 目录哈希）
 	sessionHash := extractGeminiCLISessionHash(c, body)
+	if cachedContentAccountID > 0 {
+		sessionHash = service.GeminiCachedContentSessionKey(cachedContentAccountID)
+	}
 	if sessionHash == "" {
 		// Fallback: 使用通用的会话哈希生成逻辑（适用于其他客户端）
 		parsedReq, _ := service.ParseGatewayRequest(service.NewRequestBodyRef(body), domain.PlatformGemini)
@@ -277,6 +292,9 @@ func (h *GatewayHandler) GeminiV1BetaModels(c *gin.Context) {
 	var sessionBoundAccountID int64
 	if sessionKey != "" {
 		sessionBoundAccountID, _ = h.gatewayService.GetCachedSessionAccountID(c.Request.Context(), apiKey.GroupID, sessionKey)
+		if cachedContentAccountID > 0 {
+			sessionBoundAccountID = cachedContentAccountID
+		}
 		if sessionBoundAccountID > 0 {
 			prefetchedGroupID := int64(0)
 			if apiKey.GroupID != nil {
@@ -396,6 +414,22 @@ func (h *GatewayHandler) GeminiV1BetaModels(c *gin.Context) {
 			}
 		}
 		account := selection.Account
+		if cachedContentAccountID > 0 && account.ID != cachedContentAccountID {
+			// 缓存不能跨账号使用，换号只会得到上游 403/404。
+			if selection.Acquired && selection.ReleaseFunc != nil {
+				selection.ReleaseFunc()
+			}
+			reqLog.Warn("gemini.cached_content_account_unavailable",
+				zap.Int64("cached_content_account_id", cachedContentAccountID),
+				zap.Int64("selected_account_id", account.ID),
+			)
+			if fs.LastFailoverErr != nil {
+				h.handleGeminiFailoverExhausted(c, fs.LastFailoverErr)
+				return
+			}
+			googleError(c, http.StatusServiceUnavailable, "The account holding this cachedContent is unavailable")
+			return
+		}
 		setOpsSelectedAccount(c, account.ID, account.Platform)
 
 		// 检测账号切换：如果粘性会话绑定的账号与当前选择的账号不同，清除 thoughtSignature
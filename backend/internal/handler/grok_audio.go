@@ -176,9 +176,26 @@ func (h *OpenAIGatewayHandler) GrokVoice(c *gin.Context, endpoint string) {
 		contentType = "application/json"
 	}
 
+	reqLog := requestLogger(c, "handler.openai_gateway.grok_voice", zap.String("endpoint", endpoint))
+	h.forwardGrokVoiceWithFailover(c, apiKey, reqLog, func(account *service.Account) (*service.OpenAIForwardResult, error) {
+		return h.gatewayService.ForwardGrokVoice(c.Request.Context(), c, account, endpoint, body, contentType)
+	}, func(account *service.Account, result *service.OpenAIForwardResult) {
+		h.recordGrokVoiceUsage(c, apiKey, account, subscription, endpoint, body, result)
+	})
+}
+
+// forwardGrokVoiceWithFailover selects Grok accounts and retries forward on
+// failover-eligible upstream errors. onSuccess runs once with the account that
+// served the request.
+func (h *OpenAIGatewayHandler) forwardGrokVoiceWithFailover(
+	c *gin.Context,
+	apiKey *service.APIKey,
+	reqLog *zap.Logger,
+	forward func(account *service.Account) (*service.OpenAIForwardResult, error),
+	onSuccess func(account *service.Account, result *service.OpenAIForwardResult),
+) {
 	failed := map[int64]struct{}{}
 	var last *service.UpstreamFailoverError
-	reqLog := requestLogger(c, "handler.openai_gateway.grok_voice", zap.String("endpoint", endpoint))
 	selectionModel := "grok-4.5"
 
 	for attempts := 0; attempts < 4; attempts++ {
@@ -222,10 +239,10 @@ func (h *OpenAIGatewayHandler) GrokVoice(c *gin.Context, endpoint string) {
 		}
 		result, forwardErr := func() (*service.OpenAIForwardResult, error) {
 			defer release()
-			return h.gatewayService.ForwardGrokVoice(c.Request.Context(), c, account, endpoint, body, contentType)
+			return forward(account)
 		}()
 		if forwardErr == nil {
-			h.recordGrokVoiceUsage(c, apiKey, account, subscription, endpoint, body, result)
+			onSuccess(account, result)
 			return
 		}
 		var failoverErr *service.UpstreamFailoverError
@@ -38,31 +38,45 @@ type AdminHandlers struct {
 	PromptAudit           *securityaudit.PromptAdminHandler
 	Compliance            *admin.ComplianceHandler
 	AuditLog              *admin.AuditLogHandler
+	AdminRBAC             *admin.AdminRBACHandler
+	AccountCost           *admin.AccountCostHandler
+	ProxyPool             *admin.ProxyPoolHandler
+	ProxySubscription     *admin.ProxySubscriptionHandler
+	APIKeyLeak            *admin.APIKeyLeakHandler
 }
 
 // Handlers contains all HTTP handlers
 type Handlers struct {
-	Auth             *AuthHandler
-	User             *UserHandler
-	APIKey           *APIKeyHandler
-	Usage            *UsageHandler
-	Redeem           *RedeemHandler
-	Subscription     *SubscriptionHandler
-	Announcement     *AnnouncementHandler
-	Admin            *AdminHandlers
-	Gateway          *GatewayHandler
-	OpenAIGateway    *OpenAIGatewayHandler
-	Setting          *SettingHandler
-	Totp             *TotpHandler
-	Referral         *ReferralHandler
-	ModelCatalog     *ModelCatalogHandler
-	PublicPricing    *PublicPricingHandler
-	GroupStatus      *GroupStatusHandler
-	Passkey          *PasskeyHandler
-	AvailableChannel *AvailableChannelHandler
-	AsyncImage       *AsyncImageHandler
-	BatchImage       *BatchImageHandler
-	PayBridge        *PayBridgeHandler
+	Auth                 *AuthHandler
+	User                 *UserHandler
+	APIKey               *APIKeyHandler
+	Usage                *UsageHandler
+	Redeem               *RedeemHandler
+	Subscription         *SubscriptionHandler
+	Announcement         *AnnouncementHandler
+	Admin                *AdminHandlers
+	Gateway              *GatewayHandler
+	OpenAIGateway        *OpenAIGatewayHandler
+	Setting              *SettingHandler
+	Totp                 *TotpHandler
+	Referral             *ReferralHandler
+	ModelCatalog         *ModelCatalogHandler
+	PublicPricing        *PublicPricingHandler
+	GroupStatus          *GroupStatusHandler
+	Passkey              *PasskeyHandler
+	AvailableChannel     *AvailableChannelHandler
+	AsyncImage           *AsyncImageHandler
+	BackgroundResponse   *BackgroundResponseHandler
+	StreamResume         *StreamResumeHandler
+	InferenceIdempotency *InferenceIdempotencyHandler
+	ContextCompaction    *ContextCompactionHandler
+	BatchImage           *BatchImageHandler
+	PayBridge            *PayBridgeHandler
+	SCIM                 *SCIMHandler
+	APIKeyLeak           *APIKeyLeakHandler
+	APIKeyRotation       *APIKeyRotationHandler
+	ScopedToken          *ScopedTokenHandler
+	UserData             *UserDataHandler
 }
 
 // BuildInfo contains build-time information
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/ctxkey"
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// InferenceIdempotencyHandler honors Idempotency-Key on inference endpoints so
+// that SDK retries after a timeout are not billed twice. Keys are scoped per
+// API key. A completed non-stream duplicate replays the stored response without
+// reaching the upstream or writing a usage log; a completed stream duplicate
+// gets a 409 naming the original request.
+type InferenceIdempotencyHandler struct {
+	idempotency *service.InferenceIdempotencyService
+}
+
+func NewInferenceIdempotencyHandler(idempotency *service.InferenceIdempotencyService) *InferenceIdempotencyHandler {
+	return &InferenceIdempotencyHandler{idempotency: idempotency}
+}
+
+// Wrap leaves requests without an Idempotency-Key untouched.
+func (h *InferenceIdempotencyHandler) Wrap(next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rawKey := strings.TrimSpace(c.GetHeader(idempotencyKeyHeader))
+		if rawKey == "" || h == nil || !h.idempotency.Available() {
+			next(c)
+			return
+		}
+		apiKey, ok := middleware2.GetAPIKeyFromContext(c)
+		if !ok || apiKey == nil {
+			next(c)
+			return
+		}
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			if body, err = io.ReadAll(c.Request.Body); err != nil {
+				if maxErr, ok := extractMaxBytesError(err); ok {
+					inferenceIdempotencyJSONError(c, http.StatusRequestEntityTooLarge, "invalid_request_error", buildBodyTooLargeMessage(maxErr.Limit), nil)
+					return
+				}
+				inferenceIdempotencyJSONError(c, http.StatusBadRequest, "invalid_request_error", "Failed to read request body", nil)
+				return
+			}
+			restoreBackgroundResponseBody(c.Request, body)
+		}
+
+		ctx := c.Request.Context()
+		claim, replay, err := h.idempotency.Begin(ctx, service.InferenceIdempotencyRequest{
+			APIKeyID: apiKey.ID,
+			Key:      rawKey,
+			Method:   c.Request.Method,
+			Route:    c.FullPath(),
+			Body:     body,
+		})
+		if err != nil {
+			inferenceIdempotencyError(c, err)
+			return
+		}
+		if replay != nil {
+			writeInferenceIdempotentReplay(c, replay)
+			return
+		}
+		h.execute(c, claim, next)
+	}
+}
+
+func (h *InferenceIdempotencyHandler) execute(c *gin.Context, claim *service.InferenceIdempotencyClaim, next gin.HandlerFunc) {
+	writer := &inferenceIdempotencyWriter{ResponseWriter: c.Writer, limit: h.idempotency.MaxStoredResponseLen()}
+	c.Writer = writer
+	stop := make(chan struct{})
+	go h.heartbeat(claim, stop)
+
+	completed := false
+	defer func() {
+		close(stop)
+		c.Writer = writer.ResponseWriter
+		// 客户端断开不影响落库结果，使用独立 context。
+		ctx := context.Background()
+		if !completed {
+			if err := h.idempotency.Release(ctx, claim, "EXECUTION_PANICKED"); err != nil {
+				logger.L().Warn("inference_idempotency.release_failed", zap.Error(err))
+			}
+			return
+		}
+		status := writer.Status()
+		if status >= http.StatusBadRequest || !writer.Written() {
+			// 失败的推理不计费，释放幂等键允许客户端用同一个键重试。
+			if err := h.idempotency.Release(ctx, claim, "HTTP_"+strconv.Itoa(status)); err != nil {
+				logger.L().Warn("inference_idempotency.release_failed", zap.Error(err))
+			}
+			return
+		}
+		contentType := writer.Header().Get("Content-Type")
+		response := service.InferenceIdempotentResponse{
+			Status:      status,
+			ContentType: contentType,
+			Body:        writer.body.String(),
+			Stream:      strings.HasPrefix(strings.ToLower(contentType), "text/event-stream"),
+			Truncated:   writer.overflow,
+			RequestID:   inferenceIdempotencyRequestID(c),
+		}
+		if err := h.idempotency.Complete(ctx, claim, response); err != nil {
+			logger.L().Warn("inference_idempotency.complete_failed", zap.Error(err))
+		}
+	}()
+	next(c)
+	completed = true
+}
+
+func (h *InferenceIdempotencyHandler) heartbeat(claim *service.InferenceIdempotencyClaim, stop <-chan struct{}) {
+	interval := h.idempotency.LockTTL() / 3
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := h.idempotency.Heartbeat(context.Background(), claim); err != nil {
+				logger.L().Warn("inference_idempotency.heartbeat_failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// inferenceIdempotencyWriter tees the response body up to the replay limit.
+type inferenceIdempotencyWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (w *inferenceIdempotencyWriter) Write(p []byte) (int, error) {
+	w.capture(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *inferenceIdempotencyWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *inferenceIdempotencyWriter) capture(p []byte) {
+	if w.overflow {
+		return
+	}
+	if w.limit > 0 && w.body.Len()+len(p) > w.limit {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(p)
+}
+
+func writeInferenceIdempotentReplay(c *gin.Context, replay *service.InferenceIdempotentResponse) {
+	contentType := replay.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	status := replay.Status
+	if status <= 0 {
+		status = http.StatusOK
+	}
+	c.Header("X-Idempotency-Replayed", "true")
+	if replay.RequestID != "" {
+		c.Header("X-Idempotency-Original-Request-ID", replay.RequestID)
+	}
+	c.Data(status, contentType, []byte(replay.Body))
+}
+
+func inferenceIdempotencyRequestID(c *gin.Context) string {
+	if v, _ := c.Request.Context().Value(ctxkey.ClientRequestID).(string); strings.TrimSpace(v) != "" {
+		return strings.TrimSpace(v)
+	}
+	return strings.TrimSpace(c.Writer.Header().Get("X-Client-Request-ID"))
+}
+
+func inferenceIdempotencyError(c *gin.Context, err error) {
+	status := infraerrors.Code(err)
+	if status <= 0 {
+		status = http.StatusInternalServerError
+	}
+	if retryAfter := service.RetryAfterSecondsFromError(err); retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+	}
+	errType := "api_error"
+	switch status {
+	case http.StatusBadRequest:
+		errType = "invalid_request_error"
+	case http.StatusConflict:
+		errType = "idempotency_error"
+	}
+	message := infraerrors.Message(err)
+	extra := gin.H{"code": infraerrors.Reason(err)}
+	if appErr := infraerrors.FromError(err); appErr != nil {
+		if original := appErr.Metadata["original_request_id"]; original != "" {
+			message += " (original request ID: " + original + ")"
+			extra["original_request_id"] = original
+		}
+	}
+	inferenceIdempotencyJSONError(c, status, errType, message, extra)
+}
+
+func inferenceIdempotencyJSONError(c *gin.Context, status int, errType, message string, extra gin.H) {
+	payload := gin.H{"type": errType, "message": message}
+	for key, value := range extra {
+		payload[key] = value
+	}
+	c.Header("Cache-Control", "no-store")
+	c.AbortWithStatusJSON(status, gin.H{"error": payload})
+}
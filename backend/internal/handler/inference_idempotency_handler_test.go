@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/ctxkey"
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+type inferenceIdempotencyMemoryRepo struct {
+	mu      sync.Mutex
+	nextID  int64
+	records map[string]*service.IdempotencyRecord
+}
+
+func (r *inferenceIdempotencyMemoryRepo) byID(id int64) *service.IdempotencyRecord {
+	for _, record := range r.records {
+		if record.ID == id {
+			return record
+		}
+	}
+	return nil
+}
+
+func (r *inferenceIdempotencyMemoryRepo) CreateProcessing(_ context.Context, record *service.IdempotencyRecord) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := record.Scope + "|" + record.IdempotencyKeyHash
+	if _, ok := r.records[key]; ok {
+		return false, nil
+	}
+	r.nextID++
+	record.ID = r.nextID
+	stored := *record
+	r.records[key] = &stored
+	return true, nil
+}
+
+func (r *inferenceIdempotencyMemoryRepo) GetByScopeAndKeyHash(_ context.Context, scope, keyHash string) (*service.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[scope+"|"+keyHash]
+	if !ok {
+		return nil, nil
+	}
+	out := *record
+	return &out, nil
+}
+
+func (r *inferenceIdempotencyMemoryRepo) TryReclaim(_ context.Context, id int64, fromStatus string, now, newLockedUntil, newExpiresAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record := r.byID(id)
+	if record == nil || record.Status != fromStatus || (record.LockedUntil != nil && record.LockedUntil.After(now)) {
+		return false, nil
+	}
+	record.Status = service.IdempotencyStatusProcessing
+	record.LockedUntil = &newLockedUntil
+	record.ExpiresAt = newExpiresAt
+	return true, nil
+}
+
+func (r *inferenceIdempotencyMemoryRepo) ExtendProcessingLock(_ context.Context, id int64, _ string, newLockedUntil, newExpiresAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record := r.byID(id)
+	if record == nil {
+		return false, nil
+	}
+	record.LockedUntil = &newLockedUntil
+	record.ExpiresAt = newExpiresAt
+	return true, nil
+}
+
+func (r *inferenceIdempotencyMemoryRepo) MarkSucceeded(_ context.Context, id int64, responseStatus int, responseBody string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record := r.byID(id)
+	if record == nil {
+		return errors.New("record not found")
+	}
+	record.Status = service.IdempotencyStatusSucceeded
+	record.LockedUntil = nil
+	record.ResponseStatus = &responseStatus
+	record.ResponseBody = &responseBody
+	record.ExpiresAt = expiresAt
+	return nil
+}
+
+func (r *inferenceIdempotencyMemoryRepo) MarkFailedRetryable(_ context.Context, id int64, _ string, lockedUntil, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record := r.byID(id)
+	if record == nil {
+		return errors.New("record not found")
+	}
+	record.Status = service.IdempotencyStatusFailedRetryable
+	record.LockedUntil = &lockedUntil
+	record.ExpiresAt = expiresAt
+	return nil
+}
+
+func (r *inferenceIdempotencyMemoryRepo) DeleteExpired(context.Context, time.Time, int) (int64, error) {
+	return 0, nil
+}
+
+func newInferenceIdempotencyTestRouter(t *testing.T, upstream gin.HandlerFunc) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repo := &inferenceIdempotencyMemoryRepo{records: make(map[string]*service.IdempotencyRecord)}
+	h := NewInferenceIdempotencyHandler(service.NewInferenceIdempotencyService(repo, service.DefaultIdempotencyConfig()))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), ctxkey.ClientRequestID, c.GetHeader("X-Test-Request-ID"))
+		c.Request = c.Request.WithContext(ctx)
+		c.Set(string(middleware2.ContextKeyAPIKey), &service.APIKey{ID: 9, UserID: 7})
+		c.Next()
+	})
+	router.POST("/v1/messages", h.Wrap(upstream))
+	return router
+}
+
+func serveInferenceIdempotencyRequest(router *gin.Engine, key, requestID, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+	req.Header.Set("X-Test-Request-ID", requestID)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestInferenceIdempotencyReplaysNonStreamResponse(t *testing.T) {
+	var calls atomic.Int32
+	router := newInferenceIdempotencyTestRouter(t, func(c *gin.Context) {
+		calls.Add(1)
+		c.JSON(http.StatusOK, gin.H{"id": "msg_1"})
+	})
+
+	first := serveInferenceIdempotencyRequest(router, "retry-1", "req-1", `{"model":"claude"}`)
+	require.Equal(t, http.StatusOK, first.Code)
+	second := serveInferenceIdempotencyRequest(router, "retry-1", "req-2", `{"model":"claude"}`)
+	require.Equal(t, http.StatusOK, second.Code)
+	require.JSONEq(t, first.Body.String(), second.Body.String())
+	require.Equal(t, "true", second.Header().Get("X-Idempotency-Replayed"))
+	require.Equal(t, "req-1", second.Header().Get("X-Idempotency-Original-Request-ID"))
+	require.EqualValues(t, 1, calls.Load())
+
+	conflict := serveInferenceIdempotencyRequest(router, "retry-1", "req-3", `{"model":"other"}`)
+	require.Equal(t, http.StatusConflict, conflict.Code)
+
+	// 不带 Idempotency-Key 的请求照常执行。
+	serveInferenceIdempotencyRequest(router, "", "req-4", `{"model":"claude"}`)
+	require.EqualValues(t, 2, calls.Load())
+}
+
+func TestInferenceIdempotencyStreamDuplicateReportsOriginalRequest(t *testing.T) {
+	var calls atomic.Int32
+	router := newInferenceIdempotencyTestRouter(t, func(c *gin.Context) {
+		calls.Add(1)
+		c.Header("Content-Type", "text/event-stream")
+		c.String(http.StatusOK, "event: message_stop\ndata: {}\n\n")
+	})
+
+	require.Equal(t, http.StatusOK, serveInferenceIdempotencyRequest(router, "retry-1", "req-1", `{"stream":true}`).Code)
+	duplicate := serveInferenceIdempotencyRequest(router, "retry-1", "req-2", `{"stream":true}`)
+	require.Equal(t, http.StatusConflict, duplicate.Code)
+	require.Equal(t, "IDEMPOTENCY_STREAM_COMPLETED", gjson.Get(duplicate.Body.String(), "error.code").String())
+	require.Equal(t, "req-1", gjson.Get(duplicate.Body.String(), "error.original_request_id").String())
+	require.Contains(t, gjson.Get(duplicate.Body.String(), "error.message").String(), "req-1")
+	require.EqualValues(t, 1, calls.Load())
+}
+
+func TestInferenceIdempotencyFailedRequestCanBeRetried(t *testing.T) {
+	var calls atomic.Int32
+	router := newInferenceIdempotencyTestRouter(t, func(c *gin.Context) {
+		if calls.Add(1) == 1 {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "upstream"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": "msg_2"})
+	})
+
+	require.Equal(t, http.StatusBadGateway, serveInferenceIdempotencyRequest(router, "retry-1", "req-1", `{}`).Code)
+	retried := serveInferenceIdempotencyRequest(router, "retry-1", "req-2", `{}`)
+	require.Equal(t, http.StatusOK, retried.Code)
+	require.Empty(t, retried.Header().Get("X-Idempotency-Replayed"))
+	require.EqualValues(t, 2, calls.Load())
+}
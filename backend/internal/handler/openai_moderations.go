@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	pkghttputil "github.com/Wei-Shaw/sub2api/internal/pkg/httputil"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Moderations handles the OpenAI-compatible moderation API.
+// POST /v1/moderations
+//
+// Requests are served from the content-moderation key pool rather than group
+// accounts, so the endpoint is available to every group. The input is not run
+// through the gateway's own content audit: classifying content is the point
+// of the call, and include_gateway_policy reports that audit's verdict instead.
+func (h *OpenAIGatewayHandler) Moderations(c *gin.Context) {
+	streamStarted := false
+	requestStart := time.Now()
+
+	apiKey, ok := middleware2.GetAPIKeyFromContext(c)
+	if !ok {
+		h.errorResponse(c, http.StatusUnauthorized, "authentication_error", "Invalid API key")
+		return
+	}
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		h.errorResponse(c, http.StatusInternalServerError, "api_error", "User context not found")
+		return
+	}
+	reqLog := requestLogger(
+		c,
+		"handler.openai_gateway.moderations",
+		zap.Int64("user_id", subject.UserID),
+		zap.Int64("api_key_id", apiKey.ID),
+		zap.Any("group_id", apiKey.GroupID),
+	)
+	if !h.ensureResponsesDependencies(c, reqLog) {
+		return
+	}
+	if h.contentModerationService == nil {
+		h.errorResponse(c, http.StatusServiceUnavailable, "api_error", "Moderation is not available")
+		return
+	}
+
+	body, err := pkghttputil.ReadRequestBodyWithPrealloc(c.Request)
+	if err != nil {
+		if maxErr, ok := extractMaxBytesError(err); ok {
+			h.errorResponse(c, http.StatusRequestEntityTooLarge, "invalid_request_error", buildBodyTooLargeMessage(maxErr.Limit))
+			return
+		}
+		h.errorResponse(c, http.StatusBadRequest, "invalid_request_error", "Failed to read request body")
+		return
+	}
+	if len(body) == 0 {
+		h.errorResponse(c, http.StatusBadRequest, "invalid_request_error", "Request body is empty")
+		return
+	}
+	req, err := service.ParsePublicModerationRequest(body)
+	if err != nil {
+		h.errorResponse(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	reqLog = reqLog.With(zap.String("model", req.Model))
+	setOpsRequestContext(c, req.Model, false)
+	setOpsEndpointContext(c, "", int16(service.RequestTypeSync))
+
+	subscription, _ := middleware2.GetSubscriptionFromContext(c)
+	service.SetOpsLatencyMs(c, service.OpsAuthLatencyMsKey, time.Since(requestStart).Milliseconds())
+
+	userReleaseFunc, acquired := h.acquireResponsesUserSlot(c, subject.UserID, subject.Concurrency, false, &streamStarted, reqLog)
+	if !acquired {
+		return
+	}
+	if userReleaseFunc != nil {
+		defer userReleaseFunc()
+	}
+
+	if err := h.billingCacheService.CheckBillingEligibility(c.Request.Context(), apiKey.User, apiKey, apiKey.Group, subscription, service.QuotaPlatform(c.Request.Context(), apiKey)); err != nil {
+		reqLog.Info("openai_moderations.billing_check_failed", zap.Error(err))
+		status, code, message, retryAfter := billingErrorDetails(err)
+		if retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+		h.errorResponse(c, status, code, message)
+		return
+	}
+
+	result, err := h.contentModerationService.ModeratePublic(c.Request.Context(), req, apiKey.GroupID)
+	if err != nil {
+		status := infraerrors.Code(err)
+		errType := "api_error"
+		if status == http.StatusBadRequest {
+			errType = "invalid_request_error"
+		}
+		reqLog.Warn("openai_moderations.failed", zap.Int("status", status), zap.Error(err))
+		h.errorResponse(c, status, errType, infraerrors.Message(err))
+		return
+	}
+	c.Data(http.StatusOK, "application/json", result.Body)
+
+	usageInput := &service.OpenAIModerationUsageInput{
+		Result:             result,
+		APIKey:             apiKey,
+		User:               apiKey.User,
+		Subscription:       subscription,
+		RequestPayloadHash: service.HashUsageRequestPayload(body),
+		APIKeyService:      h.apiKeyService,
+		QuotaPlatform:      service.QuotaPlatform(c.Request.Context(), apiKey),
+	}
+	h.submitMandatoryUsageRecordTask(c.Request.Context(), func(ctx context.Context) {
+		if err := h.gatewayService.RecordModerationUsage(ctx, usageInput); err != nil {
+			logger.L().With(
+				zap.String("component", "handler.openai_gateway.moderations"),
+				zap.Int64("user_id", subject.UserID),
+				zap.Int64("api_key_id", apiKey.ID),
+				zap.Any("group_id", apiKey.GroupID),
+				zap.String("model", result.Model),
+			).Error("openai_moderations.record_usage_failed", zap.Error(err))
+		}
+	})
+}
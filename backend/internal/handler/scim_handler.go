@@ -0,0 +1,561 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+)
+
+const (
+	scimContentType = "application/scim+json"
+
+	scimSchemaUser           = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimSchemaGroup          = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimSchemaListResponse   = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimSchemaError          = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimSchemaProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	scimSchemaResourceType   = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+
+	// scimBasePath 是资源 meta.location 的前缀（与 routes.RegisterSCIMRoutes 保持一致）。
+	scimBasePath = "/scim/v2"
+)
+
+// scimTypeByReason 把业务错误映射到 RFC 7644 §3.12 的 scimType。
+var scimTypeByReason = map[string]string{
+	"SCIM_INVALID_FILTER":   "invalidFilter",
+	"SCIM_INVALID_VALUE":    "invalidValue",
+	"SCIM_INVALID_PATH":     "invalidPath",
+	"SCIM_MUTABILITY":       "mutability",
+	"SCIM_USER_EXISTS":      "uniqueness",
+	"SCIM_GROUP_EXISTS":     "uniqueness",
+	"SCIM_GROUP_NOT_MAPPED": "invalidValue",
+}
+
+// SCIMHandler 实现 SCIM 2.0（RFC 7643/7644）的 /Users 与 /Groups 端点，
+// 供身份提供方（Okta、Azure AD 等）以具名管理员 Token 开通用户与分组授权。
+type SCIMHandler struct {
+	scimService *service.SCIMService
+}
+
+func NewSCIMHandler(scimService *service.SCIMService) *SCIMHandler {
+	return &SCIMHandler{scimService: scimService}
+}
+
+type scimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type scimEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type scimRef struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+type scimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Location     string `json:"location"`
+}
+
+type scimUserResource struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id"`
+	ExternalID  string      `json:"externalId,omitempty"`
+	UserName    string      `json:"userName"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Name        *scimName   `json:"name,omitempty"`
+	Active      bool        `json:"active"`
+	Emails      []scimEmail `json:"emails"`
+	Groups      []scimRef   `json:"groups"`
+	Meta        scimMeta    `json:"meta"`
+}
+
+type scimGroupResource struct {
+	Schemas     []string  `json:"schemas"`
+	ID          string    `json:"id"`
+	ExternalID  string    `json:"externalId,omitempty"`
+	DisplayName string    `json:"displayName"`
+	Members     []scimRef `json:"members"`
+	Meta        scimMeta  `json:"meta"`
+}
+
+type scimListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int64    `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+type scimUserRequest struct {
+	UserName    string          `json:"userName"`
+	ExternalID  string          `json:"externalId"`
+	DisplayName string          `json:"displayName"`
+	Name        *scimName       `json:"name"`
+	Emails      []scimEmail     `json:"emails"`
+	Active      json.RawMessage `json:"active"`
+	Password    string          `json:"password"`
+}
+
+type scimGroupRequest struct {
+	DisplayName string    `json:"displayName"`
+	ExternalID  string    `json:"externalId"`
+	Members     []scimRef `json:"members"`
+}
+
+type scimPatchRequest struct {
+	Schemas    []string                     `json:"schemas"`
+	Operations []service.SCIMPatchOperation `json:"Operations"`
+}
+
+// ServiceProviderConfig GET /scim/v2/ServiceProviderConfig
+func (h *SCIMHandler) ServiceProviderConfig(c *gin.Context) {
+	scimJSON(c, http.StatusOK, gin.H{
+		"schemas":          []string{scimSchemaProviderConfig},
+		"documentationUri": "",
+		"patch":            gin.H{"supported": true},
+		"bulk":             gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":           gin.H{"supported": true, "maxResults": service.SCIMMaxPageSize},
+		"changePassword":   gin.H{"supported": false},
+		"sort":             gin.H{"supported": false},
+		"etag":             gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "Admin API Token",
+			"description": "Scoped admin API token (admtok-...) with the scim:provision permission",
+			"primary":     true,
+		}},
+		"meta": gin.H{"resourceType": "ServiceProviderConfig", "location": scimBasePath + "/ServiceProviderConfig"},
+	})
+}
+
+// ResourceTypes GET /scim/v2/ResourceTypes
+func (h *SCIMHandler) ResourceTypes(c *gin.Context) {
+	resources := []any{
+		gin.H{
+			"schemas":  []string{scimSchemaResourceType},
+			"id":       "User",
+			"name":     "User",
+			"endpoint": "/Users",
+			"schema":   scimSchemaUser,
+			"meta":     gin.H{"resourceType": "ResourceType", "location": scimBasePath + "/ResourceTypes/User"},
+		},
+		gin.H{
+			"schemas":  []string{scimSchemaResourceType},
+			"id":       "Group",
+			"name":     "Group",
+			"endpoint": "/Groups",
+			"schema":   scimSchemaGroup,
+			"meta":     gin.H{"resourceType": "ResourceType", "location": scimBasePath + "/ResourceTypes/Group"},
+		},
+	}
+	scimJSON(c, http.StatusOK, scimListResponse{
+		Schemas:      []string{scimSchemaListResponse},
+		TotalResults: int64(len(resources)),
+		StartIndex:   1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// ListUsers GET /scim/v2/Users?filter=userName eq "..."&startIndex=1&count=100
+func (h *SCIMHandler) ListUsers(c *gin.Context) {
+	filter, err := service.ParseSCIMFilter(c.Query("filter"), "userName", "externalId", "id")
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	startIndex, count := scimPageParams(c)
+	offset, limit := service.NormalizeSCIMPage(startIndex, count)
+	users, total, err := h.scimService.ListUsers(c.Request.Context(), filter, offset, limit)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	resources := make([]any, 0, len(users))
+	for i := range users {
+		resources = append(resources, scimUserToResource(&users[i]))
+	}
+	scimJSON(c, http.StatusOK, scimListResponse{
+		Schemas:      []string{scimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   offset + 1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// GetUser GET /scim/v2/Users/:id
+func (h *SCIMHandler) GetUser(c *gin.Context) {
+	id, ok := scimResourceID(c, service.ErrSCIMUserNotFound)
+	if !ok {
+		return
+	}
+	user, err := h.scimService.GetUser(c.Request.Context(), id)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, scimUserToResource(user))
+}
+
+// CreateUser POST /scim/v2/Users
+func (h *SCIMHandler) CreateUser(c *gin.Context) {
+	input, ok := bindSCIMUserInput(c)
+	if !ok {
+		return
+	}
+	user, err := h.scimService.CreateUser(c.Request.Context(), scimActorID(c), input)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	resource := scimUserToResource(user)
+	c.Header("Location", resource.Meta.Location)
+	scimJSON(c, http.StatusCreated, resource)
+}
+
+// ReplaceUser PUT /scim/v2/Users/:id
+func (h *SCIMHandler) ReplaceUser(c *gin.Context) {
+	id, ok := scimResourceID(c, service.ErrSCIMUserNotFound)
+	if !ok {
+		return
+	}
+	input, ok := bindSCIMUserInput(c)
+	if !ok {
+		return
+	}
+	user, err := h.scimService.ReplaceUser(c.Request.Context(), scimActorID(c), id, input)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, scimUserToResource(user))
+}
+
+// PatchUser PATCH /scim/v2/Users/:id
+func (h *SCIMHandler) PatchUser(c *gin.Context) {
+	id, ok := scimResourceID(c, service.ErrSCIMUserNotFound)
+	if !ok {
+		return
+	}
+	ops, ok := bindSCIMPatch(c)
+	if !ok {
+		return
+	}
+	user, err := h.scimService.PatchUser(c.Request.Context(), scimActorID(c), id, ops)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, scimUserToResource(user))
+}
+
+// DeleteUser DELETE /scim/v2/Users/:id
+func (h *SCIMHandler) DeleteUser(c *gin.Context) {
+	id, ok := scimResourceID(c, service.ErrSCIMUserNotFound)
+	if !ok {
+		return
+	}
+	if err := h.scimService.DeleteUser(c.Request.Context(), scimActorID(c), id); err != nil {
+		scimError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListGroups GET /scim/v2/Groups?filter=displayName eq "..."
+func (h *SCIMHandler) ListGroups(c *gin.Context) {
+	filter, err := service.ParseSCIMFilter(c.Query("filter"), "displayName", "externalId", "id")
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	startIndex, count := scimPageParams(c)
+	offset, limit := service.NormalizeSCIMPage(startIndex, count)
+	groups, total, err := h.scimService.ListGroups(c.Request.Context(), filter, offset, limit)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	resources := make([]any, 0, len(groups))
+	for i := range groups {
+		resources = append(resources, scimGroupToResource(&groups[i]))
+	}
+	scimJSON(c, http.StatusOK, scimListResponse{
+		Schemas:      []string{scimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   offset + 1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// GetGroup GET /scim/v2/Groups/:id
+func (h *SCIMHandler) GetGroup(c *gin.Context) {
+	id, ok := scimResourceID(c, service.ErrSCIMGroupNotFound)
+	if !ok {
+		return
+	}
+	group, err := h.scimService.GetGroup(c.Request.Context(), id)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, scimGroupToResource(group))
+}
+
+// CreateGroup POST /scim/v2/Groups
+func (h *SCIMHandler) CreateGroup(c *gin.Context) {
+	var req scimGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimError(c, service.ErrSCIMInvalidValue)
+		return
+	}
+	group, err := h.scimService.CreateGroup(c.Request.Context(), scimActorID(c), scimGroupInput(&req))
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	resource := scimGroupToResource(group)
+	c.Header("Location", resource.Meta.Location)
+	scimJSON(c, http.StatusCreated, resource)
+}
+
+// ReplaceGroup PUT /scim/v2/Groups/:id
+func (h *SCIMHandler) ReplaceGroup(c *gin.Context) {
+	id, ok := scimResourceID(c, service.ErrSCIMGroupNotFound)
+	if !ok {
+		return
+	}
+	var req scimGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimError(c, service.ErrSCIMInvalidValue)
+		return
+	}
+	group, err := h.scimService.ReplaceGroup(c.Request.Context(), scimActorID(c), id, scimGroupInput(&req))
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, scimGroupToResource(group))
+}
+
+// PatchGroup PATCH /scim/v2/Groups/:id
+func (h *SCIMHandler) PatchGroup(c *gin.Context) {
+	id, ok := scimResourceID(c, service.ErrSCIMGroupNotFound)
+	if !ok {
+		return
+	}
+	ops, ok := bindSCIMPatch(c)
+	if !ok {
+		return
+	}
+	group, err := h.scimService.PatchGroup(c.Request.Context(), scimActorID(c), id, ops)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, scimGroupToResource(group))
+}
+
+// DeleteGroup DELETE /scim/v2/Groups/:id
+func (h *SCIMHandler) DeleteGroup(c *gin.Context) {
+	id, ok := scimResourceID(c, service.ErrSCIMGroupNotFound)
+	if !ok {
+		return
+	}
+	if err := h.scimService.DeleteGroup(c.Request.Context(), scimActorID(c), id); err != nil {
+		scimError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func bindSCIMUserInput(c *gin.Context) (*service.SCIMUserInput, bool) {
+	var req scimUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimError(c, service.ErrSCIMInvalidValue)
+		return nil, false
+	}
+	input := &service.SCIMUserInput{
+		Email:      scimRequestEmail(&req),
+		Username:   strings.TrimSpace(req.DisplayName),
+		ExternalID: req.ExternalID,
+		Password:   req.Password,
+	}
+	if input.Username == "" && req.Name != nil {
+		input.Username = strings.TrimSpace(req.Name.Formatted)
+		if input.Username == "" {
+			input.Username = strings.TrimSpace(strings.TrimSpace(req.Name.GivenName) + " " + strings.TrimSpace(req.Name.FamilyName))
+		}
+	}
+	if len(req.Active) > 0 && string(req.Active) != "null" {
+		active, err := service.ParseSCIMBool(req.Active)
+		if err != nil {
+			scimError(c, err)
+			return nil, false
+		}
+		input.Active = &active
+	}
+	return input, true
+}
+
+// scimRequestEmail 优先使用邮箱形式的 userName，否则退回 primary（或首个）邮箱。
+func scimRequestEmail(req *scimUserRequest) string {
+	userName := strings.TrimSpace(req.UserName)
+	if strings.Contains(userName, "@") || len(req.Emails) == 0 {
+		return userName
+	}
+	for _, email := range req.Emails {
+		if email.Primary {
+			return strings.TrimSpace(email.Value)
+		}
+	}
+	return strings.TrimSpace(req.Emails[0].Value)
+}
+
+func scimGroupInput(req *scimGroupRequest) *service.SCIMGroupInput {
+	members := make([]string, 0, len(req.Members))
+	for _, member := range req.Members {
+		members = append(members, member.Value)
+	}
+	return &service.SCIMGroupInput{
+		DisplayName: req.DisplayName,
+		ExternalID:  req.ExternalID,
+		Members:     members,
+	}
+}
+
+func bindSCIMPatch(c *gin.Context) ([]service.SCIMPatchOperation, bool) {
+	var req scimPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Operations) == 0 {
+		scimError(c, service.ErrSCIMInvalidValue)
+		return nil, false
+	}
+	return req.Operations, true
+}
+
+func scimUserToResource(user *service.SCIMUser) scimUserResource {
+	id := strconv.FormatInt(user.ID, 10)
+	groups := make([]scimRef, 0, len(user.Groups))
+	for _, group := range user.Groups {
+		groupID := strconv.FormatInt(group.ID, 10)
+		groups = append(groups, scimRef{Value: groupID, Display: group.Name, Ref: scimBasePath + "/Groups/" + groupID})
+	}
+	resource := scimUserResource{
+		Schemas:     []string{scimSchemaUser},
+		ID:          id,
+		ExternalID:  user.ExternalID,
+		UserName:    user.Email,
+		DisplayName: user.Username,
+		Active:      user.Status == service.StatusActive,
+		Emails:      []scimEmail{{Value: user.Email, Type: "work", Primary: true}},
+		Groups:      groups,
+		Meta: scimMeta{
+			ResourceType: "User",
+			Created:      scimTime(user.CreatedAt),
+			LastModified: scimTime(user.UpdatedAt),
+			Location:     scimBasePath + "/Users/" + id,
+		},
+	}
+	if user.Username != "" {
+		resource.Name = &scimName{Formatted: user.Username}
+	}
+	return resource
+}
+
+func scimGroupToResource(group *service.SCIMGroup) scimGroupResource {
+	id := strconv.FormatInt(group.ID, 10)
+	members := make([]scimRef, 0, len(group.Members))
+	for _, member := range group.Members {
+		userID := strconv.FormatInt(member.UserID, 10)
+		members = append(members, scimRef{Value: userID, Display: member.Email, Ref: scimBasePath + "/Users/" + userID})
+	}
+	return scimGroupResource{
+		Schemas:     []string{scimSchemaGroup},
+		ID:          id,
+		ExternalID:  group.ExternalID,
+		DisplayName: group.Name,
+		Members:     members,
+		Meta: scimMeta{
+			ResourceType: "Group",
+			Created:      scimTime(group.CreatedAt),
+			LastModified: scimTime(group.UpdatedAt),
+			Location:     scimBasePath + "/Groups/" + id,
+		},
+	}
+}
+
+func scimTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func scimPageParams(c *gin.Context) (startIndex, count int) {
+	startIndex, _ = strconv.Atoi(c.DefaultQuery("startIndex", "1"))
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(service.SCIMDefaultPageSize)))
+	if err != nil {
+		count = service.SCIMDefaultPageSize
+	}
+	return startIndex, count
+}
+
+// scimResourceID 解析路径中的资源 ID；非数字 ID 一律按资源不存在处理。
+func scimResourceID(c *gin.Context, notFound error) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		scimError(c, notFound)
+		return 0, false
+	}
+	return id, true
+}
+
+func scimActorID(c *gin.Context) int64 {
+	subject, _ := middleware2.GetAuthSubjectFromContext(c)
+	return subject.UserID
+}
+
+func scimJSON(c *gin.Context, status int, body any) {
+	c.Header("Content-Type", scimContentType)
+	c.JSON(status, body)
+}
+
+// scimError 以 RFC 7644 §3.12 的 Error 结构返回错误；5xx 不透出内部细节。
+func scimError(c *gin.Context, err error) {
+	status := infraerrors.Code(err)
+	detail := infraerrors.Message(err)
+	if status >= http.StatusInternalServerError {
+		logger.LegacyPrintf("handler.scim", "scim request failed: method=%s path=%s err=%v", c.Request.Method, c.FullPath(), err)
+		detail = "internal server error"
+	}
+	body := gin.H{
+		"schemas": []string{scimSchemaError},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType, ok := scimTypeByReason[infraerrors.Reason(err)]; ok {
+		body["scimType"] = scimType
+	}
+	scimJSON(c, status, body)
+}
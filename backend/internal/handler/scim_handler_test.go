@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type scimHandlerRepoStub struct {
+	service.SCIMRepository
+	users      []service.SCIMUser
+	lastFilter *service.SCIMFilter
+	lastOffset int
+	lastLimit  int
+}
+
+func (r *scimHandlerRepoStub) ListUsers(_ context.Context, filter *service.SCIMFilter, offset, limit int) ([]service.SCIMUser, int64, error) {
+	r.lastFilter, r.lastOffset, r.lastLimit = filter, offset, limit
+	return r.users, int64(len(r.users)), nil
+}
+
+func (r *scimHandlerRepoStub) GetUser(_ context.Context, userID int64) (*service.SCIMUser, error) {
+	for i := range r.users {
+		if r.users[i].ID == userID {
+			return &r.users[i], nil
+		}
+	}
+	return nil, service.ErrSCIMUserNotFound
+}
+
+func newSCIMHandlerTestRouter(repo service.SCIMRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewSCIMHandler(service.NewSCIMService(repo, nil, nil, nil, nil, nil, nil))
+	router := gin.New()
+	router.GET("/scim/v2/Users", h.ListUsers)
+	router.GET("/scim/v2/Users/:id", h.GetUser)
+	router.PATCH("/scim/v2/Users/:id", h.PatchUser)
+	return router
+}
+
+func TestSCIMHandlerListUsersRendersListResponse(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	repo := &scimHandlerRepoStub{users: []service.SCIMUser{{
+		ID:         42,
+		Email:      "alice@example.com",
+		Username:   "Alice",
+		Status:     service.StatusDisabled,
+		ExternalID: "00u42",
+		CreatedAt:  created,
+		UpdatedAt:  created,
+		Groups:     []service.SCIMGroupRef{{ID: 3, Name: "team"}},
+	}}}
+	router := newSCIMHandlerTestRouter(repo)
+
+	query := url.Values{"filter": {`userName eq "alice@example.com"`}, "startIndex": {"1"}, "count": {"5"}}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/scim/v2/Users?"+query.Encode(), nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/scim+json", rec.Header().Get("Content-Type"))
+	require.Equal(t, &service.SCIMFilter{Attribute: "username", Value: "alice@example.com"}, repo.lastFilter)
+	require.Equal(t, 0, repo.lastOffset)
+	require.Equal(t, 5, repo.lastLimit)
+
+	var body struct {
+		Schemas      []string `json:"schemas"`
+		TotalResults int      `json:"totalResults"`
+		Resources    []struct {
+			ID         string `json:"id"`
+			UserName   string `json:"userName"`
+			ExternalID string `json:"externalId"`
+			Active     bool   `json:"active"`
+			Groups     []struct {
+				Value   string `json:"value"`
+				Display string `json:"display"`
+			} `json:"groups"`
+			Meta struct {
+				Location string `json:"location"`
+				Created  string `json:"created"`
+			} `json:"meta"`
+		} `json:"Resources"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, []string{"urn:ietf:params:scim:api:messages:2.0:ListResponse"}, body.Schemas)
+	require.Equal(t, 1, body.TotalResults)
+	require.Len(t, body.Resources, 1)
+	resource := body.Resources[0]
+	require.Equal(t, "42", resource.ID)
+	require.Equal(t, "alice@example.com", resource.UserName)
+	require.Equal(t, "00u42", resource.ExternalID)
+	require.False(t, resource.Active)
+	require.Equal(t, "3", resource.Groups[0].Value)
+	require.Equal(t, "team", resource.Groups[0].Display)
+	require.Equal(t, "/scim/v2/Users/42", resource.Meta.Location)
+	require.Equal(t, "2026-01-02T03:04:05Z", resource.Meta.Created)
+}
+
+func TestSCIMHandlerErrorsUseSCIMSchema(t *testing.T) {
+	router := newSCIMHandlerTestRouter(&scimHandlerRepoStub{})
+
+	cases := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantType   string
+	}{
+		{name: "unknown_user", method: http.MethodGet, path: "/scim/v2/Users/7", wantStatus: http.StatusNotFound},
+		{name: "non_numeric_id", method: http.MethodGet, path: "/scim/v2/Users/abc", wantStatus: http.StatusNotFound},
+		{name: "unsupported_filter", method: http.MethodGet, path: "/scim/v2/Users?filter=" + url.QueryEscape(`userName sw "a"`), wantStatus: http.StatusBadRequest, wantType: "invalidFilter"},
+		{name: "empty_patch", method: http.MethodPatch, path: "/scim/v2/Users/7", body: `{"Operations":[]}`, wantStatus: http.StatusBadRequest, wantType: "invalidValue"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if tc.body != "" {
+				req.Header.Set("Content-Type", "application/scim+json")
+			}
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code)
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			require.Equal(t, []any{"urn:ietf:params:scim:api:messages:2.0:Error"}, body["schemas"])
+			require.Equal(t, strconv.Itoa(tc.wantStatus), body["status"])
+			if tc.wantType != "" {
+				require.Equal(t, tc.wantType, body["scimType"])
+			}
+		})
+	}
+}
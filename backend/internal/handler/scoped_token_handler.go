@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScopedTokenHandler 由 API Key 派生短期受限令牌（网关侧签发/吊销，用户面板侧吊销）。
+type ScopedTokenHandler struct {
+	scopedTokenService *service.ScopedTokenService
+}
+
+// NewScopedTokenHandler creates a new ScopedTokenHandler
+func NewScopedTokenHandler(scopedTokenService *service.ScopedTokenService) *ScopedTokenHandler {
+	return &ScopedTokenHandler{scopedTokenService: scopedTokenService}
+}
+
+// Mint 以当前 API Key 签发派生令牌；令牌的用量与扣费记在该 Key 上。
+// POST /v1/sub2api/tokens
+func (h *ScopedTokenHandler) Mint(c *gin.Context) {
+	apiKey, ok := middleware2.GetAPIKeyFromContext(c)
+	if !ok || apiKey == nil {
+		scopedTokenJSONError(c, http.StatusUnauthorized, "authentication_error", "Invalid API key")
+		return
+	}
+	var req service.MintScopedTokenRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			scopedTokenJSONError(c, http.StatusBadRequest, "invalid_request_error", "Invalid request: "+err.Error())
+			return
+		}
+	}
+	token, err := h.scopedTokenService.Mint(c.Request.Context(), apiKey, req)
+	if err != nil {
+		scopedTokenError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, token)
+}
+
+// RevokeAll 吊销当前 API Key 签发的全部派生令牌（派生令牌本身不能调用）。
+// DELETE /v1/sub2api/tokens
+func (h *ScopedTokenHandler) RevokeAll(c *gin.Context) {
+	apiKey, ok := middleware2.GetAPIKeyFromContext(c)
+	if !ok || apiKey == nil {
+		scopedTokenJSONError(c, http.StatusUnauthorized, "authentication_error", "Invalid API key")
+		return
+	}
+	if apiKey.ScopedToken != nil {
+		scopedTokenError(c, service.ErrScopedTokenNotMintable)
+		return
+	}
+	if err := h.scopedTokenService.RevokeAll(c.Request.Context(), apiKey.UserID, apiKey.ID); err != nil {
+		scopedTokenError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeAllForUser 在用户面板吊销指定 Key 签发的全部派生令牌
+// POST /api/v1/user/api-keys/:id/scoped-tokens/revoke
+func (h *ScopedTokenHandler) RevokeAllForUser(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	keyID, ok := parseAPIKeyIDParam(c)
+	if !ok {
+		return
+	}
+	if err := h.scopedTokenService.RevokeAll(c.Request.Context(), subject.UserID, keyID); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, gin.H{"api_key_id": keyID, "revoked": true})
+}
+
+func scopedTokenError(c *gin.Context, err error) {
+	status := infraerrors.Code(err)
+	code := infraerrors.Reason(err)
+	message := infraerrors.Message(err)
+	if status <= 0 {
+		status = http.StatusInternalServerError
+	}
+	if strings.TrimSpace(code) == "" {
+		code = "SCOPED_TOKEN_ERROR"
+	}
+	scopedTokenJSONError(c, status, code, message)
+}
+
+func scopedTokenJSONError(c *gin.Context, status int, code, message string) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, gin.H{"error": gin.H{"type": code, "code": code, "message": message}})
+}
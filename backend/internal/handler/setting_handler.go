@@ -96,6 +96,8 @@ func (h *SettingHandler) GetPublicSettings(c *gin.Context) {
 		OIDCOAuthProviderName:               settings.OIDCOAuthProviderName,
 		GitHubOAuthEnabled:                  settings.GitHubOAuthEnabled,
 		GoogleOAuthEnabled:                  settings.GoogleOAuthEnabled,
+		SAMLEnabled:                         settings.SAMLEnabled,
+		SAMLProviderName:                    settings.SAMLProviderName,
 		GroupStatusEnabled:                  settings.GroupStatusEnabled,
 		ReferralEnabled:                     settings.ReferralEnabled,
 		BackendModeEnabled:                  settings.BackendModeEnabled,
@@ -0,0 +1,524 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"go.uber.org/zap"
+)
+
+const (
+	streamResumeFlushInterval = 200 * time.Millisecond
+	streamResumePollInterval  = 500 * time.Millisecond
+)
+
+// streamResumeSkippedHeaders are connection-level headers that must not be
+// replayed to a reconnecting client.
+var streamResumeSkippedHeaders = map[string]struct{}{
+	"Content-Length":    {},
+	"Connection":        {},
+	"Transfer-Encoding": {},
+	"Keep-Alive":        {},
+}
+
+// StreamResumeHandler makes streaming gateway requests resumable for groups
+// that opt in. The upstream stream runs detached from the client connection
+// and every SSE event is numbered and buffered, so a client that drops can
+// reconnect with X-Sub2API-Stream-ID and Last-Event-ID and receive the rest
+// without a second upstream request (and therefore without a second charge).
+type StreamResumeHandler struct {
+	streams       *service.StreamResumeService
+	live          sync.Map // stream ID -> *streamResumeRelay
+	flushInterval time.Duration
+	pollInterval  time.Duration
+}
+
+func NewStreamResumeHandler(streams *service.StreamResumeService) *StreamResumeHandler {
+	return &StreamResumeHandler{
+		streams:       streams,
+		flushInterval: streamResumeFlushInterval,
+		pollInterval:  streamResumePollInterval,
+	}
+}
+
+// Wrap returns next unchanged in behavior unless the API key's group has
+// stream resume enabled and the request is either a reconnect or a streaming
+// request.
+func (h *StreamResumeHandler) Wrap(next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h == nil || !h.streams.Available() {
+			next(c)
+			return
+		}
+		apiKey, ok := middleware2.GetAPIKeyFromContext(c)
+		if !ok || apiKey == nil || !service.StreamResumeEnabledForGroup(apiKey.Group) {
+			next(c)
+			return
+		}
+		owner := service.StreamResumeOwner{UserID: apiKey.UserID, APIKeyID: apiKey.ID}
+		if id := strings.TrimSpace(c.GetHeader(service.StreamResumeIDHeader)); id != "" {
+			h.resume(c, owner, id)
+			return
+		}
+		if c.Request.Method != http.MethodPost || c.Request.Body == nil {
+			next(c)
+			return
+		}
+		// 原样读取并放回请求体（压缩体不解析，按非续传处理），由原 handler 负责校验与解码。
+		body, err := io.ReadAll(c.Request.Body)
+		restoreBackgroundResponseBody(c.Request, body)
+		if err != nil || !gjson.GetBytes(body, "stream").Bool() {
+			next(c)
+			return
+		}
+		h.start(c, owner, body, next)
+	}
+}
+
+// start registers a new stream, runs next against a relay writer and follows
+// the relay on the client connection.
+func (h *StreamResumeHandler) start(c *gin.Context, owner service.StreamResumeOwner, body []byte, next gin.HandlerFunc) {
+	record, err := h.streams.Create(c.Request.Context(), owner)
+	if err != nil {
+		logger.L().Warn("stream_resume.create_failed", zap.Error(err))
+		next(c)
+		return
+	}
+	relay := newStreamResumeRelay(record)
+	h.live.Store(record.ID, relay)
+
+	base := context.WithoutCancel(c.Request.Context())
+	executionCtx, cancel := context.WithTimeout(base, h.streams.MaxDuration())
+	request := c.Request.Clone(executionCtx)
+	restoreBackgroundResponseBody(request, body)
+	taskCtx := c.Copy()
+	writerCtx, _ := gin.CreateTestContext(relay)
+	taskCtx.Writer = writerCtx.Writer
+	taskCtx.Request = request
+
+	go h.run(taskCtx, relay, cancel, next)
+	h.follow(c, relay, -1)
+}
+
+func (h *StreamResumeHandler) run(taskCtx *gin.Context, relay *streamResumeRelay, cancel context.CancelFunc, next gin.HandlerFunc) {
+	defer cancel()
+	id := relay.record.ID
+	done := make(chan struct{})
+	go h.persistLoop(relay, done)
+	func() {
+		defer close(done)
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				logger.L().Error("stream_resume.execution_panicked", zap.String("stream_id", id), zap.Any("panic", recovered))
+			}
+		}()
+		next(taskCtx)
+	}()
+	relay.finish()
+
+	ctx := context.Background()
+	h.persist(ctx, relay)
+	if err := h.streams.Finish(ctx, relay.record); err != nil {
+		logger.L().Warn("stream_resume.finish_failed", zap.String("stream_id", id), zap.Error(err))
+	}
+	h.live.Delete(id)
+}
+
+func (h *StreamResumeHandler) persistLoop(relay *streamResumeRelay, done <-chan struct{}) {
+	ticker := time.NewTicker(h.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			h.persist(context.Background(), relay)
+		}
+	}
+}
+
+// persist writes the committed response head and any events not yet stored.
+func (h *StreamResumeHandler) persist(ctx context.Context, relay *streamResumeRelay) {
+	relay.persistMu.Lock()
+	defer relay.persistMu.Unlock()
+	status, header, events, committed := relay.unpersisted()
+	if !committed {
+		return
+	}
+	if relay.record.HTTPStatus == 0 {
+		if err := h.streams.Commit(ctx, relay.record, status, header); err != nil {
+			logger.L().Warn("stream_resume.commit_failed", zap.String("stream_id", relay.record.ID), zap.Error(err))
+			return
+		}
+	}
+	if err := h.streams.Append(ctx, relay.record, events); err != nil {
+		logger.L().Warn("stream_resume.append_failed", zap.String("stream_id", relay.record.ID), zap.Error(err))
+		return
+	}
+	relay.markPersisted(len(events))
+}
+
+// follow streams a live relay held by this instance to the client, starting
+// after lastEventID. A client disconnect only ends the follow; the upstream
+// execution keeps running.
+func (h *StreamResumeHandler) follow(c *gin.Context, relay *streamResumeRelay, lastEventID int64) {
+	ctx := c.Request.Context()
+	for {
+		status, header, sse, committed, done, notify := relay.head()
+		if committed && (sse || done) {
+			writeStreamResumeHead(c, relay.record.ID, status, header)
+			if !sse {
+				_, _ = c.Writer.Write(relay.bodyBytes())
+				return
+			}
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-notify:
+		}
+	}
+	next := lastEventID + 1
+	for {
+		events, done, notify := relay.eventsFrom(next)
+		if len(events) > 0 {
+			for _, event := range events {
+				if err := writeStreamResumeEvent(c.Writer, next, event); err != nil {
+					return
+				}
+				next++
+			}
+			c.Writer.Flush()
+			continue
+		}
+		if done {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-notify:
+		}
+	}
+}
+
+// resume serves a reconnect. Streams still running on this instance are
+// followed from memory; otherwise the buffer is read from the store.
+func (h *StreamResumeHandler) resume(c *gin.Context, owner service.StreamResumeOwner, id string) {
+	lastEventID := int64(-1)
+	if raw := strings.TrimSpace(c.GetHeader("Last-Event-ID")); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < -1 {
+			streamResumeJSONError(c, http.StatusBadRequest, "invalid_request_error", "Last-Event-ID must be an event ID issued by the gateway")
+			return
+		}
+		lastEventID = parsed
+	}
+	ctx := c.Request.Context()
+	record, err := h.streams.Get(ctx, owner, id)
+	if err != nil {
+		streamResumeError(c, err)
+		return
+	}
+	if value, ok := h.live.Load(record.ID); ok {
+		h.follow(c, value.(*streamResumeRelay), lastEventID)
+		return
+	}
+
+	deadline := time.Unix(record.CreatedAt, 0).Add(h.streams.MaxDuration())
+	// 等待上游响应头提交；非 SSE 响应（通常是错误）要等流结束后整体回放。
+	for record.HTTPStatus == 0 || (!streamResumeIsSSE(record.Header) && !record.Done()) {
+		if record.Done() || time.Now().After(deadline) {
+			streamResumeJSONError(c, http.StatusBadGateway, "api_error", "upstream stream ended without a response")
+			return
+		}
+		if !h.sleep(ctx) {
+			return
+		}
+		if record, err = h.streams.Get(ctx, owner, id); err != nil {
+			streamResumeError(c, err)
+			return
+		}
+	}
+	header := make(http.Header, len(record.Header))
+	for key, value := range record.Header {
+		header.Set(key, value)
+	}
+	if !streamResumeIsSSE(record.Header) {
+		events, err := h.streams.Events(ctx, record.ID, -1)
+		if err != nil {
+			streamResumeError(c, err)
+			return
+		}
+		writeStreamResumeHead(c, record.ID, record.HTTPStatus, header)
+		_, _ = c.Writer.Write(bytes.Join(events, nil))
+		return
+	}
+
+	writeStreamResumeHead(c, record.ID, record.HTTPStatus, header)
+	next := lastEventID + 1
+	for {
+		events, err := h.streams.Events(ctx, record.ID, next-1)
+		if err != nil {
+			return
+		}
+		for _, event := range events {
+			if err := writeStreamResumeEvent(c.Writer, next, event); err != nil {
+				return
+			}
+			next++
+		}
+		if len(events) > 0 {
+			c.Writer.Flush()
+		}
+		// record 读取早于事件列表：已结束时本轮读到的就是全部剩余事件。
+		if record.Done() || time.Now().After(deadline) {
+			return
+		}
+		if !h.sleep(ctx) {
+			return
+		}
+		if record, err = h.streams.Get(ctx, owner, id); err != nil {
+			return
+		}
+	}
+}
+
+func (h *StreamResumeHandler) sleep(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(h.pollInterval):
+		return true
+	}
+}
+
+func writeStreamResumeHead(c *gin.Context, id string, status int, header http.Header) {
+	for key, values := range header {
+		if _, skip := streamResumeSkippedHeaders[http.CanonicalHeaderKey(key)]; skip {
+			continue
+		}
+		c.Writer.Header()[key] = append([]string(nil), values...)
+	}
+	c.Header(service.StreamResumeIDHeader, id)
+	if status <= 0 {
+		status = http.StatusOK
+	}
+	c.Status(status)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+}
+
+func writeStreamResumeEvent(w io.Writer, id int64, event []byte) error {
+	_, err := fmt.Fprintf(w, "id: %d\n%s\n\n", id, event)
+	return err
+}
+
+func streamResumeIsSSE(header map[string]string) bool {
+	for key, value := range header {
+		if strings.EqualFold(key, "Content-Type") {
+			return strings.HasPrefix(strings.ToLower(value), "text/event-stream")
+		}
+	}
+	return false
+}
+
+func streamResumeError(c *gin.Context, err error) {
+	status := infraerrors.Code(err)
+	if status <= 0 {
+		status = http.StatusInternalServerError
+	}
+	errType := "api_error"
+	if status == http.StatusNotFound {
+		errType = "invalid_request_error"
+	}
+	streamResumeJSONError(c, status, errType, infraerrors.Message(err))
+}
+
+func streamResumeJSONError(c *gin.Context, status int, errType, message string) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, gin.H{"error": gin.H{"type": errType, "message": message}})
+}
+
+// streamResumeRelay stands in for the client connection of a resumable
+// stream. SSE output is split into numbered event blocks held in memory (and
+// persisted in batches); any other output is kept whole and stored as a single
+// block once the execution ends.
+type streamResumeRelay struct {
+	record *service.StreamResumeRecord
+
+	mu        sync.Mutex
+	header    http.Header
+	committed http.Header
+	status    int
+	sse       bool
+	done      bool
+	partial   bytes.Buffer
+	body      bytes.Buffer
+	events    [][]byte
+	persisted int
+	notify    chan struct{}
+
+	persistMu sync.Mutex
+}
+
+func newStreamResumeRelay(record *service.StreamResumeRecord) *streamResumeRelay {
+	return &streamResumeRelay{record: record, header: make(http.Header), notify: make(chan struct{})}
+}
+
+func (r *streamResumeRelay) Header() http.Header { return r.header }
+
+func (r *streamResumeRelay) WriteHeader(statusCode int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writeHeaderLocked(statusCode)
+}
+
+func (r *streamResumeRelay) writeHeaderLocked(statusCode int) {
+	if r.status != 0 {
+		return
+	}
+	r.status = statusCode
+	// 响应头在执行 goroutine 内快照，跟随方只读快照，避免与 handler 并发访问。
+	r.committed = r.header.Clone()
+	r.sse = strings.HasPrefix(strings.ToLower(r.committed.Get("Content-Type")), "text/event-stream")
+	r.broadcastLocked()
+}
+
+func (r *streamResumeRelay) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writeHeaderLocked(http.StatusOK)
+	if !r.sse {
+		r.body.Write(p)
+		return len(p), nil
+	}
+	r.partial.Write(bytes.ReplaceAll(p, []byte("\r\n"), []byte("\n")))
+	appended := false
+	for {
+		buffered := r.partial.Bytes()
+		idx := bytes.Index(buffered, []byte("\n\n"))
+		if idx < 0 {
+			break
+		}
+		block := streamResumeEventBlock(buffered[:idx])
+		r.partial.Next(idx + 2)
+		if len(block) > 0 {
+			r.events = append(r.events, block)
+			appended = true
+		}
+	}
+	if appended {
+		r.broadcastLocked()
+	}
+	return len(p), nil
+}
+
+// Flush satisfies http.Flusher; followers are woken on every complete event.
+func (r *streamResumeRelay) Flush() {}
+
+func (r *streamResumeRelay) finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status == 0 {
+		r.status = http.StatusBadGateway
+		r.committed = make(http.Header)
+	}
+	if r.sse {
+		if block := streamResumeEventBlock(bytes.TrimSpace(r.partial.Bytes())); len(block) > 0 {
+			r.events = append(r.events, block)
+		}
+		r.partial.Reset()
+	} else if r.body.Len() > 0 {
+		r.events = append(r.events, append([]byte(nil), r.body.Bytes()...))
+	}
+	r.done = true
+	r.broadcastLocked()
+}
+
+func (r *streamResumeRelay) broadcastLocked() {
+	close(r.notify)
+	r.notify = make(chan struct{})
+}
+
+func (r *streamResumeRelay) head() (int, http.Header, bool, bool, bool, <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status, r.committed, r.sse, r.status != 0, r.done, r.notify
+}
+
+func (r *streamResumeRelay) bodyBytes() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]byte(nil), r.body.Bytes()...)
+}
+
+func (r *streamResumeRelay) eventsFrom(from int64) ([][]byte, bool, <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if from < 0 {
+		from = 0
+	}
+	var events [][]byte
+	if from < int64(len(r.events)) {
+		events = append(events, r.events[from:]...)
+	}
+	return events, r.done, r.notify
+}
+
+func (r *streamResumeRelay) unpersisted() (int, map[string]string, [][]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status == 0 {
+		return 0, nil, nil, false
+	}
+	header := make(map[string]string, len(r.committed))
+	for key := range r.committed {
+		if _, skip := streamResumeSkippedHeaders[http.CanonicalHeaderKey(key)]; skip {
+			continue
+		}
+		header[key] = r.committed.Get(key)
+	}
+	events := append([][]byte(nil), r.events[r.persisted:]...)
+	return r.status, header, events, true
+}
+
+func (r *streamResumeRelay) markPersisted(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.persisted += n
+}
+
+// streamResumeEventBlock drops any upstream `id:` lines (the gateway assigns
+// its own IDs) and returns nil for comment-only blocks such as keepalives.
+func streamResumeEventBlock(block []byte) []byte {
+	var lines [][]byte
+	meaningful := false
+	for _, line := range bytes.Split(block, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("id:")) || len(line) == 0 {
+			continue
+		}
+		if line[0] != ':' {
+			meaningful = true
+		}
+		lines = append(lines, line)
+	}
+	if !meaningful {
+		return nil
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type streamResumeMemoryStore struct {
+	mu      sync.Mutex
+	records map[string]service.StreamResumeRecord
+	events  map[string][][]byte
+}
+
+func newStreamResumeMemoryStore() *streamResumeMemoryStore {
+	return &streamResumeMemoryStore{
+		records: make(map[string]service.StreamResumeRecord),
+		events:  make(map[string][][]byte),
+	}
+}
+
+func (s *streamResumeMemoryStore) Save(_ context.Context, record *service.StreamResumeRecord, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[record.ID] = *record
+	return nil
+}
+
+func (s *streamResumeMemoryStore) Get(_ context.Context, id string) (*service.StreamResumeRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[id]
+	if !ok {
+		return nil, service.ErrStreamResumeNotFound
+	}
+	return &record, nil
+}
+
+func (s *streamResumeMemoryStore) AppendEvents(_ context.Context, id string, events [][]byte, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events[id] = append(s.events[id], events...)
+	return nil
+}
+
+func (s *streamResumeMemoryStore) ListEvents(_ context.Context, id string, from int64) ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := s.events[id]
+	if from >= int64(len(events)) {
+		return nil, nil
+	}
+	return append([][]byte(nil), events[from:]...), nil
+}
+
+func (s *streamResumeMemoryStore) latest() (service.StreamResumeRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, record := range s.records {
+		return record, true
+	}
+	return service.StreamResumeRecord{}, false
+}
+
+func newStreamResumeTestRouter(t *testing.T, enabled bool, upstream gin.HandlerFunc) (*gin.Engine, *StreamResumeHandler, *streamResumeMemoryStore) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	store := newStreamResumeMemoryStore()
+	h := NewStreamResumeHandler(service.NewStreamResumeService(store))
+	h.flushInterval = 5 * time.Millisecond
+	h.pollInterval = 5 * time.Millisecond
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		userID := int64(7)
+		if c.GetHeader("X-Test-User") == "other" {
+			userID = 8
+		}
+		c.Set(string(middleware2.ContextKeyAPIKey), &service.APIKey{
+			ID:     9,
+			UserID: userID,
+			Group:  &service.Group{ID: 1, StreamResumeEnabled: enabled},
+		})
+		c.Next()
+	})
+	router.POST("/v1/messages", h.Wrap(upstream))
+	return router, h, store
+}
+
+func writeStreamResumeTestEvent(c *gin.Context, name, data string) {
+	_, _ = fmt.Fprintf(c.Writer, "id: upstream\nevent: %s\ndata: %s\n\n", name, data)
+	c.Writer.Flush()
+}
+
+func TestStreamResumeDisabledGroupPassesThrough(t *testing.T) {
+	var calls atomic.Int32
+	router, _, store := newStreamResumeTestRouter(t, false, func(c *gin.Context) {
+		calls.Add(1)
+		c.JSON(http.StatusTeapot, gin.H{"ok": true})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"stream":true}`)))
+	require.Equal(t, http.StatusTeapot, w.Code)
+	require.Empty(t, w.Header().Get(service.StreamResumeIDHeader))
+	require.EqualValues(t, 1, calls.Load())
+	_, ok := store.latest()
+	require.False(t, ok)
+}
+
+func TestStreamResumeNonStreamingRequestPassesThrough(t *testing.T) {
+	var body string
+	router, _, store := newStreamResumeTestRouter(t, true, func(c *gin.Context) {
+		raw, _ := io.ReadAll(c.Request.Body)
+		body = string(raw)
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"stream":false}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, `{"stream":false}`, body)
+	require.Empty(t, w.Header().Get(service.StreamResumeIDHeader))
+	_, ok := store.latest()
+	require.False(t, ok)
+}
+
+func TestStreamResumeReconnectAfterDisconnectReplaysRemainder(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	router, h, store := newStreamResumeTestRouter(t, true, func(c *gin.Context) {
+		calls.Add(1)
+		c.Header("Content-Type", "text/event-stream")
+		c.Status(http.StatusOK)
+		writeStreamResumeTestEvent(c, "message_start", `{"n":0}`)
+		<-release
+		writeStreamResumeTestEvent(c, "content_block_delta", `{"n":1}`)
+		_, _ = io.WriteString(c.Writer, ": keepalive\n\n")
+		writeStreamResumeTestEvent(c, "message_stop", `{"n":2}`)
+	})
+
+	// 客户端收到首个事件后断开。
+	ctx, cancel := context.WithCancel(context.Background())
+	first := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"stream":true}`)).WithContext(ctx)
+	firstWriter := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		router.ServeHTTP(firstWriter, first)
+	}()
+	var id string
+	require.Eventually(t, func() bool {
+		record, ok := store.latest()
+		if ok && record.HTTPStatus == http.StatusOK {
+			id = record.ID
+			return true
+		}
+		return false
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+	close(release)
+
+	require.Eventually(t, func() bool {
+		record, err := store.Get(context.Background(), id)
+		_, live := h.live.Load(id)
+		return err == nil && record.Done() && !live
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, id, firstWriter.Header().Get(service.StreamResumeIDHeader))
+	require.Contains(t, firstWriter.Body.String(), "id: 0\nevent: message_start\ndata: {\"n\":0}\n\n")
+
+	// 另一用户拿着流 ID 也无法读取。
+	other := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"stream":true}`))
+	other.Header.Set(service.StreamResumeIDHeader, id)
+	other.Header.Set("X-Test-User", "other")
+	otherWriter := httptest.NewRecorder()
+	router.ServeHTTP(otherWriter, other)
+	require.Equal(t, http.StatusNotFound, otherWriter.Code)
+
+	resume := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"stream":true}`))
+	resume.Header.Set(service.StreamResumeIDHeader, id)
+	resume.Header.Set("Last-Event-ID", "0")
+	resumeWriter := httptest.NewRecorder()
+	router.ServeHTTP(resumeWriter, resume)
+	require.Equal(t, http.StatusOK, resumeWriter.Code)
+	require.Equal(t, "text/event-stream", resumeWriter.Header().Get("Content-Type"))
+	require.Equal(t, "id: 1\nevent: content_block_delta\ndata: {\"n\":1}\n\nid: 2\nevent: message_stop\ndata: {\"n\":2}\n\n", resumeWriter.Body.String())
+	require.EqualValues(t, 1, calls.Load())
+}
+
+func TestStreamResumeReconnectFollowsLiveStream(t *testing.T) {
+	release := make(chan struct{})
+	router, h, store := newStreamResumeTestRouter(t, true, func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Status(http.StatusOK)
+		writeStreamResumeTestEvent(c, "message_start", `{"n":0}`)
+		<-release
+		writeStreamResumeTestEvent(c, "message_stop", `{"n":1}`)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"stream":true}`)).WithContext(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		router.ServeHTTP(httptest.NewRecorder(), first)
+	}()
+	var id string
+	require.Eventually(t, func() bool {
+		record, ok := store.latest()
+		id = record.ID
+		return ok
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+	_, live := h.live.Load(id)
+	require.True(t, live)
+
+	resume := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	resume.Header.Set(service.StreamResumeIDHeader, id)
+	resumeWriter := httptest.NewRecorder()
+	resumed := make(chan struct{})
+	go func() {
+		defer close(resumed)
+		router.ServeHTTP(resumeWriter, resume)
+	}()
+	close(release)
+	<-resumed
+	require.Equal(t, "id: 0\nevent: message_start\ndata: {\"n\":0}\n\nid: 1\nevent: message_stop\ndata: {\"n\":1}\n\n", resumeWriter.Body.String())
+}
+
+func TestStreamResumeRejectsInvalidLastEventID(t *testing.T) {
+	router, _, _ := newStreamResumeTestRouter(t, true, func(c *gin.Context) {})
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	req.Header.Set(service.StreamResumeIDHeader, "strm_abc")
+	req.Header.Set("Last-Event-ID", "abc")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
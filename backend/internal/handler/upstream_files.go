@@ -0,0 +1,292 @@
+package handler
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	pkghttputil "github.com/Wei-Shaw/sub2api/internal/pkg/httputil"
+	"github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Files API passthrough (OpenAI /v1/files, Anthropic Files beta):
+//
+//	POST   /v1/files
+//	GET    /v1/files
+//	GET    /v1/files/:file_id
+//	GET    /v1/files/:file_id/content
+//	DELETE /v1/files/:file_id
+//
+// Files live on the upstream account that received the upload, so every
+// operation on an existing file goes to that account, and generation requests
+// that reference a file are pinned to it (see service/upstream_file.go).
+
+// filesErrorWriter writes an error in the caller platform's error format.
+type filesErrorWriter func(c *gin.Context, status int, errType, message string)
+
+func filesErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	default:
+		return "api_error"
+	}
+}
+
+func writeFilesServiceError(c *gin.Context, writeErr filesErrorWriter, err error) {
+	status := infraerrors.Code(err)
+	writeErr(c, status, filesErrorType(status), infraerrors.Message(err))
+}
+
+// upstreamFilesCaller resolves the caller of a files route; it writes the error
+// response and returns false when the request cannot proceed.
+func upstreamFilesCaller(c *gin.Context, svc *service.UpstreamFileService, platform string, writeErr filesErrorWriter) (*service.APIKey, service.UpstreamFileCaller, bool) {
+	apiKey, ok := middleware.GetAPIKeyFromContext(c)
+	if !ok || apiKey == nil {
+		writeErr(c, http.StatusUnauthorized, "authentication_error", "Invalid API key")
+		return nil, service.UpstreamFileCaller{}, false
+	}
+	subject, ok := middleware.GetAuthSubjectFromContext(c)
+	if !ok {
+		writeErr(c, http.StatusInternalServerError, "api_error", "User context not found")
+		return nil, service.UpstreamFileCaller{}, false
+	}
+	if svc == nil || effectiveAPIKeyPlatform(c, apiKey) != platform {
+		writeErr(c, http.StatusNotFound, "not_found_error", "Files API is not supported for this platform")
+		return nil, service.UpstreamFileCaller{}, false
+	}
+	return apiKey, service.UpstreamFileCaller{
+		Platform: platform,
+		GroupID:  apiKey.GroupID,
+		UserID:   subject.UserID,
+		APIKeyID: apiKey.ID,
+	}, true
+}
+
+func serveFilesUpload(c *gin.Context, svc *service.UpstreamFileService, billing *service.BillingCacheService, platform string, writeErr filesErrorWriter) {
+	apiKey, caller, ok := upstreamFilesCaller(c, svc, platform, writeErr)
+	if !ok {
+		return
+	}
+	reqLog := requestLogger(c, "handler.files",
+		zap.String("platform", platform),
+		zap.Int64("user_id", caller.UserID),
+		zap.Int64("api_key_id", apiKey.ID),
+		zap.Any("group_id", apiKey.GroupID),
+	)
+
+	contentType := c.GetHeader("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || !strings.EqualFold(mediaType, "multipart/form-data") {
+		writeErr(c, http.StatusBadRequest, "invalid_request_error", "content-type must be multipart/form-data")
+		return
+	}
+	body, err := pkghttputil.ReadRequestBodyWithPrealloc(c.Request)
+	if err != nil {
+		if maxErr, ok := extractMaxBytesError(err); ok {
+			writeErr(c, http.StatusRequestEntityTooLarge, "invalid_request_error", buildBodyTooLargeMessage(maxErr.Limit))
+			return
+		}
+		writeErr(c, http.StatusBadRequest, "invalid_request_error", "Failed to read request body")
+		return
+	}
+	if len(body) == 0 {
+		writeErr(c, http.StatusBadRequest, "invalid_request_error", "Request body is empty")
+		return
+	}
+
+	subscription, _ := middleware.GetSubscriptionFromContext(c)
+	if err := billing.CheckBillingEligibility(c.Request.Context(), apiKey.User, apiKey, apiKey.Group, subscription, service.QuotaPlatform(c.Request.Context(), apiKey)); err != nil {
+		status, code, message, retryAfter := billingErrorDetails(err)
+		if retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+		writeErr(c, status, code, message)
+		return
+	}
+
+	res, account, err := svc.Upload(c.Request.Context(), caller, body, contentType)
+	if err != nil {
+		reqLog.Warn("files.upload_failed", zap.Error(err))
+		if infraerrors.Reason(err) == service.ErrUpstreamFileUnavailable.Reason {
+			markOpsRoutingCapacityLimitedIfNoAvailable(c, err)
+		}
+		writeFilesServiceError(c, writeErr, err)
+		return
+	}
+	setOpsSelectedAccount(c, account.ID, account.Platform)
+	writeUpstreamResponse(c, res)
+}
+
+func serveFilesList(c *gin.Context, svc *service.UpstreamFileService, platform string, writeErr filesErrorWriter) {
+	_, caller, ok := upstreamFilesCaller(c, svc, platform, writeErr)
+	if !ok {
+		return
+	}
+	body, err := svc.List(c.Request.Context(), caller)
+	if err != nil {
+		writeFilesServiceError(c, writeErr, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/json", body)
+}
+
+func serveFilesRetrieve(c *gin.Context, svc *service.UpstreamFileService, platform string, writeErr filesErrorWriter) {
+	_, caller, ok := upstreamFilesCaller(c, svc, platform, writeErr)
+	if !ok {
+		return
+	}
+	res, account, err := svc.Retrieve(c.Request.Context(), caller, c.Param("file_id"))
+	if err != nil {
+		writeFilesServiceError(c, writeErr, err)
+		return
+	}
+	setOpsSelectedAccount(c, account.ID, account.Platform)
+	writeUpstreamResponse(c, res)
+}
+
+func serveFilesDelete(c *gin.Context, svc *service.UpstreamFileService, platform string, writeErr filesErrorWriter) {
+	_, caller, ok := upstreamFilesCaller(c, svc, platform, writeErr)
+	if !ok {
+		return
+	}
+	res, account, err := svc.Delete(c.Request.Context(), caller, c.Param("file_id"))
+	if err != nil {
+		writeFilesServiceError(c, writeErr, err)
+		return
+	}
+	setOpsSelectedAccount(c, account.ID, account.Platform)
+	writeUpstreamResponse(c, res)
+}
+
+func serveFilesContent(c *gin.Context, svc *service.UpstreamFileService, platform string, writeErr filesErrorWriter) {
+	_, caller, ok := upstreamFilesCaller(c, svc, platform, writeErr)
+	if !ok {
+		return
+	}
+	stream, account, err := svc.Content(c.Request.Context(), caller, c.Param("file_id"))
+	if err != nil {
+		writeFilesServiceError(c, writeErr, err)
+		return
+	}
+	defer func() { _ = stream.Body.Close() }()
+	setOpsSelectedAccount(c, account.ID, account.Platform)
+
+	for k, vv := range stream.Headers {
+		if strings.EqualFold(k, "Transfer-Encoding") || strings.EqualFold(k, "Connection") {
+			continue
+		}
+		for _, v := range vv {
+			c.Writer.Header().Add(k, v)
+		}
+	}
+	c.Status(stream.StatusCode)
+	if _, err := io.Copy(c.Writer, stream.Body); err != nil {
+		requestLogger(c, "handler.files").Info("files.content_copy_interrupted", zap.Int64("account_id", account.ID), zap.Error(err))
+	}
+}
+
+// resolveUpstreamFileAffinity returns the account holding the files referenced by
+// a generation request, or 0 when none are referenced. Requests referencing an
+// unknown file, or one owned by another user, are rejected with 404.
+func resolveUpstreamFileAffinity(c *gin.Context, svc *service.UpstreamFileService, apiKey *service.APIKey, userID int64, platform string, body []byte, writeErr filesErrorWriter) (int64, bool) {
+	if svc == nil || apiKey == nil || effectiveAPIKeyPlatform(c, apiKey) != platform {
+		return 0, true
+	}
+	accountID, err := svc.ResolveReferencedFileAccount(c.Request.Context(), service.UpstreamFileCaller{
+		Platform: platform,
+		GroupID:  apiKey.GroupID,
+		UserID:   userID,
+		APIKeyID: apiKey.ID,
+	}, body)
+	if err != nil {
+		writeFilesServiceError(c, writeErr, err)
+		return 0, false
+	}
+	return accountID, true
+}
+
+// pinUpstreamFileSession binds the request's sticky session to the account that
+// holds its referenced files so the OpenAI scheduler selects that account.
+func (h *OpenAIGatewayHandler) pinUpstreamFileSession(c *gin.Context, apiKey *service.APIKey, userID int64, body []byte, sessionHash string) (int64, string, bool) {
+	fileAccountID, ok := resolveUpstreamFileAffinity(c, h.upstreamFileService, apiKey, userID, service.PlatformOpenAI, body, h.errorResponse)
+	if !ok || fileAccountID <= 0 {
+		return 0, sessionHash, ok
+	}
+	sessionHash = service.UpstreamFileSessionHash(fileAccountID)
+	_ = h.gatewayService.BindStickySession(c.Request.Context(), apiKey.GroupID, sessionHash, fileAccountID)
+	return fileAccountID, sessionHash, true
+}
+
+// rejectIfFileAccountMismatch enforces file affinity after account selection:
+// a file can only be read by the account that holds it, so selecting any other
+// account would fail upstream. It releases the selection and writes a 503.
+func rejectIfFileAccountMismatch(c *gin.Context, fileAccountID int64, selection *service.AccountSelectionResult, writeErr filesErrorWriter) bool {
+	if fileAccountID <= 0 || selection == nil || selection.Account == nil || selection.Account.ID == fileAccountID {
+		return false
+	}
+	if selection.Acquired && selection.ReleaseFunc != nil {
+		selection.ReleaseFunc()
+	}
+	writeErr(c, http.StatusServiceUnavailable, "api_error", service.ErrUpstreamFileAccountGone.Message)
+	return true
+}
+
+// FilesUpload proxies an OpenAI file upload. POST /v1/files
+func (h *OpenAIGatewayHandler) FilesUpload(c *gin.Context) {
+	serveFilesUpload(c, h.upstreamFileService, h.billingCacheService, service.PlatformOpenAI, h.errorResponse)
+}
+
+// FilesList lists the caller's OpenAI files. GET /v1/files
+func (h *OpenAIGatewayHandler) FilesList(c *gin.Context) {
+	serveFilesList(c, h.upstreamFileService, service.PlatformOpenAI, h.errorResponse)
+}
+
+// FilesRetrieve returns OpenAI file metadata. GET /v1/files/:file_id
+func (h *OpenAIGatewayHandler) FilesRetrieve(c *gin.Context) {
+	serveFilesRetrieve(c, h.upstreamFileService, service.PlatformOpenAI, h.errorResponse)
+}
+
+// FilesContent streams OpenAI file content. GET /v1/files/:file_id/content
+func (h *OpenAIGatewayHandler) FilesContent(c *gin.Context) {
+	serveFilesContent(c, h.upstreamFileService, service.PlatformOpenAI, h.errorResponse)
+}
+
+// FilesDelete deletes an OpenAI file. DELETE /v1/files/:file_id
+func (h *OpenAIGatewayHandler) FilesDelete(c *gin.Context) {
+	serveFilesDelete(c, h.upstreamFileService, service.PlatformOpenAI, h.errorResponse)
+}
+
+// FilesUpload proxies an Anthropic Files API upload. POST /v1/files
+func (h *GatewayHandler) FilesUpload(c *gin.Context) {
+	serveFilesUpload(c, h.upstreamFileService, h.billingCacheService, service.PlatformAnthropic, h.errorResponse)
+}
+
+// FilesList lists the caller's Anthropic files. GET /v1/files
+func (h *GatewayHandler) FilesList(c *gin.Context) {
+	serveFilesList(c, h.upstreamFileService, service.PlatformAnthropic, h.errorResponse)
+}
+
+// FilesRetrieve returns Anthropic file metadata. GET /v1/files/:file_id
+func (h *GatewayHandler) FilesRetrieve(c *gin.Context) {
+	serveFilesRetrieve(c, h.upstreamFileService, service.PlatformAnthropic, h.errorResponse)
+}
+
+// FilesContent streams Anthropic file content. GET /v1/files/:file_id/content
+func (h *GatewayHandler) FilesContent(c *gin.Context) {
+	serveFilesContent(c, h.upstreamFileService, service.PlatformAnthropic, h.errorResponse)
+}
+
+// FilesDelete deletes an Anthropic file. DELETE /v1/files/:file_id
+func (h *GatewayHandler) FilesDelete(c *gin.Context) {
+	serveFilesDelete(c, h.upstreamFileService, service.PlatformAnthropic, h.errorResponse)
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserDataHandler 用户自助数据导出与账号注销
+type UserDataHandler struct {
+	userDataService *service.UserDataService
+}
+
+// NewUserDataHandler creates a new UserDataHandler
+func NewUserDataHandler(userDataService *service.UserDataService) *UserDataHandler {
+	return &UserDataHandler{userDataService: userDataService}
+}
+
+// RequestAccountDeletionRequest 注销申请请求体
+type RequestAccountDeletionRequest struct {
+	Reason string `json:"reason"`
+}
+
+func parseDataExportIDParam(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.BadRequest(c, "Invalid export ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// RequestExport 发起数据导出任务，后台生成 ZIP 包
+// POST /api/v1/user/data-exports
+func (h *UserDataHandler) RequestExport(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	executeUserIdempotentJSON(c, "user.data_exports.create", struct{}{}, service.DefaultWriteIdempotencyTTL(), func(ctx context.Context) (any, error) {
+		return h.userDataService.RequestExport(ctx, subject.UserID)
+	})
+}
+
+// ListExports 列出最近的导出任务
+// GET /api/v1/user/data-exports
+func (h *UserDataHandler) ListExports(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	exports, err := h.userDataService.ListExports(c.Request.Context(), subject.UserID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, exports)
+}
+
+// GetExport 获取导出任务状态
+// GET /api/v1/user/data-exports/:id
+func (h *UserDataHandler) GetExport(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	exportID, ok := parseDataExportIDParam(c)
+	if !ok {
+		return
+	}
+	export, err := h.userDataService.GetExport(c.Request.Context(), subject.UserID, exportID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, export)
+}
+
+// DownloadExport 下载已完成的导出包
+// GET /api/v1/user/data-exports/:id/download
+func (h *UserDataHandler) DownloadExport(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	exportID, ok := parseDataExportIDParam(c)
+	if !ok {
+		return
+	}
+	export, archive, err := h.userDataService.DownloadExport(c.Request.Context(), subject.UserID, exportID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Disposition", service.AttachmentContentDisposition(fmt.Sprintf("sub2api-data-export-%d.zip", export.ID)))
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// RequestDeletion 申请注销账号，冷静期结束后执行
+// POST /api/v1/user/account-deletion
+func (h *UserDataHandler) RequestDeletion(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	var req RequestAccountDeletionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, "Invalid request: "+err.Error())
+			return
+		}
+	}
+	executeUserIdempotentJSON(c, "user.account_deletion.request", req, service.DefaultWriteIdempotencyTTL(), func(ctx context.Context) (any, error) {
+		return h.userDataService.RequestDeletion(ctx, subject.UserID, req.Reason)
+	})
+}
+
+// GetDeletion 获取注销申请状态
+// GET /api/v1/user/account-deletion
+func (h *UserDataHandler) GetDeletion(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	req, err := h.userDataService.GetDeletion(c.Request.Context(), subject.UserID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, req)
+}
+
+// CancelDeletion 冷静期内撤销注销申请
+// DELETE /api/v1/user/account-deletion
+func (h *UserDataHandler) CancelDeletion(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	if err := h.userDataService.CancelDeletion(c.Request.Context(), subject.UserID); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	response.Success(c, gin.H{"cancelled": true})
+}
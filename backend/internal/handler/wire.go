@@ -42,6 +42,11 @@ func ProvideAdminHandlers(
 	promptAuditHandler *securityaudit.PromptAdminHandler,
 	complianceHandler *admin.ComplianceHandler,
 	auditLogHandler *admin.AuditLogHandler,
+	adminRBACHandler *admin.AdminRBACHandler,
+	accountCostHandler *admin.AccountCostHandler,
+	proxyPoolHandler *admin.ProxyPoolHandler,
+	proxySubscriptionHandler *admin.ProxySubscriptionHandler,
+	apiKeyLeakHandler *admin.APIKeyLeakHandler,
 	upstreamBillingProbe *service.UpstreamBillingProbeService,
 	ollamaCloudUsage *service.OllamaCloudUsageService,
 ) *AdminHandlers {
@@ -79,6 +84,11 @@ func ProvideAdminHandlers(
 		PromptAudit:           promptAuditHandler,
 		Compliance:            complianceHandler,
 		AuditLog:              auditLogHandler,
+		AdminRBAC:             adminRBACHandler,
+		AccountCost:           accountCostHandler,
+		ProxyPool:             proxyPoolHandler,
+		ProxySubscription:     proxySubscriptionHandler,
+		APIKeyLeak:            apiKeyLeakHandler,
 	}
 }
 
@@ -99,11 +109,13 @@ func ProvideGatewayHandler(
 	cfg *config.Config,
 	settingService *service.SettingService,
 	coordinator *securityaudit.Coordinator,
+	upstreamFileService *service.UpstreamFileService,
 ) *GatewayHandler {
 	h := NewGatewayHandler(gatewayService, openAIGatewayService, geminiCompatService, antigravityGatewayService,
 		userService, concurrencyService, billingCacheService, usageService, apiKeyService, usageRecordWorkerPool,
 		errorPassthroughService, contentModerationService, userMsgQueueService, cfg, settingService)
 	h.securityAuditCoordinator = coordinator
+	h.upstreamFileService = upstreamFileService
 	return h
 }
 
@@ -119,11 +131,13 @@ func ProvideOpenAIGatewayHandler(
 	grokQuotaService *service.GrokQuotaService,
 	cfg *config.Config,
 	coordinator *securityaudit.Coordinator,
+	upstreamFileService *service.UpstreamFileService,
 ) *OpenAIGatewayHandler {
 	h := NewOpenAIGatewayHandler(gatewayService, concurrencyService, billingCacheService, apiKeyService,
 		usageRecordWorkerPool, errorPassthroughService, contentModerationService, opsService, cfg)
 	h.securityAuditCoordinator = coordinator
 	h.grokMediaEligibilityProber = grokQuotaService
+	h.upstreamFileService = upstreamFileService
 	return h
 }
 
@@ -192,33 +206,51 @@ func ProvideHandlers(
 	passkeyHandler *PasskeyHandler,
 	availableChannelHandler *AvailableChannelHandler,
 	asyncImageHandler *AsyncImageHandler,
+	backgroundResponseHandler *BackgroundResponseHandler,
+	streamResumeHandler *StreamResumeHandler,
+	inferenceIdempotencyHandler *InferenceIdempotencyHandler,
+	contextCompactionHandler *ContextCompactionHandler,
 	batchImageHandler *BatchImageHandler,
 	payBridgeHandler *PayBridgeHandler,
+	scimHandler *SCIMHandler,
+	apiKeyLeakHandler *APIKeyLeakHandler,
+	apiKeyRotationHandler *APIKeyRotationHandler,
+	scopedTokenHandler *ScopedTokenHandler,
+	userDataHandler *UserDataHandler,
 	_ *service.IdempotencyCoordinator,
 	_ *service.IdempotencyCleanupService,
 ) *Handlers {
 	return &Handlers{
-		Auth:             authHandler,
-		User:             userHandler,
-		APIKey:           apiKeyHandler,
-		Usage:            usageHandler,
-		Redeem:           redeemHandler,
-		Subscription:     subscriptionHandler,
-		Announcement:     announcementHandler,
-		Admin:            adminHandlers,
-		Gateway:          gatewayHandler,
-		OpenAIGateway:    openaiGatewayHandler,
-		Setting:          settingHandler,
-		Totp:             totpHandler,
-		Referral:         referralHandler,
-		ModelCatalog:     modelCatalogHandler,
-		PublicPricing:    publicPricingHandler,
-		GroupStatus:      groupStatusHandler,
-		Passkey:          passkeyHandler,
-		AvailableChannel: availableChannelHandler,
-		AsyncImage:       asyncImageHandler,
-		BatchImage:       batchImageHandler,
-		PayBridge:        payBridgeHandler,
+		Auth:                 authHandler,
+		User:                 userHandler,
+		APIKey:               apiKeyHandler,
+		Usage:                usageHandler,
+		Redeem:               redeemHandler,
+		Subscription:         subscriptionHandler,
+		Announcement:         announcementHandler,
+		Admin:                adminHandlers,
+		Gateway:              gatewayHandler,
+		OpenAIGateway:        openaiGatewayHandler,
+		Setting:              settingHandler,
+		Totp:                 totpHandler,
+		Referral:             referralHandler,
+		ModelCatalog:         modelCatalogHandler,
+		PublicPricing:        publicPricingHandler,
+		GroupStatus:          groupStatusHandler,
+		Passkey:              passkeyHandler,
+		AvailableChannel:     availableChannelHandler,
+		AsyncImage:           asyncImageHandler,
+		BackgroundResponse:   backgroundResponseHandler,
+		StreamResume:         streamResumeHandler,
+		InferenceIdempotency: inferenceIdempotencyHandler,
+		ContextCompaction:    contextCompactionHandler,
+		BatchImage:           batchImageHandler,
+		PayBridge:            payBridgeHandler,
+		SCIM:                 scimHandler,
+		APIKeyLeak:           apiKeyLeakHandler,
+		APIKeyRotation:       apiKeyRotationHandler,
+		ScopedToken:          scopedTokenHandler,
+		UserData:             userDataHandler,
 	}
 }
 
@@ -243,8 +275,17 @@ var ProviderSet = wire.NewSet(
 	ProvideSettingHandler,
 	NewAvailableChannelHandler,
 	NewAsyncImageHandler,
+	NewBackgroundResponseHandler,
+	NewStreamResumeHandler,
+	NewInferenceIdempotencyHandler,
+	NewContextCompactionHandler,
 	ProvideBatchImageHandler,
 	NewPayBridgeHandler,
+	NewSCIMHandler,
+	NewAPIKeyLeakHandler,
+	NewAPIKeyRotationHandler,
+	NewScopedTokenHandler,
+	NewUserDataHandler,
 
 	// Admin handlers
 	admin.NewDashboardHandler,
@@ -277,6 +318,11 @@ var ProviderSet = wire.NewSet(
 	admin.NewContentModerationHandler,
 	admin.NewComplianceHandler,
 	admin.NewAuditLogHandler,
+	admin.NewAdminRBACHandler,
+	admin.NewAccountCostHandler,
+	admin.NewProxyPoolHandler,
+	admin.NewProxySubscriptionHandler,
+	admin.NewAPIKeyLeakHandler,
 
 	// AdminHandlers and Handlers constructors
 	ProvideAdminHandlers,
@@ -10,21 +10,23 @@ import (
 // TLSFingerprintProfile TLS 指纹配置模板
 // 包含完整的 ClientHello 参数，用于模拟特定客户端的 TLS 握手特征
 type TLSFingerprintProfile struct {
-	ID                  int64     `json:"id"`
-	Name                string    `json:"name"`
-	Description         *string   `json:"description"`
-	EnableGREASE        bool      `json:"enable_grease"`
-	CipherSuites        []uint16  `json:"cipher_suites"`
-	Curves              []uint16  `json:"curves"`
-	PointFormats        []uint16  `json:"point_formats"`
-	SignatureAlgorithms []uint16  `json:"signature_algorithms"`
-	ALPNProtocols       []string  `json:"alpn_protocols"`
-	SupportedVersions   []uint16  `json:"supported_versions"`
-	KeyShareGroups      []uint16  `json:"key_share_groups"`
-	PSKModes            []uint16  `json:"psk_modes"`
-	Extensions          []uint16  `json:"extensions"`
-	CreatedAt           time.Time `json:"created_at"`
-	UpdatedAt           time.Time `json:"updated_at"`
+	ID                  int64    `json:"id"`
+	Name                string   `json:"name"`
+	Description         *string  `json:"description"`
+	EnableGREASE        bool     `json:"enable_grease"`
+	CipherSuites        []uint16 `json:"cipher_suites"`
+	Curves              []uint16 `json:"curves"`
+	PointFormats        []uint16 `json:"point_formats"`
+	SignatureAlgorithms []uint16 `json:"signature_algorithms"`
+	ALPNProtocols       []string `json:"alpn_protocols"`
+	SupportedVersions   []uint16 `json:"supported_versions"`
+	KeyShareGroups      []uint16 `json:"key_share_groups"`
+	PSKModes            []uint16 `json:"psk_modes"`
+	Extensions          []uint16 `json:"extensions"`
+	// HTTP2 HTTP/2 指纹段；为空时沿用默认 HTTP/2 行为，仅在 ALPN 含 h2 时生效
+	HTTP2     *tlsfingerprint.HTTP2Profile `json:"http2"`
+	CreatedAt time.Time                    `json:"created_at"`
+	UpdatedAt time.Time                    `json:"updated_at"`
 }
 
 // Validate 验证模板配置的有效性
@@ -32,6 +34,14 @@ func (p *TLSFingerprintProfile) Validate() error {
 	if p.Name == "" {
 		return &ValidationError{Field: "name", Message: "name is required"}
 	}
+	if p.HTTP2 != nil {
+		if err := p.HTTP2.Validate(); err != nil {
+			return &ValidationError{Field: "http2", Message: err.Error()}
+		}
+		if !p.ToTLSProfile().UsesHTTP2() {
+			return &ValidationError{Field: "http2", Message: "http2 requires \"h2\" in alpn_protocols"}
+		}
+	}
 	return nil
 }
 
@@ -50,5 +60,6 @@ func (p *TLSFingerprintProfile) ToTLSProfile() *tlsfingerprint.Profile {
 		KeyShareGroups:      p.KeyShareGroups,
 		PSKModes:            p.PSKModes,
 		Extensions:          p.Extensions,
+		HTTP2:               p.HTTP2,
 	}
 }
@@ -0,0 +1,242 @@
+package apicompat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ---------------------------------------------------------------------------
+// Non-streaming: AnthropicResponse → GeminiResponse
+// ---------------------------------------------------------------------------
+
+// AnthropicToGeminiResponse converts an Anthropic Messages response into a
+// Gemini generateContent response. Thinking blocks become thought parts only
+// when the client asked for them (thinkingConfig.includeThoughts).
+func AnthropicToGeminiResponse(resp *AnthropicResponse, model string, includeThoughts bool) *GeminiResponse {
+	parts := make([]GeminiPart, 0, len(resp.Content))
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "thinking":
+			if includeThoughts && block.Thinking != "" {
+				parts = append(parts, GeminiPart{Text: block.Thinking, Thought: true})
+			}
+		case "text":
+			if block.Text != "" {
+				parts = append(parts, GeminiPart{Text: block.Text})
+			}
+		case "tool_use":
+			parts = append(parts, GeminiPart{FunctionCall: anthropicToolUseToGeminiCall(block.ID, block.Name, block.Input)})
+		}
+	}
+	if len(parts) == 0 {
+		parts = append(parts, GeminiPart{Text: ""})
+	}
+	if model == "" {
+		model = resp.Model
+	}
+	return &GeminiResponse{
+		Candidates: []GeminiCandidate{{
+			Content:      &GeminiContent{Role: "model", Parts: parts},
+			FinishReason: anthropicStopReasonToGeminiFinishReason(AnthropicStopReasonString(resp.StopReason)),
+		}},
+		UsageMetadata: anthropicUsageToGeminiUsage(resp.Usage),
+		ModelVersion:  model,
+		ResponseID:    resp.ID,
+	}
+}
+
+// anthropicStopReasonToGeminiFinishReason maps Anthropic stop_reason to Gemini finishReason.
+// Gemini reports STOP for function calls as well; there is no tool-specific reason.
+func anthropicStopReasonToGeminiFinishReason(stopReason string) string {
+	switch stopReason {
+	case "max_tokens", "model_context_window_exceeded":
+		return "MAX_TOKENS"
+	case "refusal":
+		return "SAFETY"
+	default:
+		return "STOP"
+	}
+}
+
+// anthropicUsageToGeminiUsage converts usage. Anthropic's input_tokens excludes
+// cache reads and writes while Gemini's promptTokenCount includes them, so they
+// are added back (same as AnthropicToResponsesResponse does for OpenAI).
+func anthropicUsageToGeminiUsage(u AnthropicUsage) *GeminiUsageMetadata {
+	prompt := u.InputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens
+	return &GeminiUsageMetadata{
+		PromptTokenCount:        prompt,
+		CandidatesTokenCount:    u.OutputTokens,
+		TotalTokenCount:         prompt + u.OutputTokens,
+		CachedContentTokenCount: u.CacheReadInputTokens,
+	}
+}
+
+func anthropicToolUseToGeminiCall(id, name string, input json.RawMessage) *GeminiFunctionCall {
+	args := json.RawMessage(`{}`)
+	if trimmed := strings.TrimSpace(string(input)); trimmed != "" && json.Valid([]byte(trimmed)) {
+		args = json.RawMessage(trimmed)
+	}
+	return &GeminiFunctionCall{ID: id, Name: name, Args: args}
+}
+
+// ---------------------------------------------------------------------------
+// Streaming: AnthropicStreamEvent → []GeminiResponse (stateful converter)
+// ---------------------------------------------------------------------------
+
+// AnthropicEventToGeminiState tracks state while converting Anthropic SSE
+// events into streamGenerateContent chunks. Text and thought deltas are
+// emitted as they arrive; a function call is emitted once its input JSON is
+// complete, because Gemini sends functionCall.args as a whole object.
+type AnthropicEventToGeminiState struct {
+	Model           string
+	ResponseID      string
+	IncludeThoughts bool
+
+	// Open tool_use block being accumulated.
+	toolIndex int
+	toolID    string
+	toolName  string
+	toolArgs  strings.Builder
+	toolOpen  bool
+
+	Usage      AnthropicUsage
+	StopReason string
+	Finished   bool
+}
+
+// NewAnthropicEventToGeminiState returns an initialised stream state.
+func NewAnthropicEventToGeminiState(model string, includeThoughts bool) *AnthropicEventToGeminiState {
+	return &AnthropicEventToGeminiState{Model: model, IncludeThoughts: includeThoughts}
+}
+
+// AnthropicEventToGeminiChunks converts a single Anthropic SSE event into zero
+// or more Gemini stream chunks. usageMetadata and finishReason are carried on
+// the final chunk, emitted for message_stop.
+func AnthropicEventToGeminiChunks(evt *AnthropicStreamEvent, state *AnthropicEventToGeminiState) []GeminiResponse {
+	switch evt.Type {
+	case "message_start":
+		if evt.Message != nil {
+			state.ResponseID = evt.Message.ID
+			if state.Model == "" {
+				state.Model = evt.Message.Model
+			}
+			mergeAnthropicStreamUsage(&state.Usage, evt.Message.Usage)
+		}
+	case "content_block_start":
+		if evt.ContentBlock == nil {
+			return nil
+		}
+		switch evt.ContentBlock.Type {
+		case "tool_use":
+			state.toolOpen = true
+			state.toolIndex = derefIndex(evt.Index)
+			state.toolID = evt.ContentBlock.ID
+			state.toolName = evt.ContentBlock.Name
+			state.toolArgs.Reset()
+		case "text":
+			if evt.ContentBlock.Text != "" {
+				return []GeminiResponse{geminiChunk(state, GeminiPart{Text: evt.ContentBlock.Text})}
+			}
+		}
+	case "content_block_delta":
+		if evt.Delta == nil {
+			return nil
+		}
+		switch evt.Delta.Type {
+		case "text_delta":
+			if evt.Delta.Text != "" {
+				return []GeminiResponse{geminiChunk(state, GeminiPart{Text: evt.Delta.Text})}
+			}
+		case "thinking_delta":
+			if state.IncludeThoughts && evt.Delta.Thinking != "" {
+				return []GeminiResponse{geminiChunk(state, GeminiPart{Text: evt.Delta.Thinking, Thought: true})}
+			}
+		case "input_json_delta":
+			if state.toolOpen {
+				state.toolArgs.WriteString(evt.Delta.PartialJSON)
+			}
+		}
+	case "content_block_stop":
+		if state.toolOpen && derefIndex(evt.Index) == state.toolIndex {
+			return []GeminiResponse{closeGeminiToolCall(state)}
+		}
+	case "message_delta":
+		if evt.Usage != nil {
+			mergeAnthropicStreamUsage(&state.Usage, *evt.Usage)
+		}
+		if evt.Delta != nil && evt.Delta.StopReason != "" {
+			state.StopReason = evt.Delta.StopReason
+		}
+	case "message_stop":
+		return FinalizeAnthropicGeminiStream(state)
+	}
+	return nil
+}
+
+// FinalizeAnthropicGeminiStream emits the final chunk (finishReason and
+// usageMetadata). It is idempotent so callers may invoke it after the upstream
+// stream ends even if message_stop was already seen.
+func FinalizeAnthropicGeminiStream(state *AnthropicEventToGeminiState) []GeminiResponse {
+	if state.Finished {
+		return nil
+	}
+	state.Finished = true
+	var chunks []GeminiResponse
+	if state.toolOpen {
+		chunks = append(chunks, closeGeminiToolCall(state))
+	}
+	final := geminiChunk(state, GeminiPart{Text: ""})
+	final.Candidates[0].FinishReason = anthropicStopReasonToGeminiFinishReason(state.StopReason)
+	final.UsageMetadata = anthropicUsageToGeminiUsage(state.Usage)
+	return append(chunks, final)
+}
+
+// GeminiChunkToSSE formats a Gemini stream chunk as an SSE data line (alt=sse).
+func GeminiChunkToSSE(chunk GeminiResponse) (string, error) {
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("data: %s\r\n\r\n", data), nil
+}
+
+func geminiChunk(state *AnthropicEventToGeminiState, part GeminiPart) GeminiResponse {
+	return GeminiResponse{
+		Candidates: []GeminiCandidate{{
+			Content: &GeminiContent{Role: "model", Parts: []GeminiPart{part}},
+		}},
+		ModelVersion: state.Model,
+		ResponseID:   state.ResponseID,
+	}
+}
+
+func closeGeminiToolCall(state *AnthropicEventToGeminiState) GeminiResponse {
+	state.toolOpen = false
+	call := anthropicToolUseToGeminiCall(state.toolID, state.toolName, json.RawMessage(state.toolArgs.String()))
+	return geminiChunk(state, GeminiPart{FunctionCall: call})
+}
+
+// mergeAnthropicStreamUsage keeps the latest non-zero counters; message_start
+// carries input tokens and message_delta carries the cumulative output tokens.
+func mergeAnthropicStreamUsage(dst *AnthropicUsage, src AnthropicUsage) {
+	if src.InputTokens > 0 {
+		dst.InputTokens = src.InputTokens
+	}
+	if src.OutputTokens > 0 {
+		dst.OutputTokens = src.OutputTokens
+	}
+	if src.CacheReadInputTokens > 0 {
+		dst.CacheReadInputTokens = src.CacheReadInputTokens
+	}
+	if src.CacheCreationInputTokens > 0 {
+		dst.CacheCreationInputTokens = src.CacheCreationInputTokens
+	}
+}
+
+func derefIndex(idx *int) int {
+	if idx == nil {
+		return -1
+	}
+	return *idx
+}
@@ -0,0 +1,151 @@
+package apicompat
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Run `go test ./internal/pkg/apicompat -run Gemini -update` to regenerate the
+// *.golden.json files under testdata/gemini after an intentional change.
+var updateGolden = flag.Bool("update", false, "rewrite golden files under testdata")
+
+func geminiFixtures(t *testing.T, prefix string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join("testdata", "gemini", prefix+"*.json"))
+	require.NoError(t, err)
+	var out []string
+	for _, m := range matches {
+		if !strings.HasSuffix(m, ".golden.json") {
+			out = append(out, m)
+		}
+	}
+	require.NotEmpty(t, out, "no %s fixtures", prefix)
+	return out
+}
+
+func assertGolden(t *testing.T, fixture, suffix string, got any) {
+	t.Helper()
+	actual, err := json.MarshalIndent(got, "", "  ")
+	require.NoError(t, err)
+	actual = append(actual, '\n')
+
+	path := strings.TrimSuffix(fixture, ".json") + "." + suffix + ".golden.json"
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(path, actual, 0o644))
+		return
+	}
+	expected, err := os.ReadFile(path)
+	require.NoError(t, err, "missing golden file; run with -update")
+	require.Equal(t, string(bytes.TrimSpace(expected)), string(bytes.TrimSpace(actual)))
+}
+
+func TestGeminiToAnthropicRequest_Golden(t *testing.T) {
+	for _, fixture := range geminiFixtures(t, "request_") {
+		t.Run(filepath.Base(fixture), func(t *testing.T) {
+			var in struct {
+				Model   string        `json:"model"`
+				Stream  bool          `json:"stream"`
+				Request GeminiRequest `json:"request"`
+			}
+			payload, err := os.ReadFile(fixture)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(payload, &in))
+
+			anthropicReq, err := GeminiToAnthropicRequest(&in.Request, in.Model, in.Stream)
+			require.NoError(t, err)
+			assertGolden(t, fixture, "anthropic", anthropicReq)
+
+			// OpenAI groups continue through the Anthropic → Responses bridge.
+			responsesReq, err := AnthropicToResponses(anthropicReq)
+			require.NoError(t, err)
+			assertGolden(t, fixture, "responses", responsesReq)
+		})
+	}
+}
+
+func TestAnthropicToGeminiResponse_Golden(t *testing.T) {
+	for _, fixture := range geminiFixtures(t, "response_") {
+		t.Run(filepath.Base(fixture), func(t *testing.T) {
+			var in struct {
+				Model           string            `json:"model"`
+				IncludeThoughts bool              `json:"include_thoughts"`
+				Response        AnthropicResponse `json:"response"`
+			}
+			payload, err := os.ReadFile(fixture)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(payload, &in))
+
+			assertGolden(t, fixture, "gemini", AnthropicToGeminiResponse(&in.Response, in.Model, in.IncludeThoughts))
+		})
+	}
+}
+
+func TestAnthropicEventToGeminiChunks_Golden(t *testing.T) {
+	for _, fixture := range geminiFixtures(t, "stream_") {
+		t.Run(filepath.Base(fixture), func(t *testing.T) {
+			var in struct {
+				Model           string                 `json:"model"`
+				IncludeThoughts bool                   `json:"include_thoughts"`
+				Events          []AnthropicStreamEvent `json:"events"`
+			}
+			payload, err := os.ReadFile(fixture)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(payload, &in))
+
+			state := NewAnthropicEventToGeminiState(in.Model, in.IncludeThoughts)
+			chunks := make([]GeminiResponse, 0)
+			for i := range in.Events {
+				chunks = append(chunks, AnthropicEventToGeminiChunks(&in.Events[i], state)...)
+			}
+			chunks = append(chunks, FinalizeAnthropicGeminiStream(state)...)
+			assertGolden(t, fixture, "gemini", chunks)
+		})
+	}
+}
+
+func TestGeminiToAnthropicRequest_Rejections(t *testing.T) {
+	_, err := GeminiToAnthropicRequest(&GeminiRequest{}, "m", false)
+	require.Error(t, err)
+
+	_, err = GeminiToAnthropicRequest(&GeminiRequest{Contents: []GeminiContent{{
+		Role:  "user",
+		Parts: []GeminiPart{{FileData: &GeminiFileData{FileURI: "files/abc"}}},
+	}}}, "m", false)
+	require.ErrorContains(t, err, "fileData")
+
+	_, err = GeminiToAnthropicRequest(&GeminiRequest{Contents: []GeminiContent{{
+		Role:  "user",
+		Parts: []GeminiPart{{InlineData: &GeminiInlineData{MimeType: "audio/wav", Data: "AA=="}}},
+	}}}, "m", false)
+	require.ErrorContains(t, err, "audio/wav")
+}
+
+func TestGeminiThinkingBudgetRaisesMaxTokens(t *testing.T) {
+	budget := 16000
+	req := &GeminiRequest{
+		Contents: []GeminiContent{{Role: "user", Parts: []GeminiPart{{Text: "hi"}}}},
+		GenerationConfig: &GeminiGenerationConfig{
+			MaxOutputTokens: 1000,
+			ThinkingConfig:  &GeminiThinkingConfig{ThinkingBudget: &budget},
+		},
+	}
+	out, err := GeminiToAnthropicRequest(req, "m", false)
+	require.NoError(t, err)
+	require.Equal(t, 16000, out.Thinking.BudgetTokens)
+	require.Greater(t, out.MaxTokens, out.Thinking.BudgetTokens)
+	require.Equal(t, "high", out.OutputConfig.Effort)
+
+	disabled := 0
+	req.GenerationConfig.ThinkingConfig = &GeminiThinkingConfig{ThinkingBudget: &disabled}
+	out, err = GeminiToAnthropicRequest(req, "m", false)
+	require.NoError(t, err)
+	require.Nil(t, out.Thinking)
+	require.Nil(t, out.OutputConfig)
+}
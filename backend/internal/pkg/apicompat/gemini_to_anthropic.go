@@ -0,0 +1,384 @@
+package apicompat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ---------------------------------------------------------------------------
+// Request: GeminiRequest → AnthropicRequest
+// ---------------------------------------------------------------------------
+//
+// Gemini-native clients (/v1beta/models/{model}:generateContent) are served by
+// Anthropic and OpenAI groups through the Anthropic Messages representation:
+// Anthropic groups forward it as-is, OpenAI groups continue through the
+// existing Anthropic → Responses bridge (AnthropicToResponses).
+
+// geminiDefaultMaxTokens mirrors the default used by ResponsesToAnthropicRequest
+// when the client sets no output limit.
+const geminiDefaultMaxTokens = 8192
+
+// GeminiToAnthropicRequest converts a Gemini generateContent request into an
+// Anthropic Messages request. Gemini carries the model and the streaming mode
+// in the URL rather than the body, so both are passed in explicitly.
+func GeminiToAnthropicRequest(req *GeminiRequest, model string, stream bool) (*AnthropicRequest, error) {
+	out := &AnthropicRequest{
+		Model:     model,
+		MaxTokens: geminiDefaultMaxTokens,
+		Stream:    stream,
+	}
+
+	if req.SystemInstruction != nil {
+		if text := geminiPartsText(req.SystemInstruction.Parts); text != "" {
+			out.System, _ = json.Marshal(text)
+		}
+	}
+
+	messages, err := convertGeminiContentsToAnthropic(req.Contents)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("contents must contain at least one non-empty turn")
+	}
+	out.Messages = messages
+
+	tools, err := convertGeminiToolsToAnthropic(req.Tools)
+	if err != nil {
+		return nil, err
+	}
+	out.Tools = tools
+
+	if req.ToolConfig != nil && req.ToolConfig.FunctionCallingConfig != nil {
+		out.ToolChoice = convertGeminiFunctionCallingConfigToAnthropic(req.ToolConfig.FunctionCallingConfig)
+	}
+
+	if gc := req.GenerationConfig; gc != nil {
+		if gc.MaxOutputTokens > 0 {
+			out.MaxTokens = gc.MaxOutputTokens
+		}
+		out.Temperature = gc.Temperature
+		out.TopP = gc.TopP
+		if len(gc.StopSequences) > 0 {
+			out.StopSeqs = gc.StopSequences
+		}
+		applyGeminiThinkingConfig(out, gc.ThinkingConfig)
+	}
+
+	return out, nil
+}
+
+// GeminiIncludeThoughts reports whether the client asked for thought parts in
+// the response (generationConfig.thinkingConfig.includeThoughts).
+func GeminiIncludeThoughts(req *GeminiRequest) bool {
+	return req != nil && req.GenerationConfig != nil && req.GenerationConfig.ThinkingConfig != nil &&
+		req.GenerationConfig.ThinkingConfig.IncludeThoughts
+}
+
+// geminiToolCallIDs assigns Anthropic tool_use ids to Gemini function calls.
+// Gemini ids are optional: calls without one get a sequential id, and a
+// functionResponse without an id is paired with the oldest unanswered call
+// of the same name, which is how Gemini itself matches them.
+type geminiToolCallIDs struct {
+	seq     int
+	pending map[string][]string
+}
+
+func (g *geminiToolCallIDs) call(fc *GeminiFunctionCall) string {
+	id := strings.TrimSpace(fc.ID)
+	if id == "" {
+		g.seq++
+		id = fmt.Sprintf("toolu_gemini_%d", g.seq)
+	}
+	g.pending[fc.Name] = append(g.pending[fc.Name], id)
+	return id
+}
+
+func (g *geminiToolCallIDs) response(fr *GeminiFunctionResponse) string {
+	queue := g.pending[fr.Name]
+	if id := strings.TrimSpace(fr.ID); id != "" {
+		for i, pendingID := range queue {
+			if pendingID == id {
+				g.pending[fr.Name] = append(queue[:i:i], queue[i+1:]...)
+				break
+			}
+		}
+		return id
+	}
+	if len(queue) == 0 {
+		// Orphan response; normalizeAnthropicToolPairing drops it.
+		g.seq++
+		return fmt.Sprintf("toolu_gemini_%d", g.seq)
+	}
+	g.pending[fr.Name] = queue[1:]
+	return queue[0]
+}
+
+func convertGeminiContentsToAnthropic(contents []GeminiContent) ([]AnthropicMessage, error) {
+	ids := &geminiToolCallIDs{pending: make(map[string][]string)}
+	messages := make([]AnthropicMessage, 0, len(contents))
+	for i, content := range contents {
+		role, err := geminiRoleToAnthropic(content.Role)
+		if err != nil {
+			return nil, fmt.Errorf("contents[%d]: %w", i, err)
+		}
+		blocks := make([]AnthropicContentBlock, 0, len(content.Parts))
+		for j := range content.Parts {
+			block, ok, err := geminiPartToAnthropicBlock(&content.Parts[j], ids)
+			if err != nil {
+				return nil, fmt.Errorf("contents[%d].parts[%d]: %w", i, j, err)
+			}
+			if ok {
+				blocks = append(blocks, block)
+			}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		messages = append(messages, anthropicMessageFromBlocks(role, blocks))
+	}
+	return mergeConsecutiveMessages(normalizeAnthropicToolPairing(mergeConsecutiveMessages(messages))), nil
+}
+
+func geminiRoleToAnthropic(role string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(role)) {
+	case "", "user", "function", "tool":
+		return "user", nil
+	case "model":
+		return "assistant", nil
+	default:
+		return "", fmt.Errorf("unsupported role %q", role)
+	}
+}
+
+// geminiPartToAnthropicBlock converts one part. Thought parts are dropped:
+// their signatures belong to Gemini and cannot be replayed to another provider.
+func geminiPartToAnthropicBlock(part *GeminiPart, ids *geminiToolCallIDs) (AnthropicContentBlock, bool, error) {
+	switch {
+	case part.Thought:
+		return AnthropicContentBlock{}, false, nil
+	case part.FunctionCall != nil:
+		if strings.TrimSpace(part.FunctionCall.Name) == "" {
+			return AnthropicContentBlock{}, false, fmt.Errorf("functionCall.name is required")
+		}
+		input := json.RawMessage(`{}`)
+		if args := strings.TrimSpace(string(part.FunctionCall.Args)); args != "" && args != "null" {
+			input = part.FunctionCall.Args
+		}
+		return AnthropicContentBlock{
+			Type:  "tool_use",
+			ID:    ids.call(part.FunctionCall),
+			Name:  part.FunctionCall.Name,
+			Input: input,
+		}, true, nil
+	case part.FunctionResponse != nil:
+		text, isError := geminiFunctionResponseText(part.FunctionResponse.Response)
+		content, _ := json.Marshal(text)
+		return AnthropicContentBlock{
+			Type:      "tool_result",
+			ToolUseID: ids.response(part.FunctionResponse),
+			Content:   content,
+			IsError:   isError,
+		}, true, nil
+	case part.InlineData != nil:
+		mimeType := strings.ToLower(strings.TrimSpace(part.InlineData.MimeType))
+		source := &AnthropicImageSource{Type: "base64", MediaType: mimeType, Data: part.InlineData.Data}
+		switch {
+		case strings.HasPrefix(mimeType, "image/"):
+			return AnthropicContentBlock{Type: "image", Source: source}, true, nil
+		case mimeType == "application/pdf":
+			return AnthropicContentBlock{Type: "document", Source: source}, true, nil
+		default:
+			return AnthropicContentBlock{}, false, fmt.Errorf("unsupported inlineData mimeType %q", part.InlineData.MimeType)
+		}
+	case part.FileData != nil:
+		return AnthropicContentBlock{}, false, fmt.Errorf("fileData parts are not supported for this group; send media as inlineData")
+	case part.Text != "":
+		return AnthropicContentBlock{Type: "text", Text: part.Text}, true, nil
+	default:
+		return AnthropicContentBlock{}, false, nil
+	}
+}
+
+// geminiFunctionResponseText flattens functionResponse.response into tool_result
+// text. Gemini's convention is {"output": ...} for success and {"error": ...}
+// for failure; any other object is passed through as JSON.
+func geminiFunctionResponseText(raw json.RawMessage) (string, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return strings.TrimSpace(string(raw)), false
+	}
+	if len(fields) == 1 {
+		for key, value := range fields {
+			switch key {
+			case "output", "result", "content", "error":
+				var s string
+				if err := json.Unmarshal(value, &s); err != nil {
+					s = string(value)
+				}
+				return s, key == "error"
+			}
+		}
+	}
+	return string(raw), false
+}
+
+func geminiPartsText(parts []GeminiPart) string {
+	texts := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p.Thought || p.Text == "" {
+			continue
+		}
+		texts = append(texts, p.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+func convertGeminiToolsToAnthropic(tools []GeminiTool) ([]AnthropicTool, error) {
+	var out []AnthropicTool
+	for _, t := range tools {
+		for _, fd := range t.FunctionDeclarations {
+			schema := fd.ParametersJSONSchema
+			if len(schema) == 0 {
+				schema = lowerGeminiSchemaTypes(fd.Parameters)
+			}
+			out = append(out, AnthropicTool{
+				Name:        fd.Name,
+				Description: fd.Description,
+				InputSchema: normalizeAnthropicInputSchema(schema),
+			})
+		}
+		if len(t.GoogleSearch) > 0 {
+			out = append(out, AnthropicTool{Type: "web_search_20250305", Name: "web_search"})
+		}
+		if len(t.CodeExecution) > 0 {
+			return nil, fmt.Errorf("codeExecution tool is not supported for this group")
+		}
+	}
+	return out, nil
+}
+
+// lowerGeminiSchemaTypes rewrites the OpenAPI-style upper-case "type" values
+// Gemini accepts ("OBJECT", "STRING", ...) into JSON Schema spelling.
+func lowerGeminiSchemaTypes(schema json.RawMessage) json.RawMessage {
+	if len(schema) == 0 {
+		return schema
+	}
+	var v any
+	if err := json.Unmarshal(schema, &v); err != nil {
+		return schema
+	}
+	out, err := json.Marshal(lowerGeminiSchemaValue(v))
+	if err != nil {
+		return schema
+	}
+	return out
+}
+
+func lowerGeminiSchemaValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for key, value := range t {
+			if key == "type" {
+				if s, ok := value.(string); ok {
+					t[key] = strings.ToLower(s)
+					continue
+				}
+			}
+			t[key] = lowerGeminiSchemaValue(value)
+		}
+		return t
+	case []any:
+		for i := range t {
+			t[i] = lowerGeminiSchemaValue(t[i])
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// convertGeminiFunctionCallingConfigToAnthropic maps functionCallingConfig.mode:
+//
+//	AUTO / VALIDATED        → {"type":"auto"}
+//	ANY                     → {"type":"any"}
+//	ANY + one allowed name  → {"type":"tool","name":"X"}
+//	NONE                    → {"type":"none"}
+func convertGeminiFunctionCallingConfigToAnthropic(cfg *GeminiFunctionCallingConfig) json.RawMessage {
+	var choice map[string]string
+	switch strings.ToUpper(strings.TrimSpace(cfg.Mode)) {
+	case "AUTO", "VALIDATED":
+		choice = map[string]string{"type": "auto"}
+	case "ANY":
+		if len(cfg.AllowedFunctionNames) == 1 {
+			choice = map[string]string{"type": "tool", "name": cfg.AllowedFunctionNames[0]}
+		} else {
+			choice = map[string]string{"type": "any"}
+		}
+	case "NONE":
+		choice = map[string]string{"type": "none"}
+	default:
+		return nil
+	}
+	out, _ := json.Marshal(choice)
+	return out
+}
+
+// applyGeminiThinkingConfig maps thinkingConfig onto Anthropic thinking and
+// output_config.effort, following the same effort → budget table as the
+// Responses bridge (defaultThinkingBudget):
+//
+//	thinkingLevel low/minimal  → effort low, no extended thinking
+//	thinkingLevel medium/high  → effort + enabled thinking with the default budget
+//	thinkingBudget > 0         → enabled thinking with that budget (min 1024)
+//	thinkingBudget -1          → dynamic: medium effort
+//	thinkingBudget 0           → thinking disabled
+//
+// Gemini's maxOutputTokens already includes thought tokens, like Anthropic's
+// max_tokens, but Anthropic additionally requires max_tokens > budget_tokens.
+func applyGeminiThinkingConfig(out *AnthropicRequest, tc *GeminiThinkingConfig) {
+	if tc == nil {
+		return
+	}
+	effort := ""
+	budget := 0
+	if level := strings.ToLower(strings.TrimSpace(tc.ThinkingLevel)); level != "" {
+		if level == "minimal" {
+			level = "low"
+		}
+		effort = level
+		if effort != "low" {
+			budget = defaultThinkingBudget(effort)
+		}
+	} else if tc.ThinkingBudget != nil {
+		switch b := *tc.ThinkingBudget; {
+		case b < 0:
+			effort = "medium"
+			budget = defaultThinkingBudget(effort)
+		case b > 0:
+			budget = max(b, 1024)
+			effort = geminiThinkingBudgetEffort(budget)
+		}
+	}
+	if effort != "" {
+		out.OutputConfig = &AnthropicOutputConfig{Effort: effort}
+	}
+	if budget > 0 {
+		out.Thinking = &AnthropicThinking{Type: "enabled", BudgetTokens: budget}
+		if out.MaxTokens <= budget {
+			out.MaxTokens = budget + geminiDefaultMaxTokens
+		}
+	}
+}
+
+func geminiThinkingBudgetEffort(budget int) string {
+	switch {
+	case budget <= defaultThinkingBudget("low"):
+		return "low"
+	case budget <= defaultThinkingBudget("medium"):
+		return "medium"
+	default:
+		return "high"
+	}
+}
@@ -0,0 +1,500 @@
+// Package audioduration 从常见音频容器的头部/索引信息读取播放时长，
+// 供语音转写按时长计费使用。
+//
+// 只解析容器元数据，不解码音频：WAV、MP3（Xing/Info/VBRI 帧头或 CBR 估算）、
+// FLAC、Ogg（Opus/Vorbis）、MP4/M4A 与 WebM/Matroska。无法识别或缺少时长
+// 信息时返回 ErrUnknownDuration，由调用方回退到其它估算口径。
+package audioduration
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+// 容器格式名。
+const (
+	FormatWAV  = "wav"
+	FormatMP3  = "mp3"
+	FormatFLAC = "flac"
+	FormatOgg  = "ogg"
+	FormatMP4  = "mp4"
+	FormatWebM = "webm"
+)
+
+// ErrUnknownDuration 无法从内容中确定时长（格式不支持或元数据缺失）。
+var ErrUnknownDuration = errors.New("audioduration: unable to determine duration")
+
+// Probe 识别音频容器并返回时长与格式名。
+func Probe(data []byte) (time.Duration, string, error) {
+	type prober struct {
+		format string
+		match  func([]byte) bool
+		probe  func([]byte) (float64, bool)
+	}
+	probers := []prober{
+		{FormatWAV, isWAV, probeWAV},
+		{FormatFLAC, func(b []byte) bool { return bytes.HasPrefix(b, []byte("fLaC")) }, probeFLAC},
+		{FormatOgg, func(b []byte) bool { return bytes.HasPrefix(b, []byte("OggS")) }, probeOgg},
+		{FormatWebM, func(b []byte) bool { return bytes.HasPrefix(b, []byte{0x1A, 0x45, 0xDF, 0xA3}) }, probeWebM},
+		{FormatMP4, isMP4, probeMP4},
+		{FormatMP3, isMP3, probeMP3},
+	}
+	for _, p := range probers {
+		if !p.match(data) {
+			continue
+		}
+		seconds, ok := p.probe(data)
+		if !ok || seconds <= 0 || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+			return 0, p.format, ErrUnknownDuration
+		}
+		return time.Duration(seconds * float64(time.Second)), p.format, nil
+	}
+	return 0, "", ErrUnknownDuration
+}
+
+// ---- WAV ----
+
+func isWAV(b []byte) bool {
+	return len(b) >= 12 && string(b[0:4]) == "RIFF" && string(b[8:12]) == "WAVE"
+}
+
+func probeWAV(b []byte) (float64, bool) {
+	var byteRate uint32
+	offset := 12
+	for offset+8 <= len(b) {
+		id := string(b[offset : offset+4])
+		size := binary.LittleEndian.Uint32(b[offset+4 : offset+8])
+		body := offset + 8
+		switch id {
+		case "fmt ":
+			if body+12 > len(b) {
+				return 0, false
+			}
+			byteRate = binary.LittleEndian.Uint32(b[body+8 : body+12])
+		case "data":
+			if byteRate == 0 {
+				return 0, false
+			}
+			// 流式写入的 WAV 常把 data 长度置为 0 或 0xFFFFFFFF，按实际剩余字节计算。
+			available := uint64(len(b) - body)
+			dataSize := uint64(size)
+			if dataSize == 0 || dataSize > available {
+				dataSize = available
+			}
+			return float64(dataSize) / float64(byteRate), true
+		}
+		next := uint64(body) + uint64(size) + uint64(size&1)
+		if next > uint64(len(b)) {
+			return 0, false
+		}
+		offset = int(next)
+	}
+	return 0, false
+}
+
+// ---- FLAC ----
+
+func probeFLAC(b []byte) (float64, bool) {
+	// fLaC + 4 字节块头，第一个元数据块必须是 STREAMINFO（34 字节）。
+	if len(b) < 8+34 || b[4]&0x7F != 0 {
+		return 0, false
+	}
+	info := b[8 : 8+34]
+	sampleRate := uint32(info[10])<<12 | uint32(info[11])<<4 | uint32(info[12])>>4
+	totalSamples := uint64(info[13]&0x0F)<<32 | uint64(binary.BigEndian.Uint32(info[14:18]))
+	if sampleRate == 0 || totalSamples == 0 {
+		return 0, false
+	}
+	return float64(totalSamples) / float64(sampleRate), true
+}
+
+// ---- Ogg (Opus / Vorbis) ----
+
+func probeOgg(b []byte) (float64, bool) {
+	payload, ok := oggFirstPacket(b)
+	if !ok {
+		return 0, false
+	}
+	var sampleRate float64
+	var preSkip uint64
+	switch {
+	case bytes.HasPrefix(payload, []byte("OpusHead")) && len(payload) >= 12:
+		// Opus 的 granule position 固定以 48kHz 计数，需扣除 pre-skip。
+		sampleRate = 48000
+		preSkip = uint64(binary.LittleEndian.Uint16(payload[10:12]))
+	case bytes.HasPrefix(payload, []byte("\x01vorbis")) && len(payload) >= 16:
+		sampleRate = float64(binary.LittleEndian.Uint32(payload[12:16]))
+	default:
+		return 0, false
+	}
+	if sampleRate <= 0 {
+		return 0, false
+	}
+	last := bytes.LastIndex(b, []byte("OggS"))
+	if last < 0 || last+14 > len(b) {
+		return 0, false
+	}
+	granule := binary.LittleEndian.Uint64(b[last+6 : last+14])
+	if granule == math.MaxUint64 || granule <= preSkip {
+		return 0, false
+	}
+	return float64(granule-preSkip) / sampleRate, true
+}
+
+func oggFirstPacket(b []byte) ([]byte, bool) {
+	if len(b) < 27 {
+		return nil, false
+	}
+	segments := int(b[26])
+	headerLen := 27 + segments
+	if len(b) < headerLen {
+		return nil, false
+	}
+	size := 0
+	for _, lace := range b[27:headerLen] {
+		size += int(lace)
+		if lace < 255 {
+			break
+		}
+	}
+	if headerLen+size > len(b) {
+		return nil, false
+	}
+	return b[headerLen : headerLen+size], true
+}
+
+// ---- MP4 / M4A ----
+
+func isMP4(b []byte) bool {
+	return len(b) >= 12 && string(b[4:8]) == "ftyp"
+}
+
+func probeMP4(b []byte) (float64, bool) {
+	moov, ok := mp4FindBox(b, "moov")
+	if !ok {
+		return 0, false
+	}
+	mvhd, ok := mp4FindBox(moov, "mvhd")
+	if !ok || len(mvhd) < 4 {
+		return 0, false
+	}
+	var timescale uint32
+	var duration uint64
+	switch mvhd[0] {
+	case 0:
+		if len(mvhd) < 20 {
+			return 0, false
+		}
+		timescale = binary.BigEndian.Uint32(mvhd[12:16])
+		duration = uint64(binary.BigEndian.Uint32(mvhd[16:20]))
+	case 1:
+		if len(mvhd) < 32 {
+			return 0, false
+		}
+		timescale = binary.BigEndian.Uint32(mvhd[20:24])
+		duration = binary.BigEndian.Uint64(mvhd[24:32])
+	default:
+		return 0, false
+	}
+	if timescale == 0 || duration == 0 || duration == math.MaxUint32 || duration == math.MaxUint64 {
+		return 0, false
+	}
+	return float64(duration) / float64(timescale), true
+}
+
+// mp4FindBox 在同一层级中查找指定类型的 box，返回其内容（不含头部）。
+func mp4FindBox(b []byte, boxType string) ([]byte, bool) {
+	offset := 0
+	for offset+8 <= len(b) {
+		size := uint64(binary.BigEndian.Uint32(b[offset : offset+4]))
+		typ := string(b[offset+4 : offset+8])
+		header := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(b) - offset)
+		case 1:
+			if offset+16 > len(b) {
+				return nil, false
+			}
+			size = binary.BigEndian.Uint64(b[offset+8 : offset+16])
+			header = 16
+		}
+		if size < header || uint64(offset)+size > uint64(len(b)) {
+			return nil, false
+		}
+		if typ == boxType {
+			return b[uint64(offset)+header : uint64(offset)+size], true
+		}
+		offset += int(size)
+	}
+	return nil, false
+}
+
+// ---- WebM / Matroska ----
+
+const (
+	ebmlIDHeader        = 0x1A45DFA3
+	ebmlIDSegment       = 0x18538067
+	ebmlIDInfo          = 0x1549A966
+	ebmlIDTimecodeScale = 0x2AD7B1
+	ebmlIDDuration      = 0x4489
+)
+
+func probeWebM(b []byte) (float64, bool) {
+	offset := 0
+	for offset < len(b) {
+		id, size, header, ok := ebmlElement(b[offset:])
+		if !ok {
+			return 0, false
+		}
+		body := offset + header
+		switch id {
+		case ebmlIDHeader:
+			if size < 0 || body+size > len(b) {
+				return 0, false
+			}
+			offset = body + size
+		case ebmlIDSegment:
+			end := len(b)
+			if size >= 0 && body+size < end {
+				end = body + size
+			}
+			return probeWebMSegment(b[body:end])
+		default:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+func probeWebMSegment(b []byte) (float64, bool) {
+	offset := 0
+	for offset < len(b) {
+		id, size, header, ok := ebmlElement(b[offset:])
+		if !ok || size < 0 {
+			// 未知长度的 Cluster 等元素之后不会再出现 Info。
+			return 0, false
+		}
+		body := offset + header
+		if body+size > len(b) {
+			return 0, false
+		}
+		if id == ebmlIDInfo {
+			return probeWebMInfo(b[body : body+size])
+		}
+		offset = body + size
+	}
+	return 0, false
+}
+
+func probeWebMInfo(b []byte) (float64, bool) {
+	scale := uint64(1_000_000)
+	duration := 0.0
+	offset := 0
+	for offset < len(b) {
+		id, size, header, ok := ebmlElement(b[offset:])
+		if !ok || size < 0 {
+			return 0, false
+		}
+		body := offset + header
+		if body+size > len(b) {
+			return 0, false
+		}
+		value := b[body : body+size]
+		switch id {
+		case ebmlIDTimecodeScale:
+			var v uint64
+			for _, c := range value {
+				v = v<<8 | uint64(c)
+			}
+			if v > 0 {
+				scale = v
+			}
+		case ebmlIDDuration:
+			switch len(value) {
+			case 4:
+				duration = float64(math.Float32frombits(binary.BigEndian.Uint32(value)))
+			case 8:
+				duration = math.Float64frombits(binary.BigEndian.Uint64(value))
+			}
+		}
+		offset = body + size
+	}
+	if duration <= 0 {
+		// 浏览器 MediaRecorder 产出的 WebM 通常不写 Duration。
+		return 0, false
+	}
+	return duration * float64(scale) / float64(time.Second), true
+}
+
+// ebmlElement 读取元素 ID 与长度；size 为 -1 表示未知长度。
+func ebmlElement(b []byte) (id uint64, size int, header int, ok bool) {
+	id, idLen, ok := ebmlVint(b, false)
+	if !ok {
+		return 0, 0, 0, false
+	}
+	rawSize, sizeLen, ok := ebmlVint(b[idLen:], true)
+	if !ok {
+		return 0, 0, 0, false
+	}
+	header = idLen + sizeLen
+	if rawSize == (uint64(1)<<(7*uint(sizeLen)))-1 {
+		return id, -1, header, true
+	}
+	if rawSize > math.MaxInt32 {
+		return 0, 0, 0, false
+	}
+	return id, int(rawSize), header, true
+}
+
+// ebmlVint 解析变长整数；stripMarker 为 false 时保留长度标记位（用于元素 ID）。
+func ebmlVint(b []byte, stripMarker bool) (uint64, int, bool) {
+	if len(b) == 0 || b[0] == 0 {
+		return 0, 0, false
+	}
+	length := 1
+	for mask := byte(0x80); b[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > 8 || len(b) < length {
+		return 0, 0, false
+	}
+	value := uint64(b[0])
+	if stripMarker {
+		value &= uint64(0xFF >> uint(length))
+	}
+	for _, c := range b[1:length] {
+		value = value<<8 | uint64(c)
+	}
+	return value, length, true
+}
+
+// ---- MP3 ----
+
+var (
+	mp3BitratesV1 = [3][16]int{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0}, // Layer I
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},    // Layer II
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},     // Layer III
+	}
+	mp3BitratesV2 = [3][16]int{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0}, // Layer I
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},      // Layer II
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},      // Layer III
+	}
+	mp3SampleRates = [3]int{44100, 48000, 32000}
+)
+
+type mp3Frame struct {
+	mpeg1      bool
+	layer      int // 1..3
+	bitrate    int // kbps
+	sampleRate int
+	mono       bool
+}
+
+func (f mp3Frame) samplesPerFrame() int {
+	switch {
+	case f.layer == 1:
+		return 384
+	case f.layer == 3 && !f.mpeg1:
+		return 576
+	default:
+		return 1152
+	}
+}
+
+func isMP3(b []byte) bool {
+	if bytes.HasPrefix(b, []byte("ID3")) {
+		return true
+	}
+	_, ok := parseMP3Frame(b)
+	return ok
+}
+
+func parseMP3Frame(b []byte) (mp3Frame, bool) {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+	version := (b[1] >> 3) & 0x03 // 0=2.5, 2=2, 3=1
+	layerBits := (b[1] >> 1) & 0x03
+	bitrateIdx := b[2] >> 4
+	rateIdx := (b[2] >> 2) & 0x03
+	if version == 1 || layerBits == 0 || bitrateIdx == 0 || bitrateIdx == 15 || rateIdx == 3 {
+		return mp3Frame{}, false
+	}
+	f := mp3Frame{mpeg1: version == 3, layer: 4 - int(layerBits), mono: b[3]>>6 == 3}
+	if f.mpeg1 {
+		f.bitrate = mp3BitratesV1[f.layer-1][bitrateIdx]
+	} else {
+		f.bitrate = mp3BitratesV2[f.layer-1][bitrateIdx]
+	}
+	f.sampleRate = mp3SampleRates[rateIdx]
+	switch version {
+	case 2:
+		f.sampleRate /= 2
+	case 0:
+		f.sampleRate /= 4
+	}
+	return f, true
+}
+
+func probeMP3(b []byte) (float64, bool) {
+	start := 0
+	if bytes.HasPrefix(b, []byte("ID3")) && len(b) >= 10 {
+		size := int(b[6]&0x7F)<<21 | int(b[7]&0x7F)<<14 | int(b[8]&0x7F)<<7 | int(b[9]&0x7F)
+		start = 10 + size
+		if b[5]&0x10 != 0 {
+			start += 10
+		}
+	}
+	// ID3 之后可能有填充字节，向后扫描第一个合法帧头。
+	var frame mp3Frame
+	found := false
+	for limit := min(len(b)-4, start+64*1024); start <= limit; start++ {
+		if f, ok := parseMP3Frame(b[start:]); ok {
+			frame, found = f, true
+			break
+		}
+	}
+	if !found || frame.sampleRate == 0 {
+		return 0, false
+	}
+
+	// VBR 文件首帧携带 Xing/Info 或 VBRI 头，直接给出总帧数。
+	sideInfo := 17
+	switch {
+	case frame.mpeg1 && !frame.mono:
+		sideInfo = 32
+	case !frame.mpeg1 && frame.mono:
+		sideInfo = 9
+	}
+	if xing := start + 4 + sideInfo; xing+12 <= len(b) {
+		tag := string(b[xing : xing+4])
+		if (tag == "Xing" || tag == "Info") && b[xing+7]&0x01 != 0 {
+			frames := binary.BigEndian.Uint32(b[xing+8 : xing+12])
+			if frames > 0 {
+				return float64(frames) * float64(frame.samplesPerFrame()) / float64(frame.sampleRate), true
+			}
+		}
+	}
+	if vbri := start + 4 + 32; vbri+18 <= len(b) && string(b[vbri:vbri+4]) == "VBRI" {
+		frames := binary.BigEndian.Uint32(b[vbri+14 : vbri+18])
+		if frames > 0 {
+			return float64(frames) * float64(frame.samplesPerFrame()) / float64(frame.sampleRate), true
+		}
+	}
+
+	// CBR：音频字节数 / 码率；末尾 ID3v1 标签不计入。
+	audioBytes := len(b) - start
+	if len(b) >= 128 && string(b[len(b)-128:len(b)-125]) == "TAG" {
+		audioBytes -= 128
+	}
+	if frame.bitrate <= 0 || audioBytes <= 0 {
+		return 0, false
+	}
+	return float64(audioBytes) * 8 / float64(frame.bitrate*1000), true
+}
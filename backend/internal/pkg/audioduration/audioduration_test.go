@@ -0,0 +1,162 @@
+package audioduration
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func buildWAV(seconds int, dataSizeOverride *uint32) []byte {
+	const sampleRate, channels, bits = 16000, 1, 16
+	byteRate := sampleRate * channels * bits / 8
+	data := make([]byte, byteRate*seconds)
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(36+len(data)))
+	buf.WriteString("WAVEfmt ")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(16))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(1))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(channels))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(byteRate))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(channels*bits/8))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(bits))
+	buf.WriteString("data")
+	size := uint32(len(data))
+	if dataSizeOverride != nil {
+		size = *dataSizeOverride
+	}
+	_ = binary.Write(&buf, binary.LittleEndian, size)
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func TestProbeWAV(t *testing.T) {
+	d, format, err := Probe(buildWAV(3, nil))
+	require.NoError(t, err)
+	require.Equal(t, FormatWAV, format)
+	require.Equal(t, 3*time.Second, d)
+
+	streaming := uint32(0xFFFFFFFF)
+	d, _, err = Probe(buildWAV(2, &streaming))
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Second, d)
+}
+
+func TestProbeFLAC(t *testing.T) {
+	info := make([]byte, 34)
+	// 44100Hz, 441000 个采样 = 10s
+	rate, total := uint32(44100), uint32(441000)
+	info[10] = byte(rate >> 12)
+	info[11] = byte(rate >> 4)
+	info[12] = byte(rate<<4) | 0x02
+	binary.BigEndian.PutUint32(info[14:18], total)
+	data := append([]byte("fLaC\x80\x00\x00\x22"), info...)
+
+	d, format, err := Probe(data)
+	require.NoError(t, err)
+	require.Equal(t, FormatFLAC, format)
+	require.Equal(t, 10*time.Second, d)
+}
+
+func oggPage(granule uint64, payload []byte) []byte {
+	page := make([]byte, 27)
+	copy(page, "OggS")
+	binary.LittleEndian.PutUint64(page[6:14], granule)
+	page[26] = 1
+	page = append(page, byte(len(payload)))
+	return append(page, payload...)
+}
+
+func TestProbeOggOpusAndVorbis(t *testing.T) {
+	head := []byte("OpusHead\x01\x01")
+	head = binary.LittleEndian.AppendUint16(head, 312)
+	head = append(head, make([]byte, 7)...)
+	data := append(oggPage(0, head), oggPage(48000*5+312, []byte{0})...)
+
+	d, format, err := Probe(data)
+	require.NoError(t, err)
+	require.Equal(t, FormatOgg, format)
+	require.Equal(t, 5*time.Second, d)
+
+	vorbis := []byte("\x01vorbis\x00\x00\x00\x00\x02")
+	vorbis = binary.LittleEndian.AppendUint32(vorbis, 22050)
+	vorbis = append(vorbis, make([]byte, 14)...)
+	data = append(oggPage(0, vorbis), oggPage(22050*4, []byte{0})...)
+	d, _, err = Probe(data)
+	require.NoError(t, err)
+	require.Equal(t, 4*time.Second, d)
+}
+
+func mp4Box(typ string, body []byte) []byte {
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	box = append(box, typ...)
+	return append(box, body...)
+}
+
+func TestProbeMP4(t *testing.T) {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:16], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:20], 7500)
+	data := append(mp4Box("ftyp", []byte("M4A \x00\x00\x00\x00")), mp4Box("mdat", make([]byte, 32))...)
+	data = append(data, mp4Box("moov", mp4Box("mvhd", mvhd))...)
+
+	d, format, err := Probe(data)
+	require.NoError(t, err)
+	require.Equal(t, FormatMP4, format)
+	require.Equal(t, 7500*time.Millisecond, d)
+}
+
+func TestProbeWebM(t *testing.T) {
+	duration := binary.BigEndian.AppendUint64(nil, math.Float64bits(2500))
+	info := append([]byte{0x2A, 0xD7, 0xB1, 0x83, 0x0F, 0x42, 0x40}, 0x44, 0x89, 0x88)
+	info = append(info, duration...)
+	segment := append([]byte{0x15, 0x49, 0xA9, 0x66, 0x80 | byte(len(info))}, info...)
+	data := []byte{0x1A, 0x45, 0xDF, 0xA3, 0x80}
+	// Segment 使用未知长度（MediaRecorder 常见写法）。
+	data = append(data, 0x18, 0x53, 0x80, 0x67, 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+	data = append(data, segment...)
+
+	d, format, err := Probe(data)
+	require.NoError(t, err)
+	require.Equal(t, FormatWebM, format)
+	require.Equal(t, 2500*time.Millisecond, d)
+
+	// 缺少 Duration 时无法计费时长。
+	noDuration := []byte{0x1A, 0x45, 0xDF, 0xA3, 0x80, 0x18, 0x53, 0x80, 0x67, 0x85, 0x15, 0x49, 0xA9, 0x66, 0x80}
+	_, format, err = Probe(noDuration)
+	require.ErrorIs(t, err, ErrUnknownDuration)
+	require.Equal(t, FormatWebM, format)
+}
+
+func TestProbeMP3(t *testing.T) {
+	// MPEG1 Layer III 128kbps 44.1kHz stereo CBR，前置 ID3v2 标签。
+	header := []byte{0xFF, 0xFB, 0x90, 0x00}
+	cbr := append([]byte("ID3\x03\x00\x00\x00\x00\x00\x0A"), make([]byte, 10)...)
+	cbr = append(cbr, header...)
+	cbr = append(cbr, make([]byte, 16000*3-4)...)
+	d, format, err := Probe(cbr)
+	require.NoError(t, err)
+	require.Equal(t, FormatMP3, format)
+	require.Equal(t, 3*time.Second, d)
+
+	// Xing 头：1000 帧 × 1152 / 44100。
+	vbr := append([]byte{}, header...)
+	vbr = append(vbr, make([]byte, 32)...)
+	vbr = append(vbr, "Xing\x00\x00\x00\x01"...)
+	vbr = binary.BigEndian.AppendUint32(vbr, 1000)
+	vbr = append(vbr, make([]byte, 400)...)
+	d, _, err = Probe(vbr)
+	require.NoError(t, err)
+	require.InDelta(t, 1000*1152/44100.0, d.Seconds(), 1e-6)
+}
+
+func TestProbeUnknown(t *testing.T) {
+	_, format, err := Probe([]byte("definitely not audio"))
+	require.ErrorIs(t, err, ErrUnknownDuration)
+	require.Empty(t, format)
+}
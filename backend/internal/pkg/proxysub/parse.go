@@ -0,0 +1,332 @@
+// Package proxysub 解析代理供应商下发的订阅内容（Clash YAML、sing-box / V2Ray JSON、URI 文本）。
+//
+// 只提取本系统可直接使用的协议（http、https、socks5、socks5h），
+// vmess/vless/trojan/shadowsocks 等需要专用客户端的节点计入 Skipped 后忽略。
+package proxysub
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 订阅格式。
+const (
+	FormatAuto    = "auto"
+	FormatClash   = "clash"
+	FormatSingBox = "singbox"
+	FormatV2Ray   = "v2ray"
+	FormatText    = "text"
+)
+
+// ErrEmptyFeed 订阅内容为空。
+var ErrEmptyFeed = errors.New("subscription content is empty")
+
+// Entry 订阅中的一个可用代理节点。
+type Entry struct {
+	Name     string
+	Protocol string
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+// Key 返回去重键（与 proxies 表 host/port/username/password 唯一性一致，不含协议）。
+func (e Entry) Key() string {
+	return fmt.Sprintf("%s|%d|%s|%s", strings.ToLower(e.Host), e.Port, e.Username, e.Password)
+}
+
+// Feed 解析结果。
+type Feed struct {
+	Format  string
+	Entries []Entry
+	// Skipped 为协议不受支持或字段不完整而被忽略的节点数。
+	Skipped int
+	// ExpiresAt 订阅到期时间（来自 subscription-userinfo），未知时为 nil。
+	ExpiresAt *time.Time
+}
+
+// IsValidFormat 报告 format 是否为受支持的订阅格式（空串视为 auto）。
+func IsValidFormat(format string) bool {
+	switch format {
+	case "", FormatAuto, FormatClash, FormatSingBox, FormatV2Ray, FormatText:
+		return true
+	}
+	return false
+}
+
+// Parse 按 format 解析订阅内容；format 为空或 auto 时自动识别。
+// V2Ray 风格的整体 base64 编码 URI 列表会先解码再解析。
+func Parse(data []byte, format string) (*Feed, error) {
+	content := strings.TrimSpace(strings.TrimPrefix(string(data), "\ufeff"))
+	if content == "" {
+		return nil, ErrEmptyFeed
+	}
+	if format == "" || format == FormatAuto {
+		format = DetectFormat(content)
+	}
+
+	feed := &Feed{Format: format}
+	var err error
+	switch format {
+	case FormatClash:
+		err = parseClash(content, feed)
+	case FormatSingBox, FormatV2Ray:
+		err = parseJSONOutbounds(content, feed)
+	case FormatText:
+		parseText(decodeBase64Blob(content), feed)
+	default:
+		return nil, fmt.Errorf("unsupported subscription format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	feed.Entries = dedupeEntries(feed.Entries)
+	return feed, nil
+}
+
+// DetectFormat 根据内容特征识别订阅格式。
+func DetectFormat(content string) string {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "{") {
+		if strings.Contains(content, `"protocol"`) && !strings.Contains(content, `"type"`) {
+			return FormatV2Ray
+		}
+		return FormatSingBox
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "proxies:") {
+			return FormatClash
+		}
+	}
+	return FormatText
+}
+
+// ParseSubscriptionUserinfo 解析 subscription-userinfo 响应头中的 expire（Unix 秒）。
+// 形如 "upload=0; download=123; total=1073741824; expire=1767225600"；缺失或为 0 时返回 nil。
+func ParseSubscriptionUserinfo(header string) *time.Time {
+	for _, part := range strings.Split(header, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "expire") {
+			continue
+		}
+		sec, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || sec <= 0 {
+			return nil
+		}
+		t := time.Unix(sec, 0).UTC()
+		return &t
+	}
+	return nil
+}
+
+type clashConfig struct {
+	Proxies []struct {
+		Name     string `yaml:"name"`
+		Type     string `yaml:"type"`
+		Server   string `yaml:"server"`
+		Port     int    `yaml:"port"`
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+		TLS      bool   `yaml:"tls"`
+	} `yaml:"proxies"`
+}
+
+func parseClash(content string, feed *Feed) error {
+	var cfg clashConfig
+	if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
+		return fmt.Errorf("parse clash yaml: %w", err)
+	}
+	for _, p := range cfg.Proxies {
+		protocol := ""
+		switch strings.ToLower(p.Type) {
+		case "http":
+			protocol = "http"
+			if p.TLS {
+				protocol = "https"
+			}
+		case "socks5":
+			if !p.TLS {
+				protocol = "socks5"
+			}
+		}
+		feed.add(protocol, p.Name, p.Server, p.Port, p.Username, p.Password)
+	}
+	return nil
+}
+
+// jsonOutbound 同时覆盖 sing-box（type/server/server_port）与 V2Ray/Xray（protocol/settings.servers）两种结构。
+type jsonOutbound struct {
+	Tag string `json:"tag"`
+
+	// sing-box
+	Type       string `json:"type"`
+	Server     string `json:"server"`
+	ServerPort int    `json:"server_port"`
+	Version    string `json:"version"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	TLS        *struct {
+		Enabled bool `json:"enabled"`
+	} `json:"tls"`
+
+	// V2Ray / Xray
+	Protocol string `json:"protocol"`
+	Settings *struct {
+		Servers []struct {
+			Address string `json:"address"`
+			Port    int    `json:"port"`
+			Users   []struct {
+				User string `json:"user"`
+				Pass string `json:"pass"`
+			} `json:"users"`
+		} `json:"servers"`
+	} `json:"settings"`
+	StreamSettings *struct {
+		Security string `json:"security"`
+	} `json:"streamSettings"`
+}
+
+func parseJSONOutbounds(content string, feed *Feed) error {
+	var cfg struct {
+		Outbounds []jsonOutbound `json:"outbounds"`
+	}
+	if err := json.Unmarshal([]byte(content), &cfg); err != nil {
+		return fmt.Errorf("parse outbounds json: %w", err)
+	}
+	for _, ob := range cfg.Outbounds {
+		if ob.Protocol != "" {
+			addV2RayOutbound(ob, feed)
+			continue
+		}
+		protocol := ""
+		switch strings.ToLower(ob.Type) {
+		case "http":
+			protocol = "http"
+			if ob.TLS != nil && ob.TLS.Enabled {
+				protocol = "https"
+			}
+		case "socks":
+			if ob.Version == "" || ob.Version == "5" {
+				protocol = "socks5"
+			}
+		case "direct", "block", "dns", "selector", "urltest":
+			// 路由用的内置出站，不是代理节点。
+			continue
+		}
+		feed.add(protocol, ob.Tag, ob.Server, ob.ServerPort, ob.Username, ob.Password)
+	}
+	return nil
+}
+
+func addV2RayOutbound(ob jsonOutbound, feed *Feed) {
+	protocol := ""
+	switch strings.ToLower(ob.Protocol) {
+	case "http":
+		protocol = "http"
+		if ob.StreamSettings != nil && strings.EqualFold(ob.StreamSettings.Security, "tls") {
+			protocol = "https"
+		}
+	case "socks":
+		protocol = "socks5"
+	case "freedom", "blackhole", "dns":
+		return
+	}
+	if protocol == "" || ob.Settings == nil || len(ob.Settings.Servers) == 0 {
+		feed.Skipped++
+		return
+	}
+	for _, srv := range ob.Settings.Servers {
+		username, password := "", ""
+		if len(srv.Users) > 0 {
+			username, password = srv.Users[0].User, srv.Users[0].Pass
+		}
+		feed.add(protocol, ob.Tag, srv.Address, srv.Port, username, password)
+	}
+}
+
+func parseText(content string, feed *Feed) {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil || u.Host == "" {
+			feed.Skipped++
+			continue
+		}
+		protocol := strings.ToLower(u.Scheme)
+		if protocol == "socks" {
+			protocol = "socks5"
+		}
+		switch protocol {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			feed.Skipped++
+			continue
+		}
+		port, _ := strconv.Atoi(u.Port())
+		username, password := "", ""
+		if u.User != nil {
+			username = u.User.Username()
+			password, _ = u.User.Password()
+		}
+		feed.add(protocol, u.Fragment, u.Hostname(), port, username, password)
+	}
+}
+
+// decodeBase64Blob V2Ray 订阅通常把整个 URI 列表做一次 base64；解码后包含 "://" 才采用。
+func decodeBase64Blob(content string) string {
+	if strings.Contains(content, "://") {
+		return content
+	}
+	compact := strings.Join(strings.Fields(content), "")
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := enc.DecodeString(compact); err == nil && strings.Contains(string(decoded), "://") {
+			return string(decoded)
+		}
+	}
+	return content
+}
+
+func (f *Feed) add(protocol, name, host string, port int, username, password string) {
+	host = strings.TrimSpace(host)
+	if protocol == "" || host == "" || port <= 0 || port > 65535 {
+		f.Skipped++
+		return
+	}
+	if strings.TrimSpace(name) == "" {
+		name = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	f.Entries = append(f.Entries, Entry{
+		Name:     strings.TrimSpace(name),
+		Protocol: protocol,
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+	})
+}
+
+func dedupeEntries(entries []Entry) []Entry {
+	seen := make(map[string]struct{}, len(entries))
+	out := entries[:0]
+	for _, e := range entries {
+		if _, ok := seen[e.Key()]; ok {
+			continue
+		}
+		seen[e.Key()] = struct{}{}
+		out = append(out, e)
+	}
+	return out
+}
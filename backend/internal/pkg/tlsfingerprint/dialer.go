@@ -24,12 +24,13 @@ type Profile struct {
 	Curves              []uint16
 	PointFormats        []uint16
 	EnableGREASE        bool
-	SignatureAlgorithms []uint16 // Empty uses defaultSignatureAlgorithms
-	ALPNProtocols       []string // Empty uses ["http/1.1"]
-	SupportedVersions   []uint16 // Empty uses [TLS1.3, TLS1.2]
-	KeyShareGroups      []uint16 // Empty uses [X25519]
-	PSKModes            []uint16 // Empty uses [psk_dhe_ke]
-	Extensions          []uint16 // Extension type IDs in order; empty uses default Node.js 24.x order
+	SignatureAlgorithms []uint16      // Empty uses defaultSignatureAlgorithms
+	ALPNProtocols       []string      // Empty uses ["http/1.1"]
+	SupportedVersions   []uint16      // Empty uses [TLS1.3, TLS1.2]
+	KeyShareGroups      []uint16      // Empty uses [X25519]
+	PSKModes            []uint16      // Empty uses [psk_dhe_ke]
+	Extensions          []uint16      // Extension type IDs in order; empty uses default Node.js 24.x order
+	HTTP2               *HTTP2Profile // HTTP/2 framing fingerprint; nil keeps HTTP/1.1-only behavior
 }
 
 // Dialer creates TLS connections with custom fingerprints.
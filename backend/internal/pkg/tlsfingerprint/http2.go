@@ -0,0 +1,190 @@
+package tlsfingerprint
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HTTP/2 SETTINGS identifiers (RFC 9113 §6.5.2).
+const (
+	HTTP2SettingHeaderTableSize      uint16 = 0x1
+	HTTP2SettingEnablePush           uint16 = 0x2
+	HTTP2SettingMaxConcurrentStreams uint16 = 0x3
+	HTTP2SettingInitialWindowSize    uint16 = 0x4
+	HTTP2SettingMaxFrameSize         uint16 = 0x5
+	HTTP2SettingMaxHeaderListSize    uint16 = 0x6
+)
+
+// defaultHTTP2ConnectionFlow is the stream-0 WINDOW_UPDATE increment the Go-derived
+// HTTP/2 client sends when a profile leaves ConnectionFlow unset.
+const defaultHTTP2ConnectionFlow = 1 << 30
+
+// defaultPseudoHeaderOrder is the Go-derived HTTP/2 client's pseudo-header order.
+var defaultPseudoHeaderOrder = []string{":authority", ":method", ":path", ":scheme"}
+
+// pseudoHeaderAbbrev maps pseudo-headers to the single-letter form used by the Akamai fingerprint.
+var pseudoHeaderAbbrev = map[string]string{
+	":method":    "m",
+	":authority": "a",
+	":scheme":    "s",
+	":path":      "p",
+}
+
+// HTTP2Setting is one SETTINGS parameter. Profiles keep settings as an ordered list
+// because the order they appear in the initial SETTINGS frame is part of the fingerprint.
+type HTTP2Setting struct {
+	ID  uint16 `json:"id"`
+	Val uint32 `json:"val"`
+}
+
+// HTTP2Priority mirrors the HTTP/2 stream priority fields.
+// Weight is zero-indexed as on the wire (0 means weight 1, 255 means weight 256).
+type HTTP2Priority struct {
+	StreamDep uint32 `json:"stream_dep"`
+	Exclusive bool   `json:"exclusive"`
+	Weight    uint8  `json:"weight"`
+}
+
+// HTTP2PriorityFrame is a PRIORITY frame sent right after the connection preface
+// (Firefox-style dependency tree). Chrome-style clients send none.
+type HTTP2PriorityFrame struct {
+	StreamID uint32        `json:"stream_id"`
+	Priority HTTP2Priority `json:"priority"`
+}
+
+// HTTP2Profile describes the HTTP/2 connection preface and request framing of a client.
+// It is applied by the upstream transport only when the TLS handshake negotiates h2,
+// which requires "h2" in Profile.ALPNProtocols.
+type HTTP2Profile struct {
+	Settings          []HTTP2Setting       `json:"settings"`            // Initial SETTINGS frame, in order; empty uses transport defaults
+	ConnectionFlow    uint32               `json:"connection_flow"`     // WINDOW_UPDATE increment on stream 0; 0 uses 1<<30
+	PriorityFrames    []HTTP2PriorityFrame `json:"priority_frames"`     // PRIORITY frames sent after the preface
+	HeaderPriority    *HTTP2Priority       `json:"header_priority"`     // Priority flag on request HEADERS frames; nil omits it
+	PseudoHeaderOrder []string             `json:"pseudo_header_order"` // e.g. [":method", ":authority", ":scheme", ":path"]; empty uses a,m,p,s
+}
+
+// Validate checks that settings and pseudo-headers are well-formed.
+func (p *HTTP2Profile) Validate() error {
+	if p == nil {
+		return nil
+	}
+	seen := make(map[uint16]bool, len(p.Settings))
+	for _, s := range p.Settings {
+		if s.ID == 0 {
+			return fmt.Errorf("http2 setting id must be > 0")
+		}
+		if seen[s.ID] {
+			return fmt.Errorf("duplicate http2 setting id %d", s.ID)
+		}
+		seen[s.ID] = true
+		if s.ID == HTTP2SettingEnablePush && s.Val > 1 {
+			return fmt.Errorf("http2 ENABLE_PUSH must be 0 or 1")
+		}
+		if s.ID == HTTP2SettingInitialWindowSize && s.Val > 1<<31-1 {
+			return fmt.Errorf("http2 INITIAL_WINDOW_SIZE exceeds 2^31-1")
+		}
+		if s.ID == HTTP2SettingMaxFrameSize && (s.Val < 1<<14 || s.Val > 1<<24-1) {
+			return fmt.Errorf("http2 MAX_FRAME_SIZE must be between 16384 and 16777215")
+		}
+	}
+	if p.ConnectionFlow > 1<<31-1 {
+		return fmt.Errorf("http2 connection_flow exceeds 2^31-1")
+	}
+	for _, f := range p.PriorityFrames {
+		if f.StreamID == 0 || f.StreamID%2 == 0 {
+			return fmt.Errorf("http2 priority frame stream id must be odd and > 0")
+		}
+	}
+	if len(p.PseudoHeaderOrder) > 0 {
+		used := make(map[string]bool, len(p.PseudoHeaderOrder))
+		for _, h := range p.PseudoHeaderOrder {
+			if _, ok := pseudoHeaderAbbrev[h]; !ok || used[h] {
+				return fmt.Errorf("invalid http2 pseudo header order: %v", p.PseudoHeaderOrder)
+			}
+			used[h] = true
+		}
+		if len(used) != len(pseudoHeaderAbbrev) {
+			return fmt.Errorf("http2 pseudo header order must list :method, :authority, :scheme and :path")
+		}
+	}
+	return nil
+}
+
+// AkamaiFingerprint renders the profile in the Akamai HTTP/2 fingerprint format
+// (SETTINGS|WINDOW_UPDATE|PRIORITY|pseudo-header order), the same string capture
+// servers such as tls.peet.ws report in their "http2" field.
+func (p *HTTP2Profile) AkamaiFingerprint() string {
+	if p == nil {
+		return ""
+	}
+	settings := make([]string, 0, len(p.Settings))
+	for _, s := range p.Settings {
+		settings = append(settings, fmt.Sprintf("%d:%d", s.ID, s.Val))
+	}
+
+	priorities := "0"
+	if len(p.PriorityFrames) > 0 {
+		parts := make([]string, 0, len(p.PriorityFrames))
+		for _, f := range p.PriorityFrames {
+			exclusive := 0
+			if f.Priority.Exclusive {
+				exclusive = 1
+			}
+			parts = append(parts, fmt.Sprintf("%d:%d:%d:%d", f.StreamID, exclusive, f.Priority.StreamDep, int(f.Priority.Weight)+1))
+		}
+		priorities = strings.Join(parts, ",")
+	}
+
+	flow := p.ConnectionFlow
+	if flow == 0 {
+		flow = defaultHTTP2ConnectionFlow
+	}
+	order := p.PseudoHeaderOrder
+	if len(order) == 0 {
+		order = defaultPseudoHeaderOrder
+	}
+	pseudo := make([]string, 0, len(order))
+	for _, h := range order {
+		pseudo = append(pseudo, pseudoHeaderAbbrev[h])
+	}
+
+	return strings.Join([]string{
+		strings.Join(settings, ";"),
+		strconv.FormatUint(uint64(flow), 10),
+		priorities,
+		strings.Join(pseudo, ","),
+	}, "|")
+}
+
+// TransportKey identifies every field that shapes the HTTP/2 connection and request framing.
+// It extends the Akamai fingerprint, which does not cover the HEADERS frame priority flag,
+// so callers can tell when pooled connections built from an older profile must be rebuilt.
+func (p *HTTP2Profile) TransportKey() string {
+	if p == nil {
+		return ""
+	}
+	headerPriority := "-"
+	if hp := p.HeaderPriority; hp != nil {
+		exclusive := 0
+		if hp.Exclusive {
+			exclusive = 1
+		}
+		headerPriority = fmt.Sprintf("%d:%d:%d", exclusive, hp.StreamDep, int(hp.Weight)+1)
+	}
+	return p.AkamaiFingerprint() + "|" + headerPriority
+}
+
+// UsesHTTP2 reports whether the profile carries an HTTP/2 section and advertises h2 via ALPN,
+// i.e. whether upstream connections built from it can negotiate HTTP/2.
+func (p *Profile) UsesHTTP2() bool {
+	if p == nil || p.HTTP2 == nil {
+		return false
+	}
+	for _, proto := range p.ALPNProtocols {
+		if proto == "h2" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+//go:build unit
+
+package tlsfingerprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTP2ProfileAkamaiFingerprint(t *testing.T) {
+	p := &HTTP2Profile{
+		Settings: []HTTP2Setting{
+			{ID: HTTP2SettingHeaderTableSize, Val: 65536},
+			{ID: HTTP2SettingEnablePush, Val: 0},
+			{ID: HTTP2SettingInitialWindowSize, Val: 6291456},
+			{ID: HTTP2SettingMaxHeaderListSize, Val: 262144},
+		},
+		ConnectionFlow:    15663105,
+		PseudoHeaderOrder: []string{":method", ":authority", ":scheme", ":path"},
+	}
+	require.Equal(t, "1:65536;2:0;4:6291456;6:262144|15663105|0|m,a,s,p", p.AkamaiFingerprint())
+
+	p = &HTTP2Profile{
+		PriorityFrames: []HTTP2PriorityFrame{{StreamID: 3, Priority: HTTP2Priority{StreamDep: 0, Weight: 200}}},
+	}
+	require.Equal(t, "|1073741824|3:0:0:201|a,m,p,s", p.AkamaiFingerprint())
+}
+
+func TestHTTP2ProfileTransportKeyCoversHeaderPriority(t *testing.T) {
+	require.Empty(t, (*HTTP2Profile)(nil).TransportKey())
+
+	base := &HTTP2Profile{Settings: []HTTP2Setting{{ID: HTTP2SettingInitialWindowSize, Val: 6291456}}}
+	withPriority := &HTTP2Profile{
+		Settings:       base.Settings,
+		HeaderPriority: &HTTP2Priority{StreamDep: 0, Exclusive: true, Weight: 255},
+	}
+	require.Equal(t, base.AkamaiFingerprint(), withPriority.AkamaiFingerprint(), "Akamai 指纹不含 HEADERS 优先级")
+	require.Equal(t, "4:6291456|1073741824|0|a,m,p,s|-", base.TransportKey())
+	require.Equal(t, "4:6291456|1073741824|0|a,m,p,s|1:0:256", withPriority.TransportKey())
+
+	withPriority.HeaderPriority.Exclusive = false
+	require.Equal(t, "4:6291456|1073741824|0|a,m,p,s|0:0:256", withPriority.TransportKey())
+}
+
+func TestHTTP2ProfileValidate(t *testing.T) {
+	require.NoError(t, (*HTTP2Profile)(nil).Validate())
+	require.NoError(t, (&HTTP2Profile{PseudoHeaderOrder: []string{":method", ":path", ":authority", ":scheme"}}).Validate())
+
+	require.Error(t, (&HTTP2Profile{Settings: []HTTP2Setting{{ID: 1, Val: 1}, {ID: 1, Val: 2}}}).Validate())
+	require.Error(t, (&HTTP2Profile{Settings: []HTTP2Setting{{ID: HTTP2SettingMaxFrameSize, Val: 100}}}).Validate())
+	require.Error(t, (&HTTP2Profile{PriorityFrames: []HTTP2PriorityFrame{{StreamID: 2}}}).Validate())
+	require.Error(t, (&HTTP2Profile{PseudoHeaderOrder: []string{":method", ":path"}}).Validate())
+}
+
+func TestProfileUsesHTTP2(t *testing.T) {
+	h2 := &HTTP2Profile{}
+	require.False(t, (*Profile)(nil).UsesHTTP2())
+	require.False(t, (&Profile{ALPNProtocols: []string{"h2"}}).UsesHTTP2())
+	require.False(t, (&Profile{ALPNProtocols: []string{"http/1.1"}, HTTP2: h2}).UsesHTTP2())
+	require.True(t, (&Profile{ALPNProtocols: []string{"h2", "http/1.1"}, HTTP2: h2}).UsesHTTP2())
+}
@@ -134,6 +134,22 @@ type GroupStat struct {
 	AccountCost float64 `json:"account_cost"` // 账号成本
 }
 
+// GroupCacheHitRate reports prompt-cache effectiveness for one group.
+// TokenHitRate = cache_read / (input + cache_creation + cache_read);
+// RequestHitRate = requests with any cache read / requests.
+type GroupCacheHitRate struct {
+	GroupID             int64   `json:"group_id"`
+	GroupName           string  `json:"group_name"`
+	PromptPrefixRouting bool    `json:"prompt_prefix_routing_enabled"`
+	Requests            int64   `json:"requests"`
+	CacheHitRequests    int64   `json:"cache_hit_requests"`
+	InputTokens         int64   `json:"input_tokens"`
+	CacheCreationTokens int64   `json:"cache_creation_tokens"`
+	CacheReadTokens     int64   `json:"cache_read_tokens"`
+	TokenHitRate        float64 `json:"token_hit_rate"`
+	RequestHitRate      float64 `json:"request_hit_rate"`
+}
+
 // UserUsageTrendPoint represents user usage trend data point
 type UserUsageTrendPoint struct {
 	Date       string  `json:"date"`
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/lib/pq"
+)
+
+// accountCostRepository 账号采购成本台账（raw SQL）。
+type accountCostRepository struct {
+	db *sql.DB
+}
+
+// NewAccountCostRepository 创建账号成本仓储。
+func NewAccountCostRepository(db *sql.DB) service.AccountCostRepository {
+	return &accountCostRepository{db: db}
+}
+
+const accountCostBasisColumns = `account_id, billing_type, cost, period_days, started_at, expires_at, notes, created_at, updated_at`
+
+func (r *accountCostRepository) GetByAccountID(ctx context.Context, accountID int64) (*service.AccountCostBasis, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+accountCostBasisColumns+` FROM account_cost_bases WHERE account_id = $1`, accountID)
+	basis, err := scanAccountCostBasis(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, service.ErrAccountCostBasisNotFound
+	}
+	return basis, err
+}
+
+func (r *accountCostRepository) Upsert(ctx context.Context, basis *service.AccountCostBasis) (*service.AccountCostBasis, error) {
+	row := r.db.QueryRowContext(ctx, `
+		INSERT INTO account_cost_bases (account_id, billing_type, cost, period_days, started_at, expires_at, notes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		ON CONFLICT (account_id) DO UPDATE SET
+			billing_type = EXCLUDED.billing_type,
+			cost = EXCLUDED.cost,
+			period_days = EXCLUDED.period_days,
+			started_at = EXCLUDED.started_at,
+			expires_at = EXCLUDED.expires_at,
+			notes = EXCLUDED.notes,
+			updated_at = NOW()
+		RETURNING `+accountCostBasisColumns,
+		basis.AccountID, basis.BillingType, basis.Cost, basis.PeriodDays, basis.StartedAt, basis.ExpiresAt, basis.Notes)
+	return scanAccountCostBasis(row.Scan)
+}
+
+func (r *accountCostRepository) Delete(ctx context.Context, accountID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM account_cost_bases WHERE account_id = $1`, accountID)
+	return err
+}
+
+func (r *accountCostRepository) ListWithAccounts(ctx context.Context) ([]*service.AccountCostBasis, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.account_id, c.billing_type, c.cost, c.period_days, c.started_at, c.expires_at, c.notes, c.created_at, c.updated_at,
+			a.name, a.platform, a.status, a.proxy_id, COALESCE(p.name, '')
+		FROM account_cost_bases c
+		JOIN accounts a ON a.id = c.account_id AND a.deleted_at IS NULL
+		LEFT JOIN proxies p ON p.id = a.proxy_id
+		ORDER BY c.account_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]*service.AccountCostBasis, 0)
+	for rows.Next() {
+		var (
+			basis     service.AccountCostBasis
+			expiresAt sql.NullTime
+			proxyID   sql.NullInt64
+		)
+		if err := rows.Scan(
+			&basis.AccountID, &basis.BillingType, &basis.Cost, &basis.PeriodDays, &basis.StartedAt, &expiresAt, &basis.Notes, &basis.CreatedAt, &basis.UpdatedAt,
+			&basis.AccountName, &basis.Platform, &basis.AccountStatus, &proxyID, &basis.ProxyName,
+		); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			t := expiresAt.Time
+			basis.ExpiresAt = &t
+		}
+		if proxyID.Valid {
+			id := proxyID.Int64
+			basis.ProxyID = &id
+		}
+		out = append(out, &basis)
+	}
+	return out, rows.Err()
+}
+
+func (r *accountCostRepository) SummarizeUsage(ctx context.Context, startTime, endTime time.Time, accountIDs []int64) (map[int64]*service.AccountUsageSummary, error) {
+	out := make(map[int64]*service.AccountUsageSummary, len(accountIDs))
+	if len(accountIDs) == 0 {
+		return out, nil
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			account_id,
+			COUNT(*) AS requests,
+			COALESCE(SUM(actual_cost), 0) AS revenue,
+			COUNT(DISTINCT date_trunc('hour', created_at)) AS active_hours,
+			COUNT(DISTINCT date_trunc('day', created_at)) AS active_days,
+			MAX(created_at) AS last_used_at
+		FROM usage_logs
+		WHERE created_at >= $1 AND created_at < $2 AND account_id = ANY($3)
+		GROUP BY account_id
+	`, startTime, endTime, pq.Array(accountIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var (
+			summary    service.AccountUsageSummary
+			lastUsedAt sql.NullTime
+		)
+		if err := rows.Scan(&summary.AccountID, &summary.Requests, &summary.Revenue, &summary.ActiveHours, &summary.ActiveDays, &lastUsedAt); err != nil {
+			return nil, err
+		}
+		if lastUsedAt.Valid {
+			t := lastUsedAt.Time
+			summary.LastUsedAt = &t
+		}
+		out[summary.AccountID] = &summary
+	}
+	return out, rows.Err()
+}
+
+func scanAccountCostBasis(scan func(dest ...any) error) (*service.AccountCostBasis, error) {
+	var (
+		basis     service.AccountCostBasis
+		expiresAt sql.NullTime
+	)
+	if err := scan(&basis.AccountID, &basis.BillingType, &basis.Cost, &basis.PeriodDays, &basis.StartedAt, &expiresAt, &basis.Notes, &basis.CreatedAt, &basis.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		t := expiresAt.Time
+		basis.ExpiresAt = &t
+	}
+	return &basis, nil
+}
@@ -49,6 +49,8 @@ type accountRepository struct {
 	// Used to proactively sync account snapshot to cache when status changes,
 	// ensuring sticky sessions can promptly detect unavailable accounts.
 	schedulerCache service.SchedulerCache
+	// credentials 负责凭证与代理密码的静态加密；为 nil 或未配置主密钥时按明文读写。
+	credentials *service.CredentialCipher
 }
 
 var schedulerNeutralExtraKeyPrefixes = []string{
@@ -90,26 +92,50 @@ func newAccountRepositoryWithSQL(client *dbent.Client, sqlq sqlExecutor, schedul
 }
 
 func (r *accountRepository) Create(ctx context.Context, account *service.Account) error {
-	if err := createAccountRecord(ctx, r.client, account); err != nil {
+	client := r.client
+	var tx *dbent.Tx
+	if r.credentials.Enabled() {
+		// 凭证密文绑定账号 ID，插入后才能加密，两步写入需在同一事务内完成。
+		var err error
+		tx, err = r.client.Tx(ctx)
+		if err != nil && !errors.Is(err, dbent.ErrTxStarted) {
+			return err
+		}
+		if tx != nil {
+			defer func() { _ = tx.Rollback() }()
+			client = tx.Client()
+		}
+	}
+	if err := createAccountRecord(ctx, client, account, r.credentials); err != nil {
 		return err
 	}
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
 	if err := enqueueSchedulerOutbox(ctx, r.sql, service.SchedulerOutboxEventAccountChanged, &account.ID, nil, buildSchedulerGroupPayload(account.GroupIDs)); err != nil {
 		logger.LegacyPrintf("repository.account", "[SchedulerOutbox] enqueue account create failed: account=%d err=%v", account.ID, err)
 	}
 	return nil
 }
 
-func createAccountRecord(ctx context.Context, client *dbent.Client, account *service.Account) error {
+func createAccountRecord(ctx context.Context, client *dbent.Client, account *service.Account, cipher *service.CredentialCipher) error {
 	if account == nil {
 		return service.ErrAccountNilInput
 	}
+	credentials := normalizeJSONMap(account.Credentials)
+	if cipher.Enabled() {
+		// 凭证密文以账号 ID 作为附加数据，先写入空凭证取得 ID，保存后再补写密文。
+		credentials = map[string]any{}
+	}
 
 	builder := client.Account.Create().
 		SetName(account.Name).
 		SetNillableNotes(account.Notes).
 		SetPlatform(account.Platform).
 		SetType(account.Type).
-		SetCredentials(normalizeJSONMap(account.Credentials)).
+		SetCredentials(credentials).
 		SetExtra(normalizeJSONMap(account.Extra)).
 		SetConcurrency(account.Concurrency).
 		SetPriority(account.Priority).
@@ -162,6 +188,15 @@ func createAccountRecord(ctx context.Context, client *dbent.Client, account *ser
 	if err != nil {
 		return translatePersistenceError(err, service.ErrAccountNotFound, nil)
 	}
+	if cipher.Enabled() {
+		sealed, err := cipher.SealCredentials(ctx, created.ID, normalizeJSONMap(account.Credentials), nil)
+		if err != nil {
+			return err
+		}
+		if created, err = client.Account.UpdateOneID(created.ID).SetCredentials(sealed).Save(ctx); err != nil {
+			return translatePersistenceError(err, service.ErrAccountNotFound, nil)
+		}
+	}
 
 	account.ID = created.ID
 	account.CreatedAt = created.CreatedAt
@@ -189,7 +224,7 @@ func (r *accountRepository) CreateWithAccountGroups(ctx context.Context, account
 		txClient = r.client
 	}
 
-	if err := createAccountRecord(ctx, txClient, account); err != nil {
+	if err := createAccountRecord(ctx, txClient, account, r.credentials); err != nil {
 		return err
 	}
 	groupIDs := make([]int64, 0, len(groups))
@@ -290,10 +325,12 @@ func (r *accountRepository) GetByIDs(ctx context.Context, ids []int64) ([]*servi
 		if out == nil {
 			continue
 		}
+		r.openAccountCredentials(ctx, out)
 
 		// Prefer the preloaded proxy edge when available.
 		if entAcc.Edges.Proxy != nil {
 			out.Proxy = proxyEntityToService(entAcc.Edges.Proxy)
+			openProxySecret(ctx, r.credentials, out.Proxy)
 		}
 
 		if groups, ok := groupsByAccount[entAcc.ID]; ok {
@@ -482,7 +519,14 @@ func (r *accountRepository) updateLockedAccount(
 	explicitRateSyncEnabled *bool,
 	explicitRateMultiplier *float64,
 ) (*dbent.Account, error) {
-	extra, err := lockAndMergeAccountProbeExtra(ctx, client, account, explicitProbeEnabled, explicitRateSyncEnabled)
+	credentials, err := r.sealAccountCredentials(ctx, client, account.ID, account.Credentials)
+	if err != nil {
+		return nil, err
+	}
+	// 探测身份比较针对存储形态的凭证进行。
+	stored := *account
+	stored.Credentials = credentials
+	extra, err := lockAndMergeAccountProbeExtra(ctx, client, &stored, explicitProbeEnabled, explicitRateSyncEnabled)
 	if err != nil {
 		return nil, err
 	}
@@ -498,7 +542,7 @@ func (r *accountRepository) updateLockedAccount(
 		SetNillableNotes(account.Notes).
 		SetPlatform(account.Platform).
 		SetType(account.Type).
-		SetCredentials(normalizeJSONMap(account.Credentials)).
+		SetCredentials(credentials).
 		SetExtra(extra).
 		SetConcurrency(account.Concurrency).
 		SetPriority(account.Priority).
@@ -752,10 +796,6 @@ func decodeAccountExtraJSON(raw []byte) (any, bool, error) {
 }
 
 func (r *accountRepository) UpdateCredentials(ctx context.Context, id int64, credentials map[string]any) error {
-	payload, err := json.Marshal(normalizeJSONMap(credentials))
-	if err != nil {
-		return err
-	}
 	baseCtx := ctx
 	contextTx := dbent.TxFromContext(ctx)
 	client := r.client
@@ -774,6 +814,14 @@ func (r *accountRepository) UpdateCredentials(ctx context.Context, id int64, cre
 			client = tx.Client()
 		}
 	}
+	var queryer sqlQueryer = r.sql
+	if client != nil {
+		queryer = client
+	}
+	payload, err := r.sealAccountCredentialsJSON(ctx, queryer, id, credentials)
+	if err != nil {
+		return err
+	}
 	result, err := client.ExecContext(ctx, `
 		UPDATE accounts
 		SET
@@ -805,7 +853,7 @@ func (r *accountRepository) UpdateCredentials(ctx context.Context, id int64, cre
 			END,
 			updated_at = NOW()
 		WHERE id = $2 AND deleted_at IS NULL
-	`, string(payload), id)
+	`, payload, id)
 	if err != nil {
 		return err
 	}
@@ -1346,6 +1394,10 @@ func (r *accountRepository) SetGrokCredentialErrorIfMatch(
 	snapshot service.GrokCredentialMutationSnapshot,
 	errorMsg string,
 ) (bool, error) {
+	credentialsJSON, err := r.sealAccountCredentialsSnapshot(ctx, id, snapshot.CredentialsJSON)
+	if err != nil {
+		return false, err
+	}
 	result, err := r.sql.ExecContext(ctx, `
 		WITH updated AS (
 		UPDATE accounts AS a
@@ -1375,7 +1427,7 @@ func (r *accountRepository) SetGrokCredentialErrorIfMatch(
 		INSERT INTO scheduler_outbox (event_type, account_id, group_id, payload)
 		SELECT $10, updated.id, NULL, NULL FROM updated
 	`, service.StatusError, errorMsg, id, service.StatusActive, service.PlatformGrok, service.AccountTypeOAuth,
-		snapshot.CredentialsJSON, snapshot.ProxyID, string(service.GrokCredentialReasonProxyInvalid),
+		credentialsJSON, snapshot.ProxyID, string(service.GrokCredentialReasonProxyInvalid),
 		service.SchedulerOutboxEventAccountChanged)
 	if err != nil {
 		return false, err
@@ -1402,7 +1454,7 @@ func (r *accountRepository) SetGrokOAuthErrorIfCredentialsUnchanged(
 	if r == nil || r.sql == nil {
 		return false, errors.New("account repository SQL executor is not configured")
 	}
-	expectedJSON, err := json.Marshal(normalizeJSONMap(expectedCredentials))
+	expectedJSON, err := r.sealAccountCredentialsJSON(ctx, r.sql, id, expectedCredentials)
 	if err != nil {
 		return false, err
 	}
@@ -1431,7 +1483,7 @@ func (r *accountRepository) SetGrokOAuthErrorIfCredentialsUnchanged(
 		service.PlatformGrok,
 		service.AccountTypeOAuth,
 		service.StatusActive,
-		string(expectedJSON),
+		expectedJSON,
 		service.SchedulerOutboxEventAccountChanged,
 	)
 	if err != nil {
@@ -1463,11 +1515,11 @@ func (r *accountRepository) UpdateGrokOAuthCredentialsIfUnchanged(
 	if r == nil || r.sql == nil {
 		return false, errors.New("account repository SQL executor is not configured")
 	}
-	expectedJSON, err := json.Marshal(normalizeJSONMap(expectedCredentials))
+	expectedJSON, err := r.sealAccountCredentialsJSON(ctx, r.sql, id, expectedCredentials)
 	if err != nil {
 		return false, err
 	}
-	credentialsJSON, err := json.Marshal(normalizeJSONMap(credentials))
+	credentialsJSON, err := r.sealAccountCredentialsJSON(ctx, r.sql, id, credentials)
 	if err != nil {
 		return false, err
 	}
@@ -1487,11 +1539,11 @@ func (r *accountRepository) UpdateGrokOAuthCredentialsIfUnchanged(
 		INSERT INTO scheduler_outbox (event_type, account_id, group_id, payload)
 		SELECT $7, updated.id, NULL, NULL FROM updated
 	`,
-		string(credentialsJSON),
+		credentialsJSON,
 		id,
 		service.PlatformGrok,
 		service.AccountTypeOAuth,
-		string(expectedJSON),
+		expectedJSON,
 		expectedProxyID,
 		service.SchedulerOutboxEventAccountChanged,
 	)
@@ -1523,7 +1575,7 @@ func (r *accountRepository) SetGrokOAuthRefreshErrorIfCredentialsUnchanged(
 	if r == nil || r.sql == nil {
 		return false, errors.New("account repository SQL executor is not configured")
 	}
-	expectedJSON, err := json.Marshal(normalizeJSONMap(expectedCredentials))
+	expectedJSON, err := r.sealAccountCredentialsJSON(ctx, r.sql, id, expectedCredentials)
 	if err != nil {
 		return false, err
 	}
@@ -1552,7 +1604,7 @@ func (r *accountRepository) SetGrokOAuthRefreshErrorIfCredentialsUnchanged(
 		service.PlatformGrok,
 		service.AccountTypeOAuth,
 		service.StatusActive,
-		string(expectedJSON),
+		expectedJSON,
 		expectedProxyID,
 		service.SchedulerOutboxEventAccountChanged,
 	)
@@ -1584,7 +1636,7 @@ func (r *accountRepository) SetGrokOAuthRefreshTempUnschedulableIfCredentialsUnc
 	if r == nil || r.sql == nil {
 		return false, errors.New("account repository SQL executor is not configured")
 	}
-	expectedJSON, err := json.Marshal(normalizeJSONMap(expectedCredentials))
+	expectedJSON, err := r.sealAccountCredentialsJSON(ctx, r.sql, id, expectedCredentials)
 	if err != nil {
 		return false, err
 	}
@@ -1613,7 +1665,7 @@ func (r *accountRepository) SetGrokOAuthRefreshTempUnschedulableIfCredentialsUnc
 		service.PlatformGrok,
 		service.AccountTypeOAuth,
 		service.StatusActive,
-		string(expectedJSON),
+		expectedJSON,
 		expectedProxyID,
 		service.SchedulerOutboxEventAccountChanged,
 	)
@@ -2301,6 +2353,10 @@ func (r *accountRepository) SetGrokCredentialTempUnschedulableIfMatch(
 	until time.Time,
 	reason string,
 ) (bool, error) {
+	credentialsJSON, err := r.sealAccountCredentialsSnapshot(ctx, id, snapshot.CredentialsJSON)
+	if err != nil {
+		return false, err
+	}
 	result, err := r.sql.ExecContext(ctx, `
 		WITH updated AS (
 		UPDATE accounts AS a
@@ -2327,7 +2383,7 @@ func (r *accountRepository) SetGrokCredentialTempUnschedulableIfMatch(
 		INSERT INTO scheduler_outbox (event_type, account_id, group_id, payload)
 		SELECT $9, updated.id, NULL, NULL FROM updated
 	`, until, reason, id, service.StatusActive, service.PlatformGrok, service.AccountTypeOAuth,
-		snapshot.CredentialsJSON, snapshot.ProxyID, service.SchedulerOutboxEventAccountChanged)
+		credentialsJSON, snapshot.ProxyID, service.SchedulerOutboxEventAccountChanged)
 	if err != nil {
 		return false, err
 	}
@@ -2644,6 +2700,13 @@ func (r *accountRepository) updateUpstreamBillingProbeSnapshotInTx(
 	if err != nil {
 		return err
 	}
+	if r.credentials.Enabled() {
+		sealed, err := r.sealAccountCredentialsJSON(ctx, clientFromContext(ctx, r.client), account.ID, account.Credentials)
+		if err != nil {
+			return err
+		}
+		credentials = []byte(sealed)
+	}
 	var expectedSnapshot any
 	if account.Extra != nil {
 		expectedSnapshot = account.Extra[service.UpstreamBillingProbeExtraKey]
@@ -2669,7 +2732,7 @@ func (r *accountRepository) updateUpstreamBillingProbeSnapshotInTx(
 		return err
 	}
 	client := clientFromContext(ctx, r.client)
-	proxyMatches, err := lockAndMatchProbeProxyIdentity(ctx, client, account)
+	proxyMatches, err := lockAndMatchProbeProxyIdentity(ctx, client, account, r.credentials)
 	if err != nil {
 		return err
 	}
@@ -2715,7 +2778,7 @@ func (r *accountRepository) updateUpstreamBillingProbeSnapshotInTx(
 	return enqueueSchedulerOutbox(ctx, client, service.SchedulerOutboxEventAccountChanged, &account.ID, nil, nil)
 }
 
-func lockAndMatchProbeProxyIdentity(ctx context.Context, client *dbent.Client, account *service.Account) (bool, error) {
+func lockAndMatchProbeProxyIdentity(ctx context.Context, client *dbent.Client, account *service.Account, cipher *service.CredentialCipher) (bool, error) {
 	if account.ProxyID == nil {
 		return true, nil
 	}
@@ -2742,6 +2805,9 @@ func lockAndMatchProbeProxyIdentity(ctx context.Context, client *dbent.Client, a
 	if err := rows.Scan(&current.protocol, &current.host, &current.port, &current.username, &current.password, &current.status); err != nil {
 		return false, err
 	}
+	if current.password, err = cipher.OpenString(ctx, current.password); err != nil {
+		return false, err
+	}
 	return current == proxyProbeIdentityFromService(account.Proxy), rows.Err()
 }
 
@@ -2858,26 +2924,34 @@ func (r *accountRepository) BulkUpdate(ctx context.Context, ids []int64, updates
 		updates.Extra[service.UpstreamBillingProbeEnabledExtraKey] = *updates.ProbeEnabled
 	}
 	// JSONB 需要合并而非覆盖，使用 raw SQL 保持旧行为。
-	credentialPlaceholder := ""
+	credentialPatch := ""
 	if len(updates.Credentials) > 0 {
-		payload, err := json.Marshal(updates.Credentials)
+		// 批量补丁跨多个账号，无法沿用各自的密文，敏感字段统一重新加密。
+		payload, err := r.sealBulkCredentialPatch(ctx, ids, updates.Credentials)
 		if err != nil {
 			return 0, err
 		}
-		credentialPlaceholder = "$" + itoa(idx)
-		setClauses = append(setClauses, "credentials = COALESCE(credentials, '{}'::jsonb) || "+credentialPlaceholder+"::jsonb")
+		credentialPatch = "$" + itoa(idx) + "::jsonb"
+		if r.credentials.Enabled() {
+			credentialPatch = "(" + credentialPatch + " -> id::text)"
+		}
+		setClauses = append(setClauses, "credentials = COALESCE(credentials, '{}'::jsonb) || "+credentialPatch)
 		args = append(args, payload)
 		idx++
 	}
 
 	ollamaGroupIdentityChanges := make([]string, 0, 2)
 	if _, ok := updates.Credentials["api_key"]; ok {
-		ollamaGroupIdentityChanges = append(ollamaGroupIdentityChanges, "credentials -> 'api_key' IS DISTINCT FROM "+credentialPlaceholder+"::jsonb -> 'api_key'")
+		if r.credentials.Enabled() {
+			ollamaGroupIdentityChanges = append(ollamaGroupIdentityChanges, "COALESCE(credentials -> '"+service.CredentialAPIKeyFingerprintKey+"', credentials -> 'api_key') IS DISTINCT FROM "+credentialPatch+" -> '"+service.CredentialAPIKeyFingerprintKey+"'")
+		} else {
+			ollamaGroupIdentityChanges = append(ollamaGroupIdentityChanges, "credentials -> 'api_key' IS DISTINCT FROM "+credentialPatch+" -> 'api_key'")
+		}
 	}
 	if _, ok := updates.Credentials["base_url"]; ok {
 		ollamaGroupIdentityChanges = append(ollamaGroupIdentityChanges,
 			"NOT ("+ollamaCloudBaseURLMatchesSQL("credentials ->> 'base_url'")+
-				" AND "+ollamaCloudBaseURLMatchesSQL(credentialPlaceholder+"::jsonb ->> 'base_url'")+")")
+				" AND "+ollamaCloudBaseURLMatchesSQL(credentialPatch+" ->> 'base_url'")+")")
 	}
 
 	if len(updates.Extra) > 0 || len(ollamaGroupIdentityChanges) > 0 || ollamaProxyIdentityChanged != "" {
@@ -3107,6 +3181,7 @@ func (r *accountRepository) accountsToService(ctx context.Context, accounts []*d
 		if out == nil {
 			continue
 		}
+		r.openAccountCredentials(ctx, out)
 		if acc.ProxyID != nil {
 			if proxy, ok := proxyMap[*acc.ProxyID]; ok {
 				out.Proxy = proxy
@@ -3169,7 +3244,9 @@ func (r *accountRepository) loadProxies(ctx context.Context, proxyIDs []int64) (
 			return nil, err
 		}
 		for _, p := range proxies {
-			proxyMap[p.ID] = proxyEntityToService(p)
+			proxy := proxyEntityToService(p)
+			openProxySecret(ctx, r.credentials, proxy)
+			proxyMap[p.ID] = proxy
 		}
 	}
 	return proxyMap, nil
@@ -3775,7 +3852,9 @@ func (r *accountRepository) ListShadowsByParent(ctx context.Context, parentID in
 	}
 	out := make([]*service.Account, 0, len(rows))
 	for _, m := range rows {
-		out = append(out, accountEntityToService(m))
+		account := accountEntityToService(m)
+		r.openAccountCredentials(ctx, account)
+		out = append(out, account)
 	}
 	return out, nil
 }
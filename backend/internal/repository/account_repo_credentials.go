@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	dbent "github.com/Wei-Shaw/sub2api/ent"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+)
+
+// ProvideAccountRepository 创建启用凭证静态加密的账户仓储。
+func ProvideAccountRepository(client *dbent.Client, sqlDB *sql.DB, schedulerCache service.SchedulerCache, credentials *service.CredentialCipher) service.AccountRepository {
+	repo := newAccountRepositoryWithSQL(client, sqlDB, schedulerCache)
+	repo.credentials = credentials
+	return repo
+}
+
+// ProvideAdminAccountRepository 与 ProvideAccountRepository 相同，返回管理端接口。
+func ProvideAdminAccountRepository(client *dbent.Client, sqlDB *sql.DB, schedulerCache service.SchedulerCache, credentials *service.CredentialCipher) service.AdminAccountRepository {
+	repo := newAccountRepositoryWithSQL(client, sqlDB, schedulerCache)
+	repo.credentials = credentials
+	return repo
+}
+
+// sealAccountCredentials 返回写入 accounts.credentials 的文档。
+// 未启用加密时与 normalizeJSONMap 结果一致；启用时加密敏感字段，
+// 并沿用该账号当前存储中明文相同字段的密文，使 credentials = $x::jsonb 的比较保持成立。
+func (r *accountRepository) sealAccountCredentials(ctx context.Context, q sqlQueryer, accountID int64, credentials map[string]any) (map[string]any, error) {
+	normalized := normalizeJSONMap(credentials)
+	if !r.credentials.Enabled() {
+		return normalized, nil
+	}
+	var stored map[string]any
+	if accountID > 0 && q != nil {
+		var err error
+		if stored, err = loadStoredAccountCredentials(ctx, q, accountID); err != nil {
+			return nil, err
+		}
+	}
+	return r.credentials.SealCredentials(ctx, accountID, normalized, stored)
+}
+
+// sealAccountCredentialsJSON 是 sealAccountCredentials 的 JSON 版本，用于原生 SQL 参数。
+func (r *accountRepository) sealAccountCredentialsJSON(ctx context.Context, q sqlQueryer, accountID int64, credentials map[string]any) (string, error) {
+	sealed, err := r.sealAccountCredentials(ctx, q, accountID, credentials)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(payload), nil
+}
+
+// sealAccountCredentialsSnapshot 把服务层基于明文构造的凭证快照 JSON 转换为存储形态。
+func (r *accountRepository) sealAccountCredentialsSnapshot(ctx context.Context, accountID int64, snapshot string) (string, error) {
+	if !r.credentials.Enabled() {
+		return snapshot, nil
+	}
+	var credentials map[string]any
+	if err := json.Unmarshal([]byte(snapshot), &credentials); err != nil {
+		return "", err
+	}
+	return r.sealAccountCredentialsJSON(ctx, r.sql, accountID, credentials)
+}
+
+// sealBulkCredentialPatch 为批量更新生成凭证补丁。密文绑定账号 ID，无法跨账号共用，
+// 启用加密时返回以账号 ID 为键的补丁对象，由 SQL 按行取出各自的补丁。
+func (r *accountRepository) sealBulkCredentialPatch(ctx context.Context, ids []int64, credentials map[string]any) ([]byte, error) {
+	if !r.credentials.Enabled() {
+		return json.Marshal(credentials)
+	}
+	patches := make(map[string]map[string]any, len(ids))
+	for _, id := range ids {
+		patch, err := r.credentials.SealCredentials(ctx, id, credentials, nil)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := credentials["api_key"]; ok {
+			if _, ok := patch[service.CredentialAPIKeyFingerprintKey]; !ok {
+				patch[service.CredentialAPIKeyFingerprintKey] = nil
+			}
+		}
+		patches[strconv.FormatInt(id, 10)] = patch
+	}
+	return json.Marshal(patches)
+}
+
+func loadStoredAccountCredentials(ctx context.Context, q sqlQueryer, accountID int64) (map[string]any, error) {
+	var raw []byte
+	err := scanSingleRow(ctx, q, `SELECT credentials FROM accounts WHERE id = $1`, []any{accountID}, &raw)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var stored map[string]any
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &stored); err != nil {
+			return nil, err
+		}
+	}
+	return stored, nil
+}
+
+// openAccountCredentials 解密账号凭证。解密失败只记录日志并保留密文，
+// 避免单个损坏记录影响整批加载。
+func (r *accountRepository) openAccountCredentials(ctx context.Context, account *service.Account) {
+	if account == nil || len(account.Credentials) == 0 {
+		return
+	}
+	stored := account.Credentials
+	if r.credentials.Enabled() && service.HasUnboundCredentials(stored) {
+		stored = r.upgradeAccountCredentials(ctx, account.ID, stored)
+	}
+	opened, err := r.credentials.OpenCredentials(ctx, account.ID, stored)
+	if err != nil {
+		logger.LegacyPrintf("repository.account", "[CredentialEncryption] open credentials failed: account=%d err=%v", account.ID, err)
+	}
+	account.Credentials = opened
+}
+
+// upgradeAccountCredentials 把早期写入、未绑定账号的 v1 密文重新加密为 v2 并写回。
+// 写回以原文档做 CAS，记录已被并发改写时放弃写回，返回的升级结果仍可用于本次解密。
+func (r *accountRepository) upgradeAccountCredentials(ctx context.Context, accountID int64, stored map[string]any) map[string]any {
+	upgraded, changed, err := r.credentials.RotateCredentials(ctx, accountID, stored)
+	if err != nil {
+		logger.LegacyPrintf("repository.account", "[CredentialEncryption] upgrade credentials failed: account=%d err=%v", accountID, err)
+		return stored
+	}
+	if !changed || r.sql == nil {
+		return upgraded
+	}
+	expectedJSON, err := json.Marshal(stored)
+	if err != nil {
+		return upgraded
+	}
+	upgradedJSON, err := json.Marshal(upgraded)
+	if err != nil {
+		return upgraded
+	}
+	// 明文不变，无需刷新 updated_at 或调度快照。
+	if _, err := r.sql.ExecContext(ctx, `
+		UPDATE accounts
+		SET credentials = $1::jsonb
+		WHERE id = $2 AND credentials = $3::jsonb
+	`, string(upgradedJSON), accountID, string(expectedJSON)); err != nil {
+		logger.LegacyPrintf("repository.account", "[CredentialEncryption] persist upgraded credentials failed: account=%d err=%v", accountID, err)
+	}
+	return upgraded
+}
+
+// ollamaCloudAPIKeySQL 返回用于跨账号比较 API Key 的 SQL 表达式：
+// 启用加密后比较指纹，未加密的历史记录回退到明文字段。
+func (r *accountRepository) ollamaCloudAPIKeySQL() string {
+	if r.credentials.Enabled() {
+		return "COALESCE(credentials ->> '" + service.CredentialAPIKeyFingerprintKey + "', credentials ->> 'api_key')"
+	}
+	return "credentials ->> 'api_key'"
+}
+
+// ollamaCloudAPIKeyParam 返回与 ollamaCloudAPIKeySQL 对应的参数值。
+func (r *accountRepository) ollamaCloudAPIKeyParam(apiKey string) string {
+	if r.credentials.Enabled() {
+		return service.CredentialFingerprint(apiKey)
+	}
+	return apiKey
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/stretchr/testify/require"
+)
+
+// boundCredentialsMatcher 匹配写回的凭证文档：api_key 已是绑定账号的 v2 密文。
+type boundCredentialsMatcher struct{}
+
+func (boundCredentialsMatcher) Match(value driver.Value) bool {
+	raw, ok := value.(string)
+	if !ok {
+		return false
+	}
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		return false
+	}
+	apiKey, _ := doc["api_key"].(string)
+	return strings.HasPrefix(apiKey, "enc:v2:k1:")
+}
+
+func TestOpenAccountCredentialsUpgradesUnboundCiphertext(t *testing.T) {
+	ctx := context.Background()
+	provider, err := newLocalMasterKeyProvider("k1", map[string]string{"k1": strings.Repeat("11", 32)})
+	require.NoError(t, err)
+	cipher := service.NewCredentialCipher(provider, nil)
+	legacy, err := cipher.SealString(ctx, "sk-old")
+	require.NoError(t, err)
+	stored := map[string]any{"api_key": legacy}
+	storedJSON, err := json.Marshal(stored)
+	require.NoError(t, err)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE accounts")).
+		WithArgs(boundCredentialsMatcher{}, int64(7), string(storedJSON)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := newAccountRepositoryWithSQL(nil, db, nil)
+	repo.credentials = cipher
+	account := &service.Account{ID: 7, Credentials: stored}
+	repo.openAccountCredentials(ctx, account)
+
+	require.Equal(t, "sk-old", account.Credentials["api_key"])
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -48,7 +48,7 @@ func (r *accountRepository) ListOllamaCloudUsageGroupAccounts(ctx context.Contex
 			continue
 		}
 		seen[apiKey] = struct{}{}
-		keys = append(keys, apiKey)
+		keys = append(keys, r.ollamaCloudAPIKeyParam(apiKey))
 	}
 	if len(keys) == 0 {
 		return []service.Account{}, nil
@@ -58,7 +58,7 @@ func (r *accountRepository) ListOllamaCloudUsageGroupAccounts(ctx context.Contex
 		FROM accounts
 		WHERE deleted_at IS NULL
 			AND `+ollamaCloudUsageEligibleSQL+`
-			AND credentials ->> 'api_key' = ANY($1)
+			AND `+r.ollamaCloudAPIKeySQL()+` = ANY($1)
 		ORDER BY id
 	`, pq.Array(keys))
 	if err != nil {
@@ -183,14 +183,14 @@ func (r *accountRepository) updateOllamaCloudUsageGroup(
 		return service.ErrOllamaCloudUsageAccountInvalid
 	}
 	apply := func(txCtx context.Context, client *dbent.Client) error {
-		matchesProxy, err := lockAndMatchProbeProxyIdentity(txCtx, client, account)
+		matchesProxy, err := lockAndMatchProbeProxyIdentity(txCtx, client, account, r.credentials)
 		if err != nil {
 			return err
 		}
 		if !matchesProxy {
 			return service.ErrOllamaCloudUsageIdentityChanged
 		}
-		members, err := lockOllamaCloudUsageGroup(txCtx, client, account, apiKey)
+		members, err := r.lockOllamaCloudUsageGroup(txCtx, client, account, apiKey)
 		if err != nil {
 			return err
 		}
@@ -244,9 +244,9 @@ func (r *accountRepository) updateOllamaCloudUsageGroup(
 				updated_at = NOW()
 			WHERE deleted_at IS NULL
 				AND `+ollamaCloudUsageEligibleSQL+`
-				AND credentials ->> 'api_key' = $2
+				AND `+r.ollamaCloudAPIKeySQL()+` = $2
 				AND id = ANY($3)
-		`, string(encoded), apiKey, pq.Array(memberIDs))
+		`, string(encoded), r.ollamaCloudAPIKeyParam(apiKey), pq.Array(memberIDs))
 		if err != nil {
 			return err
 		}
@@ -277,13 +277,13 @@ func (r *accountRepository) updateOllamaCloudUsageGroup(
 	return tx.Commit()
 }
 
-func lockOllamaCloudUsageGroup(
+func (r *accountRepository) lockOllamaCloudUsageGroup(
 	ctx context.Context,
 	client *dbent.Client,
 	account *service.Account,
 	apiKey string,
 ) ([]lockedOllamaCloudUsageMember, error) {
-	credentials, err := json.Marshal(normalizeJSONMap(account.Credentials))
+	credentials, err := r.sealAccountCredentialsJSON(ctx, client, account.ID, account.Credentials)
 	if err != nil {
 		return nil, err
 	}
@@ -305,10 +305,10 @@ func lockOllamaCloudUsageGroup(
 		FROM accounts
 		WHERE deleted_at IS NULL
 			AND `+ollamaCloudUsageEligibleSQL+`
-			AND credentials ->> 'api_key' = $1
+			AND `+r.ollamaCloudAPIKeySQL()+` = $1
 		ORDER BY id
 		FOR NO KEY UPDATE
-	`, apiKey, account.ID, account.Platform, account.Type, string(credentials), proxyID)
+	`, r.ollamaCloudAPIKeyParam(apiKey), account.ID, account.Platform, account.Type, credentials, proxyID)
 	if err != nil {
 		return nil, err
 	}
@@ -425,7 +425,7 @@ func (r *accountRepository) ListDueOllamaCloudUsageAccounts(
 	rows, err := r.sql.QueryContext(ctx, `
 		WITH eligible AS (
 			SELECT id,
-				credentials ->> 'api_key' AS api_key,
+				`+r.ollamaCloudAPIKeySQL()+` AS api_key,
 				last_used_at,
 				extra -> 'ollama_cloud_usage_snapshot' AS snapshot
 			FROM accounts
@@ -435,13 +435,13 @@ func (r *accountRepository) ListDueOllamaCloudUsageAccounts(
 				AND jsonb_typeof(extra -> 'ollama_cloud_usage_session') = 'string'
 				AND extra @> '{"ollama_cloud_usage_auto_refresh": true}'::jsonb
 		), group_activity AS (
-			SELECT credentials ->> 'api_key' AS api_key,
+			SELECT `+r.ollamaCloudAPIKeySQL()+` AS api_key,
 				MAX(last_used_at) AS group_last_used_at
 			FROM accounts
 			WHERE deleted_at IS NULL
 				AND `+ollamaCloudUsageEligibleSQL+`
 				AND jsonb_typeof(credentials -> 'api_key') = 'string'
-			GROUP BY credentials ->> 'api_key'
+			GROUP BY `+r.ollamaCloudAPIKeySQL()+`
 		), joined AS (
 			SELECT e.id, e.api_key, e.snapshot, g.group_last_used_at,
 				e.snapshot #>> '{status}' AS status,
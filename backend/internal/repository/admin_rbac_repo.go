@@ -0,0 +1,292 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+)
+
+// adminRBACRepository 自定义管理员角色、角色分配与具名 Token 仓储（raw SQL）。
+type adminRBACRepository struct {
+	db *sql.DB
+}
+
+func NewAdminRBACRepository(db *sql.DB) service.AdminRBACRepository {
+	return &adminRBACRepository{db: db}
+}
+
+type adminRBACScanner interface {
+	Scan(dest ...any) error
+}
+
+func (r *adminRBACRepository) ListCustomRoles(ctx context.Context) ([]service.AdminRole, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT name, description, permissions, created_at, updated_at
+		FROM admin_roles
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list admin roles: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	roles := make([]service.AdminRole, 0)
+	for rows.Next() {
+		role, scanErr := scanAdminRole(rows)
+		if scanErr != nil {
+			return nil, fmt.Errorf("scan admin role: %w", scanErr)
+		}
+		roles = append(roles, *role)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("list admin roles: %w", err)
+	}
+	return roles, nil
+}
+
+func (r *adminRBACRepository) GetCustomRole(ctx context.Context, name string) (*service.AdminRole, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT name, description, permissions, created_at, updated_at
+		FROM admin_roles
+		WHERE name = $1
+	`, name)
+	role, err := scanAdminRole(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrAdminRoleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get admin role: %w", err)
+	}
+	return role, nil
+}
+
+func (r *adminRBACRepository) UpsertCustomRole(ctx context.Context, role *service.AdminRole) error {
+	permissions, err := json.Marshal(role.Permissions)
+	if err != nil {
+		return fmt.Errorf("encode admin role permissions: %w", err)
+	}
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO admin_roles (name, description, permissions)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET
+			description = EXCLUDED.description,
+			permissions = EXCLUDED.permissions,
+			updated_at = NOW()
+		RETURNING created_at, updated_at
+	`, role.Name, role.Description, string(permissions)).Scan(&role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("upsert admin role: %w", err)
+	}
+	return nil
+}
+
+func (r *adminRBACRepository) DeleteCustomRole(ctx context.Context, name string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM admin_roles WHERE name = $1`, name)
+	if err != nil {
+		return fmt.Errorf("delete admin role: %w", err)
+	}
+	return requireAdminRBACAffected(result, service.ErrAdminRoleNotFound)
+}
+
+func (r *adminRBACRepository) CountRoleAssignments(ctx context.Context, name string) (int64, error) {
+	var count int64
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM admin_role_assignments WHERE role_name = $1
+	`, name).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count admin role assignments: %w", err)
+	}
+	return count, nil
+}
+
+func (r *adminRBACRepository) GetUserRole(ctx context.Context, userID int64) (string, error) {
+	var role string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT role_name FROM admin_role_assignments WHERE user_id = $1
+	`, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get admin role assignment: %w", err)
+	}
+	return role, nil
+}
+
+func (r *adminRBACRepository) SetUserRole(ctx context.Context, userID int64, role string) error {
+	if role == "" {
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM admin_role_assignments WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("clear admin role assignment: %w", err)
+		}
+		return nil
+	}
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO admin_role_assignments (user_id, role_name)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET role_name = EXCLUDED.role_name, updated_at = NOW()
+	`, userID, role); err != nil {
+		return fmt.Errorf("set admin role assignment: %w", err)
+	}
+	return nil
+}
+
+func (r *adminRBACRepository) ListRoleAssignments(ctx context.Context) (map[int64]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT user_id, role_name FROM admin_role_assignments`)
+	if err != nil {
+		return nil, fmt.Errorf("list admin role assignments: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make(map[int64]string)
+	for rows.Next() {
+		var (
+			userID int64
+			role   string
+		)
+		if err := rows.Scan(&userID, &role); err != nil {
+			return nil, fmt.Errorf("scan admin role assignment: %w", err)
+		}
+		out[userID] = role
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("list admin role assignments: %w", err)
+	}
+	return out, nil
+}
+
+const adminAPITokenColumns = `id, name, token_prefix, scopes, created_by, expires_at,
+last_used_at, last_used_ip, revoked_at, created_at, updated_at`
+
+func (r *adminRBACRepository) CreateToken(ctx context.Context, token *service.AdminAPIToken, tokenHash string) error {
+	scopes, err := json.Marshal(token.Scopes)
+	if err != nil {
+		return fmt.Errorf("encode admin token scopes: %w", err)
+	}
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO admin_api_tokens (name, token_hash, token_prefix, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`, token.Name, tokenHash, token.TokenPrefix, string(scopes), token.CreatedBy, token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt, &token.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create admin token: %w", err)
+	}
+	return nil
+}
+
+func (r *adminRBACRepository) GetTokenByHash(ctx context.Context, tokenHash string) (*service.AdminAPIToken, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+adminAPITokenColumns+`
+		FROM admin_api_tokens
+		WHERE token_hash = $1
+	`, tokenHash)
+	token, err := scanAdminAPIToken(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrAdminAPITokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get admin token: %w", err)
+	}
+	return token, nil
+}
+
+func (r *adminRBACRepository) ListTokens(ctx context.Context) ([]service.AdminAPIToken, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+adminAPITokenColumns+`
+		FROM admin_api_tokens
+		ORDER BY id DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list admin tokens: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	tokens := make([]service.AdminAPIToken, 0)
+	for rows.Next() {
+		token, scanErr := scanAdminAPIToken(rows)
+		if scanErr != nil {
+			return nil, fmt.Errorf("scan admin token: %w", scanErr)
+		}
+		tokens = append(tokens, *token)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("list admin tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (r *adminRBACRepository) RevokeToken(ctx context.Context, id int64, revokedAt time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE admin_api_tokens
+		SET revoked_at = COALESCE(revoked_at, $2), updated_at = NOW()
+		WHERE id = $1
+	`, id, revokedAt)
+	if err != nil {
+		return fmt.Errorf("revoke admin token: %w", err)
+	}
+	return requireAdminRBACAffected(result, service.ErrAdminAPITokenNotFound)
+}
+
+func (r *adminRBACRepository) TouchToken(ctx context.Context, id int64, usedAt time.Time, ip string) error {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE admin_api_tokens SET last_used_at = $2, last_used_ip = $3 WHERE id = $1
+	`, id, usedAt, truncateString(ip, 64)); err != nil {
+		return fmt.Errorf("touch admin token: %w", err)
+	}
+	return nil
+}
+
+func scanAdminRole(scanner adminRBACScanner) (*service.AdminRole, error) {
+	var (
+		role        service.AdminRole
+		permissions []byte
+	)
+	if err := scanner.Scan(&role.Name, &role.Description, &permissions, &role.CreatedAt, &role.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(permissions, &role.Permissions); err != nil {
+		return nil, fmt.Errorf("decode admin role permissions: %w", err)
+	}
+	return &role, nil
+}
+
+func scanAdminAPIToken(scanner adminRBACScanner) (*service.AdminAPIToken, error) {
+	var (
+		token  service.AdminAPIToken
+		scopes []byte
+	)
+	if err := scanner.Scan(
+		&token.ID,
+		&token.Name,
+		&token.TokenPrefix,
+		&scopes,
+		&token.CreatedBy,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.LastUsedIP,
+		&token.RevokedAt,
+		&token.CreatedAt,
+		&token.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(scopes, &token.Scopes); err != nil {
+		return nil, fmt.Errorf("decode admin token scopes: %w", err)
+	}
+	return &token, nil
+}
+
+func requireAdminRBACAffected(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
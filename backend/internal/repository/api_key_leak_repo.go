@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/lib/pq"
+)
+
+// apiKeyLeakRepository API Key 泄露检测：usage_logs 窗口聚合、处置策略与检测事件（raw SQL）。
+type apiKeyLeakRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyLeakRepository(db *sql.DB) service.APIKeyLeakRepository {
+	return &apiKeyLeakRepository{db: db}
+}
+
+type apiKeyLeakScanner interface {
+	Scan(dest ...any) error
+}
+
+// ListKeyUsageSince 只聚合仍处于 active 状态的未删除 Key；已禁用的 Key 无需再检测。
+func (r *apiKeyLeakRepository) ListKeyUsageSince(ctx context.Context, since time.Time, maxIPs int) ([]service.APIKeyLeakKeyUsage, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT ul.api_key_id,
+		       ak.user_id,
+		       COUNT(*),
+		       COUNT(DISTINCT NULLIF(ul.ip_address, '')),
+		       COUNT(DISTINCT NULLIF(ul.user_agent, '')),
+		       COALESCE(SUM(ul.actual_cost), 0),
+		       COALESCE((ARRAY_AGG(DISTINCT ul.ip_address) FILTER (WHERE ul.ip_address IS NOT NULL AND ul.ip_address <> ''))[1:$2], '{}')
+		FROM usage_logs ul
+		JOIN api_keys ak ON ak.id = ul.api_key_id
+		WHERE ul.created_at >= $1
+		  AND ak.deleted_at IS NULL
+		  AND ak.status = $3
+		GROUP BY ul.api_key_id, ak.user_id`, since, maxIPs, service.StatusAPIKeyActive)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate api key usage: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]service.APIKeyLeakKeyUsage, 0)
+	for rows.Next() {
+		var usage service.APIKeyLeakKeyUsage
+		var ips []string
+		if err := rows.Scan(&usage.APIKeyID, &usage.UserID, &usage.RequestCount, &usage.DistinctIPs, &usage.DistinctUserAgents, &usage.Spend, pq.Array(&ips)); err != nil {
+			return nil, fmt.Errorf("scan api key usage: %w", err)
+		}
+		usage.IPs = ips
+		out = append(out, usage)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate api key usage: %w", err)
+	}
+	return out, nil
+}
+
+func (r *apiKeyLeakRepository) SumSpendByKeys(ctx context.Context, apiKeyIDs []int64, from, to time.Time) (map[int64]float64, error) {
+	out := make(map[int64]float64, len(apiKeyIDs))
+	if len(apiKeyIDs) == 0 {
+		return out, nil
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT api_key_id, COALESCE(SUM(actual_cost), 0)
+		FROM usage_logs
+		WHERE api_key_id = ANY($1) AND created_at >= $2 AND created_at < $3
+		GROUP BY api_key_id`, pq.Array(apiKeyIDs), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("sum api key baseline spend: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var id int64
+		var spend float64
+		if err := rows.Scan(&id, &spend); err != nil {
+			return nil, fmt.Errorf("scan api key baseline spend: %w", err)
+		}
+		out[id] = spend
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate api key baseline spend: %w", err)
+	}
+	return out, nil
+}
+
+func (r *apiKeyLeakRepository) ListTopIPsByKey(ctx context.Context, apiKeyID int64, from, to time.Time, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT ip_address
+		FROM usage_logs
+		WHERE api_key_id = $1 AND created_at >= $2 AND created_at < $3
+		  AND ip_address IS NOT NULL AND ip_address <> ''
+		GROUP BY ip_address
+		ORDER BY COUNT(*) DESC, ip_address
+		LIMIT $4`, apiKeyID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list api key baseline ips: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	out := make([]string, 0)
+	for rows.Next() {
+		var ip string
+		if err := rows.Scan(&ip); err != nil {
+			return nil, fmt.Errorf("scan api key baseline ip: %w", err)
+		}
+		out = append(out, ip)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate api key baseline ips: %w", err)
+	}
+	return out, nil
+}
+
+func (r *apiKeyLeakRepository) LatestEventTimes(ctx context.Context, apiKeyIDs []int64) (map[int64]time.Time, error) {
+	out := make(map[int64]time.Time, len(apiKeyIDs))
+	if len(apiKeyIDs) == 0 {
+		return out, nil
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT api_key_id, MAX(created_at)
+		FROM api_key_leak_events
+		WHERE api_key_id = ANY($1)
+		GROUP BY api_key_id`, pq.Array(apiKeyIDs))
+	if err != nil {
+		return nil, fmt.Errorf("list latest api key leak events: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var id int64
+		var at time.Time
+		if err := rows.Scan(&id, &at); err != nil {
+			return nil, fmt.Errorf("scan latest api key leak event: %w", err)
+		}
+		out[id] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate latest api key leak events: %w", err)
+	}
+	return out, nil
+}
+
+func (r *apiKeyLeakRepository) InsertEvent(ctx context.Context, event *service.APIKeyLeakEvent) error {
+	signals, err := json.Marshal(event.Signals)
+	if err != nil {
+		return fmt.Errorf("marshal api key leak signals: %w", err)
+	}
+	if err := r.db.QueryRowContext(ctx, `
+		INSERT INTO api_key_leak_events (api_key_id, user_id, reasons, signals, action, outcome, pinned_ips, window_start, window_end)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at`,
+		event.APIKeyID, event.UserID, pq.Array(event.Reasons), signals, event.Action, event.Outcome,
+		pq.Array(nonNilStrings(event.PinnedIPs)), event.WindowStart, event.WindowEnd,
+	).Scan(&event.ID, &event.CreatedAt); err != nil {
+		return fmt.Errorf("insert api key leak event: %w", err)
+	}
+	return nil
+}
+
+func (r *apiKeyLeakRepository) ListEvents(ctx context.Context, filter service.APIKeyLeakEventFilter, params pagination.PaginationParams) ([]service.APIKeyLeakEvent, *pagination.PaginationResult, error) {
+	where := " WHERE 1=1"
+	args := make([]any, 0, 4)
+	if filter.UserID > 0 {
+		args = append(args, filter.UserID)
+		where += fmt.Sprintf(" AND e.user_id = $%d", len(args))
+	}
+	if filter.APIKeyID > 0 {
+		args = append(args, filter.APIKeyID)
+		where += fmt.Sprintf(" AND e.api_key_id = $%d", len(args))
+	}
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM api_key_leak_events e"+where, args...).Scan(&total); err != nil {
+		return nil, nil, fmt.Errorf("count api key leak events: %w", err)
+	}
+
+	query := `
+		SELECT e.id, e.api_key_id, e.user_id, COALESCE(ak.name, ''), COALESCE(ak.key_prefix, ''),
+		       e.reasons, e.signals, e.action, e.outcome, e.pinned_ips, e.window_start, e.window_end, e.created_at
+		FROM api_key_leak_events e
+		LEFT JOIN api_keys ak ON ak.id = e.api_key_id` + where +
+		fmt.Sprintf(" ORDER BY e.created_at DESC, e.id DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	rows, err := r.db.QueryContext(ctx, query, append(args, params.Limit(), params.Offset())...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list api key leak events: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	events := make([]service.APIKeyLeakEvent, 0)
+	for rows.Next() {
+		event, err := scanAPIKeyLeakEvent(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		events = append(events, *event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("iterate api key leak events: %w", err)
+	}
+	return events, paginationResultFromTotal(total, params), nil
+}
+
+func scanAPIKeyLeakEvent(row apiKeyLeakScanner) (*service.APIKeyLeakEvent, error) {
+	var event service.APIKeyLeakEvent
+	var reasons, pinned []string
+	var signals []byte
+	if err := row.Scan(&event.ID, &event.APIKeyID, &event.UserID, &event.KeyName, &event.KeyPrefix,
+		pq.Array(&reasons), &signals, &event.Action, &event.Outcome, pq.Array(&pinned),
+		&event.WindowStart, &event.WindowEnd, &event.CreatedAt); err != nil {
+		return nil, fmt.Errorf("scan api key leak event: %w", err)
+	}
+	if len(signals) > 0 {
+		if err := json.Unmarshal(signals, &event.Signals); err != nil {
+			return nil, fmt.Errorf("decode api key leak signals: %w", err)
+		}
+	}
+	event.Reasons = reasons
+	event.PinnedIPs = pinned
+	return &event, nil
+}
+
+func (r *apiKeyLeakRepository) GetPolicy(ctx context.Context, userID int64) (*service.APIKeyLeakPolicy, error) {
+	policy := &service.APIKeyLeakPolicy{UserID: userID}
+	var updatedAt time.Time
+	err := r.db.QueryRowContext(ctx, `
+		SELECT action, updated_at FROM api_key_leak_policies WHERE user_id = $1`, userID,
+	).Scan(&policy.Action, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get api key leak policy: %w", err)
+	}
+	policy.UpdatedAt = &updatedAt
+	return policy, nil
+}
+
+func (r *apiKeyLeakRepository) UpsertPolicy(ctx context.Context, userID int64, action string) (*service.APIKeyLeakPolicy, error) {
+	policy := &service.APIKeyLeakPolicy{UserID: userID}
+	var updatedAt time.Time
+	if err := r.db.QueryRowContext(ctx, `
+		INSERT INTO api_key_leak_policies (user_id, action, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET action = EXCLUDED.action, updated_at = NOW()
+		RETURNING action, updated_at`, userID, action,
+	).Scan(&policy.Action, &updatedAt); err != nil {
+		return nil, fmt.Errorf("upsert api key leak policy: %w", err)
+	}
+	policy.UpdatedAt = &updatedAt
+	return policy, nil
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
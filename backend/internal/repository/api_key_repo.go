@@ -13,8 +13,10 @@ import (
 	dbent "github.com/Wei-Shaw/sub2api/ent"
 	"github.com/Wei-Shaw/sub2api/ent/apikey"
 	"github.com/Wei-Shaw/sub2api/ent/group"
+	"github.com/Wei-Shaw/sub2api/ent/predicate"
 	"github.com/Wei-Shaw/sub2api/ent/schema/mixins"
 	"github.com/Wei-Shaw/sub2api/ent/user"
+	"github.com/Wei-Shaw/sub2api/internal/config"
 	"github.com/Wei-Shaw/sub2api/internal/service"
 	"github.com/lib/pq"
 
@@ -27,12 +29,28 @@ import (
 type apiKeyRepository struct {
 	client *dbent.Client
 	sql    sqlExecutor
+	// hasher 非空时 Key 以 HMAC 哈希形态存储；为空时保持历史的明文存储（仅测试使用）。
+	hasher *service.APIKeyHasher
 }
 
 func NewAPIKeyRepository(client *dbent.Client, sqlDB *sql.DB) service.APIKeyRepository {
 	return newAPIKeyRepositoryWithSQL(client, sqlDB)
 }
 
+// ProvideAPIKeyRepository 构造以哈希形态存储 Key 的仓储，供依赖注入使用。
+func ProvideAPIKeyRepository(client *dbent.Client, sqlDB *sql.DB, cfg *config.Config) service.APIKeyRepository {
+	repo := newAPIKeyRepositoryWithSQL(client, sqlDB)
+	repo.hasher = service.NewAPIKeyHasher(cfg)
+	return repo
+}
+
+// ProvideAPIKeyHashBackfillRepository 构造历史明文 Key 回填所用的仓储。
+func ProvideAPIKeyHashBackfillRepository(client *dbent.Client, sqlDB *sql.DB, cfg *config.Config) service.APIKeyHashBackfillRepository {
+	repo := newAPIKeyRepositoryWithSQL(client, sqlDB)
+	repo.hasher = service.NewAPIKeyHasher(cfg)
+	return repo
+}
+
 func newAPIKeyRepositoryWithSQL(client *dbent.Client, sqlq sqlExecutor) *apiKeyRepository {
 	return &apiKeyRepository{client: client, sql: sqlq}
 }
@@ -43,9 +61,18 @@ func (r *apiKeyRepository) activeQuery() *dbent.APIKeyQuery {
 }
 
 func (r *apiKeyRepository) Create(ctx context.Context, key *service.APIKey) error {
+	storedKey := key.Key
+	if r.hasher != nil {
+		storedKey = r.hasher.StoredKey(key.Key)
+	}
+	keyPrefix := key.KeyPrefix
+	if keyPrefix == "" && !service.IsHashedAPIKey(key.Key) {
+		keyPrefix = service.APIKeyDisplayPrefix(key.Key)
+	}
 	builder := r.client.APIKey.Create().
 		SetUserID(key.UserID).
-		SetKey(key.Key).
+		SetKey(storedKey).
+		SetKeyPrefix(keyPrefix).
 		SetName(key.Name).
 		SetStatus(key.Status).
 		SetNillableGroupID(key.GroupID).
@@ -67,6 +94,7 @@ func (r *apiKeyRepository) Create(ctx context.Context, key *service.APIKey) erro
 	created, err := builder.Save(ctx)
 	if err == nil {
 		key.ID = created.ID
+		key.KeyPrefix = created.KeyPrefix
 		key.LastUsedAt = created.LastUsedAt
 		key.CreatedAt = created.CreatedAt
 		key.UpdatedAt = created.UpdatedAt
@@ -86,7 +114,7 @@ func (r *apiKeyRepository) GetByID(ctx context.Context, id int64) (*service.APIK
 		}
 		return nil, err
 	}
-	return apiKeyEntityToService(m), nil
+	return r.apiKeyToService(m), nil
 }
 
 // GetKeyAndOwnerID 根据 API Key ID 获取其 key 与所有者（用户）ID。
@@ -108,9 +136,25 @@ func (r *apiKeyRepository) GetKeyAndOwnerID(ctx context.Context, id int64) (stri
 	return m.Key, m.UserID, nil
 }
 
+// keyLookupPredicate 按明文 Key 匹配：哈希形态，或尚未回填的历史明文。
+// 调用方传入的哈希形态本身不是合法凭证，返回 false 由调用方按未找到处理。
+func (r *apiKeyRepository) keyLookupPredicate(key string) (predicate.APIKey, bool) {
+	if r.hasher == nil {
+		return apikey.KeyEQ(key), true
+	}
+	if service.IsHashedAPIKey(key) {
+		return nil, false
+	}
+	return apikey.KeyIn(r.hasher.StoredKey(key), key), true
+}
+
 func (r *apiKeyRepository) GetByKey(ctx context.Context, key string) (*service.APIKey, error) {
+	lookup, ok := r.keyLookupPredicate(key)
+	if !ok {
+		return nil, service.ErrAPIKeyNotFound
+	}
 	m, err := r.activeQuery().
-		Where(apikey.KeyEQ(key)).
+		Where(lookup).
 		WithUser(func(q *dbent.UserQuery) {
 			q.WithAllowedGroups(func(gq *dbent.GroupQuery) {
 				gq.Select(group.FieldID)
@@ -124,12 +168,45 @@ func (r *apiKeyRepository) GetByKey(ctx context.Context, key string) (*service.A
 		}
 		return nil, err
 	}
-	return apiKeyEntityToService(m), nil
+	return r.apiKeyToService(m), nil
 }
 
 func (r *apiKeyRepository) GetByKeyForAuth(ctx context.Context, key string) (*service.APIKey, error) {
+	lookup, ok := r.keyLookupPredicate(key)
+	if !ok {
+		return nil, service.ErrAPIKeyNotFound
+	}
+	return r.getForAuth(ctx, lookup)
+}
+
+// GetByStoredKeyForAuth 按存储形态（哈希）加载认证视图，供派生令牌定位父 Key。
+// 只匹配哈希形态：尚未回填的历史明文行无法由摘要反查，这类 Key 回填后才能签发可用的令牌。
+func (r *apiKeyRepository) GetByStoredKeyForAuth(ctx context.Context, storedKey string) (*service.APIKey, error) {
+	if !service.IsHashedAPIKey(storedKey) {
+		return nil, service.ErrAPIKeyNotFound
+	}
+	return r.getForAuth(ctx, apikey.KeyEQ(storedKey))
+}
+
+// SetTokenNonce 更新派生令牌签名 nonce，吊销该 Key 已签发的全部派生令牌。
+func (r *apiKeyRepository) SetTokenNonce(ctx context.Context, id int64, nonce string) error {
+	affected, err := r.client.APIKey.Update().
+		Where(apikey.IDEQ(id), apikey.DeletedAtIsNil()).
+		SetTokenNonce(nonce).
+		SetUpdatedAt(time.Now()).
+		Save(ctx)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return service.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) getForAuth(ctx context.Context, lookup predicate.APIKey) (*service.APIKey, error) {
 	m, err := r.activeQuery().
-		Where(apikey.KeyEQ(key)).
+		Where(lookup).
 		Select(
 			apikey.FieldID,
 			apikey.FieldUserID,
@@ -144,6 +221,7 @@ func (r *apiKeyRepository) GetByKeyForAuth(ctx context.Context, key string) (*se
 			apikey.FieldRateLimit5h,
 			apikey.FieldRateLimit1d,
 			apikey.FieldRateLimit7d,
+			apikey.FieldTokenNonce,
 		).
 		WithUser(func(q *dbent.UserQuery) {
 			q.Select(
@@ -198,6 +276,8 @@ func (r *apiKeyRepository) GetByKeyForAuth(ctx context.Context, key string) (*se
 				group.FieldAudioRealtimePricePerMin,
 				group.FieldAudioTtsPricePerMillionChars,
 				group.FieldAudioSttPricePerHour,
+				group.FieldModerationPricePerCall,
+				group.FieldModerationPricePerMillionTokens,
 				group.FieldLongContextPricingEnabled,
 				group.FieldModelPricing,
 				group.FieldClaudeCodeOnly,
@@ -225,6 +305,11 @@ func (r *apiKeyRepository) GetByKeyForAuth(ctx context.Context, key string) (*se
 				group.FieldProfitControlEnabled,
 				group.FieldProfitMinMargin,
 				group.FieldProfitSafetyBuffer,
+				group.FieldPromptPrefixRoutingEnabled,
+				group.FieldPromptPrefixMessages,
+				group.FieldStreamResumeEnabled,
+				group.FieldContextCompactionMode,
+				group.FieldContextCompactionModel,
 			)
 		}).
 		Only(ctx)
@@ -234,7 +319,7 @@ func (r *apiKeyRepository) GetByKeyForAuth(ctx context.Context, key string) (*se
 		}
 		return nil, err
 	}
-	return apiKeyEntityToService(m), nil
+	return r.apiKeyToService(m), nil
 }
 
 func (r *apiKeyRepository) Update(ctx context.Context, key *service.APIKey, fields service.APIKeyUpdateFields) error {
@@ -431,9 +516,14 @@ func (r *apiKeyRepository) apiKeyListByUserIDQuery(userID int64, filters service
 	q := r.activeQuery().Where(apikey.UserIDEQ(userID))
 
 	if filters.Search != "" {
+		searchKey := apikey.KeyContainsFold(filters.Search)
+		if r.hasher != nil {
+			// 哈希存储后只能按展示前缀搜索。
+			searchKey = apikey.KeyPrefixContainsFold(filters.Search)
+		}
 		q = q.Where(apikey.Or(
 			apikey.NameContainsFold(filters.Search),
-			apikey.KeyContainsFold(filters.Search),
+			searchKey,
 		))
 	}
 	if filters.Status != "" {
@@ -473,7 +563,7 @@ func (r *apiKeyRepository) ListByUserID(ctx context.Context, userID int64, param
 
 	outKeys := make([]service.APIKey, 0, len(keys))
 	for i := range keys {
-		outKeys = append(outKeys, *apiKeyEntityToService(keys[i]))
+		outKeys = append(outKeys, *r.apiKeyToService(keys[i]))
 	}
 	if err := r.attachLastUsedIPs(ctx, outKeys); err != nil {
 		return nil, nil, err
@@ -493,7 +583,7 @@ func (r *apiKeyRepository) ListAllByUserID(ctx context.Context, userID int64, fi
 
 	outKeys := make([]service.APIKey, 0, len(keys))
 	for i := range keys {
-		outKeys = append(outKeys, *apiKeyEntityToService(keys[i]))
+		outKeys = append(outKeys, *r.apiKeyToService(keys[i]))
 	}
 	if err := r.attachLastUsedIPs(ctx, outKeys); err != nil {
 		return nil, err
@@ -610,10 +700,61 @@ func (r *apiKeyRepository) CountByUserID(ctx context.Context, userID int64) (int
 }
 
 func (r *apiKeyRepository) ExistsByKey(ctx context.Context, key string) (bool, error) {
-	count, err := r.activeQuery().Where(apikey.KeyEQ(key)).Count(ctx)
+	lookup, ok := r.keyLookupPredicate(key)
+	if !ok {
+		return false, nil
+	}
+	count, err := r.activeQuery().Where(lookup).Count(ctx)
 	return count > 0, err
 }
 
+// HashLegacyAPIKeys 把一批仍以明文存储的 Key 改写为哈希形态（含软删除记录，跳过墓碑）。
+// 以原值做 CAS，避免与并发的删除改写冲突。
+func (r *apiKeyRepository) HashLegacyAPIKeys(ctx context.Context, limit int) (int, error) {
+	if r.hasher == nil {
+		return 0, nil
+	}
+	ctx = mixins.SkipSoftDelete(ctx)
+	rows, err := r.client.APIKey.Query().
+		Where(
+			apikey.Not(apikey.KeyHasPrefix(service.APIKeyHashScheme)),
+			apikey.Not(apikey.KeyHasPrefix("__deleted__")),
+		).
+		Order(dbent.Asc(apikey.FieldID)).
+		Limit(limit).
+		Select(apikey.FieldID, apikey.FieldKey, apikey.FieldKeyPrefix).
+		All(ctx)
+	if err != nil {
+		return 0, err
+	}
+	hashed := 0
+	for _, row := range rows {
+		update := r.client.APIKey.Update().
+			Where(apikey.IDEQ(row.ID), apikey.KeyEQ(row.Key)).
+			SetKey(r.hasher.StoredKey(row.Key))
+		if row.KeyPrefix == "" {
+			update = update.SetKeyPrefix(service.APIKeyDisplayPrefix(row.Key))
+		}
+		affected, err := update.Save(ctx)
+		if err != nil {
+			return hashed, err
+		}
+		hashed += affected
+	}
+	return hashed, nil
+}
+
+// HasLegacyAPIKeys 判断是否仍有未回填的明文 Key（含软删除记录，跳过墓碑）。
+func (r *apiKeyRepository) HasLegacyAPIKeys(ctx context.Context) (bool, error) {
+	ctx = mixins.SkipSoftDelete(ctx)
+	return r.client.APIKey.Query().
+		Where(
+			apikey.Not(apikey.KeyHasPrefix(service.APIKeyHashScheme)),
+			apikey.Not(apikey.KeyHasPrefix("__deleted__")),
+		).
+		Exist(ctx)
+}
+
 func (r *apiKeyRepository) ListByGroupID(ctx context.Context, groupID int64, params pagination.PaginationParams) ([]service.APIKey, *pagination.PaginationResult, error) {
 	q := r.activeQuery().Where(apikey.GroupIDEQ(groupID))
 
@@ -637,7 +778,7 @@ func (r *apiKeyRepository) ListByGroupID(ctx context.Context, groupID int64, par
 
 	outKeys := make([]service.APIKey, 0, len(keys))
 	for i := range keys {
-		outKeys = append(outKeys, *apiKeyEntityToService(keys[i]))
+		outKeys = append(outKeys, *r.apiKeyToService(keys[i]))
 	}
 
 	return outKeys, paginationResultFromTotal(int64(total), params), nil
@@ -697,7 +838,7 @@ func (r *apiKeyRepository) SearchAPIKeys(ctx context.Context, userID int64, keyw
 
 	outKeys := make([]service.APIKey, 0, len(keys))
 	for i := range keys {
-		outKeys = append(outKeys, *apiKeyEntityToService(keys[i]))
+		outKeys = append(outKeys, *r.apiKeyToService(keys[i]))
 	}
 	return outKeys, nil
 }
@@ -749,44 +890,63 @@ func (r *apiKeyRepository) ListKeysByGroupID(ctx context.Context, groupID int64)
 	return keys, nil
 }
 
-// IncrementQuotaUsed 使用 Ent 原子递增 quota_used 字段并返回新值
+// IncrementQuotaUsed 原子递增 quota_used 字段并返回新值。
+// 轮换宽限期内同谱系的 Key 一并累加（见 apiKeyChargeScopeSQL）。
 func (r *apiKeyRepository) IncrementQuotaUsed(ctx context.Context, id int64, amount float64) (float64, error) {
-	updated, err := r.client.APIKey.UpdateOneID(id).
-		Where(apikey.DeletedAtIsNil()).
-		AddQuotaUsed(amount).
-		Save(ctx)
+	state, err := r.incrementQuotaUsed(ctx, id, amount, false)
 	if err != nil {
-		if dbent.IsNotFound(err) {
-			return 0, service.ErrAPIKeyNotFound
-		}
 		return 0, err
 	}
-	return updated.QuotaUsed, nil
+	return state.QuotaUsed, nil
 }
 
 // IncrementQuotaUsedAndGetState atomically increments quota_used, conditionally marks the key
 // as quota_exhausted, and returns the latest quota state in one round trip.
+// Keys sharing a rotation lineage during the grace period are charged together.
 func (r *apiKeyRepository) IncrementQuotaUsedAndGetState(ctx context.Context, id int64, amount float64) (*service.APIKeyQuotaUsageState, error) {
-	query := `
+	return r.incrementQuotaUsed(ctx, id, amount, true)
+}
+
+func (r *apiKeyRepository) incrementQuotaUsed(ctx context.Context, id int64, amount float64, markExhausted bool) (*service.APIKeyQuotaUsageState, error) {
+	statusExpr := "status"
+	args := []any{amount, id}
+	if markExhausted {
+		statusExpr = `CASE
+				WHEN quota > 0 AND quota_used + $1 >= quota THEN $3
+				ELSE status
+			END`
+		args = append(args, service.StatusAPIKeyQuotaExhausted)
+	}
+	rows, err := r.sql.QueryContext(ctx, `
 		UPDATE api_keys
 		SET
 			quota_used = quota_used + $1,
-			status = CASE
-				WHEN quota > 0 AND quota_used + $1 >= quota THEN $2
-				ELSE status
-			END,
+			status = `+statusExpr+`,
 			updated_at = NOW()
-		WHERE id = $3 AND deleted_at IS NULL
-		RETURNING quota_used, quota, key, status
-	`
-
-	state := &service.APIKeyQuotaUsageState{}
-	if err := scanSingleRow(ctx, r.sql, query, []any{amount, service.StatusAPIKeyQuotaExhausted, id}, &state.QuotaUsed, &state.Quota, &state.Key, &state.Status); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, service.ErrAPIKeyNotFound
+		WHERE id IN `+apiKeyChargeScopeSQL("$2")+` AND deleted_at IS NULL
+		RETURNING id, quota_used, quota, key, status
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var state *service.APIKeyQuotaUsageState
+	for rows.Next() {
+		var rowID int64
+		current := &service.APIKeyQuotaUsageState{}
+		if err := rows.Scan(&rowID, &current.QuotaUsed, &current.Quota, &current.Key, &current.Status); err != nil {
+			return nil, err
+		}
+		if rowID == id {
+			state = current
 		}
+	}
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	if state == nil {
+		return nil, service.ErrAPIKeyNotFound
+	}
 	return state, nil
 }
 
@@ -806,7 +966,8 @@ func (r *apiKeyRepository) UpdateLastUsed(ctx context.Context, id int64, usedAt
 }
 
 // IncrementRateLimitUsage atomically increments all rate limit usage counters and initializes
-// window start times via COALESCE if not already set.
+// window start times via COALESCE if not already set. Keys sharing a rotation lineage during
+// the grace period are charged together.
 func (r *apiKeyRepository) IncrementRateLimitUsage(ctx context.Context, id int64, cost float64) error {
 	_, err := r.sql.ExecContext(ctx, `
 		UPDATE api_keys SET
@@ -817,12 +978,13 @@ func (r *apiKeyRepository) IncrementRateLimitUsage(ctx context.Context, id int64
 			window_1d_start = CASE WHEN window_1d_start IS NULL OR window_1d_start + INTERVAL '24 hours' <= NOW() THEN date_trunc('day', NOW()) ELSE window_1d_start END,
 			window_7d_start = CASE WHEN window_7d_start IS NULL OR window_7d_start + INTERVAL '7 days' <= NOW() THEN date_trunc('day', NOW()) ELSE window_7d_start END,
 			updated_at = NOW()
-		WHERE id = $2 AND deleted_at IS NULL`,
+		WHERE id IN `+apiKeyChargeScopeSQL("$2")+` AND deleted_at IS NULL`,
 		cost, id)
 	return err
 }
 
-// ResetRateLimitWindows resets expired rate limit windows atomically.
+// ResetRateLimitWindows resets expired rate limit windows atomically, together with keys
+// sharing a rotation lineage during the grace period so their windows stay aligned.
 func (r *apiKeyRepository) ResetRateLimitWindows(ctx context.Context, id int64) error {
 	_, err := r.sql.ExecContext(ctx, `
 		UPDATE api_keys SET
@@ -833,7 +995,7 @@ func (r *apiKeyRepository) ResetRateLimitWindows(ctx context.Context, id int64)
 			usage_7d = CASE WHEN window_7d_start IS NOT NULL AND window_7d_start + INTERVAL '7 days' <= NOW() THEN 0 ELSE usage_7d END,
 			window_7d_start = CASE WHEN window_7d_start IS NOT NULL AND window_7d_start + INTERVAL '7 days' <= NOW() THEN date_trunc('day', NOW()) ELSE window_7d_start END,
 			updated_at = NOW()
-		WHERE id = $1 AND deleted_at IS NULL`,
+		WHERE id IN `+apiKeyChargeScopeSQL("$1")+` AND deleted_at IS NULL`,
 		id)
 	return err
 }
@@ -863,6 +1025,16 @@ func (r *apiKeyRepository) GetRateLimitData(ctx context.Context, id int64) (resu
 	return data, rows.Err()
 }
 
+// apiKeyToService 在哈希存储模式下把尚未回填的历史明文也转换为哈希形态，
+// 保证读路径不会再把完整 Key 返回给调用方。
+func (r *apiKeyRepository) apiKeyToService(m *dbent.APIKey) *service.APIKey {
+	out := apiKeyEntityToService(m)
+	if out != nil && r.hasher != nil && out.Key != "" && !service.IsHashedAPIKey(out.Key) {
+		out.Key = r.hasher.StoredKey(out.Key)
+	}
+	return out
+}
+
 func apiKeyEntityToService(m *dbent.APIKey) *service.APIKey {
 	if m == nil {
 		return nil
@@ -871,6 +1043,7 @@ func apiKeyEntityToService(m *dbent.APIKey) *service.APIKey {
 		ID:            m.ID,
 		UserID:        m.UserID,
 		Key:           m.Key,
+		KeyPrefix:     m.KeyPrefix,
 		Name:          m.Name,
 		Status:        m.Status,
 		IPWhitelist:   m.IPWhitelist,
@@ -891,6 +1064,7 @@ func apiKeyEntityToService(m *dbent.APIKey) *service.APIKey {
 		Window5hStart: m.Window5hStart,
 		Window1dStart: m.Window1dStart,
 		Window7dStart: m.Window7dStart,
+		TokenNonce:    m.TokenNonce,
 	}
 	if m.Edges.User != nil {
 		out.User = userEntityToService(m.Edges.User)
@@ -992,6 +1166,8 @@ func groupEntityToService(g *dbent.Group) *service.Group {
 		AudioRealtimePricePerMin:        g.AudioRealtimePricePerMin,
 		AudioTTSPricePerMillionChars:    g.AudioTtsPricePerMillionChars,
 		AudioSTTPricePerHour:            g.AudioSttPricePerHour,
+		ModerationPricePerCall:          g.ModerationPricePerCall,
+		ModerationPricePerMillionTokens: g.ModerationPricePerMillionTokens,
 		LongContextPricingEnabled:       g.LongContextPricingEnabled,
 		ModelPricing:                    modelPricing,
 		DefaultValidityDays:             g.DefaultValidityDays,
@@ -1020,6 +1196,11 @@ func groupEntityToService(g *dbent.Group) *service.Group {
 		ProfitControlEnabled:            g.ProfitControlEnabled,
 		ProfitMinMargin:                 g.ProfitMinMargin,
 		ProfitSafetyBuffer:              g.ProfitSafetyBuffer,
+		PromptPrefixRoutingEnabled:      g.PromptPrefixRoutingEnabled,
+		PromptPrefixMessages:            g.PromptPrefixMessages,
+		StreamResumeEnabled:             g.StreamResumeEnabled,
+		ContextCompactionMode:           g.ContextCompactionMode,
+		ContextCompactionModel:          g.ContextCompactionModel,
 		CreatedAt:                       g.CreatedAt,
 		UpdatedAt:                       g.UpdatedAt,
 	}
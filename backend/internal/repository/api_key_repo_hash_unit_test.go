@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	dbent "github.com/Wei-Shaw/sub2api/ent"
+	"github.com/Wei-Shaw/sub2api/ent/enttest"
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/stretchr/testify/require"
+
+	"entgo.io/ent/dialect"
+	entsql "entgo.io/ent/dialect/sql"
+	_ "modernc.org/sqlite"
+)
+
+func newHashedAPIKeyRepoSQLite(t *testing.T) (*apiKeyRepository, *dbent.Client) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", "file:api_key_repo_hash?mode=memory&cache=shared")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = db.Exec("PRAGMA foreign_keys = ON")
+	require.NoError(t, err)
+
+	drv := entsql.OpenDB(dialect.SQLite, db)
+	client := enttest.NewClient(t, enttest.WithOptions(dbent.Driver(drv)))
+	t.Cleanup(func() { _ = client.Close() })
+
+	cfg := &config.Config{}
+	cfg.Security.APIKeyHashPepper = "test-pepper"
+	return &apiKeyRepository{client: client, sql: db, hasher: service.NewAPIKeyHasher(cfg)}, client
+}
+
+func TestAPIKeyRepository_HashedCreateAndLookup(t *testing.T) {
+	repo, client := newHashedAPIKeyRepoSQLite(t)
+	ctx := context.Background()
+	user := mustCreateAPIKeyRepoUser(t, ctx, client, "hashed-create@test.com")
+
+	key := &service.APIKey{UserID: user.ID, Key: "sk-hashed-create-0001", Name: "hashed", Status: service.StatusActive}
+	require.NoError(t, repo.Create(ctx, key))
+	require.Equal(t, "sk-hashed-create-0001", key.Key, "创建后调用方仍持有明文用于一次性展示")
+	require.Equal(t, "sk-hashe", key.KeyPrefix)
+
+	stored, err := client.APIKey.Get(ctx, key.ID)
+	require.NoError(t, err)
+	require.Equal(t, repo.hasher.StoredKey("sk-hashed-create-0001"), stored.Key)
+	require.NotContains(t, stored.Key, "sk-hashed")
+
+	got, err := repo.GetByKey(ctx, "sk-hashed-create-0001")
+	require.NoError(t, err)
+	require.Equal(t, key.ID, got.ID)
+	require.True(t, service.IsHashedAPIKey(got.Key))
+
+	_, err = repo.GetByKeyForAuth(ctx, "sk-hashed-create-0001")
+	require.NoError(t, err)
+
+	// 存储的哈希值本身不能作为凭证使用。
+	_, err = repo.GetByKey(ctx, stored.Key)
+	require.ErrorIs(t, err, service.ErrAPIKeyNotFound)
+	_, err = repo.GetByKeyForAuth(ctx, stored.Key)
+	require.ErrorIs(t, err, service.ErrAPIKeyNotFound)
+	exists, err := repo.ExistsByKey(ctx, stored.Key)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	exists, err = repo.ExistsByKey(ctx, "sk-hashed-create-0001")
+	require.NoError(t, err)
+	require.True(t, exists)
+}
+
+func TestAPIKeyRepository_LegacyPlaintextLookupAndBackfill(t *testing.T) {
+	repo, client := newHashedAPIKeyRepoSQLite(t)
+	ctx := context.Background()
+	user := mustCreateAPIKeyRepoUser(t, ctx, client, "hashed-legacy@test.com")
+
+	legacy, err := client.APIKey.Create().
+		SetUserID(user.ID).
+		SetKey("sk-legacy-plaintext-01").
+		SetName("legacy").
+		SetStatus(service.StatusActive).
+		Save(ctx)
+	require.NoError(t, err)
+	tombstone, err := client.APIKey.Create().
+		SetUserID(user.ID).
+		SetKey("__deleted__1__1").
+		SetName("tombstone").
+		SetStatus(service.StatusActive).
+		Save(ctx)
+	require.NoError(t, err)
+
+	pending, err := repo.HasLegacyAPIKeys(ctx)
+	require.NoError(t, err)
+	require.True(t, pending)
+
+	// 回填前：明文行仍可认证，读路径不返回明文。
+	got, err := repo.GetByKey(ctx, "sk-legacy-plaintext-01")
+	require.NoError(t, err)
+	require.Equal(t, legacy.ID, got.ID)
+	require.Equal(t, repo.hasher.StoredKey("sk-legacy-plaintext-01"), got.Key)
+
+	n, err := repo.HashLegacyAPIKeys(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	n, err = repo.HashLegacyAPIKeys(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	pending, err = repo.HasLegacyAPIKeys(ctx)
+	require.NoError(t, err)
+	require.False(t, pending, "墓碑不计入待回填")
+
+	stored, err := client.APIKey.Get(ctx, legacy.ID)
+	require.NoError(t, err)
+	require.Equal(t, repo.hasher.StoredKey("sk-legacy-plaintext-01"), stored.Key)
+	require.Equal(t, "sk-legac", stored.KeyPrefix)
+	untouched, err := client.APIKey.Get(ctx, tombstone.ID)
+	require.NoError(t, err)
+	require.Equal(t, "__deleted__1__1", untouched.Key)
+
+	got, err = repo.GetByKeyForAuth(ctx, "sk-legacy-plaintext-01")
+	require.NoError(t, err)
+	require.Equal(t, legacy.ID, got.ID)
+}
@@ -0,0 +1,361 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	dbent "github.com/Wei-Shaw/sub2api/ent"
+	"github.com/Wei-Shaw/sub2api/ent/apikey"
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+)
+
+// apiKeyRotationRepository API Key 轮换：新 Key 通过 ent 写入 api_keys，谱系与调度存于 api_key_rotations（raw SQL）。
+type apiKeyRotationRepository struct {
+	client *dbent.Client
+	db     *sql.DB
+	hasher *service.APIKeyHasher
+}
+
+func NewAPIKeyRotationRepository(client *dbent.Client, db *sql.DB, cfg *config.Config) service.APIKeyRotationRepository {
+	return &apiKeyRotationRepository{client: client, db: db, hasher: service.NewAPIKeyHasher(cfg)}
+}
+
+func (r *apiKeyRotationRepository) RotateKey(ctx context.Context, in service.APIKeyRotateInput) (*service.APIKeyRotationResult, error) {
+	tx, err := r.client.Tx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin api key rotation: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	old, err := tx.APIKey.Query().
+		Where(apikey.IDEQ(in.APIKeyID), apikey.UserIDEQ(in.UserID), apikey.DeletedAtIsNil()).
+		ForUpdate().
+		Only(ctx)
+	if err != nil {
+		if dbent.IsNotFound(err) {
+			return nil, service.ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("lock api key: %w", err)
+	}
+	if old.Status == service.StatusAPIKeyDisabled || old.Status == service.StatusAPIKeyExpired ||
+		(old.ExpiresAt != nil && !old.ExpiresAt.After(in.Now)) {
+		return nil, service.ErrAPIKeyRotationNotAllowed
+	}
+
+	lineageID := old.ID
+	var intervalDays int
+	var graceHours sql.NullInt64
+	var successorID sql.NullInt64
+	err = scanSingleRow(ctx, tx.Client(), `
+		SELECT lineage_id, successor_id, schedule_interval_days, schedule_grace_hours
+		FROM api_key_rotations WHERE api_key_id = $1 FOR UPDATE`,
+		[]any{old.ID}, &lineageID, &successorID, &intervalDays, &graceHours)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get api key rotation state: %w", err)
+	}
+	if successorID.Valid {
+		return nil, service.ErrAPIKeyAlreadyRotated
+	}
+
+	// 旧 Key 原本更早过期时不延长其寿命。
+	graceExpiresAt := in.GraceExpiresAt
+	if old.ExpiresAt != nil && old.ExpiresAt.Before(graceExpiresAt) {
+		graceExpiresAt = *old.ExpiresAt
+	}
+
+	// 新 Key 复制旧 Key 的配额与限流用量；宽限期内两者共同计费（见 apiKeyChargeScopeSQL），
+	// 计数保持一致，合计不超过轮换时的剩余额度。
+	builder := tx.APIKey.Create().
+		SetUserID(old.UserID).
+		SetKey(r.hasher.StoredKey(in.NewKey)).
+		SetKeyPrefix(service.APIKeyDisplayPrefix(in.NewKey)).
+		SetName(old.Name).
+		SetStatus(old.Status).
+		SetNillableGroupID(old.GroupID).
+		SetQuota(old.Quota).
+		SetQuotaUsed(old.QuotaUsed).
+		SetNillableExpiresAt(old.ExpiresAt).
+		SetRateLimit5h(old.RateLimit5h).
+		SetRateLimit1d(old.RateLimit1d).
+		SetRateLimit7d(old.RateLimit7d).
+		SetUsage5h(old.Usage5h).
+		SetUsage1d(old.Usage1d).
+		SetUsage7d(old.Usage7d).
+		SetNillableWindow5hStart(old.Window5hStart).
+		SetNillableWindow1dStart(old.Window1dStart).
+		SetNillableWindow7dStart(old.Window7dStart)
+	if len(old.IPWhitelist) > 0 {
+		builder.SetIPWhitelist(old.IPWhitelist)
+	}
+	if len(old.IPBlacklist) > 0 {
+		builder.SetIPBlacklist(old.IPBlacklist)
+	}
+	created, err := builder.Save(ctx)
+	if err != nil {
+		return nil, translatePersistenceError(err, nil, service.ErrAPIKeyExists)
+	}
+
+	updated, err := tx.APIKey.UpdateOneID(old.ID).SetExpiresAt(graceExpiresAt).Save(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("shorten rotated api key expiry: %w", err)
+	}
+
+	if _, err := tx.Client().ExecContext(ctx, `
+		INSERT INTO api_key_rotations (api_key_id, lineage_id, successor_id, rotated_at, grace_expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (api_key_id) DO UPDATE SET
+			successor_id = EXCLUDED.successor_id,
+			rotated_at = EXCLUDED.rotated_at,
+			grace_expires_at = EXCLUDED.grace_expires_at,
+			next_rotation_at = NULL,
+			pending_secret = NULL,
+			updated_at = NOW()`,
+		old.ID, lineageID, created.ID, in.Now, graceExpiresAt); err != nil {
+		return nil, fmt.Errorf("mark api key rotated: %w", err)
+	}
+
+	// 新 Key 沿用旧 Key 的定期轮换设置。
+	var nextRotationAt *time.Time
+	if intervalDays > 0 {
+		at := in.Now.AddDate(0, 0, intervalDays)
+		nextRotationAt = &at
+	}
+	var pendingSecret *string
+	if in.PendingSecret != "" {
+		pendingSecret = &in.PendingSecret
+	}
+	if _, err := tx.Client().ExecContext(ctx, `
+		INSERT INTO api_key_rotations (api_key_id, lineage_id, predecessor_id, schedule_interval_days, schedule_grace_hours, next_rotation_at, pending_secret)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		created.ID, lineageID, old.ID, intervalDays, graceHours, nextRotationAt, pendingSecret); err != nil {
+		return nil, fmt.Errorf("record api key successor: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit api key rotation: %w", err)
+	}
+	return &service.APIKeyRotationResult{
+		Predecessor:    apiKeyEntityToService(updated),
+		Successor:      apiKeyEntityToService(created),
+		LineageID:      lineageID,
+		GraceExpiresAt: graceExpiresAt,
+	}, nil
+}
+
+// apiKeyChargeScopeSQL 返回与 param 所指 Key 共同计费的 Key ID 子查询。
+// 轮换时新 Key 复制了旧 Key 的配额与限流用量；宽限期内同一谱系中仍可使用的 Key
+// （尚未被轮换的最新 Key 与宽限期未结束的旧 Key）每次计费一起累加，相当于共用一份额度，
+// 避免新旧 Key 各自拥有完整的剩余额度。未参与轮换的 Key 只包含自身。
+func apiKeyChargeScopeSQL(param string) string {
+	return `(
+		SELECT ` + param + `::bigint
+		UNION
+		SELECT peer.api_key_id
+		FROM api_key_rotations self
+		JOIN api_key_rotations peer ON peer.lineage_id = self.lineage_id
+		WHERE self.api_key_id = ` + param + `::bigint
+		  AND (peer.successor_id IS NULL OR peer.grace_expires_at > NOW())
+	)`
+}
+
+const apiKeyRotationStateColumns = `
+	r.api_key_id, ak.user_id, r.lineage_id, r.predecessor_id, r.successor_id, r.rotated_at, r.grace_expires_at,
+	r.schedule_interval_days, r.schedule_grace_hours, r.next_rotation_at, r.pending_secret IS NOT NULL`
+
+func scanAPIKeyRotationState(row interface{ Scan(dest ...any) error }) (*service.APIKeyRotationState, error) {
+	var state service.APIKeyRotationState
+	var predecessorID, successorID, graceHours sql.NullInt64
+	var rotatedAt, graceExpiresAt, nextRotationAt sql.NullTime
+	if err := row.Scan(&state.APIKeyID, &state.UserID, &state.LineageID, &predecessorID, &successorID, &rotatedAt, &graceExpiresAt,
+		&state.ScheduleIntervalDays, &graceHours, &nextRotationAt, &state.HasPendingSecret); err != nil {
+		return nil, err
+	}
+	state.PredecessorID = apiKeyRotationNullInt64Ptr(predecessorID)
+	state.SuccessorID = apiKeyRotationNullInt64Ptr(successorID)
+	state.RotatedAt = apiKeyRotationNullTimePtr(rotatedAt)
+	state.GraceExpiresAt = apiKeyRotationNullTimePtr(graceExpiresAt)
+	state.NextRotationAt = apiKeyRotationNullTimePtr(nextRotationAt)
+	if graceHours.Valid {
+		hours := int(graceHours.Int64)
+		state.ScheduleGraceHours = &hours
+	}
+	return &state, nil
+}
+
+func (r *apiKeyRotationRepository) GetState(ctx context.Context, apiKeyID int64) (*service.APIKeyRotationState, error) {
+	state, err := scanAPIKeyRotationState(r.db.QueryRowContext(ctx, `
+		SELECT`+apiKeyRotationStateColumns+`
+		FROM api_key_rotations r
+		JOIN api_keys ak ON ak.id = r.api_key_id
+		WHERE r.api_key_id = $1`, apiKeyID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get api key rotation state: %w", err)
+	}
+	return state, nil
+}
+
+func (r *apiKeyRotationRepository) ListLineage(ctx context.Context, lineageID int64, since time.Time) ([]service.APIKeyLineageMember, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT ak.id, ak.name, ak.key_prefix, ak.status, ak.deleted_at IS NOT NULL, ak.created_at, ak.expires_at,
+		       r.predecessor_id, r.successor_id, r.rotated_at, r.grace_expires_at,
+		       COALESCE(u.requests, 0), COALESCE(u.total_tokens, 0), COALESCE(u.cost, 0), COALESCE(u.actual_cost, 0)
+		FROM api_keys ak
+		LEFT JOIN api_key_rotations r ON r.api_key_id = ak.id
+		LEFT JOIN (
+			SELECT api_key_id,
+			       COUNT(*) AS requests,
+			       SUM(input_tokens + output_tokens + cache_creation_tokens + cache_read_tokens) AS total_tokens,
+			       SUM(total_cost) AS cost,
+			       SUM(actual_cost) AS actual_cost
+			FROM usage_logs
+			WHERE created_at >= $2
+			  AND api_key_id IN (SELECT api_key_id FROM api_key_rotations WHERE lineage_id = $1 UNION SELECT $1::bigint)
+			GROUP BY api_key_id
+		) u ON u.api_key_id = ak.id
+		WHERE ak.id = $1 OR r.lineage_id = $1
+		ORDER BY ak.created_at, ak.id`, lineageID, since)
+	if err != nil {
+		return nil, fmt.Errorf("list api key lineage: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	members := make([]service.APIKeyLineageMember, 0)
+	for rows.Next() {
+		var m service.APIKeyLineageMember
+		var expiresAt, rotatedAt, graceExpiresAt sql.NullTime
+		var predecessorID, successorID sql.NullInt64
+		if err := rows.Scan(&m.APIKeyID, &m.Name, &m.KeyPrefix, &m.Status, &m.Deleted, &m.CreatedAt, &expiresAt,
+			&predecessorID, &successorID, &rotatedAt, &graceExpiresAt,
+			&m.Requests, &m.TotalTokens, &m.Cost, &m.ActualCost); err != nil {
+			return nil, fmt.Errorf("scan api key lineage member: %w", err)
+		}
+		m.ExpiresAt = apiKeyRotationNullTimePtr(expiresAt)
+		m.PredecessorID = apiKeyRotationNullInt64Ptr(predecessorID)
+		m.SuccessorID = apiKeyRotationNullInt64Ptr(successorID)
+		m.RotatedAt = apiKeyRotationNullTimePtr(rotatedAt)
+		m.GraceExpiresAt = apiKeyRotationNullTimePtr(graceExpiresAt)
+		members = append(members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate api key lineage: %w", err)
+	}
+	return members, nil
+}
+
+func (r *apiKeyRotationRepository) UpsertSchedule(ctx context.Context, apiKeyID int64, intervalDays int, graceHours *int, nextRotationAt *time.Time) (*service.APIKeyRotationState, error) {
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO api_key_rotations (api_key_id, lineage_id, schedule_interval_days, schedule_grace_hours, next_rotation_at)
+		VALUES ($1, $1, $2, $3, $4)
+		ON CONFLICT (api_key_id) DO UPDATE SET
+			schedule_interval_days = EXCLUDED.schedule_interval_days,
+			schedule_grace_hours = EXCLUDED.schedule_grace_hours,
+			next_rotation_at = EXCLUDED.next_rotation_at,
+			updated_at = NOW()`,
+		apiKeyID, intervalDays, graceHours, nextRotationAt); err != nil {
+		return nil, fmt.Errorf("upsert api key rotation schedule: %w", err)
+	}
+	return r.GetState(ctx, apiKeyID)
+}
+
+func (r *apiKeyRotationRepository) ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]service.APIKeyRotationState, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT`+apiKeyRotationStateColumns+`
+		FROM api_key_rotations r
+		JOIN api_keys ak ON ak.id = r.api_key_id
+		WHERE r.schedule_interval_days > 0
+		  AND r.successor_id IS NULL
+		  AND r.next_rotation_at <= $1
+		  AND ak.deleted_at IS NULL
+		  AND ak.status = $2
+		ORDER BY r.next_rotation_at
+		LIMIT $3`, now, service.StatusAPIKeyActive, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list due api key rotations: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]service.APIKeyRotationState, 0)
+	for rows.Next() {
+		state, err := scanAPIKeyRotationState(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan due api key rotation: %w", err)
+		}
+		out = append(out, *state)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate due api key rotations: %w", err)
+	}
+	return out, nil
+}
+
+func (r *apiKeyRotationRepository) ListGraceDeadlines(ctx context.Context, now time.Time) (map[int64]time.Time, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT api_key_id, grace_expires_at
+		FROM api_key_rotations
+		WHERE successor_id IS NOT NULL AND grace_expires_at > $1`, now)
+	if err != nil {
+		return nil, fmt.Errorf("list api key grace deadlines: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make(map[int64]time.Time)
+	for rows.Next() {
+		var id int64
+		var deadline time.Time
+		if err := rows.Scan(&id, &deadline); err != nil {
+			return nil, fmt.Errorf("scan api key grace deadline: %w", err)
+		}
+		out[id] = deadline
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate api key grace deadlines: %w", err)
+	}
+	return out, nil
+}
+
+func (r *apiKeyRotationRepository) TakePendingSecret(ctx context.Context, apiKeyID, userID int64) (string, error) {
+	var secret sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+		WITH cur AS (
+			SELECT r.api_key_id, r.pending_secret
+			FROM api_key_rotations r
+			JOIN api_keys ak ON ak.id = r.api_key_id
+			WHERE r.api_key_id = $1 AND ak.user_id = $2 AND ak.deleted_at IS NULL AND r.pending_secret IS NOT NULL
+			FOR UPDATE OF r
+		)
+		UPDATE api_key_rotations r
+		SET pending_secret = NULL, updated_at = NOW()
+		FROM cur
+		WHERE r.api_key_id = cur.api_key_id
+		RETURNING cur.pending_secret`, apiKeyID, userID).Scan(&secret)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("take pending api key secret: %w", err)
+	}
+	return secret.String, nil
+}
+
+func apiKeyRotationNullInt64Ptr(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	out := v.Int64
+	return &out
+}
+
+func apiKeyRotationNullTimePtr(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	out := v.Time
+	return &out
+}
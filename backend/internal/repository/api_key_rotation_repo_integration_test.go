@@ -0,0 +1,90 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+)
+
+func TestAPIKeyRotationRepository_GraceKeysShareQuotaAndRateLimit(t *testing.T) {
+	ctx := context.Background()
+	client := testEntClient(t)
+	rotations := NewAPIKeyRotationRepository(client, integrationDB, &config.Config{})
+	billing := NewUsageBillingRepository(client, integrationDB)
+
+	user := mustCreateUser(t, client, &service.User{
+		Email:        fmt.Sprintf("rotation-quota-%d@example.com", time.Now().UnixNano()),
+		PasswordHash: "hash",
+		Balance:      100,
+	})
+	old := mustCreateApiKey(t, client, &service.APIKey{
+		UserID:      user.ID,
+		Key:         "sk-rotation-old-" + uuid.NewString(),
+		Name:        "rotation",
+		Quota:       1,
+		QuotaUsed:   0.4,
+		RateLimit5h: 1,
+	})
+
+	now := time.Now()
+	rotated, err := rotations.RotateKey(ctx, service.APIKeyRotateInput{
+		APIKeyID:       old.ID,
+		UserID:         user.ID,
+		NewKey:         "sk-rotation-new-" + uuid.NewString(),
+		GraceExpiresAt: now.Add(time.Hour),
+		Now:            now,
+	})
+	require.NoError(t, err)
+	successorID := rotated.Successor.ID
+
+	charge := func(apiKeyID int64, cost float64) *service.UsageBillingApplyResult {
+		t.Helper()
+		result, err := billing.Apply(ctx, &service.UsageBillingCommand{
+			RequestID:           uuid.NewString(),
+			APIKeyID:            apiKeyID,
+			UserID:              user.ID,
+			APIKeyQuotaCost:     cost,
+			APIKeyRateLimitCost: cost,
+		})
+		require.NoError(t, err)
+		require.True(t, result.Applied)
+		return result
+	}
+
+	first := charge(old.ID, 0.3)
+	require.False(t, first.APIKeyQuotaExhausted)
+	require.Equal(t, []int64{successorID}, first.RateLimitPeerAPIKeyIDs)
+
+	second := charge(successorID, 0.3)
+	require.True(t, second.APIKeyQuotaExhausted, "新旧 Key 的花费合计达到剩余额度")
+	require.Equal(t, []int64{old.ID}, second.RateLimitPeerAPIKeyIDs)
+
+	for _, id := range []int64{old.ID, successorID} {
+		var quotaUsed, usage5h float64
+		var status string
+		require.NoError(t, integrationDB.QueryRowContext(ctx,
+			"SELECT quota_used, usage_5h, status FROM api_keys WHERE id = $1", id).Scan(&quotaUsed, &usage5h, &status))
+		require.InDelta(t, 1.0, quotaUsed, 0.000001)
+		require.InDelta(t, 0.6, usage5h, 0.000001)
+		require.Equal(t, service.StatusAPIKeyQuotaExhausted, status, "宽限期内两把 Key 共用一份额度")
+	}
+
+	// 宽限期结束后旧 Key 不再与新 Key 共同计费。
+	_, err = integrationDB.ExecContext(ctx,
+		"UPDATE api_key_rotations SET grace_expires_at = $1 WHERE api_key_id = $2", now.Add(-time.Minute), old.ID)
+	require.NoError(t, err)
+	third := charge(successorID, 0.1)
+	require.Empty(t, third.RateLimitPeerAPIKeyIDs)
+	var oldUsage5h float64
+	require.NoError(t, integrationDB.QueryRowContext(ctx, "SELECT usage_5h FROM api_keys WHERE id = $1", old.ID).Scan(&oldUsage5h))
+	require.InDelta(t, 0.6, oldUsage5h, 0.000001)
+}
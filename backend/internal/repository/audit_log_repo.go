@@ -13,7 +13,8 @@ import (
 )
 
 // auditLogRepository 审计日志仓储（raw SQL，append-only）。
-// 刻意不实现单条删除：审计日志只允许追加、按保留期批量清理、以及带 2FA 的全量清空。
+// 刻意不实现任意删除：审计日志只允许追加，以及归档到对象存储后删除已归档的 id 前缀。
+// 写入经 advisory 锁串行化，逐条计算哈希链，使链顺序与 id 顺序一致。
 type auditLogRepository struct {
 	db *sql.DB
 }
@@ -23,15 +24,48 @@ func NewAuditLogRepository(db *sql.DB) service.AuditLogRepository {
 	return &auditLogRepository{db: db}
 }
 
-const auditLogInsertColumns = `created_at, actor_user_id, actor_email, actor_role, auth_method,
-credential_masked, action, method, path, request_id, client_ip, user_agent,
-request_body, status_code, latency_ms, extra`
+// NewAuditLogExportCursorRepository 创建 SIEM 外送游标仓储。
+func NewAuditLogExportCursorRepository(db *sql.DB) service.AuditLogExportCursorRepository {
+	return &auditLogRepository{db: db}
+}
 
-func auditLogInsertValues(log *service.AuditLog) []any {
+const (
+	auditLogChainLockKey   = "audit_logs.hash_chain"
+	auditLogArchiveLockKey = "audit_logs.archive"
+)
+
+// normalizeAuditLogForInsert 返回落库形态的副本：字段按列宽截断、时间取 UTC 微秒精度、extra 规范化。
+// 哈希必须基于该形态计算，读回后才能重算出相同结果。
+func normalizeAuditLogForInsert(log *service.AuditLog) *service.AuditLog {
 	createdAt := log.CreatedAt
 	if createdAt.IsZero() {
-		createdAt = time.Now().UTC()
-	}
+		createdAt = time.Now()
+	}
+	out := &service.AuditLog{
+		CreatedAt:        createdAt.UTC().Truncate(time.Microsecond),
+		ActorEmail:       truncateString(log.ActorEmail, 255),
+		ActorRole:        truncateString(log.ActorRole, 32),
+		AuthMethod:       truncateString(log.AuthMethod, 32),
+		CredentialMasked: truncateString(log.CredentialMasked, 160),
+		Action:           truncateString(log.Action, 128),
+		Method:           truncateString(log.Method, 16),
+		Path:             truncateString(log.Path, 512),
+		RequestID:        truncateString(log.RequestID, 64),
+		ClientIP:         truncateString(log.ClientIP, 64),
+		UserAgent:        truncateString(log.UserAgent, 512),
+		RequestBody:      log.RequestBody,
+		StatusCode:       log.StatusCode,
+		LatencyMs:        log.LatencyMs,
+		Extra:            service.NormalizeAuditLogExtra(log.Extra),
+	}
+	if log.ActorUserID != nil && *log.ActorUserID > 0 {
+		v := *log.ActorUserID
+		out.ActorUserID = &v
+	}
+	return out
+}
+
+func auditLogInsertValues(log *service.AuditLog) []any {
 	extraJSON := "{}"
 	if len(log.Extra) > 0 {
 		if encoded, err := json.Marshal(log.Extra); err == nil {
@@ -39,25 +73,35 @@ func auditLogInsertValues(log *service.AuditLog) []any {
 		}
 	}
 	return []any{
-		createdAt.UTC(),
+		log.CreatedAt,
 		nullInt64Ptr(log.ActorUserID),
-		truncateString(log.ActorEmail, 255),
-		truncateString(log.ActorRole, 32),
-		truncateString(log.AuthMethod, 32),
-		truncateString(log.CredentialMasked, 160),
-		truncateString(log.Action, 128),
-		truncateString(log.Method, 16),
-		truncateString(log.Path, 512),
-		truncateString(log.RequestID, 64),
-		truncateString(log.ClientIP, 64),
-		truncateString(log.UserAgent, 512),
+		log.ActorEmail,
+		log.ActorRole,
+		log.AuthMethod,
+		log.CredentialMasked,
+		log.Action,
+		log.Method,
+		log.Path,
+		log.RequestID,
+		log.ClientIP,
+		log.UserAgent,
 		log.RequestBody,
 		log.StatusCode,
 		log.LatencyMs,
 		extraJSON,
+		log.PrevHash,
+		log.RowHash,
 	}
 }
 
+// auditLogChainTailQuery 当前链尾哈希：表内最大 id 的 row_hash；表已被归档清空时取最近归档的锚点。
+const auditLogChainTailQuery = `
+SELECT COALESCE(
+  (SELECT row_hash FROM audit_logs ORDER BY id DESC LIMIT 1),
+  (SELECT last_row_hash FROM audit_log_archives ORDER BY last_id DESC LIMIT 1),
+  ''
+)`
+
 func (r *auditLogRepository) BatchInsert(ctx context.Context, logs []*service.AuditLog) (int64, error) {
 	if r == nil || r.db == nil {
 		return 0, fmt.Errorf("nil audit log repository")
@@ -70,11 +114,21 @@ func (r *auditLogRepository) BatchInsert(ctx context.Context, logs []*service.Au
 	if err != nil {
 		return 0, err
 	}
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", advisoryLockHash(auditLogChainLockKey)); err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	var prevHash string
+	if err := tx.QueryRowContext(ctx, auditLogChainTailQuery).Scan(&prevHash); err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+
 	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(
 		"audit_logs",
 		"created_at", "actor_user_id", "actor_email", "actor_role", "auth_method",
 		"credential_masked", "action", "method", "path", "request_id", "client_ip", "user_agent",
-		"request_body", "status_code", "latency_ms", "extra",
+		"request_body", "status_code", "latency_ms", "extra", "prev_hash", "row_hash",
 	))
 	if err != nil {
 		_ = tx.Rollback()
@@ -82,15 +136,23 @@ func (r *auditLogRepository) BatchInsert(ctx context.Context, logs []*service.Au
 	}
 
 	var inserted int64
+	sources := make([]*service.AuditLog, 0, len(logs))
+	stored := make([]*service.AuditLog, 0, len(logs))
 	for _, log := range logs {
 		if log == nil {
 			continue
 		}
-		if _, err := stmt.ExecContext(ctx, auditLogInsertValues(log)...); err != nil {
+		row := normalizeAuditLogForInsert(log)
+		row.PrevHash = prevHash
+		row.RowHash = service.ComputeAuditLogHash(prevHash, row)
+		if _, err := stmt.ExecContext(ctx, auditLogInsertValues(row)...); err != nil {
 			_ = stmt.Close()
 			_ = tx.Rollback()
 			return inserted, err
 		}
+		prevHash = row.RowHash
+		sources = append(sources, log)
+		stored = append(stored, row)
 		inserted++
 	}
 
@@ -106,19 +168,19 @@ func (r *auditLogRepository) BatchInsert(ctx context.Context, logs []*service.Au
 	if err := tx.Commit(); err != nil {
 		return inserted, err
 	}
+	// 回填哈希，便于调用方（如归档留痕）直接返回链信息。
+	for i, log := range sources {
+		log.PrevHash = stored[i].PrevHash
+		log.RowHash = stored[i].RowHash
+	}
 	return inserted, nil
 }
 
 func (r *auditLogRepository) Insert(ctx context.Context, log *service.AuditLog) error {
-	if r == nil || r.db == nil {
-		return fmt.Errorf("nil audit log repository")
-	}
 	if log == nil {
 		return fmt.Errorf("nil audit log")
 	}
-	query := `INSERT INTO audit_logs (` + auditLogInsertColumns + `)
-VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)`
-	_, err := r.db.ExecContext(ctx, query, auditLogInsertValues(log)...)
+	_, err := r.BatchInsert(ctx, []*service.AuditLog{log})
 	return err
 }
 
@@ -192,7 +254,9 @@ const auditLogSelectColumns = `
   COALESCE(l.request_body, ''),
   l.status_code,
   l.latency_ms,
-  COALESCE(l.extra::text, '{}')`
+  COALESCE(l.extra::text, '{}'),
+  COALESCE(l.prev_hash, ''),
+  COALESCE(l.row_hash, '')`
 
 func scanAuditLogRow(scan func(dest ...any) error) (*service.AuditLog, error) {
 	item := &service.AuditLog{}
@@ -216,6 +280,8 @@ func scanAuditLogRow(scan func(dest ...any) error) (*service.AuditLog, error) {
 		&item.StatusCode,
 		&item.LatencyMs,
 		&extraRaw,
+		&item.PrevHash,
+		&item.RowHash,
 	); err != nil {
 		return nil, err
 	}
@@ -321,30 +387,199 @@ func (r *auditLogRepository) Count(ctx context.Context) (int64, error) {
 	return total, nil
 }
 
-func (r *auditLogRepository) TruncateAll(ctx context.Context) error {
+func (r *auditLogRepository) ListAfter(ctx context.Context, afterID, upToID int64, limit int) ([]*service.AuditLog, error) {
 	if r == nil || r.db == nil {
-		return fmt.Errorf("nil audit log repository")
+		return nil, fmt.Errorf("nil audit log repository")
 	}
-	_, err := r.db.ExecContext(ctx, "TRUNCATE TABLE audit_logs")
-	return err
+	if limit <= 0 {
+		limit = 500
+	}
+	query := "SELECT" + auditLogSelectColumns + `
+FROM audit_logs l
+WHERE l.id > $1 AND ($2::bigint <= 0 OR l.id <= $2)
+ORDER BY l.id ASC
+LIMIT $3`
+	rows, err := r.db.QueryContext(ctx, query, afterID, upToID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	logs := make([]*service.AuditLog, 0, limit)
+	for rows.Next() {
+		item, err := scanAuditLogRow(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return logs, nil
 }
 
-func (r *auditLogRepository) DeleteBefore(ctx context.Context, cutoff time.Time, batchSize int) (int64, error) {
+func (r *auditLogRepository) MaxID(ctx context.Context, before *time.Time) (int64, error) {
 	if r == nil || r.db == nil {
 		return 0, fmt.Errorf("nil audit log repository")
 	}
-	if batchSize <= 0 {
-		batchSize = 5000
+	var maxID int64
+	var err error
+	if before != nil {
+		err = r.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM audit_logs WHERE created_at < $1", before.UTC()).Scan(&maxID)
+	} else {
+		err = r.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM audit_logs").Scan(&maxID)
 	}
-	res, err := r.db.ExecContext(ctx, `
-WITH batch AS (
-  SELECT id FROM audit_logs WHERE created_at < $1 ORDER BY id LIMIT $2
-)
-DELETE FROM audit_logs WHERE id IN (SELECT id FROM batch)`, cutoff.UTC(), batchSize)
 	if err != nil {
 		return 0, err
 	}
-	return res.RowsAffected()
+	return maxID, nil
+}
+
+const auditLogArchiveSelectColumns = `id, object_key, first_id, last_id, row_count, first_prev_hash, last_row_hash,
+object_sha256, size_bytes, chain_valid, triggered_by, actor_user_id, created_at`
+
+func (r *auditLogRepository) ListArchives(ctx context.Context) ([]*service.AuditLogArchive, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("nil audit log repository")
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT "+auditLogArchiveSelectColumns+" FROM audit_log_archives ORDER BY id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	archives := make([]*service.AuditLogArchive, 0)
+	for rows.Next() {
+		item := &service.AuditLogArchive{}
+		var actorUserID sql.NullInt64
+		if err := rows.Scan(
+			&item.ID, &item.ObjectKey, &item.FirstID, &item.LastID, &item.RowCount,
+			&item.FirstPrevHash, &item.LastRowHash, &item.ObjectSHA256, &item.SizeBytes,
+			&item.ChainValid, &item.TriggeredBy, &actorUserID, &item.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if actorUserID.Valid {
+			v := actorUserID.Int64
+			item.ActorUserID = &v
+		}
+		archives = append(archives, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return archives, nil
+}
+
+func (r *auditLogRepository) CommitArchive(ctx context.Context, archive *service.AuditLogArchive) (int64, error) {
+	if r == nil || r.db == nil {
+		return 0, fmt.Errorf("nil audit log repository")
+	}
+	if archive == nil || archive.LastID <= 0 {
+		return 0, fmt.Errorf("invalid audit log archive")
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", advisoryLockHash(auditLogArchiveLockKey)); err != nil {
+		return 0, err
+	}
+	var archivedUpTo int64
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(last_id), 0) FROM audit_log_archives").Scan(&archivedUpTo); err != nil {
+		return 0, err
+	}
+	if archivedUpTo >= archive.FirstID {
+		return 0, service.ErrAuditLogArchiveConflict
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+INSERT INTO audit_log_archives (object_key, first_id, last_id, row_count, first_prev_hash, last_row_hash,
+  object_sha256, size_bytes, chain_valid, triggered_by, actor_user_id, created_at)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,NOW())
+RETURNING id, created_at`,
+		archive.ObjectKey, archive.FirstID, archive.LastID, archive.RowCount, archive.FirstPrevHash, archive.LastRowHash,
+		archive.ObjectSHA256, archive.SizeBytes, archive.ChainValid, archive.TriggeredBy, nullInt64Ptr(archive.ActorUserID),
+	).Scan(&archive.ID, &archive.CreatedAt); err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM audit_logs WHERE id <= $1", archive.LastID)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+func (r *auditLogRepository) ListCursors(ctx context.Context) ([]*service.AuditLogExportCursor, error) {
+	if r == nil || r.db == nil {
+		return nil, fmt.Errorf("nil audit log repository")
+	}
+	rows, err := r.db.QueryContext(ctx, `
+SELECT sink_name, last_id, last_error, last_error_at, delivered_count, updated_at
+FROM audit_log_export_cursors ORDER BY sink_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	cursors := make([]*service.AuditLogExportCursor, 0)
+	for rows.Next() {
+		item := &service.AuditLogExportCursor{}
+		var lastErrorAt sql.NullTime
+		if err := rows.Scan(&item.SinkName, &item.LastID, &item.LastError, &lastErrorAt, &item.DeliveredCount, &item.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if lastErrorAt.Valid {
+			t := lastErrorAt.Time
+			item.LastErrorAt = &t
+		}
+		cursors = append(cursors, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return cursors, nil
+}
+
+func (r *auditLogRepository) AdvanceCursor(ctx context.Context, sinkName string, lastID int64, delivered int64) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("nil audit log repository")
+	}
+	_, err := r.db.ExecContext(ctx, `
+INSERT INTO audit_log_export_cursors (sink_name, last_id, delivered_count, updated_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (sink_name) DO UPDATE SET
+  last_id = GREATEST(audit_log_export_cursors.last_id, EXCLUDED.last_id),
+  delivered_count = audit_log_export_cursors.delivered_count + EXCLUDED.delivered_count,
+  last_error = '',
+  last_error_at = NULL,
+  updated_at = NOW()`, sinkName, lastID, delivered)
+	return err
+}
+
+func (r *auditLogRepository) RecordCursorError(ctx context.Context, sinkName string, errMsg string) error {
+	if r == nil || r.db == nil {
+		return fmt.Errorf("nil audit log repository")
+	}
+	_, err := r.db.ExecContext(ctx, `
+INSERT INTO audit_log_export_cursors (sink_name, last_error, last_error_at, updated_at)
+VALUES ($1, $2, NOW(), NOW())
+ON CONFLICT (sink_name) DO UPDATE SET
+  last_error = EXCLUDED.last_error,
+  last_error_at = EXCLUDED.last_error_at,
+  updated_at = NOW()`, sinkName, truncateString(errMsg, 2000))
+	return err
 }
 
 func nullInt64Ptr(v *int64) any {
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	backgroundResponseKeyPrefix       = "bg_response:"
+	backgroundResponseEventsKeyPrefix = "bg_response_events:"
+	backgroundResponseCancelKeyPrefix = "bg_response_cancel:"
+)
+
+type backgroundResponseStore struct {
+	rdb *redis.Client
+}
+
+func NewBackgroundResponseStore(rdb *redis.Client) service.BackgroundResponseStore {
+	return &backgroundResponseStore{rdb: rdb}
+}
+
+func (s *backgroundResponseStore) Save(ctx context.Context, record *service.BackgroundResponseRecord, ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.rdb.Set(ctx, backgroundResponseKey(record.ID), data, ttl).Err()
+}
+
+func (s *backgroundResponseStore) Get(ctx context.Context, id string) (*service.BackgroundResponseRecord, error) {
+	data, err := s.rdb.Get(ctx, backgroundResponseKey(id)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, service.ErrBackgroundResponseNotFound
+		}
+		return nil, err
+	}
+	var record service.BackgroundResponseRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *backgroundResponseStore) AppendEvents(ctx context.Context, id string, events []json.RawMessage, ttl time.Duration) error {
+	if len(events) == 0 {
+		return nil
+	}
+	values := make([]any, 0, len(events))
+	for _, event := range events {
+		values = append(values, []byte(event))
+	}
+	key := backgroundResponseEventsKey(id)
+	pipe := s.rdb.TxPipeline()
+	pipe.RPush(ctx, key, values...)
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *backgroundResponseStore) ListEvents(ctx context.Context, id string, from int64) ([]json.RawMessage, error) {
+	if from < 0 {
+		from = 0
+	}
+	values, err := s.rdb.LRange(ctx, backgroundResponseEventsKey(id), from, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	events := make([]json.RawMessage, 0, len(values))
+	for _, value := range values {
+		events = append(events, json.RawMessage(value))
+	}
+	return events, nil
+}
+
+func (s *backgroundResponseStore) DeleteEvents(ctx context.Context, id string) error {
+	return s.rdb.Del(ctx, backgroundResponseEventsKey(id)).Err()
+}
+
+func (s *backgroundResponseStore) RequestCancel(ctx context.Context, id string, ttl time.Duration) error {
+	return s.rdb.Set(ctx, backgroundResponseCancelKey(id), "1", ttl).Err()
+}
+
+func (s *backgroundResponseStore) CancelRequested(ctx context.Context, id string) (bool, error) {
+	n, err := s.rdb.Exists(ctx, backgroundResponseCancelKey(id)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func backgroundResponseKey(id string) string {
+	return backgroundResponseKeyPrefix + strings.TrimSpace(id)
+}
+
+func backgroundResponseEventsKey(id string) string {
+	return backgroundResponseEventsKeyPrefix + strings.TrimSpace(id)
+}
+
+func backgroundResponseCancelKey(id string) string {
+	return backgroundResponseCancelKeyPrefix + strings.TrimSpace(id)
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackgroundResponseStoreRoundTrip(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	store := NewBackgroundResponseStore(rdb)
+	ctx := context.Background()
+	record := &service.BackgroundResponseRecord{
+		ID:        "resp_bg123",
+		UserID:    7,
+		APIKeyID:  9,
+		Status:    service.BackgroundResponseStatusQueued,
+		Response:  json.RawMessage(`{"id":"resp_bg123"}`),
+		CreatedAt: 100,
+		ExpiresAt: 200,
+	}
+
+	require.NoError(t, store.Save(ctx, record, time.Hour))
+	got, err := store.Get(ctx, record.ID)
+	require.NoError(t, err)
+	require.Equal(t, record, got)
+	require.Equal(t, time.Hour, mr.TTL(backgroundResponseKey(record.ID)))
+
+	_, err = store.Get(ctx, "resp_bgmissing")
+	require.ErrorIs(t, err, service.ErrBackgroundResponseNotFound)
+}
+
+func TestBackgroundResponseStoreEventsAndCancel(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	store := NewBackgroundResponseStore(rdb)
+	ctx := context.Background()
+
+	require.NoError(t, store.AppendEvents(ctx, "resp_bg1", []json.RawMessage{
+		json.RawMessage(`{"sequence_number":0}`),
+		json.RawMessage(`{"sequence_number":1}`),
+	}, time.Hour))
+	require.NoError(t, store.AppendEvents(ctx, "resp_bg1", []json.RawMessage{json.RawMessage(`{"sequence_number":2}`)}, time.Hour))
+	require.Equal(t, time.Hour, mr.TTL(backgroundResponseEventsKey("resp_bg1")))
+
+	events, err := store.ListEvents(ctx, "resp_bg1", 1)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.JSONEq(t, `{"sequence_number":1}`, string(events[0]))
+
+	require.NoError(t, store.DeleteEvents(ctx, "resp_bg1"))
+	events, err = store.ListEvents(ctx, "resp_bg1", 0)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	cancelled, err := store.CancelRequested(ctx, "resp_bg1")
+	require.NoError(t, err)
+	require.False(t, cancelled)
+	require.NoError(t, store.RequestCancel(ctx, "resp_bg1", time.Hour))
+	cancelled, err = store.CancelRequested(ctx, "resp_bg1")
+	require.NoError(t, err)
+	require.True(t, cancelled)
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+)
+
+// credentialReencryptionRepository 为重加密任务读取存储形态的凭证与代理密码。
+// 不按 deleted_at 过滤：软删除的记录同样留在备份里，也需要轮换。
+type credentialReencryptionRepository struct {
+	sql sqlExecutor
+}
+
+func NewCredentialReencryptionRepository(sqlDB *sql.DB) service.CredentialReencryptionRepository {
+	return &credentialReencryptionRepository{sql: sqlDB}
+}
+
+func (r *credentialReencryptionRepository) ListStoredAccountCredentials(ctx context.Context, afterID int64, limit int) ([]service.StoredAccountCredentials, error) {
+	rows, err := r.sql.QueryContext(ctx, `
+		SELECT id, credentials
+		FROM accounts
+		WHERE id > $1 AND credentials IS NOT NULL
+		ORDER BY id
+		LIMIT $2
+	`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	out := make([]service.StoredAccountCredentials, 0, limit)
+	for rows.Next() {
+		var (
+			item service.StoredAccountCredentials
+			raw  []byte
+		)
+		if err := rows.Scan(&item.ID, &raw); err != nil {
+			return nil, err
+		}
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&item.Credentials); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func (r *credentialReencryptionRepository) CompareAndSwapAccountCredentials(ctx context.Context, id int64, expected, replacement map[string]any) (bool, error) {
+	expectedJSON, err := json.Marshal(expected)
+	if err != nil {
+		return false, err
+	}
+	replacementJSON, err := json.Marshal(replacement)
+	if err != nil {
+		return false, err
+	}
+	// 明文不变，无需刷新 updated_at 或调度快照。
+	result, err := r.sql.ExecContext(ctx, `
+		UPDATE accounts
+		SET credentials = $1::jsonb
+		WHERE id = $2 AND credentials = $3::jsonb
+	`, string(replacementJSON), id, string(expectedJSON))
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *credentialReencryptionRepository) ListStoredProxyPasswords(ctx context.Context, afterID int64, limit int) ([]service.StoredProxyPassword, error) {
+	rows, err := r.sql.QueryContext(ctx, `
+		SELECT id, password
+		FROM proxies
+		WHERE id > $1 AND password IS NOT NULL AND password <> ''
+		ORDER BY id
+		LIMIT $2
+	`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	out := make([]service.StoredProxyPassword, 0, limit)
+	for rows.Next() {
+		var item service.StoredProxyPassword
+		if err := rows.Scan(&item.ID, &item.Password); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func (r *credentialReencryptionRepository) CompareAndSwapProxyPassword(ctx context.Context, id int64, expected, replacement string) (bool, error) {
+	result, err := r.sql.ExecContext(ctx, `
+		UPDATE proxies
+		SET password = $1
+		WHERE id = $2 AND password = $3
+	`, replacement, id, expected)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
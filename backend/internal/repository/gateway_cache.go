@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -127,6 +128,106 @@ func (c *gatewayCache) ReleaseGrokVideoBilled(ctx context.Context, key string) e
 	return c.rdb.Del(ctx, grokVideoBilledPrefix+key).Err()
 }
 
+var _ service.GeminiCachedContentStore = (*gatewayCache)(nil)
+
+const (
+	geminiCachedContentPrefix      = "gemini_cached_content:"
+	geminiCachedContentIndexPrefix = "gemini_cached_content_idx:"
+)
+
+// 格式: gemini_cached_content:{groupID}:{id}
+func geminiCachedContentKey(groupID int64, id string) string {
+	return fmt.Sprintf("%s%d:%s", geminiCachedContentPrefix, groupID, id)
+}
+
+// 用户维度索引（set），供 list 使用；成员过期由 List 顺带清理。
+// 格式: gemini_cached_content_idx:{groupID}:{userID}
+func geminiCachedContentIndexKey(groupID, userID int64) string {
+	return fmt.Sprintf("%s%d:%d", geminiCachedContentIndexPrefix, groupID, userID)
+}
+
+func (c *gatewayCache) SaveGeminiCachedContent(ctx context.Context, record *service.GeminiCachedContentRecord, ttl time.Duration) error {
+	if record == nil || strings.TrimSpace(record.ID) == "" {
+		return errors.New("invalid gemini cached content record")
+	}
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	indexKey := geminiCachedContentIndexKey(record.GroupID, record.UserID)
+	indexTTL, err := c.rdb.TTL(ctx, indexKey).Result()
+	if err != nil {
+		return err
+	}
+	pipe := c.rdb.TxPipeline()
+	pipe.Set(ctx, geminiCachedContentKey(record.GroupID, record.ID), payload, ttl)
+	pipe.SAdd(ctx, indexKey, record.ID)
+	// 索引 TTL 取所有成员中最晚的过期时间。
+	if indexTTL < ttl {
+		pipe.Expire(ctx, indexKey, ttl)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (c *gatewayCache) GetGeminiCachedContent(ctx context.Context, groupID int64, id string) (*service.GeminiCachedContentRecord, error) {
+	raw, err := c.rdb.Get(ctx, geminiCachedContentKey(groupID, id)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, service.ErrGeminiCachedContentNotFound
+		}
+		return nil, err
+	}
+	var record service.GeminiCachedContentRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (c *gatewayCache) ListGeminiCachedContents(ctx context.Context, groupID, userID int64) ([]*service.GeminiCachedContentRecord, error) {
+	indexKey := geminiCachedContentIndexKey(groupID, userID)
+	ids, err := c.rdb.SMembers(ctx, indexKey).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, geminiCachedContentKey(groupID, id))
+	}
+	values, err := c.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*service.GeminiCachedContentRecord, 0, len(values))
+	stale := make([]any, 0)
+	for i, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			stale = append(stale, ids[i])
+			continue
+		}
+		var record service.GeminiCachedContentRecord
+		if err := json.Unmarshal([]byte(raw), &record); err != nil {
+			stale = append(stale, ids[i])
+			continue
+		}
+		records = append(records, &record)
+	}
+	if len(stale) > 0 {
+		_ = c.rdb.SRem(ctx, indexKey, stale...).Err()
+	}
+	return records, nil
+}
+
+func (c *gatewayCache) DeleteGeminiCachedContent(ctx context.Context, groupID, userID int64, id string) error {
+	pipe := c.rdb.TxPipeline()
+	pipe.Del(ctx, geminiCachedContentKey(groupID, id))
+	pipe.SRem(ctx, geminiCachedContentIndexKey(groupID, userID), id)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
 // Compile-time assertion: gatewayCache must implement CyberSessionBlockStore.
 var _ service.CyberSessionBlockStore = (*gatewayCache)(nil)
 var _ service.LiveCallStore = (*gatewayCache)(nil)
@@ -60,3 +60,45 @@ func TestGatewayCacheLiveCallIdentityAndController(t *testing.T) {
 	require.NoError(t, err)
 	require.False(t, closed)
 }
+
+func TestGatewayCacheGeminiCachedContentStore(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	store, ok := NewGatewayCache(client).(service.GeminiCachedContentStore)
+	require.True(t, ok)
+	ctx := context.Background()
+
+	_, err := store.GetGeminiCachedContent(ctx, 1, "missing")
+	require.ErrorIs(t, err, service.ErrGeminiCachedContentNotFound)
+
+	for _, record := range []*service.GeminiCachedContentRecord{
+		{ID: "c1", GroupID: 1, UserID: 10, AccountID: 100, Resource: []byte(`{"name":"cachedContents/c1"}`)},
+		{ID: "c2", GroupID: 1, UserID: 10, AccountID: 101},
+		{ID: "c3", GroupID: 1, UserID: 11, AccountID: 100},
+	} {
+		require.NoError(t, store.SaveGeminiCachedContent(ctx, record, time.Hour))
+	}
+
+	loaded, err := store.GetGeminiCachedContent(ctx, 1, "c1")
+	require.NoError(t, err)
+	require.Equal(t, int64(100), loaded.AccountID)
+	require.JSONEq(t, `{"name":"cachedContents/c1"}`, string(loaded.Resource))
+
+	records, err := store.ListGeminiCachedContents(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	// 过期的绑定在列出时从索引中剔除。
+	redisServer.Del("gemini_cached_content:1:c2")
+	records, err = store.ListGeminiCachedContents(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	members, err := redisServer.SMembers("gemini_cached_content_idx:1:10")
+	require.NoError(t, err)
+	require.Equal(t, []string{"c1"}, members)
+
+	require.NoError(t, store.DeleteGeminiCachedContent(ctx, 1, 10, "c1"))
+	_, err = store.GetGeminiCachedContent(ctx, 1, "c1")
+	require.ErrorIs(t, err, service.ErrGeminiCachedContentNotFound)
+	require.Greater(t, redisServer.TTL("gemini_cached_content:1:c3"), time.Duration(0))
+}
@@ -93,6 +93,8 @@ func createGroupRecord(ctx context.Context, client *dbent.Client, groupIn *servi
 		SetNillableAudioRealtimePricePerMin(groupIn.AudioRealtimePricePerMin).
 		SetNillableAudioTtsPricePerMillionChars(groupIn.AudioTTSPricePerMillionChars).
 		SetNillableAudioSttPricePerHour(groupIn.AudioSTTPricePerHour).
+		SetNillableModerationPricePerCall(groupIn.ModerationPricePerCall).
+		SetNillableModerationPricePerMillionTokens(groupIn.ModerationPricePerMillionTokens).
 		SetLongContextPricingEnabled(groupIn.LongContextPricingEnabled).
 		SetModelPricing(modelPricing).
 		SetDefaultValidityDays(groupIn.DefaultValidityDays).
@@ -117,7 +119,12 @@ func createGroupRecord(ctx context.Context, client *dbent.Client, groupIn *servi
 		SetPeakRateMultiplier(groupIn.PeakRateMultiplier).
 		SetProfitControlEnabled(groupIn.ProfitControlEnabled).
 		SetProfitMinMargin(groupIn.ProfitMinMargin).
-		SetProfitSafetyBuffer(groupIn.ProfitSafetyBuffer)
+		SetProfitSafetyBuffer(groupIn.ProfitSafetyBuffer).
+		SetPromptPrefixRoutingEnabled(groupIn.PromptPrefixRoutingEnabled).
+		SetPromptPrefixMessages(groupIn.PromptPrefixMessages).
+		SetStreamResumeEnabled(groupIn.StreamResumeEnabled).
+		SetContextCompactionMode(groupIn.ContextCompactionMode).
+		SetContextCompactionModel(groupIn.ContextCompactionModel)
 	if groupIn.DuplicateOperationID != "" {
 		builder = builder.SetDuplicateOperationID(groupIn.DuplicateOperationID)
 	}
@@ -293,7 +300,12 @@ func (r *groupRepository) Update(ctx context.Context, groupIn *service.Group) er
 		SetPeakRateMultiplier(groupIn.PeakRateMultiplier).
 		SetProfitControlEnabled(groupIn.ProfitControlEnabled).
 		SetProfitMinMargin(groupIn.ProfitMinMargin).
-		SetProfitSafetyBuffer(groupIn.ProfitSafetyBuffer)
+		SetProfitSafetyBuffer(groupIn.ProfitSafetyBuffer).
+		SetPromptPrefixRoutingEnabled(groupIn.PromptPrefixRoutingEnabled).
+		SetPromptPrefixMessages(groupIn.PromptPrefixMessages).
+		SetStreamResumeEnabled(groupIn.StreamResumeEnabled).
+		SetContextCompactionMode(groupIn.ContextCompactionMode).
+		SetContextCompactionModel(groupIn.ContextCompactionModel)
 
 	// 显式处理可空字段：nil 需要 clear，非 nil 需要 set。
 	if groupIn.DailyLimitUSD != nil {
@@ -366,6 +378,16 @@ func (r *groupRepository) Update(ctx context.Context, groupIn *service.Group) er
 	} else {
 		builder = builder.ClearAudioSttPricePerHour()
 	}
+	if groupIn.ModerationPricePerCall != nil {
+		builder = builder.SetModerationPricePerCall(*groupIn.ModerationPricePerCall)
+	} else {
+		builder = builder.ClearModerationPricePerCall()
+	}
+	if groupIn.ModerationPricePerMillionTokens != nil {
+		builder = builder.SetModerationPricePerMillionTokens(*groupIn.ModerationPricePerMillionTokens)
+	} else {
+		builder = builder.ClearModerationPricePerMillionTokens()
+	}
 
 	// 处理 FallbackGroupID：nil 时清除，否则设置
 	if groupIn.FallbackGroupID != nil {
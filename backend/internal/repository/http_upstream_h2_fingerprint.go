@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tlsfingerprint"
+	"github.com/imroc/req/v3"
+	reqhttp2 "github.com/imroc/req/v3/http2"
+	utls "github.com/refraction-networking/utls"
+)
+
+// buildUpstreamRoundTripperWithTLSFingerprint 在 TLS 指纹 Transport 之上按需叠加 HTTP/2 指纹。
+//
+// 只有 profile 带 HTTP/2 段且 ALPN 声明 h2、并且走的是指纹 Dialer（直连 / SOCKS5 / HTTP CONNECT）时，
+// 才改用可定制 SETTINGS、WINDOW_UPDATE、PRIORITY 与伪头顺序的 HTTP/2 Transport；
+// 其余情况（HTTPS 代理、未知代理协议回退）保持原有 *http.Transport 行为。
+func buildUpstreamRoundTripperWithTLSFingerprint(settings poolSettings, proxyURL *url.URL, profile *tlsfingerprint.Profile) (http.RoundTripper, error) {
+	transport, err := buildUpstreamTransportWithTLSFingerprint(settings, proxyURL, profile)
+	if err != nil {
+		return nil, err
+	}
+	if !profile.UsesHTTP2() || transport.DialTLSContext == nil {
+		return transport, nil
+	}
+	return buildHTTP2FingerprintTransport(settings, transport.DialTLSContext, profile.HTTP2), nil
+}
+
+// buildHTTP2FingerprintTransport 用给定的 TLS Dialer 构建按 HTTP/2 指纹发帧的 RoundTripper。
+// ALPN 协商为 http/1.1 时自动退回 HTTP/1.1，与标准 Transport 行为一致。
+func buildHTTP2FingerprintTransport(settings poolSettings, dialTLS func(ctx context.Context, network, addr string) (net.Conn, error), h2 *tlsfingerprint.HTTP2Profile) http.RoundTripper {
+	t := req.NewTransport()
+	// 代理由指纹 Dialer 自行建立隧道，不能再让 Transport 读取环境变量代理。
+	t.SetProxy(nil)
+	// 透传原始响应体，不做字符集转码。
+	t.DisableAutoDecode()
+	t.MaxIdleConns = settings.maxIdleConns
+	t.MaxIdleConnsPerHost = settings.maxIdleConnsPerHost
+	t.MaxConnsPerHost = settings.maxConnsPerHost
+	t.IdleConnTimeout = settings.idleConnTimeout
+	t.ResponseHeaderTimeout = settings.responseHeaderTimeout
+	t.SetDialTLS(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialTLS(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		// utls 连接需暴露标准库 ConnectionState，Transport 才能据 ALPN 切换到 HTTP/2。
+		if uconn, ok := conn.(*utls.UConn); ok {
+			return &utlsStdStateConn{UConn: uconn}, nil
+		}
+		return conn, nil
+	})
+
+	if len(h2.Settings) > 0 {
+		frames := make([]reqhttp2.Setting, 0, len(h2.Settings))
+		for _, s := range h2.Settings {
+			frames = append(frames, reqhttp2.Setting{ID: reqhttp2.SettingID(s.ID), Val: s.Val})
+		}
+		t.SetHTTP2SettingsFrame(frames...)
+	}
+	if h2.ConnectionFlow > 0 {
+		t.SetHTTP2ConnectionFlow(h2.ConnectionFlow)
+	}
+	if h2.HeaderPriority != nil {
+		t.SetHTTP2HeaderPriority(toReqHTTP2Priority(*h2.HeaderPriority))
+	}
+	if len(h2.PriorityFrames) > 0 {
+		frames := make([]reqhttp2.PriorityFrame, 0, len(h2.PriorityFrames))
+		for _, f := range h2.PriorityFrames {
+			frames = append(frames, reqhttp2.PriorityFrame{StreamID: f.StreamID, PriorityParam: toReqHTTP2Priority(f.Priority)})
+		}
+		t.SetHTTP2PriorityFrames(frames...)
+	}
+	if len(h2.PseudoHeaderOrder) > 0 {
+		order := append([]string(nil), h2.PseudoHeaderOrder...)
+		t.WrapRoundTripFunc(func(rt http.RoundTripper) req.HttpRoundTripFunc {
+			return func(r *http.Request) (*http.Response, error) {
+				r = r.Clone(r.Context())
+				if r.Header == nil {
+					r.Header = make(http.Header)
+				}
+				r.Header[req.PseudoHeaderOderKey] = order
+				return rt.RoundTrip(r)
+			}
+		})
+	}
+	return t
+}
+
+func toReqHTTP2Priority(p tlsfingerprint.HTTP2Priority) reqhttp2.PriorityParam {
+	return reqhttp2.PriorityParam{StreamDep: p.StreamDep, Exclusive: p.Exclusive, Weight: p.Weight}
+}
+
+// utlsStdStateConn 把 utls 的 ConnectionState 转成标准库类型。
+type utlsStdStateConn struct {
+	*utls.UConn
+}
+
+func (c *utlsStdStateConn) ConnectionState() tls.ConnectionState {
+	cs := c.UConn.ConnectionState()
+	return tls.ConnectionState{
+		Version:                    cs.Version,
+		HandshakeComplete:          cs.HandshakeComplete,
+		DidResume:                  cs.DidResume,
+		CipherSuite:                cs.CipherSuite,
+		NegotiatedProtocol:         cs.NegotiatedProtocol,
+		NegotiatedProtocolIsMutual: cs.NegotiatedProtocolIsMutual,
+		ServerName:                 cs.ServerName,
+		PeerCertificates:           cs.PeerCertificates,
+		VerifiedChains:             cs.VerifiedChains,
+	}
+}
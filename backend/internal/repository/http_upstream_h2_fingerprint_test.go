@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tlsfingerprint"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// chromeLikeHTTP2Profile 参考 profile：Chrome 风格 SETTINGS 顺序、15663105 连接窗口、
+// HEADERS 携带独占优先级、m,a,s,p 伪头顺序。
+var chromeLikeHTTP2Profile = &tlsfingerprint.HTTP2Profile{
+	Settings: []tlsfingerprint.HTTP2Setting{
+		{ID: tlsfingerprint.HTTP2SettingHeaderTableSize, Val: 65536},
+		{ID: tlsfingerprint.HTTP2SettingEnablePush, Val: 0},
+		{ID: tlsfingerprint.HTTP2SettingInitialWindowSize, Val: 6291456},
+		{ID: tlsfingerprint.HTTP2SettingMaxHeaderListSize, Val: 262144},
+	},
+	ConnectionFlow:    15663105,
+	HeaderPriority:    &tlsfingerprint.HTTP2Priority{StreamDep: 0, Exclusive: true, Weight: 255},
+	PseudoHeaderOrder: []string{":method", ":authority", ":scheme", ":path"},
+}
+
+// firefoxLikeHTTP2Profile 参考 profile：带 PRIORITY 依赖树与 m,p,a,s 伪头顺序。
+var firefoxLikeHTTP2Profile = &tlsfingerprint.HTTP2Profile{
+	Settings: []tlsfingerprint.HTTP2Setting{
+		{ID: tlsfingerprint.HTTP2SettingHeaderTableSize, Val: 65536},
+		{ID: tlsfingerprint.HTTP2SettingInitialWindowSize, Val: 131072},
+		{ID: tlsfingerprint.HTTP2SettingMaxFrameSize, Val: 16384},
+	},
+	ConnectionFlow: 12517377,
+	PriorityFrames: []tlsfingerprint.HTTP2PriorityFrame{
+		{StreamID: 3, Priority: tlsfingerprint.HTTP2Priority{StreamDep: 0, Weight: 200}},
+		{StreamID: 5, Priority: tlsfingerprint.HTTP2Priority{StreamDep: 0, Weight: 100}},
+		{StreamID: 7, Priority: tlsfingerprint.HTTP2Priority{StreamDep: 0, Weight: 0}},
+	},
+	HeaderPriority:    &tlsfingerprint.HTTP2Priority{StreamDep: 13, Weight: 41},
+	PseudoHeaderOrder: []string{":method", ":path", ":authority", ":scheme"},
+}
+
+// capturedHTTP2 capture server 记录到的客户端连接前言与首个请求帧。
+type capturedHTTP2 struct {
+	settings       []http2.Setting
+	connectionFlow uint32
+	priorities     []http2.PriorityFrame
+	headerPriority http2.PriorityParam
+	pseudoOrder    []string
+}
+
+// akamai 以 Akamai 格式输出抓到的帧，独立于 tlsfingerprint 中的实现以便交叉校验。
+func (c *capturedHTTP2) akamai() string {
+	settings := make([]string, 0, len(c.settings))
+	for _, s := range c.settings {
+		settings = append(settings, fmt.Sprintf("%d:%d", s.ID, s.Val))
+	}
+	priorities := "0"
+	if len(c.priorities) > 0 {
+		parts := make([]string, 0, len(c.priorities))
+		for _, p := range c.priorities {
+			excl := 0
+			if p.Exclusive {
+				excl = 1
+			}
+			parts = append(parts, fmt.Sprintf("%d:%d:%d:%d", p.StreamID, excl, p.StreamDep, int(p.Weight)+1))
+		}
+		priorities = strings.Join(parts, ",")
+	}
+	pseudo := make([]string, 0, len(c.pseudoOrder))
+	for _, h := range c.pseudoOrder {
+		pseudo = append(pseudo, h[1:2])
+	}
+	return fmt.Sprintf("%s|%d|%s|%s", strings.Join(settings, ";"), c.connectionFlow, priorities, strings.Join(pseudo, ","))
+}
+
+// startHTTP2CaptureServer 启动本地 TLS(h2) 服务，逐帧解析客户端前言与首个 HEADERS，
+// 回 200 后把捕获结果送入返回的 channel。
+func startHTTP2CaptureServer(t *testing.T) (string, <-chan *capturedHTTP2) {
+	t.Helper()
+	cert := newSelfSignedCert(t)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2"},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	out := make(chan *capturedHTTP2, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		preface := make([]byte, len(http2.ClientPreface))
+		if _, err := io.ReadFull(conn, preface); err != nil || string(preface) != http2.ClientPreface {
+			return
+		}
+		framer := http2.NewFramer(conn, conn)
+		captured := &capturedHTTP2{}
+		sawSettings := false
+		for {
+			frame, err := framer.ReadFrame()
+			if err != nil {
+				return
+			}
+			switch f := frame.(type) {
+			case *http2.SettingsFrame:
+				if f.IsAck() || sawSettings {
+					continue
+				}
+				sawSettings = true
+				_ = f.ForeachSetting(func(s http2.Setting) error {
+					captured.settings = append(captured.settings, s)
+					return nil
+				})
+				_ = framer.WriteSettings()
+				_ = framer.WriteSettingsAck()
+			case *http2.WindowUpdateFrame:
+				if f.StreamID == 0 && captured.connectionFlow == 0 {
+					captured.connectionFlow = f.Increment
+				}
+			case *http2.PriorityFrame:
+				captured.priorities = append(captured.priorities, *f)
+			case *http2.HeadersFrame:
+				if f.HasPriority() {
+					captured.headerPriority = f.Priority
+				}
+				dec := hpack.NewDecoder(4096, func(hf hpack.HeaderField) {
+					if strings.HasPrefix(hf.Name, ":") {
+						captured.pseudoOrder = append(captured.pseudoOrder, hf.Name)
+					}
+				})
+				if _, err := dec.Write(f.HeaderBlockFragment()); err != nil {
+					return
+				}
+				var block bytes.Buffer
+				enc := hpack.NewEncoder(&block)
+				_ = enc.WriteField(hpack.HeaderField{Name: ":status", Value: "200"})
+				_ = framer.WriteHeaders(http2.HeadersFrameParam{
+					StreamID:      f.StreamID,
+					BlockFragment: block.Bytes(),
+					EndHeaders:    true,
+					EndStream:     true,
+				})
+				out <- captured
+				// 等客户端读完响应后再关闭连接。
+				_, _ = framer.ReadFrame()
+				return
+			}
+		}
+	}()
+	return ln.Addr().String(), out
+}
+
+func newSelfSignedCert(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// insecureH2Dialer 测试用 TLS Dialer：跳过证书校验并声明 h2。HTTP/2 帧与 TLS 层无关，
+// 真实链路中这里是 tlsfingerprint 的 utls Dialer。
+func insecureH2Dialer(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := &tls.Dialer{Config: &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"h2", "http/1.1"}}} //nolint:gosec // 仅测试
+	return d.DialContext(ctx, network, addr)
+}
+
+func TestHTTP2FingerprintTransport_EmitsReferenceFrames(t *testing.T) {
+	for name, profile := range map[string]*tlsfingerprint.HTTP2Profile{
+		"chrome_like":  chromeLikeHTTP2Profile,
+		"firefox_like": firefoxLikeHTTP2Profile,
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, profile.Validate())
+			addr, captures := startHTTP2CaptureServer(t)
+
+			rt := buildHTTP2FingerprintTransport(poolSettings{maxIdleConns: 1, maxIdleConnsPerHost: 1}, insecureH2Dialer, profile)
+			req, err := http.NewRequest(http.MethodGet, "https://"+addr+"/v1/messages", nil)
+			require.NoError(t, err)
+			resp, err := rt.RoundTrip(req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Equal(t, 2, resp.ProtoMajor)
+			_ = resp.Body.Close()
+
+			var captured *capturedHTTP2
+			select {
+			case captured = <-captures:
+			case <-time.After(5 * time.Second):
+				t.Fatal("capture server did not receive HEADERS")
+			}
+
+			require.Equal(t, profile.AkamaiFingerprint(), captured.akamai())
+			require.Equal(t, http2.PriorityParam{
+				StreamDep: profile.HeaderPriority.StreamDep,
+				Exclusive: profile.HeaderPriority.Exclusive,
+				Weight:    profile.HeaderPriority.Weight,
+			}, captured.headerPriority)
+		})
+	}
+}
+
+func TestBuildUpstreamRoundTripperWithTLSFingerprint_HTTP2Selection(t *testing.T) {
+	withH2 := &tlsfingerprint.Profile{ALPNProtocols: []string{"h2", "http/1.1"}, HTTP2: chromeLikeHTTP2Profile}
+	rt, err := buildUpstreamRoundTripperWithTLSFingerprint(poolSettings{}, nil, withH2)
+	require.NoError(t, err)
+	_, isStd := rt.(*http.Transport)
+	require.False(t, isStd, "h2 profile should use the HTTP/2 fingerprint transport")
+
+	// 未声明 h2 ALPN 时无法协商 HTTP/2，保持原有 Transport。
+	noALPN := &tlsfingerprint.Profile{HTTP2: chromeLikeHTTP2Profile}
+	rt, err = buildUpstreamRoundTripperWithTLSFingerprint(poolSettings{}, nil, noALPN)
+	require.NoError(t, err)
+	_, isStd = rt.(*http.Transport)
+	require.True(t, isStd)
+}
@@ -659,6 +659,28 @@ func (s *HTTPUpstreamSuite) TestOpenAIProfileTLSFingerprintDoesNotInheritGeneric
 	require.Equal(s.T(), time.Duration(0), transport.ResponseHeaderTimeout, "OpenAI TLS path should not inherit generic header timeout")
 }
 
+func (s *HTTPUpstreamSuite) TestTLSFingerprintHTTP2HeaderPriorityChangeRebuildsClient() {
+	svc := s.newService()
+	profile := &tlsfingerprint.Profile{
+		Name:          "h2",
+		ALPNProtocols: []string{"h2", "http/1.1"},
+		HTTP2:         &tlsfingerprint.HTTP2Profile{ConnectionFlow: 15663105},
+	}
+	first, err := svc.getClientEntryWithTLS("", 1, 1, profile, service.HTTPUpstreamProfileDefault, false, false)
+	require.NoError(s.T(), err)
+
+	// 只改 HEADERS 帧优先级（不影响 Akamai 指纹）也必须重建连接池。
+	profile.HTTP2.HeaderPriority = &tlsfingerprint.HTTP2Priority{Exclusive: true, Weight: 255}
+	second, err := svc.getClientEntryWithTLS("", 1, 1, profile, service.HTTPUpstreamProfileDefault, false, false)
+	require.NoError(s.T(), err)
+	require.NotSame(s.T(), first, second)
+	require.NotEqual(s.T(), first.poolKey, second.poolKey)
+
+	third, err := svc.getClientEntryWithTLS("", 1, 1, profile, service.HTTPUpstreamProfileDefault, false, false)
+	require.NoError(s.T(), err)
+	require.Same(s.T(), second, third)
+}
+
 func (s *HTTPUpstreamSuite) TestOpenAIProfileHTTP2DisabledUsesHTTP1Transport() {
 	s.cfg.Gateway = config.GatewayConfig{
 		OpenAIHTTP2: config.GatewayOpenAIHTTP2Config{Enabled: false},
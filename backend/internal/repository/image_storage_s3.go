@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"strings"
 	"time"
 
@@ -22,7 +23,10 @@ type S3ImageStorage struct {
 	presignExpiry time.Duration
 }
 
-var _ service.ImageStorage = (*S3ImageStorage)(nil)
+var (
+	_ service.ImageStorage       = (*S3ImageStorage)(nil)
+	_ service.ImageStorageLoader = (*S3ImageStorage)(nil)
+)
 
 // NewS3ImageStorage 依据配置构造 S3 图片存储（调用方应先确认 cfg.Active()）。
 func NewS3ImageStorage(ctx context.Context, cfg *config.ImageStorageConfig) (*S3ImageStorage, error) {
@@ -78,3 +82,22 @@ func (s *S3ImageStorage) Save(ctx context.Context, key, contentType string, data
 	}
 	return result.URL, nil
 }
+
+// Load 读回 key 对应的对象内容。
+func (s *S3ImageStorage) Load(ctx context.Context, key string) ([]byte, error) {
+	finish := servertiming.ObserveDependency(ctx, "s3")
+	defer finish()
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("S3 GetObject: %w", err)
+	}
+	defer func() { _ = out.Body.Close() }()
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("S3 GetObject body: %w", err)
+	}
+	return data, nil
+}
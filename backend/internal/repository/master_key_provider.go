@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+)
+
+// localMasterKeyProvider implements service.MasterKeyProvider with AES-256-GCM
+// key wrapping using master keys from config or a key file.
+type localMasterKeyProvider struct {
+	activeKeyID string
+	keys        map[string][]byte
+}
+
+// localMasterKeyFile is the key file format:
+// {"active_key_id": "2026-10", "keys": {"2026-10": "<hex>", "2026-01": "<hex>"}}
+type localMasterKeyFile struct {
+	ActiveKeyID string            `json:"active_key_id"`
+	Keys        map[string]string `json:"keys"`
+}
+
+// ProvideMasterKeyProvider returns nil when credential encryption is disabled,
+// which keeps credentials stored as plaintext.
+func ProvideMasterKeyProvider(cfg *config.Config) (service.MasterKeyProvider, error) {
+	enc := cfg.Security.CredentialEncryption
+	if !enc.Enabled {
+		return nil, nil
+	}
+	activeKeyID := strings.TrimSpace(enc.ActiveKeyID)
+	rawKeys := make(map[string]string, len(enc.PreviousMasterKeys)+1)
+	for id, key := range enc.PreviousMasterKeys {
+		rawKeys[id] = key
+	}
+	if activeKeyID != "" && enc.MasterKey != "" {
+		rawKeys[activeKeyID] = enc.MasterKey
+	}
+	if path := strings.TrimSpace(enc.KeyFile); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read credential key file: %w", err)
+		}
+		var file localMasterKeyFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("parse credential key file: %w", err)
+		}
+		activeKeyID = strings.TrimSpace(file.ActiveKeyID)
+		rawKeys = file.Keys
+	}
+	return newLocalMasterKeyProvider(activeKeyID, rawKeys)
+}
+
+func newLocalMasterKeyProvider(activeKeyID string, rawKeys map[string]string) (*localMasterKeyProvider, error) {
+	keys := make(map[string][]byte, len(rawKeys))
+	for id, raw := range rawKeys {
+		id = strings.TrimSpace(id)
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid credential master key id %q", id)
+		}
+		key, err := hex.DecodeString(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential master key %q: %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("credential master key %q must be 32 bytes (64 hex chars), got %d bytes", id, len(key))
+		}
+		keys[id] = key
+	}
+	if _, ok := keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("credential master key %q not found", activeKeyID)
+	}
+	return &localMasterKeyProvider{activeKeyID: activeKeyID, keys: keys}, nil
+}
+
+func (p *localMasterKeyProvider) ActiveKeyID() string {
+	return p.activeKeyID
+}
+
+// WrapDataKey output format: nonce + ciphertext + tag
+func (p *localMasterKeyProvider) WrapDataKey(_ context.Context, dataKey []byte) (string, []byte, error) {
+	gcm, err := p.gcm(p.activeKeyID)
+	if err != nil {
+		return "", nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return p.activeKeyID, gcm.Seal(nonce, nonce, dataKey, []byte(p.activeKeyID)), nil
+}
+
+func (p *localMasterKeyProvider) UnwrapDataKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	gcm, err := p.gcm(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < gcm.NonceSize() {
+		return nil, fmt.Errorf("wrapped data key too short")
+	}
+	dataKey, err := gcm.Open(nil, wrapped[:gcm.NonceSize()], wrapped[gcm.NonceSize():], []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key: %w", err)
+	}
+	return dataKey, nil
+}
+
+func (p *localMasterKeyProvider) gcm(keyID string) (cipher.AEAD, error) {
+	key, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("credential master key %q not found", keyID)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,73 @@
+//go:build unit
+
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func masterKeyTestCfg(enc config.CredentialEncryptionConfig) *config.Config {
+	return &config.Config{Security: config.SecurityConfig{CredentialEncryption: enc}}
+}
+
+func TestProvideMasterKeyProvider_DisabledReturnsNil(t *testing.T) {
+	provider, err := ProvideMasterKeyProvider(masterKeyTestCfg(config.CredentialEncryptionConfig{}))
+	require.NoError(t, err)
+	require.Nil(t, provider)
+}
+
+func TestLocalMasterKeyProvider_WrapAndUnwrapAcrossRotation(t *testing.T) {
+	ctx := context.Background()
+	oldProvider, err := ProvideMasterKeyProvider(masterKeyTestCfg(config.CredentialEncryptionConfig{
+		Enabled: true, Provider: "local", ActiveKeyID: "k1", MasterKey: aesHexKey(32, 0x11),
+	}))
+	require.NoError(t, err)
+	keyID, wrapped, err := oldProvider.WrapDataKey(ctx, []byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	require.Equal(t, "k1", keyID)
+
+	rotated, err := ProvideMasterKeyProvider(masterKeyTestCfg(config.CredentialEncryptionConfig{
+		Enabled: true, Provider: "local", ActiveKeyID: "k2", MasterKey: aesHexKey(32, 0x22),
+		PreviousMasterKeys: map[string]string{"k1": aesHexKey(32, 0x11)},
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "k2", rotated.ActiveKeyID())
+	dataKey, err := rotated.UnwrapDataKey(ctx, keyID, wrapped)
+	require.NoError(t, err)
+	require.Equal(t, []byte("0123456789abcdef0123456789abcdef"), dataKey)
+
+	// 密钥 ID 作为 AAD 绑定，换用其他 ID 解包必须失败。
+	_, err = rotated.UnwrapDataKey(ctx, "k2", wrapped)
+	require.Error(t, err)
+	_, err = rotated.UnwrapDataKey(ctx, "missing", wrapped)
+	require.Error(t, err)
+}
+
+func TestProvideMasterKeyProvider_KeyFileOverridesInlineKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	content := `{"active_key_id":"file-key","keys":{"file-key":"` + aesHexKey(32, 0x33) + `"}}`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	provider, err := ProvideMasterKeyProvider(masterKeyTestCfg(config.CredentialEncryptionConfig{
+		Enabled: true, Provider: "local", ActiveKeyID: "inline", MasterKey: aesHexKey(32, 0x44), KeyFile: path,
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "file-key", provider.ActiveKeyID())
+}
+
+func TestNewLocalMasterKeyProvider_RejectsInvalidKeys(t *testing.T) {
+	_, err := newLocalMasterKeyProvider("k1", map[string]string{"k1": aesHexKey(16, 0x11)})
+	require.Error(t, err)
+	_, err = newLocalMasterKeyProvider("k1", map[string]string{"k1": "not-hex"})
+	require.Error(t, err)
+	_, err = newLocalMasterKeyProvider("a:b", map[string]string{"a:b": aesHexKey(32, 0x11)})
+	require.Error(t, err)
+	_, err = newLocalMasterKeyProvider("k2", map[string]string{"k1": aesHexKey(32, 0x11)})
+	require.Error(t, err)
+}
@@ -0,0 +1,343 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/lib/pq"
+)
+
+// proxyPoolRepository 代理池、成员、自动分配账号与改投审计（raw SQL）。
+type proxyPoolRepository struct {
+	db *sql.DB
+}
+
+// NewProxyPoolRepository 创建代理池仓储。
+func NewProxyPoolRepository(db *sql.DB) service.ProxyPoolRepository {
+	return &proxyPoolRepository{db: db}
+}
+
+const proxyPoolSelectColumns = `
+	p.id, p.name, p.region, p.tags, p.max_accounts_per_proxy, p.created_at, p.updated_at,
+	COALESCE((SELECT array_agg(m.proxy_id ORDER BY m.proxy_id) FROM proxy_pool_members m WHERE m.pool_id = p.id), '{}') AS proxy_ids,
+	(SELECT COUNT(*) FROM account_proxy_pools ap WHERE ap.pool_id = p.id) AS account_count`
+
+func (r *proxyPoolRepository) List(ctx context.Context) ([]*service.ProxyPool, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT`+proxyPoolSelectColumns+` FROM proxy_pools p ORDER BY p.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]*service.ProxyPool, 0)
+	for rows.Next() {
+		pool, err := scanProxyPool(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pool)
+	}
+	return out, rows.Err()
+}
+
+func (r *proxyPoolRepository) GetByID(ctx context.Context, id int64) (*service.ProxyPool, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT`+proxyPoolSelectColumns+` FROM proxy_pools p WHERE p.id = $1`, id)
+	pool, err := scanProxyPool(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, service.ErrProxyPoolNotFound
+	}
+	return pool, err
+}
+
+func (r *proxyPoolRepository) Create(ctx context.Context, pool *service.ProxyPool) error {
+	tags, err := json.Marshal(pool.Tags)
+	if err != nil {
+		return err
+	}
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO proxy_pools (name, region, tags, max_accounts_per_proxy, created_at, updated_at)
+		VALUES ($1, $2, $3::jsonb, $4, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`, pool.Name, pool.Region, string(tags), pool.MaxAccountsPerProxy).Scan(&pool.ID, &pool.CreatedAt, &pool.UpdatedAt)
+}
+
+func (r *proxyPoolRepository) Update(ctx context.Context, pool *service.ProxyPool) error {
+	tags, err := json.Marshal(pool.Tags)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE proxy_pools SET name = $2, region = $3, tags = $4::jsonb, max_accounts_per_proxy = $5, updated_at = NOW()
+		WHERE id = $1
+	`, pool.ID, pool.Name, pool.Region, string(tags), pool.MaxAccountsPerProxy)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return service.ErrProxyPoolNotFound
+	}
+	return nil
+}
+
+func (r *proxyPoolRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM proxy_pools WHERE id = $1`, id)
+	return err
+}
+
+func (r *proxyPoolRepository) SetMembers(ctx context.Context, poolID int64, proxyIDs []int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM proxy_pool_members WHERE pool_id = $1 AND NOT (proxy_id = ANY($2))`, poolID, pq.Array(proxyIDs)); err != nil {
+		return err
+	}
+	if len(proxyIDs) > 0 {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO proxy_pool_members (pool_id, proxy_id)
+			SELECT $1, unnest($2::bigint[])
+			ON CONFLICT (pool_id, proxy_id) DO NOTHING
+		`, poolID, pq.Array(proxyIDs)); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE proxy_pools SET updated_at = NOW() WHERE id = $1`, poolID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *proxyPoolRepository) ListMemberIDs(ctx context.Context, poolID int64) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT proxy_id FROM proxy_pool_members WHERE pool_id = $1 ORDER BY proxy_id`, poolID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *proxyPoolRepository) BindAccount(ctx context.Context, accountID int64, poolID *int64) error {
+	if poolID == nil {
+		_, err := r.db.ExecContext(ctx, `DELETE FROM account_proxy_pools WHERE account_id = $1`, accountID)
+		return err
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO account_proxy_pools (account_id, pool_id) VALUES ($1, $2)
+		ON CONFLICT (account_id) DO UPDATE SET pool_id = EXCLUDED.pool_id
+	`, accountID, *poolID)
+	return err
+}
+
+func (r *proxyPoolRepository) GetAccountPoolID(ctx context.Context, accountID int64) (*int64, error) {
+	var poolID int64
+	err := r.db.QueryRowContext(ctx, `SELECT pool_id FROM account_proxy_pools WHERE account_id = $1`, accountID).Scan(&poolID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &poolID, nil
+}
+
+func (r *proxyPoolRepository) ListAccounts(ctx context.Context, poolID int64) ([]service.ProxyPoolAccount, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT a.id, a.proxy_id
+		FROM account_proxy_pools ap
+		JOIN accounts a ON a.id = ap.account_id AND a.deleted_at IS NULL
+		WHERE ap.pool_id = $1
+		ORDER BY a.id
+	`, poolID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]service.ProxyPoolAccount, 0)
+	for rows.Next() {
+		var (
+			item    service.ProxyPoolAccount
+			proxyID sql.NullInt64
+		)
+		if err := rows.Scan(&item.AccountID, &proxyID); err != nil {
+			return nil, err
+		}
+		if proxyID.Valid {
+			id := proxyID.Int64
+			item.ProxyID = &id
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func (r *proxyPoolRepository) CountAccountsByProxyIDs(ctx context.Context, proxyIDs []int64) (map[int64]int64, error) {
+	out := make(map[int64]int64, len(proxyIDs))
+	if len(proxyIDs) == 0 {
+		return out, nil
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT proxy_id, COUNT(*) FROM accounts
+		WHERE proxy_id = ANY($1) AND deleted_at IS NULL
+		GROUP BY proxy_id
+	`, pq.Array(proxyIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var proxyID, count int64
+		if err := rows.Scan(&proxyID, &count); err != nil {
+			return nil, err
+		}
+		out[proxyID] = count
+	}
+	return out, rows.Err()
+}
+
+// ReassignAccounts 改写账号 proxy_id 并写入改投记录。UPDATE 以 from_proxy_id 作乐观校验，
+// 期间被管理员手动改过代理的账号不会被覆盖，也不写审计记录。
+// 与 SweepExpiredProxies 一致，scheduler outbox 在事务提交后单独 enqueue。
+func (r *proxyPoolRepository) ReassignAccounts(ctx context.Context, moves []*service.ProxyReassignment) error {
+	if len(moves) == 0 {
+		return nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	changed := make([]int64, 0, len(moves))
+	for _, move := range moves {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE accounts SET proxy_id = $2, updated_at = NOW()
+			WHERE id = $1 AND deleted_at IS NULL AND proxy_id IS NOT DISTINCT FROM $3::bigint
+		`, move.AccountID, move.ToProxyID, move.FromProxyID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO proxy_reassignment_logs (account_id, pool_id, from_proxy_id, to_proxy_id, reason)
+			VALUES ($1, $2, $3, $4, $5)
+		`, move.AccountID, move.PoolID, move.FromProxyID, move.ToProxyID, move.Reason); err != nil {
+			return err
+		}
+		changed = append(changed, move.AccountID)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if len(changed) > 0 {
+		payload := map[string]any{"account_ids": sortedUniqueAccountIDs(changed)}
+		if err := enqueueSchedulerOutbox(ctx, r.db, service.SchedulerOutboxEventAccountBulkChanged, nil, nil, payload); err != nil {
+			logger.LegacyPrintf("repository.proxy_pool", "[SchedulerOutbox] enqueue proxy pool reassignment failed: err=%v", err)
+		}
+	}
+	return nil
+}
+
+func (r *proxyPoolRepository) ListReassignments(ctx context.Context, params pagination.PaginationParams, filter service.ProxyReassignmentFilter) ([]*service.ProxyReassignment, *pagination.PaginationResult, error) {
+	conditions := make([]string, 0, 2)
+	args := make([]any, 0, 4)
+	if filter.AccountID > 0 {
+		args = append(args, filter.AccountID)
+		conditions = append(conditions, fmt.Sprintf("account_id = $%d", len(args)))
+	}
+	if filter.PoolID > 0 {
+		args = append(args, filter.PoolID)
+		conditions = append(conditions, fmt.Sprintf("pool_id = $%d", len(args)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM proxy_reassignment_logs`+where, args...).Scan(&total); err != nil {
+		return nil, nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, account_id, pool_id, from_proxy_id, to_proxy_id, reason, created_at
+		FROM proxy_reassignment_logs%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	rows, err := r.db.QueryContext(ctx, query, append(args, params.Limit(), params.Offset())...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]*service.ProxyReassignment, 0)
+	for rows.Next() {
+		var (
+			item                 service.ProxyReassignment
+			poolID, from, target sql.NullInt64
+		)
+		if err := rows.Scan(&item.ID, &item.AccountID, &poolID, &from, &target, &item.Reason, &item.CreatedAt); err != nil {
+			return nil, nil, err
+		}
+		item.PoolID = proxyPoolNullableID(poolID)
+		item.FromProxyID = proxyPoolNullableID(from)
+		item.ToProxyID = proxyPoolNullableID(target)
+		out = append(out, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	return out, paginationResultFromTotal(total, params), nil
+}
+
+func scanProxyPool(scan func(dest ...any) error) (*service.ProxyPool, error) {
+	var (
+		pool     service.ProxyPool
+		tagsJSON []byte
+		proxyIDs pq.Int64Array
+	)
+	if err := scan(&pool.ID, &pool.Name, &pool.Region, &tagsJSON, &pool.MaxAccountsPerProxy, &pool.CreatedAt, &pool.UpdatedAt, &proxyIDs, &pool.AccountCount); err != nil {
+		return nil, err
+	}
+	pool.Tags = []string{}
+	if len(tagsJSON) > 0 {
+		if err := json.Unmarshal(tagsJSON, &pool.Tags); err != nil {
+			return nil, err
+		}
+	}
+	pool.ProxyIDs = []int64(proxyIDs)
+	if pool.ProxyIDs == nil {
+		pool.ProxyIDs = []int64{}
+	}
+	return &pool, nil
+}
+
+func proxyPoolNullableID(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	id := v.Int64
+	return &id
+}
@@ -21,6 +21,8 @@ import (
 type proxyRepository struct {
 	client *dbent.Client
 	sql    sqlExecutor
+	// credentials 加密代理密码；为 nil 或未配置主密钥时按明文读写。
+	credentials *service.CredentialCipher
 }
 
 const proxyProbeOutboxAccountChunkSize = 500
@@ -29,11 +31,22 @@ func NewProxyRepository(client *dbent.Client, sqlDB *sql.DB) service.ProxyReposi
 	return newProxyRepositoryWithSQL(client, sqlDB)
 }
 
+// ProvideProxyRepository 创建启用代理密码静态加密的代理仓储。
+func ProvideProxyRepository(client *dbent.Client, sqlDB *sql.DB, credentials *service.CredentialCipher) service.ProxyRepository {
+	repo := newProxyRepositoryWithSQL(client, sqlDB)
+	repo.credentials = credentials
+	return repo
+}
+
 func newProxyRepositoryWithSQL(client *dbent.Client, sqlq sqlExecutor) *proxyRepository {
 	return &proxyRepository{client: client, sql: sqlq}
 }
 
 func (r *proxyRepository) Create(ctx context.Context, proxyIn *service.Proxy) error {
+	password, err := r.credentials.SealString(ctx, proxyIn.Password)
+	if err != nil {
+		return err
+	}
 	builder := r.client.Proxy.Create().
 		SetName(proxyIn.Name).
 		SetProtocol(proxyIn.Protocol).
@@ -45,8 +58,8 @@ func (r *proxyRepository) Create(ctx context.Context, proxyIn *service.Proxy) er
 	if proxyIn.Username != "" {
 		builder.SetUsername(proxyIn.Username)
 	}
-	if proxyIn.Password != "" {
-		builder.SetPassword(proxyIn.Password)
+	if password != "" {
+		builder.SetPassword(password)
 	}
 	if proxyIn.ExpiresAt != nil {
 		builder.SetExpiresAt(*proxyIn.ExpiresAt)
@@ -70,7 +83,7 @@ func (r *proxyRepository) GetByID(ctx context.Context, id int64) (*service.Proxy
 		}
 		return nil, err
 	}
-	return proxyEntityToService(m), nil
+	return r.proxyToService(ctx, m), nil
 }
 
 func (r *proxyRepository) ListByIDs(ctx context.Context, ids []int64) ([]service.Proxy, error) {
@@ -87,7 +100,7 @@ func (r *proxyRepository) ListByIDs(ctx context.Context, ids []int64) ([]service
 
 	out := make([]service.Proxy, 0, len(proxies))
 	for i := range proxies {
-		out = append(out, *proxyEntityToService(proxies[i]))
+		out = append(out, *r.proxyToService(ctx, proxies[i]))
 	}
 	return out, nil
 }
@@ -110,7 +123,7 @@ func (r *proxyRepository) Update(ctx context.Context, proxyIn *service.Proxy) er
 		}
 	}
 
-	updated, err := updateProxyAndInvalidateProbeSnapshots(ctx, client, proxyIn)
+	updated, err := updateProxyAndInvalidateProbeSnapshots(ctx, client, proxyIn, r.credentials)
 	if err != nil {
 		return err
 	}
@@ -143,11 +156,18 @@ func proxyProbeIdentityFromService(proxyIn *service.Proxy) proxyProbeIdentity {
 	}
 }
 
-func updateProxyAndInvalidateProbeSnapshots(ctx context.Context, client *dbent.Client, proxyIn *service.Proxy) (*dbent.Proxy, error) {
-	currentIdentity, err := lockProxyProbeIdentity(ctx, client, proxyIn.ID)
+func updateProxyAndInvalidateProbeSnapshots(ctx context.Context, client *dbent.Client, proxyIn *service.Proxy, cipher *service.CredentialCipher) (*dbent.Proxy, error) {
+	currentIdentity, storedPassword, err := lockProxyProbeIdentity(ctx, client, proxyIn.ID, cipher)
 	if err != nil {
 		return nil, err
 	}
+	// 密码未变化时沿用已存储的密文。
+	password := storedPassword
+	if proxyIn.Password != currentIdentity.password {
+		if password, err = cipher.SealString(ctx, proxyIn.Password); err != nil {
+			return nil, err
+		}
+	}
 	builder := client.Proxy.UpdateOneID(proxyIn.ID).
 		SetName(proxyIn.Name).
 		SetProtocol(proxyIn.Protocol).
@@ -161,8 +181,8 @@ func updateProxyAndInvalidateProbeSnapshots(ctx context.Context, client *dbent.C
 	} else {
 		builder.ClearUsername()
 	}
-	if proxyIn.Password != "" {
-		builder.SetPassword(proxyIn.Password)
+	if password != "" {
+		builder.SetPassword(password)
 	} else {
 		builder.ClearPassword()
 	}
@@ -197,7 +217,8 @@ func updateProxyAndInvalidateProbeSnapshots(ctx context.Context, client *dbent.C
 	return updated, nil
 }
 
-func lockProxyProbeIdentity(ctx context.Context, client *dbent.Client, proxyID int64) (proxyProbeIdentity, error) {
+// lockProxyProbeIdentity 返回解密后的代理身份及数据库中存储的密码（可能为密文）。
+func lockProxyProbeIdentity(ctx context.Context, client *dbent.Client, proxyID int64, cipher *service.CredentialCipher) (proxyProbeIdentity, string, error) {
 	rows, err := client.QueryContext(ctx, `
 		SELECT protocol, host, port, COALESCE(username, ''), COALESCE(password, ''), status
 		FROM proxies
@@ -205,20 +226,24 @@ func lockProxyProbeIdentity(ctx context.Context, client *dbent.Client, proxyID i
 		FOR NO KEY UPDATE
 	`, proxyID)
 	if err != nil {
-		return proxyProbeIdentity{}, err
+		return proxyProbeIdentity{}, "", err
 	}
 	defer func() { _ = rows.Close() }()
 	if !rows.Next() {
 		if err := rows.Err(); err != nil {
-			return proxyProbeIdentity{}, err
+			return proxyProbeIdentity{}, "", err
 		}
-		return proxyProbeIdentity{}, service.ErrProxyNotFound
+		return proxyProbeIdentity{}, "", service.ErrProxyNotFound
 	}
 	var identity proxyProbeIdentity
 	if err := rows.Scan(&identity.protocol, &identity.host, &identity.port, &identity.username, &identity.password, &identity.status); err != nil {
-		return proxyProbeIdentity{}, err
+		return proxyProbeIdentity{}, "", err
+	}
+	storedPassword := identity.password
+	if identity.password, err = cipher.OpenString(ctx, storedPassword); err != nil {
+		return proxyProbeIdentity{}, "", err
 	}
-	return identity, rows.Err()
+	return identity, storedPassword, rows.Err()
 }
 
 func invalidateProxyProbeSnapshots(ctx context.Context, exec sqlExecutor, proxyID int64) ([]int64, error) {
@@ -314,7 +339,7 @@ func (r *proxyRepository) ListWithFilters(ctx context.Context, params pagination
 
 	outProxies := make([]service.Proxy, 0, len(proxies))
 	for i := range proxies {
-		outProxies = append(outProxies, *proxyEntityToService(proxies[i]))
+		outProxies = append(outProxies, *r.proxyToService(ctx, proxies[i]))
 	}
 
 	return outProxies, paginationResultFromTotal(int64(total), params), nil
@@ -392,7 +417,7 @@ func (r *proxyRepository) buildProxyWithAccountCountResult(ctx context.Context,
 
 	result := make([]service.ProxyWithAccountCount, 0, len(proxies))
 	for i := range proxies {
-		proxyOut := proxyEntityToService(proxies[i])
+		proxyOut := r.proxyToService(ctx, proxies[i])
 		if proxyOut == nil {
 			continue
 		}
@@ -444,7 +469,7 @@ func (r *proxyRepository) ListActive(ctx context.Context) ([]service.Proxy, erro
 	}
 	outProxies := make([]service.Proxy, 0, len(proxies))
 	for i := range proxies {
-		outProxies = append(outProxies, *proxyEntityToService(proxies[i]))
+		outProxies = append(outProxies, *r.proxyToService(ctx, proxies[i]))
 	}
 	return outProxies, nil
 }
@@ -461,6 +486,18 @@ func (r *proxyRepository) ExistsByHostPortAuth(ctx context.Context, host string,
 	}
 	if password == "" {
 		q = q.Where(proxy.Or(proxy.PasswordIsNil(), proxy.PasswordEQ("")))
+	} else if r.credentials.Enabled() {
+		// 密文无法在 SQL 中比较，取同主机端口的候选逐条解密比对。
+		candidates, err := q.Where(proxy.PasswordNotNil()).All(ctx)
+		if err != nil {
+			return false, err
+		}
+		for _, candidate := range candidates {
+			if r.proxyToService(ctx, candidate).Password == password {
+				return true, nil
+			}
+		}
+		return false, nil
 	} else {
 		q = q.Where(proxy.PasswordEQ(password))
 	}
@@ -566,7 +603,7 @@ func (r *proxyRepository) ListActiveWithAccountCount(ctx context.Context) ([]ser
 	// Build result with account counts
 	result := make([]service.ProxyWithAccountCount, 0, len(proxies))
 	for i := range proxies {
-		proxyOut := proxyEntityToService(proxies[i])
+		proxyOut := r.proxyToService(ctx, proxies[i])
 		if proxyOut == nil {
 			continue
 		}
@@ -579,6 +616,26 @@ func (r *proxyRepository) ListActiveWithAccountCount(ctx context.Context) ([]ser
 	return result, nil
 }
 
+// proxyToService 转换实体并解密代理密码。
+func (r *proxyRepository) proxyToService(ctx context.Context, m *dbent.Proxy) *service.Proxy {
+	out := proxyEntityToService(m)
+	openProxySecret(ctx, r.credentials, out)
+	return out
+}
+
+// openProxySecret 解密代理密码；解密失败只记录日志并保留密文。
+func openProxySecret(ctx context.Context, cipher *service.CredentialCipher, proxyOut *service.Proxy) {
+	if proxyOut == nil || !service.IsCredentialEnvelope(proxyOut.Password) {
+		return
+	}
+	password, err := cipher.OpenString(ctx, proxyOut.Password)
+	if err != nil {
+		logger.LegacyPrintf("repository.proxy", "[CredentialEncryption] open proxy password failed: proxy=%d err=%v", proxyOut.ID, err)
+		return
+	}
+	proxyOut.Password = password
+}
+
 func proxyEntityToService(m *dbent.Proxy) *service.Proxy {
 	if m == nil {
 		return nil
@@ -623,7 +680,7 @@ func (r *proxyRepository) ListAllForFallback(ctx context.Context) ([]service.Pro
 	}
 	out := make([]service.Proxy, 0, len(proxies))
 	for i := range proxies {
-		out = append(out, *proxyEntityToService(proxies[i]))
+		out = append(out, *r.proxyToService(ctx, proxies[i]))
 	}
 	return out, nil
 }
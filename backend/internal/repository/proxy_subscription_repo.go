@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/lib/pq"
+)
+
+// proxySubscriptionRepository 代理订阅源及其导入代理归属（raw SQL）。
+type proxySubscriptionRepository struct {
+	db *sql.DB
+}
+
+// NewProxySubscriptionRepository 创建代理订阅仓储。
+func NewProxySubscriptionRepository(db *sql.DB) service.ProxySubscriptionRepository {
+	return &proxySubscriptionRepository{db: db}
+}
+
+const proxySubscriptionSelectColumns = `
+	s.id, s.name, s.url, s.format, s.interval_minutes, s.enabled, s.last_synced_at, s.last_error, s.expires_at,
+	s.created_at, s.updated_at,
+	(SELECT COUNT(*) FROM proxy_subscription_items i WHERE i.subscription_id = s.id) AS proxy_count`
+
+func (r *proxySubscriptionRepository) List(ctx context.Context) ([]*service.ProxySubscription, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT`+proxySubscriptionSelectColumns+` FROM proxy_subscriptions s ORDER BY s.id`)
+	if err != nil {
+		return nil, err
+	}
+	return scanProxySubscriptionRows(rows)
+}
+
+func (r *proxySubscriptionRepository) GetByID(ctx context.Context, id int64) (*service.ProxySubscription, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT`+proxySubscriptionSelectColumns+` FROM proxy_subscriptions s WHERE s.id = $1`, id)
+	sub, err := scanProxySubscription(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, service.ErrProxySubscriptionNotFound
+	}
+	return sub, err
+}
+
+func (r *proxySubscriptionRepository) Create(ctx context.Context, sub *service.ProxySubscription) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO proxy_subscriptions (name, url, format, interval_minutes, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`, sub.Name, sub.URL, sub.Format, sub.IntervalMinutes, sub.Enabled).Scan(&sub.ID, &sub.CreatedAt, &sub.UpdatedAt)
+}
+
+func (r *proxySubscriptionRepository) Update(ctx context.Context, sub *service.ProxySubscription) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE proxy_subscriptions SET name = $2, url = $3, format = $4, interval_minutes = $5, enabled = $6, updated_at = NOW()
+		WHERE id = $1
+	`, sub.ID, sub.Name, sub.URL, sub.Format, sub.IntervalMinutes, sub.Enabled)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return service.ErrProxySubscriptionNotFound
+	}
+	return nil
+}
+
+func (r *proxySubscriptionRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM proxy_subscriptions WHERE id = $1`, id)
+	return err
+}
+
+func (r *proxySubscriptionRepository) ClaimDue(ctx context.Context) ([]*service.ProxySubscription, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH claimed AS (
+			UPDATE proxy_subscriptions SET last_synced_at = NOW()
+			WHERE enabled
+			  AND (last_synced_at IS NULL OR last_synced_at + make_interval(mins => interval_minutes) <= NOW())
+			RETURNING id
+		)
+		SELECT`+proxySubscriptionSelectColumns+`
+		FROM proxy_subscriptions s JOIN claimed c ON c.id = s.id
+		ORDER BY s.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return scanProxySubscriptionRows(rows)
+}
+
+func (r *proxySubscriptionRepository) RecordSyncResult(ctx context.Context, id int64, expiresAt *time.Time, lastError string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE proxy_subscriptions SET last_synced_at = NOW(), expires_at = $2, last_error = $3
+		WHERE id = $1
+	`, id, expiresAt, lastError)
+	return err
+}
+
+func (r *proxySubscriptionRepository) ListProxyIDs(ctx context.Context, subscriptionID int64) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT proxy_id FROM proxy_subscription_items WHERE subscription_id = $1 ORDER BY proxy_id`, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *proxySubscriptionRepository) SetProxyIDs(ctx context.Context, subscriptionID int64, proxyIDs []int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM proxy_subscription_items WHERE subscription_id = $1 AND NOT (proxy_id = ANY($2))`, subscriptionID, pq.Array(proxyIDs)); err != nil {
+		return err
+	}
+	if len(proxyIDs) > 0 {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO proxy_subscription_items (subscription_id, proxy_id)
+			SELECT $1, unnest($2::bigint[])
+			ON CONFLICT (subscription_id, proxy_id) DO NOTHING
+		`, subscriptionID, pq.Array(proxyIDs)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func scanProxySubscriptionRows(rows *sql.Rows) ([]*service.ProxySubscription, error) {
+	defer func() { _ = rows.Close() }()
+
+	out := make([]*service.ProxySubscription, 0)
+	for rows.Next() {
+		sub, err := scanProxySubscription(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sub)
+	}
+	return out, rows.Err()
+}
+
+func scanProxySubscription(scan func(dest ...any) error) (*service.ProxySubscription, error) {
+	var (
+		sub          service.ProxySubscription
+		lastSyncedAt sql.NullTime
+		expiresAt    sql.NullTime
+	)
+	if err := scan(
+		&sub.ID, &sub.Name, &sub.URL, &sub.Format, &sub.IntervalMinutes, &sub.Enabled, &lastSyncedAt, &sub.LastError, &expiresAt,
+		&sub.CreatedAt, &sub.UpdatedAt, &sub.ProxyCount,
+	); err != nil {
+		return nil, err
+	}
+	if lastSyncedAt.Valid {
+		t := lastSyncedAt.Time
+		sub.LastSyncedAt = &t
+	}
+	if expiresAt.Valid {
+		t := expiresAt.Time
+		sub.ExpiresAt = &t
+	}
+	return &sub, nil
+}
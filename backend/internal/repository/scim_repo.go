@@ -0,0 +1,372 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+)
+
+// scimRepository SCIM 用户 / 分组关联与成员授权记录仓储（raw SQL）。
+type scimRepository struct {
+	db *sql.DB
+}
+
+func NewSCIMRepository(db *sql.DB) service.SCIMRepository {
+	return &scimRepository{db: db}
+}
+
+type scimScanner interface {
+	Scan(dest ...any) error
+}
+
+const scimUserSelect = `
+	SELECT u.id, u.email, u.username, u.status, u.role, COALESCE(s.external_id, ''), u.created_at, u.updated_at
+	FROM users u
+	LEFT JOIN scim_users s ON s.user_id = u.id
+	WHERE u.deleted_at IS NULL
+`
+
+// scimUserFilterClause 把已校验的过滤属性映射为 SQL 条件；id 非数字时返回恒假条件。
+func scimUserFilterClause(filter *service.SCIMFilter) (string, []any) {
+	if filter == nil {
+		return "", nil
+	}
+	switch filter.Attribute {
+	case "username":
+		return " AND LOWER(u.email) = LOWER($1)", []any{filter.Value}
+	case "externalid":
+		return " AND s.external_id = $1", []any{filter.Value}
+	case "id":
+		id, err := strconv.ParseInt(filter.Value, 10, 64)
+		if err != nil {
+			return " AND FALSE", nil
+		}
+		return " AND u.id = $1", []any{id}
+	}
+	return " AND FALSE", nil
+}
+
+func (r *scimRepository) ListUsers(ctx context.Context, filter *service.SCIMFilter, offset, limit int) ([]service.SCIMUser, int64, error) {
+	clause, args := scimUserFilterClause(filter)
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM users u
+		LEFT JOIN scim_users s ON s.user_id = u.id
+		WHERE u.deleted_at IS NULL`+clause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count scim users: %w", err)
+	}
+	if limit <= 0 || int64(offset) >= total {
+		return []service.SCIMUser{}, total, nil
+	}
+
+	query := scimUserSelect + clause + fmt.Sprintf(" ORDER BY u.id LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list scim users: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	users := make([]service.SCIMUser, 0, limit)
+	for rows.Next() {
+		var user service.SCIMUser
+		if err := rows.Scan(&user.ID, &user.Email, &user.Username, &user.Status, &user.Role, &user.ExternalID, &user.CreatedAt, &user.UpdatedAt); err != nil {
+			return nil, 0, fmt.Errorf("scan scim user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("list scim users: %w", err)
+	}
+	return users, total, nil
+}
+
+func (r *scimRepository) GetUser(ctx context.Context, userID int64) (*service.SCIMUser, error) {
+	var user service.SCIMUser
+	err := r.db.QueryRowContext(ctx, scimUserSelect+" AND u.id = $1", userID).
+		Scan(&user.ID, &user.Email, &user.Username, &user.Status, &user.Role, &user.ExternalID, &user.CreatedAt, &user.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrSCIMUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get scim user: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT g.id, g.name
+		FROM scim_group_members m
+		JOIN scim_groups sg ON sg.group_id = m.group_id
+		JOIN groups g ON g.id = m.group_id AND g.deleted_at IS NULL
+		WHERE m.user_id = $1
+		ORDER BY g.id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list scim user groups: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var ref service.SCIMGroupRef
+		if err := rows.Scan(&ref.ID, &ref.Name); err != nil {
+			return nil, fmt.Errorf("scan scim user group: %w", err)
+		}
+		user.Groups = append(user.Groups, ref)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list scim user groups: %w", err)
+	}
+	return &user, nil
+}
+
+func (r *scimRepository) UpsertUserLink(ctx context.Context, userID int64, externalID string) error {
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO scim_users (user_id, external_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET external_id = EXCLUDED.external_id, updated_at = NOW()
+	`, userID, externalID); err != nil {
+		return fmt.Errorf("upsert scim user link: %w", err)
+	}
+	return nil
+}
+
+func (r *scimRepository) DeleteUserLink(ctx context.Context, userID int64) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM scim_users WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("delete scim user link: %w", err)
+	}
+	return nil
+}
+
+func (r *scimRepository) DisableUserAPIKeys(ctx context.Context, userID int64) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys
+		SET status = $2, updated_at = NOW()
+		WHERE user_id = $1 AND deleted_at IS NULL AND status <> $2
+	`, userID, service.StatusAPIKeyDisabled)
+	if err != nil {
+		return 0, fmt.Errorf("disable scim user api keys: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("disable scim user api keys: %w", err)
+	}
+	return affected, nil
+}
+
+func (r *scimRepository) FindGroupIDByName(ctx context.Context, name string) (int64, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id FROM groups WHERE name = $1 AND deleted_at IS NULL ORDER BY id LIMIT 1
+	`, name).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, service.ErrSCIMGroupNotMapped
+	}
+	if err != nil {
+		return 0, fmt.Errorf("find scim group by name: %w", err)
+	}
+	return id, nil
+}
+
+const scimGroupSelect = `
+	SELECT g.id, g.name, sg.external_id, sg.created_at, GREATEST(sg.updated_at, g.updated_at)
+	FROM scim_groups sg
+	JOIN groups g ON g.id = sg.group_id
+	WHERE g.deleted_at IS NULL
+`
+
+func scimGroupFilterClause(filter *service.SCIMFilter) (string, []any) {
+	if filter == nil {
+		return "", nil
+	}
+	switch filter.Attribute {
+	case "displayname":
+		return " AND g.name = $1", []any{filter.Value}
+	case "externalid":
+		return " AND sg.external_id = $1", []any{filter.Value}
+	case "id":
+		id, err := strconv.ParseInt(filter.Value, 10, 64)
+		if err != nil {
+			return " AND FALSE", nil
+		}
+		return " AND g.id = $1", []any{id}
+	}
+	return " AND FALSE", nil
+}
+
+func (r *scimRepository) ListGroups(ctx context.Context, filter *service.SCIMFilter, offset, limit int) ([]service.SCIMGroup, int64, error) {
+	clause, args := scimGroupFilterClause(filter)
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM scim_groups sg
+		JOIN groups g ON g.id = sg.group_id
+		WHERE g.deleted_at IS NULL`+clause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count scim groups: %w", err)
+	}
+	if limit <= 0 || int64(offset) >= total {
+		return []service.SCIMGroup{}, total, nil
+	}
+
+	query := scimGroupSelect + clause + fmt.Sprintf(" ORDER BY g.id LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list scim groups: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	groups := make([]service.SCIMGroup, 0, limit)
+	for rows.Next() {
+		var group service.SCIMGroup
+		if err := rows.Scan(&group.ID, &group.Name, &group.ExternalID, &group.CreatedAt, &group.UpdatedAt); err != nil {
+			return nil, 0, fmt.Errorf("scan scim group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("list scim groups: %w", err)
+	}
+	return groups, total, nil
+}
+
+func (r *scimRepository) GetGroup(ctx context.Context, groupID int64) (*service.SCIMGroup, error) {
+	var group service.SCIMGroup
+	err := r.db.QueryRowContext(ctx, scimGroupSelect+" AND g.id = $1", groupID).
+		Scan(&group.ID, &group.Name, &group.ExternalID, &group.CreatedAt, &group.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrSCIMGroupNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get scim group: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT u.id, u.email
+		FROM scim_group_members m
+		JOIN users u ON u.id = m.user_id AND u.deleted_at IS NULL
+		WHERE m.group_id = $1
+		ORDER BY u.id
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("list scim group members: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var member service.SCIMMember
+		if err := rows.Scan(&member.UserID, &member.Email); err != nil {
+			return nil, fmt.Errorf("scan scim group member: %w", err)
+		}
+		group.Members = append(group.Members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list scim group members: %w", err)
+	}
+	return &group, nil
+}
+
+func (r *scimRepository) UpsertGroupLink(ctx context.Context, groupID int64, externalID string) error {
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO scim_groups (group_id, external_id)
+		VALUES ($1, $2)
+		ON CONFLICT (group_id) DO UPDATE SET external_id = EXCLUDED.external_id, updated_at = NOW()
+	`, groupID, externalID); err != nil {
+		return fmt.Errorf("upsert scim group link: %w", err)
+	}
+	return nil
+}
+
+func (r *scimRepository) DeleteGroupLink(ctx context.Context, groupID int64) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM scim_groups WHERE group_id = $1`, groupID); err != nil {
+		return fmt.Errorf("delete scim group link: %w", err)
+	}
+	return nil
+}
+
+func scanSCIMMembership(scanner scimScanner) (*service.SCIMMembership, error) {
+	var (
+		membership     service.SCIMMembership
+		subscriptionID sql.NullInt64
+	)
+	if err := scanner.Scan(&membership.GroupID, &membership.UserID, &subscriptionID, &membership.GrantedAllowedGroup); err != nil {
+		return nil, err
+	}
+	if subscriptionID.Valid {
+		id := subscriptionID.Int64
+		membership.SubscriptionID = &id
+	}
+	return &membership, nil
+}
+
+func (r *scimRepository) GetMembership(ctx context.Context, groupID, userID int64) (*service.SCIMMembership, error) {
+	membership, err := scanSCIMMembership(r.db.QueryRowContext(ctx, `
+		SELECT group_id, user_id, subscription_id, granted_allowed_group
+		FROM scim_group_members
+		WHERE group_id = $1 AND user_id = $2
+	`, groupID, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get scim membership: %w", err)
+	}
+	return membership, nil
+}
+
+func (r *scimRepository) ListGroupMemberships(ctx context.Context, groupID int64) ([]service.SCIMMembership, error) {
+	return r.listMemberships(ctx, `WHERE group_id = $1 ORDER BY user_id`, groupID)
+}
+
+func (r *scimRepository) ListUserMemberships(ctx context.Context, userID int64) ([]service.SCIMMembership, error) {
+	return r.listMemberships(ctx, `WHERE user_id = $1 ORDER BY group_id`, userID)
+}
+
+func (r *scimRepository) listMemberships(ctx context.Context, where string, arg int64) ([]service.SCIMMembership, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT group_id, user_id, subscription_id, granted_allowed_group
+		FROM scim_group_members
+		`+where, arg)
+	if err != nil {
+		return nil, fmt.Errorf("list scim memberships: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	memberships := make([]service.SCIMMembership, 0)
+	for rows.Next() {
+		membership, scanErr := scanSCIMMembership(rows)
+		if scanErr != nil {
+			return nil, fmt.Errorf("scan scim membership: %w", scanErr)
+		}
+		memberships = append(memberships, *membership)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list scim memberships: %w", err)
+	}
+	return memberships, nil
+}
+
+func (r *scimRepository) CreateMembership(ctx context.Context, membership *service.SCIMMembership) error {
+	var subscriptionID sql.NullInt64
+	if membership.SubscriptionID != nil {
+		subscriptionID = sql.NullInt64{Int64: *membership.SubscriptionID, Valid: true}
+	}
+	if _, err := r.db.ExecContext(ctx, `
+		INSERT INTO scim_group_members (group_id, user_id, subscription_id, granted_allowed_group)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (group_id, user_id) DO NOTHING
+	`, membership.GroupID, membership.UserID, subscriptionID, membership.GrantedAllowedGroup); err != nil {
+		return fmt.Errorf("create scim membership: %w", err)
+	}
+	return nil
+}
+
+func (r *scimRepository) DeleteMembership(ctx context.Context, groupID, userID int64) error {
+	if _, err := r.db.ExecContext(ctx, `
+		DELETE FROM scim_group_members WHERE group_id = $1 AND user_id = $2
+	`, groupID, userID); err != nil {
+		return fmt.Errorf("delete scim membership: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	scopedTokenRequestsKeyPrefix = "scoped_token:requests:"
+	scopedTokenSpendKeyPrefix    = "scoped_token:spend:"
+)
+
+// scopedTokenReserveScript 原子地检查上限并受理一次请求，超限时不做任何修改。
+// KEYS[1] 请求数，KEYS[2] 花费；ARGV: maxRequests, maxSpend, estimate, ttlMillis。
+// 返回 {0, 预占金额} 或 {1}（请求数超限）/ {2}（花费超限）；金额以字符串返回，避免 Redis 把 Lua 数值截断为整数。
+// 请求数键只在首次创建时设置过期时间，避免每次请求都顺延 TTL。
+var scopedTokenReserveScript = redis.NewScript(`
+local maxRequests = tonumber(ARGV[1])
+local maxSpend = tonumber(ARGV[2])
+if maxRequests > 0 and tonumber(redis.call('GET', KEYS[1]) or '0') >= maxRequests then
+	return {1}
+end
+local reserved = 0
+if maxSpend > 0 then
+	local spent = tonumber(redis.call('GET', KEYS[2]) or '0')
+	if spent >= maxSpend then
+		return {2}
+	end
+	reserved = math.min(tonumber(ARGV[3]), maxSpend - spent)
+	if reserved > 0 then
+		redis.call('INCRBYFLOAT', KEYS[2], tostring(reserved))
+		redis.call('PEXPIRE', KEYS[2], ARGV[4])
+	end
+end
+if maxRequests > 0 and redis.call('INCR', KEYS[1]) == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[4])
+end
+return {0, tostring(reserved)}
+`)
+
+// scopedTokenAddSpendScript 累加花费并刷新过期时间（TTL 只会随令牌剩余有效期缩短）。
+var scopedTokenAddSpendScript = redis.NewScript(`
+local v = redis.call('INCRBYFLOAT', KEYS[1], ARGV[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return v
+`)
+
+type scopedTokenUsageCache struct {
+	rdb *redis.Client
+}
+
+func NewScopedTokenUsageCache(rdb *redis.Client) service.ScopedTokenUsageCache {
+	return &scopedTokenUsageCache{rdb: rdb}
+}
+
+func (c *scopedTokenUsageCache) Reserve(ctx context.Context, tokenID string, maxRequests int64, maxSpend, estimate float64, ttl time.Duration) (float64, error) {
+	keys := []string{scopedTokenRequestsKeyPrefix + tokenID, scopedTokenSpendKeyPrefix + tokenID}
+	res, err := scopedTokenReserveScript.Run(ctx, c.rdb, keys, maxRequests, maxSpend, estimate, ttl.Milliseconds()).Slice()
+	if err != nil {
+		return 0, err
+	}
+	if len(res) == 0 {
+		return 0, fmt.Errorf("unexpected scoped token reserve result: %v", res)
+	}
+	switch code, _ := res[0].(int64); code {
+	case 1:
+		return 0, service.ErrScopedTokenRequestLimit
+	case 2:
+		return 0, service.ErrScopedTokenSpendLimit
+	}
+	if len(res) < 2 {
+		return 0, fmt.Errorf("unexpected scoped token reserve result: %v", res)
+	}
+	amount, _ := res[1].(string)
+	return strconv.ParseFloat(amount, 64)
+}
+
+func (c *scopedTokenUsageCache) AddSpend(ctx context.Context, tokenID string, amount float64, ttl time.Duration) error {
+	return scopedTokenAddSpendScript.Run(ctx, c.rdb, []string{scopedTokenSpendKeyPrefix + tokenID}, amount, ttl.Milliseconds()).Err()
+}
@@ -0,0 +1,61 @@
+//go:build unit
+
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func newScopedTokenUsageTestCache(t *testing.T) (service.ScopedTokenUsageCache, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return NewScopedTokenUsageCache(rdb), mr
+}
+
+func TestScopedTokenUsageCache_ReserveRequests(t *testing.T) {
+	cache, mr := newScopedTokenUsageTestCache(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		reserved, err := cache.Reserve(ctx, "t1", 2, 0, 0.5, time.Minute)
+		require.NoError(t, err)
+		require.Zero(t, reserved, "未设花费上限时不预占")
+	}
+	_, err := cache.Reserve(ctx, "t1", 2, 0, 0.5, time.Minute)
+	require.ErrorIs(t, err, service.ErrScopedTokenRequestLimit)
+
+	count, err := mr.Get(scopedTokenRequestsKeyPrefix + "t1")
+	require.NoError(t, err)
+	require.Equal(t, "2", count, "超限请求不计数")
+	require.True(t, mr.TTL(scopedTokenRequestsKeyPrefix+"t1") > 0)
+}
+
+func TestScopedTokenUsageCache_ReserveSpendCapsAtRemaining(t *testing.T) {
+	cache, _ := newScopedTokenUsageTestCache(t)
+	ctx := context.Background()
+
+	require.NoError(t, cache.AddSpend(ctx, "t2", 0.8, time.Minute))
+
+	reserved, err := cache.Reserve(ctx, "t2", 5, 1, 0.5, time.Minute)
+	require.NoError(t, err)
+	require.InDelta(t, 0.2, reserved, 1e-9, "预占不超过剩余额度")
+
+	// 额度已被预占占满，并发请求被拒绝且不计请求数。
+	_, err = cache.Reserve(ctx, "t2", 5, 1, 0.5, time.Minute)
+	require.ErrorIs(t, err, service.ErrScopedTokenSpendLimit)
+
+	// 退回预占后额度恢复。
+	require.NoError(t, cache.AddSpend(ctx, "t2", -reserved, time.Minute))
+	reserved, err = cache.Reserve(ctx, "t2", 5, 1, 0.1, time.Minute)
+	require.NoError(t, err)
+	require.InDelta(t, 0.1, reserved, 1e-9)
+}
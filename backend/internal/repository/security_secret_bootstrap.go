@@ -17,9 +17,11 @@ import (
 )
 
 const (
-	securitySecretKeyJWT        = "jwt_secret"
-	securitySecretReadRetryMax  = 5
-	securitySecretReadRetryWait = 10 * time.Millisecond
+	securitySecretKeyJWT         = "jwt_secret"
+	securitySecretKeyAPIKeyHash  = "api_key_hash_pepper"
+	securitySecretKeyScopedToken = "scoped_token_signing_secret"
+	securitySecretReadRetryMax   = 5
+	securitySecretReadRetryWait  = 10 * time.Millisecond
 )
 
 var readRandomBytes = rand.Read
@@ -42,18 +44,69 @@ func ensureBootstrapSecrets(ctx context.Context, client *ent.Client, cfg *config
 			log.Println("Warning: configured JWT secret mismatches persisted value; using persisted secret for cross-instance consistency.")
 		}
 		cfg.JWT.Secret = storedSecret
+	} else {
+		secret, created, err := getOrCreateGeneratedSecuritySecret(ctx, client, securitySecretKeyJWT, 32)
+		if err != nil {
+			return fmt.Errorf("ensure jwt secret: %w", err)
+		}
+		cfg.JWT.Secret = secret
+
+		if created {
+			log.Println("Warning: JWT secret auto-generated and persisted to database. Consider rotating to a managed secret for production.")
+		}
+	}
+
+	if err := ensureAPIKeyHashPepper(ctx, client, cfg); err != nil {
+		return err
+	}
+	return ensureScopedTokenSigningSecret(ctx, client, cfg)
+}
+
+// ensureAPIKeyHashPepper 确保 API Key 哈希 pepper 可用。pepper 一旦用于哈希便不可更换，
+// 因此与 JWT secret 相同：以数据库中已持久化的值为准，保证多实例与重启后一致。
+func ensureAPIKeyHashPepper(ctx context.Context, client *ent.Client, cfg *config.Config) error {
+	cfg.Security.APIKeyHashPepper = strings.TrimSpace(cfg.Security.APIKeyHashPepper)
+	if cfg.Security.APIKeyHashPepper != "" {
+		stored, err := createSecuritySecretIfAbsent(ctx, client, securitySecretKeyAPIKeyHash, cfg.Security.APIKeyHashPepper)
+		if err != nil {
+			return fmt.Errorf("persist api key hash pepper: %w", err)
+		}
+		if stored != cfg.Security.APIKeyHashPepper {
+			log.Println("Warning: configured API key hash pepper mismatches persisted value; using persisted pepper so existing keys keep working.")
+		}
+		cfg.Security.APIKeyHashPepper = stored
 		return nil
 	}
 
-	secret, created, err := getOrCreateGeneratedSecuritySecret(ctx, client, securitySecretKeyJWT, 32)
+	pepper, _, err := getOrCreateGeneratedSecuritySecret(ctx, client, securitySecretKeyAPIKeyHash, 32)
 	if err != nil {
-		return fmt.Errorf("ensure jwt secret: %w", err)
+		return fmt.Errorf("ensure api key hash pepper: %w", err)
+	}
+	cfg.Security.APIKeyHashPepper = pepper
+	return nil
+}
+
+// ensureScopedTokenSigningSecret 确保派生令牌签名密钥可用。多实例必须共享同一值，
+// 否则一个实例签发的令牌在另一个实例上无法通过校验。
+func ensureScopedTokenSigningSecret(ctx context.Context, client *ent.Client, cfg *config.Config) error {
+	cfg.Security.ScopedToken.SigningSecret = strings.TrimSpace(cfg.Security.ScopedToken.SigningSecret)
+	if cfg.Security.ScopedToken.SigningSecret != "" {
+		stored, err := createSecuritySecretIfAbsent(ctx, client, securitySecretKeyScopedToken, cfg.Security.ScopedToken.SigningSecret)
+		if err != nil {
+			return fmt.Errorf("persist scoped token signing secret: %w", err)
+		}
+		if stored != cfg.Security.ScopedToken.SigningSecret {
+			log.Println("Warning: configured scoped token signing secret mismatches persisted value; using persisted secret for cross-instance consistency.")
+		}
+		cfg.Security.ScopedToken.SigningSecret = stored
+		return nil
 	}
-	cfg.JWT.Secret = secret
 
-	if created {
-		log.Println("Warning: JWT secret auto-generated and persisted to database. Consider rotating to a managed secret for production.")
+	secret, _, err := getOrCreateGeneratedSecuritySecret(ctx, client, securitySecretKeyScopedToken, 32)
+	if err != nil {
+		return fmt.Errorf("ensure scoped token signing secret: %w", err)
 	}
+	cfg.Security.ScopedToken.SigningSecret = secret
 	return nil
 }
 
@@ -64,6 +64,40 @@ func TestEnsureBootstrapSecretsGenerateAndPersistJWTSecret(t *testing.T) {
 	require.Equal(t, cfg.JWT.Secret, stored.Value)
 }
 
+func TestEnsureBootstrapSecretsGenerateAndPersistAPIKeyHashPepper(t *testing.T) {
+	client := newSecuritySecretTestClient(t)
+	cfg := &config.Config{}
+
+	require.NoError(t, ensureBootstrapSecrets(context.Background(), client, cfg))
+	require.GreaterOrEqual(t, len([]byte(cfg.Security.APIKeyHashPepper)), 32)
+	stored, err := client.SecuritySecret.Query().Where(securitysecret.KeyEQ(securitySecretKeyAPIKeyHash)).Only(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, cfg.Security.APIKeyHashPepper, stored.Value)
+
+	// 再次启动时即使配置了新的 pepper，也沿用已持久化的值，避免已有 Key 全部失效。
+	restarted := &config.Config{}
+	restarted.Security.APIKeyHashPepper = "another-configured-pepper-32bytes-long!"
+	require.NoError(t, ensureBootstrapSecrets(context.Background(), client, restarted))
+	require.Equal(t, stored.Value, restarted.Security.APIKeyHashPepper)
+}
+
+func TestEnsureBootstrapSecretsGenerateAndPersistScopedTokenSecret(t *testing.T) {
+	client := newSecuritySecretTestClient(t)
+	cfg := &config.Config{}
+
+	require.NoError(t, ensureBootstrapSecrets(context.Background(), client, cfg))
+	require.GreaterOrEqual(t, len([]byte(cfg.Security.ScopedToken.SigningSecret)), 32)
+	require.NotEqual(t, cfg.Security.APIKeyHashPepper, cfg.Security.ScopedToken.SigningSecret)
+	stored, err := client.SecuritySecret.Query().Where(securitysecret.KeyEQ(securitySecretKeyScopedToken)).Only(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, cfg.Security.ScopedToken.SigningSecret, stored.Value)
+
+	// 其他实例启动时沿用已持久化的密钥，保证任一实例签发的令牌都能在其他实例上校验通过。
+	other := &config.Config{}
+	require.NoError(t, ensureBootstrapSecrets(context.Background(), client, other))
+	require.Equal(t, stored.Value, other.Security.ScopedToken.SigningSecret)
+}
+
 func TestEnsureBootstrapSecretsLoadExistingJWTSecret(t *testing.T) {
 	client := newSecuritySecretTestClient(t)
 	_, err := client.SecuritySecret.Create().SetKey(securitySecretKeyJWT).SetValue("existing-jwt-secret-32bytes-long!!!!").Save(context.Background())
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	streamResumeKeyPrefix       = "stream_resume:"
+	streamResumeEventsKeyPrefix = "stream_resume_events:"
+)
+
+type streamResumeStore struct {
+	rdb *redis.Client
+}
+
+func NewStreamResumeStore(rdb *redis.Client) service.StreamResumeStore {
+	return &streamResumeStore{rdb: rdb}
+}
+
+func (s *streamResumeStore) Save(ctx context.Context, record *service.StreamResumeRecord, ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.rdb.Set(ctx, streamResumeKey(record.ID), data, ttl).Err()
+}
+
+func (s *streamResumeStore) Get(ctx context.Context, id string) (*service.StreamResumeRecord, error) {
+	data, err := s.rdb.Get(ctx, streamResumeKey(id)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, service.ErrStreamResumeNotFound
+		}
+		return nil, err
+	}
+	var record service.StreamResumeRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *streamResumeStore) AppendEvents(ctx context.Context, id string, events [][]byte, ttl time.Duration) error {
+	if len(events) == 0 {
+		return nil
+	}
+	values := make([]any, 0, len(events))
+	for _, event := range events {
+		values = append(values, event)
+	}
+	key := streamResumeEventsKey(id)
+	pipe := s.rdb.TxPipeline()
+	pipe.RPush(ctx, key, values...)
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *streamResumeStore) ListEvents(ctx context.Context, id string, from int64) ([][]byte, error) {
+	if from < 0 {
+		from = 0
+	}
+	values, err := s.rdb.LRange(ctx, streamResumeEventsKey(id), from, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	events := make([][]byte, 0, len(values))
+	for _, value := range values {
+		events = append(events, []byte(value))
+	}
+	return events, nil
+}
+
+func streamResumeKey(id string) string {
+	return streamResumeKeyPrefix + strings.TrimSpace(id)
+}
+
+func streamResumeEventsKey(id string) string {
+	return streamResumeEventsKeyPrefix + strings.TrimSpace(id)
+}
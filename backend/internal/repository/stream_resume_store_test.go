@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamResumeStoreRoundTrip(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	store := NewStreamResumeStore(rdb)
+	ctx := context.Background()
+	record := &service.StreamResumeRecord{
+		ID:         "strm_abc",
+		UserID:     7,
+		APIKeyID:   9,
+		Status:     service.StreamResumeStatusStreaming,
+		HTTPStatus: 200,
+		Header:     map[string]string{"Content-Type": "text/event-stream"},
+		CreatedAt:  100,
+	}
+
+	require.NoError(t, store.Save(ctx, record, time.Hour))
+	got, err := store.Get(ctx, record.ID)
+	require.NoError(t, err)
+	require.Equal(t, record, got)
+	require.Equal(t, time.Hour, mr.TTL(streamResumeKey(record.ID)))
+
+	_, err = store.Get(ctx, "strm_missing")
+	require.ErrorIs(t, err, service.ErrStreamResumeNotFound)
+}
+
+func TestStreamResumeStoreEvents(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	store := NewStreamResumeStore(rdb)
+	ctx := context.Background()
+
+	require.NoError(t, store.AppendEvents(ctx, "strm_1", [][]byte{[]byte("data: a"), []byte("data: b")}, time.Hour))
+	require.NoError(t, store.AppendEvents(ctx, "strm_1", nil, time.Minute))
+	require.NoError(t, store.AppendEvents(ctx, "strm_1", [][]byte{[]byte("data: c")}, 2*time.Hour))
+	require.Equal(t, 2*time.Hour, mr.TTL(streamResumeEventsKey("strm_1")))
+
+	events, err := store.ListEvents(ctx, "strm_1", 1)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("data: b"), []byte("data: c")}, events)
+
+	events, err = store.ListEvents(ctx, "strm_1", 5)
+	require.NoError(t, err)
+	require.Empty(t, events)
+}
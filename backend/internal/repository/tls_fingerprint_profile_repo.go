@@ -82,6 +82,9 @@ func (r *tlsFingerprintProfileRepository) Create(ctx context.Context, p *model.T
 	if len(p.Extensions) > 0 {
 		builder.SetExtensions(p.Extensions)
 	}
+	if p.HTTP2 != nil {
+		builder.SetHttp2(p.HTTP2)
+	}
 
 	created, err := builder.Save(ctx)
 	if err != nil {
@@ -147,6 +150,11 @@ func (r *tlsFingerprintProfileRepository) Update(ctx context.Context, p *model.T
 	} else {
 		builder.ClearExtensions()
 	}
+	if p.HTTP2 != nil {
+		builder.SetHttp2(p.HTTP2)
+	} else {
+		builder.ClearHttp2()
+	}
 
 	updated, err := builder.Save(ctx)
 	if err != nil {
@@ -176,6 +184,7 @@ func (r *tlsFingerprintProfileRepository) toModel(e *ent.TLSFingerprintProfile)
 		KeyShareGroups:      e.KeyShareGroups,
 		PSKModes:            e.PskModes,
 		Extensions:          e.Extensions,
+		HTTP2:               e.Http2,
 		CreatedAt:           e.CreatedAt,
 		UpdatedAt:           e.UpdatedAt,
 	}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+)
+
+// upstreamFileRepository Files API 文件 → 账号绑定（raw SQL）。
+type upstreamFileRepository struct {
+	db *sql.DB
+}
+
+// NewUpstreamFileRepository 创建文件绑定仓储。
+func NewUpstreamFileRepository(db *sql.DB) service.UpstreamFileRepository {
+	return &upstreamFileRepository{db: db}
+}
+
+const upstreamFileColumns = `id, platform, file_id, user_id, api_key_id, group_id, account_id, filename, purpose, bytes, object, created_at`
+
+func (r *upstreamFileRepository) Create(ctx context.Context, file *service.UpstreamFile) error {
+	object := file.Object
+	if len(object) == 0 || !json.Valid(object) {
+		object = json.RawMessage(`{}`)
+	}
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO upstream_files (platform, file_id, user_id, api_key_id, group_id, account_id, filename, purpose, bytes, object, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW())
+		ON CONFLICT (platform, file_id) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			api_key_id = EXCLUDED.api_key_id,
+			group_id = EXCLUDED.group_id,
+			account_id = EXCLUDED.account_id,
+			filename = EXCLUDED.filename,
+			purpose = EXCLUDED.purpose,
+			bytes = EXCLUDED.bytes,
+			object = EXCLUDED.object
+		RETURNING id, created_at`,
+		file.Platform, file.FileID, file.UserID, file.APIKeyID, file.GroupID, file.AccountID, file.Filename, file.Purpose, file.Bytes, []byte(object),
+	).Scan(&file.ID, &file.CreatedAt)
+}
+
+func (r *upstreamFileRepository) GetByFileID(ctx context.Context, platform, fileID string) (*service.UpstreamFile, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+upstreamFileColumns+` FROM upstream_files WHERE platform = $1 AND file_id = $2`, platform, fileID)
+	file, err := scanUpstreamFile(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, service.ErrUpstreamFileNotFound
+	}
+	return file, err
+}
+
+func (r *upstreamFileRepository) ListByUser(ctx context.Context, userID int64, platform string, groupID *int64) ([]*service.UpstreamFile, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+upstreamFileColumns+`
+		FROM upstream_files
+		WHERE user_id = $1 AND platform = $2 AND group_id IS NOT DISTINCT FROM $3
+		ORDER BY created_at DESC, id DESC
+	`, userID, platform, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]*service.UpstreamFile, 0)
+	for rows.Next() {
+		file, err := scanUpstreamFile(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, file)
+	}
+	return out, rows.Err()
+}
+
+func (r *upstreamFileRepository) Delete(ctx context.Context, platform, fileID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM upstream_files WHERE platform = $1 AND file_id = $2`, platform, fileID)
+	return err
+}
+
+func scanUpstreamFile(scan func(dest ...any) error) (*service.UpstreamFile, error) {
+	var (
+		file    service.UpstreamFile
+		groupID sql.NullInt64
+		object  []byte
+	)
+	if err := scan(
+		&file.ID, &file.Platform, &file.FileID, &file.UserID, &file.APIKeyID, &groupID, &file.AccountID,
+		&file.Filename, &file.Purpose, &file.Bytes, &object, &file.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if groupID.Valid {
+		v := groupID.Int64
+		file.GroupID = &v
+	}
+	file.Object = json.RawMessage(object)
+	return &file, nil
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpstreamFileRepositoryGetByFileID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	repo := NewUpstreamFileRepository(db)
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM upstream_files WHERE platform = $1 AND file_id = $2")).
+		WithArgs(service.PlatformOpenAI, "file-abc").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "platform", "file_id", "user_id", "api_key_id", "group_id", "account_id", "filename", "purpose", "bytes", "object", "created_at"}).
+			AddRow(int64(1), service.PlatformOpenAI, "file-abc", int64(10), int64(20), nil, int64(30), "a.pdf", "user_data", int64(42), []byte(`{"id":"file-abc"}`), now))
+
+	file, err := repo.GetByFileID(context.Background(), service.PlatformOpenAI, "file-abc")
+	require.NoError(t, err)
+	require.Equal(t, int64(30), file.AccountID)
+	require.Nil(t, file.GroupID)
+	require.JSONEq(t, `{"id":"file-abc"}`, string(file.Object))
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM upstream_files WHERE platform = $1 AND file_id = $2")).
+		WithArgs(service.PlatformOpenAI, "file-missing").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	_, err = repo.GetByFileID(context.Background(), service.PlatformOpenAI, "file-missing")
+	require.ErrorIs(t, err, service.ErrUpstreamFileNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpstreamFileRepositoryListByUserMatchesNullGroup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	repo := NewUpstreamFileRepository(db)
+	mock.ExpectQuery(regexp.QuoteMeta("group_id IS NOT DISTINCT FROM $3")).
+		WithArgs(int64(10), service.PlatformAnthropic, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "platform", "file_id", "user_id", "api_key_id", "group_id", "account_id", "filename", "purpose", "bytes", "object", "created_at"}))
+
+	files, err := repo.ListByUser(context.Background(), 10, service.PlatformAnthropic, nil)
+	require.NoError(t, err)
+	require.Empty(t, files)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
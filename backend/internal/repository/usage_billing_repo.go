@@ -196,9 +196,11 @@ func (r *usageBillingRepository) applyUsageBillingEffects(ctx context.Context, t
 	}
 
 	if cmd.APIKeyRateLimitCost > 0 {
-		if err := incrementUsageBillingAPIKeyRateLimit(ctx, tx, cmd.APIKeyID, cmd.APIKeyRateLimitCost); err != nil {
+		peers, err := incrementUsageBillingAPIKeyRateLimit(ctx, tx, cmd.APIKeyID, cmd.APIKeyRateLimitCost)
+		if err != nil {
 			return err
 		}
+		result.RateLimitPeerAPIKeyIDs = peers
 	}
 
 	if cmd.AccountQuotaCost > 0 && (strings.EqualFold(cmd.AccountType, service.AccountTypeAPIKey) || strings.EqualFold(cmd.AccountType, service.AccountTypeBedrock)) {
@@ -413,9 +415,10 @@ func userExistsForBilling(ctx context.Context, tx *sql.Tx, userID int64) (bool,
 	return true, nil
 }
 
+// incrementUsageBillingAPIKeyQuota 累加 API Key 配额用量，轮换宽限期内同谱系的 Key 一并累加，
+// 返回本 Key 是否因本次计费耗尽配额。
 func incrementUsageBillingAPIKeyQuota(ctx context.Context, tx *sql.Tx, apiKeyID int64, amount float64) (bool, error) {
-	var exhausted bool
-	err := tx.QueryRowContext(ctx, `
+	rows, err := tx.QueryContext(ctx, `
 		UPDATE api_keys
 		SET quota_used = quota_used + $1,
 			status = CASE
@@ -427,20 +430,37 @@ func incrementUsageBillingAPIKeyQuota(ctx context.Context, tx *sql.Tx, apiKeyID
 				ELSE status
 			END,
 			updated_at = NOW()
-		WHERE id = $2 AND deleted_at IS NULL
-		RETURNING quota > 0 AND quota_used >= quota AND quota_used - $1 < quota
-	`, amount, apiKeyID, service.StatusAPIKeyActive, service.StatusAPIKeyQuotaExhausted).Scan(&exhausted)
-	if errors.Is(err, sql.ErrNoRows) {
-		return false, service.ErrAPIKeyNotFound
-	}
+		WHERE id IN `+apiKeyChargeScopeSQL("$2")+` AND deleted_at IS NULL
+		RETURNING id, quota > 0 AND quota_used >= quota AND quota_used - $1 < quota
+	`, amount, apiKeyID, service.StatusAPIKeyActive, service.StatusAPIKeyQuotaExhausted)
 	if err != nil {
 		return false, err
 	}
+	defer func() { _ = rows.Close() }()
+	found, exhausted := false, false
+	for rows.Next() {
+		var id int64
+		var crossed bool
+		if err := rows.Scan(&id, &crossed); err != nil {
+			return false, err
+		}
+		if id == apiKeyID {
+			found, exhausted = true, crossed
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	if !found {
+		return false, service.ErrAPIKeyNotFound
+	}
 	return exhausted, nil
 }
 
-func incrementUsageBillingAPIKeyRateLimit(ctx context.Context, tx *sql.Tx, apiKeyID int64, cost float64) error {
-	res, err := tx.ExecContext(ctx, `
+// incrementUsageBillingAPIKeyRateLimit 累加 API Key 限流窗口用量，轮换宽限期内同谱系的 Key 一并累加，
+// 返回一并累加的其它 Key，供调用方同步它们的限流缓存。
+func incrementUsageBillingAPIKeyRateLimit(ctx context.Context, tx *sql.Tx, apiKeyID int64, cost float64) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, `
 		UPDATE api_keys SET
 			usage_5h = CASE WHEN window_5h_start IS NOT NULL AND window_5h_start + INTERVAL '5 hours' <= NOW() THEN $1 ELSE usage_5h + $1 END,
 			usage_1d = CASE WHEN window_1d_start IS NOT NULL AND window_1d_start + INTERVAL '24 hours' <= NOW() THEN $1 ELSE usage_1d + $1 END,
@@ -449,19 +469,33 @@ func incrementUsageBillingAPIKeyRateLimit(ctx context.Context, tx *sql.Tx, apiKe
 			window_1d_start = CASE WHEN window_1d_start IS NULL OR window_1d_start + INTERVAL '24 hours' <= NOW() THEN date_trunc('day', NOW()) ELSE window_1d_start END,
 			window_7d_start = CASE WHEN window_7d_start IS NULL OR window_7d_start + INTERVAL '7 days' <= NOW() THEN date_trunc('day', NOW()) ELSE window_7d_start END,
 			updated_at = NOW()
-		WHERE id = $2 AND deleted_at IS NULL
+		WHERE id IN `+apiKeyChargeScopeSQL("$2")+` AND deleted_at IS NULL
+		RETURNING id
 	`, cost, apiKeyID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	affected, err := res.RowsAffected()
-	if err != nil {
-		return err
+	defer func() { _ = rows.Close() }()
+	found := false
+	var peers []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		if id == apiKeyID {
+			found = true
+		} else {
+			peers = append(peers, id)
+		}
 	}
-	if affected == 0 {
-		return service.ErrAPIKeyNotFound
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	return nil
+	if !found {
+		return nil, service.ErrAPIKeyNotFound
+	}
+	return peers, nil
 }
 
 func incrementUsageBillingAccountQuota(ctx context.Context, tx *sql.Tx, accountID int64, amount float64) (*service.AccountQuotaState, error) {
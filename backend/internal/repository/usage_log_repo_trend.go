@@ -609,6 +609,71 @@ func (r *usageLogRepository) getGroupStatsWithFilters(ctx context.Context, start
 	return results, nil
 }
 
+// GetGroupCacheHitRates returns per-group prompt-cache hit rates, used to
+// compare groups with and without prompt-prefix routing.
+func (r *usageLogRepository) GetGroupCacheHitRates(ctx context.Context, startTime, endTime time.Time, groupID int64) (results []usagestats.GroupCacheHitRate, err error) {
+	query := `
+		SELECT
+			COALESCE(ul.group_id, 0) as group_id,
+			COALESCE(g.name, '') as group_name,
+			COALESCE(BOOL_OR(g.prompt_prefix_routing_enabled), FALSE) as prompt_prefix_routing_enabled,
+			COUNT(*) as requests,
+			COUNT(*) FILTER (WHERE ul.cache_read_tokens > 0) as cache_hit_requests,
+			COALESCE(SUM(ul.input_tokens), 0) as input_tokens,
+			COALESCE(SUM(ul.cache_creation_tokens), 0) as cache_creation_tokens,
+			COALESCE(SUM(ul.cache_read_tokens), 0) as cache_read_tokens
+		FROM usage_logs ul
+		LEFT JOIN groups g ON g.id = ul.group_id
+		WHERE ul.created_at >= $1 AND ul.created_at < $2
+	`
+
+	args := []any{startTime, endTime}
+	if groupID > 0 {
+		query += fmt.Sprintf(" AND ul.group_id = $%d", len(args)+1)
+		args = append(args, groupID)
+	}
+	query += " GROUP BY ul.group_id, g.name ORDER BY requests DESC"
+
+	rows, err := r.sql.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+			results = nil
+		}
+	}()
+
+	results = make([]usagestats.GroupCacheHitRate, 0)
+	for rows.Next() {
+		var row usagestats.GroupCacheHitRate
+		if err := rows.Scan(
+			&row.GroupID,
+			&row.GroupName,
+			&row.PromptPrefixRouting,
+			&row.Requests,
+			&row.CacheHitRequests,
+			&row.InputTokens,
+			&row.CacheCreationTokens,
+			&row.CacheReadTokens,
+		); err != nil {
+			return nil, err
+		}
+		if prompt := row.InputTokens + row.CacheCreationTokens + row.CacheReadTokens; prompt > 0 {
+			row.TokenHitRate = float64(row.CacheReadTokens) / float64(prompt)
+		}
+		if row.Requests > 0 {
+			row.RequestHitRate = float64(row.CacheHitRequests) / float64(row.Requests)
+		}
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
 // GetUserBreakdownStats returns per-user usage breakdown within a specific dimension.
 func (r *usageLogRepository) GetUserBreakdownStats(ctx context.Context, startTime, endTime time.Time, dim usagestats.UserBreakdownDimension, limit int) (results []usagestats.UserBreakdownItem, err error) {
 	query := `
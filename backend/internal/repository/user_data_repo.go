@@ -0,0 +1,484 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+)
+
+// userDataRepository 用户数据导出任务、注销申请与注销匿名化（raw SQL）。
+type userDataRepository struct {
+	db *sql.DB
+}
+
+func NewUserDataRepository(db *sql.DB) service.UserDataRepository {
+	return &userDataRepository{db: db}
+}
+
+const userDataExportColumns = `id, user_id, status, archive_bytes, error_message, created_at, started_at, completed_at, expires_at`
+
+type userDataScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUserDataExport(row userDataScanner) (*service.UserDataExport, error) {
+	var (
+		e           service.UserDataExport
+		startedAt   sql.NullTime
+		completedAt sql.NullTime
+		expiresAt   sql.NullTime
+	)
+	if err := row.Scan(&e.ID, &e.UserID, &e.Status, &e.ArchiveBytes, &e.ErrorMessage, &e.CreatedAt, &startedAt, &completedAt, &expiresAt); err != nil {
+		return nil, err
+	}
+	e.StartedAt = nullTimePtr(startedAt)
+	e.CompletedAt = nullTimePtr(completedAt)
+	e.ExpiresAt = nullTimePtr(expiresAt)
+	return &e, nil
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time
+	return &v
+}
+
+func (r *userDataRepository) CreateExport(ctx context.Context, userID int64, now time.Time) (*service.UserDataExport, error) {
+	row := r.db.QueryRowContext(ctx, `
+		INSERT INTO user_data_exports (user_id, status, created_at)
+		VALUES ($1, 'pending', $2)
+		ON CONFLICT (user_id) WHERE status IN ('pending', 'running') DO NOTHING
+		RETURNING `+userDataExportColumns, userID, now)
+	export, err := scanUserDataExport(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrUserDataExportInProgress
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create user data export: %w", err)
+	}
+	return export, nil
+}
+
+func (r *userDataRepository) LatestExport(ctx context.Context, userID int64) (*service.UserDataExport, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+userDataExportColumns+` FROM user_data_exports
+		WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT 1`, userID)
+	export, err := scanUserDataExport(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrUserDataExportNotFound
+	}
+	return export, err
+}
+
+func (r *userDataRepository) ListExports(ctx context.Context, userID int64, limit int) (_ []service.UserDataExport, err error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+userDataExportColumns+` FROM user_data_exports
+		WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	out := make([]service.UserDataExport, 0)
+	for rows.Next() {
+		export, err := scanUserDataExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *export)
+	}
+	return out, rows.Err()
+}
+
+func (r *userDataRepository) GetExport(ctx context.Context, userID, exportID int64) (*service.UserDataExport, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+userDataExportColumns+` FROM user_data_exports
+		WHERE id = $1 AND user_id = $2`, exportID, userID)
+	export, err := scanUserDataExport(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrUserDataExportNotFound
+	}
+	return export, err
+}
+
+func (r *userDataRepository) GetExportArchive(ctx context.Context, userID, exportID int64) ([]byte, error) {
+	var archive []byte
+	err := r.db.QueryRowContext(ctx, `
+		SELECT archive FROM user_data_exports
+		WHERE id = $1 AND user_id = $2 AND status = 'completed' AND archive IS NOT NULL`,
+		exportID, userID).Scan(&archive)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrUserDataExportNotReady
+	}
+	return archive, err
+}
+
+func (r *userDataRepository) ClaimNextExport(ctx context.Context, now, staleBefore time.Time) (*service.UserDataExport, error) {
+	row := r.db.QueryRowContext(ctx, `
+		UPDATE user_data_exports SET status = 'running', started_at = $1
+		WHERE id = (
+			SELECT id FROM user_data_exports
+			WHERE status = 'pending' OR (status = 'running' AND started_at < $2)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+userDataExportColumns, now, staleBefore)
+	export, err := scanUserDataExport(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("claim user data export: %w", err)
+	}
+	return export, nil
+}
+
+func (r *userDataRepository) CompleteExport(ctx context.Context, exportID int64, archive []byte, completedAt, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE user_data_exports
+		SET status = 'completed', archive = $2, archive_bytes = $3, error_message = '',
+		    completed_at = $4, expires_at = $5
+		WHERE id = $1`, exportID, archive, int64(len(archive)), completedAt, expiresAt)
+	return err
+}
+
+func (r *userDataRepository) FailExport(ctx context.Context, exportID int64, message string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE user_data_exports
+		SET status = 'failed', archive = NULL, archive_bytes = 0, error_message = $2, completed_at = $3
+		WHERE id = $1`, exportID, message, at)
+	return err
+}
+
+func (r *userDataRepository) ExpireExports(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE user_data_exports SET status = 'expired', archive = NULL
+		WHERE status = 'completed' AND expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// userDataDatasetQueries 每个导出数据集的查询（$1 = user_id，$2 = 行数上限，NULL 表示不限）。
+// 只选取面向用户的列：API Key 只输出前缀，身份绑定不含 metadata，推荐关系不暴露对方用户。
+var userDataDatasetQueries = map[service.UserDataDataset]string{
+	service.UserDataDatasetProfile: `
+		SELECT u.id, u.email, u.username, u.role, u.status, u.signup_source,
+		       u.balance::float8 AS balance, u.frozen_balance::float8 AS frozen_balance,
+		       u.total_recharged::float8 AS total_recharged, u.concurrency, u.rpm_limit,
+		       u.totp_enabled, u.totp_enabled_at, u.referral_code,
+		       u.balance_notify_enabled, u.balance_notify_threshold_type,
+		       u.balance_notify_threshold::float8 AS balance_notify_threshold,
+		       u.balance_notify_extra_emails,
+		       COALESCE((
+		           SELECT jsonb_object_agg(d.key, v.value)
+		           FROM user_attribute_values v
+		           JOIN user_attribute_definitions d ON d.id = v.attribute_id
+		           WHERE v.user_id = u.id AND d.deleted_at IS NULL
+		       ), '{}'::jsonb) AS attributes,
+		       u.created_at, u.updated_at, u.last_login_at, u.last_active_at
+		FROM users u WHERE u.id = $1 LIMIT $2`,
+	service.UserDataDatasetAPIKeys: `
+		SELECT k.id, k.name, k.key_prefix || '****' AS key_masked, k.status, k.group_id, g.name AS group_name,
+		       k.quota::float8 AS quota, k.quota_used::float8 AS quota_used,
+		       k.ip_whitelist, k.ip_blacklist, k.expires_at, k.last_used_at,
+		       k.created_at, k.updated_at, k.deleted_at
+		FROM api_keys k LEFT JOIN groups g ON g.id = k.group_id
+		WHERE k.user_id = $1 ORDER BY k.id LIMIT $2`,
+	service.UserDataDatasetSubscriptions: `
+		SELECT s.id, s.group_id, g.name AS group_name, s.status, s.starts_at, s.expires_at,
+		       s.daily_usage_usd::float8 AS daily_usage_usd, s.weekly_usage_usd::float8 AS weekly_usage_usd,
+		       s.monthly_usage_usd::float8 AS monthly_usage_usd, s.assigned_at, s.created_at, s.deleted_at
+		FROM user_subscriptions s LEFT JOIN groups g ON g.id = s.group_id
+		WHERE s.user_id = $1 ORDER BY s.id LIMIT $2`,
+	service.UserDataDatasetBalanceHistory: `
+		SELECT r.id, r.type, r.value::float8 AS value, r.group_id, r.validity_days, r.used_at, r.created_at
+		FROM redeem_codes r
+		WHERE r.used_by = $1 ORDER BY COALESCE(r.used_at, r.created_at), r.id LIMIT $2`,
+	service.UserDataDatasetUsageLogs: `
+		SELECT l.id, l.created_at, l.request_id, l.api_key_id, l.group_id, l.model, l.requested_model,
+		       l.input_tokens, l.output_tokens, l.cache_creation_tokens, l.cache_read_tokens,
+		       l.total_cost::float8 AS total_cost, l.actual_cost::float8 AS actual_cost,
+		       l.billing_type, l.stream, l.duration_ms, l.first_token_ms, l.image_count,
+		       l.ip_address, l.user_agent
+		FROM usage_logs l
+		WHERE l.user_id = $1 ORDER BY l.created_at DESC, l.id DESC LIMIT $2`,
+	service.UserDataDatasetReferrals: `
+		SELECT r.id,
+		       CASE WHEN r.referrer_id = $1 THEN 'referrer' ELSE 'referee' END AS role,
+		       r.status,
+		       (CASE WHEN r.referrer_id = $1 THEN r.referrer_balance_reward ELSE r.referee_balance_reward END)::float8 AS balance_reward,
+		       CASE WHEN r.referrer_id = $1 THEN r.referrer_subscription_days ELSE r.referee_subscription_days END AS subscription_days,
+		       CASE WHEN r.referrer_id = $1 THEN r.referrer_rewarded_at ELSE r.referee_rewarded_at END AS rewarded_at,
+		       r.created_at
+		FROM user_referrals r
+		WHERE r.referrer_id = $1 OR r.referee_id = $1 ORDER BY r.id LIMIT $2`,
+	service.UserDataDatasetAuthIdentities: `
+		SELECT * FROM (
+		    SELECT 'identity' AS kind, a.provider_type, a.provider_key, a.provider_subject, a.issuer,
+		           a.verified_at, NULL::timestamptz AS last_used_at, a.created_at
+		    FROM auth_identities a WHERE a.user_id = $1
+		    UNION ALL
+		    SELECT 'passkey', 'passkey', p.name, NULL, NULL, NULL, p.last_used_at, p.created_at
+		    FROM passkey_credentials p WHERE p.user_id = $1
+		) t ORDER BY created_at LIMIT $2`,
+}
+
+// userDataJSONColumns 以原始 JSON 输出的列。
+var userDataJSONColumns = map[string]bool{
+	"attributes":                  true,
+	"balance_notify_extra_emails": true,
+	"ip_whitelist":                true,
+	"ip_blacklist":                true,
+}
+
+func (r *userDataRepository) StreamDataset(ctx context.Context, userID int64, dataset service.UserDataDataset, limit int, emit func(columns []string, values []any) error) (err error) {
+	query, ok := userDataDatasetQueries[dataset]
+	if !ok {
+		return fmt.Errorf("unknown user data dataset %q", dataset)
+	}
+	var limitArg any
+	if limit > 0 {
+		limitArg = limit
+	}
+	rows, err := r.db.QueryContext(ctx, query, userID, limitArg)
+	if err != nil {
+		return fmt.Errorf("query %s: %w", dataset, err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	raw := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range raw {
+		dest[i] = &raw[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		values := make([]any, len(columns))
+		for i, v := range raw {
+			values[i] = normalizeUserDataValue(columns[i], v)
+		}
+		if err := emit(columns, values); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func normalizeUserDataValue(column string, v any) any {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case []byte:
+		if userDataJSONColumns[column] && json.Valid(val) {
+			return json.RawMessage(append([]byte(nil), val...))
+		}
+		return string(val)
+	case string:
+		if userDataJSONColumns[column] && json.Valid([]byte(val)) {
+			return json.RawMessage(val)
+		}
+		return val
+	case time.Time:
+		return val.UTC().Format(time.RFC3339)
+	default:
+		return val
+	}
+}
+
+const userDeletionRequestColumns = `user_id, status, reason, requested_at, scheduled_for, cancelled_at, completed_at, last_error`
+
+func scanAccountDeletionRequest(row userDataScanner) (*service.AccountDeletionRequest, error) {
+	var (
+		req         service.AccountDeletionRequest
+		cancelledAt sql.NullTime
+		completedAt sql.NullTime
+	)
+	if err := row.Scan(&req.UserID, &req.Status, &req.Reason, &req.RequestedAt, &req.ScheduledFor, &cancelledAt, &completedAt, &req.LastError); err != nil {
+		return nil, err
+	}
+	req.CancelledAt = nullTimePtr(cancelledAt)
+	req.CompletedAt = nullTimePtr(completedAt)
+	return &req, nil
+}
+
+func (r *userDataRepository) CreateDeletionRequest(ctx context.Context, req *service.AccountDeletionRequest) error {
+	// 已撤销的申请可重新提交；待执行或已完成的申请保持不变。
+	var userID int64
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO user_deletion_requests (user_id, status, reason, requested_at, scheduled_for, updated_at)
+		VALUES ($1, 'pending', $2, $3, $4, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET status = 'pending', reason = EXCLUDED.reason, requested_at = EXCLUDED.requested_at,
+		    scheduled_for = EXCLUDED.scheduled_for, cancelled_at = NULL, completed_at = NULL,
+		    last_error = '', updated_at = EXCLUDED.updated_at
+		WHERE user_deletion_requests.status = 'cancelled'
+		RETURNING user_id`, req.UserID, req.Reason, req.RequestedAt, req.ScheduledFor).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return service.ErrAccountDeletionAlreadyRequested
+	}
+	if err != nil {
+		return fmt.Errorf("create account deletion request: %w", err)
+	}
+	return nil
+}
+
+func (r *userDataRepository) GetDeletionRequest(ctx context.Context, userID int64) (*service.AccountDeletionRequest, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+userDeletionRequestColumns+` FROM user_deletion_requests WHERE user_id = $1`, userID)
+	req, err := scanAccountDeletionRequest(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrAccountDeletionNotFound
+	}
+	return req, err
+}
+
+func (r *userDataRepository) CancelDeletionRequest(ctx context.Context, userID int64, now time.Time) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE user_deletion_requests SET status = 'cancelled', cancelled_at = $2, updated_at = $2
+		WHERE user_id = $1 AND status = 'pending'`, userID, now)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return service.ErrAccountDeletionNotFound
+	}
+	return nil
+}
+
+func (r *userDataRepository) ListDueDeletionRequests(ctx context.Context, now time.Time, limit int) (_ []service.AccountDeletionRequest, err error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+userDeletionRequestColumns+` FROM user_deletion_requests
+		WHERE status = 'pending' AND scheduled_for <= $1
+		ORDER BY scheduled_for LIMIT $2`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	var out []service.AccountDeletionRequest
+	for rows.Next() {
+		req, err := scanAccountDeletionRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *req)
+	}
+	return out, rows.Err()
+}
+
+func (r *userDataRepository) MarkDeletionCompleted(ctx context.Context, userID int64, now time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE user_deletion_requests
+		SET status = 'completed', completed_at = $2, reason = '', last_error = '', updated_at = $2
+		WHERE user_id = $1`, userID, now)
+	return err
+}
+
+func (r *userDataRepository) MarkDeletionFailed(ctx context.Context, userID int64, message string, cancel bool, now time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE user_deletion_requests
+		SET last_error = $2,
+		    status = CASE WHEN $3 THEN 'cancelled' ELSE status END,
+		    cancelled_at = CASE WHEN $3 THEN $4 ELSE cancelled_at END,
+		    updated_at = $4
+		WHERE user_id = $1 AND status = 'pending'`, userID, message, cancel, now)
+	return err
+}
+
+// AnonymizeUser 在一个事务内覆盖个人资料、删除凭证与身份绑定；usage_logs、兑换记录与审计日志保留。
+func (r *userDataRepository) AnonymizeUser(ctx context.Context, userID int64, opts service.UserDataPurgeOptions) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	statements := []string{
+		`UPDATE users
+		 SET email = 'deleted-' || id || '@deleted.invalid', username = '', notes = '',
+		     password_hash = '!', totp_secret_encrypted = NULL, totp_enabled = false, totp_enabled_at = NULL,
+		     balance_notify_enabled = false, balance_notify_extra_emails = '[]',
+		     status = 'disabled', updated_at = NOW()
+		 WHERE id = $1`,
+		`DELETE FROM auth_identities WHERE user_id = $1`,
+		`DELETE FROM passkey_credentials WHERE user_id = $1`,
+		`DELETE FROM passkey_user_handles WHERE user_id = $1`,
+		`DELETE FROM user_avatars WHERE user_id = $1`,
+		`DELETE FROM user_attribute_values WHERE user_id = $1`,
+		`DELETE FROM scim_group_members WHERE user_id = $1`,
+		`DELETE FROM scim_users WHERE user_id = $1`,
+		`DELETE FROM pending_auth_sessions WHERE target_user_id = $1`,
+		`DELETE FROM user_data_exports WHERE user_id = $1`,
+	}
+	if opts.RequestContent {
+		statements = append(statements,
+			`DELETE FROM prompt_audit_events WHERE user_id = $1`,
+			`DELETE FROM prompt_audit_jobs WHERE user_id = $1`,
+			`DELETE FROM content_moderation_logs WHERE user_id = $1`,
+		)
+	} else {
+		// 保留记录时仍去除其中的身份快照。
+		statements = append(statements,
+			`UPDATE prompt_audit_events SET username_snapshot = '', user_email_snapshot = '' WHERE user_id = $1`,
+			`UPDATE prompt_audit_jobs SET username_snapshot = '', user_email_snapshot = '' WHERE user_id = $1`,
+			`UPDATE content_moderation_logs SET user_email = '' WHERE user_id = $1`,
+		)
+	}
+	if opts.ErrorLogDetails {
+		statements = append(statements,
+			`UPDATE ops_error_logs SET request_body = NULL, client_ip = NULL, user_agent = NULL WHERE user_id = $1`,
+		)
+	}
+	for _, stmt := range statements {
+		if _, err = tx.ExecContext(ctx, stmt, userID); err != nil {
+			return fmt.Errorf("anonymize user %d: %w", userID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *userDataRepository) AnonymizeUsageLogs(ctx context.Context, userID int64, batchSize int) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE usage_logs SET ip_address = NULL, user_agent = NULL
+		WHERE id IN (
+			SELECT id FROM usage_logs
+			WHERE user_id = $1 AND (ip_address IS NOT NULL OR user_agent IS NOT NULL)
+			LIMIT $2
+		)`, userID, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
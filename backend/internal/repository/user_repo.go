@@ -438,7 +438,8 @@ func normalizeEmailAuthIdentitySubject(email string) string {
 	if strings.HasSuffix(normalized, service.LinuxDoConnectSyntheticEmailDomain) ||
 		strings.HasSuffix(normalized, service.OIDCConnectSyntheticEmailDomain) ||
 		strings.HasSuffix(normalized, service.WeChatConnectSyntheticEmailDomain) ||
-		strings.HasSuffix(normalized, service.DingTalkConnectSyntheticEmailDomain) {
+		strings.HasSuffix(normalized, service.DingTalkConnectSyntheticEmailDomain) ||
+		strings.HasSuffix(normalized, service.SAMLSyntheticEmailDomain) {
 		return ""
 	}
 	return normalized
@@ -536,7 +537,8 @@ func (r *userRepository) ListWithFilters(ctx context.Context, params pagination.
 				dbuser.EmailContainsFold(filters.Search),
 				dbuser.UsernameContainsFold(filters.Search),
 				dbuser.NotesContainsFold(filters.Search),
-				dbuser.HasAPIKeysWith(apikey.KeyContainsFold(filters.Search)),
+				// key 列存储的是 HMAC 摘要，只按明文前缀匹配，避免十六进制搜索命中摘要。
+				dbuser.HasAPIKeysWith(apikey.KeyPrefixContainsFold(filters.Search)),
 			),
 		)
 	}
@@ -1468,7 +1470,7 @@ func userSignupSourceOrDefault(signupSource string) string {
 	switch strings.TrimSpace(strings.ToLower(signupSource)) {
 	case "", "email":
 		return "email"
-	case "linuxdo", "wechat", "oidc", "dingtalk":
+	case "linuxdo", "wechat", "oidc", "dingtalk", "saml":
 		return strings.TrimSpace(strings.ToLower(signupSource))
 	default:
 		return "email"
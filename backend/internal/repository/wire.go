@@ -66,17 +66,19 @@ func ProvideSchedulerCache(rdb *redis.Client, cfg *config.Config) service.Schedu
 // ProviderSet is the Wire provider set for all repositories
 var ProviderSet = wire.NewSet(
 	NewUserRepository,
-	NewAPIKeyRepository,
+	ProvideAPIKeyRepository,
+	ProvideAPIKeyHashBackfillRepository,
 	NewGroupRepository,
 	NewGroupStatusRepository,
 	NewReferralRepository,
 	NewAdminGroupRepository,
 	NewCompositeModelRouteRepository,
-	NewAccountRepository,
-	NewAdminAccountRepository,
+	ProvideAccountRepository,
+	ProvideAdminAccountRepository,
 	NewScheduledTestPlanRepository,   // 定时测试计划仓储
 	NewScheduledTestResultRepository, // 定时测试结果仓储
-	NewProxyRepository,
+	ProvideProxyRepository,
+	NewCredentialReencryptionRepository,
 	NewRedeemCodeRepository,
 	NewPromoCodeRepository,
 	NewAnnouncementRepository,
@@ -90,6 +92,17 @@ var ProviderSet = wire.NewSet(
 	NewSettingRepository,
 	NewOpsRepository,
 	NewAuditLogRepository,
+	NewAuditLogExportCursorRepository,
+	NewAdminRBACRepository,
+	NewSCIMRepository,
+	NewAPIKeyLeakRepository,
+	NewAPIKeyRotationRepository,
+	NewScopedTokenUsageCache,
+	NewUserDataRepository,
+	NewAccountCostRepository,
+	NewUpstreamFileRepository,
+	NewProxyPoolRepository,
+	NewProxySubscriptionRepository,
 	NewPasskeyRepository,
 	NewPasskeySessionStore,
 	NewUserSubscriptionRepository,
@@ -124,6 +137,8 @@ var ProviderSet = wire.NewSet(
 	NewUpdateCache,
 	NewGeminiTokenCache,
 	NewImageTaskStore,
+	NewBackgroundResponseStore,
+	NewStreamResumeStore,
 	NewBatchImageQueue,
 	NewBatchImageDownloadLimiter,
 	NewLeaderLockCache,
@@ -139,6 +154,7 @@ var ProviderSet = wire.NewSet(
 
 	// Encryptors
 	NewAESEncryptor,
+	ProvideMasterKeyProvider,
 
 	// Backup infrastructure
 	NewPgDumper,
@@ -371,6 +371,8 @@ func TestAPIContracts(t *testing.T) {
 						"audio_tts_price_per_million_chars": null,
 						"audio_stt_price_per_hour": null,
 						"audio_realtime_price_per_min": null,
+						"moderation_price_per_call": null,
+						"moderation_price_per_million_tokens": null,
 						"allow_image_generation": false,
 						"allow_batch_image_generation": false,
 						"batch_image_discount_multiplier": 0,
@@ -17,19 +17,22 @@ func NewAdminAuthMiddleware(
 	userService *service.UserService,
 	settingService *service.SettingService,
 	auditService *service.AuditLogService,
+	rbacService *service.AdminRBACService,
 ) AdminAuthMiddleware {
-	return AdminAuthMiddleware(adminAuth(authService, userService, settingService, auditService))
+	return AdminAuthMiddleware(adminAuth(authService, userService, settingService, auditService, rbacService))
 }
 
 // adminAuth 管理员认证中间件实现
-// 支持两种认证方式（通过不同的 header 区分）：
-// 1. Admin API Key: x-api-key: <admin-api-key>
-// 2. JWT Token: Authorization: Bearer <jwt-token> (需要管理员角色)
+// 支持三种认证方式（通过不同的 header 区分）：
+// 1. Admin API Key: x-api-key: <admin-api-key>（全局密钥，拥有全部权限）
+// 2. 具名 Admin API Token: x-api-key / Authorization: Bearer admtok-...（权限为 scope 与创建者权限的交集）
+// 3. JWT Token: Authorization: Bearer <jwt-token> (需要管理员角色，权限由分配的角色决定)
 func adminAuth(
 	authService *service.AuthService,
 	userService *service.UserService,
 	settingService *service.SettingService,
 	auditService *service.AuditLogService,
+	rbacService *service.AdminRBACService,
 ) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// WebSocket upgrade requests cannot set Authorization headers in browsers.
@@ -38,7 +41,7 @@ func adminAuth(
 		//   Sec-WebSocket-Protocol: sub2api-admin, jwt.<token>
 		if isWebSocketUpgradeRequest(c) {
 			if token := extractJWTFromWebSocketSubprotocol(c); token != "" {
-				if !validateJWTForAdmin(c, token, authService, userService, settingService, auditService) {
+				if !validateJWTForAdmin(c, token, authService, userService, settingService, auditService, rbacService) {
 					return
 				}
 				c.Next()
@@ -46,9 +49,16 @@ func adminAuth(
 			}
 		}
 
-		// 检查 x-api-key header（Admin API Key 认证）
+		// 检查 x-api-key header（Admin API Key / 具名 Token 认证）
 		apiKey := c.GetHeader("x-api-key")
 		if apiKey != "" {
+			if strings.HasPrefix(apiKey, service.AdminAPITokenPrefix) {
+				if !validateAdminAPIToken(c, apiKey, userService, rbacService) {
+					return
+				}
+				c.Next()
+				return
+			}
 			if !validateAdminAPIKey(c, apiKey, settingService, userService) {
 				return
 			}
@@ -66,7 +76,14 @@ func adminAuth(
 					AbortWithError(c, 401, "UNAUTHORIZED", "Authorization required")
 					return
 				}
-				if !validateJWTForAdmin(c, token, authService, userService, settingService, auditService) {
+				if strings.HasPrefix(token, service.AdminAPITokenPrefix) {
+					if !validateAdminAPIToken(c, token, userService, rbacService) {
+						return
+					}
+					c.Next()
+					return
+				}
+				if !validateJWTForAdmin(c, token, authService, userService, settingService, auditService, rbacService) {
 					return
 				}
 				c.Next()
@@ -150,6 +167,52 @@ func validateAdminAPIKey(
 	c.Set(string(ContextKeyUserRole), admin.Role)
 	c.Set(ContextKeyAuthEmail, admin.Email)
 	c.Set("auth_method", "admin_api_key")
+	setAdminPermissions(c, service.AdminRoleSuperAdmin, service.NewAdminPermissionSet(service.AdminPermissionAll))
+	return true
+}
+
+// validateAdminAPIToken 验证具名管理员 API Token。
+// 操作以 Token 创建者的身份记录审计；有效权限取 scope 与创建者当前权限的交集，
+// 创建者被降权或停用后 Token 随之收缩或失效。
+func validateAdminAPIToken(
+	c *gin.Context,
+	key string,
+	userService *service.UserService,
+	rbacService *service.AdminRBACService,
+) bool {
+	token, err := rbacService.AuthenticateToken(c.Request.Context(), key, SecurityClientIP(c))
+	if err != nil {
+		if errors.Is(err, service.ErrAdminAPITokenInvalid) {
+			AbortWithError(c, 401, "INVALID_ADMIN_KEY", "Invalid admin API key")
+			return false
+		}
+		AbortWithError(c, 500, "INTERNAL_ERROR", "Internal server error")
+		return false
+	}
+
+	creator, err := userService.GetByID(c.Request.Context(), token.CreatedBy)
+	if err != nil || !creator.IsActive() || !creator.IsAdmin() {
+		AbortWithError(c, 401, "INVALID_ADMIN_KEY", "Invalid admin API key")
+		return false
+	}
+	role, creatorPerms, err := rbacService.ResolveUserPermissions(c.Request.Context(), creator.ID)
+	if err != nil {
+		AbortWithError(c, 500, "INTERNAL_ERROR", "Internal server error")
+		return false
+	}
+
+	c.Set(string(ContextKeyUser), AuthSubject{
+		UserID:      creator.ID,
+		Concurrency: creator.Concurrency,
+	})
+	c.Set(string(ContextKeyUserRole), creator.Role)
+	c.Set(ContextKeyAuthEmail, creator.Email)
+	c.Set("auth_method", service.AuditAuthMethodAdminAPIToken)
+	setAdminPermissions(c, role, service.NewAdminPermissionSet(token.Scopes...).Intersect(creatorPerms))
+	SetAuditExtra(c, map[string]any{
+		"admin_token_id":   token.ID,
+		"admin_token_name": token.Name,
+	})
 	return true
 }
 
@@ -161,6 +224,7 @@ func validateJWTForAdmin(
 	userService *service.UserService,
 	settingService *service.SettingService,
 	auditService *service.AuditLogService,
+	rbacService *service.AdminRBACService,
 ) bool {
 	// 验证 JWT token
 	claims, err := authService.ValidateToken(token)
@@ -212,5 +276,101 @@ func validateJWTForAdmin(
 	c.Set(ContextKeySessionID, claims.SessionID)
 	c.Set("auth_method", "jwt")
 
+	role, perms, err := rbacService.ResolveUserPermissions(c.Request.Context(), user.ID)
+	if err != nil {
+		AbortWithError(c, 500, "INTERNAL_ERROR", "Internal server error")
+		return false
+	}
+	setAdminPermissions(c, role, perms)
+	if role != service.AdminRoleSuperAdmin {
+		SetAuditExtra(c, map[string]any{"admin_role": role})
+	}
+
 	return true
 }
+
+// 管理员权限相关 gin context 键（由 adminAuth 写入）。
+const (
+	ContextKeyAdminPermissions = "admin_permissions"
+	ContextKeyAdminRole        = "admin_role"
+)
+
+// adminRoutePermissionOverrides 少数敏感路由不按所在路由组的读写权限判断（method+FullPath → 权限）。
+var adminRoutePermissionOverrides = map[string]string{
+	// 导出接口返回上游凭证 / 代理密码原文
+	"GET /api/v1/admin/accounts/data": service.AdminPermissionCredentialsReveal,
+	"GET /api/v1/admin/proxies/data":  service.AdminPermissionCredentialsReveal,
+	// 余额调整属于财务操作
+	"POST /api/v1/admin/users/:id/balance": service.AdminPermissionBillingWrite,
+	// 全局 admin API key 等同超级管理员
+	"GET /api/v1/admin/settings/admin-api-key":             service.AdminPermissionAdminsManage,
+	"POST /api/v1/admin/settings/admin-api-key/regenerate": service.AdminPermissionAdminsManage,
+	"DELETE /api/v1/admin/settings/admin-api-key":          service.AdminPermissionAdminsManage,
+	// 风控 / 提示词审计的配置属于系统设置，其余操作（如解封）归用户管理或运维
+	"GET /api/v1/admin/risk-control/config":         service.AdminPermissionSettingsRead,
+	"PUT /api/v1/admin/risk-control/config":         service.AdminPermissionSettingsWrite,
+	"POST /api/v1/admin/risk-control/api-keys/test": service.AdminPermissionSettingsWrite,
+	"PUT /api/v1/admin/prompt-audit/config":         service.AdminPermissionSettingsWrite,
+	// 路由组内的只读 POST（批量查询 / 预览）
+	"POST /api/v1/admin/dashboard/users-usage":               service.AdminPermissionUsageRead,
+	"POST /api/v1/admin/dashboard/api-keys-usage":            service.AdminPermissionUsageRead,
+	"POST /api/v1/admin/accounts/usage/batch":                service.AdminPermissionAccountsRead,
+	"POST /api/v1/admin/accounts/today-stats/batch":          service.AdminPermissionAccountsRead,
+	"POST /api/v1/admin/user-attributes/batch":               service.AdminPermissionUsersRead,
+	"POST /api/v1/admin/accounts/check-mixed-channel":        service.AdminPermissionAccountsRead,
+	"POST /api/v1/admin/groups/:id/composite-routes/preview": service.AdminPermissionGroupsRead,
+}
+
+// RequireAdminPermission 按路由组绑定权限：GET/HEAD 需要 read，其余方法需要 write。
+// 命中 adminRoutePermissionOverrides 的路由改用映射中的权限。
+// 必须挂在 adminAuth 之后；上下文中没有权限集合时拒绝（fail-closed）。
+func RequireAdminPermission(read, write string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		required := write
+		if c.Request.Method == "GET" || c.Request.Method == "HEAD" {
+			required = read
+		}
+		if perm, ok := adminRoutePermissionOverrides[c.Request.Method+" "+c.FullPath()]; ok {
+			required = perm
+		}
+		if !HasAdminPermission(c, required) {
+			AbortWithError(c, 403, "ADMIN_PERMISSION_DENIED", "Missing admin permission: "+required)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAdminAPIToken 仅放行具名管理员 API Token 认证的请求（拒绝 JWT 会话与全局 admin key），
+// 用于 SCIM 等面向机器的集成入口，保证每个调用方都可按 Token 单独吊销与审计。
+func RequireAdminAPIToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") != service.AuditAuthMethodAdminAPIToken {
+			AbortWithError(c, 403, "ADMIN_API_TOKEN_REQUIRED", "A scoped admin API token is required")
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasAdminPermission 判断当前管理员主体是否拥有权限，供 handler 做字段级校验。
+func HasAdminPermission(c *gin.Context, perm string) bool {
+	value, ok := c.Get(ContextKeyAdminPermissions)
+	if !ok {
+		return false
+	}
+	perms, ok := value.(service.AdminPermissionSet)
+	return ok && perms.Has(perm)
+}
+
+// GetAdminPermissions 返回当前管理员主体的角色名与有效权限。
+func GetAdminPermissions(c *gin.Context) (string, service.AdminPermissionSet) {
+	perms, _ := c.Get(ContextKeyAdminPermissions)
+	set, _ := perms.(service.AdminPermissionSet)
+	return c.GetString(ContextKeyAdminRole), set
+}
+
+func setAdminPermissions(c *gin.Context, role string, perms service.AdminPermissionSet) {
+	c.Set(ContextKeyAdminRole, role)
+	c.Set(ContextKeyAdminPermissions, perms)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/Wei-Shaw/sub2api/internal/config"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/ctxkey"
@@ -71,7 +72,7 @@ func apiKeyAuthWithSubscription(apiKeyService *service.APIKeyService, subscripti
 		if apiKeyString == "" {
 			apiKeyString = c.GetHeader("x-api-key")
 		}
-		if len(apiKeyString) > service.MaxAPIKeyCredentialBytes {
+		if apiKeyCredentialTooLarge(apiKeyString) {
 			recordInvalidAuthFailure(c, apiKeyService)
 			MarkIngressRejected(c, IngressRejectInvalidAPIKey)
 			AbortWithError(c, http.StatusUnauthorized, "INVALID_API_KEY", "Invalid API key")
@@ -97,14 +98,20 @@ func apiKeyAuthWithSubscription(apiKeyService *service.APIKeyService, subscripti
 
 		// ── 2. 验证 Key 存在 ─────────────────────────────────────────
 
-		apiKey, err := apiKeyService.GetByKey(c.Request.Context(), apiKeyString)
+		// 派生令牌（sst.）校验签名后解析为父 Key，用量记在父 Key 上。
+		apiKey, err := authenticateAPIKeyCredential(c.Request.Context(), apiKeyService, apiKeyString)
 		if err != nil {
-			if errors.Is(err, service.ErrAPIKeyNotFound) {
+			if errors.Is(err, service.ErrAPIKeyNotFound) || isInvalidScopedTokenError(err) {
 				recordInvalidAuthFailure(c, apiKeyService)
 				MarkIngressRejected(c, IngressRejectInvalidAPIKey)
 				AbortWithError(c, 401, "INVALID_API_KEY", "Invalid API key")
 				return
 			}
+			if errors.Is(err, service.ErrScopedTokenExpired) {
+				MarkIngressRejected(c, IngressRejectInvalidAPIKey)
+				AbortWithError(c, http.StatusUnauthorized, "SCOPED_TOKEN_EXPIRED", "Scoped token has expired")
+				return
+			}
 			if errors.Is(err, service.ErrAPIKeyAuthOverloaded) {
 				MarkIngressRejected(c, IngressRejectAPIKeyAuthOverloaded)
 				AbortWithError(c, http.StatusServiceUnavailable, "API_KEY_AUTH_OVERLOADED", "API key authentication is temporarily unavailable")
@@ -163,17 +170,29 @@ func apiKeyAuthWithSubscription(apiKeyService *service.APIKeyService, subscripti
 		if abortIfAPIKeyGroupNotAllowed(c, apiKey) {
 			return
 		}
+		// 派生令牌的来源/模型限制属于鉴权范围，始终执行。
+		if rejection, ok := checkScopedTokenScope(c, apiKey); !ok {
+			AbortWithError(c, rejection.status, rejection.code, rejection.message)
+			return
+		}
+		setAPIKeyRotationWarning(c, apiKeyService, apiKey.ID)
 		ctx := context.WithValue(c.Request.Context(), ctxkey.UserID, apiKey.User.ID)
 		c.Request = c.Request.WithContext(ctx)
 		billingInfoRequest := c.Request.URL.Path == "/v1/sub2api/billing"
 		// Async image task polling only reads data that already belongs to the
 		// authenticated key and must remain available after the completed
 		// generation consumes the key's remaining balance.
-		skipBilling := c.Request.URL.Path == "/v1/usage" || billingInfoRequest || isAsyncImageTaskRead(c.Request.Method, c.Request.URL.Path)
+		skipBilling := c.Request.URL.Path == "/v1/usage" || billingInfoRequest || isAsyncImageTaskRead(c.Request.Method, c.Request.URL.Path) || isBackgroundResponseRead(c.Request.Method, c.Request.URL.Path)
 
 		// ── 4. SimpleMode → early return ─────────────────────────────
 
 		if cfg.RunMode == config.RunModeSimple {
+			if !skipBilling {
+				if rejection, ok := admitScopedToken(c, apiKeyService, apiKey); !ok {
+					AbortWithError(c, rejection.status, rejection.code, rejection.message)
+					return
+				}
+			}
 			c.Set(string(ContextKeyAPIKey), apiKey)
 			c.Set(string(ContextKeyUser), AuthSubject{
 				UserID:      apiKey.User.ID,
@@ -184,7 +203,7 @@ func apiKeyAuthWithSubscription(apiKeyService *service.APIKeyService, subscripti
 			if !billingInfoRequest {
 				_ = apiKeyService.TouchLastUsed(c.Request.Context(), apiKey.ID)
 			}
-			c.Next()
+			nextReleasingScopedToken(c, apiKeyService, apiKey)
 			return
 		}
 
@@ -265,6 +284,12 @@ func apiKeyAuthWithSubscription(apiKeyService *service.APIKeyService, subscripti
 					return
 				}
 			}
+
+			// 派生令牌的请求数/花费上限放在最后，避免被上面拒绝的请求占用令牌配额。
+			if rejection, ok := admitScopedToken(c, apiKeyService, apiKey); !ok {
+				AbortWithError(c, rejection.status, rejection.code, rejection.message)
+				return
+			}
 		}
 
 		// ── 7. 设置上下文 → Next ─────────────────────────────────────
@@ -283,7 +308,7 @@ func apiKeyAuthWithSubscription(apiKeyService *service.APIKeyService, subscripti
 			_ = apiKeyService.TouchLastUsed(c.Request.Context(), apiKey.ID)
 		}
 
-		c.Next()
+		nextReleasingScopedToken(c, apiKeyService, apiKey)
 	}
 }
 
@@ -291,9 +316,9 @@ func apiKeyHeadersTooLarge(c *gin.Context) bool {
 	if c == nil {
 		return false
 	}
-	return len(c.GetHeader("Authorization")) > maxAPIKeyAuthorizationHeaderBytes ||
-		len(c.GetHeader("x-api-key")) > service.MaxAPIKeyCredentialBytes ||
-		len(c.GetHeader("x-goog-api-key")) > service.MaxAPIKeyCredentialBytes
+	return credentialHeaderTooLarge(c.GetHeader("Authorization"), maxAPIKeyAuthorizationHeaderBytes) ||
+		credentialHeaderTooLarge(c.GetHeader("x-api-key"), service.MaxAPIKeyCredentialBytes) ||
+		credentialHeaderTooLarge(c.GetHeader("x-goog-api-key"), service.MaxAPIKeyCredentialBytes)
 }
 
 func hasAPIKeyCredentialInput(c *gin.Context) bool {
@@ -340,6 +365,29 @@ func isAsyncImageTaskRead(method, path string) bool {
 	return strings.HasPrefix(path, "/v1/images/tasks/") || strings.HasPrefix(path, "/images/tasks/")
 }
 
+// isBackgroundResponseRead 识别网关托管后台响应的查询/续流/取消请求：
+// 与异步图片任务轮询一样，只读写调用方自己已受理的任务，不应因余额耗尽而被拒。
+func isBackgroundResponseRead(method, path string) bool {
+	rest, ok := strings.CutPrefix(path, "/v1/responses/")
+	if !ok {
+		rest, ok = strings.CutPrefix(path, "/responses/")
+	}
+	if !ok {
+		return false
+	}
+	id, action, hasAction := strings.Cut(rest, "/")
+	if !service.IsBackgroundResponseID(id) {
+		return false
+	}
+	switch method {
+	case http.MethodGet:
+		return !hasAction
+	case http.MethodPost:
+		return hasAction && action == "cancel"
+	}
+	return false
+}
+
 // GetAPIKeyFromContext 从上下文中获取API key
 func GetAPIKeyFromContext(c *gin.Context) (*service.APIKey, bool) {
 	value, exists := c.Get(string(ContextKeyAPIKey))
@@ -413,6 +461,17 @@ func abortIfAPIKeyGroupUnavailable(c *gin.Context, apiKey *service.APIKey) bool
 	return true
 }
 
+// setAPIKeyRotationWarning 已被轮换、处于宽限期内的旧 Key 仍放行，但在响应头中提示其失效时间，
+// 便于调用方在旧 Key 过期前发现遗漏更新的客户端。
+func setAPIKeyRotationWarning(c *gin.Context, apiKeyService *service.APIKeyService, apiKeyID int64) {
+	deadline, ok := apiKeyService.RotationGraceDeadline(apiKeyID)
+	if !ok {
+		return
+	}
+	c.Header("Warning", fmt.Sprintf(`299 - "API key has been rotated and stops working at %s; switch to the new key"`, deadline.UTC().Format(time.RFC3339)))
+	c.Header("Sunset", deadline.UTC().Format(http.TimeFormat))
+}
+
 func abortIfAPIKeyGroupNotAllowed(c *gin.Context, apiKey *service.APIKey) bool {
 	if validateAPIKeyGroupAllowed(apiKey) {
 		return false
@@ -51,21 +51,26 @@ func APIKeyAuthWithSubscriptionGoogle(apiKeyService *service.APIKeyService, subs
 			abortWithGoogleError(c, 401, "API key is required")
 			return
 		}
-		if len(apiKeyString) > service.MaxAPIKeyCredentialBytes {
+		if apiKeyCredentialTooLarge(apiKeyString) {
 			recordInvalidAuthFailure(c, apiKeyService)
 			MarkIngressRejected(c, IngressRejectInvalidAPIKey)
 			abortWithGoogleError(c, 401, "Invalid API key")
 			return
 		}
 
-		apiKey, err := apiKeyService.GetByKey(c.Request.Context(), apiKeyString)
+		apiKey, err := authenticateAPIKeyCredential(c.Request.Context(), apiKeyService, apiKeyString)
 		if err != nil {
-			if errors.Is(err, service.ErrAPIKeyNotFound) {
+			if errors.Is(err, service.ErrAPIKeyNotFound) || isInvalidScopedTokenError(err) {
 				recordInvalidAuthFailure(c, apiKeyService)
 				MarkIngressRejected(c, IngressRejectInvalidAPIKey)
 				abortWithGoogleError(c, 401, "Invalid API key")
 				return
 			}
+			if errors.Is(err, service.ErrScopedTokenExpired) {
+				MarkIngressRejected(c, IngressRejectInvalidAPIKey)
+				abortWithGoogleError(c, 401, "Scoped token has expired")
+				return
+			}
 			if errors.Is(err, service.ErrAPIKeyAuthOverloaded) {
 				MarkIngressRejected(c, IngressRejectAPIKeyAuthOverloaded)
 				abortWithGoogleError(c, 503, "API key authentication is temporarily unavailable")
@@ -130,9 +135,18 @@ func APIKeyAuthWithSubscriptionGoogle(apiKeyService *service.APIKeyService, subs
 			abortWithGoogleError(c, 403, "API Key 所属专属分组不再允许当前用户使用")
 			return
 		}
+		if rejection, ok := checkScopedTokenScope(c, apiKey); !ok {
+			abortWithGoogleError(c, rejection.status, rejection.message)
+			return
+		}
+		setAPIKeyRotationWarning(c, apiKeyService, apiKey.ID)
 
 		// 简易模式：跳过余额和订阅检查
 		if cfg.RunMode == config.RunModeSimple {
+			if rejection, ok := admitScopedToken(c, apiKeyService, apiKey); !ok {
+				abortWithGoogleError(c, rejection.status, rejection.message)
+				return
+			}
 			c.Set(string(ContextKeyAPIKey), apiKey)
 			c.Set(string(ContextKeyUser), AuthSubject{
 				UserID:      apiKey.User.ID,
@@ -141,7 +155,7 @@ func APIKeyAuthWithSubscriptionGoogle(apiKeyService *service.APIKeyService, subs
 			c.Set(string(ContextKeyUserRole), apiKey.User.Role)
 			setGroupContext(c, apiKey.Group)
 			_ = apiKeyService.TouchLastUsed(c.Request.Context(), apiKey.ID)
-			c.Next()
+			nextReleasingScopedToken(c, apiKeyService, apiKey)
 			return
 		}
 
@@ -205,6 +219,10 @@ func APIKeyAuthWithSubscriptionGoogle(apiKeyService *service.APIKeyService, subs
 				return
 			}
 		}
+		if rejection, ok := admitScopedToken(c, apiKeyService, apiKey); !ok {
+			abortWithGoogleError(c, rejection.status, rejection.message)
+			return
+		}
 
 		c.Set(string(ContextKeyAPIKey), apiKey)
 		c.Set(string(ContextKeyUser), AuthSubject{
@@ -214,7 +232,7 @@ func APIKeyAuthWithSubscriptionGoogle(apiKeyService *service.APIKeyService, subs
 		c.Set(string(ContextKeyUserRole), apiKey.User.Role)
 		setGroupContext(c, apiKey.Group)
 		_ = apiKeyService.TouchLastUsed(c.Request.Context(), apiKey.ID)
-		c.Next()
+		nextReleasingScopedToken(c, apiKeyService, apiKey)
 	}
 }
 
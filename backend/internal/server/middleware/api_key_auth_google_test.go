@@ -398,6 +398,59 @@ func TestApiKeyAuthWithSubscriptionGoogleSetsGroupContext(t *testing.T) {
 	require.Equal(t, http.StatusOK, rec.Code)
 }
 
+type stubRotationGraceLookup map[int64]time.Time
+
+func (s stubRotationGraceLookup) GraceDeadline(apiKeyID int64) (time.Time, bool) {
+	deadline, ok := s[apiKeyID]
+	return deadline, ok
+}
+
+func TestApiKeyAuthWithSubscriptionGoogleSetsRotationWarningDuringGrace(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	user := &service.User{ID: 7, Role: service.RoleUser, Status: service.StatusActive, Balance: 10, Concurrency: 3}
+	keys := map[string]*service.APIKey{
+		"rotated-key": {ID: 100, UserID: user.ID, Key: "rotated-key", Status: service.StatusActive, User: user},
+		"current-key": {ID: 101, UserID: user.ID, Key: "current-key", Status: service.StatusActive, User: user},
+	}
+	cfg := &config.Config{RunMode: config.RunModeSimple}
+	apiKeyService := service.NewAPIKeyService(
+		fakeAPIKeyRepo{
+			getByKey: func(ctx context.Context, key string) (*service.APIKey, error) {
+				apiKey, ok := keys[key]
+				if !ok {
+					return nil, service.ErrAPIKeyNotFound
+				}
+				clone := *apiKey
+				return &clone, nil
+			},
+		},
+		nil, nil, nil, nil, nil, cfg,
+	)
+	deadline := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	apiKeyService.SetRotationGraceLookup(stubRotationGraceLookup{100: deadline})
+
+	r := gin.New()
+	r.Use(APIKeyAuthWithSubscriptionGoogle(apiKeyService, nil, cfg))
+	r.GET("/v1beta/test", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+
+	req := httptest.NewRequest(http.MethodGet, "/v1beta/test", nil)
+	req.Header.Set("x-api-key", "rotated-key")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Header().Get("Warning"), "2030-01-02T03:04:05Z")
+	require.Equal(t, "Wed, 02 Jan 2030 03:04:05 GMT", rec.Header().Get("Sunset"))
+
+	req = httptest.NewRequest(http.MethodGet, "/v1beta/test", nil)
+	req.Header.Set("x-api-key", "current-key")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Header().Get("Warning"))
+	require.Empty(t, rec.Header().Get("Sunset"))
+}
+
 func TestApiKeyAuthWithSubscriptionGoogle_QueryKeyAllowedOnV1Beta(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
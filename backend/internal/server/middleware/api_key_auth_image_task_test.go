@@ -13,3 +13,13 @@ func TestIsAsyncImageTaskRead(t *testing.T) {
 	require.False(t, isAsyncImageTaskRead(http.MethodPost, "/v1/images/tasks/imgtask_123"))
 	require.False(t, isAsyncImageTaskRead(http.MethodGet, "/v1/images/generations"))
 }
+
+func TestIsBackgroundResponseRead(t *testing.T) {
+	require.True(t, isBackgroundResponseRead(http.MethodGet, "/v1/responses/resp_bg123"))
+	require.True(t, isBackgroundResponseRead(http.MethodGet, "/responses/resp_bg123"))
+	require.True(t, isBackgroundResponseRead(http.MethodPost, "/v1/responses/resp_bg123/cancel"))
+	require.False(t, isBackgroundResponseRead(http.MethodPost, "/v1/responses/resp_bg123"))
+	require.False(t, isBackgroundResponseRead(http.MethodPost, "/v1/responses/resp_upstream/cancel"))
+	require.False(t, isBackgroundResponseRead(http.MethodPost, "/v1/responses/compact"))
+	require.False(t, isBackgroundResponseRead(http.MethodGet, "/v1/responses"))
+}
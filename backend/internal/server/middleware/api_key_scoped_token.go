@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	pkghttputil "github.com/Wei-Shaw/sub2api/internal/pkg/httputil"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scopedTokenRejection 派生令牌校验失败时的响应内容，由主中间件与 Google 风格中间件各自渲染。
+type scopedTokenRejection struct {
+	status  int
+	code    string
+	message string
+}
+
+// credentialHeaderTooLarge 判断凭证头是否超长：API Key 按 keyLimit 限制，
+// 派生令牌携带范围声明，按 service.MaxScopedTokenBytes 放宽。
+func credentialHeaderTooLarge(value string, keyLimit int) bool {
+	if len(value) <= keyLimit {
+		return false
+	}
+	token := strings.TrimSpace(value)
+	if parts := strings.SplitN(token, " ", 2); len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
+		token = strings.TrimSpace(parts[1])
+	}
+	return !service.IsScopedToken(token) || len(token) > service.MaxScopedTokenBytes
+}
+
+// apiKeyCredentialTooLarge 判断已提取的凭证是否超长。
+func apiKeyCredentialTooLarge(credential string) bool {
+	if service.IsScopedToken(credential) {
+		return len(credential) > service.MaxScopedTokenBytes
+	}
+	return len(credential) > service.MaxAPIKeyCredentialBytes
+}
+
+// authenticateAPIKeyCredential 按凭证类型认证：派生令牌校验签名后返回父 Key，其余按 API Key 查找。
+func authenticateAPIKeyCredential(ctx context.Context, apiKeyService *service.APIKeyService, credential string) (*service.APIKey, error) {
+	if service.IsScopedToken(credential) {
+		return apiKeyService.GetByScopedToken(ctx, credential)
+	}
+	return apiKeyService.GetByKey(ctx, credential)
+}
+
+// isInvalidScopedTokenError 判断是否为应按“无效凭证”处理（计入失败次数）的派生令牌错误。
+// 功能关闭时同样视为无效，避免暴露服务端配置。
+func isInvalidScopedTokenError(err error) bool {
+	return errors.Is(err, service.ErrScopedTokenInvalid) || errors.Is(err, service.ErrScopedTokenDisabled)
+}
+
+// checkScopedTokenScope 校验派生令牌的来源与模型限制；普通 API Key 直接放行。
+//
+// 模型限制需要读取请求体，读取后会重置请求体供后续处理器使用。
+// 限制了模型的令牌若无法从请求中识别模型则拒绝，避免绕过范围限制。
+func checkScopedTokenScope(c *gin.Context, apiKey *service.APIKey) (scopedTokenRejection, bool) {
+	if apiKey == nil || apiKey.ScopedToken == nil {
+		return scopedTokenRejection{}, true
+	}
+	claims := apiKey.ScopedToken
+	if !claims.AllowsOrigin(c.GetHeader("Origin")) {
+		return scopedTokenRejection{http.StatusForbidden, "SCOPED_TOKEN_ORIGIN_NOT_ALLOWED", "Origin is not allowed for this token"}, false
+	}
+	if len(claims.Models) == 0 || c.Request == nil || c.Request.Method == http.MethodGet {
+		return scopedTokenRejection{}, true
+	}
+
+	model := GeminiModelFromParams(c)
+	if model == "" {
+		body, err := pkghttputil.ReadRequestBodyWithPrealloc(c.Request)
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				return scopedTokenRejection{http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE", "Request body is too large"}, false
+			}
+			return scopedTokenRejection{http.StatusBadRequest, "INVALID_REQUEST", "Failed to read request body"}, false
+		}
+		model = pkghttputil.RequestModelFromBody(c.GetHeader("Content-Type"), body)
+		resetRequestBody(c, body)
+	}
+	if !claims.AllowsModel(model) {
+		return scopedTokenRejection{http.StatusForbidden, "SCOPED_TOKEN_MODEL_NOT_ALLOWED", "Model is not allowed for this token"}, false
+	}
+	return scopedTokenRejection{}, true
+}
+
+// admitScopedToken 执行派生令牌的请求数与花费上限（仅计费请求），花费预占由 nextReleasingScopedToken 结清。
+func admitScopedToken(c *gin.Context, apiKeyService *service.APIKeyService, apiKey *service.APIKey) (scopedTokenRejection, bool) {
+	if apiKey == nil || apiKey.ScopedToken == nil {
+		return scopedTokenRejection{}, true
+	}
+	if err := apiKeyService.AdmitScopedToken(c.Request.Context(), apiKey.ScopedToken); err != nil {
+		status := infraerrors.Code(err)
+		if status < http.StatusBadRequest {
+			status = http.StatusInternalServerError
+		}
+		return scopedTokenRejection{status, infraerrors.Reason(err), infraerrors.Message(err)}, false
+	}
+	return scopedTokenRejection{}, true
+}
+
+// nextReleasingScopedToken 继续处理请求，结束后退回派生令牌在受理时预占、尚未被扣费结清的花费。
+func nextReleasingScopedToken(c *gin.Context, apiKeyService *service.APIKeyService, apiKey *service.APIKey) {
+	if apiKey == nil || apiKey.ScopedToken == nil {
+		c.Next()
+		return
+	}
+	defer apiKeyService.ReleaseScopedToken(c.Request.Context(), apiKey.ScopedToken)
+	c.Next()
+}
+
+// GeminiModelFromParams 从 Gemini 原生路由参数（:model 或 :modelAction）提取模型名。
+func GeminiModelFromParams(c *gin.Context) string {
+	if c == nil {
+		return ""
+	}
+	if model := strings.TrimSpace(c.Param("model")); model != "" {
+		return model
+	}
+	modelAction := strings.TrimPrefix(strings.TrimSpace(c.Param("modelAction")), "/")
+	if modelAction == "" {
+		return ""
+	}
+	if idx := strings.LastIndex(modelAction, ":"); idx >= 0 {
+		return strings.TrimSpace(modelAction[:idx])
+	}
+	return modelAction
+}
+
+func resetRequestBody(c *gin.Context, body []byte) {
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	c.Request.ContentLength = int64(len(body))
+	c.Request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+}
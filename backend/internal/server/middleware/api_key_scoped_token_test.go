@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type stubScopedTokenAuthenticator struct {
+	claims   *service.ScopedTokenClaims
+	parent   *service.APIKey
+	admitErr error
+	admitted int
+	released int
+}
+
+func (s *stubScopedTokenAuthenticator) Authenticate(_ context.Context, token string) (*service.APIKey, error) {
+	if token != "sst.valid."+strings.Repeat("x", 600) {
+		return nil, service.ErrScopedTokenInvalid
+	}
+	clone := *s.parent
+	clone.ScopedToken = s.claims
+	return &clone, nil
+}
+
+func (s *stubScopedTokenAuthenticator) Admit(_ context.Context, _ *service.ScopedTokenClaims) error {
+	s.admitted++
+	return s.admitErr
+}
+
+func (s *stubScopedTokenAuthenticator) RecordSpend(context.Context, *service.ScopedTokenClaims, float64) {
+}
+
+func (s *stubScopedTokenAuthenticator) Release(context.Context, *service.ScopedTokenClaims) {
+	s.released++
+}
+
+func TestAPIKeyAuthAcceptsScopedTokenWithinScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	user := &service.User{ID: 7, Role: service.RoleUser, Status: service.StatusActive, Balance: 10, Concurrency: 3}
+	cfg := &config.Config{RunMode: config.RunModeSimple}
+	apiKeyService := service.NewAPIKeyService(fakeAPIKeyRepo{
+		getByKey: func(ctx context.Context, key string) (*service.APIKey, error) {
+			return nil, errors.New("scoped tokens must not be looked up as keys")
+		},
+	}, nil, nil, nil, nil, nil, cfg)
+	auth := &stubScopedTokenAuthenticator{
+		parent: &service.APIKey{ID: 100, UserID: user.ID, Status: service.StatusActive, User: user},
+		claims: &service.ScopedTokenClaims{
+			ID:      "tok",
+			Models:  []string{"claude-sonnet-*"},
+			Origins: []string{"https://app.example.com"},
+		},
+	}
+	apiKeyService.SetScopedTokenAuthenticator(auth)
+	token := "sst.valid." + strings.Repeat("x", 600)
+
+	r := gin.New()
+	r.Use(apiKeyAuthWithSubscription(apiKeyService, nil, cfg))
+	r.POST("/v1/messages", func(c *gin.Context) {
+		apiKey, _ := GetAPIKeyFromContext(c)
+		body, _ := io.ReadAll(c.Request.Body)
+		c.JSON(http.StatusOK, gin.H{"key_id": apiKey.ID, "body": string(body)})
+	})
+
+	send := func(origin, model string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"model":"`+model+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("https://app.example.com", "claude-sonnet-4-5")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp struct {
+		KeyID int64  `json:"key_id"`
+		Body  string `json:"body"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, int64(100), resp.KeyID)
+	require.JSONEq(t, `{"model":"claude-sonnet-4-5"}`, resp.Body)
+	require.Equal(t, 1, auth.admitted)
+	require.Equal(t, 1, auth.released, "请求结束后结清花费预占")
+
+	rec = send("https://evil.example.com", "claude-sonnet-4-5")
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Contains(t, rec.Body.String(), "SCOPED_TOKEN_ORIGIN_NOT_ALLOWED")
+
+	rec = send("https://app.example.com", "claude-opus-4")
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Contains(t, rec.Body.String(), "SCOPED_TOKEN_MODEL_NOT_ALLOWED")
+
+	auth.admitErr = service.ErrScopedTokenRequestLimit
+	rec = send("https://app.example.com", "claude-sonnet-4-5")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Contains(t, rec.Body.String(), "SCOPED_TOKEN_REQUEST_LIMIT")
+}
+
+func TestAPIKeyAuthRejectsInvalidScopedToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{RunMode: config.RunModeSimple}
+	apiKeyService := newTestAPIKeyService(fakeAPIKeyRepo{})
+	r := gin.New()
+	r.Use(apiKeyAuthWithSubscription(apiKeyService, nil, cfg))
+	r.POST("/v1/messages", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+
+	// 未启用派生令牌时按无效凭证处理。
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer sst.valid."+strings.Repeat("x", 600))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Contains(t, rec.Body.String(), "INVALID_API_KEY")
+
+	// 超长的普通 Key 仍按 API Key 长度上限拒绝。
+	req = httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{}`))
+	req.Header.Set("x-api-key", strings.Repeat("k", 600))
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
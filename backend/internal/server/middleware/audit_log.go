@@ -53,6 +53,7 @@ func SkipAudit(c *gin.Context) {
 // are never accepted through this channel.
 var auditExtraAllowedKeys = map[string]struct{}{
 	"result": {}, "error_code": {}, "enabled": {}, "blocking_enabled": {},
+	"admin_token_id": {}, "admin_token_name": {}, "admin_role": {},
 	"config_version": {}, "endpoint_count": {}, "scanner_count": {},
 	"all_groups": {}, "group_count": {}, "guard_endpoint_id": {},
 	"http_status": {}, "latency_ms": {}, "token_applied": {}, "retryable": {},
@@ -118,6 +119,7 @@ var auditSensitiveReads = map[string]string{
 	"GET /api/v1/admin/groups/:id/api-keys":       "admin.groups.api_keys.read",
 	"GET /api/v1/admin/backups/s3-config":         "admin.backups.s3_config.read",
 	"GET /api/v1/admin/data-management/s3/config": "admin.data_management.s3_config.read",
+	"GET /api/v1/user/data-exports/:id/download":  service.AuditActionDataExportDownloaded,
 }
 
 // auditActionOverrides 变更类请求的动作名精确映射（未命中时自动推导）。
@@ -128,7 +130,12 @@ var auditActionOverrides = map[string]string{
 	"POST /api/v1/auth/register":                              service.AuditActionRegister,
 	"POST /api/v1/auth/refresh":                               service.AuditActionTokenRefresh,
 	"POST /api/v1/user/totp/step-up":                          service.AuditActionStepUpVerify,
-	"POST /api/v1/admin/audit-logs/clear":                     service.AuditActionAuditLogClear,
+	"POST /api/v1/user/api-keys/:id/rotate":                   service.AuditActionAPIKeyRotated,
+	"POST /api/v1/user/api-keys/:id/scoped-tokens/revoke":     service.AuditActionScopedTokensRevoked,
+	"POST /api/v1/user/data-exports":                          service.AuditActionDataExportRequested,
+	"POST /api/v1/user/account-deletion":                      service.AuditActionAccountDeletionRequest,
+	"DELETE /api/v1/user/account-deletion":                    service.AuditActionAccountDeletionCancel,
+	"POST /api/v1/admin/audit-logs/clear":                     service.AuditActionAuditLogArchive,
 	"POST /api/v1/admin/accounts/data":                        "admin.accounts.import",
 	"POST /api/v1/admin/backups":                              "admin.backups.create",
 	"POST /api/v1/admin/backups/:id/restore":                  "admin.backups.restore",
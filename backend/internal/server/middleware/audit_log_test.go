@@ -57,8 +57,14 @@ func (r *auditCaptureRepository) GetByID(context.Context, int64) (*service.Audit
 	return nil, service.ErrAuditLogNotFound
 }
 func (r *auditCaptureRepository) Count(context.Context) (int64, error) { return 0, nil }
-func (r *auditCaptureRepository) TruncateAll(context.Context) error    { return nil }
-func (r *auditCaptureRepository) DeleteBefore(context.Context, time.Time, int) (int64, error) {
+func (r *auditCaptureRepository) ListAfter(context.Context, int64, int64, int) ([]*service.AuditLog, error) {
+	return nil, nil
+}
+func (r *auditCaptureRepository) MaxID(context.Context, *time.Time) (int64, error) { return 0, nil }
+func (r *auditCaptureRepository) ListArchives(context.Context) ([]*service.AuditLogArchive, error) {
+	return nil, nil
+}
+func (r *auditCaptureRepository) CommitArchive(context.Context, *service.AuditLogArchive) (int64, error) {
 	return 0, nil
 }
 
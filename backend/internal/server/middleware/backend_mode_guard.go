@@ -50,20 +50,25 @@ func backendModeAllowsAuthPath(path string) bool {
 		"/auth/oauth/github/callback",
 		"/auth/oauth/google/callback",
 		"/auth/oauth/dingtalk/callback",
+		"/auth/oauth/saml/acs",
+		"/auth/oauth/saml/metadata",
 		"/auth/oauth/github/complete-registration",
 		"/auth/oauth/google/complete-registration",
 		"/auth/oauth/linuxdo/complete-registration",
 		"/auth/oauth/wechat/complete-registration",
 		"/auth/oauth/oidc/complete-registration",
 		"/auth/oauth/dingtalk/complete-registration",
+		"/auth/oauth/saml/complete-registration",
 		"/auth/oauth/linuxdo/create-account",
 		"/auth/oauth/wechat/create-account",
 		"/auth/oauth/oidc/create-account",
 		"/auth/oauth/dingtalk/create-account",
+		"/auth/oauth/saml/create-account",
 		"/auth/oauth/linuxdo/bind-login",
 		"/auth/oauth/wechat/bind-login",
 		"/auth/oauth/oidc/bind-login",
 		"/auth/oauth/dingtalk/bind-login",
+		"/auth/oauth/saml/bind-login",
 	} {
 		if strings.HasSuffix(path, suffix) {
 			return true
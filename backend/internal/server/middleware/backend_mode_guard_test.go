@@ -246,6 +246,18 @@ func TestBackendModeAuthGuard(t *testing.T) {
 			path:       "/api/v1/auth/oauth/oidc/callback",
 			wantStatus: http.StatusOK,
 		},
+		{
+			name:       "enabled_blocks_saml_oauth_start",
+			enabled:    "true",
+			path:       "/api/v1/auth/oauth/saml/start",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "enabled_allows_saml_acs",
+			enabled:    "true",
+			path:       "/api/v1/auth/oauth/saml/acs",
+			wantStatus: http.StatusOK,
+		},
 		{
 			name:       "enabled_blocks_github_oauth_start",
 			enabled:    "true",
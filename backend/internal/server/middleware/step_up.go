@@ -101,7 +101,7 @@ func enforceStepUp(c *gin.Context, grantChecker stepUpGrantChecker, userReader s
 		return true
 	}
 
-	if c.GetString("auth_method") == service.AuditAuthMethodAdminAPIKey {
+	if service.IsMachineAdminAuthMethod(c.GetString("auth_method")) {
 		AbortWithError(c, 403, "STEP_UP_ADMIN_API_KEY_FORBIDDEN",
 			"Admin API key cannot access this endpoint; a two-factor verified admin session is required")
 		return false
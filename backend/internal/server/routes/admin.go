@@ -120,11 +120,14 @@ func RegisterAdminRoutes(
 
 		// 操作审计日志
 		registerAuditLogRoutes(admin, h, stepUpAuth)
+
+		// 管理员角色与具名 API Token
+		registerAdminRBACRoutes(admin, h)
 	}
 }
 
 func registerPromptAuditRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	promptAudit := admin.Group("/prompt-audit")
+	promptAudit := admin.Group("/prompt-audit", middleware.RequireAdminPermission(service.AdminPermissionOpsRead, service.AdminPermissionOpsWrite))
 	{
 		promptAudit.GET("/config", h.Admin.PromptAudit.GetConfig)
 		promptAudit.PUT("/config", h.Admin.PromptAudit.UpdateConfig)
@@ -140,17 +143,38 @@ func registerPromptAuditRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 }
 
 func registerAuditLogRoutes(admin *gin.RouterGroup, h *handler.Handlers, _ middleware.StepUpAuthMiddleware) {
-	auditLogs := admin.Group("/audit-logs")
+	auditLogs := admin.Group("/audit-logs", middleware.RequireAdminPermission(service.AdminPermissionAuditRead, service.AdminPermissionAuditWrite))
 	{
 		auditLogs.GET("", h.Admin.AuditLog.List)
+		auditLogs.GET("/verify", h.Admin.AuditLog.Verify)
+		auditLogs.GET("/archives", h.Admin.AuditLog.ListArchives)
+		auditLogs.GET("/export-status", h.Admin.AuditLog.ExportStatus)
 		auditLogs.GET("/:id", h.Admin.AuditLog.Get)
-		// 清空需现场 TOTP 校验（在 handler 内强制），不复用 step-up sudo 窗口
+		// 清空即归档到对象存储后删除；需现场 TOTP 校验（在 handler 内强制），不复用 step-up sudo 窗口
 		auditLogs.POST("/clear", h.Admin.AuditLog.Clear)
 	}
 }
 
+func registerAdminRBACRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
+	// 当前主体的有效权限对所有管理员开放，供前端渲染菜单
+	admin.GET("/rbac/me", h.Admin.AdminRBAC.GetMe)
+
+	rbac := admin.Group("/rbac", middleware.RequireAdminPermission(service.AdminPermissionAdminsManage, service.AdminPermissionAdminsManage))
+	{
+		rbac.GET("/permissions", h.Admin.AdminRBAC.ListPermissions)
+		rbac.GET("/roles", h.Admin.AdminRBAC.ListRoles)
+		rbac.PUT("/roles/:name", h.Admin.AdminRBAC.UpsertRole)
+		rbac.DELETE("/roles/:name", h.Admin.AdminRBAC.DeleteRole)
+		rbac.GET("/assignments", h.Admin.AdminRBAC.ListAssignments)
+		rbac.PUT("/users/:id/role", h.Admin.AdminRBAC.AssignUserRole)
+		rbac.GET("/tokens", h.Admin.AdminRBAC.ListTokens)
+		rbac.POST("/tokens", h.Admin.AdminRBAC.CreateToken)
+		rbac.DELETE("/tokens/:id", h.Admin.AdminRBAC.RevokeToken)
+	}
+}
+
 func registerAdminComplianceRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	compliance := admin.Group("/compliance")
+	compliance := admin.Group("/compliance", middleware.RequireAdminPermission(service.AdminPermissionSettingsRead, service.AdminPermissionSettingsWrite))
 	{
 		compliance.GET("", h.Admin.Compliance.GetStatus)
 		compliance.POST("/accept", h.Admin.Compliance.Accept)
@@ -158,7 +182,7 @@ func registerAdminComplianceRoutes(admin *gin.RouterGroup, h *handler.Handlers)
 }
 
 func registerContentModerationRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	risk := admin.Group("/risk-control")
+	risk := admin.Group("/risk-control", middleware.RequireAdminPermission(service.AdminPermissionUsersRead, service.AdminPermissionUsersWrite))
 	{
 		risk.GET("/config", h.Admin.ContentModeration.GetConfig)
 		risk.PUT("/config", h.Admin.ContentModeration.UpdateConfig)
@@ -172,14 +196,15 @@ func registerContentModerationRoutes(admin *gin.RouterGroup, h *handler.Handlers
 }
 
 func registerAdminAPIKeyRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	apiKeys := admin.Group("/api-keys")
+	apiKeys := admin.Group("/api-keys", middleware.RequireAdminPermission(service.AdminPermissionUsersRead, service.AdminPermissionUsersWrite))
 	{
+		apiKeys.GET("/leak-events", h.Admin.APIKeyLeak.ListEvents)
 		apiKeys.PUT("/:id", h.Admin.APIKey.UpdateGroup)
 	}
 }
 
 func registerOpsRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	ops := admin.Group("/ops")
+	ops := admin.Group("/ops", middleware.RequireAdminPermission(service.AdminPermissionOpsRead, service.AdminPermissionOpsWrite))
 	{
 		// Realtime ops signals
 		ops.GET("/concurrency", h.Admin.Ops.GetConcurrencyStats)
@@ -269,7 +294,7 @@ func registerOpsRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 }
 
 func registerDashboardRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	dashboard := admin.Group("/dashboard")
+	dashboard := admin.Group("/dashboard", middleware.RequireAdminPermission(service.AdminPermissionUsageRead, service.AdminPermissionOpsWrite))
 	{
 		dashboard.GET("/snapshot-v2", h.Admin.Dashboard.GetSnapshotV2)
 		dashboard.GET("/stats", h.Admin.Dashboard.GetStats)
@@ -277,6 +302,8 @@ func registerDashboardRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 		dashboard.GET("/trend", h.Admin.Dashboard.GetUsageTrend)
 		dashboard.GET("/models", h.Admin.Dashboard.GetModelStats)
 		dashboard.GET("/groups", h.Admin.Dashboard.GetGroupStats)
+		dashboard.GET("/groups/cache-hit-rate", h.Admin.Dashboard.GetGroupCacheHitRate)
+		dashboard.GET("/account-roi", h.Admin.AccountCost.GetROIReport)
 		dashboard.GET("/api-keys-trend", h.Admin.Dashboard.GetAPIKeyUsageTrend)
 		dashboard.GET("/users-trend", h.Admin.Dashboard.GetUserUsageTrend)
 		dashboard.GET("/users-ranking", h.Admin.Dashboard.GetUserSpendingRanking)
@@ -288,7 +315,7 @@ func registerDashboardRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 }
 
 func registerUserManagementRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	users := admin.Group("/users")
+	users := admin.Group("/users", middleware.RequireAdminPermission(service.AdminPermissionUsersRead, service.AdminPermissionUsersWrite))
 	{
 		users.GET("", h.Admin.User.List)
 		users.GET("/:id", h.Admin.User.GetByID)
@@ -307,6 +334,8 @@ func registerUserManagementRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 		users.GET("/:id/platform-quotas", h.Admin.User.GetUserPlatformQuotas)
 		users.PUT("/:id/platform-quotas", h.Admin.User.UpdateUserPlatformQuotas)
 		users.POST("/:id/platform-quotas/reset", h.Admin.User.ResetUserPlatformQuotaWindow)
+		users.GET("/:id/api-key-leak-policy", h.Admin.APIKeyLeak.GetUserPolicy)
+		users.PUT("/:id/api-key-leak-policy", h.Admin.APIKeyLeak.UpdateUserPolicy)
 
 		// User attribute values
 		users.GET("/:id/attributes", h.Admin.UserAttribute.GetUserAttributes)
@@ -315,7 +344,7 @@ func registerUserManagementRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 }
 
 func registerGroupRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	groups := admin.Group("/groups")
+	groups := admin.Group("/groups", middleware.RequireAdminPermission(service.AdminPermissionGroupsRead, service.AdminPermissionGroupsWrite))
 	{
 		groups.GET("", h.Admin.Group.List)
 		groups.GET("/all", h.Admin.Group.GetAll)
@@ -349,7 +378,7 @@ func registerGroupRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 }
 
 func registerAccountRoutes(admin *gin.RouterGroup, h *handler.Handlers, stepUpAuth middleware.StepUpAuthMiddleware) {
-	accounts := admin.Group("/accounts")
+	accounts := admin.Group("/accounts", middleware.RequireAdminPermission(service.AdminPermissionAccountsRead, service.AdminPermissionAccountsWrite))
 	{
 		accounts.GET("", h.Admin.Account.List)
 		accounts.GET("/upstream-billing-probe/settings", h.Admin.Account.GetUpstreamBillingProbeSettings)
@@ -358,6 +387,11 @@ func registerAccountRoutes(admin *gin.RouterGroup, h *handler.Handlers, stepUpAu
 		accounts.GET("/ollama-cloud-usage/settings", h.Admin.Account.GetOllamaCloudUsageSettings)
 		accounts.PUT("/ollama-cloud-usage/settings", h.Admin.Account.UpdateOllamaCloudUsageSettings)
 		accounts.GET("/:id", h.Admin.Account.GetByID)
+		accounts.GET("/:id/cost-basis", h.Admin.AccountCost.Get)
+		accounts.PUT("/:id/cost-basis", h.Admin.AccountCost.Upsert)
+		accounts.DELETE("/:id/cost-basis", h.Admin.AccountCost.Delete)
+		accounts.GET("/:id/proxy-pool", h.Admin.ProxyPool.GetAccountPool)
+		accounts.PUT("/:id/proxy-pool", h.Admin.ProxyPool.SetAccountPool)
 		accounts.POST("", h.Admin.Account.Create)
 		accounts.POST("/:id/duplicate", h.Admin.Account.Duplicate)
 		accounts.POST("/check-mixed-channel", h.Admin.Account.CheckMixedChannel)
@@ -422,7 +456,7 @@ func registerAccountRoutes(admin *gin.RouterGroup, h *handler.Handlers, stepUpAu
 }
 
 func registerAnnouncementRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	announcements := admin.Group("/announcements")
+	announcements := admin.Group("/announcements", middleware.RequireAdminPermission(service.AdminPermissionSettingsRead, service.AdminPermissionSettingsWrite))
 	{
 		announcements.GET("", h.Admin.Announcement.List)
 		announcements.POST("", h.Admin.Announcement.Create)
@@ -434,7 +468,7 @@ func registerAnnouncementRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 }
 
 func registerOpenAIOAuthRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	openai := admin.Group("/openai")
+	openai := admin.Group("/openai", middleware.RequireAdminPermission(service.AdminPermissionAccountsRead, service.AdminPermissionAccountsWrite))
 	{
 		openai.POST("/generate-auth-url", h.Admin.OpenAIOAuth.GenerateAuthURL)
 		openai.POST("/exchange-code", h.Admin.OpenAIOAuth.ExchangeCode)
@@ -449,7 +483,7 @@ func registerOpenAIOAuthRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 }
 
 func registerGeminiOAuthRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	gemini := admin.Group("/gemini")
+	gemini := admin.Group("/gemini", middleware.RequireAdminPermission(service.AdminPermissionAccountsRead, service.AdminPermissionAccountsWrite))
 	{
 		gemini.POST("/oauth/auth-url", h.Admin.GeminiOAuth.GenerateAuthURL)
 		gemini.POST("/oauth/exchange-code", h.Admin.GeminiOAuth.ExchangeCode)
@@ -458,7 +492,7 @@ func registerGeminiOAuthRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 }
 
 func registerAntigravityOAuthRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	antigravity := admin.Group("/antigravity")
+	antigravity := admin.Group("/antigravity", middleware.RequireAdminPermission(service.AdminPermissionAccountsRead, service.AdminPermissionAccountsWrite))
 	{
 		antigravity.POST("/oauth/auth-url", h.Admin.AntigravityOAuth.GenerateAuthURL)
 		antigravity.POST("/oauth/exchange-code", h.Admin.AntigravityOAuth.ExchangeCode)
@@ -467,7 +501,7 @@ func registerAntigravityOAuthRoutes(admin *gin.RouterGroup, h *handler.Handlers)
 }
 
 func registerGrokOAuthRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	grok := admin.Group("/grok")
+	grok := admin.Group("/grok", middleware.RequireAdminPermission(service.AdminPermissionAccountsRead, service.AdminPermissionAccountsWrite))
 	{
 		grok.GET("/oauth/capabilities", h.Admin.GrokOAuth.GetCapabilities)
 		grok.POST("/oauth/auth-url", h.Admin.GrokOAuth.GenerateAuthURL)
@@ -486,7 +520,7 @@ func registerGrokOAuthRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 }
 
 func registerProxyRoutes(admin *gin.RouterGroup, h *handler.Handlers, stepUpAuth middleware.StepUpAuthMiddleware) {
-	proxies := admin.Group("/proxies")
+	proxies := admin.Group("/proxies", middleware.RequireAdminPermission(service.AdminPermissionAccountsRead, service.AdminPermissionAccountsWrite))
 	{
 		proxies.GET("", h.Admin.Proxy.List)
 		proxies.GET("/all", h.Admin.Proxy.GetAll)
@@ -503,11 +537,34 @@ func registerProxyRoutes(admin *gin.RouterGroup, h *handler.Handlers, stepUpAuth
 		proxies.GET("/:id/accounts", h.Admin.Proxy.GetProxyAccounts)
 		proxies.POST("/batch-delete", h.Admin.Proxy.BatchDelete)
 		proxies.POST("/batch", h.Admin.Proxy.BatchCreate)
+		proxies.POST("/import-subscription", h.Admin.ProxySubscription.Import)
+	}
+
+	pools := admin.Group("/proxy-pools", middleware.RequireAdminPermission(service.AdminPermissionAccountsRead, service.AdminPermissionAccountsWrite))
+	{
+		pools.GET("", h.Admin.ProxyPool.List)
+		pools.GET("/reassignments", h.Admin.ProxyPool.ListReassignments)
+		pools.GET("/:id", h.Admin.ProxyPool.GetByID)
+		pools.POST("", h.Admin.ProxyPool.Create)
+		pools.PUT("/:id", h.Admin.ProxyPool.Update)
+		pools.DELETE("/:id", h.Admin.ProxyPool.Delete)
+		pools.PUT("/:id/members", h.Admin.ProxyPool.SetMembers)
+		pools.POST("/:id/rebalance", h.Admin.ProxyPool.Rebalance)
+	}
+
+	subs := admin.Group("/proxy-subscriptions", middleware.RequireAdminPermission(service.AdminPermissionAccountsRead, service.AdminPermissionAccountsWrite))
+	{
+		subs.GET("", h.Admin.ProxySubscription.List)
+		subs.GET("/:id", h.Admin.ProxySubscription.GetByID)
+		subs.POST("", h.Admin.ProxySubscription.Create)
+		subs.PUT("/:id", h.Admin.ProxySubscription.Update)
+		subs.DELETE("/:id", h.Admin.ProxySubscription.Delete)
+		subs.POST("/:id/sync", h.Admin.ProxySubscription.Sync)
 	}
 }
 
 func registerRedeemCodeRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	codes := admin.Group("/redeem-codes")
+	codes := admin.Group("/redeem-codes", middleware.RequireAdminPermission(service.AdminPermissionBillingRead, service.AdminPermissionBillingWrite))
 	{
 		codes.GET("", h.Admin.Redeem.List)
 		codes.GET("/stats", h.Admin.Redeem.GetStats)
@@ -523,7 +580,7 @@ func registerRedeemCodeRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 }
 
 func registerPromoCodeRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	promoCodes := admin.Group("/promo-codes")
+	promoCodes := admin.Group("/promo-codes", middleware.RequireAdminPermission(service.AdminPermissionBillingRead, service.AdminPermissionBillingWrite))
 	{
 		promoCodes.GET("", h.Admin.Promo.List)
 		promoCodes.GET("/:id", h.Admin.Promo.GetByID)
@@ -535,7 +592,7 @@ func registerPromoCodeRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 }
 
 func registerSettingsRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	adminSettings := admin.Group("/settings")
+	adminSettings := admin.Group("/settings", middleware.RequireAdminPermission(service.AdminPermissionSettingsRead, service.AdminPermissionSettingsWrite))
 	{
 		adminSettings.GET("", h.Admin.Setting.GetSettings)
 		adminSettings.PUT("", h.Admin.Setting.UpdateSettings)
@@ -577,7 +634,7 @@ func registerSettingsRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 }
 
 func registerDataManagementRoutes(admin *gin.RouterGroup, h *handler.Handlers, stepUpAuth middleware.StepUpAuthMiddleware) {
-	dataManagement := admin.Group("/data-management")
+	dataManagement := admin.Group("/data-management", middleware.RequireAdminPermission(service.AdminPermissionSettingsWrite, service.AdminPermissionSettingsWrite))
 	{
 		dataManagement.GET("/agent/health", h.Admin.DataManagement.GetAgentHealth)
 		dataManagement.GET("/config", h.Admin.DataManagement.GetConfig)
@@ -601,7 +658,7 @@ func registerDataManagementRoutes(admin *gin.RouterGroup, h *handler.Handlers, s
 }
 
 func registerBackupRoutes(admin *gin.RouterGroup, h *handler.Handlers, stepUpAuth middleware.StepUpAuthMiddleware) {
-	backup := admin.Group("/backups")
+	backup := admin.Group("/backups", middleware.RequireAdminPermission(service.AdminPermissionSettingsWrite, service.AdminPermissionSettingsWrite))
 	{
 		// S3 存储配置
 		backup.GET("/s3-config", h.Admin.Backup.GetS3Config)
@@ -641,7 +698,7 @@ func registerBackupRoutes(admin *gin.RouterGroup, h *handler.Handlers, stepUpAut
 }
 
 func registerSystemRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	system := admin.Group("/system")
+	system := admin.Group("/system", middleware.RequireAdminPermission(service.AdminPermissionSettingsRead, service.AdminPermissionSettingsWrite))
 	{
 		system.GET("/version", h.Admin.System.GetVersion)
 		system.GET("/check-updates", h.Admin.System.CheckUpdates)
@@ -653,7 +710,7 @@ func registerSystemRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 }
 
 func registerSubscriptionRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	subscriptions := admin.Group("/subscriptions")
+	subscriptions := admin.Group("/subscriptions", middleware.RequireAdminPermission(service.AdminPermissionBillingRead, service.AdminPermissionBillingWrite))
 	{
 		subscriptions.GET("", h.Admin.Subscription.List)
 		subscriptions.GET("/:id", h.Admin.Subscription.GetByID)
@@ -668,14 +725,14 @@ func registerSubscriptionRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 	}
 
 	// 分组下的订阅列表
-	admin.GET("/groups/:id/subscriptions", h.Admin.Subscription.ListByGroup)
+	admin.GET("/groups/:id/subscriptions", middleware.RequireAdminPermission(service.AdminPermissionBillingRead, service.AdminPermissionBillingWrite), h.Admin.Subscription.ListByGroup)
 
 	// 用户下的订阅列表
-	admin.GET("/users/:id/subscriptions", h.Admin.Subscription.ListByUser)
+	admin.GET("/users/:id/subscriptions", middleware.RequireAdminPermission(service.AdminPermissionBillingRead, service.AdminPermissionBillingWrite), h.Admin.Subscription.ListByUser)
 }
 
 func registerUsageRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	usage := admin.Group("/usage")
+	usage := admin.Group("/usage", middleware.RequireAdminPermission(service.AdminPermissionUsageRead, service.AdminPermissionOpsWrite))
 	{
 		usage.GET("", h.Admin.Usage.List)
 		usage.GET("/stats", h.Admin.Usage.Stats)
@@ -688,7 +745,7 @@ func registerUsageRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 }
 
 func registerUserAttributeRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	attrs := admin.Group("/user-attributes")
+	attrs := admin.Group("/user-attributes", middleware.RequireAdminPermission(service.AdminPermissionUsersRead, service.AdminPermissionUsersWrite))
 	{
 		attrs.GET("", h.Admin.UserAttribute.ListDefinitions)
 		attrs.POST("", h.Admin.UserAttribute.CreateDefinition)
@@ -700,7 +757,7 @@ func registerUserAttributeRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 }
 
 func registerScheduledTestRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	plans := admin.Group("/scheduled-test-plans")
+	plans := admin.Group("/scheduled-test-plans", middleware.RequireAdminPermission(service.AdminPermissionAccountsRead, service.AdminPermissionAccountsWrite))
 	{
 		plans.POST("", h.Admin.ScheduledTest.Create)
 		plans.PUT("/:id", h.Admin.ScheduledTest.Update)
@@ -708,11 +765,11 @@ func registerScheduledTestRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 		plans.GET("/:id/results", h.Admin.ScheduledTest.ListResults)
 	}
 	// Nested under accounts
-	admin.GET("/accounts/:id/scheduled-test-plans", h.Admin.ScheduledTest.ListByAccount)
+	admin.GET("/accounts/:id/scheduled-test-plans", middleware.RequireAdminPermission(service.AdminPermissionAccountsRead, service.AdminPermissionAccountsWrite), h.Admin.ScheduledTest.ListByAccount)
 }
 
 func registerErrorPassthroughRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	rules := admin.Group("/error-passthrough-rules")
+	rules := admin.Group("/error-passthrough-rules", middleware.RequireAdminPermission(service.AdminPermissionAccountsRead, service.AdminPermissionAccountsWrite))
 	{
 		rules.GET("", h.Admin.ErrorPassthrough.List)
 		rules.GET("/:id", h.Admin.ErrorPassthrough.GetByID)
@@ -723,7 +780,7 @@ func registerErrorPassthroughRoutes(admin *gin.RouterGroup, h *handler.Handlers)
 }
 
 func registerReferralRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	referral := admin.Group("/referral")
+	referral := admin.Group("/referral", middleware.RequireAdminPermission(service.AdminPermissionBillingRead, service.AdminPermissionBillingWrite))
 	{
 		referral.GET("/settings", h.Admin.Referral.GetSettings)
 		referral.PUT("/settings", h.Admin.Referral.UpdateSettings)
@@ -732,7 +789,7 @@ func registerReferralRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 }
 
 func registerTLSFingerprintProfileRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	profiles := admin.Group("/tls-fingerprint-profiles")
+	profiles := admin.Group("/tls-fingerprint-profiles", middleware.RequireAdminPermission(service.AdminPermissionAccountsRead, service.AdminPermissionAccountsWrite))
 	{
 		profiles.GET("", h.Admin.TLSFingerprintProfile.List)
 		profiles.GET("/:id", h.Admin.TLSFingerprintProfile.GetByID)
@@ -743,7 +800,7 @@ func registerTLSFingerprintProfileRoutes(admin *gin.RouterGroup, h *handler.Hand
 }
 
 func registerChannelRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
-	channels := admin.Group("/channels")
+	channels := admin.Group("/channels", middleware.RequireAdminPermission(service.AdminPermissionGroupsRead, service.AdminPermissionGroupsWrite))
 	{
 		channels.GET("", h.Admin.Channel.List)
 		channels.GET("/model-pricing", h.Admin.Channel.GetModelDefaultPricing)
@@ -754,4 +811,3 @@ func registerChannelRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
 		channels.DELETE("/:id", h.Admin.Channel.Delete)
 	}
 }
-
@@ -0,0 +1,87 @@
+package routes
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Wei-Shaw/sub2api/internal/handler"
+	servermiddleware "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func newAdminRBACTestRouter(perms ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(gin.RecoveryWithWriter(io.Discard))
+
+	stubAuth := servermiddleware.AdminAuthMiddleware(func(c *gin.Context) {
+		c.Set(servermiddleware.ContextKeyAdminPermissions, service.NewAdminPermissionSet(perms...))
+		c.Next()
+	})
+	passThrough := func(c *gin.Context) { c.Next() }
+	RegisterAdminRoutes(
+		router.Group("/api/v1"),
+		&handler.Handlers{Admin: &handler.AdminHandlers{}},
+		stubAuth,
+		servermiddleware.AuditLogMiddleware(passThrough),
+		servermiddleware.StepUpAuthMiddleware(passThrough),
+		nil,
+		nil,
+	)
+	return router
+}
+
+func performAdminRBACRequest(router *gin.Engine, method, path string) int {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec.Code
+}
+
+func TestEveryAdminRouteRequiresAPermission(t *testing.T) {
+	router := newAdminRBACTestRouter()
+
+	unguarded := make([]string, 0)
+	for _, route := range router.Routes() {
+		if route.Path == "/api/v1/admin/rbac/me" {
+			continue
+		}
+		path := strings.NewReplacer(":id", "1", ":name", "x", ":user_id", "1").Replace(route.Path)
+		if code := performAdminRBACRequest(router, route.Method, path); code != http.StatusForbidden {
+			unguarded = append(unguarded, route.Method+" "+route.Path)
+		}
+	}
+	require.Empty(t, unguarded, "new admin routes must be registered under a RequireAdminPermission guard")
+}
+
+func TestAdminRoutePermissionsForSupportRole(t *testing.T) {
+	router := newAdminRBACTestRouter(
+		service.AdminPermissionUsersRead, service.AdminPermissionUsersWrite,
+		service.AdminPermissionUsageRead, service.AdminPermissionAccountsRead,
+	)
+
+	// 守卫放行后 handler 为 nil 会 panic 成 500，这里只关心是否被 403 拦截。
+	require.NotEqual(t, http.StatusForbidden, performAdminRBACRequest(router, http.MethodGet, "/api/v1/admin/users"))
+	require.NotEqual(t, http.StatusForbidden, performAdminRBACRequest(router, http.MethodPost, "/api/v1/admin/risk-control/users/1/unban"))
+	require.NotEqual(t, http.StatusForbidden, performAdminRBACRequest(router, http.MethodGet, "/api/v1/admin/accounts"))
+
+	require.Equal(t, http.StatusForbidden, performAdminRBACRequest(router, http.MethodGet, "/api/v1/admin/accounts/data"))
+	require.Equal(t, http.StatusForbidden, performAdminRBACRequest(router, http.MethodPut, "/api/v1/admin/accounts/1"))
+	require.Equal(t, http.StatusForbidden, performAdminRBACRequest(router, http.MethodPost, "/api/v1/admin/users/1/balance"))
+	require.Equal(t, http.StatusForbidden, performAdminRBACRequest(router, http.MethodPut, "/api/v1/admin/settings"))
+	require.Equal(t, http.StatusForbidden, performAdminRBACRequest(router, http.MethodPost, "/api/v1/admin/redeem-codes/generate"))
+	require.Equal(t, http.StatusForbidden, performAdminRBACRequest(router, http.MethodGet, "/api/v1/admin/rbac/tokens"))
+}
+
+func TestAdminRoutePermissionsForFinanceRole(t *testing.T) {
+	router := newAdminRBACTestRouter(service.AdminPermissionBillingRead, service.AdminPermissionBillingWrite)
+
+	require.NotEqual(t, http.StatusForbidden, performAdminRBACRequest(router, http.MethodPost, "/api/v1/admin/users/1/balance"))
+	require.NotEqual(t, http.StatusForbidden, performAdminRBACRequest(router, http.MethodGet, "/api/v1/admin/redeem-codes"))
+	require.Equal(t, http.StatusForbidden, performAdminRBACRequest(router, http.MethodPut, "/api/v1/admin/users/1"))
+	require.Equal(t, http.StatusForbidden, performAdminRBACRequest(router, http.MethodGet, "/api/v1/admin/accounts"))
+}
@@ -4,8 +4,6 @@ import (
 	"bytes"
 	"errors"
 	"io"
-	"mime"
-	"mime/multipart"
 	"net/http"
 	"strconv"
 	"strings"
@@ -73,8 +71,61 @@ func RegisterGatewayRoutes(
 		}
 		h.Gateway.Models(c)
 	}
-	isOpenAIOnlyEndpointGatewayPlatform := func(c *gin.Context) bool {
-		return getGroupPlatform(c) == service.PlatformOpenAI
+	// Embeddings：OpenAI 分组直转，Gemini 分组（含组合分组路由到 Gemini）转换为
+	// batchEmbedContents / Vertex predict。
+	embeddingsHandler := func(c *gin.Context) {
+		switch getGroupPlatform(c) {
+		case service.PlatformOpenAI:
+			h.OpenAIGateway.Embeddings(c)
+		case service.PlatformGemini:
+			h.Gateway.Embeddings(c)
+		default:
+			service.MarkOpsClientBusinessLimited(c, service.OpsClientBusinessLimitedReasonLocalFeatureGate)
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": gin.H{
+					"type":    "not_found_error",
+					"message": "Embeddings API is not supported for this platform",
+				},
+			})
+		}
+	}
+	// OpenAI Audio：OpenAI 分组透传到 API Key 账号，Grok 分组映射到 xAI /tts、/stt。
+	audioHandler := func(endpoint string) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			switch getGroupPlatform(c) {
+			case service.PlatformOpenAI:
+				h.OpenAIGateway.Audio(c, endpoint)
+			case service.PlatformGrok:
+				h.OpenAIGateway.GrokAudio(c, endpoint)
+			default:
+				service.MarkOpsClientBusinessLimited(c, service.OpsClientBusinessLimitedReasonLocalFeatureGate)
+				c.JSON(http.StatusNotFound, gin.H{
+					"error": gin.H{
+						"type":    "not_found_error",
+						"message": "Audio API is not supported for this platform",
+					},
+				})
+			}
+		}
+	}
+	// Files API：OpenAI 与 Anthropic 分组各自透传到支持 Files 的 API Key 账号。
+	filesHandler := func(openAI, anthropic gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			switch getGroupPlatform(c) {
+			case service.PlatformOpenAI:
+				openAI(c)
+			case service.PlatformAnthropic:
+				anthropic(c)
+			default:
+				service.MarkOpsClientBusinessLimited(c, service.OpsClientBusinessLimitedReasonLocalFeatureGate)
+				c.JSON(http.StatusNotFound, gin.H{
+					"error": gin.H{
+						"type":    "not_found_error",
+						"message": "Files API is not supported for this platform",
+					},
+				})
+			}
+		}
 	}
 	imagesHandler := func(c *gin.Context) {
 		switch getGroupPlatform(c) {
@@ -172,6 +223,21 @@ func RegisterGatewayRoutes(
 		}
 	}
 
+	// 推理端点的 Idempotency-Key：按 API Key 隔离，避免 SDK 超时重试导致重复计费。
+	idempotent := h.InferenceIdempotency.Wrap
+	// 流式断线续传（分组级开关）：流式请求由 StreamResume 托管执行，客户端凭
+	// X-Sub2API-Stream-ID + Last-Event-ID 重连只回放缓冲，不会再次请求上游。
+	resumableOpenAIResponses := h.StreamResume.Wrap(h.OpenAIGateway.Responses)
+	resumableGatewayResponses := h.StreamResume.Wrap(h.Gateway.Responses)
+	// 上下文窗口溢出压缩（分组级策略）：在转发前截断或摘要最旧的轮次。
+	chatCompletionsHandler := h.StreamResume.Wrap(h.ContextCompaction.Wrap(service.ContextCompactionFormatChat, func(c *gin.Context) {
+		if isOpenAIResponsesCompatibleGatewayPlatform(c) {
+			h.OpenAIGateway.ChatCompletions(c)
+			return
+		}
+		h.Gateway.ChatCompletions(c)
+	}))
+
 	// API网关（Claude API兼容）
 	gateway := r.Group("/v1")
 	gateway.Use(bodyLimit)
@@ -180,17 +246,20 @@ func RegisterGatewayRoutes(
 	gateway.Use(endpointNorm)
 	gateway.Use(gin.HandlerFunc(apiKeyAuth))
 	gateway.GET("/sub2api/billing", h.Gateway.KeyBillingInfo)
+	// 派生令牌签发/吊销只依赖 Key 本身，不经过分组路由。
+	gateway.POST("/sub2api/tokens", h.ScopedToken.Mint)
+	gateway.DELETE("/sub2api/tokens", h.ScopedToken.RevokeAll)
 	gateway.Use(compositeTarget)
 	gateway.Use(requireGroupAnthropic)
 	{
 		// /v1/messages: auto-route based on group platform
-		gateway.POST("/messages", func(c *gin.Context) {
+		gateway.POST("/messages", idempotent(h.StreamResume.Wrap(h.ContextCompaction.Wrap(service.ContextCompactionFormatAnthropic, func(c *gin.Context) {
 			if isOpenAIResponsesCompatibleGatewayPlatform(c) {
 				h.OpenAIGateway.Messages(c)
 				return
 			}
 			h.Gateway.Messages(c)
-		})
+		}))))
 		// /v1/messages/count_tokens: OpenAI bridges upstream, Grok estimates
 		// locally, and Anthropic-compatible platforms retain their existing path.
 		gateway.POST("/messages/count_tokens", countTokensHandler)
@@ -202,49 +271,49 @@ func RegisterGatewayRoutes(
 		gateway.POST("/live", h.OpenAIGateway.Live)
 		gateway.GET("/live/:call_id", h.OpenAIGateway.LiveSideband)
 		// OpenAI Responses API: auto-route based on group platform
-		gateway.POST("/responses", func(c *gin.Context) {
+		// background: true 由网关托管执行，GET/cancel/续流可落在任意实例。
+		gateway.POST("/responses", idempotent(func(c *gin.Context) {
 			if isOpenAIResponsesCompatibleGatewayPlatform(c) {
-				h.OpenAIGateway.Responses(c)
+				if h.BackgroundResponse.Submit(c) {
+					return
+				}
+				resumableOpenAIResponses(c)
 				return
 			}
-			h.Gateway.Responses(c)
-		})
+			resumableGatewayResponses(c)
+		}))
 		gateway.POST("/responses/*subpath", guardResponsesSubpath(func(c *gin.Context) {
 			if isOpenAIResponsesCompatibleGatewayPlatform(c) {
+				if h.BackgroundResponse.Cancel(c) {
+					return
+				}
 				h.OpenAIGateway.Responses(c)
 				return
 			}
 			h.Gateway.Responses(c)
 		}))
+		gateway.GET("/responses/:response_id", h.BackgroundResponse.Get)
 		gateway.POST("/alpha/search", textBodyLimit, h.OpenAIGateway.AlphaSearch)
 		gateway.GET("/responses", func(c *gin.Context) {
 			h.OpenAIGateway.ResponsesWebSocket(c)
 		})
 		// OpenAI Chat Completions API: auto-route based on group platform
-		gateway.POST("/chat/completions", func(c *gin.Context) {
-			if isOpenAIResponsesCompatibleGatewayPlatform(c) {
-				h.OpenAIGateway.ChatCompletions(c)
-				return
-			}
-			h.Gateway.ChatCompletions(c)
-		})
-		gateway.POST("/embeddings", textBodyLimit, func(c *gin.Context) {
-			if !isOpenAIOnlyEndpointGatewayPlatform(c) {
-				service.MarkOpsClientBusinessLimited(c, service.OpsClientBusinessLimitedReasonLocalFeatureGate)
-				c.JSON(http.StatusNotFound, gin.H{
-					"error": gin.H{
-						"type":    "not_found_error",
-						"message": "Embeddings API is not supported for this platform",
-					},
-				})
-				return
-			}
-			h.OpenAIGateway.Embeddings(c)
-		})
-		gateway.POST("/images/generations", imagesHandler)
-		gateway.POST("/images/edits", imagesHandler)
-		gateway.POST("/images/generations/async", h.AsyncImage.Submit)
-		gateway.POST("/images/edits/async", h.AsyncImage.Submit)
+		gateway.POST("/chat/completions", idempotent(chatCompletionsHandler))
+		gateway.POST("/embeddings", textBodyLimit, embeddingsHandler)
+		// Moderations 走内容审计 key 池，不依赖分组账号，所有平台分组均可用。
+		gateway.POST("/moderations", textBodyLimit, h.OpenAIGateway.Moderations)
+		gateway.POST("/audio/speech", textBodyLimit, audioHandler(service.OpenAIAudioEndpointSpeech))
+		gateway.POST("/audio/transcriptions", audioHandler(service.OpenAIAudioEndpointTranscriptions))
+		gateway.POST("/audio/translations", audioHandler(service.OpenAIAudioEndpointTranslations))
+		gateway.POST("/files", filesHandler(h.OpenAIGateway.FilesUpload, h.Gateway.FilesUpload))
+		gateway.GET("/files", filesHandler(h.OpenAIGateway.FilesList, h.Gateway.FilesList))
+		gateway.GET("/files/:file_id", filesHandler(h.OpenAIGateway.FilesRetrieve, h.Gateway.FilesRetrieve))
+		gateway.GET("/files/:file_id/content", filesHandler(h.OpenAIGateway.FilesContent, h.Gateway.FilesContent))
+		gateway.DELETE("/files/:file_id", filesHandler(h.OpenAIGateway.FilesDelete, h.Gateway.FilesDelete))
+		gateway.POST("/images/generations", idempotent(imagesHandler))
+		gateway.POST("/images/edits", idempotent(imagesHandler))
+		gateway.POST("/images/generations/async", idempotent(h.AsyncImage.Submit))
+		gateway.POST("/images/edits/async", idempotent(h.AsyncImage.Submit))
 		gateway.GET("/images/tasks/:task_id", h.AsyncImage.Get)
 		gateway.POST("/images/batches", h.BatchImage.Submit)
 		gateway.GET("/images/batches", h.BatchImage.List)
@@ -326,6 +395,19 @@ func RegisterGatewayRoutes(
 	}
 
 	// Gemini 原生 API 兼容层（Gemini SDK/CLI 直连）
+	// Anthropic / OpenAI 分组的 generateContent 等请求转换为 Messages 后复用对应处理器
+	geminiAnthropicCompat := handler.GeminiV1BetaMessagesCompat(h.Gateway.Messages, h.Gateway.CountTokens)
+	geminiOpenAICompat := handler.GeminiV1BetaMessagesCompat(h.OpenAIGateway.Messages, h.OpenAIGateway.CountTokens)
+	geminiModelsHandler := func(c *gin.Context) {
+		switch getGroupPlatform(c) {
+		case service.PlatformAnthropic:
+			geminiAnthropicCompat(c)
+		case service.PlatformOpenAI:
+			geminiOpenAICompat(c)
+		default:
+			h.Gateway.GeminiV1BetaModels(c)
+		}
+	}
 	gemini := r.Group("/v1beta")
 	gemini.Use(bodyLimit)
 	gemini.Use(clientRequestID)
@@ -338,20 +420,30 @@ func RegisterGatewayRoutes(
 		gemini.GET("/models", h.Gateway.GeminiV1BetaListModels)
 		gemini.GET("/models/:model", h.Gateway.GeminiV1BetaGetModel)
 		// Gin treats ":" as a param marker, but Gemini uses "{model}:{action}" in the same segment.
-		gemini.POST("/models/*modelAction", h.Gateway.GeminiV1BetaModels)
+		gemini.POST("/models/*modelAction", geminiModelsHandler)
+		// Context caching：缓存绑定到创建它的账号，后续读写与引用均固定路由到该账号。
+		gemini.POST("/cachedContents", h.Gateway.GeminiCachedContentsCreate)
+		gemini.GET("/cachedContents", h.Gateway.GeminiCachedContentsList)
+		gemini.GET("/cachedContents/:id", h.Gateway.GeminiCachedContentsGet)
+		gemini.PATCH("/cachedContents/:id", h.Gateway.GeminiCachedContentsUpdate)
+		gemini.DELETE("/cachedContents/:id", h.Gateway.GeminiCachedContentsDelete)
 	}
 
 	// OpenAI Responses API（不带v1前缀的别名）— auto-route based on group platform
 	responsesHandler := func(c *gin.Context) {
 		if isOpenAIResponsesCompatibleGatewayPlatform(c) {
-			h.OpenAIGateway.Responses(c)
+			if h.BackgroundResponse.Cancel(c) || h.BackgroundResponse.Submit(c) {
+				return
+			}
+			resumableOpenAIResponses(c)
 			return
 		}
-		h.Gateway.Responses(c)
+		resumableGatewayResponses(c)
 	}
-	r.POST("/responses", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, responsesHandler)
+	r.POST("/responses", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, idempotent(responsesHandler))
 	r.POST("/responses/*subpath", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, guardResponsesSubpath(responsesHandler))
 	r.POST("/alpha/search", textBodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, h.OpenAIGateway.AlphaSearch)
+	r.GET("/responses/:response_id", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, h.BackgroundResponse.Get)
 	r.GET("/responses", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, func(c *gin.Context) {
 		h.OpenAIGateway.ResponsesWebSocket(c)
 	})
@@ -362,7 +454,7 @@ func RegisterGatewayRoutes(
 	{
 		codexDirect.POST("/realtime/calls", h.OpenAIGateway.Live)
 		codexDirect.GET("/:call_id", h.OpenAIGateway.LiveSideband)
-		codexDirect.POST("/responses", responsesHandler)
+		codexDirect.POST("/responses", idempotent(responsesHandler))
 		codexDirect.POST("/responses/*subpath", guardResponsesSubpath(responsesHandler))
 		codexDirect.POST("/alpha/search", textBodyLimit, h.OpenAIGateway.AlphaSearch)
 		codexDirect.GET("/responses", func(c *gin.Context) {
@@ -371,30 +463,16 @@ func RegisterGatewayRoutes(
 		codexDirect.GET("/models", h.OpenAIGateway.CodexModels)
 	}
 	// OpenAI Chat Completions API（不带v1前缀的别名）— auto-route based on group platform
-	r.POST("/chat/completions", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, func(c *gin.Context) {
-		if isOpenAIResponsesCompatibleGatewayPlatform(c) {
-			h.OpenAIGateway.ChatCompletions(c)
-			return
-		}
-		h.Gateway.ChatCompletions(c)
-	})
-	r.POST("/embeddings", textBodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, func(c *gin.Context) {
-		if !isOpenAIOnlyEndpointGatewayPlatform(c) {
-			service.MarkOpsClientBusinessLimited(c, service.OpsClientBusinessLimitedReasonLocalFeatureGate)
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": gin.H{
-					"type":    "not_found_error",
-					"message": "Embeddings API is not supported for this platform",
-				},
-			})
-			return
-		}
-		h.OpenAIGateway.Embeddings(c)
-	})
-	r.POST("/images/generations", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, imagesHandler)
-	r.POST("/images/edits", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, imagesHandler)
-	r.POST("/images/generations/async", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, h.AsyncImage.Submit)
-	r.POST("/images/edits/async", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, h.AsyncImage.Submit)
+	r.POST("/chat/completions", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, idempotent(chatCompletionsHandler))
+	r.POST("/embeddings", textBodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, embeddingsHandler)
+	r.POST("/moderations", textBodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, h.OpenAIGateway.Moderations)
+	r.POST("/audio/speech", textBodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, audioHandler(service.OpenAIAudioEndpointSpeech))
+	r.POST("/audio/transcriptions", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, audioHandler(service.OpenAIAudioEndpointTranscriptions))
+	r.POST("/audio/translations", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, audioHandler(service.OpenAIAudioEndpointTranslations))
+	r.POST("/images/generations", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, idempotent(imagesHandler))
+	r.POST("/images/edits", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, idempotent(imagesHandler))
+	r.POST("/images/generations/async", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, idempotent(h.AsyncImage.Submit))
+	r.POST("/images/edits/async", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, idempotent(h.AsyncImage.Submit))
 	r.GET("/images/tasks/:task_id", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, h.AsyncImage.Get)
 	r.POST("/videos", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, videoGenerationHandler)
 	r.POST("/videos/generations", bodyLimit, clientRequestID, opsErrorLogger, endpointNorm, gin.HandlerFunc(apiKeyAuth), compositeTarget, requireGroupAnthropic, videoGenerationHandler)
@@ -538,7 +616,7 @@ func compositeTargetPlatformMiddleware(resolver *service.CompositeRouteResolver)
 			return
 		}
 
-		model := compositeRequestModelFromBody(c.GetHeader("Content-Type"), body)
+		model := pkghttputil.RequestModelFromBody(c.GetHeader("Content-Type"), body)
 		if model != "" {
 			decision, err := resolver.Resolve(c.Request.Context(), apiKey.Group.ID, model, compositeRouteEndpointForPath(c.Request.URL.Path))
 			if err != nil {
@@ -560,42 +638,6 @@ func compositeTargetPlatformMiddleware(resolver *service.CompositeRouteResolver)
 	}
 }
 
-func compositeRequestModelFromBody(contentType string, body []byte) string {
-	if model := strings.TrimSpace(gjson.GetBytes(body, "model").String()); model != "" {
-		return model
-	}
-	return compositeMultipartModelFromBody(contentType, body)
-}
-
-func compositeMultipartModelFromBody(contentType string, body []byte) string {
-	mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(contentType))
-	if err != nil || !strings.EqualFold(mediaType, "multipart/form-data") {
-		return ""
-	}
-	boundary := strings.TrimSpace(params["boundary"])
-	if boundary == "" {
-		return ""
-	}
-	reader := multipart.NewReader(bytes.NewReader(body), boundary)
-	for {
-		part, err := reader.NextPart()
-		if errors.Is(err, io.EOF) {
-			return ""
-		}
-		if err != nil {
-			return ""
-		}
-		if part.FormName() != "model" || part.FileName() != "" {
-			continue
-		}
-		data, err := io.ReadAll(part)
-		if err != nil {
-			return ""
-		}
-		return strings.TrimSpace(string(data))
-	}
-}
-
 func compositeGeminiTargetPlatformMiddleware(resolver *service.CompositeRouteResolver) gin.HandlerFunc {
 	if resolver == nil {
 		resolver = service.NewCompositeRouteResolver(nil)
@@ -603,7 +645,7 @@ func compositeGeminiTargetPlatformMiddleware(resolver *service.CompositeRouteRes
 	return func(c *gin.Context) {
 		apiKey, ok := middleware.GetAPIKeyFromContext(c)
 		if ok && apiKey != nil && apiKey.Group != nil && apiKey.Group.Platform == service.PlatformComposite {
-			model := compositeGeminiModelFromParams(c)
+			model := middleware.GeminiModelFromParams(c)
 			if model != "" {
 				decision, err := resolver.Resolve(c.Request.Context(), apiKey.Group.ID, model, service.CompositeRouteEndpointGemini)
 				if err != nil {
@@ -639,23 +681,6 @@ func grokCustomVoiceEndpoint(c *gin.Context) string {
 	return endpoint
 }
 
-func compositeGeminiModelFromParams(c *gin.Context) string {
-	if c == nil {
-		return ""
-	}
-	if model := strings.TrimSpace(c.Param("model")); model != "" {
-		return model
-	}
-	modelAction := strings.TrimPrefix(strings.TrimSpace(c.Param("modelAction")), "/")
-	if modelAction == "" {
-		return ""
-	}
-	if idx := strings.LastIndex(modelAction, ":"); idx >= 0 {
-		return strings.TrimSpace(modelAction[:idx])
-	}
-	return modelAction
-}
-
 func resetRequestBody(c *gin.Context, body []byte) {
 	c.Request.Body = io.NopCloser(bytes.NewReader(body))
 	c.Request.ContentLength = int64(len(body))
@@ -124,6 +124,89 @@ func TestGatewayRoutesOpenAIImagesPathsAreRegistered(t *testing.T) {
 	}
 }
 
+func TestGatewayRoutesOpenAIAudioPathsAreRegistered(t *testing.T) {
+	for _, platform := range []string{service.PlatformOpenAI, service.PlatformGrok} {
+		router := newGatewayRoutesTestRouter(platform)
+		for _, path := range []string{
+			"/v1/audio/speech",
+			"/v1/audio/transcriptions",
+			"/v1/audio/translations",
+			"/audio/speech",
+			"/audio/transcriptions",
+			"/audio/translations",
+		} {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"model":"tts-1","input":"hi"}`))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+			require.NotEqual(t, http.StatusNotFound, w.Code, "platform=%s path=%s should hit audio handler", platform, path)
+		}
+	}
+
+	router := newGatewayRoutesTestRouter(service.PlatformAnthropic)
+	req := httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(`{"model":"tts-1","input":"hi"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Contains(t, w.Body.String(), "Audio API is not supported")
+}
+
+func TestGatewayRoutesModerationsRegisteredForEveryPlatform(t *testing.T) {
+	for _, platform := range []string{service.PlatformOpenAI, service.PlatformAnthropic, service.PlatformGemini, service.PlatformGrok} {
+		router := newGatewayRoutesTestRouter(platform)
+		for _, path := range []string{"/v1/moderations", "/moderations"} {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"input":"hi"}`))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+			require.NotEqual(t, http.StatusNotFound, w.Code, "platform=%s path=%s should hit moderations handler", platform, path)
+		}
+	}
+}
+
+func TestGatewayRoutesFilesPathsAreRegistered(t *testing.T) {
+	router := newGatewayRoutesTestRouter()
+	registered := make(map[string]bool)
+	for _, route := range router.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	for _, route := range []string{
+		"POST /v1/files",
+		"GET /v1/files",
+		"GET /v1/files/:file_id",
+		"GET /v1/files/:file_id/content",
+		"DELETE /v1/files/:file_id",
+	} {
+		require.True(t, registered[route], "route %s should be registered", route)
+	}
+
+	router = newGatewayRoutesTestRouter(service.PlatformGemini)
+	req := httptest.NewRequest(http.MethodGet, "/v1/files", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Contains(t, w.Body.String(), "Files API is not supported")
+}
+
+func TestGatewayRoutesBackgroundResponsePathsAreRegistered(t *testing.T) {
+	router := newGatewayRoutesTestRouter()
+	registered := make(map[string]bool)
+	for _, route := range router.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	for _, route := range []string{
+		"GET /v1/responses/:response_id",
+		"GET /responses/:response_id",
+		"GET /v1/responses",
+	} {
+		require.True(t, registered[route], "%s should be registered", route)
+	}
+}
+
 func TestGatewayRoutesAsyncImagesPathsAreRegistered(t *testing.T) {
 	router := newGatewayRoutesTestRouter()
 	registered := make(map[string]bool)
@@ -345,22 +428,24 @@ func TestGatewayRoutesNonGrokVideosAreRejectedAtPlatformGate(t *testing.T) {
 	}
 }
 
-func TestGatewayRoutesCompositeOpenAIOnlyEndpointsRequireOpenAITarget(t *testing.T) {
+func TestGatewayRoutesCompositeEmbeddingsRequireOpenAIOrGeminiTarget(t *testing.T) {
 	router := newGatewayRoutesTestRouter(service.PlatformComposite)
 
-	req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(`{"model":"gemini-2.5-pro","input":"hello"}`))
+	req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(`{"model":"claude-sonnet-4-5","input":"hello"}`))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
 	router.ServeHTTP(w, req)
 	require.Equal(t, http.StatusNotFound, w.Code)
 
-	req = httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(`{"model":"text-embedding-3-small","input":"hello"}`))
-	req.Header.Set("Content-Type", "application/json")
-	w = httptest.NewRecorder()
+	for _, model := range []string{"text-embedding-3-small", "gemini-embedding-001"} {
+		req = httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(`{"model":"`+model+`","input":"hello"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
 
-	router.ServeHTTP(w, req)
-	require.NotEqual(t, http.StatusNotFound, w.Code)
+		router.ServeHTTP(w, req)
+		require.NotEqual(t, http.StatusNotFound, w.Code, "model=%s", model)
+	}
 }
 
 func TestGatewayRoutesGrokAllowsCLICompatibilityEntrypoints(t *testing.T) {
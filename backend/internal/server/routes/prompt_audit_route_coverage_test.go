@@ -46,7 +46,9 @@ func TestEveryGatewayPOSTRouteIsClassifiedForPromptAuditCoverage(t *testing.T) {
 		"/videos/edits":             {"grok_media.go"},
 		"/videos/extensions":        {"grok_media.go"},
 		"/models/*modelAction":      {"gemini_v1beta_handler.go"},
+		"/cachedContents":           {"gemini_cached_contents.go"},
 		"/tts":                      {"grok_audio.go"},
+		"/audio/speech":             {"openai_audio.go"},
 		"/web_search":               {"gateway_web_search.go"},
 		"/x_search":                 {"gateway_web_search.go"},
 	}
@@ -55,6 +57,11 @@ func TestEveryGatewayPOSTRouteIsClassifiedForPromptAuditCoverage(t *testing.T) {
 		"/images/batches/:id/cancel": "control-plane cancellation with no user prompt",
 		"/stt":                       "speech transcription is not a text-generation prompt",
 		"/custom-voices":             "voice profile management has no model prompt",
+		"/audio/transcriptions":      "speech transcription is not a text-generation prompt",
+		"/audio/translations":        "speech translation input is audio, not a text prompt",
+		"/moderations":               "moderation classifies content; the gateway verdict is reported via include_gateway_policy",
+		"/files":                     "file upload stores content without executing a model request",
+		"/sub2api/tokens":            "scoped token minting is key management with no model prompt",
 	}
 
 	unclassified := make([]string, 0)
@@ -0,0 +1,51 @@
+package routes
+
+import (
+	"github.com/Wei-Shaw/sub2api/internal/handler"
+	"github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterSCIMRoutes 注册 SCIM 2.0 开通端点（/scim/v2）。
+// 仅接受具名管理员 API Token（Authorization: Bearer admtok-...）且需 scim:provision 权限；
+// 管理员 JWT 会话与全局 admin key 均被拒绝，保证每个 IdP 集成可单独吊销与审计。
+func RegisterSCIMRoutes(
+	r *gin.Engine,
+	h *handler.Handlers,
+	adminAuth middleware.AdminAuthMiddleware,
+	auditLog middleware.AuditLogMiddleware,
+) {
+	scim := r.Group("/scim/v2")
+	scim.Use(
+		gin.HandlerFunc(adminAuth),
+		middleware.RequireAdminAPIToken(),
+		middleware.RequireAdminPermission(service.AdminPermissionSCIMProvision, service.AdminPermissionSCIMProvision),
+		gin.HandlerFunc(auditLog),
+	)
+	{
+		scim.GET("/ServiceProviderConfig", h.SCIM.ServiceProviderConfig)
+		scim.GET("/ResourceTypes", h.SCIM.ResourceTypes)
+
+		users := scim.Group("/Users")
+		{
+			users.GET("", h.SCIM.ListUsers)
+			users.POST("", h.SCIM.CreateUser)
+			users.GET("/:id", h.SCIM.GetUser)
+			users.PUT("/:id", h.SCIM.ReplaceUser)
+			users.PATCH("/:id", h.SCIM.PatchUser)
+			users.DELETE("/:id", h.SCIM.DeleteUser)
+		}
+
+		groups := scim.Group("/Groups")
+		{
+			groups.GET("", h.SCIM.ListGroups)
+			groups.POST("", h.SCIM.CreateGroup)
+			groups.GET("/:id", h.SCIM.GetGroup)
+			groups.PUT("/:id", h.SCIM.ReplaceGroup)
+			groups.PATCH("/:id", h.SCIM.PatchGroup)
+			groups.DELETE("/:id", h.SCIM.DeleteGroup)
+		}
+	}
+}
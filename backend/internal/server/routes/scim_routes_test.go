@@ -0,0 +1,60 @@
+package routes
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Wei-Shaw/sub2api/internal/handler"
+	servermiddleware "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func newSCIMTestRouter(authMethod string, perms ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(gin.RecoveryWithWriter(io.Discard))
+
+	stubAuth := servermiddleware.AdminAuthMiddleware(func(c *gin.Context) {
+		c.Set("auth_method", authMethod)
+		c.Set(servermiddleware.ContextKeyAdminPermissions, service.NewAdminPermissionSet(perms...))
+		c.Next()
+	})
+	passThrough := func(c *gin.Context) { c.Next() }
+	RegisterSCIMRoutes(router, &handler.Handlers{SCIM: &handler.SCIMHandler{}}, stubAuth, servermiddleware.AuditLogMiddleware(passThrough))
+	return router
+}
+
+func TestSCIMRoutesRequireScopedTokenWithProvisionPermission(t *testing.T) {
+	cases := []struct {
+		name       string
+		authMethod string
+		perms      []string
+		wantStatus int
+	}{
+		{name: "scoped_token_with_permission", authMethod: service.AuditAuthMethodAdminAPIToken, perms: []string{service.AdminPermissionSCIMProvision}, wantStatus: http.StatusOK},
+		{name: "scoped_token_without_permission", authMethod: service.AuditAuthMethodAdminAPIToken, perms: []string{service.AdminPermissionUsersWrite}, wantStatus: http.StatusForbidden},
+		{name: "jwt_session_rejected", authMethod: "jwt", perms: []string{service.AdminPermissionAll}, wantStatus: http.StatusForbidden},
+		{name: "global_admin_key_rejected", authMethod: service.AuditAuthMethodAdminAPIKey, perms: []string{service.AdminPermissionAll}, wantStatus: http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := newSCIMTestRouter(tc.authMethod, tc.perms...)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/scim/v2/ServiceProviderConfig", nil))
+			require.Equal(t, tc.wantStatus, rec.Code)
+		})
+	}
+}
+
+func TestSCIMRoutesGuardEveryEndpoint(t *testing.T) {
+	router := newSCIMTestRouter("jwt", service.AdminPermissionAll)
+	for _, route := range router.Routes() {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(route.Method, route.Path, nil))
+		require.Equal(t, http.StatusForbidden, rec.Code, route.Method+" "+route.Path)
+	}
+}
@@ -14,6 +14,7 @@ func RegisterUserRoutes(
 	h *handler.Handlers,
 	jwtAuth middleware.JWTAuthMiddleware,
 	auditLog middleware.AuditLogMiddleware,
+	stepUpAuth middleware.StepUpAuthMiddleware,
 	settingService *service.SettingService,
 	panelRateLimiter *middleware.PanelRateLimiter,
 ) {
@@ -37,6 +38,26 @@ func RegisterUserRoutes(
 			user.POST("/auth-identities/bind/start", h.User.StartIdentityBinding)
 			user.GET("/api-keys/:id/usage/daily", panelRateLimiter.Heavy(), h.Usage.GetMyAPIKeyDailyUsage)
 			user.GET("/platform-quotas", h.User.GetMyPlatformQuotas)
+			// API Key 泄露检测：处置策略与检测记录
+			user.GET("/api-key-leak-policy", h.APIKeyLeak.GetPolicy)
+			user.PUT("/api-key-leak-policy", h.APIKeyLeak.UpdatePolicy)
+			user.GET("/api-key-leak-events", h.APIKeyLeak.ListEvents)
+			// API Key 轮换：宽限期内新旧 Key 并存，支持定期轮换与按谱系统计用量
+			user.POST("/api-keys/:id/rotate", h.APIKeyRotation.Rotate)
+			user.GET("/api-keys/:id/rotation", h.APIKeyRotation.GetSchedule)
+			user.PUT("/api-keys/:id/rotation", h.APIKeyRotation.UpdateSchedule)
+			user.POST("/api-keys/:id/rotation/reveal", h.APIKeyRotation.RevealPendingKey)
+			user.GET("/api-keys/:id/lineage", panelRateLimiter.Heavy(), h.APIKeyRotation.GetLineage)
+			user.POST("/api-keys/:id/scoped-tokens/revoke", h.ScopedToken.RevokeAllForUser)
+
+			// 自助数据导出与账号注销（发起导出/注销需 step-up 验证）
+			user.POST("/data-exports", gin.HandlerFunc(stepUpAuth), h.UserData.RequestExport)
+			user.GET("/data-exports", h.UserData.ListExports)
+			user.GET("/data-exports/:id", h.UserData.GetExport)
+			user.GET("/data-exports/:id/download", panelRateLimiter.Heavy(), h.UserData.DownloadExport)
+			user.POST("/account-deletion", gin.HandlerFunc(stepUpAuth), h.UserData.RequestDeletion)
+			user.GET("/account-deletion", h.UserData.GetDeletion)
+			user.DELETE("/account-deletion", h.UserData.CancelDeletion)
 
 			// TOTP 双因素认证
 			totp := user.Group("/totp")
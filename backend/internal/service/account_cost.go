@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+)
+
+// 账号成本计费方式。
+const (
+	// AccountCostBillingSubscription 按 PeriodDays 周期续费的订阅，Cost 为每周期价格。
+	AccountCostBillingSubscription = "subscription"
+	// AccountCostBillingPrepaid 一次性预付额度，Cost 为预付总价。
+	AccountCostBillingPrepaid = "prepaid"
+
+	defaultAccountCostPeriodDays = 30
+)
+
+// ErrAccountCostBasisNotFound 账号未登记成本。
+var ErrAccountCostBasisNotFound = infraerrors.NotFound("ACCOUNT_COST_BASIS_NOT_FOUND", "account cost basis not found")
+
+// AccountCostBasis 上游账号的采购成本（USD）。
+type AccountCostBasis struct {
+	AccountID   int64      `json:"account_id"`
+	BillingType string     `json:"billing_type"`
+	Cost        float64    `json:"cost"`
+	PeriodDays  int        `json:"period_days"`
+	StartedAt   time.Time  `json:"started_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	Notes       string     `json:"notes"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// 以下字段仅 ListWithAccounts 填充，用于报表展示与按代理聚合。
+	AccountName   string `json:"account_name,omitempty"`
+	Platform      string `json:"platform,omitempty"`
+	AccountStatus string `json:"account_status,omitempty"`
+	ProxyID       *int64 `json:"proxy_id,omitempty"`
+	ProxyName     string `json:"proxy_name,omitempty"`
+}
+
+// AccountUsageSummary 报表窗口内单账号的用量汇总。
+type AccountUsageSummary struct {
+	AccountID   int64
+	Requests    int64
+	Revenue     float64 // SUM(actual_cost)
+	ActiveHours int64   // 有请求的自然小时数
+	ActiveDays  int64   // 有请求的自然日数
+	LastUsedAt  *time.Time
+}
+
+// AccountCostRepository 账号成本台账持久化端口。
+type AccountCostRepository interface {
+	GetByAccountID(ctx context.Context, accountID int64) (*AccountCostBasis, error)
+	Upsert(ctx context.Context, basis *AccountCostBasis) (*AccountCostBasis, error)
+	Delete(ctx context.Context, accountID int64) error
+	// ListWithAccounts 返回所有未删除账号的成本记录，并附带账号与代理信息。
+	ListWithAccounts(ctx context.Context) ([]*AccountCostBasis, error)
+	// SummarizeUsage 汇总 [startTime, endTime) 内指定账号的 usage_logs。
+	SummarizeUsage(ctx context.Context, startTime, endTime time.Time, accountIDs []int64) (map[int64]*AccountUsageSummary, error)
+}
+
+// coverage 返回成本记录在 [start, end) 内生效的区间；无交集时 ok=false。
+func (b *AccountCostBasis) coverage(start, end time.Time) (from, to time.Time, ok bool) {
+	from, to = start, end
+	if b.StartedAt.After(from) {
+		from = b.StartedAt
+	}
+	if b.ExpiresAt != nil && b.ExpiresAt.Before(to) {
+		to = *b.ExpiresAt
+	}
+	return from, to, to.After(from)
+}
+
+// AmortizedCost 返回 [start, end) 内应分摊的成本。
+// 订阅按 Cost/PeriodDays 的日均价线性摊销；预付额度在 StartedAt~ExpiresAt 间线性摊销，
+// 未设置到期时间的预付额度在购入时点一次性计入。
+func (b *AccountCostBasis) AmortizedCost(start, end time.Time) float64 {
+	if b == nil || b.Cost <= 0 || !end.After(start) {
+		return 0
+	}
+	if b.BillingType == AccountCostBillingPrepaid {
+		if b.ExpiresAt == nil || !b.ExpiresAt.After(b.StartedAt) {
+			if !b.StartedAt.Before(start) && b.StartedAt.Before(end) {
+				return b.Cost
+			}
+			return 0
+		}
+		from, to, ok := b.coverage(start, end)
+		if !ok {
+			return 0
+		}
+		return b.Cost * to.Sub(from).Hours() / b.ExpiresAt.Sub(b.StartedAt).Hours()
+	}
+
+	periodDays := b.PeriodDays
+	if periodDays <= 0 {
+		periodDays = defaultAccountCostPeriodDays
+	}
+	from, to, ok := b.coverage(start, end)
+	if !ok {
+		return 0
+	}
+	return b.Cost / float64(periodDays) * to.Sub(from).Hours() / 24
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+)
+
+// defaultAccountROIRenewalDays 报表默认的续费预警窗口（天）。
+const defaultAccountROIRenewalDays = 7
+
+// AccountROIItem 单账号 ROI 报表行。
+type AccountROIItem struct {
+	AccountID     int64      `json:"account_id"`
+	AccountName   string     `json:"account_name"`
+	Platform      string     `json:"platform"`
+	AccountStatus string     `json:"account_status"`
+	ProxyID       *int64     `json:"proxy_id"`
+	ProxyName     string     `json:"proxy_name"`
+	BillingType   string     `json:"billing_type"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	LastUsedAt    *time.Time `json:"last_used_at"`
+
+	Requests      int64   `json:"requests"`
+	Revenue       float64 `json:"revenue"`
+	AmortizedCost float64 `json:"amortized_cost"`
+	Profit        float64 `json:"profit"`
+	// ROI = (Revenue - AmortizedCost) / AmortizedCost；摊销成本为 0 时为 nil。
+	ROI *float64 `json:"roi"`
+	// Utilization 成本生效期内有请求的小时占比。
+	Utilization float64 `json:"utilization"`
+	CoveredDays int     `json:"covered_days"`
+	ActiveDays  int     `json:"active_days"`
+	IdleDays    int     `json:"idle_days"`
+
+	// DaysUntilRenewal 距到期/续费的天数（向上取整），未设置到期时间为 nil。
+	DaysUntilRenewal *int `json:"days_until_renewal"`
+	// AtRisk 将在预警窗口内续费，且窗口内收入低于摊销成本。
+	AtRisk bool `json:"at_risk"`
+}
+
+// ProxyROIItem 按代理聚合的 ROI 报表行，ProxyID=0 表示直连。
+type ProxyROIItem struct {
+	ProxyID       int64    `json:"proxy_id"`
+	ProxyName     string   `json:"proxy_name"`
+	Accounts      int      `json:"accounts"`
+	IdleAccounts  int      `json:"idle_accounts"`
+	AtRisk        int      `json:"at_risk"`
+	Requests      int64    `json:"requests"`
+	Revenue       float64  `json:"revenue"`
+	AmortizedCost float64  `json:"amortized_cost"`
+	Profit        float64  `json:"profit"`
+	ROI           *float64 `json:"roi"`
+}
+
+// AccountROIReport 账号成本与收入对比报表。
+type AccountROIReport struct {
+	StartTime   time.Time         `json:"start_time"`
+	EndTime     time.Time         `json:"end_time"`
+	RenewalDays int               `json:"renewal_days"`
+	Accounts    []*AccountROIItem `json:"accounts"`
+	Proxies     []*ProxyROIItem   `json:"proxies"`
+}
+
+// AccountCostService 账号采购成本台账与 ROI 报表。
+type AccountCostService struct {
+	repo        AccountCostRepository
+	accountRepo AccountRepository
+}
+
+// NewAccountCostService 创建账号成本服务。
+func NewAccountCostService(repo AccountCostRepository, accountRepo AccountRepository) *AccountCostService {
+	return &AccountCostService{repo: repo, accountRepo: accountRepo}
+}
+
+// Get 返回账号的成本记录。
+func (s *AccountCostService) Get(ctx context.Context, accountID int64) (*AccountCostBasis, error) {
+	return s.repo.GetByAccountID(ctx, accountID)
+}
+
+// Upsert 校验并写入账号成本记录。
+func (s *AccountCostService) Upsert(ctx context.Context, basis *AccountCostBasis) (*AccountCostBasis, error) {
+	if basis == nil {
+		return nil, infraerrors.BadRequest("ACCOUNT_COST_BASIS_INVALID", "cost basis is required")
+	}
+	basis.BillingType = strings.TrimSpace(basis.BillingType)
+	if basis.BillingType == "" {
+		basis.BillingType = AccountCostBillingSubscription
+	}
+	switch basis.BillingType {
+	case AccountCostBillingSubscription:
+		if basis.PeriodDays <= 0 {
+			basis.PeriodDays = defaultAccountCostPeriodDays
+		}
+	case AccountCostBillingPrepaid:
+		if basis.PeriodDays < 0 {
+			basis.PeriodDays = 0
+		}
+	default:
+		return nil, infraerrors.BadRequest("ACCOUNT_COST_BASIS_INVALID", "billing_type must be subscription or prepaid")
+	}
+	if basis.Cost < 0 || math.IsNaN(basis.Cost) || math.IsInf(basis.Cost, 0) {
+		return nil, infraerrors.BadRequest("ACCOUNT_COST_BASIS_INVALID", "cost must be a non-negative number")
+	}
+	if basis.StartedAt.IsZero() {
+		basis.StartedAt = time.Now().UTC()
+	}
+	if basis.ExpiresAt != nil && !basis.ExpiresAt.After(basis.StartedAt) {
+		return nil, infraerrors.BadRequest("ACCOUNT_COST_BASIS_INVALID", "expires_at must be after started_at")
+	}
+	if s.accountRepo != nil {
+		if _, err := s.accountRepo.GetByID(ctx, basis.AccountID); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.Upsert(ctx, basis)
+}
+
+// Delete 删除账号成本记录。
+func (s *AccountCostService) Delete(ctx context.Context, accountID int64) error {
+	return s.repo.Delete(ctx, accountID)
+}
+
+// GetROIReport 对比 [startTime, endTime) 内各账号经手收入与摊销成本。
+// 窗口右端截断到 now，未来时间不计成本也不计闲置。
+func (s *AccountCostService) GetROIReport(ctx context.Context, startTime, endTime time.Time, renewalDays int) (*AccountROIReport, error) {
+	now := time.Now()
+	if endTime.After(now) {
+		endTime = now
+	}
+	if renewalDays <= 0 {
+		renewalDays = defaultAccountROIRenewalDays
+	}
+	report := &AccountROIReport{
+		StartTime:   startTime,
+		EndTime:     endTime,
+		RenewalDays: renewalDays,
+		Accounts:    []*AccountROIItem{},
+		Proxies:     []*ProxyROIItem{},
+	}
+	if !endTime.After(startTime) {
+		return report, nil
+	}
+
+	bases, err := s.repo.ListWithAccounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list account cost bases: %w", err)
+	}
+	if len(bases) == 0 {
+		return report, nil
+	}
+	accountIDs := make([]int64, 0, len(bases))
+	for _, basis := range bases {
+		accountIDs = append(accountIDs, basis.AccountID)
+	}
+	usage, err := s.repo.SummarizeUsage(ctx, startTime, endTime, accountIDs)
+	if err != nil {
+		return nil, fmt.Errorf("summarize account usage: %w", err)
+	}
+
+	for _, basis := range bases {
+		report.Accounts = append(report.Accounts, buildAccountROIItem(basis, usage[basis.AccountID], startTime, endTime, now, renewalDays))
+	}
+	sort.SliceStable(report.Accounts, func(i, j int) bool {
+		return report.Accounts[i].Profit < report.Accounts[j].Profit
+	})
+	report.Proxies = aggregateProxyROI(report.Accounts)
+	return report, nil
+}
+
+func buildAccountROIItem(basis *AccountCostBasis, usage *AccountUsageSummary, start, end, now time.Time, renewalDays int) *AccountROIItem {
+	item := &AccountROIItem{
+		AccountID:     basis.AccountID,
+		AccountName:   basis.AccountName,
+		Platform:      basis.Platform,
+		AccountStatus: basis.AccountStatus,
+		ProxyID:       basis.ProxyID,
+		ProxyName:     basis.ProxyName,
+		BillingType:   basis.BillingType,
+		ExpiresAt:     basis.ExpiresAt,
+		AmortizedCost: basis.AmortizedCost(start, end),
+	}
+	if usage != nil {
+		item.Requests = usage.Requests
+		item.Revenue = usage.Revenue
+		item.ActiveDays = int(usage.ActiveDays)
+		item.LastUsedAt = usage.LastUsedAt
+	}
+	item.Profit = item.Revenue - item.AmortizedCost
+	item.ROI = accountROIRatio(item.Revenue, item.AmortizedCost)
+
+	if from, to, ok := basis.coverage(start, end); ok {
+		covered := to.Sub(from)
+		item.CoveredDays = int(math.Ceil(covered.Hours() / 24))
+		if usage != nil && covered > 0 {
+			item.Utilization = math.Min(1, float64(usage.ActiveHours)/math.Ceil(covered.Hours()))
+		}
+	}
+	if item.IdleDays = item.CoveredDays - item.ActiveDays; item.IdleDays < 0 {
+		item.IdleDays = 0
+	}
+
+	if basis.ExpiresAt != nil {
+		days := int(math.Ceil(basis.ExpiresAt.Sub(now).Hours() / 24))
+		item.DaysUntilRenewal = &days
+		item.AtRisk = days >= 0 && days <= renewalDays && item.Revenue < item.AmortizedCost
+	}
+	return item
+}
+
+func aggregateProxyROI(items []*AccountROIItem) []*ProxyROIItem {
+	byProxy := make(map[int64]*ProxyROIItem)
+	for _, item := range items {
+		var proxyID int64
+		if item.ProxyID != nil {
+			proxyID = *item.ProxyID
+		}
+		agg := byProxy[proxyID]
+		if agg == nil {
+			agg = &ProxyROIItem{ProxyID: proxyID, ProxyName: item.ProxyName}
+			byProxy[proxyID] = agg
+		}
+		agg.Accounts++
+		if item.Requests == 0 {
+			agg.IdleAccounts++
+		}
+		if item.AtRisk {
+			agg.AtRisk++
+		}
+		agg.Requests += item.Requests
+		agg.Revenue += item.Revenue
+		agg.AmortizedCost += item.AmortizedCost
+	}
+	out := make([]*ProxyROIItem, 0, len(byProxy))
+	for _, agg := range byProxy {
+		agg.Profit = agg.Revenue - agg.AmortizedCost
+		agg.ROI = accountROIRatio(agg.Revenue, agg.AmortizedCost)
+		out = append(out, agg)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Profit != out[j].Profit {
+			return out[i].Profit < out[j].Profit
+		}
+		return out[i].ProxyID < out[j].ProxyID
+	})
+	return out
+}
+
+func accountROIRatio(revenue, cost float64) *float64 {
+	if cost <= 0 {
+		return nil
+	}
+	roi := (revenue - cost) / cost
+	return &roi
+}
@@ -0,0 +1,83 @@
+//go:build unit
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountCostBasisAmortizedCost_Subscription(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	basis := &AccountCostBasis{
+		BillingType: AccountCostBillingSubscription,
+		Cost:        300,
+		PeriodDays:  30,
+		StartedAt:   start.AddDate(0, 0, -5),
+	}
+
+	require.InDelta(t, 70, basis.AmortizedCost(start, start.AddDate(0, 0, 7)), 1e-9)
+
+	expires := start.AddDate(0, 0, 3)
+	basis.ExpiresAt = &expires
+	require.InDelta(t, 30, basis.AmortizedCost(start, start.AddDate(0, 0, 7)), 1e-9)
+	require.Zero(t, basis.AmortizedCost(start.AddDate(0, 0, 4), start.AddDate(0, 0, 7)))
+}
+
+func TestAccountCostBasisAmortizedCost_Prepaid(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	expires := start.AddDate(0, 0, 10)
+	basis := &AccountCostBasis{
+		BillingType: AccountCostBillingPrepaid,
+		Cost:        100,
+		StartedAt:   start,
+		ExpiresAt:   &expires,
+	}
+	require.InDelta(t, 50, basis.AmortizedCost(start.AddDate(0, 0, 5), start.AddDate(0, 0, 20)), 1e-9)
+
+	basis.ExpiresAt = nil
+	require.InDelta(t, 100, basis.AmortizedCost(start, start.AddDate(0, 0, 1)), 1e-9)
+	require.Zero(t, basis.AmortizedCost(start.AddDate(0, 0, 1), start.AddDate(0, 0, 2)))
+}
+
+func TestBuildAccountROIItem_FlagsLosingAccountBeforeRenewal(t *testing.T) {
+	now := time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)
+	start := now.AddDate(0, 0, -7)
+	renews := now.AddDate(0, 0, 3)
+	proxyID := int64(9)
+	basis := &AccountCostBasis{
+		AccountID:   1,
+		BillingType: AccountCostBillingSubscription,
+		Cost:        300,
+		PeriodDays:  30,
+		StartedAt:   start.AddDate(0, -1, 0),
+		ExpiresAt:   &renews,
+		ProxyID:     &proxyID,
+	}
+	usage := &AccountUsageSummary{AccountID: 1, Requests: 40, Revenue: 35, ActiveHours: 42, ActiveDays: 4}
+
+	item := buildAccountROIItem(basis, usage, start, now, now, 7)
+	require.InDelta(t, 70, item.AmortizedCost, 1e-9)
+	require.InDelta(t, -35, item.Profit, 1e-9)
+	require.NotNil(t, item.ROI)
+	require.InDelta(t, -0.5, *item.ROI, 1e-9)
+	require.Equal(t, 7, item.CoveredDays)
+	require.Equal(t, 3, item.IdleDays)
+	require.InDelta(t, 0.25, item.Utilization, 1e-9)
+	require.NotNil(t, item.DaysUntilRenewal)
+	require.Equal(t, 3, *item.DaysUntilRenewal)
+	require.True(t, item.AtRisk)
+
+	usage.Revenue = 90
+	require.False(t, buildAccountROIItem(basis, usage, start, now, now, 7).AtRisk)
+	usage.Revenue = 35
+	require.False(t, buildAccountROIItem(basis, usage, start, now, now, 2).AtRisk)
+
+	proxies := aggregateProxyROI([]*AccountROIItem{item, {AccountID: 2, AmortizedCost: 10}})
+	require.Len(t, proxies, 2)
+	require.Equal(t, int64(9), proxies[0].ProxyID)
+	require.Equal(t, 1, proxies[0].AtRisk)
+	require.Equal(t, 1, proxies[1].IdleAccounts)
+}
@@ -1,7 +1,8 @@
 package service
 
 // SensitiveCredentialKeys 列出 Account.Credentials JSON map 中绝不允许返回到前端的子键。
-// dto 层做响应脱敏、service 层做更新合并都引用此清单——新增凭证类型时务必同步。
+// dto 层做响应脱敏、service 层做更新合并、repository 层做静态加密（CredentialCipher）都引用此清单——
+// 新增凭证类型时务必同步。
 var SensitiveCredentialKeys = []string{
 	// OAuth
 	"access_token", "refresh_token", "id_token", "agent_private_key",
@@ -336,6 +336,8 @@ func (s *adminServiceImpl) CreateGroup(ctx context.Context, input *CreateGroupIn
 	audioRealtimePricePerMin := normalizePrice(input.AudioRealtimePricePerMin)
 	audioTTSPricePerMillionChars := normalizePrice(input.AudioTTSPricePerMillionChars)
 	audioSTTPricePerHour := normalizePrice(input.AudioSTTPricePerHour)
+	moderationPricePerCall := normalizePrice(input.ModerationPricePerCall)
+	moderationPricePerMillionTokens := normalizePrice(input.ModerationPricePerMillionTokens)
 	imageRateMultiplier := 1.0
 	if input.ImageRateMultiplier != nil {
 		if *input.ImageRateMultiplier < 0 {
@@ -393,6 +395,13 @@ func (s *adminServiceImpl) CreateGroup(ctx context.Context, input *CreateGroupIn
 	if err := ValidateProfitControlConfig(platform, profitControlEnabled, profitMinMargin, profitSafetyBuffer); err != nil {
 		return nil, err
 	}
+	if err := ValidatePromptPrefixRoutingConfig(input.PromptPrefixMessages); err != nil {
+		return nil, err
+	}
+	compactionMode, compactionModel, err := NormalizeContextCompactionConfig(input.ContextCompactionMode, input.ContextCompactionModel)
+	if err != nil {
+		return nil, err
+	}
 
 	// 校验降级分组
 	if input.FallbackGroupID != nil {
@@ -492,6 +501,8 @@ func (s *adminServiceImpl) CreateGroup(ctx context.Context, input *CreateGroupIn
 		AudioRealtimePricePerMin:        audioRealtimePricePerMin,
 		AudioTTSPricePerMillionChars:    audioTTSPricePerMillionChars,
 		AudioSTTPricePerHour:            audioSTTPricePerHour,
+		ModerationPricePerCall:          moderationPricePerCall,
+		ModerationPricePerMillionTokens: moderationPricePerMillionTokens,
 		ClaudeCodeOnly:                  input.ClaudeCodeOnly,
 		FallbackGroupID:                 input.FallbackGroupID,
 		FallbackGroupIDOnInvalidRequest: fallbackOnInvalidRequest,
@@ -508,6 +519,11 @@ func (s *adminServiceImpl) CreateGroup(ctx context.Context, input *CreateGroupIn
 		RPMLimit:                        input.RPMLimit,
 		MaxReasoningEffort:              maxReasoningEffort,
 		ReasoningEffortMappings:         reasoningEffortMappings,
+		PromptPrefixRoutingEnabled:      input.PromptPrefixRoutingEnabled,
+		PromptPrefixMessages:            input.PromptPrefixMessages,
+		StreamResumeEnabled:             input.StreamResumeEnabled,
+		ContextCompactionMode:           compactionMode,
+		ContextCompactionModel:          compactionModel,
 	}
 	sanitizeGroupMessagesDispatchFields(group)
 	if group.Platform != PlatformOpenAI {
@@ -765,6 +781,33 @@ func (s *adminServiceImpl) UpdateGroup(ctx context.Context, id int64, input *Upd
 	if err := ValidateProfitControlConfig(group.Platform, group.ProfitControlEnabled, group.ProfitMinMargin, group.ProfitSafetyBuffer); err != nil {
 		return nil, err
 	}
+	if input.PromptPrefixRoutingEnabled != nil {
+		group.PromptPrefixRoutingEnabled = *input.PromptPrefixRoutingEnabled
+	}
+	if input.PromptPrefixMessages != nil {
+		if err := ValidatePromptPrefixRoutingConfig(*input.PromptPrefixMessages); err != nil {
+			return nil, err
+		}
+		group.PromptPrefixMessages = *input.PromptPrefixMessages
+	}
+	if input.StreamResumeEnabled != nil {
+		group.StreamResumeEnabled = *input.StreamResumeEnabled
+	}
+	if input.ContextCompactionMode != nil || input.ContextCompactionModel != nil {
+		mode, model := group.ContextCompactionMode, group.ContextCompactionModel
+		if input.ContextCompactionMode != nil {
+			mode = *input.ContextCompactionMode
+		}
+		if input.ContextCompactionModel != nil {
+			model = *input.ContextCompactionModel
+		}
+		mode, model, err := NormalizeContextCompactionConfig(mode, model)
+		if err != nil {
+			return nil, err
+		}
+		group.ContextCompactionMode = mode
+		group.ContextCompactionModel = model
+	}
 	if input.ImagePrice1K != nil {
 		group.ImagePrice1K = normalizePrice(input.ImagePrice1K)
 	}
@@ -802,6 +845,12 @@ func (s *adminServiceImpl) UpdateGroup(ctx context.Context, id int64, input *Upd
 	if input.AudioSTTPricePerHour != nil {
 		group.AudioSTTPricePerHour = normalizePrice(input.AudioSTTPricePerHour)
 	}
+	if input.ModerationPricePerCall != nil {
+		group.ModerationPricePerCall = normalizePrice(input.ModerationPricePerCall)
+	}
+	if input.ModerationPricePerMillionTokens != nil {
+		group.ModerationPricePerMillionTokens = normalizePrice(input.ModerationPricePerMillionTokens)
+	}
 
 	// Claude Code 客户端限制
 	if input.ClaudeCodeOnly != nil {
@@ -134,6 +134,8 @@ func cloneGroupForDuplicate(source *Group, operationID string) *Group {
 		AudioRealtimePricePerMin:        cloneGroupValuePointer(source.AudioRealtimePricePerMin),
 		AudioTTSPricePerMillionChars:    cloneGroupValuePointer(source.AudioTTSPricePerMillionChars),
 		AudioSTTPricePerHour:            cloneGroupValuePointer(source.AudioSTTPricePerHour),
+		ModerationPricePerCall:          cloneGroupValuePointer(source.ModerationPricePerCall),
+		ModerationPricePerMillionTokens: cloneGroupValuePointer(source.ModerationPricePerMillionTokens),
 		ClaudeCodeOnly:                  source.ClaudeCodeOnly,
 		FallbackGroupID:                 cloneGroupValuePointer(source.FallbackGroupID),
 		FallbackGroupIDOnInvalidRequest: cloneGroupValuePointer(source.FallbackGroupIDOnInvalidRequest),
@@ -155,6 +157,12 @@ func cloneGroupForDuplicate(source *Group, operationID string) *Group {
 		RPMLimit:                source.RPMLimit,
 		MaxReasoningEffort:      source.MaxReasoningEffort,
 		ReasoningEffortMappings: append([]ReasoningEffortMapping(nil), source.ReasoningEffortMappings...),
+
+		PromptPrefixRoutingEnabled: source.PromptPrefixRoutingEnabled,
+		PromptPrefixMessages:       source.PromptPrefixMessages,
+		StreamResumeEnabled:        source.StreamResumeEnabled,
+		ContextCompactionMode:      source.ContextCompactionMode,
+		ContextCompactionModel:     source.ContextCompactionModel,
 	}
 }
 
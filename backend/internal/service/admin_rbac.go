@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+)
+
+// 管理面权限。路由组按「读 / 写」各绑定一个权限（见 middleware.RequireAdminPermission），
+// 少数敏感路由单独映射（如凭证导出需要 credentials:reveal）。
+const (
+	AdminPermissionAll = "*"
+
+	AdminPermissionUsersRead         = "users:read"
+	AdminPermissionUsersWrite        = "users:write"
+	AdminPermissionAccountsRead      = "accounts:read"
+	AdminPermissionAccountsWrite     = "accounts:write"
+	AdminPermissionCredentialsReveal = "credentials:reveal"
+	AdminPermissionGroupsRead        = "groups:read"
+	AdminPermissionGroupsWrite       = "groups:write"
+	AdminPermissionBillingRead       = "billing:read"
+	AdminPermissionBillingWrite      = "billing:write"
+	AdminPermissionUsageRead         = "usage:read"
+	AdminPermissionSettingsRead      = "settings:read"
+	AdminPermissionSettingsWrite     = "settings:write"
+	AdminPermissionOpsRead           = "ops:read"
+	AdminPermissionOpsWrite          = "ops:write"
+	AdminPermissionAuditRead         = "audit:read"
+	AdminPermissionAuditWrite        = "audit:write"
+	AdminPermissionAdminsManage      = "admins:manage"
+	// AdminPermissionSCIMProvision 仅供 /scim/v2 使用：身份提供方凭具名 Token 开通/停用用户与分组授权。
+	AdminPermissionSCIMProvision = "scim:provision"
+)
+
+// AdminPermissions 是全部可分配的权限（不含通配符）。
+var AdminPermissions = []string{
+	AdminPermissionUsersRead,
+	AdminPermissionUsersWrite,
+	AdminPermissionAccountsRead,
+	AdminPermissionAccountsWrite,
+	AdminPermissionCredentialsReveal,
+	AdminPermissionGroupsRead,
+	AdminPermissionGroupsWrite,
+	AdminPermissionBillingRead,
+	AdminPermissionBillingWrite,
+	AdminPermissionUsageRead,
+	AdminPermissionSettingsRead,
+	AdminPermissionSettingsWrite,
+	AdminPermissionOpsRead,
+	AdminPermissionOpsWrite,
+	AdminPermissionAuditRead,
+	AdminPermissionAuditWrite,
+	AdminPermissionAdminsManage,
+	AdminPermissionSCIMProvision,
+}
+
+// 内置角色名。未分配角色的管理员视为 super_admin，保持引入 RBAC 前的行为。
+const (
+	AdminRoleSuperAdmin = "super_admin"
+	AdminRoleSupport    = "support"
+	AdminRoleFinance    = "finance"
+	AdminRoleAuditor    = "auditor"
+)
+
+// AuditAuthMethodAdminAPIToken 具名、带 scope 的管理员 API Token 认证。
+const AuditAuthMethodAdminAPIToken = "admin_api_token"
+
+// AdminAPITokenPrefix 区分具名 Token 与全局 admin API key（admin-）。
+const AdminAPITokenPrefix = "admtok-"
+
+var (
+	ErrAdminRoleNotFound          = infraerrors.NotFound("ADMIN_ROLE_NOT_FOUND", "admin role not found")
+	ErrAdminRoleInvalid           = infraerrors.BadRequest("ADMIN_ROLE_INVALID", "invalid admin role")
+	ErrAdminRoleBuiltIn           = infraerrors.BadRequest("ADMIN_ROLE_BUILT_IN", "built-in admin roles cannot be modified")
+	ErrAdminRoleInUse             = infraerrors.Conflict("ADMIN_ROLE_IN_USE", "admin role is still assigned to users")
+	ErrAdminPermissionInvalid     = infraerrors.BadRequest("ADMIN_PERMISSION_INVALID", "unknown admin permission")
+	ErrAdminAPITokenNotFound      = infraerrors.NotFound("ADMIN_API_TOKEN_NOT_FOUND", "admin API token not found")
+	ErrAdminAPITokenInvalid       = infraerrors.Unauthorized("INVALID_ADMIN_TOKEN", "invalid admin API token")
+	ErrAdminAPITokenScopeDenied   = infraerrors.Forbidden("ADMIN_TOKEN_SCOPE_EXCEEDS_GRANT", "token scopes must be a subset of your own permissions")
+	ErrAdminRoleGrantDenied       = infraerrors.Forbidden("ADMIN_ROLE_EXCEEDS_GRANT", "role permissions must be a subset of your own permissions")
+	ErrAdminAPITokenNameInvalid   = infraerrors.BadRequest("ADMIN_API_TOKEN_INVALID", "token name is required")
+	ErrAdminAPITokenExpiryInvalid = infraerrors.BadRequest("ADMIN_API_TOKEN_INVALID", "expires_at must be in the future")
+)
+
+var adminRoleNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// AdminRole 管理员角色：一组权限的具名集合。
+type AdminRole struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	BuiltIn     bool      `json:"built_in"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+var builtInAdminRoles = []AdminRole{
+	{
+		Name:        AdminRoleSuperAdmin,
+		Description: "Full access to every admin endpoint",
+		Permissions: []string{AdminPermissionAll},
+	},
+	{
+		Name:        AdminRoleSupport,
+		Description: "View users, usage and accounts; edit and unban users",
+		Permissions: []string{
+			AdminPermissionUsersRead, AdminPermissionUsersWrite, AdminPermissionUsageRead,
+			AdminPermissionAccountsRead, AdminPermissionGroupsRead, AdminPermissionOpsRead,
+		},
+	},
+	{
+		Name:        AdminRoleFinance,
+		Description: "Balances, redeem codes, promo codes, subscriptions and referrals",
+		Permissions: []string{
+			AdminPermissionBillingRead, AdminPermissionBillingWrite,
+			AdminPermissionUsersRead, AdminPermissionUsageRead, AdminPermissionGroupsRead,
+		},
+	},
+	{
+		Name:        AdminRoleAuditor,
+		Description: "Read-only access including the audit log",
+		Permissions: []string{
+			AdminPermissionUsersRead, AdminPermissionAccountsRead, AdminPermissionGroupsRead,
+			AdminPermissionBillingRead, AdminPermissionUsageRead, AdminPermissionSettingsRead,
+			AdminPermissionOpsRead, AdminPermissionAuditRead,
+		},
+	},
+}
+
+func builtInAdminRole(name string) (AdminRole, bool) {
+	for _, role := range builtInAdminRoles {
+		if role.Name == name {
+			role.BuiltIn = true
+			return role, true
+		}
+	}
+	return AdminRole{}, false
+}
+
+// AdminPermissionSet 是一个主体（管理员会话或 Token）的有效权限集合。
+type AdminPermissionSet map[string]struct{}
+
+// NewAdminPermissionSet 由权限列表构造集合。
+func NewAdminPermissionSet(perms ...string) AdminPermissionSet {
+	set := make(AdminPermissionSet, len(perms))
+	for _, p := range perms {
+		if p = strings.TrimSpace(p); p != "" {
+			set[p] = struct{}{}
+		}
+	}
+	return set
+}
+
+// Has 判断是否拥有权限；通配符拥有全部权限。
+func (s AdminPermissionSet) Has(perm string) bool {
+	if s == nil {
+		return false
+	}
+	if _, ok := s[AdminPermissionAll]; ok {
+		return true
+	}
+	_, ok := s[perm]
+	return ok
+}
+
+// Covers 判断 perms 是否都在集合内；授予通配符本身要求集合含通配符。
+func (s AdminPermissionSet) Covers(perms ...string) bool {
+	for _, perm := range perms {
+		if !s.Has(perm) {
+			return false
+		}
+	}
+	return true
+}
+
+// Intersect 返回两者共同拥有的权限（通配符按全集展开）。
+func (s AdminPermissionSet) Intersect(other AdminPermissionSet) AdminPermissionSet {
+	if s.Has(AdminPermissionAll) {
+		return other.clone()
+	}
+	if other.Has(AdminPermissionAll) {
+		return s.clone()
+	}
+	out := make(AdminPermissionSet)
+	for p := range s {
+		if other.Has(p) {
+			out[p] = struct{}{}
+		}
+	}
+	return out
+}
+
+// List 返回排序后的权限列表。
+func (s AdminPermissionSet) List() []string {
+	out := make([]string, 0, len(s))
+	for p := range s {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (s AdminPermissionSet) clone() AdminPermissionSet {
+	out := make(AdminPermissionSet, len(s))
+	for p := range s {
+		out[p] = struct{}{}
+	}
+	return out
+}
+
+// AdminAPIToken 具名管理员 API Token（明文只在创建时返回一次，库中仅存 SHA-256）。
+type AdminAPIToken struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Scopes      []string   `json:"scopes"`
+	CreatedBy   int64      `json:"created_by"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP  string     `json:"last_used_ip"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// IsUsable 判断 Token 在 now 时刻是否可用于认证。
+func (t *AdminAPIToken) IsUsable(now time.Time) bool {
+	if t == nil || t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || now.Before(*t.ExpiresAt)
+}
+
+// AdminRBACRepository 管理员角色、角色分配与具名 Token 的持久化。
+type AdminRBACRepository interface {
+	ListCustomRoles(ctx context.Context) ([]AdminRole, error)
+	GetCustomRole(ctx context.Context, name string) (*AdminRole, error)
+	UpsertCustomRole(ctx context.Context, role *AdminRole) error
+	DeleteCustomRole(ctx context.Context, name string) error
+	CountRoleAssignments(ctx context.Context, name string) (int64, error)
+
+	// GetUserRole 返回用户分配的角色名；未分配返回空串。
+	GetUserRole(ctx context.Context, userID int64) (string, error)
+	// SetUserRole 分配角色；role 为空时删除分配。
+	SetUserRole(ctx context.Context, userID int64, role string) error
+	ListRoleAssignments(ctx context.Context) (map[int64]string, error)
+
+	CreateToken(ctx context.Context, token *AdminAPIToken, tokenHash string) error
+	GetTokenByHash(ctx context.Context, tokenHash string) (*AdminAPIToken, error)
+	ListTokens(ctx context.Context) ([]AdminAPIToken, error)
+	RevokeToken(ctx context.Context, id int64, revokedAt time.Time) error
+	TouchToken(ctx context.Context, id int64, usedAt time.Time, ip string) error
+}
+
+// IsMachineAdminAuthMethod 判断认证方式是否为机器凭证（全局 admin API key 或具名 Token）。
+// 机器凭证无法完成 TOTP step-up，需要真人会话的操作应拒绝它们。
+func IsMachineAdminAuthMethod(method string) bool {
+	return method == AuditAuthMethodAdminAPIKey || method == AuditAuthMethodAdminAPIToken
+}
+
+func normalizeAdminPermissions(perms []string) ([]string, error) {
+	known := NewAdminPermissionSet(AdminPermissions...)
+	set := make(AdminPermissionSet, len(perms))
+	for _, p := range perms {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if p != AdminPermissionAll {
+			if _, ok := known[p]; !ok {
+				return nil, ErrAdminPermissionInvalid.WithMetadata(map[string]string{"permission": p})
+			}
+		}
+		set[p] = struct{}{}
+	}
+	if set.Has(AdminPermissionAll) {
+		return []string{AdminPermissionAll}, nil
+	}
+	return set.List(), nil
+}
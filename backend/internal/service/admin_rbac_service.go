@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// adminAPITokenTouchInterval 限制 last_used 回写频率，避免高频调用放大写入。
+const adminAPITokenTouchInterval = time.Minute
+
+// AdminRBACService 解析管理员的有效权限，并管理自定义角色、角色分配与具名 Token。
+type AdminRBACService struct {
+	repo AdminRBACRepository
+	now  func() time.Time
+}
+
+func NewAdminRBACService(repo AdminRBACRepository) *AdminRBACService {
+	return &AdminRBACService{repo: repo, now: time.Now}
+}
+
+// ListRoles 返回内置角色与自定义角色。
+func (s *AdminRBACService) ListRoles(ctx context.Context) ([]AdminRole, error) {
+	roles := make([]AdminRole, 0, len(builtInAdminRoles))
+	for _, role := range builtInAdminRoles {
+		role.BuiltIn = true
+		roles = append(roles, role)
+	}
+	custom, err := s.repo.ListCustomRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(roles, custom...), nil
+}
+
+// GetRole 按名称查找角色（内置优先）。
+func (s *AdminRBACService) GetRole(ctx context.Context, name string) (*AdminRole, error) {
+	if role, ok := builtInAdminRole(name); ok {
+		return &role, nil
+	}
+	return s.repo.GetCustomRole(ctx, name)
+}
+
+// UpsertRole 创建或更新自定义角色。grant 为调用者的有效权限：新权限与被更新角色的现有权限
+// 都必须在其范围内，避免借自定义角色提权或改动更高权限的角色。
+func (s *AdminRBACService) UpsertRole(ctx context.Context, grant AdminPermissionSet, role *AdminRole) (*AdminRole, error) {
+	if role == nil {
+		return nil, ErrAdminRoleInvalid
+	}
+	role.Name = strings.TrimSpace(role.Name)
+	if !adminRoleNamePattern.MatchString(role.Name) {
+		return nil, ErrAdminRoleInvalid
+	}
+	if _, ok := builtInAdminRole(role.Name); ok {
+		return nil, ErrAdminRoleBuiltIn
+	}
+	perms, err := normalizeAdminPermissions(role.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if !grant.Covers(perms...) {
+		return nil, ErrAdminRoleGrantDenied
+	}
+	existing, err := s.repo.GetCustomRole(ctx, role.Name)
+	if err != nil && !errors.Is(err, ErrAdminRoleNotFound) {
+		return nil, err
+	}
+	if existing != nil && !grant.Covers(existing.Permissions...) {
+		return nil, ErrAdminRoleGrantDenied
+	}
+	role.Permissions = perms
+	role.Description = strings.TrimSpace(role.Description)
+	role.BuiltIn = false
+	if err := s.repo.UpsertCustomRole(ctx, role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// DeleteRole 删除自定义角色；仍有管理员使用时拒绝，避免其权限被静默清空。
+func (s *AdminRBACService) DeleteRole(ctx context.Context, name string) error {
+	if _, ok := builtInAdminRole(name); ok {
+		return ErrAdminRoleBuiltIn
+	}
+	count, err := s.repo.CountRoleAssignments(ctx, name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrAdminRoleInUse
+	}
+	return s.repo.DeleteCustomRole(ctx, name)
+}
+
+// AssignUserRole 为管理员分配角色；role 为空表示恢复为 super_admin。
+// 新角色与目标管理员的现有权限都必须在调用者 grant 范围内：分配 super_admin 需要通配符，
+// 也不能改动权限高于自己的管理员。
+func (s *AdminRBACService) AssignUserRole(ctx context.Context, grant AdminPermissionSet, userID int64, role string) error {
+	role = strings.TrimSpace(role)
+	if role == AdminRoleSuperAdmin {
+		role = ""
+	}
+	perms := []string{AdminPermissionAll}
+	if role != "" {
+		target, err := s.GetRole(ctx, role)
+		if err != nil {
+			return err
+		}
+		perms = target.Permissions
+	}
+	if !grant.Covers(perms...) {
+		return ErrAdminRoleGrantDenied
+	}
+	_, current, err := s.ResolveUserPermissions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !grant.Covers(current.List()...) {
+		return ErrAdminRoleGrantDenied
+	}
+	return s.repo.SetUserRole(ctx, userID, role)
+}
+
+// ListRoleAssignments 返回 user_id → 角色名（未出现的管理员为 super_admin）。
+func (s *AdminRBACService) ListRoleAssignments(ctx context.Context) (map[int64]string, error) {
+	return s.repo.ListRoleAssignments(ctx)
+}
+
+// ResolveUserPermissions 返回管理员的角色名与有效权限。
+// 角色被删除等异常情况按空权限处理（fail-closed）。
+func (s *AdminRBACService) ResolveUserPermissions(ctx context.Context, userID int64) (string, AdminPermissionSet, error) {
+	if s == nil || s.repo == nil {
+		return AdminRoleSuperAdmin, NewAdminPermissionSet(AdminPermissionAll), nil
+	}
+	name, err := s.repo.GetUserRole(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if name == "" {
+		return AdminRoleSuperAdmin, NewAdminPermissionSet(AdminPermissionAll), nil
+	}
+	role, err := s.GetRole(ctx, name)
+	if err != nil {
+		if errors.Is(err, ErrAdminRoleNotFound) {
+			return name, AdminPermissionSet{}, nil
+		}
+		return "", nil, err
+	}
+	return name, NewAdminPermissionSet(role.Permissions...), nil
+}
+
+// CreateAdminAPITokenInput 创建具名 Token 的参数。
+type CreateAdminAPITokenInput struct {
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+// CreateToken 创建具名 Token，scope 不得超出创建者自身的权限。返回一次性明文。
+func (s *AdminRBACService) CreateToken(ctx context.Context, creatorID int64, creatorPerms AdminPermissionSet, in CreateAdminAPITokenInput) (string, *AdminAPIToken, error) {
+	name := strings.TrimSpace(in.Name)
+	if name == "" || len(name) > 100 {
+		return "", nil, ErrAdminAPITokenNameInvalid
+	}
+	scopes, err := normalizeAdminPermissions(in.Scopes)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(scopes) == 0 {
+		return "", nil, ErrAdminPermissionInvalid
+	}
+	if !creatorPerms.Covers(scopes...) {
+		return "", nil, ErrAdminAPITokenScopeDenied
+	}
+	now := s.now().UTC()
+	if in.ExpiresAt != nil && !in.ExpiresAt.After(now) {
+		return "", nil, ErrAdminAPITokenExpiryInvalid
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, fmt.Errorf("generate admin token: %w", err)
+	}
+	plain := AdminAPITokenPrefix + hex.EncodeToString(raw)
+	token := &AdminAPIToken{
+		Name:        name,
+		TokenPrefix: plain[:len(AdminAPITokenPrefix)+6],
+		Scopes:      scopes,
+		CreatedBy:   creatorID,
+		ExpiresAt:   in.ExpiresAt,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.repo.CreateToken(ctx, token, HashAdminAPIToken(plain)); err != nil {
+		return "", nil, err
+	}
+	return plain, token, nil
+}
+
+// ListTokens 列出全部具名 Token（含已吊销，便于追溯）。
+func (s *AdminRBACService) ListTokens(ctx context.Context) ([]AdminAPIToken, error) {
+	return s.repo.ListTokens(ctx)
+}
+
+// RevokeToken 吊销 Token，立即生效。
+func (s *AdminRBACService) RevokeToken(ctx context.Context, id int64) error {
+	return s.repo.RevokeToken(ctx, id, s.now().UTC())
+}
+
+// AuthenticateToken 校验具名 Token 并记录最近使用时间与 IP。
+func (s *AdminRBACService) AuthenticateToken(ctx context.Context, plain, clientIP string) (*AdminAPIToken, error) {
+	if s == nil || s.repo == nil || !strings.HasPrefix(plain, AdminAPITokenPrefix) {
+		return nil, ErrAdminAPITokenInvalid
+	}
+	token, err := s.repo.GetTokenByHash(ctx, HashAdminAPIToken(plain))
+	if err != nil {
+		if errors.Is(err, ErrAdminAPITokenNotFound) {
+			return nil, ErrAdminAPITokenInvalid
+		}
+		return nil, err
+	}
+	now := s.now().UTC()
+	if !token.IsUsable(now) {
+		return nil, ErrAdminAPITokenInvalid
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= adminAPITokenTouchInterval || token.LastUsedIP != clientIP {
+		if err := s.repo.TouchToken(ctx, token.ID, now, clientIP); err == nil {
+			token.LastUsedAt = &now
+			token.LastUsedIP = clientIP
+		}
+	}
+	return token, nil
+}
+
+// HashAdminAPIToken 返回 Token 的存储摘要。Token 本身是 256 位随机数，无需加盐。
+func HashAdminAPIToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type adminRBACRepoStub struct {
+	roles       map[string]AdminRole
+	assignments map[int64]string
+	tokens      map[string]*AdminAPIToken
+	touched     int
+	nextID      int64
+}
+
+func newAdminRBACRepoStub() *adminRBACRepoStub {
+	return &adminRBACRepoStub{
+		roles:       map[string]AdminRole{},
+		assignments: map[int64]string{},
+		tokens:      map[string]*AdminAPIToken{},
+	}
+}
+
+func (r *adminRBACRepoStub) ListCustomRoles(context.Context) ([]AdminRole, error) {
+	out := make([]AdminRole, 0, len(r.roles))
+	for _, role := range r.roles {
+		out = append(out, role)
+	}
+	return out, nil
+}
+
+func (r *adminRBACRepoStub) GetCustomRole(_ context.Context, name string) (*AdminRole, error) {
+	role, ok := r.roles[name]
+	if !ok {
+		return nil, ErrAdminRoleNotFound
+	}
+	return &role, nil
+}
+
+func (r *adminRBACRepoStub) UpsertCustomRole(_ context.Context, role *AdminRole) error {
+	r.roles[role.Name] = *role
+	return nil
+}
+
+func (r *adminRBACRepoStub) DeleteCustomRole(_ context.Context, name string) error {
+	if _, ok := r.roles[name]; !ok {
+		return ErrAdminRoleNotFound
+	}
+	delete(r.roles, name)
+	return nil
+}
+
+func (r *adminRBACRepoStub) CountRoleAssignments(_ context.Context, name string) (int64, error) {
+	var n int64
+	for _, role := range r.assignments {
+		if role == name {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *adminRBACRepoStub) GetUserRole(_ context.Context, userID int64) (string, error) {
+	return r.assignments[userID], nil
+}
+
+func (r *adminRBACRepoStub) SetUserRole(_ context.Context, userID int64, role string) error {
+	if role == "" {
+		delete(r.assignments, userID)
+		return nil
+	}
+	r.assignments[userID] = role
+	return nil
+}
+
+func (r *adminRBACRepoStub) ListRoleAssignments(context.Context) (map[int64]string, error) {
+	return r.assignments, nil
+}
+
+func (r *adminRBACRepoStub) CreateToken(_ context.Context, token *AdminAPIToken, tokenHash string) error {
+	r.nextID++
+	token.ID = r.nextID
+	stored := *token
+	r.tokens[tokenHash] = &stored
+	return nil
+}
+
+func (r *adminRBACRepoStub) GetTokenByHash(_ context.Context, tokenHash string) (*AdminAPIToken, error) {
+	token, ok := r.tokens[tokenHash]
+	if !ok {
+		return nil, ErrAdminAPITokenNotFound
+	}
+	out := *token
+	return &out, nil
+}
+
+func (r *adminRBACRepoStub) ListTokens(context.Context) ([]AdminAPIToken, error) {
+	out := make([]AdminAPIToken, 0, len(r.tokens))
+	for _, token := range r.tokens {
+		out = append(out, *token)
+	}
+	return out, nil
+}
+
+func (r *adminRBACRepoStub) RevokeToken(_ context.Context, id int64, revokedAt time.Time) error {
+	for _, token := range r.tokens {
+		if token.ID == id {
+			token.RevokedAt = &revokedAt
+			return nil
+		}
+	}
+	return ErrAdminAPITokenNotFound
+}
+
+func (r *adminRBACRepoStub) TouchToken(_ context.Context, id int64, usedAt time.Time, ip string) error {
+	r.touched++
+	for _, token := range r.tokens {
+		if token.ID == id {
+			token.LastUsedAt = &usedAt
+			token.LastUsedIP = ip
+		}
+	}
+	return nil
+}
+
+func TestAdminPermissionSetHasAndIntersect(t *testing.T) {
+	all := NewAdminPermissionSet(AdminPermissionAll)
+	support := NewAdminPermissionSet(AdminPermissionUsersRead, AdminPermissionUsersWrite)
+
+	require.True(t, all.Has(AdminPermissionCredentialsReveal))
+	require.True(t, support.Has(AdminPermissionUsersWrite))
+	require.False(t, support.Has(AdminPermissionBillingWrite))
+	require.False(t, AdminPermissionSet(nil).Has(AdminPermissionUsersRead))
+
+	require.Equal(t, support.List(), all.Intersect(support).List())
+	require.Equal(t, support.List(), support.Intersect(all).List())
+	require.Equal(t, []string{AdminPermissionUsersRead},
+		support.Intersect(NewAdminPermissionSet(AdminPermissionUsersRead, AdminPermissionBillingRead)).List())
+}
+
+func TestAdminRBACResolveUserPermissions(t *testing.T) {
+	superGrant := NewAdminPermissionSet(AdminPermissionAll)
+	repo := newAdminRBACRepoStub()
+	svc := NewAdminRBACService(repo)
+	ctx := context.Background()
+
+	role, perms, err := svc.ResolveUserPermissions(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, AdminRoleSuperAdmin, role, "未分配角色的管理员保持全部权限")
+	require.True(t, perms.Has(AdminPermissionAdminsManage))
+
+	require.NoError(t, svc.AssignUserRole(ctx, superGrant, 2, AdminRoleSupport))
+	role, perms, err = svc.ResolveUserPermissions(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, AdminRoleSupport, role)
+	require.True(t, perms.Has(AdminPermissionUsersWrite))
+	require.False(t, perms.Has(AdminPermissionCredentialsReveal))
+	require.False(t, perms.Has(AdminPermissionBillingWrite))
+
+	// 角色定义丢失时按空权限处理
+	repo.assignments[3] = "ghost"
+	_, perms, err = svc.ResolveUserPermissions(ctx, 3)
+	require.NoError(t, err)
+	require.Empty(t, perms)
+
+	require.ErrorIs(t, svc.AssignUserRole(ctx, superGrant, 4, "missing"), ErrAdminRoleNotFound)
+	require.NoError(t, svc.AssignUserRole(ctx, superGrant, 2, AdminRoleSuperAdmin))
+	require.NotContains(t, repo.assignments, int64(2))
+}
+
+func TestAdminRBACUpsertAndDeleteRole(t *testing.T) {
+	superGrant := NewAdminPermissionSet(AdminPermissionAll)
+	repo := newAdminRBACRepoStub()
+	svc := NewAdminRBACService(repo)
+	ctx := context.Background()
+
+	_, err := svc.UpsertRole(ctx, superGrant, &AdminRole{Name: AdminRoleFinance, Permissions: []string{AdminPermissionUsersRead}})
+	require.ErrorIs(t, err, ErrAdminRoleBuiltIn)
+	_, err = svc.UpsertRole(ctx, superGrant, &AdminRole{Name: "Bad Name", Permissions: []string{AdminPermissionUsersRead}})
+	require.ErrorIs(t, err, ErrAdminRoleInvalid)
+	_, err = svc.UpsertRole(ctx, superGrant, &AdminRole{Name: "ops", Permissions: []string{"ops:everything"}})
+	require.ErrorIs(t, err, ErrAdminPermissionInvalid)
+
+	role, err := svc.UpsertRole(ctx, superGrant, &AdminRole{
+		Name:        "oncall",
+		Permissions: []string{AdminPermissionOpsWrite, AdminPermissionOpsRead, AdminPermissionOpsRead},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{AdminPermissionOpsRead, AdminPermissionOpsWrite}, role.Permissions)
+
+	require.NoError(t, svc.AssignUserRole(ctx, superGrant, 7, "oncall"))
+	require.ErrorIs(t, svc.DeleteRole(ctx, "oncall"), ErrAdminRoleInUse)
+	require.NoError(t, svc.AssignUserRole(ctx, superGrant, 7, ""))
+	require.NoError(t, svc.DeleteRole(ctx, "oncall"))
+	require.ErrorIs(t, svc.DeleteRole(ctx, AdminRoleSupport), ErrAdminRoleBuiltIn)
+}
+
+func TestAdminRBACRoleGrantsMustBeSubset(t *testing.T) {
+	repo := newAdminRBACRepoStub()
+	svc := NewAdminRBACService(repo)
+	ctx := context.Background()
+	superGrant := NewAdminPermissionSet(AdminPermissionAll)
+	manager := NewAdminPermissionSet(AdminPermissionAdminsManage, AdminPermissionUsersRead, AdminPermissionUsersWrite)
+
+	_, err := svc.UpsertRole(ctx, manager, &AdminRole{Name: "escalate", Permissions: []string{AdminPermissionCredentialsReveal}})
+	require.ErrorIs(t, err, ErrAdminRoleGrantDenied)
+	_, err = svc.UpsertRole(ctx, manager, &AdminRole{Name: "escalate", Permissions: []string{AdminPermissionAll}})
+	require.ErrorIs(t, err, ErrAdminRoleGrantDenied)
+	_, err = svc.UpsertRole(ctx, manager, &AdminRole{Name: "helpdesk", Permissions: []string{AdminPermissionUsersRead}})
+	require.NoError(t, err)
+
+	// 不能改写权限超出自身的现有角色，即便新权限在范围内。
+	_, err = svc.UpsertRole(ctx, superGrant, &AdminRole{Name: "vault", Permissions: []string{AdminPermissionCredentialsReveal}})
+	require.NoError(t, err)
+	_, err = svc.UpsertRole(ctx, manager, &AdminRole{Name: "vault", Permissions: []string{AdminPermissionUsersRead}})
+	require.ErrorIs(t, err, ErrAdminRoleGrantDenied)
+
+	require.ErrorIs(t, svc.AssignUserRole(ctx, manager, 5, "vault"), ErrAdminRoleGrantDenied)
+	require.ErrorIs(t, svc.AssignUserRole(ctx, manager, 5, AdminRoleSuperAdmin), ErrAdminRoleGrantDenied)
+	// 目标当前是 super_admin，权限高于调用者，不能被降级。
+	require.ErrorIs(t, svc.AssignUserRole(ctx, manager, 5, "helpdesk"), ErrAdminRoleGrantDenied)
+
+	require.NoError(t, svc.AssignUserRole(ctx, superGrant, 5, "helpdesk"))
+	require.NoError(t, svc.AssignUserRole(ctx, manager, 5, "helpdesk"))
+	require.NoError(t, svc.AssignUserRole(ctx, superGrant, 5, AdminRoleSuperAdmin))
+}
+
+func TestAdminRBACCreateTokenScopesMustBeSubset(t *testing.T) {
+	repo := newAdminRBACRepoStub()
+	svc := NewAdminRBACService(repo)
+	ctx := context.Background()
+	creator := NewAdminPermissionSet(AdminPermissionUsersRead, AdminPermissionUsageRead)
+
+	_, _, err := svc.CreateToken(ctx, 1, creator, CreateAdminAPITokenInput{Name: "ci", Scopes: []string{AdminPermissionBillingWrite}})
+	require.ErrorIs(t, err, ErrAdminAPITokenScopeDenied)
+	_, _, err = svc.CreateToken(ctx, 1, creator, CreateAdminAPITokenInput{Name: "ci", Scopes: []string{AdminPermissionAll}})
+	require.ErrorIs(t, err, ErrAdminAPITokenScopeDenied)
+	_, _, err = svc.CreateToken(ctx, 1, creator, CreateAdminAPITokenInput{Name: " ", Scopes: []string{AdminPermissionUsersRead}})
+	require.ErrorIs(t, err, ErrAdminAPITokenNameInvalid)
+	past := time.Now().Add(-time.Hour)
+	_, _, err = svc.CreateToken(ctx, 1, creator, CreateAdminAPITokenInput{Name: "ci", Scopes: []string{AdminPermissionUsersRead}, ExpiresAt: &past})
+	require.ErrorIs(t, err, ErrAdminAPITokenExpiryInvalid)
+
+	plain, token, err := svc.CreateToken(ctx, 1, creator, CreateAdminAPITokenInput{Name: "ci", Scopes: []string{AdminPermissionUsersRead}})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(plain, AdminAPITokenPrefix))
+	require.True(t, strings.HasPrefix(plain, token.TokenPrefix))
+	require.Contains(t, repo.tokens, HashAdminAPIToken(plain))
+	require.NotContains(t, repo.tokens, plain, "只保存摘要")
+}
+
+func TestAdminRBACAuthenticateToken(t *testing.T) {
+	repo := newAdminRBACRepoStub()
+	svc := NewAdminRBACService(repo)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	expires := now.Add(time.Hour)
+	plain, token, err := svc.CreateToken(ctx, 1, NewAdminPermissionSet(AdminPermissionAll),
+		CreateAdminAPITokenInput{Name: "billing-export", Scopes: []string{AdminPermissionBillingRead}, ExpiresAt: &expires})
+	require.NoError(t, err)
+
+	got, err := svc.AuthenticateToken(ctx, plain, "10.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, token.ID, got.ID)
+	require.Equal(t, 1, repo.touched)
+
+	// 同一 IP 的短时间重复调用不回写
+	_, err = svc.AuthenticateToken(ctx, plain, "10.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, 1, repo.touched)
+	_, err = svc.AuthenticateToken(ctx, plain, "10.0.0.2")
+	require.NoError(t, err)
+	require.Equal(t, 2, repo.touched)
+
+	_, err = svc.AuthenticateToken(ctx, HashAdminAPIToken(plain), "10.0.0.1")
+	require.ErrorIs(t, err, ErrAdminAPITokenInvalid)
+	_, err = svc.AuthenticateToken(ctx, AdminAPITokenPrefix+"unknown", "10.0.0.1")
+	require.ErrorIs(t, err, ErrAdminAPITokenInvalid)
+
+	now = expires
+	_, err = svc.AuthenticateToken(ctx, plain, "10.0.0.1")
+	require.ErrorIs(t, err, ErrAdminAPITokenInvalid, "过期后立即失效")
+
+	now = expires.Add(-time.Minute)
+	require.NoError(t, svc.RevokeToken(ctx, token.ID))
+	_, err = svc.AuthenticateToken(ctx, plain, "10.0.0.1")
+	require.ErrorIs(t, err, ErrAdminAPITokenInvalid)
+}
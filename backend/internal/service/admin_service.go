@@ -247,11 +247,13 @@ type CreateGroupInput struct {
 	// 搜索工具单价 per 1k
 	SearchPricePer1k *float64
 	// Grok Voice 显式定价（分组级）
-	AudioRealtimePricePerMin     *float64
-	AudioTTSPricePerMillionChars *float64
-	AudioSTTPricePerHour         *float64
-	ClaudeCodeOnly               bool   // 仅允许 Claude Code 客户端
-	FallbackGroupID              *int64 // 降级分组 ID
+	AudioRealtimePricePerMin        *float64
+	AudioTTSPricePerMillionChars    *float64
+	AudioSTTPricePerHour            *float64
+	ModerationPricePerCall          *float64
+	ModerationPricePerMillionTokens *float64
+	ClaudeCodeOnly                  bool   // 仅允许 Claude Code 客户端
+	FallbackGroupID                 *int64 // 降级分组 ID
 	// 无效请求兜底分组 ID（仅 anthropic 平台使用）
 	FallbackGroupIDOnInvalidRequest *int64
 	// 模型路由配置（仅 anthropic 平台使用）
@@ -278,6 +280,14 @@ type CreateGroupInput struct {
 	ProfitControlEnabled bool
 	ProfitMinMargin      *float64
 	ProfitSafetyBuffer   *float64
+	// 提示词前缀一致性哈希路由
+	PromptPrefixRoutingEnabled bool
+	PromptPrefixMessages       int
+	// 流式断线续传
+	StreamResumeEnabled bool
+	// 上下文窗口溢出压缩
+	ContextCompactionMode  string
+	ContextCompactionModel string
 	// 从指定分组复制账号（创建分组后在同一事务内绑定）
 	CopyAccountsFromGroupIDs []int64
 }
@@ -322,11 +332,13 @@ type UpdateGroupInput struct {
 	// 搜索工具单价；nil 不修改，负数清除
 	SearchPricePer1k *float64
 	// Grok Voice 显式定价；nil 表示不修改，负数表示清除
-	AudioRealtimePricePerMin     *float64
-	AudioTTSPricePerMillionChars *float64
-	AudioSTTPricePerHour         *float64
-	ClaudeCodeOnly               *bool  // 仅允许 Claude Code 客户端
-	FallbackGroupID              *int64 // 降级分组 ID
+	AudioRealtimePricePerMin        *float64
+	AudioTTSPricePerMillionChars    *float64
+	AudioSTTPricePerHour            *float64
+	ModerationPricePerCall          *float64
+	ModerationPricePerMillionTokens *float64
+	ClaudeCodeOnly                  *bool  // 仅允许 Claude Code 客户端
+	FallbackGroupID                 *int64 // 降级分组 ID
 	// 无效请求兜底分组 ID（仅 anthropic 平台使用）
 	FallbackGroupIDOnInvalidRequest *int64
 	// 模型路由配置（仅 anthropic 平台使用）
@@ -353,6 +365,14 @@ type UpdateGroupInput struct {
 	ProfitControlEnabled *bool
 	ProfitMinMargin      *float64
 	ProfitSafetyBuffer   *float64
+	// 提示词前缀一致性哈希路由（nil 表示不修改）
+	PromptPrefixRoutingEnabled *bool
+	PromptPrefixMessages       *int
+	// 流式断线续传（nil 表示不修改）
+	StreamResumeEnabled *bool
+	// 上下文窗口溢出压缩（nil 表示不修改）
+	ContextCompactionMode  *string
+	ContextCompactionModel *string
 	// 从指定分组复制账号（同步操作：先清空当前分组的账号绑定，再绑定源分组的账号）
 	CopyAccountsFromGroupIDs []int64
 }
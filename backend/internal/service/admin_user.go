@@ -1021,6 +1021,8 @@ func normalizeAdminAuthIdentityProviderType(input string) string {
 		return "wechat"
 	case "dingtalk":
 		return "dingtalk"
+	case "saml":
+		return "saml"
 	default:
 		return ""
 	}
@@ -30,7 +30,8 @@ func IsWindowExpired(windowStart *time.Time, duration time.Duration) bool {
 type APIKey struct {
 	ID          int64
 	UserID      int64
-	Key         string
+	Key         string // 创建时与认证路径上为明文；从存储读出的是哈希形态（见 IsHashedAPIKey）
+	KeyPrefix   string // 展示用前缀，完整 Key 只在创建时返回一次
 	Name        string
 	GroupID     *int64
 	Status      string
@@ -62,6 +63,11 @@ type APIKey struct {
 	Window5hStart *time.Time // Start of current 5h window
 	Window1dStart *time.Time // Start of current 1d window
 	Window7dStart *time.Time // Start of current 7d window
+
+	// TokenNonce 派生令牌的签名 nonce；轮换即吊销该 Key 签发的全部派生令牌。
+	TokenNonce string `json:"-"`
+	// ScopedToken 本次请求以派生令牌认证时的令牌声明；直接以 Key 认证时为 nil。
+	ScopedToken *ScopedTokenClaims `json:"-"`
 }
 
 func (k *APIKey) IsActive() bool {
@@ -26,6 +26,9 @@ type APIKeyAuthSnapshot struct {
 	RateLimit5h float64 `json:"rate_limit_5h"`
 	RateLimit1d float64 `json:"rate_limit_1d"`
 	RateLimit7d float64 `json:"rate_limit_7d"`
+
+	// TokenNonce 派生令牌签名 nonce，令牌校验只读快照、不回源数据库
+	TokenNonce string `json:"token_nonce,omitempty"`
 }
 
 // APIKeyAuthUserSnapshot 用户快照
@@ -79,6 +82,8 @@ type APIKeyAuthGroupSnapshot struct {
 	AudioRealtimePricePerMin        *float64                      `json:"audio_realtime_price_per_min,omitempty"`
 	AudioTTSPricePerMillionChars    *float64                      `json:"audio_tts_price_per_million_chars,omitempty"`
 	AudioSTTPricePerHour            *float64                      `json:"audio_stt_price_per_hour,omitempty"`
+	ModerationPricePerCall          *float64                      `json:"moderation_price_per_call,omitempty"`
+	ModerationPricePerMillionTokens *float64                      `json:"moderation_price_per_million_tokens,omitempty"`
 	ClaudeCodeOnly                  bool                          `json:"claude_code_only"`
 	FallbackGroupID                 *int64                        `json:"fallback_group_id,omitempty"`
 	FallbackGroupIDOnInvalidRequest *int64                        `json:"fallback_group_id_on_invalid_request,omitempty"`
@@ -125,6 +130,17 @@ type APIKeyAuthGroupSnapshot struct {
 	ProfitControlEnabled bool    `json:"profit_control_enabled"`
 	ProfitMinMargin      float64 `json:"profit_min_margin"`
 	ProfitSafetyBuffer   float64 `json:"profit_safety_buffer"`
+
+	// 提示词前缀一致性哈希路由：调度时直接读取 ctx 中的认证分组。
+	PromptPrefixRoutingEnabled bool `json:"prompt_prefix_routing_enabled"`
+	PromptPrefixMessages       int  `json:"prompt_prefix_messages"`
+
+	// 流式断线续传开关：handler 直接读取 ctx 中的认证分组。
+	StreamResumeEnabled bool `json:"stream_resume_enabled"`
+
+	// 上下文窗口溢出压缩策略
+	ContextCompactionMode  string `json:"context_compaction_mode,omitempty"`
+	ContextCompactionModel string `json:"context_compaction_model,omitempty"`
 }
 
 // APIKeyAuthCacheEntry 缓存条目，支持负缓存
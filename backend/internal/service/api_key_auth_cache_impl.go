@@ -2,19 +2,18 @@ package service
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
 	"math/rand/v2"
+	"strings"
 	"time"
 
 	"github.com/Wei-Shaw/sub2api/internal/config"
 	"github.com/dgraph-io/ristretto"
 )
 
-const apiKeyAuthSnapshotVersion = 19 // v19: group search/audio/video_model_prices billing fields (force refresh of pre-fix snapshots)
+const apiKeyAuthSnapshotVersion = 23 // v23: scoped token signing nonce
 
 type apiKeyAuthCacheConfig struct {
 	l1Size        int
@@ -191,13 +190,32 @@ func (s *APIKeyService) StopAuthCacheInvalidationSubscriber() {
 	})
 }
 
+// authCacheKey 认证缓存以 Key 的 HMAC 摘要为键：哈希形态的存储值直接取出摘要，
+// 明文（请求携带的 Key 或尚未回填的历史数据）现算，两者对同一 Key 得到相同结果。
 func (s *APIKeyService) authCacheKey(key string) string {
-	sum := sha256.Sum256([]byte(key))
-	return hex.EncodeToString(sum[:])
+	if IsHashedAPIKey(key) {
+		return strings.TrimPrefix(key, APIKeyHashScheme)
+	}
+	return NewAPIKeyHasher(s.cfg).Digest(key)
 }
 
-func (s *APIKeyService) getAuthCacheEntry(ctx context.Context, cacheKey string) (*APIKeyAuthCacheEntry, bool) {
+// SetAuthCacheLegacyKeysPending 由历史 Key 回填任务设置：仍有明文存储的 Key 时绕过正向认证缓存，
+// 回填完成后恢复。负缓存只记录不存在的 Key，不依赖触发器失效，不受影响。
+func (s *APIKeyService) SetAuthCacheLegacyKeysPending(pending bool) {
+	if s == nil {
+		return
+	}
+	if s.authCacheLegacyPending.Swap(pending) == pending || !pending {
+		return
+	}
 	if s.authCacheL1 != nil {
+		s.authCacheL1.Clear()
+	}
+}
+
+func (s *APIKeyService) getAuthCacheEntry(ctx context.Context, cacheKey string) (*APIKeyAuthCacheEntry, bool) {
+	legacyPending := s.authCacheLegacyPending.Load()
+	if s.authCacheL1 != nil && !legacyPending {
 		if val, ok := s.authCacheL1.Get(cacheKey); ok {
 			if entry, ok := val.(*APIKeyAuthCacheEntry); ok {
 				return entry, true
@@ -211,7 +229,7 @@ func (s *APIKeyService) getAuthCacheEntry(ctx context.Context, cacheKey string)
 			}
 		}
 	}
-	if s.cache == nil || !s.authCfg.l2Enabled() {
+	if s.cache == nil || !s.authCfg.l2Enabled() || legacyPending {
 		return nil, false
 	}
 	entry, err := s.cache.GetAuthCache(ctx, cacheKey)
@@ -232,7 +250,7 @@ func (s *APIKeyService) setAuthCacheL1(cacheKey string, entry *APIKeyAuthCacheEn
 		}
 		return
 	}
-	if s.authCacheL1 == nil {
+	if s.authCacheL1 == nil || s.authCacheLegacyPending.Load() {
 		return
 	}
 	ttl := s.authCfg.l1TTL
@@ -245,7 +263,7 @@ func (s *APIKeyService) setAuthCacheEntry(ctx context.Context, cacheKey string,
 		return
 	}
 	s.setAuthCacheL1(cacheKey, entry)
-	if s.cache == nil || !s.authCfg.l2Enabled() {
+	if s.cache == nil || !s.authCfg.l2Enabled() || s.authCacheLegacyPending.Load() {
 		return
 	}
 	_ = s.cache.SetAuthCache(ctx, cacheKey, entry, s.authCfg.jitterTTL(ttl))
@@ -267,7 +285,13 @@ func (s *APIKeyService) deleteAuthCache(ctx context.Context, cacheKey string) {
 }
 
 func (s *APIKeyService) loadAuthCacheEntry(ctx context.Context, key, cacheKey string) (*APIKeyAuthCacheEntry, error) {
-	apiKey, err := s.lookupAPIKeyForAuth(ctx, key)
+	return s.loadAuthCacheEntryWith(ctx, key, cacheKey, func(ctx context.Context) (*APIKey, error) {
+		return s.lookupAPIKeyForAuth(ctx, key)
+	})
+}
+
+func (s *APIKeyService) loadAuthCacheEntryWith(ctx context.Context, key, cacheKey string, lookup func(context.Context) (*APIKey, error)) (*APIKeyAuthCacheEntry, error) {
+	apiKey, err := lookup(ctx)
 	if err != nil {
 		if errors.Is(err, ErrAPIKeyNotFound) {
 			entry := &APIKeyAuthCacheEntry{NotFound: true}
@@ -295,8 +319,15 @@ func (s *APIKeyService) lookupAPIKeyForAuth(ctx context.Context, key string) (*A
 	if s == nil || s.apiKeyRepo == nil {
 		return nil, ErrAPIKeyNotFound
 	}
-	if s.authLookupSlots == nil {
+	return s.withAuthLookupSlot(ctx, func() (*APIKey, error) {
 		return s.apiKeyRepo.GetByKeyForAuth(ctx, key)
+	})
+}
+
+// withAuthLookupSlot 限制同时回源数据库的认证查询数，超出时快速失败而不是排队压垮连接池。
+func (s *APIKeyService) withAuthLookupSlot(ctx context.Context, lookup func() (*APIKey, error)) (*APIKey, error) {
+	if s.authLookupSlots == nil {
+		return lookup()
 	}
 	s.authLookupTotal.Add(1)
 	select {
@@ -312,7 +343,56 @@ func (s *APIKeyService) lookupAPIKeyForAuth(ctx context.Context, key string) (*A
 		s.authLookupRejected.Add(1)
 		return nil, ErrAPIKeyAuthOverloaded
 	}
-	return s.apiKeyRepo.GetByKeyForAuth(ctx, key)
+	return lookup()
+}
+
+// apiKeyStoredKeyAuthLoader 由 API Key 仓储实现：按存储形态（哈希）加载认证所需字段。
+type apiKeyStoredKeyAuthLoader interface {
+	GetByStoredKeyForAuth(ctx context.Context, storedKey string) (*APIKey, error)
+}
+
+// GetByStoredKeyForAuth 按存储形态（hmac-sha256:<hex>）加载 Key 的认证视图，供派生令牌定位父 Key。
+// 与 GetByKey 共用认证缓存（缓存键即摘要），因此父 Key 的失效、轮换与删除同样立即生效。
+// 返回的 APIKey.Key 为哈希形态；它不是凭证，GetByKey 会拒绝它。
+func (s *APIKeyService) GetByStoredKeyForAuth(ctx context.Context, storedKey string) (*APIKey, error) {
+	if !IsHashedAPIKey(storedKey) || len(storedKey) > MaxAPIKeyCredentialBytes {
+		return nil, ErrAPIKeyNotFound
+	}
+	loader, ok := s.apiKeyRepo.(apiKeyStoredKeyAuthLoader)
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	cacheKey := s.authCacheKey(storedKey)
+	if entry, ok := s.getAuthCacheEntry(ctx, cacheKey); ok {
+		if apiKey, used, err := s.applyAuthCacheEntry(storedKey, entry); used {
+			if err != nil {
+				return nil, fmt.Errorf("get api key: %w", err)
+			}
+			s.compileAPIKeyIPRules(apiKey)
+			return apiKey, nil
+		}
+	}
+
+	value, err, _ := s.authGroup.Do(cacheKey, func() (any, error) {
+		return s.loadAuthCacheEntryWith(ctx, storedKey, cacheKey, func(ctx context.Context) (*APIKey, error) {
+			return s.withAuthLookupSlot(ctx, func() (*APIKey, error) {
+				return loader.GetByStoredKeyForAuth(ctx, storedKey)
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	entry, _ := value.(*APIKeyAuthCacheEntry)
+	apiKey, used, err := s.applyAuthCacheEntry(storedKey, entry)
+	if !used {
+		return nil, fmt.Errorf("get api key: %w", ErrAPIKeyNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get api key: %w", err)
+	}
+	s.compileAPIKeyIPRules(apiKey)
+	return apiKey, nil
 }
 
 func (s *APIKeyService) applyAuthCacheEntry(key string, entry *APIKeyAuthCacheEntry) (*APIKey, bool, error) {
@@ -350,6 +430,7 @@ func (s *APIKeyService) snapshotFromAPIKey(ctx context.Context, apiKey *APIKey)
 		RateLimit5h: apiKey.RateLimit5h,
 		RateLimit1d: apiKey.RateLimit1d,
 		RateLimit7d: apiKey.RateLimit7d,
+		TokenNonce:  apiKey.TokenNonce,
 		User: APIKeyAuthUserSnapshot{
 			ID:                         apiKey.User.ID,
 			Status:                     apiKey.User.Status,
@@ -401,6 +482,8 @@ func (s *APIKeyService) snapshotFromAPIKey(ctx context.Context, apiKey *APIKey)
 			AudioRealtimePricePerMin:        apiKey.Group.AudioRealtimePricePerMin,
 			AudioTTSPricePerMillionChars:    apiKey.Group.AudioTTSPricePerMillionChars,
 			AudioSTTPricePerHour:            apiKey.Group.AudioSTTPricePerHour,
+			ModerationPricePerCall:          apiKey.Group.ModerationPricePerCall,
+			ModerationPricePerMillionTokens: apiKey.Group.ModerationPricePerMillionTokens,
 			ClaudeCodeOnly:                  apiKey.Group.ClaudeCodeOnly,
 			FallbackGroupID:                 apiKey.Group.FallbackGroupID,
 			FallbackGroupIDOnInvalidRequest: apiKey.Group.FallbackGroupIDOnInvalidRequest,
@@ -423,6 +506,11 @@ func (s *APIKeyService) snapshotFromAPIKey(ctx context.Context, apiKey *APIKey)
 			ProfitControlEnabled:            apiKey.Group.ProfitControlEnabled,
 			ProfitMinMargin:                 apiKey.Group.ProfitMinMargin,
 			ProfitSafetyBuffer:              apiKey.Group.ProfitSafetyBuffer,
+			PromptPrefixRoutingEnabled:      apiKey.Group.PromptPrefixRoutingEnabled,
+			PromptPrefixMessages:            apiKey.Group.PromptPrefixMessages,
+			StreamResumeEnabled:             apiKey.Group.StreamResumeEnabled,
+			ContextCompactionMode:           apiKey.Group.ContextCompactionMode,
+			ContextCompactionModel:          apiKey.Group.ContextCompactionModel,
 		}
 	}
 	return snapshot
@@ -447,6 +535,7 @@ func (s *APIKeyService) snapshotToAPIKey(key string, snapshot *APIKeyAuthSnapsho
 		RateLimit5h: snapshot.RateLimit5h,
 		RateLimit1d: snapshot.RateLimit1d,
 		RateLimit7d: snapshot.RateLimit7d,
+		TokenNonce:  snapshot.TokenNonce,
 		User: &User{
 			ID:                         snapshot.User.ID,
 			Status:                     snapshot.User.Status,
@@ -491,6 +580,8 @@ func (s *APIKeyService) snapshotToAPIKey(key string, snapshot *APIKeyAuthSnapsho
 			AudioRealtimePricePerMin:        snapshot.Group.AudioRealtimePricePerMin,
 			AudioTTSPricePerMillionChars:    snapshot.Group.AudioTTSPricePerMillionChars,
 			AudioSTTPricePerHour:            snapshot.Group.AudioSTTPricePerHour,
+			ModerationPricePerCall:          snapshot.Group.ModerationPricePerCall,
+			ModerationPricePerMillionTokens: snapshot.Group.ModerationPricePerMillionTokens,
 			ClaudeCodeOnly:                  snapshot.Group.ClaudeCodeOnly,
 			FallbackGroupID:                 snapshot.Group.FallbackGroupID,
 			FallbackGroupIDOnInvalidRequest: snapshot.Group.FallbackGroupIDOnInvalidRequest,
@@ -513,6 +604,11 @@ func (s *APIKeyService) snapshotToAPIKey(key string, snapshot *APIKeyAuthSnapsho
 			ProfitControlEnabled:            snapshot.Group.ProfitControlEnabled,
 			ProfitMinMargin:                 snapshot.Group.ProfitMinMargin,
 			ProfitSafetyBuffer:              snapshot.Group.ProfitSafetyBuffer,
+			PromptPrefixRoutingEnabled:      snapshot.Group.PromptPrefixRoutingEnabled,
+			PromptPrefixMessages:            snapshot.Group.PromptPrefixMessages,
+			StreamResumeEnabled:             snapshot.Group.StreamResumeEnabled,
+			ContextCompactionMode:           snapshot.Group.ContextCompactionMode,
+			ContextCompactionModel:          snapshot.Group.ContextCompactionModel,
 		}
 	}
 	s.compileAPIKeyIPRules(apiKey)
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+)
+
+// APIKeyHashScheme 是 api_keys.key 中哈希形态的前缀。
+// 自定义 Key 只允许字母、数字、下划线与连字符，生成的 Key 前缀也禁止 ':'，
+// 因此带此前缀的值不可能是合法的明文 Key。
+const APIKeyHashScheme = "hmac-sha256:"
+
+// apiKeyDisplayPrefixMaxLen 是 key_prefix 展示的最大字符数，且不超过 Key 长度的一半。
+const apiKeyDisplayPrefixMaxLen = 8
+
+// APIKeyHasher 用服务端 pepper 计算 API Key 的 HMAC-SHA256。
+// 摘要同时作为存储值（加上 scheme 前缀）与认证缓存键，数据库与缓存都不再保存明文。
+// pepper 在启动阶段由 security_secrets 补齐后才可用，因此按需读取配置。
+// nil 或未配置 pepper 时退化为空 pepper（仅测试场景）。
+type APIKeyHasher struct {
+	cfg *config.Config
+}
+
+func NewAPIKeyHasher(cfg *config.Config) *APIKeyHasher {
+	return &APIKeyHasher{cfg: cfg}
+}
+
+// Digest 返回 key 的十六进制 HMAC 摘要。
+func (h *APIKeyHasher) Digest(key string) string {
+	var pepper string
+	if h != nil && h.cfg != nil {
+		pepper = h.cfg.Security.APIKeyHashPepper
+	}
+	mac := hmac.New(sha256.New, []byte(pepper))
+	_, _ = mac.Write([]byte(key))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// StoredKey 返回写入 api_keys.key 的哈希形态；已是哈希形态的值原样返回。
+func (h *APIKeyHasher) StoredKey(key string) string {
+	if IsHashedAPIKey(key) {
+		return key
+	}
+	return APIKeyHashScheme + h.Digest(key)
+}
+
+// IsHashedAPIKey 判断存储值是否为哈希形态（而非历史明文）。
+func IsHashedAPIKey(stored string) bool {
+	return strings.HasPrefix(stored, APIKeyHashScheme)
+}
+
+// APIKeyDisplayPrefix 返回用于列表展示的 Key 前缀。
+func APIKeyDisplayPrefix(key string) string {
+	n := len(key) / 2
+	if n > apiKeyDisplayPrefixMaxLen {
+		n = apiKeyDisplayPrefixMaxLen
+	}
+	return key[:n]
+}
+
+// RevealableAPIKey 返回可以展示给用户的完整 Key：只有创建时持有的明文才会返回，
+// 从存储读出的哈希形态返回空串。
+func RevealableAPIKey(key string) string {
+	if IsHashedAPIKey(key) {
+		return ""
+	}
+	return key
+}
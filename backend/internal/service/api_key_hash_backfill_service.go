@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/google/uuid"
+)
+
+const (
+	// apiKeyHashBackfillLeaderLockKey 保证多实例部署下只有一个实例执行回填。
+	apiKeyHashBackfillLeaderLockKey = "api_key:hash_backfill:leader"
+	apiKeyHashBackfillLeaderLockTTL = 10 * time.Minute
+	apiKeyHashBackfillRunTimeout    = 8 * time.Minute
+
+	// apiKeyHashBackfillGateRefreshInterval 未启用回填时检查其它实例回填进度的间隔。
+	apiKeyHashBackfillGateRefreshInterval = time.Minute
+	apiKeyHashBackfillGateCheckTimeout    = 10 * time.Second
+
+	defaultAPIKeyHashBackfillBatchSize = 500
+)
+
+// APIKeyHashBackfillRepository 把仍以明文存储的历史 Key 改写为哈希形态。
+// 以原值做 CAS，返回本批实际改写的行数；返回 0 表示已无待处理记录。
+type APIKeyHashBackfillRepository interface {
+	HashLegacyAPIKeys(ctx context.Context, limit int) (int, error)
+	HasLegacyAPIKeys(ctx context.Context) (bool, error)
+}
+
+// APIKeyHashBackfillService 周期性地回填历史明文 Key。
+// 回填完成前认证路径同时接受两种存储形态，因此客户端无需任何改动；
+// 每个实例各自检查回填进度，未完成时让 APIKeyService 绕过正向认证缓存。
+type APIKeyHashBackfillService struct {
+	repo      APIKeyHashBackfillRepository
+	authCache *APIKeyService
+	interval  time.Duration
+	batchSize int
+	// gateInterval 为 interval 为 0 时单独刷新认证缓存开关的周期。
+	gateInterval time.Duration
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	lockCache  LeaderLockCache
+	db         *sql.DB
+	instanceID string
+}
+
+func NewAPIKeyHashBackfillService(repo APIKeyHashBackfillRepository, interval time.Duration) *APIKeyHashBackfillService {
+	return &APIKeyHashBackfillService{
+		repo:         repo,
+		interval:     interval,
+		batchSize:    defaultAPIKeyHashBackfillBatchSize,
+		gateInterval: apiKeyHashBackfillGateRefreshInterval,
+		stopCh:       make(chan struct{}),
+		instanceID:   uuid.NewString(),
+	}
+}
+
+// SetLeaderLock injects the leader-lock cache and DB used to elect a single
+// instance for the backfill. When both are nil the backfill runs ungated.
+func (s *APIKeyHashBackfillService) SetLeaderLock(lockCache LeaderLockCache, db *sql.DB) {
+	if s == nil {
+		return
+	}
+	s.lockCache = lockCache
+	s.db = db
+}
+
+// SetAuthCacheGate 注入需要按回填进度开关认证缓存的 APIKeyService，并立即同步一次状态。
+// 查询失败时按未完成处理，宁可绕过缓存也不让失效事件落空。
+func (s *APIKeyHashBackfillService) SetAuthCacheGate(ctx context.Context, apiKeyService *APIKeyService) {
+	if s == nil || apiKeyService == nil {
+		return
+	}
+	s.authCache = apiKeyService
+	apiKeyService.SetAuthCacheLegacyKeysPending(true)
+	s.refreshAuthCacheGate(ctx)
+}
+
+func (s *APIKeyHashBackfillService) refreshAuthCacheGate(ctx context.Context) {
+	if s.authCache == nil || s.repo == nil || !s.authCache.authCacheLegacyPending.Load() {
+		return
+	}
+	pending, err := s.repo.HasLegacyAPIKeys(ctx)
+	if err != nil {
+		logger.LegacyPrintf("service.api_key_hash_backfill", "[APIKeyHashBackfill] check legacy keys failed: %v", err)
+		return
+	}
+	s.authCache.SetAuthCacheLegacyKeysPending(pending)
+	if !pending {
+		logger.LegacyPrintf("service.api_key_hash_backfill", "[APIKeyHashBackfill] no legacy keys left, auth cache enabled")
+	}
+}
+
+// Start 立即执行一轮，之后按 interval 周期执行。interval 为 0 时本实例不回填，
+// 但仍周期性检查回填进度，待其它实例（或手动处理）清空明文 Key 后恢复认证缓存。
+func (s *APIKeyHashBackfillService) Start() {
+	if s == nil || s.repo == nil {
+		return
+	}
+	if s.interval <= 0 {
+		s.startAuthCacheGateRefresh()
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		s.runOnce()
+		for {
+			select {
+			case <-ticker.C:
+				s.runOnce()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// startAuthCacheGateRefresh 在不回填的实例上单独刷新认证缓存开关，开关关闭后退出。
+func (s *APIKeyHashBackfillService) startAuthCacheGateRefresh() {
+	if s.authCache == nil || s.gateInterval <= 0 || !s.authCache.authCacheLegacyPending.Load() {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.gateInterval)
+		defer ticker.Stop()
+
+		for s.authCache.authCacheLegacyPending.Load() {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), apiKeyHashBackfillGateCheckTimeout)
+				s.refreshAuthCacheGate(ctx)
+				cancel()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (s *APIKeyHashBackfillService) Stop() {
+	if s == nil {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+	s.wg.Wait()
+}
+
+func (s *APIKeyHashBackfillService) runOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), apiKeyHashBackfillRunTimeout)
+	defer cancel()
+
+	// 非 leader 实例不回填，但仍需感知回填完成以恢复认证缓存。
+	defer s.refreshAuthCacheGate(ctx)
+
+	release, ok := tryAcquireSingletonLeaderLock(ctx, s.lockCache, s.db, apiKeyHashBackfillLeaderLockKey, s.instanceID, apiKeyHashBackfillLeaderLockTTL)
+	if !ok {
+		return
+	}
+	defer release()
+
+	total, err := s.Backfill(ctx)
+	if err != nil {
+		logger.LegacyPrintf("service.api_key_hash_backfill", "[APIKeyHashBackfill] run failed: %v", err)
+	}
+	if total > 0 {
+		logger.LegacyPrintf("service.api_key_hash_backfill", "[APIKeyHashBackfill] hashed=%d", total)
+	}
+}
+
+// Backfill 分批改写直到没有剩余的明文 Key，返回改写总数。
+func (s *APIKeyHashBackfillService) Backfill(ctx context.Context) (int, error) {
+	if s == nil || s.repo == nil {
+		return 0, nil
+	}
+	total := 0
+	for {
+		select {
+		case <-s.stopCh:
+			return total, nil
+		default:
+		}
+		n, err := s.repo.HashLegacyAPIKeys(ctx, s.batchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n == 0 {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAPIKeyHashConfig(pepper string) *config.Config {
+	cfg := &config.Config{}
+	cfg.Security.APIKeyHashPepper = pepper
+	return cfg
+}
+
+func TestAPIKeyHasherStoredKey(t *testing.T) {
+	h := NewAPIKeyHasher(newTestAPIKeyHashConfig("pepper-a"))
+
+	stored := h.StoredKey("sk-abcdef123456")
+	require.True(t, IsHashedAPIKey(stored))
+	require.True(t, strings.HasPrefix(stored, APIKeyHashScheme))
+	require.NotContains(t, stored, "sk-abcdef")
+	require.Equal(t, stored, h.StoredKey(stored), "已是哈希形态的值原样返回")
+	require.Equal(t, stored, h.StoredKey("sk-abcdef123456"))
+
+	other := NewAPIKeyHasher(newTestAPIKeyHashConfig("pepper-b"))
+	require.NotEqual(t, stored, other.StoredKey("sk-abcdef123456"), "不同 pepper 应得到不同摘要")
+}
+
+func TestAPIKeyHasherReadsPepperLazily(t *testing.T) {
+	cfg := newTestAPIKeyHashConfig("")
+	h := NewAPIKeyHasher(cfg)
+	before := h.Digest("sk-lazy")
+
+	cfg.Security.APIKeyHashPepper = "bootstrapped"
+	require.NotEqual(t, before, h.Digest("sk-lazy"))
+	require.Equal(t, NewAPIKeyHasher(cfg).Digest("sk-lazy"), h.Digest("sk-lazy"))
+}
+
+func TestAPIKeyDisplayPrefixAndRevealable(t *testing.T) {
+	require.Equal(t, "sk-abcde", APIKeyDisplayPrefix("sk-abcdefghijklmnop"))
+	require.Equal(t, "abc", APIKeyDisplayPrefix("abcdefg"))
+	require.Equal(t, "", APIKeyDisplayPrefix(""))
+
+	require.Equal(t, "sk-plain", RevealableAPIKey("sk-plain"))
+	require.Equal(t, "", RevealableAPIKey(APIKeyHashScheme+"00ff"))
+}
+
+func TestAPIKeyServiceAuthCacheKeyMatchesStoredAndPlaintext(t *testing.T) {
+	cfg := newTestAPIKeyHashConfig("pepper-cache")
+	svc := &APIKeyService{cfg: cfg}
+	stored := NewAPIKeyHasher(cfg).StoredKey("sk-cache-key")
+
+	require.Equal(t, svc.authCacheKey("sk-cache-key"), svc.authCacheKey(stored))
+	require.NotContains(t, svc.authCacheKey("sk-cache-key"), "sk-cache")
+}
+
+func TestAPIKeyServiceGetByKeyRejectsStoredHash(t *testing.T) {
+	svc := &APIKeyService{cfg: newTestAPIKeyHashConfig("pepper")}
+	_, err := svc.GetByKey(context.Background(), APIKeyHashScheme+strings.Repeat("a", 64))
+	require.ErrorIs(t, err, ErrAPIKeyNotFound)
+}
@@ -0,0 +1,328 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+)
+
+// API Key 泄露处置策略。
+const (
+	// APIKeyLeakActionNotify 仅邮件通知并写审计。
+	APIKeyLeakActionNotify = "notify"
+	// APIKeyLeakActionRequireAllowlist 把 Key 收紧为基线期常用 IP 的白名单；Key 已有白名单时仅通知。
+	APIKeyLeakActionRequireAllowlist = "require_allowlist"
+	// APIKeyLeakActionDisable 直接禁用 Key。
+	APIKeyLeakActionDisable = "disable"
+)
+
+// 一次检测实际执行的处置结果。
+const (
+	APIKeyLeakOutcomeNotified          = "notified"
+	APIKeyLeakOutcomeAllowlistApplied  = "allowlist_applied"
+	APIKeyLeakOutcomeAllowlistExisting = "allowlist_existing"
+	APIKeyLeakOutcomeDisabled          = "disabled"
+)
+
+// 触发检测的信号。
+const (
+	APIKeyLeakReasonDistinctIPs        = "distinct_ips"
+	APIKeyLeakReasonDistinctNetworks   = "distinct_networks"
+	APIKeyLeakReasonDistinctCountries  = "distinct_countries"
+	APIKeyLeakReasonDistinctUserAgents = "distinct_user_agents"
+	APIKeyLeakReasonSpendSpike         = "spend_spike"
+	APIKeyLeakReasonNewHostingIPs      = "new_hosting_ips"
+)
+
+const (
+	// apiKeyLeakMaxSampledIPs 每个 Key 每轮参与网络/国家归类的 IP 上限；DistinctIPs 仍为精确计数。
+	apiKeyLeakMaxSampledIPs = 500
+	// apiKeyLeakMaxPinnedIPs 收紧白名单时最多保留的基线 IP 数（按请求量取前 N 个）。
+	apiKeyLeakMaxPinnedIPs = 20
+	// apiKeyLeakMaxReportedIPs 事件与邮件中展示的 IP 样本上限。
+	apiKeyLeakMaxReportedIPs = 10
+)
+
+var (
+	ErrAPIKeyLeakInvalidAction = infraerrors.BadRequest("API_KEY_LEAK_INVALID_ACTION", "action must be one of notify, require_allowlist, disable")
+)
+
+// NormalizeAPIKeyLeakAction 归一化处置策略，无法识别时返回空串。
+func NormalizeAPIKeyLeakAction(action string) string {
+	switch strings.ToLower(strings.TrimSpace(action)) {
+	case APIKeyLeakActionNotify:
+		return APIKeyLeakActionNotify
+	case APIKeyLeakActionRequireAllowlist:
+		return APIKeyLeakActionRequireAllowlist
+	case APIKeyLeakActionDisable:
+		return APIKeyLeakActionDisable
+	default:
+		return ""
+	}
+}
+
+// APIKeyLeakPolicy 用户的泄露处置策略；IsDefault 表示用户未单独设置、沿用系统默认值。
+type APIKeyLeakPolicy struct {
+	UserID    int64      `json:"user_id"`
+	Action    string     `json:"action"`
+	IsDefault bool       `json:"is_default"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// APIKeyLeakKeyUsage 单个 Key 在检测窗口内的用量聚合。
+type APIKeyLeakKeyUsage struct {
+	APIKeyID           int64
+	UserID             int64
+	RequestCount       int64
+	DistinctIPs        int
+	DistinctUserAgents int
+	Spend              float64
+	// IPs 窗口内出现过的 IP（去重，最多 apiKeyLeakMaxSampledIPs 个）
+	IPs []string
+}
+
+// APIKeyLeakSignals 窗口统计快照，随事件入库并用于邮件展示。
+type APIKeyLeakSignals struct {
+	RequestCount       int64    `json:"request_count"`
+	DistinctIPs        int      `json:"distinct_ips"`
+	DistinctNetworks   int      `json:"distinct_networks"`
+	DistinctCountries  int      `json:"distinct_countries"`
+	DistinctUserAgents int      `json:"distinct_user_agents"`
+	WindowSpend        float64  `json:"window_spend"`
+	BaselineSpend      float64  `json:"baseline_spend"`
+	Countries          []string `json:"countries,omitempty"`
+	SampleIPs          []string `json:"sample_ips,omitempty"`
+	NewHostingIPs      []string `json:"new_hosting_ips,omitempty"`
+}
+
+// APIKeyLeakEvent 一次泄露检测记录。
+type APIKeyLeakEvent struct {
+	ID          int64             `json:"id"`
+	APIKeyID    int64             `json:"api_key_id"`
+	UserID      int64             `json:"user_id"`
+	KeyName     string            `json:"key_name"`
+	KeyPrefix   string            `json:"key_prefix"`
+	Reasons     []string          `json:"reasons"`
+	Signals     APIKeyLeakSignals `json:"signals"`
+	Action      string            `json:"action"`
+	Outcome     string            `json:"outcome"`
+	PinnedIPs   []string          `json:"pinned_ips,omitempty"`
+	WindowStart time.Time         `json:"window_start"`
+	WindowEnd   time.Time         `json:"window_end"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// APIKeyLeakEventFilter 事件列表查询条件，零值字段不过滤。
+type APIKeyLeakEventFilter struct {
+	UserID   int64
+	APIKeyID int64
+}
+
+// APIKeyLeakRepository 泄露检测的持久化端口。
+type APIKeyLeakRepository interface {
+	// ListKeyUsageSince 按 Key 聚合 since 之后的 usage_logs。
+	ListKeyUsageSince(ctx context.Context, since time.Time, maxIPs int) ([]APIKeyLeakKeyUsage, error)
+	// SumSpendByKeys 返回 [from, to) 内各 Key 的实际费用合计，无用量的 Key 不出现在结果中。
+	SumSpendByKeys(ctx context.Context, apiKeyIDs []int64, from, to time.Time) (map[int64]float64, error)
+	// ListTopIPsByKey 返回 [from, to) 内该 Key 请求量最多的 IP。
+	ListTopIPsByKey(ctx context.Context, apiKeyID int64, from, to time.Time, limit int) ([]string, error)
+	// LatestEventTimes 返回各 Key 最近一次事件时间，用于冷却。
+	LatestEventTimes(ctx context.Context, apiKeyIDs []int64) (map[int64]time.Time, error)
+	InsertEvent(ctx context.Context, event *APIKeyLeakEvent) error
+	ListEvents(ctx context.Context, filter APIKeyLeakEventFilter, params pagination.PaginationParams) ([]APIKeyLeakEvent, *pagination.PaginationResult, error)
+
+	// GetPolicy 返回用户设置的策略；未设置时返回 nil。
+	GetPolicy(ctx context.Context, userID int64) (*APIKeyLeakPolicy, error)
+	UpsertPolicy(ctx context.Context, userID int64, action string) (*APIKeyLeakPolicy, error)
+}
+
+// apiKeyLeakNetworkRule 预解析的网段规则。
+type apiKeyLeakNetworkRule struct {
+	network *net.IPNet
+	asn     string
+	country string
+	hosting bool
+}
+
+// APIKeyLeakNetworkClassifier 基于配置的网段规则给 IP 归类（网络 / 国家 / 是否托管机房）。
+// 未命中规则的 IP 以 IPv4 /24、IPv6 /48 前缀近似网络归属，国家未知。
+type APIKeyLeakNetworkClassifier struct {
+	rules []apiKeyLeakNetworkRule
+}
+
+// APIKeyLeakIPClass 单个 IP 的归类结果。
+type APIKeyLeakIPClass struct {
+	Network string
+	Country string
+	Hosting bool
+}
+
+// NewAPIKeyLeakNetworkClassifier 解析网段规则；非法 CIDR 已在配置校验阶段拒绝，这里直接跳过。
+func NewAPIKeyLeakNetworkClassifier(rules []config.APIKeyLeakNetworkRule) *APIKeyLeakNetworkClassifier {
+	c := &APIKeyLeakNetworkClassifier{rules: make([]apiKeyLeakNetworkRule, 0, len(rules))}
+	for _, rule := range rules {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(rule.CIDR))
+		if err != nil {
+			continue
+		}
+		c.rules = append(c.rules, apiKeyLeakNetworkRule{
+			network: network,
+			asn:     strings.ToUpper(strings.TrimSpace(rule.ASN)),
+			country: strings.ToUpper(strings.TrimSpace(rule.Country)),
+			hosting: rule.Hosting,
+		})
+	}
+	return c
+}
+
+// Classify 归类单个 IP；无法解析时 ok=false。
+func (c *APIKeyLeakNetworkClassifier) Classify(raw string) (APIKeyLeakIPClass, bool) {
+	parsed := net.ParseIP(strings.TrimSpace(raw))
+	if parsed == nil {
+		return APIKeyLeakIPClass{}, false
+	}
+	if c != nil {
+		for _, rule := range c.rules {
+			if !rule.network.Contains(parsed) {
+				continue
+			}
+			network := rule.asn
+			if network == "" {
+				network = rule.network.String()
+			}
+			return APIKeyLeakIPClass{Network: network, Country: rule.country, Hosting: rule.hosting}, true
+		}
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return APIKeyLeakIPClass{Network: (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()}, true
+	}
+	return APIKeyLeakIPClass{Network: (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()}, true
+}
+
+// BuildAPIKeyLeakSignals 由窗口用量与归类器生成信号快照（不含基线相关字段）。
+// 返回值 hostingIPs 为窗口内命中托管机房规则的 IP，供调用方与基线比对。
+func BuildAPIKeyLeakSignals(usage APIKeyLeakKeyUsage, classifier *APIKeyLeakNetworkClassifier) (signals APIKeyLeakSignals, hostingIPs []string) {
+	networks := make(map[string]struct{})
+	countries := make(map[string]struct{})
+	for _, ip := range usage.IPs {
+		class, ok := classifier.Classify(ip)
+		if !ok {
+			continue
+		}
+		networks[class.Network] = struct{}{}
+		if class.Country != "" {
+			countries[class.Country] = struct{}{}
+		}
+		if class.Hosting {
+			hostingIPs = append(hostingIPs, ip)
+		}
+	}
+	signals = APIKeyLeakSignals{
+		RequestCount:       usage.RequestCount,
+		DistinctIPs:        usage.DistinctIPs,
+		DistinctNetworks:   len(networks),
+		DistinctCountries:  len(countries),
+		DistinctUserAgents: usage.DistinctUserAgents,
+		WindowSpend:        usage.Spend,
+		SampleIPs:          truncateStrings(usage.IPs, apiKeyLeakMaxReportedIPs),
+	}
+	for country := range countries {
+		signals.Countries = append(signals.Countries, country)
+	}
+	sort.Strings(signals.Countries)
+	return signals, hostingIPs
+}
+
+// EvaluateAPIKeyLeakSignals 按阈值判定触发的信号，未触发时返回空切片。
+// baselineAvailable=false 表示该 Key 未参与费用基线计算（窗口费用低于下限），不判定突增。
+func EvaluateAPIKeyLeakSignals(cfg config.APIKeyLeakDetectionConfig, signals APIKeyLeakSignals, baselineAvailable bool) []string {
+	var reasons []string
+	if cfg.MaxDistinctIPs > 0 && signals.DistinctIPs > cfg.MaxDistinctIPs {
+		reasons = append(reasons, APIKeyLeakReasonDistinctIPs)
+	}
+	if cfg.MaxDistinctNetworks > 0 && signals.DistinctNetworks > cfg.MaxDistinctNetworks {
+		reasons = append(reasons, APIKeyLeakReasonDistinctNetworks)
+	}
+	if cfg.MaxDistinctCountries > 0 && signals.DistinctCountries > cfg.MaxDistinctCountries {
+		reasons = append(reasons, APIKeyLeakReasonDistinctCountries)
+	}
+	if cfg.MaxDistinctUserAgents > 0 && signals.DistinctUserAgents > cfg.MaxDistinctUserAgents {
+		reasons = append(reasons, APIKeyLeakReasonDistinctUserAgents)
+	}
+	if baselineAvailable && cfg.SpendSpikeMultiplier > 0 &&
+		signals.WindowSpend >= cfg.SpendSpikeMinUSD &&
+		signals.WindowSpend > signals.BaselineSpend*cfg.SpendSpikeMultiplier {
+		reasons = append(reasons, APIKeyLeakReasonSpendSpike)
+	}
+	if len(signals.NewHostingIPs) > 0 {
+		reasons = append(reasons, APIKeyLeakReasonNewHostingIPs)
+	}
+	return reasons
+}
+
+// describeAPIKeyLeakReasons 把触发信号渲染为邮件中的可读描述。
+func describeAPIKeyLeakReasons(locale string, reasons []string, signals APIKeyLeakSignals) string {
+	zh := normalizeNotificationLocale(locale) == notificationEmailLocaleChinese
+	parts := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		switch reason {
+		case APIKeyLeakReasonDistinctIPs:
+			parts = append(parts, pickLocale(zh, fmt.Sprintf("%d distinct source IPs", signals.DistinctIPs), fmt.Sprintf("%d 个不同来源 IP", signals.DistinctIPs)))
+		case APIKeyLeakReasonDistinctNetworks:
+			parts = append(parts, pickLocale(zh, fmt.Sprintf("%d distinct networks", signals.DistinctNetworks), fmt.Sprintf("%d 个不同网络", signals.DistinctNetworks)))
+		case APIKeyLeakReasonDistinctCountries:
+			parts = append(parts, pickLocale(zh,
+				fmt.Sprintf("%d countries (%s)", signals.DistinctCountries, strings.Join(signals.Countries, ", ")),
+				fmt.Sprintf("%d 个国家/地区（%s）", signals.DistinctCountries, strings.Join(signals.Countries, ", "))))
+		case APIKeyLeakReasonDistinctUserAgents:
+			parts = append(parts, pickLocale(zh, fmt.Sprintf("%d distinct user agents", signals.DistinctUserAgents), fmt.Sprintf("%d 种不同 User-Agent", signals.DistinctUserAgents)))
+		case APIKeyLeakReasonSpendSpike:
+			parts = append(parts, pickLocale(zh,
+				fmt.Sprintf("spend $%.2f vs. usual $%.2f", signals.WindowSpend, signals.BaselineSpend),
+				fmt.Sprintf("费用 $%.2f（平时约 $%.2f）", signals.WindowSpend, signals.BaselineSpend)))
+		case APIKeyLeakReasonNewHostingIPs:
+			parts = append(parts, pickLocale(zh,
+				"new requests from hosting/cloud ranges: "+strings.Join(signals.NewHostingIPs, ", "),
+				"新的云主机/机房来源："+strings.Join(signals.NewHostingIPs, ", ")))
+		}
+	}
+	return strings.Join(parts, pickLocale(zh, "; ", "；"))
+}
+
+// describeAPIKeyLeakOutcome 把处置结果渲染为邮件中的可读描述。
+func describeAPIKeyLeakOutcome(locale, outcome string, pinnedIPs []string) string {
+	zh := normalizeNotificationLocale(locale) == notificationEmailLocaleChinese
+	switch outcome {
+	case APIKeyLeakOutcomeAllowlistApplied:
+		return pickLocale(zh,
+			"The key now only accepts requests from: "+strings.Join(pinnedIPs, ", "),
+			"该 Key 已限制为仅接受以下 IP 的请求："+strings.Join(pinnedIPs, ", "))
+	case APIKeyLeakOutcomeAllowlistExisting:
+		return pickLocale(zh, "The key already has an IP allowlist; it was left unchanged.", "该 Key 已配置 IP 白名单，未做修改。")
+	case APIKeyLeakOutcomeDisabled:
+		return pickLocale(zh, "The key has been disabled.", "该 Key 已被禁用。")
+	default:
+		return pickLocale(zh, "No automatic action was taken.", "未自动采取处置措施。")
+	}
+}
+
+func pickLocale(zh bool, en, zhText string) string {
+	if zh {
+		return zhText
+	}
+	return en
+}
+
+func truncateStrings(values []string, limit int) []string {
+	if len(values) <= limit {
+		return append([]string(nil), values...)
+	}
+	return append([]string(nil), values[:limit]...)
+}
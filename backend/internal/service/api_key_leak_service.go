@@ -0,0 +1,441 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/google/uuid"
+)
+
+const (
+	// apiKeyLeakDetectionLeaderLockKey 保证多实例部署下只有一个实例执行检测，避免重复处置与重复邮件。
+	apiKeyLeakDetectionLeaderLockKey = "api_key:leak_detection:leader"
+	apiKeyLeakDetectionLeaderLockTTL = 5 * time.Minute
+	apiKeyLeakDetectionRunTimeout    = 2 * time.Minute
+)
+
+// APIKeyLeakDetectionStats 汇总一轮检测的结果。
+type APIKeyLeakDetectionStats struct {
+	Scanned int
+	Flagged int
+	Skipped int
+	Failed  int
+}
+
+// APIKeyLeakDetectionService 近实时监测每个用户 API Key 的来源信号（IP / 网络 / 国家 / UA 多样性、
+// 费用突增、新出现的托管机房 IP），命中阈值时按 Key 所有者的策略处置，
+// 并写入审计日志、通过通知邮件告知用户。
+type APIKeyLeakDetectionService struct {
+	repo                     APIKeyLeakRepository
+	apiKeyRepo               APIKeyRepository
+	userRepo                 UserRepository
+	authCacheInvalidator     APIKeyAuthCacheInvalidator
+	notificationEmailService *NotificationEmailService
+	auditLogService          *AuditLogService
+	cfg                      config.APIKeyLeakDetectionConfig
+	classifier               *APIKeyLeakNetworkClassifier
+	now                      func() time.Time
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	lockCache  LeaderLockCache
+	db         *sql.DB
+	instanceID string
+}
+
+func NewAPIKeyLeakDetectionService(
+	repo APIKeyLeakRepository,
+	apiKeyRepo APIKeyRepository,
+	userRepo UserRepository,
+	authCacheInvalidator APIKeyAuthCacheInvalidator,
+	notificationEmailService *NotificationEmailService,
+	auditLogService *AuditLogService,
+	cfg config.APIKeyLeakDetectionConfig,
+) *APIKeyLeakDetectionService {
+	return &APIKeyLeakDetectionService{
+		repo:                     repo,
+		apiKeyRepo:               apiKeyRepo,
+		userRepo:                 userRepo,
+		authCacheInvalidator:     authCacheInvalidator,
+		notificationEmailService: notificationEmailService,
+		auditLogService:          auditLogService,
+		cfg:                      cfg,
+		classifier:               NewAPIKeyLeakNetworkClassifier(cfg.NetworkRules),
+		now:                      time.Now,
+		stopCh:                   make(chan struct{}),
+		instanceID:               uuid.NewString(),
+	}
+}
+
+// SetLeaderLock injects the leader-lock cache and DB used to elect a single
+// instance for the detection scan. When both are nil the scan runs ungated.
+func (s *APIKeyLeakDetectionService) SetLeaderLock(lockCache LeaderLockCache, db *sql.DB) {
+	if s == nil {
+		return
+	}
+	s.lockCache = lockCache
+	s.db = db
+}
+
+// Start 立即执行一轮，之后按 interval 周期执行；未启用检测时不启动。
+func (s *APIKeyLeakDetectionService) Start() {
+	if s == nil || s.repo == nil || !s.cfg.Enabled || s.cfg.IntervalSeconds <= 0 || s.window() <= 0 {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(time.Duration(s.cfg.IntervalSeconds) * time.Second)
+		defer ticker.Stop()
+
+		s.runOnce()
+		for {
+			select {
+			case <-ticker.C:
+				s.runOnce()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (s *APIKeyLeakDetectionService) Stop() {
+	if s == nil {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+	s.wg.Wait()
+}
+
+func (s *APIKeyLeakDetectionService) runOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), apiKeyLeakDetectionRunTimeout)
+	defer cancel()
+
+	release, ok := tryAcquireSingletonLeaderLock(ctx, s.lockCache, s.db, apiKeyLeakDetectionLeaderLockKey, s.instanceID, apiKeyLeakDetectionLeaderLockTTL)
+	if !ok {
+		return
+	}
+	defer release()
+
+	stats, err := s.Detect(ctx)
+	if err != nil {
+		logger.LegacyPrintf("service.api_key_leak", "[APIKeyLeak] run failed: %v", err)
+	}
+	if stats.Flagged > 0 || stats.Failed > 0 {
+		logger.LegacyPrintf("service.api_key_leak", "[APIKeyLeak] scanned=%d flagged=%d skipped=%d failed=%d",
+			stats.Scanned, stats.Flagged, stats.Skipped, stats.Failed)
+	}
+}
+
+func (s *APIKeyLeakDetectionService) window() time.Duration {
+	return time.Duration(s.cfg.WindowMinutes) * time.Minute
+}
+
+func (s *APIKeyLeakDetectionService) baseline() time.Duration {
+	return time.Duration(s.cfg.BaselineDays) * 24 * time.Hour
+}
+
+// Detect 执行一轮检测。单个 Key 处置失败只计数，不中断整轮。
+func (s *APIKeyLeakDetectionService) Detect(ctx context.Context) (APIKeyLeakDetectionStats, error) {
+	var stats APIKeyLeakDetectionStats
+	if s == nil || s.repo == nil || s.window() <= 0 {
+		return stats, nil
+	}
+	windowEnd := s.now().UTC()
+	windowStart := windowEnd.Add(-s.window())
+	baselineStart := windowStart.Add(-s.baseline())
+
+	usages, err := s.repo.ListKeyUsageSince(ctx, windowStart, apiKeyLeakMaxSampledIPs)
+	if err != nil {
+		return stats, err
+	}
+	stats.Scanned = len(usages)
+
+	signalsByKey := make(map[int64]APIKeyLeakSignals, len(usages))
+	hostingByKey := make(map[int64][]string)
+	spendCandidates := make([]int64, 0)
+	for _, usage := range usages {
+		signals, hostingIPs := BuildAPIKeyLeakSignals(usage, s.classifier)
+		signalsByKey[usage.APIKeyID] = signals
+		if len(hostingIPs) > 0 && s.cfg.FlagNewHostingIPs {
+			hostingByKey[usage.APIKeyID] = hostingIPs
+		}
+		if s.cfg.SpendSpikeMultiplier > 0 && usage.Spend >= s.cfg.SpendSpikeMinUSD && usage.Spend > 0 {
+			spendCandidates = append(spendCandidates, usage.APIKeyID)
+		}
+	}
+
+	baselineAvailable := make(map[int64]bool, len(spendCandidates))
+	if len(spendCandidates) > 0 && s.baseline() > 0 {
+		sums, err := s.repo.SumSpendByKeys(ctx, spendCandidates, baselineStart, windowStart)
+		if err != nil {
+			return stats, err
+		}
+		// 基线折算为与窗口等长的平均费用。
+		scale := float64(s.window()) / float64(s.baseline())
+		for _, id := range spendCandidates {
+			signals := signalsByKey[id]
+			signals.BaselineSpend = sums[id] * scale
+			signalsByKey[id] = signals
+			baselineAvailable[id] = true
+		}
+	}
+
+	for id, hostingIPs := range hostingByKey {
+		known, err := s.repo.ListTopIPsByKey(ctx, id, baselineStart, windowStart, apiKeyLeakMaxSampledIPs)
+		if err != nil {
+			return stats, err
+		}
+		signals := signalsByKey[id]
+		signals.NewHostingIPs = truncateStrings(subtractStrings(hostingIPs, known), apiKeyLeakMaxReportedIPs)
+		signalsByKey[id] = signals
+	}
+
+	flagged := make([]APIKeyLeakKeyUsage, 0)
+	reasonsByKey := make(map[int64][]string)
+	for _, usage := range usages {
+		reasons := EvaluateAPIKeyLeakSignals(s.cfg, signalsByKey[usage.APIKeyID], baselineAvailable[usage.APIKeyID])
+		if len(reasons) == 0 {
+			continue
+		}
+		flagged = append(flagged, usage)
+		reasonsByKey[usage.APIKeyID] = reasons
+	}
+	if len(flagged) == 0 {
+		return stats, nil
+	}
+
+	ids := make([]int64, 0, len(flagged))
+	for _, usage := range flagged {
+		ids = append(ids, usage.APIKeyID)
+	}
+	latest, err := s.repo.LatestEventTimes(ctx, ids)
+	if err != nil {
+		return stats, err
+	}
+	cooldown := time.Duration(s.cfg.CooldownMinutes) * time.Minute
+	for _, usage := range flagged {
+		if last, ok := latest[usage.APIKeyID]; ok && windowEnd.Sub(last) < cooldown {
+			stats.Skipped++
+			continue
+		}
+		event := &APIKeyLeakEvent{
+			APIKeyID:    usage.APIKeyID,
+			UserID:      usage.UserID,
+			Reasons:     reasonsByKey[usage.APIKeyID],
+			Signals:     signalsByKey[usage.APIKeyID],
+			WindowStart: windowStart,
+			WindowEnd:   windowEnd,
+		}
+		handled, err := s.handle(ctx, event, baselineStart)
+		switch {
+		case err != nil:
+			stats.Failed++
+			logger.LegacyPrintf("service.api_key_leak", "[APIKeyLeak] handle key=%d user=%d: %v", usage.APIKeyID, usage.UserID, err)
+		case handled:
+			stats.Flagged++
+		default:
+			stats.Skipped++
+		}
+	}
+	return stats, nil
+}
+
+// handle 按用户策略处置单个可疑 Key；Key 已不可用（非 active / 已删除）时跳过。
+func (s *APIKeyLeakDetectionService) handle(ctx context.Context, event *APIKeyLeakEvent, baselineStart time.Time) (bool, error) {
+	key, err := s.apiKeyRepo.GetByID(ctx, event.APIKeyID)
+	if err != nil {
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if key.Status != StatusAPIKeyActive {
+		return false, nil
+	}
+	event.KeyName = key.Name
+	event.KeyPrefix = key.KeyPrefix
+
+	policy, err := s.GetPolicy(ctx, event.UserID)
+	if err != nil {
+		return false, err
+	}
+	event.Action = policy.Action
+
+	switch policy.Action {
+	case APIKeyLeakActionDisable:
+		if err := s.disableKey(ctx, key); err != nil {
+			return false, err
+		}
+		event.Outcome = APIKeyLeakOutcomeDisabled
+	case APIKeyLeakActionRequireAllowlist:
+		if len(key.IPWhitelist) > 0 {
+			event.Outcome = APIKeyLeakOutcomeAllowlistExisting
+			break
+		}
+		// 以基线期（泄露前）的常用 IP 作为白名单；没有基线 IP 时无法判断哪些来源可信，只能禁用。
+		pinned, err := s.repo.ListTopIPsByKey(ctx, key.ID, baselineStart, event.WindowStart, apiKeyLeakMaxPinnedIPs)
+		if err != nil {
+			return false, err
+		}
+		if len(pinned) == 0 {
+			if err := s.disableKey(ctx, key); err != nil {
+				return false, err
+			}
+			event.Outcome = APIKeyLeakOutcomeDisabled
+			break
+		}
+		key.IPWhitelist = pinned
+		if err := s.apiKeyRepo.Update(ctx, key, APIKeyUpdateFields{IPRules: true}); err != nil {
+			return false, err
+		}
+		s.invalidateKey(ctx, key)
+		event.PinnedIPs = pinned
+		event.Outcome = APIKeyLeakOutcomeAllowlistApplied
+	default:
+		event.Outcome = APIKeyLeakOutcomeNotified
+	}
+
+	if err := s.repo.InsertEvent(ctx, event); err != nil {
+		return false, err
+	}
+	s.recordAudit(event)
+	s.notify(ctx, event)
+	return true, nil
+}
+
+func (s *APIKeyLeakDetectionService) disableKey(ctx context.Context, key *APIKey) error {
+	key.Status = StatusAPIKeyDisabled
+	if err := s.apiKeyRepo.Update(ctx, key, APIKeyUpdateFields{Status: true}); err != nil {
+		return err
+	}
+	s.invalidateKey(ctx, key)
+	return nil
+}
+
+func (s *APIKeyLeakDetectionService) invalidateKey(ctx context.Context, key *APIKey) {
+	if s.authCacheInvalidator != nil {
+		s.authCacheInvalidator.InvalidateAuthCacheByKey(ctx, key.Key)
+	}
+}
+
+func (s *APIKeyLeakDetectionService) recordAudit(event *APIKeyLeakEvent) {
+	if s.auditLogService == nil {
+		return
+	}
+	s.auditLogService.Record(&AuditLog{
+		ActorRole:  AuditAuthMethodSystem,
+		AuthMethod: AuditAuthMethodSystem,
+		Action:     AuditActionAPIKeyLeakDetected,
+		Extra: map[string]any{
+			"user_id":       event.UserID,
+			"api_key_id":    event.APIKeyID,
+			"key_prefix":    event.KeyPrefix,
+			"reasons":       event.Reasons,
+			"signals":       event.Signals,
+			"action":        event.Action,
+			"outcome":       event.Outcome,
+			"pinned_ips":    event.PinnedIPs,
+			"leak_event_id": event.ID,
+			"window_start":  event.WindowStart,
+			"window_end":    event.WindowEnd,
+		},
+	})
+}
+
+func (s *APIKeyLeakDetectionService) notify(ctx context.Context, event *APIKeyLeakEvent) {
+	if s.notificationEmailService == nil || s.userRepo == nil {
+		return
+	}
+	user, err := s.userRepo.GetByID(ctx, event.UserID)
+	if err != nil || user == nil || strings.TrimSpace(user.Email) == "" {
+		return
+	}
+	locale := s.notificationEmailService.ResolveRecipientLocale(ctx, user.ID, user.Email)
+	if err := s.notificationEmailService.Send(ctx, NotificationEmailSendInput{
+		Event:          NotificationEmailEventAPIKeyLeakSuspected,
+		Locale:         locale,
+		RecipientEmail: user.Email,
+		RecipientName:  firstNonEmpty(user.Username, user.Email),
+		UserID:         user.ID,
+		SourceType:     "api_key_leak_event",
+		SourceID:       strconv.FormatInt(event.ID, 10),
+		Variables: map[string]string{
+			"api_key_name":    event.KeyName,
+			"api_key_prefix":  event.KeyPrefix,
+			"triggered_at":    event.WindowEnd.Format("2006-01-02 15:04:05"),
+			"leak_signals":    describeAPIKeyLeakReasons(locale, event.Reasons, event.Signals),
+			"leak_sample_ips": strings.Join(event.Signals.SampleIPs, ", "),
+			"leak_action":     describeAPIKeyLeakOutcome(locale, event.Outcome, event.PinnedIPs),
+		},
+	}); err != nil {
+		logger.LegacyPrintf("service.api_key_leak", "[APIKeyLeak] send notice failed: event=%d user=%d err=%v", event.ID, event.UserID, err)
+	}
+}
+
+// GetPolicy 返回用户的处置策略；未设置时返回系统默认策略。
+func (s *APIKeyLeakDetectionService) GetPolicy(ctx context.Context, userID int64) (*APIKeyLeakPolicy, error) {
+	policy, err := s.repo.GetPolicy(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if policy != nil {
+		if action := NormalizeAPIKeyLeakAction(policy.Action); action != "" {
+			policy.Action = action
+			return policy, nil
+		}
+	}
+	return &APIKeyLeakPolicy{UserID: userID, Action: s.defaultAction(), IsDefault: true}, nil
+}
+
+// SetPolicy 设置用户的处置策略。
+func (s *APIKeyLeakDetectionService) SetPolicy(ctx context.Context, userID int64, action string) (*APIKeyLeakPolicy, error) {
+	normalized := NormalizeAPIKeyLeakAction(action)
+	if normalized == "" {
+		return nil, ErrAPIKeyLeakInvalidAction
+	}
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.UpsertPolicy(ctx, userID, normalized)
+}
+
+// ListEvents 分页查询泄露检测事件（最新在前）。
+func (s *APIKeyLeakDetectionService) ListEvents(ctx context.Context, filter APIKeyLeakEventFilter, params pagination.PaginationParams) ([]APIKeyLeakEvent, *pagination.PaginationResult, error) {
+	return s.repo.ListEvents(ctx, filter, params)
+}
+
+func (s *APIKeyLeakDetectionService) defaultAction() string {
+	if action := NormalizeAPIKeyLeakAction(s.cfg.DefaultAction); action != "" {
+		return action
+	}
+	return APIKeyLeakActionNotify
+}
+
+// subtractStrings 返回 values 中不在 exclude 里的元素，保持原顺序。
+func subtractStrings(values, exclude []string) []string {
+	excluded := make(map[string]struct{}, len(exclude))
+	for _, v := range exclude {
+		excluded[v] = struct{}{}
+	}
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if _, ok := excluded[v]; !ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/stretchr/testify/require"
+)
+
+type apiKeyLeakRepoStub struct {
+	usages      []APIKeyLeakKeyUsage
+	baseline    map[int64]float64
+	topIPs      map[int64][]string
+	latest      map[int64]time.Time
+	policies    map[int64]*APIKeyLeakPolicy
+	events      []APIKeyLeakEvent
+	spendLookup []int64
+}
+
+func (r *apiKeyLeakRepoStub) ListKeyUsageSince(context.Context, time.Time, int) ([]APIKeyLeakKeyUsage, error) {
+	return r.usages, nil
+}
+
+func (r *apiKeyLeakRepoStub) SumSpendByKeys(_ context.Context, ids []int64, _, _ time.Time) (map[int64]float64, error) {
+	r.spendLookup = append(r.spendLookup, ids...)
+	return r.baseline, nil
+}
+
+func (r *apiKeyLeakRepoStub) ListTopIPsByKey(_ context.Context, id int64, _, _ time.Time, limit int) ([]string, error) {
+	return truncateStrings(r.topIPs[id], limit), nil
+}
+
+func (r *apiKeyLeakRepoStub) LatestEventTimes(context.Context, []int64) (map[int64]time.Time, error) {
+	return r.latest, nil
+}
+
+func (r *apiKeyLeakRepoStub) InsertEvent(_ context.Context, event *APIKeyLeakEvent) error {
+	event.ID = int64(len(r.events) + 1)
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func (r *apiKeyLeakRepoStub) ListEvents(context.Context, APIKeyLeakEventFilter, pagination.PaginationParams) ([]APIKeyLeakEvent, *pagination.PaginationResult, error) {
+	return r.events, &pagination.PaginationResult{Total: int64(len(r.events))}, nil
+}
+
+func (r *apiKeyLeakRepoStub) GetPolicy(_ context.Context, userID int64) (*APIKeyLeakPolicy, error) {
+	return r.policies[userID], nil
+}
+
+func (r *apiKeyLeakRepoStub) UpsertPolicy(_ context.Context, userID int64, action string) (*APIKeyLeakPolicy, error) {
+	if r.policies == nil {
+		r.policies = map[int64]*APIKeyLeakPolicy{}
+	}
+	r.policies[userID] = &APIKeyLeakPolicy{UserID: userID, Action: action}
+	return r.policies[userID], nil
+}
+
+type apiKeyLeakKeyRepoStub struct {
+	APIKeyRepository
+	keys    map[int64]*APIKey
+	updates []APIKeyUpdateFields
+}
+
+func (r *apiKeyLeakKeyRepoStub) GetByID(_ context.Context, id int64) (*APIKey, error) {
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	clone := *key
+	return &clone, nil
+}
+
+func (r *apiKeyLeakKeyRepoStub) Update(_ context.Context, key *APIKey, fields APIKeyUpdateFields) error {
+	r.updates = append(r.updates, fields)
+	stored := r.keys[key.ID]
+	if fields.Status {
+		stored.Status = key.Status
+	}
+	if fields.IPRules {
+		stored.IPWhitelist = key.IPWhitelist
+		stored.IPBlacklist = key.IPBlacklist
+	}
+	return nil
+}
+
+type apiKeyLeakUserRepoStub struct {
+	UserRepository
+}
+
+func (apiKeyLeakUserRepoStub) GetByID(_ context.Context, id int64) (*User, error) {
+	return &User{ID: id, Email: "owner@example.com"}, nil
+}
+
+type apiKeyLeakInvalidatorStub struct {
+	keys []string
+}
+
+func (s *apiKeyLeakInvalidatorStub) InvalidateAuthCacheByKey(_ context.Context, key string) {
+	s.keys = append(s.keys, key)
+}
+func (s *apiKeyLeakInvalidatorStub) InvalidateAuthCacheByUserID(context.Context, int64)  {}
+func (s *apiKeyLeakInvalidatorStub) InvalidateAuthCacheByGroupID(context.Context, int64) {}
+
+func apiKeyLeakTestConfig() config.APIKeyLeakDetectionConfig {
+	return config.APIKeyLeakDetectionConfig{
+		Enabled:               true,
+		IntervalSeconds:       60,
+		WindowMinutes:         60,
+		BaselineDays:          7,
+		DefaultAction:         APIKeyLeakActionNotify,
+		CooldownMinutes:       360,
+		MaxDistinctIPs:        5,
+		MaxDistinctNetworks:   3,
+		MaxDistinctCountries:  2,
+		MaxDistinctUserAgents: 4,
+		SpendSpikeMultiplier:  5,
+		SpendSpikeMinUSD:      10,
+		FlagNewHostingIPs:     true,
+		NetworkRules: []config.APIKeyLeakNetworkRule{
+			{CIDR: "203.0.113.0/24", ASN: "as64500", Country: "us", Hosting: true},
+			{CIDR: "198.51.100.0/24", ASN: "AS64501", Country: "DE"},
+			{CIDR: "192.0.2.0/24", Country: "SG"},
+		},
+	}
+}
+
+func newAPIKeyLeakTestService(repo *apiKeyLeakRepoStub, keys *apiKeyLeakKeyRepoStub, invalidator *apiKeyLeakInvalidatorStub) *APIKeyLeakDetectionService {
+	svc := NewAPIKeyLeakDetectionService(repo, keys, apiKeyLeakUserRepoStub{}, invalidator, nil, nil, apiKeyLeakTestConfig())
+	svc.now = func() time.Time { return time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC) }
+	return svc
+}
+
+func TestAPIKeyLeakNetworkClassifier(t *testing.T) {
+	classifier := NewAPIKeyLeakNetworkClassifier(apiKeyLeakTestConfig().NetworkRules)
+
+	cases := []struct {
+		ip   string
+		want APIKeyLeakIPClass
+	}{
+		{ip: "203.0.113.9", want: APIKeyLeakIPClass{Network: "AS64500", Country: "US", Hosting: true}},
+		{ip: "198.51.100.1", want: APIKeyLeakIPClass{Network: "AS64501", Country: "DE"}},
+		{ip: "192.0.2.77", want: APIKeyLeakIPClass{Network: "192.0.2.0/24", Country: "SG"}},
+		{ip: "10.1.2.3", want: APIKeyLeakIPClass{Network: "10.1.2.0/24"}},
+		{ip: "2001:db8:1:2::1", want: APIKeyLeakIPClass{Network: "2001:db8:1::/48"}},
+	}
+	for _, tc := range cases {
+		got, ok := classifier.Classify(tc.ip)
+		require.True(t, ok, tc.ip)
+		require.Equal(t, tc.want, got, tc.ip)
+	}
+	_, ok := classifier.Classify("not-an-ip")
+	require.False(t, ok)
+}
+
+func TestEvaluateAPIKeyLeakSignals(t *testing.T) {
+	cfg := apiKeyLeakTestConfig()
+	classifier := NewAPIKeyLeakNetworkClassifier(cfg.NetworkRules)
+
+	quiet, _ := BuildAPIKeyLeakSignals(APIKeyLeakKeyUsage{DistinctIPs: 2, DistinctUserAgents: 1, Spend: 3, IPs: []string{"10.0.0.1", "10.0.0.2"}}, classifier)
+	require.Empty(t, EvaluateAPIKeyLeakSignals(cfg, quiet, false))
+
+	noisy, hosting := BuildAPIKeyLeakSignals(APIKeyLeakKeyUsage{
+		DistinctIPs:        6,
+		DistinctUserAgents: 5,
+		Spend:              60,
+		IPs:                []string{"203.0.113.1", "198.51.100.1", "192.0.2.1", "10.0.0.1", "10.0.1.1", "10.0.2.1"},
+	}, classifier)
+	require.Equal(t, []string{"203.0.113.1"}, hosting)
+	require.Equal(t, 6, noisy.DistinctNetworks)
+	require.Equal(t, []string{"DE", "SG", "US"}, noisy.Countries)
+	noisy.BaselineSpend = 10
+	noisy.NewHostingIPs = hosting
+
+	require.Equal(t, []string{
+		APIKeyLeakReasonDistinctIPs,
+		APIKeyLeakReasonDistinctNetworks,
+		APIKeyLeakReasonDistinctCountries,
+		APIKeyLeakReasonDistinctUserAgents,
+		APIKeyLeakReasonSpendSpike,
+		APIKeyLeakReasonNewHostingIPs,
+	}, EvaluateAPIKeyLeakSignals(cfg, noisy, true))
+
+	// 窗口费用未超过基线倍数时不判定突增；未计算基线时同样不判定。
+	noisy.BaselineSpend = 20
+	require.NotContains(t, EvaluateAPIKeyLeakSignals(cfg, noisy, true), APIKeyLeakReasonSpendSpike)
+	noisy.BaselineSpend = 0
+	require.NotContains(t, EvaluateAPIKeyLeakSignals(cfg, noisy, false), APIKeyLeakReasonSpendSpike)
+}
+
+func TestAPIKeyLeakDetectAppliesUserPolicy(t *testing.T) {
+	manyIPs := []string{"10.0.0.1", "10.0.1.1", "10.0.2.1", "10.0.3.1", "10.0.4.1", "10.0.5.1"}
+	repo := &apiKeyLeakRepoStub{
+		usages: []APIKeyLeakKeyUsage{
+			{APIKeyID: 1, UserID: 10, DistinctIPs: 6, IPs: manyIPs},
+			{APIKeyID: 2, UserID: 20, DistinctIPs: 6, IPs: manyIPs},
+			{APIKeyID: 3, UserID: 30, DistinctIPs: 6, IPs: manyIPs},
+			{APIKeyID: 4, UserID: 40, DistinctIPs: 6, IPs: manyIPs},
+			{APIKeyID: 5, UserID: 50, DistinctIPs: 1, IPs: []string{"10.0.0.1"}},
+		},
+		topIPs: map[int64][]string{2: {"10.9.9.9", "10.9.9.8"}},
+		policies: map[int64]*APIKeyLeakPolicy{
+			20: {UserID: 20, Action: APIKeyLeakActionRequireAllowlist},
+			30: {UserID: 30, Action: APIKeyLeakActionRequireAllowlist},
+			40: {UserID: 40, Action: APIKeyLeakActionDisable},
+		},
+	}
+	keys := &apiKeyLeakKeyRepoStub{keys: map[int64]*APIKey{
+		1: {ID: 1, UserID: 10, Key: "hmac-sha256:k1", KeyPrefix: "sk-k1", Status: StatusAPIKeyActive},
+		2: {ID: 2, UserID: 20, Key: "hmac-sha256:k2", Status: StatusAPIKeyActive},
+		3: {ID: 3, UserID: 30, Key: "hmac-sha256:k3", Status: StatusAPIKeyActive},
+		4: {ID: 4, UserID: 40, Key: "hmac-sha256:k4", Status: StatusAPIKeyActive},
+		5: {ID: 5, UserID: 50, Key: "hmac-sha256:k5", Status: StatusAPIKeyActive},
+	}}
+	invalidator := &apiKeyLeakInvalidatorStub{}
+	svc := newAPIKeyLeakTestService(repo, keys, invalidator)
+
+	stats, err := svc.Detect(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, APIKeyLeakDetectionStats{Scanned: 5, Flagged: 4}, stats)
+	require.Len(t, repo.events, 4)
+
+	outcomes := map[int64]APIKeyLeakEvent{}
+	for _, event := range repo.events {
+		outcomes[event.APIKeyID] = event
+	}
+	require.Equal(t, APIKeyLeakActionNotify, outcomes[1].Action)
+	require.Equal(t, APIKeyLeakOutcomeNotified, outcomes[1].Outcome)
+	require.Equal(t, "sk-k1", outcomes[1].KeyPrefix)
+	require.Equal(t, []string{APIKeyLeakReasonDistinctIPs, APIKeyLeakReasonDistinctNetworks}, outcomes[1].Reasons)
+
+	// require_allowlist：用基线期常用 IP 收紧白名单。
+	require.Equal(t, APIKeyLeakOutcomeAllowlistApplied, outcomes[2].Outcome)
+	require.Equal(t, []string{"10.9.9.9", "10.9.9.8"}, outcomes[2].PinnedIPs)
+	require.Equal(t, []string{"10.9.9.9", "10.9.9.8"}, keys.keys[2].IPWhitelist)
+	require.Equal(t, StatusAPIKeyActive, keys.keys[2].Status)
+
+	// require_allowlist 但没有基线 IP：退化为禁用。
+	require.Equal(t, APIKeyLeakOutcomeDisabled, outcomes[3].Outcome)
+	require.Equal(t, StatusAPIKeyDisabled, keys.keys[3].Status)
+
+	require.Equal(t, APIKeyLeakOutcomeDisabled, outcomes[4].Outcome)
+	require.Equal(t, StatusAPIKeyDisabled, keys.keys[4].Status)
+
+	require.ElementsMatch(t, []string{"hmac-sha256:k2", "hmac-sha256:k3", "hmac-sha256:k4"}, invalidator.keys)
+	require.Equal(t, StatusAPIKeyActive, keys.keys[1].Status)
+	require.Equal(t, StatusAPIKeyActive, keys.keys[5].Status)
+}
+
+func TestAPIKeyLeakDetectRespectsCooldownAndExistingAllowlist(t *testing.T) {
+	manyIPs := []string{"10.0.0.1", "10.0.1.1", "10.0.2.1", "10.0.3.1", "10.0.4.1", "10.0.5.1"}
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	repo := &apiKeyLeakRepoStub{
+		usages: []APIKeyLeakKeyUsage{
+			{APIKeyID: 1, UserID: 10, DistinctIPs: 6, IPs: manyIPs},
+			{APIKeyID: 2, UserID: 20, DistinctIPs: 6, IPs: manyIPs},
+			{APIKeyID: 3, UserID: 30, DistinctIPs: 6, IPs: manyIPs},
+		},
+		latest:   map[int64]time.Time{1: now.Add(-time.Hour)},
+		policies: map[int64]*APIKeyLeakPolicy{20: {UserID: 20, Action: APIKeyLeakActionRequireAllowlist}},
+	}
+	keys := &apiKeyLeakKeyRepoStub{keys: map[int64]*APIKey{
+		1: {ID: 1, UserID: 10, Status: StatusAPIKeyActive},
+		2: {ID: 2, UserID: 20, Status: StatusAPIKeyActive, IPWhitelist: []string{"10.0.0.0/16"}},
+		3: {ID: 3, UserID: 30, Status: StatusAPIKeyDisabled},
+	}}
+	svc := newAPIKeyLeakTestService(repo, keys, &apiKeyLeakInvalidatorStub{})
+
+	stats, err := svc.Detect(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, APIKeyLeakDetectionStats{Scanned: 3, Flagged: 1, Skipped: 2}, stats)
+	require.Len(t, repo.events, 1)
+	require.Equal(t, int64(2), repo.events[0].APIKeyID)
+	require.Equal(t, APIKeyLeakOutcomeAllowlistExisting, repo.events[0].Outcome)
+	require.Empty(t, keys.updates)
+}
+
+func TestAPIKeyLeakDetectSpendSpikeAndNewHostingIPs(t *testing.T) {
+	repo := &apiKeyLeakRepoStub{
+		usages: []APIKeyLeakKeyUsage{
+			{APIKeyID: 1, UserID: 10, DistinctIPs: 1, Spend: 50, IPs: []string{"10.0.0.1"}},
+			{APIKeyID: 2, UserID: 20, DistinctIPs: 1, Spend: 2, IPs: []string{"10.0.0.1"}},
+			{APIKeyID: 3, UserID: 30, DistinctIPs: 2, IPs: []string{"203.0.113.5", "203.0.113.6"}},
+		},
+		// 7 天基线 168 美元 → 每小时约 1 美元，50 美元远超 5 倍。
+		baseline: map[int64]float64{1: 168},
+		topIPs:   map[int64][]string{3: {"203.0.113.5"}},
+	}
+	keys := &apiKeyLeakKeyRepoStub{keys: map[int64]*APIKey{
+		1: {ID: 1, UserID: 10, Status: StatusAPIKeyActive},
+		2: {ID: 2, UserID: 20, Status: StatusAPIKeyActive},
+		3: {ID: 3, UserID: 30, Status: StatusAPIKeyActive},
+	}}
+	svc := newAPIKeyLeakTestService(repo, keys, &apiKeyLeakInvalidatorStub{})
+
+	stats, err := svc.Detect(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, stats.Flagged)
+	require.Equal(t, []int64{1}, repo.spendLookup, "keys below spend_spike_min_usd must not trigger a baseline lookup")
+
+	require.Len(t, repo.events, 2)
+	require.Equal(t, []string{APIKeyLeakReasonSpendSpike}, repo.events[0].Reasons)
+	require.InDelta(t, 1.0, repo.events[0].Signals.BaselineSpend, 1e-9)
+	require.Equal(t, []string{APIKeyLeakReasonNewHostingIPs}, repo.events[1].Reasons)
+	require.Equal(t, []string{"203.0.113.6"}, repo.events[1].Signals.NewHostingIPs)
+}
+
+func TestAPIKeyLeakPolicyDefaultsAndValidation(t *testing.T) {
+	repo := &apiKeyLeakRepoStub{}
+	svc := newAPIKeyLeakTestService(repo, &apiKeyLeakKeyRepoStub{}, &apiKeyLeakInvalidatorStub{})
+
+	policy, err := svc.GetPolicy(context.Background(), 7)
+	require.NoError(t, err)
+	require.True(t, policy.IsDefault)
+	require.Equal(t, APIKeyLeakActionNotify, policy.Action)
+
+	_, err = svc.SetPolicy(context.Background(), 7, "quarantine")
+	require.ErrorIs(t, err, ErrAPIKeyLeakInvalidAction)
+
+	policy, err = svc.SetPolicy(context.Background(), 7, " Disable ")
+	require.NoError(t, err)
+	require.Equal(t, APIKeyLeakActionDisable, policy.Action)
+
+	policy, err = svc.GetPolicy(context.Background(), 7)
+	require.NoError(t, err)
+	require.False(t, policy.IsDefault)
+	require.Equal(t, APIKeyLeakActionDisable, policy.Action)
+}
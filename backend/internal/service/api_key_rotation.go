@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+)
+
+var (
+	ErrAPIKeyAlreadyRotated            = infraerrors.Conflict("API_KEY_ALREADY_ROTATED", "api key has already been rotated")
+	ErrAPIKeyRotationNotAllowed        = infraerrors.BadRequest("API_KEY_ROTATION_NOT_ALLOWED", "disabled or expired api keys cannot be rotated")
+	ErrAPIKeyRotationGraceInvalid      = infraerrors.BadRequest("API_KEY_ROTATION_GRACE_INVALID", "grace_hours is out of range")
+	ErrAPIKeyRotationIntervalInvalid   = infraerrors.BadRequest("API_KEY_ROTATION_INTERVAL_INVALID", "interval_days is out of range")
+	ErrAPIKeyRotationSecretUnavailable = infraerrors.NotFound("API_KEY_ROTATION_SECRET_UNAVAILABLE", "no unrevealed key is pending for this api key")
+)
+
+// APIKeyRotationState 单个 Key 的轮换状态；未参与过轮换的 Key 没有记录，谱系 ID 即自身 ID。
+type APIKeyRotationState struct {
+	APIKeyID       int64      `json:"api_key_id"`
+	UserID         int64      `json:"-"`
+	LineageID      int64      `json:"lineage_id"`
+	PredecessorID  *int64     `json:"predecessor_id,omitempty"`
+	SuccessorID    *int64     `json:"successor_id,omitempty"`
+	RotatedAt      *time.Time `json:"rotated_at,omitempty"`
+	GraceExpiresAt *time.Time `json:"grace_expires_at,omitempty"`
+
+	ScheduleIntervalDays int        `json:"schedule_interval_days"`
+	ScheduleGraceHours   *int       `json:"schedule_grace_hours,omitempty"`
+	NextRotationAt       *time.Time `json:"next_rotation_at,omitempty"`
+	HasPendingSecret     bool       `json:"has_pending_secret"`
+}
+
+// APIKeyRotateInput 一次轮换的参数。
+type APIKeyRotateInput struct {
+	APIKeyID int64
+	UserID   int64
+	// NewKey 新 Key 明文，由仓储按存储形态（哈希）落库。
+	NewKey string
+	// GraceExpiresAt 旧 Key 的宽限截止；旧 Key 原本更早过期时以原过期时间为准。
+	GraceExpiresAt time.Time
+	// PendingSecret 定期轮换生成的新 Key 密文，待用户一次性查看；手动轮换为空。
+	PendingSecret string
+	Now           time.Time
+}
+
+// APIKeyRotationResult 轮换结果。Successor.Key 由服务层回填为明文，仅在本次响应中返回。
+type APIKeyRotationResult struct {
+	Predecessor    *APIKey   `json:"-"`
+	Successor      *APIKey   `json:"-"`
+	LineageID      int64     `json:"lineage_id"`
+	GraceExpiresAt time.Time `json:"grace_expires_at"`
+}
+
+// APIKeyLineageMember 谱系中的一个 Key 及其在统计区间内的用量。
+type APIKeyLineageMember struct {
+	APIKeyID       int64      `json:"api_key_id"`
+	Name           string     `json:"name"`
+	KeyPrefix      string     `json:"key_prefix"`
+	Status         string     `json:"status"`
+	Deleted        bool       `json:"deleted"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	PredecessorID  *int64     `json:"predecessor_id,omitempty"`
+	SuccessorID    *int64     `json:"successor_id,omitempty"`
+	RotatedAt      *time.Time `json:"rotated_at,omitempty"`
+	GraceExpiresAt *time.Time `json:"grace_expires_at,omitempty"`
+
+	Requests    int64   `json:"requests"`
+	TotalTokens int64   `json:"total_tokens"`
+	Cost        float64 `json:"cost"`
+	ActualCost  float64 `json:"actual_cost"`
+}
+
+// APIKeyLineage 一个逻辑 Key（跨多次轮换）的成员与合计用量。
+type APIKeyLineage struct {
+	LineageID   int64                 `json:"lineage_id"`
+	CurrentID   int64                 `json:"current_api_key_id"`
+	Since       time.Time             `json:"since"`
+	Members     []APIKeyLineageMember `json:"members"`
+	Requests    int64                 `json:"requests"`
+	TotalTokens int64                 `json:"total_tokens"`
+	Cost        float64               `json:"cost"`
+	ActualCost  float64               `json:"actual_cost"`
+}
+
+// APIKeyRotationRepository 轮换状态与谱系存储。
+type APIKeyRotationRepository interface {
+	// RotateKey 在一个事务内锁定旧 Key、创建继承其分组/额度/限流/IP 规则/用量计数的新 Key、
+	// 写入谱系并把旧 Key 的过期时间收紧到宽限截止。旧 Key 不存在或不属于该用户时返回 ErrAPIKeyNotFound。
+	RotateKey(ctx context.Context, in APIKeyRotateInput) (*APIKeyRotationResult, error)
+	// GetState 返回 Key 的轮换状态；没有记录时返回 nil。
+	GetState(ctx context.Context, apiKeyID int64) (*APIKeyRotationState, error)
+	// ListLineage 返回谱系中的全部 Key（含已删除），附带 since 之后的用量。
+	ListLineage(ctx context.Context, lineageID int64, since time.Time) ([]APIKeyLineageMember, error)
+	// UpsertSchedule 设置定期轮换；intervalDays 为 0 表示关闭。
+	UpsertSchedule(ctx context.Context, apiKeyID int64, intervalDays int, graceHours *int, nextRotationAt *time.Time) (*APIKeyRotationState, error)
+	// ListDueSchedules 返回到期待轮换且仍为 active、未被取代的 Key。
+	ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]APIKeyRotationState, error)
+	// ListGraceDeadlines 返回仍处于宽限期内的旧 Key 及其截止时间。
+	ListGraceDeadlines(ctx context.Context, now time.Time) (map[int64]time.Time, error)
+	// TakePendingSecret 取出并清除待查看的新 Key 密文；没有时返回空串。
+	TakePendingSecret(ctx context.Context, apiKeyID, userID int64) (string, error)
+}
+
+// APIKeyRotationGraceLookup 供认证中间件判断 Key 是否处于轮换宽限期。
+type APIKeyRotationGraceLookup interface {
+	GraceDeadline(apiKeyID int64) (time.Time, bool)
+}
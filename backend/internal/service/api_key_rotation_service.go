@@ -0,0 +1,487 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/google/uuid"
+)
+
+const (
+	// apiKeyRotationLeaderLockKey 保证多实例部署下只有一个实例执行定期轮换，避免同一 Key 被重复轮换。
+	apiKeyRotationLeaderLockKey = "api_key:rotation:leader"
+	apiKeyRotationLeaderLockTTL = 5 * time.Minute
+	apiKeyRotationRunTimeout    = 2 * time.Minute
+	apiKeyRotationBatchSize     = 100
+	// apiKeyRotationGraceRefreshInterval 宽限期索引的刷新间隔；其它实例发起的轮换最多延迟这么久才会带上告警头。
+	apiKeyRotationGraceRefreshInterval = 30 * time.Second
+
+	defaultAPIKeyRotationGraceHours     = 24
+	defaultAPIKeyRotationMaxGraceHours  = 720
+	defaultAPIKeyRotationMinIntervalDay = 1
+	defaultAPIKeyRotationMaxIntervalDay = 365
+	defaultAPIKeyLineageUsageDays       = 30
+)
+
+// RotateAPIKeyRequest 轮换请求；GraceHours 为空时使用配置的默认宽限期。
+type RotateAPIKeyRequest struct {
+	GraceHours *int `json:"grace_hours"`
+}
+
+// UpdateAPIKeyRotationScheduleRequest 设置定期轮换；IntervalDays 为 0 表示关闭。
+type UpdateAPIKeyRotationScheduleRequest struct {
+	IntervalDays int  `json:"interval_days"`
+	GraceHours   *int `json:"grace_hours"`
+}
+
+// APIKeyRotationStats 汇总一轮定期轮换的结果。
+type APIKeyRotationStats struct {
+	Due     int
+	Rotated int
+	Failed  int
+}
+
+// apiKeyRotationKeys 轮换所需的 APIKeyService 能力，便于测试替换。
+type apiKeyRotationKeys interface {
+	GenerateKey() (string, error)
+	GetByID(ctx context.Context, id int64) (*APIKey, error)
+	InvalidateAuthCacheByKey(ctx context.Context, key string)
+}
+
+// APIKeyRotationService 用户 API Key 轮换：生成继承原 Key 配置与用量的新 Key，
+// 旧 Key 在宽限期内继续可用（认证中间件附加告警响应头），到期后自动过期；
+// 同一谱系的 Key 在用量报表中视为一个逻辑 Key，并支持按周期自动轮换。
+type APIKeyRotationService struct {
+	repo                     APIKeyRotationRepository
+	keys                     apiKeyRotationKeys
+	userRepo                 UserRepository
+	encryptor                SecretEncryptor
+	notificationEmailService *NotificationEmailService
+	auditLogService          *AuditLogService
+	cfg                      config.APIKeyRotationConfig
+	now                      func() time.Time
+
+	graceMu sync.RWMutex
+	grace   map[int64]time.Time
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	lockCache  LeaderLockCache
+	db         *sql.DB
+	instanceID string
+}
+
+func NewAPIKeyRotationService(
+	repo APIKeyRotationRepository,
+	apiKeyService *APIKeyService,
+	userRepo UserRepository,
+	encryptor SecretEncryptor,
+	notificationEmailService *NotificationEmailService,
+	auditLogService *AuditLogService,
+	cfg config.APIKeyRotationConfig,
+) *APIKeyRotationService {
+	svc := &APIKeyRotationService{
+		repo:                     repo,
+		userRepo:                 userRepo,
+		encryptor:                encryptor,
+		notificationEmailService: notificationEmailService,
+		auditLogService:          auditLogService,
+		cfg:                      cfg,
+		now:                      time.Now,
+		grace:                    make(map[int64]time.Time),
+		stopCh:                   make(chan struct{}),
+		instanceID:               uuid.NewString(),
+	}
+	if apiKeyService != nil {
+		svc.keys = apiKeyService
+	}
+	return svc
+}
+
+// SetLeaderLock injects the leader-lock cache and DB used to elect a single
+// instance for scheduled rotations. When both are nil the run is ungated.
+func (s *APIKeyRotationService) SetLeaderLock(lockCache LeaderLockCache, db *sql.DB) {
+	if s == nil {
+		return
+	}
+	s.lockCache = lockCache
+	s.db = db
+}
+
+// Start 每个实例都周期刷新宽限期索引；定期轮换按 CheckIntervalSeconds 执行，为 0 时不执行。
+func (s *APIKeyRotationService) Start() {
+	if s == nil || s.repo == nil {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		graceTicker := time.NewTicker(apiKeyRotationGraceRefreshInterval)
+		defer graceTicker.Stop()
+
+		var scheduleC <-chan time.Time
+		if s.cfg.CheckIntervalSeconds > 0 {
+			scheduleTicker := time.NewTicker(time.Duration(s.cfg.CheckIntervalSeconds) * time.Second)
+			defer scheduleTicker.Stop()
+			scheduleC = scheduleTicker.C
+		}
+
+		s.refreshGrace()
+		if scheduleC != nil {
+			s.runOnce()
+		}
+		for {
+			select {
+			case <-graceTicker.C:
+				s.refreshGrace()
+			case <-scheduleC:
+				s.runOnce()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (s *APIKeyRotationService) Stop() {
+	if s == nil {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+	s.wg.Wait()
+}
+
+func (s *APIKeyRotationService) runOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), apiKeyRotationRunTimeout)
+	defer cancel()
+
+	release, ok := tryAcquireSingletonLeaderLock(ctx, s.lockCache, s.db, apiKeyRotationLeaderLockKey, s.instanceID, apiKeyRotationLeaderLockTTL)
+	if !ok {
+		return
+	}
+	defer release()
+
+	stats, err := s.RunScheduled(ctx)
+	if err != nil {
+		logger.LegacyPrintf("service.api_key_rotation", "[APIKeyRotation] scheduled run failed: %v", err)
+	}
+	if stats.Due > 0 {
+		logger.LegacyPrintf("service.api_key_rotation", "[APIKeyRotation] due=%d rotated=%d failed=%d", stats.Due, stats.Rotated, stats.Failed)
+	}
+}
+
+func (s *APIKeyRotationService) refreshGrace() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	deadlines, err := s.repo.ListGraceDeadlines(ctx, s.now())
+	if err != nil {
+		logger.LegacyPrintf("service.api_key_rotation", "[APIKeyRotation] refresh grace index failed: %v", err)
+		return
+	}
+	s.graceMu.Lock()
+	s.grace = deadlines
+	s.graceMu.Unlock()
+}
+
+// GraceDeadline 报告 Key 是否为已被轮换、仍处于宽限期的旧 Key，并返回其截止时间。
+func (s *APIKeyRotationService) GraceDeadline(apiKeyID int64) (time.Time, bool) {
+	if s == nil {
+		return time.Time{}, false
+	}
+	s.graceMu.RLock()
+	deadline, ok := s.grace[apiKeyID]
+	s.graceMu.RUnlock()
+	if !ok || !deadline.After(s.now()) {
+		return time.Time{}, false
+	}
+	return deadline, true
+}
+
+func (s *APIKeyRotationService) markGrace(apiKeyID int64, deadline time.Time) {
+	s.graceMu.Lock()
+	s.grace[apiKeyID] = deadline
+	s.graceMu.Unlock()
+}
+
+// Rotate 手动轮换用户的 API Key，返回的新 Key 含明文（仅此一次）。
+func (s *APIKeyRotationService) Rotate(ctx context.Context, userID, apiKeyID int64, req RotateAPIKeyRequest) (*APIKeyRotationResult, error) {
+	graceHours, err := s.resolveGraceHours(req.GraceHours)
+	if err != nil {
+		return nil, err
+	}
+	return s.rotate(ctx, userID, apiKeyID, graceHours, false)
+}
+
+func (s *APIKeyRotationService) rotate(ctx context.Context, userID, apiKeyID int64, graceHours int, scheduled bool) (*APIKeyRotationResult, error) {
+	newKey, err := s.keys.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+	now := s.now()
+	in := APIKeyRotateInput{
+		APIKeyID:       apiKeyID,
+		UserID:         userID,
+		NewKey:         newKey,
+		GraceExpiresAt: now.Add(time.Duration(graceHours) * time.Hour),
+		Now:            now,
+	}
+	if scheduled {
+		// 定期轮换没有请求方可以接收明文，加密暂存，待用户在控制台查看一次。
+		if s.encryptor == nil {
+			return nil, fmt.Errorf("no encryptor configured for scheduled rotation")
+		}
+		sealed, err := s.encryptor.Encrypt(newKey)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt rotated key: %w", err)
+		}
+		in.PendingSecret = sealed
+	}
+
+	result, err := s.repo.RotateKey(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	// 旧 Key 的过期时间已变更，认证快照需要重新加载；新 Key 清除可能存在的负缓存。
+	s.keys.InvalidateAuthCacheByKey(ctx, result.Predecessor.Key)
+	s.keys.InvalidateAuthCacheByKey(ctx, newKey)
+	result.Successor.Key = newKey
+	if result.GraceExpiresAt.After(now) {
+		s.markGrace(result.Predecessor.ID, result.GraceExpiresAt)
+	}
+	return result, nil
+}
+
+// RunScheduled 轮换所有到期的定期轮换 Key，并通知 Key 所有者。
+func (s *APIKeyRotationService) RunScheduled(ctx context.Context) (APIKeyRotationStats, error) {
+	var stats APIKeyRotationStats
+	due, err := s.repo.ListDueSchedules(ctx, s.now(), apiKeyRotationBatchSize)
+	if err != nil {
+		return stats, err
+	}
+	stats.Due = len(due)
+	for i := range due {
+		state := due[i]
+		graceHours := s.defaultGraceHours()
+		if state.ScheduleGraceHours != nil {
+			graceHours = s.clampGraceHours(*state.ScheduleGraceHours)
+		}
+		result, err := s.rotate(ctx, state.UserID, state.APIKeyID, graceHours, true)
+		if err != nil {
+			stats.Failed++
+			logger.LegacyPrintf("service.api_key_rotation", "[APIKeyRotation] scheduled rotation failed: key=%d err=%v", state.APIKeyID, err)
+			continue
+		}
+		stats.Rotated++
+		s.recordAudit(result)
+		s.notify(ctx, result)
+	}
+	return stats, nil
+}
+
+func (s *APIKeyRotationService) recordAudit(result *APIKeyRotationResult) {
+	if s.auditLogService == nil {
+		return
+	}
+	s.auditLogService.Record(&AuditLog{
+		ActorRole:  AuditAuthMethodSystem,
+		AuthMethod: AuditAuthMethodSystem,
+		Action:     AuditActionAPIKeyRotated,
+		Extra: map[string]any{
+			"user_id":          result.Predecessor.UserID,
+			"api_key_id":       result.Predecessor.ID,
+			"successor_id":     result.Successor.ID,
+			"lineage_id":       result.LineageID,
+			"key_prefix":       result.Successor.KeyPrefix,
+			"grace_expires_at": result.GraceExpiresAt,
+			"scheduled":        true,
+		},
+	})
+}
+
+func (s *APIKeyRotationService) notify(ctx context.Context, result *APIKeyRotationResult) {
+	if s.notificationEmailService == nil || s.userRepo == nil {
+		return
+	}
+	user, err := s.userRepo.GetByID(ctx, result.Predecessor.UserID)
+	if err != nil || user == nil || strings.TrimSpace(user.Email) == "" {
+		return
+	}
+	locale := s.notificationEmailService.ResolveRecipientLocale(ctx, user.ID, user.Email)
+	if err := s.notificationEmailService.Send(ctx, NotificationEmailSendInput{
+		Event:          NotificationEmailEventAPIKeyRotated,
+		Locale:         locale,
+		RecipientEmail: user.Email,
+		RecipientName:  firstNonEmpty(user.Username, user.Email),
+		UserID:         user.ID,
+		SourceType:     "api_key_rotation",
+		SourceID:       strconv.FormatInt(result.Successor.ID, 10),
+		Variables: map[string]string{
+			"api_key_name":       result.Successor.Name,
+			"api_key_prefix":     result.Successor.KeyPrefix,
+			"old_api_key_prefix": result.Predecessor.KeyPrefix,
+			"grace_expires_at":   result.GraceExpiresAt.Format("2006-01-02 15:04:05"),
+		},
+	}); err != nil {
+		logger.LegacyPrintf("service.api_key_rotation", "[APIKeyRotation] send notice failed: key=%d user=%d err=%v", result.Successor.ID, user.ID, err)
+	}
+}
+
+// RevealPendingSecret 返回定期轮换生成的新 Key 明文，只能查看一次。
+func (s *APIKeyRotationService) RevealPendingSecret(ctx context.Context, userID, apiKeyID int64) (string, error) {
+	sealed, err := s.repo.TakePendingSecret(ctx, apiKeyID, userID)
+	if err != nil {
+		return "", err
+	}
+	if sealed == "" {
+		return "", ErrAPIKeyRotationSecretUnavailable
+	}
+	if s.encryptor == nil {
+		return "", fmt.Errorf("no encryptor configured")
+	}
+	plaintext, err := s.encryptor.Decrypt(sealed)
+	if err != nil {
+		return "", fmt.Errorf("decrypt rotated key: %w", err)
+	}
+	return plaintext, nil
+}
+
+// GetState 返回用户 Key 的轮换状态（含定期轮换设置）。
+func (s *APIKeyRotationService) GetState(ctx context.Context, userID, apiKeyID int64) (*APIKeyRotationState, error) {
+	if _, err := s.ownedKey(ctx, userID, apiKeyID); err != nil {
+		return nil, err
+	}
+	state, err := s.repo.GetState(ctx, apiKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		state = &APIKeyRotationState{APIKeyID: apiKeyID, UserID: userID, LineageID: apiKeyID}
+	}
+	return state, nil
+}
+
+// SetSchedule 设置定期轮换周期；已被轮换的旧 Key 不能再设置。
+func (s *APIKeyRotationService) SetSchedule(ctx context.Context, userID, apiKeyID int64, req UpdateAPIKeyRotationScheduleRequest) (*APIKeyRotationState, error) {
+	if req.IntervalDays != 0 && (req.IntervalDays < s.minIntervalDays() || req.IntervalDays > s.maxIntervalDays()) {
+		return nil, ErrAPIKeyRotationIntervalInvalid
+	}
+	if req.GraceHours != nil {
+		if _, err := s.resolveGraceHours(req.GraceHours); err != nil {
+			return nil, err
+		}
+	}
+	state, err := s.GetState(ctx, userID, apiKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if state.SuccessorID != nil {
+		return nil, ErrAPIKeyAlreadyRotated
+	}
+	var next *time.Time
+	graceHours := req.GraceHours
+	if req.IntervalDays > 0 {
+		at := s.now().AddDate(0, 0, req.IntervalDays)
+		next = &at
+	} else {
+		graceHours = nil
+	}
+	return s.repo.UpsertSchedule(ctx, apiKeyID, req.IntervalDays, graceHours, next)
+}
+
+// GetLineage 返回 Key 所属谱系的全部成员及最近 days 天的合计用量。
+func (s *APIKeyRotationService) GetLineage(ctx context.Context, userID, apiKeyID int64, days int) (*APIKeyLineage, error) {
+	state, err := s.GetState(ctx, userID, apiKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if days <= 0 {
+		days = defaultAPIKeyLineageUsageDays
+	}
+	since := s.now().AddDate(0, 0, -days)
+	members, err := s.repo.ListLineage(ctx, state.LineageID, since)
+	if err != nil {
+		return nil, err
+	}
+	lineage := &APIKeyLineage{LineageID: state.LineageID, CurrentID: apiKeyID, Since: since, Members: members}
+	for _, m := range members {
+		lineage.Requests += m.Requests
+		lineage.TotalTokens += m.TotalTokens
+		lineage.Cost += m.Cost
+		lineage.ActualCost += m.ActualCost
+		// 当前 Key 为谱系中未被取代且未删除的成员。
+		if m.SuccessorID == nil && !m.Deleted {
+			lineage.CurrentID = m.APIKeyID
+		}
+	}
+	return lineage, nil
+}
+
+func (s *APIKeyRotationService) ownedKey(ctx context.Context, userID, apiKeyID int64) (*APIKey, error) {
+	key, err := s.keys.GetByID(ctx, apiKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if key.UserID != userID {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+func (s *APIKeyRotationService) resolveGraceHours(hours *int) (int, error) {
+	if hours == nil {
+		return s.defaultGraceHours(), nil
+	}
+	if *hours < 0 || *hours > s.maxGraceHours() {
+		return 0, ErrAPIKeyRotationGraceInvalid
+	}
+	return *hours, nil
+}
+
+func (s *APIKeyRotationService) clampGraceHours(hours int) int {
+	if hours < 0 {
+		return 0
+	}
+	if limit := s.maxGraceHours(); hours > limit {
+		return limit
+	}
+	return hours
+}
+
+func (s *APIKeyRotationService) defaultGraceHours() int {
+	if s.cfg.DefaultGraceHours > 0 {
+		return s.clampGraceHours(s.cfg.DefaultGraceHours)
+	}
+	return s.clampGraceHours(defaultAPIKeyRotationGraceHours)
+}
+
+func (s *APIKeyRotationService) maxGraceHours() int {
+	if s.cfg.MaxGraceHours > 0 {
+		return s.cfg.MaxGraceHours
+	}
+	return defaultAPIKeyRotationMaxGraceHours
+}
+
+func (s *APIKeyRotationService) minIntervalDays() int {
+	if s.cfg.MinScheduleIntervalDays > 0 {
+		return s.cfg.MinScheduleIntervalDays
+	}
+	return defaultAPIKeyRotationMinIntervalDay
+}
+
+func (s *APIKeyRotationService) maxIntervalDays() int {
+	if s.cfg.MaxScheduleIntervalDays > 0 {
+		return s.cfg.MaxScheduleIntervalDays
+	}
+	return defaultAPIKeyRotationMaxIntervalDay
+}
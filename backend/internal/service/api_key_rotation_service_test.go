@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+type rotationRepoStub struct {
+	inputs      []APIKeyRotateInput
+	rotateErr   error
+	states      map[int64]*APIKeyRotationState
+	due         []APIKeyRotationState
+	lineage     []APIKeyLineageMember
+	pending     map[int64]string
+	scheduleArg struct {
+		apiKeyID     int64
+		intervalDays int
+		graceHours   *int
+		next         *time.Time
+	}
+}
+
+func (r *rotationRepoStub) RotateKey(_ context.Context, in APIKeyRotateInput) (*APIKeyRotationResult, error) {
+	if r.rotateErr != nil {
+		return nil, r.rotateErr
+	}
+	r.inputs = append(r.inputs, in)
+	return &APIKeyRotationResult{
+		Predecessor:    &APIKey{ID: in.APIKeyID, UserID: in.UserID, Key: "hmac-sha256:old", KeyPrefix: "sk-old"},
+		Successor:      &APIKey{ID: in.APIKeyID + 1000, UserID: in.UserID, Key: "hmac-sha256:new", KeyPrefix: APIKeyDisplayPrefix(in.NewKey)},
+		LineageID:      in.APIKeyID,
+		GraceExpiresAt: in.GraceExpiresAt,
+	}, nil
+}
+
+func (r *rotationRepoStub) GetState(_ context.Context, apiKeyID int64) (*APIKeyRotationState, error) {
+	return r.states[apiKeyID], nil
+}
+
+func (r *rotationRepoStub) ListLineage(context.Context, int64, time.Time) ([]APIKeyLineageMember, error) {
+	return r.lineage, nil
+}
+
+func (r *rotationRepoStub) UpsertSchedule(_ context.Context, apiKeyID int64, intervalDays int, graceHours *int, next *time.Time) (*APIKeyRotationState, error) {
+	r.scheduleArg.apiKeyID = apiKeyID
+	r.scheduleArg.intervalDays = intervalDays
+	r.scheduleArg.graceHours = graceHours
+	r.scheduleArg.next = next
+	return &APIKeyRotationState{APIKeyID: apiKeyID, LineageID: apiKeyID, ScheduleIntervalDays: intervalDays, ScheduleGraceHours: graceHours, NextRotationAt: next}, nil
+}
+
+func (r *rotationRepoStub) ListDueSchedules(context.Context, time.Time, int) ([]APIKeyRotationState, error) {
+	return r.due, nil
+}
+
+func (r *rotationRepoStub) ListGraceDeadlines(context.Context, time.Time) (map[int64]time.Time, error) {
+	return map[int64]time.Time{}, nil
+}
+
+func (r *rotationRepoStub) TakePendingSecret(_ context.Context, apiKeyID, _ int64) (string, error) {
+	secret := r.pending[apiKeyID]
+	delete(r.pending, apiKeyID)
+	return secret, nil
+}
+
+type rotationKeysStub struct {
+	keys        map[int64]*APIKey
+	invalidated []string
+	generated   int
+}
+
+func (k *rotationKeysStub) GenerateKey() (string, error) {
+	k.generated++
+	return "sk-rotated" + time.Duration(k.generated).String(), nil
+}
+
+func (k *rotationKeysStub) GetByID(_ context.Context, id int64) (*APIKey, error) {
+	if key, ok := k.keys[id]; ok {
+		return key, nil
+	}
+	return nil, ErrAPIKeyNotFound
+}
+
+func (k *rotationKeysStub) InvalidateAuthCacheByKey(_ context.Context, key string) {
+	k.invalidated = append(k.invalidated, key)
+}
+
+type rotationEncryptorStub struct{}
+
+func (rotationEncryptorStub) Encrypt(plaintext string) (string, error) {
+	return "sealed:" + plaintext, nil
+}
+
+func (rotationEncryptorStub) Decrypt(ciphertext string) (string, error) {
+	if len(ciphertext) < 7 || ciphertext[:7] != "sealed:" {
+		return "", errors.New("bad ciphertext")
+	}
+	return ciphertext[7:], nil
+}
+
+func newRotationTestService(repo *rotationRepoStub, keys *rotationKeysStub, now time.Time) *APIKeyRotationService {
+	svc := NewAPIKeyRotationService(repo, nil, nil, rotationEncryptorStub{}, nil, nil, config.APIKeyRotationConfig{
+		DefaultGraceHours:       24,
+		MaxGraceHours:           72,
+		MinScheduleIntervalDays: 7,
+		MaxScheduleIntervalDays: 90,
+	})
+	svc.keys = keys
+	svc.now = func() time.Time { return now }
+	return svc
+}
+
+func TestAPIKeyRotationRotateUsesDefaultGraceAndFlagsOldKey(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := &rotationRepoStub{}
+	keys := &rotationKeysStub{}
+	svc := newRotationTestService(repo, keys, now)
+
+	result, err := svc.Rotate(context.Background(), 7, 42, RotateAPIKeyRequest{})
+	require.NoError(t, err)
+	require.Len(t, repo.inputs, 1)
+	require.Equal(t, now.Add(24*time.Hour), repo.inputs[0].GraceExpiresAt)
+	require.Empty(t, repo.inputs[0].PendingSecret, "manual rotation returns the key directly")
+
+	require.Equal(t, repo.inputs[0].NewKey, result.Successor.Key, "successor carries the plaintext for the response")
+	require.Equal(t, []string{"hmac-sha256:old", repo.inputs[0].NewKey}, keys.invalidated)
+
+	deadline, ok := svc.GraceDeadline(42)
+	require.True(t, ok)
+	require.Equal(t, now.Add(24*time.Hour), deadline)
+	_, ok = svc.GraceDeadline(result.Successor.ID)
+	require.False(t, ok)
+
+	svc.now = func() time.Time { return now.Add(25 * time.Hour) }
+	_, ok = svc.GraceDeadline(42)
+	require.False(t, ok, "grace period has elapsed")
+}
+
+func TestAPIKeyRotationRotateValidatesGrace(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := &rotationRepoStub{}
+	svc := newRotationTestService(repo, &rotationKeysStub{}, now)
+
+	for _, hours := range []int{-1, 73} {
+		h := hours
+		_, err := svc.Rotate(context.Background(), 7, 42, RotateAPIKeyRequest{GraceHours: &h})
+		require.ErrorIs(t, err, ErrAPIKeyRotationGraceInvalid)
+	}
+	require.Empty(t, repo.inputs)
+
+	zero := 0
+	_, err := svc.Rotate(context.Background(), 7, 42, RotateAPIKeyRequest{GraceHours: &zero})
+	require.NoError(t, err)
+	_, ok := svc.GraceDeadline(42)
+	require.False(t, ok, "zero grace retires the old key immediately")
+}
+
+func TestAPIKeyRotationRotatePropagatesRepositoryErrors(t *testing.T) {
+	repo := &rotationRepoStub{rotateErr: ErrAPIKeyAlreadyRotated}
+	keys := &rotationKeysStub{}
+	svc := newRotationTestService(repo, keys, time.Now())
+
+	_, err := svc.Rotate(context.Background(), 7, 42, RotateAPIKeyRequest{})
+	require.ErrorIs(t, err, ErrAPIKeyAlreadyRotated)
+	require.Empty(t, keys.invalidated)
+}
+
+func TestAPIKeyRotationRunScheduledSealsKeyForOneTimeReveal(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	grace := 6
+	repo := &rotationRepoStub{
+		due: []APIKeyRotationState{
+			{APIKeyID: 42, UserID: 7, LineageID: 42, ScheduleIntervalDays: 30, ScheduleGraceHours: &grace},
+			{APIKeyID: 43, UserID: 8, LineageID: 43, ScheduleIntervalDays: 30},
+		},
+		pending: map[int64]string{},
+	}
+	svc := newRotationTestService(repo, &rotationKeysStub{}, now)
+
+	stats, err := svc.RunScheduled(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, APIKeyRotationStats{Due: 2, Rotated: 2}, stats)
+	require.Len(t, repo.inputs, 2)
+	require.Equal(t, now.Add(6*time.Hour), repo.inputs[0].GraceExpiresAt)
+	require.Equal(t, now.Add(24*time.Hour), repo.inputs[1].GraceExpiresAt)
+	require.Equal(t, "sealed:"+repo.inputs[0].NewKey, repo.inputs[0].PendingSecret)
+
+	repo.pending[1042] = repo.inputs[0].PendingSecret
+	plaintext, err := svc.RevealPendingSecret(context.Background(), 7, 1042)
+	require.NoError(t, err)
+	require.Equal(t, repo.inputs[0].NewKey, plaintext)
+
+	_, err = svc.RevealPendingSecret(context.Background(), 7, 1042)
+	require.ErrorIs(t, err, ErrAPIKeyRotationSecretUnavailable)
+}
+
+func TestAPIKeyRotationSetSchedule(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	successor := int64(99)
+	repo := &rotationRepoStub{states: map[int64]*APIKeyRotationState{
+		43: {APIKeyID: 43, UserID: 7, LineageID: 40, SuccessorID: &successor},
+	}}
+	keys := &rotationKeysStub{keys: map[int64]*APIKey{
+		42: {ID: 42, UserID: 7},
+		43: {ID: 43, UserID: 7},
+	}}
+	svc := newRotationTestService(repo, keys, now)
+	ctx := context.Background()
+
+	_, err := svc.SetSchedule(ctx, 7, 42, UpdateAPIKeyRotationScheduleRequest{IntervalDays: 3})
+	require.ErrorIs(t, err, ErrAPIKeyRotationIntervalInvalid)
+
+	_, err = svc.SetSchedule(ctx, 8, 42, UpdateAPIKeyRotationScheduleRequest{IntervalDays: 30})
+	require.ErrorIs(t, err, ErrAPIKeyNotFound, "other users' keys are not visible")
+
+	_, err = svc.SetSchedule(ctx, 7, 43, UpdateAPIKeyRotationScheduleRequest{IntervalDays: 30})
+	require.ErrorIs(t, err, ErrAPIKeyAlreadyRotated)
+
+	grace := 12
+	state, err := svc.SetSchedule(ctx, 7, 42, UpdateAPIKeyRotationScheduleRequest{IntervalDays: 30, GraceHours: &grace})
+	require.NoError(t, err)
+	require.Equal(t, 30, state.ScheduleIntervalDays)
+	require.Equal(t, now.AddDate(0, 0, 30), *repo.scheduleArg.next)
+	require.Equal(t, 12, *repo.scheduleArg.graceHours)
+
+	_, err = svc.SetSchedule(ctx, 7, 42, UpdateAPIKeyRotationScheduleRequest{IntervalDays: 0, GraceHours: &grace})
+	require.NoError(t, err)
+	require.Nil(t, repo.scheduleArg.next)
+	require.Nil(t, repo.scheduleArg.graceHours, "disabling the schedule clears its grace override")
+}
+
+func TestAPIKeyRotationGetLineageAggregatesUsage(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	first, second := int64(42), int64(1042)
+	repo := &rotationRepoStub{
+		states: map[int64]*APIKeyRotationState{
+			first:  {APIKeyID: first, UserID: 7, LineageID: first, SuccessorID: &second},
+			second: {APIKeyID: second, UserID: 7, LineageID: first, PredecessorID: &first},
+		},
+		lineage: []APIKeyLineageMember{
+			{APIKeyID: first, SuccessorID: &second, Requests: 10, TotalTokens: 1000, Cost: 1.5, ActualCost: 1.2},
+			{APIKeyID: second, PredecessorID: &first, Requests: 5, TotalTokens: 500, Cost: 0.5, ActualCost: 0.4},
+		},
+	}
+	keys := &rotationKeysStub{keys: map[int64]*APIKey{first: {ID: first, UserID: 7}}}
+	svc := newRotationTestService(repo, keys, now)
+
+	lineage, err := svc.GetLineage(context.Background(), 7, first, 7)
+	require.NoError(t, err)
+	require.Equal(t, first, lineage.LineageID)
+	require.Equal(t, second, lineage.CurrentID)
+	require.Equal(t, now.AddDate(0, 0, -7), lineage.Since)
+	require.Equal(t, int64(15), lineage.Requests)
+	require.Equal(t, int64(1500), lineage.TotalTokens)
+	require.InDelta(t, 2.0, lineage.Cost, 1e-9)
+	require.InDelta(t, 1.6, lineage.ActualCost, 1e-9)
+}
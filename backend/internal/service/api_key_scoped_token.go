@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+)
+
+const (
+	// ScopedTokenPrefix 派生令牌前缀。'.' 不是合法的 API Key 字符，认证时据此与 Key 区分。
+	ScopedTokenPrefix = "sst."
+	// MaxScopedTokenBytes 派生令牌长度上限（声明随令牌携带，比 Key 长得多）。
+	MaxScopedTokenBytes = 4096
+
+	scopedTokenMaxModels  = 32
+	scopedTokenMaxOrigins = 8
+)
+
+var (
+	ErrScopedTokenDisabled         = infraerrors.Forbidden("SCOPED_TOKEN_DISABLED", "scoped tokens are disabled")
+	ErrScopedTokenInvalid          = infraerrors.Unauthorized("SCOPED_TOKEN_INVALID", "invalid scoped token")
+	ErrScopedTokenExpired          = infraerrors.Unauthorized("SCOPED_TOKEN_EXPIRED", "scoped token has expired")
+	ErrScopedTokenNotMintable      = infraerrors.Forbidden("SCOPED_TOKEN_NOT_MINTABLE", "scoped tokens can only be minted with an API key")
+	ErrScopedTokenScopeInvalid     = infraerrors.BadRequest("SCOPED_TOKEN_SCOPE_INVALID", "scoped token scope is invalid")
+	ErrScopedTokenRequestLimit     = infraerrors.TooManyRequests("SCOPED_TOKEN_REQUEST_LIMIT", "scoped token request limit reached")
+	ErrScopedTokenSpendLimit       = infraerrors.TooManyRequests("SCOPED_TOKEN_SPEND_LIMIT", "scoped token spend limit reached")
+	ErrScopedTokenLimitUnavailable = infraerrors.ServiceUnavailable("SCOPED_TOKEN_LIMIT_UNAVAILABLE", "scoped token limits cannot be checked right now")
+)
+
+// ScopedTokenClaims 派生令牌声明：签发时收窄的范围随令牌携带，认证时无需查库即可执行；
+// 认证通过后挂在父 Key 的 APIKey.ScopedToken 上，随请求进入计费链路累计花费。
+type ScopedTokenClaims struct {
+	ID       string `json:"jti"`
+	APIKeyID int64  `json:"kid"`
+	// Nonce 签发时父 Key 的签名 nonce；轮换 nonce 后旧令牌与父 Key 不再匹配。
+	// 令牌只以 ID 引用父 Key，不携带 Key 或其摘要。
+	Nonce       string   `json:"nonce"`
+	IssuedAt    int64    `json:"iat"`
+	ExpiresAt   int64    `json:"exp"`
+	Models      []string `json:"models,omitempty"`
+	MaxSpend    float64  `json:"max_spend,omitempty"`
+	MaxRequests int64    `json:"max_requests,omitempty"`
+	Origins     []string `json:"origins,omitempty"`
+
+	// reservation 本次请求受理时预占的花费，由扣费或请求结束时的释放二者之一结清。
+	reservation *scopedTokenReservation
+}
+
+// scopedTokenReservation 以 float64 位模式原子保存预占金额，保证只被结清一次。
+type scopedTokenReservation struct {
+	spend atomic.Uint64
+}
+
+func newScopedTokenReservation(amount float64) *scopedTokenReservation {
+	r := &scopedTokenReservation{}
+	r.spend.Store(math.Float64bits(amount))
+	return r
+}
+
+// take 取走尚未结清的预占金额，之后再调用返回 0。
+func (r *scopedTokenReservation) take() float64 {
+	if r == nil {
+		return 0
+	}
+	return math.Float64frombits(r.spend.Swap(0))
+}
+
+// Expiry 返回令牌过期时间。
+func (c *ScopedTokenClaims) Expiry() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// AllowsModel 判断令牌是否允许请求该模型；未限制模型时全部放行，支持末尾 * 通配。
+func (c *ScopedTokenClaims) AllowsModel(model string) bool {
+	if c == nil || len(c.Models) == 0 {
+		return true
+	}
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return false
+	}
+	for _, pattern := range c.Models {
+		if matchWildcard(pattern, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsOrigin 判断请求来源是否在令牌的来源白名单内；未限制来源时全部放行。
+// 限制了来源的令牌要求请求携带匹配的 Origin 头。
+func (c *ScopedTokenClaims) AllowsOrigin(origin string) bool {
+	if c == nil || len(c.Origins) == 0 {
+		return true
+	}
+	normalized, ok := normalizeScopedTokenOrigin(origin)
+	if !ok {
+		return false
+	}
+	for _, allowed := range c.Origins {
+		if allowed == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+// IsScopedToken 判断凭证是否为派生令牌（而非 API Key）。
+func IsScopedToken(credential string) bool {
+	return strings.HasPrefix(credential, ScopedTokenPrefix)
+}
+
+// MintScopedTokenRequest 签发派生令牌的请求；各限制为 0 或空表示沿用父 Key 的范围。
+type MintScopedTokenRequest struct {
+	TTLSeconds  int      `json:"ttl_seconds"`
+	Models      []string `json:"models"`
+	MaxSpend    float64  `json:"max_spend"`
+	MaxRequests int64    `json:"max_requests"`
+	Origins     []string `json:"origins"`
+}
+
+// ScopedToken 签发结果。
+type ScopedToken struct {
+	Token       string    `json:"token"`
+	TokenID     string    `json:"token_id"`
+	APIKeyID    int64     `json:"api_key_id"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Models      []string  `json:"models,omitempty"`
+	MaxSpend    float64   `json:"max_spend,omitempty"`
+	MaxRequests int64     `json:"max_requests,omitempty"`
+	Origins     []string  `json:"origins,omitempty"`
+}
+
+// ScopedTokenUsageCache 令牌级请求数与花费计数（Redis），键在令牌过期后自动清理。
+type ScopedTokenUsageCache interface {
+	// Reserve 原子地检查上限并受理一次请求：maxRequests > 0 时请求数加一，
+	// maxSpend > 0 时预占 min(estimate, 剩余额度) 的花费并返回预占金额。
+	// 超限时不做任何修改，返回 ErrScopedTokenRequestLimit 或 ErrScopedTokenSpendLimit。
+	Reserve(ctx context.Context, tokenID string, maxRequests int64, maxSpend, estimate float64, ttl time.Duration) (float64, error)
+	// AddSpend 累加花费；amount 为负时用于退回预占。
+	AddSpend(ctx context.Context, tokenID string, amount float64, ttl time.Duration) error
+}
+
+// ScopedTokenAuthenticator 供认证中间件与计费链路使用的派生令牌能力。
+type ScopedTokenAuthenticator interface {
+	// Authenticate 校验令牌签名与有效期，返回挂有令牌声明的父 Key。
+	Authenticate(ctx context.Context, token string) (*APIKey, error)
+	// Admit 在放行计费请求前原子地累计请求数并预占花费。
+	Admit(ctx context.Context, claims *ScopedTokenClaims) error
+	// RecordSpend 在扣费后以实际花费结清预占。
+	RecordSpend(ctx context.Context, claims *ScopedTokenClaims, cost float64)
+	// Release 在请求结束时退回尚未结清的预占。
+	Release(ctx context.Context, claims *ScopedTokenClaims)
+}
+
+// scopedTokenSigningKey 由服务端签名密钥、父 Key ID 与父 Key 的 nonce 派生单个 Key 的签名密钥：
+// 轮换 nonce 即令该 Key 签发的全部令牌失效，而不影响其他 Key。
+func scopedTokenSigningKey(secret string, apiKeyID int64, nonce string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte("scoped-token\x00" + strconv.FormatInt(apiKeyID, 10) + "\x00" + nonce))
+	return mac.Sum(nil)
+}
+
+func signScopedToken(claims *ScopedTokenClaims, secret, nonce string) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	body := ScopedTokenPrefix + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, scopedTokenSigningKey(secret, claims.APIKeyID, nonce))
+	_, _ = mac.Write([]byte(body))
+	return body + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// parseScopedToken 只解析声明与签名，不校验；签名需要父 Key 的 nonce，由调用方加载后调用 verifyScopedTokenSignature。
+func parseScopedToken(token string) (*ScopedTokenClaims, string, []byte, bool) {
+	if len(token) > MaxScopedTokenBytes || !IsScopedToken(token) {
+		return nil, "", nil, false
+	}
+	idx := strings.LastIndexByte(token, '.')
+	if idx <= len(ScopedTokenPrefix) {
+		return nil, "", nil, false
+	}
+	body, sigPart := token[:idx], token[idx+1:]
+	sig, err := base64.RawURLEncoding.DecodeString(sigPart)
+	if err != nil || len(sig) != sha256.Size {
+		return nil, "", nil, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(body[len(ScopedTokenPrefix):])
+	if err != nil {
+		return nil, "", nil, false
+	}
+	var claims ScopedTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, "", nil, false
+	}
+	if claims.ID == "" || claims.APIKeyID <= 0 || claims.ExpiresAt <= 0 {
+		return nil, "", nil, false
+	}
+	return &claims, body, sig, true
+}
+
+func verifyScopedTokenSignature(body string, sig []byte, secret string, claims *ScopedTokenClaims, nonce string) bool {
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return false
+	}
+	mac := hmac.New(sha256.New, scopedTokenSigningKey(secret, claims.APIKeyID, nonce))
+	_, _ = mac.Write([]byte(body))
+	return hmac.Equal(mac.Sum(nil), sig)
+}
+
+// normalizeScopedTokenOrigin 把来源规范化为 scheme://host[:port]，只接受 http/https 且不带路径。
+func normalizeScopedTokenOrigin(origin string) (string, bool) {
+	origin = strings.TrimSpace(origin)
+	if origin == "" {
+		return "", false
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return "", false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", false
+	}
+	if u.Path != "" && u.Path != "/" {
+		return "", false
+	}
+	return scheme + "://" + strings.ToLower(u.Host), true
+}
@@ -0,0 +1,389 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+)
+
+const (
+	// scopedTokenUsageTTLSlack 令牌计数键在令牌过期后额外保留的时间，覆盖过期前受理、稍后才完成扣费的请求。
+	scopedTokenUsageTTLSlack = time.Hour
+	// scopedTokenReleaseTimeout 请求结束后退回预占的超时；客户端断开时请求上下文已取消，需独立计时。
+	scopedTokenReleaseTimeout = 2 * time.Second
+)
+
+// scopedTokenNonceStore 由 API Key 仓储实现，更新 Key 的派生令牌签名 nonce。
+type scopedTokenNonceStore interface {
+	SetTokenNonce(ctx context.Context, apiKeyID int64, nonce string) error
+}
+
+// scopedTokenKeys 是 ScopedTokenService 依赖的 APIKeyService 能力。
+type scopedTokenKeys interface {
+	GetByStoredKeyForAuth(ctx context.Context, storedKey string) (*APIKey, error)
+	GetByID(ctx context.Context, id int64) (*APIKey, error)
+	InvalidateAuthCacheByKey(ctx context.Context, key string)
+}
+
+// ScopedTokenService 签发与校验由 API Key 派生的短期受限令牌。
+//
+// 令牌自带收窄后的模型、花费、请求数、有效期与来源限制，由服务端密钥与父 Key 的 ID、nonce 签名；
+// 令牌只以 ID 引用父 Key。认证时按 ID 找到父 Key 的存储值后读取认证快照（与 Key 认证共用缓存），
+// 并在 Redis 中计数。用量与扣费仍记在父 Key 上。
+type ScopedTokenService struct {
+	keys   scopedTokenKeys
+	nonces scopedTokenNonceStore
+	usage  ScopedTokenUsageCache
+	cfg    *config.Config
+	now    func() time.Time
+
+	// storedKeys 父 Key ID -> 存储值（哈希形态），避免每次认证都按 ID 回源数据库；
+	// 存储值变化（轮换、删除）后按存储值查不到，届时丢弃并重新按 ID 加载。
+	storedKeys sync.Map
+}
+
+// NewScopedTokenService 创建派生令牌服务。
+func NewScopedTokenService(apiKeyRepo APIKeyRepository, apiKeyService *APIKeyService, usage ScopedTokenUsageCache, cfg *config.Config) *ScopedTokenService {
+	nonces, _ := apiKeyRepo.(scopedTokenNonceStore)
+	return &ScopedTokenService{
+		keys:   apiKeyService,
+		nonces: nonces,
+		usage:  usage,
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+func (s *ScopedTokenService) enabled() bool {
+	return s != nil && s.cfg != nil && s.cfg.Security.ScopedToken.Enabled && s.cfg.Security.ScopedToken.SigningSecret != ""
+}
+
+// Mint 以已认证的父 Key 签发派生令牌。令牌有效期不超过父 Key 的过期时间，且不能再用于签发令牌。
+func (s *ScopedTokenService) Mint(ctx context.Context, parent *APIKey, req MintScopedTokenRequest) (*ScopedToken, error) {
+	if !s.enabled() {
+		return nil, ErrScopedTokenDisabled
+	}
+	if parent == nil || parent.ScopedToken != nil || parent.Key == "" {
+		return nil, ErrScopedTokenNotMintable
+	}
+
+	ttl, err := s.resolveTTL(req.TTLSeconds)
+	if err != nil {
+		return nil, err
+	}
+	models, err := normalizeScopedTokenModels(req.Models)
+	if err != nil {
+		return nil, err
+	}
+	origins, err := normalizeScopedTokenOrigins(req.Origins)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkOriginsAllowedByCORS(origins); err != nil {
+		return nil, err
+	}
+	if req.MaxSpend < 0 || math.IsNaN(req.MaxSpend) || math.IsInf(req.MaxSpend, 0) {
+		return nil, ErrScopedTokenScopeInvalid.WithMetadata(map[string]string{"field": "max_spend"})
+	}
+	if req.MaxRequests < 0 {
+		return nil, ErrScopedTokenScopeInvalid.WithMetadata(map[string]string{"field": "max_requests"})
+	}
+
+	now := s.now()
+	expiresAt := now.Add(ttl)
+	if parent.ExpiresAt != nil && parent.ExpiresAt.Before(expiresAt) {
+		expiresAt = *parent.ExpiresAt
+	}
+	if !expiresAt.After(now) {
+		return nil, ErrScopedTokenNotMintable
+	}
+
+	id, err := newScopedTokenID()
+	if err != nil {
+		return nil, err
+	}
+	claims := &ScopedTokenClaims{
+		ID:          id,
+		APIKeyID:    parent.ID,
+		Nonce:       parent.TokenNonce,
+		IssuedAt:    now.Unix(),
+		ExpiresAt:   expiresAt.Unix(),
+		Models:      models,
+		MaxSpend:    req.MaxSpend,
+		MaxRequests: req.MaxRequests,
+		Origins:     origins,
+	}
+	token, err := signScopedToken(claims, s.cfg.Security.ScopedToken.SigningSecret, parent.TokenNonce)
+	if err != nil {
+		return nil, fmt.Errorf("sign scoped token: %w", err)
+	}
+	if len(token) > MaxScopedTokenBytes {
+		return nil, ErrScopedTokenScopeInvalid.WithMetadata(map[string]string{"field": "scope", "reason": "too_large"})
+	}
+	return &ScopedToken{
+		Token:       token,
+		TokenID:     id,
+		APIKeyID:    parent.ID,
+		ExpiresAt:   claims.Expiry(),
+		Models:      models,
+		MaxSpend:    req.MaxSpend,
+		MaxRequests: req.MaxRequests,
+		Origins:     origins,
+	}, nil
+}
+
+// Authenticate 校验派生令牌，返回挂有令牌声明的父 Key 副本。
+func (s *ScopedTokenService) Authenticate(ctx context.Context, token string) (*APIKey, error) {
+	if !s.enabled() {
+		return nil, ErrScopedTokenDisabled
+	}
+	claims, body, sig, ok := parseScopedToken(token)
+	if !ok {
+		return nil, ErrScopedTokenInvalid
+	}
+	parent, err := s.loadParent(ctx, claims.APIKeyID)
+	if err != nil {
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			return nil, ErrScopedTokenInvalid
+		}
+		return nil, err
+	}
+	if parent == nil || parent.ID != claims.APIKeyID ||
+		!verifyScopedTokenSignature(body, sig, s.cfg.Security.ScopedToken.SigningSecret, claims, parent.TokenNonce) {
+		return nil, ErrScopedTokenInvalid
+	}
+	// 过期检查放在验签之后，避免对伪造令牌返回“已过期”泄露信息。
+	if !s.now().Before(claims.Expiry()) {
+		return nil, ErrScopedTokenExpired
+	}
+	parent.ScopedToken = claims
+	return parent, nil
+}
+
+// Admit 放行计费请求前执行令牌的花费与请求数上限。计数不可用时拒绝请求，避免令牌越过收窄的范围。
+//
+// 检查与累计在 Redis 中原子完成：请求数直接加一；花费按 spend_reservation 预占（不超过剩余额度），
+// 并发请求各自占用额度，不会因“先读后比”一起放行。预占由扣费时的 RecordSpend 或请求结束时的 Release 结清。
+func (s *ScopedTokenService) Admit(ctx context.Context, claims *ScopedTokenClaims) error {
+	if claims == nil || (claims.MaxSpend <= 0 && claims.MaxRequests <= 0) {
+		return nil
+	}
+	if s.usage == nil {
+		return ErrScopedTokenLimitUnavailable
+	}
+	estimate := 0.0
+	if claims.MaxSpend > 0 {
+		estimate = s.cfg.Security.ScopedToken.SpendReservation
+	}
+	reserved, err := s.usage.Reserve(ctx, claims.ID, claims.MaxRequests, claims.MaxSpend, estimate, s.usageTTL(claims))
+	if err != nil {
+		if errors.Is(err, ErrScopedTokenRequestLimit) || errors.Is(err, ErrScopedTokenSpendLimit) {
+			return err
+		}
+		logger.LegacyPrintf("service.scoped_token", "reserve usage failed token=%s: %v", claims.ID, err)
+		return ErrScopedTokenLimitUnavailable
+	}
+	if reserved > 0 {
+		claims.reservation = newScopedTokenReservation(reserved)
+	}
+	return nil
+}
+
+// RecordSpend 扣费后以实际花费结清预占；未设花费上限的令牌不计数。
+// 预占已在请求结束时退回的（异步扣费晚于请求结束），按实际花费全额累计。
+func (s *ScopedTokenService) RecordSpend(ctx context.Context, claims *ScopedTokenClaims, cost float64) {
+	if s == nil || s.usage == nil || claims == nil || claims.MaxSpend <= 0 {
+		return
+	}
+	if cost < 0 {
+		cost = 0
+	}
+	delta := cost - claims.reservation.take()
+	if delta == 0 {
+		return
+	}
+	if err := s.usage.AddSpend(ctx, claims.ID, delta, s.usageTTL(claims)); err != nil {
+		logger.LegacyPrintf("service.scoped_token", "record spend failed token=%s cost=%f: %v", claims.ID, cost, err)
+	}
+}
+
+// Release 请求结束时退回尚未被扣费结清的预占。预占只覆盖请求处理期间的并发窗口，
+// 之后的实际花费由 RecordSpend 累计；未产生扣费的请求（失败、非计费接口）不占用额度。
+func (s *ScopedTokenService) Release(ctx context.Context, claims *ScopedTokenClaims) {
+	if s == nil || s.usage == nil || claims == nil {
+		return
+	}
+	reserved := claims.reservation.take()
+	if reserved <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), scopedTokenReleaseTimeout)
+	defer cancel()
+	if err := s.usage.AddSpend(ctx, claims.ID, -reserved, s.usageTTL(claims)); err != nil {
+		logger.LegacyPrintf("service.scoped_token", "release reservation failed token=%s amount=%f: %v", claims.ID, reserved, err)
+	}
+}
+
+// RevokeAll 轮换 Key 的签名 nonce，吊销其签发的全部派生令牌。
+func (s *ScopedTokenService) RevokeAll(ctx context.Context, userID, apiKeyID int64) error {
+	if s.nonces == nil {
+		return ErrScopedTokenDisabled
+	}
+	key, err := s.keys.GetByID(ctx, apiKeyID)
+	if err != nil {
+		return err
+	}
+	if key.UserID != userID {
+		return ErrAPIKeyNotFound
+	}
+	nonce, err := newScopedTokenID()
+	if err != nil {
+		return err
+	}
+	if err := s.nonces.SetTokenNonce(ctx, apiKeyID, nonce); err != nil {
+		return fmt.Errorf("rotate scoped token nonce: %w", err)
+	}
+	// 认证快照携带 nonce，清除后各实例下次认证即读取新值。
+	s.keys.InvalidateAuthCacheByKey(ctx, key.Key)
+	return nil
+}
+
+// loadParent 按 ID 加载父 Key 的认证快照。ID 到存储值的映射缓存在本地；
+// 按缓存的存储值查不到时（Key 已轮换或删除）丢弃缓存，重新按 ID 加载一次。
+func (s *ScopedTokenService) loadParent(ctx context.Context, apiKeyID int64) (*APIKey, error) {
+	if cached, ok := s.storedKeys.Load(apiKeyID); ok {
+		parent, err := s.keys.GetByStoredKeyForAuth(ctx, cached.(string))
+		if err == nil || !errors.Is(err, ErrAPIKeyNotFound) {
+			return parent, err
+		}
+		s.storedKeys.Delete(apiKeyID)
+	}
+	key, err := s.keys.GetByID(ctx, apiKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || !IsHashedAPIKey(key.Key) {
+		return nil, ErrAPIKeyNotFound
+	}
+	parent, err := s.keys.GetByStoredKeyForAuth(ctx, key.Key)
+	if err != nil {
+		return nil, err
+	}
+	s.storedKeys.Store(apiKeyID, key.Key)
+	return parent, nil
+}
+
+// checkOriginsAllowedByCORS 要求令牌限定的来源都在 cors.allowed_origins 内：
+// 浏览器预检只按 CORS 配置放行，不在其中的来源即便写进令牌也无法发起请求。
+func (s *ScopedTokenService) checkOriginsAllowedByCORS(origins []string) error {
+	if len(origins) == 0 {
+		return nil
+	}
+	allowed := make(map[string]struct{}, len(s.cfg.CORS.AllowedOrigins))
+	for _, origin := range s.cfg.CORS.AllowedOrigins {
+		if strings.TrimSpace(origin) == "*" {
+			return nil
+		}
+		if normalized, ok := normalizeScopedTokenOrigin(origin); ok {
+			allowed[normalized] = struct{}{}
+		}
+	}
+	for _, origin := range origins {
+		if _, ok := allowed[origin]; !ok {
+			return ErrScopedTokenScopeInvalid.WithMetadata(map[string]string{"field": "origins", "origin": origin, "reason": "not_in_cors_allowed_origins"})
+		}
+	}
+	return nil
+}
+
+func (s *ScopedTokenService) resolveTTL(seconds int) (time.Duration, error) {
+	cfg := s.cfg.Security.ScopedToken
+	maxTTL := cfg.MaxTTLSeconds
+	if maxTTL <= 0 {
+		maxTTL = 86400
+	}
+	if seconds == 0 {
+		seconds = cfg.DefaultTTLSeconds
+		if seconds <= 0 {
+			seconds = 900
+		}
+		if seconds > maxTTL {
+			seconds = maxTTL
+		}
+	}
+	if seconds < 0 || seconds > maxTTL {
+		return 0, ErrScopedTokenScopeInvalid.WithMetadata(map[string]string{"field": "ttl_seconds", "max": fmt.Sprint(maxTTL)})
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func (s *ScopedTokenService) usageTTL(claims *ScopedTokenClaims) time.Duration {
+	ttl := claims.Expiry().Sub(s.now())
+	if ttl < 0 {
+		ttl = 0
+	}
+	return ttl + scopedTokenUsageTTLSlack
+}
+
+func normalizeScopedTokenModels(models []string) ([]string, error) {
+	out := make([]string, 0, len(models))
+	seen := make(map[string]struct{}, len(models))
+	for _, model := range models {
+		model = strings.TrimSpace(model)
+		if model == "" || model == "*" {
+			return nil, ErrScopedTokenScopeInvalid.WithMetadata(map[string]string{"field": "models"})
+		}
+		if _, ok := seen[model]; ok {
+			continue
+		}
+		seen[model] = struct{}{}
+		out = append(out, model)
+	}
+	if len(out) > scopedTokenMaxModels {
+		return nil, ErrScopedTokenScopeInvalid.WithMetadata(map[string]string{"field": "models", "max": fmt.Sprint(scopedTokenMaxModels)})
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+func normalizeScopedTokenOrigins(origins []string) ([]string, error) {
+	out := make([]string, 0, len(origins))
+	seen := make(map[string]struct{}, len(origins))
+	for _, origin := range origins {
+		normalized, ok := normalizeScopedTokenOrigin(origin)
+		if !ok {
+			return nil, ErrScopedTokenScopeInvalid.WithMetadata(map[string]string{"field": "origins", "origin": origin})
+		}
+		if _, ok := seen[normalized]; ok {
+			continue
+		}
+		seen[normalized] = struct{}{}
+		out = append(out, normalized)
+	}
+	if len(out) > scopedTokenMaxOrigins {
+		return nil, ErrScopedTokenScopeInvalid.WithMetadata(map[string]string{"field": "origins", "max": fmt.Sprint(scopedTokenMaxOrigins)})
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+func newScopedTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate scoped token id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
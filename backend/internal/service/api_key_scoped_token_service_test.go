@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+type scopedTokenKeysStub struct {
+	keys        map[string]*APIKey // stored key -> key
+	invalidated []string
+}
+
+func (s *scopedTokenKeysStub) GetByStoredKeyForAuth(_ context.Context, storedKey string) (*APIKey, error) {
+	key, ok := s.keys[storedKey]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	clone := *key
+	return &clone, nil
+}
+
+func (s *scopedTokenKeysStub) GetByID(_ context.Context, id int64) (*APIKey, error) {
+	for _, key := range s.keys {
+		if key.ID == id {
+			clone := *key
+			return &clone, nil
+		}
+	}
+	return nil, ErrAPIKeyNotFound
+}
+
+func (s *scopedTokenKeysStub) InvalidateAuthCacheByKey(_ context.Context, key string) {
+	s.invalidated = append(s.invalidated, key)
+}
+
+type scopedTokenNonceStoreStub struct {
+	keys *scopedTokenKeysStub
+}
+
+func (s *scopedTokenNonceStoreStub) SetTokenNonce(_ context.Context, apiKeyID int64, nonce string) error {
+	for _, key := range s.keys.keys {
+		if key.ID == apiKeyID {
+			key.TokenNonce = nonce
+			return nil
+		}
+	}
+	return ErrAPIKeyNotFound
+}
+
+type scopedTokenUsageStub struct {
+	requests map[string]int64
+	spend    map[string]float64
+	err      error
+}
+
+func newScopedTokenUsageStub() *scopedTokenUsageStub {
+	return &scopedTokenUsageStub{requests: map[string]int64{}, spend: map[string]float64{}}
+}
+
+func (u *scopedTokenUsageStub) Reserve(_ context.Context, tokenID string, maxRequests int64, maxSpend, estimate float64, _ time.Duration) (float64, error) {
+	if u.err != nil {
+		return 0, u.err
+	}
+	if maxRequests > 0 && u.requests[tokenID] >= maxRequests {
+		return 0, ErrScopedTokenRequestLimit
+	}
+	reserved := 0.0
+	if maxSpend > 0 {
+		if u.spend[tokenID] >= maxSpend {
+			return 0, ErrScopedTokenSpendLimit
+		}
+		reserved = math.Min(estimate, maxSpend-u.spend[tokenID])
+		u.spend[tokenID] += reserved
+	}
+	if maxRequests > 0 {
+		u.requests[tokenID]++
+	}
+	return reserved, nil
+}
+
+func (u *scopedTokenUsageStub) AddSpend(_ context.Context, tokenID string, amount float64, _ time.Duration) error {
+	if u.err != nil {
+		return u.err
+	}
+	u.spend[tokenID] += amount
+	return nil
+}
+
+const scopedTokenTestPlainKey = "sk-scoped-parent-key"
+
+func newScopedTokenServiceForTest(t *testing.T) (*ScopedTokenService, *scopedTokenKeysStub, *scopedTokenUsageStub, *time.Time) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Security.APIKeyHashPepper = "pepper"
+	cfg.Security.ScopedToken = config.ScopedTokenConfig{
+		Enabled:           true,
+		SigningSecret:     "scoped-secret",
+		DefaultTTLSeconds: 900,
+		MaxTTLSeconds:     3600,
+		SpendReservation:  0.5,
+	}
+	cfg.CORS.AllowedOrigins = []string{"https://app.example.com"}
+	stored := APIKeyHashScheme + NewAPIKeyHasher(cfg).Digest(scopedTokenTestPlainKey)
+	keys := &scopedTokenKeysStub{keys: map[string]*APIKey{
+		stored: {ID: 7, UserID: 3, Key: stored, TokenNonce: "n1", Status: StatusAPIKeyActive},
+	}}
+	usage := newScopedTokenUsageStub()
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	svc := &ScopedTokenService{
+		keys:   keys,
+		nonces: &scopedTokenNonceStoreStub{keys: keys},
+		usage:  usage,
+		cfg:    cfg,
+		now:    func() time.Time { return now },
+	}
+	return svc, keys, usage, &now
+}
+
+func scopedTokenTestParent() *APIKey {
+	return &APIKey{ID: 7, UserID: 3, Key: scopedTokenTestPlainKey, TokenNonce: "n1", Status: StatusAPIKeyActive}
+}
+
+func TestScopedTokenService_MintAndAuthenticate(t *testing.T) {
+	svc, _, _, now := newScopedTokenServiceForTest(t)
+
+	minted, err := svc.Mint(context.Background(), scopedTokenTestParent(), MintScopedTokenRequest{
+		TTLSeconds:  600,
+		Models:      []string{"claude-sonnet-*", " claude-sonnet-* "},
+		MaxRequests: 5,
+		Origins:     []string{"https://App.example.com/"},
+	})
+	require.NoError(t, err)
+	require.True(t, IsScopedToken(minted.Token))
+	require.Equal(t, now.Add(10*time.Minute).Unix(), minted.ExpiresAt.Unix())
+	require.Equal(t, []string{"claude-sonnet-*"}, minted.Models)
+	require.Equal(t, []string{"https://app.example.com"}, minted.Origins)
+	require.NotContains(t, minted.Token, scopedTokenTestPlainKey)
+	claims, _, _, ok := parseScopedToken(minted.Token)
+	require.True(t, ok)
+	require.Equal(t, int64(7), claims.APIKeyID)
+	require.NotContains(t, minted.Token, NewAPIKeyHasher(svc.cfg).Digest(scopedTokenTestPlainKey), "令牌不携带父 Key 摘要")
+
+	parent, err := svc.Authenticate(context.Background(), minted.Token)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), parent.ID)
+	require.NotNil(t, parent.ScopedToken)
+	require.Equal(t, minted.TokenID, parent.ScopedToken.ID)
+	require.True(t, parent.ScopedToken.AllowsModel("claude-sonnet-4-5"))
+	require.False(t, parent.ScopedToken.AllowsModel("claude-opus-4"))
+	require.True(t, parent.ScopedToken.AllowsOrigin("https://app.example.com"))
+	require.False(t, parent.ScopedToken.AllowsOrigin("https://evil.example.com"))
+	require.False(t, parent.ScopedToken.AllowsOrigin(""))
+}
+
+func TestScopedTokenService_AuthenticateRejectsTamperedAndExpired(t *testing.T) {
+	svc, _, _, now := newScopedTokenServiceForTest(t)
+	minted, err := svc.Mint(context.Background(), scopedTokenTestParent(), MintScopedTokenRequest{TTLSeconds: 60, MaxRequests: 1})
+	require.NoError(t, err)
+
+	// 篡改声明（放宽请求数）后沿用原签名，验签失败。
+	claims, _, _, ok := parseScopedToken(minted.Token)
+	require.True(t, ok)
+	claims.MaxRequests = 1000
+	forged, err := signScopedToken(claims, "other-secret", "n1")
+	require.NoError(t, err)
+	forged = forged[:strings.LastIndexByte(forged, '.')] + minted.Token[strings.LastIndexByte(minted.Token, '.'):]
+	_, err = svc.Authenticate(context.Background(), forged)
+	require.ErrorIs(t, err, ErrScopedTokenInvalid)
+
+	_, err = svc.Authenticate(context.Background(), "sst.not-a-token")
+	require.ErrorIs(t, err, ErrScopedTokenInvalid)
+
+	*now = now.Add(2 * time.Minute)
+	_, err = svc.Authenticate(context.Background(), minted.Token)
+	require.ErrorIs(t, err, ErrScopedTokenExpired)
+}
+
+func TestScopedTokenService_RevokeAllRotatesNonce(t *testing.T) {
+	svc, keys, _, _ := newScopedTokenServiceForTest(t)
+	minted, err := svc.Mint(context.Background(), scopedTokenTestParent(), MintScopedTokenRequest{})
+	require.NoError(t, err)
+	_, err = svc.Authenticate(context.Background(), minted.Token)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, svc.RevokeAll(context.Background(), 99, 7), ErrAPIKeyNotFound)
+	require.NoError(t, svc.RevokeAll(context.Background(), 3, 7))
+	require.Len(t, keys.invalidated, 1)
+
+	_, err = svc.Authenticate(context.Background(), minted.Token)
+	require.ErrorIs(t, err, ErrScopedTokenInvalid)
+}
+
+func TestScopedTokenService_MintValidation(t *testing.T) {
+	svc, _, _, now := newScopedTokenServiceForTest(t)
+	ctx := context.Background()
+
+	_, err := svc.Mint(ctx, scopedTokenTestParent(), MintScopedTokenRequest{TTLSeconds: 7200})
+	require.ErrorIs(t, err, ErrScopedTokenScopeInvalid)
+	_, err = svc.Mint(ctx, scopedTokenTestParent(), MintScopedTokenRequest{Models: []string{"*"}})
+	require.ErrorIs(t, err, ErrScopedTokenScopeInvalid)
+	_, err = svc.Mint(ctx, scopedTokenTestParent(), MintScopedTokenRequest{Origins: []string{"javascript:alert(1)"}})
+	require.ErrorIs(t, err, ErrScopedTokenScopeInvalid)
+	// 不在 cors.allowed_origins 中的来源无法通过浏览器预检，拒绝签发。
+	_, err = svc.Mint(ctx, scopedTokenTestParent(), MintScopedTokenRequest{Origins: []string{"https://other.example.com"}})
+	require.ErrorIs(t, err, ErrScopedTokenScopeInvalid)
+	svc.cfg.CORS.AllowedOrigins = []string{"*"}
+	_, err = svc.Mint(ctx, scopedTokenTestParent(), MintScopedTokenRequest{Origins: []string{"https://other.example.com"}})
+	require.NoError(t, err)
+	_, err = svc.Mint(ctx, scopedTokenTestParent(), MintScopedTokenRequest{MaxSpend: -1})
+	require.ErrorIs(t, err, ErrScopedTokenScopeInvalid)
+
+	derived := scopedTokenTestParent()
+	derived.ScopedToken = &ScopedTokenClaims{ID: "x"}
+	_, err = svc.Mint(ctx, derived, MintScopedTokenRequest{})
+	require.ErrorIs(t, err, ErrScopedTokenNotMintable)
+
+	// 令牌有效期不超过父 Key 的过期时间。
+	parent := scopedTokenTestParent()
+	parentExpiry := now.Add(5 * time.Minute)
+	parent.ExpiresAt = &parentExpiry
+	minted, err := svc.Mint(ctx, parent, MintScopedTokenRequest{TTLSeconds: 3600})
+	require.NoError(t, err)
+	require.Equal(t, parentExpiry.Unix(), minted.ExpiresAt.Unix())
+
+	svc.cfg.Security.ScopedToken.Enabled = false
+	_, err = svc.Mint(ctx, scopedTokenTestParent(), MintScopedTokenRequest{})
+	require.ErrorIs(t, err, ErrScopedTokenDisabled)
+}
+
+func TestScopedTokenService_AdmitEnforcesLimits(t *testing.T) {
+	svc, _, usage, _ := newScopedTokenServiceForTest(t)
+	ctx := context.Background()
+
+	require.NoError(t, svc.Admit(ctx, nil))
+
+	requests := &ScopedTokenClaims{ID: "req", ExpiresAt: svc.now().Add(time.Hour).Unix(), MaxRequests: 2}
+	require.NoError(t, svc.Admit(ctx, requests))
+	require.NoError(t, svc.Admit(ctx, requests))
+	require.ErrorIs(t, svc.Admit(ctx, requests), ErrScopedTokenRequestLimit)
+
+	spend := &ScopedTokenClaims{ID: "spend", ExpiresAt: svc.now().Add(time.Hour).Unix(), MaxSpend: 1}
+	require.NoError(t, svc.Admit(ctx, spend))
+	svc.RecordSpend(ctx, spend, 0.6)
+	require.NoError(t, svc.Admit(ctx, spend))
+	svc.RecordSpend(ctx, spend, 0.6)
+	require.ErrorIs(t, svc.Admit(ctx, spend), ErrScopedTokenSpendLimit)
+
+	// 计数不可用时拒绝，避免越过令牌限制。
+	usage.err = errors.New("redis down")
+	require.ErrorIs(t, svc.Admit(ctx, requests), ErrScopedTokenLimitUnavailable)
+}
+
+func TestScopedTokenService_AdmitReservesSpendForConcurrentRequests(t *testing.T) {
+	svc, _, usage, _ := newScopedTokenServiceForTest(t)
+	ctx := context.Background()
+	expiry := svc.now().Add(time.Hour).Unix()
+
+	// 同一令牌的三个并发请求：前两个各预占 0.5 占满额度，第三个在任何扣费之前就被拒绝。
+	first := &ScopedTokenClaims{ID: "conc", ExpiresAt: expiry, MaxSpend: 1}
+	second := &ScopedTokenClaims{ID: "conc", ExpiresAt: expiry, MaxSpend: 1}
+	third := &ScopedTokenClaims{ID: "conc", ExpiresAt: expiry, MaxSpend: 1}
+	require.NoError(t, svc.Admit(ctx, first))
+	require.NoError(t, svc.Admit(ctx, second))
+	require.ErrorIs(t, svc.Admit(ctx, third), ErrScopedTokenSpendLimit)
+
+	// 扣费以实际花费结清预占；之后的释放不再重复退回。
+	svc.RecordSpend(ctx, first, 0.2)
+	svc.Release(ctx, first)
+	require.InDelta(t, 0.7, usage.spend["conc"], 1e-9)
+
+	// 未扣费的请求结束时退回预占；释放后才到达的扣费按实际花费全额累计。
+	svc.Release(ctx, second)
+	require.InDelta(t, 0.2, usage.spend["conc"], 1e-9)
+	svc.RecordSpend(ctx, second, 0.3)
+	require.InDelta(t, 0.5, usage.spend["conc"], 1e-9)
+
+	require.NoError(t, svc.Admit(ctx, third))
+}
+
+func TestScopedTokenService_AuthenticateReloadsParentAfterKeyChange(t *testing.T) {
+	svc, keys, _, _ := newScopedTokenServiceForTest(t)
+	ctx := context.Background()
+	minted, err := svc.Mint(ctx, scopedTokenTestParent(), MintScopedTokenRequest{})
+	require.NoError(t, err)
+	_, err = svc.Authenticate(ctx, minted.Token)
+	require.NoError(t, err)
+
+	// 父 Key 的存储值变化（ID 不变）后，按 ID 重新加载。
+	rotated := APIKeyHashScheme + NewAPIKeyHasher(svc.cfg).Digest("sk-scoped-parent-rotated")
+	for stored, key := range keys.keys {
+		delete(keys.keys, stored)
+		key.Key = rotated
+		keys.keys[rotated] = key
+	}
+	parent, err := svc.Authenticate(ctx, minted.Token)
+	require.NoError(t, err)
+	require.Equal(t, rotated, parent.Key)
+
+	// 父 Key 删除后令牌失效。
+	delete(keys.keys, rotated)
+	_, err = svc.Authenticate(ctx, minted.Token)
+	require.ErrorIs(t, err, ErrScopedTokenInvalid)
+}
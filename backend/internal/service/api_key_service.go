@@ -290,6 +290,8 @@ type APIKeyService struct {
 	userGroupRateRepo         UserGroupRateRepository
 	cache                     APIKeyCache
 	rateLimitCacheInvalid     RateLimitCacheInvalidator // optional: invalidate Redis rate limit cache
+	rotationGrace             APIKeyRotationGraceLookup // optional: rotated keys still inside their grace period
+	scopedTokens              ScopedTokenAuthenticator  // optional: short-lived tokens derived from keys
 	concurrencyService        *ConcurrencyService
 	cfg                       *config.Config
 	authCacheL1               *ristretto.Cache
@@ -307,8 +309,11 @@ type APIKeyService struct {
 	authInvalidationWG        sync.WaitGroup
 	authInvalidationConnected atomic.Bool
 	authInvalidationFailures  atomic.Uint64
-	lastUsedTouchL1           sync.Map // keyID -> nextAllowedAt(time.Time)
-	lastUsedTouchSF           singleflight.Group
+	// authCacheLegacyPending 为 true 时仍有未回填的明文 Key：数据库触发器无法为其算出
+	// 与 authCacheKey 一致的 HMAC 摘要，失效事件会落空，因此暂不读写正向认证缓存。
+	authCacheLegacyPending atomic.Bool
+	lastUsedTouchL1        sync.Map // keyID -> nextAllowedAt(time.Time)
+	lastUsedTouchSF        singleflight.Group
 }
 
 type APIKeyAuthLookupMetrics struct {
@@ -365,6 +370,61 @@ func (s *APIKeyService) SetRateLimitCacheInvalidator(inv RateLimitCacheInvalidat
 	s.rateLimitCacheInvalid = inv
 }
 
+// SetRotationGraceLookup sets the optional lookup used by the auth middleware to
+// flag keys that have been rotated but are still inside their grace period.
+func (s *APIKeyService) SetRotationGraceLookup(lookup APIKeyRotationGraceLookup) {
+	s.rotationGrace = lookup
+}
+
+// RotationGraceDeadline 返回已被轮换的旧 Key 的宽限截止时间；未被轮换或已过宽限期时 ok 为 false。
+func (s *APIKeyService) RotationGraceDeadline(apiKeyID int64) (time.Time, bool) {
+	if s == nil || s.rotationGrace == nil {
+		return time.Time{}, false
+	}
+	return s.rotationGrace.GraceDeadline(apiKeyID)
+}
+
+// SetScopedTokenAuthenticator sets the optional authenticator for short-lived
+// scoped tokens. Without it, scoped tokens are rejected.
+func (s *APIKeyService) SetScopedTokenAuthenticator(auth ScopedTokenAuthenticator) {
+	s.scopedTokens = auth
+}
+
+// GetByScopedToken 校验派生令牌并返回挂有令牌声明的父 Key（用于认证）。
+func (s *APIKeyService) GetByScopedToken(ctx context.Context, token string) (*APIKey, error) {
+	if s == nil || s.scopedTokens == nil {
+		return nil, ErrScopedTokenDisabled
+	}
+	return s.scopedTokens.Authenticate(ctx, token)
+}
+
+// AdmitScopedToken 执行派生令牌的请求数与花费上限；非令牌请求直接放行。
+func (s *APIKeyService) AdmitScopedToken(ctx context.Context, claims *ScopedTokenClaims) error {
+	if claims == nil {
+		return nil
+	}
+	if s == nil || s.scopedTokens == nil {
+		return ErrScopedTokenDisabled
+	}
+	return s.scopedTokens.Admit(ctx, claims)
+}
+
+// RecordScopedTokenSpend 扣费后累计派生令牌的花费（计费链路经 APIKeyQuotaUpdater 调用）。
+func (s *APIKeyService) RecordScopedTokenSpend(ctx context.Context, claims *ScopedTokenClaims, cost float64) {
+	if s == nil || s.scopedTokens == nil || claims == nil {
+		return
+	}
+	s.scopedTokens.RecordSpend(ctx, claims, cost)
+}
+
+// ReleaseScopedToken 请求结束时退回派生令牌尚未被扣费结清的花费预占。
+func (s *APIKeyService) ReleaseScopedToken(ctx context.Context, claims *ScopedTokenClaims) {
+	if s == nil || s.scopedTokens == nil || claims == nil {
+		return
+	}
+	s.scopedTokens.Release(ctx, claims)
+}
+
 func (s *APIKeyService) SetConcurrencyService(concurrencyService *ConcurrencyService) {
 	s.concurrencyService = concurrencyService
 }
@@ -534,6 +594,7 @@ func (s *APIKeyService) Create(ctx context.Context, userID int64, req CreateAPIK
 	apiKey := &APIKey{
 		UserID:      userID,
 		Key:         key,
+		KeyPrefix:   APIKeyDisplayPrefix(key),
 		Name:        html.EscapeString(req.Name),
 		GroupID:     req.GroupID,
 		Status:      StatusActive,
@@ -702,7 +763,8 @@ func (s *APIKeyService) GetByID(ctx context.Context, id int64) (*APIKey, error)
 
 // GetByKey 根据Key字符串获取API Key（用于认证）
 func (s *APIKeyService) GetByKey(ctx context.Context, key string) (*APIKey, error) {
-	if len(key) == 0 || len(key) > MaxAPIKeyCredentialBytes {
+	// 哈希形态不是合法的明文 Key，拒绝它以免泄露的存储值被直接当作凭证使用。
+	if len(key) == 0 || len(key) > MaxAPIKeyCredentialBytes || IsHashedAPIKey(key) {
 		return nil, ErrAPIKeyNotFound
 	}
 	cacheKey := s.authCacheKey(key)
@@ -453,6 +453,93 @@ func TestAPIKeyService_GetByKey_CacheMissStoresL2(t *testing.T) {
 	require.Len(t, cache.setAuthKeys, 1)
 }
 
+type legacyKeyBackfillRepoStub struct {
+	pending bool
+}
+
+func (s *legacyKeyBackfillRepoStub) HashLegacyAPIKeys(context.Context, int) (int, error) {
+	s.pending = false
+	return 0, nil
+}
+
+func (s *legacyKeyBackfillRepoStub) HasLegacyAPIKeys(context.Context) (bool, error) {
+	return s.pending, nil
+}
+
+func TestAPIKeyService_GetByKey_BypassesCacheUntilLegacyBackfillCompletes(t *testing.T) {
+	var calls int32
+	cache := &authCacheStub{}
+	repo := &authRepoStub{
+		getByKeyForAuth: func(ctx context.Context, key string) (*APIKey, error) {
+			atomic.AddInt32(&calls, 1)
+			return &APIKey{
+				ID:     6,
+				UserID: 8,
+				Status: StatusActive,
+				User:   &User{ID: 8, Status: StatusActive, Role: RoleUser, Balance: 1, Concurrency: 1},
+			}, nil
+		},
+	}
+	cfg := &config.Config{
+		APIKeyAuth: config.APIKeyAuthCacheConfig{
+			L2TTLSeconds:       60,
+			NegativeTTLSeconds: 30,
+		},
+	}
+	svc := NewAPIKeyService(repo, nil, nil, nil, nil, cache, cfg)
+	cache.getAuthCache = func(ctx context.Context, key string) (*APIKeyAuthCacheEntry, error) {
+		return &APIKeyAuthCacheEntry{NotFound: true}, nil
+	}
+
+	backfillRepo := &legacyKeyBackfillRepoStub{pending: true}
+	backfill := NewAPIKeyHashBackfillService(backfillRepo, time.Minute)
+	backfill.SetAuthCacheGate(context.Background(), svc)
+
+	// 回填未完成：不读 L2 中可能已过期的条目，也不写入新条目。
+	apiKey, err := svc.GetByKey(context.Background(), "k-legacy")
+	require.NoError(t, err)
+	require.Equal(t, int64(6), apiKey.ID)
+	require.Empty(t, cache.setAuthKeys)
+	require.EqualValues(t, 1, atomic.LoadInt32(&calls))
+
+	backfill.runOnce()
+	require.False(t, svc.authCacheLegacyPending.Load())
+
+	_, err = svc.GetByKey(context.Background(), "k-legacy")
+	require.ErrorIs(t, err, ErrAPIKeyNotFound, "回填完成后恢复读取 L2")
+	require.EqualValues(t, 1, atomic.LoadInt32(&calls))
+}
+
+type legacyKeyGateRepoStub struct {
+	pending atomic.Bool
+}
+
+func (s *legacyKeyGateRepoStub) HashLegacyAPIKeys(context.Context, int) (int, error) {
+	panic("unexpected HashLegacyAPIKeys call")
+}
+
+func (s *legacyKeyGateRepoStub) HasLegacyAPIKeys(context.Context) (bool, error) {
+	return s.pending.Load(), nil
+}
+
+func TestAPIKeyHashBackfillService_DisabledIntervalStillRefreshesAuthCacheGate(t *testing.T) {
+	svc := NewAPIKeyService(&authRepoStub{}, nil, nil, nil, nil, &authCacheStub{}, &config.Config{})
+	repo := &legacyKeyGateRepoStub{}
+	repo.pending.Store(true)
+
+	backfill := NewAPIKeyHashBackfillService(repo, 0)
+	backfill.gateInterval = 10 * time.Millisecond
+	backfill.SetAuthCacheGate(context.Background(), svc)
+	require.True(t, svc.authCacheLegacyPending.Load())
+
+	backfill.Start()
+	t.Cleanup(backfill.Stop)
+
+	// 其它实例完成回填后，本实例不回填也能恢复认证缓存。
+	repo.pending.Store(false)
+	require.Eventually(t, func() bool { return !svc.authCacheLegacyPending.Load() }, time.Second, 5*time.Millisecond)
+}
+
 func TestAPIKeyService_GetByKey_UsesL1Cache(t *testing.T) {
 	var calls int32
 	cache := &authCacheStub{}
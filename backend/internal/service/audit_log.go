@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"strconv"
 	"strings"
@@ -14,12 +16,21 @@ import (
 // ErrAuditLogNotFound 审计日志不存在。
 var ErrAuditLogNotFound = infraerrors.NotFound("AUDIT_LOG_NOT_FOUND", "audit log not found")
 
+var (
+	// ErrAuditLogArchiveStoreNotConfigured 未配置对象存储时不允许清空/清理审计日志。
+	ErrAuditLogArchiveStoreNotConfigured = infraerrors.BadRequest("AUDIT_LOG_ARCHIVE_STORE_NOT_CONFIGURED", "audit logs can only be cleared by archiving to object storage; configure backup S3 storage first")
+	// ErrAuditLogArchiveConflict 并发归档冲突（另一归档已覆盖相同区间）。
+	ErrAuditLogArchiveConflict = infraerrors.Conflict("AUDIT_LOG_ARCHIVE_CONFLICT", "another audit log archive is in progress, retry later")
+)
+
 // 审计日志相关常量。
 const (
 	// AuditAuthMethodJWT / AuditAuthMethodAdminAPIKey 与 auth 中间件写入的 auth_method 对齐。
 	AuditAuthMethodJWT         = "jwt"
 	AuditAuthMethodAdminAPIKey = "admin_api_key"
 	AuditAuthMethodPasskey     = "passkey"
+	// AuditAuthMethodSystem 后台任务自动产生的记录（无请求上下文）。
+	AuditAuthMethodSystem = "system"
 
 	// auditRequestBodyMaxBytes 请求体脱敏后入库的最大长度（字节），超出截断。
 	auditRequestBodyMaxBytes = 16 * 1024
@@ -36,7 +47,15 @@ const (
 	AuditActionTokenRefresh           = "auth.token.refresh"
 	AuditActionSessionBindingMismatch = "auth.session_binding.mismatch"
 	AuditActionStepUpVerify           = "auth.step_up.verify"
-	AuditActionAuditLogClear          = "admin.audit_log.clear"
+	AuditActionAuditLogArchive        = "admin.audit_log.archive"
+	AuditActionAPIKeyLeakDetected     = "api_key.leak_detected"
+	AuditActionAPIKeyRotated          = "api_key.rotated"
+	AuditActionScopedTokensRevoked    = "api_key.scoped_tokens_revoked"
+	AuditActionDataExportRequested    = "user.data_export.request"
+	AuditActionDataExportDownloaded   = "user.data_export.download"
+	AuditActionAccountDeletionRequest = "user.account_deletion.request"
+	AuditActionAccountDeletionCancel  = "user.account_deletion.cancel"
+	AuditActionAccountDeleted         = "user.account_deletion.completed"
 )
 
 // AuditLog 一条管理面操作审计记录。
@@ -58,6 +77,70 @@ type AuditLog struct {
 	StatusCode       int            `json:"status_code"`
 	LatencyMs        int64          `json:"latency_ms"`
 	Extra            map[string]any `json:"extra,omitempty"`
+	// PrevHash / RowHash 哈希链字段（由仓储写入时计算，历史记录为空）。
+	PrevHash string `json:"prev_hash,omitempty"`
+	RowHash  string `json:"row_hash,omitempty"`
+}
+
+// 审计归档触发来源。
+const (
+	AuditLogArchiveTriggerManual    = "manual"
+	AuditLogArchiveTriggerRetention = "retention"
+)
+
+// AuditLogArchive 一次归档：连续 id 区间 [FirstID, LastID] 已上传对象存储并从表中删除。
+// LastRowHash 作为剩余记录的链锚点。
+type AuditLogArchive struct {
+	ID            int64     `json:"id"`
+	ObjectKey     string    `json:"object_key"`
+	FirstID       int64     `json:"first_id"`
+	LastID        int64     `json:"last_id"`
+	RowCount      int64     `json:"row_count"`
+	FirstPrevHash string    `json:"first_prev_hash"`
+	LastRowHash   string    `json:"last_row_hash"`
+	ObjectSHA256  string    `json:"object_sha256"`
+	SizeBytes     int64     `json:"size_bytes"`
+	ChainValid    bool      `json:"chain_valid"`
+	TriggeredBy   string    `json:"triggered_by"`
+	ActorUserID   *int64    `json:"actor_user_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// AuditLogChainBreak 哈希链上的首个断点。
+type AuditLogChainBreak struct {
+	// LogID 出现断点的审计记录 id；归档锚点断裂时为 0。
+	LogID int64 `json:"log_id,omitempty"`
+	// ArchiveID 归档锚点之间断裂时的归档 id。
+	ArchiveID int64 `json:"archive_id,omitempty"`
+	// Reason prev_hash_mismatch / row_hash_mismatch / missing_hash / archive_gap
+	Reason           string `json:"reason"`
+	ExpectedPrevHash string `json:"expected_prev_hash,omitempty"`
+	ActualPrevHash   string `json:"actual_prev_hash,omitempty"`
+	ExpectedRowHash  string `json:"expected_row_hash,omitempty"`
+	ActualRowHash    string `json:"actual_row_hash,omitempty"`
+}
+
+// AuditLogChainVerification 链校验结果。
+type AuditLogChainVerification struct {
+	Valid           bool                `json:"valid"`
+	CheckedRows     int64               `json:"checked_rows"`
+	LegacyRows      int64               `json:"legacy_rows"`
+	ArchivesChecked int                 `json:"archives_checked"`
+	AnchorHash      string              `json:"anchor_hash"`
+	HeadID          int64               `json:"head_id"`
+	HeadHash        string              `json:"head_hash"`
+	FirstBroken     *AuditLogChainBreak `json:"first_broken,omitempty"`
+	VerifiedAt      time.Time           `json:"verified_at"`
+}
+
+// AuditLogExportCursor SIEM sink 的外送游标。
+type AuditLogExportCursor struct {
+	SinkName       string     `json:"sink_name"`
+	LastID         int64      `json:"last_id"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastErrorAt    *time.Time `json:"last_error_at,omitempty"`
+	DeliveredCount int64      `json:"delivered_count"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
 // AuditLogFilter 审计日志列表查询条件。
@@ -88,18 +171,108 @@ type AuditLogList struct {
 }
 
 // AuditLogRepository 审计日志持久化端口。
-// 注意：接口刻意不提供单条删除能力——审计日志只允许追加与全量清空。
+// 注意：接口刻意不提供任意删除能力——审计日志只允许追加，以及归档到对象存储后删除已归档的 id 前缀。
+// 写入时由实现按 id 顺序计算哈希链（见 ComputeAuditLogHash）。
 type AuditLogRepository interface {
 	BatchInsert(ctx context.Context, logs []*AuditLog) (int64, error)
-	// Insert 同步写入单条（用于清空留痕等必须落库的记录）。
+	// Insert 同步写入单条（用于归档留痕等必须落库的记录）。
 	Insert(ctx context.Context, log *AuditLog) error
 	List(ctx context.Context, filter *AuditLogFilter) (*AuditLogList, error)
 	GetByID(ctx context.Context, id int64) (*AuditLog, error)
 	Count(ctx context.Context) (int64, error)
-	// TruncateAll 全量清空（TRUNCATE），返回前需调用方自行 Count 记录行数。
-	TruncateAll(ctx context.Context) error
-	// DeleteBefore 按保留期批量删除，返回本批删除行数（幂等，可多实例并发）。
-	DeleteBefore(ctx context.Context, cutoff time.Time, batchSize int) (int64, error)
+	// ListAfter 按 id 升序读取 id > afterID 的完整记录；upToID > 0 时不超过 upToID。
+	ListAfter(ctx context.Context, afterID, upToID int64, limit int) ([]*AuditLog, error)
+	// MaxID 返回最大 id；before 非空时仅统计 created_at < before 的记录。无记录返回 0。
+	MaxID(ctx context.Context, before *time.Time) (int64, error)
+	// ListArchives 按 id 升序返回全部归档记录。
+	ListArchives(ctx context.Context) ([]*AuditLogArchive, error)
+	// CommitArchive 在同一事务中写入归档记录并删除 id <= archive.LastID 的记录，返回删除行数。
+	// 已有归档覆盖 archive.FirstID 时返回 ErrAuditLogArchiveConflict。
+	CommitArchive(ctx context.Context, archive *AuditLogArchive) (int64, error)
+}
+
+// AuditLogExportCursorRepository SIEM 外送游标持久化端口。
+type AuditLogExportCursorRepository interface {
+	ListCursors(ctx context.Context) ([]*AuditLogExportCursor, error)
+	// AdvanceCursor 批次送达后推进游标（只进不退）并清除错误。
+	AdvanceCursor(ctx context.Context, sinkName string, lastID int64, delivered int64) error
+	// RecordCursorError 记录发送失败，游标不变。
+	RecordCursorError(ctx context.Context, sinkName string, errMsg string) error
+}
+
+// auditLogHashVersion 哈希规范版本，变更字段集时递增。
+const auditLogHashVersion = 1
+
+// auditLogHashPayload 参与哈希的规范化字段（不含自增 id：链顺序即 id 顺序，删除/重排会使 prev_hash 对不上）。
+type auditLogHashPayload struct {
+	V                int            `json:"v"`
+	PrevHash         string         `json:"prev_hash"`
+	CreatedAt        string         `json:"created_at"`
+	ActorUserID      int64          `json:"actor_user_id"`
+	ActorEmail       string         `json:"actor_email"`
+	ActorRole        string         `json:"actor_role"`
+	AuthMethod       string         `json:"auth_method"`
+	CredentialMasked string         `json:"credential_masked"`
+	Action           string         `json:"action"`
+	Method           string         `json:"method"`
+	Path             string         `json:"path"`
+	RequestID        string         `json:"request_id"`
+	ClientIP         string         `json:"client_ip"`
+	UserAgent        string         `json:"user_agent"`
+	RequestBody      string         `json:"request_body"`
+	StatusCode       int            `json:"status_code"`
+	LatencyMs        int64          `json:"latency_ms"`
+	Extra            map[string]any `json:"extra"`
+}
+
+// NormalizeAuditLogExtra 将 extra 规范化为 JSON 往返后的形态（数字统一为 float64、空值为 nil），
+// 使写入时计算的哈希与从 jsonb 读回后重算的结果一致。
+func NormalizeAuditLogExtra(extra map[string]any) map[string]any {
+	if len(extra) == 0 {
+		return nil
+	}
+	encoded, err := json.Marshal(extra)
+	if err != nil {
+		return nil
+	}
+	out := make(map[string]any)
+	if err := json.Unmarshal(encoded, &out); err != nil || len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// ComputeAuditLogHash 计算一条审计记录的链哈希：SHA-256(prevHash || 规范化 JSON)，十六进制小写。
+// 调用方需保证 log 已是落库形态（字段已截断、created_at 为微秒精度、extra 已规范化）。
+func ComputeAuditLogHash(prevHash string, log *AuditLog) string {
+	payload := auditLogHashPayload{
+		V:                auditLogHashVersion,
+		PrevHash:         prevHash,
+		CreatedAt:        log.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+		ActorEmail:       log.ActorEmail,
+		ActorRole:        log.ActorRole,
+		AuthMethod:       log.AuthMethod,
+		CredentialMasked: log.CredentialMasked,
+		Action:           log.Action,
+		Method:           log.Method,
+		Path:             log.Path,
+		RequestID:        log.RequestID,
+		ClientIP:         log.ClientIP,
+		UserAgent:        log.UserAgent,
+		RequestBody:      log.RequestBody,
+		StatusCode:       log.StatusCode,
+		LatencyMs:        log.LatencyMs,
+		Extra:            NormalizeAuditLogExtra(log.Extra),
+	}
+	if log.ActorUserID != nil {
+		payload.ActorUserID = *log.ActorUserID
+	}
+	encoded, _ := json.Marshal(payload)
+	h := sha256.New()
+	_, _ = h.Write([]byte(prevHash))
+	_, _ = h.Write([]byte{'\n'})
+	_, _ = h.Write(encoded)
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 // auditNormalizeBodyKey 归一化键名：小写并去除分隔符，
@@ -0,0 +1,440 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+)
+
+// 审计外送 sink 类型与事件格式。
+const (
+	AuditLogSinkTypeSyslog = "syslog"
+	AuditLogSinkTypeHTTPS  = "https"
+
+	AuditLogExportFormatJSON = "json"
+	AuditLogExportFormatCEF  = "cef"
+
+	auditExportDefaultTimeout = 10 * time.Second
+	// auditSyslogFacilityLogAudit RFC 5424 facility 13（log audit）。
+	auditSyslogFacilityLogAudit = 13
+	// auditSyslogSDID RFC 5424 structured-data id（32473 为 IANA 文档示例用 PEN）。
+	auditSyslogSDID    = "audit@32473"
+	auditExportAppName = "sub2api"
+)
+
+// AuditLogExportSink 审计事件外送目标。
+// Send 按顺序发送，返回从头开始已确认送达的条数；游标只推进到该位置。
+type AuditLogExportSink interface {
+	Name() string
+	Send(ctx context.Context, logs []*AuditLog) (int, error)
+	Close() error
+}
+
+// auditLogExportEvent JSON 外送事件：审计记录全部字段（含哈希链）加来源信息。
+// id 与 row_hash 在重发时保持不变，下游可据此去重。
+type auditLogExportEvent struct {
+	EventType string `json:"event_type"`
+	Host      string `json:"host"`
+	Outcome   string `json:"outcome"`
+	*AuditLog
+}
+
+// auditLogFormatter 将审计记录编码为单条事件文本。
+type auditLogFormatter struct {
+	format   string
+	hostname string
+	version  string
+}
+
+func auditLogOutcome(log *AuditLog) string {
+	if log.StatusCode >= 400 {
+		return "failure"
+	}
+	return "success"
+}
+
+func (f *auditLogFormatter) encode(log *AuditLog) ([]byte, error) {
+	if f.format == AuditLogExportFormatCEF {
+		return []byte(FormatAuditLogCEF(log, f.version)), nil
+	}
+	return json.Marshal(auditLogExportEvent{
+		EventType: "sub2api.audit",
+		Host:      f.hostname,
+		Outcome:   auditLogOutcome(log),
+		AuditLog:  log,
+	})
+}
+
+// cefHeaderEscaper / cefExtensionEscaper 按 CEF 规范转义：头部字段转义 \ 与 |，扩展值转义 \ 与 = 并编码换行。
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+)
+
+func auditLogCEFSeverity(log *AuditLog) int {
+	switch {
+	case log.Action == AuditActionAuditLogArchive:
+		return 7
+	case log.StatusCode >= 400:
+		return 5
+	default:
+		return 3
+	}
+}
+
+// FormatAuditLogCEF 将审计记录编码为 ArcSight CEF:0 事件。
+func FormatAuditLogCEF(log *AuditLog, version string) string {
+	if strings.TrimSpace(version) == "" {
+		version = "unknown"
+	}
+	action := log.Action
+	if action == "" {
+		action = "unknown"
+	}
+	header := []string{
+		"CEF:0",
+		"Sub2API",
+		"sub2api",
+		cefHeaderEscaper.Replace(version),
+		cefHeaderEscaper.Replace(action),
+		cefHeaderEscaper.Replace(strings.TrimSpace(log.Method + " " + log.Path)),
+		strconv.Itoa(auditLogCEFSeverity(log)),
+	}
+
+	ext := make([]string, 0, 20)
+	add := func(key, value string) {
+		if value == "" {
+			return
+		}
+		ext = append(ext, key+"="+cefExtensionEscaper.Replace(value))
+	}
+	add("rt", strconv.FormatInt(log.CreatedAt.UnixMilli(), 10))
+	add("externalId", strconv.FormatInt(log.ID, 10))
+	add("act", action)
+	add("outcome", auditLogOutcome(log))
+	if log.ActorUserID != nil {
+		add("suid", strconv.FormatInt(*log.ActorUserID, 10))
+	}
+	add("suser", log.ActorEmail)
+	add("spriv", log.ActorRole)
+	add("src", log.ClientIP)
+	add("requestMethod", log.Method)
+	add("request", log.Path)
+	add("requestClientApplication", log.UserAgent)
+	add("cn1Label", "statusCode")
+	add("cn1", strconv.Itoa(log.StatusCode))
+	add("cn2Label", "latencyMs")
+	add("cn2", strconv.FormatInt(log.LatencyMs, 10))
+	add("cs1Label", "requestId")
+	add("cs1", log.RequestID)
+	add("cs2Label", "authMethod")
+	add("cs2", log.AuthMethod)
+	add("cs3Label", "rowHash")
+	add("cs3", log.RowHash)
+	add("cs4Label", "prevHash")
+	add("cs4", log.PrevHash)
+	return strings.Join(header, "|") + "|" + strings.Join(ext, " ")
+}
+
+// rfc5424Field 将头部字段限制为可打印 ASCII（无空格）并截断，空值为 NILVALUE "-"。
+func rfc5424Field(value string, max int) string {
+	var b strings.Builder
+	for _, r := range value {
+		if b.Len() >= max {
+			break
+		}
+		if r > 32 && r < 127 {
+			_, _ = b.WriteRune(r)
+		} else {
+			_ = b.WriteByte('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "-"
+	}
+	return b.String()
+}
+
+var rfc5424ParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// FormatAuditLogRFC5424 生成一条 RFC 5424 syslog 消息（不含传输分帧）。
+func FormatAuditLogRFC5424(log *AuditLog, facility int, hostname, appName string, procID int, msg []byte) []byte {
+	severity := 5 // notice
+	if log.StatusCode >= 400 {
+		severity = 4 // warning
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(msg) + 256)
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s %d %s ",
+		facility*8+severity,
+		log.CreatedAt.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		rfc5424Field(hostname, 255),
+		rfc5424Field(appName, 48),
+		procID,
+		rfc5424Field(log.Action, 32),
+	)
+	fmt.Fprintf(&buf, `[%s id="%d" hash="%s" prev="%s" status="%d"]`,
+		auditSyslogSDID, log.ID,
+		rfc5424ParamEscaper.Replace(log.RowHash),
+		rfc5424ParamEscaper.Replace(log.PrevHash),
+		log.StatusCode,
+	)
+	buf.WriteString(" \xEF\xBB\xBF")
+	buf.Write(msg)
+	return buf.Bytes()
+}
+
+func buildAuditExportTLSConfig(sink config.AuditLogSinkConfig, serverName string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if caFile := strings.TrimSpace(sink.CAFile); caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_file contains no PEM certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if certFile := strings.TrimSpace(sink.CertFile); certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, strings.TrimSpace(sink.KeyFile))
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func auditExportTimeout(sink config.AuditLogSinkConfig) time.Duration {
+	if sink.TimeoutSeconds > 0 {
+		return time.Duration(sink.TimeoutSeconds) * time.Second
+	}
+	return auditExportDefaultTimeout
+}
+
+// syslogAuditSink RFC 5424 over TCP/TLS，使用 RFC 6587 octet-counting 分帧（RFC 5425 要求）。
+// 连接复用，写失败后关闭并在下一批重连。
+type syslogAuditSink struct {
+	name      string
+	network   string
+	address   string
+	facility  int
+	appName   string
+	hostname  string
+	procID    int
+	timeout   time.Duration
+	tlsConfig *tls.Config
+	formatter *auditLogFormatter
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newSyslogAuditSink(sink config.AuditLogSinkConfig, formatter *auditLogFormatter) (*syslogAuditSink, error) {
+	address := strings.TrimSpace(sink.Address)
+	s := &syslogAuditSink{
+		name:      strings.TrimSpace(sink.Name),
+		network:   strings.ToLower(strings.TrimSpace(sink.Network)),
+		address:   address,
+		facility:  sink.Facility,
+		appName:   strings.TrimSpace(sink.AppName),
+		hostname:  formatter.hostname,
+		procID:    os.Getpid(),
+		timeout:   auditExportTimeout(sink),
+		formatter: formatter,
+	}
+	if s.network == "" {
+		s.network = "tcp"
+	}
+	if s.facility == 0 {
+		s.facility = auditSyslogFacilityLogAudit
+	}
+	if s.appName == "" {
+		s.appName = auditExportAppName
+	}
+	if s.network == "tls" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig, err := buildAuditExportTLSConfig(sink, host)
+		if err != nil {
+			return nil, err
+		}
+		s.tlsConfig = tlsConfig
+	}
+	return s, nil
+}
+
+func (s *syslogAuditSink) Name() string { return s.name }
+
+func (s *syslogAuditSink) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.timeout}
+	if s.tlsConfig != nil {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: s.tlsConfig}
+		return tlsDialer.DialContext(ctx, "tcp", s.address)
+	}
+	return dialer.DialContext(ctx, "tcp", s.address)
+}
+
+func (s *syslogAuditSink) Send(ctx context.Context, logs []*AuditLog) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("dial syslog %s: %w", s.address, err)
+		}
+		s.conn = conn
+	}
+	_ = s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	for i, log := range logs {
+		msg, err := s.formatter.encode(log)
+		if err != nil {
+			return i, fmt.Errorf("encode audit log %d: %w", log.ID, err)
+		}
+		frame := FormatAuditLogRFC5424(log, s.facility, s.hostname, s.appName, s.procID, msg)
+		if _, err := s.conn.Write(append([]byte(strconv.Itoa(len(frame))+" "), frame...)); err != nil {
+			_ = s.conn.Close()
+			s.conn = nil
+			return i, fmt.Errorf("write syslog %s: %w", s.address, err)
+		}
+	}
+	return len(logs), nil
+}
+
+func (s *syslogAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// httpsAuditSink 通用 HTTPS sink：每批一次 POST。
+// json 格式发送 JSON 数组，cef 格式发送换行分隔文本；2xx 视为整批送达。
+// Idempotency-Key 由批次首尾 id 组成，重发同一批时保持不变。
+type httpsAuditSink struct {
+	name        string
+	url         string
+	bearerToken string
+	headers     map[string]string
+	client      *http.Client
+	formatter   *auditLogFormatter
+}
+
+func newHTTPSAuditSink(sink config.AuditLogSinkConfig, formatter *auditLogFormatter) (*httpsAuditSink, error) {
+	tlsConfig, err := buildAuditExportTLSConfig(sink, "")
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &httpsAuditSink{
+		name:        strings.TrimSpace(sink.Name),
+		url:         strings.TrimSpace(sink.URL),
+		bearerToken: strings.TrimSpace(sink.BearerToken),
+		headers:     sink.Headers,
+		client:      &http.Client{Timeout: auditExportTimeout(sink), Transport: transport},
+		formatter:   formatter,
+	}, nil
+}
+
+func (s *httpsAuditSink) Name() string { return s.name }
+
+func (s *httpsAuditSink) Send(ctx context.Context, logs []*AuditLog) (int, error) {
+	if len(logs) == 0 {
+		return 0, nil
+	}
+	var body bytes.Buffer
+	contentType := "application/json"
+	if s.formatter.format == AuditLogExportFormatCEF {
+		contentType = "text/plain; charset=utf-8"
+	} else {
+		body.WriteByte('[')
+	}
+	for i, log := range logs {
+		encoded, err := s.formatter.encode(log)
+		if err != nil {
+			return 0, fmt.Errorf("encode audit log %d: %w", log.ID, err)
+		}
+		if i > 0 {
+			if s.formatter.format == AuditLogExportFormatCEF {
+				body.WriteByte('\n')
+			} else {
+				body.WriteByte(',')
+			}
+		}
+		body.Write(encoded)
+	}
+	if s.formatter.format != AuditLogExportFormatCEF {
+		body.WriteByte(']')
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, &body)
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Idempotency-Key", fmt.Sprintf("sub2api-audit-%d-%d", logs[0].ID, logs[len(logs)-1].ID))
+	if s.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.bearerToken)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("post audit events: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("post audit events: unexpected status %d", resp.StatusCode)
+	}
+	return len(logs), nil
+}
+
+func (s *httpsAuditSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// newAuditLogExportSink 按配置构建 sink。
+func newAuditLogExportSink(sink config.AuditLogSinkConfig, hostname, version string) (AuditLogExportSink, error) {
+	format := strings.ToLower(strings.TrimSpace(sink.Format))
+	if format == "" {
+		format = AuditLogExportFormatJSON
+	}
+	formatter := &auditLogFormatter{format: format, hostname: hostname, version: version}
+	switch strings.ToLower(strings.TrimSpace(sink.Type)) {
+	case AuditLogSinkTypeSyslog:
+		return newSyslogAuditSink(sink, formatter)
+	case AuditLogSinkTypeHTTPS:
+		return newHTTPSAuditSink(sink, formatter)
+	default:
+		return nil, fmt.Errorf("unsupported audit sink type %q", sink.Type)
+	}
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/google/uuid"
+)
+
+const (
+	// auditLogExportLeaderLockKey 多实例部署下只有一个实例外送，避免同一事件被重复发送。
+	auditLogExportLeaderLockKey = "audit_log:export:leader"
+	auditLogExportLeaderLockTTL = 5 * time.Minute
+	auditLogExportRunTimeout    = 4 * time.Minute
+
+	auditLogExportDefaultInterval  = 10 * time.Second
+	auditLogExportDefaultBatchSize = 500
+	// auditLogExportMaxBatchesPerRun 单轮每个 sink 最多发送的批次数，积压时分多轮追赶。
+	auditLogExportMaxBatchesPerRun = 50
+)
+
+// AuditLogExportSinkStatus sink 外送状态。
+type AuditLogExportSinkStatus struct {
+	Name           string     `json:"name"`
+	Type           string     `json:"type"`
+	Format         string     `json:"format"`
+	Enabled        bool       `json:"enabled"`
+	Ready          bool       `json:"ready"`
+	InitError      string     `json:"init_error,omitempty"`
+	LastID         int64      `json:"last_id"`
+	DeliveredCount int64      `json:"delivered_count"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastErrorAt    *time.Time `json:"last_error_at,omitempty"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+}
+
+// AuditLogExportStatus 外送总览。
+type AuditLogExportStatus struct {
+	Enabled bool                        `json:"enabled"`
+	HeadID  int64                       `json:"head_id"`
+	Sinks   []*AuditLogExportSinkStatus `json:"sinks"`
+}
+
+// AuditLogExportService 将审计日志流式外送到 SIEM（syslog / HTTPS）。
+// 每个 sink 独立持久化游标，只在批次送达后推进：重启后从游标继续，不丢失；
+// 事件携带稳定的审计 id 与行哈希，极端情况下（送达后、推进游标前崩溃）的重发可由下游去重。
+type AuditLogExportService struct {
+	repo    AuditLogRepository
+	cursors AuditLogExportCursorRepository
+	cfg     config.AuditLogExportConfig
+
+	sinks      []AuditLogExportSink
+	initErrors map[string]string
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	lockCache  LeaderLockCache
+	db         *sql.DB
+	instanceID string
+}
+
+// NewAuditLogExportService 按配置构建 sink；单个 sink 初始化失败（如证书文件缺失）只禁用该 sink 并记录原因，
+// 它的游标仍参与归档水位计算，避免未外送的记录被归档删除。
+func NewAuditLogExportService(
+	repo AuditLogRepository,
+	cursors AuditLogExportCursorRepository,
+	cfg config.AuditLogExportConfig,
+	buildInfo BuildInfo,
+) *AuditLogExportService {
+	svc := &AuditLogExportService{
+		repo:       repo,
+		cursors:    cursors,
+		cfg:        cfg,
+		initErrors: make(map[string]string),
+		stopCh:     make(chan struct{}),
+		instanceID: uuid.NewString(),
+	}
+	if !cfg.Enabled {
+		return svc
+	}
+	hostname, _ := os.Hostname()
+	for _, sinkCfg := range cfg.Sinks {
+		if !sinkCfg.Enabled {
+			continue
+		}
+		sink, err := newAuditLogExportSink(sinkCfg, hostname, buildInfo.Version)
+		if err != nil {
+			svc.initErrors[strings.TrimSpace(sinkCfg.Name)] = err.Error()
+			logger.LegacyPrintf("service.audit_log_export", "[AuditLogExport] sink %s disabled: %v", sinkCfg.Name, err)
+			continue
+		}
+		svc.sinks = append(svc.sinks, sink)
+	}
+	return svc
+}
+
+// SetLeaderLock injects the leader-lock cache and DB used to elect a single
+// exporting instance. When both are nil the run is ungated.
+func (s *AuditLogExportService) SetLeaderLock(lockCache LeaderLockCache, db *sql.DB) {
+	if s == nil {
+		return
+	}
+	s.lockCache = lockCache
+	s.db = db
+}
+
+func (s *AuditLogExportService) enabledSinkNames() []string {
+	if !s.cfg.Enabled {
+		return nil
+	}
+	names := make([]string, 0, len(s.cfg.Sinks))
+	for _, sinkCfg := range s.cfg.Sinks {
+		if sinkCfg.Enabled {
+			names = append(names, strings.TrimSpace(sinkCfg.Name))
+		}
+	}
+	return names
+}
+
+// Horizon 返回所有启用的 sink 均已送达的最大审计 id（AuditLogExportHorizon）。
+func (s *AuditLogExportService) Horizon(ctx context.Context) (int64, bool, error) {
+	names := s.enabledSinkNames()
+	if len(names) == 0 {
+		return 0, false, nil
+	}
+	cursors, err := s.cursors.ListCursors(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	byName := make(map[string]int64, len(cursors))
+	for _, cursor := range cursors {
+		byName[cursor.SinkName] = cursor.LastID
+	}
+	horizon := int64(-1)
+	for _, name := range names {
+		lastID := byName[name]
+		if horizon < 0 || lastID < horizon {
+			horizon = lastID
+		}
+	}
+	return horizon, true, nil
+}
+
+// Status 返回各 sink 的游标与最近错误。
+func (s *AuditLogExportService) Status(ctx context.Context) (*AuditLogExportStatus, error) {
+	headID, err := s.repo.MaxID(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	cursors, err := s.cursors.ListCursors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*AuditLogExportCursor, len(cursors))
+	for _, cursor := range cursors {
+		byName[cursor.SinkName] = cursor
+	}
+	ready := make(map[string]bool, len(s.sinks))
+	for _, sink := range s.sinks {
+		ready[sink.Name()] = true
+	}
+
+	status := &AuditLogExportStatus{Enabled: s.cfg.Enabled, HeadID: headID, Sinks: make([]*AuditLogExportSinkStatus, 0, len(s.cfg.Sinks))}
+	for _, sinkCfg := range s.cfg.Sinks {
+		name := strings.TrimSpace(sinkCfg.Name)
+		format := strings.ToLower(strings.TrimSpace(sinkCfg.Format))
+		if format == "" {
+			format = AuditLogExportFormatJSON
+		}
+		item := &AuditLogExportSinkStatus{
+			Name:      name,
+			Type:      strings.ToLower(strings.TrimSpace(sinkCfg.Type)),
+			Format:    format,
+			Enabled:   s.cfg.Enabled && sinkCfg.Enabled,
+			Ready:     ready[name],
+			InitError: s.initErrors[name],
+		}
+		if cursor := byName[name]; cursor != nil {
+			item.LastID = cursor.LastID
+			item.DeliveredCount = cursor.DeliveredCount
+			item.LastError = cursor.LastError
+			item.LastErrorAt = cursor.LastErrorAt
+			updatedAt := cursor.UpdatedAt
+			item.UpdatedAt = &updatedAt
+		}
+		status.Sinks = append(status.Sinks, item)
+	}
+	return status, nil
+}
+
+// Start 按 IntervalSeconds 轮询外送；未启用或无可用 sink 时不启动。
+func (s *AuditLogExportService) Start() {
+	if s == nil || s.repo == nil || s.cursors == nil || len(s.sinks) == 0 {
+		return
+	}
+	interval := auditLogExportDefaultInterval
+	if s.cfg.IntervalSeconds > 0 {
+		interval = time.Duration(s.cfg.IntervalSeconds) * time.Second
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		s.runOnce()
+		for {
+			select {
+			case <-ticker.C:
+				s.runOnce()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (s *AuditLogExportService) Stop() {
+	if s == nil {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+	s.wg.Wait()
+	for _, sink := range s.sinks {
+		_ = sink.Close()
+	}
+}
+
+func (s *AuditLogExportService) runOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), auditLogExportRunTimeout)
+	defer cancel()
+
+	release, ok := tryAcquireSingletonLeaderLock(ctx, s.lockCache, s.db, auditLogExportLeaderLockKey, s.instanceID, auditLogExportLeaderLockTTL)
+	if !ok {
+		return
+	}
+	defer release()
+
+	if err := s.ExportPending(ctx); err != nil {
+		logger.LegacyPrintf("service.audit_log_export", "[AuditLogExport] run failed: %v", err)
+	}
+}
+
+// ExportPending 对每个 sink 从游标之后按批外送，直到追平、失败或达到单轮批次上限。
+func (s *AuditLogExportService) ExportPending(ctx context.Context) error {
+	cursors, err := s.cursors.ListCursors(ctx)
+	if err != nil {
+		return err
+	}
+	positions := make(map[string]int64, len(cursors))
+	for _, cursor := range cursors {
+		positions[cursor.SinkName] = cursor.LastID
+	}
+	for _, sink := range s.sinks {
+		s.exportSink(ctx, sink, positions[sink.Name()])
+	}
+	return nil
+}
+
+func (s *AuditLogExportService) exportSink(ctx context.Context, sink AuditLogExportSink, afterID int64) {
+	batchSize := s.cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = auditLogExportDefaultBatchSize
+	}
+	for i := 0; i < auditLogExportMaxBatchesPerRun; i++ {
+		if ctx.Err() != nil {
+			return
+		}
+		logs, err := s.repo.ListAfter(ctx, afterID, 0, batchSize)
+		if err != nil {
+			logger.LegacyPrintf("service.audit_log_export", "[AuditLogExport] sink %s read failed: %v", sink.Name(), err)
+			return
+		}
+		if len(logs) == 0 {
+			return
+		}
+
+		delivered, sendErr := sink.Send(ctx, logs)
+		if delivered > len(logs) {
+			delivered = len(logs)
+		}
+		if delivered > 0 {
+			afterID = logs[delivered-1].ID
+			if err := s.cursors.AdvanceCursor(ctx, sink.Name(), afterID, int64(delivered)); err != nil {
+				logger.LegacyPrintf("service.audit_log_export", "[AuditLogExport] sink %s cursor update failed: %v", sink.Name(), err)
+				return
+			}
+		}
+		if sendErr != nil {
+			if err := s.cursors.RecordCursorError(ctx, sink.Name(), sendErr.Error()); err != nil {
+				logger.LegacyPrintf("service.audit_log_export", "[AuditLogExport] sink %s record error failed: %v", sink.Name(), err)
+			}
+			logger.LegacyPrintf("service.audit_log_export", "[AuditLogExport] sink %s send failed after %d/%d events: %v", sink.Name(), delivered, len(logs), sendErr)
+			return
+		}
+		if len(logs) < batchSize {
+			return
+		}
+	}
+}
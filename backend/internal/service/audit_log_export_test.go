@@ -0,0 +1,241 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+type auditExportCursorRepoStub struct {
+	mu      sync.Mutex
+	cursors map[string]*AuditLogExportCursor
+}
+
+func newAuditExportCursorRepoStub() *auditExportCursorRepoStub {
+	return &auditExportCursorRepoStub{cursors: map[string]*AuditLogExportCursor{}}
+}
+
+func (r *auditExportCursorRepoStub) get(name string) *AuditLogExportCursor {
+	cursor, ok := r.cursors[name]
+	if !ok {
+		cursor = &AuditLogExportCursor{SinkName: name}
+		r.cursors[name] = cursor
+	}
+	return cursor
+}
+
+func (r *auditExportCursorRepoStub) ListCursors(context.Context) ([]*AuditLogExportCursor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]*AuditLogExportCursor, 0, len(r.cursors))
+	for _, cursor := range r.cursors {
+		copied := *cursor
+		out = append(out, &copied)
+	}
+	return out, nil
+}
+
+func (r *auditExportCursorRepoStub) AdvanceCursor(_ context.Context, sinkName string, lastID int64, delivered int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cursor := r.get(sinkName)
+	if lastID > cursor.LastID {
+		cursor.LastID = lastID
+	}
+	cursor.DeliveredCount += delivered
+	cursor.LastError = ""
+	return nil
+}
+
+func (r *auditExportCursorRepoStub) RecordCursorError(_ context.Context, sinkName string, errMsg string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.get(sinkName).LastError = errMsg
+	return nil
+}
+
+type auditExportSinkStub struct {
+	name     string
+	received []int64
+	// failAfter >= 0 时，累计送达该条数后返回错误。
+	failAfter int
+}
+
+func (s *auditExportSinkStub) Name() string { return s.name }
+func (s *auditExportSinkStub) Close() error { return nil }
+func (s *auditExportSinkStub) Send(_ context.Context, logs []*AuditLog) (int, error) {
+	for i, log := range logs {
+		if s.failAfter >= 0 && len(s.received) >= s.failAfter {
+			return i, errors.New("connection reset")
+		}
+		s.received = append(s.received, log.ID)
+	}
+	return len(logs), nil
+}
+
+func TestAuditLogExport_CursorResumesWithoutLossOrDuplicates(t *testing.T) {
+	repo := &auditChainRepoStub{}
+	repo.seed(t, 7, time.Now())
+	cursors := newAuditExportCursorRepoStub()
+	sink := &auditExportSinkStub{name: "siem", failAfter: 3}
+
+	cfg := config.AuditLogExportConfig{Enabled: true, BatchSize: 2, Sinks: []config.AuditLogSinkConfig{{Name: "siem", Enabled: true}}}
+	svc := NewAuditLogExportService(repo, cursors, cfg, BuildInfo{})
+	svc.sinks = []AuditLogExportSink{sink}
+
+	require.NoError(t, svc.ExportPending(context.Background()))
+	require.Equal(t, []int64{1, 2, 3}, sink.received)
+	require.EqualValues(t, 3, cursors.cursors["siem"].LastID, "cursor advances to the last delivered event of a partial batch")
+	require.Equal(t, "connection reset", cursors.cursors["siem"].LastError)
+
+	horizon, limited, err := svc.Horizon(context.Background())
+	require.NoError(t, err)
+	require.True(t, limited)
+	require.EqualValues(t, 3, horizon)
+
+	// 模拟重启：新服务实例只依赖持久化游标。
+	sink = &auditExportSinkStub{name: "siem", failAfter: -1}
+	svc = NewAuditLogExportService(repo, cursors, cfg, BuildInfo{})
+	svc.sinks = []AuditLogExportSink{sink}
+	require.NoError(t, svc.ExportPending(context.Background()))
+	require.Equal(t, []int64{4, 5, 6, 7}, sink.received)
+	require.EqualValues(t, 7, cursors.cursors["siem"].LastID)
+	require.EqualValues(t, 7, cursors.cursors["siem"].DeliveredCount)
+	require.Empty(t, cursors.cursors["siem"].LastError)
+}
+
+func TestAuditLogExport_HorizonUnlimitedWhenDisabled(t *testing.T) {
+	svc := NewAuditLogExportService(&auditChainRepoStub{}, newAuditExportCursorRepoStub(), config.AuditLogExportConfig{
+		Enabled: false,
+		Sinks:   []config.AuditLogSinkConfig{{Name: "siem", Enabled: true}},
+	}, BuildInfo{})
+	_, limited, err := svc.Horizon(context.Background())
+	require.NoError(t, err)
+	require.False(t, limited)
+}
+
+func TestFormatAuditLogCEF_EscapesFields(t *testing.T) {
+	uid := int64(5)
+	log := &AuditLog{
+		ID:          42,
+		CreatedAt:   time.UnixMilli(1700000000000),
+		ActorUserID: &uid,
+		ActorEmail:  "a=b@example.com",
+		Action:      "admin.a|b",
+		Method:      "POST",
+		Path:        "/x",
+		UserAgent:   "line1\nline2\\",
+		StatusCode:  403,
+		RowHash:     "abc",
+	}
+	out := FormatAuditLogCEF(log, "1.2.3")
+	require.True(t, strings.HasPrefix(out, `CEF:0|Sub2API|sub2api|1.2.3|admin.a\|b|POST /x|5|`), out)
+	require.Contains(t, out, `suser=a\=b@example.com`)
+	require.Contains(t, out, `requestClientApplication=line1\nline2\\`)
+	require.Contains(t, out, "rt=1700000000000")
+	require.Contains(t, out, "externalId=42")
+	require.Contains(t, out, "outcome=failure")
+	require.NotContains(t, out, "\n")
+}
+
+func TestSyslogAuditSink_WritesOctetCountedRFC5424(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	frames := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		reader := bufio.NewReader(conn)
+		for i := 0; i < 2; i++ {
+			lenStr, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(lenStr))
+			buf := make([]byte, n)
+			if _, err := io.ReadFull(reader, buf); err != nil {
+				return
+			}
+			frames <- string(buf)
+		}
+	}()
+
+	sink, err := newAuditLogExportSink(config.AuditLogSinkConfig{
+		Name: "syslog", Type: "syslog", Format: "json", Network: "tcp", Address: ln.Addr().String(),
+	}, "host-1", "1.0.0")
+	require.NoError(t, err)
+	defer func() { _ = sink.Close() }()
+
+	logs := []*AuditLog{
+		{ID: 1, CreatedAt: time.Now(), Action: "admin.users.update", StatusCode: 200, RowHash: "h1"},
+		{ID: 2, CreatedAt: time.Now(), Action: "auth.login", StatusCode: 401, PrevHash: "h1", RowHash: "h2"},
+	}
+	delivered, err := sink.Send(context.Background(), logs)
+	require.NoError(t, err)
+	require.Equal(t, 2, delivered)
+
+	first := <-frames
+	require.True(t, strings.HasPrefix(first, "<109>1 "), first) // facility 13 * 8 + notice
+	require.Contains(t, first, " host-1 sub2api ")
+	require.Contains(t, first, ` admin.users.update [audit@32473 id="1" hash="h1" prev="" status="200"] `+"\xEF\xBB\xBF{")
+	second := <-frames
+	require.True(t, strings.HasPrefix(second, "<108>1 "), second) // warning for failures
+	payload := second[strings.Index(second, "\xEF\xBB\xBF")+3:]
+	var event map[string]any
+	require.NoError(t, json.Unmarshal([]byte(payload), &event))
+	require.Equal(t, "failure", event["outcome"])
+	require.Equal(t, "h2", event["row_hash"])
+	require.EqualValues(t, 2, event["id"])
+}
+
+func TestHTTPSAuditSink_PostsJSONArray(t *testing.T) {
+	var gotAuth, gotKey string
+	var gotEvents []map[string]any
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotKey = r.Header.Get("Idempotency-Key")
+		_ = json.NewDecoder(r.Body).Decode(&gotEvents)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sink, err := newHTTPSAuditSink(config.AuditLogSinkConfig{Name: "https", URL: server.URL, BearerToken: "tok"}, &auditLogFormatter{format: AuditLogExportFormatJSON, hostname: "h"})
+	require.NoError(t, err)
+	sink.client = server.Client()
+
+	delivered, err := sink.Send(context.Background(), []*AuditLog{{ID: 10, Action: "a"}, {ID: 11, Action: "b"}})
+	require.NoError(t, err)
+	require.Equal(t, 2, delivered)
+	require.Equal(t, "Bearer tok", gotAuth)
+	require.Equal(t, "sub2api-audit-10-11", gotKey)
+	require.Len(t, gotEvents, 2)
+	require.Equal(t, "sub2api.audit", gotEvents[0]["event_type"])
+
+	failing := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	sink.url = failing.URL
+	sink.client = failing.Client()
+	delivered, err = sink.Send(context.Background(), []*AuditLog{{ID: 12}})
+	require.Error(t, err)
+	require.Zero(t, delivered)
+}
@@ -1,9 +1,16 @@
 package service
 
 import (
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -16,16 +23,31 @@ const (
 
 	auditRetentionCheckInterval = 24 * time.Hour
 	auditRetentionStartupDelay  = 5 * time.Minute
-	auditRetentionBatchSize     = 5000
+	auditArchiveDefaultPageSize = 5000
 )
 
+// AuditLogArchiveStore 审计归档所用的对象存储（由 BackupService 提供，复用备份 S3 配置）。
+type AuditLogArchiveStore interface {
+	ObjectStore(ctx context.Context) (BackupObjectStore, *BackupS3Config, error)
+}
+
+// AuditLogExportHorizon 返回所有启用的 SIEM sink 均已送达的最大审计 id；
+// limited 为 false 表示无外送限制。归档不会越过该 id，避免未外送的记录被删除。
+type AuditLogExportHorizon func(ctx context.Context) (maxID int64, limited bool, err error)
+
 // AuditLogService 管理面操作审计日志服务。
-// 写入端为非阻塞异步批量落库（不拖慢管理请求）；
-// 读取端提供分页查询；清空端点由 handler 层做 TOTP 强校验后调用 ClearAll。
+// 写入端为非阻塞异步批量落库（不拖慢管理请求），仓储按 id 顺序计算哈希链；
+// 读取端提供分页查询与链校验；清空端点由 handler 层做 TOTP 强校验后调用 ArchiveAll，
+// 记录只在归档到对象存储后才会删除（保留期清理同样走归档）。
 type AuditLogService struct {
 	repo           AuditLogRepository
 	settingService *SettingService
 
+	archiveStore    AuditLogArchiveStore
+	archivePageSize int
+	exportHorizon   AuditLogExportHorizon
+	archiveMu       sync.Mutex
+
 	queue chan *AuditLog
 
 	ctx    context.Context
@@ -40,14 +62,34 @@ type AuditLogService struct {
 func NewAuditLogService(repo AuditLogRepository, settingService *SettingService) *AuditLogService {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &AuditLogService{
-		repo:           repo,
-		settingService: settingService,
-		queue:          make(chan *AuditLog, auditLogQueueCapacity),
-		ctx:            ctx,
-		cancel:         cancel,
+		repo:            repo,
+		settingService:  settingService,
+		queue:           make(chan *AuditLog, auditLogQueueCapacity),
+		archivePageSize: auditArchiveDefaultPageSize,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+// SetArchiveStore 注入归档对象存储；未注入时清空与保留期清理均不可用。
+func (s *AuditLogService) SetArchiveStore(store AuditLogArchiveStore, pageSize int) {
+	if s == nil {
+		return
+	}
+	s.archiveStore = store
+	if pageSize > 0 {
+		s.archivePageSize = pageSize
 	}
 }
 
+// SetExportHorizon 注入 SIEM 外送进度，归档不越过尚未外送的记录。
+func (s *AuditLogService) SetExportHorizon(horizon AuditLogExportHorizon) {
+	if s == nil {
+		return
+	}
+	s.exportHorizon = horizon
+}
+
 // Start 启动异步写入与保留期清理协程。
 func (s *AuditLogService) Start() {
 	if s == nil || s.repo == nil {
@@ -97,34 +139,287 @@ func (s *AuditLogService) GetByID(ctx context.Context, id int64) (*AuditLog, err
 	return s.repo.GetByID(ctx, id)
 }
 
-// ClearAll 全量清空审计日志并写入留痕记录。
+// ListArchives 列出归档记录（按 id 升序）。
+func (s *AuditLogService) ListArchives(ctx context.Context) ([]*AuditLogArchive, error) {
+	return s.repo.ListArchives(ctx)
+}
+
+// ArchiveAll 将当前全部审计日志归档到对象存储后删除，并写入留痕记录。
 // 调用方（handler）必须先完成 TOTP 验证；本方法负责：
-//  1. 统计并清空全表
-//  2. 同步写入一条 "audit_log.clear" 留痕记录（绕过异步队列，保证落库）
-func (s *AuditLogService) ClearAll(ctx context.Context, trace *AuditLog) (int64, error) {
-	deleted, err := s.repo.Count(ctx)
-	if err != nil {
-		return 0, fmt.Errorf("count audit logs: %w", err)
+//  1. 导出 id 前缀到对象存储（gzip JSONL，含哈希链字段），不越过 SIEM 尚未送达的记录
+//  2. 同一事务写入归档锚点并删除已归档记录
+//  3. 同步写入一条 "audit_log.archive" 留痕记录（绕过异步队列，保证落库），它成为新链的首条
+//
+// 未配置对象存储时返回 ErrAuditLogArchiveStoreNotConfigured，不删除任何记录。
+func (s *AuditLogService) ArchiveAll(ctx context.Context, trace *AuditLog) (*AuditLogArchive, int64, error) {
+	var actorUserID *int64
+	if trace != nil {
+		actorUserID = trace.ActorUserID
 	}
-	if err := s.repo.TruncateAll(ctx); err != nil {
-		return 0, fmt.Errorf("truncate audit logs: %w", err)
+	archive, deleted, err := s.archive(ctx, AuditLogArchiveTriggerManual, nil, actorUserID)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	if trace != nil {
-		trace.Action = AuditActionAuditLogClear
+		trace.Action = AuditActionAuditLogArchive
 		if trace.CreatedAt.IsZero() {
 			trace.CreatedAt = time.Now().UTC()
 		}
 		if trace.Extra == nil {
 			trace.Extra = map[string]any{}
 		}
-		trace.Extra["deleted_rows"] = deleted
+		for k, v := range auditLogArchiveTraceExtra(archive, deleted) {
+			trace.Extra[k] = v
+		}
 		if err := s.repo.Insert(ctx, trace); err != nil {
-			// 留痕失败必须显式暴露：清空已发生，但审计链断裂。
-			return deleted, fmt.Errorf("audit logs cleared (%d rows) but failed to persist clear-trace record: %w", deleted, err)
+			// 留痕失败必须显式暴露：归档已发生，但留痕缺失。
+			return archive, deleted, fmt.Errorf("audit logs archived (%d rows) but failed to persist archive-trace record: %w", deleted, err)
+		}
+	}
+	return archive, deleted, nil
+}
+
+func auditLogArchiveTraceExtra(archive *AuditLogArchive, deleted int64) map[string]any {
+	extra := map[string]any{"deleted_rows": deleted}
+	if archive != nil {
+		extra["archive_id"] = archive.ID
+		extra["object_key"] = archive.ObjectKey
+		extra["first_id"] = archive.FirstID
+		extra["last_id"] = archive.LastID
+		extra["object_sha256"] = archive.ObjectSHA256
+		extra["chain_valid"] = archive.ChainValid
+	}
+	return extra
+}
+
+// archive 归档 id <= 上限 的连续前缀（before 非空时上限为 created_at < before 的最大 id）。
+// 无可归档记录时返回 (nil, 0, nil)。
+func (s *AuditLogService) archive(ctx context.Context, trigger string, before *time.Time, actorUserID *int64) (*AuditLogArchive, int64, error) {
+	s.archiveMu.Lock()
+	defer s.archiveMu.Unlock()
+
+	if s.archiveStore == nil {
+		return nil, 0, ErrAuditLogArchiveStoreNotConfigured
+	}
+	store, s3Cfg, err := s.archiveStore.ObjectStore(ctx)
+	if err != nil {
+		if errors.Is(err, ErrBackupS3NotConfigured) {
+			return nil, 0, ErrAuditLogArchiveStoreNotConfigured
+		}
+		return nil, 0, fmt.Errorf("open archive store: %w", err)
+	}
+
+	upToID, err := s.repo.MaxID(ctx, before)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query audit log max id: %w", err)
+	}
+	if s.exportHorizon != nil {
+		horizon, limited, err := s.exportHorizon(ctx)
+		if err != nil {
+			return nil, 0, fmt.Errorf("query audit export horizon: %w", err)
+		}
+		if limited && horizon < upToID {
+			upToID = horizon
+		}
+	}
+	archives, err := s.repo.ListArchives(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list audit log archives: %w", err)
+	}
+	var afterID int64
+	anchor := ""
+	if n := len(archives); n > 0 {
+		afterID = archives[n-1].LastID
+		anchor = archives[n-1].LastRowHash
+	}
+	if upToID <= afterID {
+		return nil, 0, nil
+	}
+
+	file, err := os.CreateTemp("", "sub2api-audit-archive-*.jsonl.gz")
+	if err != nil {
+		return nil, 0, fmt.Errorf("create archive temp file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+
+	hasher := sha256.New()
+	gz := gzip.NewWriter(io.MultiWriter(file, hasher))
+	enc := json.NewEncoder(gz)
+	walker := &auditLogChainWalker{expected: anchor}
+	archive := &AuditLogArchive{
+		FirstPrevHash: anchor,
+		TriggeredBy:   trigger,
+		ActorUserID:   actorUserID,
+	}
+	for {
+		rows, err := s.repo.ListAfter(ctx, afterID, upToID, s.archivePageSize)
+		if err != nil {
+			return nil, 0, fmt.Errorf("read audit logs: %w", err)
+		}
+		for _, row := range rows {
+			if archive.FirstID == 0 {
+				archive.FirstID = row.ID
+			}
+			walker.step(row)
+			if err := enc.Encode(row); err != nil {
+				return nil, 0, fmt.Errorf("encode audit log %d: %w", row.ID, err)
+			}
+			archive.LastID = row.ID
+			archive.LastRowHash = row.RowHash
+			archive.RowCount++
+			afterID = row.ID
+		}
+		if len(rows) < s.archivePageSize {
+			break
+		}
+	}
+	if archive.RowCount == 0 {
+		return nil, 0, nil
+	}
+	if err := gz.Close(); err != nil {
+		return nil, 0, fmt.Errorf("finish archive: %w", err)
+	}
+	archive.ChainValid = walker.broken == nil
+	archive.ObjectSHA256 = hex.EncodeToString(hasher.Sum(nil))
+	archive.ObjectKey = buildAuditLogArchiveKey(s3Cfg, archive.FirstID, archive.LastID, time.Now().UTC())
+
+	size, err := store.UploadFile(ctx, archive.ObjectKey, file.Name(), "application/gzip")
+	if err != nil {
+		return nil, 0, fmt.Errorf("upload audit log archive: %w", err)
+	}
+	archive.SizeBytes = size
+
+	deleted, err := s.repo.CommitArchive(ctx, archive)
+	if err != nil {
+		if errors.Is(err, ErrAuditLogArchiveConflict) {
+			// 其他实例已归档同一区间，清理本次上传的重复对象。
+			_ = store.Delete(ctx, archive.ObjectKey)
 		}
+		return nil, 0, err
 	}
-	return deleted, nil
+	return archive, deleted, nil
+}
+
+func buildAuditLogArchiveKey(cfg *BackupS3Config, firstID, lastID int64, now time.Time) string {
+	prefix := ""
+	if cfg != nil {
+		prefix = strings.TrimRight(cfg.Prefix, "/")
+	}
+	if prefix == "" {
+		prefix = "backups"
+	}
+	return fmt.Sprintf("%s/audit-logs/%s/audit-logs-%d-%d-%s.jsonl.gz",
+		prefix, now.Format("2006/01/02"), firstID, lastID, now.Format("20060102T150405Z"))
+}
+
+// VerifyChain 校验归档锚点与剩余记录的哈希链，返回首个断点。
+func (s *AuditLogService) VerifyChain(ctx context.Context) (*AuditLogChainVerification, error) {
+	result := &AuditLogChainVerification{VerifiedAt: time.Now().UTC()}
+
+	archives, err := s.repo.ListArchives(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list audit log archives: %w", err)
+	}
+	var afterID int64
+	anchor := ""
+	for _, archive := range archives {
+		result.ArchivesChecked++
+		if archive.FirstPrevHash != anchor {
+			result.FirstBroken = &AuditLogChainBreak{
+				ArchiveID:        archive.ID,
+				Reason:           "archive_gap",
+				ExpectedPrevHash: anchor,
+				ActualPrevHash:   archive.FirstPrevHash,
+			}
+			return result, nil
+		}
+		if !archive.ChainValid {
+			result.FirstBroken = &AuditLogChainBreak{ArchiveID: archive.ID, Reason: "archive_chain_invalid"}
+			return result, nil
+		}
+		anchor = archive.LastRowHash
+		afterID = archive.LastID
+	}
+	result.AnchorHash = anchor
+
+	pageSize := s.archivePageSize
+	if pageSize <= 0 {
+		pageSize = auditArchiveDefaultPageSize
+	}
+	walker := &auditLogChainWalker{expected: anchor}
+	for walker.broken == nil {
+		rows, err := s.repo.ListAfter(ctx, afterID, 0, pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("read audit logs: %w", err)
+		}
+		for _, row := range rows {
+			if !walker.step(row) {
+				break
+			}
+			result.HeadID = row.ID
+			result.HeadHash = row.RowHash
+			afterID = row.ID
+		}
+		if len(rows) < pageSize {
+			break
+		}
+	}
+	result.CheckedRows = walker.checked
+	result.LegacyRows = walker.legacy
+	result.FirstBroken = walker.broken
+	result.Valid = walker.broken == nil
+	return result, nil
+}
+
+// auditLogChainWalker 按 id 升序逐行校验哈希链。
+// 迁移前写入的记录没有哈希（legacy），只允许出现在链开始之前。
+type auditLogChainWalker struct {
+	expected string
+	started  bool
+	checked  int64
+	legacy   int64
+	broken   *AuditLogChainBreak
+}
+
+func (w *auditLogChainWalker) step(row *AuditLog) bool {
+	if w.broken != nil {
+		return false
+	}
+	if row.RowHash == "" {
+		if w.started || w.expected != "" {
+			w.broken = &AuditLogChainBreak{LogID: row.ID, Reason: "missing_hash", ExpectedPrevHash: w.expected}
+			return false
+		}
+		w.checked++
+		w.legacy++
+		return true
+	}
+	w.started = true
+	if row.PrevHash != w.expected {
+		w.broken = &AuditLogChainBreak{
+			LogID:            row.ID,
+			Reason:           "prev_hash_mismatch",
+			ExpectedPrevHash: w.expected,
+			ActualPrevHash:   row.PrevHash,
+		}
+		return false
+	}
+	if computed := ComputeAuditLogHash(row.PrevHash, row); computed != row.RowHash {
+		w.broken = &AuditLogChainBreak{
+			LogID:           row.ID,
+			Reason:          "row_hash_mismatch",
+			ExpectedRowHash: computed,
+			ActualRowHash:   row.RowHash,
+		}
+		return false
+	}
+	w.checked++
+	w.expected = row.RowHash
+	return true
 }
 
 func (s *AuditLogService) runWriter() {
@@ -212,7 +507,7 @@ func (s *AuditLogService) runRetentionLoop() {
 }
 
 func (s *AuditLogService) runRetentionOnce() {
-	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Minute)
+	ctx, cancel := context.WithTimeout(s.ctx, 30*time.Minute)
 	defer cancel()
 
 	days := 0
@@ -220,18 +515,24 @@ func (s *AuditLogService) runRetentionOnce() {
 		days = s.settingService.GetAuditLogRetentionDays(ctx)
 	}
 	if days <= 0 {
-		return // 0 或负值表示永久保留，仅支持手动清空
+		return // 0 或负值表示永久保留，仅支持手动归档
 	}
 	cutoff := time.Now().UTC().AddDate(0, 0, -days)
-	for {
-		deleted, err := s.repo.DeleteBefore(ctx, cutoff, auditRetentionBatchSize)
-		if err != nil {
-			_, _ = fmt.Fprintf(os.Stderr, "time=%s level=WARN msg=\"audit log retention cleanup failed\" err=%v\n",
-				time.Now().Format(time.RFC3339Nano), err)
-			return
-		}
-		if deleted == 0 {
-			return
-		}
+	archive, deleted, err := s.archive(ctx, AuditLogArchiveTriggerRetention, &cutoff, nil)
+	if err != nil {
+		// 未配置对象存储时不删除任何记录：保留期清理同样只能以归档方式进行。
+		_, _ = fmt.Fprintf(os.Stderr, "time=%s level=WARN msg=\"audit log retention archive skipped\" err=%v\n",
+			time.Now().Format(time.RFC3339Nano), err)
+		return
+	}
+	if archive == nil {
+		return
 	}
+	s.Record(&AuditLog{
+		ActorRole:  AuditAuthMethodSystem,
+		AuthMethod: AuditAuthMethodSystem,
+		Action:     AuditActionAuditLogArchive,
+		StatusCode: 200,
+		Extra:      auditLogArchiveTraceExtra(archive, deleted),
+	})
 }
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// auditChainRepoStub 内存版审计仓储：写入时按仓储实现同样的方式串联哈希链。
+type auditChainRepoStub struct {
+	AuditLogRepository
+
+	mu       sync.Mutex
+	nextID   int64
+	rows     []*AuditLog
+	archives []*AuditLogArchive
+}
+
+func (r *auditChainRepoStub) tail() string {
+	if n := len(r.rows); n > 0 {
+		return r.rows[n-1].RowHash
+	}
+	if n := len(r.archives); n > 0 {
+		return r.archives[n-1].LastRowHash
+	}
+	return ""
+}
+
+func (r *auditChainRepoStub) BatchInsert(_ context.Context, logs []*AuditLog) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, log := range logs {
+		r.nextID++
+		row := *log
+		row.ID = r.nextID
+		row.CreatedAt = log.CreatedAt.UTC().Truncate(time.Microsecond)
+		row.Extra = NormalizeAuditLogExtra(log.Extra)
+		row.PrevHash = r.tail()
+		row.RowHash = ComputeAuditLogHash(row.PrevHash, &row)
+		r.rows = append(r.rows, &row)
+	}
+	return int64(len(logs)), nil
+}
+
+func (r *auditChainRepoStub) Insert(ctx context.Context, log *AuditLog) error {
+	_, err := r.BatchInsert(ctx, []*AuditLog{log})
+	return err
+}
+
+func (r *auditChainRepoStub) ListAfter(_ context.Context, afterID, upToID int64, limit int) ([]*AuditLog, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]*AuditLog, 0, limit)
+	for _, row := range r.rows {
+		if row.ID <= afterID || (upToID > 0 && row.ID > upToID) {
+			continue
+		}
+		copied := *row
+		out = append(out, &copied)
+		if len(out) >= limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (r *auditChainRepoStub) MaxID(_ context.Context, before *time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var maxID int64
+	for _, row := range r.rows {
+		if before != nil && !row.CreatedAt.Before(*before) {
+			continue
+		}
+		maxID = row.ID
+	}
+	return maxID, nil
+}
+
+func (r *auditChainRepoStub) ListArchives(context.Context) ([]*AuditLogArchive, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*AuditLogArchive(nil), r.archives...), nil
+}
+
+func (r *auditChainRepoStub) CommitArchive(_ context.Context, archive *AuditLogArchive) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n := len(r.archives); n > 0 && r.archives[n-1].LastID >= archive.FirstID {
+		return 0, ErrAuditLogArchiveConflict
+	}
+	archive.ID = int64(len(r.archives) + 1)
+	r.archives = append(r.archives, archive)
+	kept := r.rows[:0]
+	var deleted int64
+	for _, row := range r.rows {
+		if row.ID <= archive.LastID {
+			deleted++
+			continue
+		}
+		kept = append(kept, row)
+	}
+	r.rows = kept
+	return deleted, nil
+}
+
+func (r *auditChainRepoStub) seed(t *testing.T, n int, createdAt time.Time) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		uid := int64(i + 1)
+		_, err := r.BatchInsert(context.Background(), []*AuditLog{{
+			CreatedAt:   createdAt.Add(time.Duration(i) * time.Second),
+			ActorUserID: &uid,
+			ActorEmail:  fmt.Sprintf("admin%d@example.com", i),
+			Action:      "admin.accounts.update",
+			Method:      "PUT",
+			Path:        "/api/v1/admin/accounts/:id",
+			StatusCode:  200,
+			Extra:       map[string]any{"n": i},
+		}})
+		require.NoError(t, err)
+	}
+}
+
+type auditArchiveObjectStoreStub struct {
+	BackupObjectStore
+
+	objects map[string][]byte
+	deleted []string
+}
+
+func (s *auditArchiveObjectStoreStub) UploadFile(_ context.Context, key string, filePath string, _ string) (int64, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return 0, err
+	}
+	s.objects[key] = data
+	return int64(len(data)), nil
+}
+
+func (s *auditArchiveObjectStoreStub) Delete(_ context.Context, key string) error {
+	s.deleted = append(s.deleted, key)
+	delete(s.objects, key)
+	return nil
+}
+
+type auditArchiveStoreProviderStub struct {
+	store *auditArchiveObjectStoreStub
+	err   error
+}
+
+func (p *auditArchiveStoreProviderStub) ObjectStore(context.Context) (BackupObjectStore, *BackupS3Config, error) {
+	if p.err != nil {
+		return nil, nil, p.err
+	}
+	return p.store, &BackupS3Config{Bucket: "b", Prefix: "backups/"}, nil
+}
+
+func readAuditArchive(t *testing.T, data []byte) []*AuditLog {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	raw, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	var out []*AuditLog
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		item := &AuditLog{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), item))
+		out = append(out, item)
+	}
+	return out
+}
+
+func TestComputeAuditLogHash_StableAcrossExtraRoundTrip(t *testing.T) {
+	uid := int64(9)
+	log := &AuditLog{
+		CreatedAt:   time.Date(2026, 1, 2, 3, 4, 5, 123456789, time.FixedZone("x", 8*3600)),
+		ActorUserID: &uid,
+		Action:      "admin.users.update",
+		Extra:       map[string]any{"count": int64(3), "nested": map[string]any{"b": 1, "a": "x"}},
+	}
+	stored := *log
+	stored.CreatedAt = log.CreatedAt.UTC().Truncate(time.Microsecond)
+	encoded, err := json.Marshal(log.Extra)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(encoded, &stored.Extra))
+
+	require.Equal(t, ComputeAuditLogHash("prev", log), ComputeAuditLogHash("prev", &stored))
+	require.NotEqual(t, ComputeAuditLogHash("prev", log), ComputeAuditLogHash("other", log))
+
+	tampered := stored
+	tampered.StatusCode = 500
+	require.NotEqual(t, ComputeAuditLogHash("prev", &stored), ComputeAuditLogHash("prev", &tampered))
+}
+
+func TestAuditLogVerifyChain_ReportsFirstBrokenLink(t *testing.T) {
+	repo := &auditChainRepoStub{}
+	repo.seed(t, 6, time.Now().Add(-time.Hour))
+	svc := NewAuditLogService(repo, nil)
+
+	result, err := svc.VerifyChain(context.Background())
+	require.NoError(t, err)
+	require.True(t, result.Valid)
+	require.EqualValues(t, 6, result.CheckedRows)
+	require.EqualValues(t, 6, result.HeadID)
+
+	// 改写内容：该行自身哈希对不上。
+	repo.rows[2].ActorEmail = "someone-else@example.com"
+	// 删除一行：其后一行的 prev_hash 对不上（但首个断点仍是被改写的第 3 行）。
+	repo.rows = append(repo.rows[:4], repo.rows[5:]...)
+
+	result, err = svc.VerifyChain(context.Background())
+	require.NoError(t, err)
+	require.False(t, result.Valid)
+	require.NotNil(t, result.FirstBroken)
+	require.EqualValues(t, 3, result.FirstBroken.LogID)
+	require.Equal(t, "row_hash_mismatch", result.FirstBroken.Reason)
+
+	repo.rows[2].ActorEmail = "admin2@example.com"
+	result, err = svc.VerifyChain(context.Background())
+	require.NoError(t, err)
+	require.False(t, result.Valid)
+	require.EqualValues(t, 6, result.FirstBroken.LogID)
+	require.Equal(t, "prev_hash_mismatch", result.FirstBroken.Reason)
+}
+
+func TestAuditLogVerifyChain_LegacyRowsOnlyBeforeChain(t *testing.T) {
+	repo := &auditChainRepoStub{}
+	// 迁移前写入的记录没有哈希。
+	repo.rows = []*AuditLog{{ID: 1, Action: "legacy"}, {ID: 2, Action: "legacy"}}
+	repo.nextID = 2
+	repo.seed(t, 2, time.Now())
+	svc := NewAuditLogService(repo, nil)
+
+	result, err := svc.VerifyChain(context.Background())
+	require.NoError(t, err)
+	require.True(t, result.Valid)
+	require.EqualValues(t, 2, result.LegacyRows)
+	require.EqualValues(t, 4, result.CheckedRows)
+
+	// 链开始后出现无哈希记录视为断链（哈希被抹掉）。
+	repo.rows[3].RowHash = ""
+	result, err = svc.VerifyChain(context.Background())
+	require.NoError(t, err)
+	require.False(t, result.Valid)
+	require.EqualValues(t, 4, result.FirstBroken.LogID)
+	require.Equal(t, "missing_hash", result.FirstBroken.Reason)
+}
+
+func TestAuditLogArchiveAll_RequiresObjectStore(t *testing.T) {
+	repo := &auditChainRepoStub{}
+	repo.seed(t, 3, time.Now())
+	svc := NewAuditLogService(repo, nil)
+
+	_, _, err := svc.ArchiveAll(context.Background(), &AuditLog{})
+	require.ErrorIs(t, err, ErrAuditLogArchiveStoreNotConfigured)
+
+	svc.SetArchiveStore(&auditArchiveStoreProviderStub{err: ErrBackupS3NotConfigured}, 0)
+	_, _, err = svc.ArchiveAll(context.Background(), &AuditLog{})
+	require.ErrorIs(t, err, ErrAuditLogArchiveStoreNotConfigured)
+	require.Len(t, repo.rows, 3, "nothing may be deleted without an archive")
+}
+
+func TestAuditLogArchiveAll_UploadsThenDeletesAndKeepsChain(t *testing.T) {
+	repo := &auditChainRepoStub{}
+	repo.seed(t, 5, time.Now().Add(-time.Hour))
+	store := &auditArchiveObjectStoreStub{objects: map[string][]byte{}}
+	svc := NewAuditLogService(repo, nil)
+	svc.SetArchiveStore(&auditArchiveStoreProviderStub{store: store}, 2)
+	// SIEM 只送达到 id=4：第 5 条不得归档。
+	svc.SetExportHorizon(func(context.Context) (int64, bool, error) { return 4, true, nil })
+
+	uid := int64(77)
+	trace := &AuditLog{ActorUserID: &uid, Method: "POST", Path: "/api/v1/admin/audit-logs/clear", StatusCode: 200}
+	archive, deleted, err := svc.ArchiveAll(context.Background(), trace)
+	require.NoError(t, err)
+	require.NotNil(t, archive)
+	require.EqualValues(t, 4, deleted)
+	require.EqualValues(t, 1, archive.FirstID)
+	require.EqualValues(t, 4, archive.LastID)
+	require.EqualValues(t, 4, archive.RowCount)
+	require.True(t, archive.ChainValid)
+	require.Equal(t, &uid, archive.ActorUserID)
+	require.Contains(t, archive.ObjectKey, "backups/audit-logs/")
+
+	archived := readAuditArchive(t, store.objects[archive.ObjectKey])
+	require.Len(t, archived, 4)
+	require.Equal(t, archive.LastRowHash, archived[3].RowHash)
+
+	// 剩余：第 5 条 + 归档留痕记录。
+	require.Len(t, repo.rows, 2)
+	require.EqualValues(t, 5, repo.rows[0].ID)
+	require.Equal(t, AuditActionAuditLogArchive, repo.rows[1].Action)
+	require.EqualValues(t, 4, repo.rows[1].Extra["deleted_rows"])
+
+	result, err := svc.VerifyChain(context.Background())
+	require.NoError(t, err)
+	require.True(t, result.Valid)
+	require.Equal(t, archive.LastRowHash, result.AnchorHash)
+	require.Equal(t, 1, result.ArchivesChecked)
+}
+
+func TestAuditLogArchive_ConflictRemovesUploadedObject(t *testing.T) {
+	repo := &auditChainRepoStub{}
+	repo.seed(t, 2, time.Now())
+	store := &auditArchiveObjectStoreStub{objects: map[string][]byte{}}
+	svc := NewAuditLogService(repo, nil)
+	svc.SetArchiveStore(&auditArchiveStoreProviderStub{store: store}, 0)
+
+	conflicting := &conflictingArchiveRepo{auditChainRepoStub: repo}
+	svc.repo = conflicting
+	_, _, err := svc.ArchiveAll(context.Background(), nil)
+	require.ErrorIs(t, err, ErrAuditLogArchiveConflict)
+	require.Len(t, store.deleted, 1)
+	require.Empty(t, store.objects)
+	require.Len(t, repo.rows, 2)
+}
+
+type conflictingArchiveRepo struct {
+	*auditChainRepoStub
+}
+
+func (r *conflictingArchiveRepo) CommitArchive(context.Context, *AuditLogArchive) (int64, error) {
+	return 0, ErrAuditLogArchiveConflict
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	dbent "github.com/Wei-Shaw/sub2api/ent"
+	dbgroup "github.com/Wei-Shaw/sub2api/ent/group"
+	"github.com/Wei-Shaw/sub2api/ent/userallowedgroup"
+)
+
+// SAMLUserMapping 是一次 SAML 登录从断言属性映射出的本地角色与允许分组。
+// IdP 是这两项的权威来源：配置了映射时每次登录都会覆盖本地值。
+type SAMLUserMapping struct {
+	// Role 为空表示未配置角色映射，不改动用户角色。
+	Role string
+	// SyncGroups 为 true 时以 AllowedGroupIDs 替换用户的允许分组（可为空，即清空）。
+	SyncGroups      bool
+	AllowedGroupIDs []int64
+}
+
+// ApplySAMLUserMapping 把 SAML 属性映射结果写回用户。
+// 调用方已开启事务时复用该事务，与身份绑定一起提交或回滚。
+func (s *AuthService) ApplySAMLUserMapping(ctx context.Context, userID int64, mapping SAMLUserMapping) error {
+	if s == nil || s.entClient == nil || userID <= 0 {
+		return nil
+	}
+	if mapping.Role == "" && !mapping.SyncGroups {
+		return nil
+	}
+
+	if dbent.TxFromContext(ctx) != nil {
+		return s.applySAMLUserMapping(ctx, userID, mapping)
+	}
+
+	tx, err := s.entClient.Tx(ctx)
+	if err != nil {
+		return fmt.Errorf("begin saml mapping transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	txCtx := dbent.NewTxContext(ctx, tx)
+	if err := s.applySAMLUserMapping(txCtx, userID, mapping); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *AuthService) applySAMLUserMapping(ctx context.Context, userID int64, mapping SAMLUserMapping) error {
+	client := s.entClient
+	if tx := dbent.TxFromContext(ctx); tx != nil {
+		client = tx.Client()
+	}
+
+	switch role := strings.TrimSpace(mapping.Role); role {
+	case "":
+	case RoleAdmin, RoleUser:
+		if err := client.User.UpdateOneID(userID).SetRole(role).Exec(ctx); err != nil {
+			return fmt.Errorf("apply saml role mapping: %w", err)
+		}
+	default:
+		return fmt.Errorf("apply saml role mapping: invalid role %q", role)
+	}
+
+	if !mapping.SyncGroups {
+		return nil
+	}
+
+	// 映射表里可能残留已删除的分组，只保留仍存在的，避免外键失败阻断登录
+	desired := make([]int64, 0, len(mapping.AllowedGroupIDs))
+	if len(mapping.AllowedGroupIDs) > 0 {
+		ids, err := client.Group.Query().Where(dbgroup.IDIn(mapping.AllowedGroupIDs...)).IDs(ctx)
+		if err != nil {
+			return fmt.Errorf("load saml mapped groups: %w", err)
+		}
+		desired = ids
+	}
+
+	if _, err := client.UserAllowedGroup.Delete().
+		Where(userallowedgroup.UserIDEQ(userID), userallowedgroup.GroupIDNotIn(desired...)).
+		Exec(ctx); err != nil {
+		return fmt.Errorf("remove saml unmapped groups: %w", err)
+	}
+	if len(desired) == 0 {
+		return nil
+	}
+	creates := make([]*dbent.UserAllowedGroupCreate, 0, len(desired))
+	for _, groupID := range desired {
+		creates = append(creates, client.UserAllowedGroup.Create().SetUserID(userID).SetGroupID(groupID))
+	}
+	if err := client.UserAllowedGroup.CreateBulk(creates...).
+		OnConflictColumns(userallowedgroup.FieldUserID, userallowedgroup.FieldGroupID).
+		DoNothing().
+		Exec(ctx); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("add saml mapped groups: %w", err)
+	}
+	return nil
+}
@@ -1178,6 +1178,8 @@ func inferLegacySignupSource(email string) string {
 		return "oidc"
 	case strings.HasSuffix(normalized, WeChatConnectSyntheticEmailDomain):
 		return "wechat"
+	case strings.HasSuffix(normalized, SAMLSyntheticEmailDomain):
+		return "saml"
 	default:
 		return "email"
 	}
@@ -1336,7 +1338,8 @@ func isReservedEmail(email string) bool {
 		strings.HasSuffix(normalized, GitHubOAuthSyntheticEmailDomain) ||
 		strings.HasSuffix(normalized, OIDCConnectSyntheticEmailDomain) ||
 		strings.HasSuffix(normalized, WeChatConnectSyntheticEmailDomain) ||
-		strings.HasSuffix(normalized, DingTalkConnectSyntheticEmailDomain)
+		strings.HasSuffix(normalized, DingTalkConnectSyntheticEmailDomain) ||
+		strings.HasSuffix(normalized, SAMLSyntheticEmailDomain)
 }
 
 // GenerateToken 生成JWT access token
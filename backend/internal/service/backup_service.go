@@ -356,6 +356,23 @@ func (s *BackupService) GetS3Config(ctx context.Context) (*BackupS3Config, error
 	return cfg, nil
 }
 
+// ObjectStore 返回已配置的备份对象存储及其配置，供审计日志归档等复用同一存储。
+// 未配置时返回 ErrBackupS3NotConfigured。
+func (s *BackupService) ObjectStore(ctx context.Context) (BackupObjectStore, *BackupS3Config, error) {
+	s3Cfg, err := s.loadS3Config(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s3Cfg == nil || !s3Cfg.IsConfigured() {
+		return nil, nil, ErrBackupS3NotConfigured
+	}
+	objectStore, err := s.getOrCreateStore(ctx, s3Cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("init object store: %w", err)
+	}
+	return objectStore, s3Cfg, nil
+}
+
 func (s *BackupService) UpdateS3Config(ctx context.Context, cfg BackupS3Config) (*BackupS3Config, error) {
 	// 如果没提供 secret，保留原有值
 	if cfg.SecretAccessKey == "" {
@@ -1657,6 +1657,72 @@ func (s *BillingService) CalculateAudioCost(mode string, durationOrUnits float64
 	}
 }
 
+type moderationPriceConfig struct {
+	PerCall          *float64
+	PerMillionTokens *float64
+}
+
+// CalculateModerationCost 计算 /v1/moderations 费用：按次价 + 每百万输入 token 价，两者叠加。
+// 审计上游本身免费，未配置（nil）的维度不收费。
+func (s *BillingService) CalculateModerationCost(inputTokens int, groupConfig *moderationPriceConfig, rateMultiplier float64) *CostBreakdown {
+	if groupConfig == nil {
+		return &CostBreakdown{BillingMode: string(BillingModePerRequest)}
+	}
+	var total float64
+	if groupConfig.PerCall != nil && *groupConfig.PerCall > 0 {
+		total += *groupConfig.PerCall
+	}
+	if groupConfig.PerMillionTokens != nil && *groupConfig.PerMillionTokens > 0 && inputTokens > 0 {
+		total += *groupConfig.PerMillionTokens * float64(inputTokens) / 1_000_000
+	}
+	if rateMultiplier < 0 {
+		rateMultiplier = 0
+	}
+	return &CostBreakdown{
+		TotalCost:   total,
+		ActualCost:  total * rateMultiplier,
+		BillingMode: string(BillingModePerRequest),
+	}
+}
+
+// Gemini context caching 存储单价（USD / token / 小时），价格表未提供
+// cache_storage_cost_per_token_per_hour 时使用 Google 公布的价格。
+const (
+	defaultGeminiCacheStoragePricePerTokenHour    = 1.0e-6 // $1.00 / MTok / 小时（Flash 系列）
+	defaultGeminiProCacheStoragePricePerTokenHour = 4.5e-6 // $4.50 / MTok / 小时（Pro 系列）
+)
+
+// cachedContentStoragePricePerTokenHour 返回模型的缓存存储单价，优先使用价格表。
+func (s *BillingService) cachedContentStoragePricePerTokenHour(model string) float64 {
+	if s != nil && s.pricingService != nil {
+		if pricing := s.pricingService.GetModelPricing(model); pricing != nil && pricing.CacheStorageCostPerTokenPerHour > 0 {
+			return pricing.CacheStorageCostPerTokenPerHour
+		}
+	}
+	if strings.Contains(strings.ToLower(model), "pro") {
+		return defaultGeminiProCacheStoragePricePerTokenHour
+	}
+	return defaultGeminiCacheStoragePricePerTokenHour
+}
+
+// CalculateCachedContentStorageCost 计算 Gemini cachedContents 存储费用：缓存 token 数 × 存储小时 × 单价。
+// 缓存命中（cachedContentTokenCount）走 token 计费的 cache_read 价格，不在此计算。
+func (s *BillingService) CalculateCachedContentStorageCost(model string, tokens int, hours float64, rateMultiplier float64) *CostBreakdown {
+	if tokens <= 0 || hours <= 0 {
+		return &CostBreakdown{BillingMode: string(BillingModePerRequest)}
+	}
+	if rateMultiplier < 0 {
+		rateMultiplier = 0
+	}
+	total := float64(tokens) * hours * s.cachedContentStoragePricePerTokenHour(model)
+	return &CostBreakdown{
+		CacheCreationCost: total,
+		TotalCost:         total,
+		ActualCost:        total * rateMultiplier,
+		BillingMode:       string(BillingModePerRequest),
+	}
+}
+
 // CalculateImageCost 计算图片生成费用
 // model: 请求的模型名称（用于获取 LiteLLM 默认价格）
 // imageSize: 图片尺寸 "1K", "2K", "4K"
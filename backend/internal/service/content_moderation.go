@@ -63,6 +63,7 @@ const (
 	maxContentModerationTimeoutMS     = 30000
 	maxModerationInputRunes           = 12000
 	maxModerationExcerptRunes         = 240
+	maxModerationResponseBytes        = 4 << 20
 
 	defaultContentModerationWorkerCount          = 4
 	maxContentModerationWorkerCount              = 32
@@ -1683,6 +1684,36 @@ func (s *ContentModerationService) validateConfig(ctx context.Context, cfg *Cont
 }
 
 func (s *ContentModerationService) callModeration(ctx context.Context, cfg *ContentModerationConfig, input any, trackKeyLoad ...bool) (*moderationAPIResult, error) {
+	var result *moderationAPIResult
+	err := s.withModerationAPIKeys(ctx, cfg, len(trackKeyLoad) > 0 && trackKeyLoad[0], func(key string, httpStatus *int) error {
+		var err error
+		result, err = s.callModerationOnceWithInput(ctx, cfg, key, input, httpStatus)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// callModerationRaw 与 callModeration 共用 key 池轮转、重试与冻结策略，
+// 但返回上游原始响应体（对外 /v1/moderations 需要完整的 categories 等字段）。
+func (s *ContentModerationService) callModerationRaw(ctx context.Context, cfg *ContentModerationConfig, input any, trackKeyLoad bool) ([]byte, error) {
+	var body []byte
+	err := s.withModerationAPIKeys(ctx, cfg, trackKeyLoad, func(key string, httpStatus *int) error {
+		var err error
+		body, err = s.postModeration(ctx, cfg, key, input, httpStatus)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// withModerationAPIKeys 依次从 key 池取可用 key 执行 call，失败时记录 key 健康度并按
+// cfg.RetryCount 重试；上游 400 视为输入问题，不再换 key 重试。
+func (s *ContentModerationService) withModerationAPIKeys(ctx context.Context, cfg *ContentModerationConfig, trackLoad bool, call func(key string, httpStatus *int) error) error {
 	attempts := cfg.RetryCount + 1
 	if attempts <= 0 {
 		attempts = 1
@@ -1690,7 +1721,6 @@ func (s *ContentModerationService) callModeration(ctx context.Context, cfg *Cont
 	if attempts > maxContentModerationRetryCount+1 {
 		attempts = maxContentModerationRetryCount + 1
 	}
-	trackLoad := len(trackKeyLoad) > 0 && trackKeyLoad[0]
 	var lastErr error
 	for attempt := 0; attempt < attempts; attempt++ {
 		key, ok := s.nextUsableAPIKey(cfg)
@@ -1703,14 +1733,14 @@ func (s *ContentModerationService) callModeration(ctx context.Context, cfg *Cont
 		}
 		start := time.Now()
 		httpStatus := 0
-		result, err := s.callModerationOnceWithInput(ctx, cfg, key, input, &httpStatus)
+		err := call(key, &httpStatus)
 		latency := int(time.Since(start).Milliseconds())
 		if err == nil {
 			if trackLoad {
 				s.finishModerationAPIKeyCall(key, latency, true)
 			}
 			s.markAPIKeySuccess(key, latency, httpStatus)
-			return result, nil
+			return nil
 		}
 		if trackLoad {
 			s.finishModerationAPIKeyCall(key, latency, false)
@@ -1726,14 +1756,39 @@ func (s *ContentModerationService) callModeration(ctx context.Context, cfg *Cont
 		wait := time.Duration(100*(attempt+1)) * time.Millisecond
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return ctx.Err()
 		case <-time.After(wait):
 		}
 	}
-	return nil, lastErr
+	return lastErr
 }
 
 func (s *ContentModerationService) callModerationOnceWithInput(ctx context.Context, cfg *ContentModerationConfig, apiKey string, input any, httpStatus *int) (*moderationAPIResult, error) {
+	raw, err := s.postModeration(ctx, cfg, apiKey, input, httpStatus)
+	if err != nil {
+		return nil, err
+	}
+	var out moderationAPIResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+	if len(out.Results) == 0 {
+		return nil, errors.New("moderation api returned empty results")
+	}
+	return &out.Results[0], nil
+}
+
+// moderationAPIStatusError 是上游返回非 2xx 时的错误，保留状态码与截断后的响应体。
+type moderationAPIStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *moderationAPIStatusError) Error() string {
+	return fmt.Sprintf("moderation api status %d: %s", e.StatusCode, e.Body)
+}
+
+func (s *ContentModerationService) postModeration(ctx context.Context, cfg *ContentModerationConfig, apiKey string, input any, httpStatus *int) ([]byte, error) {
 	base := strings.TrimRight(cfg.BaseURL, "/")
 	endpoint, err := url.JoinPath(base, "/v1/moderations")
 	if err != nil {
@@ -1773,16 +1828,9 @@ func (s *ContentModerationService) callModerationOnceWithInput(ctx context.Conte
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
-		return nil, fmt.Errorf("moderation api status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
-	}
-	var out moderationAPIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return nil, err
+		return nil, &moderationAPIStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
 	}
-	if len(out.Results) == 0 {
-		return nil, errors.New("moderation api returned empty results")
-	}
-	return &out.Results[0], nil
+	return io.ReadAll(io.LimitReader(resp.Body, maxModerationResponseBytes))
 }
 
 // moderationProxyURLCacheEntry 缓存 proxy_id 到代理 URL 的解析结果，
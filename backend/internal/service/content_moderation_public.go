@@ -0,0 +1,276 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/tidwall/sjson"
+)
+
+// 对外 /v1/moderations：复用风控审计的 key 池（轮转、冻结、负载统计）、代理与
+// flagged hash 缓存，让业务方用自己的 sub2api key 直接调用审核。
+//
+// 对外调用只是"查询"：不写风控日志、不记录 hash、不计入违规次数/自动封号，
+// 否则业务方预检内容会被当成违规请求处罚。
+
+const (
+	maxPublicModerationInputs = 32
+
+	publicModerationInputPartText  = "text"
+	publicModerationInputPartImage = "image_url"
+)
+
+var (
+	ErrPublicModerationUnavailable = infraerrors.ServiceUnavailable("MODERATION_UNAVAILABLE", "moderation is not available")
+	ErrPublicModerationUpstream    = infraerrors.New(http.StatusBadGateway, "MODERATION_UPSTREAM_ERROR", "moderation upstream request failed")
+)
+
+// PublicModerationRequest 是解析后的 /v1/moderations 请求。
+type PublicModerationRequest struct {
+	Model string
+	// Input 原样透传给上游（string、string 数组或 text/image_url 多模态数组）。
+	Input json.RawMessage
+	// IncludeGatewayPolicy 为 true 时在响应中附带 gateway_policy，
+	// 说明网关自身的审计策略是否会拦截该输入。
+	IncludeGatewayPolicy bool
+
+	Texts  []string
+	Images []string
+}
+
+type publicModerationRequestBody struct {
+	Model                string          `json:"model"`
+	Input                json.RawMessage `json:"input"`
+	IncludeGatewayPolicy bool            `json:"include_gateway_policy"`
+}
+
+// ParsePublicModerationRequest 解析并校验 OpenAI 兼容的 moderation 请求体。
+func ParsePublicModerationRequest(body []byte) (*PublicModerationRequest, error) {
+	var raw publicModerationRequestBody
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, errors.New("failed to parse request body")
+	}
+	req := &PublicModerationRequest{
+		Model:                strings.TrimSpace(raw.Model),
+		Input:                bytes.TrimSpace(raw.Input),
+		IncludeGatewayPolicy: raw.IncludeGatewayPolicy,
+	}
+	if len(req.Input) == 0 || bytes.Equal(req.Input, []byte("null")) {
+		return nil, errors.New("input is required")
+	}
+
+	var text string
+	if err := json.Unmarshal(req.Input, &text); err == nil {
+		req.Texts = []string{text}
+		return req.validate()
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(req.Input, &items); err != nil {
+		return nil, errors.New("input must be a string or an array")
+	}
+	if len(items) == 0 {
+		return nil, errors.New("input is required")
+	}
+	if len(items) > maxPublicModerationInputs {
+		return nil, fmt.Errorf("input supports at most %d items", maxPublicModerationInputs)
+	}
+	for _, item := range items {
+		if err := json.Unmarshal(item, &text); err == nil {
+			req.Texts = append(req.Texts, text)
+			continue
+		}
+		var part moderationAPIInputPart
+		if err := json.Unmarshal(item, &part); err != nil {
+			return nil, errors.New("input items must be strings or content parts")
+		}
+		switch part.Type {
+		case publicModerationInputPartText:
+			req.Texts = append(req.Texts, part.Text)
+		case publicModerationInputPartImage:
+			if part.ImageURL == nil || strings.TrimSpace(part.ImageURL.URL) == "" {
+				return nil, errors.New("image_url.url is required")
+			}
+			req.Images = append(req.Images, part.ImageURL.URL)
+		default:
+			return nil, fmt.Errorf("unsupported input type %q", part.Type)
+		}
+	}
+	return req.validate()
+}
+
+func (r *PublicModerationRequest) validate() (*PublicModerationRequest, error) {
+	for _, text := range r.Texts {
+		if strings.TrimSpace(text) != "" {
+			return r, nil
+		}
+	}
+	if len(r.Images) > 0 {
+		return r, nil
+	}
+	return nil, errors.New("input is required")
+}
+
+// Content 返回与网关审计相同口径的输入（文本换行拼接），用于关键词/hash 判定。
+func (r *PublicModerationRequest) Content() ContentModerationInput {
+	content := ContentModerationInput{Text: strings.Join(r.Texts, "\n"), Images: r.Images}
+	content.Normalize()
+	return content
+}
+
+// InputTokens 估算文本输入的 token 数，用于按 token 计费；图片只按次计费。
+func (r *PublicModerationRequest) InputTokens() int {
+	codec, err := openAIInputTokensCodecForModel(r.Model)
+	if err != nil {
+		return 0
+	}
+	total := 0
+	for _, text := range r.Texts {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if n, err := codec.Count(text); err == nil {
+			total += n
+		}
+	}
+	return total
+}
+
+// ContentModerationPolicyVerdict 描述网关自身审计策略对某输入的判定。
+// 不考虑采样率与模型范围：对外调用时尚不知道调用方最终使用的模型。
+type ContentModerationPolicyVerdict struct {
+	Enabled         bool    `json:"enabled"`
+	Mode            string  `json:"mode"`
+	InScope         bool    `json:"in_scope"`
+	Flagged         bool    `json:"flagged"`
+	WouldBlock      bool    `json:"would_block"`
+	Action          string  `json:"action"`
+	HighestCategory string  `json:"highest_category,omitempty"`
+	HighestScore    float64 `json:"highest_score,omitempty"`
+}
+
+// PublicModerationResult 是对外审核结果。
+type PublicModerationResult struct {
+	// Body 为上游响应体；请求了策略判定时已注入 gateway_policy 字段。
+	Body        []byte
+	ID          string
+	Model       string
+	InputTokens int
+	Policy      *ContentModerationPolicyVerdict
+}
+
+type publicModerationAPIResponse struct {
+	ID      string                `json:"id"`
+	Model   string                `json:"model"`
+	Results []moderationAPIResult `json:"results"`
+}
+
+// ModeratePublic 通过审计 key 池调用上游 moderation，并按需给出网关策略判定。
+func (s *ContentModerationService) ModeratePublic(ctx context.Context, req *PublicModerationRequest, groupID *int64) (*PublicModerationResult, error) {
+	if s == nil || s.settingRepo == nil || req == nil {
+		return nil, ErrPublicModerationUnavailable
+	}
+	snapshot, err := s.loadRuntimeSnapshot(ctx)
+	if err != nil {
+		return nil, ErrPublicModerationUnavailable.WithCause(err)
+	}
+	cfg := snapshot.config
+	if len(cfg.apiKeys()) == 0 {
+		return nil, ErrPublicModerationUnavailable
+	}
+	callCfg := *cfg
+	if req.Model != "" {
+		callCfg.Model = req.Model
+	}
+
+	raw, err := s.callModerationRaw(ctx, &callCfg, req.Input, true)
+	if err != nil {
+		var statusErr *moderationAPIStatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusBadRequest {
+			return nil, infraerrors.BadRequest("MODERATION_INVALID_REQUEST", publicModerationUpstreamMessage(statusErr.Body))
+		}
+		return nil, ErrPublicModerationUpstream.WithCause(err)
+	}
+	var parsed publicModerationAPIResponse
+	if err := json.Unmarshal(raw, &parsed); err != nil || len(parsed.Results) == 0 {
+		return nil, ErrPublicModerationUpstream.WithCause(errors.New("moderation api returned empty results"))
+	}
+
+	out := &PublicModerationResult{
+		Body:        raw,
+		ID:          parsed.ID,
+		Model:       firstNonEmpty(parsed.Model, callCfg.Model),
+		InputTokens: req.InputTokens(),
+	}
+	if req.IncludeGatewayPolicy {
+		out.Policy = s.publicModerationPolicy(ctx, snapshot, groupID, req.Content(), parsed.Results)
+		if body, err := sjson.SetBytes(raw, "gateway_policy", out.Policy); err == nil {
+			out.Body = body
+		}
+	}
+	return out, nil
+}
+
+// publicModerationPolicy 按 Check 的判定顺序（关键词 → hash → 分数阈值）复现网关策略，
+// 但没有任何副作用。
+func (s *ContentModerationService) publicModerationPolicy(ctx context.Context, snapshot *contentModerationRuntimeSnapshot, groupID *int64, content ContentModerationInput, results []moderationAPIResult) *ContentModerationPolicyVerdict {
+	cfg := snapshot.config
+	verdict := &ContentModerationPolicyVerdict{
+		Enabled: snapshot.riskControlEnabled && cfg.Enabled && cfg.Mode != ContentModerationModeOff,
+		Mode:    cfg.Mode,
+		InScope: cfg.includesGroup(groupID),
+		Action:  ContentModerationActionAllow,
+	}
+	if !verdict.Enabled || !verdict.InScope {
+		return verdict
+	}
+	preBlock := cfg.Mode == ContentModerationModePreBlock
+	if preBlock && cfg.KeywordBlockingMode != ContentModerationKeywordModeAPIOnly && len(cfg.BlockedKeywords) > 0 {
+		if _, hit := snapshot.matchBlockedKeyword(content.Text); hit {
+			verdict.Flagged, verdict.WouldBlock = true, true
+			verdict.Action = ContentModerationActionKeywordBlock
+			verdict.HighestCategory, verdict.HighestScore = contentModerationKeywordCategory, 1.0
+			return verdict
+		}
+	}
+	if cfg.PreHashCheckEnabled && s.hashCache != nil {
+		matched, err := s.hashCache.HasFlaggedInputHash(ctx, content.Hash())
+		if err == nil && matched {
+			verdict.Flagged, verdict.WouldBlock = true, true
+			verdict.Action = ContentModerationActionHashBlock
+			return verdict
+		}
+	}
+	if preBlock && cfg.KeywordBlockingMode == ContentModerationKeywordModeKeywordOnly {
+		return verdict
+	}
+	for _, result := range results {
+		flagged, category, score := evaluateModerationScores(result.CategoryScores, cfg.Thresholds)
+		verdict.Flagged = verdict.Flagged || flagged
+		if verdict.HighestCategory == "" || score > verdict.HighestScore {
+			verdict.HighestCategory, verdict.HighestScore = category, score
+		}
+	}
+	if verdict.Flagged && preBlock {
+		verdict.WouldBlock = true
+		verdict.Action = ContentModerationActionBlock
+	}
+	return verdict
+}
+
+func publicModerationUpstreamMessage(body string) string {
+	var payload struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(body), &payload); err == nil && strings.TrimSpace(payload.Error.Message) != "" {
+		return payload.Error.Message
+	}
+	return "invalid moderation request"
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestParsePublicModerationRequest(t *testing.T) {
+	req, err := ParsePublicModerationRequest([]byte(`{"input":"hello world"}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"hello world"}, req.Texts)
+	require.False(t, req.IncludeGatewayPolicy)
+	require.Positive(t, req.InputTokens())
+
+	req, err = ParsePublicModerationRequest([]byte(`{
+		"model":"omni-moderation-latest",
+		"include_gateway_policy":true,
+		"input":[{"type":"text","text":"a"},{"type":"image_url","image_url":{"url":"https://example.com/x.png"}}]
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, "omni-moderation-latest", req.Model)
+	require.True(t, req.IncludeGatewayPolicy)
+	require.Equal(t, []string{"a"}, req.Texts)
+	require.Equal(t, []string{"https://example.com/x.png"}, req.Images)
+
+	req, err = ParsePublicModerationRequest([]byte(`{"input":["first","second"]}`))
+	require.NoError(t, err)
+	require.Equal(t, "first second", req.Content().Text)
+
+	for _, body := range []string{
+		`{}`,
+		`{"input":""}`,
+		`{"input":[]}`,
+		`{"input":42}`,
+		`{"input":[{"type":"audio"}]}`,
+		`{"input":[{"type":"image_url"}]}`,
+	} {
+		_, err := ParsePublicModerationRequest([]byte(body))
+		require.Error(t, err, body)
+	}
+}
+
+func newPublicModerationTestService(t *testing.T, cfg *ContentModerationConfig, riskControl bool, cache *contentModerationTestHashCache) *ContentModerationService {
+	t.Helper()
+	rawCfg, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	enabled := "false"
+	if riskControl {
+		enabled = "true"
+	}
+	return NewContentModerationService(
+		&contentModerationTestSettingRepo{values: map[string]string{
+			SettingKeyRiskControlEnabled:      enabled,
+			SettingKeyContentModerationConfig: string(rawCfg),
+		}},
+		nil,
+		cache,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+	)
+}
+
+func TestContentModerationModeratePublic_PassesThroughAndReportsPolicy(t *testing.T) {
+	var upstream moderationAPIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/moderations", r.URL.Path)
+		require.Equal(t, "Bearer sk-pool", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&upstream))
+		_, _ = w.Write([]byte(`{"id":"modr-1","model":"omni-moderation-2024-09-26","results":[
+			{"flagged":true,"categories":{"violence":true},"category_scores":{"violence":0.97}}
+		]}`))
+	}))
+	defer server.Close()
+
+	cfg := defaultContentModerationConfig()
+	cfg.Enabled = true
+	cfg.Mode = ContentModerationModePreBlock
+	cfg.BaseURL = server.URL
+	cfg.APIKeys = []string{"sk-pool"}
+	cfg.PreHashCheckEnabled = true
+	cache := &contentModerationTestHashCache{}
+	svc := newPublicModerationTestService(t, cfg, true, cache)
+
+	req, err := ParsePublicModerationRequest([]byte(`{"input":["x","y"],"include_gateway_policy":true}`))
+	require.NoError(t, err)
+	result, err := svc.ModeratePublic(context.Background(), req, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, cfg.Model, upstream.Model)
+	require.Equal(t, []any{"x", "y"}, upstream.Input)
+	require.Equal(t, "modr-1", result.ID)
+	require.Equal(t, "omni-moderation-2024-09-26", result.Model)
+	require.True(t, gjson.GetBytes(result.Body, "results.0.categories.violence").Bool())
+	require.True(t, gjson.GetBytes(result.Body, "gateway_policy.would_block").Bool())
+	require.Equal(t, ContentModerationActionBlock, result.Policy.Action)
+	require.Equal(t, "violence", result.Policy.HighestCategory)
+	// 对外调用只查询 hash 缓存，不写入。
+	require.Len(t, cache.checked, 1)
+	require.Empty(t, cache.recorded)
+}
+
+func TestContentModerationModeratePublic_PolicyWhenRiskControlDisabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"modr-2","results":[{"flagged":true,"category_scores":{"violence":0.99}}]}`))
+	}))
+	defer server.Close()
+
+	cfg := defaultContentModerationConfig()
+	cfg.Enabled = true
+	cfg.Mode = ContentModerationModePreBlock
+	cfg.BaseURL = server.URL
+	cfg.APIKeys = []string{"sk-pool"}
+	svc := newPublicModerationTestService(t, cfg, false, &contentModerationTestHashCache{})
+
+	req, err := ParsePublicModerationRequest([]byte(`{"model":"text-moderation-latest","input":"x","include_gateway_policy":true}`))
+	require.NoError(t, err)
+	result, err := svc.ModeratePublic(context.Background(), req, nil)
+	require.NoError(t, err)
+	require.False(t, result.Policy.Enabled)
+	require.False(t, result.Policy.WouldBlock)
+	require.Equal(t, ContentModerationActionAllow, result.Policy.Action)
+
+	req.IncludeGatewayPolicy = false
+	result, err = svc.ModeratePublic(context.Background(), req, nil)
+	require.NoError(t, err)
+	require.Nil(t, result.Policy)
+	require.False(t, gjson.GetBytes(result.Body, "gateway_policy").Exists())
+}
+
+func TestContentModerationModeratePublic_Errors(t *testing.T) {
+	cfg := defaultContentModerationConfig()
+	svc := newPublicModerationTestService(t, cfg, true, nil)
+	req, err := ParsePublicModerationRequest([]byte(`{"input":"x"}`))
+	require.NoError(t, err)
+	_, err = svc.ModeratePublic(context.Background(), req, nil)
+	require.ErrorIs(t, err, ErrPublicModerationUnavailable)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"Invalid image URL"}}`))
+	}))
+	defer server.Close()
+	cfg.BaseURL = server.URL
+	cfg.APIKeys = []string{"sk-pool"}
+	svc = newPublicModerationTestService(t, cfg, true, nil)
+	_, err = svc.ModeratePublic(context.Background(), req, nil)
+	require.Equal(t, http.StatusBadRequest, infraerrors.Code(err))
+	require.Equal(t, "Invalid image URL", infraerrors.Message(err))
+}
+
+func TestCalculateModerationCost(t *testing.T) {
+	s := &BillingService{}
+	require.Zero(t, s.CalculateModerationCost(1000, nil, 1).ActualCost)
+
+	perCall, perM := 0.001, 0.5
+	cost := s.CalculateModerationCost(2_000_000, &moderationPriceConfig{PerCall: &perCall, PerMillionTokens: &perM}, 2)
+	require.InDelta(t, 1.001, cost.TotalCost, 1e-9)
+	require.InDelta(t, 2.002, cost.ActualCost, 1e-9)
+	require.Equal(t, string(BillingModePerRequest), cost.BillingMode)
+}
@@ -0,0 +1,374 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// 分组级上下文溢出压缩策略。
+const (
+	ContextCompactionModeOff       = "off"
+	ContextCompactionModeTruncate  = "truncate"
+	ContextCompactionModeSummarize = "summarize"
+)
+
+// ContextCompactionHeader 告知客户端网关对本次请求做了什么压缩。
+const ContextCompactionHeader = "X-Sub2API-Context-Compaction"
+
+// ContextCompactionFormat 区分请求体格式。
+type ContextCompactionFormat string
+
+const (
+	ContextCompactionFormatAnthropic ContextCompactionFormat = "anthropic"
+	ContextCompactionFormatChat      ContextCompactionFormat = "chat"
+)
+
+const (
+	// contextCompactionSafetyRatio 预留的窗口比例，抵消 token 估算误差。
+	contextCompactionSafetyRatio = 0.05
+	// contextCompactionDefaultOutputTokens 请求未声明输出上限时预留的输出 token。
+	contextCompactionDefaultOutputTokens = 4096
+	// contextCompactionSummaryMaxTokens 摘要请求的输出上限，同时作为摘要在主请求中的预算。
+	contextCompactionSummaryMaxTokens = 1024
+
+	contextCompactionSummaryPrompt = "You compress earlier parts of a conversation so it can continue within a limited context window. " +
+		"Summarize the transcript below. Keep facts, decisions, open tasks, file names, identifiers, and the results of tool calls. " +
+		"Reply with the summary only."
+	contextCompactionSummaryPreamble = "Summary of earlier conversation turns removed by the gateway to fit the model context window:\n\n"
+)
+
+// NormalizeContextCompactionConfig 校验并规范化分组的压缩配置；关闭时两个字段均返回空串。
+func NormalizeContextCompactionConfig(mode, model string) (string, string, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	model = strings.TrimSpace(model)
+	switch mode {
+	case "", ContextCompactionModeOff:
+		return "", "", nil
+	case ContextCompactionModeTruncate:
+		return mode, "", nil
+	case ContextCompactionModeSummarize:
+		if model == "" {
+			return "", "", infraerrors.BadRequest("INVALID_CONTEXT_COMPACTION", "context_compaction_model is required when context_compaction_mode is summarize")
+		}
+		if len(model) > 100 {
+			return "", "", infraerrors.BadRequest("INVALID_CONTEXT_COMPACTION", "context_compaction_model must be at most 100 characters")
+		}
+		return mode, model, nil
+	default:
+		return "", "", infraerrors.BadRequest("INVALID_CONTEXT_COMPACTION", "context_compaction_mode must be one of off, truncate, summarize")
+	}
+}
+
+// ContextCompactionPlan 描述一次需要压缩的请求。
+// Body 为丢弃最旧轮次后的请求体；summarize 模式下由调用方先执行 SummaryRequest，
+// 再通过 ApplySummary 把摘要写回 Body。
+type ContextCompactionPlan struct {
+	Format          ContextCompactionFormat
+	Mode            string
+	SummaryModel    string
+	Window          int
+	EstimatedBefore int
+	EstimatedAfter  int
+	DroppedMessages int
+	Body            []byte
+
+	dropped []string
+}
+
+// SummaryRequest 构造发给廉价模型的非流式摘要请求，格式与原请求一致。
+func (p *ContextCompactionPlan) SummaryRequest() ([]byte, error) {
+	transcript := "[" + strings.Join(p.dropped, ",") + "]"
+	var body []byte
+	var err error
+	switch p.Format {
+	case ContextCompactionFormatAnthropic:
+		body, err = sjson.SetBytes([]byte(`{}`), "model", p.SummaryModel)
+		if err == nil {
+			body, err = sjson.SetBytes(body, "max_tokens", contextCompactionSummaryMaxTokens)
+		}
+		if err == nil {
+			body, err = sjson.SetBytes(body, "system", contextCompactionSummaryPrompt)
+		}
+		if err == nil {
+			body, err = sjson.SetBytes(body, "messages.0", map[string]string{"role": "user", "content": transcript})
+		}
+	default:
+		body, err = sjson.SetBytes([]byte(`{}`), "model", p.SummaryModel)
+		if err == nil {
+			body, err = sjson.SetBytes(body, "max_tokens", contextCompactionSummaryMaxTokens)
+		}
+		if err == nil {
+			body, err = sjson.SetBytes(body, "messages.0", map[string]string{"role": "system", "content": contextCompactionSummaryPrompt})
+		}
+		if err == nil {
+			body, err = sjson.SetBytes(body, "messages.1", map[string]string{"role": "user", "content": transcript})
+		}
+	}
+	if err == nil {
+		body, err = sjson.SetBytes(body, "stream", false)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("build summary request: %w", err)
+	}
+	return body, nil
+}
+
+// ApplySummary 把摘要写入压缩后的请求体：Anthropic 追加到 system，Chat 插在开头的
+// system/developer 消息之后。
+func (p *ContextCompactionPlan) ApplySummary(summary string) error {
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return fmt.Errorf("empty summary")
+	}
+	text := contextCompactionSummaryPreamble + summary
+	var body []byte
+	var err error
+	switch p.Format {
+	case ContextCompactionFormatAnthropic:
+		system := gjson.GetBytes(p.Body, "system")
+		switch {
+		case system.IsArray():
+			body, err = sjson.SetBytes(p.Body, "system.-1", map[string]string{"type": "text", "text": text})
+		case system.Type == gjson.String && strings.TrimSpace(system.String()) != "":
+			body, err = sjson.SetBytes(p.Body, "system", system.String()+"\n\n"+text)
+		default:
+			body, err = sjson.SetBytes(p.Body, "system", text)
+		}
+	default:
+		messages := gjson.GetBytes(p.Body, "messages").Array()
+		pinned := chatPinnedPrefix(messages)
+		raws := make([]string, 0, len(messages)+1)
+		for i, message := range messages {
+			if i == pinned {
+				raws = append(raws, chatSystemMessageRaw(text))
+			}
+			raws = append(raws, message.Raw)
+		}
+		if pinned == len(messages) {
+			raws = append(raws, chatSystemMessageRaw(text))
+		}
+		body, err = sjson.SetRawBytes(p.Body, "messages", []byte("["+strings.Join(raws, ",")+"]"))
+	}
+	if err != nil {
+		return fmt.Errorf("apply summary: %w", err)
+	}
+	p.Body = body
+	p.EstimatedAfter += estimateTokensForText(text)
+	return nil
+}
+
+// HeaderValue 渲染 X-Sub2API-Context-Compaction 响应头。
+func (p *ContextCompactionPlan) HeaderValue(summarized bool) string {
+	action := "truncated"
+	if summarized {
+		action = "summarized"
+	}
+	parts := []string{
+		action,
+		"dropped_messages=" + strconv.Itoa(p.DroppedMessages),
+		"estimated_tokens=" + strconv.Itoa(p.EstimatedBefore) + "->" + strconv.Itoa(p.EstimatedAfter),
+		"window=" + strconv.Itoa(p.Window),
+	}
+	if summarized {
+		parts = append(parts, "summary_model="+p.SummaryModel)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ContextCompactionService 在请求超出映射后模型的上下文窗口时裁剪最旧的轮次。
+// 窗口取自定价数据的 max_input_tokens；未知窗口的模型不做处理。
+type ContextCompactionService struct {
+	pricingService *PricingService
+	channelService *ChannelService
+}
+
+func NewContextCompactionService(pricingService *PricingService, channelService *ChannelService) *ContextCompactionService {
+	return &ContextCompactionService{pricingService: pricingService, channelService: channelService}
+}
+
+// Plan 返回 nil 表示无需压缩（策略关闭、窗口未知、请求未超限或无可丢弃的轮次）。
+func (s *ContextCompactionService) Plan(ctx context.Context, group *Group, format ContextCompactionFormat, body []byte) *ContextCompactionPlan {
+	if s == nil || group == nil {
+		return nil
+	}
+	mode, summaryModel, err := NormalizeContextCompactionConfig(group.ContextCompactionMode, group.ContextCompactionModel)
+	if err != nil || mode == "" {
+		return nil
+	}
+	model := strings.TrimSpace(gjson.GetBytes(body, "model").String())
+	if model == "" {
+		return nil
+	}
+	window := s.contextWindow(ctx, group.ID, model)
+	if window <= 0 {
+		return nil
+	}
+	return planContextCompaction(format, mode, summaryModel, window, body)
+}
+
+func (s *ContextCompactionService) contextWindow(ctx context.Context, groupID int64, model string) int {
+	if s.pricingService == nil {
+		return 0
+	}
+	mapped := model
+	if s.channelService != nil {
+		if mapping, _ := s.channelService.ResolveChannelMappingAndRestrict(ctx, &groupID, model); mapping.MappedModel != "" {
+			mapped = mapping.MappedModel
+		}
+	}
+	pricing := s.pricingService.GetModelPricing(mapped)
+	if pricing == nil {
+		return 0
+	}
+	return pricing.MaxInputTokens
+}
+
+// ExtractContextCompactionSummary 从摘要请求的非流式响应中取出文本。
+func ExtractContextCompactionSummary(format ContextCompactionFormat, responseBody []byte) string {
+	if format == ContextCompactionFormatAnthropic {
+		var parts []string
+		for _, block := range gjson.GetBytes(responseBody, "content").Array() {
+			if block.Get("type").String() == "text" {
+				parts = append(parts, block.Get("text").String())
+			}
+		}
+		return strings.TrimSpace(strings.Join(parts, "\n"))
+	}
+	return strings.TrimSpace(gjson.GetBytes(responseBody, "choices.0.message.content").String())
+}
+
+func planContextCompaction(format ContextCompactionFormat, mode, summaryModel string, window int, body []byte) *ContextCompactionPlan {
+	messages := gjson.GetBytes(body, "messages").Array()
+	if len(messages) < 2 {
+		return nil
+	}
+	fixed := estimateTokensForText(gjson.GetBytes(body, "tools").Raw)
+	pinned := 0
+	if format == ContextCompactionFormatAnthropic {
+		fixed += estimateTokensForText(gjson.GetBytes(body, "system").Raw)
+	} else {
+		pinned = chatPinnedPrefix(messages)
+	}
+	costs := make([]int, len(messages))
+	total := fixed
+	for i, message := range messages {
+		costs[i] = estimateTokensForText(message.Raw)
+		total += costs[i]
+	}
+
+	budget := window - contextCompactionOutputTokens(format, body) - int(float64(window)*contextCompactionSafetyRatio)
+	if mode == ContextCompactionModeSummarize {
+		budget -= contextCompactionSummaryMaxTokens
+	}
+	if total <= budget {
+		return nil
+	}
+
+	starts := contextCompactionUnitStarts(format, messages, pinned)
+	if len(starts) < 2 {
+		return nil
+	}
+	// 按轮次从最旧开始丢弃，最后一轮始终保留；工具调用与其结果同属一轮，不会被拆开。
+	after := total
+	cut := pinned
+	for _, next := range starts[1:] {
+		if after <= budget {
+			break
+		}
+		for i := cut; i < next; i++ {
+			after -= costs[i]
+		}
+		cut = next
+	}
+	if cut == pinned {
+		return nil
+	}
+
+	raws := make([]string, 0, len(messages)-cut+pinned)
+	dropped := make([]string, 0, cut-pinned)
+	for i, message := range messages {
+		switch {
+		case i < pinned || i >= cut:
+			raws = append(raws, message.Raw)
+		default:
+			dropped = append(dropped, message.Raw)
+		}
+	}
+	compacted, err := sjson.SetRawBytes(body, "messages", []byte("["+strings.Join(raws, ",")+"]"))
+	if err != nil {
+		return nil
+	}
+	return &ContextCompactionPlan{
+		Format:          format,
+		Mode:            mode,
+		SummaryModel:    summaryModel,
+		Window:          window,
+		EstimatedBefore: total,
+		EstimatedAfter:  after,
+		DroppedMessages: len(dropped),
+		Body:            compacted,
+		dropped:         dropped,
+	}
+}
+
+// contextCompactionUnitStarts 返回每一轮起始消息的下标（第一轮从 pinned 开始）。
+// 一轮从一条真正的用户输入开始：Anthropic 中只含 tool_result 的 user 消息、
+// Chat 中的 tool 消息都属于上一轮的工具调用。
+func contextCompactionUnitStarts(format ContextCompactionFormat, messages []gjson.Result, pinned int) []int {
+	starts := []int{pinned}
+	for i := pinned + 1; i < len(messages); i++ {
+		if messages[i].Get("role").String() != "user" {
+			continue
+		}
+		if format == ContextCompactionFormatAnthropic && anthropicMessageHasToolResult(messages[i]) {
+			continue
+		}
+		starts = append(starts, i)
+	}
+	return starts
+}
+
+func anthropicMessageHasToolResult(message gjson.Result) bool {
+	for _, block := range message.Get("content").Array() {
+		if block.Get("type").String() == "tool_result" {
+			return true
+		}
+	}
+	return false
+}
+
+// chatPinnedPrefix 返回开头连续 system/developer 消息的数量，这些消息永不丢弃。
+func chatPinnedPrefix(messages []gjson.Result) int {
+	for i, message := range messages {
+		switch message.Get("role").String() {
+		case "system", "developer":
+		default:
+			return i
+		}
+	}
+	return len(messages)
+}
+
+func contextCompactionOutputTokens(format ContextCompactionFormat, body []byte) int {
+	paths := []string{"max_tokens"}
+	if format == ContextCompactionFormatChat {
+		paths = []string{"max_completion_tokens", "max_tokens"}
+	}
+	for _, path := range paths {
+		if v := gjson.GetBytes(body, path).Int(); v > 0 {
+			return int(v)
+		}
+	}
+	return contextCompactionDefaultOutputTokens
+}
+
+func chatSystemMessageRaw(text string) string {
+	raw, _ := sjson.Set(`{"role":"system"}`, "content", text)
+	return raw
+}
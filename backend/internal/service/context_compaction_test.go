@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func contextCompactionFiller(n int) string {
+	return strings.Repeat("word ", n)
+}
+
+func TestNormalizeContextCompactionConfig(t *testing.T) {
+	mode, model, err := NormalizeContextCompactionConfig(" Truncate ", "ignored")
+	require.NoError(t, err)
+	require.Equal(t, ContextCompactionModeTruncate, mode)
+	require.Empty(t, model)
+
+	mode, model, err = NormalizeContextCompactionConfig("off", "claude-haiku")
+	require.NoError(t, err)
+	require.Empty(t, mode)
+	require.Empty(t, model)
+
+	_, _, err = NormalizeContextCompactionConfig("summarize", " ")
+	require.Error(t, err)
+	_, _, err = NormalizeContextCompactionConfig("drop", "")
+	require.Error(t, err)
+}
+
+func TestContextCompactionPlanSkipsRequestsWithinWindow(t *testing.T) {
+	body := []byte(`{"model":"claude","max_tokens":100,"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"hello"},{"role":"user","content":"bye"}]}`)
+	require.Nil(t, planContextCompaction(ContextCompactionFormatAnthropic, ContextCompactionModeTruncate, "", 10000, body))
+}
+
+func TestContextCompactionTruncateKeepsToolPairsTogether(t *testing.T) {
+	filler := contextCompactionFiller(400)
+	body := []byte(`{"model":"claude","max_tokens":100,"system":"be brief","messages":[` +
+		`{"role":"user","content":"` + filler + `"},` +
+		`{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"read","input":{}}]},` +
+		`{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"` + filler + `"}]},` +
+		`{"role":"assistant","content":"done"},` +
+		`{"role":"user","content":"` + filler + `"},` +
+		`{"role":"assistant","content":"ok"},` +
+		`{"role":"user","content":"latest question"}]}`)
+
+	plan := planContextCompaction(ContextCompactionFormatAnthropic, ContextCompactionModeTruncate, "", 1200, body)
+	require.NotNil(t, plan)
+	// 第一轮（含 tool_use / tool_result）整体丢弃，不会留下孤立的 tool_result。
+	require.Equal(t, 4, plan.DroppedMessages)
+	messages := gjson.GetBytes(plan.Body, "messages").Array()
+	require.Len(t, messages, 3)
+	require.Equal(t, "user", messages[0].Get("role").String())
+	require.NotContains(t, string(plan.Body), "toolu_1")
+	require.Equal(t, "latest question", messages[2].Get("content").String())
+	require.Equal(t, "be brief", gjson.GetBytes(plan.Body, "system").String())
+	require.Less(t, plan.EstimatedAfter, plan.EstimatedBefore)
+	require.Equal(t, "truncated; dropped_messages=4; estimated_tokens="+
+		strconv.Itoa(plan.EstimatedBefore)+"->"+strconv.Itoa(plan.EstimatedAfter)+"; window=1200", plan.HeaderValue(false))
+}
+
+func TestContextCompactionAlwaysKeepsLastTurn(t *testing.T) {
+	filler := contextCompactionFiller(2000)
+	body := []byte(`{"model":"claude","max_tokens":100,"messages":[{"role":"user","content":"` + filler + `"}]}`)
+	require.Nil(t, planContextCompaction(ContextCompactionFormatAnthropic, ContextCompactionModeTruncate, "", 500, body))
+}
+
+func TestContextCompactionSummarizeChatInsertsSummaryAfterSystem(t *testing.T) {
+	filler := contextCompactionFiller(400)
+	body := []byte(`{"model":"gpt","max_tokens":100,"stream":true,"messages":[` +
+		`{"role":"system","content":"you are helpful"},` +
+		`{"role":"user","content":"` + filler + `"},` +
+		`{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"f","arguments":"{}"}}]},` +
+		`{"role":"tool","tool_call_id":"call_1","content":"` + filler + `"},` +
+		`{"role":"user","content":"latest"}]}`)
+
+	plan := planContextCompaction(ContextCompactionFormatChat, ContextCompactionModeSummarize, "gpt-mini", 2000, body)
+	require.NotNil(t, plan)
+	require.Equal(t, 3, plan.DroppedMessages)
+
+	summaryRequest, err := plan.SummaryRequest()
+	require.NoError(t, err)
+	require.Equal(t, "gpt-mini", gjson.GetBytes(summaryRequest, "model").String())
+	require.False(t, gjson.GetBytes(summaryRequest, "stream").Bool())
+	require.Contains(t, gjson.GetBytes(summaryRequest, "messages.1.content").String(), "call_1")
+
+	summary := ExtractContextCompactionSummary(ContextCompactionFormatChat, []byte(`{"choices":[{"message":{"content":" user asked about files "}}]}`))
+	require.Equal(t, "user asked about files", summary)
+	require.NoError(t, plan.ApplySummary(summary))
+
+	messages := gjson.GetBytes(plan.Body, "messages").Array()
+	require.Len(t, messages, 3)
+	require.Equal(t, "you are helpful", messages[0].Get("content").String())
+	require.Equal(t, "system", messages[1].Get("role").String())
+	require.Contains(t, messages[1].Get("content").String(), "user asked about files")
+	require.Equal(t, "latest", messages[2].Get("content").String())
+	require.True(t, gjson.GetBytes(plan.Body, "stream").Bool())
+	require.Contains(t, plan.HeaderValue(true), "summary_model=gpt-mini")
+}
+
+func TestContextCompactionSummarizeAnthropicAppendsToSystemBlocks(t *testing.T) {
+	filler := contextCompactionFiller(400)
+	body := []byte(`{"model":"claude","max_tokens":100,"system":[{"type":"text","text":"rules"}],"messages":[` +
+		`{"role":"user","content":"` + filler + `"},` +
+		`{"role":"assistant","content":"` + filler + `"},` +
+		`{"role":"user","content":"latest"}]}`)
+
+	plan := planContextCompaction(ContextCompactionFormatAnthropic, ContextCompactionModeSummarize, "claude-haiku", 2000, body)
+	require.NotNil(t, plan)
+	summary := ExtractContextCompactionSummary(ContextCompactionFormatAnthropic, []byte(`{"content":[{"type":"text","text":"earlier: greeting"}]}`))
+	require.NoError(t, plan.ApplySummary(summary))
+
+	system := gjson.GetBytes(plan.Body, "system").Array()
+	require.Len(t, system, 2)
+	require.Equal(t, "rules", system[0].Get("text").String())
+	require.Contains(t, system[1].Get("text").String(), "earlier: greeting")
+	require.Len(t, gjson.GetBytes(plan.Body, "messages").Array(), 1)
+}
+
+func TestContextCompactionServiceUsesPricingWindow(t *testing.T) {
+	pricing := &PricingService{pricingData: map[string]*LiteLLMModelPricing{"claude-small": {MaxInputTokens: 1200}}}
+	svc := NewContextCompactionService(pricing, nil)
+	filler := contextCompactionFiller(400)
+	body := []byte(`{"model":"claude-small","max_tokens":100,"messages":[` +
+		`{"role":"user","content":"` + filler + `"},{"role":"assistant","content":"` + filler + `"},{"role":"user","content":"` + filler + `"},{"role":"user","content":"latest"}]}`)
+
+	require.Nil(t, svc.Plan(context.Background(), &Group{ID: 1}, ContextCompactionFormatAnthropic, body))
+	plan := svc.Plan(context.Background(), &Group{ID: 1, ContextCompactionMode: ContextCompactionModeTruncate}, ContextCompactionFormatAnthropic, body)
+	require.NotNil(t, plan)
+	require.Equal(t, 1200, plan.Window)
+
+	unknown := []byte(strings.Replace(string(body), "claude-small", "unknown-model", 1))
+	require.Nil(t, svc.Plan(context.Background(), &Group{ID: 1, ContextCompactionMode: ContextCompactionModeTruncate}, ContextCompactionFormatAnthropic, unknown))
+}
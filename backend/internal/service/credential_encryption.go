@@ -0,0 +1,535 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+)
+
+// 信封加密后的字符串格式：enc:<版本>:<主密钥 ID>:<被主密钥包裹的数据密钥>:<nonce+密文>
+//
+// v2 用于账号凭证，以 "账号 ID|字段名" 作为 AES-GCM 附加数据，密文被挪到其它账号或字段后无法解密；
+// v1 不带附加数据，用于代理密码与设置中的密钥。早期写入的账号凭证也是 v1，
+// 只能经 RotateCredentials 升级为 v2（读取时或由重加密任务触发），账号凭证的读写路径不接受 v1。
+const (
+	credentialEnvelopeV1Prefix = "enc:v1:"
+	credentialEnvelopeV2Prefix = "enc:v2:"
+)
+
+// CredentialAPIKeyFingerprintKey 是加密后随 api_key 一起写入 credentials 的 SHA-256 指纹，
+// 供 SQL 在不解密的情况下比较多个账号是否共享同一个上游 API Key（Ollama Cloud 用量分组）。
+// 读取时会被剥离，不会出现在 Account.Credentials 中。
+const CredentialAPIKeyFingerprintKey = "api_key_fingerprint"
+
+// credentialDataKeyCacheSize 限制已解包数据密钥的缓存条目数，避免每次读取都调用 KMS。
+const credentialDataKeyCacheSize = 4096
+
+var ErrCredentialEnvelopeInvalid = errors.New("invalid credential envelope")
+
+// ErrCredentialCiphertextRejected 写入的凭证字段是密文，且与该账号该字段当前存储的密文不一致。
+var ErrCredentialCiphertextRejected = infraerrors.BadRequest("CREDENTIAL_CIPHERTEXT_REJECTED", "credential values must be submitted in plaintext")
+
+// MasterKeyProvider 用主密钥包裹/解包每条记录的数据密钥（KMS 抽象）。
+// 本地实现从配置或密钥文件读取主密钥；接入外部 KMS 只需实现该接口。
+type MasterKeyProvider interface {
+	// ActiveKeyID 返回新数据密钥使用的主密钥 ID（不得包含冒号）。
+	ActiveKeyID() string
+	// WrapDataKey 用当前主密钥包裹数据密钥，返回所用主密钥 ID。
+	WrapDataKey(ctx context.Context, dataKey []byte) (keyID string, wrapped []byte, err error)
+	// UnwrapDataKey 用指定主密钥解包数据密钥；轮换期间旧主密钥仍须可用。
+	UnwrapDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// CredentialCipher 对账号凭证、代理密码、设置中的第三方密钥做信封加密：
+// 每条记录生成独立的数据密钥（AES-256-GCM）加密字段值，数据密钥再由主密钥包裹后
+// 与密文存放在一起。轮换主密钥只需重新包裹数据密钥，字段密文保持不变。
+//
+// 未配置主密钥时（provider 为 nil）保持明文读写，兼容未启用加密的部署；
+// 作为 SecretEncryptor 使用时回落到 legacy 加密器，已有的 TOTP 密钥加密数据可继续解密。
+type CredentialCipher struct {
+	provider MasterKeyProvider
+	legacy   SecretEncryptor
+
+	mu       sync.Mutex
+	dataKeys map[string][]byte
+}
+
+func NewCredentialCipher(provider MasterKeyProvider, legacy SecretEncryptor) *CredentialCipher {
+	return &CredentialCipher{
+		provider: provider,
+		legacy:   legacy,
+		dataKeys: make(map[string][]byte),
+	}
+}
+
+// Enabled 表示是否配置了主密钥，即写入时是否加密。
+func (c *CredentialCipher) Enabled() bool {
+	return c != nil && c.provider != nil
+}
+
+// ActiveKeyID 返回当前主密钥 ID；未启用时为空。
+func (c *CredentialCipher) ActiveKeyID() string {
+	if !c.Enabled() {
+		return ""
+	}
+	return c.provider.ActiveKeyID()
+}
+
+// IsCredentialEnvelope 判断字符串是否为信封密文。
+func IsCredentialEnvelope(value string) bool {
+	return strings.HasPrefix(value, credentialEnvelopeV1Prefix) || strings.HasPrefix(value, credentialEnvelopeV2Prefix)
+}
+
+// CredentialFingerprint 返回 API Key 的 SHA-256 指纹（十六进制）。
+func CredentialFingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Encrypt 实现 SecretEncryptor：启用时输出信封密文，否则使用 legacy 加密器。
+func (c *CredentialCipher) Encrypt(plaintext string) (string, error) {
+	if c.Enabled() {
+		return c.SealString(context.Background(), plaintext)
+	}
+	if c == nil || c.legacy == nil {
+		return "", fmt.Errorf("no encryptor configured")
+	}
+	return c.legacy.Encrypt(plaintext)
+}
+
+// Decrypt 实现 SecretEncryptor：信封密文走主密钥，其它密文交给 legacy 加密器。
+func (c *CredentialCipher) Decrypt(ciphertext string) (string, error) {
+	if IsCredentialEnvelope(ciphertext) {
+		return c.OpenString(context.Background(), ciphertext)
+	}
+	if c == nil || c.legacy == nil {
+		return "", fmt.Errorf("no encryptor configured")
+	}
+	return c.legacy.Decrypt(ciphertext)
+}
+
+// SealString 加密单个明文字符串（代理密码、搜索 API Key 等）；未启用或空串原样返回。
+func (c *CredentialCipher) SealString(ctx context.Context, plaintext string) (string, error) {
+	if !c.Enabled() || plaintext == "" || IsCredentialEnvelope(plaintext) {
+		return plaintext, nil
+	}
+	dataKey, header, err := c.newDataKey(ctx, credentialEnvelopeV1Prefix)
+	if err != nil {
+		return "", err
+	}
+	return sealCredentialValue(dataKey, header, plaintext, nil)
+}
+
+// OpenString 解密 SealString 的结果；非信封字符串视为历史明文原样返回。
+// 绑定账号的 v2 密文不能通过该方法解密。
+func (c *CredentialCipher) OpenString(ctx context.Context, value string) (string, error) {
+	if !IsCredentialEnvelope(value) {
+		return value, nil
+	}
+	opened, err := c.openValue(ctx, value, nil)
+	if err != nil {
+		return "", err
+	}
+	text, ok := opened.(string)
+	if !ok {
+		return "", ErrCredentialEnvelopeInvalid
+	}
+	return text, nil
+}
+
+// SealCredentials 加密凭证中的敏感子键（SensitiveCredentialKeys）。
+// stored 为数据库中现有的（已加密）凭证：明文未变化的字段沿用原密文，
+// 使 SQL 中 credentials = $x::jsonb 形式的比较与变更检测在加密后依旧成立。
+// 新密文以 accountID 与字段名作为附加数据，因此必须在账号 ID 已知后调用。
+// 传入的密文只有与 stored 中同一字段的密文完全一致时才保留（解密失败后原样写回的字段），
+// 否则返回 ErrCredentialCiphertextRejected，防止把其它账号或未绑定的密文写入该账号。
+func (c *CredentialCipher) SealCredentials(ctx context.Context, accountID int64, plain, stored map[string]any) (map[string]any, error) {
+	if !c.Enabled() || plain == nil {
+		return plain, nil
+	}
+	if accountID <= 0 {
+		return nil, fmt.Errorf("seal credentials: invalid account id %d", accountID)
+	}
+	out := make(map[string]any, len(plain)+1)
+	var dataKey []byte
+	var header string
+	for key, value := range plain {
+		if key == CredentialAPIKeyFingerprintKey {
+			continue
+		}
+		if !IsSensitiveCredentialKey(key) || isEmptyCredentialValue(value) {
+			out[key] = value
+			continue
+		}
+		if text, ok := value.(string); ok && IsCredentialEnvelope(text) {
+			if previous, ok := stored[key].(string); !ok || previous != text {
+				return nil, ErrCredentialCiphertextRejected
+			}
+			out[key] = text
+			continue
+		}
+		if previous, ok := stored[key].(string); ok && IsCredentialEnvelope(previous) {
+			if opened, err := c.openValue(ctx, previous, credentialAAD(accountID, key)); err == nil && sameCredentialValue(opened, value) {
+				out[key] = previous
+				continue
+			}
+		}
+		if dataKey == nil {
+			var err error
+			if dataKey, header, err = c.newDataKey(ctx, credentialEnvelopeV2Prefix); err != nil {
+				return nil, err
+			}
+		}
+		sealed, err := sealCredentialValue(dataKey, header, value, credentialAAD(accountID, key))
+		if err != nil {
+			return nil, err
+		}
+		out[key] = sealed
+	}
+	if apiKey, ok := plain["api_key"].(string); ok && apiKey != "" && !IsCredentialEnvelope(apiKey) {
+		out[CredentialAPIKeyFingerprintKey] = CredentialFingerprint(apiKey)
+	} else if storedKey, ok := stored["api_key"].(string); ok && storedKey != "" && out["api_key"] == storedKey {
+		if fingerprint, ok := stored[CredentialAPIKeyFingerprintKey]; ok {
+			out[CredentialAPIKeyFingerprintKey] = fingerprint
+		}
+	}
+	return out, nil
+}
+
+// OpenCredentials 解密凭证中的信封密文并剥离指纹。单个字段解密失败时保留密文并返回错误，
+// 由调用方决定记录日志还是中止；保留密文可避免后续写回时把字段清空。
+func (c *CredentialCipher) OpenCredentials(ctx context.Context, accountID int64, stored map[string]any) (map[string]any, error) {
+	if !credentialsNeedOpening(stored) {
+		return stored, nil
+	}
+	var firstErr error
+	out := make(map[string]any, len(stored))
+	for key, value := range stored {
+		if key == CredentialAPIKeyFingerprintKey {
+			continue
+		}
+		text, ok := value.(string)
+		if !ok || !IsCredentialEnvelope(text) {
+			out[key] = value
+			continue
+		}
+		opened, err := c.openValue(ctx, text, credentialAAD(accountID, key))
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("decrypt credential %q: %w", key, err)
+			}
+			out[key] = value
+			continue
+		}
+		out[key] = opened
+	}
+	return out, firstErr
+}
+
+// RotateCredentials 供重加密任务使用：加密仍为明文的敏感子键，把未绑定账号的 v1 密文
+// 重新加密为 v2，并把非当前主密钥包裹的数据密钥改用当前主密钥重新包裹（字段密文不变）。
+// changed 为 false 时无需写回。
+func (c *CredentialCipher) RotateCredentials(ctx context.Context, accountID int64, stored map[string]any) (map[string]any, bool, error) {
+	if !c.Enabled() || stored == nil {
+		return stored, false, nil
+	}
+	if accountID <= 0 {
+		return nil, false, fmt.Errorf("rotate credentials: invalid account id %d", accountID)
+	}
+	out := make(map[string]any, len(stored)+1)
+	changed := false
+	var dataKey []byte
+	var header string
+	seal := func(key string, value any) (string, error) {
+		if dataKey == nil {
+			var err error
+			if dataKey, header, err = c.newDataKey(ctx, credentialEnvelopeV2Prefix); err != nil {
+				return "", err
+			}
+		}
+		return sealCredentialValue(dataKey, header, value, credentialAAD(accountID, key))
+	}
+	for key, value := range stored {
+		text, isString := value.(string)
+		switch {
+		case isString && strings.HasPrefix(text, credentialEnvelopeV1Prefix):
+			opened, err := c.openValue(ctx, text, nil)
+			if err != nil {
+				return nil, false, err
+			}
+			sealed, err := seal(key, opened)
+			if err != nil {
+				return nil, false, err
+			}
+			out[key] = sealed
+			changed = true
+		case isString && IsCredentialEnvelope(text):
+			rotated, rewrapped, err := c.RewrapString(ctx, text)
+			if err != nil {
+				return nil, false, err
+			}
+			out[key] = rotated
+			changed = changed || rewrapped
+		case IsSensitiveCredentialKey(key) && !isEmptyCredentialValue(value):
+			sealed, err := seal(key, value)
+			if err != nil {
+				return nil, false, err
+			}
+			out[key] = sealed
+			changed = true
+		default:
+			out[key] = value
+		}
+	}
+	if apiKey, ok := out["api_key"].(string); ok && IsCredentialEnvelope(apiKey) {
+		if _, ok := out[CredentialAPIKeyFingerprintKey]; !ok {
+			opened, err := c.openValue(ctx, apiKey, credentialAAD(accountID, "api_key"))
+			if err != nil {
+				return nil, false, err
+			}
+			plain, ok := opened.(string)
+			if !ok {
+				return nil, false, ErrCredentialEnvelopeInvalid
+			}
+			out[CredentialAPIKeyFingerprintKey] = CredentialFingerprint(plain)
+			changed = true
+		}
+	}
+	return out, changed, nil
+}
+
+// RewrapString 把信封中的数据密钥改用当前主密钥包裹；已是当前主密钥时原样返回。
+func (c *CredentialCipher) RewrapString(ctx context.Context, value string) (string, bool, error) {
+	if !c.Enabled() || !IsCredentialEnvelope(value) {
+		return value, false, nil
+	}
+	prefix, keyID, wrapped, payload, err := parseCredentialEnvelope(value)
+	if err != nil {
+		return "", false, err
+	}
+	if keyID == c.provider.ActiveKeyID() {
+		return value, false, nil
+	}
+	dataKey, err := c.unwrapDataKey(ctx, keyID, wrapped)
+	if err != nil {
+		return "", false, err
+	}
+	newKeyID, newWrapped, err := c.provider.WrapDataKey(ctx, dataKey)
+	if err != nil {
+		return "", false, fmt.Errorf("wrap data key: %w", err)
+	}
+	return credentialEnvelopeHeader(prefix, newKeyID, newWrapped) + payload, true, nil
+}
+
+// NeedsRotation 判断字符串是否为非当前主密钥包裹的信封密文。
+func (c *CredentialCipher) NeedsRotation(value string) bool {
+	if !c.Enabled() || !IsCredentialEnvelope(value) {
+		return false
+	}
+	_, keyID, _, _, err := parseCredentialEnvelope(value)
+	return err == nil && keyID != c.provider.ActiveKeyID()
+}
+
+func (c *CredentialCipher) newDataKey(ctx context.Context, prefix string) ([]byte, string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, "", fmt.Errorf("generate data key: %w", err)
+	}
+	keyID, wrapped, err := c.provider.WrapDataKey(ctx, dataKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("wrap data key: %w", err)
+	}
+	if keyID == "" || strings.Contains(keyID, ":") {
+		return nil, "", fmt.Errorf("invalid master key id %q", keyID)
+	}
+	return dataKey, credentialEnvelopeHeader(prefix, keyID, wrapped), nil
+}
+
+// openValue 解密单个信封密文。v2 密文必须提供加密时的附加数据；v1 密文只在不带附加数据
+// 的场景（SealString 的结果、RotateCredentials 升级旧账号凭证）下接受。
+func (c *CredentialCipher) openValue(ctx context.Context, value string, aad []byte) (any, error) {
+	if !c.Enabled() {
+		return nil, fmt.Errorf("credential encryption is not configured")
+	}
+	prefix, keyID, wrapped, payload, err := parseCredentialEnvelope(value)
+	if err != nil {
+		return nil, err
+	}
+	if (prefix == credentialEnvelopeV1Prefix) != (aad == nil) {
+		return nil, ErrCredentialEnvelopeInvalid
+	}
+	dataKey, err := c.unwrapDataKey(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrCredentialEnvelopeInvalid
+	}
+	plain, err := aesGCMOpen(dataKey, sealed, aad)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(plain))
+	decoder.UseNumber()
+	var out any
+	if err := decoder.Decode(&out); err != nil {
+		return nil, ErrCredentialEnvelopeInvalid
+	}
+	return out, nil
+}
+
+func (c *CredentialCipher) unwrapDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	cacheKey := keyID + ":" + string(wrapped)
+	c.mu.Lock()
+	if dataKey, ok := c.dataKeys[cacheKey]; ok {
+		c.mu.Unlock()
+		return dataKey, nil
+	}
+	c.mu.Unlock()
+	dataKey, err := c.provider.UnwrapDataKey(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key: %w", err)
+	}
+	c.mu.Lock()
+	if len(c.dataKeys) >= credentialDataKeyCacheSize {
+		c.dataKeys = make(map[string][]byte)
+	}
+	c.dataKeys[cacheKey] = dataKey
+	c.mu.Unlock()
+	return dataKey, nil
+}
+
+func credentialEnvelopeHeader(prefix, keyID string, wrapped []byte) string {
+	return prefix + keyID + ":" + base64.RawURLEncoding.EncodeToString(wrapped) + ":"
+}
+
+func parseCredentialEnvelope(value string) (prefix, keyID string, wrapped []byte, payload string, err error) {
+	switch {
+	case strings.HasPrefix(value, credentialEnvelopeV2Prefix):
+		prefix = credentialEnvelopeV2Prefix
+	case strings.HasPrefix(value, credentialEnvelopeV1Prefix):
+		prefix = credentialEnvelopeV1Prefix
+	default:
+		return "", "", nil, "", ErrCredentialEnvelopeInvalid
+	}
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", nil, "", ErrCredentialEnvelopeInvalid
+	}
+	wrapped, err = base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", "", nil, "", ErrCredentialEnvelopeInvalid
+	}
+	return prefix, parts[0], wrapped, parts[2], nil
+}
+
+// credentialAAD 返回账号凭证字段的 AES-GCM 附加数据："账号 ID|字段名"。
+func credentialAAD(accountID int64, field string) []byte {
+	return []byte(strconv.FormatInt(accountID, 10) + "|" + field)
+}
+
+// sealCredentialValue 以 JSON 编码字段值后加密，对象类型（如 service_account）同样适用。
+func sealCredentialValue(dataKey []byte, header string, value any, aad []byte) (string, error) {
+	plain, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("encode credential: %w", err)
+	}
+	sealed, err := aesGCMSeal(dataKey, plain, aad)
+	if err != nil {
+		return "", err
+	}
+	return header + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func aesGCMSeal(key, plaintext, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %w", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func aesGCMOpen(key, sealed, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrCredentialEnvelopeInvalid
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], aad)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+	return plain, nil
+}
+
+// HasUnboundCredentials 判断凭证中是否还有未绑定账号的 v1 密文，需经 RotateCredentials 升级后才能读取。
+func HasUnboundCredentials(stored map[string]any) bool {
+	for _, value := range stored {
+		if text, ok := value.(string); ok && strings.HasPrefix(text, credentialEnvelopeV1Prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func credentialsNeedOpening(stored map[string]any) bool {
+	if _, ok := stored[CredentialAPIKeyFingerprintKey]; ok {
+		return true
+	}
+	for _, value := range stored {
+		if text, ok := value.(string); ok && IsCredentialEnvelope(text) {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmptyCredentialValue(value any) bool {
+	if value == nil {
+		return true
+	}
+	text, ok := value.(string)
+	return ok && text == ""
+}
+
+// sameCredentialValue 按 JSON 语义比较两个字段值（兼容 json.Number 与 float64）。
+func sameCredentialValue(a, b any) bool {
+	left, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	right, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(left, right)
+}
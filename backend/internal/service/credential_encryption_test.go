@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testMasterKeyProvider wraps data keys with in-memory AES-GCM master keys.
+type testMasterKeyProvider struct {
+	active string
+	keys   map[string][]byte
+}
+
+func newTestMasterKeyProvider(active string, ids ...string) *testMasterKeyProvider {
+	p := &testMasterKeyProvider{active: active, keys: make(map[string][]byte)}
+	for _, id := range append(ids, active) {
+		if _, ok := p.keys[id]; ok {
+			continue
+		}
+		key := make([]byte, 32)
+		_, _ = rand.Read(key)
+		p.keys[id] = key
+	}
+	return p
+}
+
+func (p *testMasterKeyProvider) ActiveKeyID() string { return p.active }
+
+func (p *testMasterKeyProvider) WrapDataKey(_ context.Context, dataKey []byte) (string, []byte, error) {
+	gcm := p.gcm(p.active)
+	nonce := make([]byte, gcm.NonceSize())
+	_, _ = rand.Read(nonce)
+	return p.active, gcm.Seal(nonce, nonce, dataKey, nil), nil
+}
+
+func (p *testMasterKeyProvider) UnwrapDataKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	if _, ok := p.keys[keyID]; !ok {
+		return nil, errors.New("unknown master key")
+	}
+	gcm := p.gcm(keyID)
+	return gcm.Open(nil, wrapped[:gcm.NonceSize()], wrapped[gcm.NonceSize():], nil)
+}
+
+func (p *testMasterKeyProvider) gcm(keyID string) cipher.AEAD {
+	block, _ := aes.NewCipher(p.keys[keyID])
+	gcm, _ := cipher.NewGCM(block)
+	return gcm
+}
+
+type fakeLegacyEncryptor struct{}
+
+func (fakeLegacyEncryptor) Encrypt(plaintext string) (string, error) {
+	return "legacy:" + plaintext, nil
+}
+func (fakeLegacyEncryptor) Decrypt(ciphertext string) (string, error) {
+	return strings.TrimPrefix(ciphertext, "legacy:"), nil
+}
+
+func TestCredentialCipherSealAndOpenCredentials(t *testing.T) {
+	ctx := context.Background()
+	c := NewCredentialCipher(newTestMasterKeyProvider("k1"), nil)
+	plain := map[string]any{
+		"api_key":         "sk-secret",
+		"base_url":        "https://api.example.com",
+		"service_account": map[string]any{"private_key": "pk"},
+		"refresh_token":   "",
+	}
+
+	sealed, err := c.SealCredentials(ctx, 7, plain, nil)
+	require.NoError(t, err)
+	require.True(t, IsCredentialEnvelope(sealed["api_key"].(string)))
+	require.True(t, strings.HasPrefix(sealed["api_key"].(string), "enc:v2:k1:"))
+	require.True(t, IsCredentialEnvelope(sealed["service_account"].(string)))
+	require.Equal(t, "https://api.example.com", sealed["base_url"])
+	require.Equal(t, "", sealed["refresh_token"])
+	require.Equal(t, CredentialFingerprint("sk-secret"), sealed[CredentialAPIKeyFingerprintKey])
+	require.NotContains(t, sealed["api_key"], "sk-secret")
+
+	opened, err := c.OpenCredentials(ctx, 7, sealed)
+	require.NoError(t, err)
+	require.Equal(t, "sk-secret", opened["api_key"])
+	require.Equal(t, map[string]any{"private_key": "pk"}, opened["service_account"])
+	require.NotContains(t, opened, CredentialAPIKeyFingerprintKey)
+}
+
+func TestCredentialCipherSealReusesUnchangedCiphertext(t *testing.T) {
+	ctx := context.Background()
+	c := NewCredentialCipher(newTestMasterKeyProvider("k1"), nil)
+	stored, err := c.SealCredentials(ctx, 7, map[string]any{"api_key": "sk-1", "access_token": "at-1"}, nil)
+	require.NoError(t, err)
+
+	resealed, err := c.SealCredentials(ctx, 7, map[string]any{"api_key": "sk-1", "access_token": "at-2"}, stored)
+	require.NoError(t, err)
+	require.Equal(t, stored["api_key"], resealed["api_key"])
+	require.NotEqual(t, stored["access_token"], resealed["access_token"])
+
+	opened, err := c.OpenCredentials(ctx, 7, resealed)
+	require.NoError(t, err)
+	require.Equal(t, "at-2", opened["access_token"])
+}
+
+func TestCredentialCipherDisabledIsPassthrough(t *testing.T) {
+	ctx := context.Background()
+	var nilCipher *CredentialCipher
+	plain := map[string]any{"api_key": "sk-secret"}
+
+	sealed, err := nilCipher.SealCredentials(ctx, 7, plain, nil)
+	require.NoError(t, err)
+	require.Equal(t, plain, sealed)
+	password, err := nilCipher.SealString(ctx, "pw")
+	require.NoError(t, err)
+	require.Equal(t, "pw", password)
+
+	legacy := NewCredentialCipher(nil, fakeLegacyEncryptor{})
+	encrypted, err := legacy.Encrypt("s3-secret")
+	require.NoError(t, err)
+	require.Equal(t, "legacy:s3-secret", encrypted)
+}
+
+func TestCredentialCipherDecryptsLegacyCiphertextWhenEnabled(t *testing.T) {
+	c := NewCredentialCipher(newTestMasterKeyProvider("k1"), fakeLegacyEncryptor{})
+	encrypted, err := c.Encrypt("s3-secret")
+	require.NoError(t, err)
+	require.True(t, IsCredentialEnvelope(encrypted))
+
+	decrypted, err := c.Decrypt(encrypted)
+	require.NoError(t, err)
+	require.Equal(t, "s3-secret", decrypted)
+	decrypted, err = c.Decrypt("legacy:old-secret")
+	require.NoError(t, err)
+	require.Equal(t, "old-secret", decrypted)
+}
+
+func TestCredentialCipherRotateRewrapsDataKeys(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestMasterKeyProvider("k1", "k2")
+	c := NewCredentialCipher(provider, nil)
+	stored, err := c.SealCredentials(ctx, 7, map[string]any{"refresh_token": "rt"}, nil)
+	require.NoError(t, err)
+	stored["api_key"] = "sk-plain" // 启用加密前写入的历史明文
+
+	provider.active = "k2"
+	rotated, changed, err := c.RotateCredentials(ctx, 7, stored)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.True(t, strings.HasPrefix(rotated["refresh_token"].(string), "enc:v2:k2:"))
+	require.True(t, strings.HasPrefix(rotated["api_key"].(string), "enc:v2:k2:"))
+	require.Equal(t, CredentialFingerprint("sk-plain"), rotated[CredentialAPIKeyFingerprintKey])
+	// 只重新包裹数据密钥，字段密文保持不变。
+	oldParts := strings.Split(stored["refresh_token"].(string), ":")
+	newParts := strings.Split(rotated["refresh_token"].(string), ":")
+	require.Equal(t, oldParts[len(oldParts)-1], newParts[len(newParts)-1])
+
+	_, changed, err = c.RotateCredentials(ctx, 7, rotated)
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	delete(provider.keys, "k1")
+	opened, err := c.OpenCredentials(ctx, 7, rotated)
+	require.NoError(t, err)
+	require.Equal(t, "rt", opened["refresh_token"])
+	require.Equal(t, "sk-plain", opened["api_key"])
+}
+
+func TestCredentialCipherOpenKeepsCiphertextOnFailure(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestMasterKeyProvider("k1")
+	c := NewCredentialCipher(provider, nil)
+	sealed, err := c.SealCredentials(ctx, 7, map[string]any{"api_key": "sk"}, nil)
+	require.NoError(t, err)
+
+	other := NewCredentialCipher(newTestMasterKeyProvider("k9"), nil)
+	opened, err := other.OpenCredentials(ctx, 7, sealed)
+	require.Error(t, err)
+	require.Equal(t, sealed["api_key"], opened["api_key"])
+}
+
+func TestCredentialCipherBindsCiphertextToAccountAndField(t *testing.T) {
+	ctx := context.Background()
+	c := NewCredentialCipher(newTestMasterKeyProvider("k1"), nil)
+	sealed, err := c.SealCredentials(ctx, 7, map[string]any{"api_key": "sk-7", "refresh_token": "rt-7"}, nil)
+	require.NoError(t, err)
+
+	// 密文挪到其它账号后无法解密。
+	_, err = c.OpenCredentials(ctx, 8, map[string]any{"api_key": sealed["api_key"]})
+	require.Error(t, err)
+
+	// 同一账号内交换字段同样无法解密。
+	_, err = c.OpenCredentials(ctx, 7, map[string]any{"refresh_token": sealed["api_key"]})
+	require.Error(t, err)
+
+	// 绑定账号的密文不能当作普通字符串解密。
+	_, err = c.OpenString(ctx, sealed["api_key"].(string))
+	require.Error(t, err)
+
+	// 沿用原密文时同样校验绑定关系，其它账号的密文不会被复用。
+	resealed, err := c.SealCredentials(ctx, 8, map[string]any{"api_key": "sk-7"}, sealed)
+	require.NoError(t, err)
+	require.NotEqual(t, sealed["api_key"], resealed["api_key"])
+}
+
+func TestCredentialCipherUpgradesUnboundCiphertextOnlyViaRotation(t *testing.T) {
+	ctx := context.Background()
+	c := NewCredentialCipher(newTestMasterKeyProvider("k1"), nil)
+	// 早期版本写入的账号凭证不带附加数据（v1）。
+	legacy, err := c.SealString(ctx, "sk-old")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(legacy, "enc:v1:k1:"))
+	stored := map[string]any{"api_key": legacy}
+	require.True(t, HasUnboundCredentials(stored))
+
+	// 账号凭证读路径不接受 v1，必须先升级。
+	opened, err := c.OpenCredentials(ctx, 7, stored)
+	require.Error(t, err)
+	require.Equal(t, legacy, opened["api_key"])
+
+	// 明文未变化时也不沿用 v1 密文，而是重新加密为 v2。
+	resealed, err := c.SealCredentials(ctx, 7, map[string]any{"api_key": "sk-old"}, stored)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(resealed["api_key"].(string), "enc:v2:k1:"))
+
+	rotated, changed, err := c.RotateCredentials(ctx, 7, stored)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.False(t, HasUnboundCredentials(rotated))
+	require.True(t, strings.HasPrefix(rotated["api_key"].(string), "enc:v2:k1:"))
+	require.Equal(t, CredentialFingerprint("sk-old"), rotated[CredentialAPIKeyFingerprintKey])
+
+	opened, err = c.OpenCredentials(ctx, 7, rotated)
+	require.NoError(t, err)
+	require.Equal(t, "sk-old", opened["api_key"])
+	_, err = c.OpenCredentials(ctx, 8, rotated)
+	require.Error(t, err)
+}
+
+func TestCredentialCipherSealRejectsForeignCiphertext(t *testing.T) {
+	ctx := context.Background()
+	c := NewCredentialCipher(newTestMasterKeyProvider("k1"), nil)
+	stored, err := c.SealCredentials(ctx, 7, map[string]any{"api_key": "sk-7"}, nil)
+	require.NoError(t, err)
+	other, err := c.SealCredentials(ctx, 8, map[string]any{"api_key": "sk-8"}, nil)
+	require.NoError(t, err)
+	unbound, err := c.SealString(ctx, "sk-unbound")
+	require.NoError(t, err)
+
+	// 与存储一致的密文（解密失败后原样写回）保留。
+	kept, err := c.SealCredentials(ctx, 7, map[string]any{"api_key": stored["api_key"]}, stored)
+	require.NoError(t, err)
+	require.Equal(t, stored["api_key"], kept["api_key"])
+
+	for _, foreign := range []any{other["api_key"], unbound} {
+		_, err = c.SealCredentials(ctx, 7, map[string]any{"api_key": foreign}, stored)
+		require.ErrorIs(t, err, ErrCredentialCiphertextRejected)
+	}
+	_, err = c.SealCredentials(ctx, 7, map[string]any{"refresh_token": stored["api_key"]}, stored)
+	require.ErrorIs(t, err, ErrCredentialCiphertextRejected, "同一账号的密文也不能挪到其它字段")
+	_, err = c.SealCredentials(ctx, 7, map[string]any{"api_key": stored["api_key"]}, nil)
+	require.ErrorIs(t, err, ErrCredentialCiphertextRejected)
+}
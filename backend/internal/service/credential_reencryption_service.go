@@ -0,0 +1,346 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/google/uuid"
+)
+
+const (
+	// credentialReencryptionLeaderLockKey 保证多实例部署下只有一个实例执行重加密扫描。
+	credentialReencryptionLeaderLockKey = "credential:reencrypt:leader"
+	// credentialReencryptionLeaderLockTTL 需覆盖一轮全量扫描的耗时。
+	credentialReencryptionLeaderLockTTL = 30 * time.Minute
+	credentialReencryptionRunTimeout    = 25 * time.Minute
+
+	defaultCredentialReencryptionBatchSize = 200
+)
+
+// credentialReencryptionSettingKeys 是存有信封密文（或待加密的第三方密钥）的设置项。
+var credentialReencryptionSettingKeys = []string{
+	SettingKeyWebSearchEmulationConfig,
+	settingKeyBackupS3Config,
+	settingKeyImageStorageConfig,
+	settingKeyInvoiceStorageConfig,
+}
+
+// StoredAccountCredentials 是 accounts.credentials 的存储形态（可能含信封密文）。
+type StoredAccountCredentials struct {
+	ID          int64
+	Credentials map[string]any
+}
+
+// StoredProxyPassword 是 proxies.password 的存储形态。
+type StoredProxyPassword struct {
+	ID       int64
+	Password string
+}
+
+// CredentialReencryptionRepository 按 ID 游标读取存储形态的密钥，并以 CAS 方式回写，
+// 避免覆盖扫描期间由业务路径写入的新值。软删除的记录同样会被处理。
+type CredentialReencryptionRepository interface {
+	ListStoredAccountCredentials(ctx context.Context, afterID int64, limit int) ([]StoredAccountCredentials, error)
+	CompareAndSwapAccountCredentials(ctx context.Context, id int64, expected, replacement map[string]any) (bool, error)
+	ListStoredProxyPasswords(ctx context.Context, afterID int64, limit int) ([]StoredProxyPassword, error)
+	CompareAndSwapProxyPassword(ctx context.Context, id int64, expected, replacement string) (bool, error)
+}
+
+// CredentialReencryptionStats 汇总一轮重加密的结果。
+type CredentialReencryptionStats struct {
+	Accounts int
+	Proxies  int
+	Settings int
+	Failed   int
+}
+
+// CredentialReencryptionService 定期把历史明文凭证加密，并把旧主密钥包裹的数据密钥
+// 改用当前主密钥重新包裹。轮换主密钥的流程：把旧密钥移入 previous_master_keys、
+// 配置新的 active_key_id 与 master_key 并重启；任务完成后即可移除旧密钥。
+type CredentialReencryptionService struct {
+	repo        CredentialReencryptionRepository
+	settingRepo SettingRepository
+	cipher      *CredentialCipher
+	interval    time.Duration
+	batchSize   int
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	lockCache  LeaderLockCache
+	db         *sql.DB
+	instanceID string
+}
+
+func NewCredentialReencryptionService(
+	repo CredentialReencryptionRepository,
+	settingRepo SettingRepository,
+	cipher *CredentialCipher,
+	interval time.Duration,
+	batchSize int,
+) *CredentialReencryptionService {
+	if batchSize <= 0 {
+		batchSize = defaultCredentialReencryptionBatchSize
+	}
+	return &CredentialReencryptionService{
+		repo:        repo,
+		settingRepo: settingRepo,
+		cipher:      cipher,
+		interval:    interval,
+		batchSize:   batchSize,
+		stopCh:      make(chan struct{}),
+		instanceID:  uuid.NewString(),
+	}
+}
+
+// SetLeaderLock injects the leader-lock cache and DB used to elect a single
+// instance for the re-encryption scan. When both are nil the scan runs ungated.
+func (s *CredentialReencryptionService) SetLeaderLock(lockCache LeaderLockCache, db *sql.DB) {
+	if s == nil {
+		return
+	}
+	s.lockCache = lockCache
+	s.db = db
+}
+
+// Start 立即执行一轮，之后按 interval 周期执行；未启用凭证加密时不启动。
+func (s *CredentialReencryptionService) Start() {
+	if s == nil || s.repo == nil || !s.cipher.Enabled() || s.interval <= 0 {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		s.runOnce()
+		for {
+			select {
+			case <-ticker.C:
+				s.runOnce()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (s *CredentialReencryptionService) Stop() {
+	if s == nil {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+	s.wg.Wait()
+}
+
+func (s *CredentialReencryptionService) runOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), credentialReencryptionRunTimeout)
+	defer cancel()
+
+	release, ok := tryAcquireSingletonLeaderLock(ctx, s.lockCache, s.db, credentialReencryptionLeaderLockKey, s.instanceID, credentialReencryptionLeaderLockTTL)
+	if !ok {
+		return
+	}
+	defer release()
+
+	stats, err := s.Reencrypt(ctx)
+	if err != nil {
+		logger.LegacyPrintf("service.credential_reencryption", "[CredentialReencryption] run failed: %v", err)
+	}
+	if stats.Accounts > 0 || stats.Proxies > 0 || stats.Settings > 0 || stats.Failed > 0 {
+		logger.LegacyPrintf("service.credential_reencryption", "[CredentialReencryption] active_key=%s accounts=%d proxies=%d settings=%d failed=%d",
+			s.cipher.ActiveKeyID(), stats.Accounts, stats.Proxies, stats.Settings, stats.Failed)
+	}
+}
+
+// Reencrypt 执行一轮全量扫描。单条记录失败（如旧主密钥缺失）只计数，不中断整轮。
+func (s *CredentialReencryptionService) Reencrypt(ctx context.Context) (CredentialReencryptionStats, error) {
+	var stats CredentialReencryptionStats
+	if s == nil || s.repo == nil || !s.cipher.Enabled() {
+		return stats, nil
+	}
+	if err := s.reencryptAccounts(ctx, &stats); err != nil {
+		return stats, err
+	}
+	if err := s.reencryptProxies(ctx, &stats); err != nil {
+		return stats, err
+	}
+	s.reencryptSettings(ctx, &stats)
+	return stats, nil
+}
+
+func (s *CredentialReencryptionService) reencryptAccounts(ctx context.Context, stats *CredentialReencryptionStats) error {
+	var afterID int64
+	for {
+		if s.stopping() {
+			return nil
+		}
+		batch, err := s.repo.ListStoredAccountCredentials(ctx, afterID, s.batchSize)
+		if err != nil {
+			return err
+		}
+		for _, item := range batch {
+			afterID = item.ID
+			rotated, changed, err := s.cipher.RotateCredentials(ctx, item.ID, item.Credentials)
+			if err != nil {
+				stats.Failed++
+				logger.LegacyPrintf("service.credential_reencryption", "[CredentialReencryption] account=%d: %v", item.ID, err)
+				continue
+			}
+			if !changed {
+				continue
+			}
+			swapped, err := s.repo.CompareAndSwapAccountCredentials(ctx, item.ID, item.Credentials, rotated)
+			if err != nil {
+				return err
+			}
+			// 未命中 CAS 说明记录已被业务路径改写，新值由写入路径加密，下一轮再处理。
+			if swapped {
+				stats.Accounts++
+			}
+		}
+		if len(batch) < s.batchSize {
+			return nil
+		}
+	}
+}
+
+func (s *CredentialReencryptionService) reencryptProxies(ctx context.Context, stats *CredentialReencryptionStats) error {
+	var afterID int64
+	for {
+		if s.stopping() {
+			return nil
+		}
+		batch, err := s.repo.ListStoredProxyPasswords(ctx, afterID, s.batchSize)
+		if err != nil {
+			return err
+		}
+		for _, item := range batch {
+			afterID = item.ID
+			var rotated string
+			var changed bool
+			if IsCredentialEnvelope(item.Password) {
+				rotated, changed, err = s.cipher.RewrapString(ctx, item.Password)
+			} else {
+				rotated, err = s.cipher.SealString(ctx, item.Password)
+				changed = rotated != item.Password
+			}
+			if err != nil {
+				stats.Failed++
+				logger.LegacyPrintf("service.credential_reencryption", "[CredentialReencryption] proxy=%d: %v", item.ID, err)
+				continue
+			}
+			if !changed {
+				continue
+			}
+			swapped, err := s.repo.CompareAndSwapProxyPassword(ctx, item.ID, item.Password, rotated)
+			if err != nil {
+				return err
+			}
+			if swapped {
+				stats.Proxies++
+			}
+		}
+		if len(batch) < s.batchSize {
+			return nil
+		}
+	}
+}
+
+func (s *CredentialReencryptionService) reencryptSettings(ctx context.Context, stats *CredentialReencryptionStats) {
+	if s.settingRepo == nil {
+		return
+	}
+	for _, key := range credentialReencryptionSettingKeys {
+		raw, err := s.settingRepo.GetValue(ctx, key)
+		if err != nil || raw == "" {
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader([]byte(raw)))
+		decoder.UseNumber()
+		var doc any
+		if err := decoder.Decode(&doc); err != nil {
+			continue
+		}
+		rotated, changed, err := s.rotateSettingValue(ctx, "", doc)
+		if err != nil {
+			stats.Failed++
+			logger.LegacyPrintf("service.credential_reencryption", "[CredentialReencryption] setting=%s: %v", key, err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		data, err := json.Marshal(rotated)
+		if err != nil {
+			stats.Failed++
+			continue
+		}
+		if err := s.settingRepo.Set(ctx, key, string(data)); err != nil {
+			stats.Failed++
+			logger.LegacyPrintf("service.credential_reencryption", "[CredentialReencryption] save setting=%s: %v", key, err)
+			continue
+		}
+		stats.Settings++
+	}
+}
+
+// rotateSettingValue 遍历设置 JSON：重新包裹信封密文，并加密仍为明文的 api_key 字段
+// （S3 secret 由对应服务用 legacy 加密器写入，这里只处理其中的信封密文）。
+func (s *CredentialReencryptionService) rotateSettingValue(ctx context.Context, field string, value any) (any, bool, error) {
+	switch typed := value.(type) {
+	case map[string]any:
+		changed := false
+		for key, child := range typed {
+			rotated, childChanged, err := s.rotateSettingValue(ctx, key, child)
+			if err != nil {
+				return nil, false, err
+			}
+			if childChanged {
+				typed[key] = rotated
+				changed = true
+			}
+		}
+		return typed, changed, nil
+	case []any:
+		changed := false
+		for i, child := range typed {
+			rotated, childChanged, err := s.rotateSettingValue(ctx, field, child)
+			if err != nil {
+				return nil, false, err
+			}
+			if childChanged {
+				typed[i] = rotated
+				changed = true
+			}
+		}
+		return typed, changed, nil
+	case string:
+		if IsCredentialEnvelope(typed) {
+			return s.cipher.RewrapString(ctx, typed)
+		}
+		if field == "api_key" && typed != "" {
+			sealed, err := s.cipher.SealString(ctx, typed)
+			return sealed, err == nil, err
+		}
+	}
+	return value, false, nil
+}
+
+func (s *CredentialReencryptionService) stopping() bool {
+	select {
+	case <-s.stopCh:
+		return true
+	default:
+		return false
+	}
+}
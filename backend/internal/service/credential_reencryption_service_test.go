@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type credentialReencryptionRepoStub struct {
+	accounts map[int64]map[string]any
+	proxies  map[int64]string
+}
+
+func (r *credentialReencryptionRepoStub) ListStoredAccountCredentials(_ context.Context, afterID int64, limit int) ([]StoredAccountCredentials, error) {
+	var out []StoredAccountCredentials
+	for id := afterID + 1; id <= afterID+int64(len(r.accounts))+1 && len(out) < limit; id++ {
+		if creds, ok := r.accounts[id]; ok {
+			copied := make(map[string]any, len(creds))
+			for k, v := range creds {
+				copied[k] = v
+			}
+			out = append(out, StoredAccountCredentials{ID: id, Credentials: copied})
+		}
+	}
+	return out, nil
+}
+
+func (r *credentialReencryptionRepoStub) CompareAndSwapAccountCredentials(_ context.Context, id int64, expected, replacement map[string]any) (bool, error) {
+	current, _ := json.Marshal(r.accounts[id])
+	want, _ := json.Marshal(expected)
+	if string(current) != string(want) {
+		return false, nil
+	}
+	r.accounts[id] = replacement
+	return true, nil
+}
+
+func (r *credentialReencryptionRepoStub) ListStoredProxyPasswords(_ context.Context, afterID int64, limit int) ([]StoredProxyPassword, error) {
+	var out []StoredProxyPassword
+	for id := afterID + 1; id <= afterID+int64(len(r.proxies))+1 && len(out) < limit; id++ {
+		if password, ok := r.proxies[id]; ok {
+			out = append(out, StoredProxyPassword{ID: id, Password: password})
+		}
+	}
+	return out, nil
+}
+
+func (r *credentialReencryptionRepoStub) CompareAndSwapProxyPassword(_ context.Context, id int64, expected, replacement string) (bool, error) {
+	if r.proxies[id] != expected {
+		return false, nil
+	}
+	r.proxies[id] = replacement
+	return true, nil
+}
+
+type credentialReencryptionSettingRepoStub struct {
+	values map[string]string
+}
+
+func (s *credentialReencryptionSettingRepoStub) Get(context.Context, string) (*Setting, error) {
+	panic("unexpected Get call")
+}
+
+func (s *credentialReencryptionSettingRepoStub) GetValue(_ context.Context, key string) (string, error) {
+	return s.values[key], nil
+}
+
+func (s *credentialReencryptionSettingRepoStub) Set(_ context.Context, key, value string) error {
+	s.values[key] = value
+	return nil
+}
+
+func (s *credentialReencryptionSettingRepoStub) GetMultiple(context.Context, []string) (map[string]string, error) {
+	panic("unexpected GetMultiple call")
+}
+
+func (s *credentialReencryptionSettingRepoStub) SetMultiple(context.Context, map[string]string) error {
+	panic("unexpected SetMultiple call")
+}
+
+func (s *credentialReencryptionSettingRepoStub) GetAll(context.Context) (map[string]string, error) {
+	panic("unexpected GetAll call")
+}
+
+func (s *credentialReencryptionSettingRepoStub) Delete(context.Context, string) error {
+	panic("unexpected Delete call")
+}
+
+func TestCredentialReencryptionService_RotatesStoredSecrets(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestMasterKeyProvider("k1", "k2")
+	c := NewCredentialCipher(provider, nil)
+	sealed, err := c.SealCredentials(ctx, 1, map[string]any{"api_key": "sk-1"}, nil)
+	require.NoError(t, err)
+	sealedPassword, err := c.SealString(ctx, "pw-1")
+	require.NoError(t, err)
+
+	repo := &credentialReencryptionRepoStub{
+		accounts: map[int64]map[string]any{
+			1: sealed,
+			2: {"api_key": "sk-plain", "base_url": "https://api.example.com"},
+			3: {"base_url": "https://only-config.example.com"},
+		},
+		proxies: map[int64]string{1: sealedPassword, 2: "pw-plain"},
+	}
+	settings := &credentialReencryptionSettingRepoStub{values: map[string]string{
+		SettingKeyWebSearchEmulationConfig: `{"enabled":true,"providers":[{"type":"brave","api_key":"brave-key"}]}`,
+	}}
+
+	provider.active = "k2"
+	svc := NewCredentialReencryptionService(repo, settings, c, 0, 1)
+	stats, err := svc.Reencrypt(ctx)
+	require.NoError(t, err)
+	require.Equal(t, CredentialReencryptionStats{Accounts: 2, Proxies: 2, Settings: 1}, stats)
+
+	for _, id := range []int64{1, 2} {
+		require.True(t, strings.HasPrefix(repo.accounts[id]["api_key"].(string), "enc:v2:k2:"))
+	}
+	require.Equal(t, map[string]any{"base_url": "https://only-config.example.com"}, repo.accounts[3])
+	for _, id := range []int64{1, 2} {
+		require.True(t, strings.HasPrefix(repo.proxies[id], "enc:v1:k2:"))
+	}
+	require.NotContains(t, settings.values[SettingKeyWebSearchEmulationConfig], "brave-key")
+
+	delete(provider.keys, "k1")
+	opened, err := c.OpenCredentials(ctx, 1, repo.accounts[1])
+	require.NoError(t, err)
+	require.Equal(t, "sk-1", opened["api_key"])
+	password, err := c.OpenString(ctx, repo.proxies[2])
+	require.NoError(t, err)
+	require.Equal(t, "pw-plain", password)
+
+	stats, err = svc.Reencrypt(ctx)
+	require.NoError(t, err)
+	require.Equal(t, CredentialReencryptionStats{}, stats)
+}
+
+func TestCredentialReencryptionService_DisabledCipherIsNoop(t *testing.T) {
+	repo := &credentialReencryptionRepoStub{accounts: map[int64]map[string]any{1: {"api_key": "sk"}}}
+	svc := NewCredentialReencryptionService(repo, nil, nil, 0, 0)
+	stats, err := svc.Reencrypt(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, CredentialReencryptionStats{}, stats)
+	require.Equal(t, "sk", repo.accounts[1]["api_key"])
+}
@@ -212,6 +212,22 @@ func (s *DashboardService) GetGroupStatsWithUsageFilters(ctx context.Context, st
 	return s.GetGroupStatsWithFilters(ctx, startTime, endTime, filters.UserID, filters.APIKeyID, filters.AccountID, filters.GroupID, filters.RequestType, filters.Stream, filters.BillingType)
 }
 
+// GetGroupCacheHitRates returns per-group prompt-cache hit rates.
+func (s *DashboardService) GetGroupCacheHitRates(ctx context.Context, startTime, endTime time.Time, groupID int64) ([]usagestats.GroupCacheHitRate, error) {
+	type groupCacheHitRateRepo interface {
+		GetGroupCacheHitRates(context.Context, time.Time, time.Time, int64) ([]usagestats.GroupCacheHitRate, error)
+	}
+	repo, ok := s.usageRepo.(groupCacheHitRateRepo)
+	if !ok {
+		return []usagestats.GroupCacheHitRate{}, nil
+	}
+	stats, err := repo.GetGroupCacheHitRates(ctx, startTime, endTime, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("get group cache hit rates: %w", err)
+	}
+	return stats, nil
+}
+
 // GetGroupUsageSummary returns today's, yesterday's, and cumulative cost for all groups.
 func (s *DashboardService) GetGroupUsageSummary(ctx context.Context, todayStart time.Time) ([]usagestats.GroupUsageSummary, error) {
 	results, err := s.usageRepo.GetAllGroupUsageSummary(ctx, todayStart)
@@ -134,6 +134,9 @@ const WeChatConnectSyntheticEmailDomain = "@wechat-connect.invalid"
 // DingTalkConnectSyntheticEmailDomain 是 DingTalk Connect 用户的合成邮箱后缀（RFC 保留域名）。
 const DingTalkConnectSyntheticEmailDomain = "@dingtalk-connect.invalid"
 
+// SAMLSyntheticEmailDomain 是 SAML SSO 用户的合成邮箱后缀（RFC 保留域名）。
+const SAMLSyntheticEmailDomain = "@saml-sso.invalid"
+
 // Setting keys
 const (
 	// 注册设置
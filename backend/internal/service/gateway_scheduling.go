@@ -708,6 +708,16 @@ func (s *GatewayService) SelectAccountWithLoadAwareness(ctx context.Context, gro
 			}
 		}
 
+		// 提示词前缀路由：无粘性绑定的新会话按可缓存前缀一致性哈希选账号，
+		// 账号饱和（超出有界负载容量或槽位已满）时回落到常规分层选择。
+		if group != nil && group.PromptPrefixRoutingEnabled {
+			if result, ok, err := s.tryAcquireByPromptPrefixRing(ctx, available, groupID, sessionHash); err != nil {
+				return nil, err
+			} else if ok {
+				return result, nil
+			}
+		}
+
 		// 分层过滤选择：优先级 →（可选）最早重置 → 负载率 → LRU
 		for len(available) > 0 {
 			// 1. 取优先级最小的集合
@@ -616,6 +616,8 @@ type ForwardResult struct {
 	ImageSizeBreakdown map[string]int
 	SearchCount        int
 	AudioUsage         *AudioUsage
+	// CachedContentStorage 为 Gemini cachedContents 的存储计费单位（创建或延长 TTL）。
+	CachedContentStorage *CachedContentStorageUsage
 }
 
 // GatewayFailureStage identifies which request stage failed. The zero value is
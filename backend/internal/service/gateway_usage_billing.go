@@ -66,6 +66,11 @@ type apiKeyAuthCacheInvalidator interface {
 	InvalidateAuthCacheByKey(ctx context.Context, key string)
 }
 
+// scopedTokenSpendRecorder 由 APIKeyService 实现：请求以派生令牌认证时，扣费后累计令牌花费。
+type scopedTokenSpendRecorder interface {
+	RecordScopedTokenSpend(ctx context.Context, claims *ScopedTokenClaims, cost float64)
+}
+
 type usageLogBestEffortWriter interface {
 	CreateBestEffort(ctx context.Context, log *UsageLog) error
 }
@@ -75,7 +80,7 @@ type postUsageBillingParams struct {
 	Cost                  *CostBreakdown
 	User                  *User
 	APIKey                *APIKey
-	Account               *Account
+	Account               *Account // 不经过上游账号的调用（如对外 moderation）为 nil
 	Subscription          *UserSubscription
 	RequestPayloadHash    string
 	IsSubscriptionBill    bool
@@ -125,8 +130,17 @@ func (p *postUsageBillingParams) shouldUpdateRateLimits() bool {
 	return p.Cost.ActualCost > 0 && p.APIKey.HasRateLimits() && p.APIKeyService != nil
 }
 
+func (p *postUsageBillingParams) recordScopedTokenSpend(ctx context.Context) {
+	if p.Cost.ActualCost <= 0 || p.APIKey == nil || p.APIKey.ScopedToken == nil {
+		return
+	}
+	if recorder, ok := p.APIKeyService.(scopedTokenSpendRecorder); ok {
+		recorder.RecordScopedTokenSpend(ctx, p.APIKey.ScopedToken, p.Cost.ActualCost)
+	}
+}
+
 func (p *postUsageBillingParams) shouldUpdateAccountQuota() bool {
-	return p.Cost.TotalCost > 0 && p.Account.IsAPIKeyOrBedrock() && p.Account.HasAnyQuotaLimit()
+	return p.Cost.TotalCost > 0 && p.Account != nil && p.Account.IsAPIKeyOrBedrock() && p.Account.HasAnyQuotaLimit()
 }
 
 // postUsageBilling is the legacy fallback billing path used when the unified
@@ -170,6 +184,8 @@ func postUsageBilling(ctx context.Context, p *postUsageBillingParams, deps *bill
 		}
 	}
 
+	p.recordScopedTokenSpend(billingCtx)
+
 	if p.shouldUpdateAccountQuota() {
 		accountCost := cost.TotalCost * p.AccountRateMultiplier
 		if err := deps.accountRepo.IncrementQuotaUsed(billingCtx, p.Account.ID, accountCost); err != nil {
@@ -275,7 +291,7 @@ func resolveUsageBillingPayloadFingerprint(ctx context.Context, requestPayloadHa
 }
 
 func buildUsageBillingCommand(requestID string, usageLog *UsageLog, p *postUsageBillingParams) *UsageBillingCommand {
-	if p == nil || p.Cost == nil || p.APIKey == nil || p.User == nil || p.Account == nil {
+	if p == nil || p.Cost == nil || p.APIKey == nil || p.User == nil {
 		return nil
 	}
 
@@ -283,10 +299,12 @@ func buildUsageBillingCommand(requestID string, usageLog *UsageLog, p *postUsage
 		RequestID:          requestID,
 		APIKeyID:           p.APIKey.ID,
 		UserID:             p.User.ID,
-		AccountID:          p.Account.ID,
-		AccountType:        p.Account.Type,
 		RequestPayloadHash: strings.TrimSpace(p.RequestPayloadHash),
 	}
+	if p.Account != nil {
+		cmd.AccountID = p.Account.ID
+		cmd.AccountType = p.Account.Type
+	}
 	if usageLog != nil {
 		cmd.Model = usageLog.Model
 		cmd.BillingType = usageLog.BillingType
@@ -351,7 +369,7 @@ func applyUsageBilling(ctx context.Context, requestID string, usageLog *UsageLog
 	}
 
 	if result == nil || !result.Applied {
-		deps.deferredService.ScheduleLastUsedUpdate(p.Account.ID)
+		scheduleBillingAccountLastUsed(p, deps)
 		return false, nil
 	}
 
@@ -365,6 +383,12 @@ func applyUsageBilling(ctx context.Context, requestID string, usageLog *UsageLog
 	return true, nil
 }
 
+func scheduleBillingAccountLastUsed(p *postUsageBillingParams, deps *billingDeps) {
+	if p.Account != nil {
+		deps.deferredService.ScheduleLastUsedUpdate(p.Account.ID)
+	}
+}
+
 func finalizePostUsageBilling(ctx context.Context, p *postUsageBillingParams, deps *billingDeps, result *UsageBillingApplyResult) {
 	if p == nil || p.Cost == nil || deps == nil {
 		return
@@ -380,9 +404,16 @@ func finalizePostUsageBilling(ctx context.Context, p *postUsageBillingParams, de
 
 	if p.Cost.ActualCost > 0 && p.APIKey != nil && p.APIKey.HasRateLimits() {
 		deps.billingCacheService.QueueUpdateAPIKeyRateLimitUsage(p.APIKey.ID, p.Cost.ActualCost)
+		if result != nil {
+			for _, peerID := range result.RateLimitPeerAPIKeyIDs {
+				deps.billingCacheService.QueueUpdateAPIKeyRateLimitUsage(peerID, p.Cost.ActualCost)
+			}
+		}
 	}
 
-	deps.deferredService.ScheduleLastUsedUpdate(p.Account.ID)
+	p.recordScopedTokenSpend(ctx)
+
+	scheduleBillingAccountLastUsed(p, deps)
 
 	// Platform quota 累加：仅在 standard（余额）模式生效；订阅模式豁免；仅对有 limit 的用户写
 	// Redis 同步写 + DB 异步持久化（flag=false 降级）或 flusher 异步刷（flag=true）:
@@ -847,7 +878,7 @@ func (s *GatewayService) recordUsageCore(ctx context.Context, input *recordUsage
 		input.BillingModelSource,
 		result.UpstreamResponseModel,
 		result.UpstreamResponseModelConflict,
-		result.ImageCount > 0 || result.AudioUsage != nil || result.SearchCount > 0,
+		result.ImageCount > 0 || result.AudioUsage != nil || result.SearchCount > 0 || result.CachedContentStorage != nil,
 	); responseModel != "" && !strings.EqualFold(responseModel, strings.TrimSpace(billingModel)) {
 		if identified, responseChannelPriced := s.hasIdentifiedResponseModelPricing(ctx, responseModel, apiKey); identified {
 			responseCost := s.calculateRecordUsageCost(ctx, result, apiKey, responseModel, multiplier, imageMultiplier, opts)
@@ -949,6 +980,11 @@ func (s *GatewayService) calculateRecordUsageCost(
 		return s.calculateImageCost(ctx, result, apiKey, billingModel, imageMultiplier)
 	}
 
+	// Gemini cachedContents 存储：按缓存 token × 小时计费，单价来自价格表。
+	if result.CachedContentStorage != nil {
+		return s.billingService.CalculateCachedContentStorageCost(billingModel, result.CachedContentStorage.Tokens, result.CachedContentStorage.Hours, multiplier)
+	}
+
 	// Voice audio (TTS / STT / realtime) when present on the forward result.
 	if result.AudioUsage != nil {
 		if resolved := s.resolveChannelPricing(ctx, billingModel, apiKey); resolved != nil &&
@@ -0,0 +1,600 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/geminicli"
+	"github.com/Wei-Shaw/sub2api/internal/util/responseheaders"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Gemini context caching（cachedContents）透传。
+//
+// 缓存只存在于创建它的上游账号上，因此创建成功后把"缓存 ID → 账号"的绑定写入
+// GatewayCache；后续 get/patch/delete 以及引用 cachedContent 的 generateContent
+// 都强制落到该账号。客户端看到的资源名统一为 cachedContents/{id}，Vertex 的
+// projects/.../cachedContents/{id} 完整名只在网关内部使用。
+//
+// 上游的 list 会返回账号上所有用户的缓存，这里改为按绑定记录列出调用方自己的缓存。
+
+const (
+	geminiCachedContentsResource = "cachedContents"
+	// geminiCachedContentDefaultTTL 与 Gemini 默认缓存 TTL 一致，用于上游未返回 expireTime 的兜底。
+	geminiCachedContentDefaultTTL = time.Hour
+	// geminiCachedContentBindingGrace 让绑定比上游缓存略晚过期，吸收时钟偏差。
+	geminiCachedContentBindingGrace = 5 * time.Minute
+	geminiCachedContentMaxBodyBytes = 8 << 20
+)
+
+var geminiVertexCachedContentNamePattern = regexp.MustCompile(`^projects/[A-Za-z0-9_.-]+/locations/([a-z0-9-]+)/cachedContents/([A-Za-z0-9_.-]+)$`)
+
+var (
+	ErrGeminiCachedContentNotFound    = infraerrors.NotFound("CACHED_CONTENT_NOT_FOUND", "cachedContent not found")
+	ErrGeminiCachedContentUnavailable = infraerrors.ServiceUnavailable("CACHED_CONTENT_UNAVAILABLE", "context caching is not available")
+	ErrGeminiCachedContentAccount     = infraerrors.ServiceUnavailable("CACHED_CONTENT_ACCOUNT_UNAVAILABLE", "the account holding this cachedContent is unavailable")
+	ErrGeminiCachedContentBadRequest  = infraerrors.BadRequest("CACHED_CONTENT_INVALID_REQUEST", "invalid cachedContent request")
+)
+
+// GeminiCachedContentRecord 是缓存 ID 与上游账号的绑定。
+type GeminiCachedContentRecord struct {
+	ID           string    `json:"id"`
+	UpstreamName string    `json:"upstream_name"`
+	GroupID      int64     `json:"group_id"`
+	UserID       int64     `json:"user_id"`
+	APIKeyID     int64     `json:"api_key_id"`
+	AccountID    int64     `json:"account_id"`
+	Model        string    `json:"model"`
+	TokenCount   int       `json:"token_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpireTime   time.Time `json:"expire_time"`
+	// Resource 为最近一次上游返回的资源体（已改写为客户端视角），用于 list。
+	Resource json.RawMessage `json:"resource,omitempty"`
+}
+
+// GeminiCachedContentStore 由 GatewayCache 的 Redis 实现可选提供，避免扩大旧缓存接口。
+// 缺失记录时 Get 返回 ErrGeminiCachedContentNotFound。
+type GeminiCachedContentStore interface {
+	SaveGeminiCachedContent(ctx context.Context, record *GeminiCachedContentRecord, ttl time.Duration) error
+	GetGeminiCachedContent(ctx context.Context, groupID int64, id string) (*GeminiCachedContentRecord, error)
+	ListGeminiCachedContents(ctx context.Context, groupID, userID int64) ([]*GeminiCachedContentRecord, error)
+	DeleteGeminiCachedContent(ctx context.Context, groupID, userID int64, id string) error
+}
+
+// CachedContentStorageUsage 是 cachedContents 的存储计费单位（缓存 token × 小时）。
+type CachedContentStorageUsage struct {
+	Tokens int
+	Hours  float64
+}
+
+// GeminiCachedContentCaller 标识发起缓存操作的调用方，用于绑定归属校验。
+type GeminiCachedContentCaller struct {
+	GroupID  *int64
+	UserID   int64
+	APIKeyID int64
+}
+
+// GeminiCachedContentResult 是一次缓存操作的上游响应。
+type GeminiCachedContentResult struct {
+	Response *UpstreamHTTPResult
+	Account  *Account
+	// Usage 非空表示本次操作产生了存储费用（创建或延长 TTL）。
+	Usage *ForwardResult
+}
+
+// GeminiCachedContentSessionKey 返回引用缓存的请求使用的粘性会话 key（按缓存所在账号区分）。
+func GeminiCachedContentSessionKey(accountID int64) string {
+	return "gemini_cached_content:" + strconv.FormatInt(accountID, 10)
+}
+
+// geminiAccountSupportsCachedContents 与 embeddings 相同：Code Assist OAuth 没有 cachedContents 接口。
+func geminiAccountSupportsCachedContents(account *Account) bool {
+	return GeminiAccountSupportsEmbeddings(account)
+}
+
+func (s *GeminiMessagesCompatService) cachedContentStore() GeminiCachedContentStore {
+	if s == nil || s.cache == nil {
+		return nil
+	}
+	store, ok := s.cache.(GeminiCachedContentStore)
+	if !ok {
+		return nil
+	}
+	return store
+}
+
+// geminiCachedContentID 从客户端或上游资源名中取出缓存 ID（最后一个路径片段）。
+func geminiCachedContentID(name string) string {
+	name = strings.Trim(strings.TrimSpace(name), "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if !isSafeUpstreamPathSegment(name) {
+		return ""
+	}
+	return name
+}
+
+// normalizeGeminiCachedContentModel 去掉 models/ 或 Vertex publishers 前缀，返回裸模型名。
+func normalizeGeminiCachedContentModel(model string) string {
+	model = strings.TrimSpace(model)
+	if i := strings.LastIndex(model, "/models/"); i >= 0 {
+		return model[i+len("/models/"):]
+	}
+	return strings.TrimPrefix(model, "models/")
+}
+
+// GeminiCachedContentModel 返回创建请求体中的裸模型名。
+func GeminiCachedContentModel(body []byte) string {
+	return normalizeGeminiCachedContentModel(gjson.GetBytes(body, "model").String())
+}
+
+type geminiCachedContentResource struct {
+	upstreamName string
+	id           string
+	model        string
+	tokens       int
+	expireTime   time.Time
+}
+
+func parseGeminiCachedContentResource(raw []byte) geminiCachedContentResource {
+	name := gjson.GetBytes(raw, "name").String()
+	res := geminiCachedContentResource{
+		upstreamName: name,
+		id:           geminiCachedContentID(name),
+		model:        normalizeGeminiCachedContentModel(gjson.GetBytes(raw, "model").String()),
+		tokens:       int(gjson.GetBytes(raw, "usageMetadata.totalTokenCount").Int()),
+	}
+	if expire := gjson.GetBytes(raw, "expireTime").String(); expire != "" {
+		if t, err := time.Parse(time.RFC3339Nano, expire); err == nil {
+			res.expireTime = t
+		}
+	}
+	return res
+}
+
+// rewriteGeminiCachedContentResource 把上游资源名与模型名改写为客户端视角。
+func rewriteGeminiCachedContentResource(raw []byte, id, model string) []byte {
+	out := raw
+	if id != "" {
+		if b, err := sjson.SetBytes(out, "name", geminiCachedContentsResource+"/"+id); err == nil {
+			out = b
+		}
+	}
+	if model != "" && gjson.GetBytes(out, "model").Exists() {
+		if b, err := sjson.SetBytes(out, "model", "models/"+model); err == nil {
+			out = b
+		}
+	}
+	return out
+}
+
+func geminiCachedContentBindingTTL(expireTime, now time.Time) time.Duration {
+	if expireTime.IsZero() {
+		return geminiCachedContentDefaultTTL + geminiCachedContentBindingGrace
+	}
+	ttl := expireTime.Sub(now)
+	if ttl < 0 {
+		ttl = 0
+	}
+	return ttl + geminiCachedContentBindingGrace
+}
+
+// geminiCachedContentStorageHours 计算 [from, to) 的存储小时数；to 未知时按默认 TTL。
+func geminiCachedContentStorageHours(from, to time.Time) float64 {
+	if to.IsZero() {
+		return geminiCachedContentDefaultTTL.Hours()
+	}
+	if !to.After(from) {
+		return 0
+	}
+	return to.Sub(from).Hours()
+}
+
+func vertexAPIHost(location string) string {
+	if location == "global" {
+		return "aiplatform.googleapis.com"
+	}
+	return fmt.Sprintf("%s-aiplatform.googleapis.com", location)
+}
+
+// cachedContentsURL 构造 cachedContents 资源 URL。resource 为空时指向集合（用于创建）。
+func (s *GeminiMessagesCompatService) cachedContentsURL(account *Account, resource, model string) (string, error) {
+	if account.Type == AccountTypeServiceAccount {
+		if resource != "" {
+			m := geminiVertexCachedContentNamePattern.FindStringSubmatch(resource)
+			if m == nil {
+				return "", errors.New("invalid vertex cachedContent name")
+			}
+			return "https://" + vertexAPIHost(m[1]) + "/v1/" + resource, nil
+		}
+		projectID := strings.TrimSpace(account.VertexProjectID())
+		if projectID == "" {
+			return "", errors.New("vertex project_id is required")
+		}
+		location := account.VertexLocation(model)
+		if !vertexLocationPattern.MatchString(location) {
+			return "", fmt.Errorf("invalid vertex location: %s", location)
+		}
+		return fmt.Sprintf("https://%s/v1/projects/%s/locations/%s/cachedContents", vertexAPIHost(location), url.PathEscape(projectID), location), nil
+	}
+	normalizedBaseURL, err := s.validateUpstreamBaseURL(account.GetGeminiBaseURL(geminicli.AIStudioBaseURL))
+	if err != nil {
+		return "", err
+	}
+	base := strings.TrimRight(normalizedBaseURL, "/") + "/v1beta/"
+	if resource == "" {
+		return base + geminiCachedContentsResource, nil
+	}
+	id := geminiCachedContentID(resource)
+	if id == "" {
+		return "", errors.New("invalid cachedContent name")
+	}
+	return base + geminiCachedContentsResource + "/" + id, nil
+}
+
+// vertexCachedContentModelPath 返回 Vertex 创建缓存时要求的完整模型资源名。
+func vertexCachedContentModelPath(account *Account, model string) string {
+	return fmt.Sprintf("projects/%s/locations/%s/publishers/google/models/%s", account.VertexProjectID(), account.VertexLocation(model), model)
+}
+
+func (s *GeminiMessagesCompatService) doCachedContentsRequest(ctx context.Context, account *Account, method, fullURL string, body []byte) (*UpstreamHTTPResult, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch account.Type {
+	case AccountTypeAPIKey:
+		apiKey := strings.TrimSpace(account.GetCredential("api_key"))
+		if apiKey == "" {
+			return nil, errors.New("gemini api_key not configured")
+		}
+		req.Header.Set("x-goog-api-key", apiKey)
+	case AccountTypeOAuth, AccountTypeServiceAccount:
+		if s.tokenProvider == nil {
+			return nil, errors.New("gemini token provider not configured")
+		}
+		accessToken, err := s.tokenProvider.GetAccessToken(ctx, account)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	default:
+		return nil, fmt.Errorf("unsupported account type: %s", account.Type)
+	}
+
+	proxyURL := ""
+	if account.ProxyID != nil && account.Proxy != nil {
+		proxyURL = account.Proxy.URL()
+	}
+	resp, err := s.httpUpstream.Do(req, proxyURL, account.ID, account.Concurrency)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, geminiCachedContentMaxBodyBytes))
+	// 缓存接口的 401/403 往往只表示账号未开通该能力，不据此标记账号异常；只处理限流。
+	if resp.StatusCode == http.StatusTooManyRequests {
+		s.handleGeminiUpstreamError(ctx, account, resp.StatusCode, resp.Header, respBody)
+	}
+	return &UpstreamHTTPResult{
+		StatusCode: resp.StatusCode,
+		Headers:    responseheaders.FilterHeaders(resp.Header, s.responseHeaderFilter),
+		Body:       respBody,
+	}, nil
+}
+
+// selectCachedContentsAccount 选择能承接 cachedContents 且支持该模型的账号。
+func (s *GeminiMessagesCompatService) selectCachedContentsAccount(ctx context.Context, groupID *int64, model string) (*Account, error) {
+	excluded := make(map[int64]struct{})
+	for {
+		account, err := s.SelectAccountForModelWithExclusions(ctx, groupID, "", model, excluded)
+		if err != nil {
+			return nil, err
+		}
+		if geminiAccountSupportsCachedContents(account) {
+			return account, nil
+		}
+		excluded[account.ID] = struct{}{}
+	}
+}
+
+// CreateCachedContent 创建缓存并把缓存 ID 绑定到承接的账号。
+func (s *GeminiMessagesCompatService) CreateCachedContent(ctx context.Context, caller GeminiCachedContentCaller, body []byte) (*GeminiCachedContentResult, error) {
+	store := s.cachedContentStore()
+	if store == nil {
+		return nil, ErrGeminiCachedContentUnavailable
+	}
+	if !gjson.ValidBytes(body) {
+		return nil, infraerrors.BadRequest("CACHED_CONTENT_INVALID_REQUEST", "request body must be valid JSON")
+	}
+	originalModel := GeminiCachedContentModel(body)
+	if originalModel == "" || !isSafeUpstreamPathSegment(originalModel) {
+		return nil, infraerrors.BadRequest("CACHED_CONTENT_INVALID_REQUEST", "model is required")
+	}
+
+	account, err := s.selectCachedContentsAccount(ctx, caller.GroupID, originalModel)
+	if err != nil {
+		return nil, ErrGeminiCachedContentUnavailable.WithCause(err)
+	}
+	mappedModel := originalModel
+	if account.Type == AccountTypeAPIKey || account.Type == AccountTypeServiceAccount {
+		mappedModel = account.GetMappedModel(originalModel)
+	}
+	upstreamModel := "models/" + mappedModel
+	if account.Type == AccountTypeServiceAccount {
+		upstreamModel = vertexCachedContentModelPath(account, mappedModel)
+	}
+	payload, err := sjson.SetBytes(body, "model", upstreamModel)
+	if err != nil {
+		return nil, ErrGeminiCachedContentBadRequest.WithCause(err)
+	}
+	fullURL, err := s.cachedContentsURL(account, "", mappedModel)
+	if err != nil {
+		return nil, ErrGeminiCachedContentAccount.WithCause(err)
+	}
+
+	start := time.Now()
+	res, err := s.doCachedContentsRequest(ctx, account, http.MethodPost, fullURL, payload)
+	if err != nil {
+		return nil, ErrGeminiCachedContentAccount.WithCause(err)
+	}
+	out := &GeminiCachedContentResult{Response: res, Account: account}
+	if res.StatusCode >= 300 {
+		return out, nil
+	}
+
+	resource := parseGeminiCachedContentResource(res.Body)
+	if resource.id == "" {
+		return nil, ErrGeminiCachedContentAccount.WithCause(errors.New("upstream returned no cachedContent name"))
+	}
+	res.Body = rewriteGeminiCachedContentResource(res.Body, resource.id, originalModel)
+
+	now := time.Now()
+	record := &GeminiCachedContentRecord{
+		ID:           resource.id,
+		UpstreamName: resource.upstreamName,
+		GroupID:      derefGroupID(caller.GroupID),
+		UserID:       caller.UserID,
+		APIKeyID:     caller.APIKeyID,
+		AccountID:    account.ID,
+		Model:        originalModel,
+		TokenCount:   resource.tokens,
+		CreatedAt:    now,
+		ExpireTime:   resource.expireTime,
+		Resource:     res.Body,
+	}
+	if err := store.SaveGeminiCachedContent(ctx, record, geminiCachedContentBindingTTL(resource.expireTime, now)); err != nil {
+		// 绑定写不进去时缓存无法被路由到正确账号，删除上游缓存避免产生无法使用的存储费用。
+		if delURL, urlErr := s.cachedContentsURL(account, resource.upstreamName, mappedModel); urlErr == nil {
+			_, _ = s.doCachedContentsRequest(ctx, account, http.MethodDelete, delURL, nil)
+		}
+		return nil, ErrGeminiCachedContentUnavailable.WithCause(err)
+	}
+
+	out.Usage = &ForwardResult{
+		RequestID:     res.Headers.Get("x-goog-request-id"),
+		Model:         originalModel,
+		UpstreamModel: mappedModel,
+		Duration:      time.Since(start),
+		CachedContentStorage: &CachedContentStorageUsage{
+			Tokens: resource.tokens,
+			Hours:  geminiCachedContentStorageHours(now, resource.expireTime),
+		},
+	}
+	return out, nil
+}
+
+// loadCachedContent 读取调用方自己的绑定记录及其账号。
+func (s *GeminiMessagesCompatService) loadCachedContent(ctx context.Context, caller GeminiCachedContentCaller, id string) (GeminiCachedContentStore, *GeminiCachedContentRecord, *Account, error) {
+	store := s.cachedContentStore()
+	if store == nil {
+		return nil, nil, nil, ErrGeminiCachedContentUnavailable
+	}
+	id = geminiCachedContentID(id)
+	if id == "" {
+		return nil, nil, nil, ErrGeminiCachedContentNotFound
+	}
+	record, err := store.GetGeminiCachedContent(ctx, derefGroupID(caller.GroupID), id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if record.UserID != caller.UserID {
+		return nil, nil, nil, ErrGeminiCachedContentNotFound
+	}
+	account, err := s.accountRepo.GetByID(ctx, record.AccountID)
+	if err != nil || account == nil || !account.IsActive() {
+		return nil, nil, nil, ErrGeminiCachedContentAccount
+	}
+	return store, record, account, nil
+}
+
+// GetCachedContent 在绑定账号上读取缓存元数据。
+func (s *GeminiMessagesCompatService) GetCachedContent(ctx context.Context, caller GeminiCachedContentCaller, id string) (*GeminiCachedContentResult, error) {
+	store, record, account, err := s.loadCachedContent(ctx, caller, id)
+	if err != nil {
+		return nil, err
+	}
+	fullURL, err := s.cachedContentsURL(account, record.UpstreamName, record.Model)
+	if err != nil {
+		return nil, ErrGeminiCachedContentAccount.WithCause(err)
+	}
+	res, err := s.doCachedContentsRequest(ctx, account, http.MethodGet, fullURL, nil)
+	if err != nil {
+		return nil, ErrGeminiCachedContentAccount.WithCause(err)
+	}
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		_ = store.DeleteGeminiCachedContent(ctx, record.GroupID, record.UserID, record.ID)
+	case res.StatusCode < 300:
+		res.Body = rewriteGeminiCachedContentResource(res.Body, record.ID, record.Model)
+	}
+	return &GeminiCachedContentResult{Response: res, Account: account}, nil
+}
+
+// UpdateCachedContent 透传 PATCH（通常用于修改 ttl/expireTime）；TTL 延长的部分按存储计费。
+func (s *GeminiMessagesCompatService) UpdateCachedContent(ctx context.Context, caller GeminiCachedContentCaller, id string, rawQuery string, body []byte) (*GeminiCachedContentResult, error) {
+	store, record, account, err := s.loadCachedContent(ctx, caller, id)
+	if err != nil {
+		return nil, err
+	}
+	if !gjson.ValidBytes(body) {
+		return nil, infraerrors.BadRequest("CACHED_CONTENT_INVALID_REQUEST", "request body must be valid JSON")
+	}
+	// 资源名与模型不可修改，避免客户端视角的名字被原样发往上游。
+	payload := body
+	for _, field := range []string{"name", "model"} {
+		if b, err := sjson.DeleteBytes(payload, field); err == nil {
+			payload = b
+		}
+	}
+	fullURL, err := s.cachedContentsURL(account, record.UpstreamName, record.Model)
+	if err != nil {
+		return nil, ErrGeminiCachedContentAccount.WithCause(err)
+	}
+	if query, err := url.ParseQuery(rawQuery); err == nil {
+		if mask := query.Get("updateMask"); mask != "" {
+			fullURL += "?" + url.Values{"updateMask": {mask}}.Encode()
+		}
+	}
+
+	start := time.Now()
+	res, err := s.doCachedContentsRequest(ctx, account, http.MethodPatch, fullURL, payload)
+	if err != nil {
+		return nil, ErrGeminiCachedContentAccount.WithCause(err)
+	}
+	out := &GeminiCachedContentResult{Response: res, Account: account}
+	if res.StatusCode >= 300 {
+		return out, nil
+	}
+
+	resource := parseGeminiCachedContentResource(res.Body)
+	res.Body = rewriteGeminiCachedContentResource(res.Body, record.ID, record.Model)
+	now := time.Now()
+	previousExpire := record.ExpireTime
+	if previousExpire.Before(now) {
+		previousExpire = now
+	}
+	if resource.tokens > 0 {
+		record.TokenCount = resource.tokens
+	}
+	if !resource.expireTime.IsZero() {
+		record.ExpireTime = resource.expireTime
+	}
+	record.Resource = res.Body
+	if err := store.SaveGeminiCachedContent(ctx, record, geminiCachedContentBindingTTL(record.ExpireTime, now)); err != nil {
+		return nil, ErrGeminiCachedContentUnavailable.WithCause(err)
+	}
+
+	if hours := geminiCachedContentStorageHours(previousExpire, resource.expireTime); !resource.expireTime.IsZero() && hours > 0 {
+		out.Usage = &ForwardResult{
+			RequestID: res.Headers.Get("x-goog-request-id"),
+			Model:     record.Model,
+			Duration:  time.Since(start),
+			CachedContentStorage: &CachedContentStorageUsage{
+				Tokens: record.TokenCount,
+				Hours:  hours,
+			},
+		}
+	}
+	return out, nil
+}
+
+// DeleteCachedContent 删除上游缓存与绑定。提前删除不退还已计的存储费用。
+func (s *GeminiMessagesCompatService) DeleteCachedContent(ctx context.Context, caller GeminiCachedContentCaller, id string) (*GeminiCachedContentResult, error) {
+	store, record, account, err := s.loadCachedContent(ctx, caller, id)
+	if err != nil {
+		return nil, err
+	}
+	fullURL, err := s.cachedContentsURL(account, record.UpstreamName, record.Model)
+	if err != nil {
+		return nil, ErrGeminiCachedContentAccount.WithCause(err)
+	}
+	res, err := s.doCachedContentsRequest(ctx, account, http.MethodDelete, fullURL, nil)
+	if err != nil {
+		return nil, ErrGeminiCachedContentAccount.WithCause(err)
+	}
+	if res.StatusCode < 300 || res.StatusCode == http.StatusNotFound {
+		if err := store.DeleteGeminiCachedContent(ctx, record.GroupID, record.UserID, record.ID); err != nil {
+			return nil, ErrGeminiCachedContentUnavailable.WithCause(err)
+		}
+	}
+	return &GeminiCachedContentResult{Response: res, Account: account}, nil
+}
+
+// ListCachedContents 按绑定记录列出调用方自己的缓存（不分页）。
+func (s *GeminiMessagesCompatService) ListCachedContents(ctx context.Context, caller GeminiCachedContentCaller) ([]byte, error) {
+	store := s.cachedContentStore()
+	if store == nil {
+		return nil, ErrGeminiCachedContentUnavailable
+	}
+	records, err := store.ListGeminiCachedContents(ctx, derefGroupID(caller.GroupID), caller.UserID)
+	if err != nil {
+		return nil, ErrGeminiCachedContentUnavailable.WithCause(err)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].CreatedAt.Before(records[j].CreatedAt) })
+	items := make([]json.RawMessage, 0, len(records))
+	for _, record := range records {
+		if len(record.Resource) > 0 {
+			items = append(items, record.Resource)
+		}
+	}
+	if len(items) == 0 {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(map[string]any{"cachedContents": items})
+}
+
+// ResolveCachedContentAccount 返回 generateContent 请求体中 cachedContent 所属的账号 ID。
+// 未引用缓存（或绑定存储不可用）时返回 0；引用了不属于调用方或已过期的缓存时返回
+// ErrGeminiCachedContentNotFound。返回的请求体中 cachedContent 已改写为上游资源名。
+func (s *GeminiMessagesCompatService) ResolveCachedContentAccount(ctx context.Context, caller GeminiCachedContentCaller, body []byte) (int64, []byte, error) {
+	ref := gjson.GetBytes(body, "cachedContent")
+	if !ref.Exists() || strings.TrimSpace(ref.String()) == "" {
+		return 0, body, nil
+	}
+	store := s.cachedContentStore()
+	if store == nil {
+		return 0, body, nil
+	}
+	id := geminiCachedContentID(ref.String())
+	if id == "" {
+		return 0, nil, ErrGeminiCachedContentNotFound
+	}
+	record, err := store.GetGeminiCachedContent(ctx, derefGroupID(caller.GroupID), id)
+	if err != nil {
+		return 0, nil, err
+	}
+	if record.UserID != caller.UserID {
+		return 0, nil, ErrGeminiCachedContentNotFound
+	}
+	if record.UpstreamName != "" && record.UpstreamName != ref.String() {
+		rewritten, err := sjson.SetBytes(body, "cachedContent", record.UpstreamName)
+		if err != nil {
+			return 0, nil, ErrGeminiCachedContentBadRequest.WithCause(err)
+		}
+		body = rewritten
+	}
+	return record.AccountID, body, nil
+}
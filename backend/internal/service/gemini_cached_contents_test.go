@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+type geminiCachedContentStoreStub struct {
+	GatewayCache
+
+	records map[string]*GeminiCachedContentRecord
+}
+
+func (s *geminiCachedContentStoreStub) SaveGeminiCachedContent(_ context.Context, record *GeminiCachedContentRecord, _ time.Duration) error {
+	if s.records == nil {
+		s.records = make(map[string]*GeminiCachedContentRecord)
+	}
+	s.records[record.ID] = record
+	return nil
+}
+
+func (s *geminiCachedContentStoreStub) GetGeminiCachedContent(_ context.Context, _ int64, id string) (*GeminiCachedContentRecord, error) {
+	record, ok := s.records[id]
+	if !ok {
+		return nil, ErrGeminiCachedContentNotFound
+	}
+	return record, nil
+}
+
+func (s *geminiCachedContentStoreStub) ListGeminiCachedContents(_ context.Context, _ int64, userID int64) ([]*GeminiCachedContentRecord, error) {
+	out := make([]*GeminiCachedContentRecord, 0, len(s.records))
+	for _, record := range s.records {
+		if record.UserID == userID {
+			out = append(out, record)
+		}
+	}
+	return out, nil
+}
+
+func (s *geminiCachedContentStoreStub) DeleteGeminiCachedContent(_ context.Context, _ int64, _ int64, id string) error {
+	delete(s.records, id)
+	return nil
+}
+
+func TestGeminiCachedContentIDAndModel(t *testing.T) {
+	require.Equal(t, "abc123", geminiCachedContentID("cachedContents/abc123"))
+	require.Equal(t, "abc123", geminiCachedContentID("projects/p/locations/us-central1/cachedContents/abc123"))
+	require.Equal(t, "abc123", geminiCachedContentID("abc123"))
+	require.Empty(t, geminiCachedContentID("cachedContents/.."))
+
+	require.Equal(t, "gemini-2.5-flash", GeminiCachedContentModel([]byte(`{"model":"models/gemini-2.5-flash"}`)))
+	require.Equal(t, "gemini-2.5-pro", normalizeGeminiCachedContentModel("projects/p/locations/l/publishers/google/models/gemini-2.5-pro"))
+}
+
+func TestGeminiCachedContentResourceRewrite(t *testing.T) {
+	raw := []byte(`{
+		"name":"projects/p/locations/us-central1/cachedContents/c1",
+		"model":"projects/p/locations/us-central1/publishers/google/models/gemini-2.5-flash",
+		"usageMetadata":{"totalTokenCount":4096},
+		"expireTime":"2026-01-01T01:00:00Z"
+	}`)
+	res := parseGeminiCachedContentResource(raw)
+	require.Equal(t, "c1", res.id)
+	require.Equal(t, "gemini-2.5-flash", res.model)
+	require.Equal(t, 4096, res.tokens)
+	require.Equal(t, time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC), res.expireTime)
+
+	out := rewriteGeminiCachedContentResource(raw, res.id, "gemini-2.5-flash")
+	require.Equal(t, "cachedContents/c1", gjson.GetBytes(out, "name").String())
+	require.Equal(t, "models/gemini-2.5-flash", gjson.GetBytes(out, "model").String())
+}
+
+func TestGeminiCachedContentStorageHours(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.InDelta(t, 2.5, geminiCachedContentStorageHours(now, now.Add(150*time.Minute)), 1e-9)
+	require.Zero(t, geminiCachedContentStorageHours(now, now.Add(-time.Minute)))
+	require.InDelta(t, geminiCachedContentDefaultTTL.Hours(), geminiCachedContentStorageHours(now, time.Time{}), 1e-9)
+
+	require.Equal(t, geminiCachedContentDefaultTTL+geminiCachedContentBindingGrace, geminiCachedContentBindingTTL(time.Time{}, now))
+	require.Equal(t, geminiCachedContentBindingGrace, geminiCachedContentBindingTTL(now.Add(-time.Hour), now))
+}
+
+func TestResolveCachedContentAccount(t *testing.T) {
+	groupID := int64(7)
+	store := &geminiCachedContentStoreStub{records: map[string]*GeminiCachedContentRecord{
+		"c1": {ID: "c1", UpstreamName: "cachedContents/up-c1", GroupID: groupID, UserID: 1, AccountID: 42},
+	}}
+	svc := &GeminiMessagesCompatService{cache: store}
+	caller := GeminiCachedContentCaller{GroupID: &groupID, UserID: 1}
+
+	accountID, body, err := svc.ResolveCachedContentAccount(context.Background(), caller, []byte(`{"contents":[]}`))
+	require.NoError(t, err)
+	require.Zero(t, accountID)
+	require.JSONEq(t, `{"contents":[]}`, string(body))
+
+	accountID, body, err = svc.ResolveCachedContentAccount(context.Background(), caller, []byte(`{"cachedContent":"cachedContents/c1"}`))
+	require.NoError(t, err)
+	require.Equal(t, int64(42), accountID)
+	require.Equal(t, "cachedContents/up-c1", gjson.GetBytes(body, "cachedContent").String())
+
+	_, _, err = svc.ResolveCachedContentAccount(context.Background(), GeminiCachedContentCaller{GroupID: &groupID, UserID: 2}, []byte(`{"cachedContent":"cachedContents/c1"}`))
+	require.ErrorIs(t, err, ErrGeminiCachedContentNotFound)
+
+	_, _, err = svc.ResolveCachedContentAccount(context.Background(), caller, []byte(`{"cachedContent":"cachedContents/missing"}`))
+	require.ErrorIs(t, err, ErrGeminiCachedContentNotFound)
+}
+
+func TestListCachedContentsOnlyReturnsCallerCaches(t *testing.T) {
+	now := time.Now()
+	store := &geminiCachedContentStoreStub{records: map[string]*GeminiCachedContentRecord{
+		"b": {ID: "b", UserID: 1, CreatedAt: now, Resource: []byte(`{"name":"cachedContents/b"}`)},
+		"a": {ID: "a", UserID: 1, CreatedAt: now.Add(-time.Minute), Resource: []byte(`{"name":"cachedContents/a"}`)},
+		"x": {ID: "x", UserID: 2, CreatedAt: now, Resource: []byte(`{"name":"cachedContents/x"}`)},
+	}}
+	svc := &GeminiMessagesCompatService{cache: store}
+
+	body, err := svc.ListCachedContents(context.Background(), GeminiCachedContentCaller{UserID: 1})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"cachedContents":[{"name":"cachedContents/a"},{"name":"cachedContents/b"}]}`, string(body))
+
+	body, err = svc.ListCachedContents(context.Background(), GeminiCachedContentCaller{UserID: 3})
+	require.NoError(t, err)
+	require.JSONEq(t, `{}`, string(body))
+}
+
+func TestCalculateCachedContentStorageCost(t *testing.T) {
+	s := &BillingService{}
+	cost := s.CalculateCachedContentStorageCost("gemini-2.5-flash", 1_000_000, 2, 1.5)
+	require.InDelta(t, 2.0, cost.TotalCost, 1e-9)
+	require.InDelta(t, 3.0, cost.ActualCost, 1e-9)
+	require.InDelta(t, cost.TotalCost, cost.CacheCreationCost, 1e-9)
+
+	cost = s.CalculateCachedContentStorageCost("gemini-2.5-pro", 1_000_000, 1, 1)
+	require.InDelta(t, 4.5, cost.TotalCost, 1e-9)
+}
@@ -0,0 +1,457 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/geminicli"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// GeminiAccountSupportsEmbeddings 判断 Gemini 账号能否承接 /v1/embeddings。
+// AI Studio（API Key / 无 project_id 的 OAuth）走 batchEmbedContents，Vertex
+// 服务账号走 predict；Code Assist（带 project_id 的 OAuth）没有 embedding 接口。
+func GeminiAccountSupportsEmbeddings(account *Account) bool {
+	if account == nil || account.Platform != PlatformGemini {
+		return false
+	}
+	switch account.Type {
+	case AccountTypeAPIKey, AccountTypeServiceAccount:
+		return true
+	case AccountTypeOAuth:
+		return strings.TrimSpace(account.GetCredential("project_id")) == ""
+	default:
+		return false
+	}
+}
+
+// geminiEmbeddingsRequest 是 OpenAI /v1/embeddings 请求中本转换关心的字段。
+type geminiEmbeddingsRequest struct {
+	Model          string          `json:"model"`
+	Input          json.RawMessage `json:"input"`
+	Dimensions     *int            `json:"dimensions,omitempty"`
+	EncodingFormat string          `json:"encoding_format,omitempty"`
+}
+
+type geminiEmbeddingsParsed struct {
+	model      string
+	inputs     []string
+	dimensions int
+	base64     bool
+}
+
+func parseGeminiEmbeddingsRequest(body []byte) (*geminiEmbeddingsParsed, error) {
+	var req geminiEmbeddingsRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, errors.New("request body is not valid JSON")
+	}
+	out := &geminiEmbeddingsParsed{model: strings.TrimSpace(req.Model)}
+	if out.model == "" {
+		return nil, errors.New("model is required")
+	}
+
+	raw := bytes.TrimSpace(req.Input)
+	switch {
+	case len(raw) == 0 || bytes.Equal(raw, []byte("null")):
+		return nil, errors.New("input is required")
+	case raw[0] == '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, errors.New("input must be a string or an array of strings")
+		}
+		out.inputs = []string{s}
+	case raw[0] == '[':
+		if err := json.Unmarshal(raw, &out.inputs); err != nil {
+			return nil, errors.New("token array input is not supported by Gemini embedding models; send text instead")
+		}
+	default:
+		return nil, errors.New("input must be a string or an array of strings")
+	}
+	if len(out.inputs) == 0 {
+		return nil, errors.New("input must not be empty")
+	}
+	for _, s := range out.inputs {
+		if s == "" {
+			return nil, errors.New("input must not contain empty strings")
+		}
+	}
+
+	if req.Dimensions != nil {
+		if *req.Dimensions <= 0 {
+			return nil, errors.New("dimensions must be a positive integer")
+		}
+		out.dimensions = *req.Dimensions
+	}
+	switch strings.ToLower(strings.TrimSpace(req.EncodingFormat)) {
+	case "", "float":
+	case "base64":
+		out.base64 = true
+	default:
+		return nil, fmt.Errorf("unsupported encoding_format: %s", req.EncodingFormat)
+	}
+	return out, nil
+}
+
+// buildGeminiBatchEmbedContentsBody 构造 AI Studio batchEmbedContents 请求体。
+func buildGeminiBatchEmbedContentsBody(model string, req *geminiEmbeddingsParsed) []byte {
+	type part struct {
+		Text string `json:"text"`
+	}
+	type content struct {
+		Parts []part `json:"parts"`
+	}
+	type embedRequest struct {
+		Model                string  `json:"model"`
+		Content              content `json:"content"`
+		OutputDimensionality int     `json:"outputDimensionality,omitempty"`
+	}
+	requests := make([]embedRequest, 0, len(req.inputs))
+	for _, text := range req.inputs {
+		requests = append(requests, embedRequest{
+			Model:                "models/" + model,
+			Content:              content{Parts: []part{{Text: text}}},
+			OutputDimensionality: req.dimensions,
+		})
+	}
+	body, _ := json.Marshal(map[string]any{"requests": requests})
+	return body
+}
+
+// buildVertexEmbeddingsPredictBody 构造 Vertex publishers/google/models/{model}:predict 请求体。
+func buildVertexEmbeddingsPredictBody(req *geminiEmbeddingsParsed) []byte {
+	instances := make([]map[string]string, 0, len(req.inputs))
+	for _, text := range req.inputs {
+		instances = append(instances, map[string]string{"content": text})
+	}
+	payload := map[string]any{"instances": instances}
+	if req.dimensions > 0 {
+		payload["parameters"] = map[string]any{"outputDimensionality": req.dimensions}
+	}
+	body, _ := json.Marshal(payload)
+	return body
+}
+
+// parseGeminiEmbeddingsResponse 解析 batchEmbedContents / predict 的响应，返回向量
+// 与上游报告的 token 数（AI Studio 不返回用量，此时为 0，由调用方估算）。
+func parseGeminiEmbeddingsResponse(body []byte, vertex bool) ([][]float64, int, error) {
+	if vertex {
+		var resp struct {
+			Predictions []struct {
+				Embeddings struct {
+					Values     []float64 `json:"values"`
+					Statistics struct {
+						TokenCount float64 `json:"token_count"`
+					} `json:"statistics"`
+				} `json:"embeddings"`
+			} `json:"predictions"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, 0, err
+		}
+		vectors := make([][]float64, 0, len(resp.Predictions))
+		tokens := 0
+		for _, p := range resp.Predictions {
+			vectors = append(vectors, p.Embeddings.Values)
+			tokens += int(p.Embeddings.Statistics.TokenCount)
+		}
+		return vectors, tokens, nil
+	}
+	var resp struct {
+		Embeddings []struct {
+			Values []float64 `json:"values"`
+		} `json:"embeddings"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, 0, err
+	}
+	vectors := make([][]float64, 0, len(resp.Embeddings))
+	for _, e := range resp.Embeddings {
+		vectors = append(vectors, e.Values)
+	}
+	return vectors, 0, nil
+}
+
+// buildOpenAIEmbeddingsResponse 把向量组装为 OpenAI embeddings 响应。
+// 指定 dimensions 时 Gemini 返回的是截断向量，这里按 OpenAI 语义重新做 L2 归一化。
+func buildOpenAIEmbeddingsResponse(model string, vectors [][]float64, promptTokens int, req *geminiEmbeddingsParsed) map[string]any {
+	data := make([]map[string]any, 0, len(vectors))
+	for i, vec := range vectors {
+		if req.dimensions > 0 {
+			vec = normalizeEmbeddingVector(vec)
+		}
+		var embedding any = vec
+		if req.base64 {
+			embedding = encodeEmbeddingBase64(vec)
+		}
+		data = append(data, map[string]any{
+			"object":    "embedding",
+			"index":     i,
+			"embedding": embedding,
+		})
+	}
+	return map[string]any{
+		"object": "list",
+		"data":   data,
+		"model":  model,
+		"usage": map[string]int{
+			"prompt_tokens": promptTokens,
+			"total_tokens":  promptTokens,
+		},
+	}
+}
+
+func normalizeEmbeddingVector(vec []float64) []float64 {
+	var sum float64
+	for _, v := range vec {
+		sum += v * v
+	}
+	if sum == 0 {
+		return vec
+	}
+	norm := math.Sqrt(sum)
+	out := make([]float64, len(vec))
+	for i, v := range vec {
+		out[i] = v / norm
+	}
+	return out
+}
+
+// encodeEmbeddingBase64 与 OpenAI 一致：little-endian float32 序列的 base64。
+func encodeEmbeddingBase64(vec []float64) string {
+	buf := make([]byte, 4*len(vec))
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(float32(v)))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// ForwardAsEmbeddings serves OpenAI Embeddings clients through Gemini accounts.
+// AI Studio accounts are called via batchEmbedContents and Vertex service
+// accounts via predict; the response is translated back to the OpenAI shape.
+func (s *GeminiMessagesCompatService) ForwardAsEmbeddings(
+	ctx context.Context,
+	c *gin.Context,
+	account *Account,
+	body []byte,
+) (*ForwardResult, error) {
+	startTime := time.Now()
+
+	req, err := parseGeminiEmbeddingsRequest(body)
+	if err != nil {
+		return nil, s.writeChatCompletionsError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+	}
+	if !GeminiAccountSupportsEmbeddings(account) {
+		return nil, s.writeChatCompletionsError(c, http.StatusBadRequest, "invalid_request_error", "Embeddings are not supported by this Gemini account type")
+	}
+
+	originalModel := req.model
+	mappedModel := originalModel
+	if account.Type == AccountTypeAPIKey || account.Type == AccountTypeServiceAccount {
+		mappedModel = account.GetMappedModel(originalModel)
+	}
+	vertex := account.Type == AccountTypeServiceAccount
+
+	proxyURL := ""
+	if account.ProxyID != nil && account.Proxy != nil {
+		proxyURL = account.Proxy.URL()
+	}
+
+	buildReq := func(ctx context.Context) (*http.Request, error) {
+		var (
+			fullURL  string
+			payload  []byte
+			authName string
+			authVal  string
+		)
+		switch account.Type {
+		case AccountTypeServiceAccount:
+			if s.tokenProvider == nil {
+				return nil, errors.New("gemini token provider not configured")
+			}
+			accessToken, err := s.tokenProvider.GetAccessToken(ctx, account)
+			if err != nil {
+				return nil, err
+			}
+			fullURL, err = buildVertexGeminiURL(account.VertexProjectID(), account.VertexLocation(mappedModel), mappedModel, "predict", false)
+			if err != nil {
+				return nil, err
+			}
+			payload = buildVertexEmbeddingsPredictBody(req)
+			authName, authVal = "Authorization", "Bearer "+accessToken
+		default:
+			if account.Type == AccountTypeAPIKey {
+				apiKey := account.GetCredential("api_key")
+				if strings.TrimSpace(apiKey) == "" {
+					return nil, errors.New("gemini api_key not configured")
+				}
+				authName, authVal = "x-goog-api-key", apiKey
+			} else {
+				if s.tokenProvider == nil {
+					return nil, errors.New("gemini token provider not configured")
+				}
+				accessToken, err := s.tokenProvider.GetAccessToken(ctx, account)
+				if err != nil {
+					return nil, err
+				}
+				authName, authVal = "Authorization", "Bearer "+accessToken
+			}
+			normalizedBaseURL, err := s.validateUpstreamBaseURL(account.GetGeminiBaseURL(geminicli.AIStudioBaseURL))
+			if err != nil {
+				return nil, err
+			}
+			fullURL, err = buildGeminiAIStudioModelActionURL(normalizedBaseURL, mappedModel, "batchEmbedContents", false)
+			if err != nil {
+				return nil, err
+			}
+			payload = buildGeminiBatchEmbedContentsBody(mappedModel, req)
+		}
+		upstreamReq, err := http.NewRequestWithContext(ctx, http.MethodPost, fullURL, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		upstreamReq.Header.Set("Content-Type", "application/json")
+		upstreamReq.Header.Set(authName, authVal)
+		return upstreamReq, nil
+	}
+
+	var resp *http.Response
+	for attempt := 1; attempt <= geminiMaxRetries; attempt++ {
+		upstreamReq, err := buildReq(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return nil, err
+			}
+			return nil, s.writeChatCompletionsError(c, http.StatusBadGateway, "upstream_error", err.Error())
+		}
+
+		resp, err = s.httpUpstream.Do(upstreamReq, proxyURL, account.ID, account.Concurrency)
+		if err != nil {
+			safeErr := sanitizeUpstreamErrorMessage(err.Error())
+			appendOpsUpstreamError(c, OpsUpstreamErrorEvent{
+				Platform:           account.Platform,
+				AccountID:          account.ID,
+				AccountName:        account.Name,
+				UpstreamStatusCode: 0,
+				Kind:               "request_error",
+				Message:            safeErr,
+			})
+			if attempt < geminiMaxRetries {
+				logger.LegacyPrintf("service.gemini_embeddings", "Gemini account %d: upstream request failed, retry %d/%d: %v", account.ID, attempt, geminiMaxRetries, err)
+				sleepGeminiBackoff(attempt)
+				continue
+			}
+			setOpsUpstreamError(c, 0, safeErr, "")
+			return nil, s.writeChatCompletionsError(c, http.StatusBadGateway, "upstream_error", "Upstream request failed after retries: "+safeErr)
+		}
+
+		if matched, rebuilt := s.checkErrorPolicyInLoop(ctx, account, resp, mappedModel); matched {
+			resp = rebuilt
+			break
+		} else {
+			resp = rebuilt
+		}
+
+		if resp.StatusCode >= 400 && s.shouldRetryGeminiUpstreamError(account, resp.StatusCode) {
+			respBody := s.readUpstreamErrorBody(resp)
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusTooManyRequests {
+				s.handleGeminiUpstreamError(ctx, account, resp.StatusCode, resp.Header, respBody)
+			}
+			if attempt < geminiMaxRetries {
+				appendOpsUpstreamError(c, OpsUpstreamErrorEvent{
+					Platform:           account.Platform,
+					AccountID:          account.ID,
+					AccountName:        account.Name,
+					UpstreamStatusCode: resp.StatusCode,
+					UpstreamRequestID:  resp.Header.Get("x-goog-request-id"),
+					Kind:               "retry",
+					Message:            sanitizeUpstreamErrorMessage(strings.TrimSpace(extractUpstreamErrorMessage(respBody))),
+				})
+				sleepGeminiBackoff(attempt)
+				continue
+			}
+			resp = &http.Response{
+				StatusCode: resp.StatusCode,
+				Header:     resp.Header.Clone(),
+				Body:       io.NopCloser(bytes.NewReader(respBody)),
+			}
+		}
+		break
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	requestID := resp.Header.Get("x-request-id")
+	if requestID == "" {
+		requestID = resp.Header.Get("x-goog-request-id")
+	}
+	if requestID != "" {
+		c.Header("x-request-id", requestID)
+	}
+
+	if resp.StatusCode >= 400 {
+		respBody := s.readUpstreamErrorBody(resp)
+		policy := ErrorPolicyNone
+		if s.rateLimitService != nil {
+			policy = s.rateLimitService.CheckErrorPolicy(ctx, account, resp.StatusCode, respBody, mappedModel)
+		}
+		if policy == ErrorPolicyNone || policy == ErrorPolicyMatched {
+			s.handleGeminiUpstreamError(ctx, account, resp.StatusCode, resp.Header, respBody)
+		}
+		if s.shouldFailoverGeminiUpstreamError(resp.StatusCode) {
+			appendOpsUpstreamError(c, OpsUpstreamErrorEvent{
+				Platform:           account.Platform,
+				AccountID:          account.ID,
+				AccountName:        account.Name,
+				UpstreamStatusCode: resp.StatusCode,
+				UpstreamRequestID:  requestID,
+				Kind:               "failover",
+				Message:            sanitizeUpstreamErrorMessage(strings.TrimSpace(extractUpstreamErrorMessage(respBody))),
+			})
+			return nil, &UpstreamFailoverError{
+				StatusCode:             resp.StatusCode,
+				ResponseBody:           respBody,
+				RetryableOnSameAccount: account.IsPoolMode() && account.IsPoolModeRetryableStatus(resp.StatusCode),
+			}
+		}
+		return nil, s.writeGeminiChatCompletionsMappedError(c, account, resp.StatusCode, requestID, respBody)
+	}
+
+	respBody, err := ReadUpstreamResponseBody(resp.Body, s.cfg, c, openAITooLargeError)
+	if err != nil {
+		if !errors.Is(err, ErrUpstreamResponseBodyTooLarge) {
+			_ = s.writeChatCompletionsError(c, http.StatusBadGateway, "upstream_error", "Failed to read upstream response")
+		}
+		return nil, err
+	}
+	vectors, promptTokens, err := parseGeminiEmbeddingsResponse(respBody, vertex)
+	if err != nil || len(vectors) != len(req.inputs) {
+		return nil, s.writeChatCompletionsError(c, http.StatusBadGateway, "upstream_error", "Failed to parse upstream response")
+	}
+	if promptTokens == 0 {
+		for _, text := range req.inputs {
+			promptTokens += estimateTokensForText(text)
+		}
+	}
+
+	c.JSON(http.StatusOK, buildOpenAIEmbeddingsResponse(originalModel, vectors, promptTokens, req))
+
+	return &ForwardResult{
+		RequestID:     requestID,
+		Usage:         ClaudeUsage{InputTokens: promptTokens},
+		Model:         originalModel,
+		UpstreamModel: mappedModel,
+		Stream:        false,
+		Duration:      time.Since(startTime),
+	}, nil
+}
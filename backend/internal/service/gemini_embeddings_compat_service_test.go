@@ -0,0 +1,102 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGeminiEmbeddingsRequest(t *testing.T) {
+	req, err := parseGeminiEmbeddingsRequest([]byte(`{"model":"gemini-embedding-001","input":"hello","dimensions":256,"encoding_format":"base64"}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"hello"}, req.inputs)
+	require.Equal(t, 256, req.dimensions)
+	require.True(t, req.base64)
+
+	req, err = parseGeminiEmbeddingsRequest([]byte(`{"model":"text-embedding-005","input":["a","b"]}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, req.inputs)
+	require.False(t, req.base64)
+
+	for _, body := range []string{
+		`{"model":"m","input":[1,2,3]}`,
+		`{"model":"m","input":[]}`,
+		`{"model":"m"}`,
+		`{"model":"m","input":"x","encoding_format":"int8"}`,
+		`{"model":"m","input":"x","dimensions":0}`,
+		`{"input":"x"}`,
+	} {
+		_, err := parseGeminiEmbeddingsRequest([]byte(body))
+		require.Error(t, err, body)
+	}
+}
+
+func TestBuildGeminiEmbeddingsUpstreamBodies(t *testing.T) {
+	req := &geminiEmbeddingsParsed{model: "gemini-embedding-001", inputs: []string{"a", "b"}, dimensions: 768}
+
+	require.JSONEq(t, `{"requests":[
+		{"model":"models/gemini-embedding-001","content":{"parts":[{"text":"a"}]},"outputDimensionality":768},
+		{"model":"models/gemini-embedding-001","content":{"parts":[{"text":"b"}]},"outputDimensionality":768}
+	]}`, string(buildGeminiBatchEmbedContentsBody("gemini-embedding-001", req)))
+
+	require.JSONEq(t, `{"instances":[{"content":"a"},{"content":"b"}],"parameters":{"outputDimensionality":768}}`,
+		string(buildVertexEmbeddingsPredictBody(req)))
+
+	req.dimensions = 0
+	require.JSONEq(t, `{"instances":[{"content":"a"},{"content":"b"}]}`, string(buildVertexEmbeddingsPredictBody(req)))
+}
+
+func TestGeminiEmbeddingsResponseToOpenAI(t *testing.T) {
+	vectors, tokens, err := parseGeminiEmbeddingsResponse([]byte(`{"predictions":[
+		{"embeddings":{"values":[3,4],"statistics":{"token_count":5,"truncated":false}}},
+		{"embeddings":{"values":[0,1],"statistics":{"token_count":2}}}
+	]}`), true)
+	require.NoError(t, err)
+	require.Equal(t, 7, tokens)
+
+	out := buildOpenAIEmbeddingsResponse("text-embedding-005", vectors, tokens, &geminiEmbeddingsParsed{dimensions: 2})
+	raw, err := json.Marshal(out)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"object":"list","model":"text-embedding-005",
+		"data":[{"object":"embedding","index":0,"embedding":[0.6,0.8]},{"object":"embedding","index":1,"embedding":[0,1]}],
+		"usage":{"prompt_tokens":7,"total_tokens":7}
+	}`, string(raw))
+
+	vectors, tokens, err = parseGeminiEmbeddingsResponse([]byte(`{"embeddings":[{"values":[0.5,-1.25]}]}`), false)
+	require.NoError(t, err)
+	require.Zero(t, tokens)
+	out = buildOpenAIEmbeddingsResponse("gemini-embedding-001", vectors, 3, &geminiEmbeddingsParsed{base64: true})
+	encoded := out["data"].([]map[string]any)[0]["embedding"].(string)
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+	require.Len(t, decoded, 8)
+	require.Equal(t, float32(0.5), math.Float32frombits(binary.LittleEndian.Uint32(decoded[0:])))
+	require.Equal(t, float32(-1.25), math.Float32frombits(binary.LittleEndian.Uint32(decoded[4:])))
+}
+
+func TestGeminiAccountSupportsEmbeddings(t *testing.T) {
+	require.True(t, GeminiAccountSupportsEmbeddings(&Account{Platform: PlatformGemini, Type: AccountTypeAPIKey}))
+	require.True(t, GeminiAccountSupportsEmbeddings(&Account{Platform: PlatformGemini, Type: AccountTypeServiceAccount}))
+	require.True(t, GeminiAccountSupportsEmbeddings(&Account{Platform: PlatformGemini, Type: AccountTypeOAuth}))
+	require.False(t, GeminiAccountSupportsEmbeddings(&Account{
+		Platform:    PlatformGemini,
+		Type:        AccountTypeOAuth,
+		Credentials: map[string]any{"project_id": "code-assist-project"},
+	}))
+	require.False(t, GeminiAccountSupportsEmbeddings(&Account{Platform: PlatformAntigravity, Type: AccountTypeOAuth}))
+}
+
+func TestBuildGeminiEmbeddingsURLs(t *testing.T) {
+	u, err := buildGeminiAIStudioModelActionURL("https://generativelanguage.googleapis.com", "gemini-embedding-001", "batchEmbedContents", false)
+	require.NoError(t, err)
+	require.Equal(t, "https://generativelanguage.googleapis.com/v1beta/models/gemini-embedding-001:batchEmbedContents", u)
+
+	u, err = buildVertexGeminiURL("proj", "us-central1", "text-embedding-005", "predict", false)
+	require.NoError(t, err)
+	require.Equal(t, "https://us-central1-aiplatform.googleapis.com/v1/projects/proj/locations/us-central1/publishers/google/models/text-embedding-005:predict", u)
+}
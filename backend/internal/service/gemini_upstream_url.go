@@ -12,6 +12,8 @@ var geminiAIStudioActions = map[string]struct{}{
 	"generateContent":       {},
 	"streamGenerateContent": {},
 	"countTokens":           {},
+	// OpenAI /v1/embeddings 兼容层使用，见 gemini_embeddings_compat_service.go。
+	"batchEmbedContents": {},
 }
 
 // buildGeminiAIStudioModelActionURL 组装 AI Studio 的
@@ -69,6 +69,9 @@ type Group struct {
 	AudioRealtimePricePerMin     *float64
 	AudioTTSPricePerMillionChars *float64
 	AudioSTTPricePerHour         *float64
+	// /v1/moderations 对外审计定价（分组级）；nil 表示该维度不收费。
+	ModerationPricePerCall          *float64
+	ModerationPricePerMillionTokens *float64
 
 	// ModelPricing overrides channel and built-in prices for matching models.
 	// Token intervals are selected only when LongContextPricingEnabled is true.
@@ -124,6 +127,24 @@ type Group struct {
 	ProfitMinMargin      float64 // 最低毛利率，小数存储（0.30=30%）
 	ProfitSafetyBuffer   float64 // 安全缓冲，小数，与 margin 相加后从 D 中扣除
 
+	// PromptPrefixRoutingEnabled maps requests without a sticky binding onto
+	// accounts by consistent-hashing the cacheable prompt prefix, so identical
+	// system prompts and tool lists keep hitting the same upstream prompt cache.
+	PromptPrefixRoutingEnabled bool
+	// PromptPrefixMessages is how many leading messages join the prefix hash;
+	// 0 hashes tools + system only.
+	PromptPrefixMessages int
+
+	// StreamResumeEnabled buffers streaming responses so a client that drops
+	// mid-stream can reconnect with Last-Event-ID and receive the remainder.
+	StreamResumeEnabled bool
+
+	// ContextCompactionMode decides what happens when a prompt exceeds the
+	// mapped model's context window: "" / "off", "truncate" or "summarize".
+	ContextCompactionMode string
+	// ContextCompactionModel is the cheap model used by "summarize".
+	ContextCompactionModel string
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 
@@ -27,6 +27,13 @@ type ImageStorage interface {
 	Save(ctx context.Context, key, contentType string, data []byte) (url string, err error)
 }
 
+// ImageStorageLoader 是 ImageStorage 的可选扩展：支持按 key 读回对象。
+// 后台 Responses 任务把终态结果存入对象存储后需要从任意实例读回，未实现本接口的存储
+// 只能用于图片转存。
+type ImageStorageLoader interface {
+	Load(ctx context.Context, key string) ([]byte, error)
+}
+
 // ImageResultUploader 是 ImageStorage 的上层编排器（与具体厂商无关）：
 // 把上游生图响应里的每张图片（b64_json 解码 / url 下载）转存到对象存储，
 // 并把响应结果改写为只含短链接的紧凑 JSON，从而避免大 base64 落 Redis。
@@ -222,6 +229,30 @@ func (u *ImageResultUploader) download(ctx context.Context, rawURL string) ([]by
 	return data, contentType, nil
 }
 
+// SaveObject 以 uploader 的 key 前缀把任意对象写入对象存储，返回实际使用的 key。
+func (u *ImageResultUploader) SaveObject(ctx context.Context, name, contentType string, data []byte) (string, error) {
+	if u == nil || u.storage == nil {
+		return "", errors.New("object storage is not configured")
+	}
+	key := u.prefix + name
+	if _, err := u.storage.Save(ctx, key, contentType, data); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// LoadObject 按 SaveObject 返回的 key 读回对象；存储不支持读取时返回错误。
+func (u *ImageResultUploader) LoadObject(ctx context.Context, key string) ([]byte, error) {
+	if u == nil || u.storage == nil {
+		return nil, errors.New("object storage is not configured")
+	}
+	loader, ok := u.storage.(ImageStorageLoader)
+	if !ok {
+		return nil, errors.New("object storage does not support reading objects back")
+	}
+	return loader.Load(ctx, key)
+}
+
 func (u *ImageResultUploader) buildKey(taskID string, index int, contentType string) string {
 	return u.prefix + taskID + "-" + strconv.Itoa(index) + extensionForContentType(contentType)
 }
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+)
+
+const (
+	// inferenceIdempotencyScopePrefix 按 API Key 隔离推理请求的幂等键。
+	inferenceIdempotencyScopePrefix  = "inference:api_key:"
+	inferenceIdempotencyPollInterval = 500 * time.Millisecond
+)
+
+var (
+	ErrInferenceIdempotencyStreamCompleted  = infraerrors.Conflict("IDEMPOTENCY_STREAM_COMPLETED", "a streaming request with this idempotency key has already completed and cannot be replayed")
+	ErrInferenceIdempotencyResponseTooLarge = infraerrors.Conflict("IDEMPOTENCY_RESPONSE_NOT_STORED", "the original response exceeded the replay size limit and cannot be replayed")
+)
+
+// InferenceIdempotentResponse 是推理请求成功后落库的回放内容。
+// 流式响应与超出上限的响应只记录原始请求 ID，重复请求会得到明确的错误。
+type InferenceIdempotentResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body,omitempty"`
+	Stream      bool   `json:"stream,omitempty"`
+	Truncated   bool   `json:"truncated,omitempty"`
+	RequestID   string `json:"request_id,omitempty"`
+}
+
+// InferenceIdempotencyRequest 描述一次携带 Idempotency-Key 的推理请求。
+type InferenceIdempotencyRequest struct {
+	APIKeyID int64
+	Key      string
+	Method   string
+	Route    string
+	Body     []byte
+}
+
+// InferenceIdempotencyClaim 表示当前请求持有幂等键，需要在结束时 Complete 或 Release。
+type InferenceIdempotencyClaim struct {
+	id          int64
+	fingerprint string
+	expiresAt   time.Time
+}
+
+// InferenceIdempotencyService 为 /v1/messages、/v1/chat/completions、/v1/responses
+// 与图片等推理端点提供 Idempotency-Key 支持，复用 idempotency_records 存储。
+//
+// 与 IdempotencyCoordinator 不同，推理请求直接写出 HTTP 响应，因此这里拆成
+// Begin / Heartbeat / Complete / Release 四步，由网关 handler 包裹原始处理器。
+type InferenceIdempotencyService struct {
+	repo         IdempotencyRepository
+	cfg          IdempotencyConfig
+	waitTimeout  time.Duration
+	pollInterval time.Duration
+}
+
+func NewInferenceIdempotencyService(repo IdempotencyRepository, cfg IdempotencyConfig) *InferenceIdempotencyService {
+	return &InferenceIdempotencyService{
+		repo:         repo,
+		cfg:          cfg,
+		waitTimeout:  cfg.ProcessingTimeout,
+		pollInterval: inferenceIdempotencyPollInterval,
+	}
+}
+
+// ProvideInferenceIdempotencyService 使用全局幂等配置构造推理幂等服务。
+func ProvideInferenceIdempotencyService(repo IdempotencyRepository, cfg *config.Config) *InferenceIdempotencyService {
+	return NewInferenceIdempotencyService(repo, buildIdempotencyConfig(cfg))
+}
+
+func (s *InferenceIdempotencyService) Available() bool {
+	return s != nil && s.repo != nil
+}
+
+// LockTTL 返回处理中锁的时长；调用方应以更短的间隔 Heartbeat 续期。
+func (s *InferenceIdempotencyService) LockTTL() time.Duration {
+	return s.cfg.ProcessingTimeout
+}
+
+// MaxStoredResponseLen 返回可回放响应体的上限，<=0 表示不限制。
+func (s *InferenceIdempotencyService) MaxStoredResponseLen() int {
+	return s.cfg.MaxStoredResponseLen
+}
+
+// Begin 认领幂等键。返回 claim 时由调用方执行请求；返回 replay 时直接回放；
+// 同键请求仍在处理时最多等待 waitTimeout，之后返回带 retry_after 的 409。
+func (s *InferenceIdempotencyService) Begin(ctx context.Context, req InferenceIdempotencyRequest) (*InferenceIdempotencyClaim, *InferenceIdempotentResponse, error) {
+	if !s.Available() {
+		return nil, nil, ErrIdempotencyStoreUnavail
+	}
+	key, err := NormalizeIdempotencyKey(req.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if key == "" {
+		return nil, nil, ErrIdempotencyKeyRequired
+	}
+	scope := inferenceIdempotencyScopePrefix + strconv.FormatInt(req.APIKeyID, 10)
+	fingerprint, err := BuildIdempotencyFingerprint(req.Method, req.Route, scope, HashIdempotencyKey(string(req.Body)))
+	if err != nil {
+		return nil, nil, err
+	}
+	keyHash := HashIdempotencyKey(key)
+
+	now := time.Now()
+	lockedUntil := now.Add(s.cfg.ProcessingTimeout)
+	record := &IdempotencyRecord{
+		Scope:              scope,
+		IdempotencyKeyHash: keyHash,
+		RequestFingerprint: fingerprint,
+		Status:             IdempotencyStatusProcessing,
+		LockedUntil:        &lockedUntil,
+		ExpiresAt:          now.Add(s.cfg.DefaultTTL),
+	}
+	owner, err := s.repo.CreateProcessing(ctx, record)
+	if err != nil {
+		RecordIdempotencyStoreUnavailable(req.Route, scope, "create_processing_error")
+		return nil, nil, ErrIdempotencyStoreUnavail.WithCause(err)
+	}
+	if owner {
+		recordIdempotencyClaim(req.Route, scope, map[string]string{"mode": "new_claim"})
+		return &InferenceIdempotencyClaim{id: record.ID, fingerprint: fingerprint, expiresAt: record.ExpiresAt}, nil, nil
+	}
+
+	deadline := now.Add(s.waitTimeout)
+	for {
+		existing, err := s.repo.GetByScopeAndKeyHash(ctx, scope, keyHash)
+		if err != nil {
+			RecordIdempotencyStoreUnavailable(req.Route, scope, "get_existing_error")
+			return nil, nil, ErrIdempotencyStoreUnavail.WithCause(err)
+		}
+		if existing == nil {
+			RecordIdempotencyStoreUnavailable(req.Route, scope, "missing_existing")
+			return nil, nil, ErrIdempotencyStoreUnavail
+		}
+		if existing.RequestFingerprint != fingerprint {
+			recordIdempotencyConflict(req.Route, scope, map[string]string{"reason": "fingerprint_mismatch"})
+			return nil, nil, ErrIdempotencyKeyConflict
+		}
+
+		now = time.Now()
+		lockExpired := existing.LockedUntil == nil || !existing.LockedUntil.After(now)
+		// 已过期的记录、失败后的记录以及持有者失联（锁过期）的处理中记录均可重新认领。
+		if !existing.ExpiresAt.After(now) || (existing.Status != IdempotencyStatusSucceeded && lockExpired) {
+			lockedUntil := now.Add(s.cfg.ProcessingTimeout)
+			expiresAt := now.Add(s.cfg.DefaultTTL)
+			taken, err := s.repo.TryReclaim(ctx, existing.ID, existing.Status, now, lockedUntil, expiresAt)
+			if err != nil {
+				RecordIdempotencyStoreUnavailable(req.Route, scope, "try_reclaim_error")
+				return nil, nil, ErrIdempotencyStoreUnavail.WithCause(err)
+			}
+			if taken {
+				recordIdempotencyClaim(req.Route, scope, map[string]string{"mode": "reclaim"})
+				return &InferenceIdempotencyClaim{id: existing.ID, fingerprint: fingerprint, expiresAt: expiresAt}, nil, nil
+			}
+		} else if existing.Status == IdempotencyStatusSucceeded {
+			replay, err := decodeInferenceIdempotentResponse(existing)
+			if err != nil {
+				RecordIdempotencyStoreUnavailable(req.Route, scope, "decode_stored_response_error")
+				return nil, nil, ErrIdempotencyStoreUnavail.WithCause(err)
+			}
+			if replay.Stream {
+				return nil, nil, inferenceIdempotencyReplayError(ErrInferenceIdempotencyStreamCompleted, replay.RequestID)
+			}
+			if replay.Truncated {
+				return nil, nil, inferenceIdempotencyReplayError(ErrInferenceIdempotencyResponseTooLarge, replay.RequestID)
+			}
+			recordIdempotencyReplay(req.Route, scope, nil)
+			return nil, replay, nil
+		}
+
+		if !now.Before(deadline) {
+			recordIdempotencyConflict(req.Route, scope, map[string]string{"reason": "in_progress"})
+			return nil, nil, inferenceIdempotencyInProgress(existing.LockedUntil, now)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(s.pollInterval):
+		}
+	}
+}
+
+// Heartbeat 为长时间运行的请求（尤其是流式请求）续期处理中锁。
+func (s *InferenceIdempotencyService) Heartbeat(ctx context.Context, claim *InferenceIdempotencyClaim) error {
+	if claim == nil {
+		return nil
+	}
+	_, err := s.repo.ExtendProcessingLock(ctx, claim.id, claim.fingerprint, time.Now().Add(s.cfg.ProcessingTimeout), claim.expiresAt)
+	return err
+}
+
+// Complete 记录成功的响应；之后的重复请求回放该响应或得到明确错误，不会再请求上游。
+func (s *InferenceIdempotencyService) Complete(ctx context.Context, claim *InferenceIdempotencyClaim, response InferenceIdempotentResponse) error {
+	if claim == nil {
+		return nil
+	}
+	if !response.Stream && s.cfg.MaxStoredResponseLen > 0 && len(response.Body) > s.cfg.MaxStoredResponseLen {
+		response.Body = ""
+		response.Truncated = true
+	}
+	if response.Stream || response.Truncated {
+		response.Body = ""
+		response.ContentType = ""
+	}
+	stored, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return s.repo.MarkSucceeded(ctx, claim.id, response.Status, string(stored), claim.expiresAt)
+}
+
+// Release 在请求失败（未计费）时释放幂等键，客户端可立即用同一个键重试。
+func (s *InferenceIdempotencyService) Release(ctx context.Context, claim *InferenceIdempotencyClaim, reason string) error {
+	if claim == nil {
+		return nil
+	}
+	if reason == "" {
+		reason = "EXECUTION_FAILED"
+	}
+	return s.repo.MarkFailedRetryable(ctx, claim.id, reason, time.Now(), claim.expiresAt)
+}
+
+func decodeInferenceIdempotentResponse(record *IdempotencyRecord) (*InferenceIdempotentResponse, error) {
+	if record.ResponseBody == nil {
+		return nil, fmt.Errorf("stored response is empty")
+	}
+	var replay InferenceIdempotentResponse
+	if err := json.Unmarshal([]byte(*record.ResponseBody), &replay); err != nil {
+		return nil, fmt.Errorf("decode stored response: %w", err)
+	}
+	if replay.Status == 0 && record.ResponseStatus != nil {
+		replay.Status = *record.ResponseStatus
+	}
+	return &replay, nil
+}
+
+func inferenceIdempotencyReplayError(base *infraerrors.ApplicationError, requestID string) error {
+	if requestID == "" {
+		return base
+	}
+	return base.WithMetadata(map[string]string{"original_request_id": requestID})
+}
+
+func inferenceIdempotencyInProgress(lockedUntil *time.Time, now time.Time) error {
+	sec := 1
+	if lockedUntil != nil {
+		if remaining := int(lockedUntil.Sub(now).Seconds()); remaining > 0 {
+			sec = remaining
+		}
+	}
+	return ErrIdempotencyInProgress.WithMetadata(map[string]string{"retry_after": strconv.Itoa(sec)})
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func newInferenceIdempotencyServiceForTest(repo IdempotencyRepository) *InferenceIdempotencyService {
+	cfg := DefaultIdempotencyConfig()
+	cfg.ProcessingTimeout = time.Second
+	cfg.MaxStoredResponseLen = 64
+	svc := NewInferenceIdempotencyService(repo, cfg)
+	svc.waitTimeout = 200 * time.Millisecond
+	svc.pollInterval = 10 * time.Millisecond
+	return svc
+}
+
+func inferenceIdempotencyRequestForTest(body string) InferenceIdempotencyRequest {
+	return InferenceIdempotencyRequest{APIKeyID: 9, Key: "retry-1", Method: "POST", Route: "/v1/messages", Body: []byte(body)}
+}
+
+func TestInferenceIdempotencyReplaysCompletedResponse(t *testing.T) {
+	svc := newInferenceIdempotencyServiceForTest(newInMemoryIdempotencyRepo())
+	ctx := context.Background()
+
+	claim, replay, err := svc.Begin(ctx, inferenceIdempotencyRequestForTest(`{"stream":false}`))
+	require.NoError(t, err)
+	require.NotNil(t, claim)
+	require.Nil(t, replay)
+	require.NoError(t, svc.Complete(ctx, claim, InferenceIdempotentResponse{
+		Status:      200,
+		ContentType: "application/json",
+		Body:        `{"id":"msg_1"}`,
+		RequestID:   "req-1",
+	}))
+
+	claim, replay, err = svc.Begin(ctx, inferenceIdempotencyRequestForTest(`{"stream":false}`))
+	require.NoError(t, err)
+	require.Nil(t, claim)
+	require.Equal(t, `{"id":"msg_1"}`, replay.Body)
+	require.Equal(t, "application/json", replay.ContentType)
+
+	// 同一 API Key 下复用键但请求体不同。
+	_, _, err = svc.Begin(ctx, inferenceIdempotencyRequestForTest(`{"stream":false,"x":1}`))
+	require.ErrorIs(t, err, ErrIdempotencyKeyConflict)
+
+	// 其他 API Key 的同名键互不影响。
+	other := inferenceIdempotencyRequestForTest(`{"stream":false}`)
+	other.APIKeyID = 10
+	claim, _, err = svc.Begin(ctx, other)
+	require.NoError(t, err)
+	require.NotNil(t, claim)
+}
+
+func TestInferenceIdempotencyStreamAndOversizedDuplicatesFail(t *testing.T) {
+	svc := newInferenceIdempotencyServiceForTest(newInMemoryIdempotencyRepo())
+	ctx := context.Background()
+
+	claim, _, err := svc.Begin(ctx, inferenceIdempotencyRequestForTest(`{"stream":true}`))
+	require.NoError(t, err)
+	require.NoError(t, svc.Complete(ctx, claim, InferenceIdempotentResponse{Status: 200, Body: "data: x", Stream: true, RequestID: "req-stream"}))
+	_, _, err = svc.Begin(ctx, inferenceIdempotencyRequestForTest(`{"stream":true}`))
+	require.ErrorIs(t, err, ErrInferenceIdempotencyStreamCompleted)
+	require.Equal(t, "req-stream", infraerrors.FromError(err).Metadata["original_request_id"])
+
+	large := inferenceIdempotencyRequestForTest(`{"large":true}`)
+	large.Key = "retry-2"
+	claim, _, err = svc.Begin(ctx, large)
+	require.NoError(t, err)
+	require.NoError(t, svc.Complete(ctx, claim, InferenceIdempotentResponse{Status: 200, Body: string(make([]byte, 65)), RequestID: "req-large"}))
+	_, _, err = svc.Begin(ctx, large)
+	require.ErrorIs(t, err, ErrInferenceIdempotencyResponseTooLarge)
+}
+
+func TestInferenceIdempotencyReleaseAllowsImmediateRetry(t *testing.T) {
+	svc := newInferenceIdempotencyServiceForTest(newInMemoryIdempotencyRepo())
+	ctx := context.Background()
+
+	claim, _, err := svc.Begin(ctx, inferenceIdempotencyRequestForTest(`{}`))
+	require.NoError(t, err)
+	require.NoError(t, svc.Release(ctx, claim, "HTTP_502"))
+
+	claim, replay, err := svc.Begin(ctx, inferenceIdempotencyRequestForTest(`{}`))
+	require.NoError(t, err)
+	require.NotNil(t, claim)
+	require.Nil(t, replay)
+}
+
+func TestInferenceIdempotencyConcurrentDuplicateWaitsThenConflicts(t *testing.T) {
+	svc := newInferenceIdempotencyServiceForTest(newInMemoryIdempotencyRepo())
+	ctx := context.Background()
+
+	claim, _, err := svc.Begin(ctx, inferenceIdempotencyRequestForTest(`{}`))
+	require.NoError(t, err)
+
+	// 处理中的重复请求在等待窗口内拿到首个请求的结果。
+	var waited atomic.Pointer[InferenceIdempotentResponse]
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, replay, err := svc.Begin(ctx, inferenceIdempotencyRequestForTest(`{}`))
+		if err == nil {
+			waited.Store(replay)
+		}
+	}()
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, svc.Complete(ctx, claim, InferenceIdempotentResponse{Status: 200, Body: `{"ok":true}`}))
+	wg.Wait()
+	require.NotNil(t, waited.Load())
+	require.Equal(t, `{"ok":true}`, waited.Load().Body)
+
+	// 超出等待窗口仍在处理时返回带 Retry-After 的 409。
+	pending := inferenceIdempotencyRequestForTest(`{"pending":true}`)
+	pending.Key = "retry-2"
+	_, _, err = svc.Begin(ctx, pending)
+	require.NoError(t, err)
+	_, _, err = svc.Begin(ctx, pending)
+	require.ErrorIs(t, err, ErrIdempotencyInProgress)
+	require.Positive(t, RetryAfterSecondsFromError(err))
+}
+
+func TestInferenceIdempotencyReclaimsStaleProcessingLock(t *testing.T) {
+	svc := newInferenceIdempotencyServiceForTest(newInMemoryIdempotencyRepo())
+	svc.cfg.ProcessingTimeout = 20 * time.Millisecond
+	ctx := context.Background()
+
+	_, _, err := svc.Begin(ctx, inferenceIdempotencyRequestForTest(`{}`))
+	require.NoError(t, err)
+	// 持有者失联且未续期，锁过期后重复请求可以重新认领。
+	claim, _, err := svc.Begin(ctx, inferenceIdempotencyRequestForTest(`{}`))
+	require.NoError(t, err)
+	require.NotNil(t, claim)
+}
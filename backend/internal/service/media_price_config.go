@@ -56,3 +56,13 @@ func groupAudioPriceConfigFromAPIKey(apiKey *APIKey) *audioPriceConfig {
 		STTPerHour:     g.AudioSTTPricePerHour,
 	}
 }
+
+func moderationPriceConfigFromAPIKey(apiKey *APIKey) *moderationPriceConfig {
+	if apiKey == nil || apiKey.Group == nil {
+		return nil
+	}
+	return &moderationPriceConfig{
+		PerCall:          apiKey.Group.ModerationPricePerCall,
+		PerMillionTokens: apiKey.Group.ModerationPricePerMillionTokens,
+	}
+}
@@ -32,6 +32,8 @@ const (
 	NotificationEmailEventContentModerationViolation  = "content_moderation.violation_notice"
 	NotificationEmailEventContentModerationDisabled   = "content_moderation.account_disabled"
 	NotificationEmailEventCyberPolicyNotice           = "content_moderation.cyber_policy_notice"
+	NotificationEmailEventAPIKeyLeakSuspected         = "api_key.leak_suspected"
+	NotificationEmailEventAPIKeyRotated               = "api_key.rotated"
 	NotificationEmailEventOpsAlert                    = "ops.alert"
 	NotificationEmailEventOpsScheduledReport          = "ops.scheduled_report"
 
@@ -934,6 +936,13 @@ func notificationEmailSampleVariables(locale string) map[string]string {
 			"moderation_score":    "0.982",
 			"violation_count":     "2",
 			"ban_threshold":       "3",
+			"api_key_name":        "生产环境",
+			"api_key_prefix":      "sk-abc123",
+			"leak_signals":        "37 个不同来源 IP；4 个国家/地区（BR, DE, SG, US）",
+			"leak_sample_ips":     "203.0.113.7, 198.51.100.24",
+			"leak_action":         "该 Key 已被禁用。",
+			"old_api_key_prefix":  "sk-def456",
+			"grace_expires_at":    "2026-01-16 10:00:00",
 			"rule_name":           "错误率过高",
 			"severity":            "critical",
 			"alert_status":        "firing",
@@ -987,6 +996,13 @@ func notificationEmailSampleVariables(locale string) map[string]string {
 		"moderation_score":    "0.982",
 		"violation_count":     "2",
 		"ban_threshold":       "3",
+		"api_key_name":        "production",
+		"api_key_prefix":      "sk-abc123",
+		"leak_signals":        "37 distinct source IPs; 4 countries (BR, DE, SG, US)",
+		"leak_sample_ips":     "203.0.113.7, 198.51.100.24",
+		"leak_action":         "The key has been disabled.",
+		"old_api_key_prefix":  "sk-def456",
+		"grace_expires_at":    "2026-01-16 10:00:00",
 		"rule_name":           "High error rate",
 		"severity":            "critical",
 		"alert_status":        "firing",
@@ -1044,6 +1060,8 @@ var notificationEmailEventOrder = []string{
 	NotificationEmailEventContentModerationViolation,
 	NotificationEmailEventContentModerationDisabled,
 	NotificationEmailEventCyberPolicyNotice,
+	NotificationEmailEventAPIKeyLeakSuspected,
+	NotificationEmailEventAPIKeyRotated,
 	NotificationEmailEventOpsAlert,
 	NotificationEmailEventOpsScheduledReport,
 }
@@ -1152,6 +1170,24 @@ var notificationEmailEventDefinitions = map[string]NotificationEmailEventInfo{
 		Placeholders: append(append([]string{}, notificationEmailCommonPlaceholders...),
 			"triggered_at", "model", "group_name", "upstream_message"),
 	},
+	NotificationEmailEventAPIKeyLeakSuspected: {
+		Event:       NotificationEmailEventAPIKeyLeakSuspected,
+		Label:       "API key leak suspected",
+		Description: "Sent to users when the leak detector flags one of their API keys and applies their chosen policy.",
+		Category:    "security",
+		Optional:    false,
+		Placeholders: append(append([]string{}, notificationEmailCommonPlaceholders...),
+			"api_key_name", "api_key_prefix", "triggered_at", "leak_signals", "leak_sample_ips", "leak_action"),
+	},
+	NotificationEmailEventAPIKeyRotated: {
+		Event:       NotificationEmailEventAPIKeyRotated,
+		Label:       "API key rotated",
+		Description: "Sent to users when a scheduled rotation replaces one of their API keys.",
+		Category:    "security",
+		Optional:    false,
+		Placeholders: append(append([]string{}, notificationEmailCommonPlaceholders...),
+			"api_key_name", "api_key_prefix", "old_api_key_prefix", "grace_expires_at"),
+	},
 	NotificationEmailEventOpsAlert: {
 		Event:       NotificationEmailEventOpsAlert,
 		Label:       "Ops alert",
@@ -1459,6 +1495,64 @@ var notificationEmailOfficialTemplates = map[string]map[string]notificationEmail
 <p>如认为系误判，可调整请求措辞后重试，或申请获得授权的安全访问权限。</p>`),
 		},
 	},
+	NotificationEmailEventAPIKeyLeakSuspected: {
+		notificationEmailDefaultLocale: {
+			Subject: "[{{site_name}}] Possible leak of API key {{api_key_name}}",
+			HTML: notificationEmailCard("#dc2626", "Possible API key leak", `
+<p>Hello {{recipient_name}},</p>
+<p>Traffic on one of your API keys no longer looks like your usual usage. The key may have been exposed, for example pushed to a public repository.</p>
+<table style="width:100%;border-collapse:collapse;table-layout:fixed;">
+  <tr><td style="width:128px;vertical-align:top;">API key</td><td style="overflow-wrap:anywhere;word-break:break-word;">{{api_key_name}} ({{api_key_prefix}})</td></tr>
+  <tr><td style="width:128px;vertical-align:top;">Detected at</td><td style="overflow-wrap:anywhere;word-break:break-word;">{{triggered_at}}</td></tr>
+  <tr><td style="width:128px;vertical-align:top;">Signals</td><td style="overflow-wrap:anywhere;word-break:break-word;">{{leak_signals}}</td></tr>
+  <tr><td style="width:128px;vertical-align:top;">Source IPs</td><td style="overflow-wrap:anywhere;word-break:break-all;">{{leak_sample_ips}}</td></tr>
+  <tr><td style="width:128px;vertical-align:top;">Action</td><td style="overflow-wrap:anywhere;word-break:break-word;">{{leak_action}}</td></tr>
+</table>
+<p>If you do not recognize this traffic, delete the key and create a new one. You can change how leaks are handled in your API key settings.</p>`),
+		},
+		notificationEmailLocaleChinese: {
+			Subject: "[{{site_name}}] API Key {{api_key_name}} 疑似泄露",
+			HTML: notificationEmailCard("#dc2626", "API Key 疑似泄露", `
+<p>{{recipient_name}}，您好：</p>
+<p>您的一个 API Key 近期的调用特征与平时明显不同，可能已经泄露（例如被提交到公开代码仓库）。</p>
+<table style="width:100%;border-collapse:collapse;table-layout:fixed;">
+  <tr><td style="width:128px;vertical-align:top;">API Key</td><td style="overflow-wrap:anywhere;word-break:break-word;">{{api_key_name}}（{{api_key_prefix}}）</td></tr>
+  <tr><td style="width:128px;vertical-align:top;">检测时间</td><td style="overflow-wrap:anywhere;word-break:break-word;">{{triggered_at}}</td></tr>
+  <tr><td style="width:128px;vertical-align:top;">异常信号</td><td style="overflow-wrap:anywhere;word-break:break-word;">{{leak_signals}}</td></tr>
+  <tr><td style="width:128px;vertical-align:top;">来源 IP</td><td style="overflow-wrap:anywhere;word-break:break-all;">{{leak_sample_ips}}</td></tr>
+  <tr><td style="width:128px;vertical-align:top;">处置结果</td><td style="overflow-wrap:anywhere;word-break:break-word;">{{leak_action}}</td></tr>
+</table>
+<p>如果这些调用并非您本人发起，请删除该 Key 并重新创建。您可以在 API Key 设置中调整泄露处置策略。</p>`),
+		},
+	},
+	NotificationEmailEventAPIKeyRotated: {
+		notificationEmailDefaultLocale: {
+			Subject: "[{{site_name}}] API key {{api_key_name}} has been rotated",
+			HTML: notificationEmailCard("#2563eb", "API key rotated", `
+<p>Hello {{recipient_name}},</p>
+<p>Your API key was rotated on schedule. The new key keeps the same group, quotas, rate limits and IP rules.</p>
+<table style="width:100%;border-collapse:collapse;table-layout:fixed;">
+  <tr><td style="width:128px;vertical-align:top;">API key</td><td style="overflow-wrap:anywhere;word-break:break-word;">{{api_key_name}}</td></tr>
+  <tr><td style="width:128px;vertical-align:top;">New key</td><td style="overflow-wrap:anywhere;word-break:break-word;">{{api_key_prefix}}</td></tr>
+  <tr><td style="width:128px;vertical-align:top;">Old key</td><td style="overflow-wrap:anywhere;word-break:break-word;">{{old_api_key_prefix}}</td></tr>
+  <tr><td style="width:128px;vertical-align:top;">Old key expires</td><td style="overflow-wrap:anywhere;word-break:break-word;">{{grace_expires_at}}</td></tr>
+</table>
+<p>Sign in to view the new key (it can be shown once) and update your clients before the old key expires.</p>`),
+		},
+		notificationEmailLocaleChinese: {
+			Subject: "[{{site_name}}] API Key {{api_key_name}} 已轮换",
+			HTML: notificationEmailCard("#2563eb", "API Key 已轮换", `
+<p>{{recipient_name}}，您好：</p>
+<p>您的 API Key 已按计划完成轮换，新 Key 沿用原有的分组、额度、限流与 IP 规则。</p>
+<table style="width:100%;border-collapse:collapse;table-layout:fixed;">
+  <tr><td style="width:128px;vertical-align:top;">API Key</td><td style="overflow-wrap:anywhere;word-break:break-word;">{{api_key_name}}</td></tr>
+  <tr><td style="width:128px;vertical-align:top;">新 Key</td><td style="overflow-wrap:anywhere;word-break:break-word;">{{api_key_prefix}}</td></tr>
+  <tr><td style="width:128px;vertical-align:top;">旧 Key</td><td style="overflow-wrap:anywhere;word-break:break-word;">{{old_api_key_prefix}}</td></tr>
+  <tr><td style="width:128px;vertical-align:top;">旧 Key 失效时间</td><td style="overflow-wrap:anywhere;word-break:break-word;">{{grace_expires_at}}</td></tr>
+</table>
+<p>请登录控制台查看新 Key（仅可查看一次），并在旧 Key 失效前更新所有客户端。</p>`),
+		},
+	},
 	NotificationEmailEventOpsAlert: {
 		notificationEmailDefaultLocale: {
 			Subject: "[Ops Alert][{{severity}}] {{rule_name}}",
@@ -0,0 +1,401 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/audioduration"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"go.uber.org/zap"
+)
+
+// OpenAI Audio API 端点（/v1/audio/<endpoint>）。
+const (
+	OpenAIAudioEndpointSpeech         = "speech"
+	OpenAIAudioEndpointTranscriptions = "transcriptions"
+	OpenAIAudioEndpointTranslations   = "translations"
+)
+
+// openAIAudioMaxInputChars 对齐 OpenAI /v1/audio/speech 的 input 长度上限。
+const openAIAudioMaxInputChars = 4096
+
+// OpenAIAudioRequest 是解析后的 /v1/audio/* 请求。
+// speech 为 JSON 请求体；transcriptions / translations 为 multipart 上传。
+type OpenAIAudioRequest struct {
+	Endpoint       string
+	Model          string
+	Input          string
+	Voice          string
+	ResponseFormat string
+	Language       string
+
+	FileName        string
+	FileContentType string
+	File            []byte
+	// AudioDuration 由上传文件的容器元数据测得；为 0 表示无法识别。
+	AudioDuration time.Duration
+	AudioFormat   string
+
+	Body        []byte
+	ContentType string
+}
+
+// IsTranscription 表示请求携带待识别的音频文件（transcriptions / translations）。
+func (r *OpenAIAudioRequest) IsTranscription() bool {
+	return r != nil && r.Endpoint != OpenAIAudioEndpointSpeech
+}
+
+// ParseOpenAIAudioRequest 解析并校验 /v1/audio/* 请求。返回的错误信息可直接回给客户端。
+func ParseOpenAIAudioRequest(endpoint string, body []byte, contentType string) (*OpenAIAudioRequest, error) {
+	req := &OpenAIAudioRequest{Endpoint: endpoint, Body: body, ContentType: contentType}
+	switch endpoint {
+	case OpenAIAudioEndpointSpeech:
+		if !gjson.ValidBytes(body) {
+			return nil, errors.New("request body must be valid JSON")
+		}
+		req.Model = strings.TrimSpace(gjson.GetBytes(body, "model").String())
+		req.Input = gjson.GetBytes(body, "input").String()
+		req.Voice = strings.TrimSpace(gjson.GetBytes(body, "voice").String())
+		req.ResponseFormat = strings.ToLower(strings.TrimSpace(gjson.GetBytes(body, "response_format").String()))
+		if strings.TrimSpace(req.Input) == "" {
+			return nil, errors.New("input is required")
+		}
+		if len([]rune(req.Input)) > openAIAudioMaxInputChars {
+			return nil, fmt.Errorf("input must be at most %d characters", openAIAudioMaxInputChars)
+		}
+	case OpenAIAudioEndpointTranscriptions, OpenAIAudioEndpointTranslations:
+		if err := parseOpenAIAudioMultipart(body, contentType, req); err != nil {
+			return nil, err
+		}
+		if len(req.File) == 0 {
+			return nil, errors.New("file is required")
+		}
+		if duration, format, err := audioduration.Probe(req.File); err == nil {
+			req.AudioDuration = duration
+			req.AudioFormat = format
+		} else {
+			req.AudioFormat = format
+		}
+	default:
+		return nil, fmt.Errorf("unsupported audio endpoint: %s", endpoint)
+	}
+	if req.Model == "" {
+		return nil, errors.New("model is required")
+	}
+	return req, nil
+}
+
+// ReplaceModel 把请求中的 model 改写为 model（渠道模型映射），同步更新请求体。
+func (r *OpenAIAudioRequest) ReplaceModel(model string) error {
+	body, contentType, err := r.bodyWithModel(model)
+	if err != nil {
+		return err
+	}
+	r.Model, r.Body, r.ContentType = model, body, contentType
+	return nil
+}
+
+func (r *OpenAIAudioRequest) bodyWithModel(model string) ([]byte, string, error) {
+	if model == "" || model == r.Model {
+		return r.Body, r.ContentType, nil
+	}
+	if !r.IsTranscription() {
+		return ReplaceModelInBody(r.Body, model), r.ContentType, nil
+	}
+	body, contentType, err := rewriteOpenAIImagesMultipartModel(r.Body, r.ContentType, model)
+	if err != nil {
+		return nil, "", fmt.Errorf("rewrite audio request model: %w", err)
+	}
+	return body, contentType, nil
+}
+
+func parseOpenAIAudioMultipart(body []byte, contentType string, req *OpenAIAudioRequest) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.EqualFold(mediaType, "multipart/form-data") {
+		return errors.New("content-type must be multipart/form-data")
+	}
+	boundary := strings.TrimSpace(params["boundary"])
+	if boundary == "" {
+		return errors.New("multipart boundary is required")
+	}
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid multipart body: %w", err)
+		}
+		data, err := io.ReadAll(part)
+		_ = part.Close()
+		if err != nil {
+			return fmt.Errorf("invalid multipart body: %w", err)
+		}
+		switch part.FormName() {
+		case "file":
+			req.File = data
+			req.FileName = part.FileName()
+			req.FileContentType = part.Header.Get("Content-Type")
+		case "model":
+			req.Model = strings.TrimSpace(string(data))
+		case "language":
+			req.Language = strings.TrimSpace(string(data))
+		case "response_format":
+			req.ResponseFormat = strings.ToLower(strings.TrimSpace(string(data)))
+		}
+	}
+}
+
+// openAIAudioUsage 计算音频计费单位：speech 按输入字符（百万字符），
+// 转写/翻译按时长（小时）。时长优先取容器元数据，其次取上游 verbose_json
+// 返回的 duration，最后按文件大小下限估算（约 16KB/s 的压缩语音）。
+func openAIAudioUsage(req *OpenAIAudioRequest, respBody []byte) *AudioUsage {
+	if req == nil {
+		return nil
+	}
+	if !req.IsTranscription() {
+		chars := len([]rune(req.Input))
+		if chars <= 0 {
+			return nil
+		}
+		return &AudioUsage{Mode: "tts", DurationOrUnits: float64(chars) / 1_000_000.0}
+	}
+	secs := req.AudioDuration.Seconds()
+	if secs <= 0 && gjson.ValidBytes(respBody) {
+		if v := gjson.GetBytes(respBody, "duration"); v.Type == gjson.Number && v.Float() > 0 {
+			secs = v.Float()
+		}
+	}
+	if secs <= 0 {
+		secs = float64(len(req.File)) / 16000.0
+	}
+	if secs <= 0 {
+		return nil
+	}
+	return &AudioUsage{Mode: "stt", DurationOrUnits: secs / 3600.0}
+}
+
+// ForwardAudio 将 OpenAI Audio 请求透传到 OpenAI API Key 账号。
+// speech 返回音频字节，转写返回 JSON/文本，统一原样写回客户端。
+func (s *OpenAIGatewayService) ForwardAudio(
+	ctx context.Context,
+	c *gin.Context,
+	account *Account,
+	req *OpenAIAudioRequest,
+) (*OpenAIForwardResult, error) {
+	startTime := time.Now()
+	if req == nil || account == nil {
+		return nil, errors.New("audio request and account are required")
+	}
+
+	billingModel := resolveOpenAIForwardModel(account, req.Model, "")
+	upstreamModel := normalizeOpenAIModelForUpstream(account, billingModel)
+	upstreamBody, upstreamContentType, err := req.bodyWithModel(upstreamModel)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(upstreamContentType) == "" {
+		upstreamContentType = "application/json"
+	}
+
+	logger.L().Debug("openai audio: forwarding",
+		zap.Int64("account_id", account.ID),
+		zap.String("endpoint", req.Endpoint),
+		zap.String("original_model", req.Model),
+		zap.String("upstream_model", upstreamModel),
+		zap.Duration("audio_duration", req.AudioDuration),
+	)
+
+	apiKey := account.GetOpenAIApiKey()
+	if apiKey == "" {
+		return nil, fmt.Errorf("account %d missing api_key", account.ID)
+	}
+	baseURL := account.GetOpenAIBaseURL()
+	if baseURL == "" {
+		baseURL = "https://api.openai.com"
+	}
+	validatedURL, err := s.validateUpstreamBaseURL(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base_url: %w", err)
+	}
+	targetURL := buildOpenAIEndpointURL(validatedURL, "/v1/audio/"+req.Endpoint)
+
+	upstreamCtx, releaseUpstreamCtx := detachUpstreamContext(ctx)
+	upstreamReq, err := http.NewRequestWithContext(upstreamCtx, http.MethodPost, targetURL, bytes.NewReader(upstreamBody))
+	releaseUpstreamCtx()
+	if err != nil {
+		return nil, fmt.Errorf("build upstream request: %w", err)
+	}
+	upstreamReq = upstreamReq.WithContext(WithHTTPUpstreamProfile(upstreamReq.Context(), HTTPUpstreamProfileOpenAI))
+	upstreamReq.Header.Set("Content-Type", upstreamContentType)
+	upstreamReq.Header.Set("Authorization", "Bearer "+apiKey)
+	upstreamReq.Header.Set("Accept", "application/json, audio/*, text/plain")
+	for key, values := range c.Request.Header {
+		if openaiCCRawAllowedHeaders[strings.ToLower(key)] {
+			for _, v := range values {
+				upstreamReq.Header.Add(key, v)
+			}
+		}
+	}
+	if customUA := account.GetOpenAIUserAgent(); customUA != "" {
+		upstreamReq.Header.Set("user-agent", customUA)
+	}
+	account.ApplyHeaderOverrides(upstreamReq.Header)
+
+	proxyURL := ""
+	if account.Proxy != nil {
+		proxyURL = account.Proxy.URL()
+	}
+	resp, err := s.httpUpstream.Do(upstreamReq, proxyURL, account.ID, account.Concurrency)
+	SetOpsLatencyMs(c, OpsUpstreamLatencyMsKey, time.Since(startTime).Milliseconds())
+	if err != nil {
+		safeErr := sanitizeUpstreamErrorMessage(err.Error())
+		setOpsUpstreamError(c, 0, safeErr, "")
+		appendOpsUpstreamError(c, OpsUpstreamErrorEvent{
+			Platform:           account.Platform,
+			AccountID:          account.ID,
+			AccountName:        account.Name,
+			UpstreamStatusCode: 0,
+			Kind:               "request_error",
+			Message:            safeErr,
+		})
+		writeOpenAIEmbeddingsError(c, http.StatusBadGateway, "upstream_error", "Upstream request failed")
+		return nil, fmt.Errorf("upstream request failed: %s", safeErr)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		respBody := s.readUpstreamErrorBody(resp)
+		upstreamMsg := sanitizeUpstreamErrorMessage(strings.TrimSpace(extractUpstreamErrorMessage(respBody)))
+		if s.shouldFailoverOpenAIUpstreamResponse(resp.StatusCode, upstreamMsg, respBody) {
+			appendOpsUpstreamError(c, OpsUpstreamErrorEvent{
+				Platform:           account.Platform,
+				AccountID:          account.ID,
+				AccountName:        account.Name,
+				UpstreamStatusCode: resp.StatusCode,
+				UpstreamRequestID:  resp.Header.Get("x-request-id"),
+				Kind:               "failover",
+				Message:            upstreamMsg,
+			})
+			shouldDisable := s.handleOpenAIAccountUpstreamError(ctx, account, resp.StatusCode, resp.Header, respBody, upstreamModel)
+			return nil, &UpstreamFailoverError{
+				StatusCode:             resp.StatusCode,
+				ResponseBody:           respBody,
+				RetryableOnSameAccount: !shouldDisable && account.IsPoolMode() && account.IsPoolModeRetryableStatus(resp.StatusCode),
+			}
+		}
+		writeOpenAIEmbeddingsUpstreamResponse(c, resp, respBody, s.responseHeaderFilter)
+		return nil, fmt.Errorf("upstream returned status %d", resp.StatusCode)
+	}
+
+	respBody, err := ReadUpstreamResponseBody(resp.Body, s.cfg, c, openAITooLargeError)
+	if err != nil {
+		if !errors.Is(err, ErrUpstreamResponseBodyTooLarge) {
+			writeOpenAIEmbeddingsError(c, http.StatusBadGateway, "api_error", "Failed to read upstream response")
+		}
+		return nil, fmt.Errorf("read upstream body: %w", err)
+	}
+	writeOpenAIEmbeddingsUpstreamResponse(c, resp, respBody, s.responseHeaderFilter)
+
+	return &OpenAIForwardResult{
+		RequestID:     firstNonEmptyString(resp.Header.Get("x-request-id"), resp.Header.Get("request-id")),
+		Model:         req.Model,
+		BillingModel:  billingModel,
+		UpstreamModel: upstreamModel,
+		Duration:      time.Since(startTime),
+		AudioUsage:    openAIAudioUsage(req, respBody),
+	}, nil
+}
+
+var multipartQuoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// grokAudioTTSCodecs 是 xAI TTS output_format.codec 支持的格式。
+var grokAudioTTSCodecs = map[string]struct{}{"mp3": {}, "wav": {}, "pcm": {}}
+
+// BuildGrokVoiceAudioRequest 把 OpenAI Audio 请求映射为 xAI Voice 请求：
+// speech → /tts（JSON），transcriptions → /stt（multipart，仅保留 file 与 language）。
+// 返回 Grok 端点名、请求体与 Content-Type；不支持的组合返回可回给客户端的错误。
+func BuildGrokVoiceAudioRequest(req *OpenAIAudioRequest) (string, []byte, string, error) {
+	if req == nil {
+		return "", nil, "", errors.New("audio request is required")
+	}
+	switch req.Endpoint {
+	case OpenAIAudioEndpointSpeech:
+		payload := map[string]any{"text": req.Input}
+		if req.Voice != "" {
+			payload["voice_id"] = req.Voice
+		}
+		if language := strings.TrimSpace(gjson.GetBytes(req.Body, "language").String()); language != "" {
+			payload["language"] = language
+		}
+		if req.ResponseFormat != "" {
+			if _, ok := grokAudioTTSCodecs[req.ResponseFormat]; !ok {
+				return "", nil, "", fmt.Errorf("response_format %q is not supported by Grok voice (use mp3, wav or pcm)", req.ResponseFormat)
+			}
+			payload["output_format"] = map[string]any{"codec": req.ResponseFormat}
+		}
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return "", nil, "", err
+		}
+		return "tts", body, "application/json", nil
+	case OpenAIAudioEndpointTranscriptions:
+		if req.ResponseFormat != "" && req.ResponseFormat != "json" && req.ResponseFormat != "verbose_json" {
+			return "", nil, "", fmt.Errorf("response_format %q is not supported by Grok voice (use json or verbose_json)", req.ResponseFormat)
+		}
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		header := make(textproto.MIMEHeader)
+		fileName := multipartQuoteEscaper.Replace(firstNonEmptyString(req.FileName, "audio"))
+		header.Set("Content-Disposition", `form-data; name="file"; filename="`+fileName+`"`)
+		header.Set("Content-Type", firstNonEmptyString(req.FileContentType, "application/octet-stream"))
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return "", nil, "", err
+		}
+		if _, err := part.Write(req.File); err != nil {
+			return "", nil, "", err
+		}
+		if req.Language != "" {
+			if err := writer.WriteField("language", req.Language); err != nil {
+				return "", nil, "", err
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return "", nil, "", err
+		}
+		return "stt", buf.Bytes(), writer.FormDataContentType(), nil
+	default:
+		return "", nil, "", fmt.Errorf("audio %s is not supported by Grok voice", req.Endpoint)
+	}
+}
+
+// ForwardGrokAudio 通过 xAI Voice 后端服务 OpenAI Audio 请求。
+// 转写计费以容器测得的时长为准，覆盖 ForwardGrokVoice 基于耗时/大小的估算。
+func (s *OpenAIGatewayService) ForwardGrokAudio(ctx context.Context, c *gin.Context, account *Account, req *OpenAIAudioRequest) (*OpenAIForwardResult, error) {
+	endpoint, body, contentType, err := BuildGrokVoiceAudioRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.ForwardGrokVoice(ctx, c, account, endpoint, body, contentType)
+	if err != nil || result == nil {
+		return result, err
+	}
+	if req.IsTranscription() && req.AudioDuration > 0 {
+		result.AudioUsage = &AudioUsage{Mode: "stt", DurationOrUnits: req.AudioDuration.Hours()}
+	}
+	return result, nil
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// testWAV 生成 16kHz/16bit/单声道的静音 WAV。
+func testWAV(seconds int) []byte {
+	const byteRate = 32000
+	data := make([]byte, byteRate*seconds)
+	header := []byte("RIFF")
+	header = binary.LittleEndian.AppendUint32(header, uint32(36+len(data)))
+	header = append(header, "WAVEfmt "...)
+	header = binary.LittleEndian.AppendUint32(header, 16)
+	header = binary.LittleEndian.AppendUint16(header, 1)
+	header = binary.LittleEndian.AppendUint16(header, 1)
+	header = binary.LittleEndian.AppendUint32(header, 16000)
+	header = binary.LittleEndian.AppendUint32(header, byteRate)
+	header = binary.LittleEndian.AppendUint16(header, 2)
+	header = binary.LittleEndian.AppendUint16(header, 16)
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(data)))
+	return append(header, data...)
+}
+
+func testAudioMultipart(t *testing.T, fields map[string]string, file []byte) ([]byte, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for name, value := range fields {
+		require.NoError(t, writer.WriteField(name, value))
+	}
+	if file != nil {
+		part, err := writer.CreateFormFile("file", "clip.wav")
+		require.NoError(t, err)
+		_, err = part.Write(file)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	return buf.Bytes(), writer.FormDataContentType()
+}
+
+func TestParseOpenAIAudioRequest(t *testing.T) {
+	req, err := ParseOpenAIAudioRequest(OpenAIAudioEndpointSpeech, []byte(`{"model":"tts-1","input":"你好","voice":"alloy"}`), "application/json")
+	require.NoError(t, err)
+	require.Equal(t, "tts-1", req.Model)
+	require.False(t, req.IsTranscription())
+	require.Equal(t, &AudioUsage{Mode: "tts", DurationOrUnits: 2 / 1_000_000.0}, openAIAudioUsage(req, nil))
+
+	body, contentType := testAudioMultipart(t, map[string]string{"model": "whisper-1", "language": "en"}, testWAV(90))
+	req, err = ParseOpenAIAudioRequest(OpenAIAudioEndpointTranscriptions, body, contentType)
+	require.NoError(t, err)
+	require.Equal(t, "whisper-1", req.Model)
+	require.Equal(t, "en", req.Language)
+	require.Equal(t, 90*time.Second, req.AudioDuration)
+	require.Equal(t, "wav", req.AudioFormat)
+	usage := openAIAudioUsage(req, []byte(`{"text":"hi","duration":1}`))
+	require.Equal(t, "stt", usage.Mode)
+	require.InDelta(t, 90.0/3600.0, usage.DurationOrUnits, 1e-9)
+
+	for _, tc := range []struct {
+		endpoint, contentType string
+		body                  []byte
+	}{
+		{OpenAIAudioEndpointSpeech, "application/json", []byte(`{"model":"tts-1"}`)},
+		{OpenAIAudioEndpointSpeech, "application/json", []byte(`{"input":"x"}`)},
+		{OpenAIAudioEndpointTranscriptions, "application/json", []byte(`{"model":"whisper-1"}`)},
+		{"voices", "application/json", []byte(`{}`)},
+	} {
+		_, err := ParseOpenAIAudioRequest(tc.endpoint, tc.body, tc.contentType)
+		require.Error(t, err, string(tc.body))
+	}
+	body, contentType = testAudioMultipart(t, map[string]string{"model": "whisper-1"}, nil)
+	_, err = ParseOpenAIAudioRequest(OpenAIAudioEndpointTranslations, body, contentType)
+	require.EqualError(t, err, "file is required")
+}
+
+func TestOpenAIAudioUsage_FallsBackWhenContainerUnknown(t *testing.T) {
+	req := &OpenAIAudioRequest{Endpoint: OpenAIAudioEndpointTranscriptions, File: make([]byte, 32000)}
+	require.InDelta(t, 12.5/3600.0, openAIAudioUsage(req, []byte(`{"duration":12.5}`)).DurationOrUnits, 1e-9)
+	require.InDelta(t, 2.0/3600.0, openAIAudioUsage(req, []byte(`{"text":"hi"}`)).DurationOrUnits, 1e-9)
+}
+
+func TestForwardAudio_TranscriptionRewritesModelAndBillsMeasuredDuration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body, contentType := testAudioMultipart(t, map[string]string{"model": "stt-alias"}, testWAV(30))
+	req, err := ParseOpenAIAudioRequest(OpenAIAudioEndpointTranscriptions, body, contentType)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/audio/transcriptions", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", contentType)
+
+	upstream := &httpUpstreamRecorder{resp: &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}, "X-Request-Id": []string{"aud-rid"}},
+		Body:       io.NopCloser(strings.NewReader(`{"text":"hello"}`)),
+	}}
+	svc := &OpenAIGatewayService{cfg: &config.Config{}, httpUpstream: upstream}
+	account := &Account{
+		ID:       7,
+		Platform: PlatformOpenAI,
+		Type:     AccountTypeAPIKey,
+		Credentials: map[string]any{
+			"api_key":       "sk-test",
+			"model_mapping": map[string]any{"stt-alias": "whisper-1"},
+		},
+	}
+
+	result, err := svc.ForwardAudio(context.Background(), c, account, req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"text":"hello"}`, rec.Body.String())
+	require.Equal(t, "aud-rid", result.RequestID)
+	require.Equal(t, "stt-alias", result.Model)
+	require.Equal(t, "whisper-1", result.UpstreamModel)
+	require.Equal(t, &AudioUsage{Mode: "stt", DurationOrUnits: 30.0 / 3600.0}, result.AudioUsage)
+	require.Equal(t, "https://api.openai.com/v1/audio/transcriptions", upstream.lastReq.URL.String())
+
+	_, params, err := mime.ParseMediaType(upstream.lastReq.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	form, err := multipart.NewReader(bytes.NewReader(upstream.lastBody), params["boundary"]).ReadForm(1 << 20)
+	require.NoError(t, err)
+	require.Equal(t, []string{"whisper-1"}, form.Value["model"])
+	require.Len(t, form.File["file"], 1)
+}
+
+func TestBuildGrokVoiceAudioRequest(t *testing.T) {
+	req, err := ParseOpenAIAudioRequest(OpenAIAudioEndpointSpeech, []byte(`{"model":"tts-1","input":"hi","voice":"eve","response_format":"wav"}`), "application/json")
+	require.NoError(t, err)
+	endpoint, body, contentType, err := BuildGrokVoiceAudioRequest(req)
+	require.NoError(t, err)
+	require.Equal(t, "tts", endpoint)
+	require.Equal(t, "application/json", contentType)
+	require.JSONEq(t, `{"text":"hi","voice_id":"eve","output_format":{"codec":"wav"}}`, string(body))
+
+	req.ResponseFormat = "opus"
+	_, _, _, err = BuildGrokVoiceAudioRequest(req)
+	require.Error(t, err)
+
+	upload, uploadType := testAudioMultipart(t, map[string]string{"model": "whisper-1", "language": "de", "temperature": "0.2"}, testWAV(1))
+	req, err = ParseOpenAIAudioRequest(OpenAIAudioEndpointTranscriptions, upload, uploadType)
+	require.NoError(t, err)
+	endpoint, body, contentType, err = BuildGrokVoiceAudioRequest(req)
+	require.NoError(t, err)
+	require.Equal(t, "stt", endpoint)
+	_, params, err := mime.ParseMediaType(contentType)
+	require.NoError(t, err)
+	form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(1 << 20)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"language": {"de"}}, form.Value)
+	require.Equal(t, "clip.wav", form.File["file"][0].Filename)
+
+	req.Endpoint = OpenAIAudioEndpointTranslations
+	_, _, _, err = BuildGrokVoiceAudioRequest(req)
+	require.Error(t, err)
+}
@@ -0,0 +1,485 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"go.uber.org/zap"
+)
+
+const (
+	BackgroundResponseStatusQueued     = "queued"
+	BackgroundResponseStatusInProgress = "in_progress"
+	BackgroundResponseStatusCompleted  = "completed"
+	BackgroundResponseStatusFailed     = "failed"
+	BackgroundResponseStatusIncomplete = "incomplete"
+	BackgroundResponseStatusCancelled  = "cancelled"
+
+	// backgroundResponseIDPrefix 区分网关托管的后台响应与上游原生响应 ID。
+	backgroundResponseIDPrefix = "resp_bg"
+
+	defaultBackgroundResponseTTL              = 24 * time.Hour
+	defaultBackgroundResponseExecutionTimeout = time.Hour
+)
+
+var (
+	ErrBackgroundResponseNotFound    = infraerrors.New(http.StatusNotFound, "RESPONSE_NOT_FOUND", "response not found")
+	ErrBackgroundResponseUnavailable = infraerrors.New(http.StatusServiceUnavailable, "BACKGROUND_RESPONSE_UNAVAILABLE", "background response storage is unavailable")
+)
+
+// BackgroundResponseRecord 是网关托管的后台 Responses 任务在 Redis 中的私有表示。
+//
+// 执行期间事件逐条追加到 Redis 事件列表；进入终态后完整响应与事件流转存到对象存储，
+// Record 只保留不含 output 的摘要与对象 key，从而任意实例都能提供查询与续流。
+type BackgroundResponseRecord struct {
+	ID          string          `json:"id"`
+	UserID      int64           `json:"user_id"`
+	APIKeyID    int64           `json:"api_key_id"`
+	Model       string          `json:"model"`
+	Status      string          `json:"status"`
+	Response    json.RawMessage `json:"response,omitempty"`
+	EventCount  int64           `json:"event_count"`
+	ResponseKey string          `json:"response_key,omitempty"`
+	EventsKey   string          `json:"events_key,omitempty"`
+	CreatedAt   int64           `json:"created_at"`
+	CompletedAt *int64          `json:"completed_at,omitempty"`
+	ExpiresAt   int64           `json:"expires_at"`
+}
+
+// Terminal 表示任务是否已结束（不会再追加事件）。
+func (r *BackgroundResponseRecord) Terminal() bool {
+	return r != nil && IsBackgroundResponseTerminalStatus(r.Status)
+}
+
+type BackgroundResponseOwner struct {
+	UserID   int64
+	APIKeyID int64
+}
+
+// BackgroundResponseStore 持久化后台响应的状态、事件与取消标记。
+// 取消标记单独存放：它由任意实例写入，而 Record 只由执行任务的 worker 改写。
+type BackgroundResponseStore interface {
+	Save(ctx context.Context, record *BackgroundResponseRecord, ttl time.Duration) error
+	Get(ctx context.Context, id string) (*BackgroundResponseRecord, error)
+	AppendEvents(ctx context.Context, id string, events []json.RawMessage, ttl time.Duration) error
+	ListEvents(ctx context.Context, id string, from int64) ([]json.RawMessage, error)
+	DeleteEvents(ctx context.Context, id string) error
+	RequestCancel(ctx context.Context, id string, ttl time.Duration) error
+	CancelRequested(ctx context.Context, id string) (bool, error)
+}
+
+// BackgroundResponseService 管理 `background: true` 的 /v1/responses 请求。
+//
+// 与异步图片任务一样，对象存储是启用前提：终态响应与事件流必须落到对象存储，
+// 未配置时请求照常透传上游（由上游自行处理 background）。
+type BackgroundResponseService struct {
+	store            BackgroundResponseStore
+	resolve          ImageStorageResolver
+	ttl              time.Duration
+	executionTimeout time.Duration
+}
+
+func NewBackgroundResponseService(store BackgroundResponseStore, resolve ImageStorageResolver, ttl, executionTimeout time.Duration) *BackgroundResponseService {
+	if ttl <= 0 {
+		ttl = defaultBackgroundResponseTTL
+	}
+	if executionTimeout <= 0 {
+		executionTimeout = defaultBackgroundResponseExecutionTimeout
+	}
+	return &BackgroundResponseService{store: store, resolve: resolve, ttl: ttl, executionTimeout: executionTimeout}
+}
+
+// IsBackgroundResponseID 判断 id 是否为网关签发的后台响应 ID。
+func IsBackgroundResponseID(id string) bool {
+	id = strings.TrimSpace(id)
+	return len(id) > len(backgroundResponseIDPrefix) && strings.HasPrefix(id, backgroundResponseIDPrefix)
+}
+
+// IsBackgroundResponseTerminalStatus 判断状态是否为终态。
+func IsBackgroundResponseTerminalStatus(status string) bool {
+	switch status {
+	case BackgroundResponseStatusCompleted, BackgroundResponseStatusFailed, BackgroundResponseStatusIncomplete, BackgroundResponseStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (s *BackgroundResponseService) uploader() (*ImageResultUploader, bool) {
+	if s == nil || s.resolve == nil {
+		return nil, false
+	}
+	return s.resolve()
+}
+
+// Enabled 表示新的后台请求能否由网关托管执行。
+func (s *BackgroundResponseService) Enabled() bool {
+	if s == nil || s.store == nil {
+		return false
+	}
+	uploader, enabled := s.uploader()
+	return enabled && uploader != nil
+}
+
+// Pollable 表示已受理的任务能否被查询；功能关闭后仍可取回进行中的任务。
+func (s *BackgroundResponseService) Pollable() bool {
+	return s != nil && s.store != nil
+}
+
+func (s *BackgroundResponseService) ExecutionTimeout() time.Duration {
+	if s == nil || s.executionTimeout <= 0 {
+		return defaultBackgroundResponseExecutionTimeout
+	}
+	return s.executionTimeout
+}
+
+// Create 受理一个后台请求并返回 queued 状态的响应对象。
+func (s *BackgroundResponseService) Create(ctx context.Context, owner BackgroundResponseOwner, model string) (*BackgroundResponseRecord, error) {
+	if s == nil || s.store == nil {
+		return nil, ErrBackgroundResponseUnavailable
+	}
+	now := time.Now().UTC()
+	record := &BackgroundResponseRecord{
+		ID:        backgroundResponseIDPrefix + strings.ReplaceAll(uuid.NewString(), "-", ""),
+		UserID:    owner.UserID,
+		APIKeyID:  owner.APIKeyID,
+		Model:     model,
+		Status:    BackgroundResponseStatusQueued,
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(s.ttl).Unix(),
+	}
+	record.Response = backgroundResponseSkeleton(record)
+	if err := s.store.Save(ctx, record, s.ttl); err != nil {
+		return nil, ErrBackgroundResponseUnavailable.WithCause(err)
+	}
+	return record, nil
+}
+
+// Get 返回调用方自己的后台任务；不属于调用方的 ID 一律视为不存在。
+func (s *BackgroundResponseService) Get(ctx context.Context, owner BackgroundResponseOwner, id string) (*BackgroundResponseRecord, error) {
+	if s == nil || s.store == nil {
+		return nil, ErrBackgroundResponseUnavailable
+	}
+	id = strings.TrimSpace(id)
+	if !IsBackgroundResponseID(id) {
+		return nil, ErrBackgroundResponseNotFound
+	}
+	record, err := s.store.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrBackgroundResponseNotFound) {
+			return nil, ErrBackgroundResponseNotFound
+		}
+		return nil, ErrBackgroundResponseUnavailable.WithCause(err)
+	}
+	if record.UserID != owner.UserID || record.APIKeyID != owner.APIKeyID {
+		return nil, ErrBackgroundResponseNotFound
+	}
+	if !record.Terminal() {
+		if cancelled, err := s.store.CancelRequested(ctx, id); err == nil && cancelled {
+			// worker 可能在其他实例上尚未观察到取消，对外先按已取消呈现。
+			record.Status = BackgroundResponseStatusCancelled
+			record.Response = withBackgroundResponseStatus(record.Response, BackgroundResponseStatusCancelled)
+		}
+	}
+	return record, nil
+}
+
+// ResponseObject 返回任务当前的完整响应对象：终态从对象存储读回，执行中返回最新快照。
+func (s *BackgroundResponseService) ResponseObject(ctx context.Context, record *BackgroundResponseRecord) (json.RawMessage, error) {
+	if record == nil {
+		return nil, ErrBackgroundResponseNotFound
+	}
+	if record.ResponseKey == "" {
+		return record.Response, nil
+	}
+	uploader, _ := s.uploader()
+	data, err := uploader.LoadObject(ctx, record.ResponseKey)
+	if err != nil {
+		return nil, ErrBackgroundResponseUnavailable.WithCause(err)
+	}
+	return data, nil
+}
+
+// Events 返回 sequence_number 大于 startingAfter 的事件。
+func (s *BackgroundResponseService) Events(ctx context.Context, record *BackgroundResponseRecord, startingAfter int64) ([]json.RawMessage, error) {
+	if record == nil {
+		return nil, ErrBackgroundResponseNotFound
+	}
+	from := startingAfter + 1
+	if from < 0 {
+		from = 0
+	}
+	if record.EventsKey == "" {
+		events, err := s.store.ListEvents(ctx, record.ID, from)
+		if err != nil {
+			return nil, ErrBackgroundResponseUnavailable.WithCause(err)
+		}
+		return events, nil
+	}
+	uploader, _ := s.uploader()
+	data, err := uploader.LoadObject(ctx, record.EventsKey)
+	if err != nil {
+		return nil, ErrBackgroundResponseUnavailable.WithCause(err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	if from >= int64(len(lines)) {
+		return nil, nil
+	}
+	events := make([]json.RawMessage, 0, int64(len(lines))-from)
+	for _, line := range lines[from:] {
+		if len(line) > 0 {
+			events = append(events, json.RawMessage(line))
+		}
+	}
+	return events, nil
+}
+
+// Cancel 请求取消调用方的后台任务；已结束的任务原样返回。
+func (s *BackgroundResponseService) Cancel(ctx context.Context, owner BackgroundResponseOwner, id string) (*BackgroundResponseRecord, error) {
+	record, err := s.Get(ctx, owner, id)
+	if err != nil {
+		return nil, err
+	}
+	if record.Terminal() {
+		return record, nil
+	}
+	if err := s.store.RequestCancel(ctx, record.ID, s.ttl); err != nil {
+		return nil, ErrBackgroundResponseUnavailable.WithCause(err)
+	}
+	record.Status = BackgroundResponseStatusCancelled
+	record.Response = withBackgroundResponseStatus(record.Response, BackgroundResponseStatusCancelled)
+	return record, nil
+}
+
+// CancelRequested 供 worker 轮询取消标记。
+func (s *BackgroundResponseService) CancelRequested(ctx context.Context, id string) bool {
+	if s == nil || s.store == nil {
+		return false
+	}
+	cancelled, err := s.store.CancelRequested(ctx, id)
+	return err == nil && cancelled
+}
+
+// BackgroundResponseRun 是 worker 侧的写入器：为事件编号、改写响应 ID 并追加到事件列表。
+// 同一任务只有一个 run，因此序号与快照在内存中维护即可。
+type BackgroundResponseRun struct {
+	svc     *BackgroundResponseService
+	record  *BackgroundResponseRecord
+	final   json.RawMessage
+	failure json.RawMessage
+}
+
+// Start 把任务标记为 in_progress 并返回写入器。
+func (s *BackgroundResponseService) Start(ctx context.Context, id string) (*BackgroundResponseRun, error) {
+	if s == nil || s.store == nil {
+		return nil, ErrBackgroundResponseUnavailable
+	}
+	record, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, ErrBackgroundResponseUnavailable.WithCause(err)
+	}
+	record.Status = BackgroundResponseStatusInProgress
+	record.Response = withBackgroundResponseStatus(record.Response, BackgroundResponseStatusInProgress)
+	if err := s.store.Save(ctx, record, s.ttl); err != nil {
+		return nil, ErrBackgroundResponseUnavailable.WithCause(err)
+	}
+	return &BackgroundResponseRun{svc: s, record: record}, nil
+}
+
+// Append 追加一批上游 SSE 事件（data 部分的 JSON）。
+func (r *BackgroundResponseRun) Append(ctx context.Context, payloads []json.RawMessage) error {
+	if len(payloads) == 0 {
+		return nil
+	}
+	events := make([]json.RawMessage, 0, len(payloads))
+	snapshotChanged := false
+	for _, payload := range payloads {
+		if !gjson.ValidBytes(payload) {
+			continue
+		}
+		event := r.rewriteEvent(payload)
+		events = append(events, event)
+		r.record.EventCount++
+
+		eventType := gjson.GetBytes(event, "type").String()
+		if response := gjson.GetBytes(event, "response"); response.IsObject() {
+			switch eventType {
+			case "response.completed", "response.failed", "response.incomplete":
+				r.final = json.RawMessage(response.Raw)
+			default:
+				r.record.Response = backgroundResponseSummary(json.RawMessage(response.Raw))
+				snapshotChanged = true
+			}
+		}
+		if eventType == "error" {
+			if errObj := gjson.GetBytes(event, "error"); errObj.IsObject() {
+				r.failure = json.RawMessage(errObj.Raw)
+			} else {
+				r.failure = backgroundResponseErrorJSON(gjson.GetBytes(event, "code").String(), gjson.GetBytes(event, "message").String())
+			}
+		}
+	}
+	if err := r.svc.store.AppendEvents(ctx, r.record.ID, events, r.svc.ttl); err != nil {
+		return err
+	}
+	if snapshotChanged {
+		return r.svc.store.Save(ctx, r.record, r.svc.ttl)
+	}
+	return nil
+}
+
+// Final 返回上游终态事件携带的响应对象（未收到时为 nil）。
+func (r *BackgroundResponseRun) Final() json.RawMessage { return r.final }
+
+// Failure 返回上游 error 事件携带的错误对象（未收到时为 nil）。
+func (r *BackgroundResponseRun) Failure() json.RawMessage { return r.failure }
+
+func (r *BackgroundResponseRun) rewriteEvent(payload json.RawMessage) json.RawMessage {
+	event := []byte(payload)
+	if gjson.GetBytes(event, "response").IsObject() {
+		event, _ = sjson.SetBytes(event, "response.id", r.record.ID)
+		event, _ = sjson.SetBytes(event, "response.background", true)
+	}
+	event, _ = sjson.SetBytes(event, "sequence_number", r.record.EventCount)
+	return event
+}
+
+// Complete 以上游终态响应结束任务。
+func (r *BackgroundResponseRun) Complete(ctx context.Context, response json.RawMessage) error {
+	status := gjson.GetBytes(response, "status").String()
+	if !IsBackgroundResponseTerminalStatus(status) {
+		status = BackgroundResponseStatusCompleted
+	}
+	return r.finish(ctx, status, response, nil)
+}
+
+// Fail 以错误结束任务。
+func (r *BackgroundResponseRun) Fail(ctx context.Context, taskErr json.RawMessage) error {
+	if !json.Valid(taskErr) {
+		taskErr = backgroundResponseErrorJSON("server_error", "background response failed")
+	}
+	return r.finish(ctx, BackgroundResponseStatusFailed, nil, taskErr)
+}
+
+// Cancelled 以 cancelled 结束任务。
+func (r *BackgroundResponseRun) Cancelled(ctx context.Context) error {
+	return r.finish(ctx, BackgroundResponseStatusCancelled, nil, nil)
+}
+
+func (r *BackgroundResponseRun) finish(ctx context.Context, status string, response, taskErr json.RawMessage) error {
+	record := r.record
+	if len(response) == 0 || !gjson.ValidBytes(response) {
+		response = withBackgroundResponseStatus(record.Response, status)
+		if len(taskErr) > 0 {
+			response, _ = sjson.SetRawBytes(response, "error", taskErr)
+		}
+	}
+	response, _ = sjson.SetBytes(response, "id", record.ID)
+	response, _ = sjson.SetBytes(response, "background", true)
+	response, _ = sjson.SetBytes(response, "status", status)
+
+	now := time.Now().UTC()
+	completedAt := now.Unix()
+	record.Status = status
+	record.CompletedAt = &completedAt
+	record.ExpiresAt = now.Add(r.svc.ttl).Unix()
+	record.Response = response
+
+	if err := r.offload(ctx, response); err != nil {
+		// 转存失败时事件仍留在 Redis（随 TTL 过期），完整响应直接存入 Record，保证仍可查询。
+		logger.L().Error("background_response.offload_failed", zap.String("response_id", record.ID), zap.Error(err))
+	}
+	if err := r.svc.store.Save(ctx, record, r.svc.ttl); err != nil {
+		return ErrBackgroundResponseUnavailable.WithCause(err)
+	}
+	if record.EventsKey != "" {
+		if err := r.svc.store.DeleteEvents(ctx, record.ID); err != nil {
+			logger.L().Warn("background_response.delete_events_failed", zap.String("response_id", record.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// offload 把终态响应与完整事件流写入对象存储，成功后 Record 只保留摘要。
+func (r *BackgroundResponseRun) offload(ctx context.Context, response json.RawMessage) error {
+	uploader, _ := r.svc.uploader()
+	if uploader == nil {
+		return errors.New("object storage is not configured")
+	}
+	record := r.record
+	events, err := r.svc.store.ListEvents(ctx, record.ID, 0)
+	if err != nil {
+		return err
+	}
+	var stream bytes.Buffer
+	for _, event := range events {
+		stream.Write(event)
+		stream.WriteByte('\n')
+	}
+	eventsKey, err := uploader.SaveObject(ctx, "responses/"+record.ID+"/events.jsonl", "application/x-ndjson", stream.Bytes())
+	if err != nil {
+		return err
+	}
+	responseKey, err := uploader.SaveObject(ctx, "responses/"+record.ID+"/response.json", "application/json", response)
+	if err != nil {
+		return err
+	}
+	record.EventsKey = eventsKey
+	record.ResponseKey = responseKey
+	record.Response = backgroundResponseSummary(response)
+	return nil
+}
+
+func backgroundResponseSkeleton(record *BackgroundResponseRecord) json.RawMessage {
+	data, _ := json.Marshal(map[string]any{
+		"id":                 record.ID,
+		"object":             "response",
+		"created_at":         record.CreatedAt,
+		"status":             record.Status,
+		"background":         true,
+		"model":              record.Model,
+		"output":             []any{},
+		"error":              nil,
+		"incomplete_details": nil,
+	})
+	return data
+}
+
+// backgroundResponseSummary 去掉 output 等大字段，避免把完整输出写进 Redis。
+func backgroundResponseSummary(response json.RawMessage) json.RawMessage {
+	summary := []byte(response)
+	summary, _ = sjson.SetRawBytes(summary, "output", []byte(`[]`))
+	summary, _ = sjson.DeleteBytes(summary, "output_text")
+	return summary
+}
+
+func withBackgroundResponseStatus(response json.RawMessage, status string) json.RawMessage {
+	if len(response) == 0 {
+		response = json.RawMessage(`{}`)
+	}
+	out, err := sjson.SetBytes(response, "status", status)
+	if err != nil {
+		return response
+	}
+	return out
+}
+
+func backgroundResponseErrorJSON(code, message string) json.RawMessage {
+	if strings.TrimSpace(code) == "" {
+		code = "server_error"
+	}
+	if strings.TrimSpace(message) == "" {
+		message = "background response failed"
+	}
+	data, _ := json.Marshal(map[string]string{"code": code, "message": message})
+	return data
+}
@@ -834,6 +834,8 @@ func groupMediaPricingLooksIncomplete(group *Group) bool {
 		group.AudioRealtimePricePerMin != nil ||
 		group.AudioTTSPricePerMillionChars != nil ||
 		group.AudioSTTPricePerHour != nil ||
+		group.ModerationPricePerCall != nil ||
+		group.ModerationPricePerMillionTokens != nil ||
 		group.WebSearchPricePerCall != nil {
 		return false
 	}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// OpenAIModerationUsageInput 是对外 /v1/moderations 一次调用的计费入参。
+type OpenAIModerationUsageInput struct {
+	Result             *PublicModerationResult
+	APIKey             *APIKey
+	User               *User
+	Subscription       *UserSubscription
+	RequestPayloadHash string
+	APIKeyService      APIKeyQuotaUpdater
+	QuotaPlatform      string
+}
+
+// RecordModerationUsage 按分组的 moderation 单价扣费。
+//
+// 审计调用走风控 key 池而非上游账号，而 usage_logs.account_id 是指向 accounts 的非空外键，
+// 因此这里只写计费账本（去重 + 余额/订阅/API Key 额度与限速），不写 usage_logs 行。
+func (s *OpenAIGatewayService) RecordModerationUsage(ctx context.Context, input *OpenAIModerationUsageInput) error {
+	if input == nil || input.Result == nil || input.APIKey == nil || input.User == nil {
+		return errors.New("moderation usage input is incomplete")
+	}
+	apiKey := input.APIKey
+	multiplier := 1.0
+	if s.cfg != nil {
+		multiplier = s.cfg.Default.RateMultiplier
+	}
+	if apiKey.GroupID != nil && apiKey.Group != nil {
+		multiplier = s.ResolveUserGroupRateMultiplier(ctx, input.User.ID, *apiKey.GroupID, apiKey.Group.RateMultiplier)
+	}
+	cost := s.billingService.CalculateModerationCost(input.Result.InputTokens, moderationPriceConfigFromAPIKey(apiKey), multiplier)
+	if cost.ActualCost <= 0 {
+		return nil
+	}
+	if s.cfg != nil && s.cfg.RunMode == config.RunModeSimple {
+		return nil
+	}
+
+	quotaPlatform := input.QuotaPlatform
+	if quotaPlatform == "" {
+		quotaPlatform = PlatformFromAPIKey(apiKey)
+	}
+	isSubscriptionBilling := input.Subscription != nil && apiKey.Group != nil && apiKey.Group.IsSubscriptionType()
+	requestID := resolveUsageBillingRequestID(ctx, input.Result.ID)
+	if _, err := applyUsageBilling(ctx, requestID, nil, &postUsageBillingParams{
+		Cost:               cost,
+		User:               input.User,
+		APIKey:             apiKey,
+		Subscription:       input.Subscription,
+		RequestPayloadHash: resolveUsageBillingPayloadFingerprint(ctx, input.RequestPayloadHash),
+		IsSubscriptionBill: isSubscriptionBilling,
+		APIKeyService:      input.APIKeyService,
+		Platform:           quotaPlatform,
+	}, s.billingDeps(), s.usageBillingRepo); err != nil {
+		return err
+	}
+	logger.L().With(
+		zap.String("component", "service.openai_gateway.moderation"),
+		zap.String("request_id", requestID),
+		zap.Int64("api_key_id", apiKey.ID),
+		zap.String("model", input.Result.Model),
+		zap.Int("input_tokens", input.Result.InputTokens),
+		zap.Float64("actual_cost", cost.ActualCost),
+	).Info("openai_moderation.billed")
+	return nil
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/tidwall/gjson"
+)
+
+// Prompt-prefix routing maps requests that have no sticky binding yet onto
+// accounts with a bounded-load consistent-hash ring keyed by the cacheable
+// prompt prefix. Agents that open many new conversations with the same large
+// system prompt and tool list then keep landing on the account whose upstream
+// prompt cache already holds that prefix, instead of paying cache creation on
+// a random account each time.
+const (
+	// promptPrefixRingReplicas is the number of virtual nodes per account.
+	promptPrefixRingReplicas = 40
+	// promptPrefixRingLoadFactor is the (1+ε) bound of consistent hashing with
+	// bounded loads: an account is skipped once its in-flight share exceeds
+	// 1.25x the weighted average, and the key moves to the next ring node.
+	promptPrefixRingLoadFactor = 1.25
+	// MaxPromptPrefixMessages caps groups.prompt_prefix_messages.
+	MaxPromptPrefixMessages = 64
+)
+
+type promptPrefixHashCtxKey struct{}
+
+// ValidatePromptPrefixRoutingConfig is shared by the admin handler and service layer.
+func ValidatePromptPrefixRoutingConfig(messages int) error {
+	if messages < 0 || messages > MaxPromptPrefixMessages {
+		return errors.New("prompt_prefix_messages must be between 0 and " + strconv.Itoa(MaxPromptPrefixMessages))
+	}
+	return nil
+}
+
+// WithPromptPrefixHash attaches the request's prompt-prefix hash for account selection.
+// A zero hash (no cacheable prefix) leaves ctx untouched.
+func WithPromptPrefixHash(ctx context.Context, hash uint64) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if hash == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, promptPrefixHashCtxKey{}, hash)
+}
+
+func promptPrefixHashFromContext(ctx context.Context) (uint64, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	hash, ok := ctx.Value(promptPrefixHashCtxKey{}).(uint64)
+	return hash, ok && hash != 0
+}
+
+// WithPromptPrefixRouting computes the prompt-prefix hash for groups that
+// enabled prompt-prefix routing. Other groups get ctx back unchanged, so the
+// hot path pays nothing unless the feature is on.
+func WithPromptPrefixRouting(ctx context.Context, group *Group, parsed *ParsedRequest) context.Context {
+	if group == nil || !group.PromptPrefixRoutingEnabled || parsed == nil || parsed.Body == nil {
+		return ctx
+	}
+	return WithPromptPrefixHash(ctx, ComputePromptPrefixHash(parsed.Body.Bytes(), group.PromptPrefixMessages))
+}
+
+// ComputePromptPrefixHash hashes the cacheable prefix of an Anthropic Messages
+// body in upstream cache order: tools, system, then the first maxMessages
+// messages. When the prefix carries cache_control breakpoints the hash stops
+// at the last one, matching what the upstream prompt cache actually stores;
+// content after the final breakpoint never influences routing. Returns 0 when
+// the body has no prefix worth routing on.
+func ComputePromptPrefixHash(body []byte, maxMessages int) uint64 {
+	if len(body) == 0 {
+		return 0
+	}
+	if maxMessages < 0 {
+		maxMessages = 0
+	}
+	if maxMessages > MaxPromptPrefixMessages {
+		maxMessages = MaxPromptPrefixMessages
+	}
+
+	var segments []promptPrefixSegment
+	tools := gjson.GetBytes(body, "tools")
+	if tools.IsArray() {
+		tools.ForEach(func(_, tool gjson.Result) bool {
+			segments = append(segments, newPromptPrefixSegment("tool", tool))
+			return true
+		})
+	}
+
+	system := gjson.GetBytes(body, "system")
+	switch {
+	case system.Type == gjson.String && system.String() != "":
+		segments = append(segments, promptPrefixSegment{tag: "system", raw: system.Raw})
+	case system.IsArray():
+		system.ForEach(func(_, block gjson.Result) bool {
+			segments = append(segments, newPromptPrefixSegment("system", block))
+			return true
+		})
+	}
+
+	if maxMessages > 0 {
+		messages := gjson.GetBytes(body, "messages")
+		if messages.IsArray() {
+			count := 0
+			messages.ForEach(func(_, msg gjson.Result) bool {
+				if count >= maxMessages {
+					return false
+				}
+				count++
+				tag := "message:" + msg.Get("role").String()
+				content := msg.Get("content")
+				if content.IsArray() {
+					content.ForEach(func(_, block gjson.Result) bool {
+						segments = append(segments, newPromptPrefixSegment(tag, block))
+						return true
+					})
+					return true
+				}
+				segments = append(segments, promptPrefixSegment{tag: tag, raw: content.Raw})
+				return true
+			})
+		}
+	}
+
+	if len(segments) == 0 {
+		return 0
+	}
+	end := len(segments)
+	for i := len(segments) - 1; i >= 0; i-- {
+		if segments[i].breakpoint {
+			end = i + 1
+			break
+		}
+	}
+
+	digest := xxhash.New()
+	for _, seg := range segments[:end] {
+		_, _ = digest.WriteString(seg.tag)
+		_, _ = digest.WriteString("\x00")
+		_, _ = digest.WriteString(seg.raw)
+		_, _ = digest.WriteString("\x1e")
+	}
+	sum := digest.Sum64()
+	if sum == 0 {
+		// 0 is reserved for "no prefix"; fold it onto a fixed non-zero value.
+		sum = 1
+	}
+	return sum
+}
+
+type promptPrefixSegment struct {
+	tag        string
+	raw        string
+	breakpoint bool
+}
+
+func newPromptPrefixSegment(tag string, block gjson.Result) promptPrefixSegment {
+	return promptPrefixSegment{
+		tag:        tag,
+		raw:        block.Raw,
+		breakpoint: block.Get("cache_control").Exists(),
+	}
+}
+
+type promptPrefixRingNode struct {
+	hash  uint64
+	index int
+}
+
+// orderByPromptPrefixRing returns the candidates walked clockwise from the
+// key's position on a consistent-hash ring, skipping accounts whose in-flight
+// load exceeds the bounded-load capacity. Candidates skipped for load are not
+// returned; the caller falls back to regular load-aware selection for them.
+//
+// Capacity follows consistent hashing with bounded loads, weighted by each
+// account's effective load factor: account i may take the request while
+// current_i + 1 <= ceil(c * (total + 1) * w_i / W).
+func orderByPromptPrefixRing(key uint64, candidates []accountWithLoad) []accountWithLoad {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	totalInFlight := 1
+	totalWeight := 0
+	for _, item := range candidates {
+		if item.loadInfo != nil {
+			totalInFlight += item.loadInfo.CurrentConcurrency
+		}
+		totalWeight += item.account.EffectiveLoadFactor()
+	}
+
+	nodes := make([]promptPrefixRingNode, 0, len(candidates)*promptPrefixRingReplicas)
+	var buf [16]byte
+	for i, item := range candidates {
+		id := strconv.FormatInt(item.account.ID, 10)
+		for r := 0; r < promptPrefixRingReplicas; r++ {
+			b := append(buf[:0], id...)
+			b = append(b, '#')
+			b = strconv.AppendInt(b, int64(r), 10)
+			nodes = append(nodes, promptPrefixRingNode{hash: xxhash.Sum64(b), index: i})
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].hash < nodes[j].hash })
+
+	start := sort.Search(len(nodes), func(i int) bool { return nodes[i].hash >= key })
+	ordered := make([]accountWithLoad, 0, len(candidates))
+	seen := make([]bool, len(candidates))
+	visited := 0
+	for n := 0; n < len(nodes) && visited < len(candidates); n++ {
+		node := nodes[(start+n)%len(nodes)]
+		if seen[node.index] {
+			continue
+		}
+		seen[node.index] = true
+		visited++
+		item := candidates[node.index]
+		current := 0
+		if item.loadInfo != nil {
+			current = item.loadInfo.CurrentConcurrency
+		}
+		capacity := int(math.Ceil(promptPrefixRingLoadFactor * float64(totalInFlight) * float64(item.account.EffectiveLoadFactor()) / float64(totalWeight)))
+		if current+1 > capacity {
+			continue
+		}
+		ordered = append(ordered, item)
+	}
+	return ordered
+}
+
+// tryAcquireByPromptPrefixRing acquires the first ring-ordered candidate with a
+// free slot and binds the sticky session to it, so follow-up turns of the new
+// conversation stay on the account that owns the cached prefix.
+func (s *GatewayService) tryAcquireByPromptPrefixRing(ctx context.Context, available []accountWithLoad, groupID *int64, sessionHash string) (*AccountSelectionResult, bool, error) {
+	key, ok := promptPrefixHashFromContext(ctx)
+	if !ok || len(available) == 0 {
+		return nil, false, nil
+	}
+	for _, item := range orderByPromptPrefixRing(key, filterByMinPriority(available)) {
+		result, err := s.tryAcquireAccountSlot(ctx, item.account.ID, item.account.Concurrency)
+		if err != nil || !result.Acquired {
+			continue
+		}
+		if !s.checkAndRegisterSession(ctx, item.account, sessionHash) {
+			result.ReleaseFunc()
+			continue
+		}
+		if sessionHash != "" && s.cache != nil {
+			_ = s.bindGatewayStickySessionDuringSelection(ctx, groupID, sessionHash, item.account.ID)
+		}
+		selection, err := s.newSelectionResult(ctx, item.account, true, result.ReleaseFunc, nil)
+		return selection, err == nil, err
+	}
+	return nil, false, nil
+}
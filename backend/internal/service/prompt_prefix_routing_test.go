@@ -0,0 +1,116 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func promptPrefixCandidate(id int64, currentConcurrency int) accountWithLoad {
+	return accountWithLoad{
+		account:  &Account{ID: id, Schedulable: true, Status: StatusActive, Concurrency: 10},
+		loadInfo: &AccountLoadInfo{AccountID: id, CurrentConcurrency: currentConcurrency},
+	}
+}
+
+func promptPrefixIDs(items []accountWithLoad) []int64 {
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.account.ID)
+	}
+	return ids
+}
+
+func TestComputePromptPrefixHash_SamePrefixSameHash(t *testing.T) {
+	a := []byte(`{"system":"You are a coding agent.","tools":[{"name":"bash"}],"messages":[{"role":"user","content":"fix bug A"}]}`)
+	b := []byte(`{"system":"You are a coding agent.","tools":[{"name":"bash"}],"messages":[{"role":"user","content":"fix bug B"}]}`)
+
+	require.NotZero(t, ComputePromptPrefixHash(a, 0))
+	require.Equal(t, ComputePromptPrefixHash(a, 0), ComputePromptPrefixHash(b, 0))
+	require.NotEqual(t, ComputePromptPrefixHash(a, 1), ComputePromptPrefixHash(b, 1))
+}
+
+func TestComputePromptPrefixHash_StopsAtLastBreakpoint(t *testing.T) {
+	a := []byte(`{"system":[{"type":"text","text":"base","cache_control":{"type":"ephemeral"}},{"type":"text","text":"today is monday"}],"messages":[{"role":"user","content":"hi"}]}`)
+	b := []byte(`{"system":[{"type":"text","text":"base","cache_control":{"type":"ephemeral"}},{"type":"text","text":"today is tuesday"}],"messages":[{"role":"user","content":"hi"}]}`)
+	c := []byte(`{"system":[{"type":"text","text":"other","cache_control":{"type":"ephemeral"}}],"messages":[{"role":"user","content":"hi"}]}`)
+
+	require.Equal(t, ComputePromptPrefixHash(a, 4), ComputePromptPrefixHash(b, 4))
+	require.NotEqual(t, ComputePromptPrefixHash(a, 4), ComputePromptPrefixHash(c, 4))
+}
+
+func TestComputePromptPrefixHash_EmptyPrefix(t *testing.T) {
+	require.Zero(t, ComputePromptPrefixHash(nil, 0))
+	require.Zero(t, ComputePromptPrefixHash([]byte(`{"messages":[{"role":"user","content":"hi"}]}`), 0))
+}
+
+func TestOrderByPromptPrefixRing_Deterministic(t *testing.T) {
+	candidates := []accountWithLoad{
+		promptPrefixCandidate(1, 0),
+		promptPrefixCandidate(2, 0),
+		promptPrefixCandidate(3, 0),
+	}
+	reversed := []accountWithLoad{candidates[2], candidates[1], candidates[0]}
+
+	first := promptPrefixIDs(orderByPromptPrefixRing(12345, candidates))
+	require.Len(t, first, 3)
+	require.Equal(t, first, promptPrefixIDs(orderByPromptPrefixRing(12345, reversed)))
+}
+
+func TestOrderByPromptPrefixRing_SkipsOverloadedAccount(t *testing.T) {
+	idle := []accountWithLoad{promptPrefixCandidate(1, 0), promptPrefixCandidate(2, 0)}
+	owner := orderByPromptPrefixRing(987654321, idle)[0].account.ID
+
+	loaded := []accountWithLoad{promptPrefixCandidate(1, 0), promptPrefixCandidate(2, 0)}
+	for i := range loaded {
+		if loaded[i].account.ID == owner {
+			loaded[i].loadInfo.CurrentConcurrency = 8
+		}
+	}
+	ordered := promptPrefixIDs(orderByPromptPrefixRing(987654321, loaded))
+	require.NotContains(t, ordered, owner)
+	require.Len(t, ordered, 1)
+}
+
+func TestWithPromptPrefixRouting_DisabledGroupLeavesContext(t *testing.T) {
+	body := []byte(`{"system":"sys","messages":[{"role":"user","content":"hi"}]}`)
+	parsed, err := ParseGatewayRequest(NewRequestBodyRef(body), PlatformAnthropic)
+	require.NoError(t, err)
+
+	ctx := WithPromptPrefixRouting(context.Background(), &Group{}, parsed)
+	_, ok := promptPrefixHashFromContext(ctx)
+	require.False(t, ok)
+
+	ctx = WithPromptPrefixRouting(context.Background(), &Group{PromptPrefixRoutingEnabled: true}, parsed)
+	hash, ok := promptPrefixHashFromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, ComputePromptPrefixHash(body, 0), hash)
+}
+
+// 认证快照 L2 JSON 往返：分组的提示词前缀路由配置必须保真（v20 起）。
+func TestAPIKeyAuthSnapshotPromptPrefixRoutingRoundtrip(t *testing.T) {
+	svc := &APIKeyService{}
+	apiKey := profitAuthTestAPIKey()
+	apiKey.Group.PromptPrefixRoutingEnabled = true
+	apiKey.Group.PromptPrefixMessages = 3
+
+	snapshot := svc.snapshotFromAPIKey(context.Background(), apiKey)
+	require.NotNil(t, snapshot)
+	require.Equal(t, 23, snapshot.Version, "v20 起认证快照携带 prompt_prefix_routing_enabled/prompt_prefix_messages 字段")
+
+	payload, err := json.Marshal(&APIKeyAuthCacheEntry{Snapshot: snapshot})
+	require.NoError(t, err)
+	var restored APIKeyAuthCacheEntry
+	require.NoError(t, json.Unmarshal(payload, &restored))
+
+	materialized, used, err := svc.applyAuthCacheEntry(apiKey.Key, &restored)
+	require.NoError(t, err)
+	require.True(t, used)
+	require.NotNil(t, materialized.Group)
+	require.True(t, materialized.Group.PromptPrefixRoutingEnabled)
+	require.Equal(t, 3, materialized.Group.PromptPrefixMessages)
+}
@@ -8,8 +8,12 @@ import (
 )
 
 // ProxyExpiryService 周期扫描到期代理并把绑定账号改投备用/直连。
+// 配置了代理池时，先在池内改投自动分配账号，剩余账号再走代理自身的 fallback 策略。
+// 配置了代理订阅时，每轮最先同步到期订阅，使从订阅下线的代理在同一轮内完成改投。
 type ProxyExpiryService struct {
 	proxyRepo ProxyRepository
+	poolSvc   *ProxyPoolService
+	subSvc    *ProxySubscriptionService
 	interval  time.Duration
 	stopCh    chan struct{}
 	stopOnce  sync.Once
@@ -20,6 +24,22 @@ func NewProxyExpiryService(proxyRepo ProxyRepository, interval time.Duration) *P
 	return &ProxyExpiryService{proxyRepo: proxyRepo, interval: interval, stopCh: make(chan struct{})}
 }
 
+// SetProxyPoolService 注入代理池服务，使每轮扫描前先执行池内改投。
+func (s *ProxyExpiryService) SetProxyPoolService(poolSvc *ProxyPoolService) {
+	if s == nil {
+		return
+	}
+	s.poolSvc = poolSvc
+}
+
+// SetProxySubscriptionService 注入代理订阅服务，使每轮扫描前先同步到期订阅。
+func (s *ProxyExpiryService) SetProxySubscriptionService(subSvc *ProxySubscriptionService) {
+	if s == nil {
+		return
+	}
+	s.subSvc = subSvc
+}
+
 func (s *ProxyExpiryService) Start() {
 	if s == nil || s.proxyRepo == nil || s.interval <= 0 {
 		return
@@ -50,6 +70,9 @@ func (s *ProxyExpiryService) Stop() {
 }
 
 func (s *ProxyExpiryService) runOnce() {
+	s.syncSubscriptions()
+	s.rebalancePools()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	changed, err := s.proxyRepo.SweepExpiredProxies(ctx, time.Now())
@@ -61,3 +84,28 @@ func (s *ProxyExpiryService) runOnce() {
 		log.Printf("[ProxyExpiry] re-routed %d accounts off expired proxies", changed)
 	}
 }
+
+func (s *ProxyExpiryService) syncSubscriptions() {
+	if s.subSvc == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	s.subSvc.SyncDue(ctx)
+}
+
+func (s *ProxyExpiryService) rebalancePools() {
+	if s.poolSvc == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
+	defer cancel()
+	moved, err := s.poolSvc.RebalanceAll(ctx)
+	if err != nil {
+		log.Printf("[ProxyExpiry] rebalance proxy pools failed: %v", err)
+		return
+	}
+	if moved > 0 {
+		log.Printf("[ProxyExpiry] reassigned %d pooled accounts", moved)
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+)
+
+// ErrProxyPoolNotFound 代理池不存在。
+var ErrProxyPoolNotFound = infraerrors.NotFound("PROXY_POOL_NOT_FOUND", "proxy pool not found")
+
+// 代理改投原因，写入 proxy_reassignment_logs.reason。
+const (
+	ProxyReassignReasonAssign       = "auto_assign"    // 账号首次加入代理池
+	ProxyReassignReasonNotInPool    = "not_in_pool"    // 当前代理不属于所在池
+	ProxyReassignReasonExpired      = "proxy_expired"  // 当前代理已到期
+	ProxyReassignReasonInactive     = "proxy_inactive" // 当前代理被停用
+	ProxyReassignReasonProbeFailed  = "probe_failed"   // 当前代理最近一次探测失败
+	ProxyReassignReasonRegion       = "region_mismatch"
+	ProxyReassignReasonOverCapacity = "over_capacity" // 当前代理承载账号数超过池上限
+	ProxyReassignReasonManual       = "manual_rebalance"
+)
+
+// ProxyPool 代理池。
+type ProxyPool struct {
+	ID                  int64     `json:"id"`
+	Name                string    `json:"name"`
+	Region              string    `json:"region"`
+	Tags                []string  `json:"tags"`
+	MaxAccountsPerProxy int       `json:"max_accounts_per_proxy"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+
+	// 以下字段仅列表查询填充。
+	ProxyIDs     []int64 `json:"proxy_ids"`
+	AccountCount int64   `json:"account_count"`
+}
+
+// ProxyPoolAccount 处于自动分配模式的账号及其当前代理。
+type ProxyPoolAccount struct {
+	AccountID int64
+	ProxyID   *int64
+}
+
+// ProxyReassignment 一条代理改投审计记录。
+type ProxyReassignment struct {
+	ID          int64     `json:"id"`
+	AccountID   int64     `json:"account_id"`
+	PoolID      *int64    `json:"pool_id"`
+	FromProxyID *int64    `json:"from_proxy_id"`
+	ToProxyID   *int64    `json:"to_proxy_id"`
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ProxyReassignmentFilter 改投记录查询条件，零值字段不过滤。
+type ProxyReassignmentFilter struct {
+	AccountID int64
+	PoolID    int64
+}
+
+// ProxyPoolRepository 代理池持久化端口。
+type ProxyPoolRepository interface {
+	List(ctx context.Context) ([]*ProxyPool, error)
+	GetByID(ctx context.Context, id int64) (*ProxyPool, error)
+	Create(ctx context.Context, pool *ProxyPool) error
+	Update(ctx context.Context, pool *ProxyPool) error
+	Delete(ctx context.Context, id int64) error
+	SetMembers(ctx context.Context, poolID int64, proxyIDs []int64) error
+	ListMemberIDs(ctx context.Context, poolID int64) ([]int64, error)
+
+	// BindAccount 使账号进入自动分配模式；poolID 为 nil 时解绑（保留当前 proxy_id）。
+	BindAccount(ctx context.Context, accountID int64, poolID *int64) error
+	GetAccountPoolID(ctx context.Context, accountID int64) (*int64, error)
+	ListAccounts(ctx context.Context, poolID int64) ([]ProxyPoolAccount, error)
+	// CountAccountsByProxyIDs 统计绑定到各代理的未删除账号数（含手动绑定）。
+	CountAccountsByProxyIDs(ctx context.Context, proxyIDs []int64) (map[int64]int64, error)
+
+	// ReassignAccounts 在单事务内改写账号 proxy_id 并写入改投记录，随后通知调度快照刷新。
+	ReassignAccounts(ctx context.Context, moves []*ProxyReassignment) error
+	ListReassignments(ctx context.Context, params pagination.PaginationParams, filter ProxyReassignmentFilter) ([]*ProxyReassignment, *pagination.PaginationResult, error)
+}
+
+// proxyPoolCandidate 代理池成员在一次改投计算中的快照。
+type proxyPoolCandidate struct {
+	proxy   Proxy
+	info    *ProxyLatencyInfo
+	load    int64
+	healthy bool
+	reason  string // healthy=false 时的不可用原因
+}
+
+// evaluateProxyPoolMember 判断池成员能否承载账号；不可用时返回对应的改投原因。
+// 未探测过的代理在池未限定地域时视为可用，但排序在已探测健康代理之后。
+func evaluateProxyPoolMember(p Proxy, info *ProxyLatencyInfo, region string, now time.Time) (bool, string) {
+	if p.IsExpired(now) {
+		return false, ProxyReassignReasonExpired
+	}
+	if !p.IsActive() {
+		return false, ProxyReassignReasonInactive
+	}
+	if info != nil && (!info.Success || info.QualityStatus == "failed") {
+		return false, ProxyReassignReasonProbeFailed
+	}
+	if region = strings.TrimSpace(region); region != "" {
+		if info == nil || !(strings.EqualFold(info.CountryCode, region) || strings.EqualFold(info.Region, region) || strings.EqualFold(info.Country, region)) {
+			return false, ProxyReassignReasonRegion
+		}
+	}
+	return true, ""
+}
+
+// pickProxyPoolTarget 选出负载最低的可用代理：先比承载账号数，再比是否已探测、延迟、ID。
+// maxPerProxy>0 时跳过已满的代理；exclude 为当前代理（不会原地改投）。
+func pickProxyPoolTarget(candidates []*proxyPoolCandidate, maxPerProxy int, exclude *int64) *proxyPoolCandidate {
+	eligible := make([]*proxyPoolCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if !c.healthy {
+			continue
+		}
+		if exclude != nil && c.proxy.ID == *exclude {
+			continue
+		}
+		if maxPerProxy > 0 && c.load >= int64(maxPerProxy) {
+			continue
+		}
+		eligible = append(eligible, c)
+	}
+	if len(eligible) == 0 {
+		return nil
+	}
+	sort.SliceStable(eligible, func(i, j int) bool {
+		a, b := eligible[i], eligible[j]
+		if a.load != b.load {
+			return a.load < b.load
+		}
+		if (a.info == nil) != (b.info == nil) {
+			return a.info != nil
+		}
+		la, lb := proxyPoolLatency(a.info), proxyPoolLatency(b.info)
+		if la != lb {
+			return la < lb
+		}
+		return a.proxy.ID < b.proxy.ID
+	})
+	return eligible[0]
+}
+
+func proxyPoolLatency(info *ProxyLatencyInfo) int64 {
+	if info == nil || info.LatencyMs == nil {
+		return 1<<63 - 1
+	}
+	return *info.LatencyMs
+}
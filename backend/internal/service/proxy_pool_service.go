@@ -0,0 +1,431 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+)
+
+const (
+	// proxyPoolProbeStaleAfter 池成员探测结果超过该时长视为过期，改投前重新探测。
+	proxyPoolProbeStaleAfter = 10 * time.Minute
+	// proxyPoolProbeBatch 单轮最多重新探测的代理数，避免拖慢到期扫描周期。
+	proxyPoolProbeBatch       = 16
+	proxyPoolProbeConcurrency = 4
+	proxyPoolProbeTimeout     = 15 * time.Second
+)
+
+// ProxyPoolService 代理池管理与账号自动分配/改投。
+// 周期改投由 ProxyExpiryService 在到期扫描之前驱动，保证自动分配账号优先在池内改投，
+// 池内无可用代理时才落入代理自身的 fallback 策略。
+type ProxyPoolService struct {
+	poolRepo     ProxyPoolRepository
+	proxyRepo    ProxyRepository
+	accountRepo  AccountRepository
+	latencyCache ProxyLatencyCache
+	prober       ProxyExitInfoProber
+}
+
+// NewProxyPoolService 创建代理池服务。
+func NewProxyPoolService(
+	poolRepo ProxyPoolRepository,
+	proxyRepo ProxyRepository,
+	accountRepo AccountRepository,
+	latencyCache ProxyLatencyCache,
+	prober ProxyExitInfoProber,
+) *ProxyPoolService {
+	return &ProxyPoolService{
+		poolRepo:     poolRepo,
+		proxyRepo:    proxyRepo,
+		accountRepo:  accountRepo,
+		latencyCache: latencyCache,
+		prober:       prober,
+	}
+}
+
+// ListPools 返回所有代理池（含成员与自动分配账号数）。
+func (s *ProxyPoolService) ListPools(ctx context.Context) ([]*ProxyPool, error) {
+	return s.poolRepo.List(ctx)
+}
+
+// GetPool 返回单个代理池。
+func (s *ProxyPoolService) GetPool(ctx context.Context, id int64) (*ProxyPool, error) {
+	return s.poolRepo.GetByID(ctx, id)
+}
+
+// CreatePool 校验并创建代理池。
+func (s *ProxyPoolService) CreatePool(ctx context.Context, pool *ProxyPool) (*ProxyPool, error) {
+	if err := normalizeProxyPool(pool); err != nil {
+		return nil, err
+	}
+	if err := s.poolRepo.Create(ctx, pool); err != nil {
+		return nil, err
+	}
+	return s.poolRepo.GetByID(ctx, pool.ID)
+}
+
+// UpdatePool 更新代理池配置；上限或地域变化后立即改投不再满足条件的账号。
+func (s *ProxyPoolService) UpdatePool(ctx context.Context, pool *ProxyPool) (*ProxyPool, error) {
+	if err := normalizeProxyPool(pool); err != nil {
+		return nil, err
+	}
+	if err := s.poolRepo.Update(ctx, pool); err != nil {
+		return nil, err
+	}
+	if _, err := s.RebalancePool(ctx, pool.ID, false); err != nil {
+		logger.LegacyPrintf("service.proxy_pool", "[ProxyPool] rebalance pool %d after update failed: %v", pool.ID, err)
+	}
+	return s.poolRepo.GetByID(ctx, pool.ID)
+}
+
+// DeletePool 删除代理池；池内账号退出自动分配模式并保留当前代理。
+func (s *ProxyPoolService) DeletePool(ctx context.Context, id int64) error {
+	if _, err := s.poolRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return s.poolRepo.Delete(ctx, id)
+}
+
+// SetMembers 覆盖代理池成员，移出的代理上的自动分配账号随即改投。
+func (s *ProxyPoolService) SetMembers(ctx context.Context, poolID int64, proxyIDs []int64) (*ProxyPool, error) {
+	if _, err := s.poolRepo.GetByID(ctx, poolID); err != nil {
+		return nil, err
+	}
+	proxyIDs = normalizeProxyPoolMemberIDs(proxyIDs)
+	if len(proxyIDs) > 0 {
+		proxies, err := s.proxyRepo.ListByIDs(ctx, proxyIDs)
+		if err != nil {
+			return nil, err
+		}
+		if len(proxies) != len(proxyIDs) {
+			return nil, infraerrors.BadRequest("PROXY_POOL_MEMBER_INVALID", "some proxies do not exist")
+		}
+	}
+	if err := s.poolRepo.SetMembers(ctx, poolID, proxyIDs); err != nil {
+		return nil, err
+	}
+	if _, err := s.RebalancePool(ctx, poolID, false); err != nil {
+		logger.LegacyPrintf("service.proxy_pool", "[ProxyPool] rebalance pool %d after member change failed: %v", poolID, err)
+	}
+	return s.poolRepo.GetByID(ctx, poolID)
+}
+
+// SetAccountPool 切换账号的代理分配模式：poolID 非空进入自动分配并立即从池中选取代理，
+// 为空则退出自动分配，保留当前 proxy_id 由管理员手动管理。
+func (s *ProxyPoolService) SetAccountPool(ctx context.Context, accountID int64, poolID *int64) error {
+	if _, err := s.accountRepo.GetByID(ctx, accountID); err != nil {
+		return err
+	}
+	if poolID != nil {
+		if _, err := s.poolRepo.GetByID(ctx, *poolID); err != nil {
+			return err
+		}
+	}
+	if err := s.poolRepo.BindAccount(ctx, accountID, poolID); err != nil {
+		return err
+	}
+	if poolID == nil {
+		return nil
+	}
+	_, err := s.RebalancePool(ctx, *poolID, false)
+	return err
+}
+
+// GetAccountPoolID 返回账号所在代理池，未开启自动分配时为 nil。
+func (s *ProxyPoolService) GetAccountPoolID(ctx context.Context, accountID int64) (*int64, error) {
+	return s.poolRepo.GetAccountPoolID(ctx, accountID)
+}
+
+// ListReassignments 分页查询改投审计记录。
+func (s *ProxyPoolService) ListReassignments(ctx context.Context, params pagination.PaginationParams, filter ProxyReassignmentFilter) ([]*ProxyReassignment, *pagination.PaginationResult, error) {
+	return s.poolRepo.ListReassignments(ctx, params, filter)
+}
+
+// RebalanceAll 对所有代理池执行一轮改投，返回改投账号数。单个池失败不影响其他池。
+func (s *ProxyPoolService) RebalanceAll(ctx context.Context) (int, error) {
+	pools, err := s.poolRepo.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, pool := range pools {
+		moved, err := s.rebalancePool(ctx, pool, false)
+		if err != nil {
+			logger.LegacyPrintf("service.proxy_pool", "[ProxyPool] rebalance pool %d failed: %v", pool.ID, err)
+			continue
+		}
+		total += moved
+	}
+	return total, nil
+}
+
+// RebalancePool 对单个代理池执行改投。even=true 时额外把账号从高负载代理均衡到低负载代理。
+func (s *ProxyPoolService) RebalancePool(ctx context.Context, poolID int64, even bool) (int, error) {
+	pool, err := s.poolRepo.GetByID(ctx, poolID)
+	if err != nil {
+		return 0, err
+	}
+	return s.rebalancePool(ctx, pool, even)
+}
+
+func (s *ProxyPoolService) rebalancePool(ctx context.Context, pool *ProxyPool, even bool) (int, error) {
+	accounts, err := s.poolRepo.ListAccounts(ctx, pool.ID)
+	if err != nil {
+		return 0, fmt.Errorf("list pool accounts: %w", err)
+	}
+	if len(accounts) == 0 {
+		return 0, nil
+	}
+	candidates, err := s.loadPoolCandidates(ctx, pool, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	moves := planProxyPoolMoves(pool, candidates, accounts, even)
+	if len(moves) == 0 {
+		return 0, nil
+	}
+	if err := s.poolRepo.ReassignAccounts(ctx, moves); err != nil {
+		return 0, fmt.Errorf("reassign accounts: %w", err)
+	}
+	return len(moves), nil
+}
+
+func (s *ProxyPoolService) loadPoolCandidates(ctx context.Context, pool *ProxyPool, now time.Time) ([]*proxyPoolCandidate, error) {
+	memberIDs, err := s.poolRepo.ListMemberIDs(ctx, pool.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list pool members: %w", err)
+	}
+	if len(memberIDs) == 0 {
+		return nil, nil
+	}
+	proxies, err := s.proxyRepo.ListByIDs(ctx, memberIDs)
+	if err != nil {
+		return nil, fmt.Errorf("list pool proxies: %w", err)
+	}
+	loads, err := s.poolRepo.CountAccountsByProxyIDs(ctx, memberIDs)
+	if err != nil {
+		return nil, fmt.Errorf("count proxy accounts: %w", err)
+	}
+	infos := s.loadLatencies(ctx, memberIDs)
+	s.refreshStaleProbes(ctx, proxies, infos, now)
+
+	candidates := make([]*proxyPoolCandidate, 0, len(proxies))
+	for _, p := range proxies {
+		info := infos[p.ID]
+		healthy, reason := evaluateProxyPoolMember(p, info, pool.Region, now)
+		candidates = append(candidates, &proxyPoolCandidate{
+			proxy:   p,
+			info:    info,
+			load:    loads[p.ID],
+			healthy: healthy,
+			reason:  reason,
+		})
+	}
+	return candidates, nil
+}
+
+func (s *ProxyPoolService) loadLatencies(ctx context.Context, proxyIDs []int64) map[int64]*ProxyLatencyInfo {
+	if s.latencyCache == nil {
+		return map[int64]*ProxyLatencyInfo{}
+	}
+	infos, err := s.latencyCache.GetProxyLatencies(ctx, proxyIDs)
+	if err != nil || infos == nil {
+		if err != nil {
+			logger.LegacyPrintf("service.proxy_pool", "[ProxyPool] load proxy latency cache failed: %v", err)
+		}
+		return map[int64]*ProxyLatencyInfo{}
+	}
+	return infos
+}
+
+// refreshStaleProbes 重新探测结果缺失或过期的活跃成员，并就地更新 infos 与延迟缓存。
+func (s *ProxyPoolService) refreshStaleProbes(ctx context.Context, proxies []Proxy, infos map[int64]*ProxyLatencyInfo, now time.Time) {
+	if s.prober == nil || s.latencyCache == nil {
+		return
+	}
+	stale := make([]Proxy, 0)
+	for _, p := range proxies {
+		if !p.IsActive() || p.IsExpired(now) {
+			continue
+		}
+		if info := infos[p.ID]; info != nil && now.Sub(info.UpdatedAt) < proxyPoolProbeStaleAfter {
+			continue
+		}
+		stale = append(stale, p)
+		if len(stale) >= proxyPoolProbeBatch {
+			break
+		}
+	}
+	if len(stale) == 0 {
+		return
+	}
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, proxyPoolProbeConcurrency)
+	)
+	for _, p := range stale {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(p Proxy) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			probeCtx, cancel := context.WithTimeout(ctx, proxyPoolProbeTimeout)
+			defer cancel()
+
+			info := &ProxyLatencyInfo{UpdatedAt: time.Now()}
+			exitInfo, latencyMs, err := s.prober.ProbeProxy(probeCtx, p.URL())
+			if err != nil {
+				info.Message = err.Error()
+			} else {
+				info.Success = true
+				info.LatencyMs = &latencyMs
+				info.Message = "Proxy is accessible"
+				if exitInfo != nil {
+					info.IPAddress = exitInfo.IP
+					info.Country = exitInfo.Country
+					info.CountryCode = exitInfo.CountryCode
+					info.Region = exitInfo.Region
+					info.City = exitInfo.City
+				}
+			}
+
+			mu.Lock()
+			if existing := infos[p.ID]; existing != nil {
+				info.QualityStatus = existing.QualityStatus
+				info.QualityScore = existing.QualityScore
+				info.QualityGrade = existing.QualityGrade
+				info.QualitySummary = existing.QualitySummary
+				info.QualityCheckedAt = existing.QualityCheckedAt
+				info.QualityCFRay = existing.QualityCFRay
+			}
+			infos[p.ID] = info
+			mu.Unlock()
+
+			if err := s.latencyCache.SetProxyLatency(ctx, p.ID, info); err != nil {
+				logger.LegacyPrintf("service.proxy_pool", "[ProxyPool] store proxy latency failed: proxy=%d err=%v", p.ID, err)
+			}
+		}(p)
+	}
+	wg.Wait()
+}
+
+// planProxyPoolMoves 计算一轮改投：无代理、代理不在池内、到期/停用/探测失败/地域不符、
+// 以及超出单代理上限的账号改投到负载最低的可用成员。池内无可用代理时账号保持原状，
+// 交由代理自身的 fallback 策略兜底。candidates 的 load 会随计划同步更新。
+func planProxyPoolMoves(pool *ProxyPool, candidates []*proxyPoolCandidate, accounts []ProxyPoolAccount, even bool) []*ProxyReassignment {
+	byID := make(map[int64]*proxyPoolCandidate, len(candidates))
+	for _, c := range candidates {
+		byID[c.proxy.ID] = c
+	}
+	sorted := append([]ProxyPoolAccount(nil), accounts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].AccountID < sorted[j].AccountID })
+
+	poolID := pool.ID
+	moves := make([]*ProxyReassignment, 0)
+	move := func(acc ProxyPoolAccount, current, target *proxyPoolCandidate, reason string) {
+		toID := target.proxy.ID
+		moves = append(moves, &ProxyReassignment{
+			AccountID:   acc.AccountID,
+			PoolID:      &poolID,
+			FromProxyID: acc.ProxyID,
+			ToProxyID:   &toID,
+			Reason:      reason,
+		})
+		target.load++
+		if current != nil {
+			current.load--
+		}
+	}
+
+	settled := make([]ProxyPoolAccount, 0, len(sorted))
+	for _, acc := range sorted {
+		var current *proxyPoolCandidate
+		reason := ""
+		switch {
+		case acc.ProxyID == nil:
+			reason = ProxyReassignReasonAssign
+		case byID[*acc.ProxyID] == nil:
+			reason = ProxyReassignReasonNotInPool
+		default:
+			current = byID[*acc.ProxyID]
+			if !current.healthy {
+				reason = current.reason
+			} else if pool.MaxAccountsPerProxy > 0 && current.load > int64(pool.MaxAccountsPerProxy) {
+				reason = ProxyReassignReasonOverCapacity
+			}
+		}
+		if reason == "" {
+			settled = append(settled, acc)
+			continue
+		}
+		if target := pickProxyPoolTarget(candidates, pool.MaxAccountsPerProxy, acc.ProxyID); target != nil {
+			move(acc, current, target, reason)
+		}
+	}
+
+	if even {
+		for _, acc := range settled {
+			current := byID[*acc.ProxyID]
+			target := pickProxyPoolTarget(candidates, pool.MaxAccountsPerProxy, acc.ProxyID)
+			if target != nil && target.load+1 < current.load {
+				move(acc, current, target, ProxyReassignReasonManual)
+			}
+		}
+	}
+	return moves
+}
+
+func normalizeProxyPool(pool *ProxyPool) error {
+	if pool == nil {
+		return infraerrors.BadRequest("PROXY_POOL_INVALID", "proxy pool is required")
+	}
+	pool.Name = strings.TrimSpace(pool.Name)
+	if pool.Name == "" {
+		return infraerrors.BadRequest("PROXY_POOL_INVALID", "name is required")
+	}
+	if pool.MaxAccountsPerProxy < 0 {
+		return infraerrors.BadRequest("PROXY_POOL_INVALID", "max_accounts_per_proxy must be >= 0")
+	}
+	pool.Region = strings.TrimSpace(pool.Region)
+	tags := make([]string, 0, len(pool.Tags))
+	seen := make(map[string]struct{}, len(pool.Tags))
+	for _, tag := range pool.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		tags = append(tags, tag)
+	}
+	pool.Tags = tags
+	return nil
+}
+
+func normalizeProxyPoolMemberIDs(ids []int64) []int64 {
+	out := make([]int64, 0, len(ids))
+	seen := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		if id <= 0 {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
@@ -0,0 +1,107 @@
+//go:build unit
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func proxyPoolTestCandidate(id int64, load int64, latencyMs int64) *proxyPoolCandidate {
+	latency := latencyMs
+	return &proxyPoolCandidate{
+		proxy:   Proxy{ID: id, Status: StatusActive},
+		info:    &ProxyLatencyInfo{Success: true, LatencyMs: &latency, CountryCode: "US"},
+		load:    load,
+		healthy: true,
+	}
+}
+
+func TestEvaluateProxyPoolMember(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	ok := &ProxyLatencyInfo{Success: true, CountryCode: "JP", Region: "Tokyo"}
+
+	healthy, reason := evaluateProxyPoolMember(Proxy{Status: StatusActive}, ok, "jp", now)
+	require.True(t, healthy)
+	require.Empty(t, reason)
+
+	_, reason = evaluateProxyPoolMember(Proxy{Status: StatusActive, ExpiresAt: &past}, ok, "", now)
+	require.Equal(t, ProxyReassignReasonExpired, reason)
+	_, reason = evaluateProxyPoolMember(Proxy{Status: "inactive"}, ok, "", now)
+	require.Equal(t, ProxyReassignReasonInactive, reason)
+	_, reason = evaluateProxyPoolMember(Proxy{Status: StatusActive}, &ProxyLatencyInfo{Success: false}, "", now)
+	require.Equal(t, ProxyReassignReasonProbeFailed, reason)
+	_, reason = evaluateProxyPoolMember(Proxy{Status: StatusActive}, ok, "US", now)
+	require.Equal(t, ProxyReassignReasonRegion, reason)
+
+	healthy, _ = evaluateProxyPoolMember(Proxy{Status: StatusActive}, nil, "", now)
+	require.True(t, healthy, "unprobed proxies are usable when the pool has no region")
+	healthy, _ = evaluateProxyPoolMember(Proxy{Status: StatusActive}, nil, "US", now)
+	require.False(t, healthy)
+}
+
+func TestPickProxyPoolTarget_LeastLoadedThenLatency(t *testing.T) {
+	a := proxyPoolTestCandidate(1, 2, 50)
+	b := proxyPoolTestCandidate(2, 1, 300)
+	c := proxyPoolTestCandidate(3, 1, 80)
+	d := proxyPoolTestCandidate(4, 0, 10)
+	d.healthy = false
+
+	require.Equal(t, int64(3), pickProxyPoolTarget([]*proxyPoolCandidate{a, b, c, d}, 0, nil).proxy.ID)
+
+	exclude := int64(3)
+	require.Equal(t, int64(2), pickProxyPoolTarget([]*proxyPoolCandidate{a, b, c, d}, 0, &exclude).proxy.ID)
+	require.Nil(t, pickProxyPoolTarget([]*proxyPoolCandidate{a, b, c}, 1, nil))
+}
+
+func TestPlanProxyPoolMoves(t *testing.T) {
+	healthy := proxyPoolTestCandidate(1, 2, 50)
+	failed := proxyPoolTestCandidate(2, 1, 50)
+	failed.healthy = false
+	failed.reason = ProxyReassignReasonProbeFailed
+	spare := proxyPoolTestCandidate(3, 0, 90)
+
+	pool := &ProxyPool{ID: 7, MaxAccountsPerProxy: 2}
+	p1, p2, outside := int64(1), int64(2), int64(99)
+	accounts := []ProxyPoolAccount{
+		{AccountID: 10, ProxyID: &p1},
+		{AccountID: 11, ProxyID: &p1},
+		{AccountID: 12, ProxyID: &p2},
+		{AccountID: 13},
+		{AccountID: 14, ProxyID: &outside},
+	}
+
+	moves := planProxyPoolMoves(pool, []*proxyPoolCandidate{healthy, failed, spare}, accounts, false)
+	require.Len(t, moves, 2)
+
+	require.Equal(t, int64(12), moves[0].AccountID)
+	require.Equal(t, ProxyReassignReasonProbeFailed, moves[0].Reason)
+	require.Equal(t, int64(3), *moves[0].ToProxyID)
+	require.Equal(t, int64(13), moves[1].AccountID)
+	require.Equal(t, ProxyReassignReasonAssign, moves[1].Reason)
+	require.Equal(t, int64(3), *moves[1].ToProxyID)
+	require.Equal(t, int64(2), spare.load)
+	// 账号 14 所在代理不在池内，但池已满：保持原状，交给代理 fallback 兜底。
+}
+
+func TestPlanProxyPoolMoves_EvenRebalance(t *testing.T) {
+	busy := proxyPoolTestCandidate(1, 3, 50)
+	idle := proxyPoolTestCandidate(2, 0, 50)
+	p1 := int64(1)
+	accounts := []ProxyPoolAccount{
+		{AccountID: 1, ProxyID: &p1},
+		{AccountID: 2, ProxyID: &p1},
+		{AccountID: 3, ProxyID: &p1},
+	}
+
+	require.Empty(t, planProxyPoolMoves(&ProxyPool{ID: 1}, []*proxyPoolCandidate{busy, idle}, accounts, false))
+
+	moves := planProxyPoolMoves(&ProxyPool{ID: 1}, []*proxyPoolCandidate{busy, idle}, accounts, true)
+	require.Len(t, moves, 1)
+	require.Equal(t, ProxyReassignReasonManual, moves[0].Reason)
+	require.Equal(t, int64(2), busy.load)
+	require.Equal(t, int64(1), idle.load)
+}
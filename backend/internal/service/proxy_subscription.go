@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/proxysub"
+)
+
+var (
+	// ErrProxySubscriptionNotFound 代理订阅不存在。
+	ErrProxySubscriptionNotFound = infraerrors.NotFound("PROXY_SUBSCRIPTION_NOT_FOUND", "proxy subscription not found")
+	// ErrProxySubscriptionEmpty 订阅中没有可用代理；拒绝同步以免把已有代理全部标记过期。
+	ErrProxySubscriptionEmpty = infraerrors.BadRequest("PROXY_SUBSCRIPTION_EMPTY", "subscription contains no supported proxies")
+)
+
+// ProxySubscription 定时同步的代理订阅源。
+type ProxySubscription struct {
+	ID              int64      `json:"id"`
+	Name            string     `json:"name"`
+	URL             string     `json:"url"`
+	Format          string     `json:"format"`
+	IntervalMinutes int        `json:"interval_minutes"`
+	Enabled         bool       `json:"enabled"`
+	LastSyncedAt    *time.Time `json:"last_synced_at"`
+	LastError       string     `json:"last_error"`
+	ExpiresAt       *time.Time `json:"expires_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// ProxyCount 当前归属该订阅的代理数，仅查询时填充。
+	ProxyCount int64 `json:"proxy_count"`
+}
+
+// ProxyImportResult 一次导入/同步的结果统计。
+type ProxyImportResult struct {
+	Format    string   `json:"format"`
+	Total     int      `json:"total"`
+	Created   int      `json:"created"`
+	Updated   int      `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	Skipped   int      `json:"skipped"`
+	Expired   int      `json:"expired"`
+	Failed    int      `json:"failed"`
+	Errors    []string `json:"errors,omitempty"`
+
+	proxyIDs []int64
+}
+
+// ProxySubscriptionRepository 代理订阅持久化端口。
+type ProxySubscriptionRepository interface {
+	List(ctx context.Context) ([]*ProxySubscription, error)
+	GetByID(ctx context.Context, id int64) (*ProxySubscription, error)
+	Create(ctx context.Context, sub *ProxySubscription) error
+	Update(ctx context.Context, sub *ProxySubscription) error
+	Delete(ctx context.Context, id int64) error
+
+	// ClaimDue 原子地认领已到同步周期的启用订阅（先写 last_synced_at），多实例下每个订阅只会被一个实例拉取。
+	ClaimDue(ctx context.Context) ([]*ProxySubscription, error)
+	RecordSyncResult(ctx context.Context, id int64, expiresAt *time.Time, lastError string) error
+
+	ListProxyIDs(ctx context.Context, subscriptionID int64) ([]int64, error)
+	SetProxyIDs(ctx context.Context, subscriptionID int64, proxyIDs []int64) error
+}
+
+// proxySubscriptionKey 代理去重键，与 ExistsByHostPortAuth 的判重口径一致（不含协议）。
+func proxySubscriptionKey(host string, port int, username, password string) string {
+	return proxysub.Entry{Host: host, Port: port, Username: username, Password: password}.Key()
+}
+
+// planProxySubscriptionUpdate 计算订阅中已存在代理需要的变更：
+// 订阅给出到期时间时同步 expires_at；已过期（被订阅下线后又重新出现）的代理恢复为 active。
+// admin 手动停用（inactive）的代理保持原状态。返回是否需要写库。
+func planProxySubscriptionUpdate(p *Proxy, expiresAt *time.Time, now time.Time) bool {
+	changed := false
+	if p.Status == StatusExpired || p.IsExpired(now) {
+		if p.Status == StatusExpired {
+			p.Status = StatusActive
+		}
+		p.ExpiresAt = expiresAt
+		return true
+	}
+	if expiresAt != nil && (p.ExpiresAt == nil || !p.ExpiresAt.Equal(*expiresAt)) {
+		p.ExpiresAt = expiresAt
+		changed = true
+	}
+	return changed
+}
+
+// missingProxyIDs 返回上次同步归属订阅、本次已不在订阅中的代理。
+func missingProxyIDs(previous, current []int64) []int64 {
+	keep := make(map[int64]struct{}, len(current))
+	for _, id := range current {
+		keep[id] = struct{}{}
+	}
+	out := make([]int64, 0)
+	for _, id := range previous {
+		if _, ok := keep[id]; !ok {
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/httpclient"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/proxysub"
+	"github.com/Wei-Shaw/sub2api/internal/util/urlvalidator"
+)
+
+const (
+	proxySubscriptionFetchTimeout = 30 * time.Second
+	proxySubscriptionMaxBodyBytes = 8 << 20
+	proxySubscriptionMinInterval  = 5
+	proxySubscriptionDefaultIntv  = 60
+)
+
+// ProxySubscriptionService 代理批量导入与订阅同步。
+//
+// 导入按 host/port/username/password 与已有代理去重；订阅同步额外维护归属关系，
+// 从订阅中消失的代理只把 expires_at 置为当前时间，由 ProxyExpiryService 的到期扫描
+// 统一标记 expired 并按 fallback 策略改投账号，不做删除。
+type ProxySubscriptionService struct {
+	subRepo   ProxySubscriptionRepository
+	proxyRepo ProxyRepository
+	cfg       *config.Config
+}
+
+// NewProxySubscriptionService 创建代理订阅服务。
+func NewProxySubscriptionService(subRepo ProxySubscriptionRepository, proxyRepo ProxyRepository, cfg *config.Config) *ProxySubscriptionService {
+	return &ProxySubscriptionService{subRepo: subRepo, proxyRepo: proxyRepo, cfg: cfg}
+}
+
+// List 返回全部订阅。
+func (s *ProxySubscriptionService) List(ctx context.Context) ([]*ProxySubscription, error) {
+	return s.subRepo.List(ctx)
+}
+
+// Get 返回单个订阅。
+func (s *ProxySubscriptionService) Get(ctx context.Context, id int64) (*ProxySubscription, error) {
+	return s.subRepo.GetByID(ctx, id)
+}
+
+// Create 创建订阅。
+func (s *ProxySubscriptionService) Create(ctx context.Context, sub *ProxySubscription) (*ProxySubscription, error) {
+	if err := s.normalize(sub); err != nil {
+		return nil, err
+	}
+	if err := s.subRepo.Create(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Update 更新订阅配置。
+func (s *ProxySubscriptionService) Update(ctx context.Context, sub *ProxySubscription) (*ProxySubscription, error) {
+	if err := s.normalize(sub); err != nil {
+		return nil, err
+	}
+	if err := s.subRepo.Update(ctx, sub); err != nil {
+		return nil, err
+	}
+	return s.subRepo.GetByID(ctx, sub.ID)
+}
+
+// Delete 删除订阅；已导入的代理保留。
+func (s *ProxySubscriptionService) Delete(ctx context.Context, id int64) error {
+	return s.subRepo.Delete(ctx, id)
+}
+
+func (s *ProxySubscriptionService) normalize(sub *ProxySubscription) error {
+	sub.Name = strings.TrimSpace(sub.Name)
+	if sub.Name == "" {
+		return infraerrors.BadRequest("PROXY_SUBSCRIPTION_NAME_REQUIRED", "name is required")
+	}
+	if sub.Format == "" {
+		sub.Format = proxysub.FormatAuto
+	}
+	if !proxysub.IsValidFormat(sub.Format) {
+		return infraerrors.BadRequest("PROXY_SUBSCRIPTION_FORMAT_INVALID", "format must be one of auto, clash, singbox, v2ray, text")
+	}
+	if sub.IntervalMinutes == 0 {
+		sub.IntervalMinutes = proxySubscriptionDefaultIntv
+	}
+	if sub.IntervalMinutes < proxySubscriptionMinInterval {
+		return infraerrors.BadRequest("PROXY_SUBSCRIPTION_INTERVAL_INVALID", fmt.Sprintf("interval_minutes must be >= %d", proxySubscriptionMinInterval))
+	}
+	normalized, err := s.validateURL(sub.URL)
+	if err != nil {
+		return infraerrors.BadRequest("PROXY_SUBSCRIPTION_URL_INVALID", err.Error())
+	}
+	sub.URL = normalized
+	return nil
+}
+
+func (s *ProxySubscriptionService) validateURL(raw string) (string, error) {
+	allowInsecure, allowPrivate := false, false
+	if s.cfg != nil {
+		allowInsecure = s.cfg.Security.URLAllowlist.AllowInsecureHTTP
+		allowPrivate = s.cfg.Security.URLAllowlist.AllowPrivateHosts
+	}
+	return urlvalidator.ValidateHTTPURL(raw, allowInsecure, urlvalidator.ValidationOptions{AllowPrivate: allowPrivate})
+}
+
+// Import 解析一段订阅内容并写入代理列表（不建立订阅归属）。expiresAt 非 nil 时写入新建/已有代理。
+func (s *ProxySubscriptionService) Import(ctx context.Context, content []byte, format string, expiresAt *time.Time) (*ProxyImportResult, error) {
+	if !proxysub.IsValidFormat(format) {
+		return nil, infraerrors.BadRequest("PROXY_SUBSCRIPTION_FORMAT_INVALID", "format must be one of auto, clash, singbox, v2ray, text")
+	}
+	feed, err := proxysub.Parse(content, format)
+	if err != nil {
+		return nil, infraerrors.BadRequest("PROXY_SUBSCRIPTION_PARSE_FAILED", err.Error())
+	}
+	if expiresAt != nil {
+		feed.ExpiresAt = expiresAt
+	}
+	return s.applyFeed(ctx, feed, time.Now())
+}
+
+// Sync 立即同步指定订阅。
+func (s *ProxySubscriptionService) Sync(ctx context.Context, id int64) (*ProxyImportResult, error) {
+	sub, err := s.subRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.syncOne(ctx, sub)
+}
+
+// SyncDue 同步所有到期的启用订阅，由 ProxyExpiryService 周期调用。
+func (s *ProxySubscriptionService) SyncDue(ctx context.Context) {
+	subs, err := s.subRepo.ClaimDue(ctx)
+	if err != nil {
+		logger.LegacyPrintf("service.proxy_subscription", "[ProxySubscription] claim due subscriptions failed: %v", err)
+		return
+	}
+	for _, sub := range subs {
+		result, err := s.syncOne(ctx, sub)
+		if err != nil {
+			logger.LegacyPrintf("service.proxy_subscription", "[ProxySubscription] sync subscription %d failed: %v", sub.ID, err)
+			continue
+		}
+		if result.Created > 0 || result.Updated > 0 || result.Expired > 0 {
+			logger.LegacyPrintf("service.proxy_subscription", "[ProxySubscription] subscription %d synced: created=%d updated=%d expired=%d",
+				sub.ID, result.Created, result.Updated, result.Expired)
+		}
+	}
+}
+
+// syncOne 拉取并应用订阅。拉取或解析失败、订阅为空时不改动任何代理，只记录错误。
+func (s *ProxySubscriptionService) syncOne(ctx context.Context, sub *ProxySubscription) (*ProxyImportResult, error) {
+	feed, err := s.fetch(ctx, sub)
+	if err == nil && len(feed.Entries) == 0 {
+		err = ErrProxySubscriptionEmpty
+	}
+	if err != nil {
+		if recErr := s.subRepo.RecordSyncResult(ctx, sub.ID, sub.ExpiresAt, err.Error()); recErr != nil {
+			logger.LegacyPrintf("service.proxy_subscription", "[ProxySubscription] record sync error for %d failed: %v", sub.ID, recErr)
+		}
+		if errors.Is(err, ErrProxySubscriptionEmpty) {
+			return nil, err
+		}
+		return nil, infraerrors.BadRequest("PROXY_SUBSCRIPTION_FETCH_FAILED", err.Error())
+	}
+
+	now := time.Now()
+	result, err := s.applyFeed(ctx, feed, now)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := s.subRepo.ListProxyIDs(ctx, sub.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range missingProxyIDs(previous, result.proxyIDs) {
+		expired, err := s.expireProxy(ctx, id, now)
+		if err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Sprintf("expire proxy %d: %v", id, err))
+			continue
+		}
+		if expired {
+			result.Expired++
+		}
+	}
+	if err := s.subRepo.SetProxyIDs(ctx, sub.ID, result.proxyIDs); err != nil {
+		return nil, err
+	}
+
+	lastError := ""
+	if result.Failed > 0 {
+		lastError = strings.Join(result.Errors, "; ")
+	}
+	if err := s.subRepo.RecordSyncResult(ctx, sub.ID, feed.ExpiresAt, lastError); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *ProxySubscriptionService) fetch(ctx context.Context, sub *ProxySubscription) (*proxysub.Feed, error) {
+	target, err := s.validateURL(sub.URL)
+	if err != nil {
+		return nil, err
+	}
+	opts := httpclient.Options{Timeout: proxySubscriptionFetchTimeout}
+	if s.cfg != nil {
+		opts.ValidateResolvedIP = s.cfg.Security.URLAllowlist.Enabled
+		opts.AllowPrivateHosts = s.cfg.Security.URLAllowlist.AllowPrivateHosts
+	}
+	client, err := httpclient.GetClient(opts)
+	if err != nil {
+		return nil, fmt.Errorf("create http client failed: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	// 部分供应商按 UA 决定下发格式，clash 兼容 UA 同时会返回 subscription-userinfo。
+	req.Header.Set("User-Agent", "clash-verge/v2")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch subscription: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch subscription: unexpected status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, proxySubscriptionMaxBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read subscription: %w", err)
+	}
+
+	feed, err := proxysub.Parse(body, sub.Format)
+	if err != nil {
+		return nil, err
+	}
+	feed.ExpiresAt = proxysub.ParseSubscriptionUserinfo(resp.Header.Get("Subscription-Userinfo"))
+	return feed, nil
+}
+
+// applyFeed 把订阅条目合并进 proxies：已存在的按 planProxySubscriptionUpdate 更新，不存在的新建。
+func (s *ProxySubscriptionService) applyFeed(ctx context.Context, feed *proxysub.Feed, now time.Time) (*ProxyImportResult, error) {
+	result := &ProxyImportResult{Format: feed.Format, Total: len(feed.Entries), Skipped: feed.Skipped}
+
+	existing, err := s.proxyRepo.ListAllForFallback(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*Proxy, len(existing))
+	for i := range existing {
+		p := &existing[i]
+		byKey[proxySubscriptionKey(p.Host, p.Port, p.Username, p.Password)] = p
+	}
+
+	for _, entry := range feed.Entries {
+		if p, ok := byKey[entry.Key()]; ok {
+			if planProxySubscriptionUpdate(p, feed.ExpiresAt, now) {
+				if err := s.proxyRepo.Update(ctx, p); err != nil {
+					result.Failed++
+					result.Errors = append(result.Errors, fmt.Sprintf("%s:%d: %v", entry.Host, entry.Port, err))
+					continue
+				}
+				result.Updated++
+			} else {
+				result.Unchanged++
+			}
+			result.proxyIDs = append(result.proxyIDs, p.ID)
+			continue
+		}
+
+		proxy := &Proxy{
+			Name:         entry.Name,
+			Protocol:     entry.Protocol,
+			Host:         entry.Host,
+			Port:         entry.Port,
+			Username:     entry.Username,
+			Password:     entry.Password,
+			Status:       StatusActive,
+			ExpiresAt:    feed.ExpiresAt,
+			FallbackMode: FallbackModeNone,
+		}
+		if err := s.proxyRepo.Create(ctx, proxy); err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Sprintf("%s:%d: %v", entry.Host, entry.Port, err))
+			continue
+		}
+		byKey[entry.Key()] = proxy
+		result.Created++
+		result.proxyIDs = append(result.proxyIDs, proxy.ID)
+	}
+	return result, nil
+}
+
+// expireProxy 将已从订阅下线的代理 expires_at 提前到 now；已过期或已停用的代理不动。
+func (s *ProxySubscriptionService) expireProxy(ctx context.Context, id int64, now time.Time) (bool, error) {
+	p, err := s.proxyRepo.GetByID(ctx, id)
+	if errors.Is(err, ErrProxyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if p.IsExpired(now) || p.Status != StatusActive {
+		return false, nil
+	}
+	p.ExpiresAt = &now
+	if err := s.proxyRepo.Update(ctx, p); err != nil {
+		return false, err
+	}
+	return true, nil
+}
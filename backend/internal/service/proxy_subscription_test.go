@@ -0,0 +1,63 @@
+//go:build unit
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanProxySubscriptionUpdate(t *testing.T) {
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	feedExpiry := now.Add(30 * 24 * time.Hour)
+	past := now.Add(-time.Hour)
+
+	t.Run("sync expiry from feed", func(t *testing.T) {
+		p := &Proxy{Status: StatusActive}
+		require.True(t, planProxySubscriptionUpdate(p, &feedExpiry, now))
+		require.Equal(t, &feedExpiry, p.ExpiresAt)
+	})
+
+	t.Run("unchanged when expiry equal", func(t *testing.T) {
+		same := feedExpiry
+		p := &Proxy{Status: StatusActive, ExpiresAt: &same}
+		require.False(t, planProxySubscriptionUpdate(p, &feedExpiry, now))
+	})
+
+	t.Run("keep manual expiry when feed has none", func(t *testing.T) {
+		future := now.Add(time.Hour)
+		p := &Proxy{Status: StatusActive, ExpiresAt: &future}
+		require.False(t, planProxySubscriptionUpdate(p, nil, now))
+		require.Equal(t, &future, p.ExpiresAt)
+	})
+
+	t.Run("revive proxy that reappeared", func(t *testing.T) {
+		p := &Proxy{Status: StatusExpired, ExpiresAt: &past}
+		require.True(t, planProxySubscriptionUpdate(p, nil, now))
+		require.Equal(t, StatusActive, p.Status)
+		require.Nil(t, p.ExpiresAt)
+	})
+
+	t.Run("admin-disabled proxy stays inactive", func(t *testing.T) {
+		p := &Proxy{Status: "inactive", ExpiresAt: &past}
+		require.True(t, planProxySubscriptionUpdate(p, &feedExpiry, now))
+		require.Equal(t, "inactive", p.Status)
+		require.Equal(t, &feedExpiry, p.ExpiresAt)
+	})
+}
+
+func TestMissingProxyIDs(t *testing.T) {
+	require.Equal(t, []int64{2, 4}, missingProxyIDs([]int64{1, 2, 3, 4}, []int64{3, 1, 5}))
+	require.Empty(t, missingProxyIDs(nil, []int64{1}))
+}
+
+func TestProxySubscriptionKey_IgnoresProtocolAndHostCase(t *testing.T) {
+	require.Equal(t,
+		proxySubscriptionKey("Proxy.Example.com", 1080, "u", "p"),
+		proxySubscriptionKey("proxy.example.com", 1080, "u", "p"))
+	require.NotEqual(t,
+		proxySubscriptionKey("proxy.example.com", 1080, "u", "p"),
+		proxySubscriptionKey("proxy.example.com", 1080, "u", "q"))
+}
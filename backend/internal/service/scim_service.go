@@ -0,0 +1,826 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+)
+
+const (
+	// SCIMDefaultPageSize / SCIMMaxPageSize 约束 ListResponse 的 count 参数。
+	SCIMDefaultPageSize = 100
+	SCIMMaxPageSize     = 200
+
+	// scimSubscriptionNotes 标记由 SCIM 开通的订阅，便于后台区分手工分配。
+	scimSubscriptionNotes = "provisioned via SCIM"
+)
+
+var (
+	ErrSCIMUserNotFound          = infraerrors.NotFound("SCIM_USER_NOT_FOUND", "user not found")
+	ErrSCIMGroupNotFound         = infraerrors.NotFound("SCIM_GROUP_NOT_FOUND", "group not found")
+	ErrSCIMUserExists            = infraerrors.Conflict("SCIM_USER_EXISTS", "a user with this userName already exists")
+	ErrSCIMGroupExists           = infraerrors.Conflict("SCIM_GROUP_EXISTS", "group is already provisioned")
+	ErrSCIMGroupNotMapped        = infraerrors.BadRequest("SCIM_GROUP_NOT_MAPPED", "displayName must match an existing group")
+	ErrSCIMGroupRenameDenied     = infraerrors.BadRequest("SCIM_MUTABILITY", "displayName of a provisioned group cannot be changed")
+	ErrSCIMInvalidFilter         = infraerrors.BadRequest("SCIM_INVALID_FILTER", "unsupported filter expression")
+	ErrSCIMInvalidValue          = infraerrors.BadRequest("SCIM_INVALID_VALUE", "invalid attribute value")
+	ErrSCIMInvalidPath           = infraerrors.BadRequest("SCIM_INVALID_PATH", "unsupported patch path")
+	ErrSCIMAdminUserProtected    = infraerrors.Forbidden("SCIM_ADMIN_USER_PROTECTED", "admin users cannot be managed via SCIM")
+	errSCIMMemberUserUnavailable = infraerrors.BadRequest("SCIM_INVALID_VALUE", "member does not reference an existing user")
+)
+
+// SCIMFilter 是解析后的 `attr eq "value"` 过滤表达式；Attribute 已转为小写。
+type SCIMFilter struct {
+	Attribute string
+	Value     string
+}
+
+// SCIMGroupRef 用户所属的 SCIM 分组。
+type SCIMGroupRef struct {
+	ID   int64
+	Name string
+}
+
+// SCIMUser 是 SCIM User 资源对应的用户视图。
+type SCIMUser struct {
+	ID         int64
+	Email      string
+	Username   string
+	Status     string
+	Role       string
+	ExternalID string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Groups     []SCIMGroupRef
+}
+
+// SCIMMember SCIM 分组成员。
+type SCIMMember struct {
+	UserID int64
+	Email  string
+}
+
+// SCIMGroup 是 SCIM Group 资源对应的（已关联的）sub2api 分组视图。
+type SCIMGroup struct {
+	ID         int64
+	Name       string
+	ExternalID string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Members    []SCIMMember
+}
+
+// SCIMMembership 记录 SCIM 为某个成员实际授予了什么，移除成员时只回收这些授权。
+type SCIMMembership struct {
+	GroupID             int64
+	UserID              int64
+	SubscriptionID      *int64
+	GrantedAllowedGroup bool
+}
+
+// SCIMUserInput 创建 / 整体替换用户时的输入（已从 SCIM 报文中提取）。
+type SCIMUserInput struct {
+	Email      string
+	Username   string
+	ExternalID string
+	Password   string
+	Active     *bool
+}
+
+// SCIMGroupInput 创建 / 整体替换分组时的输入。Members 为 SCIM member value（即用户 ID）。
+type SCIMGroupInput struct {
+	DisplayName string
+	ExternalID  string
+	Members     []string
+}
+
+// SCIMPatchOperation 是 PatchOp 请求中的单个操作，Value 保留原始 JSON 以兼容各 IdP 的写法。
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// SCIMRepository SCIM 关联关系与查询仓储。
+type SCIMRepository interface {
+	ListUsers(ctx context.Context, filter *SCIMFilter, offset, limit int) ([]SCIMUser, int64, error)
+	// GetUser 返回未删除用户（含所属 SCIM 分组），不存在时返回 ErrSCIMUserNotFound。
+	GetUser(ctx context.Context, userID int64) (*SCIMUser, error)
+	UpsertUserLink(ctx context.Context, userID int64, externalID string) error
+	DeleteUserLink(ctx context.Context, userID int64) error
+	// DisableUserAPIKeys 把用户所有启用中的 API Key 置为 disabled，返回受影响数量。
+	DisableUserAPIKeys(ctx context.Context, userID int64) (int64, error)
+
+	// FindGroupIDByName 按名称精确查找未删除分组，不存在时返回 ErrSCIMGroupNotMapped。
+	FindGroupIDByName(ctx context.Context, name string) (int64, error)
+	ListGroups(ctx context.Context, filter *SCIMFilter, offset, limit int) ([]SCIMGroup, int64, error)
+	// GetGroup 返回已关联的分组（含成员），未关联时返回 ErrSCIMGroupNotFound。
+	GetGroup(ctx context.Context, groupID int64) (*SCIMGroup, error)
+	UpsertGroupLink(ctx context.Context, groupID int64, externalID string) error
+	DeleteGroupLink(ctx context.Context, groupID int64) error
+
+	// GetMembership 不存在时返回 (nil, nil)。
+	GetMembership(ctx context.Context, groupID, userID int64) (*SCIMMembership, error)
+	ListGroupMemberships(ctx context.Context, groupID int64) ([]SCIMMembership, error)
+	ListUserMemberships(ctx context.Context, userID int64) ([]SCIMMembership, error)
+	CreateMembership(ctx context.Context, membership *SCIMMembership) error
+	DeleteMembership(ctx context.Context, groupID, userID int64) error
+}
+
+// scimSubscriptionManager 是 SCIM 授权所需的订阅能力子集（便于测试替换）。
+type scimSubscriptionManager interface {
+	GetActiveSubscription(ctx context.Context, userID, groupID int64) (*UserSubscription, error)
+	AssignSubscription(ctx context.Context, input *AssignSubscriptionInput) (*UserSubscription, error)
+	RevokeSubscription(ctx context.Context, subscriptionID int64) error
+}
+
+// scimSessionRevoker 复用「撤销全部会话」路径。
+type scimSessionRevoker interface {
+	RevokeAllUserTokens(ctx context.Context, userID int64) error
+}
+
+// SCIMService 实现 SCIM 2.0 用户与分组开通：
+// 用户映射到 sub2api 用户（userName = 邮箱），分组映射到同名的既有分组，
+// 分组成员关系落到订阅（订阅型分组）或 user_allowed_groups（其他分组）。
+type SCIMService struct {
+	repo                 SCIMRepository
+	userRepo             UserRepository
+	groupRepo            GroupRepository
+	adminService         AdminService
+	subscriptions        scimSubscriptionManager
+	sessions             scimSessionRevoker
+	authCacheInvalidator APIKeyAuthCacheInvalidator
+}
+
+// NewSCIMService 创建 SCIM 服务。
+func NewSCIMService(
+	repo SCIMRepository,
+	userRepo UserRepository,
+	groupRepo GroupRepository,
+	adminService AdminService,
+	subscriptionService *SubscriptionService,
+	authService *AuthService,
+	authCacheInvalidator APIKeyAuthCacheInvalidator,
+) *SCIMService {
+	return &SCIMService{
+		repo:                 repo,
+		userRepo:             userRepo,
+		groupRepo:            groupRepo,
+		adminService:         adminService,
+		subscriptions:        subscriptionService,
+		sessions:             authService,
+		authCacheInvalidator: authCacheInvalidator,
+	}
+}
+
+var scimFilterPattern = regexp.MustCompile(`(?i)^\s*([a-z][a-z0-9._]*)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// ParseSCIMFilter 解析 SCIM 过滤表达式。IdP 只会用 `attr eq "value"` 做存在性查询，
+// 这里只支持该形式，且属性必须在 allowed 中（小写比较）。空表达式返回 nil。
+func ParseSCIMFilter(expr string, allowed ...string) (*SCIMFilter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	match := scimFilterPattern.FindStringSubmatch(expr)
+	if match == nil {
+		return nil, ErrSCIMInvalidFilter
+	}
+	attr := strings.ToLower(match[1])
+	for _, candidate := range allowed {
+		if attr == strings.ToLower(candidate) {
+			value, err := strconv.Unquote(`"` + match[2] + `"`)
+			if err != nil {
+				return nil, ErrSCIMInvalidFilter
+			}
+			return &SCIMFilter{Attribute: attr, Value: value}, nil
+		}
+	}
+	return nil, ErrSCIMInvalidFilter
+}
+
+// ParseSCIMBool 解析布尔属性。Azure AD 在 PATCH 中会把 active 发成 "False" 字符串。
+func ParseSCIMBool(raw json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if parsed, parseErr := strconv.ParseBool(strings.TrimSpace(s)); parseErr == nil {
+			return parsed, nil
+		}
+	}
+	return false, ErrSCIMInvalidValue
+}
+
+// NormalizeSCIMPage 把 startIndex（1 起始）/ count 转成 offset / limit。
+func NormalizeSCIMPage(startIndex, count int) (offset, limit int) {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = 0
+	}
+	if count > SCIMMaxPageSize {
+		count = SCIMMaxPageSize
+	}
+	return startIndex - 1, count
+}
+
+// ListUsers 按过滤条件分页列出用户。
+func (s *SCIMService) ListUsers(ctx context.Context, filter *SCIMFilter, offset, limit int) ([]SCIMUser, int64, error) {
+	return s.repo.ListUsers(ctx, filter, offset, limit)
+}
+
+// GetUser 获取单个用户。
+func (s *SCIMService) GetUser(ctx context.Context, userID int64) (*SCIMUser, error) {
+	return s.repo.GetUser(ctx, userID)
+}
+
+// CreateUser 开通新用户。邮箱已存在时返回 409，由 IdP 通过 userName 过滤查询后关联既有账号。
+func (s *SCIMService) CreateUser(ctx context.Context, actorID int64, input *SCIMUserInput) (*SCIMUser, error) {
+	email, err := normalizeSCIMEmail(input.Email)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := s.userRepo.ExistsByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("check scim user email: %w", err)
+	}
+	if exists {
+		return nil, ErrSCIMUserExists
+	}
+
+	password := input.Password
+	if password == "" {
+		// SCIM 用户通过 SSO 登录，本地密码随机生成且不对外暴露。
+		if password, err = randomHexString(24); err != nil {
+			return nil, fmt.Errorf("generate scim user password: %w", err)
+		}
+	}
+	user, err := s.adminService.CreateUser(ctx, &CreateUserInput{
+		Email:        email,
+		Password:     password,
+		Username:     scimUsername(input.Username, email),
+		Notes:        "provisioned via SCIM",
+		ActorAdminID: actorID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpsertUserLink(ctx, user.ID, strings.TrimSpace(input.ExternalID)); err != nil {
+		return nil, err
+	}
+	if input.Active != nil && !*input.Active {
+		if err := s.setUserActive(ctx, actorID, user, false); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.GetUser(ctx, user.ID)
+}
+
+// ReplaceUser 处理 PUT：以请求内容覆盖邮箱、显示名、externalId 与启用状态。
+func (s *SCIMService) ReplaceUser(ctx context.Context, actorID, userID int64, input *SCIMUserInput) (*SCIMUser, error) {
+	email, err := normalizeSCIMEmail(input.Email)
+	if err != nil {
+		return nil, err
+	}
+	username := scimUsername(input.Username, email)
+	externalID := strings.TrimSpace(input.ExternalID)
+	return s.applyUserChanges(ctx, actorID, userID, &scimUserChanges{
+		Email:      &email,
+		Username:   &username,
+		ExternalID: &externalID,
+		Active:     input.Active,
+	})
+}
+
+// PatchUser 处理 PATCH。兼容 Okta（带 path）与 Azure AD（无 path、值为对象、布尔值为字符串）的写法，
+// 未识别的属性（如 name.givenName、emails 等）按 SCIM 规范之外的宽松策略忽略。
+func (s *SCIMService) PatchUser(ctx context.Context, actorID, userID int64, ops []SCIMPatchOperation) (*SCIMUser, error) {
+	changes := &scimUserChanges{}
+	for _, op := range ops {
+		switch strings.ToLower(strings.TrimSpace(op.Op)) {
+		case "add", "replace":
+		case "remove":
+			// 用户上没有可移除的可写属性；externalId 置空视为解除关联标识。
+			if strings.EqualFold(strings.TrimSpace(op.Path), "externalId") {
+				empty := ""
+				changes.ExternalID = &empty
+			}
+			continue
+		default:
+			return nil, ErrSCIMInvalidValue
+		}
+		if strings.TrimSpace(op.Path) == "" {
+			var values map[string]json.RawMessage
+			if err := json.Unmarshal(op.Value, &values); err != nil {
+				return nil, ErrSCIMInvalidValue
+			}
+			for attr, value := range values {
+				if err := changes.apply(attr, value); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if err := changes.apply(op.Path, op.Value); err != nil {
+			return nil, err
+		}
+	}
+	return s.applyUserChanges(ctx, actorID, userID, changes)
+}
+
+// DeleteUser 停用用户（禁用 Key、撤销会话）、回收 SCIM 授予的分组权限后软删除用户。
+func (s *SCIMService) DeleteUser(ctx context.Context, actorID, userID int64) error {
+	user, err := s.loadManagedUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.setUserActive(ctx, actorID, user, false); err != nil {
+		return err
+	}
+	memberships, err := s.repo.ListUserMemberships(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for i := range memberships {
+		if err := s.revokeMembership(ctx, &memberships[i]); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.DeleteUserLink(ctx, userID); err != nil {
+		return err
+	}
+	return s.adminService.DeleteUser(ctx, userID)
+}
+
+// scimUserChanges 是一次更新中被显式提供的属性；nil 表示不修改。
+type scimUserChanges struct {
+	Email      *string
+	Username   *string
+	ExternalID *string
+	Active     *bool
+}
+
+func (c *scimUserChanges) apply(attr string, value json.RawMessage) error {
+	switch strings.ToLower(strings.TrimSpace(attr)) {
+	case "active":
+		active, err := ParseSCIMBool(value)
+		if err != nil {
+			return err
+		}
+		c.Active = &active
+	case "username":
+		var email string
+		if err := json.Unmarshal(value, &email); err != nil {
+			return ErrSCIMInvalidValue
+		}
+		normalized, err := normalizeSCIMEmail(email)
+		if err != nil {
+			return err
+		}
+		c.Email = &normalized
+	case "externalid":
+		var externalID string
+		if err := json.Unmarshal(value, &externalID); err != nil {
+			return ErrSCIMInvalidValue
+		}
+		externalID = strings.TrimSpace(externalID)
+		c.ExternalID = &externalID
+	case "displayname", "name.formatted":
+		var name string
+		if err := json.Unmarshal(value, &name); err != nil {
+			return ErrSCIMInvalidValue
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			c.Username = &name
+		}
+	case "name":
+		var name struct {
+			Formatted string `json:"formatted"`
+		}
+		if err := json.Unmarshal(value, &name); err != nil {
+			return ErrSCIMInvalidValue
+		}
+		if formatted := strings.TrimSpace(name.Formatted); formatted != "" {
+			c.Username = &formatted
+		}
+	}
+	return nil
+}
+
+func (s *SCIMService) applyUserChanges(ctx context.Context, actorID, userID int64, changes *scimUserChanges) (*SCIMUser, error) {
+	user, err := s.loadManagedUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	update := &UpdateUserInput{ActorAdminID: actorID}
+	dirty := false
+	if changes.Email != nil && !strings.EqualFold(*changes.Email, user.Email) {
+		exists, err := s.userRepo.ExistsByEmail(ctx, *changes.Email)
+		if err != nil {
+			return nil, fmt.Errorf("check scim user email: %w", err)
+		}
+		if exists {
+			return nil, ErrSCIMUserExists
+		}
+		update.Email = *changes.Email
+		dirty = true
+	}
+	if changes.Username != nil && *changes.Username != user.Username {
+		update.Username = changes.Username
+		dirty = true
+	}
+	if dirty {
+		if user, err = s.adminService.UpdateUser(ctx, userID, update); err != nil {
+			return nil, err
+		}
+	}
+	if changes.ExternalID != nil {
+		if err := s.repo.UpsertUserLink(ctx, userID, *changes.ExternalID); err != nil {
+			return nil, err
+		}
+	}
+	if changes.Active != nil {
+		if err := s.setUserActive(ctx, actorID, user, *changes.Active); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.GetUser(ctx, userID)
+}
+
+// setUserActive 切换用户启用状态。停用时禁用其全部 API Key、失效认证缓存，
+// 并走 RevokeAllUserTokens 撤销所有登录会话；重新启用不会自动恢复已禁用的 Key。
+func (s *SCIMService) setUserActive(ctx context.Context, actorID int64, user *User, active bool) error {
+	if active {
+		if user.Status == StatusActive {
+			return nil
+		}
+		_, err := s.adminService.UpdateUser(ctx, user.ID, &UpdateUserInput{Status: StatusActive, ActorAdminID: actorID})
+		return err
+	}
+
+	if user.Status != StatusDisabled {
+		if _, err := s.adminService.UpdateUser(ctx, user.ID, &UpdateUserInput{Status: StatusDisabled, ActorAdminID: actorID}); err != nil {
+			return err
+		}
+	}
+	disabled, err := s.repo.DisableUserAPIKeys(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if s.authCacheInvalidator != nil {
+		s.authCacheInvalidator.InvalidateAuthCacheByUserID(ctx, user.ID)
+	}
+	if err := s.sessions.RevokeAllUserTokens(ctx, user.ID); err != nil {
+		return fmt.Errorf("revoke scim user sessions: %w", err)
+	}
+	logger.LegacyPrintf("service.scim", "audit: user deactivated via SCIM actor_admin_id=%d target_user_id=%d disabled_api_keys=%d",
+		actorID, user.ID, disabled)
+	return nil
+}
+
+// loadManagedUser 加载可被 SCIM 修改的用户：管理员账号只读，防止 IdP 误操作锁死管理面。
+func (s *SCIMService) loadManagedUser(ctx context.Context, userID int64) (*User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrSCIMUserNotFound
+		}
+		return nil, err
+	}
+	if user.IsAdmin() {
+		return nil, ErrSCIMAdminUserProtected
+	}
+	return user, nil
+}
+
+// ListGroups 分页列出已由 SCIM 关联的分组。
+func (s *SCIMService) ListGroups(ctx context.Context, filter *SCIMFilter, offset, limit int) ([]SCIMGroup, int64, error) {
+	return s.repo.ListGroups(ctx, filter, offset, limit)
+}
+
+// GetGroup 获取单个已关联分组。
+func (s *SCIMService) GetGroup(ctx context.Context, groupID int64) (*SCIMGroup, error) {
+	return s.repo.GetGroup(ctx, groupID)
+}
+
+// CreateGroup 把 IdP 分组按 displayName 关联到同名的既有分组（不会新建 sub2api 分组），
+// 并按 members 开通成员权限。
+func (s *SCIMService) CreateGroup(ctx context.Context, actorID int64, input *SCIMGroupInput) (*SCIMGroup, error) {
+	groupID, err := s.repo.FindGroupIDByName(ctx, strings.TrimSpace(input.DisplayName))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.GetGroup(ctx, groupID); err == nil {
+		return nil, ErrSCIMGroupExists
+	} else if !errors.Is(err, ErrSCIMGroupNotFound) {
+		return nil, err
+	}
+	if err := s.repo.UpsertGroupLink(ctx, groupID, strings.TrimSpace(input.ExternalID)); err != nil {
+		return nil, err
+	}
+	if err := s.setGroupMembers(ctx, actorID, groupID, input.Members); err != nil {
+		return nil, err
+	}
+	return s.repo.GetGroup(ctx, groupID)
+}
+
+// ReplaceGroup 处理 PUT：成员集合整体替换为请求中的 members。
+func (s *SCIMService) ReplaceGroup(ctx context.Context, actorID, groupID int64, input *SCIMGroupInput) (*SCIMGroup, error) {
+	group, err := s.repo.GetGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if name := strings.TrimSpace(input.DisplayName); name != "" && name != group.Name {
+		return nil, ErrSCIMGroupRenameDenied
+	}
+	if err := s.repo.UpsertGroupLink(ctx, groupID, strings.TrimSpace(input.ExternalID)); err != nil {
+		return nil, err
+	}
+	if err := s.setGroupMembers(ctx, actorID, groupID, input.Members); err != nil {
+		return nil, err
+	}
+	return s.repo.GetGroup(ctx, groupID)
+}
+
+// PatchGroup 处理 PATCH 的成员增删：
+//   - add members: [{"value":"<id>"}]
+//   - remove members[value eq "<id>"]（Okta）或 members + value 列表（Azure AD）
+//   - replace members: 整体替换
+func (s *SCIMService) PatchGroup(ctx context.Context, actorID, groupID int64, ops []SCIMPatchOperation) (*SCIMGroup, error) {
+	group, err := s.repo.GetGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		path := strings.TrimSpace(op.Path)
+		opName := strings.ToLower(strings.TrimSpace(op.Op))
+		if path == "" {
+			var values map[string]json.RawMessage
+			if err := json.Unmarshal(op.Value, &values); err != nil {
+				return nil, ErrSCIMInvalidValue
+			}
+			for attr, value := range values {
+				if err := s.patchGroupAttribute(ctx, actorID, group, opName, attr, value); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if err := s.patchGroupAttribute(ctx, actorID, group, opName, path, op.Value); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.GetGroup(ctx, groupID)
+}
+
+var scimMemberPathPattern = regexp.MustCompile(`(?i)^members\[\s*value\s+eq\s+"([^"]*)"\s*\]$`)
+
+func (s *SCIMService) patchGroupAttribute(ctx context.Context, actorID int64, group *SCIMGroup, op, path string, value json.RawMessage) error {
+	if match := scimMemberPathPattern.FindStringSubmatch(path); match != nil {
+		if op != "remove" {
+			return ErrSCIMInvalidPath
+		}
+		return s.removeGroupMembers(ctx, group.ID, []string{match[1]})
+	}
+
+	switch strings.ToLower(path) {
+	case "members":
+		var members []string
+		if len(value) > 0 && string(value) != "null" {
+			var err error
+			if members, err = parseSCIMMemberValues(value); err != nil {
+				return err
+			}
+		}
+		switch op {
+		case "add":
+			return s.addGroupMembers(ctx, actorID, group.ID, members)
+		case "remove":
+			if members == nil {
+				return s.setGroupMembers(ctx, actorID, group.ID, nil)
+			}
+			return s.removeGroupMembers(ctx, group.ID, members)
+		case "replace":
+			return s.setGroupMembers(ctx, actorID, group.ID, members)
+		}
+		return ErrSCIMInvalidValue
+	case "displayname":
+		var name string
+		if err := json.Unmarshal(value, &name); err != nil {
+			return ErrSCIMInvalidValue
+		}
+		if strings.TrimSpace(name) != group.Name {
+			return ErrSCIMGroupRenameDenied
+		}
+		return nil
+	case "externalid":
+		var externalID string
+		if op != "remove" {
+			if err := json.Unmarshal(value, &externalID); err != nil {
+				return ErrSCIMInvalidValue
+			}
+		}
+		return s.repo.UpsertGroupLink(ctx, group.ID, strings.TrimSpace(externalID))
+	}
+	return ErrSCIMInvalidPath
+}
+
+// DeleteGroup 解除关联：回收所有 SCIM 授予的成员权限，保留 sub2api 分组本身。
+func (s *SCIMService) DeleteGroup(ctx context.Context, actorID, groupID int64) error {
+	if _, err := s.repo.GetGroup(ctx, groupID); err != nil {
+		return err
+	}
+	if err := s.setGroupMembers(ctx, actorID, groupID, nil); err != nil {
+		return err
+	}
+	return s.repo.DeleteGroupLink(ctx, groupID)
+}
+
+func parseSCIMMemberValues(raw json.RawMessage) ([]string, error) {
+	var members []struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &members); err != nil {
+		return nil, ErrSCIMInvalidValue
+	}
+	values := make([]string, 0, len(members))
+	for _, member := range members {
+		values = append(values, member.Value)
+	}
+	return values, nil
+}
+
+func parseSCIMMemberIDs(values []string) ([]int64, error) {
+	ids := make([]int64, 0, len(values))
+	seen := make(map[int64]struct{}, len(values))
+	for _, value := range values {
+		id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || id <= 0 {
+			return nil, errSCIMMemberUserUnavailable
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (s *SCIMService) setGroupMembers(ctx context.Context, actorID, groupID int64, values []string) error {
+	wanted, err := parseSCIMMemberIDs(values)
+	if err != nil {
+		return err
+	}
+	current, err := s.repo.ListGroupMemberships(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	keep := make(map[int64]struct{}, len(wanted))
+	for _, id := range wanted {
+		keep[id] = struct{}{}
+	}
+	for i := range current {
+		if _, ok := keep[current[i].UserID]; ok {
+			continue
+		}
+		if err := s.revokeMembership(ctx, &current[i]); err != nil {
+			return err
+		}
+	}
+	return s.grantMembers(ctx, actorID, groupID, wanted)
+}
+
+func (s *SCIMService) addGroupMembers(ctx context.Context, actorID, groupID int64, values []string) error {
+	ids, err := parseSCIMMemberIDs(values)
+	if err != nil {
+		return err
+	}
+	return s.grantMembers(ctx, actorID, groupID, ids)
+}
+
+func (s *SCIMService) removeGroupMembers(ctx context.Context, groupID int64, values []string) error {
+	ids, err := parseSCIMMemberIDs(values)
+	if err != nil {
+		return err
+	}
+	for _, userID := range ids {
+		membership, err := s.repo.GetMembership(ctx, groupID, userID)
+		if err != nil {
+			return err
+		}
+		if membership == nil {
+			continue
+		}
+		if err := s.revokeMembership(ctx, membership); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// grantMembers 为成员开通分组权限：订阅型分组分配订阅，其他分组加入 allowed_groups。
+// 用户已经通过其他途径拥有的权限只记录成员关系，不登记为 SCIM 授权，移除时也不会回收。
+func (s *SCIMService) grantMembers(ctx context.Context, actorID, groupID int64, userIDs []int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	group, err := s.groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		existing, err := s.repo.GetMembership(ctx, groupID, userID)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			continue
+		}
+		user, err := s.userRepo.GetByID(ctx, userID)
+		if err != nil {
+			if errors.Is(err, ErrUserNotFound) {
+				return errSCIMMemberUserUnavailable
+			}
+			return err
+		}
+
+		membership := &SCIMMembership{GroupID: groupID, UserID: userID}
+		if group.IsSubscriptionType() {
+			if _, err := s.subscriptions.GetActiveSubscription(ctx, userID, groupID); err != nil {
+				if !errors.Is(err, ErrSubscriptionNotFound) {
+					return err
+				}
+				sub, assignErr := s.subscriptions.AssignSubscription(ctx, &AssignSubscriptionInput{
+					UserID:       userID,
+					GroupID:      groupID,
+					ValidityDays: MaxValidityDays,
+					AssignedBy:   actorID,
+					Notes:        scimSubscriptionNotes,
+				})
+				if assignErr != nil {
+					return assignErr
+				}
+				membership.SubscriptionID = &sub.ID
+			}
+		} else if !containsInt64(user.AllowedGroups, groupID) {
+			if err := s.userRepo.AddGroupToAllowedGroups(ctx, userID, groupID); err != nil {
+				return err
+			}
+			membership.GrantedAllowedGroup = true
+			if s.authCacheInvalidator != nil {
+				s.authCacheInvalidator.InvalidateAuthCacheByUserID(ctx, userID)
+			}
+		}
+		if err := s.repo.CreateMembership(ctx, membership); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeMembership 只回收 SCIM 自己授予的订阅 / allowed_groups，随后删除成员关系。
+func (s *SCIMService) revokeMembership(ctx context.Context, membership *SCIMMembership) error {
+	if membership.SubscriptionID != nil {
+		if err := s.subscriptions.RevokeSubscription(ctx, *membership.SubscriptionID); err != nil && !errors.Is(err, ErrSubscriptionNotFound) {
+			return err
+		}
+	}
+	if membership.GrantedAllowedGroup {
+		if err := s.userRepo.RemoveGroupFromUserAllowedGroups(ctx, membership.UserID, membership.GroupID); err != nil {
+			return err
+		}
+		if s.authCacheInvalidator != nil {
+			s.authCacheInvalidator.InvalidateAuthCacheByUserID(ctx, membership.UserID)
+		}
+	}
+	return s.repo.DeleteMembership(ctx, membership.GroupID, membership.UserID)
+}
+
+func normalizeSCIMEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" || !strings.Contains(email, "@") || len(email) > 255 {
+		return "", ErrSCIMInvalidValue
+	}
+	return email, nil
+}
+
+func scimUsername(username, email string) string {
+	if username = strings.TrimSpace(username); username != "" {
+		return username
+	}
+	local, _, _ := strings.Cut(email, "@")
+	return local
+}
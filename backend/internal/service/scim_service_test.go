@@ -0,0 +1,487 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type scimRepoStub struct {
+	users         map[int64]*SCIMUser
+	groups        map[int64]*SCIMGroup
+	groupNames    map[string]int64
+	memberships   map[[2]int64]SCIMMembership
+	disabledCalls []int64
+}
+
+func newSCIMRepoStub() *scimRepoStub {
+	return &scimRepoStub{
+		users:       map[int64]*SCIMUser{},
+		groups:      map[int64]*SCIMGroup{},
+		groupNames:  map[string]int64{},
+		memberships: map[[2]int64]SCIMMembership{},
+	}
+}
+
+func (r *scimRepoStub) ListUsers(context.Context, *SCIMFilter, int, int) ([]SCIMUser, int64, error) {
+	panic("unexpected ListUsers call")
+}
+
+func (r *scimRepoStub) GetUser(_ context.Context, userID int64) (*SCIMUser, error) {
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, ErrSCIMUserNotFound
+	}
+	cp := *user
+	return &cp, nil
+}
+
+func (r *scimRepoStub) UpsertUserLink(_ context.Context, userID int64, externalID string) error {
+	if user, ok := r.users[userID]; ok {
+		user.ExternalID = externalID
+		return nil
+	}
+	r.users[userID] = &SCIMUser{ID: userID, ExternalID: externalID}
+	return nil
+}
+
+func (r *scimRepoStub) DeleteUserLink(_ context.Context, userID int64) error {
+	if user, ok := r.users[userID]; ok {
+		user.ExternalID = ""
+	}
+	return nil
+}
+
+func (r *scimRepoStub) DisableUserAPIKeys(_ context.Context, userID int64) (int64, error) {
+	r.disabledCalls = append(r.disabledCalls, userID)
+	return 2, nil
+}
+
+func (r *scimRepoStub) FindGroupIDByName(_ context.Context, name string) (int64, error) {
+	id, ok := r.groupNames[name]
+	if !ok {
+		return 0, ErrSCIMGroupNotMapped
+	}
+	return id, nil
+}
+
+func (r *scimRepoStub) ListGroups(context.Context, *SCIMFilter, int, int) ([]SCIMGroup, int64, error) {
+	panic("unexpected ListGroups call")
+}
+
+func (r *scimRepoStub) GetGroup(_ context.Context, groupID int64) (*SCIMGroup, error) {
+	group, ok := r.groups[groupID]
+	if !ok {
+		return nil, ErrSCIMGroupNotFound
+	}
+	cp := *group
+	cp.Members = nil
+	for key := range r.memberships {
+		if key[0] == groupID {
+			cp.Members = append(cp.Members, SCIMMember{UserID: key[1]})
+		}
+	}
+	return &cp, nil
+}
+
+func (r *scimRepoStub) UpsertGroupLink(_ context.Context, groupID int64, externalID string) error {
+	for name, id := range r.groupNames {
+		if id == groupID {
+			r.groups[groupID] = &SCIMGroup{ID: groupID, Name: name, ExternalID: externalID}
+		}
+	}
+	return nil
+}
+
+func (r *scimRepoStub) DeleteGroupLink(_ context.Context, groupID int64) error {
+	delete(r.groups, groupID)
+	return nil
+}
+
+func (r *scimRepoStub) GetMembership(_ context.Context, groupID, userID int64) (*SCIMMembership, error) {
+	membership, ok := r.memberships[[2]int64{groupID, userID}]
+	if !ok {
+		return nil, nil
+	}
+	return &membership, nil
+}
+
+func (r *scimRepoStub) ListGroupMemberships(_ context.Context, groupID int64) ([]SCIMMembership, error) {
+	out := make([]SCIMMembership, 0)
+	for key, membership := range r.memberships {
+		if key[0] == groupID {
+			out = append(out, membership)
+		}
+	}
+	return out, nil
+}
+
+func (r *scimRepoStub) ListUserMemberships(_ context.Context, userID int64) ([]SCIMMembership, error) {
+	out := make([]SCIMMembership, 0)
+	for key, membership := range r.memberships {
+		if key[1] == userID {
+			out = append(out, membership)
+		}
+	}
+	return out, nil
+}
+
+func (r *scimRepoStub) CreateMembership(_ context.Context, membership *SCIMMembership) error {
+	r.memberships[[2]int64{membership.GroupID, membership.UserID}] = *membership
+	return nil
+}
+
+func (r *scimRepoStub) DeleteMembership(_ context.Context, groupID, userID int64) error {
+	delete(r.memberships, [2]int64{groupID, userID})
+	return nil
+}
+
+type scimUserRepoStub struct {
+	UserRepository
+	users         map[int64]*User
+	addedGroups   [][2]int64
+	removedGroups [][2]int64
+}
+
+func (r *scimUserRepoStub) GetByID(_ context.Context, id int64) (*User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	cp := *user
+	return &cp, nil
+}
+
+func (r *scimUserRepoStub) ExistsByEmail(_ context.Context, email string) (bool, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *scimUserRepoStub) AddGroupToAllowedGroups(_ context.Context, userID, groupID int64) error {
+	r.addedGroups = append(r.addedGroups, [2]int64{userID, groupID})
+	r.users[userID].AllowedGroups = append(r.users[userID].AllowedGroups, groupID)
+	return nil
+}
+
+func (r *scimUserRepoStub) RemoveGroupFromUserAllowedGroups(_ context.Context, userID, groupID int64) error {
+	r.removedGroups = append(r.removedGroups, [2]int64{userID, groupID})
+	return nil
+}
+
+type scimGroupRepoStub struct {
+	GroupRepository
+	groups map[int64]*Group
+}
+
+func (r *scimGroupRepoStub) GetByID(_ context.Context, id int64) (*Group, error) {
+	group, ok := r.groups[id]
+	if !ok {
+		return nil, ErrGroupNotFound
+	}
+	return group, nil
+}
+
+type scimAdminServiceStub struct {
+	AdminService
+	userRepo *scimUserRepoStub
+	scimRepo *scimRepoStub
+	nextID   int64
+	updates  []UpdateUserInput
+	deleted  []int64
+}
+
+func (s *scimAdminServiceStub) CreateUser(_ context.Context, input *CreateUserInput) (*User, error) {
+	s.nextID++
+	user := &User{ID: s.nextID, Email: input.Email, Username: input.Username, Role: RoleUser, Status: StatusActive}
+	s.userRepo.users[user.ID] = user
+	s.scimRepo.users[user.ID] = &SCIMUser{ID: user.ID, Email: user.Email, Username: user.Username, Status: user.Status}
+	return user, nil
+}
+
+func (s *scimAdminServiceStub) UpdateUser(_ context.Context, id int64, input *UpdateUserInput) (*User, error) {
+	s.updates = append(s.updates, *input)
+	user := s.userRepo.users[id]
+	if input.Status != "" {
+		user.Status = input.Status
+		s.scimRepo.users[id].Status = input.Status
+	}
+	if input.Email != "" {
+		user.Email = input.Email
+	}
+	cp := *user
+	return &cp, nil
+}
+
+func (s *scimAdminServiceStub) DeleteUser(_ context.Context, id int64) error {
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+type scimSubscriptionStub struct {
+	active   map[[2]int64]*UserSubscription
+	assigned []AssignSubscriptionInput
+	revoked  []int64
+}
+
+func (s *scimSubscriptionStub) GetActiveSubscription(_ context.Context, userID, groupID int64) (*UserSubscription, error) {
+	if sub, ok := s.active[[2]int64{userID, groupID}]; ok {
+		return sub, nil
+	}
+	return nil, ErrSubscriptionNotFound
+}
+
+func (s *scimSubscriptionStub) AssignSubscription(_ context.Context, input *AssignSubscriptionInput) (*UserSubscription, error) {
+	s.assigned = append(s.assigned, *input)
+	return &UserSubscription{ID: 900 + int64(len(s.assigned)), UserID: input.UserID, GroupID: input.GroupID}, nil
+}
+
+func (s *scimSubscriptionStub) RevokeSubscription(_ context.Context, subscriptionID int64) error {
+	s.revoked = append(s.revoked, subscriptionID)
+	return nil
+}
+
+type scimSessionStub struct {
+	revoked []int64
+}
+
+func (s *scimSessionStub) RevokeAllUserTokens(_ context.Context, userID int64) error {
+	s.revoked = append(s.revoked, userID)
+	return nil
+}
+
+type scimCacheInvalidatorStub struct {
+	APIKeyAuthCacheInvalidator
+	users []int64
+}
+
+func (s *scimCacheInvalidatorStub) InvalidateAuthCacheByUserID(_ context.Context, userID int64) {
+	s.users = append(s.users, userID)
+}
+
+type scimTestEnv struct {
+	svc      *SCIMService
+	repo     *scimRepoStub
+	users    *scimUserRepoStub
+	admin    *scimAdminServiceStub
+	subs     *scimSubscriptionStub
+	sessions *scimSessionStub
+	cache    *scimCacheInvalidatorStub
+}
+
+func newSCIMTestEnv() *scimTestEnv {
+	repo := newSCIMRepoStub()
+	users := &scimUserRepoStub{users: map[int64]*User{}}
+	admin := &scimAdminServiceStub{userRepo: users, scimRepo: repo, nextID: 100}
+	env := &scimTestEnv{
+		repo:     repo,
+		users:    users,
+		admin:    admin,
+		subs:     &scimSubscriptionStub{active: map[[2]int64]*UserSubscription{}},
+		sessions: &scimSessionStub{},
+		cache:    &scimCacheInvalidatorStub{},
+	}
+	groups := &scimGroupRepoStub{groups: map[int64]*Group{
+		1: {ID: 1, Name: "team-exclusive", IsExclusive: true, SubscriptionType: SubscriptionTypeStandard},
+		2: {ID: 2, Name: "team-plan", SubscriptionType: SubscriptionTypeSubscription},
+	}}
+	repo.groupNames["team-exclusive"] = 1
+	repo.groupNames["team-plan"] = 2
+	env.svc = &SCIMService{
+		repo:                 repo,
+		userRepo:             users,
+		groupRepo:            groups,
+		adminService:         admin,
+		subscriptions:        env.subs,
+		sessions:             env.sessions,
+		authCacheInvalidator: env.cache,
+	}
+	return env
+}
+
+func TestParseSCIMFilter(t *testing.T) {
+	filter, err := ParseSCIMFilter(`userName eq "alice@example.com"`, "userName", "externalId")
+	require.NoError(t, err)
+	require.Equal(t, &SCIMFilter{Attribute: "username", Value: "alice@example.com"}, filter)
+
+	filter, err = ParseSCIMFilter(`externalId EQ "a\"b"`, "userName", "externalId")
+	require.NoError(t, err)
+	require.Equal(t, `a"b`, filter.Value)
+
+	filter, err = ParseSCIMFilter("  ", "userName")
+	require.NoError(t, err)
+	require.Nil(t, filter)
+
+	_, err = ParseSCIMFilter(`userName co "alice"`, "userName")
+	require.ErrorIs(t, err, ErrSCIMInvalidFilter)
+	_, err = ParseSCIMFilter(`password eq "x"`, "userName")
+	require.ErrorIs(t, err, ErrSCIMInvalidFilter)
+}
+
+func TestParseSCIMBool(t *testing.T) {
+	for raw, want := range map[string]bool{`true`: true, `false`: false, `"False"`: false, `"True"`: true} {
+		got, err := ParseSCIMBool(json.RawMessage(raw))
+		require.NoError(t, err, raw)
+		require.Equal(t, want, got, raw)
+	}
+	_, err := ParseSCIMBool(json.RawMessage(`"maybe"`))
+	require.ErrorIs(t, err, ErrSCIMInvalidValue)
+}
+
+func TestNormalizeSCIMPage(t *testing.T) {
+	offset, limit := NormalizeSCIMPage(0, 1000)
+	require.Equal(t, 0, offset)
+	require.Equal(t, SCIMMaxPageSize, limit)
+
+	offset, limit = NormalizeSCIMPage(11, 10)
+	require.Equal(t, 10, offset)
+	require.Equal(t, 10, limit)
+}
+
+func TestSCIMCreateUser(t *testing.T) {
+	env := newSCIMTestEnv()
+
+	user, err := env.svc.CreateUser(context.Background(), 7, &SCIMUserInput{Email: "alice@example.com", ExternalID: "00u1"})
+	require.NoError(t, err)
+	require.Equal(t, "alice@example.com", user.Email)
+	require.Equal(t, "alice", user.Username)
+	require.Equal(t, "00u1", user.ExternalID)
+	require.Equal(t, StatusActive, user.Status)
+
+	_, err = env.svc.CreateUser(context.Background(), 7, &SCIMUserInput{Email: "alice@example.com"})
+	require.ErrorIs(t, err, ErrSCIMUserExists)
+
+	_, err = env.svc.CreateUser(context.Background(), 7, &SCIMUserInput{Email: "not-an-email"})
+	require.ErrorIs(t, err, ErrSCIMInvalidValue)
+}
+
+func TestSCIMPatchUserDeactivateDisablesKeysAndRevokesSessions(t *testing.T) {
+	env := newSCIMTestEnv()
+	env.users.users[5] = &User{ID: 5, Email: "bob@example.com", Role: RoleUser, Status: StatusActive}
+	env.repo.users[5] = &SCIMUser{ID: 5, Email: "bob@example.com", Status: StatusActive}
+
+	// Azure AD 形式：无 path，布尔值为字符串。
+	user, err := env.svc.PatchUser(context.Background(), 7, 5, []SCIMPatchOperation{{
+		Op:    "Replace",
+		Value: json.RawMessage(`{"active":"False"}`),
+	}})
+	require.NoError(t, err)
+	require.Equal(t, StatusDisabled, user.Status)
+	require.Equal(t, []int64{5}, env.repo.disabledCalls)
+	require.Equal(t, []int64{5}, env.sessions.revoked)
+	require.Contains(t, env.cache.users, int64(5))
+
+	// 重新启用不恢复 API Key，也不再撤销会话。
+	user, err = env.svc.PatchUser(context.Background(), 7, 5, []SCIMPatchOperation{{
+		Op: "replace", Path: "active", Value: json.RawMessage(`true`),
+	}})
+	require.NoError(t, err)
+	require.Equal(t, StatusActive, user.Status)
+	require.Len(t, env.repo.disabledCalls, 1)
+	require.Len(t, env.sessions.revoked, 1)
+}
+
+func TestSCIMRejectsAdminUsers(t *testing.T) {
+	env := newSCIMTestEnv()
+	env.users.users[1] = &User{ID: 1, Email: "root@example.com", Role: RoleAdmin, Status: StatusActive}
+
+	_, err := env.svc.PatchUser(context.Background(), 7, 1, []SCIMPatchOperation{{
+		Op: "replace", Path: "active", Value: json.RawMessage(`false`),
+	}})
+	require.ErrorIs(t, err, ErrSCIMAdminUserProtected)
+	require.ErrorIs(t, env.svc.DeleteUser(context.Background(), 7, 1), ErrSCIMAdminUserProtected)
+	require.Empty(t, env.repo.disabledCalls)
+	require.Empty(t, env.sessions.revoked)
+}
+
+func TestSCIMGroupMembershipGrantsAndRevokesOnlyOwnGrants(t *testing.T) {
+	env := newSCIMTestEnv()
+	ctx := context.Background()
+	env.users.users[5] = &User{ID: 5, Email: "bob@example.com", Role: RoleUser, Status: StatusActive}
+	env.users.users[6] = &User{ID: 6, Email: "carol@example.com", Role: RoleUser, Status: StatusActive, AllowedGroups: []int64{1}}
+	env.subs.active[[2]int64{6, 2}] = &UserSubscription{ID: 50, UserID: 6, GroupID: 2}
+
+	_, err := env.svc.CreateGroup(ctx, 7, &SCIMGroupInput{DisplayName: "team-exclusive", Members: []string{"5", "6"}})
+	require.NoError(t, err)
+	_, err = env.svc.CreateGroup(ctx, 7, &SCIMGroupInput{DisplayName: "team-plan", Members: []string{"5", "6"}})
+	require.NoError(t, err)
+
+	// 只有 bob 缺少权限，因此只为他授予 allowed_groups 与订阅。
+	require.Equal(t, [][2]int64{{5, 1}}, env.users.addedGroups)
+	require.Len(t, env.subs.assigned, 1)
+	require.Equal(t, int64(5), env.subs.assigned[0].UserID)
+	require.Equal(t, MaxValidityDays, env.subs.assigned[0].ValidityDays)
+
+	_, err = env.svc.CreateGroup(ctx, 7, &SCIMGroupInput{DisplayName: "team-plan"})
+	require.ErrorIs(t, err, ErrSCIMGroupExists)
+	_, err = env.svc.CreateGroup(ctx, 7, &SCIMGroupInput{DisplayName: "unknown"})
+	require.ErrorIs(t, err, ErrSCIMGroupNotMapped)
+
+	// Okta 形式移除成员：SCIM 授予的权限被回收，手工授予的保持不变。
+	for _, groupID := range []int64{1, 2} {
+		for _, userID := range []string{"5", "6"} {
+			_, err = env.svc.PatchGroup(ctx, 7, groupID, []SCIMPatchOperation{{
+				Op: "remove", Path: `members[value eq "` + userID + `"]`,
+			}})
+			require.NoError(t, err)
+		}
+	}
+	require.Equal(t, [][2]int64{{5, 1}}, env.users.removedGroups)
+	require.Equal(t, []int64{901}, env.subs.revoked)
+	require.Empty(t, env.repo.memberships)
+}
+
+func TestSCIMPatchGroupAddAndReplaceMembers(t *testing.T) {
+	env := newSCIMTestEnv()
+	ctx := context.Background()
+	env.users.users[5] = &User{ID: 5, Email: "bob@example.com", Role: RoleUser, Status: StatusActive}
+	env.users.users[6] = &User{ID: 6, Email: "carol@example.com", Role: RoleUser, Status: StatusActive}
+
+	_, err := env.svc.CreateGroup(ctx, 7, &SCIMGroupInput{DisplayName: "team-exclusive"})
+	require.NoError(t, err)
+
+	group, err := env.svc.PatchGroup(ctx, 7, 1, []SCIMPatchOperation{{
+		Op: "add", Path: "members", Value: json.RawMessage(`[{"value":"5"}]`),
+	}})
+	require.NoError(t, err)
+	require.Len(t, group.Members, 1)
+
+	group, err = env.svc.PatchGroup(ctx, 7, 1, []SCIMPatchOperation{{
+		Op: "replace", Value: json.RawMessage(`{"members":[{"value":"6"}]}`),
+	}})
+	require.NoError(t, err)
+	require.Equal(t, []SCIMMember{{UserID: 6}}, group.Members)
+	require.Equal(t, [][2]int64{{5, 1}}, env.users.removedGroups)
+
+	_, err = env.svc.PatchGroup(ctx, 7, 1, []SCIMPatchOperation{{
+		Op: "add", Path: "members", Value: json.RawMessage(`[{"value":"999"}]`),
+	}})
+	require.ErrorIs(t, err, ErrSCIMInvalidValue)
+
+	_, err = env.svc.PatchGroup(ctx, 7, 1, []SCIMPatchOperation{{
+		Op: "replace", Path: "displayName", Value: json.RawMessage(`"renamed"`),
+	}})
+	require.ErrorIs(t, err, ErrSCIMGroupRenameDenied)
+}
+
+func TestSCIMDeleteUserDeactivatesAndRevokesMemberships(t *testing.T) {
+	env := newSCIMTestEnv()
+	ctx := context.Background()
+	env.users.users[5] = &User{ID: 5, Email: "bob@example.com", Role: RoleUser, Status: StatusActive}
+	env.repo.users[5] = &SCIMUser{ID: 5, Email: "bob@example.com", Status: StatusActive}
+
+	_, err := env.svc.CreateGroup(ctx, 7, &SCIMGroupInput{DisplayName: "team-plan", Members: []string{"5"}})
+	require.NoError(t, err)
+
+	require.NoError(t, env.svc.DeleteUser(ctx, 7, 5))
+	require.Equal(t, []int64{5}, env.repo.disabledCalls)
+	require.Equal(t, []int64{5}, env.sessions.revoked)
+	require.Equal(t, []int64{901}, env.subs.revoked)
+	require.Equal(t, []int64{5}, env.admin.deleted)
+	require.Empty(t, env.repo.memberships)
+}
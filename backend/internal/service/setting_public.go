@@ -275,6 +275,14 @@ func (s *SettingService) GetPublicSettings(ctx context.Context) (*PublicSettings
 	if oidcProviderName == "" {
 		oidcProviderName = "OIDC"
 	}
+	samlEnabled := s.cfg != nil && s.cfg.SAML.Enabled
+	samlProviderName := ""
+	if samlEnabled {
+		samlProviderName = strings.TrimSpace(s.cfg.SAML.ProviderName)
+		if samlProviderName == "" {
+			samlProviderName = "SAML"
+		}
+	}
 	gitHubEnabled := s.emailOAuthPublicEnabled(settings, "github")
 	googleEnabled := s.emailOAuthPublicEnabled(settings, "google")
 	weChatEnabled, weChatOpenEnabled, weChatMPEnabled, weChatMobileEnabled := s.weChatOAuthCapabilitiesFromSettings(settings)
@@ -351,6 +359,8 @@ func (s *SettingService) GetPublicSettings(ctx context.Context) (*PublicSettings
 		OIDCOAuthProviderName:               oidcProviderName,
 		GitHubOAuthEnabled:                  gitHubEnabled,
 		GoogleOAuthEnabled:                  googleEnabled,
+		SAMLEnabled:                         samlEnabled,
+		SAMLProviderName:                    samlProviderName,
 		BalanceLowNotifyEnabled:             settings[SettingKeyBalanceLowNotifyEnabled] == "true",
 		AccountQuotaNotifyEnabled:           settings[SettingKeyAccountQuotaNotifyEnabled] == "true",
 		BalanceLowNotifyThreshold:           balanceLowNotifyThreshold,
@@ -585,6 +595,8 @@ type PublicSettingsInjectionPayload struct {
 	OIDCOAuthProviderName               string                   `json:"oidc_oauth_provider_name"`
 	GitHubOAuthEnabled                  bool                     `json:"github_oauth_enabled"`
 	GoogleOAuthEnabled                  bool                     `json:"google_oauth_enabled"`
+	SAMLEnabled                         bool                     `json:"saml_enabled"`
+	SAMLProviderName                    string                   `json:"saml_provider_name"`
 	BackendModeEnabled                  bool                     `json:"backend_mode_enabled"`
 	Version                             string                   `json:"version"`
 	// fork 自有的公开设置
@@ -670,6 +682,8 @@ func (s *SettingService) GetPublicSettingsForInjection(ctx context.Context) (any
 		OIDCOAuthProviderName:               settings.OIDCOAuthProviderName,
 		GitHubOAuthEnabled:                  settings.GitHubOAuthEnabled,
 		GoogleOAuthEnabled:                  settings.GoogleOAuthEnabled,
+		SAMLEnabled:                         settings.SAMLEnabled,
+		SAMLProviderName:                    settings.SAMLProviderName,
 		BackendModeEnabled:                  settings.BackendModeEnabled,
 		Version:                             s.version,
 		ServerTimezone:                      timezone.Name(),
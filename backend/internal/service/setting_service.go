@@ -119,7 +119,8 @@ type WebSearchManagerBuilder func(cfg *WebSearchEmulationConfig, proxyURLs map[i
 type SettingService struct {
 	settingRepo                 SettingRepository
 	defaultSubGroupReader       DefaultSubscriptionGroupReader
-	proxyRepo                   ProxyRepository // for resolving websearch provider proxy URLs
+	proxyRepo                   ProxyRepository   // for resolving websearch provider proxy URLs
+	credentialCipher            *CredentialCipher // encrypts websearch provider API keys at rest
 	cfg                         *config.Config
 	onUpdate                    func() // Callback when settings are updated (for cache invalidation)
 	version                     string // Application version
@@ -298,6 +299,11 @@ func (s *SettingService) SetProxyRepository(repo ProxyRepository) {
 	s.proxyRepo = repo
 }
 
+// SetCredentialCipher injects the cipher used to encrypt websearch provider API keys.
+func (s *SettingService) SetCredentialCipher(cipher *CredentialCipher) {
+	s.credentialCipher = cipher
+}
+
 func (s *SettingService) LoadForwardedClientIPSettings(ctx context.Context) error {
 	if s == nil || s.cfg == nil || s.settingRepo == nil {
 		return nil
@@ -361,6 +361,8 @@ type PublicSettings struct {
 	OIDCOAuthProviderName    string
 	GitHubOAuthEnabled       bool
 	GoogleOAuthEnabled       bool
+	SAMLEnabled              bool
+	SAMLProviderName         string
 	Version                  string
 
 	BalanceLowNotifyEnabled     bool
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/google/uuid"
+)
+
+const (
+	StreamResumeStatusStreaming = "streaming"
+	StreamResumeStatusDone      = "done"
+
+	// StreamResumeIDHeader 返回给客户端的流 ID；重连时原样带回，配合 Last-Event-ID 续传。
+	StreamResumeIDHeader = "X-Sub2API-Stream-ID"
+
+	streamResumeIDPrefix = "strm_"
+
+	// defaultStreamResumeTTL 是缓冲在客户端断开或流结束后的保留时长。
+	defaultStreamResumeTTL = 15 * time.Minute
+	// defaultStreamResumeMaxDuration 限制一次被托管的上游流的最长执行时间。
+	defaultStreamResumeMaxDuration = time.Hour
+)
+
+var (
+	ErrStreamResumeNotFound    = infraerrors.New(http.StatusNotFound, "STREAM_NOT_FOUND", "stream not found or expired")
+	ErrStreamResumeUnavailable = infraerrors.New(http.StatusServiceUnavailable, "STREAM_RESUME_UNAVAILABLE", "stream resume storage is unavailable")
+)
+
+// StreamResumeRecord 描述一个可续传的流式响应。事件本体单独存放在事件列表中，
+// 下标即网关分配的事件 ID（从 0 开始）。
+type StreamResumeRecord struct {
+	ID          string            `json:"id"`
+	UserID      int64             `json:"user_id"`
+	APIKeyID    int64             `json:"api_key_id"`
+	Status      string            `json:"status"`
+	HTTPStatus  int               `json:"http_status,omitempty"`
+	Header      map[string]string `json:"header,omitempty"`
+	EventCount  int64             `json:"event_count"`
+	CreatedAt   int64             `json:"created_at"`
+	CompletedAt *int64            `json:"completed_at,omitempty"`
+}
+
+// Done 表示上游流已结束，不会再追加事件。
+func (r *StreamResumeRecord) Done() bool {
+	return r != nil && r.Status == StreamResumeStatusDone
+}
+
+type StreamResumeOwner struct {
+	UserID   int64
+	APIKeyID int64
+}
+
+// StreamResumeStore 持久化流状态与已转发的 SSE 事件块。
+type StreamResumeStore interface {
+	Save(ctx context.Context, record *StreamResumeRecord, ttl time.Duration) error
+	Get(ctx context.Context, id string) (*StreamResumeRecord, error)
+	AppendEvents(ctx context.Context, id string, events [][]byte, ttl time.Duration) error
+	ListEvents(ctx context.Context, id string, from int64) ([][]byte, error)
+}
+
+// StreamResumeService 管理分组开启“流式断线续传”后的事件缓冲。
+//
+// 上游流只执行一次（计费一次）：客户端断开后网关继续把事件写入缓冲，
+// 重连只回放缓冲，不会再次请求上游。
+type StreamResumeService struct {
+	store       StreamResumeStore
+	ttl         time.Duration
+	maxDuration time.Duration
+}
+
+func NewStreamResumeService(store StreamResumeStore) *StreamResumeService {
+	return &StreamResumeService{store: store, ttl: defaultStreamResumeTTL, maxDuration: defaultStreamResumeMaxDuration}
+}
+
+// StreamResumeEnabledForGroup 判断分组是否开启了流式断线续传。
+func StreamResumeEnabledForGroup(group *Group) bool {
+	return group != nil && group.StreamResumeEnabled
+}
+
+// IsStreamResumeID 判断 id 是否为网关签发的流 ID。
+func IsStreamResumeID(id string) bool {
+	id = strings.TrimSpace(id)
+	return len(id) > len(streamResumeIDPrefix) && strings.HasPrefix(id, streamResumeIDPrefix)
+}
+
+func (s *StreamResumeService) Available() bool {
+	return s != nil && s.store != nil
+}
+
+// MaxDuration 返回托管执行的超时时间。
+func (s *StreamResumeService) MaxDuration() time.Duration {
+	if s == nil || s.maxDuration <= 0 {
+		return defaultStreamResumeMaxDuration
+	}
+	return s.maxDuration
+}
+
+// Create 登记一个新的可续传流。
+func (s *StreamResumeService) Create(ctx context.Context, owner StreamResumeOwner) (*StreamResumeRecord, error) {
+	if !s.Available() {
+		return nil, ErrStreamResumeUnavailable
+	}
+	record := &StreamResumeRecord{
+		ID:        streamResumeIDPrefix + strings.ReplaceAll(uuid.NewString(), "-", ""),
+		UserID:    owner.UserID,
+		APIKeyID:  owner.APIKeyID,
+		Status:    StreamResumeStatusStreaming,
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := s.store.Save(ctx, record, s.ttl+s.MaxDuration()); err != nil {
+		return nil, ErrStreamResumeUnavailable.WithCause(err)
+	}
+	return record, nil
+}
+
+// Get 返回调用方自己的流；不属于调用方的 ID 一律视为不存在。
+func (s *StreamResumeService) Get(ctx context.Context, owner StreamResumeOwner, id string) (*StreamResumeRecord, error) {
+	if !s.Available() {
+		return nil, ErrStreamResumeUnavailable
+	}
+	id = strings.TrimSpace(id)
+	if !IsStreamResumeID(id) {
+		return nil, ErrStreamResumeNotFound
+	}
+	record, err := s.store.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrStreamResumeNotFound) {
+			return nil, ErrStreamResumeNotFound
+		}
+		return nil, ErrStreamResumeUnavailable.WithCause(err)
+	}
+	if record.UserID != owner.UserID || record.APIKeyID != owner.APIKeyID {
+		return nil, ErrStreamResumeNotFound
+	}
+	return record, nil
+}
+
+// Append 追加一批事件块；record.EventCount 随之前移。
+func (s *StreamResumeService) Append(ctx context.Context, record *StreamResumeRecord, events [][]byte) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if err := s.store.AppendEvents(ctx, record.ID, events, s.ttl+s.MaxDuration()); err != nil {
+		return err
+	}
+	record.EventCount += int64(len(events))
+	return nil
+}
+
+// Events 返回事件 ID 大于 lastEventID 的事件块。
+func (s *StreamResumeService) Events(ctx context.Context, id string, lastEventID int64) ([][]byte, error) {
+	events, err := s.store.ListEvents(ctx, id, lastEventID+1)
+	if err != nil {
+		return nil, ErrStreamResumeUnavailable.WithCause(err)
+	}
+	return events, nil
+}
+
+// Commit 记录上游响应头，重连时按同样的头部回放。
+func (s *StreamResumeService) Commit(ctx context.Context, record *StreamResumeRecord, status int, header map[string]string) error {
+	record.HTTPStatus = status
+	record.Header = header
+	return s.store.Save(ctx, record, s.ttl+s.MaxDuration())
+}
+
+// Finish 把流标记为结束，缓冲从此刻起保留 ttl。
+func (s *StreamResumeService) Finish(ctx context.Context, record *StreamResumeRecord) error {
+	completedAt := time.Now().Unix()
+	record.Status = StreamResumeStatusDone
+	record.CompletedAt = &completedAt
+	// 事件列表的 TTL 可能略长于状态；状态过期后流即不可续传，残留事件随后自然过期。
+	return s.store.Save(ctx, record, s.ttl)
+}
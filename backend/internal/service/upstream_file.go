@@ -0,0 +1,475 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+	"github.com/Wei-Shaw/sub2api/internal/util/responseheaders"
+	"github.com/tidwall/gjson"
+)
+
+// Files API（OpenAI /v1/files、Anthropic Files beta）透传。
+//
+// 上游文件只存在于上传它的账号上，而网关会在账号间负载均衡，所以上传时记录
+// (平台, 文件 ID) → 账号 的绑定；之后引用该文件的请求固定调度到该账号，
+// 检索/下载/删除也只转发到该账号。绑定同时记录上传者，其他用户引用一律按不存在处理。
+
+const (
+	// anthropicFilesBetaHeader 是 Anthropic Files API 要求的 beta 标识。
+	anthropicFilesBetaHeader = "files-api-2025-04-14"
+	anthropicFilesAPIVersion = "2023-06-01"
+
+	// upstreamFileMetadataMaxBytes 限制 JSON 元数据响应（上传/检索/删除）的读取大小；
+	// 文件内容下载以流的形式透传，不受此限制。
+	upstreamFileMetadataMaxBytes = 1 << 20
+)
+
+var (
+	ErrUpstreamFileNotFound         = infraerrors.NotFound("FILE_NOT_FOUND", "file not found")
+	ErrUpstreamFileUnavailable      = infraerrors.ServiceUnavailable("FILES_UNAVAILABLE", "no available account supports the Files API")
+	ErrUpstreamFileAccountGone      = infraerrors.ServiceUnavailable("FILE_ACCOUNT_UNAVAILABLE", "the account holding this file is unavailable")
+	ErrUpstreamFileMixedAccounts    = infraerrors.BadRequest("FILES_ON_DIFFERENT_ACCOUNTS", "referenced files were uploaded through different upstream accounts and cannot be used in one request")
+	ErrUpstreamFileUnsupported      = infraerrors.NotFound("FILES_NOT_SUPPORTED", "Files API is not supported for this platform")
+	ErrUpstreamFileInvalidReference = infraerrors.BadRequest("FILE_ID_INVALID", "invalid file id")
+)
+
+// UpstreamFile 是一条文件 → 账号绑定。
+type UpstreamFile struct {
+	ID        int64
+	Platform  string
+	FileID    string
+	UserID    int64
+	APIKeyID  int64
+	GroupID   *int64
+	AccountID int64
+	Filename  string
+	Purpose   string
+	Bytes     int64
+	// Object 为上传时上游返回的文件对象，list 接口据此返回。
+	Object    json.RawMessage
+	CreatedAt time.Time
+}
+
+// UpstreamFileRepository 持久化文件绑定。
+type UpstreamFileRepository interface {
+	Create(ctx context.Context, file *UpstreamFile) error
+	// GetByFileID 未找到时返回 ErrUpstreamFileNotFound。
+	GetByFileID(ctx context.Context, platform, fileID string) (*UpstreamFile, error)
+	ListByUser(ctx context.Context, userID int64, platform string, groupID *int64) ([]*UpstreamFile, error)
+	Delete(ctx context.Context, platform, fileID string) error
+}
+
+// UpstreamFileCaller 标识发起文件操作的调用方。
+type UpstreamFileCaller struct {
+	Platform string
+	GroupID  *int64
+	UserID   int64
+	APIKeyID int64
+}
+
+// UpstreamFileStream 是文件内容下载的上游响应，调用方负责关闭 Body。
+type UpstreamFileStream struct {
+	StatusCode int
+	Headers    http.Header
+	Body       io.ReadCloser
+}
+
+// UpstreamFileService 负责 Files API 透传与文件 → 账号绑定。
+type UpstreamFileService struct {
+	repo                 UpstreamFileRepository
+	accountRepo          AccountRepository
+	gatewayService       *GatewayService
+	openAIGatewayService *OpenAIGatewayService
+	httpUpstream         HTTPUpstream
+}
+
+// NewUpstreamFileService 创建 Files API 服务。
+func NewUpstreamFileService(
+	repo UpstreamFileRepository,
+	accountRepo AccountRepository,
+	gatewayService *GatewayService,
+	openAIGatewayService *OpenAIGatewayService,
+	httpUpstream HTTPUpstream,
+) *UpstreamFileService {
+	return &UpstreamFileService{
+		repo:                 repo,
+		accountRepo:          accountRepo,
+		gatewayService:       gatewayService,
+		openAIGatewayService: openAIGatewayService,
+		httpUpstream:         httpUpstream,
+	}
+}
+
+// UpstreamFileSessionHash 返回引用文件的请求使用的粘性会话 key（按持有账号区分）。
+func UpstreamFileSessionHash(accountID int64) string {
+	return fmt.Sprintf("upstream_file:%d", accountID)
+}
+
+// upstreamFileAccountSupported 判断账号能否承接 Files API：只有直连官方 API 的
+// API Key 账号有 Files 接口（OAuth / 订阅账号没有）。
+func upstreamFileAccountSupported(platform string, account *Account) bool {
+	if account == nil || account.Type != AccountTypeAPIKey || account.Platform != platform {
+		return false
+	}
+	return platform == PlatformOpenAI || platform == PlatformAnthropic
+}
+
+// validUpstreamFileID 校验客户端传入的文件 ID 可以安全拼入上游路径。
+func validUpstreamFileID(fileID string) bool {
+	return fileID != "" && len(fileID) <= 255 && isSafeUpstreamPathSegment(fileID)
+}
+
+// collectReferencedFileIDs 收集请求体中所有 "file_id" 字段的值：
+// OpenAI Responses input_file / Chat Completions file 与 Anthropic source.type=file 均使用该字段。
+func collectReferencedFileIDs(body []byte) []string {
+	if !bytes.Contains(body, []byte(`"file_id"`)) || !gjson.ValidBytes(body) {
+		return nil
+	}
+	seen := make(map[string]struct{})
+	var ids []string
+	var walk func(value gjson.Result)
+	walk = func(value gjson.Result) {
+		switch {
+		case value.IsObject():
+			value.ForEach(func(key, child gjson.Result) bool {
+				if key.String() == "file_id" && child.Type == gjson.String {
+					if id := strings.TrimSpace(child.String()); id != "" {
+						if _, ok := seen[id]; !ok {
+							seen[id] = struct{}{}
+							ids = append(ids, id)
+						}
+					}
+					return true
+				}
+				walk(child)
+				return true
+			})
+		case value.IsArray():
+			value.ForEach(func(_, child gjson.Result) bool {
+				walk(child)
+				return true
+			})
+		}
+	}
+	walk(gjson.ParseBytes(body))
+	return ids
+}
+
+// ResolveReferencedFileAccount 返回请求体引用的文件所在的账号 ID；未引用文件时返回 0。
+// 引用了未经网关上传、或属于其他用户/分组的文件时返回 ErrUpstreamFileNotFound，
+// 引用的文件分属不同账号时返回 ErrUpstreamFileMixedAccounts。
+func (s *UpstreamFileService) ResolveReferencedFileAccount(ctx context.Context, caller UpstreamFileCaller, body []byte) (int64, error) {
+	if s == nil || s.repo == nil {
+		return 0, nil
+	}
+	ids := collectReferencedFileIDs(body)
+	var accountID int64
+	for _, id := range ids {
+		file, err := s.ownedFile(ctx, caller, id)
+		if err != nil {
+			return 0, err
+		}
+		if accountID != 0 && accountID != file.AccountID {
+			return 0, ErrUpstreamFileMixedAccounts
+		}
+		accountID = file.AccountID
+	}
+	return accountID, nil
+}
+
+// ownedFile 读取绑定并校验归属；不区分"不存在"与"属于他人"，避免泄露文件 ID 的存在性。
+func (s *UpstreamFileService) ownedFile(ctx context.Context, caller UpstreamFileCaller, fileID string) (*UpstreamFile, error) {
+	if !validUpstreamFileID(fileID) {
+		return nil, ErrUpstreamFileNotFound
+	}
+	file, err := s.repo.GetByFileID(ctx, caller.Platform, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.UserID != caller.UserID || derefGroupID(file.GroupID) != derefGroupID(caller.GroupID) {
+		return nil, ErrUpstreamFileNotFound
+	}
+	return file, nil
+}
+
+func (s *UpstreamFileService) fileAccount(ctx context.Context, caller UpstreamFileCaller, fileID string) (*UpstreamFile, *Account, error) {
+	file, err := s.ownedFile(ctx, caller, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	account, err := s.accountRepo.GetByID(ctx, file.AccountID)
+	if err != nil || account == nil || !account.IsActive() {
+		return nil, nil, ErrUpstreamFileAccountGone
+	}
+	return file, account, nil
+}
+
+// selectUploadAccount 在分组内选择支持 Files API 的账号。
+func (s *UpstreamFileService) selectUploadAccount(ctx context.Context, caller UpstreamFileCaller) (*Account, error) {
+	excluded := make(map[int64]struct{})
+	for {
+		var (
+			account *Account
+			err     error
+		)
+		switch caller.Platform {
+		case PlatformOpenAI:
+			account, err = s.openAIGatewayService.SelectAccountForModelWithExclusions(ctx, caller.GroupID, "", "", excluded)
+		case PlatformAnthropic:
+			account, err = s.gatewayService.SelectAccountForModelWithExclusions(ctx, caller.GroupID, "", "", excluded)
+		default:
+			return nil, ErrUpstreamFileUnsupported
+		}
+		if err != nil {
+			return nil, ErrUpstreamFileUnavailable.WithCause(err)
+		}
+		if upstreamFileAccountSupported(caller.Platform, account) {
+			return account, nil
+		}
+		excluded[account.ID] = struct{}{}
+	}
+}
+
+// filesURL 返回账号的 Files 端点；fileID 为空时指向集合。
+func (s *UpstreamFileService) filesURL(account *Account, fileID, suffix string) (string, error) {
+	endpoint := "/v1/files"
+	if fileID != "" {
+		endpoint += "/" + url.PathEscape(fileID) + suffix
+	}
+	switch account.Platform {
+	case PlatformOpenAI:
+		base, err := s.openAIGatewayService.validateUpstreamBaseURL(account.GetOpenAIBaseURL())
+		if err != nil {
+			return "", err
+		}
+		return buildOpenAIEndpointURL(base, endpoint), nil
+	case PlatformAnthropic:
+		base, err := s.gatewayService.validateUpstreamBaseURL(account.GetBaseURL())
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(base, "/") + endpoint, nil
+	default:
+		return "", ErrUpstreamFileUnsupported
+	}
+}
+
+func (s *UpstreamFileService) responseHeaderFilter(platform string) *responseheaders.CompiledHeaderFilter {
+	if platform == PlatformOpenAI && s.openAIGatewayService != nil {
+		return s.openAIGatewayService.responseHeaderFilter
+	}
+	if s.gatewayService != nil {
+		return s.gatewayService.responseHeaderFilter
+	}
+	return nil
+}
+
+func (s *UpstreamFileService) doRequest(ctx context.Context, account *Account, method, fullURL string, body []byte, contentType string) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, reader)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	switch account.Platform {
+	case PlatformOpenAI:
+		apiKey := account.GetOpenAIApiKey()
+		if apiKey == "" {
+			return nil, fmt.Errorf("account %d missing api_key", account.ID)
+		}
+		req = req.WithContext(WithHTTPUpstreamProfile(req.Context(), HTTPUpstreamProfileOpenAI))
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	case PlatformAnthropic:
+		apiKey := strings.TrimSpace(account.GetCredential("api_key"))
+		if apiKey == "" {
+			return nil, fmt.Errorf("account %d missing api_key", account.ID)
+		}
+		req.Header.Set("x-api-key", apiKey)
+		req.Header.Set("anthropic-version", anthropicFilesAPIVersion)
+		req.Header.Set("anthropic-beta", anthropicFilesBetaHeader)
+	default:
+		return nil, ErrUpstreamFileUnsupported
+	}
+	account.ApplyHeaderOverrides(req.Header)
+
+	proxyURL := ""
+	if account.ProxyID != nil && account.Proxy != nil {
+		proxyURL = account.Proxy.URL()
+	}
+	return s.httpUpstream.Do(req, proxyURL, account.ID, account.Concurrency)
+}
+
+// doMetadataRequest 发起返回 JSON 的文件请求并读取完整响应。
+func (s *UpstreamFileService) doMetadataRequest(ctx context.Context, account *Account, method, fullURL string, body []byte, contentType string) (*UpstreamHTTPResult, error) {
+	resp, err := s.doRequest(ctx, account, method, fullURL, body, contentType)
+	if err != nil {
+		return nil, infraerrors.New(http.StatusBadGateway, "FILES_UPSTREAM_ERROR", "upstream request failed").WithCause(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, upstreamFileMetadataMaxBytes))
+	if err != nil {
+		return nil, infraerrors.New(http.StatusBadGateway, "FILES_UPSTREAM_ERROR", "failed to read upstream response").WithCause(err)
+	}
+	return &UpstreamHTTPResult{
+		StatusCode: resp.StatusCode,
+		Headers:    responseheaders.FilterHeaders(resp.Header, s.responseHeaderFilter(account.Platform)),
+		Body:       respBody,
+	}, nil
+}
+
+// Upload 把文件上传到分组内一个支持 Files API 的账号，并记录绑定。
+// body 与 contentType 为客户端原始 multipart 请求，原样转发。
+func (s *UpstreamFileService) Upload(ctx context.Context, caller UpstreamFileCaller, body []byte, contentType string) (*UpstreamHTTPResult, *Account, error) {
+	account, err := s.selectUploadAccount(ctx, caller)
+	if err != nil {
+		return nil, nil, err
+	}
+	fullURL, err := s.filesURL(account, "", "")
+	if err != nil {
+		return nil, nil, ErrUpstreamFileUnavailable.WithCause(err)
+	}
+	res, err := s.doMetadataRequest(ctx, account, http.MethodPost, fullURL, body, contentType)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res, account, nil
+	}
+
+	fileID := strings.TrimSpace(gjson.GetBytes(res.Body, "id").String())
+	if !validUpstreamFileID(fileID) {
+		return nil, nil, infraerrors.New(http.StatusBadGateway, "FILES_UPSTREAM_ERROR", "upstream returned no file id")
+	}
+	file := &UpstreamFile{
+		Platform:  caller.Platform,
+		FileID:    fileID,
+		UserID:    caller.UserID,
+		APIKeyID:  caller.APIKeyID,
+		GroupID:   caller.GroupID,
+		AccountID: account.ID,
+		Filename:  gjson.GetBytes(res.Body, "filename").String(),
+		Purpose:   gjson.GetBytes(res.Body, "purpose").String(),
+		Bytes:     firstPositiveInt64(gjson.GetBytes(res.Body, "bytes").Int(), gjson.GetBytes(res.Body, "size_bytes").Int()),
+		Object:    json.RawMessage(res.Body),
+	}
+	if err := s.repo.Create(ctx, file); err != nil {
+		// 无法记录绑定的文件之后既无法引用也无法管理，回滚上游文件。
+		if deleteURL, urlErr := s.filesURL(account, fileID, ""); urlErr == nil {
+			if resp, delErr := s.doRequest(ctx, account, http.MethodDelete, deleteURL, nil, ""); delErr == nil {
+				_ = resp.Body.Close()
+			}
+		}
+		return nil, nil, fmt.Errorf("save file binding: %w", err)
+	}
+	return res, account, nil
+}
+
+// List 返回调用方在当前分组上传的文件（不分页），格式与对应平台的 list 接口一致。
+func (s *UpstreamFileService) List(ctx context.Context, caller UpstreamFileCaller) ([]byte, error) {
+	files, err := s.repo.ListByUser(ctx, caller.UserID, caller.Platform, caller.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]json.RawMessage, 0, len(files))
+	for _, file := range files {
+		if len(file.Object) > 0 {
+			data = append(data, file.Object)
+		}
+	}
+	if caller.Platform == PlatformAnthropic {
+		out := map[string]any{"data": data, "has_more": false, "first_id": nil, "last_id": nil}
+		if len(files) > 0 {
+			out["first_id"] = files[0].FileID
+			out["last_id"] = files[len(files)-1].FileID
+		}
+		return json.Marshal(out)
+	}
+	return json.Marshal(map[string]any{"object": "list", "data": data, "has_more": false})
+}
+
+// Retrieve 从持有文件的账号读取文件元数据。
+func (s *UpstreamFileService) Retrieve(ctx context.Context, caller UpstreamFileCaller, fileID string) (*UpstreamHTTPResult, *Account, error) {
+	file, account, err := s.fileAccount(ctx, caller, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	fullURL, err := s.filesURL(account, file.FileID, "")
+	if err != nil {
+		return nil, nil, ErrUpstreamFileAccountGone.WithCause(err)
+	}
+	res, err := s.doMetadataRequest(ctx, account, http.MethodGet, fullURL, nil, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		// 上游已删除（过期或在网关外删除），同步清理绑定。
+		_ = s.repo.Delete(ctx, file.Platform, file.FileID)
+	}
+	return res, account, nil
+}
+
+// Content 从持有文件的账号流式下载文件内容。
+func (s *UpstreamFileService) Content(ctx context.Context, caller UpstreamFileCaller, fileID string) (*UpstreamFileStream, *Account, error) {
+	file, account, err := s.fileAccount(ctx, caller, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	fullURL, err := s.filesURL(account, file.FileID, "/content")
+	if err != nil {
+		return nil, nil, ErrUpstreamFileAccountGone.WithCause(err)
+	}
+	resp, err := s.doRequest(ctx, account, http.MethodGet, fullURL, nil, "")
+	if err != nil {
+		return nil, nil, infraerrors.New(http.StatusBadGateway, "FILES_UPSTREAM_ERROR", "upstream request failed").WithCause(err)
+	}
+	return &UpstreamFileStream{
+		StatusCode: resp.StatusCode,
+		Headers:    responseheaders.FilterHeaders(resp.Header, s.responseHeaderFilter(account.Platform)),
+		Body:       resp.Body,
+	}, account, nil
+}
+
+// Delete 在持有文件的账号上删除文件并移除绑定；上游返回 404 时同样移除绑定。
+func (s *UpstreamFileService) Delete(ctx context.Context, caller UpstreamFileCaller, fileID string) (*UpstreamHTTPResult, *Account, error) {
+	file, account, err := s.fileAccount(ctx, caller, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	fullURL, err := s.filesURL(account, file.FileID, "")
+	if err != nil {
+		return nil, nil, ErrUpstreamFileAccountGone.WithCause(err)
+	}
+	res, err := s.doMetadataRequest(ctx, account, http.MethodDelete, fullURL, nil, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if (res.StatusCode >= 200 && res.StatusCode < 300) || res.StatusCode == http.StatusNotFound {
+		if err := s.repo.Delete(ctx, file.Platform, file.FileID); err != nil && !errors.Is(err, ErrUpstreamFileNotFound) {
+			return nil, nil, err
+		}
+	}
+	return res, account, nil
+}
+
+func firstPositiveInt64(values ...int64) int64 {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type upstreamFileRepoStub struct {
+	files map[string]*UpstreamFile
+}
+
+func (r *upstreamFileRepoStub) Create(_ context.Context, file *UpstreamFile) error {
+	r.files[file.Platform+"/"+file.FileID] = file
+	return nil
+}
+
+func (r *upstreamFileRepoStub) GetByFileID(_ context.Context, platform, fileID string) (*UpstreamFile, error) {
+	file, ok := r.files[platform+"/"+fileID]
+	if !ok {
+		return nil, ErrUpstreamFileNotFound
+	}
+	return file, nil
+}
+
+func (r *upstreamFileRepoStub) ListByUser(_ context.Context, userID int64, platform string, groupID *int64) ([]*UpstreamFile, error) {
+	var out []*UpstreamFile
+	for _, file := range r.files {
+		if file.UserID == userID && file.Platform == platform && derefGroupID(file.GroupID) == derefGroupID(groupID) {
+			out = append(out, file)
+		}
+	}
+	return out, nil
+}
+
+func (r *upstreamFileRepoStub) Delete(_ context.Context, platform, fileID string) error {
+	delete(r.files, platform+"/"+fileID)
+	return nil
+}
+
+func TestCollectReferencedFileIDs(t *testing.T) {
+	require.Nil(t, collectReferencedFileIDs([]byte(`{"input":"hello"}`)))
+	require.Equal(t, []string{"file-a", "file-b"}, collectReferencedFileIDs([]byte(`{
+		"input":[{"role":"user","content":[
+			{"type":"input_file","file_id":"file-a"},
+			{"type":"input_text","text":"mentions \"file_id\" in text"},
+			{"type":"input_file","file_id":"file-b"},
+			{"type":"input_file","file_id":"file-a"}
+		]}]
+	}`)))
+	require.Equal(t, []string{"file_011"}, collectReferencedFileIDs([]byte(`{
+		"messages":[{"role":"user","content":[{"type":"document","source":{"type":"file","file_id":"file_011"}}]}]
+	}`)))
+}
+
+func TestResolveReferencedFileAccount(t *testing.T) {
+	groupID := int64(5)
+	otherGroup := int64(6)
+	svc := &UpstreamFileService{repo: &upstreamFileRepoStub{files: map[string]*UpstreamFile{
+		"openai/file-a": {Platform: PlatformOpenAI, FileID: "file-a", UserID: 1, GroupID: &groupID, AccountID: 100},
+		"openai/file-b": {Platform: PlatformOpenAI, FileID: "file-b", UserID: 1, GroupID: &groupID, AccountID: 100},
+		"openai/file-c": {Platform: PlatformOpenAI, FileID: "file-c", UserID: 1, GroupID: &groupID, AccountID: 200},
+		"openai/file-x": {Platform: PlatformOpenAI, FileID: "file-x", UserID: 2, GroupID: &groupID, AccountID: 100},
+	}}}
+	caller := UpstreamFileCaller{Platform: PlatformOpenAI, GroupID: &groupID, UserID: 1}
+	ctx := context.Background()
+
+	accountID, err := svc.ResolveReferencedFileAccount(ctx, caller, []byte(`{"input":"no files"}`))
+	require.NoError(t, err)
+	require.Zero(t, accountID)
+
+	accountID, err = svc.ResolveReferencedFileAccount(ctx, caller, []byte(`{"input":[{"file_id":"file-a"},{"file_id":"file-b"}]}`))
+	require.NoError(t, err)
+	require.Equal(t, int64(100), accountID)
+
+	_, err = svc.ResolveReferencedFileAccount(ctx, caller, []byte(`{"input":[{"file_id":"file-a"},{"file_id":"file-c"}]}`))
+	require.ErrorIs(t, err, ErrUpstreamFileMixedAccounts)
+
+	// 其他用户的文件、未经网关上传的文件、其他分组的文件一律视为不存在。
+	for _, body := range []string{
+		`{"input":[{"file_id":"file-x"}]}`,
+		`{"input":[{"file_id":"file-unknown"}]}`,
+		`{"input":[{"file_id":"../etc"}]}`,
+	} {
+		_, err = svc.ResolveReferencedFileAccount(ctx, caller, []byte(body))
+		require.ErrorIs(t, err, ErrUpstreamFileNotFound, body)
+	}
+	_, err = svc.ResolveReferencedFileAccount(ctx, UpstreamFileCaller{Platform: PlatformOpenAI, GroupID: &otherGroup, UserID: 1}, []byte(`{"file_id":"file-a"}`))
+	require.ErrorIs(t, err, ErrUpstreamFileNotFound)
+}
+
+func TestUpstreamFileListFormats(t *testing.T) {
+	svc := &UpstreamFileService{repo: &upstreamFileRepoStub{files: map[string]*UpstreamFile{
+		"openai/file-a":       {Platform: PlatformOpenAI, FileID: "file-a", UserID: 1, Object: []byte(`{"id":"file-a","object":"file"}`)},
+		"anthropic/file_011":  {Platform: PlatformAnthropic, FileID: "file_011", UserID: 1, Object: []byte(`{"id":"file_011","type":"file"}`)},
+		"anthropic/file_othr": {Platform: PlatformAnthropic, FileID: "file_othr", UserID: 2, Object: []byte(`{"id":"file_othr"}`)},
+	}}}
+	ctx := context.Background()
+
+	body, err := svc.List(ctx, UpstreamFileCaller{Platform: PlatformOpenAI, UserID: 1})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"object":"list","data":[{"id":"file-a","object":"file"}],"has_more":false}`, string(body))
+
+	body, err = svc.List(ctx, UpstreamFileCaller{Platform: PlatformAnthropic, UserID: 1})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data":[{"id":"file_011","type":"file"}],"has_more":false,"first_id":"file_011","last_id":"file_011"}`, string(body))
+}
+
+func TestUpstreamFileAccountSupported(t *testing.T) {
+	require.True(t, upstreamFileAccountSupported(PlatformOpenAI, &Account{Platform: PlatformOpenAI, Type: AccountTypeAPIKey}))
+	require.True(t, upstreamFileAccountSupported(PlatformAnthropic, &Account{Platform: PlatformAnthropic, Type: AccountTypeAPIKey}))
+	require.False(t, upstreamFileAccountSupported(PlatformOpenAI, &Account{Platform: PlatformOpenAI, Type: AccountTypeOAuth}))
+	require.False(t, upstreamFileAccountSupported(PlatformAnthropic, &Account{Platform: PlatformOpenAI, Type: AccountTypeAPIKey}))
+}
@@ -168,6 +168,8 @@ type UsageBillingApplyResult struct {
 	NewBalance           *float64           // post-deduction balance (nil = no balance deduction)
 	BalanceOverdrafted   bool               // true when the sufficient-balance guard missed and debt was still recorded
 	QuotaState           *AccountQuotaState // post-increment quota state (nil = no quota increment)
+	// RateLimitPeerAPIKeyIDs 轮换宽限期内与本 Key 一并累加限流用量的同谱系 Key。
+	RateLimitPeerAPIKeyIDs []int64
 }
 
 // BatchImageBalanceHoldCommand describes an idempotent balance hold operation.
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/pkg/errors"
+)
+
+var (
+	ErrUserDataDisabled                = infraerrors.Forbidden("USER_DATA_DISABLED", "self-service data export and account deletion are disabled")
+	ErrUserDataExportInProgress        = infraerrors.Conflict("USER_DATA_EXPORT_IN_PROGRESS", "a data export is already in progress")
+	ErrUserDataExportTooFrequent       = infraerrors.TooManyRequests("USER_DATA_EXPORT_TOO_FREQUENT", "data exports are rate limited, please try again later")
+	ErrUserDataExportNotFound          = infraerrors.NotFound("USER_DATA_EXPORT_NOT_FOUND", "data export not found")
+	ErrUserDataExportNotReady          = infraerrors.Conflict("USER_DATA_EXPORT_NOT_READY", "data export is not available for download")
+	ErrAccountDeletionAlreadyRequested = infraerrors.Conflict("ACCOUNT_DELETION_ALREADY_REQUESTED", "account deletion has already been requested")
+	ErrAccountDeletionNotFound         = infraerrors.NotFound("ACCOUNT_DELETION_NOT_FOUND", "no pending account deletion request")
+	ErrAccountDeletionAdminNotAllowed  = infraerrors.Forbidden("ACCOUNT_DELETION_ADMIN_NOT_ALLOWED", "admin accounts cannot be deleted through self-service")
+)
+
+const (
+	UserDataExportStatusPending   = "pending"
+	UserDataExportStatusRunning   = "running"
+	UserDataExportStatusCompleted = "completed"
+	UserDataExportStatusFailed    = "failed"
+	UserDataExportStatusExpired   = "expired"
+
+	AccountDeletionStatusPending   = "pending"
+	AccountDeletionStatusCancelled = "cancelled"
+	AccountDeletionStatusCompleted = "completed"
+)
+
+// UserDataDataset 导出包中的一个数据集；仓储按数据集逐行输出，服务层负责序列化。
+type UserDataDataset string
+
+const (
+	UserDataDatasetProfile        UserDataDataset = "profile"
+	UserDataDatasetAPIKeys        UserDataDataset = "api_keys"
+	UserDataDatasetSubscriptions  UserDataDataset = "subscriptions"
+	UserDataDatasetBalanceHistory UserDataDataset = "balance_history"
+	UserDataDatasetUsageLogs      UserDataDataset = "usage_logs"
+	UserDataDatasetReferrals      UserDataDataset = "referrals"
+	UserDataDatasetAuthIdentities UserDataDataset = "auth_identities"
+)
+
+// UserDataExport 一次数据导出任务；归档内容单独读取，不随列表返回。
+type UserDataExport struct {
+	ID           int64      `json:"id"`
+	UserID       int64      `json:"-"`
+	Status       string     `json:"status"`
+	ArchiveBytes int64      `json:"archive_bytes"`
+	ErrorMessage string     `json:"error_message,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+// AccountDeletionRequest 账号注销申请；ScheduledFor 之前可撤销。
+type AccountDeletionRequest struct {
+	UserID       int64      `json:"-"`
+	Status       string     `json:"status"`
+	Reason       string     `json:"reason,omitempty"`
+	RequestedAt  time.Time  `json:"requested_at"`
+	ScheduledFor time.Time  `json:"scheduled_for"`
+	CancelledAt  *time.Time `json:"cancelled_at,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	LastError    string     `json:"-"`
+}
+
+// UserDataPurgeOptions 注销执行时的可选清理项（来自保留规则配置）。
+type UserDataPurgeOptions struct {
+	RequestContent  bool
+	ErrorLogDetails bool
+}
+
+// UserDataRepository 数据导出任务、注销申请与注销时的匿名化写入。
+type UserDataRepository interface {
+	// CreateExport 新建导出任务；该用户已有进行中的任务时返回 ErrUserDataExportInProgress。
+	CreateExport(ctx context.Context, userID int64, now time.Time) (*UserDataExport, error)
+	LatestExport(ctx context.Context, userID int64) (*UserDataExport, error)
+	ListExports(ctx context.Context, userID int64, limit int) ([]UserDataExport, error)
+	GetExport(ctx context.Context, userID, exportID int64) (*UserDataExport, error)
+	GetExportArchive(ctx context.Context, userID, exportID int64) ([]byte, error)
+	// ClaimNextExport 领取一个待处理任务（或执行超时的任务）并标记为 running；无任务时返回 nil, nil。
+	ClaimNextExport(ctx context.Context, now, staleBefore time.Time) (*UserDataExport, error)
+	CompleteExport(ctx context.Context, exportID int64, archive []byte, completedAt, expiresAt time.Time) error
+	FailExport(ctx context.Context, exportID int64, message string, at time.Time) error
+	// ExpireExports 删除已过期导出包的归档内容，保留任务记录。
+	ExpireExports(ctx context.Context, now time.Time) (int64, error)
+	// StreamDataset 按数据集逐行回调；limit > 0 时最多输出 limit 行。
+	// 时间值以 RFC3339 字符串输出，敏感列（Key 明文、凭证）不会出现在结果中。
+	StreamDataset(ctx context.Context, userID int64, dataset UserDataDataset, limit int, emit func(columns []string, values []any) error) error
+
+	// CreateDeletionRequest 新建注销申请；已有待执行申请时返回 ErrAccountDeletionAlreadyRequested。
+	CreateDeletionRequest(ctx context.Context, req *AccountDeletionRequest) error
+	GetDeletionRequest(ctx context.Context, userID int64) (*AccountDeletionRequest, error)
+	CancelDeletionRequest(ctx context.Context, userID int64, now time.Time) error
+	ListDueDeletionRequests(ctx context.Context, now time.Time, limit int) ([]AccountDeletionRequest, error)
+	MarkDeletionCompleted(ctx context.Context, userID int64, now time.Time) error
+	MarkDeletionFailed(ctx context.Context, userID int64, message string, cancel bool, now time.Time) error
+
+	// AnonymizeUser 覆盖用户资料中的个人信息并删除登录凭证、身份绑定等附属数据（幂等）。
+	AnonymizeUser(ctx context.Context, userID int64, opts UserDataPurgeOptions) error
+	// AnonymizeUsageLogs 清除一批使用记录的 IP 与 User-Agent，保留计费字段；返回本批更新行数。
+	AnonymizeUsageLogs(ctx context.Context, userID int64, batchSize int) (int64, error)
+}
@@ -0,0 +1,604 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/google/uuid"
+)
+
+const (
+	// userDataLeaderLockKey 保证多实例部署下只有一个实例处理导出与注销任务。
+	userDataLeaderLockKey = "user_data:worker:leader"
+	userDataLeaderLockTTL = 15 * time.Minute
+	userDataRunTimeout    = 10 * time.Minute
+	// userDataExportStaleAfter running 状态超过该时长视为执行实例已退出，任务重新领取。
+	userDataExportStaleAfter    = 30 * time.Minute
+	userDataExportsPerRun       = 5
+	userDataDeletionsPerRun     = 20
+	userDataExportListLimit     = 20
+	userDataDeletionReasonLimit = 500
+
+	defaultUserDataWorkerIntervalSeconds = 30
+	defaultUserDataDownloadTTLHours      = 72
+	defaultUserDataMaxArchiveBytes       = 256 * 1024 * 1024
+	defaultUserDataDeletionBatchSize     = 5000
+)
+
+var errUserDataArchiveTooLarge = errors.New("data export exceeds the archive size limit")
+
+// userDataExportFile 导出包中的一个文件及其格式。
+type userDataExportFile struct {
+	dataset UserDataDataset
+	name    string
+	csv     bool
+	// single 为 true 时输出单个 JSON 对象而非数组。
+	single bool
+}
+
+var userDataExportFiles = []userDataExportFile{
+	{dataset: UserDataDatasetProfile, name: "profile.json", single: true},
+	{dataset: UserDataDatasetAPIKeys, name: "api_keys.json"},
+	{dataset: UserDataDatasetSubscriptions, name: "subscriptions.json"},
+	{dataset: UserDataDatasetBalanceHistory, name: "balance_history.csv", csv: true},
+	{dataset: UserDataDatasetUsageLogs, name: "usage_logs.csv", csv: true},
+	{dataset: UserDataDatasetReferrals, name: "referrals.json"},
+	{dataset: UserDataDatasetAuthIdentities, name: "auth_identities.json"},
+}
+
+// userDataSessionRevoker 复用「撤销全部会话」路径。
+type userDataSessionRevoker interface {
+	RevokeAllUserTokens(ctx context.Context, userID int64) error
+}
+
+// userDataUserDeleter 注销最后一步复用管理员删除用户的路径（软删除用户、删除 Key 并失效认证缓存）。
+type userDataUserDeleter interface {
+	DeleteUser(ctx context.Context, id int64) error
+}
+
+// UserDataService 用户自助数据导出与账号注销：
+// 导出为异步任务，后台生成包含 JSON/CSV 的 ZIP 包，限时可下载；
+// 注销申请经过冷静期后由后台执行，个人信息被匿名化，使用记录保留金额只清除 IP 与 User-Agent，
+// 保证计费汇总不受影响；审计日志按设计只追加，不在清理范围内。
+type UserDataService struct {
+	repo            UserDataRepository
+	userRepo        UserRepository
+	users           userDataUserDeleter
+	sessions        userDataSessionRevoker
+	auditLogService *AuditLogService
+	cfg             config.UserDataConfig
+	now             func() time.Time
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	lockCache  LeaderLockCache
+	db         *sql.DB
+	instanceID string
+}
+
+func NewUserDataService(
+	repo UserDataRepository,
+	userRepo UserRepository,
+	adminService AdminService,
+	authService *AuthService,
+	auditLogService *AuditLogService,
+	cfg config.UserDataConfig,
+) *UserDataService {
+	svc := &UserDataService{
+		repo:            repo,
+		userRepo:        userRepo,
+		auditLogService: auditLogService,
+		cfg:             cfg,
+		now:             time.Now,
+		stopCh:          make(chan struct{}),
+		instanceID:      uuid.NewString(),
+	}
+	if adminService != nil {
+		svc.users = adminService
+	}
+	if authService != nil {
+		svc.sessions = authService
+	}
+	return svc
+}
+
+// SetLeaderLock injects the leader-lock cache and DB used to elect a single
+// instance for export and deletion jobs. When both are nil the run is ungated.
+func (s *UserDataService) SetLeaderLock(lockCache LeaderLockCache, db *sql.DB) {
+	if s == nil {
+		return
+	}
+	s.lockCache = lockCache
+	s.db = db
+}
+
+// Start 按 WorkerIntervalSeconds 周期处理导出与到期的注销申请；功能关闭时不启动。
+func (s *UserDataService) Start() {
+	if s == nil || s.repo == nil || !s.cfg.Enabled {
+		return
+	}
+	interval := time.Duration(s.workerIntervalSeconds()) * time.Second
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		s.runOnce()
+		for {
+			select {
+			case <-ticker.C:
+				s.runOnce()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (s *UserDataService) Stop() {
+	if s == nil {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+	s.wg.Wait()
+}
+
+func (s *UserDataService) runOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), userDataRunTimeout)
+	defer cancel()
+
+	release, ok := tryAcquireSingletonLeaderLock(ctx, s.lockCache, s.db, userDataLeaderLockKey, s.instanceID, userDataLeaderLockTTL)
+	if !ok {
+		return
+	}
+	defer release()
+
+	if expired, err := s.repo.ExpireExports(ctx, s.now()); err != nil {
+		logger.LegacyPrintf("service.user_data", "[UserData] expire exports failed: %v", err)
+	} else if expired > 0 {
+		logger.LegacyPrintf("service.user_data", "[UserData] expired %d export archives", expired)
+	}
+	if _, err := s.RunExports(ctx, userDataExportsPerRun); err != nil {
+		logger.LegacyPrintf("service.user_data", "[UserData] export run failed: %v", err)
+	}
+	if _, err := s.RunDeletions(ctx, userDataDeletionsPerRun); err != nil {
+		logger.LegacyPrintf("service.user_data", "[UserData] deletion run failed: %v", err)
+	}
+}
+
+// RequestExport 为当前用户创建导出任务。
+func (s *UserDataService) RequestExport(ctx context.Context, userID int64) (*UserDataExport, error) {
+	if !s.cfg.Enabled {
+		return nil, ErrUserDataDisabled
+	}
+	now := s.now()
+	if minutes := s.cfg.Export.MinIntervalMinutes; minutes > 0 {
+		latest, err := s.repo.LatestExport(ctx, userID)
+		if err != nil && !errors.Is(err, ErrUserDataExportNotFound) {
+			return nil, err
+		}
+		if latest != nil && latest.Status != UserDataExportStatusFailed &&
+			now.Sub(latest.CreatedAt) < time.Duration(minutes)*time.Minute {
+			if latest.Status == UserDataExportStatusPending || latest.Status == UserDataExportStatusRunning {
+				return nil, ErrUserDataExportInProgress
+			}
+			return nil, ErrUserDataExportTooFrequent
+		}
+	}
+	return s.repo.CreateExport(ctx, userID, now)
+}
+
+func (s *UserDataService) ListExports(ctx context.Context, userID int64) ([]UserDataExport, error) {
+	return s.repo.ListExports(ctx, userID, userDataExportListLimit)
+}
+
+func (s *UserDataService) GetExport(ctx context.Context, userID, exportID int64) (*UserDataExport, error) {
+	return s.repo.GetExport(ctx, userID, exportID)
+}
+
+// DownloadExport 返回已完成且未过期的导出包。
+func (s *UserDataService) DownloadExport(ctx context.Context, userID, exportID int64) (*UserDataExport, []byte, error) {
+	export, err := s.repo.GetExport(ctx, userID, exportID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export.Status != UserDataExportStatusCompleted || (export.ExpiresAt != nil && !export.ExpiresAt.After(s.now())) {
+		return nil, nil, ErrUserDataExportNotReady
+	}
+	archive, err := s.repo.GetExportArchive(ctx, userID, exportID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return export, archive, nil
+}
+
+// RunExports 处理最多 limit 个导出任务，返回完成数量。
+func (s *UserDataService) RunExports(ctx context.Context, limit int) (int, error) {
+	done := 0
+	for i := 0; i < limit; i++ {
+		now := s.now()
+		export, err := s.repo.ClaimNextExport(ctx, now, now.Add(-userDataExportStaleAfter))
+		if err != nil {
+			return done, err
+		}
+		if export == nil {
+			return done, nil
+		}
+		archive, err := s.BuildExportArchive(ctx, export.UserID)
+		if err != nil {
+			logger.LegacyPrintf("service.user_data", "[UserData] export %d for user %d failed: %v", export.ID, export.UserID, err)
+			message := "failed to build data export"
+			if errors.Is(err, errUserDataArchiveTooLarge) {
+				message = errUserDataArchiveTooLarge.Error()
+			}
+			if failErr := s.repo.FailExport(ctx, export.ID, message, s.now()); failErr != nil {
+				return done, failErr
+			}
+			continue
+		}
+		completedAt := s.now()
+		expiresAt := completedAt.Add(time.Duration(s.downloadTTLHours()) * time.Hour)
+		if err := s.repo.CompleteExport(ctx, export.ID, archive, completedAt, expiresAt); err != nil {
+			return done, err
+		}
+		done++
+	}
+	return done, nil
+}
+
+// BuildExportArchive 生成用户数据 ZIP 包：每个数据集一个文件，外加 manifest.json。
+func (s *UserDataService) BuildExportArchive(ctx context.Context, userID int64) ([]byte, error) {
+	var buf bytes.Buffer
+	limited := &userDataLimitedWriter{w: &buf, remaining: s.maxArchiveBytes()}
+	zw := zip.NewWriter(limited)
+
+	type manifestFile struct {
+		Name      string `json:"name"`
+		Rows      int    `json:"rows"`
+		Truncated bool   `json:"truncated,omitempty"`
+	}
+	manifest := struct {
+		UserID      int64          `json:"user_id"`
+		GeneratedAt time.Time      `json:"generated_at"`
+		Files       []manifestFile `json:"files"`
+	}{UserID: userID, GeneratedAt: s.now().UTC()}
+
+	for _, file := range userDataExportFiles {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return nil, userDataArchiveError(err)
+		}
+		limit := 0
+		if file.dataset == UserDataDatasetUsageLogs {
+			limit = s.cfg.Export.MaxUsageRows
+		}
+		rows, err := s.writeDataset(ctx, w, userID, file, limit)
+		if err != nil {
+			return nil, userDataArchiveError(err)
+		}
+		manifest.Files = append(manifest.Files, manifestFile{Name: file.name, Rows: rows, Truncated: limit > 0 && rows >= limit})
+	}
+
+	w, err := zw.Create("manifest.json")
+	if err != nil {
+		return nil, userDataArchiveError(err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return nil, userDataArchiveError(err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, userDataArchiveError(err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *UserDataService) writeDataset(ctx context.Context, w io.Writer, userID int64, file userDataExportFile, limit int) (int, error) {
+	rows := 0
+	if file.csv {
+		cw := csv.NewWriter(w)
+		var header []string
+		err := s.repo.StreamDataset(ctx, userID, file.dataset, limit, func(columns []string, values []any) error {
+			if header == nil {
+				header = columns
+				if err := cw.Write(header); err != nil {
+					return err
+				}
+			}
+			record := make([]string, len(values))
+			for i, v := range values {
+				record[i] = userDataCSVValue(v)
+			}
+			rows++
+			return cw.Write(record)
+		})
+		if err != nil {
+			return rows, err
+		}
+		cw.Flush()
+		return rows, cw.Error()
+	}
+
+	if !file.single {
+		if _, err := io.WriteString(w, "["); err != nil {
+			return 0, err
+		}
+	}
+	err := s.repo.StreamDataset(ctx, userID, file.dataset, limit, func(columns []string, values []any) error {
+		if file.single && rows > 0 {
+			return nil
+		}
+		prefix := "\n  "
+		if rows > 0 {
+			prefix = ",\n  "
+		}
+		if file.single {
+			prefix = ""
+		}
+		obj, err := userDataJSONObject(columns, values)
+		if err != nil {
+			return err
+		}
+		rows++
+		_, err = io.WriteString(w, prefix+obj)
+		return err
+	})
+	if err != nil {
+		return rows, err
+	}
+	switch {
+	case file.single && rows == 0:
+		_, err = io.WriteString(w, "{}\n")
+	case file.single:
+		_, err = io.WriteString(w, "\n")
+	case rows == 0:
+		_, err = io.WriteString(w, "]\n")
+	default:
+		_, err = io.WriteString(w, "\n]\n")
+	}
+	return rows, err
+}
+
+// userDataJSONObject 按列顺序序列化一行（map 会打乱字段顺序）。
+func userDataJSONObject(columns []string, values []any) (string, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, col := range columns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		key, _ := json.Marshal(col)
+		b.Write(key)
+		b.WriteString(": ")
+		var v any
+		if i < len(values) {
+			v = values[i]
+		}
+		if raw, ok := v.(json.RawMessage); ok && json.Valid(raw) {
+			b.Write(raw)
+			continue
+		}
+		val, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		b.Write(val)
+	}
+	b.WriteByte('}')
+	return b.String(), nil
+}
+
+func userDataCSVValue(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	case json.RawMessage:
+		return string(val)
+	case bool:
+		return strconv.FormatBool(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case time.Time:
+		return val.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+func userDataArchiveError(err error) error {
+	if errors.Is(err, errUserDataArchiveTooLarge) {
+		return errUserDataArchiveTooLarge
+	}
+	return err
+}
+
+// userDataLimitedWriter 超过上限时返回 errUserDataArchiveTooLarge，避免超大导出占满内存。
+type userDataLimitedWriter struct {
+	w         io.Writer
+	remaining int64
+}
+
+func (l *userDataLimitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > l.remaining {
+		return 0, errUserDataArchiveTooLarge
+	}
+	n, err := l.w.Write(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// RequestDeletion 提交注销申请，冷静期结束后由后台执行；管理员账号不能自助注销。
+func (s *UserDataService) RequestDeletion(ctx context.Context, userID int64, reason string) (*AccountDeletionRequest, error) {
+	if !s.cfg.Enabled {
+		return nil, ErrUserDataDisabled
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsAdmin() {
+		return nil, ErrAccountDeletionAdminNotAllowed
+	}
+	reason = strings.TrimSpace(reason)
+	if runes := []rune(reason); len(runes) > userDataDeletionReasonLimit {
+		reason = string(runes[:userDataDeletionReasonLimit])
+	}
+	now := s.now()
+	req := &AccountDeletionRequest{
+		UserID:       userID,
+		Status:       AccountDeletionStatusPending,
+		Reason:       reason,
+		RequestedAt:  now,
+		ScheduledFor: now.Add(time.Duration(s.cfg.Deletion.CoolingOffDays) * 24 * time.Hour),
+	}
+	if err := s.repo.CreateDeletionRequest(ctx, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (s *UserDataService) GetDeletion(ctx context.Context, userID int64) (*AccountDeletionRequest, error) {
+	return s.repo.GetDeletionRequest(ctx, userID)
+}
+
+// CancelDeletion 在冷静期内撤销注销申请。
+func (s *UserDataService) CancelDeletion(ctx context.Context, userID int64) error {
+	return s.repo.CancelDeletionRequest(ctx, userID, s.now())
+}
+
+// RunDeletions 执行最多 limit 个已过冷静期的注销申请，返回完成数量。
+func (s *UserDataService) RunDeletions(ctx context.Context, limit int) (int, error) {
+	due, err := s.repo.ListDueDeletionRequests(ctx, s.now(), limit)
+	if err != nil {
+		return 0, err
+	}
+	done := 0
+	for i := range due {
+		completed, err := s.executeDeletion(ctx, &due[i])
+		if err != nil {
+			logger.LegacyPrintf("service.user_data", "[UserData] deletion for user %d failed: %v", due[i].UserID, err)
+			if markErr := s.repo.MarkDeletionFailed(ctx, due[i].UserID, err.Error(), false, s.now()); markErr != nil {
+				return done, markErr
+			}
+			continue
+		}
+		if completed {
+			done++
+		}
+	}
+	return done, nil
+}
+
+// executeDeletion 各步骤均幂等，失败后下一轮重试即可。
+// 申请因账号已成为管理员被撤销时返回 false, nil。
+func (s *UserDataService) executeDeletion(ctx context.Context, req *AccountDeletionRequest) (bool, error) {
+	user, err := s.userRepo.GetByID(ctx, req.UserID)
+	switch {
+	case err == nil:
+		// 冷静期内被提升为管理员的账号不执行注销。
+		if user.IsAdmin() {
+			return false, s.repo.MarkDeletionFailed(ctx, req.UserID, ErrAccountDeletionAdminNotAllowed.Error(), true, s.now())
+		}
+	case errors.Is(err, ErrUserNotFound):
+		// 上一轮已软删除，继续完成剩余的匿名化步骤。
+		user = nil
+	default:
+		return false, err
+	}
+
+	if user != nil && s.sessions != nil {
+		if err := s.sessions.RevokeAllUserTokens(ctx, req.UserID); err != nil {
+			return false, fmt.Errorf("revoke sessions: %w", err)
+		}
+	}
+	opts := UserDataPurgeOptions{
+		RequestContent:  s.cfg.Deletion.PurgeRequestContent,
+		ErrorLogDetails: s.cfg.Deletion.PurgeErrorLogDetails,
+	}
+	if err := s.repo.AnonymizeUser(ctx, req.UserID, opts); err != nil {
+		return false, fmt.Errorf("anonymize user: %w", err)
+	}
+	batchSize := s.deletionBatchSize()
+	for {
+		n, err := s.repo.AnonymizeUsageLogs(ctx, req.UserID, batchSize)
+		if err != nil {
+			return false, fmt.Errorf("anonymize usage logs: %w", err)
+		}
+		if n < int64(batchSize) {
+			break
+		}
+	}
+	if user != nil && s.users != nil {
+		if err := s.users.DeleteUser(ctx, req.UserID); err != nil && !errors.Is(err, ErrUserNotFound) {
+			return false, fmt.Errorf("delete user: %w", err)
+		}
+	}
+	if err := s.repo.MarkDeletionCompleted(ctx, req.UserID, s.now()); err != nil {
+		return false, err
+	}
+	if s.auditLogService != nil {
+		s.auditLogService.Record(&AuditLog{
+			ActorRole:  AuditAuthMethodSystem,
+			AuthMethod: AuditAuthMethodSystem,
+			Action:     AuditActionAccountDeleted,
+			Extra: map[string]any{
+				"user_id":      req.UserID,
+				"requested_at": req.RequestedAt,
+			},
+		})
+	}
+	return true, nil
+}
+
+func (s *UserDataService) workerIntervalSeconds() int {
+	if s.cfg.WorkerIntervalSeconds > 0 {
+		return s.cfg.WorkerIntervalSeconds
+	}
+	return defaultUserDataWorkerIntervalSeconds
+}
+
+func (s *UserDataService) downloadTTLHours() int {
+	if s.cfg.Export.DownloadTTLHours > 0 {
+		return s.cfg.Export.DownloadTTLHours
+	}
+	return defaultUserDataDownloadTTLHours
+}
+
+func (s *UserDataService) maxArchiveBytes() int64 {
+	if s.cfg.Export.MaxArchiveBytes > 0 {
+		return s.cfg.Export.MaxArchiveBytes
+	}
+	return defaultUserDataMaxArchiveBytes
+}
+
+func (s *UserDataService) deletionBatchSize() int {
+	if s.cfg.Deletion.BatchSize > 0 {
+		return s.cfg.Deletion.BatchSize
+	}
+	return defaultUserDataDeletionBatchSize
+}
@@ -0,0 +1,463 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+type userDataRepoStub struct {
+	UserDataRepository
+
+	exports   []*UserDataExport
+	archives  map[int64][]byte
+	datasets  map[UserDataDataset][][]any
+	columns   map[UserDataDataset][]string
+	deletions map[int64]*AccountDeletionRequest
+
+	anonymized    []int64
+	purgeOpts     UserDataPurgeOptions
+	usageRows     int64
+	usageBatches  int
+	nextExportID  int64
+	completedUser []int64
+}
+
+func newUserDataRepoStub() *userDataRepoStub {
+	return &userDataRepoStub{
+		archives:  map[int64][]byte{},
+		datasets:  map[UserDataDataset][][]any{},
+		columns:   map[UserDataDataset][]string{},
+		deletions: map[int64]*AccountDeletionRequest{},
+	}
+}
+
+func (r *userDataRepoStub) CreateExport(_ context.Context, userID int64, now time.Time) (*UserDataExport, error) {
+	for _, e := range r.exports {
+		if e.UserID == userID && (e.Status == UserDataExportStatusPending || e.Status == UserDataExportStatusRunning) {
+			return nil, ErrUserDataExportInProgress
+		}
+	}
+	r.nextExportID++
+	e := &UserDataExport{ID: r.nextExportID, UserID: userID, Status: UserDataExportStatusPending, CreatedAt: now}
+	r.exports = append(r.exports, e)
+	clone := *e
+	return &clone, nil
+}
+
+func (r *userDataRepoStub) LatestExport(_ context.Context, userID int64) (*UserDataExport, error) {
+	for i := len(r.exports) - 1; i >= 0; i-- {
+		if r.exports[i].UserID == userID {
+			clone := *r.exports[i]
+			return &clone, nil
+		}
+	}
+	return nil, ErrUserDataExportNotFound
+}
+
+func (r *userDataRepoStub) GetExport(_ context.Context, userID, exportID int64) (*UserDataExport, error) {
+	for _, e := range r.exports {
+		if e.ID == exportID && e.UserID == userID {
+			clone := *e
+			return &clone, nil
+		}
+	}
+	return nil, ErrUserDataExportNotFound
+}
+
+func (r *userDataRepoStub) GetExportArchive(_ context.Context, _ int64, exportID int64) ([]byte, error) {
+	archive, ok := r.archives[exportID]
+	if !ok {
+		return nil, ErrUserDataExportNotReady
+	}
+	return archive, nil
+}
+
+func (r *userDataRepoStub) ClaimNextExport(_ context.Context, now, _ time.Time) (*UserDataExport, error) {
+	for _, e := range r.exports {
+		if e.Status == UserDataExportStatusPending {
+			e.Status = UserDataExportStatusRunning
+			e.StartedAt = &now
+			clone := *e
+			return &clone, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *userDataRepoStub) CompleteExport(_ context.Context, exportID int64, archive []byte, completedAt, expiresAt time.Time) error {
+	for _, e := range r.exports {
+		if e.ID == exportID {
+			e.Status = UserDataExportStatusCompleted
+			e.ArchiveBytes = int64(len(archive))
+			e.CompletedAt = &completedAt
+			e.ExpiresAt = &expiresAt
+			r.archives[exportID] = archive
+		}
+	}
+	return nil
+}
+
+func (r *userDataRepoStub) FailExport(_ context.Context, exportID int64, message string, at time.Time) error {
+	for _, e := range r.exports {
+		if e.ID == exportID {
+			e.Status = UserDataExportStatusFailed
+			e.ErrorMessage = message
+			e.CompletedAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *userDataRepoStub) StreamDataset(_ context.Context, _ int64, dataset UserDataDataset, limit int, emit func(columns []string, values []any) error) error {
+	for i, row := range r.datasets[dataset] {
+		if limit > 0 && i >= limit {
+			break
+		}
+		if err := emit(r.columns[dataset], row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *userDataRepoStub) CreateDeletionRequest(_ context.Context, req *AccountDeletionRequest) error {
+	if existing, ok := r.deletions[req.UserID]; ok && existing.Status != AccountDeletionStatusCancelled {
+		return ErrAccountDeletionAlreadyRequested
+	}
+	clone := *req
+	r.deletions[req.UserID] = &clone
+	return nil
+}
+
+func (r *userDataRepoStub) GetDeletionRequest(_ context.Context, userID int64) (*AccountDeletionRequest, error) {
+	req, ok := r.deletions[userID]
+	if !ok {
+		return nil, ErrAccountDeletionNotFound
+	}
+	clone := *req
+	return &clone, nil
+}
+
+func (r *userDataRepoStub) CancelDeletionRequest(_ context.Context, userID int64, now time.Time) error {
+	req, ok := r.deletions[userID]
+	if !ok || req.Status != AccountDeletionStatusPending {
+		return ErrAccountDeletionNotFound
+	}
+	req.Status = AccountDeletionStatusCancelled
+	req.CancelledAt = &now
+	return nil
+}
+
+func (r *userDataRepoStub) ListDueDeletionRequests(_ context.Context, now time.Time, _ int) ([]AccountDeletionRequest, error) {
+	var out []AccountDeletionRequest
+	for _, req := range r.deletions {
+		if req.Status == AccountDeletionStatusPending && !req.ScheduledFor.After(now) {
+			out = append(out, *req)
+		}
+	}
+	return out, nil
+}
+
+func (r *userDataRepoStub) MarkDeletionCompleted(_ context.Context, userID int64, now time.Time) error {
+	r.deletions[userID].Status = AccountDeletionStatusCompleted
+	r.deletions[userID].CompletedAt = &now
+	r.completedUser = append(r.completedUser, userID)
+	return nil
+}
+
+func (r *userDataRepoStub) MarkDeletionFailed(_ context.Context, userID int64, message string, cancel bool, now time.Time) error {
+	req := r.deletions[userID]
+	req.LastError = message
+	if cancel {
+		req.Status = AccountDeletionStatusCancelled
+		req.CancelledAt = &now
+	}
+	return nil
+}
+
+func (r *userDataRepoStub) AnonymizeUser(_ context.Context, userID int64, opts UserDataPurgeOptions) error {
+	r.anonymized = append(r.anonymized, userID)
+	r.purgeOpts = opts
+	return nil
+}
+
+func (r *userDataRepoStub) AnonymizeUsageLogs(_ context.Context, _ int64, batchSize int) (int64, error) {
+	r.usageBatches++
+	n := r.usageRows
+	if n > int64(batchSize) {
+		n = int64(batchSize)
+	}
+	r.usageRows -= n
+	return n, nil
+}
+
+type userDataUserRepoStub struct {
+	UserRepository
+	users map[int64]*User
+}
+
+func (s *userDataUserRepoStub) GetByID(_ context.Context, id int64) (*User, error) {
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	clone := *user
+	return &clone, nil
+}
+
+type userDataDeleterStub struct {
+	users   *userDataUserRepoStub
+	deleted []int64
+}
+
+func (s *userDataDeleterStub) DeleteUser(_ context.Context, id int64) error {
+	s.deleted = append(s.deleted, id)
+	delete(s.users.users, id)
+	return nil
+}
+
+type userDataSessionStub struct {
+	revoked []int64
+}
+
+func (s *userDataSessionStub) RevokeAllUserTokens(_ context.Context, userID int64) error {
+	s.revoked = append(s.revoked, userID)
+	return nil
+}
+
+func newUserDataServiceForTest(t *testing.T) (*UserDataService, *userDataRepoStub, *userDataUserRepoStub, *time.Time) {
+	t.Helper()
+	repo := newUserDataRepoStub()
+	users := &userDataUserRepoStub{users: map[int64]*User{
+		1: {ID: 1, Role: RoleUser, Email: "user@example.com"},
+		2: {ID: 2, Role: RoleAdmin, Email: "admin@example.com"},
+	}}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	svc := &UserDataService{
+		repo:     repo,
+		userRepo: users,
+		users:    &userDataDeleterStub{users: users},
+		sessions: &userDataSessionStub{},
+		cfg: config.UserDataConfig{
+			Enabled: true,
+			Export:  config.UserDataExportConfig{DownloadTTLHours: 24, MaxArchiveBytes: 1 << 20, MaxUsageRows: 2, MinIntervalMinutes: 60},
+			Deletion: config.UserDataDeletionConfig{
+				CoolingOffDays:      14,
+				PurgeRequestContent: true,
+				BatchSize:           2,
+			},
+		},
+		now: func() time.Time { return now },
+	}
+	return svc, repo, users, &now
+}
+
+func readUserDataZip(t *testing.T, archive []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		files[f.Name] = string(data)
+	}
+	return files
+}
+
+func TestUserDataService_RequestExportLimits(t *testing.T) {
+	svc, _, _, now := newUserDataServiceForTest(t)
+	ctx := context.Background()
+
+	export, err := svc.RequestExport(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, UserDataExportStatusPending, export.Status)
+
+	_, err = svc.RequestExport(ctx, 1)
+	require.ErrorIs(t, err, ErrUserDataExportInProgress)
+
+	_, err = svc.RunExports(ctx, 5)
+	require.NoError(t, err)
+	_, err = svc.RequestExport(ctx, 1)
+	require.ErrorIs(t, err, ErrUserDataExportTooFrequent)
+
+	*now = now.Add(61 * time.Minute)
+	_, err = svc.RequestExport(ctx, 1)
+	require.NoError(t, err)
+
+	svc.cfg.Enabled = false
+	_, err = svc.RequestExport(ctx, 3)
+	require.ErrorIs(t, err, ErrUserDataDisabled)
+}
+
+func TestUserDataService_RunExportsBuildsArchive(t *testing.T) {
+	svc, repo, _, now := newUserDataServiceForTest(t)
+	ctx := context.Background()
+	repo.columns[UserDataDatasetProfile] = []string{"id", "email", "attributes"}
+	repo.datasets[UserDataDatasetProfile] = [][]any{{int64(1), "user@example.com", json.RawMessage(`{"company":"acme"}`)}}
+	repo.columns[UserDataDatasetAPIKeys] = []string{"id", "key_masked"}
+	repo.datasets[UserDataDatasetAPIKeys] = [][]any{{int64(7), "sk-abc****"}}
+	repo.columns[UserDataDatasetUsageLogs] = []string{"id", "model", "actual_cost", "ip_address"}
+	repo.datasets[UserDataDatasetUsageLogs] = [][]any{
+		{int64(3), "claude-sonnet-4-5", 0.25, "203.0.113.9"},
+		{int64(2), "claude-sonnet-4-5", 0.5, nil},
+		{int64(1), "gpt-5", 1.0, nil},
+	}
+
+	export, err := svc.RequestExport(ctx, 1)
+	require.NoError(t, err)
+	_, _, err = svc.DownloadExport(ctx, 1, export.ID)
+	require.ErrorIs(t, err, ErrUserDataExportNotReady)
+
+	done, err := svc.RunExports(ctx, 5)
+	require.NoError(t, err)
+	require.Equal(t, 1, done)
+
+	got, archive, err := svc.DownloadExport(ctx, 1, export.ID)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(24*time.Hour), *got.ExpiresAt)
+	files := readUserDataZip(t, archive)
+	for _, name := range []string{"profile.json", "api_keys.json", "subscriptions.json", "balance_history.csv", "usage_logs.csv", "referrals.json", "auth_identities.json", "manifest.json"} {
+		require.Contains(t, files, name)
+	}
+
+	var profile map[string]any
+	require.NoError(t, json.Unmarshal([]byte(files["profile.json"]), &profile))
+	require.Equal(t, "user@example.com", profile["email"])
+	require.Equal(t, map[string]any{"company": "acme"}, profile["attributes"])
+
+	var keys []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(files["api_keys.json"]), &keys))
+	require.Len(t, keys, 1)
+	require.Equal(t, "sk-abc****", keys[0]["key_masked"])
+
+	var subs []any
+	require.NoError(t, json.Unmarshal([]byte(files["subscriptions.json"]), &subs))
+	require.Empty(t, subs)
+
+	records, err := csv.NewReader(bytes.NewReader([]byte(files["usage_logs.csv"]))).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"id", "model", "actual_cost", "ip_address"},
+		{"3", "claude-sonnet-4-5", "0.25", "203.0.113.9"},
+		{"2", "claude-sonnet-4-5", "0.5", ""},
+	}, records)
+
+	var manifest struct {
+		UserID int64 `json:"user_id"`
+		Files  []struct {
+			Name      string `json:"name"`
+			Rows      int    `json:"rows"`
+			Truncated bool   `json:"truncated"`
+		} `json:"files"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(files["manifest.json"]), &manifest))
+	require.Equal(t, int64(1), manifest.UserID)
+	for _, f := range manifest.Files {
+		if f.Name == "usage_logs.csv" {
+			require.Equal(t, 2, f.Rows)
+			require.True(t, f.Truncated)
+		}
+	}
+
+	// 其他用户不能下载；过期后不可下载。
+	_, _, err = svc.DownloadExport(ctx, 2, export.ID)
+	require.ErrorIs(t, err, ErrUserDataExportNotFound)
+	*now = now.Add(25 * time.Hour)
+	_, _, err = svc.DownloadExport(ctx, 1, export.ID)
+	require.ErrorIs(t, err, ErrUserDataExportNotReady)
+}
+
+func TestUserDataService_RunExportsFailsOversizedArchive(t *testing.T) {
+	svc, repo, _, _ := newUserDataServiceForTest(t)
+	ctx := context.Background()
+	svc.cfg.Export.MaxArchiveBytes = 256
+	repo.columns[UserDataDatasetProfile] = []string{"id", "notes"}
+	repo.datasets[UserDataDatasetProfile] = [][]any{{int64(1), string(bytes.Repeat([]byte("x"), 4096))}}
+
+	export, err := svc.RequestExport(ctx, 1)
+	require.NoError(t, err)
+	done, err := svc.RunExports(ctx, 5)
+	require.NoError(t, err)
+	require.Zero(t, done)
+
+	got, err := svc.GetExport(ctx, 1, export.ID)
+	require.NoError(t, err)
+	require.Equal(t, UserDataExportStatusFailed, got.Status)
+	require.Equal(t, errUserDataArchiveTooLarge.Error(), got.ErrorMessage)
+}
+
+func TestUserDataService_DeletionLifecycle(t *testing.T) {
+	svc, repo, users, now := newUserDataServiceForTest(t)
+	ctx := context.Background()
+	deleter := svc.users.(*userDataDeleterStub)
+	sessions := svc.sessions.(*userDataSessionStub)
+
+	_, err := svc.RequestDeletion(ctx, 2, "")
+	require.ErrorIs(t, err, ErrAccountDeletionAdminNotAllowed)
+
+	req, err := svc.RequestDeletion(ctx, 1, "  leaving  ")
+	require.NoError(t, err)
+	require.Equal(t, "leaving", req.Reason)
+	require.Equal(t, now.Add(14*24*time.Hour), req.ScheduledFor)
+	_, err = svc.RequestDeletion(ctx, 1, "")
+	require.ErrorIs(t, err, ErrAccountDeletionAlreadyRequested)
+
+	// 冷静期内撤销后可重新申请。
+	require.NoError(t, svc.CancelDeletion(ctx, 1))
+	require.ErrorIs(t, svc.CancelDeletion(ctx, 1), ErrAccountDeletionNotFound)
+	_, err = svc.RequestDeletion(ctx, 1, "")
+	require.NoError(t, err)
+
+	done, err := svc.RunDeletions(ctx, 10)
+	require.NoError(t, err)
+	require.Zero(t, done)
+	require.Empty(t, repo.anonymized)
+
+	*now = now.Add(15 * 24 * time.Hour)
+	repo.usageRows = 5
+	done, err = svc.RunDeletions(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, done)
+
+	require.Equal(t, []int64{1}, repo.anonymized)
+	require.True(t, repo.purgeOpts.RequestContent)
+	require.Zero(t, repo.usageRows)
+	require.Equal(t, 3, repo.usageBatches)
+	require.Equal(t, []int64{1}, deleter.deleted)
+	require.Equal(t, []int64{1}, sessions.revoked)
+	require.NotContains(t, users.users, int64(1))
+
+	got, err := svc.GetDeletion(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, AccountDeletionStatusCompleted, got.Status)
+}
+
+func TestUserDataService_DeletionSkipsPromotedAdmin(t *testing.T) {
+	svc, repo, users, now := newUserDataServiceForTest(t)
+	ctx := context.Background()
+
+	_, err := svc.RequestDeletion(ctx, 1, "")
+	require.NoError(t, err)
+	users.users[1].Role = RoleAdmin
+
+	*now = now.Add(15 * 24 * time.Hour)
+	done, err := svc.RunDeletions(ctx, 10)
+	require.NoError(t, err)
+	require.Zero(t, done)
+	require.Empty(t, repo.anonymized)
+	require.Equal(t, AccountDeletionStatusCancelled, repo.deletions[1].Status)
+	require.NotEmpty(t, repo.deletions[1].LastError)
+}
@@ -799,7 +799,7 @@ func (s *UserService) canUnbindProvider(provider string, user *User, records []U
 		return true
 	}
 
-	for _, candidate := range []string{"linuxdo", "oidc", "wechat", "dingtalk"} {
+	for _, candidate := range []string{"linuxdo", "oidc", "wechat", "dingtalk", "saml"} {
 		if candidate == provider {
 			continue
 		}
@@ -877,6 +877,8 @@ func buildUserIdentityBindAuthorizeURL(provider, redirectTo string) (string, err
 		path = "/api/v1/auth/oauth/wechat/bind/start"
 	case "dingtalk":
 		path = "/api/v1/auth/oauth/dingtalk/bind/start"
+	case "saml":
+		path = "/api/v1/auth/oauth/saml/bind/start"
 	default:
 		return "", ErrIdentityProviderInvalid
 	}
@@ -897,6 +899,8 @@ func normalizeUserIdentityProvider(provider string) string {
 		return "wechat"
 	case "dingtalk":
 		return "dingtalk"
+	case "saml":
+		return "saml"
 	case "email":
 		return "email"
 	default:
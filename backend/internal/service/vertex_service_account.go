@@ -301,7 +301,7 @@ func buildVertexGeminiURL(projectID, location, model, action string, stream bool
 		return "", errors.New("vertex model is required")
 	}
 	switch action {
-	case "generateContent", "streamGenerateContent", "countTokens":
+	case "generateContent", "streamGenerateContent", "countTokens", "predict":
 	default:
 		return "", fmt.Errorf("unsupported vertex gemini action: %s", action)
 	}
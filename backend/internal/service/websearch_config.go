@@ -123,6 +123,7 @@ func (s *SettingService) loadWebSearchConfigFromDB() (*WebSearchEmulationConfig,
 		return &WebSearchEmulationConfig{}, err
 	}
 	cfg := parseWebSearchConfigJSON(raw)
+	s.openWebSearchAPIKeys(dbCtx, cfg)
 	webSearchEmulationCache.Store(&cachedWebSearchEmulationConfig{
 		config:    cfg,
 		expiresAt: time.Now().Add(webSearchEmulationCacheTTL).UnixNano(),
@@ -160,9 +161,9 @@ func (s *SettingService) SaveWebSearchEmulationConfig(ctx context.Context, cfg *
 		}
 	}
 
-	data, err := json.Marshal(cfg)
+	data, err := s.marshalWebSearchConfigForStorage(ctx, cfg)
 	if err != nil {
-		return fmt.Errorf("websearch: marshal config: %w", err)
+		return err
 	}
 	if err := s.settingRepo.Set(ctx, SettingKeyWebSearchEmulationConfig, string(data)); err != nil {
 		return fmt.Errorf("websearch: save config: %w", err)
@@ -205,7 +206,41 @@ func (s *SettingService) getWebSearchEmulationConfigRaw(ctx context.Context) (*W
 	if err != nil {
 		return nil, err
 	}
-	return parseWebSearchConfigJSON(raw), nil
+	cfg := parseWebSearchConfigJSON(raw)
+	s.openWebSearchAPIKeys(ctx, cfg)
+	return cfg, nil
+}
+
+// marshalWebSearchConfigForStorage encrypts provider API keys before persisting;
+// cfg itself keeps the plaintext keys for the in-process cache.
+func (s *SettingService) marshalWebSearchConfigForStorage(ctx context.Context, cfg *WebSearchEmulationConfig) ([]byte, error) {
+	stored := *cfg
+	stored.Providers = append([]WebSearchProviderConfig(nil), cfg.Providers...)
+	for i := range stored.Providers {
+		sealed, err := s.credentialCipher.SealString(ctx, stored.Providers[i].APIKey)
+		if err != nil {
+			return nil, fmt.Errorf("websearch: encrypt api key: %w", err)
+		}
+		stored.Providers[i].APIKey = sealed
+	}
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return nil, fmt.Errorf("websearch: marshal config: %w", err)
+	}
+	return data, nil
+}
+
+// openWebSearchAPIKeys decrypts provider API keys loaded from the settings table.
+// Keys that fail to decrypt are cleared so the ciphertext is never sent upstream.
+func (s *SettingService) openWebSearchAPIKeys(ctx context.Context, cfg *WebSearchEmulationConfig) {
+	for i := range cfg.Providers {
+		key, err := s.credentialCipher.OpenString(ctx, cfg.Providers[i].APIKey)
+		if err != nil {
+			slog.Warn("websearch: failed to decrypt provider api key", "provider", cfg.Providers[i].Type, "error", err)
+			key = ""
+		}
+		cfg.Providers[i].APIKey = key
+	}
 }
 
 // IsWebSearchEmulationEnabled is a quick check for whether the global switch is on.
@@ -340,8 +340,10 @@ func ProvideOpenAICodexVersionSyncService(
 }
 
 // ProvideProxyExpiryService creates and starts ProxyExpiryService.
-func ProvideProxyExpiryService(proxyRepo ProxyRepository) *ProxyExpiryService {
+func ProvideProxyExpiryService(proxyRepo ProxyRepository, poolSvc *ProxyPoolService, subSvc *ProxySubscriptionService) *ProxyExpiryService {
 	svc := NewProxyExpiryService(proxyRepo, time.Minute)
+	svc.SetProxyPoolService(poolSvc)
+	svc.SetProxySubscriptionService(subSvc)
 	svc.Start()
 	return svc
 }
@@ -499,9 +501,29 @@ func ProvideOpsSystemLogSink(opsRepo OpsRepository) *OpsSystemLogSink {
 }
 
 // ProvideAuditLogService 创建操作审计日志服务并启动异步写入与保留期清理协程。
-// 停止逻辑挂在 cmd/server 的 provideCleanup。
-func ProvideAuditLogService(repo AuditLogRepository, settingService *SettingService) *AuditLogService {
+// 归档复用备份服务的对象存储；停止逻辑挂在 cmd/server 的 provideCleanup。
+func ProvideAuditLogService(repo AuditLogRepository, settingService *SettingService, backupService *BackupService, cfg *config.Config) *AuditLogService {
 	svc := NewAuditLogService(repo, settingService)
+	if backupService != nil {
+		svc.SetArchiveStore(backupService, cfg.Security.AuditLog.ArchivePageSize)
+	}
+	svc.Start()
+	return svc
+}
+
+// ProvideAuditLogExportService 创建 SIEM 外送服务，并让审计归档不越过尚未外送的记录。
+func ProvideAuditLogExportService(
+	repo AuditLogRepository,
+	cursors AuditLogExportCursorRepository,
+	auditLogService *AuditLogService,
+	cfg *config.Config,
+	buildInfo BuildInfo,
+	lockCache LeaderLockCache,
+	db *sql.DB,
+) *AuditLogExportService {
+	svc := NewAuditLogExportService(repo, cursors, cfg.Security.AuditLog.Export, buildInfo)
+	auditLogService.SetExportHorizon(svc.Horizon)
+	svc.SetLeaderLock(lockCache, db)
 	svc.Start()
 	return svc
 }
@@ -592,7 +614,7 @@ func ProvideAPIKeyAuthCacheInvalidator(apiKeyService *APIKeyService) APIKeyAuthC
 // 使升级前已通过配置文件开启该功能的部署不被打断。
 func ProvideImageStorageSettingService(
 	settingRepo SettingRepository,
-	encryptor SecretEncryptor,
+	encryptor *CredentialCipher,
 	backup *BackupService,
 	factory ImageStorageFactory,
 	cfg *config.Config,
@@ -615,11 +637,124 @@ func ProvideImageTaskService(store ImageTaskStore, settings *ImageStorageSetting
 	return NewImageTaskServiceWithResolver(store, settings.Resolver(), defaultImageTaskTTL, defaultImageTaskExecutionTimeout)
 }
 
+// ProvideBackgroundResponseService 构造网关托管的后台 Responses 服务。
+// 与异步图片任务共用对象存储设置：终态结果落对象存储，开关热切换无需重启。
+func ProvideBackgroundResponseService(store BackgroundResponseStore, settings *ImageStorageSettingService) *BackgroundResponseService {
+	return NewBackgroundResponseService(store, settings.Resolver(), defaultBackgroundResponseTTL, defaultBackgroundResponseExecutionTimeout)
+}
+
+// ProvideInvoiceStorageSettingService 构造发票对象存储设置服务，S3 secret 使用凭证信封加密。
+func ProvideInvoiceStorageSettingService(settingRepo SettingRepository, encryptor *CredentialCipher, backup *BackupService) *InvoiceStorageSettingService {
+	return NewInvoiceStorageSettingService(settingRepo, encryptor, backup)
+}
+
+// ProvideCredentialReencryptionService creates and starts CredentialReencryptionService.
+func ProvideCredentialReencryptionService(
+	repo CredentialReencryptionRepository,
+	settingRepo SettingRepository,
+	cipher *CredentialCipher,
+	cfg *config.Config,
+	lockCache LeaderLockCache,
+	db *sql.DB,
+) *CredentialReencryptionService {
+	enc := cfg.Security.CredentialEncryption
+	svc := NewCredentialReencryptionService(repo, settingRepo, cipher, time.Duration(enc.ReencryptIntervalMinutes)*time.Minute, enc.ReencryptBatchSize)
+	svc.SetLeaderLock(lockCache, db)
+	svc.Start()
+	return svc
+}
+
+// ProvideAPIKeyHashBackfillService creates and starts APIKeyHashBackfillService.
+func ProvideAPIKeyHashBackfillService(
+	repo APIKeyHashBackfillRepository,
+	apiKeyService *APIKeyService,
+	cfg *config.Config,
+	lockCache LeaderLockCache,
+	db *sql.DB,
+) *APIKeyHashBackfillService {
+	svc := NewAPIKeyHashBackfillService(repo, time.Duration(cfg.Security.APIKeyHashBackfillIntervalMinutes)*time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	svc.SetAuthCacheGate(ctx, apiKeyService)
+	cancel()
+	svc.SetLeaderLock(lockCache, db)
+	svc.Start()
+	return svc
+}
+
+// ProvideAPIKeyLeakDetectionService creates and starts APIKeyLeakDetectionService.
+func ProvideAPIKeyLeakDetectionService(
+	repo APIKeyLeakRepository,
+	apiKeyRepo APIKeyRepository,
+	userRepo UserRepository,
+	authCacheInvalidator APIKeyAuthCacheInvalidator,
+	notificationEmailService *NotificationEmailService,
+	auditLogService *AuditLogService,
+	cfg *config.Config,
+	lockCache LeaderLockCache,
+	db *sql.DB,
+) *APIKeyLeakDetectionService {
+	svc := NewAPIKeyLeakDetectionService(repo, apiKeyRepo, userRepo, authCacheInvalidator, notificationEmailService, auditLogService, cfg.Security.APIKeyLeakDetection)
+	svc.SetLeaderLock(lockCache, db)
+	svc.Start()
+	return svc
+}
+
+// ProvideAPIKeyRotationService creates and starts APIKeyRotationService and
+// registers it as the grace-period lookup used by API key auth.
+func ProvideAPIKeyRotationService(
+	repo APIKeyRotationRepository,
+	apiKeyService *APIKeyService,
+	userRepo UserRepository,
+	encryptor *CredentialCipher,
+	notificationEmailService *NotificationEmailService,
+	auditLogService *AuditLogService,
+	cfg *config.Config,
+	lockCache LeaderLockCache,
+	db *sql.DB,
+) *APIKeyRotationService {
+	svc := NewAPIKeyRotationService(repo, apiKeyService, userRepo, encryptor, notificationEmailService, auditLogService, cfg.Security.APIKeyRotation)
+	svc.SetLeaderLock(lockCache, db)
+	apiKeyService.SetRotationGraceLookup(svc)
+	svc.Start()
+	return svc
+}
+
+// ProvideScopedTokenService creates ScopedTokenService and registers it as the
+// scoped token authenticator used by API key auth and usage billing.
+func ProvideScopedTokenService(
+	apiKeyRepo APIKeyRepository,
+	apiKeyService *APIKeyService,
+	usage ScopedTokenUsageCache,
+	cfg *config.Config,
+) *ScopedTokenService {
+	svc := NewScopedTokenService(apiKeyRepo, apiKeyService, usage, cfg)
+	apiKeyService.SetScopedTokenAuthenticator(svc)
+	return svc
+}
+
+// ProvideUserDataService creates and starts UserDataService (export and
+// account deletion worker).
+func ProvideUserDataService(
+	repo UserDataRepository,
+	userRepo UserRepository,
+	adminService AdminService,
+	authService *AuthService,
+	auditLogService *AuditLogService,
+	cfg *config.Config,
+	lockCache LeaderLockCache,
+	db *sql.DB,
+) *UserDataService {
+	svc := NewUserDataService(repo, userRepo, adminService, authService, auditLogService, cfg.UserData)
+	svc.SetLeaderLock(lockCache, db)
+	svc.Start()
+	return svc
+}
+
 // ProvideBackupService creates and starts BackupService
 func ProvideBackupService(
 	settingRepo SettingRepository,
 	cfg *config.Config,
-	encryptor SecretEncryptor,
+	encryptor *CredentialCipher,
 	storeFactory BackupObjectStoreFactory,
 	dumper DBDumper,
 	lockCache LeaderLockCache,
@@ -700,11 +835,12 @@ func ProvideOpsIngressRejectAggregator(opsRepo OpsRepository, opsService *OpsSer
 	return aggregator
 }
 
-// ProvideSettingService wires SettingService with group reader and proxy repo.
-func ProvideSettingService(settingRepo SettingRepository, groupRepo GroupRepository, proxyRepo ProxyRepository, cfg *config.Config) *SettingService {
+// ProvideSettingService wires SettingService with group reader, proxy repo and credential cipher.
+func ProvideSettingService(settingRepo SettingRepository, groupRepo GroupRepository, proxyRepo ProxyRepository, credentialCipher *CredentialCipher, cfg *config.Config) *SettingService {
 	svc := NewSettingService(settingRepo, cfg)
 	svc.SetDefaultSubscriptionGroupReader(groupRepo)
 	svc.SetProxyRepository(proxyRepo)
+	svc.SetCredentialCipher(credentialCipher)
 	if err := svc.LoadForwardedClientIPSettings(context.Background()); err != nil {
 		logger.LegacyPrintf("service.setting", "Warning: load forwarded client IP settings failed: %v", err)
 	}
@@ -781,10 +917,12 @@ var ProviderSet = wire.NewSet(
 	NewGatewayService,
 	NewOpenAIGatewayService,
 	ProvideImageStorageSettingService,
-	NewInvoiceStorageSettingService,
+	ProvideInvoiceStorageSettingService,
 	NewPayAttachmentService,
 	NewPayInvoiceNotifyService,
 	ProvideImageTaskService,
+	ProvideBackgroundResponseService,
+	NewStreamResumeService,
 	ProvideBatchImageModelPricingResolver,
 	NewBatchImagePublicService,
 	NewBatchImageDownloadService,
@@ -822,6 +960,9 @@ var ProviderSet = wire.NewSet(
 	ProvideOpsService,
 	ProvideOpsIngressRejectAggregator,
 	ProvideAuditLogService,
+	ProvideAuditLogExportService,
+	NewAdminRBACService,
+	NewSCIMService,
 	ProvideOpsMetricsCollector,
 	ProvideOpsAggregationService,
 	ProvideOpsAlertEvaluatorService,
@@ -848,6 +989,13 @@ var ProviderSet = wire.NewSet(
 	ProvideOpenAICodexVersionSyncService,
 	ProvideProxyExpiryService,
 	ProvideSubscriptionExpiryService,
+	NewCredentialCipher,
+	ProvideCredentialReencryptionService,
+	ProvideAPIKeyHashBackfillService,
+	ProvideAPIKeyLeakDetectionService,
+	ProvideAPIKeyRotationService,
+	ProvideScopedTokenService,
+	ProvideUserDataService,
 	ProvideTimingWheelService,
 	ProvideDashboardAggregationService,
 	ProvideUsageCleanupService,
@@ -861,9 +1009,15 @@ var ProviderSet = wire.NewSet(
 	NewTLSFingerprintProfileService,
 	NewDigestSessionStore,
 	ProvideIdempotencyCoordinator,
+	ProvideInferenceIdempotencyService,
+	NewContextCompactionService,
 	ProvideSystemOperationLockService,
 	ProvideIdempotencyCleanupService,
 	ProvideScheduledTestService,
+	NewAccountCostService,
+	NewUpstreamFileService,
+	NewProxyPoolService,
+	NewProxySubscriptionService,
 	ProvideScheduledTestRunnerService,
 	NewGroupCapacityService,
 	NewChannelService,
@@ -527,6 +527,7 @@ func TestFrontendServer_Middleware(t *testing.T) {
 			"/backend-api/codex/responses/compact",
 			"/antigravity/test",
 			"/setup/init",
+			"/scim/v2/Users",
 			"/health",
 			"/responses",
 			"/responses/compact",
@@ -790,6 +791,7 @@ func TestServeEmbeddedFrontend(t *testing.T) {
 			"/backend-api/codex/responses/compact",
 			"/antigravity/test",
 			"/setup/init",
+			"/scim/v2/Users",
 			"/health",
 			"/responses",
 			"/responses/compact",
@@ -0,0 +1,8 @@
+-- Optional prompt-prefix consistent-hash routing per group.
+-- When enabled, requests without a sticky binding are mapped onto accounts by
+-- hashing the cacheable prompt prefix (tools + system + first N messages, cut
+-- at the last cache_control breakpoint), so identical prefixes reuse the same
+-- upstream prompt cache. prompt_prefix_messages = 0 hashes tools + system only.
+ALTER TABLE groups
+    ADD COLUMN IF NOT EXISTS prompt_prefix_routing_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS prompt_prefix_messages INTEGER NOT NULL DEFAULT 0;
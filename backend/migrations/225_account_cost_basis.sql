@@ -0,0 +1,21 @@
+-- 账号采购成本台账：记录每个上游账号的购入价格与计费周期，
+-- 供账号 ROI 报表按时间摊销成本并与 usage_logs.actual_cost 收入对比。
+
+CREATE TABLE IF NOT EXISTS account_cost_bases (
+    account_id   BIGINT PRIMARY KEY REFERENCES accounts(id) ON DELETE CASCADE,
+    billing_type VARCHAR(20) NOT NULL DEFAULT 'subscription',
+    cost         DECIMAL(20, 8) NOT NULL DEFAULT 0,
+    period_days  INT NOT NULL DEFAULT 30,
+    started_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMPTZ,
+    notes        TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_cost_bases_expires_at ON account_cost_bases(expires_at) WHERE expires_at IS NOT NULL;
+
+COMMENT ON TABLE account_cost_bases IS '上游账号采购成本（USD），一账号一行。';
+COMMENT ON COLUMN account_cost_bases.billing_type IS 'subscription: 按 period_days 周期续费的订阅；prepaid: 一次性预付额度。';
+COMMENT ON COLUMN account_cost_bases.cost IS 'subscription 为每周期价格；prepaid 为预付总价。';
+COMMENT ON COLUMN account_cost_bases.expires_at IS '订阅下次续费/预付额度到期时间，NULL 表示未知。';
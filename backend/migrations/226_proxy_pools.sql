@@ -0,0 +1,43 @@
+-- 代理池：按标签/地域组织代理，账号开启自动分配后由调度器从池中挑选负载最低的健康代理，
+-- 并在代理到期或探测失败时自动改投；所有改投写入 proxy_reassignment_logs 留痕。
+
+CREATE TABLE IF NOT EXISTS proxy_pools (
+    id                     BIGSERIAL PRIMARY KEY,
+    name                   VARCHAR(100) NOT NULL,
+    region                 VARCHAR(64) NOT NULL DEFAULT '',
+    tags                   JSONB NOT NULL DEFAULT '[]'::jsonb,
+    max_accounts_per_proxy INT NOT NULL DEFAULT 0,
+    created_at             TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at             TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON COLUMN proxy_pools.region IS '国家代码或地区名，非空时仅选用探测出口地域匹配的代理。';
+COMMENT ON COLUMN proxy_pools.max_accounts_per_proxy IS '单代理可承载账号数上限（含手动绑定账号），0 表示不限。';
+
+CREATE TABLE IF NOT EXISTS proxy_pool_members (
+    pool_id    BIGINT NOT NULL REFERENCES proxy_pools(id) ON DELETE CASCADE,
+    proxy_id   BIGINT NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (pool_id, proxy_id)
+);
+CREATE INDEX IF NOT EXISTS idx_proxy_pool_members_proxy_id ON proxy_pool_members(proxy_id);
+
+-- 存在记录即表示账号处于自动分配模式。
+CREATE TABLE IF NOT EXISTS account_proxy_pools (
+    account_id BIGINT PRIMARY KEY REFERENCES accounts(id) ON DELETE CASCADE,
+    pool_id    BIGINT NOT NULL REFERENCES proxy_pools(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_account_proxy_pools_pool_id ON account_proxy_pools(pool_id);
+
+CREATE TABLE IF NOT EXISTS proxy_reassignment_logs (
+    id            BIGSERIAL PRIMARY KEY,
+    account_id    BIGINT NOT NULL,
+    pool_id       BIGINT,
+    from_proxy_id BIGINT,
+    to_proxy_id   BIGINT,
+    reason        VARCHAR(32) NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_proxy_reassignment_logs_account ON proxy_reassignment_logs(account_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_proxy_reassignment_logs_pool ON proxy_reassignment_logs(pool_id, created_at DESC);
@@ -0,0 +1,27 @@
+-- 代理订阅：定时拉取供应商下发的 Clash / sing-box / V2Ray / URI 文本列表并同步到 proxies。
+-- proxy_subscription_items 记录每个订阅导入的代理，用于识别"已从订阅中消失"的代理并将其标记过期（不删除）。
+
+CREATE TABLE IF NOT EXISTS proxy_subscriptions (
+    id               BIGSERIAL PRIMARY KEY,
+    name             VARCHAR(100) NOT NULL,
+    url              TEXT NOT NULL,
+    format           VARCHAR(20) NOT NULL DEFAULT 'auto',
+    interval_minutes INT NOT NULL DEFAULT 60,
+    enabled          BOOLEAN NOT NULL DEFAULT TRUE,
+    last_synced_at   TIMESTAMPTZ,
+    last_error       TEXT NOT NULL DEFAULT '',
+    expires_at       TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON COLUMN proxy_subscriptions.format IS 'auto / clash / singbox / v2ray / text';
+COMMENT ON COLUMN proxy_subscriptions.expires_at IS '最近一次同步从 subscription-userinfo 读到的订阅到期时间，会写入所导入代理的 expires_at。';
+
+CREATE TABLE IF NOT EXISTS proxy_subscription_items (
+    subscription_id BIGINT NOT NULL REFERENCES proxy_subscriptions(id) ON DELETE CASCADE,
+    proxy_id        BIGINT NOT NULL REFERENCES proxies(id) ON DELETE CASCADE,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (subscription_id, proxy_id)
+);
+CREATE INDEX IF NOT EXISTS idx_proxy_subscription_items_proxy_id ON proxy_subscription_items(proxy_id);
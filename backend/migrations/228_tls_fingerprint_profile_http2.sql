@@ -0,0 +1,6 @@
+-- Add HTTP/2 fingerprint section to TLS fingerprint profiles.
+-- Applied only when the profile advertises h2 via ALPN and the handshake negotiates HTTP/2.
+
+ALTER TABLE tls_fingerprint_profiles ADD COLUMN IF NOT EXISTS http2 JSONB;
+
+COMMENT ON COLUMN tls_fingerprint_profiles.http2 IS 'HTTP/2 fingerprint: ordered SETTINGS, connection WINDOW_UPDATE, PRIORITY frames, HEADERS priority and pseudo-header order';
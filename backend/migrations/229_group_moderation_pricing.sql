@@ -0,0 +1,4 @@
+-- /v1/moderations 对外审计定价：按次价格与每百万输入 token 价格，可叠加。
+-- NULL = 该维度不收费；>0 = 分组单价（乘分组倍率）。
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS moderation_price_per_call DECIMAL(20,8);
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS moderation_price_per_million_tokens DECIMAL(20,8);
@@ -0,0 +1,23 @@
+-- Files API 绑定：上游文件只存在于上传它的账号上，记录 (平台, 文件 ID) → 账号，
+-- 使引用该文件的请求固定路由到该账号，并据 user_id 校验文件归属。
+
+CREATE TABLE IF NOT EXISTS upstream_files (
+    id          BIGSERIAL PRIMARY KEY,
+    platform    VARCHAR(20) NOT NULL,
+    file_id     VARCHAR(255) NOT NULL,
+    user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    api_key_id  BIGINT NOT NULL,
+    group_id    BIGINT,
+    account_id  BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    filename    TEXT NOT NULL DEFAULT '',
+    purpose     VARCHAR(64) NOT NULL DEFAULT '',
+    bytes       BIGINT NOT NULL DEFAULT 0,
+    object      JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_upstream_files_platform_file_id ON upstream_files(platform, file_id);
+CREATE INDEX IF NOT EXISTS idx_upstream_files_user_platform ON upstream_files(user_id, platform, created_at);
+
+COMMENT ON TABLE upstream_files IS '经网关上传的上游文件（OpenAI Files / Anthropic Files）与持有账号的绑定。';
+COMMENT ON COLUMN upstream_files.object IS '上传时上游返回的文件对象，用于 list 接口（只列出调用方自己的文件）。';
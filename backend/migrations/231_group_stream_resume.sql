@@ -0,0 +1,7 @@
+-- Optional resumable streaming per group.
+-- When enabled, streaming requests get gateway-assigned SSE event IDs and an
+-- X-Sub2API-Stream-ID header; the upstream stream keeps being relayed into a
+-- short-lived buffer after the client drops, and the client can reconnect with
+-- Last-Event-ID to receive the remainder without a second upstream request.
+ALTER TABLE groups
+    ADD COLUMN IF NOT EXISTS stream_resume_enabled BOOLEAN NOT NULL DEFAULT FALSE;
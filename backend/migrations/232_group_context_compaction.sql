@@ -0,0 +1,8 @@
+-- Optional context-window overflow compaction per group.
+-- When a /v1/messages or /v1/chat/completions prompt is estimated to exceed
+-- the mapped model's context window, the gateway either drops the oldest turns
+-- (truncate) or replaces them with a summary produced by
+-- context_compaction_model from the same account pool (summarize).
+ALTER TABLE groups
+    ADD COLUMN IF NOT EXISTS context_compaction_mode VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS context_compaction_model VARCHAR(100) NOT NULL DEFAULT '';
@@ -0,0 +1,165 @@
+-- Hashed API key storage.
+-- api_keys.key now holds 'hmac-sha256:<hex>' (HMAC-SHA256 keyed by the server
+-- pepper in security_secrets) instead of the secret itself; key_prefix keeps
+-- the leading characters for display. Existing rows are hashed in the
+-- background by the application (the pepper never enters SQL), and lookups
+-- accept both forms until then, so clients keep working during the rollout.
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS key_prefix VARCHAR(16) NOT NULL DEFAULT '';
+
+UPDATE api_keys
+SET key_prefix = left(key, LEAST(8, length(key) / 2))
+WHERE key_prefix = ''
+  AND key NOT LIKE 'hmac-sha256:%'
+  AND key NOT LIKE '\_\_deleted\_\_%';
+
+-- The auth cache is keyed by the HMAC digest, which for hashed rows is stored
+-- verbatim after the scheme marker. Legacy plaintext rows cannot be mapped to
+-- the digest without the pepper; until the backfill rewrites them (the rewrite
+-- itself enqueues the digest) they fall back to the previous SHA-256 key and
+-- rely on application-level invalidation.
+CREATE OR REPLACE FUNCTION api_key_auth_cache_key(stored_key TEXT)
+RETURNS TEXT
+LANGUAGE sql
+IMMUTABLE
+AS $$
+    SELECT CASE
+        WHEN stored_key LIKE 'hmac-sha256:%' THEN substr(stored_key, length('hmac-sha256:') + 1)
+        ELSE encode(sha256(convert_to(stored_key, 'UTF8')), 'hex')
+    END;
+$$;
+
+CREATE OR REPLACE FUNCTION enqueue_auth_cache_invalidation(raw_key TEXT)
+RETURNS VOID
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    IF raw_key IS NULL OR raw_key = '' THEN
+        RETURN;
+    END IF;
+    INSERT INTO auth_cache_invalidation_outbox (cache_key)
+    VALUES (api_key_auth_cache_key(raw_key));
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION enqueue_user_auth_cache_invalidation()
+RETURNS TRIGGER
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    target_user_id BIGINT;
+BEGIN
+    target_user_id := OLD.id;
+    IF TG_OP = 'UPDATE'
+       AND OLD.status IS NOT DISTINCT FROM NEW.status
+       AND OLD.role IS NOT DISTINCT FROM NEW.role
+       AND OLD.deleted_at IS NOT DISTINCT FROM NEW.deleted_at THEN
+        RETURN NEW;
+    END IF;
+
+    INSERT INTO auth_cache_invalidation_outbox (cache_key)
+    SELECT api_key_auth_cache_key(k.key)
+    FROM api_keys AS k
+    WHERE k.user_id = target_user_id
+      AND k.deleted_at IS NULL
+      AND k.key <> '';
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$;
+
+-- Based on the latest function body from 193_group_profit_control_auth_cache_invalidation.sql.
+CREATE OR REPLACE FUNCTION enqueue_group_auth_cache_invalidation()
+RETURNS TRIGGER
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    target_group_id BIGINT;
+BEGIN
+    target_group_id := OLD.id;
+    IF TG_OP = 'UPDATE'
+       AND OLD.status IS NOT DISTINCT FROM NEW.status
+       AND OLD.is_exclusive IS NOT DISTINCT FROM NEW.is_exclusive
+       AND OLD.allow_image_generation IS NOT DISTINCT FROM NEW.allow_image_generation
+       AND OLD.platform IS NOT DISTINCT FROM NEW.platform
+       AND OLD.subscription_type IS NOT DISTINCT FROM NEW.subscription_type
+       AND OLD.rate_multiplier IS NOT DISTINCT FROM NEW.rate_multiplier
+       AND OLD.peak_rate_enabled IS NOT DISTINCT FROM NEW.peak_rate_enabled
+       AND OLD.peak_start IS NOT DISTINCT FROM NEW.peak_start
+       AND OLD.peak_end IS NOT DISTINCT FROM NEW.peak_end
+       AND OLD.peak_rate_multiplier IS NOT DISTINCT FROM NEW.peak_rate_multiplier
+       AND OLD.profit_control_enabled IS NOT DISTINCT FROM NEW.profit_control_enabled
+       AND OLD.profit_min_margin IS NOT DISTINCT FROM NEW.profit_min_margin
+       AND OLD.profit_safety_buffer IS NOT DISTINCT FROM NEW.profit_safety_buffer
+       AND OLD.deleted_at IS NOT DISTINCT FROM NEW.deleted_at THEN
+        RETURN NEW;
+    END IF;
+
+    INSERT INTO auth_cache_invalidation_outbox (cache_key)
+    SELECT api_key_auth_cache_key(k.key)
+    FROM api_keys AS k
+    WHERE k.group_id = target_group_id
+      AND k.deleted_at IS NULL
+      AND k.key <> '';
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION enqueue_allowed_group_auth_cache_invalidation()
+RETURNS TRIGGER
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    target_user_id BIGINT;
+    target_group_id BIGINT;
+BEGIN
+    IF TG_OP = 'UPDATE'
+       AND (OLD.user_id IS DISTINCT FROM NEW.user_id
+            OR OLD.group_id IS DISTINCT FROM NEW.group_id) THEN
+        IF EXISTS (
+            SELECT 1 FROM groups g
+            WHERE g.id = OLD.group_id AND g.is_exclusive = TRUE
+        ) THEN
+            INSERT INTO auth_cache_invalidation_outbox (cache_key)
+            SELECT api_key_auth_cache_key(k.key)
+            FROM api_keys AS k
+            WHERE k.user_id = OLD.user_id
+              AND k.group_id = OLD.group_id
+              AND k.deleted_at IS NULL
+              AND k.key <> '';
+        END IF;
+        target_user_id := NEW.user_id;
+        target_group_id := NEW.group_id;
+    ELSIF TG_OP = 'UPDATE' THEN
+        RETURN NEW;
+    ELSIF TG_OP = 'INSERT' THEN
+        target_user_id := NEW.user_id;
+        target_group_id := NEW.group_id;
+    ELSE
+        target_user_id := OLD.user_id;
+        target_group_id := OLD.group_id;
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM groups g
+        WHERE g.id = target_group_id AND g.is_exclusive = TRUE
+    ) THEN
+        INSERT INTO auth_cache_invalidation_outbox (cache_key)
+        SELECT api_key_auth_cache_key(k.key)
+        FROM api_keys AS k
+        WHERE k.user_id = target_user_id
+          AND k.group_id = target_group_id
+          AND k.deleted_at IS NULL
+          AND k.key <> '';
+    END IF;
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$;
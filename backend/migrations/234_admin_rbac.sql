@@ -0,0 +1,39 @@
+-- Fine-grained admin RBAC.
+-- Built-in roles (super_admin, support, finance, auditor) live in code; this
+-- table only stores operator-defined roles. Admins without an assignment keep
+-- full access, so existing deployments behave exactly as before.
+CREATE TABLE IF NOT EXISTS admin_roles (
+    name VARCHAR(64) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    permissions JSONB NOT NULL DEFAULT '[]'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS admin_role_assignments (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    role_name VARCHAR(64) NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS admin_role_assignments_role_name_idx
+    ON admin_role_assignments (role_name);
+
+-- Named, scoped admin API tokens. Only the SHA-256 of the token is stored.
+CREATE TABLE IF NOT EXISTS admin_api_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    token_prefix VARCHAR(16) NOT NULL DEFAULT '',
+    scopes JSONB NOT NULL DEFAULT '[]'::jsonb,
+    created_by BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NULL,
+    last_used_at TIMESTAMPTZ NULL,
+    last_used_ip VARCHAR(64) NOT NULL DEFAULT '',
+    revoked_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS admin_api_tokens_created_by_idx
+    ON admin_api_tokens (created_by);
@@ -0,0 +1,36 @@
+-- SCIM 2.0 provisioning.
+-- scim_users links sub2api users to the identity provider's externalId;
+-- scim_groups marks which sub2api groups the IdP manages. A SCIM group never
+-- creates a sub2api group, it only maps onto an existing one by name.
+CREATE TABLE IF NOT EXISTS scim_users (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    external_id VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS scim_users_external_id_idx
+    ON scim_users (external_id)
+    WHERE external_id <> '';
+
+CREATE TABLE IF NOT EXISTS scim_groups (
+    group_id BIGINT PRIMARY KEY REFERENCES groups(id) ON DELETE CASCADE,
+    external_id VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Memberships pushed by the IdP. subscription_id / granted_allowed_group
+-- record exactly which grant SCIM made, so removing the member only reverts
+-- access that SCIM itself added and never touches manual assignments.
+CREATE TABLE IF NOT EXISTS scim_group_members (
+    group_id BIGINT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    subscription_id BIGINT NULL,
+    granted_allowed_group BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS scim_group_members_user_id_idx
+    ON scim_group_members (user_id);
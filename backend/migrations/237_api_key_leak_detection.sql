@@ -0,0 +1,34 @@
+-- API key leak detection.
+-- api_key_leak_policies stores the owner's chosen response when one of their
+-- keys looks leaked; users without a row fall back to the configured default.
+CREATE TABLE IF NOT EXISTS api_key_leak_policies (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    action VARCHAR(32) NOT NULL DEFAULT 'notify',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One row per detection. signals keeps the window statistics that tripped the
+-- detector; outcome records what was actually done (which can differ from the
+-- policy, e.g. require_allowlist degrades to disable when no baseline IP exists).
+CREATE TABLE IF NOT EXISTS api_key_leak_events (
+    id BIGSERIAL PRIMARY KEY,
+    api_key_id BIGINT NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reasons TEXT[] NOT NULL DEFAULT '{}',
+    signals JSONB NOT NULL DEFAULT '{}'::jsonb,
+    action VARCHAR(32) NOT NULL,
+    outcome VARCHAR(32) NOT NULL,
+    pinned_ips TEXT[] NOT NULL DEFAULT '{}',
+    window_start TIMESTAMPTZ NOT NULL,
+    window_end TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS api_key_leak_events_api_key_created_idx
+    ON api_key_leak_events (api_key_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS api_key_leak_events_user_created_idx
+    ON api_key_leak_events (user_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS api_key_leak_events_created_idx
+    ON api_key_leak_events (created_at DESC);
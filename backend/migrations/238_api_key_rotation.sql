@@ -0,0 +1,36 @@
+-- API key rotation.
+-- One row per key that has been rotated, was minted by a rotation, or carries
+-- a rotation schedule. lineage_id is the id of the first key in the chain so
+-- usage can be reported per logical key across rotations; keys without a row
+-- are their own lineage.
+CREATE TABLE IF NOT EXISTS api_key_rotations (
+    api_key_id BIGINT PRIMARY KEY REFERENCES api_keys(id) ON DELETE CASCADE,
+    lineage_id BIGINT NOT NULL,
+    predecessor_id BIGINT REFERENCES api_keys(id) ON DELETE SET NULL,
+    successor_id BIGINT REFERENCES api_keys(id) ON DELETE SET NULL,
+    -- Set once the key has been superseded; the key keeps working until
+    -- grace_expires_at, which is also written to api_keys.expires_at.
+    rotated_at TIMESTAMPTZ,
+    grace_expires_at TIMESTAMPTZ,
+    -- Periodic rotation: 0 disables. schedule_grace_hours NULL means the
+    -- configured default grace period.
+    schedule_interval_days INT NOT NULL DEFAULT 0,
+    schedule_grace_hours INT,
+    next_rotation_at TIMESTAMPTZ,
+    -- Encrypted plaintext of a key minted by the scheduler, kept until the
+    -- owner reveals it once. Manual rotations return the key directly.
+    pending_secret TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS api_key_rotations_lineage_idx
+    ON api_key_rotations (lineage_id);
+
+CREATE INDEX IF NOT EXISTS api_key_rotations_due_idx
+    ON api_key_rotations (next_rotation_at)
+    WHERE schedule_interval_days > 0 AND successor_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS api_key_rotations_grace_idx
+    ON api_key_rotations (grace_expires_at)
+    WHERE successor_id IS NOT NULL;
@@ -0,0 +1,6 @@
+-- Short-lived scoped tokens.
+-- Tokens derived from an API key are signed with a key that mixes the server
+-- signing secret with this per-key nonce. Rotating the nonce invalidates every
+-- outstanding token of the key at once; an empty nonce is a valid initial value.
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS token_nonce VARCHAR(64) NOT NULL DEFAULT '';
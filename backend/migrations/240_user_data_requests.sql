@@ -0,0 +1,55 @@
+-- Self-service data export and account deletion (GDPR).
+--
+-- user_data_exports: one row per export job. The worker builds a ZIP archive of
+-- the user's data and stores it inline; the archive is dropped once
+-- expires_at passes, the row itself is kept as a record of the request.
+CREATE TABLE IF NOT EXISTS user_data_exports (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- pending / running / completed / failed / expired
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    archive BYTEA,
+    archive_bytes BIGINT NOT NULL DEFAULT 0,
+    error_message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS user_data_exports_user_idx
+    ON user_data_exports (user_id, created_at DESC);
+
+-- At most one in-flight export per user.
+CREATE UNIQUE INDEX IF NOT EXISTS user_data_exports_active_uidx
+    ON user_data_exports (user_id)
+    WHERE status IN ('pending', 'running');
+
+CREATE INDEX IF NOT EXISTS user_data_exports_queue_idx
+    ON user_data_exports (created_at)
+    WHERE status IN ('pending', 'running');
+
+CREATE INDEX IF NOT EXISTS user_data_exports_expiry_idx
+    ON user_data_exports (expires_at)
+    WHERE status = 'completed';
+
+-- user_deletion_requests: at most one row per user. A pending request is
+-- executed after scheduled_for (the cooling-off period) unless cancelled.
+-- Execution anonymizes the user instead of dropping rows so usage and billing
+-- aggregates stay intact.
+CREATE TABLE IF NOT EXISTS user_deletion_requests (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    -- pending / cancelled / completed
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    reason TEXT NOT NULL DEFAULT '',
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    scheduled_for TIMESTAMPTZ NOT NULL,
+    cancelled_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    last_error TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS user_deletion_requests_due_idx
+    ON user_deletion_requests (scheduled_for)
+    WHERE status = 'pending';
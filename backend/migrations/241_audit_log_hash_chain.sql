@@ -0,0 +1,43 @@
+-- Tamper-evident audit log.
+--
+-- Each row stores the hash of the previous row (by id) and its own hash over
+-- prev_hash plus the row content. Writers serialize on an advisory lock so the
+-- chain order equals the id order. Rows written before this migration keep an
+-- empty row_hash and are reported as legacy by the verifier.
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS prev_hash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS row_hash VARCHAR(64) NOT NULL DEFAULT '';
+
+-- audit_log_archives: rows are only removed from audit_logs after being
+-- uploaded to object storage. Each archive covers a contiguous id prefix; its
+-- last_row_hash anchors the chain for the rows that remain.
+CREATE TABLE IF NOT EXISTS audit_log_archives (
+    id BIGSERIAL PRIMARY KEY,
+    object_key TEXT NOT NULL,
+    first_id BIGINT NOT NULL,
+    last_id BIGINT NOT NULL,
+    row_count BIGINT NOT NULL DEFAULT 0,
+    first_prev_hash VARCHAR(64) NOT NULL DEFAULT '',
+    last_row_hash VARCHAR(64) NOT NULL DEFAULT '',
+    object_sha256 VARCHAR(64) NOT NULL DEFAULT '',
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    chain_valid BOOLEAN NOT NULL DEFAULT TRUE,
+    -- manual / retention
+    triggered_by VARCHAR(20) NOT NULL DEFAULT 'manual',
+    actor_user_id BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_archives_last_id_idx
+    ON audit_log_archives (last_id DESC);
+
+-- audit_log_export_cursors: last audit id acknowledged by each SIEM sink.
+-- The cursor only advances after a batch is delivered, so a restart resumes
+-- from the first undelivered row.
+CREATE TABLE IF NOT EXISTS audit_log_export_cursors (
+    sink_name VARCHAR(64) PRIMARY KEY,
+    last_id BIGINT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    last_error_at TIMESTAMPTZ,
+    delivered_count BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -30,6 +30,7 @@ import channelsAPI from './channels'
 import riskControlAPI from './riskControl'
 import adminComplianceAPI from './compliance'
 import auditAPI from './audit'
+import adminRBACAPI from './rbac'
 
 /**
  * Unified admin API object for convenient access
@@ -61,7 +62,8 @@ export const adminAPI = {
   channels: channelsAPI,
   riskControl: riskControlAPI,
   compliance: adminComplianceAPI,
-  audit: auditAPI
+  audit: auditAPI,
+  rbac: adminRBACAPI
 }
 
 export {
@@ -91,7 +93,8 @@ export {
   channelsAPI,
   riskControlAPI,
   adminComplianceAPI,
-  auditAPI
+  auditAPI,
+  adminRBACAPI
 }
 
 export default adminAPI
@@ -0,0 +1,92 @@
+import { apiClient } from '@/api/client'
+
+export interface AdminRole {
+  name: string
+  description: string
+  permissions: string[]
+  built_in: boolean
+  created_at?: string
+  updated_at?: string
+}
+
+export interface AdminAPIToken {
+  id: number
+  name: string
+  token_prefix: string
+  scopes: string[]
+  created_by: number
+  expires_at?: string
+  last_used_at?: string
+  last_used_ip: string
+  revoked_at?: string
+  created_at: string
+  updated_at: string
+}
+
+export interface AdminPermissionsInfo {
+  role: string
+  permissions: string[]
+}
+
+export interface CreateAdminAPITokenRequest {
+  name: string
+  scopes: string[]
+  expires_at?: string
+}
+
+export interface CreateAdminAPITokenResponse {
+  token: string
+  info: AdminAPIToken
+}
+
+export const adminRBACAPI = {
+  async getMe(): Promise<AdminPermissionsInfo> {
+    const { data } = await apiClient.get<AdminPermissionsInfo>('/admin/rbac/me')
+    return data
+  },
+
+  async listPermissions(): Promise<{ permissions: string[] }> {
+    const { data } = await apiClient.get<{ permissions: string[] }>('/admin/rbac/permissions')
+    return data
+  },
+
+  async listRoles(): Promise<AdminRole[]> {
+    const { data } = await apiClient.get<AdminRole[]>('/admin/rbac/roles')
+    return data
+  },
+
+  async upsertRole(name: string, payload: { description?: string; permissions: string[] }): Promise<AdminRole> {
+    const { data } = await apiClient.put<AdminRole>(`/admin/rbac/roles/${encodeURIComponent(name)}`, payload)
+    return data
+  },
+
+  async deleteRole(name: string): Promise<void> {
+    await apiClient.delete(`/admin/rbac/roles/${encodeURIComponent(name)}`)
+  },
+
+  async listAssignments(): Promise<Record<string, string>> {
+    const { data } = await apiClient.get<Record<string, string>>('/admin/rbac/assignments')
+    return data
+  },
+
+  async assignUserRole(userId: number, role: string): Promise<{ user_id: number; role: string }> {
+    const { data } = await apiClient.put<{ user_id: number; role: string }>(`/admin/rbac/users/${userId}/role`, { role })
+    return data
+  },
+
+  async listTokens(): Promise<AdminAPIToken[]> {
+    const { data } = await apiClient.get<AdminAPIToken[]>('/admin/rbac/tokens')
+    return data
+  },
+
+  async createToken(payload: CreateAdminAPITokenRequest): Promise<CreateAdminAPITokenResponse> {
+    const { data } = await apiClient.post<CreateAdminAPITokenResponse>('/admin/rbac/tokens', payload)
+    return data
+  },
+
+  async revokeToken(id: number): Promise<void> {
+    await apiClient.delete(`/admin/rbac/tokens/${id}`)
+  }
+}
+
+export default adminRBACAPI
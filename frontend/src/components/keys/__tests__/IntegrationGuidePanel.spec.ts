@@ -53,7 +53,8 @@ import IntegrationGuidePanel from '../IntegrationGuidePanel.vue'
 function createApiKey(overrides: Record<string, unknown> = {}) {
   return {
     id: 1,
-    user_id: 1,
+    key: 'sk-live-example-1234567890',
+    key_prefix: 'sk-live-',
     key: 'sk-live-example-1234567890',
     name: 'Demo Key',
     group_id: 11,
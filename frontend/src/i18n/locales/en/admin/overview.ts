@@ -1029,6 +1029,13 @@ export default {
         audioSttPerHour: 'STT price per hour (USD)',
         pricePlaceholder: 'optional'
       },
+      moderationPricing: {
+        title: 'Moderation API Pricing',
+        description: 'Price for /v1/moderations calls served from the content-moderation key pool. The per-call and per-token prices add up; leave empty for free. The group rate multiplier is applied on top.',
+        pricePerCall: 'Price per call (USD)',
+        pricePerMillionTokens: 'Price per million input tokens (USD)',
+        pricePlaceholder: 'optional'
+      },
       webSearchPricing: {
         title: 'Codex Web Search Pricing',
         pricePerCall: 'Price per search call (USD)',
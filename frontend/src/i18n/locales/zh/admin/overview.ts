@@ -1026,6 +1026,13 @@ export default {
         audioSttPerHour: 'STT 每小时价格（USD）',
         pricePlaceholder: '可选'
       },
+      moderationPricing: {
+        title: '内容审核 API 定价',
+        description: '/v1/moderations 调用（使用内容审计 key 池）的价格。按次价与按 token 价叠加计费，留空表示免费；实际扣费会叠加分组费率倍数。',
+        pricePerCall: '每次调用价格（USD）',
+        pricePerMillionTokens: '每百万输入 token 价格（USD）',
+        pricePlaceholder: '可选'
+      },
       webSearchPricing: {
         title: 'Codex 网页搜索计费',
         pricePerCall: '搜索单次价格（USD/次）',
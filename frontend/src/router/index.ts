@@ -258,6 +258,16 @@ const routes: RouteRecordRaw[] = [
       titleKey: 'auth.oidcCallbackPageTitle'
     }
   },
+  {
+    path: '/auth/saml/callback',
+    name: 'SAMLCallback',
+    component: () => import('@/views/auth/OidcCallbackView.vue'),
+    meta: {
+      requiresAuth: false,
+      title: 'SAML SSO Callback',
+      titleKey: 'auth.oidcCallbackPageTitle'
+    }
+  },
   {
     path: '/forgot-password',
     name: 'ForgotPassword',
@@ -662,6 +672,7 @@ const BACKEND_MODE_CALLBACK_PATHS = [
   '/auth/dingtalk/callback',
   '/auth/dingtalk/email-completion',
   '/auth/oidc/callback',
+  '/auth/saml/callback',
   '/auth/wechat/callback',
 ]
 const BACKEND_MODE_PENDING_AUTH_PATHS = ['/register', '/email-verify']
@@ -34,7 +34,7 @@ export interface NotifyEmailEntry {
 
 // ==================== User & Auth Types ====================
 
-export type UserAuthProvider = 'email' | 'linuxdo' | 'oidc' | 'wechat' | 'github' | 'google' | 'dingtalk'
+export type UserAuthProvider = 'email' | 'linuxdo' | 'oidc' | 'saml' | 'wechat' | 'github' | 'google' | 'dingtalk'
 
 export interface UserAuthBindingStatus {
   bound?: boolean
@@ -251,6 +251,8 @@ export interface PublicSettings {
   wechat_oauth_mobile_enabled?: boolean
   oidc_oauth_enabled: boolean
   oidc_oauth_provider_name: string
+  saml_enabled?: boolean
+  saml_provider_name?: string
   github_oauth_enabled: boolean
   google_oauth_enabled: boolean
   backend_mode_enabled: boolean
@@ -595,6 +597,9 @@ export interface Group {
   audio_realtime_price_per_min: number | null
   audio_tts_price_per_million_chars: number | null
   audio_stt_price_per_hour: number | null
+  // /v1/moderations 定价（按次 + 每百万输入 token，可叠加）；null 表示不收费
+  moderation_price_per_call: number | null
+  moderation_price_per_million_tokens: number | null
   // 高峰时段倍率配置
   peak_rate_enabled: boolean
   peak_start: string
@@ -713,7 +718,8 @@ export interface CompositeRouteDecision {
 export interface ApiKey {
   id: number
   user_id: number
-  key: string
+  key: string // only populated in the create response; the server stores a hash
+  key_prefix: string
   name: string
   group_id: number | null
   status: 'active' | 'inactive' | 'quota_exhausted' | 'expired'
@@ -802,6 +808,8 @@ export interface CreateGroupRequest {
   audio_realtime_price_per_min?: number | null
   audio_tts_price_per_million_chars?: number | null
   audio_stt_price_per_hour?: number | null
+  moderation_price_per_call?: number | null
+  moderation_price_per_million_tokens?: number | null
   peak_rate_enabled?: boolean
   peak_start?: string
   peak_end?: string
@@ -864,6 +872,8 @@ export interface UpdateGroupRequest {
   audio_realtime_price_per_min?: number | null
   audio_tts_price_per_million_chars?: number | null
   audio_stt_price_per_hour?: number | null
+  moderation_price_per_call?: number | null
+  moderation_price_per_million_tokens?: number | null
   peak_rate_enabled?: boolean
   peak_start?: string
   peak_end?: string